@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/batch"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newBatchCommand())
+}
+
+func newBatchCommand() *cobra.Command {
+	var (
+		input, output string
+		concurrency   int
+		quiet         bool
+	)
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run many prompts through the agent and write the answers as JSONL",
+		Long: `Run every prompt of a JSONL file through the agent, with tools, and write
+one JSON result per line in input order.
+
+Input lines are objects such as {"id": "row-1", "prompt": "..."} ("text" is
+accepted for "prompt"), or plain text taken as the prompt. Each prompt gets
+its own conversation; items naming the same "conversation" share context.
+Identical prompts are answered once.
+
+Results look like {"index": 0, "id": "row-1", "prompt": "...", "output":
+"...", "duration_ms": 1234} with "error" instead of "output" on failure.
+Progress goes to stderr. Use - for stdin or stdout.
+
+  coco batch --input prompts.jsonl --output results.jsonl --concurrency 8`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if input != "-" {
+				f, err := os.Open(input)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			items, err := batch.ReadItems(in)
+			if err != nil {
+				return fmt.Errorf("%s: %w", input, err)
+			}
+			if len(items) == 0 {
+				return fmt.Errorf("%s has no prompts", input)
+			}
+
+			out := cmd.OutOrStdout()
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+
+			aiAgent, err := agent.New(agent.Config{
+				AllowedPaths:          loadAllowedPaths(),
+				BlockedCommands:       loadBlockedCommands(),
+				RequireConfirmation:   loadRequireConfirmation(),
+				AllowFrom:             loadAllowFrom(),
+				RequireMentionInGroup: loadRequireMentionInGroup(),
+				DisableFileTools:      loadDisableFileTools(),
+			})
+			if err != nil {
+				return fmt.Errorf("creating agent: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			failed, err := runBatch(ctx, aiAgent.HandleMessage, items, concurrency, out, progressWriter(cmd.ErrOrStderr(), quiet))
+			if err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d prompts failed", failed, len(items))
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&input, "input", "i", "-", "JSONL file of prompts")
+	f.StringVarP(&output, "output", "o", "-", "JSONL file for the results")
+	f.IntVarP(&concurrency, "concurrency", "c", batch.DefaultConcurrency, fmt.Sprintf("Prompts to run at once (at most %d)", batch.MaxConcurrency))
+	f.BoolVarP(&quiet, "quiet", "q", false, "Do not report progress")
+	return cmd
+}
+
+// runBatch writes the results of items to out and returns how many failed.
+func runBatch(ctx context.Context, handle batch.Handler, items []batch.Item, concurrency int, out io.Writer, progress func(done, total int, r batch.Result)) (int, error) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	failed := 0
+	var writeErr error
+	batch.Run(ctx, handle, items, batch.Options{Concurrency: concurrency, Progress: progress}, func(r batch.Result) {
+		if r.Error != "" {
+			failed++
+		}
+		if writeErr == nil {
+			writeErr = enc.Encode(r)
+		}
+	})
+	return failed, writeErr
+}
+
+func progressWriter(w io.Writer, quiet bool) func(done, total int, r batch.Result) {
+	if quiet {
+		return nil
+	}
+	start := time.Now()
+	return func(done, total int, r batch.Result) {
+		status := "ok"
+		switch {
+		case r.Error != "":
+			status = "error: " + r.Error
+		case r.Cached:
+			status = "ok (same as an earlier prompt)"
+		}
+		name := r.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", r.Index+1)
+		}
+		fmt.Fprintf(w, "[%d/%d %s] %s %s\n", done, total, time.Since(start).Round(time.Second), name, status)
+	}
+}
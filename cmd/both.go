@@ -39,6 +39,8 @@ func runBoth(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	claimInstance("both", nil)
+
 	cfg, _ := config.Load()
 
 	port := keeperPort
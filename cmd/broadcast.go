@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/instance"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newBroadcastCommand())
+}
+
+func newBroadcastCommand() *cobra.Command {
+	var (
+		tags    []string
+		noWait  bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "broadcast <message>",
+		Short: "Announce a message to every active conversation",
+		Long: `Queue an announcement for the running coco to send to every conversation
+used in the last 30 days, e.g. a maintenance notice:
+
+  coco broadcast "我今晚要升级，稍后离线"
+
+With --tag only channels whose profile carries one of the tags get it:
+
+  channels:
+    "wecom:family-group":
+      tags: [family]
+
+  coco broadcast --tag family "周末聚餐改到周日"
+
+The running coco picks the broadcast up within 15 seconds and sends one
+message per second. This command waits for the delivery report unless
+--no-wait is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			message := strings.TrimSpace(args[0])
+			if message == "" {
+				return fmt.Errorf("message is empty")
+			}
+			out := cmd.OutOrStdout()
+			if !relayRunning() {
+				fmt.Fprintln(out, "Warning: no coco relay is running on this data dir; the broadcast is sent once one starts.")
+			}
+
+			store, err := persist.NewStore(datadir.Path(".coco.db"))
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			var normalized []string
+			for _, t := range tags {
+				if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+					normalized = append(normalized, t)
+				}
+			}
+			id, err := store.QueueBroadcast(persist.Broadcast{Message: message, Tags: normalized, Source: "cli"})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Queued broadcast #%d\n", id)
+			if noWait {
+				return nil
+			}
+			return waitForBroadcast(out, store, id, timeout)
+		},
+	}
+	cmd.Flags().StringArrayVar(&tags, "tag", nil, "Only send to channels with this tag (repeatable)")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "Return once queued instead of waiting for delivery")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "How long to wait for delivery")
+	return cmd
+}
+
+// relayRunning reports whether a coco relay owns this data dir, so queued
+// broadcasts will be delivered.
+func relayRunning() bool {
+	instances, err := instance.List()
+	if err != nil {
+		return false
+	}
+	dir := datadir.Dir()
+	for _, in := range instances {
+		if in.DataDir == dir && (in.Mode == "relay" || in.UserID != "") {
+			return true
+		}
+	}
+	return false
+}
+
+func waitForBroadcast(out io.Writer, store *persist.Store, id int64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		b, err := store.GetBroadcast(id)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("broadcast #%d disappeared", id)
+		}
+		if b.Status == persist.BroadcastDone {
+			fmt.Fprintf(out, "Delivered to %d/%d conversation(s)\n", b.Sent, b.Total)
+			if b.Report != "" {
+				fmt.Fprintf(out, "Not delivered:\n  %s\n", strings.ReplaceAll(b.Report, "\n", "\n  "))
+			}
+			if b.Sent < b.Total {
+				return fmt.Errorf("broadcast #%d reached %d of %d conversations", id, b.Sent, b.Total)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("broadcast #%d is still queued after %s; the running coco sends it when it can", id, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
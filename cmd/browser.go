@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/kayz/coco/internal/browser"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newBrowserCommand())
+}
+
+func newBrowserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "browser",
+		Short: "Manage the browser used by the browser tools",
+	}
+	profiles := &cobra.Command{
+		Use:   "profiles",
+		Short: "List browser profiles and their size",
+		Long: `List the browser profiles coco keeps logins in.
+
+Each profile is a separate Chrome user-data dir. browser_start uses the
+profile passed to it, else browser.profile from .coco.yaml, else "default".
+Cookies are saved when the browser stops, so logins survive restarts.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			list, err := browser.ListProfiles()
+			if err != nil {
+				return err
+			}
+			printBrowserProfiles(cmd.OutOrStdout(), list)
+			return nil
+		},
+	}
+	clean := &cobra.Command{
+		Use:   "clean [name...]",
+		Short: "Delete the caches of profiles, keeping their logins",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := args
+			if len(names) == 0 {
+				list, err := browser.ListProfiles()
+				if err != nil {
+					return err
+				}
+				for _, p := range list {
+					if !p.InUse {
+						names = append(names, p.Name)
+					}
+				}
+			}
+			for _, name := range names {
+				freed, err := browser.CleanProfile(name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Cleaned %s, freed %s\n", name, formatBytes(uint64(freed)))
+			}
+			return nil
+		},
+	}
+	remove := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Delete a profile with its logins",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := browser.RemoveProfile(args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed browser profile %s\n", args[0])
+			return nil
+		},
+	}
+	profiles.AddCommand(clean, remove)
+	cmd.AddCommand(profiles)
+	return cmd
+}
+
+func printBrowserProfiles(w io.Writer, list []browser.Profile) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No browser profiles yet; browser_start creates them.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tLAST USED\tSTATUS\tDIR")
+	for _, p := range list {
+		status := "idle"
+		if p.InUse {
+			status = "in use"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Name, formatBytes(uint64(p.Size)), p.Modified.Format("2006-01-02 15:04"), status, p.Dir)
+	}
+	tw.Flush()
+}
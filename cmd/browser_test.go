@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/browser"
+)
+
+func TestBrowserProfilesCleanAndRemove(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+
+	if _, err := browser.ProfileDir("../etc"); err == nil {
+		t.Fatal("path-like profile name was accepted")
+	}
+	dir, err := browser.ProfileDir("xiaohongshu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "Default", "Cache")
+	cookies := filepath.Join(dir, "Default", "Cookies")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(cache, "data_0"), make([]byte, 2048), 0644)
+	os.WriteFile(cookies, []byte("login"), 0644)
+
+	var out bytes.Buffer
+	cmd := newBrowserCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"profiles"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "xiaohongshu") || strings.Contains(out.String(), "default") {
+		t.Fatalf("profiles output:\n%s", out.String())
+	}
+
+	out.Reset()
+	cmd.SetArgs([]string{"profiles", "clean"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Cleaned xiaohongshu, freed 2.0 KiB") {
+		t.Fatalf("clean output: %s", out.String())
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Fatal("clean kept the cache")
+	}
+	if _, err := os.Stat(cookies); err != nil {
+		t.Fatal("clean removed the cookies")
+	}
+
+	os.WriteFile(filepath.Join(dir, "SingletonLock"), nil, 0644)
+	cmd.SetArgs([]string{"profiles", "remove", "xiaohongshu"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("removing a locked profile: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "SingletonLock"))
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatal("remove kept the profile")
+	}
+}
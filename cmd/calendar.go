@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/netcal"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newCalendarCommand())
+}
+
+func newCalendarCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "calendar",
+		Short: "Connect the calendar tools to a network calendar",
+		Long: `Connect the calendar tools to CalDAV or Google Calendar.
+
+CalDAV needs no command: set calendar.provider: caldav and the
+calendar.caldav url, username and password in .coco.yaml. For Google,
+create an OAuth client of type "Desktop app" in Google Cloud Console,
+enable the Calendar API and run "coco calendar google-auth".`,
+	}
+
+	var clientID, clientSecret, calendarID string
+	googleAuth := &cobra.Command{
+		Use:   "google-auth",
+		Short: "Authorize Google Calendar and save the refresh token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if clientID == "" {
+				clientID = cfg.Calendar.Google.ClientID
+			}
+			if clientSecret == "" {
+				clientSecret = cfg.Calendar.Google.ClientSecret
+			}
+			if clientID == "" {
+				return fmt.Errorf("--client-id is required")
+			}
+
+			token, err := googleLoopbackAuth(cmd, clientID, clientSecret)
+			if err != nil {
+				return err
+			}
+			if token.RefreshToken == "" {
+				return fmt.Errorf("google returned no refresh token; remove coco's access at myaccount.google.com/permissions and retry")
+			}
+
+			cfg.Calendar.Provider = "google"
+			cfg.Calendar.Google.ClientID = clientID
+			cfg.Calendar.Google.ClientSecret = clientSecret
+			cfg.Calendar.Google.RefreshToken = token.RefreshToken
+			if calendarID != "" {
+				cfg.Calendar.Google.CalendarID = calendarID
+			}
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Google Calendar connected; saved to %s\n", config.ConfigPath())
+			return nil
+		},
+	}
+	googleAuth.Flags().StringVar(&clientID, "client-id", "", "OAuth client ID (Desktop app)")
+	googleAuth.Flags().StringVar(&clientSecret, "client-secret", "", "OAuth client secret")
+	googleAuth.Flags().StringVar(&calendarID, "calendar-id", "", "Calendar to use (default primary)")
+
+	cmd.AddCommand(googleAuth)
+	return cmd
+}
+
+// googleLoopbackAuth runs the OAuth installed-app flow: the user opens the
+// consent URL and Google redirects the code to a one-shot local server.
+func googleLoopbackAuth(cmd *cobra.Command, clientID, clientSecret string) (*netcal.GoogleToken, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	redirect := fmt.Sprintf("http://%s/", ln.Addr())
+	stateBytes := make([]byte, 16)
+	rand.Read(stateBytes)
+	state := hex.EncodeToString(stateBytes)
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			fmt.Fprintln(w, "Authorization failed, you can close this page.")
+			done <- result{err: fmt.Errorf("authorization failed: %s", q.Get("error"))}
+		default:
+			fmt.Fprintln(w, "coco is connected to Google Calendar, you can close this page.")
+			done <- result{code: q.Get("code")}
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	authURL := netcal.GoogleAuthURL + "?" + url.Values{
+		"client_id":     {clientID},
+		"redirect_uri":  {redirect},
+		"response_type": {"code"},
+		"scope":         {netcal.GoogleScope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}.Encode()
+	fmt.Fprintf(cmd.OutOrStdout(), "Open this URL in a browser to authorize coco:\n\n%s\n\nWaiting for authorization...\n", authURL)
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Minute):
+		return nil, fmt.Errorf("timed out waiting for authorization")
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return netcal.ExchangeGoogleToken(ctx, http.DefaultClient, url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {res.code},
+		"redirect_uri":  {redirect},
+		"grant_type":    {"authorization_code"},
+	})
+}
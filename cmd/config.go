@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rootCmd.AddCommand(newConfigCommand())
+}
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read, change and validate .coco.yaml",
+		Long: `Read, change and validate .coco.yaml without editing it by hand.
+
+Keys are dotted paths such as security.allowed_paths or
+platforms.telegram.token; map entries and list items use their key or index,
+e.g. tools.timeouts.web_fetch or remote_storage.0.url.`,
+	}
+
+	get := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config value (defaults included)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			value, err := cfg.Get(args[0])
+			if err != nil {
+				return err
+			}
+			out, err := formatConfigValue(value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), out)
+			return nil
+		},
+	}
+
+	set := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a config value, keeping comments and other settings",
+		Long: `Change a config value, keeping comments and other settings.
+
+The value is checked against the key's type: strings are taken as they are,
+lists of strings may be comma separated, and anything else is read as YAML,
+e.g. true, 8080 or "[a, b]". A running coco picks up the change by itself.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.SetValue(config.ConfigPath(), args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Set %s in %s\n", args[0], config.ConfigPath())
+			return nil
+		},
+	}
+
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Check .coco.yaml for unknown keys and invalid values",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := config.Validate(config.ConfigPath())
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				fmt.Fprintf(cmd.OutOrStdout(), "- %s\n", p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%s has %d problem(s)", config.ConfigPath(), len(problems))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", config.ConfigPath())
+			return nil
+		},
+	}
+
+	backup := &cobra.Command{
+		Use:   "backup",
+		Short: "Snapshot the config and cron jobs into the Obsidian vault",
+		Long: `Copy .coco.yaml, providers.yaml, models.yaml and the cron jobs into a
+folder named after today's date in the Obsidian vault, with API keys, tokens
+and other credentials redacted. Nothing is written when nothing changed since
+the latest snapshot.
+
+A running coco does this by itself every backup.days (default 7) while
+memory.obsidian_vault is set; backup.folder (default coco-backups) is the
+vault folder and backup.disabled turns it off.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			store, jobs, _, err := loadCronJobs("")
+			if err != nil {
+				return err
+			}
+			store.Close()
+			backup, err := agent.SnapshotConfig(cfg.Memory.ObsidianVault, cfg.Backup, jobs, time.Now())
+			if err != nil {
+				return err
+			}
+			if backup.Unchanged {
+				fmt.Fprintf(cmd.OutOrStdout(), "Nothing changed since %s\n", backup.Dir)
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s\n", strings.Join(backup.Files, ", "), backup.Dir)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(get, set, validate, backup)
+	return cmd
+}
+
+// formatConfigValue prints scalars bare and everything else as YAML.
+func formatConfigValue(value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Pointer:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\n"), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/provenance"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newCronCommand())
+}
+
+func newCronCommand() *cobra.Command {
+	var dbPath string
+	cmd := &cobra.Command{
+		Use:   "cron",
+		Short: "Inspect and prune scheduled jobs",
+		Long: `Inspect scheduled jobs and where they came from.
+
+Every job records its provenance — the chat message, HEARTBEAT.md task,
+external agent or API call that created it — signed with the key in
+.coco/signing.key. A job whose signature no longer matches was edited
+outside coco.`,
+	}
+	cmd.PersistentFlags().StringVar(&dbPath, "db", "", "Job database (default: .coco.db next to the executable; use .coco-keeper.db for keeper)")
+
+	var verbose bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List scheduled jobs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, jobs, signer, err := loadCronJobs(dbPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			if len(jobs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No scheduled jobs.")
+				return nil
+			}
+
+			out := cmd.OutOrStdout()
+			if !verbose {
+				w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tNAME\tSCHEDULE\tSTATUS\tORIGIN\tSIGNATURE")
+				for _, job := range jobs {
+					origin := "-"
+					if job.Provenance != nil {
+						origin = job.Provenance.Origin
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", shortJobID(job.ID), job.Name, cronJobSchedule(job),
+						cronJobStatus(job), origin, cronpkg.VerifyJob(signer, job))
+				}
+				return w.Flush()
+			}
+
+			for _, job := range jobs {
+				fmt.Fprintf(out, "%s  %s\n", job.ID, job.Name)
+				fmt.Fprintf(out, "  Type:      %s (%s)\n", job.Type, cronJobStatus(job))
+				fmt.Fprintf(out, "  Schedule:  %s\n", cronJobSchedule(job))
+				if job.Tag != "" {
+					fmt.Fprintf(out, "  Tag:       %s\n", job.Tag)
+				}
+				if job.Platform != "" {
+					fmt.Fprintf(out, "  Target:    %s:%s:%s\n", job.Platform, job.ChannelID, job.UserID)
+				}
+				fmt.Fprintf(out, "  Created:   %s\n", job.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+				if p := job.Provenance; p != nil {
+					fmt.Fprintf(out, "  Origin:    %s\n", p.Origin)
+					if p.Actor != "" {
+						fmt.Fprintf(out, "  Actor:     %s\n", p.Actor)
+					}
+					if p.Conversation != "" {
+						fmt.Fprintf(out, "  Chat:      %s\n", p.Conversation)
+					}
+					if p.MessageID != "" {
+						fmt.Fprintf(out, "  Message:   %s\n", p.MessageID)
+					}
+					if p.Reason != "" {
+						fmt.Fprintf(out, "  Reason:    %s\n", p.Reason)
+					}
+				} else {
+					fmt.Fprintln(out, "  Origin:    unknown (created before provenance tracking)")
+				}
+				fmt.Fprintf(out, "  Signature: %s\n", cronpkg.VerifyJob(signer, job))
+				if job.LastRun != nil {
+					fmt.Fprintf(out, "  Last run:  %s\n", job.LastRun.Local().Format("2006-01-02 15:04:05"))
+				}
+				if job.LastError != "" {
+					fmt.Fprintf(out, "  Error:     %s\n", job.LastError)
+				}
+				if job.FailCount > 0 {
+					fmt.Fprintf(out, "  Failures:  %d in a row\n", job.FailCount)
+				}
+				if job.StaleSince != nil {
+					fmt.Fprintf(out, "  Stale:     since %s, owner warned\n", job.StaleSince.Local().Format("2006-01-02 15:04:05"))
+				}
+				fmt.Fprintln(out)
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show who or what created each job and why")
+
+	var (
+		dryRun          bool
+		includeUnsigned bool
+		origins         []string
+	)
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove orphaned jobs",
+		Long: `Remove jobs that should no longer exist:
+  - jobs whose provenance signature does not match (edited outside coco)
+  - one-shot reminders more than a day overdue
+  - with --include-unsigned, jobs created before provenance tracking
+  - with --origin, every job from the given origins (e.g. external-agent)
+
+Stop "coco relay" (or keeper) first; a running scheduler keeps its jobs in memory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, jobs, signer, err := loadCronJobs(dbPath)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			now := time.Now()
+			removed := 0
+			for _, job := range jobs {
+				reason := cronPruneReason(job, signer, now, includeUnsigned, origins)
+				if reason == "" {
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s  %s  (%s)\n", shortJobID(job.ID), job.Name, reason)
+				if dryRun {
+					continue
+				}
+				if err := store.DeleteJob(job.ID); err != nil {
+					return fmt.Errorf("delete %s: %w", job.ID, err)
+				}
+				removed++
+			}
+			if dryRun {
+				fmt.Fprintln(cmd.OutOrStdout(), "Dry run, nothing removed.")
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %d job(s).\n", removed)
+			}
+			return nil
+		},
+	}
+	pruneCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show what would be removed")
+	pruneCmd.Flags().BoolVar(&includeUnsigned, "include-unsigned", false, "Also remove jobs without provenance")
+	pruneCmd.Flags().StringSliceVar(&origins, "origin", nil, "Also remove jobs from these origins (user, heartbeat, cron, external-agent, keeper-api, mcp, system)")
+
+	cmd.AddCommand(listCmd, pruneCmd)
+	return cmd
+}
+
+// cronGCConfig turns the cron section of the config into scheduler GC settings.
+// It reports false when stale-job GC is switched off.
+func cronGCConfig(cc config.CronConfig, targets cronpkg.TargetChecker) (cronpkg.GCConfig, bool) {
+	if cc.StaleGC != nil && !*cc.StaleGC {
+		return cronpkg.GCConfig{}, false
+	}
+	gc := cronpkg.DefaultGCConfig()
+	gc.Targets = targets
+	if cc.MaxFailures > 0 {
+		gc.MaxFailures = cc.MaxFailures
+	}
+	if cc.GracePeriod != "" {
+		if d, err := time.ParseDuration(cc.GracePeriod); err == nil && d > 0 {
+			gc.GracePeriod = d
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: invalid cron.grace_period %q, using %s\n", cc.GracePeriod, gc.GracePeriod)
+		}
+	}
+	if parts := strings.SplitN(cc.NotifyTo, ":", 3); len(parts) == 3 {
+		gc.NotifyPlatform, gc.NotifyChannelID, gc.NotifyUserID = parts[0], parts[1], parts[2]
+	} else if cc.NotifyTo != "" {
+		fmt.Fprintf(os.Stderr, "Warning: cron.notify_to must be platform:channel_id:user_id, got %q\n", cc.NotifyTo)
+	}
+	return gc, true
+}
+
+func resolveCronDBPath(dbPath string) string {
+	if strings.TrimSpace(dbPath) != "" {
+		return dbPath
+	}
+	return datadir.Path(".coco.db")
+}
+
+// loadCronJobs opens the job store and reads jobs sorted by creation time,
+// together with the local signing key.
+func loadCronJobs(dbPath string) (*cronpkg.Store, []*cronpkg.Job, *provenance.Signer, error) {
+	store, err := cronpkg.NewStore(resolveCronDBPath(dbPath))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	jobs, err := store.Load()
+	if err != nil {
+		store.Close()
+		return nil, nil, nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	// Without a key every signed job reads as "foreign", which is still useful.
+	signer, err := provenance.LoadOrCreate(provenance.DefaultKeyPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		signer = nil
+	}
+	return store, jobs, signer, nil
+}
+
+func cronPruneReason(job *cronpkg.Job, signer *provenance.Signer, now time.Time, includeUnsigned bool, origins []string) string {
+	switch cronpkg.VerifyJob(signer, job) {
+	case provenance.StatusInvalid:
+		return "signature mismatch"
+	case provenance.StatusUnsigned:
+		if includeUnsigned {
+			return "unsigned"
+		}
+	}
+	// Missed reminders still fire on the next start; only drop ones long overdue.
+	if job.IsOneShot() && job.RunAt.Before(now.Add(-24*time.Hour)) {
+		return "stale reminder"
+	}
+	if job.Provenance != nil {
+		for _, o := range origins {
+			if strings.EqualFold(strings.TrimSpace(o), job.Provenance.Origin) {
+				return "origin " + job.Provenance.Origin
+			}
+		}
+	}
+	return ""
+}
+
+func cronJobSchedule(job *cronpkg.Job) string {
+	if job.RunAt != nil {
+		return "once at " + job.RunAt.Local().Format("2006-01-02 15:04")
+	}
+	return job.Schedule
+}
+
+func cronJobStatus(job *cronpkg.Job) string {
+	if job.StaleSince != nil {
+		return "stale"
+	}
+	if job.Enabled {
+		return "enabled"
+	}
+	return "paused"
+}
+
+func shortJobID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	agentpkg "github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/diag"
+	"github.com/kayz/coco/internal/mcp"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/spf13/cobra"
+)
+
+// defaultLogFiles are checked for recent logs in addition to logging.file.
+// The service manager writes to /tmp/coco.log on macOS and Linux.
+var defaultLogFiles = []string{filepath.Join(os.TempDir(), "coco.log"), "/tmp/coco.log"}
+
+func init() {
+	rootCmd.AddCommand(newDiagCommand())
+}
+
+func newDiagCommand() *cobra.Command {
+	var (
+		output         string
+		logFiles       []string
+		logLines       int
+		checkProviders bool
+	)
+	cmd := &cobra.Command{
+		Use:   "diag",
+		Short: "Collect a redacted diagnostic bundle for bug reports",
+		Long: `Collect everything usually asked for in a bug report into one zip:
+versions, redacted config, recent logs, provider health, tool stats,
+scheduled jobs and the latest watchdog bundles.
+
+API keys, tokens, passwords and vault secrets are replaced with [REDACTED]
+in every file. Review the archive before sharing it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				output = "coco-diag-" + time.Now().Format("20060102-150405") + ".zip"
+			}
+			bundle, err := diag.Create(output)
+			if err != nil {
+				return err
+			}
+
+			// Collect secrets first so every file added later is scrubbed.
+			bundle.AddSecrets(vaultSecretValues()...)
+			configYAML := redactedFile(bundle, config.ConfigPath())
+			providersYAML := redactedFile(bundle, ai.ProvidersPath())
+			modelsYAML := redactedFile(bundle, ai.ModelsPath())
+
+			var notes []string
+			add := func(name string, data []byte) {
+				if err := bundle.AddFile(name, data); err != nil {
+					notes = append(notes, fmt.Sprintf("%s: %v", name, err))
+				}
+			}
+			add("config/coco.yaml", configYAML)
+			add("config/providers.yaml", providersYAML)
+			add("config/models.yaml", modelsYAML)
+			add("providers.txt", []byte(diagProviderHealth(checkProviders)))
+			add("tool_stats.txt", []byte(diagToolStats()))
+			add("cron_jobs.txt", []byte(diagCronJobs()))
+
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.DefaultConfig()
+			}
+			for _, path := range diagLogCandidates(cfg.Logging.File, logFiles) {
+				data, err := diag.Tail(path, logLines)
+				if err != nil {
+					if !os.IsNotExist(err) {
+						notes = append(notes, fmt.Sprintf("log %s: %v", path, err))
+					}
+					continue
+				}
+				add("logs/"+filepath.Base(path), data)
+			}
+			for _, name := range addWatchdogBundles(bundle, 3) {
+				notes = append(notes, "included watchdog bundle "+name)
+			}
+
+			add("summary.txt", []byte(diagSummary(notes)))
+			if err := bundle.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s (%d files):\n", output, len(bundle.Files()))
+			for _, name := range bundle.Files() {
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", name)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Secrets are redacted; please review before attaching it to an issue.")
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Archive path (default coco-diag-<time>.zip)")
+	cmd.Flags().StringSliceVar(&logFiles, "log-file", nil, "Extra log files to include")
+	cmd.Flags().IntVar(&logLines, "log-lines", 2000, "Lines kept from the end of each log")
+	cmd.Flags().BoolVar(&checkProviders, "check-providers", false, "Send a short ping to every model (uses API quota)")
+	return cmd
+}
+
+// redactedFile reads a YAML file, redacts it and registers the removed values
+// as secrets. Missing or unparsable files yield a short note instead.
+func redactedFile(bundle *diag.Bundle, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []byte(fmt.Sprintf("# %s: %v\n", path, err))
+	}
+	redacted, removed, err := diag.RedactYAML(data)
+	if err != nil {
+		// Never ship a file we could not redact.
+		return []byte(fmt.Sprintf("# %s: not included, failed to parse: %v\n", path, err))
+	}
+	bundle.AddSecrets(removed...)
+	return append([]byte(fmt.Sprintf("# %s\n", path)), redacted...)
+}
+
+func vaultSecretValues() []string {
+	vaultPath, _ := agentpkg.SecretVaultPaths()
+	if _, err := os.Stat(vaultPath); err != nil {
+		return nil
+	}
+	vault, err := agentpkg.OpenSecretVault()
+	if err != nil {
+		return nil
+	}
+	values, err := vault.Values()
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+	}
+	return out
+}
+
+func diagSummary(notes []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "coco diagnostic bundle\n")
+	fmt.Fprintf(&sb, "created:   %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "version:   %s (build %s)\n", mcp.ServerVersion, build)
+	fmt.Fprintf(&sb, "go:        %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if exe, err := os.Executable(); err == nil {
+		fmt.Fprintf(&sb, "exe:       %s\n", exe)
+	}
+	fmt.Fprintf(&sb, "data dir:  %s\n", datadir.Dir())
+	fmt.Fprintf(&sb, "config:    %s\n", config.ConfigPath())
+	if len(notes) > 0 {
+		sb.WriteString("\nnotes:\n")
+		for _, n := range notes {
+			sb.WriteString("  " + n + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func diagProviderHealth(online bool) string {
+	reg, err := ai.LoadRegistry()
+	if err != nil {
+		return fmt.Sprintf("registry: %v\n", err)
+	}
+	var sb strings.Builder
+	now := time.Now()
+	for _, m := range reg.ListModels() {
+		status := "ok"
+		provider, ok := reg.GetProvider(m.Provider)
+		switch {
+		case !ok:
+			status = "provider not found"
+		case len(provider.Keys()) == 0:
+			status = "no api key"
+		case !m.IsEnabled():
+			status = "disabled: " + strings.TrimSpace(m.DisabledReason)
+		case m.IsTemporarilyDisabled(now):
+			status = "off-shelf until " + strings.TrimSpace(m.DisabledUntil)
+		}
+		fmt.Fprintf(&sb, "%-24s provider=%s roles=%s  %s\n", m.Name, m.Provider,
+			strings.Join(defaultIfEmptySlice(m.Roles, "none"), ","), status)
+	}
+	if online {
+		sb.WriteString("\nOnline check:\n")
+		for _, m := range reg.ListModels() {
+			r := benchOneModel(reg, m)
+			fmt.Fprintf(&sb, "%-24s %s (%s) %s\n", m.Name, r.Status, r.Latency.Round(time.Millisecond), r.Detail)
+		}
+	} else {
+		sb.WriteString("\n(Run with --check-providers to ping each model.)\n")
+	}
+	return sb.String()
+}
+
+func diagToolStats() string {
+	store, err := persist.NewStore(filepath.Join(filepath.Dir(config.ConfigPath()), ".coco.db"))
+	if err != nil {
+		return fmt.Sprintf("store: %v\n", err)
+	}
+	defer store.Close()
+
+	var sb strings.Builder
+	for i := 0; i < 7; i++ {
+		date := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
+		stats, err := store.SlowestTools(date, 20)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n", date, err)
+			continue
+		}
+		if len(stats) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\n", date)
+		for _, st := range stats {
+			fmt.Fprintf(&sb, "  %-24s calls=%d errors=%d avg=%s max=%s avg_result=%dB\n", st.ToolName, st.Calls, st.Errors,
+				st.AvgDuration.Round(time.Millisecond), st.MaxDuration.Round(time.Millisecond), st.AvgResultBytes)
+		}
+	}
+	if sb.Len() == 0 {
+		return "No tool metrics in the last 7 days.\n"
+	}
+	return sb.String()
+}
+
+func diagCronJobs() string {
+	store, jobs, signer, err := loadCronJobs("")
+	if err != nil {
+		return fmt.Sprintf("cron store: %v\n", err)
+	}
+	defer store.Close()
+	if len(jobs) == 0 {
+		return "No scheduled jobs.\n"
+	}
+	var sb strings.Builder
+	for _, job := range jobs {
+		origin := "-"
+		if job.Provenance != nil {
+			origin = job.Provenance.Origin
+		}
+		fmt.Fprintf(&sb, "%s  %-24s %-20s %-8s origin=%s signature=%s failures=%d", shortJobID(job.ID), job.Name,
+			cronJobSchedule(job), cronJobStatus(job), origin, cronpkg.VerifyJob(signer, job), job.FailCount)
+		if job.LastError != "" {
+			fmt.Fprintf(&sb, " last_error=%q", job.LastError)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func diagLogCandidates(configured string, extra []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, path := range append(append([]string{configured}, extra...), defaultLogFiles...) {
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if !seen[path] {
+			seen[path] = true
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// addWatchdogBundles copies the newest watchdog bundles into the archive.
+func addWatchdogBundles(bundle *diag.Bundle, limit int) []string {
+	dir := filepath.Join(config.ConfigDir(), "diagnostics")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	for _, name := range names {
+		files, _ := os.ReadDir(filepath.Join(dir, name))
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, name, f.Name()))
+			if err == nil {
+				bundle.AddFile("watchdog/"+name+"/"+f.Name(), data)
+			}
+		}
+	}
+	return names
+}
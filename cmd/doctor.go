@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	agentpkg "github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/browser"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorOffline bool
+	doctorTimeout int
+)
+
+const (
+	diskWarnBytes = 1 << 30
+	diskFailBytes = 100 << 20
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check config, providers, database, tools and disk space",
+	Long: `Run the health checks that onboarding runs, at any time:
+config validity, provider API reachability, model registry consistency,
+database health, ffmpeg, browser, platform credentials and free disk space
+for the workspace.
+
+Exits with an error when any check fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results := runDoctorChecks(doctorOffline, time.Duration(doctorTimeout)*time.Second)
+		if failed := printDoctorTable(cmd.OutOrStdout(), results); failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Skip provider reachability checks")
+	doctorCmd.Flags().IntVar(&doctorTimeout, "timeout", 10, "Per-provider request timeout in seconds")
+}
+
+func runDoctorChecks(offline bool, timeout time.Duration) []toolSmokeResult {
+	var results []toolSmokeResult
+
+	cfg, cfgResult := doctorConfig(config.ConfigPath())
+	results = append(results, cfgResult)
+
+	reg, err := ai.LoadRegistry()
+	if err != nil {
+		results = append(results, toolSmokeResult{Name: "registry", Status: "FAIL", Details: err.Error()})
+	} else {
+		results = append(results, doctorRegistry(reg))
+		if offline {
+			results = append(results, toolSmokeResult{Name: "providers", Status: "WARN", Details: "skipped (--offline)"})
+		} else {
+			results = append(results, doctorProviders(reg, timeout)...)
+		}
+	}
+
+	results = append(results, doctorDatabase(filepath.Join(filepath.Dir(config.ConfigPath()), ".coco.db")))
+	results = append(results, doctorFFmpeg(), doctorBrowser())
+	results = append(results, doctorPlatforms(cfg)...)
+	results = append(results, doctorDisk(doctorWorkspaceDir()))
+	return results
+}
+
+// printDoctorTable writes the results as a table followed by a summary line
+// and returns the number of failed checks.
+func printDoctorTable(w io.Writer, results []toolSmokeResult) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, r.Status, r.Details)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\npass=%d warn=%d fail=%d\n", counts["PASS"], counts["WARN"], counts["FAIL"])
+	return counts["FAIL"]
+}
+
+func doctorConfig(path string) (*config.Config, toolSmokeResult) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return config.DefaultConfig(), toolSmokeResult{Name: "config", Status: "WARN", Details: path + " not found, using defaults"}
+	}
+	cfg, err := config.LoadFromPath(path)
+	if err != nil {
+		return config.DefaultConfig(), toolSmokeResult{Name: "config", Status: "FAIL", Details: err.Error()}
+	}
+	if problems, err := config.Validate(path); err == nil && len(problems) > 0 {
+		return cfg, toolSmokeResult{Name: "config", Status: "FAIL", Details: strings.Join(problems, "; ")}
+	}
+	return cfg, toolSmokeResult{Name: "config", Status: "PASS", Details: path}
+}
+
+func doctorRegistry(reg *ai.Registry) toolSmokeResult {
+	models := reg.ListModels()
+	if len(models) == 0 {
+		return toolSmokeResult{Name: "registry", Status: "FAIL", Details: "no models in " + ai.ModelsPath()}
+	}
+	problems := reg.Validate()
+	switch {
+	case len(problems) == 0:
+		return toolSmokeResult{Name: "registry", Status: "PASS", Details: fmt.Sprintf("%d models", len(models))}
+	case problems[len(problems)-1] == "no enabled model has a configured provider":
+		return toolSmokeResult{Name: "registry", Status: "FAIL", Details: strings.Join(problems, "; ")}
+	default:
+		return toolSmokeResult{Name: "registry", Status: "WARN", Details: strings.Join(problems, "; ")}
+	}
+}
+
+// doctorProviders lists the models endpoint of every provider that a model
+// uses. Listing models costs no tokens, unlike the ping of models bench.
+func doctorProviders(reg *ai.Registry, timeout time.Duration) []toolSmokeResult {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range reg.ListModels() {
+		if !seen[m.Provider] {
+			seen[m.Provider] = true
+			names = append(names, m.Provider)
+		}
+	}
+	sort.Strings(names)
+
+	client := &http.Client{Timeout: timeout}
+	var results []toolSmokeResult
+	for _, name := range names {
+		provider, ok := reg.GetProvider(name)
+		if !ok || len(provider.Keys()) == 0 {
+			continue // already reported by the registry check
+		}
+		results = append(results, checkProvider(client, provider))
+	}
+	return results
+}
+
+func checkProvider(client *http.Client, p *ai.ProviderConfig) toolSmokeResult {
+	result := toolSmokeResult{Name: "provider " + p.Name, Status: "FAIL"}
+	req, err := providerModelsRequest(p)
+	if err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		result.Status = "PASS"
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		result.Details = fmt.Sprintf("%s (check api key), %s", resp.Status, latency)
+		return result
+	default:
+		result.Status = "WARN"
+	}
+	result.Details = fmt.Sprintf("%s, %s", resp.Status, latency)
+	return result
+}
+
+func providerModelsRequest(p *ai.ProviderConfig) (*http.Request, error) {
+	key := p.Keys()[0]
+	base := strings.TrimRight(strings.TrimSpace(p.BaseURL), "/")
+	switch strings.ToLower(strings.TrimSpace(p.Type)) {
+	case "claude", "anthropic":
+		if base == "" {
+			base = "https://api.anthropic.com/v1"
+		}
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, base+"/models", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-api-key", key)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		return req, nil
+	case "deepseek":
+		if base == "" {
+			base = "https://api.deepseek.com/v1"
+		}
+	case "qwen", "qianwen", "tongyi":
+		if base == "" {
+			base = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+		}
+	case "kimi", "moonshot":
+		if base == "" {
+			base = "https://api.moonshot.cn/v1"
+		}
+	default:
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, base+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	return req, nil
+}
+
+func doctorDatabase(path string) toolSmokeResult {
+	result := toolSmokeResult{Name: "database", Status: "FAIL"}
+	store, err := persist.NewStore(path)
+	if err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	defer store.Close()
+	if err := store.IntegrityCheck(); err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	version, err := store.SchemaVersion()
+	if err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	if version != persist.SchemaVersion {
+		result.Status = "WARN"
+		result.Details = fmt.Sprintf("schema v%d, this build expects v%d", version, persist.SchemaVersion)
+		return result
+	}
+	result.Status = "PASS"
+	result.Details = fmt.Sprintf("%s (schema v%d)", path, version)
+	return result
+}
+
+func doctorFFmpeg() toolSmokeResult {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return toolSmokeResult{Name: "ffmpeg", Status: "WARN", Details: "not found; AMR voice messages cannot be converted"}
+	}
+	return toolSmokeResult{Name: "ffmpeg", Status: "PASS", Details: path}
+}
+
+func doctorBrowser() toolSmokeResult {
+	if path := browser.ExecutablePath(); path != "" {
+		return toolSmokeResult{Name: "browser", Status: "PASS", Details: path}
+	}
+	return toolSmokeResult{Name: "browser", Status: "WARN", Details: "no Chrome found; browser_start will download Chromium"}
+}
+
+type credentialField struct {
+	key   string
+	value string
+}
+
+// platformCredentials lists the fields each platform needs to connect.
+func platformCredentials(p config.PlatformConfig) map[string][]credentialField {
+	return map[string][]credentialField{
+		"wecom":      {{"corp_id", p.WeCom.CorpID}, {"agent_id", p.WeCom.AgentID}, {"secret", p.WeCom.Secret}},
+		"slack":      {{"bot_token", p.Slack.BotToken}, {"app_token", p.Slack.AppToken}},
+		"telegram":   {{"token", p.Telegram.Token}},
+		"discord":    {{"token", p.Discord.Token}},
+		"wechat":     {{"app_id", p.WeChat.AppID}, {"app_secret", p.WeChat.AppSecret}},
+		"feishu":     {{"app_id", p.Feishu.AppID}, {"app_secret", p.Feishu.AppSecret}},
+		"dingtalk":   {{"client_id", p.DingTalk.ClientID}, {"client_secret", p.DingTalk.ClientSecret}},
+		"whatsapp":   {{"phone_number_id", p.WhatsApp.PhoneNumberID}, {"access_token", p.WhatsApp.AccessToken}},
+		"line":       {{"channel_secret", p.LINE.ChannelSecret}, {"channel_token", p.LINE.ChannelToken}},
+		"teams":      {{"app_id", p.Teams.AppID}, {"app_password", p.Teams.AppPassword}},
+		"matrix":     {{"homeserver_url", p.Matrix.HomeserverURL}, {"user_id", p.Matrix.UserID}, {"access_token", p.Matrix.AccessToken}},
+		"googlechat": {{"project_id", p.GoogleChat.ProjectID}, {"credentials_file", p.GoogleChat.CredentialsFile}},
+		"mattermost": {{"server_url", p.Mattermost.ServerURL}, {"token", p.Mattermost.Token}},
+		"imessage":   {{"bluebubbles_url", p.IMessage.BlueBubblesURL}, {"bluebubbles_password", p.IMessage.BlueBubblesPassword}},
+		"signal":     {{"api_url", p.Signal.APIURL}, {"phone_number", p.Signal.PhoneNumber}},
+		"twitch":     {{"token", p.Twitch.Token}, {"channel", p.Twitch.Channel}},
+		"nostr":      {{"private_key", p.NOSTR.PrivateKey}},
+		"zalo":       {{"app_id", p.Zalo.AppID}, {"access_token", p.Zalo.AccessToken}},
+		"nextcloud":  {{"server_url", p.Nextcloud.ServerURL}, {"username", p.Nextcloud.Username}, {"password", p.Nextcloud.Password}},
+		"email":      {{"imap_server", p.Email.IMAPServer}, {"smtp_server", p.Email.SMTPServer}, {"username", p.Email.Username}, {"password", p.Email.Password}},
+	}
+}
+
+// doctorPlatforms reports every platform with at least one credential set.
+// A platform missing some of its credentials fails to connect, so it FAILs.
+func doctorPlatforms(cfg *config.Config) []toolSmokeResult {
+	creds := platformCredentials(cfg.Platforms)
+	names := make([]string, 0, len(creds))
+	for name := range creds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []toolSmokeResult
+	for _, name := range names {
+		var set, missing []string
+		for _, f := range creds[name] {
+			if strings.TrimSpace(f.value) == "" {
+				missing = append(missing, f.key)
+			} else {
+				set = append(set, f.key)
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+		r := toolSmokeResult{Name: "platform " + name, Status: "PASS", Details: "credentials set"}
+		if len(missing) > 0 {
+			r.Status = "FAIL"
+			r.Details = "missing " + strings.Join(missing, ", ")
+		}
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		if strings.TrimSpace(cfg.Relay.ServerURL) != "" {
+			return []toolSmokeResult{{Name: "platforms", Status: "PASS", Details: "none configured locally; messages come through relay " + cfg.Relay.ServerURL}}
+		}
+		return []toolSmokeResult{{Name: "platforms", Status: "WARN", Details: "no platform credentials and no relay.server_url"}}
+	}
+	return results
+}
+
+func doctorWorkspaceDir() string {
+	dir := agentpkg.WorkspaceDir()
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir
+}
+
+func doctorDisk(dir string) toolSmokeResult {
+	result := toolSmokeResult{Name: "disk", Status: "FAIL"}
+	// The workspace may not exist yet; measure the nearest existing parent.
+	path := dir
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	free, err := diskFree(path)
+	if err != nil {
+		result.Details = err.Error()
+		return result
+	}
+	result.Details = fmt.Sprintf("%s free at %s", formatBytes(free), dir)
+	switch {
+	case free < diskFailBytes:
+	case free < diskWarnBytes:
+		result.Status = "WARN"
+	default:
+		result.Status = "PASS"
+	}
+	return result
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cmd
+
+import "golang.org/x/sys/unix"
+
+// diskFree returns the bytes available to unprivileged users at path.
+func diskFree(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bsize) * uint64(st.Bavail), nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestDoctorPlatforms(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Relay.ServerURL = ""
+	if r := doctorPlatforms(cfg); len(r) != 1 || r[0].Status != "WARN" {
+		t.Fatalf("nothing configured = %+v", r)
+	}
+	cfg.Relay.ServerURL = "wss://relay.example.com"
+	if r := doctorPlatforms(cfg); len(r) != 1 || r[0].Status != "PASS" {
+		t.Fatalf("relay only = %+v", r)
+	}
+
+	cfg.Platforms.Telegram.Token = "123:abc"
+	cfg.Platforms.Slack.BotToken = "xoxb-1"
+	r := doctorPlatforms(cfg)
+	if len(r) != 2 {
+		t.Fatalf("results = %+v", r)
+	}
+	if r[0].Name != "platform slack" || r[0].Status != "FAIL" || r[0].Details != "missing app_token" {
+		t.Fatalf("slack = %+v", r[0])
+	}
+	if r[1].Name != "platform telegram" || r[1].Status != "PASS" {
+		t.Fatalf("telegram = %+v", r[1])
+	}
+}
+
+func TestDoctorDatabaseAndDisk(t *testing.T) {
+	dir := t.TempDir()
+	if r := doctorDatabase(filepath.Join(dir, ".coco.db")); r.Status != "PASS" {
+		t.Fatalf("database = %+v", r)
+	}
+	if r := doctorDisk(filepath.Join(dir, "not", "created")); !strings.Contains(r.Details, "free") {
+		t.Fatalf("disk = %+v", r)
+	}
+}
+
+func TestPrintDoctorTable(t *testing.T) {
+	var out bytes.Buffer
+	failed := printDoctorTable(&out, []toolSmokeResult{
+		{Name: "config", Status: "PASS", Details: "coco.yaml"},
+		{Name: "ffmpeg", Status: "WARN", Details: "not found"},
+		{Name: "platform slack", Status: "FAIL", Details: "missing app_token"},
+	})
+	if failed != 1 {
+		t.Fatalf("failed = %d", failed)
+	}
+	got := out.String()
+	if !strings.Contains(got, "platform slack  FAIL    missing app_token") || !strings.HasSuffix(got, "pass=1 warn=1 fail=1\n") {
+		t.Fatalf("table:\n%s", got)
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/platforms/feishu"
+	"github.com/kayz/coco/internal/router"
+)
+
+// newFeishuDirect connects to Feishu with the user's own app instead of Keeper.
+func newFeishuDirect() (router.Platform, error) {
+	var fc config.FeishuConfig
+	if cfg, err := config.Load(); err == nil {
+		fc = cfg.Platforms.Feishu
+	}
+	fc.AppID = firstNonEmpty(os.Getenv("FEISHU_APP_ID"), fc.AppID)
+	fc.AppSecret = firstNonEmpty(os.Getenv("FEISHU_APP_SECRET"), fc.AppSecret)
+	fc.VerificationToken = firstNonEmpty(os.Getenv("FEISHU_VERIFICATION_TOKEN"), fc.VerificationToken)
+	fc.EncryptKey = firstNonEmpty(os.Getenv("FEISHU_ENCRYPT_KEY"), fc.EncryptKey)
+	if fc.AppID == "" || fc.AppSecret == "" {
+		return nil, fmt.Errorf("platforms.feishu.app_id and app_secret are required (or FEISHU_APP_ID / FEISHU_APP_SECRET)")
+	}
+	return feishu.New(feishu.Config{
+		AppID:             fc.AppID,
+		AppSecret:         fc.AppSecret,
+		CallbackListen:    fc.CallbackListen,
+		VerificationToken: fc.VerificationToken,
+		EncryptKey:        fc.EncryptKey,
+	})
+}
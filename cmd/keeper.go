@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -10,8 +13,10 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -19,9 +24,12 @@ import (
 	agentpkg "github.com/kayz/coco/internal/agent"
 	"github.com/kayz/coco/internal/config"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/instance"
 	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/offlinequeue"
 	"github.com/kayz/coco/internal/platforms/relay"
 	"github.com/kayz/coco/internal/platforms/wecom"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/router"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -41,6 +49,8 @@ Keeper handles:
   - WeCom (企业微信) webhook callbacks
   - WebSocket endpoint for coco client connections
   - Offline fallback replies when coco is not connected
+  - A status dashboard at /dashboard (keeper.token required, sent as
+    ?token=<token>; without a token only from localhost)
 
 Usage:
   coco keeper --port 8080`,
@@ -53,13 +63,39 @@ func init() {
 	keeperCmd.Flags().StringVar(&keeperServiceAction, "service", "", serviceActionHelp)
 }
 
-// cocoClient represents a connected coco instance.
+// cocoClient is a coco session. It outlives a dropped connection for
+// keeperResumeGrace so the client can resume it; conn is nil meanwhile.
 type cocoClient struct {
-	conn      *websocket.Conn
-	userID    string
-	platform  string
-	sessionID string
-	mu        sync.Mutex
+	userID      string
+	platform    string
+	sessionID   string
+	connectedAt time.Time
+	lastSeen    atomic.Int64 // unix nanoseconds of the last frame received
+
+	mu         sync.Mutex // guards the fields below and writes to conn
+	conn       *websocket.Conn
+	remoteAddr string
+	seq        int64
+	outbox     []outboxEntry
+	expiry     *time.Timer
+	closed     bool
+}
+
+func (c *cocoClient) view() keeperClientView {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := "online"
+	if c.conn == nil {
+		state = "resuming"
+	}
+	return keeperClientView{
+		UserID:      c.userID,
+		Platform:    c.platform,
+		RemoteAddr:  c.remoteAddr,
+		State:       state,
+		ConnectedAt: c.connectedAt,
+		LastSeen:    time.Unix(0, c.lastSeen.Load()),
+	}
 }
 
 // keeperServer holds all Keeper state.
@@ -68,14 +104,23 @@ type keeperServer struct {
 	msgCrypt *wecom.MsgCrypt
 	wecom    *wecom.Platform
 	upgrader websocket.Upgrader
-	client   *cocoClient // single coco connection (Phase 0)
+	clients  map[string]*cocoClient // connected coco instances by clientKey
 	clientMu sync.RWMutex
+	users    map[string]time.Time // WeCom users seen, for broadcasts
+	usersMu  sync.Mutex
 	ctx      context.Context
 	cancel   context.CancelFunc
 
 	heartbeatScheduler *cronpkg.Scheduler
 	heartbeatExecutor  *keeperPromptExecutor
+	heartbeatMu        sync.Mutex // serializes heartbeat job syncs
 	fallbackExecutor   *keeperPromptExecutor
+	activity           *keeperActivity
+	queue              *offlinequeue.Queue // nil when the offline queue is disabled
+	uploads            *keeperUploads
+	shares             *keeperShares
+	market             *keeperMarket
+	spam               *keeperSpamFilter // nil when keeper.spam.disabled
 }
 
 func newKeeperServer(cfg *config.Config) (*keeperServer, error) {
@@ -113,8 +158,14 @@ func newKeeperServer(cfg *config.Config) (*keeperServer, error) {
 		msgCrypt: msgCrypt,
 		wecom:    wp,
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin:       func(r *http.Request) bool { return true },
+			EnableCompression: true,
 		},
+		activity: newKeeperActivity(),
+		uploads:  newKeeperUploads(filepath.Join(os.TempDir(), "coco-keeper-uploads")),
+		shares:   newKeeperShares(filepath.Join(keeperWorkspaceDir(), ".coco", "shared")),
+		market:   newKeeperMarket(filepath.Join(keeperWorkspaceDir(), ".coco", "market")),
+		spam:     newKeeperSpamFilter(kc.Spam),
 	}
 	return s, nil
 }
@@ -281,9 +332,18 @@ func (n *keeperCronNotifier) NotifyChatUser(platform, channelID, userID, message
 		logger.Warn("[KeeperCron] unsupported platform %s, skip notify", platform)
 		return nil
 	}
+	n.server.activity.message("out", userID, message, "cron")
 	return n.server.sendWeComReply(userID, message)
 }
 
+// TargetAvailable reports whether keeper can still deliver to the job's platform.
+func (n *keeperCronNotifier) TargetAvailable(platform, channelID, userID string) error {
+	if !strings.EqualFold(strings.TrimSpace(platform), "wecom") {
+		return fmt.Errorf("keeper cannot deliver to platform %s", platform)
+	}
+	return nil
+}
+
 func (s *keeperServer) initHeartbeatScheduler() {
 	workspaceDir := keeperWorkspaceDir()
 	dbPath := filepath.Join(workspaceDir, ".coco-keeper.db")
@@ -302,17 +362,26 @@ func (s *keeperServer) initHeartbeatScheduler() {
 			s.fallbackExecutor = executor
 		}
 	}
+	notifier := &keeperCronNotifier{server: s}
 	s.heartbeatScheduler = cronpkg.NewScheduler(
 		store,
 		nil,
 		executor,
-		&keeperCronNotifier{server: s},
+		notifier,
 	)
+	if signer, err := provenance.LoadOrCreate(provenance.DefaultKeyPath()); err != nil {
+		logger.Warn("[KeeperCron] Jobs will be unsigned: %v", err)
+	} else {
+		s.heartbeatScheduler.SetSigner(signer)
+	}
 	if err := s.heartbeatScheduler.Start(); err != nil {
 		logger.Warn("[KeeperCron] Failed to start scheduler: %v", err)
 		s.heartbeatScheduler = nil
 		return
 	}
+	if gc, ok := cronGCConfig(s.cfg.Cron, notifier); ok {
+		s.heartbeatScheduler.EnableGC(gc)
+	}
 	logger.Info("[KeeperCron] Scheduler started (store: %s)", dbPath)
 }
 
@@ -325,41 +394,76 @@ func (s *keeperServer) stopHeartbeatScheduler() {
 	}
 }
 
+// ensureHeartbeatJobsForUser makes the user's heartbeat jobs match their
+// spec (their override, else HEARTBEAT.md): missing tasks are added, changed
+// ones replaced and tasks no longer in the spec removed. A spec that fails
+// to parse leaves the jobs alone, so a half-saved edit does not drop them.
 func (s *keeperServer) ensureHeartbeatJobsForUser(userID string) {
 	if s == nil || s.heartbeatScheduler == nil || s.heartbeatExecutor == nil {
 		return
 	}
-	spec, err := loadKeeperHeartbeatSpec()
+	spec, source, err := loadKeeperHeartbeatSpec(userID)
 	if err != nil {
-		logger.Warn("[KeeperCron] Failed to load HEARTBEAT.md: %v", err)
+		logger.Warn("[KeeperCron] Failed to load %s: %v", source, err)
 		return
 	}
-	if spec == nil || !spec.Enabled || len(spec.Tasks) == 0 {
-		return
+
+	want := map[string]keeperHeartbeatJob{}
+	var names []string
+	if spec != nil && spec.Enabled {
+		for idx, task := range spec.Tasks {
+			task.Name = strings.TrimSpace(task.Name)
+			if task.Name == "" {
+				task.Name = fmt.Sprintf("task-%d", idx+1)
+			}
+			task.Schedule = strings.TrimSpace(task.Schedule)
+			task.Prompt = strings.TrimSpace(task.Prompt)
+			if task.Schedule == "" || task.Prompt == "" {
+				continue
+			}
+			jobName := keeperHeartbeatJobName(userID, task.Name)
+			if _, dup := want[jobName]; !dup {
+				names = append(names, jobName)
+			}
+			want[jobName] = task
+		}
 	}
 
-	existing := s.heartbeatScheduler.ListJobsByTag("heartbeat")
-	for idx, task := range spec.Tasks {
-		name := strings.TrimSpace(task.Name)
-		if name == "" {
-			name = fmt.Sprintf("task-%d", idx+1)
+	s.heartbeatMu.Lock()
+	defer s.heartbeatMu.Unlock()
+
+	prefix := keeperHeartbeatJobPrefix(userID)
+	for _, job := range s.heartbeatScheduler.ListJobsByTag("heartbeat") {
+		if !strings.HasPrefix(job.Name, prefix) || job.Platform != "wecom" || job.UserID != userID {
+			continue
 		}
-		schedule := strings.TrimSpace(task.Schedule)
-		prompt := strings.TrimSpace(task.Prompt)
-		if schedule == "" || prompt == "" {
+		task, ok := want[job.Name]
+		if ok && job.Schedule == cronpkg.NormalizeSchedule(task.Schedule) && job.Prompt == decorateKeeperHeartbeatPrompt(task.Prompt, task.Notify) {
+			delete(want, job.Name)
 			continue
 		}
-
-		jobName := keeperHeartbeatJobName(userID, name)
-		if keeperHeartbeatJobExists(existing, jobName, "wecom", userID, userID) {
+		if err := s.heartbeatScheduler.RemoveJob(job.ID); err != nil {
+			logger.Warn("[KeeperCron] Failed to remove heartbeat job %s: %v", job.Name, err)
+			delete(want, job.Name)
 			continue
 		}
+		if ok {
+			logger.Info("[KeeperCron] Heartbeat job changed for %s: %s", userID, job.Name)
+		} else {
+			logger.Info("[KeeperCron] Heartbeat job removed for %s: %s", userID, job.Name)
+		}
+	}
 
-		_, err := s.heartbeatScheduler.AddJobWithPromptAndTag(
+	for _, jobName := range names {
+		task, ok := want[jobName]
+		if !ok {
+			continue
+		}
+		job, err := s.heartbeatScheduler.AddJobWithPromptAndTag(
 			jobName,
 			"heartbeat",
-			schedule,
-			decorateKeeperHeartbeatPrompt(prompt, task.Notify),
+			task.Schedule,
+			decorateKeeperHeartbeatPrompt(task.Prompt, task.Notify),
 			"wecom",
 			userID,
 			userID,
@@ -368,11 +472,30 @@ func (s *keeperServer) ensureHeartbeatJobsForUser(userID string) {
 			logger.Warn("[KeeperCron] Failed to create heartbeat job %s: %v", jobName, err)
 			continue
 		}
-		logger.Info("[KeeperCron] Heartbeat job created for %s: %s (%s)", userID, jobName, schedule)
+		if err := s.heartbeatScheduler.SetProvenance(job.ID, provenance.Record{
+			Origin:       provenance.OriginHeartbeat,
+			Actor:        source,
+			Conversation: "wecom:" + userID + ":" + userID,
+			Reason:       "task " + task.Name,
+		}); err != nil {
+			logger.Warn("[KeeperCron] Failed to record provenance for %s: %v", jobName, err)
+		}
+		logger.Info("[KeeperCron] Heartbeat job created for %s: %s (%s)", userID, jobName, task.Schedule)
 	}
 }
 
-func (s *keeperServer) buildOfflineReply(userID, text string) string {
+// buildOfflineReply answers for coco, with the fallback model when one is
+// configured, and records the attempt for the dashboard. reason says why
+// coco could not take the message.
+func (s *keeperServer) buildOfflineReply(userID, text, reason string) string {
+	ev := keeperFailoverEvent{Time: time.Now(), UserID: userID, Reason: reason, Outcome: "canned"}
+	defer func() {
+		if s != nil {
+			ev.Latency = time.Since(ev.Time).Milliseconds()
+			s.activity.failover(ev)
+		}
+	}()
+
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return "coco 暂时不在线，请稍后再试。"
@@ -380,6 +503,8 @@ func (s *keeperServer) buildOfflineReply(userID, text string) string {
 	if s == nil || s.fallbackExecutor == nil {
 		return "coco 暂时不在线，请稍后再试。"
 	}
+	ev.Provider = strings.TrimSpace(s.cfg.Keeper.DefaultProvider)
+	ev.Model = strings.TrimSpace(s.cfg.Keeper.DefaultModel)
 
 	ctx, cancel := context.WithTimeout(s.ctx, 8*time.Second)
 	defer cancel()
@@ -398,26 +523,24 @@ func (s *keeperServer) buildOfflineReply(userID, text string) string {
 	reply, err := s.fallbackExecutor.ExecutePrompt(ctx, "wecom", userID, userID, prompt)
 	if err != nil {
 		logger.Warn("[KeeperFallback] LLM fallback failed: %v", err)
+		ev.Outcome, ev.Error = "llm failed", err.Error()
 		return "coco 暂时不在线，请稍后再试。"
 	}
 	reply = strings.TrimSpace(reply)
 	if reply == "" {
+		ev.Outcome, ev.Error = "llm failed", "empty reply"
 		return "coco 暂时不在线，请稍后再试。"
 	}
+	ev.Outcome = "llm"
 	return reply
 }
 
-func keeperHeartbeatJobExists(jobs []*cronpkg.Job, name, platform, channelID, userID string) bool {
-	for _, j := range jobs {
-		if j.Name == name && j.Platform == platform && j.ChannelID == channelID && j.UserID == userID {
-			return true
-		}
-	}
-	return false
+func keeperHeartbeatJobName(userID, taskName string) string {
+	return keeperHeartbeatJobPrefix(userID) + sanitizeHeartbeatTokenKeeper(taskName)
 }
 
-func keeperHeartbeatJobName(userID, taskName string) string {
-	return "keeper-heartbeat:" + sanitizeHeartbeatTokenKeeper(userID) + ":" + sanitizeHeartbeatTokenKeeper(taskName)
+func keeperHeartbeatJobPrefix(userID string) string {
+	return "keeper-heartbeat:" + sanitizeHeartbeatTokenKeeper(userID) + ":"
 }
 
 func sanitizeHeartbeatTokenKeeper(s string) string {
@@ -459,8 +582,21 @@ func normalizeKeeperHeartbeatNotify(notify string) string {
 	}
 }
 
-func loadKeeperHeartbeatSpec() (*keeperHeartbeatSpec, error) {
-	path := filepath.Join(keeperWorkspaceDir(), "HEARTBEAT.md")
+// loadKeeperHeartbeatSpec reads the user's heartbeat override when one was
+// uploaded, else the shared HEARTBEAT.md. source names the file for logs
+// and job provenance.
+func loadKeeperHeartbeatSpec(userID string) (spec *keeperHeartbeatSpec, source string, err error) {
+	path := keeperHeartbeatOverridePath(userID)
+	source = filepath.Join(keeperHeartbeatDirName, filepath.Base(path))
+	if _, statErr := os.Stat(path); statErr != nil {
+		path = filepath.Join(keeperWorkspaceDir(), "HEARTBEAT.md")
+		source = "HEARTBEAT.md"
+	}
+	spec, err = parseKeeperHeartbeatSpec(path)
+	return spec, source, err
+}
+
+func parseKeeperHeartbeatSpec(path string) (*keeperHeartbeatSpec, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -517,7 +653,8 @@ func splitKeeperMarkdownFrontMatter(content string) (frontMatter string, body st
 	if !strings.HasPrefix(normalized, "---\n") {
 		return "", strings.TrimSpace(content)
 	}
-	rest := normalized[len("---\n"):]
+	// The closing --- may end the file.
+	rest := normalized[len("---\n"):] + "\n"
 	idx := strings.Index(rest, "\n---\n")
 	if idx < 0 {
 		return "", strings.TrimSpace(content)
@@ -528,16 +665,22 @@ func splitKeeperMarkdownFrontMatter(content string) (frontMatter string, body st
 // ---------- HTTP handlers ----------
 
 func (s *keeperServer) handleHealth(w http.ResponseWriter, r *http.Request) {
-	s.clientMu.RLock()
-	cocoOnline := s.client != nil
-	s.clientMu.RUnlock()
-
-	status := "offline"
-	if cocoOnline {
-		status = "online"
+	var clients []*cocoClient
+	for _, c := range s.listClients() {
+		if c.online() {
+			clients = append(clients, c)
+		}
+	}
+	health := map[string]any{"status": "ok", "coco": "offline", "clients": len(clients)}
+	if len(clients) > 0 {
+		health["coco"] = "online"
+	}
+	if len(clients) == 1 {
+		health["user_id"] = clients[0].userID
+		health["platform"] = clients[0].platform
 	}
 	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"status":"ok","coco":"%s"}`, status)
+	json.NewEncoder(w).Encode(health)
 }
 
 // handleWeComCallback handles GET (URL verification) and POST (message callback).
@@ -615,25 +758,14 @@ func (s *keeperServer) processWeComMessage(plaintext []byte) {
 
 	userID := msg.FromUserName
 	text := msg.Content
-	logger.Info("[Keeper] WeCom message from %s: %s", userID, text)
-	s.ensureHeartbeatJobsForUser(userID)
-
-	// Try to forward to coco
-	s.clientMu.RLock()
-	client := s.client
-	s.clientMu.RUnlock()
-
-	if client == nil {
-		// coco offline — send fallback reply
-		logger.Info("[Keeper] coco offline, sending fallback reply to %s", userID)
-		reply := s.buildOfflineReply(userID, text)
-		if err := s.sendWeComReply(userID, reply); err != nil {
-			logger.Error("[Keeper] Failed to send fallback reply: %v", err)
-		}
+	if reason, banned := s.spam.check(userID, text, time.Now()); reason != "" {
+		s.dropSpam(userID, text, reason, banned)
 		return
 	}
+	logger.Info("[Keeper] WeCom message from %s: %s", userID, text)
+	s.noteUser(userID)
+	s.ensureHeartbeatJobsForUser(userID)
 
-	// Forward to coco via WebSocket
 	incoming := relay.IncomingMessage{
 		Type:      "message",
 		ID:        msg.MsgId,
@@ -648,24 +780,32 @@ func (s *keeperServer) processWeComMessage(plaintext []byte) {
 		},
 	}
 
-	client.mu.Lock()
-	err := client.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	if err == nil {
-		err = client.conn.WriteJSON(incoming)
-	}
-	client.mu.Unlock()
+	// Try to forward to the coco serving this user
+	client := s.routeClient("wecom", userID)
 
-	if err != nil {
-		logger.Error("[Keeper] Failed to forward message to coco: %v", err)
-		// coco connection broken, send fallback
-		reply := s.buildOfflineReply(userID, text)
+	if client == nil {
+		// coco offline — send fallback reply and keep the message for later
+		logger.Info("[Keeper] coco offline, sending fallback reply to %s", userID)
+		s.activity.message("in", userID, text, "fallback")
+		s.enqueueOffline(incoming)
+		reply := s.buildOfflineReply(userID, text, "coco offline")
+		s.activity.message("out", userID, reply, "fallback")
 		if err := s.sendWeComReply(userID, reply); err != nil {
 			logger.Error("[Keeper] Failed to send fallback reply: %v", err)
 		}
 		return
 	}
 
-	logger.Info("[Keeper] Forwarded message to coco: %s -> %s", userID, text)
+	if !client.deliver(incoming) {
+		// The connection dropped; the message goes out when coco resumes its
+		// session, or gets the fallback reply if it does not come back.
+		s.activity.message("in", userID, text, "buffered")
+		logger.Info("[Keeper] coco %s is reconnecting; message from %s buffered for up to %s", client.userID, userID, keeperResumeGrace)
+		return
+	}
+
+	s.activity.message("in", userID, text, "coco")
+	logger.Info("[Keeper] Forwarded message to coco %s: %s -> %s", client.userID, userID, text)
 }
 
 // sendWeComReply sends a text message to a WeCom user via the WeCom API.
@@ -712,7 +852,29 @@ func (s *keeperServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sessionID := fmt.Sprintf("keeper-%s-%d", authMsg.UserID, time.Now().UnixMilli())
+	// A coco that lost its connection briefly continues its session and
+	// gets what was buffered meanwhile; anyone else starts a new one.
+	client := s.resumableClient(authMsg)
+	var sessionID string
+	if client != nil {
+		sessionID = client.sessionID
+	} else {
+		if s.clientIDInUse(authMsg.Platform, authMsg.UserID) {
+			logger.Warn("[Keeper] Auth rejected: user-id %s is already connected (new connection from %s)", authMsg.UserID, r.RemoteAddr)
+			conn.WriteJSON(relay.AuthResult{Type: "auth_result", Success: false,
+				Error: fmt.Sprintf("user-id %s is already connected; give each coco its own relay.user_id", authMsg.UserID)})
+			conn.Close()
+			return
+		}
+		// The session ID is what /webhook and session resume trust, so it
+		// must not be guessable.
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			conn.Close()
+			return
+		}
+		sessionID = "keeper-" + hex.EncodeToString(buf)
+	}
 
 	// Send auth result
 	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -720,52 +882,64 @@ func (s *keeperServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		Type:      "auth_result",
 		Success:   true,
 		SessionID: sessionID,
+		Resumed:   client != nil,
+		Features:  keeperRelayFeatures,
 	}); err != nil {
 		logger.Error("[Keeper] Failed to send auth result: %v", err)
 		conn.Close()
 		return
 	}
 
-	client := &cocoClient{
-		conn:      conn,
-		userID:    authMsg.UserID,
-		platform:  authMsg.Platform,
-		sessionID: sessionID,
-	}
-
-	// Register client (replace existing if any)
-	s.clientMu.Lock()
-	old := s.client
-	s.client = client
-	s.clientMu.Unlock()
+	if client != nil {
+		client.lastSeen.Store(time.Now().UnixNano())
+		replaced, replayed := client.attach(conn, r.RemoteAddr, authMsg.LastSeq)
+		if replaced != nil {
+			// Same session from a fresh connection: the old one is dead even
+			// if keeper has not noticed yet. Not a duplicate client.
+			replaced.Close()
+		}
+		logger.Info("[Keeper] coco resumed: user=%s, session=%s, replayed %d message(s)", authMsg.UserID, sessionID, replayed)
+	} else {
+		client = &cocoClient{
+			conn:        conn,
+			userID:      authMsg.UserID,
+			platform:    authMsg.Platform,
+			sessionID:   sessionID,
+			remoteAddr:  r.RemoteAddr,
+			connectedAt: time.Now(),
+		}
+		client.lastSeen.Store(client.connectedAt.UnixNano())
 
-	if old != nil {
-		logger.Info("[Keeper] Replacing previous coco connection")
-		old.conn.Close()
+		// Register client, taking over a disconnected session with the same user-id
+		if err := s.registerClient(client); err != nil {
+			logger.Warn("[Keeper] Dropping connection from %s: %v", r.RemoteAddr, err)
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+		logger.Info("[Keeper] coco connected: user=%s, platform=%s, session=%s", authMsg.UserID, authMsg.Platform, sessionID)
+		s.replayOffline(client)
 	}
 
-	logger.Info("[Keeper] coco connected: user=%s, platform=%s, session=%s", authMsg.UserID, authMsg.Platform, sessionID)
-
 	// Read loop — handle responses from coco
-	s.cocoReadLoop(client)
+	s.cocoReadLoop(client, conn)
 }
 
-// cocoReadLoop reads messages from a connected coco client.
-func (s *keeperServer) cocoReadLoop(client *cocoClient) {
+// cocoReadLoop reads messages from one connection of a coco client.
+func (s *keeperServer) cocoReadLoop(client *cocoClient, conn *websocket.Conn) {
 	defer func() {
-		// Unregister client
-		s.clientMu.Lock()
-		if s.client == client {
-			s.client = nil
+		conn.Close()
+		// Keep the session for a while so the client can resume it.
+		if client.detach(conn, func() { s.expireClient(client) }) {
+			logger.Info("[Keeper] coco disconnected: user=%s, session=%s; keeping session for %s",
+				client.userID, client.sessionID, keeperResumeGrace)
 		}
-		s.clientMu.Unlock()
-		client.conn.Close()
-		logger.Info("[Keeper] coco disconnected: user=%s, session=%s", client.userID, client.sessionID)
 	}()
 
 	// Set up ping/pong handlers
-	client.conn.SetPongHandler(func(appData string) error {
-		client.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	conn.SetPongHandler(func(appData string) error {
+		client.lastSeen.Store(time.Now().UnixNano())
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		return nil
 	})
 
@@ -779,8 +953,12 @@ func (s *keeperServer) cocoReadLoop(client *cocoClient) {
 				return
 			case <-ticker.C:
 				client.mu.Lock()
-				client.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-				err := client.conn.WriteMessage(websocket.PingMessage, nil)
+				if client.conn != conn {
+					client.mu.Unlock()
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				err := conn.WriteMessage(websocket.PingMessage, nil)
 				client.mu.Unlock()
 				if err != nil {
 					return
@@ -790,14 +968,15 @@ func (s *keeperServer) cocoReadLoop(client *cocoClient) {
 	}()
 
 	for {
-		client.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		_, message, err := client.conn.ReadMessage()
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
 				logger.Error("[Keeper] coco read error: %v", err)
 			}
 			return
 		}
+		client.lastSeen.Store(time.Now().UnixNano())
 
 		// Parse message type
 		var jsonMsg struct {
@@ -810,11 +989,11 @@ func (s *keeperServer) cocoReadLoop(client *cocoClient) {
 
 		switch jsonMsg.Type {
 		case "response":
-			s.handleCocoResponse(message)
+			s.handleCocoResponse(client.sessionID, message)
 		case "ping":
 			client.mu.Lock()
-			client.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			client.conn.WriteJSON(relay.PingPong{Type: "pong"})
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			conn.WriteJSON(relay.PingPong{Type: "pong"})
 			client.mu.Unlock()
 		case "pong":
 			// ignore
@@ -826,21 +1005,28 @@ func (s *keeperServer) cocoReadLoop(client *cocoClient) {
 
 // handleCocoResponse processes a response from coco and sends it to WeCom.
 // Called both from the WebSocket read loop and the /webhook HTTP handler.
-func (s *keeperServer) handleCocoResponse(data []byte) {
+func (s *keeperServer) handleCocoResponse(sessionID string, data []byte) {
 	var resp relay.OutgoingResponse
 	if err := json.Unmarshal(data, &resp); err != nil {
 		logger.Error("[Keeper] Failed to parse coco response: %v", err)
 		return
 	}
 
-	if resp.Text == "" {
-		return
-	}
+	if resp.Text != "" {
+		logger.Info("[Keeper] Sending coco reply to WeCom user %s: %s", resp.ChannelID, truncate(resp.Text, 80))
+		s.activity.message("out", resp.ChannelID, resp.Text, "reply")
 
-	logger.Info("[Keeper] Sending coco reply to WeCom user %s: %s", resp.ChannelID, truncate(resp.Text, 80))
+		if err := s.sendWeComReply(resp.ChannelID, resp.Text); err != nil {
+			logger.Error("[Keeper] Failed to send WeCom reply: %v", err)
+		}
+	}
 
-	if err := s.sendWeComReply(resp.ChannelID, resp.Text); err != nil {
-		logger.Error("[Keeper] Failed to send WeCom reply: %v", err)
+	for _, file := range resp.Files {
+		logger.Info("[Keeper] Sending coco file to WeCom user %s: %s (%s)", resp.ChannelID, file.Name, file.MediaType)
+		s.activity.message("out", resp.ChannelID, "📎 "+file.Name, "reply")
+		if err := s.sendCocoFile(sessionID, resp.ChannelID, file); err != nil {
+			logger.Error("[Keeper] Failed to send file %s: %v", file.Name, err)
+		}
 	}
 }
 
@@ -859,17 +1045,23 @@ func (s *keeperServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.clientMu.RLock()
-	client := s.client
-	s.clientMu.RUnlock()
-
-	if client == nil || client.sessionID != sessionID {
+	if client := s.clientBySession(sessionID); client == nil {
 		logger.Warn("[Keeper] Webhook from unknown session %s (coco not connected or session mismatch)", sessionID)
 		http.Error(w, "unknown session", http.StatusUnauthorized)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	var reader io.Reader = r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, "invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		reader = zr
+	}
+	body, err := io.ReadAll(reader)
 	if err != nil {
 		http.Error(w, "read failed", http.StatusBadRequest)
 		return
@@ -877,10 +1069,12 @@ func (s *keeperServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 
-	go s.handleCocoResponse(body)
+	go s.handleCocoResponse(sessionID, body)
 }
 
 // handleHeartbeatUpload receives HEARTBEAT.md content from onboard bootstrap.
+// With a user_id the content becomes that user's override instead of the
+// shared spec. Heartbeat jobs follow the new spec right away.
 func (s *keeperServer) handleHeartbeatUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -913,6 +1107,7 @@ func (s *keeperServer) handleHeartbeatUpload(w http.ResponseWriter, r *http.Requ
 	var payload struct {
 		Filename string `json:"filename"`
 		Content  string `json:"content"`
+		UserID   string `json:"user_id"`
 	}
 	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "invalid json payload", http.StatusBadRequest)
@@ -933,21 +1128,31 @@ func (s *keeperServer) handleHeartbeatUpload(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	workspaceDir := keeperWorkspaceDir()
-	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+	userID := strings.TrimSpace(payload.UserID)
+	target := filepath.Join(keeperWorkspaceDir(), filename)
+	if userID != "" {
+		target = keeperHeartbeatOverridePath(userID)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 		http.Error(w, "failed to create workspace dir", http.StatusInternalServerError)
 		return
 	}
-	target := filepath.Join(workspaceDir, filename)
 	if err := os.WriteFile(target, []byte(payload.Content), 0644); err != nil {
 		http.Error(w, "failed to save heartbeat", http.StatusInternalServerError)
 		return
 	}
+	switch {
+	case userID != "":
+		s.ensureHeartbeatJobsForUser(userID)
+	case strings.EqualFold(filename, "HEARTBEAT.md"):
+		s.reloadHeartbeats()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"ok":   true,
-		"path": target,
+		"ok":      true,
+		"path":    target,
+		"user_id": userID,
 	})
 }
 
@@ -974,11 +1179,22 @@ func (s *keeperServer) requireKeeperAPIAuth(w http.ResponseWriter, r *http.Reque
 	return true
 }
 
+// requireKeeperToken is requireKeeperAPIAuth for endpoints that must never
+// be open: without keeper.token configured they are refused outright.
+func (s *keeperServer) requireKeeperToken(w http.ResponseWriter, r *http.Request) bool {
+	if strings.TrimSpace(s.cfg.Keeper.Token) == "" {
+		http.Error(w, "set keeper.token to use this endpoint", http.StatusForbidden)
+		return false
+	}
+	return s.requireKeeperAPIAuth(w, r)
+}
+
 type keeperCronCreateRequest struct {
 	Name      string         `json:"name"`
 	Tag       string         `json:"tag,omitempty"`
 	Type      string         `json:"type,omitempty"`
 	Schedule  string         `json:"schedule"`
+	RunAt     string         `json:"run_at,omitempty"`
 	Message   string         `json:"message,omitempty"`
 	Prompt    string         `json:"prompt,omitempty"`
 	Tool      string         `json:"tool,omitempty"`
@@ -989,6 +1205,8 @@ type keeperCronCreateRequest struct {
 	Platform  string         `json:"platform"`
 	ChannelID string         `json:"channel_id"`
 	UserID    string         `json:"user_id"`
+
+	Provenance *provenance.Record `json:"provenance,omitempty"` // Attribution supplied by the calling relay
 }
 
 type keeperCronIDRequest struct {
@@ -1021,17 +1239,31 @@ func (s *keeperServer) handleCronCreate(w http.ResponseWriter, r *http.Request)
 
 	req.Name = strings.TrimSpace(req.Name)
 	req.Schedule = strings.TrimSpace(req.Schedule)
+	req.RunAt = strings.TrimSpace(req.RunAt)
 	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
 	req.Platform = strings.TrimSpace(req.Platform)
 	req.ChannelID = strings.TrimSpace(req.ChannelID)
 	req.UserID = strings.TrimSpace(req.UserID)
-	if req.Name == "" || req.Schedule == "" || req.Platform == "" || req.ChannelID == "" || req.UserID == "" {
-		http.Error(w, "name/schedule/platform/channel_id/user_id are required", http.StatusBadRequest)
+	if req.Name == "" || (req.Schedule == "" && req.RunAt == "") || req.Platform == "" || req.ChannelID == "" || req.UserID == "" {
+		http.Error(w, "name/schedule(or run_at)/platform/channel_id/user_id are required", http.StatusBadRequest)
 		return
 	}
 
 	var job *cronpkg.Job
 	switch {
+	case req.RunAt != "":
+		runAt, parseErr := time.Parse(time.RFC3339, req.RunAt)
+		if parseErr != nil {
+			http.Error(w, "run_at must be RFC3339", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Prompt) == "" && strings.TrimSpace(req.Message) == "" {
+			http.Error(w, "prompt or message is required for one-shot job", http.StatusBadRequest)
+			return
+		}
+		job, err = s.heartbeatScheduler.AddOnceJob(
+			req.Name, req.Tag, runAt, req.Message, req.Prompt, req.Platform, req.ChannelID, req.UserID,
+		)
 	case req.Type == "external" || strings.TrimSpace(req.Endpoint) != "":
 		if strings.TrimSpace(req.Endpoint) == "" {
 			http.Error(w, "endpoint is required for external job", http.StatusBadRequest)
@@ -1060,6 +1292,18 @@ func (s *keeperServer) handleCronCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// coco relays attach the chat that asked for the job; any other caller is an external agent.
+	rec := provenance.Record{Origin: provenance.OriginExternalAgent, Actor: r.RemoteAddr}
+	if req.Provenance != nil {
+		rec = *req.Provenance
+		if rec.Origin == "" {
+			rec.Origin = provenance.OriginKeeperAPI
+		}
+	}
+	if err := s.heartbeatScheduler.SetProvenance(job.ID, rec); err != nil {
+		logger.Warn("[KeeperCron] Failed to record provenance for %s: %v", job.ID, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"ok":  true,
@@ -1204,6 +1448,153 @@ func (s *keeperServer) handleCronResume(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
 }
 
+// keeperSyncMaxBlobSize caps a single workspace sync blob (encrypted, opaque to Keeper).
+const keeperSyncMaxBlobSize = 32 << 20
+
+// keeperSyncNamespacePattern matches the X-Sync-Namespace that coco derives
+// from its sync passphrase (wsync.Namespace).
+var keeperSyncNamespacePattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// keeperSyncRoot is the directory holding the sync blobs of the devices that
+// send r's X-Sync-Namespace. The shared keeper.token says nothing about whose
+// workspace a request is for, so every blob lives under its namespace and a
+// request only ever reaches its own.
+func keeperSyncRoot(r *http.Request) (string, error) {
+	namespace := strings.TrimSpace(r.Header.Get("X-Sync-Namespace"))
+	if namespace == "" {
+		return "", fmt.Errorf("X-Sync-Namespace is required (update coco)")
+	}
+	if !keeperSyncNamespacePattern.MatchString(namespace) {
+		return "", fmt.Errorf("invalid X-Sync-Namespace")
+	}
+	return filepath.Join(keeperWorkspaceDir(), ".coco", "sync", namespace), nil
+}
+
+// keeperSyncPath maps a sync blob key to a file under root.
+func keeperSyncPath(root, key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("key is required")
+	}
+	clean := filepath.ToSlash(filepath.Clean("/" + key))
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid key")
+	}
+	return filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(clean, "/"))), nil
+}
+
+// handleSyncBlob stores encrypted workspace sync blobs (GET/PUT/DELETE ?key=)
+// under the caller's sync namespace.
+func (s *keeperServer) handleSyncBlob(w http.ResponseWriter, r *http.Request) {
+	if !s.requireKeeperAPIAuth(w, r) {
+		return
+	}
+	root, err := keeperSyncRoot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := keeperSyncPath(root, r.URL.Query().Get("key"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		data, err := os.ReadFile(target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "read failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	case http.MethodPut, http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, keeperSyncMaxBlobSize+1))
+		if err != nil {
+			http.Error(w, "read failed", http.StatusBadRequest)
+			return
+		}
+		if len(data) > keeperSyncMaxBlobSize {
+			http.Error(w, "blob too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			http.Error(w, "failed to create sync dir", http.StatusInternalServerError)
+			return
+		}
+		tmp := target + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			http.Error(w, "write failed", http.StatusInternalServerError)
+			return
+		}
+		if err := os.Rename(tmp, target); err != nil {
+			http.Error(w, "write failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "size": len(data)})
+	case http.MethodDelete:
+		if err := os.Remove(target); err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "delete failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSyncList lists the caller's sync blobs under ?prefix=.
+func (s *keeperServer) handleSyncList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireKeeperAPIAuth(w, r) {
+		return
+	}
+	root, err := keeperSyncRoot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prefix := strings.TrimLeft(strings.TrimSpace(r.URL.Query().Get("prefix")), "/")
+
+	type syncObject struct {
+		Key          string    `json:"key"`
+		Size         int64     `json:"size"`
+		LastModified time.Time `json:"last_modified"`
+	}
+	objects := []syncObject{}
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, syncObject{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+		return nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"objects": objects})
+}
+
 func keeperWorkspaceDir() string {
 	if env := strings.TrimSpace(os.Getenv("COCO_WORKSPACE_DIR")); env != "" {
 		return env
@@ -1240,6 +1631,12 @@ func runKeeper(cmd *cobra.Command, args []string) {
 		port = 8080
 	}
 
+	claimInstance("keeper", func(info *instance.Info) { info.KeeperPort = port })
+	if err := checkKeeperPort(port); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	srv, err := newKeeperServer(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize Keeper: %v\n", err)
@@ -1257,11 +1654,14 @@ func runKeeper(cmd *cobra.Command, args []string) {
 
 	srv.initFallbackExecutor()
 	srv.initHeartbeatScheduler()
+	srv.watchHeartbeatSpecs(srv.ctx)
+	srv.initOfflineQueue()
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", srv.handleWebSocket)
 	mux.HandleFunc("/wecom", srv.handleWeComCallback)
 	mux.HandleFunc("/webhook", srv.handleWebhook)
+	mux.HandleFunc("/webhook/upload", srv.handleUpload)
 	mux.HandleFunc("/health", srv.handleHealth)
 	mux.HandleFunc("/api/heartbeat/upload", srv.handleHeartbeatUpload)
 	mux.HandleFunc("/api/cron/create", srv.handleCronCreate)
@@ -1269,6 +1669,15 @@ func runKeeper(cmd *cobra.Command, args []string) {
 	mux.HandleFunc("/api/cron/delete", srv.handleCronDelete)
 	mux.HandleFunc("/api/cron/pause", srv.handleCronPause)
 	mux.HandleFunc("/api/cron/resume", srv.handleCronResume)
+	mux.HandleFunc("/api/sync/blob", srv.handleSyncBlob)
+	mux.HandleFunc("/api/sync/list", srv.handleSyncList)
+	mux.HandleFunc("/api/files", srv.handleShareUpload)
+	mux.HandleFunc("/files/", srv.handleShareDownload)
+	mux.HandleFunc("/market/", srv.handleMarket)
+	mux.HandleFunc("/clients", srv.handleClients)
+	mux.HandleFunc("/api/broadcast", srv.handleBroadcast)
+	mux.HandleFunc("/dashboard", srv.handleDashboard)
+	mux.HandleFunc("/dashboard/api/state", srv.handleDashboardState)
 
 	addr := fmt.Sprintf(":%d", port)
 	httpServer := &http.Server{
@@ -1284,6 +1693,8 @@ func runKeeper(cmd *cobra.Command, args []string) {
 		logger.Info("[Keeper] Health check:   http://0.0.0.0%s/health", addr)
 		logger.Info("[Keeper] Bootstrap API:  http://0.0.0.0%s/api/heartbeat/upload", addr)
 		logger.Info("[Keeper] Cron API:       http://0.0.0.0%s/api/cron/*", addr)
+		logger.Info("[Keeper] Dashboard:      http://0.0.0.0%s/dashboard", addr)
+		logger.Info("[Keeper] Market index:   http://0.0.0.0%s/market/index.json", addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("[Keeper] Server error: %v", err)
 			os.Exit(1)
@@ -1302,6 +1713,10 @@ func runKeeper(cmd *cobra.Command, args []string) {
 	srv.wecom.Stop()
 	srv.stopHeartbeatScheduler()
 	httpServer.Shutdown(shutdownCtx)
+	if srv.queue != nil {
+		srv.queue.Close()
+	}
+	releaseInstance()
 	logger.Info("[Keeper] Stopped")
 }
 
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+)
+
+// keeperBroadcastMaxBody is the largest POST /api/broadcast body read.
+const keeperBroadcastMaxBody = 64 << 10
+
+// keeperBroadcastRequest is the body of POST /api/broadcast.
+type keeperBroadcastRequest struct {
+	Message string   `json:"message"`
+	UserIDs []string `json:"user_ids,omitempty"` // WeCom user IDs to reach
+	All     bool     `json:"all,omitempty"`      // every user keeper knows about
+}
+
+type keeperBroadcastResult struct {
+	UserID string `json:"user_id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// noteUser remembers a WeCom user who talked to keeper, so broadcasts to
+// "all" reach them.
+func (s *keeperServer) noteUser(userID string) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	if s.users == nil {
+		s.users = make(map[string]time.Time)
+	}
+	s.users[userID] = time.Now()
+}
+
+// knownUsers returns the WeCom users keeper can reach: everyone who sent a
+// message since keeper started, and the user-ids of connected wecom coco
+// clients (by default a coco's user_id is its owner's WeCom user ID).
+func (s *keeperServer) knownUsers() []string {
+	seen := map[string]bool{}
+	s.usersMu.Lock()
+	for id := range s.users {
+		seen[id] = true
+	}
+	s.usersMu.Unlock()
+	for _, c := range s.listClients() {
+		if strings.EqualFold(c.platform, "wecom") {
+			seen[c.userID] = true
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for id := range seen {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// broadcastRecipients resolves who a broadcast goes to, without
+// duplicates. Only users keeper knows about are reachable; other user_ids
+// are returned as unknown.
+func (s *keeperServer) broadcastRecipients(req keeperBroadcastRequest) (recipients, unknown []string) {
+	known := s.knownUsers()
+	isKnown := make(map[string]bool, len(known))
+	for _, id := range known {
+		isKnown[id] = true
+	}
+	ids := req.UserIDs
+	if req.All {
+		ids = append(known, ids...)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if isKnown[id] {
+			recipients = append(recipients, id)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	return recipients, unknown
+}
+
+// handleBroadcast sends an operator announcement to WeCom users as a
+// normal message (POST /api/broadcast). It needs keeper.token.
+func (s *keeperServer) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireKeeperToken(w, r) {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, keeperBroadcastMaxBody+1))
+	if err != nil {
+		http.Error(w, "read failed", http.StatusBadRequest)
+		return
+	}
+	if len(body) > keeperBroadcastMaxBody {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var req keeperBroadcastRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid json payload", http.StatusBadRequest)
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		http.Error(w, "message is required", http.StatusBadRequest)
+		return
+	}
+	if !req.All && len(req.UserIDs) == 0 {
+		http.Error(w, "user_ids or all is required", http.StatusBadRequest)
+		return
+	}
+	recipients, unknown := s.broadcastRecipients(req)
+	if len(recipients) == 0 {
+		http.Error(w, "no known users to broadcast to", http.StatusNotFound)
+		return
+	}
+
+	results := make([]keeperBroadcastResult, 0, len(recipients)+len(unknown))
+	sent := 0
+	for _, userID := range recipients {
+		res := keeperBroadcastResult{UserID: userID, OK: true}
+		if err := s.sendWeComReply(userID, req.Message); err != nil {
+			res.OK, res.Error = false, err.Error()
+			logger.Warn("[Keeper] Broadcast to %s failed: %v", userID, err)
+		} else {
+			sent++
+			s.activity.message("out", userID, req.Message, "broadcast")
+		}
+		results = append(results, res)
+	}
+	for _, userID := range unknown {
+		results = append(results, keeperBroadcastResult{UserID: userID, Error: "unknown user: has not messaged keeper"})
+	}
+	logger.Info("[Keeper] Broadcast from %s delivered to %d/%d user(s)", r.RemoteAddr, sent, len(recipients))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"ok":      sent == len(recipients) && len(unknown) == 0,
+		"sent":    sent,
+		"results": results,
+	})
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestKeeperBroadcastRecipients(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}}
+	s.clients = map[string]*cocoClient{
+		clientKey("wecom", "alice"): {userID: "alice", platform: "wecom", connectedAt: time.Now()},
+		clientKey("feishu", "ou_x"): {userID: "ou_x", platform: "feishu", connectedAt: time.Now()},
+	}
+	s.noteUser("bob")
+	s.noteUser("alice")
+
+	got, unknown := s.broadcastRecipients(keeperBroadcastRequest{All: true, UserIDs: []string{"carol", "bob"}})
+	if !reflect.DeepEqual(got, []string{"alice", "bob"}) || !reflect.DeepEqual(unknown, []string{"carol"}) {
+		t.Fatalf("all = %v, unknown %v", got, unknown)
+	}
+	got, unknown = s.broadcastRecipients(keeperBroadcastRequest{UserIDs: []string{" bob ", "bob", "", "dave"}})
+	if !reflect.DeepEqual(got, []string{"bob"}) || !reflect.DeepEqual(unknown, []string{"dave"}) {
+		t.Fatalf("selected = %v, unknown %v", got, unknown)
+	}
+}
+
+func TestKeeperBroadcastValidation(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}}
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/broadcast", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer k33p")
+		rr := httptest.NewRecorder()
+		s.handleBroadcast(rr, req)
+		return rr.Code
+	}
+	if got := post(`{"message":"hi","all":true}`); got != http.StatusForbidden {
+		t.Fatalf("without keeper.token = %d", got)
+	}
+
+	s.cfg.Keeper.Token = "k33p"
+	for body, want := range map[string]int{
+		`{`:                           http.StatusBadRequest,
+		`{"message":" ","all":true}`:  http.StatusBadRequest,
+		`{"message":"hi"}`:            http.StatusBadRequest,
+		`{"message":"hi","all":true}`: http.StatusNotFound,
+		`{"message":"hi","user_ids":["mallory"]}`:                                      http.StatusNotFound,
+		`{"message":"` + strings.Repeat("x", keeperBroadcastMaxBody) + `","all":true}`: http.StatusRequestEntityTooLarge,
+	} {
+		if got := post(body); got != want {
+			t.Errorf("%.40s = %d, want %d", body, got, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	s.handleBroadcast(rr, httptest.NewRequest(http.MethodPost, "/api/broadcast", strings.NewReader(`{"message":"hi","all":true}`)))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("without token = %d", rr.Code)
+	}
+}
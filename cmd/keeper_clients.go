@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kayz/coco/internal/logger"
+)
+
+// clientKey identifies a coco connection; one connection per platform and
+// user-id is allowed.
+func clientKey(platform, userID string) string {
+	return strings.ToLower(strings.TrimSpace(platform)) + ":" + strings.TrimSpace(userID)
+}
+
+// errClientIDInUse refuses a connection for a user-id whose coco is online.
+// Every coco shares keeper.token, so letting the newer one win would let
+// any of them take over another's messages.
+var errClientIDInUse = errors.New("user-id is already connected")
+
+// registerClient adds c to the registry. A session with the same user-id
+// that is waiting to be resumed is replaced, and its buffered messages
+// move to c; one that is still online keeps the user-id.
+func (s *keeperServer) registerClient(c *cocoClient) error {
+	key := clientKey(c.platform, c.userID)
+	s.clientMu.Lock()
+	if s.clients == nil {
+		s.clients = make(map[string]*cocoClient)
+	}
+	old := s.clients[key]
+	if old != nil && old.online() {
+		s.clientMu.Unlock()
+		return fmt.Errorf("%w from %s", errClientIDInUse, old.view().RemoteAddr)
+	}
+	s.clients[key] = c
+	s.clientMu.Unlock()
+
+	if old == nil {
+		return nil
+	}
+	_, pending := old.close()
+	logger.Warn("[Keeper] Replacing disconnected coco session user=%s session=%s with session=%s from %s",
+		old.userID, old.sessionID, c.sessionID, c.remoteAddr)
+	for _, msg := range pending {
+		c.deliver(msg)
+	}
+	return nil
+}
+
+// clientIDInUse reports whether a coco with this user-id is online.
+func (s *keeperServer) clientIDInUse(platform, userID string) bool {
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	c := s.clients[clientKey(platform, userID)]
+	return c != nil && c.online()
+}
+
+// clientBySession finds the connection that was handed sessionID.
+func (s *keeperServer) clientBySession(sessionID string) *cocoClient {
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	for _, c := range s.clients {
+		if c.sessionID == sessionID {
+			return c
+		}
+	}
+	return nil
+}
+
+// keeperAnyClient as keeper.default_client hands unrouted users to whichever
+// coco connected last. It has to be set explicitly: on a shared Keeper that
+// coco is usually someone else's.
+const keeperAnyClient = "*"
+
+// routeClient picks the coco that should answer userID on platform:
+// keeper.routes, then a coco registered under the same user-id, then
+// keeper.default_client. A user none of them covers gets nil, and with it
+// the offline fallback.
+func (s *keeperServer) routeClient(platform, userID string) *cocoClient {
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	if target := strings.TrimSpace(s.cfg.Keeper.Routes[userID]); target != "" {
+		// An explicit route never falls through to someone else's coco.
+		return s.clients[clientKey(platform, target)]
+	}
+	if c := s.clients[clientKey(platform, userID)]; c != nil {
+		return c
+	}
+	def := strings.TrimSpace(s.cfg.Keeper.DefaultClient)
+	if def != keeperAnyClient {
+		if def == "" {
+			return nil
+		}
+		return s.clients[clientKey(platform, def)]
+	}
+	// Prefer a live connection over a session waiting to be resumed.
+	var newest *cocoClient
+	newestOnline := false
+	for _, c := range s.clients {
+		if !strings.EqualFold(c.platform, platform) {
+			continue
+		}
+		online := c.online()
+		if newest == nil || (online && !newestOnline) || (online == newestOnline && c.connectedAt.After(newest.connectedAt)) {
+			newest, newestOnline = c, online
+		}
+	}
+	return newest
+}
+
+// listClients returns the connected clients, oldest connection first.
+func (s *keeperServer) listClients() []*cocoClient {
+	s.clientMu.RLock()
+	out := make([]*cocoClient, 0, len(s.clients))
+	for _, c := range s.clients {
+		out = append(out, c)
+	}
+	s.clientMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].connectedAt.Before(out[j].connectedAt) })
+	return out
+}
+
+// handleClients lists connected coco clients (GET /clients).
+func (s *keeperServer) handleClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireKeeperToken(w, r) {
+		return
+	}
+	views := []keeperClientView{}
+	for _, c := range s.listClients() {
+		views = append(views, c.view())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "clients": views})
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestKeeperRouteClient(t *testing.T) {
+	now := time.Now()
+	s := &keeperServer{cfg: &config.Config{}}
+	s.clients = map[string]*cocoClient{
+		clientKey("wecom", "alice"):      {userID: "alice", platform: "wecom", sessionID: "s-alice", connectedAt: now},
+		clientKey("wecom", "wecom-corp"): {userID: "wecom-corp", platform: "wecom", sessionID: "s-corp", connectedAt: now.Add(time.Minute)},
+		clientKey("wecom", "bob-laptop"): {userID: "bob-laptop", platform: "wecom", sessionID: "s-bob", connectedAt: now.Add(2 * time.Minute)},
+	}
+
+	route := func(user string) string {
+		if c := s.routeClient("wecom", user); c != nil {
+			return c.userID
+		}
+		return ""
+	}
+	if got := route("alice"); got != "alice" {
+		t.Fatalf("same user-id = %q", got)
+	}
+	if got := route("carol"); got != "" {
+		t.Fatalf("an unrouted user must not reach someone else's coco, got %q", got)
+	}
+	s.cfg.Keeper.DefaultClient = "*"
+	if got := route("carol"); got != "bob-laptop" {
+		t.Fatalf("default_client * = %q, want the newest connection", got)
+	}
+	s.cfg.Keeper.DefaultClient = "wecom-corp"
+	if got := route("carol"); got != "wecom-corp" {
+		t.Fatalf("default client = %q", got)
+	}
+	s.cfg.Keeper.DefaultClient = "erin-pc"
+	if got := route("carol"); got != "" {
+		t.Fatalf("an offline default client must not fall through, got %q", got)
+	}
+	s.cfg.Keeper.DefaultClient = "wecom-corp"
+	s.cfg.Keeper.Routes = map[string]string{"bob": "bob-laptop", "dave": "dave-pc"}
+	if got := route("bob"); got != "bob-laptop" {
+		t.Fatalf("explicit route = %q", got)
+	}
+	if got := route("dave"); got != "" {
+		t.Fatalf("route to an offline coco must not fall through, got %q", got)
+	}
+	if got := s.routeClient("feishu", "alice"); got != nil {
+		t.Fatalf("other platform = %v", got)
+	}
+
+	if c := s.clientBySession("s-corp"); c == nil || c.userID != "wecom-corp" {
+		t.Fatalf("clientBySession = %v", c)
+	}
+	s.expireClient(s.clients[clientKey("wecom", "alice")])
+	if route("alice") == "alice" {
+		t.Fatal("expired client still routed")
+	}
+
+	rr := httptest.NewRecorder()
+	s.handleClients(rr, httptest.NewRequest(http.MethodGet, "/clients", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("/clients without keeper.token = %d", rr.Code)
+	}
+	s.cfg.Keeper.Token = "secret"
+	req := httptest.NewRequest(http.MethodGet, "/clients", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr = httptest.NewRecorder()
+	s.handleClients(rr, req)
+	body := rr.Body.String()
+	if rr.Code != http.StatusOK || strings.Index(body, "wecom-corp") > strings.Index(body, "bob-laptop") {
+		t.Fatalf("/clients = %d %s", rr.Code, body)
+	}
+	// Session IDs are credentials for /webhook and resume.
+	if strings.Contains(body, "s-corp") || strings.Contains(body, "session") {
+		t.Fatalf("/clients leaks session IDs: %s", body)
+	}
+}
+
+func TestKeeperRefusesALiveUserID(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}, clients: map[string]*cocoClient{}}
+	first, _ := wsPair(t)
+	alice := &cocoClient{conn: first, userID: "alice", platform: "wecom", sessionID: "s1", connectedAt: time.Now()}
+	if err := s.registerClient(alice); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := wsPair(t)
+	impostor := &cocoClient{conn: second, userID: "alice", platform: "wecom", sessionID: "s2", connectedAt: time.Now()}
+	if err := s.registerClient(impostor); !errors.Is(err, errClientIDInUse) {
+		t.Fatalf("second alice = %v", err)
+	}
+	if !s.clientIDInUse("wecom", "alice") || s.routeClient("wecom", "alice") != alice {
+		t.Fatal("the live coco lost its user-id")
+	}
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+)
+
+// keeperActivityLimit bounds each history the dashboard keeps in memory.
+const keeperActivityLimit = 100
+
+// keeperActivity records what keeper did recently for /dashboard. It only
+// lives in memory; a restart starts from empty.
+type keeperActivity struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	messages  []keeperMessageEvent
+	failovers []keeperFailoverEvent
+	stats     keeperFallbackStats
+}
+
+type keeperMessageEvent struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // "in" from WeCom, "out" to WeCom
+	UserID    string    `json:"user_id"`
+	Text      string    `json:"text"`
+	Route     string    `json:"route"` // "coco", "buffered", "fallback", "reply", "cron", "broadcast", "spam"
+}
+
+// keeperFailoverEvent is one message keeper answered itself because coco
+// could not take it.
+type keeperFailoverEvent struct {
+	Time     time.Time `json:"time"`
+	UserID   string    `json:"user_id"`
+	Reason   string    `json:"reason"`  // "coco offline", "forward failed" or "resume expired"
+	Outcome  string    `json:"outcome"` // "llm", "llm failed", "canned"
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Latency  int64     `json:"latency_ms"`
+}
+
+type keeperFallbackStats struct {
+	Forwarded     int `json:"forwarded"`
+	Offline       int `json:"offline"`
+	ForwardFailed int `json:"forward_failed"`
+	LLMReplies    int `json:"llm_replies"`
+	LLMFailures   int `json:"llm_failures"`
+	CannedReplies int `json:"canned_replies"`
+}
+
+func newKeeperActivity() *keeperActivity {
+	return &keeperActivity{startedAt: time.Now()}
+}
+
+func (a *keeperActivity) message(direction, userID, text, route string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = appendBounded(a.messages, keeperMessageEvent{
+		Time: time.Now(), Direction: direction, UserID: userID, Text: truncate(text, 160), Route: route,
+	})
+	if direction == "in" && route == "coco" {
+		a.stats.Forwarded++
+	}
+}
+
+func (a *keeperActivity) failover(ev keeperFailoverEvent) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failovers = appendBounded(a.failovers, ev)
+	switch ev.Reason {
+	case "coco offline", "resume expired":
+		a.stats.Offline++
+	case "forward failed":
+		a.stats.ForwardFailed++
+	}
+	switch ev.Outcome {
+	case "llm":
+		a.stats.LLMReplies++
+	case "llm failed":
+		a.stats.LLMFailures++
+		a.stats.CannedReplies++
+	default:
+		a.stats.CannedReplies++
+	}
+}
+
+func appendBounded[T any](list []T, item T) []T {
+	list = append(list, item)
+	if len(list) > keeperActivityLimit {
+		list = list[len(list)-keeperActivityLimit:]
+	}
+	return list
+}
+
+type keeperClientView struct {
+	UserID      string    `json:"user_id"`
+	Platform    string    `json:"platform"`
+	RemoteAddr  string    `json:"remote_addr"`
+	State       string    `json:"state"` // "online" or "resuming"
+	ConnectedAt time.Time `json:"connected_at"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+type keeperJobView struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Tag       string     `json:"tag,omitempty"`
+	Type      string     `json:"type,omitempty"`
+	Schedule  string     `json:"schedule"`
+	UserID    string     `json:"user_id,omitempty"`
+	Enabled   bool       `json:"enabled"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	FailCount int        `json:"fail_count,omitempty"`
+}
+
+type keeperDashboardState struct {
+	StartedAt time.Time             `json:"started_at"`
+	UptimeSec int64                 `json:"uptime_sec"`
+	Clients   []keeperClientView    `json:"clients"`
+	Messages  []keeperMessageEvent  `json:"messages"`
+	Failovers []keeperFailoverEvent `json:"failovers"`
+	Fallback  keeperFallbackStats   `json:"fallback"`
+	Cron      *[]keeperJobView      `json:"cron"` // nil when the scheduler is unavailable
+}
+
+// dashboardState collects everything /dashboard shows, newest events first.
+func (s *keeperServer) dashboardState() keeperDashboardState {
+	a := s.activity
+	a.mu.Lock()
+	state := keeperDashboardState{
+		StartedAt: a.startedAt,
+		UptimeSec: int64(time.Since(a.startedAt).Seconds()),
+		Messages:  reversed(a.messages),
+		Failovers: reversed(a.failovers),
+		Fallback:  a.stats,
+		Clients:   []keeperClientView{},
+	}
+	a.mu.Unlock()
+
+	for _, c := range s.listClients() {
+		state.Clients = append(state.Clients, c.view())
+	}
+
+	if s.heartbeatScheduler != nil {
+		jobs := []keeperJobView{}
+		for _, job := range s.heartbeatScheduler.ListJobs() {
+			jobs = append(jobs, keeperJobViewOf(job))
+		}
+		state.Cron = &jobs
+	}
+	return state
+}
+
+func keeperJobViewOf(job *cronpkg.Job) keeperJobView {
+	return keeperJobView{
+		ID: job.ID, Name: job.Name, Tag: job.Tag, Type: job.Type, Schedule: job.Schedule,
+		UserID: job.UserID, Enabled: job.Enabled, LastRun: job.LastRun, LastError: job.LastError, FailCount: job.FailCount,
+	}
+}
+
+func reversed[T any](list []T) []T {
+	out := make([]T, len(list))
+	for i, item := range list {
+		out[len(list)-1-i] = item
+	}
+	return out
+}
+
+// requireDashboardAuth guards the dashboard, which shows user IDs and
+// message text, with keeper.token. There is no local exemption: behind a
+// reverse proxy on the same host every request looks local.
+func (s *keeperServer) requireDashboardAuth(w http.ResponseWriter, r *http.Request) bool {
+	return s.requireKeeperToken(w, r)
+}
+
+func (s *keeperServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDashboardAuth(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte(keeperDashboardHTML))
+}
+
+func (s *keeperServer) handleDashboardState(w http.ResponseWriter, r *http.Request) {
+	if !s.requireDashboardAuth(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(s.dashboardState())
+}
+
+const keeperDashboardHTML = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>coco keeper</title>
+  <style>
+    body { font-family: "Segoe UI", sans-serif; margin: 0; background: linear-gradient(145deg,#f7fafc,#e9eef7); color: #1f2937; }
+    .wrap { max-width: 1100px; margin: 0 auto; padding: 20px; }
+    .panel { background: #fff; border-radius: 12px; box-shadow: 0 8px 30px rgba(15,23,42,.08); padding: 16px; margin-bottom: 16px; }
+    h2 { margin: 0 0 4px; } h3 { margin: 0 0 10px; font-size: 16px; }
+    .muted { color: #6b7280; font-size: 13px; }
+    table { width: 100%; border-collapse: collapse; font-size: 13px; }
+    th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e5e7eb; vertical-align: top; }
+    th { color: #6b7280; font-weight: 600; }
+    .stats { display: flex; gap: 12px; flex-wrap: wrap; }
+    .stat { background: #f9fafb; border: 1px solid #e5e7eb; border-radius: 8px; padding: 8px 12px; min-width: 110px; }
+    .stat b { display: block; font-size: 20px; }
+    .ok { color: #0f766e; } .bad { color: #b91c1c; }
+  </style>
+</head>
+<body>
+  <div class="wrap">
+    <div class="panel"><h2>coco keeper</h2><div class="muted" id="uptime">loading…</div></div>
+    <div class="panel"><h3>已连接的 coco</h3><div id="clients"></div></div>
+    <div class="panel"><h3>离线代答统计</h3><div class="stats" id="stats"></div></div>
+    <div class="panel"><h3>模型代答记录</h3><div id="failovers"></div></div>
+    <div class="panel"><h3>最近消息</h3><div id="messages"></div></div>
+    <div class="panel"><h3>定时任务</h3><div id="cron"></div></div>
+  </div>
+  <script>
+    const token = new URLSearchParams(location.search).get('token') || '';
+    const esc = (v) => String(v ?? '').replace(/[&<>"]/g, (c) => ({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;'}[c]));
+    const time = (v) => v ? new Date(v).toLocaleString() : '-';
+    const table = (rows, cols) => rows.length === 0 ? '<div class="muted">暂无</div>' :
+      '<table><tr>' + cols.map((c) => '<th>' + c[0] + '</th>').join('') + '</tr>' +
+      rows.map((r) => '<tr>' + cols.map((c) => '<td>' + c[1](r) + '</td>').join('') + '</tr>').join('') + '</table>';
+    function render(s) {
+      document.getElementById('uptime').textContent = '启动于 ' + time(s.started_at) + '，已运行 ' + Math.floor(s.uptime_sec / 60) + ' 分钟';
+      document.getElementById('clients').innerHTML = table(s.clients, [
+        ['用户', (c) => esc(c.user_id)], ['平台', (c) => esc(c.platform)], ['来源', (c) => esc(c.remote_addr)],
+['状态', (c) => c.state === 'online' ? '<span class="ok">在线</span>' : '<span class="bad">等待重连</span>'], ['连接于', (c) => time(c.connected_at)], ['最近活动', (c) => time(c.last_seen)]]);
+      const f = s.fallback;
+      document.getElementById('stats').innerHTML = [
+        ['转发给 coco', f.forwarded], ['coco 离线', f.offline], ['转发失败', f.forward_failed],
+        ['模型代答', f.llm_replies], ['模型失败', f.llm_failures], ['固定回复', f.canned_replies]]
+        .map((x) => '<div class="stat"><span class="muted">' + x[0] + '</span><b>' + x[1] + '</b></div>').join('');
+      document.getElementById('failovers').innerHTML = table(s.failovers, [
+        ['时间', (e) => time(e.time)], ['用户', (e) => esc(e.user_id)], ['原因', (e) => esc(e.reason)],
+        ['结果', (e) => '<span class="' + (e.outcome === 'llm' ? 'ok' : 'bad') + '">' + esc(e.outcome) + '</span>'],
+        ['模型', (e) => esc([e.provider, e.model].filter(Boolean).join('/'))], ['耗时', (e) => e.latency_ms + ' ms'], ['错误', (e) => esc(e.error)]]);
+      document.getElementById('messages').innerHTML = table(s.messages, [
+        ['时间', (m) => time(m.time)], ['方向', (m) => m.direction === 'in' ? '收' : '发'], ['用户', (m) => esc(m.user_id)],
+        ['路由', (m) => esc(m.route)], ['内容', (m) => esc(m.text)]]);
+      document.getElementById('cron').innerHTML = s.cron === null ? '<div class="muted">调度器不可用</div>' : table(s.cron, [
+        ['名称', (j) => esc(j.name)], ['计划', (j) => esc(j.schedule)], ['用户', (j) => esc(j.user_id)],
+        ['状态', (j) => j.enabled ? '<span class="ok">启用</span>' : '<span class="bad">暂停</span>'],
+        ['上次运行', (j) => time(j.last_run)], ['连续失败', (j) => j.fail_count || 0], ['上次错误', (j) => esc(j.last_error)]]);
+    }
+    async function refresh() {
+      try {
+        const resp = await fetch('/dashboard/api/state', { headers: token ? { 'Authorization': 'Bearer ' + token } : {} });
+        if (!resp.ok) throw new Error(resp.status + ' ' + (await resp.text()));
+        render(await resp.json());
+      } catch (e) {
+        document.getElementById('uptime').innerHTML = '<span class="bad">' + esc(e.message) + '</span>';
+      }
+    }
+    refresh();
+    setInterval(refresh, 5000);
+  </script>
+</body>
+</html>`
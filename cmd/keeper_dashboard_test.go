@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestKeeperActivityStatsAndBounds(t *testing.T) {
+	a := newKeeperActivity()
+	for i := 0; i < keeperActivityLimit+5; i++ {
+		a.message("in", "u1", fmt.Sprintf("msg %d", i), "coco")
+	}
+	a.failover(keeperFailoverEvent{UserID: "u1", Reason: "coco offline", Outcome: "llm"})
+	a.failover(keeperFailoverEvent{UserID: "u1", Reason: "forward failed", Outcome: "llm failed", Error: "timeout"})
+
+	if len(a.messages) != keeperActivityLimit || a.messages[0].Text != "msg 5" {
+		t.Fatalf("messages not bounded: len=%d first=%q", len(a.messages), a.messages[0].Text)
+	}
+	want := keeperFallbackStats{Forwarded: keeperActivityLimit + 5, Offline: 1, ForwardFailed: 1, LLMReplies: 1, LLMFailures: 1, CannedReplies: 1}
+	if a.stats != want {
+		t.Fatalf("stats = %+v, want %+v", a.stats, want)
+	}
+}
+
+func TestKeeperDashboardAuth(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}, activity: newKeeperActivity()}
+	s.activity.message("in", "u1", "hello", "fallback")
+
+	get := func(remote, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		s.handleDashboardState(rr, req)
+		return rr
+	}
+
+	// Without keeper.token nobody gets in, not even a local proxy.
+	if rr := get("127.0.0.1:4000", "/dashboard/api/state"); rr.Code != http.StatusForbidden {
+		t.Fatalf("local without token = %d", rr.Code)
+	}
+
+	s.cfg.Keeper.Token = "k33p"
+	if rr := get("127.0.0.1:4000", "/dashboard/api/state"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("token configured, none sent = %d", rr.Code)
+	}
+	rr := get("203.0.113.5:4000", "/dashboard/api/state?token=k33p")
+	var state keeperDashboardState
+	if err := json.Unmarshal(rr.Body.Bytes(), &state); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("remote with token = %d %s", rr.Code, rr.Body.String())
+	}
+	if len(state.Messages) != 1 || state.Cron != nil || state.Clients == nil {
+		t.Fatalf("state = %+v", state)
+	}
+}
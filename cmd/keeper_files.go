@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+)
+
+const (
+	keeperShareMaxBytes   = 2 << 30 // largest file shared through a download link
+	keeperShareQuota      = 4 << 30 // live shares per client; the oldest go first
+	keeperShareDefaultTTL = 24 * time.Hour
+	keeperShareMaxTTL     = 7 * 24 * time.Hour
+)
+
+var shareIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// keeperShares holds files coco shares as expiring download links, for
+// files too large for the chat platform. Links carry their expiry and an
+// HMAC of it, so they open from any chat client without a login but cannot
+// be extended or guessed.
+type keeperShares struct {
+	mu  sync.Mutex
+	dir string
+	key []byte // signs links; kept in <dir>/share.key
+
+	quota int64 // bytes of live shares per owner
+}
+
+// keeperShare is the sidecar of a shared file.
+type keeperShare struct {
+	Name    string    `json:"name"`
+	Expires time.Time `json:"expires"`
+	Owner   string    `json:"owner,omitempty"` // user-id of the coco that shared it
+	Created time.Time `json:"created,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+}
+
+func newKeeperShares(dir string) *keeperShares {
+	return &keeperShares{dir: dir, quota: keeperShareQuota}
+}
+
+// signingKeyLocked loads the link signing key, creating it on first use.
+func (k *keeperShares) signingKeyLocked() ([]byte, error) {
+	if k.key != nil {
+		return k.key, nil
+	}
+	path := filepath.Join(k.dir, "share.key")
+	if raw, err := os.ReadFile(path); err == nil {
+		if key, err := hex.DecodeString(strings.TrimSpace(string(raw))); err == nil && len(key) == 32 {
+			k.key = key
+			return key, nil
+		}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0o600); err != nil {
+		return nil, err
+	}
+	k.key = key
+	return key, nil
+}
+
+func shareSignature(key []byte, id string, exp int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s/%d", id, exp)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// signedPath is the download path of a share, valid until its expiry.
+func (k *keeperShares) signedPath(id string, sh keeperShare) (string, error) {
+	k.mu.Lock()
+	key, err := k.signingKeyLocked()
+	k.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	exp := sh.Expires.Unix()
+	return fmt.Sprintf("/files/%s/%s?exp=%d&sig=%s", id, url.PathEscape(sh.Name), exp, shareSignature(key, id, exp)), nil
+}
+
+// verify reports whether exp and sig are a live signature for id.
+func (k *keeperShares) verify(id, exp, sig string) bool {
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	k.mu.Lock()
+	key, err := k.signingKeyLocked()
+	k.mu.Unlock()
+	return err == nil && hmac.Equal([]byte(sig), []byte(shareSignature(key, id, expires)))
+}
+
+func (k *keeperShares) paths(id string) (data, meta string) {
+	return filepath.Join(k.dir, id+".bin"), filepath.Join(k.dir, id+".json")
+}
+
+// expireLocked removes shares past their expiry.
+func (k *keeperShares) expireLocked(now time.Time) {
+	metas, _ := filepath.Glob(filepath.Join(k.dir, "*.json"))
+	for _, meta := range metas {
+		id := strings.TrimSuffix(filepath.Base(meta), ".json")
+		if sh, err := k.readLocked(id); err != nil || now.After(sh.Expires) {
+			data, _ := k.paths(id)
+			os.Remove(data)
+			os.Remove(meta)
+		}
+	}
+}
+
+func (k *keeperShares) readLocked(id string) (keeperShare, error) {
+	var sh keeperShare
+	_, meta := k.paths(id)
+	raw, err := os.ReadFile(meta)
+	if err != nil {
+		return sh, err
+	}
+	err = json.Unmarshal(raw, &sh)
+	return sh, err
+}
+
+// evictLocked removes owner's oldest shares, other than keep, until the
+// rest fit in the quota.
+func (k *keeperShares) evictLocked(owner, keep string) {
+	type owned struct {
+		id string
+		sh keeperShare
+	}
+	var shares []owned
+	var total int64
+	metas, _ := filepath.Glob(filepath.Join(k.dir, "*.json"))
+	for _, meta := range metas {
+		id := strings.TrimSuffix(filepath.Base(meta), ".json")
+		if sh, err := k.readLocked(id); err == nil && sh.Owner == owner {
+			shares = append(shares, owned{id, sh})
+			total += sh.Size
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].sh.Created.Before(shares[j].sh.Created) })
+	for _, o := range shares {
+		if total <= k.quota {
+			return
+		}
+		if o.id == keep {
+			continue
+		}
+		data, meta := k.paths(o.id)
+		os.Remove(data)
+		os.Remove(meta)
+		total -= o.sh.Size
+		logger.Info("[Keeper] Removed share %s of %s to stay within its quota", o.sh.Name, owner)
+	}
+}
+
+// add stores body as name for ttl on behalf of owner and returns the
+// share's ID. owner's older shares are removed past the quota.
+func (k *keeperShares) add(owner, name string, ttl time.Duration, body io.Reader) (string, keeperShare, int64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := time.Now()
+	k.expireLocked(now)
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return "", keeperShare{}, 0, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", keeperShare{}, 0, err
+	}
+	id := hex.EncodeToString(buf)
+	data, meta := k.paths(id)
+	f, err := os.OpenFile(data, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", keeperShare{}, 0, err
+	}
+	n, err := io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	sh := keeperShare{Name: name, Expires: now.Add(ttl), Owner: owner, Created: now, Size: n}
+	if err == nil {
+		var raw []byte
+		raw, _ = json.Marshal(sh)
+		err = os.WriteFile(meta, raw, 0o600)
+	}
+	if err != nil {
+		os.Remove(data)
+		return "", keeperShare{}, 0, err
+	}
+	k.evictLocked(owner, id)
+	return id, sh, n, nil
+}
+
+// open returns a live share's file and sidecar.
+func (k *keeperShares) open(id string) (*os.File, keeperShare, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.expireLocked(time.Now())
+	sh, err := k.readLocked(id)
+	if err != nil {
+		return nil, sh, err
+	}
+	data, _ := k.paths(id)
+	f, err := os.Open(data)
+	return f, sh, err
+}
+
+// handleShareUpload stores a file from coco and answers with its download
+// path: POST /api/files?name=report.zip&ttl=24h. It needs a live coco
+// session (X-Session-ID) or keeper.token, so an open Keeper is not
+// anonymous file hosting.
+func (s *keeperServer) handleShareUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var owner string
+	if sessionID := r.Header.Get("X-Session-ID"); sessionID != "" && s.clientBySession(sessionID) != nil {
+		owner = s.clientBySession(sessionID).userID
+	} else {
+		if !s.requireKeeperToken(w, r) {
+			return
+		}
+		owner = strings.TrimSpace(r.Header.Get("X-User-ID"))
+	}
+	name := filepath.Base(strings.TrimSpace(r.URL.Query().Get("name")))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	ttl := keeperShareDefaultTTL
+	if v := strings.TrimSpace(r.URL.Query().Get("ttl")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = min(d, keeperShareMaxTTL)
+	}
+	if r.ContentLength > keeperShareMaxBytes {
+		http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	id, sh, size, err := s.shares.add(owner, name, ttl, http.MaxBytesReader(w, r.Body, keeperShareMaxBytes))
+	if err != nil {
+		logger.Warn("[Keeper] Sharing %s failed: %v", name, err)
+		http.Error(w, "store failed", http.StatusBadRequest)
+		return
+	}
+	path, err := s.shares.signedPath(id, sh)
+	if err != nil {
+		logger.Warn("[Keeper] Signing the link for %s failed: %v", name, err)
+		http.Error(w, "store failed", http.StatusInternalServerError)
+		return
+	}
+	logger.Info("[Keeper] Shared %s (%d bytes) until %s", name, size, sh.Expires.Format(time.RFC3339))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"path":       path,
+		"expires_at": sh.Expires.Format(time.RFC3339),
+		"size":       size,
+	})
+}
+
+// handleShareDownload serves GET /files/<id>/<name>?exp=&sig= until the
+// share expires.
+func (s *keeperServer) handleShareDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/files/"), "/")
+	if !shareIDPattern.MatchString(id) {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.shares.verify(id, r.URL.Query().Get("exp"), r.URL.Query().Get("sig")) {
+		http.Error(w, "link expired or invalid", http.StatusForbidden)
+		return
+	}
+	f, sh, err := s.shares.open(id)
+	if err != nil {
+		http.Error(w, "link expired or not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": sh.Name}))
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(time.Until(sh.Expires).Seconds())))
+	http.ServeContent(w, r, sh.Name, time.Time{}, f)
+}
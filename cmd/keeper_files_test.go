@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestKeeperShareLink(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Keeper.Token = "secret"
+	dir := t.TempDir()
+	s := &keeperServer{cfg: cfg, shares: newKeeperShares(dir)}
+
+	upload := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/files?name=report.zip&ttl=1h", strings.NewReader("zip bytes"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		s.handleShareUpload(rr, req)
+		return rr
+	}
+	if rr := upload(""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("upload without token = %d", rr.Code)
+	}
+	rr := upload("secret")
+	var out struct {
+		Path      string `json:"path"`
+		ExpiresAt string `json:"expires_at"`
+		Size      int64  `json:"size"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil || rr.Code != http.StatusOK || out.Size != 9 {
+		t.Fatalf("upload = %d %s", rr.Code, rr.Body.String())
+	}
+	if expires, _ := time.Parse(time.RFC3339, out.ExpiresAt); time.Until(expires) > time.Hour || time.Until(expires) < 59*time.Minute {
+		t.Fatalf("expires_at = %s", out.ExpiresAt)
+	}
+
+	download := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		s.handleShareDownload(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	// The link needs no token: the random ID is the credential.
+	if rr := download(out.Path); rr.Code != http.StatusOK || rr.Body.String() != "zip bytes" ||
+		!strings.Contains(rr.Header().Get("Content-Disposition"), `filename=report.zip`) {
+		t.Fatalf("download = %d %q %q", rr.Code, rr.Body.String(), rr.Header().Get("Content-Disposition"))
+	}
+	if rr := download("/files/../keeper.yaml"); rr.Code != http.StatusNotFound {
+		t.Fatalf("bad id = %d", rr.Code)
+	}
+	// The expiry is signed: a link cannot be extended or opened without it.
+	u, _ := url.Parse(out.Path)
+	q := u.Query()
+	exp, _ := strconv.ParseInt(q.Get("exp"), 10, 64)
+	q.Set("exp", strconv.FormatInt(exp+3600, 10))
+	if rr := download(u.Path + "?" + q.Encode()); rr.Code != http.StatusForbidden {
+		t.Fatalf("extended link = %d", rr.Code)
+	}
+	if rr := download(u.Path); rr.Code != http.StatusForbidden {
+		t.Fatalf("unsigned link = %d", rr.Code)
+	}
+
+	// Rewind the expiry: the link stops working and the file is removed.
+	id := strings.Split(strings.TrimPrefix(u.Path, "/files/"), "/")[0]
+	raw, _ := json.Marshal(keeperShare{Name: "report.zip", Expires: time.Now().Add(-time.Minute)})
+	if err := os.WriteFile(filepath.Join(dir, id+".json"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if rr := download(out.Path); rr.Code != http.StatusNotFound {
+		t.Fatalf("expired download = %d", rr.Code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, id+".bin")); !os.IsNotExist(err) {
+		t.Fatalf("expired share kept its file: %v", err)
+	}
+}
+
+func TestKeeperShareUploadNeedsCredentialsAndKeepsQuota(t *testing.T) {
+	dir := t.TempDir()
+	s := &keeperServer{cfg: &config.Config{}, shares: newKeeperShares(dir)}
+	s.shares.quota = 12
+	s.clients = map[string]*cocoClient{
+		clientKey("wecom", "alice"): {userID: "alice", platform: "wecom", sessionID: "s-alice", connectedAt: time.Now()},
+	}
+	upload := func(body string, header map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/files?name=a.txt", strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		s.handleShareUpload(rr, req)
+		return rr.Code
+	}
+
+	// No keeper.token: only a live coco session may upload.
+	if code := upload("x", nil); code != http.StatusForbidden {
+		t.Fatalf("anonymous upload = %d", code)
+	}
+	if code := upload("x", map[string]string{"X-Session-ID": "s-stale"}); code != http.StatusForbidden {
+		t.Fatalf("unknown session = %d", code)
+	}
+	for _, body := range []string{"first", "second", "third"} {
+		if code := upload(body, map[string]string{"X-Session-ID": "s-alice"}); code != http.StatusOK {
+			t.Fatalf("session upload = %d", code)
+		}
+	}
+	// 16 bytes against a 12 byte quota: the oldest share went.
+	var kept []string
+	metas, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, meta := range metas {
+		var sh keeperShare
+		raw, _ := os.ReadFile(meta)
+		json.Unmarshal(raw, &sh)
+		data, _ := os.ReadFile(strings.TrimSuffix(meta, ".json") + ".bin")
+		if sh.Owner != "alice" {
+			t.Fatalf("owner = %q", sh.Owner)
+		}
+		kept = append(kept, string(data))
+	}
+	if len(kept) != 2 || strings.Contains(strings.Join(kept, ","), "first") {
+		t.Fatalf("kept = %q", kept)
+	}
+
+	s.cfg.Keeper.Token = "secret"
+	if code := upload("x", map[string]string{"Authorization": "Bearer secret", "X-User-ID": "bob"}); code != http.StatusOK {
+		t.Fatalf("token upload = %d", code)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/fswatch"
+	"github.com/kayz/coco/internal/logger"
+)
+
+// keeperHeartbeatDirName is the workspace folder of per-user HEARTBEAT
+// overrides, one <user>.md each.
+const keeperHeartbeatDirName = "heartbeats"
+
+// heartbeatSpecSettle lets an editor finish saving before the spec is read.
+const heartbeatSpecSettle = time.Second
+
+func keeperHeartbeatOverridePath(userID string) string {
+	return filepath.Join(keeperWorkspaceDir(), keeperHeartbeatDirName, sanitizeHeartbeatTokenKeeper(userID)+".md")
+}
+
+// reloadHeartbeats syncs the heartbeat jobs of every user keeper knows of:
+// users seen or connected, and users who already have heartbeat jobs.
+func (s *keeperServer) reloadHeartbeats() {
+	if s == nil || s.heartbeatScheduler == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, id := range s.knownUsers() {
+		seen[id] = true
+	}
+	for _, job := range s.heartbeatScheduler.ListJobsByTag("heartbeat") {
+		if strings.HasPrefix(job.Name, "keeper-heartbeat:") && job.Platform == "wecom" && job.UserID != "" {
+			seen[job.UserID] = true
+		}
+	}
+	users := make([]string, 0, len(seen))
+	for id := range seen {
+		users = append(users, id)
+	}
+	sort.Strings(users)
+	for _, id := range users {
+		s.ensureHeartbeatJobsForUser(id)
+	}
+}
+
+// watchHeartbeatSpecs reloads heartbeat jobs when HEARTBEAT.md or a user
+// override is written. A deleted spec is noticed on the user's next
+// message, which syncs their jobs again.
+func (s *keeperServer) watchHeartbeatSpecs(ctx context.Context) {
+	if s.heartbeatScheduler == nil {
+		return
+	}
+	workspace := keeperWorkspaceDir()
+	overrides := filepath.Join(workspace, keeperHeartbeatDirName)
+	if err := os.MkdirAll(overrides, 0755); err != nil {
+		logger.Warn("[KeeperCron] Heartbeat hot reload disabled: %v", err)
+		return
+	}
+	w, err := fswatch.New(heartbeatSpecSettle)
+	if err != nil {
+		logger.Warn("[KeeperCron] Heartbeat hot reload disabled: %v", err)
+		return
+	}
+	for _, dir := range []string{workspace, overrides} {
+		if err := w.Add(dir); err != nil {
+			logger.Warn("[KeeperCron] Cannot watch %s: %v", dir, err)
+		}
+	}
+	go w.Run(ctx, func(path string) {
+		if filepath.Base(path) != "HEARTBEAT.md" && (filepath.Dir(path) != overrides || filepath.Ext(path) != ".md") {
+			return
+		}
+		logger.Info("[KeeperCron] %s changed, reloading heartbeat jobs", path)
+		s.reloadHeartbeats()
+	}, func(err error) {
+		logger.Warn("[KeeperCron] Heartbeat watcher: %v", err)
+	})
+}
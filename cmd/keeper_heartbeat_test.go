@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+)
+
+func newHeartbeatTestServer(t *testing.T) (*keeperServer, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", workspace)
+	store, err := cronpkg.NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	s := &keeperServer{cfg: &config.Config{}, heartbeatExecutor: &keeperPromptExecutor{}}
+	s.heartbeatScheduler = cronpkg.NewScheduler(store, nil, s.heartbeatExecutor, &keeperCronNotifier{server: s})
+	return s, workspace
+}
+
+func heartbeatJobs(s *keeperServer, userID string) []string {
+	var out []string
+	for _, j := range s.heartbeatScheduler.ListJobsByTag("heartbeat") {
+		if j.UserID == userID {
+			out = append(out, strings.TrimPrefix(j.Name, keeperHeartbeatJobPrefix(userID))+"="+strings.TrimSpace(j.Prompt[strings.Index(j.Prompt, "\n"):]))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func writeHeartbeat(t *testing.T, path, tasks string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte("---\ninterval: 30m\ntasks:\n"+tasks+"---\n\n# Heartbeat\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeeperHeartbeatJobsFollowSpec(t *testing.T) {
+	s, workspace := newHeartbeatTestServer(t)
+	shared := filepath.Join(workspace, "HEARTBEAT.md")
+	writeHeartbeat(t, shared, "  - {name: mail, prompt: check mail}\n  - {name: news, prompt: read news}\n")
+
+	s.ensureHeartbeatJobsForUser("alice")
+	if got := heartbeatJobs(s, "alice"); strings.Join(got, ",") != "mail=check mail,news=read news" {
+		t.Fatalf("initial jobs = %v", got)
+	}
+
+	// A changed task is replaced, a dropped one removed, a new one added.
+	writeHeartbeat(t, shared, "  - {name: mail, prompt: check urgent mail}\n  - {name: pr, prompt: review PRs}\n")
+	s.ensureHeartbeatJobsForUser("alice")
+	if got := heartbeatJobs(s, "alice"); strings.Join(got, ",") != "mail=check urgent mail,pr=review PRs" {
+		t.Fatalf("after edit = %v", got)
+	}
+
+	// A spec that does not parse leaves the jobs as they are.
+	os.WriteFile(shared, []byte("---\ntasks: [\n---\n"), 0o644)
+	s.ensureHeartbeatJobsForUser("alice")
+	if got := heartbeatJobs(s, "alice"); len(got) != 2 {
+		t.Fatalf("after broken edit = %v", got)
+	}
+}
+
+func TestKeeperHeartbeatUserOverride(t *testing.T) {
+	s, workspace := newHeartbeatTestServer(t)
+	writeHeartbeat(t, filepath.Join(workspace, "HEARTBEAT.md"), "  - {name: mail, prompt: check mail}\n")
+	s.noteUser("bob")
+	s.reloadHeartbeats()
+
+	rr := httptest.NewRecorder()
+	body := `{"user_id":"alice","content":"---\ntasks:\n  - {name: gym, schedule: '0 7 * * *', prompt: remind gym}\n---\n# Alice\n"}`
+	s.handleHeartbeatUpload(rr, httptest.NewRequest(http.MethodPost, "/api/heartbeat/upload", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload = %d %s", rr.Code, rr.Body)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, keeperHeartbeatDirName, "alice.md")); err != nil {
+		t.Fatalf("override not saved: %v", err)
+	}
+	if got := heartbeatJobs(s, "alice"); strings.Join(got, ",") != "gym=remind gym" {
+		t.Fatalf("alice = %v", got)
+	}
+	if got := heartbeatJobs(s, "bob"); strings.Join(got, ",") != "mail=check mail" {
+		t.Fatalf("bob = %v", got)
+	}
+}
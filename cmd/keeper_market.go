@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kayz/coco/internal/logger"
+	skillspkg "github.com/kayz/coco/internal/skills"
+	"gopkg.in/yaml.v3"
+)
+
+// marketKindDirs maps the top-level folders of the market directory to the
+// index entry kind they hold.
+var marketKindDirs = map[string]string{
+	"skills":    skillspkg.KindSkill,
+	"workflows": skillspkg.KindWorkflow,
+	"personas":  skillspkg.KindPersona,
+}
+
+var marketNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// keeperMarket serves the curated index of skills, workflow templates and
+// persona packs the operator puts in its directory:
+//
+//	<dir>/skills/<name>/<version>/SKILL.md ...
+//	<dir>/workflows/<name>/<version>/workflow.yaml
+//	<dir>/personas/<name>/<version>/SOUL.md ...
+//
+// The index is built from the directory on each request and signed with
+// the market key, so cocos pinning that key (skills.index_key) only install
+// what this keeper publishes.
+type keeperMarket struct {
+	mu  sync.Mutex
+	dir string
+	key ed25519.PrivateKey // kept in <dir>/market.key
+}
+
+func newKeeperMarket(dir string) *keeperMarket {
+	return &keeperMarket{dir: dir}
+}
+
+// signingKey loads the market key, creating it on first use.
+func (m *keeperMarket) signingKey() (ed25519.PrivateKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.key != nil {
+		return m.key, nil
+	}
+	path := filepath.Join(m.dir, "market.key")
+	if raw, err := os.ReadFile(path); err == nil {
+		if seed, err := hex.DecodeString(strings.TrimSpace(string(raw))); err == nil && len(seed) == ed25519.SeedSize {
+			m.key = ed25519.NewKeyFromSeed(seed)
+			return m.key, nil
+		}
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(seed)), 0o600); err != nil {
+		return nil, err
+	}
+	m.key = ed25519.NewKeyFromSeed(seed)
+	return m.key, nil
+}
+
+// publicKey is the base64 key cocos put in skills.index_key.
+func (m *keeperMarket) publicKey() (string, error) {
+	key, err := m.signingKey()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), nil
+}
+
+// index builds index.json from the market directory. Archive URLs are
+// relative to the index, so it works behind any host name.
+func (m *keeperMarket) index() ([]byte, error) {
+	var entries []skillspkg.IndexSkill
+	for folder, kind := range marketKindDirs {
+		names, err := os.ReadDir(filepath.Join(m.dir, folder))
+		if err != nil {
+			continue
+		}
+		for _, n := range names {
+			if !n.IsDir() || !marketNamePattern.MatchString(n.Name()) {
+				continue
+			}
+			if entry, ok := m.entry(folder, kind, n.Name()); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].EntryKind() < entries[j].EntryKind()
+	})
+	if entries == nil {
+		entries = []skillspkg.IndexSkill{}
+	}
+	return json.MarshalIndent(map[string]any{"skills": entries}, "", "  ")
+}
+
+func (m *keeperMarket) entry(folder, kind, name string) (skillspkg.IndexSkill, bool) {
+	entry := skillspkg.IndexSkill{Name: name, Kind: kind}
+	if kind == skillspkg.KindSkill {
+		entry.Kind = "" // plain skills stay readable by older cocos
+	}
+	versions, _ := os.ReadDir(filepath.Join(m.dir, folder, name))
+	latest := ""
+	for _, v := range versions {
+		dir := filepath.Join(m.dir, folder, name, v.Name())
+		if !v.IsDir() || !marketNamePattern.MatchString(v.Name()) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, skillspkg.KindMarker(kind))); err != nil {
+			logger.Warn("[Keeper] Market: %s/%s/%s has no %s, skipped", folder, name, v.Name(), skillspkg.KindMarker(kind))
+			continue
+		}
+		sum, err := skillspkg.DirChecksum(dir)
+		if err != nil {
+			logger.Warn("[Keeper] Market: checksum %s: %v", dir, err)
+			continue
+		}
+		entry.Versions = append(entry.Versions, skillspkg.IndexVersion{
+			Version: v.Name(),
+			URL:     folder + "/" + name + "/" + v.Name() + ".tar.gz",
+			SHA256:  sum,
+		})
+		if latest == "" || skillspkg.CompareVersions(v.Name(), latest) > 0 {
+			latest = v.Name()
+			entry.Description = marketDescription(kind, dir)
+		}
+	}
+	return entry, len(entry.Versions) > 0
+}
+
+// marketDescription takes an entry's description from its SKILL.md
+// front matter, the description of its workflow.yaml, or the first line
+// of text in a persona's README.md or SOUL.md.
+func marketDescription(kind, dir string) string {
+	switch kind {
+	case skillspkg.KindSkill:
+		if e, err := skillspkg.ParseSkillMD(filepath.Join(dir, "SKILL.md")); err == nil {
+			return e.Description
+		}
+	case skillspkg.KindWorkflow:
+		var wf struct {
+			Description string `yaml:"description"`
+		}
+		if raw, err := os.ReadFile(filepath.Join(dir, "workflow.yaml")); err == nil && yaml.Unmarshal(raw, &wf) == nil {
+			return wf.Description
+		}
+	case skillspkg.KindPersona:
+		for _, file := range []string{"README.md", "SOUL.md"} {
+			f, err := os.Open(filepath.Join(dir, file))
+			if err != nil {
+				continue
+			}
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") && line != "---" {
+					f.Close()
+					return line
+				}
+			}
+			f.Close()
+		}
+	}
+	return ""
+}
+
+// archive packs a version directory as .tar.gz with the files DirChecksum
+// covers.
+func (m *keeperMarket) archive(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (d.Name() == skillspkg.OriginFileName && filepath.Dir(path) == dir) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     filepath.ToSlash(rel),
+			Mode:     int64(info.Mode().Perm()),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	return buf.Bytes(), err
+}
+
+// handleMarket serves the market: index.json, index.json.sig, key, and
+// <kind>s/<name>/<version>.tar.gz. It is public like a package mirror;
+// what it serves is what the operator put in the market directory.
+func (s *keeperServer) handleMarket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	m := s.market
+	rest := strings.TrimPrefix(r.URL.Path, "/market/")
+	switch rest {
+	case "key":
+		key, err := m.publicKey()
+		if err != nil {
+			http.Error(w, "market key unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, key+"\n")
+		return
+	case "index.json", "index.json.sig":
+		data, err := m.index()
+		if err != nil {
+			http.Error(w, "failed to build index", http.StatusInternalServerError)
+			return
+		}
+		if rest == "index.json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+		key, err := m.signingKey()
+		if err != nil {
+			http.Error(w, "market key unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(skillspkg.SignIndex(data, key))
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || marketKindDirs[parts[0]] == "" || !marketNamePattern.MatchString(parts[1]) ||
+		!strings.HasSuffix(parts[2], ".tar.gz") || !marketNamePattern.MatchString(strings.TrimSuffix(parts[2], ".tar.gz")) {
+		http.NotFound(w, r)
+		return
+	}
+	dir := filepath.Join(m.dir, parts[0], parts[1], strings.TrimSuffix(parts[2], ".tar.gz"))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := m.archive(dir)
+	if err != nil {
+		logger.Warn("[Keeper] Market: archive %s: %v", dir, err)
+		http.Error(w, "failed to pack", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Write(data)
+}
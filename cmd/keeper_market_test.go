@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	skillspkg "github.com/kayz/coco/internal/skills"
+)
+
+func writeMarketFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeeperMarketIndexAndTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeMarketFile(t, filepath.Join(dir, "skills", "weather", "1.0.0", "SKILL.md"), "---\nname: weather\ndescription: forecasts\n---\nbody\n")
+	writeMarketFile(t, filepath.Join(dir, "workflows", "leave", "1.0.0", "workflow.yaml"), "description: old\ntemplate: v1\n")
+	writeMarketFile(t, filepath.Join(dir, "workflows", "leave", "1.1.0", "workflow.yaml"),
+		"description: leave requests\ntriggers: [请假]\nslots:\n  - name: days\n    prompt: 几天？\ntemplate: 已提交 {{days}} 天\n")
+	writeMarketFile(t, filepath.Join(dir, "personas", "tutor", "2.0.0", "README.md"), "# Tutor\n\nA patient tutor\n")
+	writeMarketFile(t, filepath.Join(dir, "personas", "tutor", "2.0.0", "SOUL.md"), "# Soul\nbe patient\n")
+	writeMarketFile(t, filepath.Join(dir, "personas", "tutor", "2.0.0", "memory", "response_style.md"), "short answers\n")
+	writeMarketFile(t, filepath.Join(dir, "personas", "broken", "1.0.0", "notes.md"), "no SOUL.md\n")
+
+	s := &keeperServer{market: newKeeperMarket(dir)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/market/", s.handleMarket)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/market/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	ctx := context.Background()
+	idx, err := skillspkg.LoadSignedIndex(ctx, srv.URL+"/market/index.json", string(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	// Another key does not accept this keeper's index.
+	other := newKeeperMarket(t.TempDir())
+	otherKey, _ := other.publicKey()
+	if _, err := skillspkg.LoadSignedIndex(ctx, srv.URL+"/market/index.json", otherKey); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Fatalf("foreign key err = %v", err)
+	}
+
+	if found := idx.Search(""); len(found) != 1 || found[0].Name != "weather" || found[0].Description != "forecasts" {
+		t.Fatalf("skills = %+v", found)
+	}
+	templates := idx.SearchKind("", skillspkg.KindPersona, skillspkg.KindWorkflow)
+	if len(templates) != 2 || templates[0].Name != "leave" || templates[0].Description != "leave requests" || templates[1].Description != "A patient tutor" {
+		t.Fatalf("templates = %+v", templates)
+	}
+
+	v, err := idx.Resolve("weather", "^1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, cleanup, err := idx.Fetch(ctx, "weather", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if entry.Name != "weather" {
+		t.Fatalf("skill = %+v", entry)
+	}
+
+	// A pin keeps the older workflow; no pin takes the newest.
+	pinned, done, err := fetchOnboardTemplates(ctx, idx, []string{"leave@~1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+	if pinned[0].version != "1.0.0" {
+		t.Fatalf("pinned = %+v", pinned)
+	}
+	fetched, done, err := fetchOnboardTemplates(ctx, idx, []string{"tutor", "workflow:leave"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	cfg := &config.Config{}
+	cfg.Intents.Workflows = []config.WorkflowConfig{{Name: "leave", Template: "old"}}
+	state := &onboardState{cfg: cfg, workspaceDir: t.TempDir()}
+	if err := applyOnboardTemplates(state, fetched); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(state.workspaceDir, "SOUL.md")); string(data) != "# Soul\nbe patient\n" {
+		t.Fatalf("SOUL.md = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(state.workspaceDir, "memory", "response_style.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(state.workspaceDir, "README.md")); !os.IsNotExist(err) {
+		t.Fatal("README.md of the pack was copied")
+	}
+	wf := cfg.Intents.Workflows
+	if len(wf) != 1 || wf[0].Template != "已提交 {{days}} 天" || len(wf[0].Slots) != 1 || wf[0].Triggers[0] != "请假" {
+		t.Fatalf("workflows = %+v", wf)
+	}
+
+	if _, _, err := fetchOnboardTemplates(ctx, idx, []string{"broken"}); err == nil {
+		t.Fatal("entry without its marker file was listed")
+	}
+}
+
+func TestKeeperMarketRejectsBadPaths(t *testing.T) {
+	s := &keeperServer{market: newKeeperMarket(t.TempDir())}
+	for _, path := range []string{"/market/skills/../market.key", "/market/market.key", "/market/tools/x/1.0.0.tar.gz"} {
+		rr := httptest.NewRecorder()
+		s.handleMarket(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("%s = %d", path, rr.Code)
+		}
+	}
+}
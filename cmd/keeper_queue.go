@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/offlinequeue"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+// keeperOfflineQueueTTL is the default for keeper.offline_queue_ttl. The
+// user already has keeper's fallback reply, so an answer much later than
+// this is more confusing than helpful.
+const keeperOfflineQueueTTL = time.Hour
+
+// initOfflineQueue opens the queue for messages that arrive while no coco
+// can take them. It shares keeper's database with the cron store.
+func (s *keeperServer) initOfflineQueue() {
+	ttl := keeperOfflineQueueTTL
+	if v := strings.TrimSpace(s.cfg.Keeper.OfflineQueueTTL); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Warn("[Keeper] Invalid keeper.offline_queue_ttl %q, using %s", v, ttl)
+		} else if d <= 0 {
+			logger.Info("[Keeper] Offline queue disabled")
+			return
+		} else {
+			ttl = d
+		}
+	}
+	dbPath := filepath.Join(keeperWorkspaceDir(), ".coco-keeper.db")
+	q, err := offlinequeue.Open(dbPath, ttl)
+	if err != nil {
+		logger.Warn("[Keeper] Failed to open offline queue: %v", err)
+		return
+	}
+	s.queue = q
+	if n, err := q.Prune(); err == nil && n > 0 {
+		logger.Info("[Keeper] Dropped %d expired offline message(s)", n)
+	}
+	logger.Info("[Keeper] Offline queue ready (ttl %s)", ttl)
+}
+
+// enqueueOffline keeps msg for the coco that will serve its user.
+func (s *keeperServer) enqueueOffline(msg relay.IncomingMessage) {
+	if s.queue == nil {
+		return
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		logger.Error("[Keeper] Failed to encode offline message: %v", err)
+		return
+	}
+	added, err := s.queue.Enqueue(msg.ID, msg.Platform, msg.UserID, payload)
+	switch {
+	case err != nil:
+		logger.Error("[Keeper] Failed to queue message from %s: %v", msg.UserID, err)
+	case added:
+		logger.Info("[Keeper] Queued message from %s until its coco reconnects", msg.UserID)
+	default:
+		logger.Trace("[Keeper] Message %s from %s already queued", msg.ID, msg.UserID)
+	}
+}
+
+// replayOffline sends c the queued messages it now serves, oldest first.
+func (s *keeperServer) replayOffline(c *cocoClient) {
+	if s.queue == nil {
+		return
+	}
+	items, err := s.queue.Pending()
+	if err != nil {
+		logger.Error("[Keeper] Failed to read offline queue: %v", err)
+		return
+	}
+	replayed := 0
+	for _, it := range items {
+		if s.routeClient(it.Platform, it.UserID) != c {
+			continue
+		}
+		var msg relay.IncomingMessage
+		if err := json.Unmarshal(it.Payload, &msg); err != nil {
+			logger.Warn("[Keeper] Dropping unreadable offline message %d: %v", it.ID, err)
+			s.queue.Remove(it.ID)
+			continue
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = map[string]string{}
+		}
+		// coco tells the model when the message was really sent and that
+		// keeper already answered it.
+		msg.Metadata["queued_at"] = it.QueuedAt.Format(time.RFC3339)
+		// Once handed to the session it is the session's to redeliver.
+		c.deliver(msg)
+		s.queue.Remove(it.ID)
+		replayed++
+	}
+	if replayed > 0 {
+		logger.Info("[Keeper] Delivered %d queued message(s) to coco %s", replayed, c.userID)
+	}
+}
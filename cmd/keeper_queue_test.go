@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/offlinequeue"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+func TestKeeperOfflineQueueReplay(t *testing.T) {
+	q, err := offlinequeue.Open(filepath.Join(t.TempDir(), "keeper.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	s := &keeperServer{cfg: &config.Config{}, clients: map[string]*cocoClient{}, queue: q}
+	s.cfg.Keeper.Routes = map[string]string{"bob": "bob-pc"}
+
+	s.enqueueOffline(relay.IncomingMessage{Type: "message", ID: "m1", Platform: "wecom", UserID: "alice", Text: "first"})
+	s.enqueueOffline(relay.IncomingMessage{Type: "message", ID: "m1", Platform: "wecom", UserID: "alice", Text: "first"})
+	s.enqueueOffline(relay.IncomingMessage{Type: "message", ID: "m2", Platform: "wecom", UserID: "bob", Text: "for bob"})
+	s.enqueueOffline(relay.IncomingMessage{Type: "message", ID: "m3", Platform: "wecom", UserID: "carol", Text: "second"})
+
+	conn, coco := wsPair(t)
+	c := &cocoClient{conn: conn, userID: "alice", platform: "wecom", sessionID: "s1", connectedAt: time.Now()}
+	s.registerClient(c)
+	s.replayOffline(c)
+
+	var got relay.IncomingMessage
+	coco.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := coco.ReadJSON(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "first" || got.Metadata["queued_at"] == "" {
+		t.Fatalf("replayed = %+v", got)
+	}
+	// carol has no coco of her own and no default_client: not alice's to read.
+	items, _ := q.Pending()
+	if len(items) != 2 || items[0].UserID != "bob" || items[1].UserID != "carol" {
+		t.Fatalf("left in queue = %+v", items)
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+const (
+	// keeperResumeGrace is how long keeper keeps a disconnected coco's
+	// session, buffering its messages, before answering them itself.
+	keeperResumeGrace = 2 * time.Minute
+	// keeperOutboxLimit bounds the messages kept per session for redelivery.
+	keeperOutboxLimit = 100
+)
+
+// outboxEntry is a message sent (or waiting to be sent) to a coco client.
+// Delivered entries are kept too: a write can succeed just before the
+// connection dies, and the client reports what it actually received.
+type outboxEntry struct {
+	msg       relay.IncomingMessage
+	delivered bool
+}
+
+// deliver numbers msg and sends it, or buffers it while the client is
+// reconnecting. It reports whether the message went out now.
+func (c *cocoClient) deliver(msg relay.IncomingMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	msg.Seq = c.seq
+	entry := outboxEntry{msg: msg}
+	if c.conn != nil {
+		c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := c.conn.WriteJSON(msg); err != nil {
+			logger.Warn("[Keeper] Send to coco %s failed, buffering until it reconnects: %v", c.userID, err)
+		} else {
+			entry.delivered = true
+		}
+	}
+	c.outbox = append(c.outbox, entry)
+	if len(c.outbox) > keeperOutboxLimit {
+		c.outbox = c.outbox[len(c.outbox)-keeperOutboxLimit:]
+	}
+	return entry.delivered
+}
+
+// attach hands the session to a new connection, sends everything after
+// lastSeq that the client has not seen, and returns the connection it
+// replaced, if the old one was still open.
+func (c *cocoClient) attach(conn *websocket.Conn, remoteAddr string, lastSeq int64) (replaced *websocket.Conn, replayed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	replaced, c.conn, c.remoteAddr = c.conn, conn, remoteAddr
+	for i := range c.outbox {
+		e := &c.outbox[i]
+		if e.msg.Seq <= lastSeq {
+			e.delivered = true
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := conn.WriteJSON(e.msg); err != nil {
+			logger.Warn("[Keeper] Replay to coco %s failed: %v", c.userID, err)
+			break
+		}
+		e.delivered = true
+		replayed++
+	}
+	return replaced, replayed
+}
+
+// detach marks the session as waiting for a reconnect if conn is still the
+// client's connection, and calls expire once the grace period runs out.
+// It reports false when the session has already moved on to another
+// connection (resumed, or replaced by a newer client).
+func (c *cocoClient) detach(conn *websocket.Conn, expire func()) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != conn {
+		return false
+	}
+	c.conn = nil
+	c.expiry = time.AfterFunc(keeperResumeGrace, expire)
+	return true
+}
+
+// close ends the session for good: it stops the resume timer, marks the
+// client gone, and returns the messages it never received.
+func (c *cocoClient) close() (conn *websocket.Conn, pending []relay.IncomingMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	conn, c.conn = c.conn, nil
+	c.closed = true
+	for _, e := range c.outbox {
+		if !e.delivered {
+			pending = append(pending, e.msg)
+		}
+	}
+	c.outbox = nil
+	return conn, pending
+}
+
+// online reports whether the client currently has a live connection.
+func (c *cocoClient) online() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// resumableClient returns the registered client whose session auth asks to
+// resume, or nil if that session is unknown or expired.
+func (s *keeperServer) resumableClient(auth relay.AuthMessage) *cocoClient {
+	if auth.ResumeSession == "" {
+		return nil
+	}
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	c := s.clients[clientKey(auth.Platform, auth.UserID)]
+	if c == nil || c.sessionID != auth.ResumeSession {
+		return nil
+	}
+	return c
+}
+
+// expireClient drops a session whose coco did not come back in time and
+// answers the messages buffered for it with the offline fallback.
+func (s *keeperServer) expireClient(c *cocoClient) {
+	key := clientKey(c.platform, c.userID)
+	s.clientMu.Lock()
+	if s.clients[key] != c || c.online() {
+		s.clientMu.Unlock()
+		return
+	}
+	delete(s.clients, key)
+	s.clientMu.Unlock()
+
+	_, pending := c.close()
+	logger.Info("[Keeper] coco session expired: user=%s, session=%s, %d buffered message(s) answered by fallback",
+		c.userID, c.sessionID, len(pending))
+	for _, msg := range pending {
+		s.enqueueOffline(msg)
+		reply := s.buildOfflineReply(msg.UserID, msg.Text, "resume expired")
+		s.activity.message("out", msg.UserID, reply, "fallback")
+		if err := s.sendWeComReply(msg.UserID, reply); err != nil {
+			logger.Error("[Keeper] Failed to send fallback reply: %v", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+// wsPair returns the keeper side and the coco side of a WebSocket connection.
+func wsPair(t *testing.T) (server, client *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- c
+	}))
+	t.Cleanup(srv.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = <-conns
+	t.Cleanup(func() { server.Close(); client.Close() })
+	return server, client
+}
+
+func readSeqs(t *testing.T, conn *websocket.Conn, n int) []int64 {
+	t.Helper()
+	var seqs []int64
+	for i := 0; i < n; i++ {
+		var msg relay.IncomingMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read message %d: %v", i, err)
+		}
+		seqs = append(seqs, msg.Seq)
+	}
+	return seqs
+}
+
+func TestKeeperSessionResume(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}, clients: map[string]*cocoClient{}}
+	first, coco := wsPair(t)
+	c := &cocoClient{conn: first, userID: "alice", platform: "wecom", sessionID: "s1", connectedAt: time.Now()}
+	s.registerClient(c)
+
+	if !c.deliver(relay.IncomingMessage{Type: "message", Text: "one"}) {
+		t.Fatal("deliver on a live connection failed")
+	}
+	if got := readSeqs(t, coco, 1); got[0] != 1 {
+		t.Fatalf("seq = %v", got)
+	}
+
+	// The connection drops; messages are kept for the session.
+	expired := make(chan struct{})
+	if !c.detach(first, func() { close(expired) }) {
+		t.Fatal("detach of the current connection refused")
+	}
+	if c.detach(first, func() {}) {
+		t.Fatal("second detach of the same connection accepted")
+	}
+	if c.deliver(relay.IncomingMessage{Type: "message", Text: "two"}) {
+		t.Fatal("deliver while detached reported sent")
+	}
+	c.deliver(relay.IncomingMessage{Type: "message", Text: "three"})
+
+	if got := s.resumableClient(relay.AuthMessage{UserID: "alice", Platform: "wecom", ResumeSession: "other"}); got != nil {
+		t.Fatal("resumed an unknown session")
+	}
+	if got := s.resumableClient(relay.AuthMessage{UserID: "alice", Platform: "wecom", ResumeSession: "s1"}); got != c {
+		t.Fatalf("resumableClient = %v", got)
+	}
+
+	// coco comes back having seen seq 1 and gets the rest, in order.
+	second, coco2 := wsPair(t)
+	if replaced, n := c.attach(second, "127.0.0.1:1", 1); replaced != nil || n != 2 {
+		t.Fatalf("attach replaced=%v replayed=%d", replaced, n)
+	}
+	if got := readSeqs(t, coco2, 2); got[0] != 2 || got[1] != 3 {
+		t.Fatalf("replayed seqs = %v", got)
+	}
+	select {
+	case <-expired:
+		t.Fatal("expiry fired after resume")
+	default:
+	}
+	if !c.online() || c.view().State != "online" {
+		t.Fatal("resumed client not online")
+	}
+}
+
+func TestKeeperSessionReplaceMovesPending(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}, clients: map[string]*cocoClient{}}
+	first, _ := wsPair(t)
+	old := &cocoClient{conn: first, userID: "alice", platform: "wecom", sessionID: "s1", connectedAt: time.Now()}
+	s.registerClient(old)
+	old.detach(first, func() {})
+	old.deliver(relay.IncomingMessage{Type: "message", Text: "waiting"})
+	if old.view().State != "resuming" {
+		t.Fatalf("state = %q", old.view().State)
+	}
+
+	conn, coco := wsPair(t)
+	c := &cocoClient{conn: conn, userID: "alice", platform: "wecom", sessionID: "s2", connectedAt: time.Now()}
+	s.registerClient(c)
+	if got := readSeqs(t, coco, 1); got[0] != 1 {
+		t.Fatalf("pending message seq on new session = %v", got)
+	}
+	if s.resumableClient(relay.AuthMessage{UserID: "alice", Platform: "wecom", ResumeSession: "s1"}) != nil {
+		t.Fatal("replaced session still resumable")
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+)
+
+// Defaults for keeper.spam.
+const (
+	keeperSpamMaxPerMinute = 20
+	keeperSpamMaxRepeats   = 3
+	keeperSpamBanAfter     = 5
+	keeperSpamBanDuration  = 30 * time.Minute
+)
+
+// Reasons keeperSpamFilter drops a message for.
+const (
+	spamBanned  = "banned"
+	spamFlood   = "flood"
+	spamRepeat  = "repeat"
+	spamBlocked = "blocked-url"
+)
+
+var spamHostPattern = regexp.MustCompile(`(?i)(?:https?://)?((?:[a-z0-9-]+\.)+[a-z]{2,})`)
+
+// keeperSpamFilter drops floods, repeated messages and blacklisted links
+// from public WeCom users before they reach coco, and bans users who keep
+// at it for a while.
+type keeperSpamFilter struct {
+	maxPerMinute int
+	maxRepeats   int
+	banAfter     int
+	banFor       time.Duration
+	blocked      []string
+
+	mu        sync.Mutex
+	users     map[string]*spamUser
+	lastSweep time.Time
+}
+
+type spamUser struct {
+	recent      []time.Time // messages in the last minute
+	lastText    string
+	repeats     int // times lastText came in a row
+	strikes     int // dropped messages since the last ban
+	lastStrike  time.Time
+	bannedUntil time.Time
+}
+
+// newKeeperSpamFilter returns nil when keeper.spam.disabled is set.
+func newKeeperSpamFilter(cfg config.KeeperSpamConfig) *keeperSpamFilter {
+	if cfg.Disabled {
+		return nil
+	}
+	f := &keeperSpamFilter{
+		maxPerMinute: spamLimit(cfg.MaxPerMinute, keeperSpamMaxPerMinute),
+		maxRepeats:   spamLimit(cfg.MaxRepeats, keeperSpamMaxRepeats),
+		banAfter:     spamLimit(cfg.BanAfter, keeperSpamBanAfter),
+		banFor:       keeperSpamBanDuration,
+		users:        map[string]*spamUser{},
+	}
+	if v := strings.TrimSpace(cfg.BanDuration); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			f.banFor = d
+		} else {
+			logger.Warn("[Keeper] Invalid keeper.spam.ban_duration %q, using %s", v, f.banFor)
+		}
+	}
+	for _, d := range cfg.BlockedDomains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+		if d != "" {
+			f.blocked = append(f.blocked, d)
+		}
+	}
+	return f
+}
+
+// spamLimit is v, def when v is zero, or 0 (off) when v is negative.
+func spamLimit(v, def int) int {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return 0
+	default:
+		return v
+	}
+}
+
+// check reports why the message should be dropped, or "" to relay it.
+// banned is true when this message started the user's ban.
+func (f *keeperSpamFilter) check(userID, text string, now time.Time) (reason string, banned bool) {
+	if f == nil {
+		return "", false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sweep(now)
+
+	u := f.users[userID]
+	if u == nil {
+		u = &spamUser{}
+		f.users[userID] = u
+	}
+	if now.Before(u.bannedUntil) {
+		return spamBanned, false
+	}
+
+	cutoff := now.Add(-time.Minute)
+	kept := u.recent[:0]
+	for _, t := range u.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	u.recent = append(kept, now)
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	if normalized == u.lastText {
+		u.repeats++
+	} else {
+		u.lastText, u.repeats = normalized, 1
+	}
+
+	switch {
+	case f.blockedURL(text):
+		reason = spamBlocked
+	case f.maxPerMinute > 0 && len(u.recent) > f.maxPerMinute:
+		reason = spamFlood
+	case f.maxRepeats > 0 && u.repeats > f.maxRepeats:
+		reason = spamRepeat
+	default:
+		return "", false
+	}
+
+	// Strikes are forgiven once the user behaves for a ban's length.
+	if now.Sub(u.lastStrike) > f.banFor {
+		u.strikes = 0
+	}
+	u.strikes++
+	u.lastStrike = now
+	if f.banAfter > 0 && u.strikes >= f.banAfter {
+		u.bannedUntil = now.Add(f.banFor)
+		u.strikes = 0
+		return reason, true
+	}
+	return reason, false
+}
+
+func (f *keeperSpamFilter) blockedURL(text string) bool {
+	if len(f.blocked) == 0 {
+		return false
+	}
+	for _, m := range spamHostPattern.FindAllStringSubmatch(text, -1) {
+		host := strings.ToLower(m[1])
+		for _, d := range f.blocked {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sweep forgets users who have been quiet and unbanned for a while, at
+// most once a minute.
+func (f *keeperSpamFilter) sweep(now time.Time) {
+	if now.Sub(f.lastSweep) < time.Minute {
+		return
+	}
+	f.lastSweep = now
+	idle := max(f.banFor, time.Minute)
+	for id, u := range f.users {
+		last := u.lastStrike
+		if n := len(u.recent); n > 0 && u.recent[n-1].After(last) {
+			last = u.recent[n-1]
+		}
+		if now.Sub(last) > idle && !now.Before(u.bannedUntil) {
+			delete(f.users, id)
+		}
+	}
+}
+
+// dropSpam records a filtered message and, when it started a ban, tells
+// the user once so they know why keeper has gone quiet.
+func (s *keeperServer) dropSpam(userID, text, reason string, banned bool) {
+	s.activity.message("in", userID, text, "spam")
+	if !banned {
+		logger.Trace("[Keeper] Dropped message from %s (%s)", userID, reason)
+		return
+	}
+	logger.Warn("[Keeper] Banned %s for %s after repeated %s", userID, s.spam.banFor, reason)
+	notice := fmt.Sprintf("消息发送过于频繁或包含不允许的链接，%d 分钟内的消息将不再处理。", max(1, int(s.spam.banFor.Minutes())))
+	if err := s.sendWeComReply(userID, notice); err != nil {
+		logger.Error("[Keeper] Failed to send ban notice to %s: %v", userID, err)
+	}
+}
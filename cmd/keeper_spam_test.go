@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestKeeperSpamFilter(t *testing.T) {
+	f := newKeeperSpamFilter(config.KeeperSpamConfig{
+		MaxPerMinute:   5,
+		BlockedDomains: []string{"*.spam.example"},
+		BanAfter:       3,
+		BanDuration:    "10m",
+	})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	for i := 0; i < 3; i++ {
+		if reason, _ := f.check("alice", "你好", now); reason != "" {
+			t.Fatalf("greeting %d dropped: %s", i, reason)
+		}
+	}
+	if reason, _ := f.check("alice", " 你好 ", now); reason != spamRepeat {
+		t.Fatalf("fourth repeat = %q", reason)
+	}
+	if reason, _ := f.check("bob", "看这个 https://win.spam.example/prize", now); reason != spamBlocked {
+		t.Fatalf("blacklisted link = %q", reason)
+	}
+	if reason, _ := f.check("bob", "see notspam.example", now); reason != "" {
+		t.Fatalf("unrelated domain dropped: %s", reason)
+	}
+
+	// carol floods: messages past the fifth in a minute are dropped and the
+	// third dropped one bans her.
+	var banned bool
+	for i := 0; i < 8; i++ {
+		var reason string
+		reason, banned = f.check("carol", fmt.Sprintf("msg %d", i), now.Add(time.Duration(i)*time.Second))
+		if i < 5 && reason != "" {
+			t.Fatalf("message %d dropped: %s", i, reason)
+		}
+		if i >= 5 && reason != spamFlood {
+			t.Fatalf("message %d = %q", i, reason)
+		}
+	}
+	if !banned {
+		t.Fatal("carol was not banned")
+	}
+	if reason, banned := f.check("carol", "sorry", now.Add(5*time.Minute)); reason != spamBanned || banned {
+		t.Fatalf("during ban = %q, %v", reason, banned)
+	}
+	if reason, _ := f.check("carol", "sorry", now.Add(11*time.Minute)); reason != "" {
+		t.Fatalf("after ban = %q", reason)
+	}
+
+	if f := newKeeperSpamFilter(config.KeeperSpamConfig{Disabled: true}); f != nil {
+		t.Fatal("disabled filter was built")
+	}
+	var off *keeperSpamFilter
+	if reason, _ := off.check("dave", "hi", now); reason != "" {
+		t.Fatalf("nil filter = %q", reason)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/objstore"
+)
+
+func TestKeeperSyncKeepsNamespacesApart(t *testing.T) {
+	t.Setenv("COCO_WORKSPACE_DIR", t.TempDir())
+	cfg := &config.Config{}
+	cfg.Keeper.Token = "secret"
+	s := &keeperServer{cfg: cfg}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sync/blob", s.handleSyncBlob)
+	mux.HandleFunc("/api/sync/list", s.handleSyncList)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	store := func(namespace string) objstore.Store {
+		st, err := objstore.New(objstore.Config{Type: "keeper", Endpoint: srv.URL, Token: "secret", Namespace: namespace, Prefix: "coco-sync"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	ctx := context.Background()
+	alice := store(strings.Repeat("a", 32))
+	bob := store(strings.Repeat("b", 32))
+
+	if err := alice.Put(ctx, "manifest.enc", []byte("alice")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.Put(ctx, "manifest.enc", []byte("bob")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := alice.Get(ctx, "manifest.enc"); err != nil || string(data) != "alice" {
+		t.Fatalf("alice's manifest = %q, %v", data, err)
+	}
+	if _, err := alice.Get(ctx, "../"+strings.Repeat("b", 32)+"/coco-sync/manifest.enc"); err == nil {
+		t.Fatal("key escaped the namespace")
+	}
+	objects, err := bob.List(ctx, "")
+	if err != nil || len(objects) != 1 || objects[0].Key != "manifest.enc" || objects[0].Size != 3 {
+		t.Fatalf("bob's list = %+v, %v", objects, err)
+	}
+	if err := bob.Delete(ctx, "manifest.enc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Get(ctx, "manifest.enc"); err != nil {
+		t.Fatalf("bob's delete reached alice: %v", err)
+	}
+	if _, err := bob.Get(ctx, "manifest.enc"); !errors.Is(err, objstore.ErrNotFound) {
+		t.Fatalf("bob's manifest after delete: %v", err)
+	}
+
+	// A token alone, or a namespace that is not one, reaches nobody's blobs.
+	for _, namespace := range []string{"", "../" + strings.Repeat("a", 29), strings.Repeat("A", 32)} {
+		for _, path := range []string{"/api/sync/blob?key=coco-sync/manifest.enc", "/api/sync/list?prefix="} {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			if namespace != "" {
+				req.Header.Set("X-Sync-Namespace", namespace)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("namespace %q %s = %d", namespace, path, resp.StatusCode)
+			}
+		}
+	}
+}
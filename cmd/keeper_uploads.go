@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+const (
+	keeperUploadMaxBytes = 100 << 20 // largest file accepted through chunked upload
+	keeperUploadMaxChunk = 8 << 20   // largest single chunk
+	keeperUploadTTL      = time.Hour // unfinished uploads are dropped after this long idle
+)
+
+// keeperRelayFeatures are the optional relay protocol features keeper offers.
+var keeperRelayFeatures = []string{relay.FeatureGzip, relay.FeatureUpload}
+
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+var errUploadOffset = errors.New("upload offset mismatch")
+
+// keeperUploads holds files coco sends in chunks until the webhook that
+// references them arrives. Each upload belongs to the session that began it.
+type keeperUploads struct {
+	mu    sync.Mutex
+	dir   string
+	items map[string]*keeperUpload
+}
+
+type keeperUpload struct {
+	session  string
+	path     string
+	total    int64
+	received int64
+	updated  time.Time
+}
+
+func newKeeperUploads(dir string) *keeperUploads {
+	return &keeperUploads{dir: dir, items: make(map[string]*keeperUpload)}
+}
+
+// expireLocked removes uploads that stalled for longer than keeperUploadTTL.
+func (u *keeperUploads) expireLocked() {
+	for id, it := range u.items {
+		if time.Since(it.updated) > keeperUploadTTL {
+			os.Remove(it.path)
+			delete(u.items, id)
+		}
+	}
+}
+
+// status returns how much of upload id the session has sent.
+func (u *keeperUploads) status(session, id string) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if it := u.items[id]; it != nil && it.session == session {
+		return it.received
+	}
+	return 0
+}
+
+// write appends chunk at offset. A chunk at the wrong offset is refused
+// with errUploadOffset; the caller reports the current position instead.
+func (u *keeperUploads) write(session, id string, offset, total int64, chunk io.Reader) (int64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.expireLocked()
+
+	it := u.items[id]
+	if it != nil && it.session != session {
+		return 0, fmt.Errorf("upload %s belongs to another session", id)
+	}
+	if it == nil {
+		if offset != 0 {
+			return 0, errUploadOffset
+		}
+		if err := os.MkdirAll(u.dir, 0o700); err != nil {
+			return 0, err
+		}
+		it = &keeperUpload{session: session, path: filepath.Join(u.dir, id), total: total}
+		if err := os.WriteFile(it.path, nil, 0o600); err != nil {
+			return 0, err
+		}
+		u.items[id] = it
+	}
+	if offset != it.received {
+		return it.received, errUploadOffset
+	}
+	if total != it.total {
+		return it.received, fmt.Errorf("upload %s total changed from %d to %d", id, it.total, total)
+	}
+
+	f, err := os.OpenFile(it.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return it.received, err
+	}
+	n, err := io.Copy(f, io.LimitReader(chunk, it.total-it.received+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && it.received+n > it.total {
+		err = fmt.Errorf("upload %s exceeds its declared size", id)
+	}
+	if err != nil {
+		// Cut back to the last good position so the client can resume.
+		os.Truncate(it.path, it.received)
+		return it.received, err
+	}
+	it.received += n
+	it.updated = time.Now()
+	return it.received, nil
+}
+
+// take hands over a finished upload's file; the caller removes it.
+func (u *keeperUploads) take(session, id string) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	it := u.items[id]
+	if it == nil || it.session != session {
+		return "", fmt.Errorf("unknown upload %s", id)
+	}
+	if it.received != it.total {
+		return "", fmt.Errorf("upload %s incomplete (%d/%d bytes)", id, it.received, it.total)
+	}
+	delete(u.items, id)
+	return it.path, nil
+}
+
+// handleUpload receives chunked file uploads from coco (POST) and reports
+// how much of an upload has arrived (GET), so coco can resume after errors.
+func (s *keeperServer) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionID := r.Header.Get("X-Session-ID")
+	if sessionID == "" || s.clientBySession(sessionID) == nil {
+		http.Error(w, "unknown session", http.StatusUnauthorized)
+		return
+	}
+	id := r.Header.Get(relay.UploadIDHeader)
+	if !uploadIDPattern.MatchString(id) {
+		http.Error(w, "invalid upload id", http.StatusBadRequest)
+		return
+	}
+
+	writeStatus := func(status int, received int64) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(relay.UploadStatus{Received: received})
+	}
+	if r.Method == http.MethodGet {
+		writeStatus(http.StatusOK, s.uploads.status(sessionID, id))
+		return
+	}
+
+	offset, err1 := strconv.ParseInt(r.Header.Get(relay.UploadOffsetHeader), 10, 64)
+	total, err2 := strconv.ParseInt(r.Header.Get(relay.UploadTotalHeader), 10, 64)
+	if err1 != nil || err2 != nil || offset < 0 || total <= 0 {
+		http.Error(w, "invalid upload offset or total", http.StatusBadRequest)
+		return
+	}
+	if total > keeperUploadMaxBytes {
+		http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	received, err := s.uploads.write(sessionID, id, offset, total, http.MaxBytesReader(w, r.Body, keeperUploadMaxChunk))
+	switch {
+	case err == errUploadOffset:
+		writeStatus(http.StatusConflict, received)
+	case err != nil:
+		logger.Warn("[Keeper] Upload %s failed: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeStatus(http.StatusOK, received)
+	}
+}
+
+// sendCocoFile delivers a file from a coco response to a WeCom user. Its
+// content is inline (base64) or a finished chunked upload.
+func (s *keeperServer) sendCocoFile(sessionID, userID string, file relay.OutgoingFile) error {
+	var path string
+	if file.UploadID != "" {
+		p, err := s.uploads.take(sessionID, file.UploadID)
+		if err != nil {
+			return err
+		}
+		path = p
+	} else {
+		data, err := base64.StdEncoding.DecodeString(file.Data)
+		if err != nil {
+			return fmt.Errorf("invalid file data: %w", err)
+		}
+		f, err := os.CreateTemp("", "coco-keeper-file-*")
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		path = f.Name()
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+	}
+	defer func() { os.Remove(path) }()
+
+	// WeCom names the upload after the file, so give it its real name.
+	named := filepath.Join(filepath.Dir(path), filepath.Base(path)+"-"+filepath.Base(file.Name))
+	if err := os.Rename(path, named); err == nil {
+		path = named
+	}
+	mediaType := file.MediaType
+	if mediaType == "" {
+		mediaType = "file"
+	}
+	mediaID, err := s.wecom.UploadMedia(path, mediaType)
+	if err != nil {
+		return fmt.Errorf("upload to WeCom: %w", err)
+	}
+	return s.wecom.SendMediaMessage(userID, mediaID, mediaType)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/platforms/relay"
+)
+
+func TestKeeperChunkedUpload(t *testing.T) {
+	s := &keeperServer{cfg: &config.Config{}, uploads: newKeeperUploads(t.TempDir())}
+	s.clients = map[string]*cocoClient{
+		clientKey("wecom", "alice"): {userID: "alice", platform: "wecom", sessionID: "s1", connectedAt: time.Now()},
+	}
+	data := []byte("hello, chunked world")
+	const id = "0123456789abcdef"
+
+	send := func(session string, offset int, chunk []byte) (int, int64) {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/upload", bytes.NewReader(chunk))
+		req.Header.Set("X-Session-ID", session)
+		req.Header.Set(relay.UploadIDHeader, id)
+		req.Header.Set(relay.UploadOffsetHeader, strconv.Itoa(offset))
+		req.Header.Set(relay.UploadTotalHeader, strconv.Itoa(len(data)))
+		rr := httptest.NewRecorder()
+		s.handleUpload(rr, req)
+		var st relay.UploadStatus
+		json.Unmarshal(rr.Body.Bytes(), &st)
+		return rr.Code, st.Received
+	}
+
+	if code, got := send("s1", 0, data[:5]); code != http.StatusOK || got != 5 {
+		t.Fatalf("first chunk = %d %d", code, got)
+	}
+	// A retried chunk the server already has is refused with the position.
+	if code, got := send("s1", 0, data[:5]); code != http.StatusConflict || got != 5 {
+		t.Fatalf("repeated chunk = %d %d", code, got)
+	}
+	if code, _ := send("other", 5, data[5:]); code != http.StatusUnauthorized {
+		t.Fatalf("unknown session = %d", code)
+	}
+	if _, err := s.uploads.take("s1", id); err == nil {
+		t.Fatal("took an incomplete upload")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/webhook/upload", nil)
+	req.Header.Set("X-Session-ID", "s1")
+	req.Header.Set(relay.UploadIDHeader, id)
+	rr := httptest.NewRecorder()
+	s.handleUpload(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "{\"received\":5}\n" {
+		t.Fatalf("status = %d %s", rr.Code, rr.Body.String())
+	}
+
+	if code, got := send("s1", 5, append(data[5:], 'x')); code != http.StatusBadRequest || got != 0 {
+		t.Fatalf("oversized chunk = %d %d", code, got)
+	}
+	if code, got := send("s1", 5, data[5:]); code != http.StatusOK || got != int64(len(data)) {
+		t.Fatalf("last chunk = %d %d", code, got)
+	}
+	path, err := s.uploads.take("s1", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Fatalf("assembled = %q", got)
+	}
+}
@@ -36,11 +36,13 @@ type modelBenchResult struct {
 	Status  string
 	Detail  string
 	Latency time.Duration
+	Usage   agent.TokenUsage
+	Reply   string
 }
 
 var modelsCmd = &cobra.Command{
 	Use:   "models",
-	Short: "Model governance tools (status, bench, enable, disable)",
+	Short: "Manage models (list, add, remove, test, status, bench, enable, disable)",
 }
 
 var modelStatusCmd = &cobra.Command{
@@ -107,11 +109,6 @@ var modelEnableCmd = &cobra.Command{
 	},
 }
 
-var doctorCmd = &cobra.Command{
-	Use:   "doctor",
-	Short: "Diagnostics commands",
-}
-
 var doctorModelsCmd = &cobra.Command{
 	Use:   "models",
 	Short: "Check model/provider configuration and optional online bench",
@@ -237,6 +234,12 @@ func runModelsBench(disableFailures bool, disableFor string, roleFilters []strin
 }
 
 func benchOneModel(reg *ai.Registry, model *ai.ModelConfig) modelBenchResult {
+	return probeModel(reg, model, "ping")
+}
+
+// probeModel sends prompt to model and reports whether it answered, how
+// fast, and the tokens it was billed for.
+func probeModel(reg *ai.Registry, model *ai.ModelConfig, prompt string) modelBenchResult {
 	result := modelBenchResult{Model: model, Status: "FAIL", Detail: "unknown"}
 	if model == nil {
 		result.Detail = "nil model"
@@ -274,7 +277,7 @@ func benchOneModel(reg *ai.Registry, model *ai.ModelConfig) modelBenchResult {
 	start := time.Now()
 	resp, err := p.Chat(ctx, agent.ChatRequest{
 		Messages: []agent.Message{
-			{Role: "user", Content: "ping"},
+			{Role: "user", Content: prompt},
 		},
 		SystemPrompt: "Reply with one short line.",
 		MaxTokens:    64,
@@ -291,6 +294,8 @@ func benchOneModel(reg *ai.Registry, model *ai.ModelConfig) modelBenchResult {
 	}
 	result.Status = "PASS"
 	result.Detail = "ok"
+	result.Usage = resp.Usage
+	result.Reply = content
 	return result
 }
 
@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	modelIntellects = []string{"full", "excellent", "good", "usable"}
+	modelSpeeds     = []string{"fast", "medium", "slow"}
+	modelCosts      = []string{"expensive", "high", "medium", "low", "free"}
+)
+
+// registryFiles is the content of providers.yaml and models.yaml.
+type registryFiles struct {
+	Providers []*ai.ProviderConfig
+	Models    []*ai.ModelConfig
+}
+
+type providersYAMLFile struct {
+	Providers []*ai.ProviderConfig `yaml:"providers"`
+}
+
+func init() {
+	modelsCmd.AddCommand(newModelsListCommand(), newModelsAddCommand(), newModelsRemoveCommand(), newModelsTestCommand())
+}
+
+func newModelsListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List configured models with their provider, roles and price",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := readRegistryFiles()
+			if err != nil {
+				return err
+			}
+			printModelList(cmd.OutOrStdout(), files, time.Now())
+			return nil
+		},
+	}
+}
+
+func newModelsAddCommand() *cobra.Command {
+	var (
+		model       ai.ModelConfig
+		provider    ai.ProviderConfig
+		force, skip bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add <code>",
+		Short: "Add a model to models.yaml and check that it answers",
+		Long: `Add a model to models.yaml and send it a "hello" to check that it answers.
+
+The provider must already be in providers.yaml, or be created with
+--provider-type, --base-url and --api-key. A running coco picks up the new
+model by itself.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			model.Code = strings.TrimSpace(args[0])
+			if strings.TrimSpace(model.Name) == "" {
+				model.Name = model.Code
+			}
+			var newProvider *ai.ProviderConfig
+			if provider.Type != "" || provider.BaseURL != "" || provider.APIKey != "" {
+				provider.Name = model.Provider
+				newProvider = &provider
+			}
+
+			files, err := readRegistryFiles()
+			if err != nil {
+				return err
+			}
+			if err := files.addModel(&model, newProvider, force); err != nil {
+				return err
+			}
+			if err := files.write(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added %s (%s via %s) to %s\n", model.Name, model.Code, model.Provider, ai.ModelsPath())
+			if skip {
+				return nil
+			}
+
+			reg, err := ai.LoadRegistry()
+			if err != nil {
+				return err
+			}
+			m, _ := reg.GetModel(model.Name)
+			result := probeModel(reg, m, "hello")
+			printModelProbes(cmd.OutOrStdout(), []modelBenchResult{result})
+			if result.Status != "PASS" {
+				fmt.Fprintf(cmd.OutOrStdout(), "The model was added but did not answer; fix it or run `coco models remove %s`\n", model.Name)
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&model.Name, "name", "", "Model name used by coco (default the code)")
+	f.StringVar(&model.Provider, "provider", "", "Provider name from providers.yaml")
+	f.StringVar(&model.Intellect, "intellect", "excellent", "Intellect: "+strings.Join(modelIntellects, "|"))
+	f.StringVar(&model.Speed, "speed", "fast", "Speed: "+strings.Join(modelSpeeds, "|"))
+	f.StringVar(&model.Cost, "cost", "medium", "Cost tier: "+strings.Join(modelCosts, "|"))
+	f.StringSliceVar(&model.Skills, "skills", nil, "Skills, e.g. thinking,vision,code")
+	f.StringSliceVar(&model.Roles, "roles", nil, "Roles: primary, cron, expert")
+	f.IntVar(&model.ContextWindow, "context-window", 0, "Context window in tokens (default inferred from the code)")
+	f.Float64Var(&model.InputPrice, "input-price", 0, "Price per million input tokens")
+	f.Float64Var(&model.OutputPrice, "output-price", 0, "Price per million output tokens")
+	f.StringVar(&model.ToolCalling, "tool-calling", "", "How the model calls tools: native (default) or text for models without function calling")
+	f.StringVar(&provider.Type, "provider-type", "", "Create the provider with this type (openai, deepseek, qwen, kimi, claude, ...)")
+	f.StringVar(&provider.BaseURL, "base-url", "", "Base URL of the provider to create")
+	f.StringVar(&provider.APIKey, "api-key", "", "API key of the provider to create")
+	f.BoolVar(&force, "force", false, "Replace a model with the same name")
+	f.BoolVar(&skip, "no-test", false, "Do not send the test message")
+	f.IntVar(&modelBenchTimeout, "timeout", 12, "Test timeout in seconds")
+	_ = cmd.MarkFlagRequired("provider")
+	return cmd
+}
+
+func newModelsRemoveCommand() *cobra.Command {
+	var prune bool
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a model from models.yaml",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := readRegistryFiles()
+			if err != nil {
+				return err
+			}
+			pruned, err := files.removeModel(args[0], prune)
+			if err != nil {
+				return err
+			}
+			if err := files.write(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s from %s\n", args[0], ai.ModelsPath())
+			if pruned != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed unused provider %s from %s\n", pruned, ai.ProvidersPath())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&prune, "prune-provider", false, "Also remove the provider when no other model uses it")
+	return cmd
+}
+
+func newModelsTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [name...]",
+		Short: `Send "hello" to models and show latency, tokens and cost`,
+		Long: `Send "hello" to the named models, or to every model, and show whether
+they answered, how long it took, the tokens used and what that cost at the
+model's input_price and output_price.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reg, err := ai.LoadRegistry()
+			if err != nil {
+				return err
+			}
+			targets := reg.ListModels()
+			if len(args) > 0 {
+				targets = nil
+				for _, name := range args {
+					m, ok := reg.GetModel(name)
+					if !ok {
+						return fmt.Errorf("model %s not found", name)
+					}
+					targets = append(targets, m)
+				}
+			}
+			results := make([]modelBenchResult, 0, len(targets))
+			failed := 0
+			for _, m := range targets {
+				r := probeModel(reg, m, "hello")
+				if r.Status == "FAIL" {
+					failed++
+				}
+				results = append(results, r)
+			}
+			printModelProbes(cmd.OutOrStdout(), results)
+			if failed > 0 {
+				return fmt.Errorf("%d of %d models failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&modelBenchTimeout, "timeout", 12, "Per-model timeout in seconds")
+	return cmd
+}
+
+// readRegistryFiles reads providers.yaml and models.yaml; missing files
+// read as empty so the first model can be added on a fresh install.
+func readRegistryFiles() (*registryFiles, error) {
+	files := &registryFiles{}
+	var pf providersYAMLFile
+	if err := readYAMLFile(ai.ProvidersPath(), &pf); err != nil {
+		return nil, err
+	}
+	files.Providers = pf.Providers
+	var mf modelsFile
+	if err := readYAMLFile(ai.ModelsPath(), &mf); err != nil {
+		return nil, err
+	}
+	files.Models = mf.Models
+	return files, nil
+}
+
+func readYAMLFile(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// write saves both files. providers.yaml holds API keys and is kept
+// private.
+func (f *registryFiles) write() error {
+	if err := os.MkdirAll(filepath.Dir(ai.ModelsPath()), 0o755); err != nil {
+		return err
+	}
+	pd, err := yaml.Marshal(providersYAMLFile{Providers: f.Providers})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ai.ProvidersPath(), pd, 0600); err != nil {
+		return err
+	}
+	md, err := yaml.Marshal(modelsFile{Models: f.Models})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ai.ModelsPath(), md, 0644)
+}
+
+func (f *registryFiles) provider(name string) *ai.ProviderConfig {
+	for _, p := range f.Providers {
+		if p != nil && p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// addModel adds m, and provider when given and not yet configured. A model
+// with the same name is only replaced with force.
+func (f *registryFiles) addModel(m *ai.ModelConfig, provider *ai.ProviderConfig, force bool) error {
+	if m.Code == "" || m.Name == "" {
+		return fmt.Errorf("model code is required")
+	}
+	if strings.TrimSpace(m.Provider) == "" {
+		return fmt.Errorf("--provider is required")
+	}
+	for _, check := range []struct {
+		flag, value string
+		allowed     []string
+	}{
+		{"intellect", m.Intellect, modelIntellects},
+		{"speed", m.Speed, modelSpeeds},
+		{"cost", m.Cost, modelCosts},
+	} {
+		if !slices.Contains(check.allowed, check.value) {
+			return fmt.Errorf("invalid --%s %q (want %s)", check.flag, check.value, strings.Join(check.allowed, ", "))
+		}
+	}
+	if m.InputPrice < 0 || m.OutputPrice < 0 {
+		return fmt.Errorf("prices cannot be negative")
+	}
+	if m.ToolCalling != "" && m.ToolCalling != "native" && m.ToolCalling != "text" {
+		return fmt.Errorf("invalid --tool-calling %q (want native, text)", m.ToolCalling)
+	}
+	if m.Skills == nil {
+		m.Skills = []string{}
+	}
+
+	if existing := f.provider(m.Provider); existing == nil {
+		if provider == nil {
+			return fmt.Errorf("provider %s is not in %s; create it with --provider-type, --base-url and --api-key", m.Provider, ai.ProvidersPath())
+		}
+		if strings.TrimSpace(provider.Type) == "" {
+			return fmt.Errorf("--provider-type is required to create provider %s", m.Provider)
+		}
+		f.Providers = append(f.Providers, provider)
+	} else if provider != nil {
+		return fmt.Errorf("provider %s already exists; edit %s to change it", m.Provider, ai.ProvidersPath())
+	}
+
+	for i, old := range f.Models {
+		if old == nil || old.Name != m.Name {
+			continue
+		}
+		if !force {
+			return fmt.Errorf("model %s already exists; use --force to replace it", m.Name)
+		}
+		f.Models[i] = m
+		return nil
+	}
+	f.Models = append(f.Models, m)
+	return nil
+}
+
+// removeModel removes the named model. With prune, its provider goes too
+// when no other model uses it; the pruned provider's name is returned.
+func (f *registryFiles) removeModel(name string, prune bool) (string, error) {
+	idx := slices.IndexFunc(f.Models, func(m *ai.ModelConfig) bool {
+		return m != nil && strings.EqualFold(m.Name, strings.TrimSpace(name))
+	})
+	if idx < 0 {
+		return "", fmt.Errorf("model %s not found", name)
+	}
+	if len(f.Models) == 1 {
+		return "", fmt.Errorf("%s is the only model; add another one first", name)
+	}
+	provider := f.Models[idx].Provider
+	f.Models = slices.Delete(f.Models, idx, idx+1)
+	if !prune {
+		return "", nil
+	}
+	for _, m := range f.Models {
+		if m != nil && m.Provider == provider {
+			return "", nil
+		}
+	}
+	before := len(f.Providers)
+	f.Providers = slices.DeleteFunc(f.Providers, func(p *ai.ProviderConfig) bool {
+		return p != nil && p.Name == provider
+	})
+	if len(f.Providers) == before {
+		return "", nil
+	}
+	return provider, nil
+}
+
+func printModelList(w io.Writer, files *registryFiles, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPROVIDER\tCODE\tROLES\tINTELLECT/SPEED/COST\tPRICE IN/OUT\tSTATUS")
+	for _, m := range files.Models {
+		if m == nil {
+			continue
+		}
+		status := "enabled"
+		switch {
+		case !m.IsEnabled():
+			status = "disabled"
+		case m.IsTemporarilyDisabled(now):
+			status = "off-shelf until " + m.DisabledUntil
+		case files.provider(m.Provider) == nil:
+			status = "provider missing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s/%s/%s\t%s\t%s\n",
+			m.Name, m.Provider, m.Code, strings.Join(defaultIfEmptySlice(m.Roles, "-"), ","),
+			m.Intellect, m.Speed, m.Cost, formatModelPrice(m), status)
+	}
+	tw.Flush()
+}
+
+func formatModelPrice(m *ai.ModelConfig) string {
+	if m.InputPrice == 0 && m.OutputPrice == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%g/%g", m.InputPrice, m.OutputPrice)
+}
+
+func printModelProbes(w io.Writer, results []modelBenchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tSTATUS\tLATENCY\tTOKENS IN/OUT\tCOST\tREPLY")
+	for _, r := range results {
+		latency, tokens, cost, reply := "-", "-", "-", r.Detail
+		if r.Latency > 0 {
+			latency = r.Latency.Truncate(time.Millisecond).String()
+		}
+		if r.Status == "PASS" {
+			reply = truncate(strings.Join(strings.Fields(r.Reply), " "), 40)
+			if r.Usage.InputTokens > 0 || r.Usage.OutputTokens > 0 {
+				tokens = fmt.Sprintf("%d/%d", r.Usage.InputTokens, r.Usage.OutputTokens)
+				if r.Model.InputPrice > 0 || r.Model.OutputPrice > 0 {
+					cost = fmt.Sprintf("%.6f", r.Model.TokenCost(r.Usage.InputTokens, r.Usage.OutputTokens))
+				}
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Model.Name, r.Status, latency, tokens, cost, reply)
+	}
+	tw.Flush()
+}
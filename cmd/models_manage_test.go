@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/ai"
+)
+
+func newTestModel(name, provider string) *ai.ModelConfig {
+	return &ai.ModelConfig{Name: name, Code: name, Provider: provider, Intellect: "excellent", Speed: "fast", Cost: "medium"}
+}
+
+func TestAddAndRemoveModel(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+
+	files, err := readRegistryFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := files.addModel(newTestModel("deepseek-chat", "deepseek"), nil, false); err == nil || !strings.Contains(err.Error(), "--provider-type") {
+		t.Fatalf("unknown provider err = %v", err)
+	}
+	provider := &ai.ProviderConfig{Name: "deepseek", Type: "deepseek", APIKey: "sk-test"}
+	if err := files.addModel(newTestModel("deepseek-chat", "deepseek"), provider, false); err != nil {
+		t.Fatal(err)
+	}
+	bad := newTestModel("deepseek-reasoner", "deepseek")
+	bad.Speed = "instant"
+	if err := files.addModel(bad, nil, false); err == nil || !strings.Contains(err.Error(), "--speed") {
+		t.Fatalf("bad speed err = %v", err)
+	}
+	second := newTestModel("deepseek-reasoner", "deepseek")
+	second.InputPrice, second.OutputPrice = 0.55, 2.19
+	if err := files.addModel(second, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.addModel(newTestModel("deepseek-chat", "deepseek"), nil, false); err == nil {
+		t.Fatal("duplicate model was added without --force")
+	}
+	if err := files.write(); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := ai.LoadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := reg.GetModel("deepseek-reasoner")
+	if !ok || m.OutputPrice != 2.19 {
+		t.Fatalf("reloaded model = %+v", m)
+	}
+	if info, err := os.Stat(ai.ProvidersPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("providers.yaml mode = %v, %v", info, err)
+	}
+
+	files, _ = readRegistryFiles()
+	if pruned, err := files.removeModel("deepseek-chat", true); err != nil || pruned != "" {
+		t.Fatalf("remove with provider still used = %q, %v", pruned, err)
+	}
+	if _, err := files.removeModel("deepseek-reasoner", true); err == nil {
+		t.Fatal("removed the only model")
+	}
+	files.Models = append(files.Models, newTestModel("gpt-4o", "openai"))
+	files.Providers = append(files.Providers, &ai.ProviderConfig{Name: "openai", Type: "openai"})
+	if pruned, err := files.removeModel("gpt-4o", true); err != nil || pruned != "openai" || len(files.Providers) != 1 {
+		t.Fatalf("prune = %q, %v, providers %d", pruned, err, len(files.Providers))
+	}
+}
+
+func TestPrintModelListAndProbes(t *testing.T) {
+	priced := newTestModel("deepseek-reasoner", "deepseek")
+	priced.InputPrice, priced.OutputPrice = 0.5, 2
+	off := newTestModel("gpt-4o", "openai")
+	off.Enabled = boolPtr(false)
+	files := &registryFiles{
+		Providers: []*ai.ProviderConfig{{Name: "deepseek"}},
+		Models:    []*ai.ModelConfig{priced, off},
+	}
+
+	var out bytes.Buffer
+	printModelList(&out, files, time.Now())
+	if got := out.String(); !strings.Contains(got, "0.5/2") || !strings.Contains(got, "disabled") {
+		t.Fatalf("list:\n%s", got)
+	}
+
+	out.Reset()
+	printModelProbes(&out, []modelBenchResult{
+		{Model: priced, Status: "PASS", Latency: 850 * time.Millisecond, Usage: agent.TokenUsage{InputTokens: 1000, OutputTokens: 500}, Reply: "Hello!\nHow can I help?"},
+		{Model: off, Status: "SKIP", Detail: "disabled"},
+	})
+	got := out.String()
+	if !strings.Contains(got, "1000/500") || !strings.Contains(got, "0.001500") || !strings.Contains(got, "Hello! How can I help?") {
+		t.Fatalf("probes:\n%s", got)
+	}
+	if !strings.Contains(got, "gpt-4o") || !strings.Contains(got, "disabled") {
+		t.Fatalf("probes:\n%s", got)
+	}
+}
@@ -31,6 +31,9 @@ var (
 	onboardSetValues      []string
 	onboardSkipService    bool
 	onboardWorkspace      string
+	onboardTemplates      []string
+	onboardListTemplates  bool
+	onboardIndex          string
 )
 
 var onboardCmd = &cobra.Command{
@@ -51,7 +54,12 @@ Flow:
   4) Keeper address registration (no online test)
   5) Tool capability export and checks
   6) Autostart setup
-  7) Final handoff`,
+  7) Final handoff
+
+--template applies persona packs (workspace files such as SOUL.md) and
+workflow templates (intents.workflows entries) from the skill index, e.g.
+the market a keeper serves at /market/index.json. Persona packs replace
+the files the wizard writes. --list-templates shows what the index offers.`,
 	RunE: runOnboard,
 }
 
@@ -63,6 +71,9 @@ func init() {
 	onboardCmd.Flags().StringArrayVar(&onboardSetValues, "set", nil, "Pre-fill answers as key=value (repeatable)")
 	onboardCmd.Flags().BoolVar(&onboardSkipService, "skip-service", false, "Skip autostart/service setup")
 	onboardCmd.Flags().StringVar(&onboardWorkspace, "workspace", "", "Workspace directory for SOUL/USER/HEARTBEAT files (default: current directory)")
+	onboardCmd.Flags().StringArrayVar(&onboardTemplates, "template", nil, "Apply a persona pack or workflow template from the skill index as name[@version] (repeatable)")
+	onboardCmd.Flags().BoolVar(&onboardListTemplates, "list-templates", false, "List persona packs and workflow templates in the skill index and exit")
+	onboardCmd.Flags().StringVar(&onboardIndex, "index", "", "Skill index URL or git repository (default skills.index)")
 }
 
 type onboardQuestion struct {
@@ -182,17 +193,38 @@ var builtInTools = []builtInTool{
 	{Name: "memory_search", Category: "memory", Description: "Search markdown memory snippets"},
 	{Name: "memory_get", Category: "memory", Description: "Read memory note content"},
 	{Name: "memory_write", Category: "memory", Description: "Write memory note content"},
+	{Name: "conversation_recall", Category: "memory", Description: "Pull context from another of the user's conversations"},
+	{Name: "project_create", Category: "memory", Description: "Start a long-term project with a living plan note"},
+	{Name: "project_update", Category: "memory", Description: "Check off, extend or log progress on a project plan"},
+	{Name: "project_status", Category: "memory", Description: "Summarize long-term projects"},
 	{Name: "soul_append", Category: "persona", Description: "Append permanent growth notes into SOUL.md"},
 	{Name: "file_read", Category: "files", Description: "Read local file content"},
 	{Name: "file_write", Category: "files", Description: "Write local file content"},
+	{Name: "file_write_batch", Category: "files", Description: "Write several files all-or-nothing"},
+	{Name: "file_edit", Category: "files", Description: "Edit part of a file with search/replace or a diff"},
+	{Name: "document_read", Category: "files", Description: "Read pages of PDF and Office documents"},
 	{Name: "file_list", Category: "files", Description: "List files in directory"},
 	{Name: "file_trash", Category: "files", Description: "Move file to trash"},
+	{Name: "file_share", Category: "files", Description: "Share a file as an expiring Keeper download link"},
+	{Name: "file_watch", Category: "files", Description: "Watch a folder and handle each new file in the chat"},
+	{Name: "artifact_read", Category: "files", Description: "Page through a large tool output saved as an artifact"},
 	{Name: "shell_execute", Category: "system", Description: "Execute shell command"},
+	{Name: "secrets_generate", Category: "system", Description: "Generate a password into the encrypted vault"},
+	{Name: "secrets_list", Category: "system", Description: "List vault secret names"},
 	{Name: "process_list", Category: "system", Description: "List running processes"},
+	{Name: "db_query", Category: "system", Description: "Query the user's configured databases with SQL"},
 	{Name: "system_info", Category: "system", Description: "Inspect CPU/memory/OS info"},
 	{Name: "web_search", Category: "web", Description: "Search the web with configured engine"},
 	{Name: "web_fetch", Category: "web", Description: "Fetch and summarize a URL"},
+	{Name: "summarize", Category: "web", Description: "Layered summary of a page or document with drill-down"},
+	{Name: "price_watch", Category: "web", Description: "Watch a product price and alert at a target"},
+	{Name: "parcel_track", Category: "web", Description: "Track parcels and notify on status changes"},
+	{Name: "flight_status", Category: "web", Description: "Look up live flight status"},
+	{Name: "train_status", Category: "web", Description: "Look up live train status"},
+	{Name: "itinerary", Category: "web", Description: "Save trips from booking confirmations and remind before departure"},
+	{Name: "news_briefing", Category: "web", Description: "Morning briefing of weather, schedule, tasks and followed news"},
 	{Name: "open_url", Category: "web", Description: "Open URL and extract page content"},
+	{Name: "http_request", Category: "web", Description: "Call an API with credentials from a configured profile"},
 	{Name: "weather_current", Category: "lifestyle", Description: "Current weather query"},
 	{Name: "weather_forecast", Category: "lifestyle", Description: "Forecast query"},
 	{Name: "calendar_today", Category: "schedule", Description: "List today's events"},
@@ -200,10 +232,19 @@ var builtInTools = []builtInTool{
 	{Name: "calendar_create_event", Category: "schedule", Description: "Create calendar event"},
 	{Name: "calendar_search", Category: "schedule", Description: "Search calendar"},
 	{Name: "calendar_delete", Category: "schedule", Description: "Delete calendar event"},
+	{Name: "calendar_freebusy", Category: "schedule", Description: "Check free/busy and common free slots"},
 	{Name: "reminders_list", Category: "schedule", Description: "List reminders"},
 	{Name: "reminders_add", Category: "schedule", Description: "Add reminder"},
 	{Name: "reminders_complete", Category: "schedule", Description: "Complete reminder"},
 	{Name: "reminders_delete", Category: "schedule", Description: "Delete reminder"},
+	{Name: "timer_start", Category: "schedule", Description: "Start timing a task under a category"},
+	{Name: "timer_stop", Category: "schedule", Description: "Stop the running timer"},
+	{Name: "timer_report", Category: "schedule", Description: "Summarize tracked time by category and task"},
+	{Name: "focus_session", Category: "schedule", Description: "Run a pomodoro focus block with notifications held"},
+	{Name: "task_add", Category: "schedule", Description: "Add a to-do with due date, priority and project"},
+	{Name: "task_update", Category: "schedule", Description: "Change a to-do's status, priority or due date"},
+	{Name: "task_list", Category: "schedule", Description: "List open to-dos"},
+	{Name: "task_complete", Category: "schedule", Description: "Mark a to-do done"},
 	{Name: "notes_list", Category: "notes", Description: "List notes"},
 	{Name: "notes_read", Category: "notes", Description: "Read note"},
 	{Name: "notes_create", Category: "notes", Description: "Create note"},
@@ -212,6 +253,8 @@ var builtInTools = []builtInTool{
 	{Name: "clipboard_write", Category: "desktop", Description: "Write clipboard"},
 	{Name: "notification_send", Category: "desktop", Description: "Send local notification"},
 	{Name: "screenshot", Category: "desktop", Description: "Capture screenshot"},
+	{Name: "image_ocr", Category: "desktop", Description: "Read text from an image"},
+	{Name: "print_file", Category: "desktop", Description: "Print a local file"},
 	{Name: "music_play", Category: "media", Description: "Play media"},
 	{Name: "music_pause", Category: "media", Description: "Pause media"},
 	{Name: "music_next", Category: "media", Description: "Next track"},
@@ -243,11 +286,18 @@ var builtInTools = []builtInTool{
 	{Name: "browser_tab_close", Category: "browser", Description: "Close tab"},
 	{Name: "browser_status", Category: "browser", Description: "Inspect browser state"},
 	{Name: "browser_stop", Category: "browser", Description: "Stop browser automation"},
+	{Name: "browser_network_start_capture", Category: "browser", Description: "Record XHR/fetch requests"},
+	{Name: "browser_network_get_requests", Category: "browser", Description: "List captured requests"},
 	{Name: "cron_create", Category: "automation", Description: "Create scheduled job"},
 	{Name: "cron_list", Category: "automation", Description: "List scheduled jobs"},
 	{Name: "cron_delete", Category: "automation", Description: "Delete scheduled job"},
 	{Name: "cron_pause", Category: "automation", Description: "Pause scheduled job"},
 	{Name: "cron_resume", Category: "automation", Description: "Resume scheduled job"},
+	{Name: "contacts_add", Category: "automation", Description: "Save a chat under a name like \"boss\""},
+	{Name: "contacts_list", Category: "automation", Description: "List saved contacts"},
+	{Name: "contacts_remove", Category: "automation", Description: "Delete a contact"},
+	{Name: "message_send_to", Category: "automation", Description: "Push a message to contacts by name"},
+	{Name: "broadcast", Category: "automation", Description: "Announce to all active chats or tagged channels"},
 	{Name: "sessions_spawn", Category: "orchestration", Description: "Spawn sub-session"},
 	{Name: "sessions_send", Category: "orchestration", Description: "Send message to sub-session"},
 	{Name: "spawn_agent", Category: "orchestration", Description: "Spawn specialist agent"},
@@ -269,6 +319,25 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Templates are fetched and verified before the wizard asks anything.
+	var templates []onboardTemplate
+	if onboardListTemplates || len(onboardTemplates) > 0 {
+		idx, err := loadSkillIndex(cmd, skillIndexSource(onboardIndex))
+		if err != nil {
+			return err
+		}
+		defer idx.Close()
+		if onboardListTemplates {
+			return listOnboardTemplates(idx, cmd.OutOrStdout())
+		}
+		fetched, cleanup, err := fetchOnboardTemplates(cmd.Context(), idx, onboardTemplates)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		templates = fetched
+	}
+
 	state := &onboardState{
 		cfg:            cfg,
 		workspaceDir:   workspaceDir,
@@ -297,6 +366,9 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("%s step failed: %w", step.Name, err)
 		}
 	}
+	if err := applyOnboardTemplates(state, templates); err != nil {
+		return err
+	}
 
 	if err := state.cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save .coco.yaml: %w", err)
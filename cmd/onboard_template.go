@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	skillspkg "github.com/kayz/coco/internal/skills"
+	"gopkg.in/yaml.v3"
+)
+
+// onboardTemplate is a persona pack or workflow template fetched from the
+// skill index, applied once the wizard has run.
+type onboardTemplate struct {
+	kind    string
+	name    string
+	version string
+	root    string // verified files of the version
+}
+
+// workflowTemplateFile is the workflow.yaml of a workflow template: one
+// intents.workflows entry plus a description for the index.
+type workflowTemplateFile struct {
+	config.WorkflowConfig `yaml:",inline"`
+	Description           string `yaml:"description,omitempty"`
+}
+
+// listOnboardTemplates prints the persona packs and workflow templates in
+// the index.
+func listOnboardTemplates(idx *skillspkg.RemoteIndex, out io.Writer) error {
+	found := idx.SearchKind("", skillspkg.KindPersona, skillspkg.KindWorkflow)
+	if len(found) == 0 {
+		_, err := fmt.Fprintf(out, "No persona packs or workflow templates in %s\n", idx.Source)
+		return err
+	}
+	for _, t := range found {
+		version := "no release"
+		if v, ok := t.Latest(""); ok {
+			version = v.Version
+		}
+		if _, err := fmt.Fprintf(out, "%-8s %s %s - %s\n", t.EntryKind(), t.Name, version, t.Description); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(out, "\nApply with `coco onboard --template <name>` or pin with `--template <name>@^<version>`")
+	return err
+}
+
+// fetchOnboardTemplates resolves and downloads each name[@version] ref.
+// A ref may be prefixed with persona: or workflow: when both kinds share
+// a name. cleanup removes the downloads.
+func fetchOnboardTemplates(ctx context.Context, idx *skillspkg.RemoteIndex, refs []string) (templates []onboardTemplate, cleanup func(), err error) {
+	var cleanups []func()
+	cleanup = func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		kind := ""
+		if k, rest, ok := strings.Cut(ref, ":"); ok && (k == skillspkg.KindPersona || k == skillspkg.KindWorkflow) {
+			kind, ref = k, rest
+		}
+		name, constraint, _ := strings.Cut(ref, "@")
+		if kind == "" {
+			_, isPersona := idx.LookupKind(skillspkg.KindPersona, name)
+			_, isWorkflow := idx.LookupKind(skillspkg.KindWorkflow, name)
+			switch {
+			case isPersona && isWorkflow:
+				cleanup()
+				return nil, nil, fmt.Errorf("template %q is both a persona pack and a workflow template; use persona:%s or workflow:%s", name, name, name)
+			case isPersona:
+				kind = skillspkg.KindPersona
+			case isWorkflow:
+				kind = skillspkg.KindWorkflow
+			default:
+				cleanup()
+				return nil, nil, fmt.Errorf("template %q not found in index %s; run `coco onboard --list-templates`", name, idx.Source)
+			}
+		}
+		v, err := idx.ResolveKind(kind, name, constraint)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		root, done, err := idx.FetchFiles(ctx, kind, name, v)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, done)
+		templates = append(templates, onboardTemplate{kind: kind, name: name, version: v.Version, root: root})
+	}
+	return templates, cleanup, nil
+}
+
+// applyOnboardTemplates copies persona packs into the workspace, replacing
+// the files the wizard wrote, and adds workflow templates to
+// intents.workflows, replacing a workflow of the same name.
+func applyOnboardTemplates(s *onboardState, templates []onboardTemplate) error {
+	for _, t := range templates {
+		switch t.kind {
+		case skillspkg.KindPersona:
+			if err := copyPersonaPack(s, t.root); err != nil {
+				return fmt.Errorf("persona pack %s: %w", t.name, err)
+			}
+		case skillspkg.KindWorkflow:
+			raw, err := os.ReadFile(filepath.Join(t.root, "workflow.yaml"))
+			if err != nil {
+				return fmt.Errorf("workflow template %s: %w", t.name, err)
+			}
+			var wf workflowTemplateFile
+			if err := yaml.Unmarshal(raw, &wf); err != nil {
+				return fmt.Errorf("workflow template %s: %w", t.name, err)
+			}
+			if strings.TrimSpace(wf.Name) == "" {
+				wf.Name = t.name
+			}
+			replaced := false
+			for i, existing := range s.cfg.Intents.Workflows {
+				if existing.Name == wf.Name {
+					s.cfg.Intents.Workflows[i] = wf.WorkflowConfig
+					replaced = true
+				}
+			}
+			if !replaced {
+				s.cfg.Intents.Workflows = append(s.cfg.Intents.Workflows, wf.WorkflowConfig)
+			}
+		}
+		fmt.Printf("Applied %s %s %s\n", t.kind, t.name, t.version)
+	}
+	return nil
+}
+
+// copyPersonaPack writes the files of a persona pack into the workspace.
+// Its README.md describes the pack and is left out.
+func copyPersonaPack(s *onboardState, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "README.md" || rel == skillspkg.OriginFileName {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(s.workspaceDir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		s.generatedFiles = append(s.generatedFiles, target)
+		return nil
+	})
+}
@@ -6,16 +6,18 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/browser"
 	"github.com/kayz/coco/internal/config"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/instance"
 	"github.com/kayz/coco/internal/platforms/relay"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/router"
-	"github.com/kayz/coco/internal/tools"
 	"github.com/kayz/coco/internal/voice"
 	"github.com/spf13/cobra"
 )
@@ -29,6 +31,7 @@ var (
 	relayWebhookURL    string
 	relayUseMediaProxy bool
 	relayInstructions  string
+	relayDirect        bool
 	// WeCom credentials for cloud relay
 	relayWeComCorpID  string
 	relayWeComAgentID string
@@ -77,6 +80,16 @@ WeCom Cloud Relay:
   3. Save config in WeCom - verification will succeed automatically
   4. Messages will be processed with your AI provider
 
+Feishu Direct Connection:
+  With your own Feishu app, coco can talk to Feishu without Keeper.
+  Credentials come from platforms.feishu in .coco.yaml (or FEISHU_APP_ID
+  and FEISHU_APP_SECRET). It uses the long connection unless
+  platforms.feishu.callback_listen is set, in which case point the event
+  subscription at http://<host>/feishu/event and card callbacks at
+  http://<host>/feishu/card.
+
+  coco relay feishu --direct
+
 Required:
   --user-id     Your user ID from /whoami (not needed for WeCom)
   --platform    Platform type: feishu, slack, wechat, or wecom
@@ -108,6 +121,7 @@ func init() {
 	relayCmd.Flags().StringVar(&relayWebhookURL, "webhook", "", "Webhook URL (default: https://keeper.kayz.com/webhook, or RELAY_WEBHOOK_URL env)")
 	relayCmd.Flags().BoolVar(&relayUseMediaProxy, "use-media-proxy", false, "Proxy media download/upload through relay server")
 	relayCmd.Flags().StringVar(&relayInstructions, "instructions", "", "Path to custom instructions file appended to system prompt")
+	relayCmd.Flags().BoolVar(&relayDirect, "direct", false, "Connect to the platform with your own app instead of through Keeper (feishu only, or RELAY_DIRECT env)")
 
 	// WeCom credentials for cloud relay
 	relayCmd.Flags().StringVar(&relayWeComCorpID, "wecom-corp-id", "", "WeCom Corp ID (or WECOM_CORP_ID env)")
@@ -155,6 +169,11 @@ func runRelay(cmd *cobra.Command, args []string) {
 	if relayToken == "" {
 		relayToken = os.Getenv("RELAY_TOKEN")
 	}
+	if !relayDirect {
+		if os.Getenv("RELAY_DIRECT") == "true" || os.Getenv("RELAY_DIRECT") == "1" {
+			relayDirect = true
+		}
+	}
 	if !relayUseMediaProxy {
 		if os.Getenv("RELAY_USE_MEDIA_PROXY") == "true" || os.Getenv("RELAY_USE_MEDIA_PROXY") == "1" {
 			relayUseMediaProxy = true
@@ -216,6 +235,9 @@ func runRelay(cmd *cobra.Command, args []string) {
 		if !relayUseMediaProxy && savedCfg.Relay.UseMediaProxy {
 			relayUseMediaProxy = savedCfg.Relay.UseMediaProxy
 		}
+		if !relayDirect && savedCfg.Relay.Direct {
+			relayDirect = true
+		}
 		if relayPlatform == "" && savedCfg.Mode == "relay" {
 			// Infer platform from saved platform credentials
 			if savedCfg.Platforms.WeCom.CorpID != "" {
@@ -254,6 +276,10 @@ func runRelay(cmd *cobra.Command, args []string) {
 		fmt.Fprintln(os.Stderr, "Error: --platform must be 'feishu', 'slack', 'wechat', or 'wecom'")
 		os.Exit(1)
 	}
+	if relayDirect && relayPlatform != "feishu" {
+		fmt.Fprintln(os.Stderr, "Error: --direct is only supported for feishu")
+		os.Exit(1)
+	}
 
 	// For WeCom, user-id is optional - auto-generate from corp_id
 	// For other platforms, user-id is required
@@ -262,6 +288,8 @@ func runRelay(cmd *cobra.Command, args []string) {
 			relayUserID = "wecom-" + relayWeComCorpID
 		} else if relayPlatform == "wecom" {
 			relayUserID = buildFallbackRelayUserID("wecom")
+		} else if relayDirect {
+			relayUserID = buildFallbackRelayUserID(relayPlatform)
 		} else if relayPlatform != "wecom" {
 			fmt.Fprintln(os.Stderr, "Error: --user-id is required (get it from /whoami)")
 			os.Exit(1)
@@ -296,6 +324,12 @@ func runRelay(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	claimInstance("relay", func(info *instance.Info) {
+		info.UserID = relayUserID
+		info.Platform = relayPlatform
+		info.ServerURL = firstNonEmpty(relayServerURL, relay.DefaultServerURL)
+	})
+
 	// Load custom instructions if specified
 	var customInstructions string
 	if relayInstructions != "" {
@@ -311,6 +345,7 @@ func runRelay(cmd *cobra.Command, args []string) {
 	// Create the AI agent
 	aiAgent, err := agent.New(agent.Config{
 		CustomInstructions:    customInstructions,
+		InstructionsFile:      relayInstructions,
 		AllowedPaths:          loadAllowedPaths(),
 		BlockedCommands:       loadBlockedCommands(),
 		RequireConfirmation:   loadRequireConfirmation(),
@@ -327,21 +362,32 @@ func runRelay(cmd *cobra.Command, args []string) {
 	r := router.New(aiAgent.HandleMessage)
 
 	// Initialize cron scheduler
-	exeDir := tools.GetExecutableDir()
-	if exeDir == "" {
-		exeDir = os.TempDir()
-	}
-	cronPath := filepath.Join(exeDir, ".coco.db")
+	cronPath := datadir.Path(".coco.db")
 	cronStore, err := cronpkg.NewStore(cronPath)
 	if err != nil {
 		log.Fatalf("Failed to open cron store: %v", err)
 	}
 	cronNotifier := agent.NewRouterCronNotifier(r)
 	cronScheduler := cronpkg.NewScheduler(cronStore, aiAgent, aiAgent, cronNotifier)
+	registerCronNotifiers(cronScheduler)
+	if signer, err := provenance.LoadOrCreate(provenance.DefaultKeyPath()); err != nil {
+		log.Printf("Warning: cron jobs will be unsigned: %v", err)
+	} else {
+		cronScheduler.SetSigner(signer)
+	}
 	aiAgent.SetCronScheduler(cronScheduler)
+	aiAgent.SetNotifier(cronNotifier)
+	cronNotifier.SetGate(aiAgent)
 	if err := cronScheduler.Start(); err != nil {
 		log.Printf("Warning: Failed to start cron scheduler: %v", err)
 	}
+	var cronCfg config.CronConfig
+	if savedCfg, err := config.Load(); err == nil {
+		cronCfg = savedCfg.Cron
+	}
+	if gc, ok := cronGCConfig(cronCfg, cronNotifier); ok {
+		cronScheduler.EnableGC(gc)
+	}
 
 	// Create voice transcriber if STT provider is configured
 	var transcriber *voice.Transcriber
@@ -358,30 +404,23 @@ func runRelay(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Create and register relay platform
-	relayPlatformInstance, err := relay.New(relay.Config{
-		UserID:          relayUserID,
-		Platform:        relayPlatform,
-		Token:           relayToken,
-		ServerURL:       relayServerURL,
-		WebhookURL:      relayWebhookURL,
-		UseMediaProxy:   relayUseMediaProxy,
-		AIProvider:      "",
-		AIModel:         "",
-		WeComCorpID:     relayWeComCorpID,
-		WeComAgentID:    relayWeComAgentID,
-		WeComSecret:     relayWeComSecret,
-		WeComToken:      relayWeComToken,
-		WeComAESKey:     relayWeComAESKey,
-		WeChatAppID:     relayWeChatAppID,
-		WeChatAppSecret: relayWeChatAppSecret,
-		Transcriber:     transcriber,
-	})
+	// Create and register the platform: Feishu directly, or the relay
+	var relayPlatformInstance router.Platform
+	if relayDirect {
+		relayPlatformInstance, err = newFeishuDirect()
+	} else {
+		relayPlatformInstance, err = newRelayPlatform(transcriber)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating relay platform: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error creating %s platform: %v\n", relayPlatform, err)
 		os.Exit(1)
 	}
 	r.Register(relayPlatformInstance)
+	if emailPlatform, err := newEmailPlatform(); err != nil {
+		log.Printf("Warning: email channel disabled: %v", err)
+	} else if emailPlatform != nil {
+		r.Register(emailPlatform)
+	}
 
 	// Start the router
 	ctx, cancel := context.WithCancel(context.Background())
@@ -392,7 +431,23 @@ func runRelay(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	log.Printf("Relay connected. User: %s, Platform: %s", relayUserID, relayPlatform)
+	if relayDirect {
+		log.Printf("Connected to %s directly (no Keeper)", relayPlatform)
+	} else {
+		log.Printf("Relay connected. User: %s, Platform: %s", relayUserID, relayPlatform)
+	}
+
+	aiAgent.StartWorkspaceSync(ctx)
+	aiAgent.StartRetention(ctx)
+	aiAgent.StartConfigBackup(ctx)
+	aiAgent.StartFileWatches(ctx)
+	aiAgent.StartHeartbeat(ctx)
+	aiAgent.StartProactive(ctx)
+	aiAgent.StartBroadcasts(ctx)
+	aiAgent.StartDND(ctx)
+	if err := aiAgent.WatchConfig(ctx); err != nil {
+		log.Printf("Config watcher disabled: %v", err)
+	}
 	log.Println("Press Ctrl+C to stop.")
 
 	// Wait for shutdown signal
@@ -403,6 +458,30 @@ func runRelay(cmd *cobra.Command, args []string) {
 	log.Println("Shutting down...")
 	cronScheduler.Stop()
 	r.Stop()
+	browser.Instance().Shutdown()
+	releaseInstance()
+}
+
+// newRelayPlatform connects through Keeper with the resolved relay settings.
+func newRelayPlatform(transcriber *voice.Transcriber) (router.Platform, error) {
+	return relay.New(relay.Config{
+		UserID:          relayUserID,
+		Platform:        relayPlatform,
+		Token:           relayToken,
+		ServerURL:       relayServerURL,
+		WebhookURL:      relayWebhookURL,
+		UseMediaProxy:   relayUseMediaProxy,
+		AIProvider:      "",
+		AIModel:         "",
+		WeComCorpID:     relayWeComCorpID,
+		WeComAgentID:    relayWeComAgentID,
+		WeComSecret:     relayWeComSecret,
+		WeComToken:      relayWeComToken,
+		WeComAESKey:     relayWeComAESKey,
+		WeChatAppID:     relayWeChatAppID,
+		WeChatAppSecret: relayWeChatAppSecret,
+		Transcriber:     transcriber,
+	})
 }
 
 func normalizePlatformArg(args []string) string {
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/platforms/email"
+	"github.com/kayz/coco/internal/router"
+)
+
+// newEmailPlatform builds the email channel from platforms.email, or
+// returns nil when it is not enabled.
+func newEmailPlatform() (router.Platform, error) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Platforms.Email.Enabled {
+		return nil, nil
+	}
+	ec := cfg.Platforms.Email
+
+	interval := time.Duration(0)
+	if ec.PollInterval != "" {
+		d, err := time.ParseDuration(ec.PollInterval)
+		if err != nil || d <= 0 {
+			log.Printf("Warning: invalid platforms.email.poll_interval %q, using 1m", ec.PollInterval)
+		} else {
+			interval = d
+		}
+	}
+	return email.New(email.Config{
+		IMAPServer:     ec.IMAPServer,
+		SMTPServer:     ec.SMTPServer,
+		Username:       ec.Username,
+		Password:       firstNonEmpty(os.Getenv("EMAIL_PASSWORD"), ec.Password),
+		From:           ec.From,
+		Folder:         ec.Folder,
+		PollInterval:   interval,
+		WebhookListen:  ec.WebhookListen,
+		AllowedSenders: ec.AllowedSenders,
+	})
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/platforms/email"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// registerCronNotifiers adds the delivery channels that need this machine
+// or its settings: desktop notifications, and email when platforms.email
+// has an SMTP server (the inbound channel need not be enabled).
+func registerCronNotifiers(s *cronpkg.Scheduler) {
+	s.RegisterNotifier(cronpkg.NotifyViaDesktop, cronpkg.NotifierFunc(func(ctx context.Context, d cronpkg.Delivery) error {
+		return tools.Notify(ctx, "coco · "+d.Job.Name, d.Text)
+	}))
+
+	cfg, err := config.Load()
+	if err != nil || cfg.Platforms.Email.SMTPServer == "" {
+		return
+	}
+	ec := cfg.Platforms.Email
+	mailCfg := email.Config{
+		SMTPServer: ec.SMTPServer,
+		Username:   ec.Username,
+		Password:   firstNonEmpty(os.Getenv("EMAIL_PASSWORD"), ec.Password),
+		From:       ec.From,
+	}
+	s.RegisterNotifier(cronpkg.NotifyViaEmail, cronpkg.NotifierFunc(func(_ context.Context, d cronpkg.Delivery) error {
+		subject := "coco · " + d.Job.Name
+		if d.Failed {
+			subject = "⚠️ " + subject
+		}
+		return email.SendText(mailCfg, d.Target, subject, d.Text)
+	}))
+}
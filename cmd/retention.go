@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newRetentionCommand())
+}
+
+func newRetentionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retention",
+		Short: "Delete messages, memories and logs past their retention",
+		Long: `Retention is set in the retention section of the config:
+
+  retention:
+    days: 365            # every category (0 keeps data forever)
+    categories:
+      messages: 90
+      memories: 365
+      audit: 730
+      metrics: -1        # keep forever
+
+Categories are messages, memories, reports, feedback, metrics and audit.
+A running coco prunes once a day by itself.`,
+	}
+
+	var dryRun bool
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete data past its retention now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			store, err := persist.NewStore(filepath.Join(filepath.Dir(config.ConfigPath()), ".coco.db"))
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			results, err := agent.PruneRetention(store, cfg.Retention, time.Now(), dryRun)
+			printRetentionResults(cmd.OutOrStdout(), results, dryRun)
+			return err
+		},
+	}
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "Only report what would be deleted")
+
+	cmd.AddCommand(prune)
+	return cmd
+}
+
+func printRetentionResults(w io.Writer, results []agent.RetentionResult, dryRun bool) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No retention configured; every category is kept forever.")
+		return
+	}
+	action := "DELETED"
+	if dryRun {
+		action = "WOULD DELETE"
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "CATEGORY\tKEEP\tOLDER THAN\t%s\n", action)
+	total := 0
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d days\t%s\t%d\n", r.Category, r.Days, r.Cutoff.Format("2006-01-02"), r.Count)
+		total += r.Count
+	}
+	tw.Flush()
+	if dryRun {
+		fmt.Fprintf(w, "%d item(s) would be deleted; run without --dry-run to delete them.\n", total)
+	}
+}
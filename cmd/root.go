@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/datadir"
 	"github.com/kayz/coco/internal/logger"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +29,11 @@ Modes:
   coco           Run relay mode (default)
   coco relay     Run as relay client
   coco keeper    Run as public-facing relay server (Keeper mode)
-  coco both      Run keeper + relay in one process`,
+  coco both      Run keeper + relay in one process
+
+State (.coco.db, .coco.yaml, .coco/) lives in the data directory:
+$COCO_DATA_DIR, else $XDG_DATA_HOME/coco (~/.local/share/coco),
+~/Library/Application Support/coco on macOS, %APPDATA%\coco on Windows.`,
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
@@ -61,6 +66,17 @@ func init() {
 		"Enable automatic search for uncertain queries")
 }
 
+func migrateDataDir() {
+	notes, err := datadir.Migrate()
+	for _, note := range notes {
+		fmt.Fprintf(os.Stderr, "Data migration: %s\n", note)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: data migration to %s incomplete: %v (set %s to keep the old location)\n",
+			datadir.Dir(), err, datadir.EnvVar)
+	}
+}
+
 // IsAutoApprove returns true if auto-approve mode is enabled globally
 func IsAutoApprove() bool {
 	return autoApprove
@@ -177,6 +193,10 @@ func updateSearchConfig() {
 }
 
 func Execute() {
+	// Move state older versions kept next to the executable before anything
+	// creates fresh files in the data directory.
+	migrateDataDir()
+
 	// Update search config from command line flags
 	updateSearchConfig()
 
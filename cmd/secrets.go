@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	agentpkg "github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newSecretsCommand())
+}
+
+func newSecretsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the encrypted local secret vault",
+		Long: `Manage secrets the assistant can use by name without ever seeing them.
+
+Secrets are encrypted with NaCl secretbox in .coco/vault.json in the data
+directory; the key lives in .coco/vault.key (or set COCO_VAULT_KEY). In chat,
+refer to a secret by name ("use my-nas-password"); tools receive the value at
+execution time and their output is redacted back to {{secret:name}}.`,
+	}
+
+	var note string
+	setCmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Store a secret (value is read from stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := agentpkg.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Value for %s: ", args[0])
+			line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("read value: %w", err)
+			}
+			if err := vault.Set(args[0], strings.TrimRight(line, "\r\n"), note); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Stored %s\n", secrets.Ref(args[0]))
+			return nil
+		},
+	}
+	setCmd.Flags().StringVar(&note, "note", "", "What the secret is for")
+
+	getCmd := &cobra.Command{
+		Use:   "get <name>",
+		Short: "Print a secret's value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := agentpkg.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			value, err := vault.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), value)
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List secret names",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := agentpkg.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			entries, err := vault.List()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(entries) == 0 {
+				fmt.Fprintln(out, "Vault is empty")
+				return nil
+			}
+			for _, e := range entries {
+				fmt.Fprintf(out, "%-24s %s  %s\n", e.Name, e.UpdatedAt.Format("2006-01-02"), e.Note)
+			}
+			return nil
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete <name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a secret",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := agentpkg.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			if err := vault.Delete(args[0]); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s\n", args[0])
+			return nil
+		},
+	}
+
+	var (
+		kind      string
+		length    int
+		noSymbols bool
+		show      bool
+	)
+	generateCmd := &cobra.Command{
+		Use:   "generate <name>",
+		Short: "Generate a random password and store it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := agentpkg.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			value, err := secrets.Generate(kind, length, !noSymbols)
+			if err != nil {
+				return err
+			}
+			if err := vault.Set(args[0], value, note); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Stored %s\n", secrets.Ref(args[0]))
+			if show {
+				fmt.Fprintln(cmd.OutOrStdout(), value)
+			}
+			return nil
+		},
+	}
+	generateCmd.Flags().StringVar(&kind, "kind", "password", "password, pin or hex")
+	generateCmd.Flags().IntVar(&length, "length", 0, "Length (default depends on kind)")
+	generateCmd.Flags().BoolVar(&noSymbols, "no-symbols", false, "Letters and digits only")
+	generateCmd.Flags().BoolVar(&show, "show", false, "Print the generated value")
+	generateCmd.Flags().StringVar(&note, "note", "", "What the secret is for")
+
+	cmd.AddCommand(setCmd, getCmd, listCmd, deleteCmd, generateCmd)
+	return cmd
+}
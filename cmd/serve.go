@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/apiserver"
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/security"
+	"github.com/spf13/cobra"
+)
+
+const defaultAPIListen = "127.0.0.1:18081"
+
+var (
+	serveAPI    bool
+	serveListen string
+	serveToken  string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run coco as a local service for scripts and custom UIs",
+	Long: `Run coco as a local service.
+
+With --api, coco exposes a REST API:
+
+  POST /v1/messages       {"conversation","user_id","text","stream"}; SSE when streaming
+  GET  /v1/conversations  conversations the agent remembers
+  GET  /v1/cron/jobs      scheduled jobs
+  POST /v1/tools/{name}   run one tool with a JSON object of arguments
+  POST /v1/batch          {"items":[{"id","prompt"}],"concurrency","stream"}; many prompts at once
+
+It also speaks the OpenAI chat API, so editors and CLIs can use coco as a
+model (base URL http://127.0.0.1:18081/v1, model "coco"):
+
+  POST /v1/chat/completions  the agent answers the last user message, tools enabled
+  GET  /v1/models            lists "coco"
+
+Set the X-Coco-Conversation header or the "user" field to choose the
+conversation; otherwise it is derived from the chat's opening messages.
+
+The API listens on 127.0.0.1:18081 by default. Every request must send
+"Authorization: Bearer <token>" (--token, api.token or COCO_API_TOKEN),
+even on loopback, since any web page open in a browser can reach it too.
+Without a configured token one is generated on first start and kept in
+<data dir>/.coco/api.token. /v1/tools calls run under the api.profile tool
+profile (default admin).`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().BoolVar(&serveAPI, "api", false, "Serve the REST API")
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Listen address (default api.listen or "+defaultAPIListen+")")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token clients must send (default api.token or $COCO_API_TOKEN)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if !serveAPI {
+		return fmt.Errorf("nothing to serve; pass --api")
+	}
+
+	var apiCfg config.APIConfig
+	if cfg, err := config.Load(); err == nil {
+		apiCfg = cfg.API
+	}
+	listen := firstNonEmpty(serveListen, apiCfg.Listen, defaultAPIListen)
+	token := firstNonEmpty(serveToken, os.Getenv("COCO_API_TOKEN"), apiCfg.Token)
+	if token == "" {
+		path := datadir.Path(".coco", "api.token")
+		generated, created, err := apiserver.LoadOrCreateToken(path)
+		if err != nil {
+			return fmt.Errorf("api token: %w", err)
+		}
+		if created {
+			log.Printf("Generated an API token in %s", path)
+		}
+		log.Printf("Send \"Authorization: Bearer $(cat %s)\" with every request", path)
+		token = generated
+	}
+
+	aiAgent, err := agent.New(agent.Config{
+		AllowedPaths:          loadAllowedPaths(),
+		BlockedCommands:       loadBlockedCommands(),
+		RequireConfirmation:   loadRequireConfirmation(),
+		AllowFrom:             loadAllowFrom(),
+		RequireMentionInGroup: loadRequireMentionInGroup(),
+		DisableFileTools:      loadDisableFileTools(),
+	})
+	if err != nil {
+		return fmt.Errorf("creating agent: %w", err)
+	}
+
+	server := apiserver.NewServer(aiAgent, apiserver.Options{
+		Token:       token,
+		ToolProfile: firstNonEmpty(apiCfg.Profile, security.ProfileAdmin),
+		CronJobs:    listCronJobs,
+	})
+	httpServer := &http.Server{
+		Addr:              listen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("REST API listening on http://%s/v1", listen)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("REST API server error: %v", err)
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(ctx)
+}
+
+// listCronJobs reads jobs from the cron store each time, so jobs created by a
+// running relay show up without restarting the API.
+func listCronJobs() ([]*cronpkg.Job, error) {
+	store, jobs, _, err := loadCronJobs("")
+	if err != nil {
+		return nil, err
+	}
+	store.Close()
+	return jobs, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
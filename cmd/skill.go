@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/kayz/coco/internal/config"
 	skillspkg "github.com/kayz/coco/internal/skills"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +26,9 @@ func newSkillCommand() *cobra.Command {
 		newSkillInstallCommand(),
 		newSkillListCommand(),
 		newSkillDownloadCommand(),
+		newSkillUpdateCommand(),
+		newSkillUpgradeCommand(),
+		newSkillChecksumCommand(),
 	)
 	return cmd
 }
@@ -58,6 +63,8 @@ func newSkillSearchCommand() *cobra.Command {
 	var showEligible bool
 	var verbose bool
 	var asJSON bool
+	var remote bool
+	var index string
 
 	cmd := &cobra.Command{
 		Use:   "search [keyword]",
@@ -68,6 +75,9 @@ func newSkillSearchCommand() *cobra.Command {
 			if len(args) > 0 {
 				query = args[0]
 			}
+			if remote {
+				return searchSkillIndex(cmd, skillIndexSource(index), query, asJSON)
+			}
 
 			report := skillspkg.BuildStatusReport(nil, nil)
 			report = filterSkillReport(report, query)
@@ -84,6 +94,8 @@ func newSkillSearchCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&showEligible, "eligible", false, "Show only ready skills")
 	cmd.Flags().BoolVar(&verbose, "verbose", false, "Show missing requirement details")
 	cmd.Flags().BoolVar(&asJSON, "json", false, "Render output as JSON")
+	cmd.Flags().BoolVar(&remote, "remote", false, "Search the skill index instead of discovered skills")
+	cmd.Flags().StringVar(&index, "index", "", "Skill index URL or git repository (default skills.index)")
 	return cmd
 }
 
@@ -93,72 +105,73 @@ func newSkillInstallCommand() *cobra.Command {
 	var overwrite bool
 	var managedDir string
 	var asJSON bool
+	var index string
 
 	cmd := &cobra.Command{
-		Use:   "install <name>",
-		Short: "Install a discovered skill into managed skills directory",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			name := strings.TrimSpace(args[0])
-			entry, found := skillspkg.FindSkillByName(name, nil, nil)
-			if !found {
-				return fmt.Errorf("skill %q not found; run `coco skill search` first", name)
-			}
+		Use:   "install <name[@version]|git-url>",
+		Short: "Install a discovered or indexed skill, or the skills in a git repository, into managed skills directory",
+		Long: `Install a skill into the managed skills directory.
 
-			assessment := skillspkg.EvaluateSkillSecurity(entry)
-			if asJSON {
-				payload := map[string]any{
-					"skill":      entry.Name,
-					"assessment": assessment,
+A name is looked up among the discovered skills first and then in the skill
+index (skills.index). name@version installs from the index and pins the
+skill for later upgrades: "weather@1.2.0" exactly, "weather@^1.2" any 1.x
+from 1.2, "weather@~1.2" any 1.2.x.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := strings.TrimSpace(args[0])
+			var entries []skillspkg.SkillEntry
+			var origin *skillspkg.Origin
+			name, constraint, pinned := strings.Cut(source, "@")
+			indexSource := skillIndexSource(index)
+			switch {
+			case skillspkg.IsGitURL(source):
+				fetched, cleanup, err := skillspkg.FetchGitSkills(cmd.Context(), source)
+				if err != nil {
+					return err
 				}
-				data, _ := json.MarshalIndent(payload, "", "  ")
-				if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(data)); err != nil {
+				defer cleanup()
+				entries = fetched
+			case pinned || (indexSource != "" && !skillDiscovered(name)):
+				idx, err := loadSkillIndex(cmd, indexSource)
+				if err != nil {
 					return err
 				}
-			} else {
-				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Security assessment for %s: %s (score=%d)\n", entry.Name, assessment.Level, assessment.Score); err != nil {
+				defer idx.Close()
+				v, err := idx.Resolve(name, constraint)
+				if err != nil {
 					return err
 				}
-				for _, reason := range assessment.Reasons {
-					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "- %s\n", reason); err != nil {
-						return err
-					}
+				entry, cleanup, err := idx.Fetch(cmd.Context(), name, v)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				entries = []skillspkg.SkillEntry{entry}
+				origin = &skillspkg.Origin{Index: idx.Source, Version: v.Version, Constraint: constraint, SHA256: v.SHA256}
+			default:
+				entry, found := skillspkg.FindSkillByName(source, nil, nil)
+				if !found {
+					return fmt.Errorf("skill %q not found; run `coco skill search` first", source)
 				}
+				entries = []skillspkg.SkillEntry{entry}
 			}
 
-			if assessment.Level == skillspkg.SecurityDangerous && !force {
-				return fmt.Errorf("skill %q is rated dangerous; re-run with --force to override", entry.Name)
+			for _, entry := range entries {
+				if err := reviewSkill(cmd, entry, asJSON, force); err != nil {
+					return err
+				}
 			}
 			if !confirm && !IsAutoApprove() {
 				return fmt.Errorf("installation requires explicit confirmation; re-run with --yes")
 			}
 
-			result, err := skillspkg.InstallSkillEntry(entry, skillspkg.InstallOptions{
-				ManagedDir: managedDir,
-				Overwrite:  overwrite,
-			})
-			if err != nil {
-				return err
-			}
-
-			if asJSON {
-				payload := map[string]any{
-					"skill":      entry.Name,
-					"installed":  !result.AlreadyExists,
-					"result":     result,
-					"assessment": result.Assessment,
+			opts := skillspkg.InstallOptions{ManagedDir: managedDir, Overwrite: overwrite}
+			for _, entry := range entries {
+				if err := installSkill(cmd, entry, opts, origin, asJSON); err != nil {
+					return err
 				}
-				data, _ := json.MarshalIndent(payload, "", "  ")
-				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
-				return err
-			}
-
-			if result.AlreadyExists {
-				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Skill %s already installed at %s\n", entry.Name, result.InstalledPath)
-				return err
 			}
-			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Installed %s to %s\n", entry.Name, result.InstalledPath)
-			return err
+			return nil
 		},
 	}
 
@@ -167,9 +180,155 @@ func newSkillInstallCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing managed skill directory")
 	cmd.Flags().StringVar(&managedDir, "dest", "", "Managed skills directory override")
 	cmd.Flags().BoolVar(&asJSON, "json", false, "Render output as JSON")
+	cmd.Flags().StringVar(&index, "index", "", "Skill index URL or git repository (default skills.index)")
 	return cmd
 }
 
+// reviewSkill prints the security assessment and tools of a skill about to
+// be installed, and refuses dangerous skills unless forced.
+func reviewSkill(cmd *cobra.Command, entry skillspkg.SkillEntry, asJSON, force bool) error {
+	assessment := skillspkg.EvaluateSkillSecurity(entry)
+	if asJSON {
+		payload := map[string]any{
+			"skill":       entry.Name,
+			"assessment":  assessment,
+			"tools":       entry.Tools,
+			"permissions": entry.Permissions,
+		}
+		data, _ := json.MarshalIndent(payload, "", "  ")
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(data)); err != nil {
+			return err
+		}
+	} else {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Security assessment for %s: %s (score=%d)\n", entry.Name, assessment.Level, assessment.Score); err != nil {
+			return err
+		}
+		for _, reason := range assessment.Reasons {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "- %s\n", reason); err != nil {
+				return err
+			}
+		}
+		if err := printSkillTools(cmd, entry); err != nil {
+			return err
+		}
+	}
+
+	if assessment.Level == skillspkg.SecurityDangerous && !force {
+		return fmt.Errorf("skill %q is rated dangerous; re-run with --force to override", entry.Name)
+	}
+	return nil
+}
+
+// installSkill installs a reviewed skill and, for skills from the index,
+// records its origin so `coco skill upgrade` can find newer versions.
+func installSkill(cmd *cobra.Command, entry skillspkg.SkillEntry, opts skillspkg.InstallOptions, origin *skillspkg.Origin, asJSON bool) error {
+	result, err := skillspkg.InstallSkillEntry(entry, opts)
+	if err != nil {
+		return err
+	}
+	if origin != nil && !result.AlreadyExists {
+		o := *origin
+		o.InstalledAt = time.Now()
+		if err := skillspkg.WriteOrigin(result.InstalledPath, o); err != nil {
+			return err
+		}
+	}
+
+	if asJSON {
+		payload := map[string]any{
+			"skill":      entry.Name,
+			"installed":  !result.AlreadyExists,
+			"result":     result,
+			"assessment": result.Assessment,
+		}
+		if origin != nil {
+			payload["version"] = origin.Version
+		}
+		data, _ := json.MarshalIndent(payload, "", "  ")
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return err
+	}
+
+	name := entry.Name
+	if origin != nil {
+		name += " " + origin.Version
+	}
+	if result.AlreadyExists {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Skill %s already installed at %s (use --overwrite to update)\n", entry.Name, result.InstalledPath)
+	} else {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Installed %s to %s\n", name, result.InstalledPath)
+	}
+	return err
+}
+
+func skillDiscovered(name string) bool {
+	_, found := skillspkg.FindSkillByName(name, nil, nil)
+	return found
+}
+
+// skillIndexSource returns the --index flag, or skills.index from the
+// config when the flag is empty.
+func skillIndexSource(flag string) string {
+	if flag = strings.TrimSpace(flag); flag != "" {
+		return flag
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(cfg.Skills.Index)
+}
+
+// loadSkillIndex loads the index at source, checking its signature when
+// skills.index_key is set.
+func loadSkillIndex(cmd *cobra.Command, source string) (*skillspkg.RemoteIndex, error) {
+	key := ""
+	if cfg, err := config.Load(); err == nil {
+		key = strings.TrimSpace(cfg.Skills.IndexKey)
+	}
+	return skillspkg.LoadSignedIndex(cmd.Context(), source, key)
+}
+
+// printSkillTools lists the tools a skill adds and the permissions they run
+// with, so they can be reviewed before confirming. Only the network is
+// isolated, which the listing says.
+func printSkillTools(cmd *cobra.Command, entry skillspkg.SkillEntry) error {
+	if len(entry.Tools) == 0 {
+		return nil
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, "Tools:")
+	for _, t := range entry.Tools {
+		run := t.Command
+		if t.Script != "" {
+			run = "script " + t.Script
+		}
+		fmt.Fprintf(out, "- %s: %s (%s)\n", skillspkg.ToolName(entry.Name, t.Name), t.Description, run)
+	}
+	p := entry.Permissions
+	network := "no"
+	if p.Network {
+		network = "yes"
+	} else if !skillspkg.CanIsolateNetwork() {
+		network = "no (cannot be enforced on this system: refused unless skills.allow_unisolated_network)"
+	}
+	perms := []string{"network: " + network}
+	if len(p.Env) > 0 {
+		perms = append(perms, "env: "+strings.Join(p.Env, ", "))
+	}
+	if len(p.Paths) > 0 {
+		perms = append(perms, "path arguments under: "+strings.Join(p.Paths, ", "))
+	}
+	if p.Timeout != "" {
+		perms = append(perms, "timeout: "+p.Timeout)
+	}
+	if _, err := fmt.Fprintf(out, "Permissions: %s\n", strings.Join(perms, "; ")); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out, "Note: only the network is restricted. These tools run as you and can read and write any file you can.")
+	return err
+}
+
 func newSkillDownloadCommand() *cobra.Command {
 	var managedDir string
 
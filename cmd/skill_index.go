@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	skillspkg "github.com/kayz/coco/internal/skills"
+	"github.com/spf13/cobra"
+)
+
+// searchSkillIndex lists the skills in the index matching query.
+func searchSkillIndex(cmd *cobra.Command, source, query string, asJSON bool) error {
+	idx, err := loadSkillIndex(cmd, source)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+	found := idx.Search(query)
+
+	out := cmd.OutOrStdout()
+	if asJSON {
+		data, _ := json.MarshalIndent(found, "", "  ")
+		_, err := fmt.Fprintln(out, string(data))
+		return err
+	}
+	if len(found) == 0 {
+		_, err := fmt.Fprintf(out, "No skills in %s match %q\n", idx.Source, query)
+		return err
+	}
+	for _, s := range found {
+		latest, ok := s.Latest("")
+		version := "no release"
+		if ok {
+			version = latest.Version
+		}
+		if _, err := fmt.Fprintf(out, "%s %s - %s\n", s.Name, version, s.Description); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(out, "\nInstall with `coco skill install <name>` or pin with `coco skill install <name>@^<version>`")
+	return err
+}
+
+func newSkillUpdateCommand() *cobra.Command {
+	var managedDir string
+	var index string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Check the skill index for newer versions of installed skills",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, err := loadSkillIndex(cmd, skillIndexSource(index))
+			if err != nil {
+				return err
+			}
+			defer idx.Close()
+			upgrades, err := idx.Upgrades(managedDir)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				data, _ := json.MarshalIndent(upgrades, "", "  ")
+				_, err := fmt.Fprintln(out, string(data))
+				return err
+			}
+			if len(upgrades) == 0 {
+				_, err := fmt.Fprintln(out, "All skills installed from the index are up to date")
+				return err
+			}
+			for _, u := range upgrades {
+				if u.Available == "" {
+					_, err = fmt.Fprintf(out, "%s %s (pinned %s; latest %s)\n", u.Name, u.Installed, u.Constraint, u.Latest)
+				} else {
+					_, err = fmt.Fprintf(out, "%s %s -> %s\n", u.Name, u.Installed, u.Available)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintln(out, "\nRun `coco skill upgrade` to install them")
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&managedDir, "dest", "", "Managed skills directory override")
+	cmd.Flags().StringVar(&index, "index", "", "Skill index URL or git repository (default skills.index)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Render output as JSON")
+	return cmd
+}
+
+func newSkillUpgradeCommand() *cobra.Command {
+	var confirm bool
+	var force bool
+	var managedDir string
+	var index string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "upgrade [name...]",
+		Short: "Upgrade skills installed from the index to the newest version their pin allows",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, err := loadSkillIndex(cmd, skillIndexSource(index))
+			if err != nil {
+				return err
+			}
+			defer idx.Close()
+			upgrades, err := idx.Upgrades(managedDir)
+			if err != nil {
+				return err
+			}
+
+			type pending struct {
+				entry  skillspkg.SkillEntry
+				origin skillspkg.Origin
+			}
+			var todo []pending
+			for _, u := range upgrades {
+				if u.Available == "" || (len(args) > 0 && !slices.Contains(args, u.Name)) {
+					continue
+				}
+				v, err := idx.Resolve(u.Name, u.Constraint)
+				if err != nil {
+					return err
+				}
+				entry, cleanup, err := idx.Fetch(cmd.Context(), u.Name, v)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				if err := reviewSkill(cmd, entry, asJSON, force); err != nil {
+					return err
+				}
+				todo = append(todo, pending{entry, skillspkg.Origin{Index: idx.Source, Version: v.Version, Constraint: u.Constraint, SHA256: v.SHA256}})
+			}
+			if len(todo) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), "Nothing to upgrade")
+				return err
+			}
+			if !confirm && !IsAutoApprove() {
+				return fmt.Errorf("upgrade requires explicit confirmation; re-run with --yes")
+			}
+
+			opts := skillspkg.InstallOptions{ManagedDir: managedDir, Overwrite: true}
+			for _, p := range todo {
+				if err := installSkill(cmd, p.entry, opts, &p.origin, asJSON); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&confirm, "yes", "y", false, "Confirm upgrade")
+	cmd.Flags().BoolVar(&force, "force", false, "Allow upgrade even when assessment is dangerous")
+	cmd.Flags().StringVar(&managedDir, "dest", "", "Managed skills directory override")
+	cmd.Flags().StringVar(&index, "index", "", "Skill index URL or git repository (default skills.index)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Render output as JSON")
+	return cmd
+}
+
+func newSkillChecksumCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "checksum <dir>",
+		Short: "Print the sha256 a skill index lists for a skill directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sum, err := skillspkg.DirChecksum(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), sum)
+			return err
+		},
+	}
+}
@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	skillspkg "github.com/kayz/coco/internal/skills"
 )
 
 func TestSkillSearchCommandFindsBundledSkill(t *testing.T) {
@@ -89,3 +93,116 @@ func writeSkillFixture(t *testing.T, root, name, description, body string) {
 		t.Fatalf("write SKILL.md: %v", err)
 	}
 }
+
+func TestSkillInstallCommandInstallsFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	writeSkillFixture(t, filepath.Join(repo, "skills"), "phase4-git-skill", "git fixture", "safe content")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "skill"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	dest := filepath.Join(t.TempDir(), "managed")
+
+	cmd := newSkillCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"install", "file://" + repo, "--yes", "--dest", dest})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute install: %v\noutput=%s", err, out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dest, "phase4-git-skill", "SKILL.md")); err != nil {
+		t.Fatalf("expected installed skill: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "phase4-git-skill", ".git")); !os.IsNotExist(err) {
+		t.Fatalf("git metadata copied: %v", err)
+	}
+}
+
+func TestSkillInstallPinnedFromGitIndexAndUpgrade(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	var versions []skillspkg.IndexVersion
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		dir := filepath.Join(repo, "phase4-index-skill", v)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "---\nname: phase4-index-skill\ndescription: index fixture\n---\nversion " + v + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum, err := skillspkg.DirChecksum(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, skillspkg.IndexVersion{Version: v, Path: "phase4-index-skill/" + v, SHA256: sum})
+	}
+	index, _ := json.Marshal(map[string]any{"skills": []skillspkg.IndexSkill{{Name: "phase4-index-skill", Versions: versions}}})
+	if err := os.WriteFile(filepath.Join(repo, "index.json"), index, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "index"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	t.Setenv("COCO_BUNDLED_SKILLS_DIR", t.TempDir())
+	dest := filepath.Join(t.TempDir(), "managed")
+	indexURL := "file://" + repo
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := newSkillCommand()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(append(args, "--dest", dest, "--index", indexURL))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v\noutput=%s", args, err, out.String())
+		}
+		return out.String()
+	}
+	skillFile := filepath.Join(dest, "phase4-index-skill", "SKILL.md")
+
+	run("install", "phase4-index-skill@^1.0", "--yes")
+	if data, _ := os.ReadFile(skillFile); !strings.Contains(string(data), "version 1.1.0") {
+		t.Fatalf("installed %q, want 1.1.0", data)
+	}
+	if out := run("update"); !strings.Contains(out, "pinned ^1.0; latest 2.0.0") {
+		t.Fatalf("update output = %s", out)
+	}
+	if out := run("upgrade", "--yes"); !strings.Contains(out, "Nothing to upgrade") {
+		t.Fatalf("upgrade output = %s", out)
+	}
+
+	run("install", "phase4-index-skill@1.0.0", "--yes", "--overwrite")
+	origin, _, _ := skillspkg.ReadOrigin(filepath.Dir(skillFile))
+	origin.Constraint = "^1"
+	skillspkg.WriteOrigin(filepath.Dir(skillFile), origin)
+	if out := run("update"); !strings.Contains(out, "1.0.0 -> 1.1.0") {
+		t.Fatalf("update output = %s", out)
+	}
+	run("upgrade", "--yes")
+	if data, _ := os.ReadFile(skillFile); !strings.Contains(string(data), "version 1.1.0") {
+		t.Fatalf("upgraded to %q, want 1.1.0", data)
+	}
+}
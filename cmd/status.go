@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/instance"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show running coco instances and what each one owns",
+	Long: `Show the coco keeper/relay processes running on this machine: their
+data directory, keeper port and relay user-id. Only one instance may use a
+data directory, a keeper port, or a relay user-id on the same server.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instances, err := instance.List()
+		if err != nil {
+			return err
+		}
+		if len(instances) == 0 {
+			fmt.Println("No coco instances running.")
+			return nil
+		}
+		current := datadir.Dir()
+		for _, in := range instances {
+			marker := ""
+			if in.DataDir == current {
+				marker = " (this data dir)"
+			}
+			fmt.Printf("- pid %d: %s since %s\n", in.PID, in.Mode, in.StartedAt.Format(time.RFC3339))
+			fmt.Printf("    data dir: %s%s\n", in.DataDir, marker)
+			if in.KeeperPort != 0 {
+				desc, ok := describeLocalKeeper(in.KeeperPort)
+				if !ok {
+					desc = "not responding"
+				}
+				fmt.Printf("    keeper:   :%d (%s)\n", in.KeeperPort, desc)
+			}
+			if in.UserID != "" {
+				fmt.Printf("    relay:    user-id %s, platform %s, server %s\n", in.UserID, in.Platform, in.ServerURL)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+// instanceLock is this process's entry in the instance registry, taken by
+// the first long-running mode that starts (both takes it for keeper and relay).
+var instanceLock *instance.Lock
+
+// claimInstance locks the data directory on first use and records what this
+// process is about to own, exiting with a readable message on conflict.
+func claimInstance(mode string, update func(*instance.Info)) {
+	if instanceLock == nil {
+		lock, err := instance.Acquire(datadir.Dir(), mode)
+		if err != nil {
+			exitInstanceConflict(err)
+		}
+		instanceLock = lock
+	}
+	if update == nil {
+		return
+	}
+	if err := instanceLock.Claim(update); err != nil {
+		exitInstanceConflict(err)
+	}
+}
+
+func releaseInstance() {
+	instanceLock.Release()
+}
+
+func exitInstanceConflict(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	var conflict *instance.ConflictError
+	if errors.As(err, &conflict) {
+		fmt.Fprintf(os.Stderr, "Stop pid %d first, or run `coco status` to see what is running.\n", conflict.Owner.PID)
+		if conflict.What == "data dir" {
+			fmt.Fprintf(os.Stderr, "To run a second instance on purpose, give it its own data dir with %s.\n", datadir.EnvVar)
+		}
+	}
+	os.Exit(1)
+}
+
+// checkKeeperPort reports a friendly error if port is already taken,
+// naming the coco keeper behind it when there is one.
+func checkKeeperPort(port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err == nil {
+		ln.Close()
+		return nil
+	}
+	if desc, ok := describeLocalKeeper(port); ok {
+		return fmt.Errorf("port %d is already used by a running coco keeper (%s); run `coco status` to find it", port, desc)
+	}
+	return fmt.Errorf("port %d is already in use by another program: %v (choose one with --port or keeper.port)", port, err)
+}
+
+// describeLocalKeeper asks a keeper on this machine for its health; ok is
+// false when nothing that looks like a keeper answers.
+func describeLocalKeeper(port int) (desc string, ok bool) {
+	client := &http.Client{Timeout: 1 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Status  string `json:"status"`
+		Coco    string `json:"coco"`
+		Clients int    `json:"clients"`
+		UserID  string `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil || health.Status == "" {
+		return "", false
+	}
+	if health.Clients > 1 {
+		return fmt.Sprintf("coco %s, %d clients", health.Coco, health.Clients), true
+	}
+	if health.UserID != "" {
+		return fmt.Sprintf("coco %s as %s", health.Coco, health.UserID), true
+	}
+	return "coco " + health.Coco, true
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	agentpkg "github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newSyncCommand())
+}
+
+func newSyncCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Sync workspace prompt files and memories with other devices",
+		Long: `Run one encrypted sync of workspace persona and memory files.
+
+Configure the "sync" section of .coco.yaml (backend keeper, webdav or s3, and a
+passphrase shared by all devices). Cron jobs are synced by the running relay
+process, which owns the scheduler.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if !cfg.Sync.Enabled {
+				return fmt.Errorf("sync is not enabled (set sync.enabled: true in .coco.yaml)")
+			}
+			engine, err := agentpkg.NewWorkspaceSyncEngine(cfg, nil)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			res, err := engine.Sync(ctx)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, res.Summary())
+			for _, p := range res.Pulled {
+				fmt.Fprintf(out, "  ↓ %s\n", p)
+			}
+			for _, p := range res.Pushed {
+				fmt.Fprintf(out, "  ↑ %s\n", p)
+			}
+			for _, p := range res.Conflicts {
+				fmt.Fprintf(out, "  ! conflict copy: %s\n", p)
+			}
+			for _, e := range res.Errors {
+				fmt.Fprintf(out, "  x %s\n", e)
+			}
+			return nil
+		},
+	}
+}
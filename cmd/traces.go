@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/diag"
+	"github.com/kayz/coco/internal/finetune"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newTracesCommand())
+}
+
+func newTracesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "traces",
+		Short: "Export recorded agent runs as fine-tuning data",
+		Long: `Recorded runs hold the prompt, every tool call and result, and the final
+reply of each message coco answered. Recording is off by default; enable it
+with traces.enabled in the config.`,
+	}
+
+	var (
+		format   string
+		output   string
+		days     int
+		noSystem bool
+		noRedact bool
+	)
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write recorded runs as OpenAI or ShareGPT JSONL",
+		Long: `Write recorded runs as one JSON object per line, in the OpenAI chat
+fine-tuning format (messages and tools) or the ShareGPT format used by
+LLaMA-Factory and similar trainers.
+
+API keys and passwords from the config, vault secrets, e-mail addresses,
+phone and ID numbers are redacted unless --no-redact is given. Review the
+file before sharing it or training on it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var since time.Time
+			if days > 0 {
+				since = time.Now().AddDate(0, 0, -days)
+			}
+			store, err := persist.NewStore(filepath.Join(filepath.Dir(config.ConfigPath()), ".coco.db"))
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			traces, err := store.RunTraces(since)
+			if err != nil {
+				return err
+			}
+
+			var redactor *finetune.Redactor
+			if !noRedact {
+				redactor = finetune.NewRedactor(append(configSecretValues(), vaultSecretValues()...))
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			bw := bufio.NewWriter(w)
+			n, err := exportTraces(bw, traces, format, redactor, !noSystem)
+			if err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			if output != "" && output != "-" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d of %d runs to %s\n", n, len(traces), output)
+			}
+			return nil
+		},
+	}
+	export.Flags().StringVarP(&format, "format", "f", "openai", "Dataset format: "+strings.Join(finetune.Formats, " or "))
+	export.Flags().StringVarP(&output, "output", "o", "", "Output file (default stdout)")
+	export.Flags().IntVar(&days, "days", 0, "Only runs from the last N days (default all)")
+	export.Flags().BoolVar(&noSystem, "no-system", false, "Leave out system prompts")
+	export.Flags().BoolVar(&noRedact, "no-redact", false, "Keep credentials and personal data")
+	cmd.AddCommand(export)
+	return cmd
+}
+
+// exportTraces writes one line per trace and returns how many were written.
+// Runs without a reply, e.g. ones stopped by /cancel, are skipped.
+func exportTraces(w io.Writer, traces []persist.RunTrace, format string, redactor *finetune.Redactor, withSystem bool) (int, error) {
+	written := 0
+	for _, rt := range traces {
+		var t finetune.Trace
+		if err := json.Unmarshal([]byte(rt.Data), &t); err != nil {
+			return written, fmt.Errorf("trace %d: %w", rt.ID, err)
+		}
+		if len(t.Messages) == 0 || strings.TrimSpace(t.Messages[len(t.Messages)-1].Content) == "" {
+			continue
+		}
+		if !withSystem {
+			t.System = ""
+		}
+		if redactor != nil {
+			t = redactor.Trace(t)
+		}
+		line, err := finetune.Encode(t, format)
+		if err != nil {
+			return written, err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// configSecretValues returns the credentials in the config, providers and
+// models files.
+func configSecretValues() []string {
+	var out []string
+	for _, path := range []string{config.ConfigPath(), ai.ProvidersPath(), ai.ModelsPath()} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if _, removed, err := diag.RedactYAML(data); err == nil {
+			out = append(out, removed...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kayz/coco/internal/mcp"
+	"github.com/kayz/coco/internal/service"
+	"github.com/kayz/coco/internal/skills"
+	"github.com/kayz/coco/internal/upgrade"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newUpgradeCommand())
+}
+
+func newUpgradeCommand() *cobra.Command {
+	var (
+		check   bool
+		version string
+		force   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade coco to the latest release",
+		Long: `Upgrade coco the same way it was installed:
+
+  Homebrew        brew upgrade coco
+  Scoop           scoop update coco
+  macOS .pkg      installs the new package with installer(8)
+  Windows .msi    installs the new package with msiexec
+  service/binary  replaces the binary in place with the release download
+
+Downloads are checked against the release's checksums.txt. The data
+directory is left alone, and installed services keep their registration:
+their binary is refreshed and running ones are restarted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUpgrade(cmd.Context(), check, version, force)
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "Only report whether a newer release exists")
+	cmd.Flags().StringVar(&version, "version", "", "Install this version instead of the latest (not for Homebrew/Scoop)")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall even when already up to date")
+	return cmd
+}
+
+func runUpgrade(ctx context.Context, check bool, version string, force bool) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the coco binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+	method := upgrade.Detect(execPath, runtime.GOOS)
+	current := mcp.ServerVersion
+	fmt.Printf("coco %s (%s install at %s)\n", current, method, execPath)
+
+	if version != "" && (method == upgrade.MethodHomebrew || method == upgrade.MethodScoop) {
+		return fmt.Errorf("--version is not supported for %s installs; use %s to pin a version", method, method)
+	}
+	rel, err := upgrade.FetchRelease(ctx, version)
+	if err != nil {
+		return err
+	}
+	newer := skills.CompareVersions(rel.Version, current) > 0
+	if check {
+		if newer {
+			fmt.Printf("coco %s is available. Run `coco upgrade` to install it.\n", rel.Version)
+		} else {
+			fmt.Println("coco is up to date.")
+		}
+		return nil
+	}
+	if !newer && version == "" && !force {
+		fmt.Println("coco is up to date.")
+		return nil
+	}
+
+	newBinary := execPath
+	switch method {
+	case upgrade.MethodHomebrew:
+		if err := runStreaming("brew", "upgrade", "coco"); err != nil {
+			return fmt.Errorf("brew upgrade failed: %w", err)
+		}
+		if out, err := exec.Command("brew", "--prefix", "coco").Output(); err == nil {
+			newBinary = filepath.Join(strings.TrimSpace(string(out)), "bin", "coco")
+		}
+	case upgrade.MethodScoop:
+		if err := runStreaming("scoop", "update", "coco"); err != nil {
+			return fmt.Errorf("scoop update failed: %w", err)
+		}
+	default:
+		if err := installRelease(ctx, rel, method, execPath); err != nil {
+			return err
+		}
+	}
+
+	refreshServices(newBinary)
+	fmt.Printf("Upgraded to coco %s.\n", rel.Version)
+	return nil
+}
+
+// installRelease downloads the release asset for method and installs it.
+func installRelease(ctx context.Context, rel *upgrade.Release, method upgrade.Method, execPath string) error {
+	dir, err := os.MkdirTemp("", "coco-upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	name := upgrade.AssetName(method, rel.Version, runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("Downloading %s...\n", name)
+	path, err := rel.Download(ctx, name, dir)
+	if err != nil {
+		return err
+	}
+
+	switch method {
+	case upgrade.MethodPkg:
+		args := []string{"installer", "-pkg", path, "-target", "/"}
+		if os.Geteuid() != 0 {
+			args = append([]string{"sudo"}, args...)
+		}
+		return runStreaming(args[0], args[1:]...)
+	case upgrade.MethodMSI:
+		// msiexec swaps files in use through the Restart Manager.
+		return runStreaming("msiexec", "/i", path, "/passive", "/norestart")
+	}
+	if err := upgrade.Replace(path, execPath); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("no permission to replace %s; run the upgrade with sudo or as administrator", execPath)
+		}
+		return fmt.Errorf("failed to replace %s: %w", execPath, err)
+	}
+	return nil
+}
+
+// refreshServices copies the upgraded binary to installed services that run
+// their own copy and restarts the running ones. Their unit or plist is kept.
+func refreshServices(newBinary string) {
+	refreshed := map[string]bool{}
+	for _, mode := range []string{service.ModeRelay, service.ModeKeeper, service.ModeBoth} {
+		if !service.IsInstalled(mode) {
+			continue
+		}
+		binaryPath, _, err := service.Paths(mode)
+		if err != nil {
+			continue
+		}
+		if !refreshed[binaryPath] && filepath.Clean(binaryPath) != filepath.Clean(newBinary) {
+			if err := upgrade.Replace(newBinary, binaryPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s service still runs the old binary: %v\n", mode, err)
+				continue
+			}
+		}
+		refreshed[binaryPath] = true
+		if service.IsRunning(mode) {
+			if err := service.Restart(mode); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to restart %s service: %v\n", mode, err)
+				continue
+			}
+			fmt.Printf("Restarted %s service.\n", mode)
+		}
+	}
+}
+
+func runStreaming(name string, args ...string) error {
+	c := exec.Command(name, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return c.Run()
+}
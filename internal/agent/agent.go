@@ -3,11 +3,13 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,13 +17,23 @@ import (
 	"github.com/kayz/coco/internal/ai"
 	"github.com/kayz/coco/internal/config"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/fswatch"
 	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/ocr"
+	"github.com/kayz/coco/internal/parcel"
 	"github.com/kayz/coco/internal/persist"
 	"github.com/kayz/coco/internal/promptbuild"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/router"
 	"github.com/kayz/coco/internal/search"
 	"github.com/kayz/coco/internal/security"
 	"github.com/kayz/coco/internal/skills"
+	"github.com/kayz/coco/internal/taskqueue"
+	"github.com/kayz/coco/internal/travel"
+	"github.com/kayz/coco/internal/voice"
+	"github.com/kayz/coco/internal/watchdog"
+	"github.com/kayz/coco/internal/wsync"
 )
 
 var (
@@ -61,33 +73,95 @@ type Agent struct {
 	subSessions           *SubSessionStore
 	autoApprove           bool
 	customInstructions    string
+	instructionsFile      string // reread on config reload when set
 	cronScheduler         *cronpkg.Scheduler
-	currentMsg            router.Message // set during HandleMessage for cron_create context
-	cronCreatedCount      int            // tracks cron_create calls per HandleMessage turn
+	convLocks             conversationLocks // one turn at a time per conversation
 	securityMu            sync.RWMutex
 	pathChecker           *security.PathChecker
 	disableFileTools      bool
 	blockedCommands       []string
 	requireConfirmCmds    []string
 	allowFrom             []string
+	senderProfiles        map[string]string // allow_from sender → tool profile name
+	toolProfiles          map[string]security.ToolProfile
+	defaultToolProfile    string
+	channelProfiles       map[string]config.ChannelProfileConfig // channels section, by "platform:channel_id" or channel ID
+	promptSections        config.PromptSectionsConfig            // top-level prompt_sections
+	promptStats           promptStatsStore                       // last turn's prompt sizes, for /prompt debug
+	feedback              feedbackPrompts                        // answers awaiting a 👍/👎 or 1-5 rating
+	intentWorkflows       []*intentWorkflow                      // intents.workflows
+	intentClassifier      string                                 // intents.classifier: "keywords" or "model"
+	askMissingTools       []string                               // tools.ask_missing globs
+	artifactThreshold     int                                    // tools.artifact_threshold in characters; 0 keeps results inline
+	dialogs               dialogSessions                         // form dialogs collecting workflow slots or tool arguments
+	planApprovalTools     map[string]bool // tools held for "/approve" (security.plan_approval)
+	planApprovals         planApprovalQueue
+	toolTimeouts          []toolTimeoutRule // tools.timeouts merged over defaultToolTimeouts
+	turns                 turnRegistry      // in-flight HandleMessage calls, for "/cancel"
+	tasks                 *taskqueue.Queue  // orders chats and scheduled jobs (queue section)
+	taskRetry             taskqueue.Retry   // retry policy for failed scheduled prompts
+	undo                  undoLog           // reversible side effects per turn, for "/undo"
+	synthesizer           *voice.Synthesizer // voice.tts; nil when voice replies are off
+	ocr                   *ocr.Recognizer    // nil without a usable OCR backend
+	ocrAttachments        bool               // read incoming images for models without vision
+	focusCfg              config.FocusConfig
+	focusMu               sync.Mutex
+	focusSessions         map[string]*focusSession // running focus_session per conversation
+	parcelTracker         parcel.Tracker           // nil when tracking.provider is unset
+	parcelAutoExtract     bool                     // follow tracking numbers found in messages
+	travel                travelSettings
+	briefing              briefingSettings
+	proactive             proactiveSettings
+	proactiveState        proactiveState // idle check-in bookkeeping
+	broadcasts            broadcastQueue // delivers queued announcements one at a time
+	dnd                   quietHours     // dnd.hours, for channels without their own
+	topics                topicSelections // topic thread each chat is on (/fork, /threads)
+	traces                traceSettings
+	retention             config.RetentionConfig
+	backup                backupSettings
+	rituals               ritualSettings
+	skillTools            map[string]skillTool // by tool name; from skills.BuildStatusReport
+	skillUnisolatedNet    bool                 // skills.allow_unisolated_network
+	fileWatchMu           sync.Mutex
+	fileWatcher           *fswatch.Watcher // set while StartFileWatches runs
+	heartbeatMu           sync.Mutex       // serializes heartbeat job syncs
+	planner               plannerSettings
+	routing               routingSettings
+	budgets               *ai.Budgets // routing.budgets; shared by every model router
+	sources               sourceCache // fetched pages and documents, for summary drill-down
+	ttsConfig             config.TTSConfig
 	requireMentionInGroup bool
 	configPath            string
 	configMtime           time.Time
+	loadedConfig          *config.Config // last applied, to tell what a reload changed
+	reloadMu              sync.Mutex
+	configWatched         bool // WatchConfig is running; turns skip the mtime check
+	fileSend              fileSendSettings
+	fileLinks             *fileLinkClient // nil without a Keeper to host download links
+	unfurl                unfurlSettings
+	linkPreviews          linkPreviewCache
 	persistStore          *persist.Store
 	firstMessageSent      map[string]bool
 	firstMessageMu        sync.RWMutex
 	bootstrapSent         map[string]bool
 	bootstrapMu           sync.Mutex
 	latestReport          *persist.DailyReport
+	reportMu              sync.RWMutex
 	searchRegistry        *search.Registry
 	searchManager         *search.Manager
 	remoteCron            *remoteCronClient
+	workspaceGit          *workspaceVersioner
+	workspaceSync         *wsync.Engine
+	watchdog              *watchdog.Watchdog // nil when watchdog.enabled is false
+	watchdogCfg           watchdogSettings
+	notifier              cronpkg.ChatNotifier
 }
 
 // Config holds agent configuration
 type Config struct {
 	AutoApprove           bool     // Skip all confirmation prompts (default: false)
 	CustomInstructions    string   // Additional instructions appended to system prompt (optional)
+	InstructionsFile      string   // File CustomInstructions was read from, reread on config reload (optional)
 	AllowedPaths          []string // Restrict file/shell operations to these directories (empty = no restriction)
 	BlockedCommands       []string // Block command patterns for shell execution
 	RequireConfirmation   []string // Shell command patterns requiring confirmation unless auto approve
@@ -141,6 +215,11 @@ var workspacePromptOrder = []workspacePromptFile{
 
 const workspaceBootstrapFile = "BOOTSTRAP.md"
 
+// WorkspaceDir returns the directory holding the workspace prompt files.
+func WorkspaceDir() string {
+	return getWorkspaceDir()
+}
+
 func getWorkspaceDir() string {
 	if env := strings.TrimSpace(os.Getenv("COCO_WORKSPACE_DIR")); env != "" {
 		return env
@@ -148,10 +227,7 @@ func getWorkspaceDir() string {
 	if wd, err := os.Getwd(); err == nil && strings.TrimSpace(wd) != "" {
 		return wd
 	}
-	if exeDir := getExecutableDir(); exeDir != "" {
-		return exeDir
-	}
-	return "."
+	return datadir.Dir()
 }
 
 func stripYAMLFrontmatter(content string) string {
@@ -166,6 +242,10 @@ func stripYAMLFrontmatter(content string) string {
 	return strings.TrimSpace(parts[2])
 }
 
+// missingPromptWarned remembers required prompt files already reported
+// missing, so the warning is not repeated on every message.
+var missingPromptWarned sync.Map
+
 func loadWorkspacePromptBundle() string {
 	workspaceDir := getWorkspaceDir()
 	var sections []string
@@ -173,20 +253,18 @@ func loadWorkspacePromptBundle() string {
 	for _, file := range workspacePromptOrder {
 		path := filepath.Join(workspaceDir, file.name)
 		data, err := os.ReadFile(path)
-		if err != nil {
-			if file.required {
-				return ""
-			}
-			continue
+		content := ""
+		if err == nil {
+			content = stripYAMLFrontmatter(string(data))
 		}
-
-		content := stripYAMLFrontmatter(string(data))
 		if strings.TrimSpace(content) == "" {
-			if file.required {
-				return ""
+			// A missing required file degrades the prompt; it must not drop the rest of it.
+			if _, warned := missingPromptWarned.LoadOrStore(path, true); file.required && !warned {
+				logger.Warn("[Agent] Required workspace file %s is missing or empty; restart coco to restore it from the template", path)
 			}
 			continue
 		}
+		missingPromptWarned.Delete(path)
 		sections = append(sections, fmt.Sprintf("# %s\n\n%s", file.name, content))
 	}
 
@@ -210,19 +288,33 @@ func ConversationKey(platform, channelID, userID string) string {
 }
 
 func (a *Agent) chatWithModel(ctx context.Context, req ChatRequest) (ChatResponse, error) {
-	role := a.currentRequestModelRole()
-	return a.chatWithModelForRole(ctx, req, role)
+	role := a.currentRequestModelRole(ctx)
+	return a.chatWithModelForRole(ctx, req, role, turnOf(ctx).model)
 }
 
-func (a *Agent) currentRequestModelRole() string {
-	if strings.EqualFold(strings.TrimSpace(a.currentMsg.Username), "cron") {
+func (a *Agent) currentRequestModelRole(ctx context.Context) string {
+	msg := turnMessage(ctx)
+	if strings.EqualFold(strings.TrimSpace(msg.Username), "cron") {
 		return ai.RoleCron
 	}
+	if _, role := a.channelModel(msg); role != "" {
+		return role
+	}
 	return ai.RolePrimary
 }
 
-func (a *Agent) chatWithModelForRole(ctx context.Context, req ChatRequest, role string) (ChatResponse, error) {
-	model := a.modelRouter.PickModelForRole(role)
+// pickModel returns the model a request of role goes to. preferred, the
+// model chosen for this turn, stands in for the primary model, so turns
+// running side by side never change each other's model.
+func (a *Agent) pickModel(role string, preferred *ai.ModelConfig) *ai.ModelConfig {
+	if preferred != nil && role == ai.RolePrimary {
+		return a.modelRouter.PickModelPreferring(preferred, role)
+	}
+	return a.modelRouter.PickModelForRole(role)
+}
+
+func (a *Agent) chatWithModelForRole(ctx context.Context, req ChatRequest, role string, preferred *ai.ModelConfig) (ChatResponse, error) {
+	model := a.pickModel(role, preferred)
 	if model == nil {
 		return ChatResponse{}, fmt.Errorf("no current model")
 	}
@@ -234,14 +326,18 @@ func (a *Agent) chatWithModelForRole(ctx context.Context, req ChatRequest, role
 
 	logger.Debug("[AGENT] Using model: %s (provider: %s, role: %s)", model.Name, model.Provider, role)
 
-	resp, err := provider.Chat(ctx, req)
+	resp, err := a.watchedChat(ctx, provider, req, model)
+	if isContextLengthError(err) {
+		resp, err = retryWithSmallerContext(ctx, provider, req, model)
+	}
 	if err == nil {
 		a.modelRouter.RecordSuccess(model)
+		a.recordModelUsage(model, resp.Usage)
 		return resp, nil
 	}
 
 	logger.Warn("[AGENT] Model %s failed (role=%s): %v", model.Name, role, err)
-	a.modelRouter.RecordFailure(model)
+	a.modelRouter.RecordFailure(model, err)
 
 	newModel, failoverErr := a.modelRouter.FailoverForRole(role, model)
 	if failoverErr != nil {
@@ -255,10 +351,11 @@ func (a *Agent) chatWithModelForRole(ctx context.Context, req ChatRequest, role
 		return ChatResponse{}, fmt.Errorf("failed to get provider for failover model %s: %w", newModel.Name, err)
 	}
 
-	resp, err = newProvider.Chat(ctx, req)
+	resp, err = a.watchedChat(ctx, newProvider, req, newModel)
 	if err == nil {
 		a.modelRouter.RecordSuccess(newModel)
-		if role == ai.RolePrimary && a.modelRouter.ShouldRotatePrimary(model) {
+		a.recordModelUsage(newModel, resp.Usage)
+		if role == ai.RolePrimary && preferred == nil && a.modelRouter.ShouldRotatePrimary(model) {
 			if switchErr := a.modelRouter.SwitchToModel(newModel.Name, true); switchErr != nil {
 				logger.Warn("[AGENT] Failed to rotate primary model to %s: %v", newModel.Name, switchErr)
 			} else {
@@ -269,11 +366,26 @@ func (a *Agent) chatWithModelForRole(ctx context.Context, req ChatRequest, role
 	}
 
 	logger.Warn("[AGENT] Failover model %s also failed: %v", newModel.Name, err)
-	a.modelRouter.RecordFailure(newModel)
+	a.modelRouter.RecordFailure(newModel, err)
 
 	return ChatResponse{}, fmt.Errorf("all models failed, last error: %w", err)
 }
 
+// watchedChat runs a provider call under the watchdog so a hung call can be found and cancelled.
+func (a *Agent) watchedChat(ctx context.Context, provider Provider, req ChatRequest, model *ai.ModelConfig) (ChatResponse, error) {
+	ctx, done := a.watchdog.Begin(ctx, "provider", model.Name, currentConversationKey(ctx))
+	defer done()
+	return provider.Chat(ctx, fitRequestToModel(req, model))
+}
+
+func currentConversationKey(ctx context.Context) string {
+	msg := turnMessage(ctx)
+	if msg.Platform == "" {
+		return ""
+	}
+	return conversationKeyOf(msg)
+}
+
 func (a *Agent) getProviderForModel(model *ai.ModelConfig, role string) (Provider, error) {
 	if model == nil {
 		return nil, fmt.Errorf("model is nil")
@@ -288,6 +400,9 @@ func (a *Agent) getProviderForModel(model *ai.ModelConfig, role string) (Provide
 		return nil, err
 	}
 	key := model.Provider + ":" + model.Code + ":" + apiKey
+	if model.TextToolCalling() {
+		key += ":text-tools"
+	}
 
 	a.providerMu.RLock()
 	if provider, ok := a.providerCache[key]; ok {
@@ -307,6 +422,9 @@ func (a *Agent) getProviderForModel(model *ai.ModelConfig, role string) (Provide
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider %s: %w", model.Provider, err)
 	}
+	if model.TextToolCalling() {
+		provider = newTextToolProvider(provider)
+	}
 
 	a.providerCache[key] = provider
 	return provider, nil
@@ -463,17 +581,18 @@ func New(cfg Config) (*Agent, error) {
 	}
 
 	modelRouter := ai.NewModelRouter(registry, cooldownDuration)
+	budgets := ai.NewBudgets()
+	modelRouter.SetBudgets(budgets)
 
-	exeDir := getExecutableDir()
-	if exeDir == "" {
-		exeDir = "."
-	}
-
-	dbPath := filepath.Join(exeDir, ".coco.db")
+	dbPath := datadir.Path(".coco.db")
 	persistStore, err := persist.NewStore(dbPath)
 	if err != nil {
 		return nil, err
 	}
+	if err := unlockStore(persistStore, configCfg.Storage); err != nil {
+		persistStore.Close()
+		return nil, err
+	}
 
 	memory := NewMemory(persistStore, 200)
 
@@ -490,7 +609,7 @@ func New(cfg Config) (*Agent, error) {
 
 	var ragMemory *RAGMemory
 	if effectiveEmbedding.Enabled {
-		ragMemory, err = NewRAGMemory(effectiveEmbedding)
+		ragMemory, err = NewRAGMemory(effectiveEmbedding, configCfg.Memory, persistStore)
 		if err != nil {
 			log.Printf("[AGENT] Failed to initialize RAG memory: %v", err)
 		}
@@ -503,12 +622,11 @@ func New(cfg Config) (*Agent, error) {
 		log.Printf("[AGENT] Markdown semantic search disabled: %v", err)
 	}
 	markdownMemory.StartWatcher(10 * time.Second)
-	if err := ensureWorkspaceContractFiles(); err != nil {
-		log.Printf("[AGENT] Failed to initialize workspace contract files: %v", err)
-	}
+	runStartupSelfCheck(persistStore, registry)
 
 	agent := &Agent{
 		modelRouter:        modelRouter,
+		budgets:            budgets,
 		registry:           registry,
 		providerCache:      make(map[string]Provider),
 		providerKeyCursor:  make(map[string]int),
@@ -519,13 +637,20 @@ func New(cfg Config) (*Agent, error) {
 		subSessions:        NewSubSessionStore(),
 		autoApprove:        cfg.AutoApprove,
 		customInstructions: cfg.CustomInstructions,
+		instructionsFile:   cfg.InstructionsFile,
 		configPath:         config.ConfigPath(),
+		loadedConfig:       configCfg,
 		persistStore:       persistStore,
 		firstMessageSent:   make(map[string]bool),
 		bootstrapSent:      make(map[string]bool),
 		searchRegistry:     searchRegistry,
 		searchManager:      searchManager,
 		remoteCron:         newRemoteCronClient(configCfg),
+		fileLinks:          newFileLinkClient(configCfg),
+		workspaceGit:       newWorkspaceVersioner(configCfg.Memory.GitVersioning),
+	}
+	if err := agent.workspaceGit.Commit("baseline"); err != nil {
+		log.Printf("[AGENT] Failed to record workspace baseline: %v", err)
 	}
 	agent.applySecurityConfig(
 		cfg.AllowedPaths,
@@ -535,9 +660,37 @@ func New(cfg Config) (*Agent, error) {
 		cfg.AllowFrom,
 		cfg.RequireMentionInGroup,
 	)
+	agent.applyToolProfiles(configCfg.Security.Profiles, configCfg.Security.DefaultProfile)
+	agent.applyPlanApproval(configCfg.Security.PlanApproval, configCfg.Security.PlanApprovalTools)
+	agent.applyToolTimeouts(configCfg.Tools.Timeouts)
+	agent.applyAskMissing(configCfg.Tools.AskMissing)
+	agent.applyArtifactThreshold(configCfg.Tools.ArtifactThreshold)
+	agent.applyFileSend(configCfg.Tools.FileSend)
+	agent.applyUnfurl(configCfg.Tools.Unfurl)
+	agent.applyPromptSections(configCfg.PromptSections)
+	agent.applyVoice(configCfg.Voice.TTS)
+	agent.applyOCR(configCfg.OCR)
+	agent.applyFocus(configCfg.Focus)
+	agent.applyCalendar(configCfg.Calendar)
+	agent.applyQueue(configCfg.Queue)
+	agent.applyTracking(configCfg.Tracking)
+	agent.applyTravel(configCfg.Travel)
+	agent.applyBriefing(configCfg.Briefing)
+	agent.applyProactive(configCfg.Proactive)
+	agent.applyDND(configCfg.DND)
+	agent.applyTraces(configCfg.Traces)
+	agent.applyRetention(configCfg.Retention)
+	agent.applyBackup(configCfg.Backup, configCfg.Memory.ObsidianVault)
+	agent.applyRituals(configCfg.Rituals, configCfg.Memory.ObsidianVault)
+	agent.applySkillTools(configCfg.Skills)
+	agent.applyPlanner(configCfg.Planner)
+	agent.applyRouting(configCfg.Routing)
+	agent.restoreModelSpend()
+	agent.restoreModelHealth()
 	agent.refreshRuntimeSecurityConfig()
 
 	agent.initializeDailyReport()
+	agent.initWatchdog(configCfg.Watchdog)
 
 	return agent, nil
 }
@@ -571,7 +724,7 @@ func normalizeAllowFrom(values []string) []string {
 func (a *Agent) applySecurityConfig(allowedPaths []string, disableFileTools bool, blockedCommands []string, requireConfirmation []string, allowFrom []string, requireMentionInGroup bool) {
 	blocked := security.NormalizeCommandPatterns(blockedCommands, security.DefaultBlockedCommandPatterns)
 	requireConfirm := security.NormalizeCommandPatterns(requireConfirmation, nil)
-	normalizedAllowFrom := normalizeAllowFrom(allowFrom)
+	normalizedAllowFrom, senderProfiles := splitAllowFromProfiles(normalizeAllowFrom(allowFrom))
 
 	a.securityMu.Lock()
 	defer a.securityMu.Unlock()
@@ -581,47 +734,26 @@ func (a *Agent) applySecurityConfig(allowedPaths []string, disableFileTools bool
 	a.blockedCommands = blocked
 	a.requireConfirmCmds = requireConfirm
 	a.allowFrom = normalizedAllowFrom
+	a.senderProfiles = senderProfiles
 	a.requireMentionInGroup = requireMentionInGroup
 }
 
+// refreshRuntimeSecurityConfig reloads the config when the file changed
+// since it was last applied. While WatchConfig runs the watcher keeps the
+// config current and turns do not touch the disk.
 func (a *Agent) refreshRuntimeSecurityConfig() {
 	if strings.TrimSpace(a.configPath) == "" {
 		return
 	}
-
-	info, err := os.Stat(a.configPath)
-	if err != nil {
-		return
-	}
-
 	a.securityMu.RLock()
-	unchanged := !info.ModTime().After(a.configMtime)
+	watched := a.configWatched
 	a.securityMu.RUnlock()
-	if unchanged {
+	if watched {
 		return
 	}
-
-	cfg, err := config.LoadFromPath(a.configPath)
-	if err != nil {
+	if _, err := a.reloadConfig(false); err != nil && !os.IsNotExist(err) {
 		logger.Warn("[Agent] Failed to reload runtime config: %v", err)
-		return
 	}
-
-	a.applySecurityConfig(
-		cfg.Security.AllowedPaths,
-		cfg.Security.DisableFileTools,
-		cfg.Security.BlockedCommands,
-		cfg.Security.RequireConfirmation,
-		cfg.Security.AllowFrom,
-		cfg.Security.RequireMentionInGroup,
-	)
-	a.applyModelRouterConfig(cfg.ModelCooldown)
-	a.applySearchConfig(cfg.Search)
-
-	a.securityMu.Lock()
-	a.configMtime = info.ModTime()
-	a.securityMu.Unlock()
-	logger.Info("[Agent] Reloaded runtime config from %s", a.configPath)
 }
 
 func (a *Agent) applySearchConfig(searchCfg config.SearchConfig) {
@@ -658,6 +790,11 @@ func (a *Agent) applyModelRouterConfig(modelCooldown string) {
 	}
 
 	router := ai.NewModelRouter(registry, cooldownDuration)
+	router.SetBudgets(a.budgets)
+	if a.modelRouter != nil {
+		router.RestoreHealth(a.modelRouter.Health())
+	}
+	router.OnHealthChange(a.saveModelHealth)
 	if currentModelName != "" {
 		_ = router.SwitchToModel(currentModelName, true)
 	}
@@ -731,21 +868,11 @@ func isSenderAllowed(msg router.Message, allowFrom []string) bool {
 		return true
 	}
 
-	candidates := []string{
-		strings.ToLower(strings.TrimSpace(msg.UserID)),
-		strings.ToLower(strings.TrimSpace(msg.Username)),
-		strings.ToLower(strings.TrimSpace(msg.Platform + ":" + msg.UserID)),
-		strings.ToLower(strings.TrimSpace(msg.Platform + ":" + msg.Username)),
-	}
-
 	allowed := make(map[string]struct{}, len(allowFrom))
 	for _, v := range allowFrom {
 		allowed[strings.ToLower(strings.TrimSpace(v))] = struct{}{}
 	}
-	for _, candidate := range candidates {
-		if candidate == "" {
-			continue
-		}
+	for _, candidate := range senderCandidates(msg) {
 		if _, ok := allowed[candidate]; ok {
 			return true
 		}
@@ -753,6 +880,25 @@ func isSenderAllowed(msg router.Message, allowFrom []string) bool {
 	return false
 }
 
+// senderCandidates returns the allow_from keys that can match msg, most specific first.
+func senderCandidates(msg router.Message) []string {
+	raw := []string{
+		msg.Platform + ":" + msg.UserID,
+		msg.Platform + ":" + msg.Username,
+		msg.UserID,
+		msg.Username,
+	}
+	out := make([]string, 0, len(raw))
+	for _, c := range raw {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || strings.HasSuffix(c, ":") {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
 func isGroupConversation(msg router.Message) bool {
 	meta := msg.Metadata
 	if len(meta) == 0 {
@@ -801,33 +947,24 @@ func isMessageExplicitlyMentioned(msg router.Message) bool {
 	return strings.Contains(text, "@")
 }
 
-// initializeDailyReport initializes the daily report functionality
+// initializeDailyReport loads the latest daily report and, when
+// yesterday's is missing, writes it in the background.
 func (a *Agent) initializeDailyReport() {
-	yesterday := persist.GetYesterdayDate()
-	report, err := a.persistStore.GetDailyReport(yesterday, "default")
-
-	if err != nil || report == nil {
-		a.generateDailyReport(yesterday)
+	if a.persistStore == nil {
+		return
 	}
-
 	latest, _ := a.persistStore.GetLatestDailyReport("default")
-	a.latestReport = latest
-}
+	a.setLatestReport(latest)
 
-// generateDailyReport generates a daily report for a specific date
-func (a *Agent) generateDailyReport(date string) {
-	report := &persist.DailyReport{
-		Date:      date,
-		UserID:    "default",
-		Summary:   "系统启动时自动生成的日报",
-		Content:   fmt.Sprintf("日报自动生成于 %s", time.Now().Format(time.RFC3339)),
-		Tasks:     []persist.TaskItem{},
-		Calendars: []persist.CalendarItem{},
-	}
-
-	if err := a.persistStore.SaveDailyReport(report); err != nil {
-		log.Printf("[AGENT] Failed to save daily report: %v", err)
+	yesterday := persist.GetYesterdayDate()
+	if report, err := a.persistStore.GetDailyReport(yesterday, "default"); err == nil && report != nil {
+		return
 	}
+	go func() {
+		if _, err := a.generateDailyReport(context.Background(), yesterday); err != nil {
+			log.Printf("[AGENT] Failed to generate daily report: %v", err)
+		}
+	}()
 }
 
 // isFirstMessage checks if this is the first message from a user
@@ -857,18 +994,19 @@ func (a *Agent) consumeBootstrapOnce(key string) bool {
 
 // getReportNotification gets the report notification message
 func (a *Agent) getReportNotification() string {
-	if a.latestReport == nil {
+	report := a.currentReport()
+	if report == nil {
 		return ""
 	}
 
-	notification := fmt.Sprintf("📋 今日日报 (%s)\n", a.latestReport.Date)
-	if a.latestReport.Summary != "" {
-		notification += fmt.Sprintf("摘要: %s\n\n", a.latestReport.Summary)
+	notification := fmt.Sprintf("📋 今日日报 (%s)\n", report.Date)
+	if report.Summary != "" {
+		notification += fmt.Sprintf("摘要: %s\n\n", report.Summary)
 	}
 
-	if len(a.latestReport.Tasks) > 0 {
+	if len(report.Tasks) > 0 {
 		notification += "📌 当前任务:\n"
-		for _, task := range a.latestReport.Tasks {
+		for _, task := range report.Tasks {
 			status := "⭕"
 			if task.Status == "completed" {
 				status = "✅"
@@ -880,9 +1018,9 @@ func (a *Agent) getReportNotification() string {
 		notification += "\n"
 	}
 
-	if len(a.latestReport.Calendars) > 0 {
+	if len(report.Calendars) > 0 {
 		notification += "📅 日历事件:\n"
-		for _, cal := range a.latestReport.Calendars {
+		for _, cal := range report.Calendars {
 			notification += fmt.Sprintf("  - %s (%s)\n", cal.Title, cal.StartTime)
 		}
 	}
@@ -891,10 +1029,14 @@ func (a *Agent) getReportNotification() string {
 }
 
 // handleBuiltinCommand handles special commands without calling AI
-func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool) {
+func (a *Agent) handleBuiltinCommand(ctx context.Context, msg router.Message) (router.Response, bool) {
 	text := strings.TrimSpace(msg.Text)
 	textLower := strings.ToLower(text)
-	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	convKey := conversationKeyOf(msg)
+
+	if denial, denied := a.denyAdminCommand(msg, text); denied {
+		return router.Response{Text: denial}, true
+	}
 
 	// Exact match commands
 	switch textLower {
@@ -910,7 +1052,11 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 
 会话管理:
   /new, /reset    开始新对话，清除历史
+  /fork 话题      把当前对话分叉成新话题（独立的历史和设置）
+  /threads        列出或切换话题（/threads main 回到主线）
   /status         查看当前会话状态
+  /incognito on   开启无痕模式（不保存、不记忆，/incognito off 退出）
+  /dnd on 2h      勿扰两小时（主动消息先暂存，结束后汇总；/dnd off 关闭）
 
 思考模式:
   /think off      关闭深度思考
@@ -924,7 +1070,25 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 
 其他:
   /whoami         查看用户信息
+  /debug          查看调试信息（含今日最慢工具）
+  /prompt debug   查看系统提示词各部分的 token 占用（/prompt off skills 关闭本会话的某部分）
+  /context        检查上下文：目标模型和窗口、加载的工作区文件、历史和工具占用
+  /feedback       查看近 7 天满意度趋势
+  /history 文件   查看工作区文件最近修改（需开启 git_versioning）
+  /revert 文件    撤销该文件最近一次修改
+  /undo           撤销上一轮的文件写入、日程和定时任务
+  /recall-from    引入另一个对话的上下文（/recall-from telegram 关键词）
+  /project        查看长期项目进度（/project status 项目名 看详情）
+  /timer          查看本周计时统计（开始计时：任务 #分类，停止计时）
+  /sync           立即跨设备同步工作区（需开启 sync）
+  /config reload  重新加载配置（平台等设置需重启）
+  /news           立即生成早安简报（/news follow|mute 话题，/news on 每日推送）
+  展开第2点       展开上一份网页/文档摘要的某个要点（也可 /expand 2）
+  /secret         管理本地加密密钥库（set/list/del）
+  /approve        执行待确认的操作（/reject 取消，/pending 查看）
+  /cancel         中止本会话正在进行的请求
   /model          查看当前模型
+  /models         查看各模型健康状态（失败次数、冷却、最近错误）
   /tools          列出可用工具
   /help           显示帮助
 
@@ -933,6 +1097,7 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 
 	case "/new", "/reset", "/clear", "新对话", "清除历史":
 		a.memory.Clear(convKey)
+		a.dialogs.clear(convKey)
 		a.sessions.Clear(convKey)
 		return router.Response{
 			Text: "已开始新对话，历史记录和会话设置已重置。",
@@ -941,17 +1106,61 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 	case "/status", "状态":
 		history := a.memory.GetHistory(convKey)
 		settings := a.sessions.Get(convKey)
-		return router.Response{
-			Text: fmt.Sprintf(`会话状态:
+		status := fmt.Sprintf(`会话状态:
 - 平台: %s
 - 用户: %s
 - 历史消息: %d 条
 - 思考模式: %s
 - 详细模式: %v
 - AI 模型: %s`,
-				msg.Platform, msg.Username, len(history),
-				settings.ThinkingLevel, settings.Verbose, a.currentModelName()),
-		}, true
+			msg.Platform, msg.Username, len(history),
+			settings.ThinkingLevel, settings.Verbose, a.currentModelName())
+		if topic := msg.Metadata[topicMetadataKey]; topic != "" {
+			status += "\n- 话题: " + topicLabel(topic) + "（/threads main 回到主线）"
+		}
+		if a.isIncognito(convKey) {
+			status += "\n- 无痕模式: 🕶️ 开启（消息不保存、不记忆）"
+		}
+		if on, _ := a.dndState(msg.Platform, msg.ChannelID, time.Now()); on {
+			status += "\n- 勿扰: 🌙 开启（/dnd 查看）"
+		}
+		if tasks := a.formatTaskStatus(timeUserID(msg)); tasks != "" {
+			status += "\n" + tasks
+		}
+		if queue := a.formatQueueStatus(); queue != "" {
+			status += "\n" + queue
+		}
+		return router.Response{Text: status}, true
+
+	case "/debug", "调试":
+		history := a.memory.GetHistory(convKey)
+		debugText := fmt.Sprintf("调试信息:\n- 会话: %s\n- 历史消息: %d 条\n- AI 模型: %s\n\n",
+			convKey, len(history), a.currentModelName())
+		slowest := a.formatSlowestTools(persist.GetTodayDate(), 5)
+		if slowest == "" {
+			slowest = "今日暂无工具调用记录"
+		}
+		return router.Response{Text: debugText + slowest}, true
+
+	case "/undo", "撤销":
+		return router.Response{Text: a.undoLastActions(ctx, convKey)}, true
+
+	case "/feedback", "满意度":
+		summary := a.formatSatisfaction(7)
+		if summary == "" {
+			summary = "近 7 天暂无满意度评价（在 channels.<频道>.feedback 中开启）"
+		}
+		return router.Response{Text: summary}, true
+
+	case "/sync", "同步":
+		if a.workspaceSync == nil {
+			return router.Response{Text: "跨设备同步未开启（在 .coco.yaml 中配置 sync.enabled 和 sync.passphrase）"}, true
+		}
+		summary, err := a.runWorkspaceSync(context.Background())
+		if err != nil {
+			return router.Response{Text: fmt.Sprintf("同步失败: %v", err)}, true
+		}
+		return router.Response{Text: summary}, true
 
 	case "/model", "模型":
 		return router.Response{
@@ -962,23 +1171,26 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 		toolsText := `可用工具:
 
 📁 文件操作:
-  file_send, file_list, file_read, file_write, file_trash, file_list_old
+  file_send, file_share, file_list, file_read, file_write, file_edit, file_trash, file_list_old
+  file_watch
 
-📅 日历 (macOS):
+📅 日历:
   calendar_today, calendar_list_events, calendar_create_event
-  calendar_search, calendar_delete
+  calendar_search, calendar_delete, calendar_freebusy
 
-✅ 提醒事项 (macOS):
+✅ 提醒事项:
   reminders_list, reminders_add, reminders_complete, reminders_delete
 
-📝 备忘录 (macOS):
+📝 备忘录:
   notes_list, notes_read, notes_create, notes_search
 
+` + formatPIMSection() + `
+
 🌤 天气:
   weather_current, weather_forecast
 
 🌐 网页:
-  web_search, web_fetch, open_url
+  web_search, web_fetch, summarize, open_url, http_request
 
 📋 剪贴板:
   clipboard_read, clipboard_write
@@ -986,8 +1198,11 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 🔔 通知:
   notification_send
 
-📸 截图:
-  screenshot
+📸 截图与识字:
+  screenshot, image_ocr
+
+📄 文档:
+  document_read
 
 🎵 音乐 (macOS):
   music_play, music_pause, music_next, music_previous
@@ -996,8 +1211,32 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 💻 系统:
   system_info, shell_execute, process_list
 
+🗄 数据库:
+  db_query
+
+⏱ 计时:
+  timer_start, timer_stop, timer_report, focus_session
+
+✅ 任务:
+  task_add, task_update, task_list, task_complete
+
+📇 通讯录:
+  contacts_add, contacts_list, contacts_remove, message_send_to, broadcast
+
+🏷 价格关注:
+  price_watch
+
+📦 快递:
+  parcel_track
+
+✈️ 出行:
+  flight_status, train_status, itinerary
+
+☀️ 简报:
+  news_briefing
+
 ⏰ 定时任务:
-  cron_create, cron_list, cron_delete, cron_pause, cron_resume` + formatSkillsSection()
+  cron_create, remind_once, cron_list, cron_delete, cron_pause, cron_resume` + formatSkillsSection()
 		return router.Response{Text: toolsText}, true
 
 	case "/verbose on", "详细模式开":
@@ -1025,6 +1264,73 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 		return router.Response{Text: "思考模式: 深度"}, true
 	}
 
+	if reply, ok := a.handleIncognitoCommand(convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleTopicCommand(msg, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleDNDCommand(msg, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handlePromptCommand(msg, convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleContextCommand(msg, convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleHistoryCommand(text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleRecallCommand(msg, convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleProjectCommand(text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleModelsCommand(text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleConfigCommand(text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleNewsCommand(ctx, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleRitualCommand(msg, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleExpandCommand(ctx, convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleTimerCommand(msg, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := handleSecretCommand(text); ok {
+		return router.Response{Text: reply}, true
+	}
+
+	if reply, ok := a.handleCancelCommand(convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+	if reply, ok := a.handlePlanApprovalCommand(ctx, convKey, text); ok {
+		return router.Response{Text: reply}, true
+	}
+
 	return router.Response{}, false
 }
 
@@ -1032,9 +1338,15 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 func (a *Agent) SetCronScheduler(s *cronpkg.Scheduler) {
 	a.cronScheduler = s
 	a.setupDailyReportJob()
+	if a.persistStore != nil {
+		a.ensureBriefingJob()
+	}
+	a.ensureRitualJobs()
 }
 
-// setupDailyReportJob sets up the daily report cron job
+// setupDailyReportJob schedules report_regenerate every night. A job from
+// before the report was built from real data asked the model to write it
+// freehand; it is replaced.
 func (a *Agent) setupDailyReportJob() {
 	if a.cronScheduler == nil {
 		return
@@ -1042,45 +1354,64 @@ func (a *Agent) setupDailyReportJob() {
 
 	jobs := a.cronScheduler.ListJobs()
 	for _, job := range jobs {
-		if job.Name == "每日日报生成" {
+		if job.Name != dailyReportJobName {
+			continue
+		}
+		if job.Tool == "report_regenerate" {
 			log.Printf("[AGENT] Daily report job already exists")
 			return
 		}
+		if err := a.cronScheduler.RemoveJob(job.ID); err != nil {
+			log.Printf("[AGENT] Failed to replace daily report job: %v", err)
+			return
+		}
 	}
 
-	prompt := `请生成今日日报，包括：
-1. 对昨天的对话内容进行整理和总结
-2. 分析当前的任务状态
-3. 检查日历事件
-4. 生成今日任务清单
-5. 调整定时任务（如有需要）
-
-请使用中文回复。`
-
-	_, err := a.cronScheduler.AddJobWithPrompt(
-		"每日日报生成",
+	job, err := a.cronScheduler.AddJobWithTag(
+		dailyReportJobName,
+		"assistant-task",
 		"0 3 * * *", // 每天凌晨3点
-		prompt,
-		"local",
-		"daily-report",
-		"default",
+		"report_regenerate",
+		map[string]any{},
 	)
 
 	if err != nil {
 		log.Printf("[AGENT] Failed to create daily report job: %v", err)
 	} else {
+		a.cronScheduler.SetProvenance(job.ID, provenance.Record{Origin: provenance.OriginSystem, Actor: "coco", Reason: "daily report"})
 		log.Printf("[AGENT] Daily report job created successfully")
 	}
 }
 
 // ExecuteTool implements the cron.ToolExecutor interface
 func (a *Agent) ExecuteTool(ctx context.Context, toolName string, arguments map[string]any) (any, error) {
-	result := callToolDirect(ctx, toolName, arguments)
-	return result, nil
+	return a.scheduledTool(ctx, toolName, func(ctx context.Context) any {
+		// The price, parcel, trip, briefing, ritual and report jobs need the agent's store and notifier.
+		if toolName == "price_watch" && getString(arguments, "action") == "check" {
+			return a.checkPriceWatches(ctx, "")
+		}
+		if toolName == "parcel_track" && getString(arguments, "action") == "check" {
+			return a.checkParcels(ctx, "")
+		}
+		if toolName == "itinerary" && getString(arguments, "action") == "remind" {
+			return a.remindTrips(ctx, time.Now())
+		}
+		if toolName == "news_briefing" && getString(arguments, "action") == "send" {
+			return a.sendBriefings(ctx, time.Now())
+		}
+		if toolName == "ritual" && getString(arguments, "action") == "start" {
+			return a.runRitual(getString(arguments, "name"))
+		}
+		if toolName == "report_regenerate" {
+			return a.executeReportRegenerate(ctx, arguments)
+		}
+		return callToolDirect(ctx, toolName, arguments)
+	})
 }
 
 // ExecutePrompt runs a full AI conversation with tools and returns the text response.
-// Used by cron scheduler for prompt-based jobs.
+// Used by cron scheduler for prompt-based jobs, which wait behind live chats
+// and are retried when they fail.
 func (a *Agent) ExecutePrompt(ctx context.Context, platform, channelID, userID, prompt string) (string, error) {
 	msg := router.Message{
 		Platform:  platform,
@@ -1089,7 +1420,7 @@ func (a *Agent) ExecutePrompt(ctx context.Context, platform, channelID, userID,
 		Username:  "cron",
 		Text:      prompt,
 	}
-	resp, err := a.HandleMessage(ctx, msg)
+	resp, err := a.scheduledPrompt(ctx, msg)
 	if err != nil {
 		return "", err
 	}
@@ -1118,10 +1449,6 @@ func (a *Agent) planOrchestration(ctx context.Context, userInput string, memoryR
 		return nil, fmt.Errorf("model router not initialized")
 	}
 
-	plannerModel := a.selectPlannerModel()
-	restore := a.switchModelTemporarily(plannerModel)
-	defer restore()
-
 	systemPrompt := `You are a response orchestration planner.
 Output STRICT JSON only with keys:
 - need_clarification (boolean)
@@ -1142,19 +1469,35 @@ Rules:
 	}
 	userPrompt := fmt.Sprintf("User input:\n%s\n\nKnown memory snippet:\n%s", strings.TrimSpace(userInput), recall)
 
-	resp, err := a.chatWithModel(ctx, ChatRequest{
+	settings := a.currentPlanner()
+	if plan := a.planLocally(ctx, settings, systemPrompt, userPrompt); plan != nil {
+		return plan, nil
+	}
+
+	resp, err := a.chatWithModelForRole(ctx, ChatRequest{
 		Messages: []Message{
 			{Role: "user", Content: userPrompt},
 		},
 		SystemPrompt: systemPrompt,
 		Tools:        nil,
 		MaxTokens:    600,
-	})
+	}, a.currentRequestModelRole(ctx), a.selectPlannerModel())
 	if err != nil {
 		return nil, err
 	}
 
-	jsonPayload := extractJSONObject(strings.TrimSpace(resp.Content))
+	plan, err := parseOrchestrationPlan(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+	if settings.record {
+		recordPlannerExample(systemPrompt, userPrompt, plan)
+	}
+	return plan, nil
+}
+
+func parseOrchestrationPlan(content string) (*orchestrationPlan, error) {
+	jsonPayload := extractJSONObject(strings.TrimSpace(content))
 	if jsonPayload == "" {
 		return nil, fmt.Errorf("planner returned non-json content")
 	}
@@ -1173,14 +1516,15 @@ Rules:
 }
 
 func (a *Agent) appendPlannerMemoryRecall(ctx context.Context, queries []string, memoryRecallForPromptBuild *strings.Builder, markdownMemoriesSection *string) {
-	if a.markdownMemory == nil || !a.markdownMemory.IsEnabled() || len(queries) == 0 {
+	md := a.markdownStore()
+	if md == nil || !md.IsEnabled() || len(queries) == 0 {
 		return
 	}
 
 	seenPath := map[string]bool{}
 	var lines []string
 	for _, q := range queries {
-		hits, err := a.markdownMemory.Search(ctx, q, 3)
+		hits, err := md.Search(ctx, q, 3)
 		if err != nil {
 			logger.Warn("[Agent] planner memory search failed for %q: %v", q, err)
 			continue
@@ -1260,126 +1604,24 @@ func extractJSONObject(content string) string {
 	return strings.TrimSpace(content[start : end+1])
 }
 
-func hasSkill(m *ai.ModelConfig, skill string) bool {
-	if m == nil {
-		return false
-	}
-	for _, s := range m.Skills {
-		if strings.EqualFold(strings.TrimSpace(s), skill) {
-			return true
-		}
-	}
-	return false
-}
-
-func speedRank(speed string) int {
-	switch strings.ToLower(strings.TrimSpace(speed)) {
-	case "fast":
-		return 3
-	case "medium":
-		return 2
-	case "slow":
-		return 1
-	default:
-		return 0
-	}
-}
-
 func (a *Agent) selectPlannerModel() *ai.ModelConfig {
-	models := a.modelRouter.ListModelsForRole(ai.RoleExpert)
-	if len(models) == 0 {
-		models = a.modelRouter.ListModels()
-	}
-	if len(models) == 0 {
-		return nil
-	}
-	best := models[0]
-	bestScore := -1
-	for _, m := range models {
-		score := speedRank(m.Speed)*100 + m.IntellectRank()*10
-		if hasSkill(m, "thinking") {
-			score += 2
-		}
-		if score > bestScore {
-			best = m
-			bestScore = score
-		}
-	}
-	return best
+	return a.modelRouter.PickModelByPolicy(a.currentRouting().planner, plannerMinIntellect)
 }
 
-func (a *Agent) selectFinalModel(complexity string) *ai.ModelConfig {
-	models := a.modelRouter.ListModelsForRole(ai.RoleExpert)
-	if len(models) == 0 {
-		models = a.modelRouter.ListModels()
-	}
-	if len(models) == 0 {
-		return nil
-	}
-
-	best := models[0]
-	bestScore := -1
-	for _, m := range models {
-		score := m.IntellectRank() * 100
-		if hasSkill(m, "thinking") {
-			score += 25
-		}
-		switch complexity {
-		case "simple":
-			score += speedRank(m.Speed) * 8
-		case "complex":
-			score += m.IntellectRank() * 10
-		default:
-			score += speedRank(m.Speed) * 3
-		}
-		if score > bestScore {
-			best = m
-			bestScore = score
-		}
-	}
-	return best
-}
-
-func (a *Agent) switchModelTemporarily(target *ai.ModelConfig) func() {
-	if target == nil || a.modelRouter == nil {
-		return func() {}
-	}
-
-	current := a.modelRouter.GetCurrentModel()
-	if current != nil && current.Name == target.Name {
-		return func() {}
-	}
-
-	var previous string
-	if current != nil {
-		previous = current.Name
-	}
-
-	if err := a.modelRouter.SwitchToModel(target.Name, true); err != nil {
-		logger.Warn("[Agent] failed to switch to model %s: %v", target.Name, err)
-		return func() {}
-	}
-	logger.Debug("[Agent] temporary model switch: %s", target.Name)
-
-	return func() {
-		if previous == "" {
-			return
-		}
-		if err := a.modelRouter.SwitchToModel(previous, true); err != nil {
-			logger.Warn("[Agent] failed to restore model %s: %v", previous, err)
-		}
-	}
+func (a *Agent) selectFinalModel(msg router.Message, complexity string) *ai.ModelConfig {
+	return a.modelRouter.PickModelByPolicy(a.answerPolicy(msg, complexity), complexityIntellect(complexity))
 }
 
 func (a *Agent) persistTurnAndLongMemory(ctx context.Context, convKey string, msg router.Message, assistantText string) {
+	rag := a.ragStore()
 	a.memory.AddExchange(convKey,
 		Message{Role: "user", Content: msg.Text},
 		Message{Role: "assistant", Content: assistantText},
 	)
 
-	if a.ragMemory != nil && a.ragMemory.IsEnabled() {
+	if rag != nil && rag.IsEnabled() && !a.isIncognito(convKey) {
 		conversationText := fmt.Sprintf("User: %s\nAssistant: %s", msg.Text, assistantText)
-		err := a.ragMemory.AddMemory(ctx, MemoryItem{
+		err := rag.AddMemory(ctx, MemoryItem{
 			ID:      fmt.Sprintf("conv-%s-%d", convKey, time.Now().Unix()),
 			Type:    "conversation",
 			Content: conversationText,
@@ -1403,12 +1645,37 @@ func (a *Agent) persistTurnAndLongMemory(ctx context.Context, convKey string, ms
 	}
 }
 
-// HandleMessage processes a message and returns a response
+// HandleMessage processes a message and returns a response. Messages wait
+// in the task queue ahead of scheduled jobs. A platform message delivered
+// a second time gets no response.
 func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.Response, error) {
+	if a.duplicateDelivery(msg) {
+		return router.Response{}, nil
+	}
+	msg = a.withActiveTopic(msg)
+	a.noteUserMessage(msg, time.Now())
+	if resp, handled := a.handleQuickCommand(ctx, msg); handled {
+		return resp, nil
+	}
+	return a.queuedMessage(ctx, msg, taskqueue.Interactive)
+}
+
+func (a *Agent) handleMessage(ctx context.Context, msg router.Message) (reply router.Response, replyErr error) {
+	rag := a.ragStore()
+	md := a.markdownStore()
+	// Turns of one conversation run one at a time; "/cancel" skips the line
+	// to reach the turn it aborts.
+	if !isCancelCommand(msg.Text) {
+		unlock, err := a.convLocks.lock(ctx, ConversationKey(msg.Platform, msg.ChannelID, msg.UserID))
+		if err != nil {
+			return router.Response{}, err
+		}
+		defer unlock()
+	}
+	ctx = withTurn(ctx, msg)
+
 	a.refreshRuntimeSecurityConfig()
-	a.currentMsg = msg
-	a.cronCreatedCount = 0
-	logger.Info("[Agent] Processing message from %s: %s (model: %s)", msg.Username, msg.Text, a.currentModelName())
+	logger.Info("[Agent] Processing message from %s: %s (model: %s)", msg.Username, logSafeText(msg.Text), a.currentModelName())
 
 	if denial, drop := a.enforceMessageSecurityPolicy(msg); drop {
 		if denial == "" {
@@ -1417,21 +1684,76 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 		return router.Response{Text: denial}, nil
 	}
 
+	if len(msg.Attachments) > 0 {
+		msg = a.readImageAttachments(ctx, msg)
+		msg = a.readDocumentAttachments(ctx, msg)
+		turnOf(ctx).msg = msg
+	}
+
+	if resp, handled := a.captureFeedback(msg); handled {
+		return resp, nil
+	}
+
+	// Side effects of this message form one action set for "/undo"
+	a.undo.begin(conversationKeyOf(msg), msg.Text)
+
 	// Handle built-in commands
-	if resp, handled := a.handleBuiltinCommand(msg); handled {
+	if resp, handled := a.handleBuiltinCommand(ctx, msg); handled {
+		return resp, nil
+	}
+
+	// A running form dialog takes the message as its next answer
+	if resp, handled := a.continueDialog(ctx, msg); handled {
 		return resp, nil
 	}
 
+	// Tracking numbers and booking confirmations in the message are followed
+	// without being asked, except in incognito
+	var notes []string
+	if !a.isIncognito(conversationKeyOf(msg)) {
+		for _, note := range []string{a.autoTrackParcels(msg), a.autoSaveItinerary(msg)} {
+			if note != "" {
+				notes = append(notes, note)
+			}
+		}
+	}
+	if len(notes) > 0 {
+		defer func() {
+			if replyErr == nil {
+				reply.Text = strings.TrimRight(reply.Text, "\n") + "\n\n" + strings.Join(notes, "\n")
+			}
+		}()
+	}
+
+	// Recognized business intents run their workflow instead of the model
+	if resp, handled := a.routeIntent(ctx, msg); handled {
+		return resp, nil
+	}
+
+	// A message keeper held while coco was offline says when it was sent
+	if noted := noteQueuedMessage(msg, time.Now()); noted.Text != msg.Text {
+		msg = noted
+		turnOf(ctx).msg = msg
+	}
+
+	// Links in the message come with their title and a preview
+	if unfurled := a.unfurlLinks(ctx, msg); unfurled.Text != msg.Text {
+		msg = unfurled
+		turnOf(ctx).msg = msg
+	}
+
 	// Generate conversation key
-	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	convKey := conversationKeyOf(msg)
+	ctx, endTurn := a.turns.begin(ctx, convKey)
+	defer endTurn()
 	a.ensureHeartbeatJobsForConversation(msg)
 	bootstrapPrompt := ""
 	if a.consumeBootstrapOnce(convKey) {
 		bootstrapPrompt = loadWorkspaceBootstrapPrompt()
 	}
 
-	// Build the tools list
-	tools := a.buildToolsList()
+	// Build the tools list, limited to what the sender's profile and the channel permit
+	tools := a.filterToolsForChannel(filterToolsForProfile(a.buildToolsList(), a.toolProfileFor(msg)), msg)
 
 	// Get conversation history
 	history := a.memory.GetHistory(convKey)
@@ -1482,6 +1804,10 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 	aboutMe := loadPromptFile("ABOUTME.md")
 	systemContent := loadPromptFile("SYSTEM.md")
 	workspacePromptBundle := loadWorkspacePromptBundle()
+	persona := a.channelPersona(msg)
+	if persona != "" {
+		aboutMe = persona
+	}
 
 	// Fallback to default if files not found
 	if aboutMe == "" {
@@ -1493,8 +1819,9 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 	var memoriesSection string
 	var preferencesSection string
 	var memoryRecallForPromptBuild strings.Builder
-	if a.markdownMemory != nil && a.markdownMemory.IsEnabled() {
-		markdownMemories, err := a.markdownMemory.Search(ctx, msg.Text, 6)
+	sections := a.promptSectionsFor(msg, convKey)
+	if md != nil && md.IsEnabled() && sections.on(promptSectionMarkdownMemories) {
+		markdownMemories, err := md.Search(ctx, cronRecallQuery(ctx, msg.Text), 6)
 		if err != nil {
 			logger.Warn("[Agent] Failed to search markdown memories: %v", err)
 		} else if len(markdownMemories) > 0 {
@@ -1510,8 +1837,8 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 		}
 	}
 
-	if a.ragMemory != nil && a.ragMemory.IsEnabled() {
-		memories, err := a.ragMemory.SearchMemories(ctx, msg.Text, 5)
+	if rag != nil && rag.IsEnabled() {
+		memories, err := rag.SearchMemories(ctx, cronRecallQuery(ctx, msg.Text), 5)
 		if err == nil && len(memories) > 0 {
 			memoriesSection = "\n\n## Relevant Memories\nHere are some relevant memories from previous conversations that might help you respond:\n"
 			for i, mem := range memories {
@@ -1525,7 +1852,7 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 		}
 
 		// Retrieve user preferences
-		preferences, err := a.ragMemory.SearchMemories(ctx, "user preferences communication style tone format", 3)
+		preferences, err := rag.SearchMemories(ctx, "user preferences communication style tone format", 3)
 		if err == nil && len(preferences) > 0 {
 			preferencesSection = "\n\n## User Preferences\nHere are some known preferences about this user that you should follow:\n"
 			for i, pref := range preferences {
@@ -1552,7 +1879,7 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 		} else if plan != nil {
 			taskComplexity = normalizeTaskComplexity(plan.TaskComplexity)
 			plannerInstruction = strings.TrimSpace(plan.FinalInstruction)
-			if len(plan.MemoryQueries) > 0 {
+			if len(plan.MemoryQueries) > 0 && sections.on(promptSectionMarkdownMemories) {
 				a.appendPlannerMemoryRecall(ctx, plan.MemoryQueries, &memoryRecallForPromptBuild, &markdownMemoriesSection)
 			}
 
@@ -1566,7 +1893,7 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 	}
 
 	// System prompt with actual paths
-	var systemPrompt string
+	var systemPrompt, skillsSection, thinkingSection string
 	if systemContent != "" {
 		systemPrompt = fmt.Sprintf(aboutMe+"%s\n\n"+systemContent,
 			autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg.Username, time.Now().Format("2006-01-02"))
@@ -1582,12 +1909,24 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 ## Available Tools
 
 ### File Operations
-- file_send: Send/transfer a file to the user via messaging platform
+- file_send: Send/transfer a file to the user via messaging platform; files over the platform's upload limit are zipped, split or shared as a download link
+- file_share: Share a file as an expiring download link hosted by Keeper, for large reports or videos or when the user asks for a link
 - file_list: List directory contents (use ~ for executable directory)
 - file_read: Read file contents
 - file_write: Write content to a file (creates parent directories if needed)
+- file_edit: Change part of an existing file with search/replace blocks or a unified diff; prefer it over file_write for edits so nothing else in the file is lost
 - file_trash: Move files to trash (for delete operations)
 - file_list_old: Find old files not modified for N days
+- file_watch: Watch a folder and act on each new file in this chat (e.g. "watch ~/Downloads for new PDFs and summarize them")
+- print_file: Print a local file on the user's printer (use absolute paths)
+- remote_put / remote_get / remote_list: Upload, download and list files on the user's configured S3/WebDAV/OSS storage (e.g. "back up today's report to my NAS"); prefer remote_put over file_send for large files
+- db_query: Answer questions from the user's own databases with SQL (list them by calling without query)
+- http_request: Call an API that has no dedicated tool; configured http profiles add credentials server-side
+
+### Secrets
+- secrets_generate: Generate a password and store it in the encrypted vault by name
+- secrets_list: List vault secret names
+- When the user refers to a stored secret ("use my-nas-password"), write {{secret:my-nas-password}} in http_request headers together with a profile; it is substituted at execution time and plaintext is never shown to you. Other tools and arguments refuse secret references. Never ask the user to paste passwords into chat; point them to /secret set or "coco secrets set".
 
 ### User Schedules & Reminders
 - Use cron_create with tag="user-schedule" to create user's personal schedules, reminders, and calendar events
@@ -1595,7 +1934,18 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 - Use cron_list with tag="user-schedule" to list only user's schedules
 - For assistant's background tasks (daily reports, etc.), use tag="assistant-task"
 
-### Notes (macOS)
+### Tasks
+- task_add: Add a to-do ("记一下周五前交报销") with an optional due date, priority and project; a due date also sets a reminder in this chat
+- task_update / task_complete: Change or finish a task by its number
+- task_list: List open tasks, optionally of one project; prefer tasks over remind_once for things the user has to get done
+
+### Contacts
+- contacts_add: Save a friendly name ("老板", "家庭群") for a chat; with here=true (or no address) it saves the current chat
+- contacts_list / contacts_remove: Show or delete saved contacts
+- message_send_to: Send a message to one or more contacts by name ("告诉老板和家庭群我晚点到"); in cron jobs use it to push results to specific people or groups. Only send what the user asked for, and never invent a contact name
+- broadcast: Announce something to every chat used in the last 30 days, or only channels with given tags ("通知大家我今晚升级，稍后离线"). Call it first without confirm to get the recipient list, show it to the user, and only call again with confirm=true after they agree
+
+### Notes
 - notes_list: List notes
 - notes_read: Read note content
 - notes_create: Create new note
@@ -1629,13 +1979,14 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 
 ### Scheduled Tasks (Cron)
 - cron_create: Create ONE scheduled task with 'prompt' parameter. The AI runs a full conversation each trigger (can use web_search, weather, etc.) and sends the result to the user. For raw tool execution, use 'tool'+'arguments' instead.
+- remind_once: Create a one-shot reminder ("remind me in 20 minutes", "明天下午3点提醒我开会") that fires once and is auto-deleted
 - cron_list: List all scheduled tasks with their status
 - cron_delete: Delete a scheduled task by ID
 - cron_pause: Pause a scheduled task
 - cron_resume: Resume a paused scheduled task
 
 ### Browser Automation (snapshot-then-act pattern)
-- browser_start: Start new browser or connect to existing Chrome via cdp_url (e.g. "127.0.0.1:9222")
+- browser_start: Start new browser or connect to existing Chrome via cdp_url (e.g. "127.0.0.1:9222"), or launch a named profile (e.g. profile "xiaohongshu") whose logins survive restarts
 - browser_navigate: Navigate to a URL (auto-connects to Chrome on port 9222 if available, otherwise launches new)
 - browser_snapshot: Capture accessibility tree with numbered refs
 - browser_click: Click an element by ref number
@@ -1649,6 +2000,7 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 - browser_tab_close: Close a tab
 - browser_status: Check browser state
 - browser_stop: Close browser (or disconnect from external Chrome)
+- browser_network_start_capture / browser_network_get_requests: Record the page's XHR/fetch traffic and read the JSON the site's API returned; to extract lists (search results, posts, prices), start the capture before the UI step that loads them and read the data from the responses instead of the DOM
 
 ## Browser Automation Rules
 You MUST follow the **snapshot-then-act** pattern for ALL browser interactions:
@@ -1685,16 +2037,20 @@ Do NOT waste rounds — try clicking first, inspect only if it fails.
 7. **User schedules with cron** - When user asks for calendar events, reminders, or schedules:
    - Use cron_create with tag="user-schedule"
    - Set a clear 'prompt' describing what to remind the user about
-   - Use 5-field cron format (minute hour day month weekday)
-   - Convert relative dates/time to cron expressions (e.g., 每天下午3点 → "0 15 * * *", 明天下午2:30 → calculate exact time and use cron)
+   - Use 5-field cron format (minute hour day month weekday) for RECURRING schedules (e.g., 每天下午3点 → "0 15 * * *")
+   - For ONE-TIME reminders (e.g., 20分钟后, 明天下午2:30) use remind_once with 'in_minutes' or an exact 'at' time instead of cron
 8. **CRITICAL: Cron job rules** - When user asks for periodic/scheduled tasks:
    - Call cron_create EXACTLY ONCE with the 'prompt' parameter.
    - Example: cron_create(name="motivation", schedule="43 * * * *", prompt="生成一条独特的编程激励鸡汤，鼓励用户写代码创造新产品")
    - NEVER call cron_create multiple times. NEVER use shell_execute or file_write for cron tasks.
 
 Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg.Username, time.Now().Format("2006-01-02"))
-		systemPrompt += thinkingPrompt
-		systemPrompt += formatSkillsSection()
+		thinkingSection = thinkingPrompt
+		systemPrompt += thinkingSection
+		skillsSection = formatSkillsSection()
+		if sections.on(promptSectionSkills) {
+			systemPrompt += skillsSection
+		}
 	}
 
 	if workspacePromptBundle != "" {
@@ -1716,19 +2072,37 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 		systemPrompt += preferencesSection
 	}
 
-	if a.customInstructions != "" {
-		systemPrompt += "\n\n## Custom Instructions\n" + a.customInstructions
+	cronSection := cronContextSection(ctx)
+	systemPrompt += cronSection
+
+	instructions := a.instructions()
+	if instructions != "" {
+		systemPrompt += "\n\n## Custom Instructions\n" + instructions
 	}
 
-	systemPrompt += "\n\n" + a.modelRouter.FormatModelsPrompt()
+	if persona != "" {
+		systemPrompt += "\n\n## Channel Persona\nIn this channel, act as described below. This takes precedence over SOUL.md and IDENTITY.md.\n" + persona
+	}
+
+	modelsPrompt := "\n\n" + a.modelRouter.FormatModelsPrompt()
+	if sections.on(promptSectionModels) {
+		systemPrompt += modelsPrompt
+	}
 
 	// Optional promptbuild integration (disabled by default).
 	// When enabled, any failure falls back to legacy system prompt behavior.
+	reportNotification := ""
+	promptBuildPrompt := ""
 	if isPromptBuildEnabled() {
+		reportNotification = a.getReportNotification()
+		report := reportNotification
+		if !sections.on(promptSectionReport) {
+			report = ""
+		}
 		if pbPrompt, used, err := a.buildPromptWithPromptBuild(
 			msg,
 			thinkingPrompt,
-			a.getReportNotification(),
+			report,
 			strings.TrimSpace(memoryRecallForPromptBuild.String()),
 			plannerInstruction,
 			workspacePromptBundle,
@@ -1736,6 +2110,7 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 		); err != nil {
 			logger.Warn("[Agent] promptbuild failed, fallback to legacy prompt: %v", err)
 		} else if used {
+			promptBuildPrompt = pbPrompt
 			systemPrompt = pbPrompt + "\n\n" + systemPrompt
 		}
 	}
@@ -1744,12 +2119,39 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 		systemPrompt += "\n\n## Planner Instruction\n" + plannerInstruction
 	}
 
-	restoreFinalModel := func() {}
-	if isTwoStageOrchestrationEnabled() {
-		finalModel := a.selectFinalModel(taskComplexity)
-		restoreFinalModel = a.switchModelTemporarily(finalModel)
+	// The answering model belongs to this turn only
+	if channelModel, _ := a.channelModel(msg); channelModel != nil {
+		turnOf(ctx).model = channelModel
+	} else if isTwoStageOrchestrationEnabled() {
+		turnOf(ctx).model = a.selectFinalModel(msg, taskComplexity)
 	}
-	defer restoreFinalModel()
+
+	stats := promptStats{
+		Total: estimateTokens(systemPrompt),
+		Sections: map[string]promptSectionStat{
+			promptSectionModels:           {On: sections.on(promptSectionModels), Tokens: estimateTokens(modelsPrompt), Measured: true},
+			promptSectionSkills:           {On: sections.on(promptSectionSkills), Tokens: estimateTokens(skillsSection), Measured: true},
+			promptSectionReport:           {On: sections.on(promptSectionReport), Tokens: estimateTokens(reportNotification), Measured: true},
+			promptSectionMarkdownMemories: {On: sections.on(promptSectionMarkdownMemories), Tokens: estimateTokens(markdownMemoriesSection), Measured: sections.on(promptSectionMarkdownMemories)},
+		},
+		Parts: measurePromptParts([][2]string{
+			{"promptbuild", promptBuildPrompt},
+			{"BOOTSTRAP.md", bootstrapPrompt},
+			{"工作区文件", workspacePromptBundle},
+			{"思考模式", thinkingSection},
+			{"RAG 记忆", memoriesSection},
+			{"用户偏好", preferencesSection},
+			{"定时任务上下文", cronSection},
+			{"自定义指令", instructions},
+			{"频道人设", persona},
+			{"规划指令", plannerInstruction},
+		}),
+		At: time.Now(),
+	}
+	if model := a.pickModel(a.currentRequestModelRole(ctx), turnOf(ctx).model); model != nil {
+		stats.Model = model.Name
+	}
+	a.promptStats.record(convKey, stats)
 
 	// Call AI provider
 	resp, err := a.chatWithModel(ctx, ChatRequest{
@@ -1759,6 +2161,13 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 		MaxTokens:    4096,
 	})
 	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return router.Response{}, nil // aborted with /cancel, which already replied
+		}
+		if reply, ok := a.offlineReply(ctx); ok {
+			logger.Warn("[Agent] No model answered (%v); used the offline command rules", err)
+			return router.Response{Text: reply}, nil
+		}
 		return router.Response{}, fmt.Errorf("AI error: %w", err)
 	}
 
@@ -1766,6 +2175,8 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 	const maxToolRounds = 20
 	var pendingFiles []router.FileAttachment
 	toolCallCounts := map[string]int{} // track per-tool call counts
+	var loops watchdog.LoopDetector
+	loopStopped := ""
 	for round := range maxToolRounds {
 		if resp.FinishReason != "tool_use" {
 			break
@@ -1777,10 +2188,25 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 			if toolCallCounts[tc.Name] > 1 {
 				logger.Warn("[Agent] Tool %s called %d times (round %d/%d, user: %s)", tc.Name, toolCallCounts[tc.Name], round+1, maxToolRounds, msg.Username)
 			}
+			if a.watchdog != nil && loops.Observe(tc.Name, tc.Input) == a.watchdogCfg.loopThreshold {
+				a.watchdog.Report(watchdog.KindLoop, "tool",
+					fmt.Sprintf("%s called %d times with identical input", tc.Name, a.watchdogCfg.loopThreshold), convKey)
+				if a.watchdogCfg.autoRestart {
+					loopStopped = tc.Name
+				}
+			}
+		}
+		if loopStopped != "" {
+			break
 		}
 
 		toolResults, files := a.processToolCalls(ctx, resp.ToolCalls)
 		pendingFiles = append(pendingFiles, files...)
+		if question := a.dialogs.takePending(convKey); question != "" {
+			// A tool call lacks required arguments; the dialog asks for them.
+			a.persistTurnAndLongMemory(ctx, convKey, msg, question)
+			return router.Response{Text: question, Files: pendingFiles}, nil
+		}
 
 		// Log tool results that look like errors
 		for _, result := range toolResults {
@@ -1813,14 +2239,21 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 			MaxTokens:    4096,
 		})
 		if err != nil {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return router.Response{}, nil // aborted with /cancel, which already replied
+			}
 			return router.Response{}, fmt.Errorf("AI error: %w", err)
 		}
 	}
-	if resp.FinishReason == "tool_use" {
+	if loopStopped != "" {
+		resp.Content = strings.TrimSpace(resp.Content + fmt.Sprintf("\n\n（%s 被反复以相同参数调用，已中止本轮操作。请换个说法或补充信息后重试。）", loopStopped))
+	} else if resp.FinishReason == "tool_use" {
 		logger.Warn("[Agent] Tool loop hit max rounds (%d), forcing stop (user: %s)", maxToolRounds, msg.Username)
 	}
 
+	a.recordRunTrace(convKey, systemPrompt, tools, messages, resp.Content)
 	a.persistTurnAndLongMemory(ctx, convKey, msg, resp.Content)
+	resp.Content = a.askFeedback(ctx, convKey, msg, resp.Content)
 
 	// Track first message (reserved for future use)
 	a.isFirstMessage(convKey)
@@ -1828,7 +2261,13 @@ Current date: %s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, exeDir, msg
 	// Log response at verbose level
 	logger.Debug("[Agent] Response: %s", resp.Content)
 
-	return router.Response{Text: resp.Content, Files: pendingFiles}, nil
+	pendingFiles = append(pendingFiles, a.voiceReply(ctx, msg, resp.Content)...)
+	out := router.Response{Text: resp.Content, Files: pendingFiles}
+	if len(a.planApprovals.peek(convKey)) > 0 {
+		// Lets platforms with buttons render /approve and /reject.
+		out.Metadata = map[string]string{"approval": "pending"}
+	}
+	return out, nil
 }
 
 func (a *Agent) buildPromptWithPromptBuild(
@@ -1855,6 +2294,7 @@ func (a *Agent) buildPromptWithPromptBuild(
 			ChannelID: msg.ChannelID,
 			UserID:    msg.UserID,
 			Limit:     200,
+			Reveal:    a.persistStore.Reveal,
 		},
 		Inputs: map[string]string{
 			"thinking_prompt":       thinkingPrompt,
@@ -1897,6 +2337,13 @@ func formatSkillsSection() string {
 	sb.WriteString("\n\nSkills:\n")
 	for _, s := range eligible {
 		fmt.Fprintf(&sb, "  %s: %s\n", s.Name, s.Description)
+		if len(s.Tools) > 0 {
+			names := make([]string, 0, len(s.Tools))
+			for _, t := range s.Tools {
+				names = append(names, skills.ToolName(s.Name, t.Name))
+			}
+			fmt.Fprintf(&sb, "    工具: %s\n", strings.Join(names, ", "))
+		}
 	}
 	fmt.Fprintf(&sb, "\n安装 Skill: 将 skill 文件夹放入 %s 即可", skills.ShortenHomePath(report.ManagedDir))
 	return sb.String()
@@ -1904,6 +2351,11 @@ func formatSkillsSection() string {
 
 // buildToolsList creates the tools list for the AI provider
 func (a *Agent) buildToolsList() []Tool {
+	return append(a.builtinTools(), a.skillToolList()...)
+}
+
+// builtinTools lists the tools coco itself provides.
+func (a *Agent) builtinTools() []Tool {
 	return []Tool{
 		// === AI MODEL ROUTING ===
 		{
@@ -1934,6 +2386,14 @@ func (a *Agent) buildToolsList() []Tool {
 				"properties": map[string]any{},
 			}),
 		},
+		{
+			Name:        "ai.model_health",
+			Description: "查看各 AI 模型的健康状态：成功/失败次数、是否冷却或隔离、最近一次错误",
+			InputSchema: jsonSchema(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
+			}),
+		},
 		{
 			Name:        "sessions_spawn",
 			Description: "创建一个子 Agent 会话，用于分派并跟踪子任务",
@@ -1995,102 +2455,452 @@ func (a *Agent) buildToolsList() []Tool {
 						},
 					},
 				},
-				"required": []string{"summary"},
+				"required": []string{"summary"},
+			}),
+		},
+		{
+			Name:        "get_daily_report",
+			Description: "获取指定日期的日报",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"date": map[string]string{"type": "string", "description": "日报日期，格式：YYYY-MM-DD（默认：最近一天）"},
+				},
+			}),
+		},
+		{
+			Name:        "report_regenerate",
+			Description: "根据当天的对话、定时任务运行、项目进展、日历和提醒重新生成日报（会覆盖该日期已有的日报）",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"date": map[string]string{"type": "string", "description": "日报日期，格式：YYYY-MM-DD（默认：昨天）"},
+				},
+			}),
+		},
+		{
+			Name:        "list_daily_reports",
+			Description: "列出所有日报，按日期降序排列",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"limit": map[string]string{"type": "number", "description": "返回数量限制（默认：30）"},
+				},
+			}),
+		},
+		{
+			Name:        "search_messages",
+			Description: "在历史对话消息中搜索关键词",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"keyword": map[string]string{"type": "string", "description": "搜索关键词"},
+					"limit":   map[string]string{"type": "number", "description": "返回数量限制（默认：50）"},
+				},
+				"required": []string{"keyword"},
+			}),
+		},
+		{
+			Name:        "get_conversation_summary",
+			Description: "获取当前对话的摘要信息",
+			InputSchema: jsonSchema(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
+			}),
+		},
+		{
+			Name:        "conversation_recall",
+			Description: "仅在用户明确要求时，读取该用户另一个对话（其他平台或频道）的相关片段；不合并两段历史。不给 source 时列出可引用的对话",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"source": map[string]string{"type": "string", "description": "对话来源：平台名（如 telegram）、平台:频道 或完整会话键"},
+					"query":  map[string]string{"type": "string", "description": "只取包含该关键词的消息（可选）"},
+					"limit":  map[string]string{"type": "number", "description": "最多返回消息数（默认 10）"},
+				},
+			}),
+		},
+		{
+			Name:        "memory_search",
+			Description: "搜索本地 Markdown 长程记忆（含 Obsidian 与核心记忆文件），按相关性和更新时间排序",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]string{"type": "string", "description": "搜索关键词或问题"},
+					"limit": map[string]string{"type": "number", "description": "返回条目数（默认 6）"},
+				},
+				"required": []string{"query"},
+			}),
+		},
+		{
+			Name:        "memory_get",
+			Description: "读取单个 Markdown 记忆文件完整内容（仅允许 Obsidian vault 和核心记忆文件）",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]string{"type": "string", "description": "文件路径（绝对路径或 vault 内相对路径）"},
+				},
+				"required": []string{"path"},
+			}),
+		},
+		{
+			Name:        "memory_write",
+			Description: "写入 Markdown 记忆文件（仅允许 Obsidian vault 和核心记忆文件）。可覆盖或追加；提供 title/tags 时写成带 frontmatter 的结构化笔记。已记录过的内容会被跳过。",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path":    map[string]string{"type": "string", "description": "文件路径（绝对路径或 vault 内相对路径，默认 MEMORY.md）"},
+					"content": map[string]string{"type": "string", "description": "要写入的内容"},
+					"mode":    map[string]string{"type": "string", "description": "写入模式：append 或 overwrite（默认 append）"},
+					"title":   map[string]string{"type": "string", "description": "笔记标题（可选，作为小节标题）"},
+					"tags": map[string]any{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "标签（可选，合并进 frontmatter 的 tags）",
+					},
+				},
+				"required": []string{"content"},
+			}),
+		},
+		{
+			Name:        "soul_append",
+			Description: "向 SOUL.md 追加一条人格成长记录（只追加，不覆盖历史内容）",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"entry":   map[string]string{"type": "string", "description": "成长条目正文"},
+					"reason":  map[string]string{"type": "string", "description": "触发原因（可选）"},
+					"section": map[string]string{"type": "string", "description": "记录分组（默认 Growth Ledger）"},
+				},
+				"required": []string{"entry"},
+			}),
+		},
+		// === TASKS ===
+		{
+			Name:        "task_add",
+			Description: "添加一项待办任务；给出截止时间时会在到期时于本会话提醒，完成后会同步到日报",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"title":       map[string]string{"type": "string", "description": "任务标题"},
+					"description": map[string]string{"type": "string", "description": "补充说明（可选）"},
+					"due_date":    map[string]string{"type": "string", "description": "截止时间 YYYY-MM-DD 或 YYYY-MM-DD HH:MM（可选；只给日期时当天 9 点提醒）"},
+					"priority":    map[string]string{"type": "string", "description": "low、medium（默认）或 high"},
+					"project":     map[string]string{"type": "string", "description": "所属长期项目名称（可选）"},
+				},
+				"required": []string{"title"},
+			}),
+		},
+		{
+			Name:        "task_update",
+			Description: "修改任务的标题、说明、状态、优先级、截止时间或所属项目；改截止时间会重设提醒",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id":          map[string]string{"type": "number", "description": "任务编号"},
+					"title":       map[string]string{"type": "string", "description": "新标题"},
+					"description": map[string]string{"type": "string", "description": "新说明"},
+					"status":      map[string]string{"type": "string", "description": "pending、in_progress 或 completed"},
+					"priority":    map[string]string{"type": "string", "description": "low、medium 或 high"},
+					"due_date":    map[string]string{"type": "string", "description": "新截止时间；none 取消截止时间和提醒"},
+					"project":     map[string]string{"type": "string", "description": "所属长期项目名称；空字符串移出项目"},
+				},
+				"required": []string{"id"},
+			}),
+		},
+		{
+			Name:        "task_complete",
+			Description: "把任务标记为完成并取消它的到期提醒",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]string{"type": "number", "description": "任务编号"},
+				},
+				"required": []string{"id"},
+			}),
+		},
+		{
+			Name:        "task_list",
+			Description: "列出任务：默认未完成的任务按截止时间排序，逾期的会标出",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"status":  map[string]string{"type": "string", "description": "open（默认）、all、pending、in_progress 或 completed（近 7 天完成的）"},
+					"project": map[string]string{"type": "string", "description": "只看某个项目的任务（可选）"},
+				},
+			}),
+		},
+		// === CONTACTS ===
+		{
+			Name:        "contacts_add",
+			Description: "把一个会话存为通讯录联系人（如“老板”“家庭群”），之后可按名字发消息；同名联系人会被覆盖",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":       map[string]string{"type": "string", "description": "联系人名称"},
+					"here":       map[string]string{"type": "boolean", "description": "存当前会话（不给 platform/channel_id 时默认如此）"},
+					"platform":   map[string]string{"type": "string", "description": "平台，如 wecom、telegram、slack"},
+					"channel_id": map[string]string{"type": "string", "description": "会话/群 ID"},
+					"user_id":    map[string]string{"type": "string", "description": "对方的平台用户 ID（按用户投递的平台需要，可选）"},
+					"kind":       map[string]string{"type": "string", "description": "person（默认）或 group"},
+					"note":       map[string]string{"type": "string", "description": "备注（可选）"},
+				},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "contacts_list",
+			Description: "列出通讯录联系人",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]string{"type": "string", "description": "按名称或备注筛选（可选）"},
+				},
+			}),
+		},
+		{
+			Name:        "contacts_remove",
+			Description: "从通讯录删除联系人",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]string{"type": "string", "description": "联系人名称"},
+				},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "message_send_to",
+			Description: "按通讯录名字给一个或多个联系人主动发消息；有名字找不到时不发送任何消息",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"to":      map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "联系人名称列表"},
+					"message": map[string]string{"type": "string", "description": "消息内容"},
+				},
+				"required": []string{"to", "message"},
+			}),
+		},
+		{
+			Name:        "broadcast",
+			Description: "向最近 30 天内所有活跃会话（或带指定标签的频道）广播一条通知，如维护公告；不带 confirm 时只返回收件名单，用户确认后再以 confirm=true 调用；逐条限速发送，两次广播至少间隔 10 分钟",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"message": map[string]string{"type": "string", "description": "广播内容"},
+					"tags":    map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "只发给 channels 配置中带这些标签的频道（可选）"},
+					"confirm": map[string]string{"type": "boolean", "description": "用户已确认名单和内容后设为 true"},
+				},
+				"required": []string{"message"},
+			}),
+		},
+		// === PROJECTS ===
+		{
+			Name:        "project_create",
+			Description: "为持续数周的长期目标建立项目：在笔记库写一份活的计划（里程碑、下一步、进展），并按 nudge 定期提醒推进",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":         map[string]string{"type": "string", "description": "项目名称"},
+					"goal":         map[string]string{"type": "string", "description": "项目目标（可衡量的结果）"},
+					"milestones":   map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "里程碑列表"},
+					"next_actions": map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "近期的下一步行动"},
+					"deadline":     map[string]string{"type": "string", "description": "截止日期 YYYY-MM-DD（可选）"},
+					"nudge":        map[string]string{"type": "string", "description": "提醒推进的 cron 表达式（5 段，默认每周一 9 点 \"0 9 * * 1\"；off 关闭）"},
+				},
+				"required": []string{"name", "goal"},
+			}),
+		},
+		{
+			Name:        "project_update",
+			Description: "更新长期项目计划：勾掉完成的里程碑/下一步、追加新项、记录进展、修改状态",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":             map[string]string{"type": "string", "description": "项目名称"},
+					"done":             map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "已完成的里程碑或下一步（按文字匹配）"},
+					"add_milestones":   map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "新增里程碑"},
+					"add_next_actions": map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "新增下一步"},
+					"progress":         map[string]string{"type": "string", "description": "一句话进展记录"},
+					"deadline":         map[string]string{"type": "string", "description": "新的截止日期（可选）"},
+					"status":           map[string]string{"type": "string", "description": "active、paused 或 done；暂停或完成后停止提醒"},
+				},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "project_status",
+			Description: "查看长期项目的目标、里程碑完成度、下一步和最近进展；不给 name 时列出所有项目",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]string{"type": "string", "description": "项目名称（可选）"},
+				},
+			}),
+		},
+		// === TIME TRACKING ===
+		{
+			Name:        "timer_start",
+			Description: "开始为一项任务计时（如「开始计时：写周报」），同时停止正在进行的计时",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"task":     map[string]string{"type": "string", "description": "任务名称"},
+					"category": map[string]string{"type": "string", "description": "分类，如 写代码、会议、写作（默认 未分类）"},
+				},
+				"required": []string{"task"},
+			}),
+		},
+		{
+			Name:        "timer_stop",
+			Description: "停止当前计时并返回用时",
+			InputSchema: jsonSchema(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
+			}),
+		},
+		{
+			Name:        "timer_report",
+			Description: "统计计时记录，按分类和任务汇总用时，回答「这周写代码花了多久」之类的问题",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"period": map[string]string{"type": "string", "description": "today、week（默认）、last_week 或 month"},
+					"query":  map[string]string{"type": "string", "description": "只统计任务或分类包含该词的记录（可选）"},
+				},
 			}),
 		},
 		{
-			Name:        "get_daily_report",
-			Description: "获取指定日期的日报",
+			Name:        "focus_session",
+			Description: "番茄钟/专注时段：计时专注，期间暂缓定时任务等主动通知（结束后汇总），可选开启系统勿扰，轮间提醒休息，专注时间记入计时",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"date": map[string]string{"type": "string", "description": "日报日期，格式：YYYY-MM-DD（默认：最近一天）"},
+					"action":        map[string]string{"type": "string", "description": "start（默认）、stop 或 status"},
+					"task":          map[string]string{"type": "string", "description": "专注的任务"},
+					"category":      map[string]string{"type": "string", "description": "计时分类（默认 专注）"},
+					"minutes":       map[string]string{"type": "number", "description": "每轮专注分钟数（默认 25）"},
+					"break_minutes": map[string]string{"type": "number", "description": "轮间休息分钟数（默认 5）"},
+					"rounds":        map[string]string{"type": "number", "description": "轮数（默认 1，最多 8）"},
+					"dnd":           map[string]string{"type": "boolean", "description": "是否开启系统勿扰模式（macOS 需配置快捷指令）"},
 				},
 			}),
 		},
+		// === PRICE WATCH ===
 		{
-			Name:        "list_daily_reports",
-			Description: "列出所有日报，按日期降序排列",
+			Name:        "price_watch",
+			Description: "关注商品价格：记录商品链接和目标价，每 6 小时自动读取页面价格（静态页面读不到时用浏览器渲染），降到目标价时主动提醒；report 给出价格走势图",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"limit": map[string]string{"type": "number", "description": "返回数量限制（默认：30）"},
+					"action":       map[string]string{"type": "string", "description": "add（给出 url 时默认）、list（默认）、remove、check（立即检查）或 report"},
+					"url":          map[string]string{"type": "string", "description": "商品页面链接（add）"},
+					"target_price": map[string]string{"type": "number", "description": "目标价，价格不高于它时提醒（add）"},
+					"title":        map[string]string{"type": "string", "description": "商品名称（可选，默认取页面标题）"},
+					"id":           map[string]string{"type": "number", "description": "关注编号（remove；report 可选，省略则全部）"},
 				},
 			}),
 		},
+		// === PARCEL TRACKING ===
 		{
-			Name:        "search_messages",
-			Description: "在历史对话消息中搜索关键词",
+			Name:        "parcel_track",
+			Description: "查询并跟踪快递：按单号查询物流状态并持续跟踪，每 2 小时自动查询，状态变化或签收时主动提醒；用户消息中的单号会被自动跟踪",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"keyword": map[string]string{"type": "string", "description": "搜索关键词"},
-					"limit":   map[string]string{"type": "number", "description": "返回数量限制（默认：50）"},
+					"action":  map[string]string{"type": "string", "description": "track（给出 number 时默认）、list（默认）、remove 或 check（立即查询全部）"},
+					"number":  map[string]string{"type": "string", "description": "快递单号（track；remove 可用单号代替 id）"},
+					"carrier": map[string]string{"type": "string", "description": "快递公司编码，如 shunfeng、zhongtong（可选，默认自动识别）"},
+					"phone":   map[string]string{"type": "string", "description": "收/寄件人手机尾号后四位（顺丰必填）"},
+					"label":   map[string]string{"type": "string", "description": "备注，如包裹里是什么（可选）"},
+					"id":      map[string]string{"type": "number", "description": "跟踪编号（remove）"},
 				},
-				"required": []string{"keyword"},
 			}),
 		},
+		// === TRAVEL ===
 		{
-			Name:        "get_conversation_summary",
-			Description: "获取当前对话的摘要信息",
+			Name:        "flight_status",
+			Description: "查询航班实时状态：是否延误或取消、预计起降时间、航站楼和登机口",
 			InputSchema: jsonSchema(map[string]any{
-				"type":       "object",
-				"properties": map[string]any{},
+				"type": "object",
+				"properties": map[string]any{
+					"number": map[string]string{"type": "string", "description": "航班号，如 CA1234"},
+					"date":   map[string]string{"type": "string", "description": "出发日期 YYYY-MM-DD（默认今天）"},
+				},
+				"required": []string{"number"},
 			}),
 		},
 		{
-			Name:        "memory_search",
-			Description: "搜索本地 Markdown 长程记忆（含 Obsidian 与核心记忆文件），按相关性和更新时间排序",
+			Name:        "train_status",
+			Description: "查询列车实时状态：是否晚点、预计发车和到达时间、检票口",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"query": map[string]string{"type": "string", "description": "搜索关键词或问题"},
-					"limit": map[string]string{"type": "number", "description": "返回条目数（默认 6）"},
+					"number": map[string]string{"type": "string", "description": "车次，如 G1234"},
+					"date":   map[string]string{"type": "string", "description": "出发日期 YYYY-MM-DD（默认今天）"},
 				},
-				"required": []string{"query"},
+				"required": []string{"number"},
 			}),
 		},
 		{
-			Name:        "memory_get",
-			Description: "读取单个 Markdown 记忆文件完整内容（仅允许 Obsidian vault 和核心记忆文件）",
+			Name:        "itinerary",
+			Description: "管理用户的航班和火车行程：从转发的订票确认短信/邮件中识别行程，或按给出的班次保存；出发前（航班 3 小时、火车 1 小时）主动提醒，附上实时状态和目的地天气。用户消息中的订票确认会被自动保存",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"path": map[string]string{"type": "string", "description": "文件路径（绝对路径或 vault 内相对路径）"},
+					"action":    map[string]string{"type": "string", "description": "add（给出 text 或 number 时默认）、list（默认）或 remove"},
+					"text":      map[string]string{"type": "string", "description": "订票确认的原文（add，识别其中所有航班和车次）"},
+					"number":    map[string]string{"type": "string", "description": "航班号或车次（add，不给 text 时必填）"},
+					"departure": map[string]string{"type": "string", "description": "出发时间 YYYY-MM-DD HH:MM（add，不给 text 时必填）"},
+					"arrival":   map[string]string{"type": "string", "description": "到达时间 YYYY-MM-DD HH:MM（可选）"},
+					"from":      map[string]string{"type": "string", "description": "出发地（可选）"},
+					"to":        map[string]string{"type": "string", "description": "目的地，用于天气（可选）"},
+					"seat":      map[string]string{"type": "string", "description": "座位（可选）"},
+					"id":        map[string]string{"type": "number", "description": "行程编号（remove）"},
 				},
-				"required": []string{"path"},
 			}),
 		},
+		// === BRIEFING ===
 		{
-			Name:        "memory_write",
-			Description: "写入 Markdown 记忆文件（仅允许 Obsidian vault 和核心记忆文件）。可覆盖或追加。",
+			Name:        "news_briefing",
+			Description: "生成早安简报：天气、今日日程、待办、关注话题的最新新闻（web_search）和 RSS 订阅头条合成一条消息；也管理用户关注/屏蔽的话题和每日定时推送",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"path":    map[string]string{"type": "string", "description": "文件路径（绝对路径或 vault 内相对路径）"},
-					"content": map[string]string{"type": "string", "description": "要写入的内容"},
-					"mode":    map[string]string{"type": "string", "description": "写入模式：append 或 overwrite（默认 append）"},
+					"action": map[string]string{"type": "string", "description": "now（默认，立即生成）、follow、mute、topics（查看关注的话题）、subscribe（每天在本对话推送）或 unsubscribe"},
+					"topic":  map[string]string{"type": "string", "description": "话题关键词（follow/mute）"},
 				},
-				"required": []string{"path", "content"},
 			}),
 		},
+		// === SECRETS ===
 		{
-			Name:        "soul_append",
-			Description: "向 SOUL.md 追加一条人格成长记录（只追加，不覆盖历史内容）",
+			Name:        "secrets_generate",
+			Description: "Generate a strong random password/PIN and store it in the user's encrypted local vault under a name. The value is never returned to you; reference it later as {{secret:name}} in http_request headers (with a profile).",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"entry":   map[string]string{"type": "string", "description": "成长条目正文"},
-					"reason":  map[string]string{"type": "string", "description": "触发原因（可选）"},
-					"section": map[string]string{"type": "string", "description": "记录分组（默认 Growth Ledger）"},
+					"name":      map[string]string{"type": "string", "description": "Vault name, e.g. nas-password"},
+					"kind":      map[string]string{"type": "string", "description": "password (default), pin or hex"},
+					"length":    map[string]string{"type": "number", "description": "Length (default 20 for password, 6 for pin, 32 for hex)"},
+					"symbols":   map[string]string{"type": "boolean", "description": "Include symbols in passwords (default true)"},
+					"note":      map[string]string{"type": "string", "description": "What the secret is for (optional)"},
+					"overwrite": map[string]string{"type": "boolean", "description": "Replace an existing secret with the same name (only when the user asked)"},
 				},
-				"required": []string{"entry"},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "secrets_list",
+			Description: "List the names of secrets in the user's encrypted vault (never values). Use {{secret:name}} in http_request headers (with a profile) to use one.",
+			InputSchema: jsonSchema(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{},
 			}),
 		},
 		// === FILE OPERATIONS ===
 		{
 			Name:        "file_send",
-			Description: "Send a file to the user via the messaging platform. Use this when the user asks you to send/transfer/share a file. Use ~ for home directory.",
+			Description: "Send a file to the user via the messaging platform. Use this when the user asks you to send/transfer/share a file. Use ~ for home directory. Files over the platform's size limit are zipped, split into parts or shared as an expiring download link automatically; pass on what the result says.",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
@@ -2100,6 +2910,18 @@ func (a *Agent) buildToolsList() []Tool {
 				"required": []string{"path"},
 			}),
 		},
+		{
+			Name:        "file_share",
+			Description: "Share a local file as a signed download link hosted by Keeper that expires after ttl. Use it for large reports or videos, or when the user asks for a link; include the link in your reply.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]string{"type": "string", "description": "File to share. Use ~ for home directory."},
+					"ttl":  map[string]string{"type": "string", "description": "How long the link works, e.g. 2h or 72h (default 24h, at most 7 days)"},
+				},
+				"required": []string{"path"},
+			}),
+		},
 		{
 			Name:        "file_read",
 			Description: "Read the contents of a file. Use ~ for home directory.",
@@ -2121,6 +2943,68 @@ func (a *Agent) buildToolsList() []Tool {
 				"required": []string{"path", "content"},
 			}),
 		},
+		{
+			Name:        "file_write_batch",
+			Description: "Write several files as one transaction: all targets are snapshotted first, and if any write fails every file is restored. Use this instead of repeated file_write for multi-file edits.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"files": map[string]any{
+						"type":        "array",
+						"description": "Files to write",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"path":    map[string]string{"type": "string", "description": "Path to the file (use ~ for home)"},
+								"content": map[string]string{"type": "string", "description": "Full new content of the file"},
+							},
+							"required": []string{"path", "content"},
+						},
+					},
+				},
+				"required": []string{"files"},
+			}),
+		},
+		{
+			Name:        "file_edit",
+			Description: "Edit part of an existing file instead of rewriting it with file_write. Pass either edits (search/replace blocks; each search must match the file exactly and only once unless replace_all is set) or diff (a unified diff). The edit is validated before anything is written, the previous version is saved to the trash, and the result shows the diff. Set preview to see the diff without writing.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]string{"type": "string", "description": "Path to the file (use ~ for home)"},
+					"edits": map[string]any{
+						"type":        "array",
+						"description": "Search/replace blocks, applied in order",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"search":      map[string]string{"type": "string", "description": "Exact text to find, including whitespace and enough surrounding lines to be unique"},
+								"replace":     map[string]string{"type": "string", "description": "Text to put in its place"},
+								"replace_all": map[string]string{"type": "boolean", "description": "Replace every occurrence instead of requiring exactly one"},
+							},
+							"required": []string{"search", "replace"},
+						},
+					},
+					"diff":    map[string]string{"type": "string", "description": "Unified diff with @@ hunks, as an alternative to edits"},
+					"preview": map[string]string{"type": "boolean", "description": "Only return the diff, do not write (default false)"},
+				},
+				"required": []string{"path"},
+			}),
+		},
+		{
+			Name:        "file_watch",
+			Description: "Watch a folder for new files and handle each one with prompt, sending the result to this chat, e.g. \"watch ~/Downloads for new PDFs and summarize them\". A file counts once it has finished downloading or copying. Watches are saved and survive restarts.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"action":  map[string]string{"type": "string", "description": "add (default when path is given), list (default) or remove"},
+					"path":    map[string]string{"type": "string", "description": "Folder to watch (add; use ~ for home)"},
+					"pattern": map[string]string{"type": "string", "description": "File name globs, comma separated, e.g. *.pdf,*.docx (default every file)"},
+					"prompt":  map[string]string{"type": "string", "description": "What to do with each new file (default: read it and summarize the key points)"},
+					"id":      map[string]string{"type": "number", "description": "Watch number (remove)"},
+				},
+			}),
+		},
 		{
 			Name:        "file_list",
 			Description: "List contents of a directory. Use ~/Desktop for desktop, ~/Downloads for downloads, etc.",
@@ -2153,6 +3037,72 @@ func (a *Agent) buildToolsList() []Tool {
 			}),
 		},
 
+		// === REMOTE STORAGE (S3 / WebDAV / OSS) ===
+		{
+			Name:        "remote_put",
+			Description: "Upload a local file to a configured remote storage (S3, WebDAV/NAS, Alibaba OSS). Use for backups and for files too large to send through chat.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"local_path":  map[string]string{"type": "string", "description": "Local file to upload"},
+					"remote_path": map[string]string{"type": "string", "description": "Destination path on the remote storage (default: file name)"},
+					"target":      map[string]string{"type": "string", "description": "Name of the remote_storage entry (optional when only one is configured)"},
+				},
+				"required": []string{"local_path"},
+			}),
+		},
+		{
+			Name:        "remote_get",
+			Description: "Download a file from a configured remote storage to a local path",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"remote_path": map[string]string{"type": "string", "description": "File path on the remote storage"},
+					"local_path":  map[string]string{"type": "string", "description": "Where to save locally (default: file name in current directory)"},
+					"target":      map[string]string{"type": "string", "description": "Name of the remote_storage entry (optional when only one is configured)"},
+				},
+				"required": []string{"remote_path"},
+			}),
+		},
+		{
+			Name:        "remote_list",
+			Description: "List files on a configured remote storage",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"prefix": map[string]string{"type": "string", "description": "Directory or key prefix to list (default: root)"},
+					"target": map[string]string{"type": "string", "description": "Name of the remote_storage entry (optional when only one is configured)"},
+				},
+			}),
+		},
+		{
+			Name:        "db_query",
+			Description: "Run one SQL statement against a database configured by the user (Postgres, MySQL or SQLite) and get the rows as a markdown table. Databases are read-only and SELECT-only unless configured otherwise. Call without query to list the databases; look up table names first (e.g. information_schema, SHOW TABLES, sqlite_master) and prefer aggregates or LIMIT over fetching many rows.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":    map[string]string{"type": "string", "description": "A single SQL statement, without ';' inside it; empty to list the configured databases"},
+					"database": map[string]string{"type": "string", "description": "Name of the databases entry (optional when only one is configured)"},
+					"max_rows": map[string]string{"type": "number", "description": "Rows to return, up to the database's max_rows (default 50)"},
+				},
+			}),
+		},
+		{
+			Name:        "http_request",
+			Description: "Send an HTTP request to an API that has no dedicated tool. Credentials of the user's http profiles are added automatically to requests under their base_url and are never shown; do not ask the user for API keys that a profile covers. JSON responses are pretty-printed. Call without url to list the profiles.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"method":    map[string]string{"type": "string", "description": "GET (default), POST, PUT, PATCH, DELETE or HEAD"},
+					"url":       map[string]string{"type": "string", "description": "Full URL, or a path such as /v1/items when profile is given; empty to list the profiles"},
+					"profile":   map[string]string{"type": "string", "description": "Credential profile to use (optional: picked by base_url)"},
+					"headers":   map[string]any{"type": "object", "description": "Extra request headers; with a profile, values may use {{secret:name}}", "additionalProperties": map[string]string{"type": "string"}},
+					"body":      map[string]any{"description": "Request body: a string, or an object sent as JSON"},
+					"max_bytes": map[string]string{"type": "number", "description": "Response bytes to return (default 65536, at most 1048576)"},
+				},
+			}),
+		},
+
 		// === CALENDAR ===
 		{
 			Name:        "calendar_today",
@@ -2179,6 +3129,11 @@ func (a *Agent) buildToolsList() []Tool {
 					"calendar":   map[string]string{"type": "string", "description": "Calendar name (optional)"},
 					"location":   map[string]string{"type": "string", "description": "Event location (optional)"},
 					"notes":      map[string]string{"type": "string", "description": "Event notes (optional)"},
+					"attendees": map[string]any{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "Email addresses to invite (optional; needs a CalDAV or Google calendar)",
+					},
 				},
 				"required": []string{"title", "start_time"},
 			}),
@@ -2208,6 +3163,23 @@ func (a *Agent) buildToolsList() []Tool {
 				"required": []string{"title"},
 			}),
 		},
+		{
+			Name:        "calendar_freebusy",
+			Description: "Show when the user and the given attendees are busy and which slots are free for everyone. Use before proposing a meeting time. Needs a CalDAV or Google calendar",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"start":    map[string]string{"type": "string", "description": "Window start (YYYY-MM-DD HH:MM, default: now or 09:00 today)"},
+					"end":      map[string]string{"type": "string", "description": "Window end (YYYY-MM-DD HH:MM, default: 18:00 the same day)"},
+					"duration": map[string]string{"type": "number", "description": "Shortest free slot to report in minutes (default 30)"},
+					"attendees": map[string]any{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "Email addresses of other people to check (optional)",
+					},
+				},
+			}),
+		},
 
 		// === REMINDERS ===
 		{
@@ -2335,6 +3307,30 @@ func (a *Agent) buildToolsList() []Tool {
 				"required":   []string{"url"},
 			}),
 		},
+		{
+			Name:        "artifact_read",
+			Description: "Read part of a large tool output that was cut short. When a result ends with \"saved as artifact art_…\", pass that id and the offset to continue from (in characters).",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id":     map[string]string{"type": "string", "description": "Artifact ID, e.g. art_1a2b3c4d5e6f"},
+					"offset": map[string]string{"type": "integer", "description": "Character offset to start from (default 0)"},
+					"limit":  map[string]string{"type": "integer", "description": "Characters to read (default 8000, max 20000)"},
+				},
+				"required": []string{"id"},
+			}),
+		},
+		{
+			Name:        "summarize",
+			Description: "Summarize a web page or a PDF/DOCX/XLSX/PPTX file in the standard layered format: 一句话 (one line), 要点 (numbered key points), 细节 (details). Use it whenever the user asks for a summary of a page or document. The source is kept so the user can reply \"展开第N点\" to drill into a point without fetching it again; a page already fetched with web_fetch is not fetched twice.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url":  map[string]string{"type": "string", "description": "Page URL"},
+					"path": map[string]string{"type": "string", "description": "Document path (instead of url)"},
+				},
+			}),
+		},
 		{
 			Name:        "open_url",
 			Description: "Open a URL in the default web browser",
@@ -2376,6 +3372,21 @@ func (a *Agent) buildToolsList() []Tool {
 			}),
 		},
 
+		// === PRINTING ===
+		{
+			Name:        "print_file",
+			Description: "Print a local file (PDF, image, text) on a printer attached to this computer. Use an absolute path, e.g. the user's Downloads folder.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path":    map[string]string{"type": "string", "description": "Absolute path of the file to print"},
+					"printer": map[string]string{"type": "string", "description": "Printer alias or system name (optional, default from config or OS)"},
+					"copies":  map[string]string{"type": "number", "description": "Number of copies (default 1, max 20)"},
+				},
+				"required": []string{"path"},
+			}),
+		},
+
 		// === SCREENSHOT ===
 		{
 			Name:        "screenshot",
@@ -2388,6 +3399,30 @@ func (a *Agent) buildToolsList() []Tool {
 				},
 			}),
 		},
+		{
+			Name:        "document_read",
+			Description: "Read pages of a PDF, DOCX, XLSX or PPTX file (PDF pages, slides, sheets, or ~3000-character sections of a DOCX). Files users send are saved locally; the [文件: …] note in their message gives the path.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path":  map[string]string{"type": "string", "description": "Document path"},
+					"page":  map[string]string{"type": "number", "description": "First page to read, 1-based (default 1)"},
+					"pages": map[string]string{"type": "number", "description": "Number of pages to read (default 1, max 5)"},
+				},
+				"required": []string{"path"},
+			}),
+		},
+		{
+			Name:        "image_ocr",
+			Description: "Read the text in an image file (screenshots, photos of documents). Incoming chat images are saved locally and show up as [图片: path].",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]string{"type": "string", "description": "Image file path"},
+				},
+				"required": []string{"path"},
+			}),
+		},
 
 		// === MUSIC ===
 		{
@@ -2553,12 +3588,13 @@ func (a *Agent) buildToolsList() []Tool {
 		// === BROWSER AUTOMATION ===
 		{
 			Name:        "browser_start",
-			Description: "Start a new browser or connect to an existing Chrome. Use cdp_url to attach to a Chrome launched with --remote-debugging-port (e.g. \"127.0.0.1:9222\"). Without cdp_url, launches a new Chrome instance.",
+			Description: "Start a new browser or connect to an existing Chrome. Use cdp_url to attach to a Chrome launched with --remote-debugging-port (e.g. \"127.0.0.1:9222\"). Without cdp_url, launches a new Chrome instance. Use profile to keep a site's logins in their own named profile (e.g. \"xiaohongshu\"); logins survive restarts.",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"cdp_url":  map[string]string{"type": "string", "description": "CDP address of existing Chrome (e.g. 127.0.0.1:9222). Chrome must be started with --remote-debugging-port flag."},
 					"headless": map[string]string{"type": "boolean", "description": "Launch in headless mode (default: false, ignored when using cdp_url)"},
+					"profile":  map[string]string{"type": "string", "description": "Named profile (separate user-data dir) to launch with; letters, digits, - and _ (default: browser.profile or \"default\")"},
 					"url":      map[string]string{"type": "string", "description": "Initial URL to navigate to"},
 				},
 			}),
@@ -2672,6 +3708,30 @@ func (a *Agent) buildToolsList() []Tool {
 			Description: "Close the browser",
 			InputSchema: jsonSchema(map[string]any{"type": "object", "properties": map[string]any{}}),
 		},
+		{
+			Name:        "browser_network_start_capture",
+			Description: "Start recording the XHR/fetch requests of the open tabs (URL, method, status and JSON/text bodies). Prefer this over DOM scraping when a page loads its data from an API: start the capture, navigate or scroll, then read the responses with browser_network_get_requests. Restarting clears earlier requests; tabs opened later are not captured.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url_filter":     map[string]string{"type": "string", "description": "Only record requests whose URL contains this text (e.g. \"/api/\")"},
+					"max_body_bytes": map[string]string{"type": "number", "description": "Cut request and response bodies at this many bytes (default 32768)"},
+				},
+			}),
+		},
+		{
+			Name:        "browser_network_get_requests",
+			Description: "List the requests recorded since browser_network_start_capture, oldest first, with their JSON/text bodies.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url_filter":     map[string]string{"type": "string", "description": "Only list requests whose URL contains this text"},
+					"include_bodies": map[string]string{"type": "boolean", "description": "Include request and response bodies (default true)"},
+					"clear":          map[string]string{"type": "boolean", "description": "Forget the listed requests afterwards"},
+					"stop":           map[string]string{"type": "boolean", "description": "Stop capturing after listing"},
+				},
+			}),
+		},
 
 		// === SCHEDULED TASKS (CRON) ===
 		{
@@ -2690,10 +3750,25 @@ func (a *Agent) buildToolsList() []Tool {
 					"auth":       map[string]string{"type": "string", "description": "Optional HTTP Authorization header value for external jobs (example: 'Bearer xxx')."},
 					"relay_mode": map[string]string{"type": "boolean", "description": "When true, treat external output as pass-through forwarded content."},
 					"arguments":  map[string]string{"type": "object", "description": "Arguments for the tool (when using tool parameter)"},
+					"notify_via": map[string]string{"type": "string", "description": "Optional comma-separated delivery channels for the output instead of this chat: 'chat[:platform:channel_id[:user_id]]', 'desktop', 'email[:address]', 'webhook:<url>', 'file[:path]'. Only set when the user asks for output elsewhere."},
 				},
 				"required": []string{"name", "schedule"},
 			}),
 		},
+		{
+			Name:        "remind_once",
+			Description: "Create a ONE-SHOT reminder that fires exactly once and is then deleted automatically. Use this (not cron_create) for non-recurring requests like 'remind me in 20 minutes' or '明天下午3点提醒我开会'. Give either 'in_minutes' for relative delays or 'at' as an absolute local time computed from the current date.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"message":    map[string]string{"type": "string", "description": "Reminder text sent to the user when it fires"},
+					"at":         map[string]string{"type": "string", "description": "Absolute local time 'YYYY-MM-DD HH:MM' (or RFC3339). Example: 明天下午3点 → '2025-01-02 15:00'"},
+					"in_minutes": map[string]string{"type": "number", "description": "Fire after this many minutes from now (use instead of 'at' for relative times)"},
+					"name":       map[string]string{"type": "string", "description": "Optional short name for the reminder"},
+					"prompt":     map[string]string{"type": "string", "description": "Optional: instead of a fixed message, what the AI should do when the reminder fires"},
+				},
+			}),
+		},
 		{
 			Name:        "cron_list",
 			Description: "List all scheduled tasks with their status, schedule, and last run time. Use 'tag' parameter to filter by tag (e.g., 'user-schedule' to list only user schedules).",
@@ -2754,32 +3829,70 @@ func (a *Agent) processToolCalls(ctx context.Context, toolCalls []ToolCall) ([]T
 	var files []router.FileAttachment
 
 	for _, tc := range toolCalls {
+		start := time.Now()
+		if denied := a.checkToolProfile(ctx, tc.Name); denied != "" {
+			results = append(results, ToolResult{ToolCallID: tc.ID, Content: denied})
+			a.recordToolMetric(tc.Name, time.Since(start), len(denied), false)
+			continue
+		}
+		if pending := a.askMissingArgs(ctx, tc); pending != "" {
+			results = append(results, ToolResult{ToolCallID: tc.ID, Content: pending})
+			continue
+		}
 		if tc.Name == "file_send" {
 			content, file := executeFileSend(tc.Input)
+			failed := file == nil
 			if file != nil {
-				files = append(files, *file)
+				sent, note, err := a.fitFileSend(ctx, *file)
+				if err != nil {
+					content, failed = "Error: "+err.Error(), true
+				} else {
+					files = append(files, sent...)
+					content += note
+				}
 			}
 			results = append(results, ToolResult{
 				ToolCallID: tc.ID,
 				Content:    content,
-				IsError:    file == nil,
+				IsError:    failed,
 			})
+			a.recordToolMetric(tc.Name, time.Since(start), len(content), failed)
 			continue
 		}
 
-		result := a.executeTool(ctx, tc.Name, tc.Input)
+		observeTool(ctx, ToolEvent{Phase: "start", Tool: tc.Name, Input: redactSecretValues(string(tc.Input))})
+		toolCtx, done := a.watchdog.Begin(ctx, "tool", tc.Name, currentConversationKey(ctx))
+		result := redactSecretValues(a.executeTool(toolCtx, tc.Name, tc.Input))
+		done()
+		isError := strings.HasPrefix(result, "Error")
+		observeTool(ctx, ToolEvent{Phase: "end", Tool: tc.Name, Duration: time.Since(start).Milliseconds(), IsError: isError})
 		results = append(results, ToolResult{
 			ToolCallID: tc.ID,
-			Content:    result,
-			IsError:    strings.HasPrefix(result, "Error"),
+			Content:    a.storeLargeResult(ctx, tc.Name, result),
+			IsError:    isError,
 		})
+		a.recordToolMetric(tc.Name, time.Since(start), len(result), isError)
 	}
 
 	return results, files
 }
 
-// executeTool runs a tool and returns the result
+// executeTool runs a tool under its configured timeout and returns the result
 func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMessage) string {
+	var args map[string]any
+	_ = json.Unmarshal(input, &args)
+	recordUndo := a.trackUndo(ctx, name, args)
+	result := runToolWithTimeout(ctx, name, a.toolTimeout(name, args), func(ctx context.Context) string {
+		return a.runTool(ctx, name, input)
+	})
+	if recordUndo != nil {
+		recordUndo(result)
+	}
+	return result
+}
+
+// runTool runs a tool and returns the result
+func (a *Agent) runTool(ctx context.Context, name string, input json.RawMessage) string {
 	logger.Info("[Agent] Executing tool: %s", name)
 
 	// Parse input arguments
@@ -2788,6 +3901,10 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 		return fmt.Sprintf("Error parsing arguments: %v", err)
 	}
 
+	if t, ok := a.lookupSkillTool(name); ok {
+		return a.executeSkillTool(ctx, t, args)
+	}
+
 	// Handle search tools that need Agent context
 	switch name {
 	case "ai.list_models":
@@ -2796,13 +3913,19 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 		return a.executeAISwitchModel(args)
 	case "ai.get_current_model":
 		return a.executeAIGetCurrentModel()
+	case "ai.model_health":
+		return a.executeAIModelHealth()
+	case "artifact_read":
+		return a.executeArtifactRead(ctx, args)
 	case "web_search":
 		query, _ := args["query"].(string)
 		return a.executeWebSearchWithManager(ctx, query)
 	case "cron_create":
-		return a.executeCronCreate(args)
+		return a.executeCronCreate(ctx, args)
+	case "remind_once":
+		return a.executeRemindOnce(ctx, args)
 	case "cron_list":
-		return a.executeCronList(args)
+		return a.executeCronList(ctx, args)
 	case "cron_delete":
 		return a.executeCronDelete(args)
 	case "cron_pause":
@@ -2813,26 +3936,87 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 		return a.executeSaveDailyReport(args)
 	case "get_daily_report":
 		return a.executeGetDailyReport(args)
+	case "report_regenerate":
+		return a.executeReportRegenerate(ctx, args)
 	case "list_daily_reports":
 		return a.executeListDailyReports(args)
 	case "search_messages":
 		return a.executeSearchMessages(args)
 	case "get_conversation_summary":
-		return a.executeGetConversationSummary(args)
+		return a.executeGetConversationSummary(ctx, args)
+	case "conversation_recall":
+		return a.executeConversationRecall(ctx, args)
 	case "memory_search":
 		return a.executeMemorySearch(ctx, args)
 	case "memory_get":
 		return a.executeMemoryGet(args)
 	case "memory_write":
-		return a.executeMemoryWrite(args)
+		if a.isIncognito(currentConversationKey(ctx)) {
+			return "Error: incognito mode is on, so nothing may be remembered. The user can send /incognito off to leave it."
+		}
+		result := a.executeMemoryWrite(args)
+		a.recordWorkspaceWrite(ctx, name, args, result)
+		return result
 	case "soul_append":
-		return a.executeSoulAppend(args)
+		result := a.executeSoulAppend(ctx, args)
+		a.recordWorkspaceWrite(ctx, name, args, result)
+		return result
 	case "sessions_spawn":
 		return a.executeSessionsSpawn(args)
 	case "sessions_send":
 		return a.executeSessionsSend(args)
 	case "spawn_agent":
 		return a.executeSpawnAgent(ctx, args)
+	case "task_add":
+		return a.executeTaskAdd(ctx, args)
+	case "task_update":
+		return a.executeTaskUpdate(ctx, args)
+	case "task_complete":
+		return a.executeTaskComplete(ctx, args)
+	case "task_list":
+		return a.executeTaskList(ctx, args)
+	case "contacts_add":
+		return a.executeContactsAdd(ctx, args)
+	case "contacts_list":
+		return a.executeContactsList(ctx, args)
+	case "contacts_remove":
+		return a.executeContactsRemove(ctx, args)
+	case "message_send_to":
+		return a.executeMessageSendTo(ctx, args)
+	case "broadcast":
+		return a.executeBroadcast(ctx, args)
+	case "project_create":
+		return a.executeProjectCreate(ctx, args)
+	case "project_update":
+		return a.executeProjectUpdate(ctx, args)
+	case "project_status":
+		return a.executeProjectStatus(args)
+	case "timer_start":
+		return a.executeTimerStart(ctx, args)
+	case "timer_stop":
+		return a.executeTimerStop(ctx)
+	case "timer_report":
+		return a.executeTimerReport(ctx, args)
+	case "focus_session":
+		return a.executeFocusSession(ctx, args)
+	case "price_watch":
+		return a.executePriceWatch(ctx, args)
+	case "file_watch":
+		return a.executeFileWatch(ctx, args)
+	case "parcel_track":
+		return a.executeParcelTrack(ctx, args)
+	case "flight_status":
+		return a.executeTravelStatus(ctx, travel.Flight, args)
+	case "train_status":
+		return a.executeTravelStatus(ctx, travel.Train, args)
+	case "itinerary":
+		return a.executeItinerary(ctx, args)
+	case "news_briefing":
+		return a.executeNewsBriefing(ctx, args)
+	case "secrets_generate":
+		return executeSecretsGenerate(args)
+	case "secrets_list":
+		return executeSecretsList()
 	}
 
 	securitySnapshot := a.securitySnapshot()
@@ -2852,9 +4036,16 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 	}
 
 	// Protect workspace SOUL from destructive/overwrite operations.
-	if (name == "file_write" || name == "file_delete" || name == "file_move") && targetsWorkspaceSOUL(args) {
+	if (name == "file_write" || name == "file_edit" || name == "file_delete" || name == "file_move") && targetsWorkspaceSOUL(args) {
 		return "ACCESS DENIED: SOUL.md is append-only in runtime. Use `soul_append` to evolve personality traits."
 	}
+	if name == "file_write_batch" {
+		for _, f := range batchFileArgs(args) {
+			if targetsWorkspaceSOUL(f) {
+				return "ACCESS DENIED: SOUL.md is append-only in runtime. Use `soul_append` to evolve personality traits."
+			}
+		}
+	}
 
 	if name == "shell_execute" {
 		cmd := ""
@@ -2869,8 +4060,47 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 		}
 	}
 
+	// Hold destructive actions until the user replies "/approve" in this conversation.
+	// A file_edit preview writes nothing, so it needs no approval.
+	preview, _ := args["preview"].(bool)
+	if a.requiresPlanApproval(name) && !isPlanApproved(ctx) && !(name == "file_edit" && preview) {
+		return a.proposePlan(ctx, name, args, input)
+	}
+
+	// Substitute {{secret:name}} references only at the point of execution.
+	toolArgs, err := expandSecretArgs(name, args)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	if name == "image_ocr" {
+		return a.executeImageOCR(ctx, toolArgs)
+	}
+	if name == "document_read" {
+		return a.executeDocumentRead(ctx, toolArgs)
+	}
+	if name == "summarize" {
+		return a.executeSummarize(ctx, toolArgs)
+	}
+	if name == "file_share" {
+		return a.executeFileShare(ctx, toolArgs)
+	}
+
 	// Call tools directly
-	result := callToolDirect(ctx, name, args)
+	result := redactSecretValues(callToolDirect(ctx, name, toolArgs))
+	if name == "web_fetch" {
+		a.cacheFetchedPage(ctx, getString(toolArgs, "url"), result)
+	}
+	if name == "file_write" || (name == "file_edit" && !preview) {
+		a.recordWorkspaceWrite(ctx, name, args, result)
+		a.recordConfigWrite(ctx, args, result)
+	}
+	if name == "file_write_batch" {
+		for _, f := range batchFileArgs(args) {
+			a.recordWorkspaceWrite(ctx, name, f, result)
+			a.recordConfigWrite(ctx, f, result)
+		}
+	}
 
 	// Log result at verbose level (truncate if too long)
 	if len(result) > 500 {
@@ -2884,17 +4114,35 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 
 // fileToolPaths maps tool names to the argument key that contains the path.
 var fileToolPaths = map[string]string{
-	"file_list":     "path",
-	"file_list_old": "path",
-	"file_read":     "path",
-	"file_write":    "path",
-	"file_trash":    "path",
-	"file_search":   "path",
-	"file_info":     "path",
+	"file_list":        "path",
+	"file_list_old":    "path",
+	"file_read":        "path",
+	"file_write":       "path",
+	"file_write_batch": "files",
+	"file_edit":        "path",
+	"file_trash":       "path",
+	"file_search":      "path",
+	"file_info":        "path",
+	"remote_put":       "local_path",
+	"file_share":       "path",
+	"remote_get":       "local_path",
+	"print_file":       "path",
+	"image_ocr":        "path",
+	"document_read":    "path",
+	"summarize":        "path",
 }
 
 // checkToolPathAccess validates that tool arguments respect allowed_paths.
 func (a *Agent) checkToolPathAccess(name string, args map[string]any, checker *security.PathChecker) error {
+	if name == "file_write_batch" {
+		for _, f := range batchFileArgs(args) {
+			p, _ := f["path"].(string)
+			if err := checker.CheckPath(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	if pathKey, ok := fileToolPaths[name]; ok {
 		path := "."
 		if p, ok := args[pathKey].(string); ok && p != "" {
@@ -2932,6 +4180,10 @@ func callToolDirect(ctx context.Context, name string, args map[string]any) strin
 		return executeFileListOld(ctx, path, days)
 	case "file_trash":
 		return executeFileTrash(ctx, args)
+	case "file_write_batch":
+		return executeFileWriteBatch(ctx, args)
+	case "file_edit":
+		return executeFileEdit(ctx, args)
 	case "file_read":
 		path := ""
 		if p, ok := args["path"].(string); ok {
@@ -2948,6 +4200,16 @@ func callToolDirect(ctx context.Context, name string, args map[string]any) strin
 			content = c
 		}
 		return executeFileWrite(ctx, path, content)
+	case "remote_put":
+		return executeRemotePut(ctx, args)
+	case "remote_get":
+		return executeRemoteGet(ctx, args)
+	case "remote_list":
+		return executeRemoteList(ctx, args)
+	case "db_query":
+		return executeDBQuery(ctx, args)
+	case "http_request":
+		return executeHTTPRequest(ctx, args)
 
 	// Calendar
 	case "calendar_today":
@@ -2964,6 +4226,8 @@ func callToolDirect(ctx context.Context, name string, args map[string]any) strin
 		return executeCalendarSearch(ctx, args)
 	case "calendar_delete":
 		return executeCalendarDelete(ctx, args)
+	case "calendar_freebusy":
+		return executeCalendarFreeBusy(ctx, args)
 
 	// Reminders
 	case "reminders_list":
@@ -3047,6 +4311,10 @@ func callToolDirect(ctx context.Context, name string, args map[string]any) strin
 	case "notification_send":
 		return executeNotificationSend(ctx, args)
 
+	// Printing
+	case "print_file":
+		return executePrintFile(ctx, args)
+
 	// Screenshot
 	case "screenshot":
 		return executeScreenshot(ctx, args)
@@ -3166,6 +4434,10 @@ func callToolDirect(ctx context.Context, name string, args map[string]any) strin
 		return executeBrowserStatus(ctx)
 	case "browser_stop":
 		return executeBrowserStop(ctx)
+	case "browser_network_start_capture":
+		return executeBrowserNetworkStartCapture(ctx, args)
+	case "browser_network_get_requests":
+		return executeBrowserNetworkGetRequests(ctx, args)
 
 	default:
 		return fmt.Sprintf("Tool '%s' not implemented", name)
@@ -3234,11 +4506,12 @@ func (a *Agent) executeSaveDailyReport(args map[string]any) string {
 		Calendars: calendars,
 	}
 
+	a.syncTasksFromReport(report)
 	if err := a.persistStore.SaveDailyReport(report); err != nil {
 		return fmt.Sprintf("Error saving daily report: %v", err)
 	}
 
-	a.latestReport = report
+	a.setLatestReport(report)
 	log.Printf("[AGENT] Daily report saved for %s", date)
 	return fmt.Sprintf("Daily report saved successfully for %s", date)
 }
@@ -3281,7 +4554,12 @@ func (a *Agent) executeGetDailyReport(args map[string]any) string {
 		for _, cal := range report.Calendars {
 			result += fmt.Sprintf("  - %s (%s)\n", cal.Title, cal.StartTime)
 		}
+		result += "\n"
 	}
+	result += a.formatSlowestTools(report.Date, 5)
+	result += a.formatSatisfaction(7)
+	result += a.formatWeeklyTime()
+	result += a.formatPriceReport("", 0)
 
 	return result
 }
@@ -3364,12 +4642,13 @@ func (a *Agent) executeSearchMessages(args map[string]any) string {
 }
 
 // executeGetConversationSummary gets a summary of the current conversation
-func (a *Agent) executeGetConversationSummary(args map[string]any) string {
+func (a *Agent) executeGetConversationSummary(ctx context.Context, args map[string]any) string {
 	if a.persistStore == nil {
 		return "Error: persist store not available"
 	}
 
-	conv, err := a.persistStore.GetOrCreateConversation(a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID)
+	msg := turnMessage(ctx)
+	conv, err := a.persistStore.GetOrCreateConversation(msg.Platform, msg.ChannelID, msg.UserID)
 	if err != nil {
 		return fmt.Sprintf("Error getting conversation: %v", err)
 	}
@@ -3388,7 +4667,8 @@ func (a *Agent) executeGetConversationSummary(args map[string]any) string {
 }
 
 func (a *Agent) executeMemorySearch(ctx context.Context, args map[string]any) string {
-	if a.markdownMemory == nil || !a.markdownMemory.IsEnabled() {
+	md := a.markdownStore()
+	if md == nil || !md.IsEnabled() {
 		return "Error: markdown memory is disabled. Please configure memory.enabled and memory.obsidian_vault in ~/.coco.yaml"
 	}
 
@@ -3406,7 +4686,7 @@ func (a *Agent) executeMemorySearch(ctx context.Context, args map[string]any) st
 		limit = 6
 	}
 
-	results, err := a.markdownMemory.Search(ctx, query, limit)
+	results, err := md.Search(ctx, query, limit)
 	if err != nil {
 		return fmt.Sprintf("Error searching markdown memory: %v", err)
 	}
@@ -3438,7 +4718,8 @@ func (a *Agent) executeMemorySearch(ctx context.Context, args map[string]any) st
 }
 
 func (a *Agent) executeMemoryGet(args map[string]any) string {
-	if a.markdownMemory == nil || !a.markdownMemory.IsEnabled() {
+	md := a.markdownStore()
+	if md == nil || !md.IsEnabled() {
 		return "Error: markdown memory is disabled. Please configure memory.enabled and memory.obsidian_vault in ~/.coco.yaml"
 	}
 
@@ -3448,7 +4729,7 @@ func (a *Agent) executeMemoryGet(args map[string]any) string {
 		return "Error: path is required"
 	}
 
-	result, err := a.markdownMemory.Get(path)
+	result, err := md.Get(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Sprintf("Memory file not found: %s", path)
@@ -3467,12 +4748,16 @@ func (a *Agent) executeMemoryGet(args map[string]any) string {
 }
 
 func (a *Agent) executeMemoryWrite(args map[string]any) string {
-	if a.markdownMemory == nil || !a.markdownMemory.IsEnabled() {
+	md := a.markdownStore()
+	if md == nil || !md.IsEnabled() {
 		return "Error: markdown memory is disabled. Please configure memory.enabled and memory.obsidian_vault in ~/.coco.yaml"
 	}
 
 	path, _ := args["path"].(string)
 	path = strings.TrimSpace(path)
+	if path == "" {
+		path = md.DefaultNotePath()
+	}
 	if path == "" {
 		return "Error: path is required"
 	}
@@ -3485,7 +4770,30 @@ func (a *Agent) executeMemoryWrite(args map[string]any) string {
 	mode, _ := args["mode"].(string)
 	mode = strings.TrimSpace(mode)
 
-	result, err := a.markdownMemory.Put(path, content, mode)
+	title, _ := args["title"].(string)
+	title = strings.TrimSpace(title)
+	var tags []string
+	switch v := args["tags"].(type) {
+	case []any:
+		for _, t := range v {
+			if s, ok := t.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+	case string:
+		tags = strings.Split(v, ",")
+	}
+
+	var result MarkdownMemoryResult
+	var err error
+	if title != "" || len(tags) > 0 {
+		result, err = md.PutNote(path, MemoryNote{Title: title, Content: content, Tags: tags}, mode)
+	} else {
+		result, err = md.Put(path, content, mode)
+	}
+	if errors.Is(err, ErrMemoryDuplicate) {
+		return fmt.Sprintf("Memory unchanged: this content is already recorded in %s", path)
+	}
 	if err != nil {
 		return fmt.Sprintf("Error writing markdown memory: %v", err)
 	}
@@ -3508,11 +4816,12 @@ func targetsWorkspaceSOUL(args map[string]any) bool {
 	return target != "" && target == soulPath
 }
 
-func (a *Agent) executeSoulAppend(args map[string]any) string {
-	if strings.EqualFold(strings.TrimSpace(a.currentMsg.Username), "cron") {
+func (a *Agent) executeSoulAppend(ctx context.Context, args map[string]any) string {
+	msg := turnMessage(ctx)
+	if strings.EqualFold(strings.TrimSpace(msg.Username), "cron") {
 		return "ACCESS DENIED: soul_append cannot be executed by heartbeat/cron. Trigger it explicitly in a user conversation."
 	}
-	if !isExplicitSoulAppendIntent(a.currentMsg.Text) {
+	if !isExplicitSoulAppendIntent(msg.Text) {
 		return "ACCESS DENIED: soul_append requires explicit user intent in current message (e.g. \"在你的SOUL文件里追加...\")."
 	}
 
@@ -3540,6 +4849,10 @@ func (a *Agent) executeSoulAppend(args map[string]any) string {
 		}
 	}
 	existing := strings.TrimRight(string(existingBytes), "\n")
+	// Entries are written as "- Entry: <entry>" lines.
+	if memoryContainsNote(existing, "Entry: "+entry) {
+		return fmt.Sprintf("SOUL unchanged: this entry is already recorded in %s", soulPath)
+	}
 
 	timestamp := time.Now().Format("2006-01-02 15:04")
 	var b strings.Builder
@@ -3629,6 +4942,18 @@ func getString(m map[string]any, key string) string {
 	return ""
 }
 
+// getFloat reads a number that the model may also send as a string.
+func getFloat(m map[string]any, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	}
+	return 0
+}
+
 func (a *Agent) executeWebSearchWithManager(ctx context.Context, query string) string {
 	if a.searchManager == nil {
 		return "Error: search manager not initialized. Please configure search engines in ~/.coco.yaml or use --metaso-api-key or --tavily-api-key"
@@ -3663,7 +4988,8 @@ func (a *Agent) executeWebSearchWithManager(ctx context.Context, query string) s
 
 // learnUserPreferences analyzes recent conversations and extracts user preferences
 func (a *Agent) learnUserPreferences(ctx context.Context, convKey string, msg router.Message) {
-	if a.ragMemory == nil || !a.ragMemory.IsEnabled() {
+	rag := a.ragStore()
+	if rag == nil || !rag.IsEnabled() {
 		return
 	}
 
@@ -3720,7 +5046,7 @@ Extract ONLY the preferences, one per line, starting with "- ". Keep it concise
 		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
 			preference := strings.TrimSpace(line[2:])
 			if preference != "" {
-				err := a.ragMemory.AddMemory(ctx, MemoryItem{
+				err := rag.AddMemory(ctx, MemoryItem{
 					ID:      fmt.Sprintf("pref-%s-%d", convKey, time.Now().UnixNano()),
 					Type:    "preference",
 					Content: preference,
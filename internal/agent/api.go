@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+// ConversationSummary describes one conversation held in memory.
+type ConversationSummary struct {
+	Key         string    `json:"key"`
+	Platform    string    `json:"platform"`
+	ChannelID   string    `json:"channel_id"`
+	UserID      string    `json:"user_id"`
+	Messages    int       `json:"messages"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	LastMessage string    `json:"last_message,omitempty"`
+}
+
+// Summaries lists conversations, most recently updated first.
+func (m *ConversationMemory) Summaries() []ConversationSummary {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]ConversationSummary, 0, len(m.conversations))
+	for key, conv := range m.conversations {
+		parts := strings.SplitN(key, ":", 3)
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		summary := ConversationSummary{
+			Key:       key,
+			Platform:  parts[0],
+			ChannelID: parts[1],
+			UserID:    parts[2],
+			Messages:  len(conv.Messages),
+			UpdatedAt: conv.UpdatedAt,
+		}
+		for i := len(conv.Messages) - 1; i >= 0; i-- {
+			if text := strings.TrimSpace(conv.Messages[i].Content); text != "" {
+				if r := []rune(text); len(r) > 200 {
+					text = string(r[:200]) + "…"
+				}
+				summary.LastMessage = text
+				break
+			}
+		}
+		out = append(out, summary)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out
+}
+
+// Conversations lists the conversations the agent remembers.
+func (a *Agent) Conversations() []ConversationSummary {
+	if a.memory == nil {
+		return nil
+	}
+	return a.memory.Summaries()
+}
+
+// ErrUnknownTool is returned by RunTool for names that are not agent tools.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ErrToolDenied is returned by RunTool for tools outside the caller's profile.
+var ErrToolDenied = errors.New("tool not permitted")
+
+// RunTool runs a single tool under the named tool profile, with the same
+// security checks, timeouts and secret redaction as a tool call made by
+// the model.
+func (a *Agent) RunTool(ctx context.Context, profile, name string, args map[string]any) (string, error) {
+	if !a.hasTool(name) {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	if p := a.toolProfileNamed(profile, router.Message{}); !p.Allows(name) {
+		logger.Warn("[Agent] Tool %s denied by API profile %q", name, p.Name)
+		return "", fmt.Errorf("%w: %s (profile %q)", ErrToolDenied, name, p.Name)
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	start := time.Now()
+	result := redactSecretValues(a.executeTool(ctx, name, input))
+	a.recordToolMetric(name, time.Since(start), len(result), strings.HasPrefix(result, "Error"))
+	return result, nil
+}
+
+func (a *Agent) hasTool(name string) bool {
+	for _, tool := range a.buildToolsList() {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolEvent reports the progress of a tool call made while handling a message.
+type ToolEvent struct {
+	Phase    string `json:"phase"` // "start" or "end"
+	Tool     string `json:"tool"`
+	Input    string `json:"input,omitempty"`
+	Duration int64  `json:"duration_ms,omitempty"`
+	IsError  bool   `json:"is_error,omitempty"`
+}
+
+type toolObserverKey struct{}
+
+// WithToolObserver returns a context whose HandleMessage calls report each
+// tool call to fn. fn is called synchronously from the tool loop.
+func WithToolObserver(ctx context.Context, fn func(ToolEvent)) context.Context {
+	return context.WithValue(ctx, toolObserverKey{}, fn)
+}
+
+func observeTool(ctx context.Context, ev ToolEvent) {
+	if fn, ok := ctx.Value(toolObserverKey{}).(func(ToolEvent)); ok && fn != nil {
+		fn(ev)
+	}
+}
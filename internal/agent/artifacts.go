@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+const (
+	defaultArtifactThreshold = 16000 // characters; tools.artifact_threshold
+	artifactPreviewChars     = 2000
+	artifactPageChars        = 8000
+	artifactMaxPageChars     = 20000
+	artifactRetention        = 7 * 24 * time.Hour
+)
+
+// applyArtifactThreshold sets tools.artifact_threshold.
+func (a *Agent) applyArtifactThreshold(n int) {
+	switch {
+	case n == 0:
+		n = defaultArtifactThreshold
+	case n < 0:
+		n = 0
+	}
+	a.securityMu.Lock()
+	a.artifactThreshold = n
+	a.securityMu.Unlock()
+}
+
+// storeLargeResult keeps a tool result over tools.artifact_threshold out of
+// the prompt: the full text is saved as an artifact and the model gets its
+// opening plus the artifact ID to page through with artifact_read.
+func (a *Agent) storeLargeResult(ctx context.Context, tool, result string) string {
+	a.securityMu.RLock()
+	limit := a.artifactThreshold
+	a.securityMu.RUnlock()
+	if limit <= 0 || a.persistStore == nil || tool == "artifact_read" || a.isIncognito(currentConversationKey(ctx)) {
+		return result
+	}
+	runes := []rune(result)
+	if len(runes) <= limit {
+		return result
+	}
+
+	id := newArtifactID()
+	err := a.persistStore.SaveArtifact(persist.Artifact{
+		ID:           id,
+		Tool:         tool,
+		Conversation: currentConversationKey(ctx),
+		Content:      result,
+	})
+	if err != nil {
+		logger.Warn("[Agent] Failed to save %s output as an artifact: %v", tool, err)
+		return result
+	}
+	if n, err := a.persistStore.PruneArtifacts(time.Now().Add(-artifactRetention)); err == nil && n > 0 {
+		logger.Debug("[Agent] Pruned %d old artifact(s)", n)
+	}
+
+	preview := min(artifactPreviewChars, limit)
+	return fmt.Sprintf("%s\n\n…[showing %d of %d characters. The full output is saved as artifact %s; call artifact_read with this id and an offset to read the rest.]",
+		string(runes[:preview]), preview, len(runes), id)
+}
+
+func newArtifactID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "art_" + hex.EncodeToString(b)
+}
+
+func (a *Agent) executeArtifactRead(ctx context.Context, args map[string]any) string {
+	id := strings.TrimSpace(getString(args, "id"))
+	if id == "" {
+		return "Error: id is required"
+	}
+	if a.persistStore == nil {
+		return "Error: artifact store is not available"
+	}
+	art, err := a.persistStore.GetArtifact(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Sprintf("Error: artifact %s not found (artifacts are kept for 7 days)", id)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	// Artifacts belong to the conversation whose tool call produced them.
+	if key := currentConversationKey(ctx); key != "" && art.Conversation != "" && art.Conversation != key {
+		return fmt.Sprintf("Error: artifact %s not found (artifacts are kept for 7 days)", id)
+	}
+
+	runes := []rune(art.Content)
+	offset := max(0, int(getFloat(args, "offset")))
+	limit := int(getFloat(args, "limit"))
+	if limit <= 0 {
+		limit = artifactPageChars
+	}
+	limit = min(limit, artifactMaxPageChars)
+	if offset >= len(runes) {
+		return fmt.Sprintf("Error: offset %d is past the end of artifact %s (%d characters)", offset, id, len(runes))
+	}
+	end := min(offset+limit, len(runes))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Artifact %s (%s output), characters %d-%d of %d:\n\n", id, art.Tool, offset, end, len(runes)))
+	sb.WriteString(string(runes[offset:end]))
+	if end < len(runes) {
+		sb.WriteString(fmt.Sprintf("\n\n…[%d characters left; continue with offset %d]", len(runes)-end, end))
+	}
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestLargeToolResultBecomesArtifact(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.applyArtifactThreshold(5000)
+	ctx := testTurn()
+
+	if got := a.storeLargeResult(ctx, "web_fetch", "short page"); got != "short page" {
+		t.Fatalf("small result changed: %q", got)
+	}
+
+	page := strings.Repeat("页", 3000) + strings.Repeat("x", 9000)
+	preview := a.storeLargeResult(ctx, "web_fetch", page)
+	if len([]rune(preview)) > 2300 || !strings.Contains(preview, "of 12000 characters") {
+		t.Fatalf("preview = %q", preview[len(preview)-200:])
+	}
+	id := regexp.MustCompile(`art_[0-9a-f]+`).FindString(preview)
+	if id == "" {
+		t.Fatalf("no artifact id in %q", preview[len(preview)-200:])
+	}
+
+	got := a.executeArtifactRead(ctx, map[string]any{"id": id, "offset": float64(2500), "limit": float64(1000)})
+	if !strings.Contains(got, "characters 2500-3500 of 12000") || !strings.Contains(got, strings.Repeat("页", 500)+strings.Repeat("x", 500)) {
+		t.Fatalf("page = %q", got[:120])
+	}
+	if !strings.Contains(got, "continue with offset 3500") {
+		t.Fatalf("no continuation hint in page")
+	}
+	last := a.executeArtifactRead(ctx, map[string]any{"id": id, "offset": "11000"})
+	if strings.Contains(last, "continue with offset") || !strings.Contains(last, "11000-12000") {
+		t.Fatalf("last page = %q", last[:80])
+	}
+
+	other := withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "c2", UserID: "u2"})
+	if got := a.executeArtifactRead(other, map[string]any{"id": id}); !strings.Contains(got, "not found") {
+		t.Fatalf("another conversation read the artifact: %q", got[:80])
+	}
+
+	a.applyArtifactThreshold(-1)
+	if got := a.storeLargeResult(ctx, "web_fetch", page); got != page {
+		t.Fatal("disabled threshold still cut the result")
+	}
+}
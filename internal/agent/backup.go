@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/diag"
+	"github.com/kayz/coco/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// backupCheckInterval is how often the backup job checks whether a
+	// snapshot is due.
+	backupCheckInterval = 24 * time.Hour
+	defaultBackupFolder = "coco-backups"
+	defaultBackupDays   = 7
+	// backupDateLayout names the snapshot folders.
+	backupDateLayout = "2006-01-02"
+	cronJobsFile     = "cron-jobs.yaml"
+)
+
+// volatileJobFields change whenever a job runs; they are left out of
+// snapshots so that only real edits show up as a new snapshot.
+var volatileJobFields = []string{"last_run", "last_error", "fail_count", "stale_since"}
+
+// ConfigBackup describes a config snapshot.
+type ConfigBackup struct {
+	Dir       string   // the snapshot folder
+	Files     []string // file names in the folder
+	Unchanged bool     // nothing changed since Dir, the latest snapshot, so nothing was written
+}
+
+type backupSettings struct {
+	cfg   config.BackupConfig
+	vault string
+}
+
+func (a *Agent) applyBackup(cfg config.BackupConfig, vault string) {
+	a.securityMu.Lock()
+	a.backup = backupSettings{cfg: cfg, vault: normalizePath(vault)}
+	a.securityMu.Unlock()
+}
+
+// backupSources maps the config files to their names in a snapshot.
+func backupSources() [][2]string {
+	return [][2]string{
+		{"coco.yaml", config.ConfigPath()},
+		{"providers.yaml", ai.ProvidersPath()},
+		{"models.yaml", ai.ModelsPath()},
+	}
+}
+
+// BackupRoot returns the vault folder holding the snapshots.
+func BackupRoot(vault string, cfg config.BackupConfig) string {
+	folder := strings.TrimSpace(cfg.Folder)
+	if folder == "" {
+		folder = defaultBackupFolder
+	}
+	return filepath.Join(normalizePath(vault), folder)
+}
+
+// SnapshotConfig copies .coco.yaml, providers.yaml, models.yaml and jobs
+// into a folder named after today's date under BackupRoot, with every
+// credential redacted. When nothing changed since the latest snapshot it
+// writes nothing and returns that snapshot as Unchanged.
+func SnapshotConfig(vault string, cfg config.BackupConfig, jobs []*cronpkg.Job, now time.Time) (ConfigBackup, error) {
+	if normalizePath(vault) == "" {
+		return ConfigBackup{}, fmt.Errorf("memory.obsidian_vault is not set")
+	}
+	files := map[string][]byte{}
+	for _, src := range backupSources() {
+		data, err := os.ReadFile(src[1])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return ConfigBackup{}, err
+		}
+		redacted, _, err := diag.RedactYAML(data)
+		if err != nil {
+			return ConfigBackup{}, fmt.Errorf("failed to read %s: %w", src[1], err)
+		}
+		files[src[0]] = redacted
+	}
+	if len(jobs) > 0 {
+		data, err := marshalBackupJobs(jobs)
+		if err != nil {
+			return ConfigBackup{}, err
+		}
+		files[cronJobsFile] = data
+	}
+	names := slices.Sorted(maps.Keys(files))
+
+	root := BackupRoot(vault, cfg)
+	if latest, _ := latestBackup(root, now.Location()); latest != "" && sameBackup(latest, files) {
+		return ConfigBackup{Dir: latest, Files: names, Unchanged: true}, nil
+	}
+
+	dir := filepath.Join(root, now.Format(backupDateLayout))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return ConfigBackup{}, err
+	}
+	// A second snapshot on the same day replaces the first, including files
+	// that no longer exist.
+	stale := []string{cronJobsFile}
+	for _, src := range backupSources() {
+		stale = append(stale, src[0])
+	}
+	for _, name := range stale {
+		if _, ok := files[name]; !ok {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0600); err != nil {
+			return ConfigBackup{}, err
+		}
+	}
+	return ConfigBackup{Dir: dir, Files: names}, nil
+}
+
+// marshalBackupJobs writes jobs as YAML, sorted by ID, without their run
+// state and with credentials such as auth headers redacted.
+func marshalBackupJobs(jobs []*cronpkg.Job) ([]byte, error) {
+	sorted := slices.Clone(jobs)
+	slices.SortFunc(sorted, func(a, b *cronpkg.Job) int { return strings.Compare(a.ID, b.ID) })
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]any
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, job := range list {
+		for _, field := range volatileJobFields {
+			delete(job, field)
+		}
+	}
+	out, err := yaml.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err = diag.RedactYAML(out)
+	return out, err
+}
+
+// latestBackup returns the newest snapshot folder under root and its date.
+func latestBackup(root string, loc *time.Location) (string, time.Time) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", time.Time{}
+	}
+	var dir string
+	var latest time.Time
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		at, err := time.ParseInLocation(backupDateLayout, e.Name(), loc)
+		if err != nil || (!latest.IsZero() && !at.After(latest)) {
+			continue
+		}
+		dir, latest = filepath.Join(root, e.Name()), at
+	}
+	return dir, latest
+}
+
+// sameBackup reports whether dir holds exactly files.
+func sameBackup(dir string, files map[string][]byte) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	count := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		want, ok := files[e.Name()]
+		if !ok {
+			return false
+		}
+		got, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil || !bytes.Equal(got, want) {
+			return false
+		}
+		count++
+	}
+	return count == len(files)
+}
+
+// StartConfigBackup snapshots the config into the Obsidian vault now and
+// then whenever backup.days have passed since the latest snapshot.
+func (a *Agent) StartConfigBackup(ctx context.Context) {
+	go func() {
+		a.runConfigBackup(time.Now())
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				a.runConfigBackup(now)
+			}
+		}
+	}()
+}
+
+func (a *Agent) runConfigBackup(now time.Time) {
+	a.securityMu.RLock()
+	settings := a.backup
+	a.securityMu.RUnlock()
+	if settings.cfg.Disabled || settings.vault == "" {
+		return
+	}
+
+	days := settings.cfg.Days
+	if days <= 0 {
+		days = defaultBackupDays
+	}
+	if _, at := latestBackup(BackupRoot(settings.vault, settings.cfg), now.Location()); !at.IsZero() && at.After(now.AddDate(0, 0, -days)) {
+		return
+	}
+
+	var jobs []*cronpkg.Job
+	if a.cronScheduler != nil {
+		jobs = a.cronScheduler.ListJobs()
+	}
+	backup, err := SnapshotConfig(settings.vault, settings.cfg, jobs, now)
+	switch {
+	case err != nil:
+		logger.Warn("[Agent] Config backup failed: %v", err)
+	case !backup.Unchanged:
+		logger.Info("[Agent] Config backed up to %s", backup.Dir)
+	}
+}
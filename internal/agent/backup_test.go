@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+)
+
+func TestSnapshotConfigRedactsAndSkipsUnchanged(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	vault := t.TempDir()
+	if err := os.WriteFile(config.ConfigPath(), []byte("platforms:\n  telegram:\n    token: tg-secret-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ai.ProvidersPath()), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ai.ProvidersPath(), []byte("providers:\n  - name: openai\n    api_key: sk-secret-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lastRun := time.Now()
+	jobs := []*cronpkg.Job{{ID: "j1", Name: "ping", Schedule: "0 9 * * *", AuthHeader: "Bearer hook-secret", LastRun: &lastRun}}
+
+	day := time.Date(2026, 10, 12, 9, 0, 0, 0, time.Local)
+	backup, err := SnapshotConfig(vault, config.BackupConfig{}, jobs, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(vault, "coco-backups", "2026-10-12"); backup.Dir != want || backup.Unchanged {
+		t.Fatalf("backup = %+v, want a new snapshot in %s", backup, want)
+	}
+	if got := strings.Join(backup.Files, ","); got != "coco.yaml,cron-jobs.yaml,providers.yaml" {
+		t.Fatalf("files = %s", got)
+	}
+	for _, name := range backup.Files {
+		data, err := os.ReadFile(filepath.Join(backup.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"tg-secret-token", "sk-secret-key", "hook-secret", "last_run"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q:\n%s", name, secret, data)
+			}
+		}
+	}
+
+	// Only the run state changed: no new snapshot.
+	later := lastRun.Add(time.Hour)
+	jobs[0].LastRun = &later
+	backup, err = SnapshotConfig(vault, config.BackupConfig{}, jobs, day.AddDate(0, 0, 7))
+	if err != nil || !backup.Unchanged || filepath.Base(backup.Dir) != "2026-10-12" {
+		t.Fatalf("backup = %+v, %v; want unchanged 2026-10-12", backup, err)
+	}
+
+	jobs[0].Schedule = "0 10 * * *"
+	backup, err = SnapshotConfig(vault, config.BackupConfig{}, jobs, day.AddDate(0, 0, 7))
+	if err != nil || backup.Unchanged || filepath.Base(backup.Dir) != "2026-10-19" {
+		t.Fatalf("backup = %+v, %v; want a new snapshot 2026-10-19", backup, err)
+	}
+}
+
+func TestSnapshotConfigNeedsVault(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	if _, err := SnapshotConfig("", config.BackupConfig{}, nil, time.Now()); err == nil {
+		t.Fatal("expected an error without a vault")
+	}
+}
+
+func TestRunConfigBackupWaitsForInterval(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	vault := t.TempDir()
+	if err := os.WriteFile(config.ConfigPath(), []byte("logging:\n  level: info\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{}
+	a.applyBackup(config.BackupConfig{Folder: "backups", Days: 7}, vault)
+
+	day := time.Date(2026, 10, 12, 9, 0, 0, 0, time.Local)
+	a.runConfigBackup(day)
+	if err := os.WriteFile(config.ConfigPath(), []byte("logging:\n  level: debug\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a.runConfigBackup(day.AddDate(0, 0, 3))
+	a.runConfigBackup(day.AddDate(0, 0, 7))
+
+	entries, err := os.ReadDir(filepath.Join(vault, "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, ","); got != "2026-10-12,2026-10-19" {
+		t.Fatalf("snapshots = %s, want 2026-10-12,2026-10-19", got)
+	}
+}
@@ -0,0 +1,487 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/news"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	briefingJobName      = "news-briefing"
+	briefingJobTag       = "assistant-task"
+	defaultBriefingTime  = "07:30"
+	defaultBriefingItems = 3
+	briefingFetchTimeout = 20 * time.Second
+	briefingFeedMaxAge   = 36 * time.Hour // older headlines are left out
+)
+
+var defaultBriefingSections = []string{"weather", "calendar", "tasks", "topics", "feeds"}
+
+// The briefing's sources; tests replace them.
+var (
+	fetchFeed = func(ctx context.Context, url string) ([]news.Item, error) {
+		return news.Fetch(ctx, &http.Client{Timeout: briefingFetchTimeout}, url)
+	}
+	briefingCalendar = executeCalendarToday
+	briefingTasks    = executeRemindersToday
+	// searchTopic returns headlines about a followed topic from web_search.
+	searchTopic = func(ctx context.Context, a *Agent, topic string, limit int) ([]news.Item, error) {
+		if a.searchManager == nil {
+			return nil, fmt.Errorf("web_search is not configured")
+		}
+		resp, err := a.searchManager.Search(ctx, topic+" 最新消息", limit)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]news.Item, 0, len(resp.Results))
+		for _, r := range resp.Results {
+			items = append(items, news.Item{Title: r.Title, Link: r.URL, Summary: r.Snippet, Published: r.PublishedAt})
+		}
+		return items, nil
+	}
+)
+
+type briefingSettings struct {
+	hour, minute int
+	feeds        []string
+	topics       []string
+	location     string
+	sections     []string
+	maxItems     int
+}
+
+// applyBriefing installs the briefing section and moves the daily job if
+// the time changed.
+func (a *Agent) applyBriefing(cfg config.BriefingConfig) {
+	s := briefingSettings{
+		feeds:    cfg.Feeds,
+		topics:   cfg.Topics,
+		location: strings.TrimSpace(cfg.Location),
+		maxItems: cfg.MaxItems,
+	}
+	raw := strings.TrimSpace(cfg.Time)
+	if raw == "" {
+		raw = defaultBriefingTime
+	}
+	at, err := time.Parse("15:04", raw)
+	if err != nil {
+		logger.Warn("[Agent] Invalid briefing.time %q, using %s", cfg.Time, defaultBriefingTime)
+		at, _ = time.Parse("15:04", defaultBriefingTime)
+	}
+	s.hour, s.minute = at.Hour(), at.Minute()
+	if s.maxItems <= 0 {
+		s.maxItems = defaultBriefingItems
+	}
+	for _, section := range cfg.Sections {
+		section = strings.ToLower(strings.TrimSpace(section))
+		if !slices.Contains(defaultBriefingSections, section) {
+			logger.Warn("[Agent] Unknown briefing section %q", section)
+			continue
+		}
+		s.sections = append(s.sections, section)
+	}
+	if len(s.sections) == 0 {
+		s.sections = defaultBriefingSections
+	}
+
+	a.securityMu.Lock()
+	a.briefing = s
+	a.securityMu.Unlock()
+	if a.cronScheduler != nil && a.persistStore != nil {
+		a.ensureBriefingJob()
+	}
+}
+
+func (a *Agent) currentBriefing() briefingSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.briefing
+}
+
+func (s briefingSettings) schedule() string {
+	return fmt.Sprintf("%d %d * * *", s.minute, s.hour)
+}
+
+func (a *Agent) executeNewsBriefing(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	action := strings.ToLower(strings.TrimSpace(getString(args, "action")))
+	msg := turnMessage(ctx)
+	userID := timeUserID(msg)
+	topic := strings.TrimSpace(getString(args, "topic"))
+	switch action {
+	case "", "now":
+		prefs, err := a.persistStore.GetBriefingPrefs(userID)
+		if err != nil {
+			return fmt.Sprintf("Error loading briefing preferences: %v", err)
+		}
+		return a.buildBriefing(ctx, prefs, time.Now())
+	case "follow", "mute":
+		if topic == "" {
+			return "Error: topic is required"
+		}
+		return a.setBriefingTopic(userID, topic, action == "follow")
+	case "topics":
+		return a.listBriefingTopics(userID)
+	case "subscribe":
+		return a.subscribeBriefing(userID, msg, true)
+	case "unsubscribe":
+		return a.subscribeBriefing(userID, msg, false)
+	case "send":
+		return a.sendBriefings(ctx, time.Now())
+	default:
+		return "Error: action must be now, follow, mute, topics, subscribe or unsubscribe"
+	}
+}
+
+// handleNewsCommand answers /news and its topic subcommands.
+func (a *Agent) handleNewsCommand(ctx context.Context, text string) (string, bool) {
+	if a.persistStore == nil {
+		return "", false
+	}
+	var args map[string]any
+	fields := strings.Fields(text)
+	switch {
+	case text == "/news" || text == "今日简报":
+		args = map[string]any{"action": "now"}
+	case strings.HasPrefix(text, "关注话题"):
+		args = map[string]any{"action": "follow", "topic": strings.TrimLeft(strings.TrimPrefix(text, "关注话题"), ":： ")}
+	case strings.HasPrefix(text, "屏蔽话题"):
+		args = map[string]any{"action": "mute", "topic": strings.TrimLeft(strings.TrimPrefix(text, "屏蔽话题"), ":： ")}
+	case len(fields) >= 2 && strings.ToLower(fields[0]) == "/news":
+		sub := strings.ToLower(fields[1])
+		topic := strings.TrimSpace(strings.Join(fields[2:], " "))
+		switch sub {
+		case "follow", "mute":
+			if topic == "" {
+				return "用法: /news " + sub + " 话题", true
+			}
+			args = map[string]any{"action": sub, "topic": topic}
+		case "topics":
+			args = map[string]any{"action": "topics"}
+		case "on":
+			args = map[string]any{"action": "subscribe"}
+		case "off":
+			args = map[string]any{"action": "unsubscribe"}
+		default:
+			return "用法: /news（立即生成简报）、/news follow 话题、/news mute 话题、/news topics、/news on|off（每日推送）", true
+		}
+	default:
+		return "", false
+	}
+	if topic, ok := args["topic"]; ok && topic == "" {
+		return "用法: 关注话题 话题 / 屏蔽话题 话题", true
+	}
+	return a.executeNewsBriefing(ctx, args), true
+}
+
+func (a *Agent) loadBriefingPrefs(userID string) (persist.BriefingPrefs, error) {
+	prefs, err := a.persistStore.GetBriefingPrefs(userID)
+	if err != nil || prefs == nil {
+		return persist.BriefingPrefs{UserID: userID}, err
+	}
+	return *prefs, nil
+}
+
+// setBriefingTopic follows or mutes a topic. Muting also drops feed
+// headlines that mention it.
+func (a *Agent) setBriefingTopic(userID, topic string, follow bool) string {
+	prefs, err := a.loadBriefingPrefs(userID)
+	if err != nil {
+		return fmt.Sprintf("Error loading briefing preferences: %v", err)
+	}
+	prefs.Followed = removeTopic(prefs.Followed, topic)
+	prefs.Muted = removeTopic(prefs.Muted, topic)
+	configured := containsTopic(a.currentBriefing().topics, topic)
+	switch {
+	case follow && !configured:
+		prefs.Followed = append(prefs.Followed, topic)
+	case !follow:
+		prefs.Muted = append(prefs.Muted, topic)
+	}
+	prefs.UpdatedAt = time.Now()
+	if err := a.persistStore.SaveBriefingPrefs(prefs); err != nil {
+		return fmt.Sprintf("Error saving briefing preferences: %v", err)
+	}
+	if follow {
+		return fmt.Sprintf("✅ 已关注「%s」，简报会附上相关新闻", topic)
+	}
+	return fmt.Sprintf("🔇 已屏蔽「%s」，简报不再出现相关内容", topic)
+}
+
+func (a *Agent) listBriefingTopics(userID string) string {
+	prefs, err := a.loadBriefingPrefs(userID)
+	if err != nil {
+		return fmt.Sprintf("Error loading briefing preferences: %v", err)
+	}
+	topics := briefingTopics(a.currentBriefing().topics, prefs)
+	var sb strings.Builder
+	if len(topics) == 0 {
+		sb.WriteString("还没有关注任何话题（/news follow 话题）")
+	} else {
+		sb.WriteString("📌 关注的话题: " + strings.Join(topics, "、"))
+	}
+	if len(prefs.Muted) > 0 {
+		sb.WriteString("\n🔇 已屏蔽: " + strings.Join(prefs.Muted, "、"))
+	}
+	if prefs.Subscribed {
+		s := a.currentBriefing()
+		fmt.Fprintf(&sb, "\n每天 %02d:%02d 推送简报", s.hour, s.minute)
+	}
+	return sb.String()
+}
+
+func (a *Agent) subscribeBriefing(userID string, msg router.Message, on bool) string {
+	prefs, err := a.loadBriefingPrefs(userID)
+	if err != nil {
+		return fmt.Sprintf("Error loading briefing preferences: %v", err)
+	}
+	prefs.Subscribed = on
+	if on {
+		if msg.Platform == "" || msg.ChannelID == "" {
+			return "Error: subscribe from the chat that should receive the briefing"
+		}
+		prefs.Platform, prefs.ChannelID = msg.Platform, msg.ChannelID
+	}
+	prefs.UpdatedAt = time.Now()
+	if err := a.persistStore.SaveBriefingPrefs(prefs); err != nil {
+		return fmt.Sprintf("Error saving briefing preferences: %v", err)
+	}
+	warning := a.ensureBriefingJob()
+	if !on {
+		return "已取消每日简报"
+	}
+	s := a.currentBriefing()
+	reply := fmt.Sprintf("☀️ 每天 %02d:%02d 会在这里推送简报", s.hour, s.minute)
+	if warning != "" {
+		reply += "\n" + warning
+	}
+	return reply
+}
+
+// sendBriefings delivers today's briefing to every subscriber that has not
+// had it yet.
+func (a *Agent) sendBriefings(ctx context.Context, now time.Time) string {
+	subs, err := a.persistStore.BriefingSubscribers()
+	if err != nil {
+		return fmt.Sprintf("Error loading briefing subscribers: %v", err)
+	}
+	sent := 0
+	for _, p := range subs {
+		if a.notifier == nil || p.Platform == "" || p.ChannelID == "" || sameDay(p.LastSent, now) {
+			continue
+		}
+		text := a.buildBriefing(ctx, &p, now)
+		if err := a.notifier.NotifyChatUser(p.Platform, p.ChannelID, p.UserID, text); err != nil {
+			logger.Warn("[Agent] Failed to send briefing to %s: %v", p.UserID, err)
+			continue
+		}
+		p.LastSent = now
+		if err := a.persistStore.SaveBriefingPrefs(p); err != nil {
+			logger.Warn("[Agent] Failed to save briefing state for %s: %v", p.UserID, err)
+		}
+		sent++
+	}
+	return fmt.Sprintf("sent %d briefing(s)", sent)
+}
+
+func sameDay(a, b time.Time) bool {
+	if a.IsZero() {
+		return false
+	}
+	ay, am, ad := a.In(b.Location()).Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+var weekdayNames = [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// buildBriefing puts the configured sections together. A section whose
+// source fails or has nothing to say is left out.
+func (a *Agent) buildBriefing(ctx context.Context, prefs *persist.BriefingPrefs, now time.Time) string {
+	s := a.currentBriefing()
+	var p persist.BriefingPrefs
+	if prefs != nil {
+		p = *prefs
+	}
+	parts := []string{fmt.Sprintf("☀️ 早安简报 · %s %s", now.Format("01-02"), weekdayNames[now.Weekday()])}
+	for _, section := range s.sections {
+		var body string
+		switch section {
+		case "weather":
+			lookupCtx, cancel := context.WithTimeout(ctx, briefingFetchTimeout)
+			weather, err := weatherAt(lookupCtx, s.location, now)
+			cancel()
+			if err != nil {
+				logger.Warn("[Agent] Briefing weather failed: %v", err)
+			} else {
+				body = "🌤 天气\n" + weather
+			}
+		case "calendar":
+			body = briefingToolSection(ctx, "📅 今日日程", briefingCalendar)
+		case "tasks":
+			body = briefingToolSection(ctx, "✅ 待办", briefingTasks)
+		case "topics":
+			body = a.briefingTopicSection(ctx, s, p)
+		case "feeds":
+			body = briefingFeedSection(ctx, s, p, now)
+		}
+		if body != "" {
+			parts = append(parts, body)
+		}
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "今天没有可汇报的内容（在 .coco.yaml 的 briefing 中配置 feeds 或 topics，或 /news follow 话题）")
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func briefingToolSection(ctx context.Context, title string, source func(context.Context) string) string {
+	lookupCtx, cancel := context.WithTimeout(ctx, briefingFetchTimeout)
+	defer cancel()
+	text := strings.TrimSpace(source(lookupCtx))
+	if text == "" || strings.HasPrefix(text, "Error") {
+		if text != "" {
+			logger.Warn("[Agent] Briefing %s: %s", title, text)
+		}
+		return ""
+	}
+	return title + "\n" + text
+}
+
+func (a *Agent) briefingTopicSection(ctx context.Context, s briefingSettings, p persist.BriefingPrefs) string {
+	var sb strings.Builder
+	for _, topic := range briefingTopics(s.topics, p) {
+		lookupCtx, cancel := context.WithTimeout(ctx, briefingFetchTimeout)
+		items, err := searchTopic(lookupCtx, a, topic, s.maxItems)
+		cancel()
+		if err != nil {
+			logger.Warn("[Agent] Briefing search for %q failed: %v", topic, err)
+			continue
+		}
+		lines := headlines(items, p.Muted, time.Time{}, s.maxItems)
+		if len(lines) == 0 {
+			continue
+		}
+		sb.WriteString("\n🔎 " + topic + "\n" + strings.Join(lines, "\n"))
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "📌 关注话题" + sb.String()
+}
+
+func briefingFeedSection(ctx context.Context, s briefingSettings, p persist.BriefingPrefs, now time.Time) string {
+	var sb strings.Builder
+	for _, url := range s.feeds {
+		lookupCtx, cancel := context.WithTimeout(ctx, briefingFetchTimeout)
+		items, err := fetchFeed(lookupCtx, url)
+		cancel()
+		if err != nil {
+			logger.Warn("[Agent] Briefing feed %s failed: %v", url, err)
+			continue
+		}
+		lines := headlines(items, p.Muted, now.Add(-briefingFeedMaxAge), s.maxItems)
+		if len(lines) == 0 {
+			continue
+		}
+		source := url
+		if len(items) > 0 && items[0].Source != "" {
+			source = items[0].Source
+		}
+		sb.WriteString("\n📰 " + source + "\n" + strings.Join(lines, "\n"))
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "🗞 新闻" + sb.String()
+}
+
+// headlines formats up to limit items that mention no muted topic and, when
+// since is set, were not published before it.
+func headlines(items []news.Item, muted []string, since time.Time, limit int) []string {
+	var lines []string
+	for _, it := range items {
+		if len(lines) == limit {
+			break
+		}
+		if !since.IsZero() && !it.Published.IsZero() && it.Published.Before(since) {
+			continue
+		}
+		if slices.ContainsFunc(muted, it.Mentions) {
+			continue
+		}
+		line := "• " + it.Title
+		if it.Link != "" {
+			line += "\n  " + it.Link
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// briefingTopics is the configured topics plus the user's own, minus the
+// muted ones.
+func briefingTopics(configured []string, p persist.BriefingPrefs) []string {
+	var topics []string
+	for _, t := range append(slices.Clone(configured), p.Followed...) {
+		t = strings.TrimSpace(t)
+		if t == "" || containsTopic(topics, t) || containsTopic(p.Muted, t) {
+			continue
+		}
+		topics = append(topics, t)
+	}
+	return topics
+}
+
+func containsTopic(topics []string, topic string) bool {
+	return slices.ContainsFunc(topics, func(t string) bool { return strings.EqualFold(t, topic) })
+}
+
+func removeTopic(topics []string, topic string) []string {
+	return slices.DeleteFunc(slices.Clone(topics), func(t string) bool { return strings.EqualFold(t, topic) })
+}
+
+// ensureBriefingJob keeps one daily briefing job, at the configured time,
+// while anyone is subscribed and removes it when no one is.
+func (a *Agent) ensureBriefingJob() string {
+	if a.cronScheduler == nil {
+		return ""
+	}
+	subs, err := a.persistStore.BriefingSubscribers()
+	if err != nil {
+		logger.Warn("[Agent] Failed to load briefing subscribers: %v", err)
+		return ""
+	}
+	schedule := a.currentBriefing().schedule()
+	keep := false
+	for _, job := range a.cronScheduler.ListJobsByTag(briefingJobTag) {
+		if job.Name != briefingJobName {
+			continue
+		}
+		if len(subs) > 0 && job.Schedule == schedule && !keep {
+			keep = true
+			continue
+		}
+		if err := a.cronScheduler.RemoveJob(job.ID); err != nil {
+			logger.Warn("[Agent] Failed to remove briefing job: %v", err)
+		}
+	}
+	if len(subs) > 0 && !keep {
+		if _, err := a.cronScheduler.AddJobWithTag(briefingJobName, briefingJobTag, schedule, "news_briefing", map[string]any{"action": "send"}); err != nil {
+			logger.Warn("[Agent] Failed to schedule briefing: %v", err)
+			return fmt.Sprintf("Warning: daily briefing not scheduled: %v", err)
+		}
+	}
+	return ""
+}
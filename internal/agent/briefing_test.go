@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/news"
+)
+
+func fakeBriefingSources(t *testing.T, now time.Time) *[]string {
+	t.Helper()
+	var searched []string
+	oldFeed, oldCal, oldTasks, oldSearch, oldWeather := fetchFeed, briefingCalendar, briefingTasks, searchTopic, weatherAt
+	t.Cleanup(func() {
+		fetchFeed, briefingCalendar, briefingTasks, searchTopic, weatherAt = oldFeed, oldCal, oldTasks, oldSearch, oldWeather
+	})
+	fetchFeed = func(ctx context.Context, url string) ([]news.Item, error) {
+		return []news.Item{
+			{Title: "电动车销量创新高", Source: "36氪", Published: now.Add(-time.Hour)},
+			{Title: "新一代 GPU 发布", Link: "https://example.com/gpu", Source: "36氪", Published: now.Add(-2 * time.Hour)},
+			{Title: "上周的旧闻", Source: "36氪", Published: now.Add(-72 * time.Hour)},
+		}, nil
+	}
+	briefingCalendar = func(ctx context.Context) string { return "10:00 周会" }
+	briefingTasks = func(ctx context.Context) string { return "Error: reminders are only available on macOS" }
+	searchTopic = func(ctx context.Context, a *Agent, topic string, limit int) ([]news.Item, error) {
+		searched = append(searched, topic)
+		return []news.Item{{Title: topic + " 今日动态"}}, nil
+	}
+	weatherAt = func(ctx context.Context, location string, at time.Time) (string, error) {
+		return location + " 晴 18°C", nil
+	}
+	return &searched
+}
+
+func TestNewsBriefingTopics(t *testing.T) {
+	now := time.Now()
+	searched := fakeBriefingSources(t, now)
+	a, _ := newFocusTestAgent(t)
+	a.applyBriefing(config.BriefingConfig{Feeds: []string{"https://example.com/rss"}, Topics: []string{"AI"}, Location: "上海"})
+	ctx := testTurn()
+
+	a.handleNewsCommand(ctx, "/news mute 电动车")
+	if reply, _ := a.handleNewsCommand(ctx, "关注话题：机器人"); !strings.Contains(reply, "已关注「机器人」") {
+		t.Fatalf("follow: %q", reply)
+	}
+	if reply, _ := a.handleNewsCommand(ctx, "/news topics"); !strings.Contains(reply, "AI、机器人") || !strings.Contains(reply, "已屏蔽: 电动车") {
+		t.Fatalf("topics: %q", reply)
+	}
+
+	briefing, _ := a.handleNewsCommand(ctx, "/news")
+	for _, want := range []string{"上海 晴 18°C", "📅 今日日程\n10:00 周会", "🔎 AI\n• AI 今日动态", "🔎 机器人", "📰 36氪\n• 新一代 GPU 发布\n  https://example.com/gpu"} {
+		if !strings.Contains(briefing, want) {
+			t.Fatalf("briefing missing %q:\n%s", want, briefing)
+		}
+	}
+	for _, unwanted := range []string{"电动车销量", "旧闻", "待办"} {
+		if strings.Contains(briefing, unwanted) {
+			t.Fatalf("briefing has %q:\n%s", unwanted, briefing)
+		}
+	}
+
+	// Muting a configured topic stops searching it.
+	a.handleNewsCommand(ctx, "屏蔽话题 ai")
+	*searched = nil
+	a.handleNewsCommand(ctx, "/news")
+	if strings.Join(*searched, ",") != "机器人" {
+		t.Fatalf("searched %v after muting AI", *searched)
+	}
+}
+
+func TestNewsBriefingDailyDelivery(t *testing.T) {
+	now := time.Now()
+	fakeBriefingSources(t, now)
+	a, n := newFocusTestAgent(t)
+	a.applyBriefing(config.BriefingConfig{Time: "06:45", Sections: []string{"weather", "calendar"}})
+	ctx := testTurn()
+
+	if reply, _ := a.handleNewsCommand(ctx, "/news on"); !strings.Contains(reply, "每天 06:45") {
+		t.Fatalf("subscribe: %q", reply)
+	}
+	a.sendBriefings(context.Background(), now)
+	a.sendBriefings(context.Background(), now.Add(time.Minute))
+	sent := n.messages()
+	if len(sent) != 1 || !strings.Contains(sent[0], "早安简报") || strings.Contains(sent[0], "新闻") {
+		t.Fatalf("sent = %q", sent)
+	}
+
+	a.handleNewsCommand(ctx, "/news off")
+	a.sendBriefings(context.Background(), now.Add(24*time.Hour))
+	if len(n.messages()) != 1 {
+		t.Fatal("briefing sent after unsubscribing")
+	}
+}
@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	// broadcastActiveWindow is how recently a conversation must have been
+	// used to receive broadcasts.
+	broadcastActiveWindow = 30 * 24 * time.Hour
+	// broadcastCooldown is how long the broadcast tool waits after the
+	// last broadcast before queueing another.
+	broadcastCooldown = 10 * time.Minute
+	// broadcastPollInterval is how often queued broadcasts from
+	// `coco broadcast` are picked up.
+	broadcastPollInterval = 15 * time.Second
+	// broadcastPreviewLimit caps the conversations listed in a preview.
+	broadcastPreviewLimit = 20
+)
+
+// broadcastSendInterval spaces the messages of a broadcast so platforms do
+// not rate-limit or flag the bot. Tests shorten it.
+var broadcastSendInterval = time.Second
+
+// broadcastQueue serializes delivery; wake starts it early when the tool
+// queues a broadcast.
+type broadcastQueue struct {
+	mu       sync.Mutex // held while delivering
+	wakeOnce sync.Once
+	wake     chan struct{}
+}
+
+func (q *broadcastQueue) wakeup() chan struct{} {
+	q.wakeOnce.Do(func() { q.wake = make(chan struct{}, 1) })
+	return q.wake
+}
+
+func (q *broadcastQueue) kick() {
+	select {
+	case q.wakeup() <- struct{}{}:
+	default:
+	}
+}
+
+// broadcastTarget is one chat a broadcast goes to.
+type broadcastTarget struct {
+	platform, channelID, userID string
+}
+
+func (t broadcastTarget) String() string {
+	return t.platform + ":" + t.channelID
+}
+
+// broadcastTargets lists the chats used in the last broadcastActiveWindow,
+// once per channel, that carry one of tags (any chat when tags is empty)
+// and can still be reached. The chat that asked for the broadcast is left
+// out; it gets the delivery report instead.
+func (a *Agent) broadcastTargets(tags []string, origin router.Message) ([]broadcastTarget, error) {
+	convs, err := a.persistStore.ConversationsSince(time.Now().Add(-broadcastActiveWindow))
+	if err != nil {
+		return nil, err
+	}
+	checker, _ := a.notifier.(cronpkg.TargetChecker)
+	seen := make(map[string]bool)
+	var targets []broadcastTarget
+	for _, c := range convs {
+		userID, _, _ := strings.Cut(c.UserID, persist.TopicSeparator) // a topic thread of the chat
+		t := broadcastTarget{platform: c.Platform, channelID: c.ChannelID, userID: userID}
+		if t.platform == "" || t.channelID == "" || seen[t.String()] {
+			continue
+		}
+		seen[t.String()] = true
+		if t.platform == origin.Platform && t.channelID == origin.ChannelID {
+			continue
+		}
+		if len(tags) > 0 && !a.channelHasTag(t.platform, t.channelID, tags) {
+			continue
+		}
+		if checker != nil && checker.TargetAvailable(t.platform, t.channelID, t.userID) != nil {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// channelHasTag reports whether the channel's profile carries one of tags.
+func (a *Agent) channelHasTag(platform, channelID string, tags []string) bool {
+	p, ok := a.channelProfileFor(router.Message{Platform: platform, ChannelID: channelID})
+	if !ok {
+		return false
+	}
+	for _, have := range p.Tags {
+		for _, want := range tags {
+			if strings.EqualFold(strings.TrimSpace(have), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeBroadcastTags reads tags from a list or a comma separated string.
+func normalizeBroadcastTags(v any) []string {
+	var raw []string
+	switch tags := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == '，' || r == '、' })
+	case []any:
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	case []string:
+		raw = tags
+	}
+	var out []string
+	for _, t := range raw {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (a *Agent) executeBroadcast(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	if a.notifier == nil {
+		return "Error: no messaging platform is connected"
+	}
+	msg := turnMessage(ctx)
+	if isGroupConversation(msg) {
+		return "Error: 不能在群聊中发起广播，请在与 coco 的私聊中操作"
+	}
+	text := strings.TrimSpace(getString(args, "message"))
+	if text == "" {
+		return "Error: message is required"
+	}
+	if last, err := a.persistStore.LastBroadcastAt(); err == nil && time.Since(last) < broadcastCooldown {
+		return fmt.Sprintf("Error: 上一次广播在 %s，两次广播至少间隔 %d 分钟", last.Format("15:04"), int(broadcastCooldown.Minutes()))
+	}
+	tags := normalizeBroadcastTags(args["tags"])
+	targets, err := a.broadcastTargets(tags, msg)
+	if err != nil {
+		return fmt.Sprintf("Error loading conversations: %v", err)
+	}
+	if len(targets) == 0 {
+		if len(tags) > 0 {
+			return fmt.Sprintf("最近 %d 天没有带标签 %s 的活跃会话，未广播", int(broadcastActiveWindow.Hours()/24), strings.Join(tags, "、"))
+		}
+		return fmt.Sprintf("最近 %d 天没有其他活跃会话，未广播", int(broadcastActiveWindow.Hours()/24))
+	}
+
+	if confirm, _ := args["confirm"].(bool); !confirm {
+		var b strings.Builder
+		fmt.Fprintf(&b, "将向 %d 个会话广播：\n", len(targets))
+		for i, t := range targets {
+			if i == broadcastPreviewLimit {
+				fmt.Fprintf(&b, "- ……另外 %d 个\n", len(targets)-i)
+				break
+			}
+			b.WriteString("- " + t.String() + "\n")
+		}
+		b.WriteString("\n内容：" + text + "\n\n尚未发送。请把名单和内容给用户确认；用户明确同意后再以 confirm=true 调用 broadcast。")
+		return b.String()
+	}
+
+	id, err := a.persistStore.QueueBroadcast(persist.Broadcast{
+		Message:   text,
+		Tags:      tags,
+		Source:    "tool",
+		Platform:  msg.Platform,
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+	})
+	if err != nil {
+		return fmt.Sprintf("Error queueing broadcast: %v", err)
+	}
+	a.broadcasts.kick()
+	return fmt.Sprintf("📢 广播 #%d 已排队，将逐条发给 %d 个会话（每 %s 一条），发完后在这里汇报结果", id, len(targets), broadcastSendInterval)
+}
+
+// StartBroadcasts delivers queued broadcasts: right away for ones the tool
+// queues, and every broadcastPollInterval for ones from `coco broadcast`.
+func (a *Agent) StartBroadcasts(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(broadcastPollInterval)
+		defer ticker.Stop()
+		for {
+			a.deliverBroadcasts(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-a.broadcasts.wakeup():
+			}
+		}
+	}()
+}
+
+// deliverBroadcasts sends every pending broadcast, one at a time.
+func (a *Agent) deliverBroadcasts(ctx context.Context) {
+	if a.persistStore == nil || a.notifier == nil {
+		return
+	}
+	a.broadcasts.mu.Lock()
+	defer a.broadcasts.mu.Unlock()
+
+	pending, err := a.persistStore.PendingBroadcasts()
+	if err != nil {
+		logger.Warn("[Agent] Failed to load queued broadcasts: %v", err)
+		return
+	}
+	for _, b := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		a.deliverBroadcast(ctx, b)
+	}
+}
+
+func (a *Agent) deliverBroadcast(ctx context.Context, b persist.Broadcast) {
+	origin := router.Message{Platform: b.Platform, ChannelID: b.ChannelID, UserID: b.UserID}
+	targets, err := a.broadcastTargets(b.Tags, origin)
+	if err != nil {
+		logger.Warn("[Agent] Failed to resolve broadcast #%d: %v", b.ID, err)
+		return
+	}
+
+	text := "📢 " + b.Message
+	sent := 0
+	var failures []string
+	for i, t := range targets {
+		if i > 0 && !sleepCtx(ctx, broadcastSendInterval) {
+			failures = append(failures, fmt.Sprintf("coco 已停止，其余 %d 个会话未发送", len(targets)-i))
+			break
+		}
+		if err := a.notifier.NotifyChatUser(t.platform, t.channelID, t.userID, text); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", t, err))
+			continue
+		}
+		sent++
+	}
+	report := strings.Join(failures, "\n")
+	if err := a.persistStore.FinishBroadcast(b.ID, len(targets), sent, report); err != nil {
+		logger.Warn("[Agent] Failed to record broadcast #%d: %v", b.ID, err)
+	}
+	logger.Info("[Agent] Broadcast #%d (%s) delivered to %d/%d conversation(s)", b.ID, b.Source, sent, len(targets))
+
+	if b.Platform == "" {
+		return
+	}
+	summary := fmt.Sprintf("📢 广播 #%d 已发送 %d/%d 个会话", b.ID, sent, len(targets))
+	if report != "" {
+		summary += "\n未送达:\n" + report
+	}
+	if err := a.notifier.NotifyChatUser(b.Platform, b.ChannelID, b.UserID, summary); err != nil {
+		logger.Warn("[Agent] Failed to report broadcast #%d: %v", b.ID, err)
+	}
+}
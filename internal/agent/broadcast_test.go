@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestBroadcastPreviewConfirmAndDeliver(t *testing.T) {
+	orig := broadcastSendInterval
+	broadcastSendInterval = 0
+	defer func() { broadcastSendInterval = orig }()
+
+	a := newTaskTestAgent(t)
+	n := &recordingNotifier{}
+	a.notifier = n
+	for _, c := range [][3]string{
+		{"telegram", "me", "u1"},
+		{"wecom", "dm-a", "alice"},
+		{"slack", "G1", "u2"},
+		{"slack", "G1", "u3"}, // same channel, sent once
+	} {
+		if _, err := a.persistStore.GetOrCreateConversation(c[0], c[1], c[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{"slack:G1": {Tags: []string{"Family"}}})
+	ctx := withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "me", UserID: "u1"})
+
+	group := withTurn(context.Background(), router.Message{Platform: "slack", ChannelID: "G1", UserID: "u2", Metadata: map[string]string{"chat_type": "group"}})
+	if got := a.executeBroadcast(group, map[string]any{"message": "升级"}); !strings.Contains(got, "群聊") {
+		t.Fatalf("group broadcast = %q", got)
+	}
+
+	preview := a.executeBroadcast(ctx, map[string]any{"message": "今晚升级，稍后离线"})
+	if !strings.Contains(preview, "将向 2 个会话广播") || !strings.Contains(preview, "wecom:dm-a") || strings.Contains(preview, "telegram:me") {
+		t.Fatalf("preview = %q", preview)
+	}
+	if tagged := a.executeBroadcast(ctx, map[string]any{"message": "聚餐", "tags": []any{"family"}}); !strings.Contains(tagged, "将向 1 个会话广播") || !strings.Contains(tagged, "slack:G1") {
+		t.Fatalf("tagged preview = %q", tagged)
+	}
+	if pending, _ := a.persistStore.PendingBroadcasts(); len(pending) != 0 {
+		t.Fatalf("preview queued %+v", pending)
+	}
+
+	if got := a.executeBroadcast(ctx, map[string]any{"message": "今晚升级，稍后离线", "confirm": true}); !strings.Contains(got, "广播 #1 已排队") {
+		t.Fatalf("confirm = %q", got)
+	}
+	if got := a.executeBroadcast(ctx, map[string]any{"message": "again", "confirm": true}); !strings.Contains(got, "至少间隔") {
+		t.Fatalf("cooldown = %q", got)
+	}
+
+	a.deliverBroadcasts(context.Background())
+	if len(n.messages) != 3 || n.messages[0] != "📢 今晚升级，稍后离线" || !strings.HasPrefix(n.messages[2], "📢 广播 #1 已发送 2/2") {
+		t.Fatalf("messages = %q", n.messages)
+	}
+	if n.targets[2] != ConversationKey("telegram", "me", "u1") {
+		t.Fatalf("report went to %s", n.targets[2])
+	}
+	b, err := a.persistStore.GetBroadcast(1)
+	if err != nil || b.Status != persist.BroadcastDone || b.Sent != 2 || b.Total != 2 {
+		t.Fatalf("broadcast = %+v, %v", b, err)
+	}
+}
+
+func TestBroadcastQueuedFromCLI(t *testing.T) {
+	orig := broadcastSendInterval
+	broadcastSendInterval = 0
+	defer func() { broadcastSendInterval = orig }()
+
+	a := newTaskTestAgent(t)
+	n := &recordingNotifier{}
+	a.notifier = n
+	a.persistStore.GetOrCreateConversation("wecom", "dm-a", "alice")
+	a.persistStore.GetOrCreateConversation("wecom", "dm-b", "bob")
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{"dm-b": {Tags: []string{"ops"}}})
+
+	if _, err := a.persistStore.QueueBroadcast(persist.Broadcast{Message: "维护中", Tags: []string{"ops"}, Source: "cli"}); err != nil {
+		t.Fatal(err)
+	}
+	a.deliverBroadcasts(context.Background())
+	if len(n.targets) != 1 || n.targets[0] != ConversationKey("wecom", "dm-b", "bob") {
+		t.Fatalf("targets = %v", n.targets)
+	}
+	// Delivered broadcasts are not sent again.
+	a.deliverBroadcasts(context.Background())
+	if len(n.targets) != 1 {
+		t.Fatalf("resent: %v", n.targets)
+	}
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/netcal"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// applyCalendar installs the calendar section. With a provider set, the
+// calendar tools use that CalDAV or Google calendar instead of the
+// platform's calendar app; a broken configuration falls back to the app.
+func (a *Agent) applyCalendar(cfg config.CalendarConfig) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if provider == "" {
+		tools.SetNetworkCalendar(nil)
+		return
+	}
+	cal, err := netcal.New(netcal.Config{
+		Provider:     provider,
+		URL:          cfg.CalDAV.URL,
+		Username:     cfg.CalDAV.Username,
+		Password:     cfg.CalDAV.Password,
+		Email:        cfg.CalDAV.Email,
+		ClientID:     cfg.Google.ClientID,
+		ClientSecret: cfg.Google.ClientSecret,
+		RefreshToken: cfg.Google.RefreshToken,
+		CalendarID:   cfg.Google.CalendarID,
+	})
+	if err != nil {
+		logger.Warn("[Agent] Network calendar disabled: %v", err)
+		tools.SetNetworkCalendar(nil)
+		return
+	}
+	tools.SetNetworkCalendar(cal)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+)
+
+// applyChannelProfiles installs the channels section of the config.
+func (a *Agent) applyChannelProfiles(configured map[string]config.ChannelProfileConfig) {
+	profiles := make(map[string]config.ChannelProfileConfig, len(configured))
+	for key, p := range configured {
+		key = strings.TrimSpace(key)
+		if platform, channel, ok := strings.Cut(key, ":"); ok {
+			key = strings.ToLower(strings.TrimSpace(platform)) + ":" + strings.TrimSpace(channel)
+		}
+		if key == "" {
+			continue
+		}
+		profiles[key] = p
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.channelProfiles = profiles
+}
+
+// channelProfileFor returns the profile bound to the message's channel.
+// "platform:channel_id" wins over a bare channel ID.
+func (a *Agent) channelProfileFor(msg router.Message) (config.ChannelProfileConfig, bool) {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	if len(a.channelProfiles) == 0 || msg.ChannelID == "" {
+		return config.ChannelProfileConfig{}, false
+	}
+	if p, ok := a.channelProfiles[strings.ToLower(msg.Platform)+":"+msg.ChannelID]; ok {
+		return p, true
+	}
+	p, ok := a.channelProfiles[msg.ChannelID]
+	return p, ok
+}
+
+// channelAllowsTool reports whether the channel's tool whitelist permits tool.
+func (a *Agent) channelAllowsTool(msg router.Message, tool string) bool {
+	p, ok := a.channelProfileFor(msg)
+	if !ok || len(p.Tools) == 0 {
+		return true
+	}
+	return security.ToolProfile{Allow: p.Tools}.Allows(tool)
+}
+
+// filterToolsForChannel drops tools outside the channel's whitelist.
+func (a *Agent) filterToolsForChannel(tools []Tool, msg router.Message) []Tool {
+	if p, ok := a.channelProfileFor(msg); !ok || len(p.Tools) == 0 {
+		return tools
+	}
+	filtered := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		if a.channelAllowsTool(msg, t.Name) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// checkChannelTools rejects calls to tools the current channel does not permit.
+func (a *Agent) checkChannelTools(ctx context.Context, name string) string {
+	msg := turnMessage(ctx)
+	if a.channelAllowsTool(msg, name) {
+		return ""
+	}
+	logger.Warn("[Agent] Tool %s denied by channel profile for %s/%s", name, msg.Platform, msg.ChannelID)
+	return fmt.Sprintf("ACCESS DENIED: tool %s is not available in this channel. Do NOT retry. Answer without it.", name)
+}
+
+// channelPersona returns the persona prompt for the message's channel. A
+// value ending in .md is read from the workspace directory.
+func (a *Agent) channelPersona(msg router.Message) string {
+	p, ok := a.channelProfileFor(msg)
+	if !ok {
+		return ""
+	}
+	persona := strings.TrimSpace(p.Persona)
+	if !strings.HasSuffix(strings.ToLower(persona), ".md") || strings.ContainsAny(persona, "\n") {
+		return persona
+	}
+	path := persona
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(getWorkspaceDir(), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Warn("[Agent] Channel persona file %s unreadable: %v", path, err)
+		return ""
+	}
+	return stripYAMLFrontmatter(string(data))
+}
+
+// channelModel resolves the channel's model setting: a role name changes
+// the role models are picked for, anything else names a model.
+func (a *Agent) channelModel(msg router.Message) (model *ai.ModelConfig, role string) {
+	p, ok := a.channelProfileFor(msg)
+	name := strings.TrimSpace(p.Model)
+	if !ok || name == "" {
+		return nil, ""
+	}
+	switch strings.ToLower(name) {
+	case ai.RolePrimary, ai.RoleExpert, ai.RoleCron:
+		return nil, strings.ToLower(name)
+	}
+	if a.modelRouter != nil {
+		for _, m := range a.modelRouter.ListModels() {
+			if m.Name == name {
+				return m, ""
+			}
+		}
+	}
+	logger.Warn("[Agent] Channel %s/%s asks for unknown model %q, using the default", msg.Platform, msg.ChannelID, name)
+	return nil, ""
+}
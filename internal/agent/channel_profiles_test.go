@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestChannelProfiles(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "SUPPORT.md"), []byte("---\nkind: persona\n---\nYou are the support desk."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{}
+	a.applyToolProfiles(nil, "")
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{
+		"WeCom:kf_support": {Persona: "SUPPORT.md", Model: "expert", Tools: []string{"web_*", "weather_current"}},
+		"kf_support":       {Persona: "generic"},
+		"team":             {Persona: "Be brief."},
+	})
+
+	kf := router.Message{Platform: "wecom", ChannelID: "kf_support", UserID: "customer"}
+	other := router.Message{Platform: "slack", ChannelID: "kf_support", UserID: "u"}
+	plain := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "owner"}
+
+	if got := a.channelPersona(kf); got != "You are the support desk." {
+		t.Fatalf("persona file = %q", got)
+	}
+	if got := a.channelPersona(other); got != "generic" {
+		t.Fatalf("bare channel id = %q", got)
+	}
+	if got := a.channelPersona(plain); got != "" {
+		t.Fatalf("unbound channel persona = %q", got)
+	}
+
+	tools := []Tool{{Name: "web_search"}, {Name: "file_read"}, {Name: "shell_execute"}, {Name: "weather_current"}}
+	got := a.filterToolsForChannel(filterToolsForProfile(tools, a.toolProfileFor(kf)), kf)
+	if len(got) != 2 || got[0].Name != "web_search" || got[1].Name != "weather_current" {
+		t.Fatalf("kf tools = %#v", got)
+	}
+	if got := a.filterToolsForChannel(tools, plain); len(got) != len(tools) {
+		t.Fatalf("unbound channel lost tools: %#v", got)
+	}
+	ctx := withTurn(context.Background(), kf)
+	if denied := a.checkToolProfile(ctx, "shell_execute"); !strings.HasPrefix(denied, "ACCESS DENIED") {
+		t.Fatalf("shell_execute in kf channel = %q", denied)
+	}
+	if denied := a.checkToolProfile(ctx, "web_fetch"); denied != "" {
+		t.Fatalf("web_fetch in kf channel = %q", denied)
+	}
+
+	if role := a.currentRequestModelRole(ctx); role != ai.RoleExpert {
+		t.Fatalf("kf role = %q", role)
+	}
+	if role := a.currentRequestModelRole(withTurn(context.Background(), plain)); role != ai.RolePrimary {
+		t.Fatalf("plain role = %q", role)
+	}
+}
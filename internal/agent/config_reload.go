@@ -0,0 +1,358 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+)
+
+// restartSections are the config sections read once at startup: platform
+// connections, servers and background loops started by the command.
+var restartSections = map[string]bool{
+	"transport": true,
+	"port":      true,
+	"logging":   true,
+	"platforms": true,
+	"mode":      true,
+	"relay":     true,
+	"browser":   true,
+	"keeper":    true,
+	"sync":      true,
+	"cron":      true,
+	"watchdog":  true,
+	"api":       true,
+}
+
+// configReloadDelay lets an editor finish writing before the file is read.
+const configReloadDelay = 300 * time.Millisecond
+
+// configReload is what a reload changed.
+type configReload struct {
+	Applied []string // changed sections now in effect
+	Restart []string // changed sections that take effect after a restart
+}
+
+func (r configReload) empty() bool {
+	return len(r.Applied) == 0 && len(r.Restart) == 0
+}
+
+// reloadConfig reads the config file and applies every section that can
+// change at runtime. Unless force is set it does nothing while the file's
+// modification time is unchanged.
+func (a *Agent) reloadConfig(force bool) (configReload, error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	info, err := os.Stat(a.configPath)
+	if err != nil {
+		return configReload{}, err
+	}
+	a.securityMu.RLock()
+	unchanged := !info.ModTime().After(a.configMtime)
+	firstLoad := a.configMtime.IsZero()
+	previous := a.loadedConfig
+	a.securityMu.RUnlock()
+	if unchanged && !force {
+		return configReload{}, nil
+	}
+
+	cfg, err := config.LoadFromPath(a.configPath)
+	if err != nil {
+		return configReload{}, err
+	}
+	if !firstLoad && !unchanged {
+		a.recordExternalConfigEdit()
+	}
+
+	changed := changedConfigSections(previous, cfg)
+	a.applyRuntimeConfig(cfg, changed)
+
+	var result configReload
+	for _, section := range changed {
+		if restartSections[section] {
+			result.Restart = append(result.Restart, section)
+		} else {
+			result.Applied = append(result.Applied, section)
+		}
+	}
+	if a.reloadInstructions() {
+		result.Applied = append(result.Applied, "custom_instructions")
+	}
+
+	a.securityMu.Lock()
+	a.configMtime = info.ModTime()
+	a.loadedConfig = cfg
+	a.securityMu.Unlock()
+
+	switch {
+	case firstLoad:
+		logger.Info("[Agent] Loaded runtime config from %s", a.configPath)
+	case result.empty():
+		logger.Info("[Agent] Reloaded runtime config from %s (no changes)", a.configPath)
+	default:
+		if len(result.Applied) > 0 {
+			logger.Info("[Agent] Reloaded runtime config from %s: %s", a.configPath, strings.Join(result.Applied, ", "))
+		}
+		if len(result.Restart) > 0 {
+			logger.Warn("[Agent] Config sections %s changed; restart coco to apply them", strings.Join(result.Restart, ", "))
+		}
+	}
+	return result, nil
+}
+
+// applyRuntimeConfig installs cfg. Sections whose subsystems are costly to
+// rebuild are only rebuilt when listed in changed.
+func (a *Agent) applyRuntimeConfig(cfg *config.Config, changed []string) {
+	a.applySecurityConfig(
+		cfg.Security.AllowedPaths,
+		cfg.Security.DisableFileTools,
+		cfg.Security.BlockedCommands,
+		cfg.Security.RequireConfirmation,
+		cfg.Security.AllowFrom,
+		cfg.Security.RequireMentionInGroup,
+	)
+	a.applyToolProfiles(cfg.Security.Profiles, cfg.Security.DefaultProfile)
+	a.applyChannelProfiles(cfg.Channels)
+	a.applyPromptSections(cfg.PromptSections)
+	a.applyIntents(cfg.Intents)
+	a.applyPlanApproval(cfg.Security.PlanApproval, cfg.Security.PlanApprovalTools)
+	a.applyToolTimeouts(cfg.Tools.Timeouts)
+	a.applyAskMissing(cfg.Tools.AskMissing)
+	a.applyArtifactThreshold(cfg.Tools.ArtifactThreshold)
+	a.applyFileSend(cfg.Tools.FileSend)
+	a.applyUnfurl(cfg.Tools.Unfurl)
+	a.applyVoice(cfg.Voice.TTS)
+	a.applyOCR(cfg.OCR)
+	a.applyFocus(cfg.Focus)
+	a.applyCalendar(cfg.Calendar)
+	a.applyQueue(cfg.Queue)
+	a.applyTracking(cfg.Tracking)
+	a.applyTravel(cfg.Travel)
+	a.applyBriefing(cfg.Briefing)
+	a.applyProactive(cfg.Proactive)
+	a.applyDND(cfg.DND)
+	a.applyTraces(cfg.Traces)
+	a.applyRetention(cfg.Retention)
+	a.applyBackup(cfg.Backup, cfg.Memory.ObsidianVault)
+	a.applyRituals(cfg.Rituals, cfg.Memory.ObsidianVault)
+	a.applySkillTools(cfg.Skills)
+	a.applyPlanner(cfg.Planner)
+	a.applyRouting(cfg.Routing)
+	a.applyModelRouterConfig(cfg.ModelCooldown)
+	a.applySearchConfig(cfg.Search)
+
+	if slices.Contains(changed, "memory") || slices.Contains(changed, "embedding") {
+		a.applyMemoryConfig(cfg.Memory, cfg.Embedding)
+	}
+}
+
+// applyMemoryConfig rebuilds the markdown and RAG memories and workspace
+// versioning. Stored memories stay in place; only how they are searched
+// changes.
+func (a *Agent) applyMemoryConfig(memCfg config.MemoryConfig, embCfg config.EmbeddingConfig) {
+	markdown := NewMarkdownMemory(memCfg)
+	if err := markdown.EnableSemanticSearch(embCfg); err != nil {
+		logger.Warn("[Agent] Markdown semantic search disabled: %v", err)
+	}
+	markdown.StartWatcher(10 * time.Second)
+
+	rag := &RAGMemory{enabled: false}
+	if embCfg.Enabled {
+		var err error
+		if rag, err = NewRAGMemory(embCfg, memCfg, a.persistStore); err != nil {
+			logger.Warn("[Agent] Failed to reload RAG memory: %v", err)
+			rag = &RAGMemory{enabled: false}
+		}
+	}
+
+	a.securityMu.Lock()
+	old := a.markdownMemory
+	a.markdownMemory = markdown
+	a.ragMemory = rag
+	a.workspaceGit = newWorkspaceVersioner(memCfg.GitVersioning)
+	a.securityMu.Unlock()
+	if old != nil {
+		old.StopWatcher()
+	}
+}
+
+func (a *Agent) markdownStore() *MarkdownMemory {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.markdownMemory
+}
+
+func (a *Agent) ragStore() *RAGMemory {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.ragMemory
+}
+
+func (a *Agent) versioner() *workspaceVersioner {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.workspaceGit
+}
+
+func (a *Agent) instructions() string {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.customInstructions
+}
+
+// reloadInstructions rereads the custom instructions file, reporting
+// whether its content changed.
+func (a *Agent) reloadInstructions() bool {
+	if a.instructionsFile == "" {
+		return false
+	}
+	data, err := os.ReadFile(a.instructionsFile)
+	if err != nil {
+		logger.Warn("[Agent] Failed to reload custom instructions: %v", err)
+		return false
+	}
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	if string(data) == a.customInstructions {
+		return false
+	}
+	a.customInstructions = string(data)
+	return true
+}
+
+// changedConfigSections lists the top-level sections, by YAML name, that
+// differ between old and cur. Nothing has changed when old is nil.
+func changedConfigSections(old, cur *config.Config) []string {
+	if old == nil || cur == nil {
+		return nil
+	}
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem()
+	var changed []string
+	for i := 0; i < ov.NumField(); i++ {
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(ov.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "" {
+			name = strings.ToLower(ov.Type().Field(i).Name)
+		}
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// WatchConfig reloads the config as soon as the config file, the custom
+// instructions file or the model registry (providers.yaml and models.yaml)
+// is written, until ctx ends. The directories are watched rather than the
+// files, since editors often replace a file instead of writing it in place.
+func (a *Agent) WatchConfig(ctx context.Context) error {
+	if strings.TrimSpace(a.configPath) == "" {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	watched := map[string]bool{}
+	for i, path := range []string{a.configPath, a.instructionsFile, ai.ProvidersPath(), ai.ModelsPath()} {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		watched[abs] = true
+		if i >= 2 {
+			// The registry directory may not exist yet; then there is
+			// nothing to reload.
+			if _, err := os.Stat(filepath.Dir(abs)); err != nil {
+				continue
+			}
+		}
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			watcher.Close()
+			return fmt.Errorf("watch %s: %w", filepath.Dir(abs), err)
+		}
+	}
+
+	a.setConfigWatched(true)
+	go func() {
+		defer watcher.Close()
+		defer a.setConfigWatched(false)
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if abs, err := filepath.Abs(ev.Name); err == nil && watched[abs] && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					pending = time.After(configReloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("[Agent] Config watcher: %v", err)
+			case <-pending:
+				pending = nil
+				if _, err := a.reloadConfig(true); err != nil {
+					logger.Warn("[Agent] Failed to reload runtime config: %v", err)
+				}
+			}
+		}
+	}()
+	logger.Info("[Agent] Watching %s for changes", a.configPath)
+	return nil
+}
+
+func (a *Agent) setConfigWatched(on bool) {
+	a.securityMu.Lock()
+	a.configWatched = on
+	a.securityMu.Unlock()
+}
+
+// handleConfigCommand answers "/config reload".
+func (a *Agent) handleConfigCommand(text string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 || (fields[0] != "/config" && fields[0] != "重新加载配置") {
+		return "", false
+	}
+	if fields[0] == "/config" && (len(fields) < 2 || fields[1] != "reload") {
+		return "用法: /config reload（重新读取 .coco.yaml 并应用可热更新的配置）", true
+	}
+	if strings.TrimSpace(a.configPath) == "" {
+		return "没有配置文件可以重新加载", true
+	}
+	result, err := a.reloadConfig(true)
+	if err != nil {
+		return fmt.Sprintf("重新加载配置失败: %v", err), true
+	}
+	if result.empty() {
+		return "配置已重新加载，没有变化", true
+	}
+	var sb strings.Builder
+	sb.WriteString("✅ 配置已重新加载")
+	if len(result.Applied) > 0 {
+		sb.WriteString("\n已生效: " + strings.Join(result.Applied, ", "))
+	}
+	if len(result.Restart) > 0 {
+		sb.WriteString("\n需重启 coco 后生效: " + strings.Join(result.Restart, ", "))
+	}
+	return sb.String(), true
+}
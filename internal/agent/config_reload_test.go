@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestChangedConfigSections(t *testing.T) {
+	old, cur := config.DefaultConfig(), config.DefaultConfig()
+	if got := changedConfigSections(old, cur); len(got) != 0 {
+		t.Fatalf("unchanged config reported %v", got)
+	}
+	cur.Focus.Minutes = 50
+	cur.Platforms.Telegram.Token = "t"
+	got := changedConfigSections(old, cur)
+	if strings.Join(got, ",") != "focus,platforms" {
+		t.Fatalf("changed = %v", got)
+	}
+	if changedConfigSections(nil, cur) != nil {
+		t.Fatal("first load reported changes")
+	}
+}
+
+func TestConfigReloadCommand(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, ".coco.yaml")
+	instructions := filepath.Join(dir, "instructions.md")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(cfgPath, "focus:\n  minutes: 25\n")
+	write(instructions, "Be brief.")
+
+	a := &Agent{configPath: cfgPath, instructionsFile: instructions, customInstructions: "Be brief."}
+	a.applySecurityConfig(nil, false, nil, nil, nil, false)
+	if reply, _ := a.handleConfigCommand("/config reload"); reply != "配置已重新加载，没有变化" {
+		t.Fatalf("first reload: %q", reply)
+	}
+
+	write(cfgPath, "focus:\n  minutes: 50\nplatforms:\n  telegram:\n    token: \"t\"\n")
+	write(instructions, "Answer in English.")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(cfgPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	reply, ok := a.handleConfigCommand("重新加载配置")
+	if !ok || !strings.Contains(reply, "已生效: focus, custom_instructions") || !strings.Contains(reply, "需重启 coco 后生效: platforms") {
+		t.Fatalf("reload reply: %q", reply)
+	}
+	if a.focusConfigSnapshot().Minutes != 50 || a.instructions() != "Answer in English." {
+		t.Fatalf("focus = %d, instructions = %q", a.focusConfigSnapshot().Minutes, a.instructions())
+	}
+
+	if reply, _ := a.handleConfigCommand("/config"); !strings.HasPrefix(reply, "用法") {
+		t.Fatalf("usage: %q", reply)
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+var contactKindNames = map[string]string{
+	persist.ContactPerson: "个人",
+	persist.ContactGroup:  "群",
+}
+
+func formatContact(c persist.Contact) string {
+	line := fmt.Sprintf("%s [%s] %s:%s", c.Name, contactKindNames[c.Kind], c.Platform, c.ChannelID)
+	if c.RecipientID != "" && c.RecipientID != c.ChannelID {
+		line += " → " + c.RecipientID
+	}
+	if c.Note != "" {
+		line += "（" + c.Note + "）"
+	}
+	if c.UserID == "default" {
+		line += " · 共享"
+	}
+	return line
+}
+
+// splitContactNames reads message_send_to's "to": a list of names, or one
+// string of names separated by commas or 、.
+func splitContactNames(v any) []string {
+	var raw []string
+	switch to := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(to, func(r rune) bool {
+			return r == ',' || r == '，' || r == '、' || r == ';' || r == '；'
+		})
+	case []any:
+		for _, item := range to {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range raw {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func (a *Agent) executeContactsAdd(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	msg := turnMessage(ctx)
+	name := strings.TrimSpace(getString(args, "name"))
+	if name == "" {
+		return "Error: name is required"
+	}
+	kind := strings.ToLower(strings.TrimSpace(getString(args, "kind")))
+	if kind == "" {
+		kind = persist.ContactPerson
+	}
+	if _, ok := contactKindNames[kind]; !ok {
+		return "Error: kind must be person or group"
+	}
+
+	c := persist.Contact{
+		UserID:      timeUserID(msg),
+		Name:        name,
+		Platform:    strings.TrimSpace(getString(args, "platform")),
+		ChannelID:   strings.TrimSpace(getString(args, "channel_id")),
+		RecipientID: strings.TrimSpace(getString(args, "user_id")),
+		Kind:        kind,
+		Note:        strings.TrimSpace(getString(args, "note")),
+	}
+	// "here" (or no address at all) names the current chat.
+	if here, _ := args["here"].(bool); here || (c.Platform == "" && c.ChannelID == "") {
+		if msg.Platform == "" || msg.ChannelID == "" {
+			return "Error: no current chat to save; give platform and channel_id"
+		}
+		c.Platform, c.ChannelID = msg.Platform, msg.ChannelID
+		if c.RecipientID == "" && kind == persist.ContactPerson {
+			c.RecipientID = msg.UserID
+		}
+	}
+	if c.Platform == "" || c.ChannelID == "" {
+		return "Error: platform and channel_id are both required"
+	}
+	if _, err := a.persistStore.SaveContact(c); err != nil {
+		return fmt.Sprintf("Error saving contact: %v", err)
+	}
+	return "已保存联系人 " + formatContact(c)
+}
+
+func (a *Agent) executeContactsList(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	contacts, err := a.persistStore.Contacts(timeUserID(turnMessage(ctx)))
+	if err != nil {
+		return fmt.Sprintf("Error loading contacts: %v", err)
+	}
+	query := strings.ToLower(strings.TrimSpace(getString(args, "query")))
+	var lines []string
+	for _, c := range contacts {
+		if query != "" && !strings.Contains(strings.ToLower(c.Name+" "+c.Note), query) {
+			continue
+		}
+		lines = append(lines, "- "+formatContact(c))
+	}
+	if len(lines) == 0 {
+		return "通讯录里还没有联系人；在对方的会话里说“把这里存为 XX”即可添加"
+	}
+	return "📇 通讯录:\n" + strings.Join(lines, "\n")
+}
+
+func (a *Agent) executeContactsRemove(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	name := strings.TrimSpace(getString(args, "name"))
+	if name == "" {
+		return "Error: name is required"
+	}
+	removed, err := a.persistStore.DeleteContact(timeUserID(turnMessage(ctx)), name)
+	if err != nil {
+		return fmt.Sprintf("Error removing contact: %v", err)
+	}
+	if !removed {
+		return fmt.Sprintf("通讯录里没有你添加的联系人“%s”", name)
+	}
+	return fmt.Sprintf("已删除联系人 %s", name)
+}
+
+func (a *Agent) executeMessageSendTo(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	if a.notifier == nil {
+		return "Error: no messaging platform is connected"
+	}
+	text := strings.TrimSpace(getString(args, "message"))
+	if text == "" {
+		return "Error: message is required"
+	}
+	names := splitContactNames(args["to"])
+	if len(names) == 0 {
+		return "Error: to is required"
+	}
+
+	userID := timeUserID(turnMessage(ctx))
+	// Resolve every name before sending, so a typo sends nothing.
+	var contacts []persist.Contact
+	var unknown []string
+	for _, name := range names {
+		c, err := a.persistStore.FindContact(userID, name)
+		if err != nil {
+			return fmt.Sprintf("Error loading contacts: %v", err)
+		}
+		if c == nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		contacts = append(contacts, *c)
+	}
+	if len(unknown) > 0 {
+		return fmt.Sprintf("通讯录里没有：%s。未发送任何消息；可先用 contacts_list 查看或 contacts_add 添加", strings.Join(unknown, "、"))
+	}
+
+	var lines []string
+	sent := 0
+	for _, c := range contacts {
+		recipient := c.RecipientID
+		if recipient == "" {
+			recipient = c.ChannelID
+		}
+		if err := a.notifier.NotifyChatUser(c.Platform, c.ChannelID, recipient, text); err != nil {
+			logger.Warn("[Agent] Failed to send to contact %s: %v", c.Name, err)
+			lines = append(lines, fmt.Sprintf("❌ %s: %v", c.Name, err))
+			continue
+		}
+		sent++
+		lines = append(lines, "✅ "+c.Name)
+	}
+	return fmt.Sprintf("已发送 %d/%d:\n%s", sent, len(contacts), strings.Join(lines, "\n"))
+}
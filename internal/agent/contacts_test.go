@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestContactsAddHereAndSendTo(t *testing.T) {
+	a := newTaskTestAgent(t)
+	n := &recordingNotifier{}
+	a.notifier = n
+
+	boss := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "dm-boss", UserID: "u1"})
+	if got := a.executeContactsAdd(boss, map[string]any{"name": "老板", "here": true}); !strings.Contains(got, "已保存联系人 老板 [个人] wecom:dm-boss → u1") {
+		t.Fatalf("contacts_add = %q", got)
+	}
+	ctx := withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "me", UserID: "u1"})
+	if got := a.executeContactsAdd(ctx, map[string]any{"name": "家庭群", "platform": "telegram", "channel_id": "-100", "kind": "group"}); !strings.HasPrefix(got, "已保存联系人") {
+		t.Fatalf("contacts_add group = %q", got)
+	}
+	// Contacts of the default user are shared; another user's are not.
+	a.persistStore.SaveContact(persist.Contact{UserID: "default", Name: "值班", Platform: "slack", ChannelID: "C1", Kind: persist.ContactGroup})
+	a.persistStore.SaveContact(persist.Contact{UserID: "u2", Name: "秘书", Platform: "slack", ChannelID: "D2"})
+
+	list := a.executeContactsList(ctx, map[string]any{})
+	if !strings.Contains(list, "家庭群") || !strings.Contains(list, "值班 [群] slack:C1 · 共享") || strings.Contains(list, "秘书") {
+		t.Fatalf("contacts_list = %q", list)
+	}
+
+	if got := a.executeMessageSendTo(ctx, map[string]any{"to": "老板、秘书", "message": "晚点到"}); !strings.Contains(got, "没有：秘书") {
+		t.Fatalf("unknown contact = %q", got)
+	}
+	if len(n.messages) != 0 {
+		t.Fatalf("sent %v with an unknown contact", n.messages)
+	}
+
+	got := a.executeMessageSendTo(ctx, map[string]any{"to": []any{"老板", "家庭群", "老板"}, "message": "晚点到"})
+	if !strings.HasPrefix(got, "已发送 2/2") {
+		t.Fatalf("message_send_to = %q", got)
+	}
+	want := []string{ConversationKey("wecom", "dm-boss", "u1"), ConversationKey("telegram", "-100", "-100")}
+	if strings.Join(n.targets, "|") != strings.Join(want, "|") {
+		t.Fatalf("targets = %v, want %v", n.targets, want)
+	}
+
+	if got := a.executeContactsRemove(ctx, map[string]any{"name": "值班"}); !strings.Contains(got, "没有你添加的联系人") {
+		t.Fatalf("removed a shared contact: %q", got)
+	}
+	if got := a.executeContactsRemove(ctx, map[string]any{"name": "老板"}); got != "已删除联系人 老板" {
+		t.Fatalf("contacts_remove = %q", got)
+	}
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/router"
+)
+
+// contextWarnRatio is the share of the context window above which
+// /context warns that older turns will be summarized away.
+const contextWarnRatio = 0.8
+
+// promptPart is one piece of a turn's system prompt, for /context.
+type promptPart struct {
+	Name   string
+	Tokens int
+}
+
+// measurePromptParts measures the named pieces of a system prompt, leaving
+// out empty ones.
+func measurePromptParts(pieces [][2]string) []promptPart {
+	var parts []promptPart
+	for _, p := range pieces {
+		if strings.TrimSpace(p[1]) != "" {
+			parts = append(parts, promptPart{Name: p[0], Tokens: estimateTokens(p[1])})
+		}
+	}
+	return parts
+}
+
+// workspaceFileStat is how a prompt file in the workspace looks right now.
+type workspaceFileStat struct {
+	name     string
+	required bool
+	tokens   int // 0 when missing or empty
+}
+
+// workspaceFileStats reads the workspace prompt files the way
+// loadWorkspacePromptBundle does, without warning about missing ones.
+func workspaceFileStats() []workspaceFileStat {
+	dir := getWorkspaceDir()
+	stats := make([]workspaceFileStat, 0, len(workspacePromptOrder))
+	for _, file := range workspacePromptOrder {
+		s := workspaceFileStat{name: file.name, required: file.required}
+		if data, err := os.ReadFile(filepath.Join(dir, file.name)); err == nil {
+			s.tokens = estimateTokens(stripYAMLFrontmatter(string(data)))
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// contextTargetModel is the model the next turn of msg goes to unless it
+// fails over, and how it was chosen. planned is set when the planner may
+// still pick another by task complexity.
+func (a *Agent) contextTargetModel(msg router.Message) (model *ai.ModelConfig, how string, planned bool) {
+	model, role := a.channelModel(msg)
+	if model != nil {
+		return model, "频道指定", false
+	}
+	if role == "" {
+		role = ai.RolePrimary
+	}
+	if a.modelRouter != nil {
+		model = a.modelRouter.PickModelForRole(role)
+	}
+	return model, role, role == ai.RolePrimary && isTwoStageOrchestrationEnabled()
+}
+
+// handleContextCommand answers "/context" with what goes into the model's
+// context for this conversation: the target model and its window, the
+// workspace files loaded, history and tools, and how the last turn's
+// system prompt was made up.
+func (a *Agent) handleContextCommand(msg router.Message, convKey, text string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/context", "上下文":
+	default:
+		return "", false
+	}
+	var b strings.Builder
+	var warnings []string
+	b.WriteString("🔍 上下文检查\n")
+
+	model, how, planned := a.contextTargetModel(msg)
+	window := 0
+	if model == nil {
+		b.WriteString("\n模型: 无可用模型\n")
+	} else {
+		window = model.EffectiveContextWindow()
+		fmt.Fprintf(&b, "\n模型: %s（%s）", model.Name, how)
+		if window > 0 {
+			fmt.Fprintf(&b, " · 上下文窗口 %d tokens", window)
+		} else {
+			b.WriteString(" · 上下文窗口未知")
+		}
+		b.WriteString("\n")
+		if planned {
+			b.WriteString("  规划器会按任务复杂度选择回答的模型，实际可能不同\n")
+		}
+	}
+
+	b.WriteString("\n工作区文件（" + getWorkspaceDir() + "）:\n")
+	var missing []string
+	for _, f := range workspaceFileStats() {
+		switch {
+		case f.tokens > 0:
+			fmt.Fprintf(&b, "  ✅ %s · 约 %d tokens\n", f.name, f.tokens)
+		case f.required:
+			fmt.Fprintf(&b, "  ⚠️ %s 缺失或为空\n", f.name)
+			warnings = append(warnings, f.name+" 缺失，模型看不到其中的指令；重启 coco 可从模板恢复")
+		default:
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		b.WriteString("  未加载: " + strings.Join(missing, ", ") + "\n")
+	}
+	for _, f := range [][2]string{{"SYSTEM.md", "系统指令"}, {"ABOUTME.md", "自我介绍"}} {
+		if content := loadPromptFile(f[0]); content != "" {
+			fmt.Fprintf(&b, "  ✅ %s · 约 %d tokens（替换内置的%s）\n", f[0], estimateTokens(content), f[1])
+		}
+	}
+
+	history := a.memory.GetHistory(convKey)
+	sent := history
+	if thresholdChars, keepRecent := contextCompactionSettings(); thresholdChars > 0 {
+		if compacted, ok := compactHistoryForPrompt(history, thresholdChars, keepRecent); ok {
+			sent = compacted
+		}
+	}
+	historyTokens := 0
+	for _, m := range sent {
+		historyTokens += estimateMessageTokens(m)
+	}
+	fmt.Fprintf(&b, "\n历史: %d 条", len(history))
+	if len(sent) != len(history) {
+		fmt.Fprintf(&b, "（压缩后发送 %d 条）", len(sent))
+	}
+	fmt.Fprintf(&b, " · 约 %d tokens\n", historyTokens)
+
+	tools := a.filterToolsForChannel(filterToolsForProfile(a.buildToolsList(), a.toolProfileFor(msg)), msg)
+	toolTokens := estimateRequestTokens(ChatRequest{Tools: tools})
+	fmt.Fprintf(&b, "工具: %d 个 · 约 %d tokens\n", len(tools), toolTokens)
+
+	stats, measured := a.promptStats.get(convKey)
+	if !measured {
+		b.WriteString("\n系统提示词: 本会话还没有对话轮次，发一条消息后再查看它的组成")
+	} else {
+		fmt.Fprintf(&b, "\n上一轮（%s", stats.At.Format("01-02 15:04"))
+		if stats.Model != "" {
+			b.WriteString("，" + stats.Model)
+		}
+		fmt.Fprintf(&b, "）系统提示词约 %d tokens:\n", stats.Total)
+		rest := stats.Total
+		for _, p := range stats.Parts {
+			fmt.Fprintf(&b, "  - %s · 约 %d\n", p.Name, p.Tokens)
+			rest -= p.Tokens
+		}
+		var off []string
+		for _, name := range promptSectionNames {
+			s := stats.Sections[name]
+			switch {
+			case !s.On:
+				off = append(off, promptSectionLabels[name])
+			case s.Measured && s.Tokens > 0:
+				fmt.Fprintf(&b, "  - %s · 约 %d\n", promptSectionLabels[name], s.Tokens)
+				rest -= s.Tokens
+			}
+		}
+		if rest > 0 {
+			fmt.Fprintf(&b, "  - 内置指令和工具说明 · 约 %d\n", rest)
+		}
+		if len(off) > 0 {
+			b.WriteString("  已关闭: " + strings.Join(off, "、") + "（/prompt debug 查看）\n")
+		}
+		if md := stats.Sections[promptSectionMarkdownMemories]; !(md.On && md.Tokens > 0) && !hasPromptPart(stats.Parts, "RAG 记忆") {
+			b.WriteString("  上一轮没有检索到相关记忆\n")
+		}
+
+		next := stats.Total + historyTokens + toolTokens
+		fmt.Fprintf(&b, "\n下一轮预计约 %d tokens（未含新消息）", next)
+		if window > 0 {
+			fmt.Fprintf(&b, "，占窗口 %d%%", next*100/window)
+			if float64(next) > float64(window)*contextWarnRatio {
+				warnings = append(warnings, "接近上下文上限，较早的对话会被摘要替代；可 /new 开始新对话，或 /prompt off 关闭用不到的部分")
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(warnings) > 0 {
+		b.WriteString("\n")
+		for _, w := range warnings {
+			b.WriteString("⚠️ " + w + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), true
+}
+
+func hasPromptPart(parts []promptPart, name string) bool {
+	for _, p := range parts {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestContextCommandReportsWhatTheModelSees(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", workspace)
+	t.Setenv("COCO_AGENT_ORCHESTRATION_ENABLE", "false")
+	if err := os.WriteFile(filepath.Join(workspace, "SOUL.md"), []byte("你是 coco，说话简洁。"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{memory: NewMemory(nil, 0), sessions: NewSessionStore()}
+	a.modelRouter = newHealthTestRouter(t)
+	a.modelRouter.GetCurrentModel().ContextWindow = 2000
+	msg := router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1"}
+	convKey := conversationKeyOf(msg)
+
+	reply, ok := a.handleContextCommand(msg, convKey, "/context")
+	if !ok {
+		t.Fatal("/context not handled")
+	}
+	for _, want := range []string{
+		"模型: main（primary） · 上下文窗口 2000 tokens",
+		"✅ SOUL.md · 约",
+		"⚠️ AGENTS.md 缺失或为空",
+		"未加载: IDENTITY.md, USER.md",
+		"历史: 0 条 · 约 0 tokens",
+		"还没有对话轮次",
+	} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("before a turn, /context lacks %q:\n%s", want, reply)
+		}
+	}
+
+	a.memory.AddExchange(convKey, Message{Role: "user", Content: strings.Repeat("长", 1500)}, Message{Role: "assistant", Content: "好"})
+	a.promptStats.record(convKey, promptStats{
+		Total: 1000,
+		Sections: map[string]promptSectionStat{
+			promptSectionModels: {On: true, Tokens: 100, Measured: true},
+			promptSectionSkills: {On: false, Tokens: 80, Measured: true},
+		},
+		Parts: []promptPart{{Name: "工作区文件", Tokens: 300}, {Name: "RAG 记忆", Tokens: 50}},
+		Model: "main",
+	})
+	reply, _ = a.handleContextCommand(msg, convKey, "/context")
+	for _, want := range []string{
+		"历史: 2 条 · 约 1509 tokens",
+		"main）系统提示词约 1000 tokens",
+		"  - 工作区文件 · 约 300",
+		"  - RAG 记忆 · 约 50",
+		"  - 模型列表 · 约 100",
+		"  - 内置指令和工具说明 · 约 550",
+		"已关闭: 技能列表",
+		"⚠️ 接近上下文上限",
+	} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("after a turn, /context lacks %q:\n%s", want, reply)
+		}
+	}
+	if strings.Contains(reply, "没有检索到相关记忆") {
+		t.Error("RAG memories were included")
+	}
+
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{"slack:dm": {Model: "backup"}})
+	if reply, _ := a.handleContextCommand(msg, convKey, "/context"); !strings.Contains(reply, "模型: backup（频道指定）") {
+		t.Errorf("channel model not shown:\n%s", reply)
+	}
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/logger"
+)
+
+const (
+	// perMessageTokenOverhead approximates role/formatting tokens added per message.
+	perMessageTokenOverhead = 4
+	// contextWindowSafetyRatio leaves headroom for estimator error.
+	contextWindowSafetyRatio = 0.9
+	// minFitSummaryChars is the smallest summary worth inserting for dropped turns.
+	minFitSummaryChars = 200
+)
+
+// estimateTokens gives a rough token count without a model-specific tokenizer:
+// CJK characters count as one token each, other text as ~4 bytes per token.
+func estimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	cjk := 0
+	other := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other += size
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+func estimateMessageTokens(m Message) int {
+	n := perMessageTokenOverhead + estimateTokens(m.Content) + estimateTokens(m.ReasoningContent)
+	for _, tc := range m.ToolCalls {
+		n += estimateTokens(tc.Name) + estimateTokens(string(tc.Input))
+	}
+	if m.ToolResult != nil {
+		n += estimateTokens(m.ToolResult.Content)
+	}
+	return n
+}
+
+func estimateRequestTokens(req ChatRequest) int {
+	n := estimateTokens(req.SystemPrompt)
+	for _, t := range req.Tools {
+		n += estimateTokens(t.Name) + estimateTokens(t.Description) + estimateTokens(string(t.InputSchema))
+	}
+	for _, m := range req.Messages {
+		n += estimateMessageTokens(m)
+	}
+	return n
+}
+
+// fitRequestToModel trims req.Messages so the request fits the model's context
+// window. Older turns are replaced by a short summary; the newest message is
+// always kept and tool results are never separated from their tool calls.
+func fitRequestToModel(req ChatRequest, model *ai.ModelConfig) ChatRequest {
+	if model == nil {
+		return req
+	}
+	return fitRequestToWindow(req, model.EffectiveContextWindow(), model.Name)
+}
+
+func fitRequestToWindow(req ChatRequest, window int, modelName string) ChatRequest {
+	if window <= 0 || len(req.Messages) < 2 {
+		return req
+	}
+
+	budget := int(float64(window)*contextWindowSafetyRatio) - req.MaxTokens
+	if req.MaxTokens > 0 && budget < window/4 {
+		// Tiny windows: shrink the completion budget instead of the whole history.
+		req.MaxTokens = window / 4
+		budget = int(float64(window)*contextWindowSafetyRatio) - req.MaxTokens
+	}
+	if estimateRequestTokens(req) <= budget {
+		return req
+	}
+
+	fixed := estimateTokens(req.SystemPrompt)
+	for _, t := range req.Tools {
+		fixed += estimateTokens(t.Name) + estimateTokens(t.Description) + estimateTokens(string(t.InputSchema))
+	}
+	available := budget - fixed
+
+	// Walk backwards keeping as many recent messages as fit.
+	start := len(req.Messages)
+	used := 0
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		cost := estimateMessageTokens(req.Messages[i])
+		if used+cost > available && start < len(req.Messages) {
+			break
+		}
+		used += cost
+		start = i
+	}
+	// Do not begin with a tool result whose tool call was dropped; pull the
+	// assistant tool call back in even if it overshoots the estimate.
+	for start > 0 && req.Messages[start].ToolResult != nil {
+		start--
+		used += estimateMessageTokens(req.Messages[start])
+	}
+	if start == 0 {
+		return req
+	}
+
+	kept := req.Messages[start:]
+	dropped := req.Messages[:start]
+	fitted := make([]Message, 0, len(kept)+1)
+
+	summaryChars := (available - used) * 2
+	if summaryChars > maxCompactSummaryChars {
+		summaryChars = maxCompactSummaryChars
+	}
+	if summaryChars >= minFitSummaryChars {
+		summary := summarizeHistoryMessages(dropped, summaryChars)
+		if cost := estimateMessageTokens(Message{Content: summary}); used+cost <= available {
+			fitted = append(fitted, Message{Role: "assistant", Content: summary})
+		}
+	}
+	fitted = append(fitted, kept...)
+
+	logger.Info("[Agent] Context window fit for %s (%d tokens): kept %d/%d messages",
+		modelName, window, len(kept), len(req.Messages))
+	req.Messages = fitted
+	return req
+}
+
+// isContextLengthError reports whether a provider error means the prompt was too long.
+func isContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"context_length_exceeded",
+		"context length",
+		"maximum context",
+		"context window",
+		"too many tokens",
+		"exceeded model token limit",
+		"prompt is too long",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryWithSmallerContext re-sends a request that overflowed the model context,
+// fitting it to half of the model window, or half of the request's estimated
+// size when the window is unknown.
+func retryWithSmallerContext(ctx context.Context, provider Provider, req ChatRequest, model *ai.ModelConfig) (ChatResponse, error) {
+	window := model.EffectiveContextWindow()
+	if window <= 0 {
+		window = estimateRequestTokens(req) + req.MaxTokens
+	}
+	window /= 2
+	if window < 2048 {
+		window = 2048
+	}
+	logger.Warn("[Agent] Model %s rejected prompt as too long, retrying within %d tokens", model.Name, window)
+	return provider.Chat(ctx, fitRequestToWindow(req, window, model.Name))
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/ai"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens(""); got != 0 {
+		t.Fatalf("empty string: %d", got)
+	}
+	if got := estimateTokens("abcdefgh"); got != 2 {
+		t.Fatalf("ascii estimate = %d, want 2", got)
+	}
+	if got := estimateTokens("你好世界"); got != 4 {
+		t.Fatalf("cjk estimate = %d, want 4", got)
+	}
+}
+
+func TestEffectiveContextWindowInfersFromCode(t *testing.T) {
+	cases := map[string]int{
+		"moonshot-v1-8k":   8 * 1024,
+		"moonshot-v1-128k": 128 * 1024,
+		"gpt-4-32k-0613":   32 * 1024,
+		"deepseek-chat":    0,
+		"qwen2.5-7b":       0,
+	}
+	for code, want := range cases {
+		m := &ai.ModelConfig{Code: code}
+		if got := m.EffectiveContextWindow(); got != want {
+			t.Errorf("%s: got %d, want %d", code, got, want)
+		}
+	}
+	m := &ai.ModelConfig{Code: "moonshot-v1-8k", ContextWindow: 4000}
+	if got := m.EffectiveContextWindow(); got != 4000 {
+		t.Fatalf("explicit context_window ignored: %d", got)
+	}
+}
+
+func TestFitRequestToModelTruncatesOldHistory(t *testing.T) {
+	var messages []Message
+	for i := 0; i < 60; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, Message{Role: role, Content: strings.Repeat("x", 800)})
+	}
+	messages = append(messages, Message{Role: "user", Content: "latest question"})
+
+	model := &ai.ModelConfig{Name: "kimi-8k", Code: "moonshot-v1-8k"}
+	req := fitRequestToModel(ChatRequest{
+		Messages:     messages,
+		SystemPrompt: strings.Repeat("s", 2000),
+		MaxTokens:    1024,
+	}, model)
+
+	if len(req.Messages) >= len(messages) {
+		t.Fatalf("expected truncation, still %d messages", len(req.Messages))
+	}
+	if last := req.Messages[len(req.Messages)-1]; last.Content != "latest question" {
+		t.Fatalf("newest message must be kept, got %q", last.Content)
+	}
+	if !strings.Contains(req.Messages[0].Content, "Conversation Summary") {
+		t.Fatalf("expected summary of dropped turns first, got %q", req.Messages[0].Content[:40])
+	}
+	if got := estimateRequestTokens(req); float64(got) > float64(8*1024)*contextWindowSafetyRatio-float64(req.MaxTokens) {
+		t.Fatalf("fitted request still too large: %d tokens", got)
+	}
+}
+
+func TestFitRequestToModelKeepsToolCallWithResult(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: strings.Repeat("old ", 4000)},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "file_read", Input: []byte(`{}`)}}},
+		{Role: "user", ToolResult: &ToolResult{ToolCallID: "1", Content: strings.Repeat("r", 12000)}},
+	}
+	req := fitRequestToWindow(ChatRequest{Messages: messages, MaxTokens: 512}, 4096, "tiny")
+	for i, m := range req.Messages {
+		if m.ToolResult != nil && (i == 0 || len(req.Messages[i-1].ToolCalls) == 0) {
+			t.Fatalf("tool result at %d lost its tool call: %#v", i, req.Messages)
+		}
+	}
+}
+
+func TestFitRequestToModelUnknownWindowIsNoop(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "a"}, {Role: "assistant", Content: "b"}}
+	req := fitRequestToModel(ChatRequest{Messages: messages}, &ai.ModelConfig{Code: "deepseek-chat"})
+	if len(req.Messages) != 2 {
+		t.Fatalf("unexpected change: %#v", req.Messages)
+	}
+}
+
+func TestIsContextLengthError(t *testing.T) {
+	if !isContextLengthError(errors.New("Invalid request: context_length_exceeded")) {
+		t.Fatal("expected context length error")
+	}
+	if isContextLengthError(errors.New("rate limit")) {
+		t.Fatal("rate limit is not a context error")
+	}
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/provenance"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	// cronContextMessages is how many earlier chat messages are kept with a
+	// prompt job, besides the request itself.
+	cronContextMessages = 6
+	cronContextMaxRunes = 1500
+)
+
+// setCronContext keeps the conversation that asked for a prompt job, so
+// each run knows why the job exists and what it is about.
+func (a *Agent) setCronContext(ctx context.Context, job *cronpkg.Job) {
+	text := a.cronRequestContext(turnMessage(ctx))
+	if text == "" {
+		return
+	}
+	if err := a.cronScheduler.SetContext(job.ID, text); err != nil {
+		logger.Warn("[Cron] Failed to record context for job %s: %v", job.ID, err)
+	}
+}
+
+// cronRequestContext renders the request and the messages before it.
+// Incognito chats keep only the request; scheduled prompts creating jobs
+// pass their own job's context on.
+func (a *Agent) cronRequestContext(msg router.Message) string {
+	if strings.EqualFold(strings.TrimSpace(msg.Username), "cron") {
+		return ""
+	}
+	var lines []string
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	if !a.isIncognito(convKey) {
+		var earlier []string
+		for _, m := range a.memory.GetHistory(convKey) {
+			content := strings.TrimSpace(m.Content)
+			if content == "" || (m.Role != "user" && m.Role != "assistant") {
+				continue
+			}
+			who := "用户"
+			if m.Role == "assistant" {
+				who = "助手"
+			}
+			earlier = append(earlier, who+": "+provenance.Excerpt(content, 300))
+		}
+		if len(earlier) > cronContextMessages {
+			earlier = earlier[len(earlier)-cronContextMessages:]
+		}
+		lines = earlier
+	}
+	if text := strings.TrimSpace(msg.Text); text != "" {
+		lines = append(lines, "用户: "+provenance.Excerpt(text, 600))
+	}
+	out := strings.Join(lines, "\n")
+	if r := []rune(out); len(r) > cronContextMaxRunes {
+		out = "…" + string(r[len(r)-cronContextMaxRunes:])
+	}
+	return out
+}
+
+// cronContextSection tells a scheduled prompt run why its job exists.
+func cronContextSection(ctx context.Context) string {
+	job := cronpkg.JobFromContext(ctx)
+	if job == nil || strings.TrimSpace(job.Context) == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\n## Scheduled Task Context\nThis message is the scheduled task %q (created %s), not a new message from the user. It was created during this conversation:\n%s\n\nAnswer with the current state of what the user asked about: use the memories above and tools (files, notes, web) to find out what changed, rather than giving a generic reminder.",
+		job.Name, job.CreatedAt.Format("2006-01-02"), job.Context)
+}
+
+// cronRecallQuery widens the memory search of a scheduled prompt with the
+// conversation that created its job, since the prompt alone ("提醒我项目进展")
+// rarely names what it is about.
+func cronRecallQuery(ctx context.Context, text string) string {
+	job := cronpkg.JobFromContext(ctx)
+	if job == nil || strings.TrimSpace(job.Context) == "" {
+		return text
+	}
+	return text + "\n" + job.Context
+}
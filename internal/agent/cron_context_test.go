@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+)
+
+func TestCronPromptJobKeepsItsConversation(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.memory = NewMemory(a.persistStore, 0)
+	store, err := cronpkg.NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("cron store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	a.cronScheduler = cronpkg.NewScheduler(store, nil, nil, nil)
+
+	key := ConversationKey("telegram", "c1", "u1")
+	a.memory.AddExchange(key, Message{Role: "user", Content: "我在把 coco 的调度器拆成独立服务"}, Message{Role: "assistant", Content: "好的，先从存储层开始？"})
+	ctx := testTurn()
+	turnOf(ctx).msg.Text = "每天早上提醒我项目进展"
+
+	got := a.runTool(ctx, "cron_create", json.RawMessage(`{"name":"progress","schedule":"0 9 * * *","prompt":"提醒用户项目进展"}`))
+	if !strings.Contains(got, "Scheduled AI task created") {
+		t.Fatalf("cron_create = %q", got)
+	}
+	jobs := a.cronScheduler.ListJobs()
+	if len(jobs) != 1 {
+		t.Fatalf("jobs = %d", len(jobs))
+	}
+	job := jobs[0]
+	for _, want := range []string{"调度器拆成独立服务", "助手: 好的", "用户: 每天早上提醒我项目进展"} {
+		if !strings.Contains(job.Context, want) {
+			t.Fatalf("context %q lacks %q", job.Context, want)
+		}
+	}
+
+	run := cronpkg.WithJob(ctx, job)
+	if section := cronContextSection(run); !strings.Contains(section, "调度器拆成独立服务") || !strings.Contains(section, `"progress"`) {
+		t.Fatalf("section = %q", section)
+	}
+	if q := cronRecallQuery(run, "提醒用户项目进展"); !strings.Contains(q, "调度器") {
+		t.Fatalf("recall query = %q", q)
+	}
+	if cronContextSection(ctx) != "" || cronRecallQuery(ctx, "hi") != "hi" {
+		t.Fatal("chat turns should get no scheduled task context")
+	}
+}
@@ -1,13 +1,26 @@
 package agent
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/kayz/coco/internal/logger"
 	"github.com/kayz/coco/internal/router"
 )
 
+// NotificationGate decides whether a notification waits instead of being
+// sent, e.g. during do-not-disturb hours. Agent implements it.
+type NotificationGate interface {
+	HoldNotification(platform, channelID, userID, message string) bool
+}
+
 // RouterCronNotifier implements cron.ChatNotifier by sending messages through the router
 type RouterCronNotifier struct {
 	router *router.Router
+
+	mu   sync.Mutex
+	held map[string][]string // "platform:channel" -> messages held during a focus session
+	gate NotificationGate
 }
 
 // NewRouterCronNotifier creates a new notifier that sends cron messages through the router
@@ -21,7 +34,62 @@ func (n *RouterCronNotifier) NotifyChat(message string) error {
 	return nil
 }
 
-// NotifyChatUser sends a cron notification to a specific user via the router
+// SetGate makes every notification ask g before it is sent
+func (n *RouterCronNotifier) SetGate(g NotificationGate) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.gate = g
+}
+
+// NotifyChatUser sends a cron notification to a specific user via the router,
+// or holds it while the chat is in a focus session or the gate keeps it
 func (n *RouterCronNotifier) NotifyChatUser(platform, channelID, userID, message string) error {
+	n.mu.Lock()
+	if held, ok := n.held[platform+":"+channelID]; ok {
+		n.held[platform+":"+channelID] = append(held, message)
+		n.mu.Unlock()
+		logger.Info("[CRON] Holding notification for %s:%s during focus session", platform, channelID)
+		return nil
+	}
+	gate := n.gate
+	n.mu.Unlock()
+	if gate != nil && gate.HoldNotification(platform, channelID, userID, message) {
+		logger.Info("[CRON] Holding notification for %s:%s during do-not-disturb", platform, channelID)
+		return nil
+	}
+	return n.SendNow(platform, channelID, userID, message)
+}
+
+// SendNow sends a message even while the chat's notifications are held
+func (n *RouterCronNotifier) SendNow(platform, channelID, userID, message string) error {
 	return n.router.SendToUser(platform, channelID, router.Response{Text: message})
 }
+
+// Hold queues notifications for a chat instead of sending them
+func (n *RouterCronNotifier) Hold(platform, channelID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.held == nil {
+		n.held = make(map[string][]string)
+	}
+	if _, ok := n.held[platform+":"+channelID]; !ok {
+		n.held[platform+":"+channelID] = []string{}
+	}
+}
+
+// Release stops holding a chat's notifications and returns the held ones
+func (n *RouterCronNotifier) Release(platform, channelID string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	held := n.held[platform+":"+channelID]
+	delete(n.held, platform+":"+channelID)
+	return held
+}
+
+// TargetAvailable reports whether the job target's platform is still registered
+func (n *RouterCronNotifier) TargetAvailable(platform, channelID, userID string) error {
+	if !n.router.HasPlatform(platform) {
+		return fmt.Errorf("platform %s not registered", platform)
+	}
+	return nil
+}
@@ -8,19 +8,22 @@ import (
 	"time"
 
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/logger"
 )
 
 // executeCronCreate creates a new scheduled task
-func (a *Agent) executeCronCreate(args map[string]any) string {
+func (a *Agent) executeCronCreate(ctx context.Context, args map[string]any) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
 
 	// Enforce: only ONE cron_create per user request
-	a.cronCreatedCount++
-	if a.cronCreatedCount > 1 {
+	t := turnOf(ctx)
+	t.cronCreated++
+	if t.cronCreated > 1 {
 		return "Error: You already created a cron job for this request. Only ONE cron job per user request is allowed. If you need varied/random content each time, use the 'prompt' parameter instead of creating multiple 'message' jobs."
 	}
+	msg := t.msg
 
 	name, _ := args["name"].(string)
 	schedule, _ := args["schedule"].(string)
@@ -38,6 +41,15 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 	if schedule == "" {
 		return "Error: schedule is required"
 	}
+	via := notifyViaArg(args["notify_via"])
+	if len(via) > 0 {
+		if a.remoteCron != nil {
+			return "Error: notify_via is not supported for keeper scheduled tasks"
+		}
+		if err := a.cronScheduler.CheckNotifyVia(via); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
 
 	// Auto-upgrade: if AI sent 'message' but no 'prompt' or 'tool',
 	// wrap the message in a generation instruction so AI creates fresh content each time
@@ -49,7 +61,7 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 	var job *cronpkg.Job
 	var err error
 	if a.remoteCron != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+		ctx, cancel := context.WithTimeout(ctx, 12*time.Second)
 		defer cancel()
 		job, err = a.createRemoteCronJob(ctx, name, tag, jobType, schedule, message, prompt, tool, endpoint, authHeader, args)
 		if err != nil {
@@ -63,18 +75,21 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 		if tag != "" {
 			job, err = a.cronScheduler.AddJobWithPromptAndTag(
 				name, tag, schedule, prompt,
-				a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+				msg.Platform, msg.ChannelID, msg.UserID,
 			)
 		} else {
 			job, err = a.cronScheduler.AddJobWithPrompt(
 				name, schedule, prompt,
-				a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+				msg.Platform, msg.ChannelID, msg.UserID,
 			)
 		}
 		if err != nil {
 			return fmt.Sprintf("Error creating scheduled task: %v", err)
 		}
-		return fmt.Sprintf("Scheduled AI task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Prompt: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Prompt)
+		a.setCronNotifyVia(job, via)
+		a.setCronContext(ctx, job)
+		a.attributeCronJob(ctx, job)
+		return fmt.Sprintf("Scheduled AI task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Prompt: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Prompt) + formatNotifyVia(job)
 	}
 
 	// External-agent job
@@ -94,12 +109,14 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 		}
 		job, err = a.cronScheduler.AddExternalJob(
 			name, tag, schedule, endpoint, authHeader, relayMode, arguments,
-			a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+			msg.Platform, msg.ChannelID, msg.UserID,
 		)
 		if err != nil {
 			return fmt.Sprintf("Error creating external scheduled task: %v", err)
 		}
-		return fmt.Sprintf("External scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Endpoint: %s\n- Relay mode: %t", job.ID, job.Name, job.Schedule, job.Tag, job.Endpoint, job.RelayMode)
+		a.setCronNotifyVia(job, via)
+		a.attributeCronJob(ctx, job)
+		return fmt.Sprintf("External scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Endpoint: %s\n- Relay mode: %t", job.ID, job.Name, job.Schedule, job.Tag, job.Endpoint, job.RelayMode) + formatNotifyVia(job)
 	}
 
 	// Message-based job
@@ -107,18 +124,20 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 		if tag != "" {
 			job, err = a.cronScheduler.AddJobWithMessageAndTag(
 				name, tag, schedule, message,
-				a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+				msg.Platform, msg.ChannelID, msg.UserID,
 			)
 		} else {
 			job, err = a.cronScheduler.AddJobWithMessage(
 				name, schedule, message,
-				a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+				msg.Platform, msg.ChannelID, msg.UserID,
 			)
 		}
 		if err != nil {
 			return fmt.Sprintf("Error creating scheduled task: %v", err)
 		}
-		return fmt.Sprintf("Scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Message: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Message)
+		a.setCronNotifyVia(job, via)
+		a.attributeCronJob(ctx, job)
+		return fmt.Sprintf("Scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Message: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Message) + formatNotifyVia(job)
 	}
 
 	// Tool-based job
@@ -143,13 +162,183 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 		if err != nil {
 			return fmt.Sprintf("Error creating scheduled task: %v", err)
 		}
-		return fmt.Sprintf("Scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Tool: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Tool)
+		a.setCronNotifyVia(job, via)
+		a.attributeCronJob(ctx, job)
+		return fmt.Sprintf("Scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tag: %s\n- Tool: %s", job.ID, job.Name, job.Schedule, job.Tag, job.Tool) + formatNotifyVia(job)
 	}
 
 	return "Error: either 'prompt', 'message', or 'tool' is required"
 }
 
+// notifyViaArg accepts notify_via as a list or a comma-separated string.
+func notifyViaArg(raw any) []string {
+	var entries []string
+	switch v := raw.(type) {
+	case string:
+		entries = strings.Split(v, ",")
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				entries = append(entries, s)
+			}
+		}
+	case []string:
+		entries = v
+	}
+	var via []string
+	for _, e := range entries {
+		if e = strings.TrimSpace(e); e != "" {
+			via = append(via, e)
+		}
+	}
+	return via
+}
+
+// setCronNotifyVia routes a new job's output; it runs before
+// attributeCronJob so the signature covers the channels.
+func (a *Agent) setCronNotifyVia(job *cronpkg.Job, via []string) {
+	if len(via) == 0 {
+		return
+	}
+	if err := a.cronScheduler.SetNotifyVia(job.ID, via); err != nil {
+		logger.Warn("[Cron] Failed to set notify_via for job %s: %v", job.ID, err)
+	}
+}
+
+// formatNotifyVia is empty for jobs that report to their chat.
+func formatNotifyVia(job *cronpkg.Job) string {
+	if len(job.NotifyVia) == 0 {
+		return ""
+	}
+	return "\n- Notify via: " + strings.Join(job.NotifyVia, ", ")
+}
+
+// attributeCronJob records the chat message (or scheduled prompt) that made the model create job.
+func (a *Agent) attributeCronJob(ctx context.Context, job *cronpkg.Job) {
+	if err := a.cronScheduler.SetProvenance(job.ID, messageProvenance(turnMessage(ctx))); err != nil {
+		logger.Warn("[Cron] Failed to record provenance for job %s: %v", job.ID, err)
+	}
+}
+
+// executeRemindOnce creates a one-shot reminder that fires once and is then removed
+func (a *Agent) executeRemindOnce(ctx context.Context, args map[string]any) string {
+	if a.cronScheduler == nil && a.remoteCron == nil {
+		return "Error: cron scheduler not available"
+	}
+
+	message := strings.TrimSpace(getString(args, "message"))
+	prompt := strings.TrimSpace(getString(args, "prompt"))
+	if message == "" && prompt == "" {
+		return "Error: message or prompt is required"
+	}
+
+	msg := turnMessage(ctx)
+	runAt, err := parseRemindAt(args, time.Now())
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	name := strings.TrimSpace(getString(args, "name"))
+	if name == "" {
+		name = message
+		if name == "" {
+			name = prompt
+		}
+		if r := []rune(name); len(r) > 30 {
+			name = string(r[:30])
+		}
+	}
+	if message != "" && prompt == "" {
+		message = "⏰ 提醒：" + message
+	}
+
+	if a.remoteCron != nil {
+		ctx, cancel := context.WithTimeout(ctx, 12*time.Second)
+		defer cancel()
+		job, err := a.remoteCron.Create(ctx, remoteCronCreateRequest{
+			Name:      name,
+			Tag:       "user-schedule",
+			RunAt:     runAt.Format(time.RFC3339),
+			Message:   message,
+			Prompt:    prompt,
+			Platform:  msg.Platform,
+			ChannelID: msg.ChannelID,
+			UserID:    msg.UserID,
+		}.withProvenance(messageProvenance(msg)))
+		if err != nil {
+			return fmt.Sprintf("Error creating keeper reminder: %v", err)
+		}
+		return fmt.Sprintf("Keeper one-shot reminder created:\n- ID: %s\n- Name: %s\n- Fires at: %s", job.ID, job.Name, runAt.Format("2006-01-02 15:04:05"))
+	}
+
+	job, err := a.cronScheduler.AddOnceJob(
+		name, "user-schedule", runAt, message, prompt,
+		msg.Platform, msg.ChannelID, msg.UserID,
+	)
+	if err != nil {
+		return fmt.Sprintf("Error creating reminder: %v", err)
+	}
+	if prompt != "" {
+		a.setCronContext(ctx, job)
+	}
+	a.attributeCronJob(ctx, job)
+	return fmt.Sprintf("One-shot reminder created:\n- ID: %s\n- Name: %s\n- Fires at: %s", job.ID, job.Name, runAt.Format("2006-01-02 15:04:05"))
+}
+
+// remindAtLayouts are the absolute time formats accepted by remind_once, interpreted in local time.
+var remindAtLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006/01/02 15:04",
+}
+
+// parseRemindAt resolves the fire time from either 'in_minutes' (relative) or 'at' (absolute).
+func parseRemindAt(args map[string]any, now time.Time) (time.Time, error) {
+	if v, ok := args["in_minutes"]; ok && v != nil {
+		var minutes float64
+		switch n := v.(type) {
+		case float64:
+			minutes = n
+		case int:
+			minutes = float64(n)
+		case string:
+			if _, err := fmt.Sscanf(strings.TrimSpace(n), "%g", &minutes); err != nil {
+				return time.Time{}, fmt.Errorf("invalid in_minutes: %q", n)
+			}
+		default:
+			return time.Time{}, fmt.Errorf("invalid in_minutes: %v", v)
+		}
+		if minutes <= 0 {
+			return time.Time{}, fmt.Errorf("in_minutes must be positive")
+		}
+		return now.Add(time.Duration(minutes * float64(time.Minute))), nil
+	}
+
+	at := strings.TrimSpace(getString(args, "at"))
+	if at == "" {
+		return time.Time{}, fmt.Errorf("either 'at' or 'in_minutes' is required")
+	}
+	if t, err := time.Parse(time.RFC3339, at); err == nil {
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("time %s is in the past", at)
+		}
+		return t, nil
+	}
+	for _, layout := range remindAtLayouts {
+		if t, err := time.ParseInLocation(layout, at, now.Location()); err == nil {
+			if !t.After(now) {
+				return time.Time{}, fmt.Errorf("time %s is in the past", at)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format %q (use 'YYYY-MM-DD HH:MM')", at)
+}
+
 func (a *Agent) createRemoteCronJob(ctx context.Context, name, tag, jobType, schedule, message, prompt, tool, endpoint, authHeader string, args map[string]any) (*cronpkg.Job, error) {
+	msg := turnMessage(ctx)
 	req := remoteCronCreateRequest{
 		Name:      name,
 		Tag:       tag,
@@ -160,10 +349,10 @@ func (a *Agent) createRemoteCronJob(ctx context.Context, name, tag, jobType, sch
 		Tool:      tool,
 		Endpoint:  endpoint,
 		Auth:      authHeader,
-		Platform:  a.currentMsg.Platform,
-		ChannelID: a.currentMsg.ChannelID,
-		UserID:    a.currentMsg.UserID,
-	}
+		Platform:  msg.Platform,
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+	}.withProvenance(messageProvenance(msg))
 	if v, ok := args["relay_mode"].(bool); ok {
 		req.RelayMode = v
 	}
@@ -192,7 +381,7 @@ func (a *Agent) formatCreatedCronJob(job *cronpkg.Job) string {
 }
 
 // executeCronList lists all scheduled tasks, optionally filtered by tag
-func (a *Agent) executeCronList(args map[string]any) string {
+func (a *Agent) executeCronList(ctx context.Context, args map[string]any) string {
 	if a.cronScheduler == nil {
 		if a.remoteCron == nil {
 			return "Error: cron scheduler not available"
@@ -206,9 +395,9 @@ func (a *Agent) executeCronList(args map[string]any) string {
 	)
 
 	if a.remoteCron != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+		ctx, cancel := context.WithTimeout(ctx, 12*time.Second)
 		defer cancel()
-		jobs, err = a.remoteCron.List(ctx, a.currentMsg, tag)
+		jobs, err = a.remoteCron.List(ctx, turnMessage(ctx), tag)
 		if err != nil {
 			return fmt.Sprintf("Error listing keeper scheduled tasks: %v", err)
 		}
@@ -240,7 +429,11 @@ func (a *Agent) executeCronList(args map[string]any) string {
 			status = "paused"
 		}
 
-		sb.WriteString(fmt.Sprintf("- ID: %s\n  Name: %s\n  Schedule: %s\n  Tag: %s\n  Status: %s\n", job.ID, job.Name, job.Schedule, job.Tag, status))
+		schedule := job.Schedule
+		if job.RunAt != nil {
+			schedule = "once at " + job.RunAt.Local().Format("2006-01-02 15:04:05")
+		}
+		sb.WriteString(fmt.Sprintf("- ID: %s\n  Name: %s\n  Schedule: %s\n  Tag: %s\n  Status: %s\n", job.ID, job.Name, schedule, job.Tag, status))
 		if job.Prompt != "" {
 			sb.WriteString(fmt.Sprintf("  Prompt: %s\n", job.Prompt))
 		}
@@ -256,6 +449,16 @@ func (a *Agent) executeCronList(args map[string]any) string {
 		if job.LastError != "" {
 			sb.WriteString(fmt.Sprintf("  Last error: %s\n", job.LastError))
 		}
+		if job.StaleSince != nil {
+			sb.WriteString(fmt.Sprintf("  Stale since %s (%d consecutive failures); will be deleted unless it recovers\n",
+				job.StaleSince.Format("2006-01-02 15:04"), job.FailCount))
+		}
+		if len(job.NotifyVia) > 0 {
+			sb.WriteString(fmt.Sprintf("  Notify via: %s\n", strings.Join(job.NotifyVia, ", ")))
+		}
+		if job.Provenance != nil {
+			sb.WriteString(fmt.Sprintf("  Created by: %s\n", job.Provenance.Describe()))
+		}
 		sb.WriteString("\n")
 	}
 
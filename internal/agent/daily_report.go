@@ -0,0 +1,348 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+const (
+	dailyReportJobName = "每日日报生成"
+	dailyReportTimeout = 3 * time.Minute
+	// dailyReportMessages caps the messages read for one day; the input to
+	// the summary is cut to dailyReportInputRunes after that.
+	dailyReportMessages   = 400
+	dailyReportInputRunes = 24000
+	dailyReportLineRunes  = 300
+)
+
+const dailyReportPrompt = `You write the user's daily report from the activity log of one day.
+Output STRICT JSON only with keys:
+- summary (string): two or three sentences on what the day was about
+- content (string): the report in Markdown, in Chinese: what was discussed and done, which scheduled jobs ran or failed, what changed in projects and tasks, and what is coming up
+- tasks (array of {id, title, description, status, priority, due_date}): open and finished tasks; status is pending, in_progress or completed; priority is low, medium or high
+- calendars (array of {id, title, description, start_time, end_time, location}): upcoming events from the calendar section
+Only report what the log shows. Tasks from the task list keep their id (task-N) and report their current status; give new tasks an empty id. Carry over unfinished tasks from the previous report unless the log shows them done.`
+
+// dailyReportInput is the activity of one day the report is written from.
+type dailyReportInput struct {
+	Date          string
+	Messages      []persist.DayMessage
+	Jobs          []string // scheduled jobs that ran that day
+	Projects      []string // project progress logged that day
+	Calendar      string
+	Reminders     string
+	Tasks         []persist.Task // open tasks and those finished since the day began
+	PreviousTasks []persist.TaskItem
+}
+
+// collectDailyReport gathers date's conversations, finished cron jobs and
+// project progress, the calendar and reminders coming up, the task list,
+// and the tasks of the report before it that are not in the task list.
+func (a *Agent) collectDailyReport(ctx context.Context, date string) (dailyReportInput, error) {
+	in := dailyReportInput{Date: date}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return in, fmt.Errorf("date %q is not YYYY-MM-DD", date)
+	}
+	next := day.AddDate(0, 0, 1)
+
+	if a.persistStore != nil {
+		if in.Messages, err = a.persistStore.MessagesBetween(day, next, dailyReportMessages); err != nil {
+			return in, fmt.Errorf("read messages: %w", err)
+		}
+		if in.Tasks, err = a.persistStore.Tasks("", day); err != nil {
+			return in, fmt.Errorf("read tasks: %w", err)
+		}
+		prev, err := a.persistStore.GetDailyReport(day.AddDate(0, 0, -1).Format("2006-01-02"), "default")
+		if err == nil && prev != nil {
+			in.PreviousTasks = untrackedTasks(prev.Tasks, in.Tasks)
+		}
+	}
+
+	if a.cronScheduler != nil {
+		for _, job := range a.cronScheduler.ListJobs() {
+			if job.Name == dailyReportJobName || job.LastRun == nil || job.LastRun.Before(day) || !job.LastRun.Before(next) {
+				continue
+			}
+			line := fmt.Sprintf("%s %s", job.LastRun.Format("15:04"), job.Name)
+			switch {
+			case job.LastError != "":
+				line += " 失败: " + job.LastError
+			case job.LastResult != "":
+				line += " 结果: " + job.LastResult
+			default:
+				line += " 完成"
+			}
+			in.Jobs = append(in.Jobs, clipRunes(line, dailyReportLineRunes))
+		}
+	}
+
+	for _, p := range a.listProjects() {
+		for _, line := range p.Progress {
+			if entry, ok := strings.CutPrefix(line, date+": "); ok {
+				in.Projects = append(in.Projects, fmt.Sprintf("%s (%s): %s", p.Project, p.Status, entry))
+			}
+		}
+	}
+
+	in.Calendar = toolOutput(briefingCalendar(ctx))
+	in.Reminders = toolOutput(briefingTasks(ctx))
+	return in, nil
+}
+
+// untrackedTasks drops the report items that are already in tasks.
+func untrackedTasks(items []persist.TaskItem, tasks []persist.Task) []persist.TaskItem {
+	tracked := make(map[string]bool, 2*len(tasks))
+	for _, t := range tasks {
+		tracked[taskItem(t).ID] = true
+		tracked[strings.ToLower(t.Title)] = true
+	}
+	var out []persist.TaskItem
+	for _, item := range items {
+		if !tracked[item.ID] && !tracked[strings.ToLower(strings.TrimSpace(item.Title))] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// toolOutput drops the output of a tool that failed.
+func toolOutput(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "Error") {
+		return ""
+	}
+	return s
+}
+
+func clipRunes(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+func (in dailyReportInput) empty() bool {
+	return len(in.Messages) == 0 && len(in.Jobs) == 0 && len(in.Projects) == 0 &&
+		in.Calendar == "" && in.Reminders == "" && len(in.Tasks) == 0 && len(in.PreviousTasks) == 0
+}
+
+// render lays the activity out for the summary prompt.
+func (in dailyReportInput) render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s 的活动记录\n", in.Date)
+	if len(in.Messages) > 0 {
+		b.WriteString("\n## 对话\n")
+		conv := ""
+		for _, m := range in.Messages {
+			if m.Conversation != conv {
+				conv = m.Conversation
+				fmt.Fprintf(&b, "### %s\n", conv)
+			}
+			fmt.Fprintf(&b, "- %s %s: %s\n", m.CreatedAt.Format("15:04"), m.Role, clipRunes(m.Content, dailyReportLineRunes))
+		}
+	}
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{{"定时任务", in.Jobs}, {"项目进展", in.Projects}} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		b.WriteString("\n## " + section.title + "\n")
+		for _, line := range section.lines {
+			b.WriteString("- " + line + "\n")
+		}
+	}
+	if len(in.Tasks) > 0 {
+		b.WriteString("\n## 任务清单\n")
+		for _, t := range in.Tasks {
+			fmt.Fprintf(&b, "- %s [%s/%s] %s", taskItem(t).ID, t.Status, t.Priority, t.Title)
+			if t.DueDate != "" {
+				fmt.Fprintf(&b, " (截止 %s)", t.DueDate)
+			}
+			if t.Project != "" {
+				fmt.Fprintf(&b, " (项目 %s)", t.Project)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(in.PreviousTasks) > 0 {
+		b.WriteString("\n## 上一份日报的任务\n")
+		for _, t := range in.PreviousTasks {
+			fmt.Fprintf(&b, "- [%s] %s", t.Status, t.Title)
+			if t.DueDate != "" {
+				fmt.Fprintf(&b, " (截止 %s)", t.DueDate)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if in.Reminders != "" {
+		b.WriteString("\n## 提醒事项\n" + in.Reminders + "\n")
+	}
+	if in.Calendar != "" {
+		b.WriteString("\n## 接下来的日程\n" + in.Calendar + "\n")
+	}
+
+	out := b.String()
+	if r := []rune(out); len(r) > dailyReportInputRunes {
+		out = string(r[:dailyReportInputRunes]) + "\n...[truncated]"
+	}
+	return out
+}
+
+// buildDailyReport writes date's report from its activity, with the model
+// when one is available and as a plain digest otherwise.
+func (a *Agent) buildDailyReport(ctx context.Context, date string) (*persist.DailyReport, error) {
+	in, err := a.collectDailyReport(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	report := &persist.DailyReport{
+		Date:      date,
+		UserID:    "default",
+		Calendars: []persist.CalendarItem{},
+	}
+	for _, t := range in.Tasks {
+		report.Tasks = append(report.Tasks, taskItem(t))
+	}
+	report.Tasks = append(report.Tasks, in.PreviousTasks...)
+	if in.empty() {
+		report.Summary = "当天没有活动记录"
+		return report, nil
+	}
+	if report.Tasks == nil {
+		report.Tasks = []persist.TaskItem{}
+	}
+
+	log := in.render()
+	if a.modelRouter != nil {
+		resp, err := a.chatWithModel(ctx, ChatRequest{
+			Messages:     []Message{{Role: "user", Content: log}},
+			SystemPrompt: dailyReportPrompt,
+			MaxTokens:    2000,
+		})
+		if err == nil {
+			err = parseDailyReport(resp.Content, report)
+		}
+		if err == nil {
+			return report, nil
+		}
+		logger.Warn("[Agent] Failed to summarize the daily report for %s: %v", date, err)
+	}
+
+	report.Summary = fmt.Sprintf("%d 条对话消息，%d 个定时任务运行，%d 条项目进展", len(in.Messages), len(in.Jobs), len(in.Projects))
+	report.Content = log
+	return report, nil
+}
+
+// parseDailyReport fills report from the model's JSON answer.
+func parseDailyReport(content string, report *persist.DailyReport) error {
+	payload := extractJSONObject(strings.TrimSpace(content))
+	if payload == "" {
+		return fmt.Errorf("model returned non-json content")
+	}
+	var parsed struct {
+		Summary string `json:"summary"`
+		Content string `json:"content"`
+		Tasks   []struct {
+			ID          string `json:"id"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Status      string `json:"status"`
+			Priority    string `json:"priority"`
+			DueDate     string `json:"due_date"`
+		} `json:"tasks"`
+		Calendars []struct {
+			ID          string `json:"id"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			StartTime   string `json:"start_time"`
+			EndTime     string `json:"end_time"`
+			Location    string `json:"location"`
+		} `json:"calendars"`
+	}
+	if err := json.Unmarshal([]byte(payload), &parsed); err != nil {
+		return fmt.Errorf("invalid report json: %w", err)
+	}
+	if strings.TrimSpace(parsed.Summary) == "" {
+		return fmt.Errorf("report has no summary")
+	}
+	report.Summary = strings.TrimSpace(parsed.Summary)
+	report.Content = strings.TrimSpace(parsed.Content)
+	report.Tasks = []persist.TaskItem{}
+	for _, t := range parsed.Tasks {
+		if strings.TrimSpace(t.Title) == "" {
+			continue
+		}
+		report.Tasks = append(report.Tasks, persist.TaskItem{
+			ID: t.ID, Title: t.Title, Description: t.Description,
+			Status: t.Status, Priority: t.Priority, DueDate: t.DueDate,
+		})
+	}
+	report.Calendars = []persist.CalendarItem{}
+	for _, c := range parsed.Calendars {
+		if strings.TrimSpace(c.Title) == "" {
+			continue
+		}
+		report.Calendars = append(report.Calendars, persist.CalendarItem{
+			ID: c.ID, Title: c.Title, Description: c.Description,
+			StartTime: c.StartTime, EndTime: c.EndTime, Location: c.Location,
+		})
+	}
+	return nil
+}
+
+// generateDailyReport writes and stores date's report, carrying its tasks
+// into the task list, and makes it the latest one when it is.
+func (a *Agent) generateDailyReport(ctx context.Context, date string) (*persist.DailyReport, error) {
+	if a.persistStore == nil {
+		return nil, fmt.Errorf("persist store not available")
+	}
+	ctx, cancel := context.WithTimeout(ctx, dailyReportTimeout)
+	defer cancel()
+	report, err := a.buildDailyReport(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	a.syncTasksFromReport(report)
+	if err := a.persistStore.SaveDailyReport(report); err != nil {
+		return nil, fmt.Errorf("save daily report: %w", err)
+	}
+	if latest, err := a.persistStore.GetLatestDailyReport("default"); err == nil {
+		a.setLatestReport(latest)
+	}
+	return report, nil
+}
+
+func (a *Agent) currentReport() *persist.DailyReport {
+	a.reportMu.RLock()
+	defer a.reportMu.RUnlock()
+	return a.latestReport
+}
+
+func (a *Agent) setLatestReport(report *persist.DailyReport) {
+	a.reportMu.Lock()
+	a.latestReport = report
+	a.reportMu.Unlock()
+}
+
+// executeReportRegenerate rebuilds a day's report from its activity.
+func (a *Agent) executeReportRegenerate(ctx context.Context, args map[string]any) string {
+	date := strings.TrimSpace(getString(args, "date"))
+	if date == "" {
+		date = persist.GetYesterdayDate()
+	}
+	report, err := a.generateDailyReport(ctx, date)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return fmt.Sprintf("Daily report for %s regenerated: %s (%d tasks, %d calendar events)", report.Date, report.Summary, len(report.Tasks), len(report.Calendars))
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/persist"
+)
+
+func TestDailyReportBuiltFromStoredActivity(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+	store, err := persist.NewStore(filepath.Join(tmp, "coco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	cronStore, err := cronpkg.NewStore(filepath.Join(tmp, "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cronStore.Close() })
+
+	origCal, origTasks := briefingCalendar, briefingTasks
+	briefingCalendar = func(ctx context.Context) string { return "10:00 周会" }
+	briefingTasks = func(ctx context.Context) string { return "Error: reminders unavailable" }
+	defer func() { briefingCalendar, briefingTasks = origCal, origTasks }()
+
+	conv, err := store.GetOrCreateConversation("slack", "dm", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []persist.Message{
+		{Role: "user", Content: "帮我订周五去上海的高铁"},
+		{Role: "tool", Content: "raw tool output"},
+		{Role: "assistant", Content: "已订好 G7 次，周五 8:00 出发"},
+	} {
+		if err := store.AddMessage(conv.ID, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without a model the report is a digest of the activity.
+	a := &Agent{persistStore: store, cronScheduler: cronpkg.NewScheduler(cronStore, nil, nil, nil)}
+	got := a.executeReportRegenerate(context.Background(), map[string]any{"date": persist.GetTodayDate()})
+	if !strings.Contains(got, "regenerated") {
+		t.Fatalf("regenerate = %q", got)
+	}
+	report := a.currentReport()
+	if report == nil || report.Date != persist.GetTodayDate() {
+		t.Fatalf("latest report = %+v", report)
+	}
+	if !strings.Contains(report.Summary, "2 条对话消息") {
+		t.Fatalf("summary = %q", report.Summary)
+	}
+	for _, want := range []string{"### slack:dm:u1", "user: 帮我订周五去上海的高铁", "assistant: 已订好 G7 次", "## 接下来的日程\n10:00 周会"} {
+		if !strings.Contains(report.Content, want) {
+			t.Errorf("content lacks %q:\n%s", want, report.Content)
+		}
+	}
+	if strings.Contains(report.Content, "raw tool output") || strings.Contains(report.Content, "提醒事项") {
+		t.Errorf("content has tool output or a failed section:\n%s", report.Content)
+	}
+
+	if got := a.executeReportRegenerate(context.Background(), map[string]any{"date": "yesterday"}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("bad date = %q", got)
+	}
+}
+
+func TestDailyReportJobReplacesFreehandPrompt(t *testing.T) {
+	cronStore, err := cronpkg.NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cronStore.Close() })
+	a := &Agent{cronScheduler: cronpkg.NewScheduler(cronStore, nil, nil, nil)}
+	if _, err := a.cronScheduler.AddJobWithPrompt(dailyReportJobName, "0 3 * * *", "请生成今日日报", "local", "daily-report", "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	a.setupDailyReportJob()
+	a.setupDailyReportJob()
+	var jobs []*cronpkg.Job
+	for _, j := range a.cronScheduler.ListJobs() {
+		if j.Name == dailyReportJobName {
+			jobs = append(jobs, j)
+		}
+	}
+	if len(jobs) != 1 || jobs[0].Tool != "report_regenerate" || jobs[0].Prompt != "" {
+		t.Fatalf("daily report jobs = %+v", jobs)
+	}
+}
+
+func TestParseDailyReport(t *testing.T) {
+	report := &persist.DailyReport{}
+	err := parseDailyReport("```json\n"+`{"summary":"出差准备","content":"- 订了高铁","tasks":[{"title":"报销","status":"pending","priority":"high"},{"title":""}],"calendars":[{"title":"周会","start_time":"10:00"}]}`+"\n```", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary != "出差准备" || len(report.Tasks) != 1 || report.Tasks[0].Priority != "high" || len(report.Calendars) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if err := parseDailyReport(`{"content":"no summary"}`, report); err == nil {
+		t.Fatal("a report without a summary should be rejected")
+	}
+}
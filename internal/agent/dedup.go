@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+// messageDedupTTL is how long a platform message ID is remembered. Callback
+// retries come within seconds, but keeper replays messages queued while
+// coco was offline after a reconnect, hours later.
+const messageDedupTTL = 24 * time.Hour
+
+// duplicateDelivery reports whether msg is a platform message already
+// handled, such as a WeCom callback retry or a replay after the relay
+// reconnects, so it does not get a second reply or create a cron job
+// twice. Messages without a platform ID (API, batch, scheduled prompts)
+// are never duplicates.
+func (a *Agent) duplicateDelivery(msg router.Message) bool {
+	if a.persistStore == nil || msg.ID == "" || msg.Platform == "" {
+		return false
+	}
+	first, err := a.persistStore.ClaimMessage(msg.Platform, msg.ChannelID, msg.ID, messageDedupTTL)
+	if err != nil {
+		logger.Warn("[Agent] Failed to check message %s for duplicates: %v", msg.ID, err)
+		return false
+	}
+	if !first {
+		logger.Info("[Agent] Dropping duplicate delivery of %s message %s", msg.Platform, msg.ID)
+	}
+	return !first
+}
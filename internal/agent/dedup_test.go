@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestDuplicateDeliveryIsDropped(t *testing.T) {
+	a := newTaskTestAgent(t)
+	msg := router.Message{ID: "m-1", Platform: "wecom", ChannelID: "dm", UserID: "u1", Text: "每天 9 点提醒我打卡"}
+
+	if a.duplicateDelivery(msg) {
+		t.Fatal("first delivery reported as a duplicate")
+	}
+	resp, err := a.HandleMessage(context.Background(), msg)
+	if err != nil || resp.Text != "" || len(resp.Files) > 0 {
+		t.Fatalf("retry got %+v, %v", resp, err)
+	}
+
+	other := msg
+	other.ChannelID = "group"
+	if a.duplicateDelivery(other) {
+		t.Fatal("the same ID in another chat is not a duplicate")
+	}
+	other = msg
+	other.Platform = "telegram"
+	if a.duplicateDelivery(other) {
+		t.Fatal("the same ID on another platform is not a duplicate")
+	}
+	noID := msg
+	noID.ID = ""
+	if a.duplicateDelivery(noID) || a.duplicateDelivery(noID) {
+		t.Fatal("messages without an ID must never be dropped")
+	}
+}
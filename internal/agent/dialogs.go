@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/dialog"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+// dialogSessionTTL is how long a form dialog waits for the next answer.
+const dialogSessionTTL = 30 * time.Minute
+
+// defaultAskMissingTools are asked for missing arguments when
+// tools.ask_missing is not set.
+var defaultAskMissingTools = []string{"calendar_create_event"}
+
+// dialogSession is a form dialog running in one conversation.
+type dialogSession struct {
+	dlg     *dialog.Dialog
+	pending string // question not yet delivered, set when a tool call starts the dialog
+	finish  func(ctx context.Context, values map[string]string) string
+	ttl     time.Duration // how long to wait for an answer (default dialogSessionTTL)
+	updated time.Time
+}
+
+// dialogSessions holds the running dialog of each conversation.
+type dialogSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*dialogSession
+}
+
+func (d *dialogSessions) get(convKey string) *dialogSession {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.sessions[convKey]
+	if s != nil && time.Since(s.updated) > s.timeout() {
+		delete(d.sessions, convKey)
+		return nil
+	}
+	return s
+}
+
+func (s *dialogSession) timeout() time.Duration {
+	if s.ttl > 0 {
+		return s.ttl
+	}
+	return dialogSessionTTL
+}
+
+func (d *dialogSessions) set(convKey string, s *dialogSession) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sessions == nil {
+		d.sessions = make(map[string]*dialogSession)
+	}
+	s.updated = time.Now()
+	d.sessions[convKey] = s
+}
+
+func (d *dialogSessions) clear(convKey string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, convKey)
+}
+
+// takePending returns the opening question of a dialog a tool call started
+// during this turn, once.
+func (d *dialogSessions) takePending(convKey string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.sessions[convKey]
+	if s == nil {
+		return ""
+	}
+	q := s.pending
+	s.pending = ""
+	return q
+}
+
+// continueDialog takes msg as the answer to the running dialog, if any.
+func (a *Agent) continueDialog(ctx context.Context, msg router.Message) (router.Response, bool) {
+	convKey := conversationKeyOf(msg)
+	s := a.dialogs.get(convKey)
+	if s == nil {
+		return router.Response{}, false
+	}
+	reply, status := s.dlg.Answer(msg.Text)
+	return router.Response{Text: a.settleDialog(ctx, convKey, msg, s, reply, status)}, true
+}
+
+// settleDialog keeps s running while it asks, and runs its finish step
+// once the values are complete.
+func (a *Agent) settleDialog(ctx context.Context, convKey string, msg router.Message, s *dialogSession, reply string, status dialog.Status) string {
+	switch status {
+	case dialog.Asking:
+		a.dialogs.set(convKey, s)
+		return reply
+	case dialog.Cancelled:
+		a.dialogs.clear(convKey)
+		return reply
+	}
+	a.dialogs.clear(convKey)
+	reply = s.finish(ctx, s.dlg.Values())
+	logger.Info("[Agent] Dialog %s completed for %s", s.dlg.Title(), msg.Username)
+
+	// Keep the outcome in history so follow-up questions have context.
+	a.memory.AddExchange(convKey,
+		Message{Role: "user", Content: msg.Text},
+		Message{Role: "assistant", Content: reply},
+	)
+	return reply
+}
+
+// applyAskMissing sets which tools collect missing required arguments in a
+// dialog (tools.ask_missing).
+func (a *Agent) applyAskMissing(patterns []string) {
+	if len(patterns) == 0 {
+		patterns = defaultAskMissingTools
+	}
+	var out []string
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" && p != "off" {
+			out = append(out, p)
+		}
+	}
+	a.securityMu.Lock()
+	a.askMissingTools = out
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) asksMissing(name string) bool {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	for _, p := range a.askMissingTools {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// askMissingArgs starts a dialog for the required arguments tc left out and
+// returns the tool result telling the model to wait. It returns "" when the
+// call is complete or the tool is not configured for it.
+func (a *Agent) askMissingArgs(ctx context.Context, tc ToolCall) string {
+	convKey := currentConversationKey(ctx)
+	if convKey == "" || !a.asksMissing(tc.Name) {
+		return ""
+	}
+	var args map[string]any
+	if err := json.Unmarshal(tc.Input, &args); err != nil {
+		return ""
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type        string   `json:"type"`
+			Description string   `json:"description"`
+			Enum        []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	description := ""
+	for _, t := range a.buildToolsList() {
+		if t.Name == tc.Name {
+			_ = json.Unmarshal(t.InputSchema, &schema)
+			description = t.Description
+			break
+		}
+	}
+
+	form := dialog.Form{Title: description, Confirm: true}
+	known := map[string]string{}
+	missing := false
+	for _, name := range schema.Required {
+		prop := schema.Properties[name]
+		field := dialog.Field{Name: name, Label: prop.Description, Type: dialog.TypeText}
+		switch {
+		case len(prop.Enum) > 0:
+			field.Type, field.Choices = dialog.TypeChoice, prop.Enum
+		case prop.Type == "number" || prop.Type == "integer":
+			field.Type = dialog.TypeNumber
+		case strings.Contains(prop.Description, "YYYY-MM-DD HH:MM"):
+			field.Type = dialog.TypeDateTime
+		case strings.Contains(prop.Description, "YYYY-MM-DD"):
+			field.Type = dialog.TypeDate
+		}
+		form.Fields = append(form.Fields, field)
+		if v, ok := args[name]; ok && v != nil && fmt.Sprint(v) != "" {
+			known[name] = fmt.Sprint(v)
+		} else {
+			missing = true
+		}
+	}
+	if !missing {
+		return ""
+	}
+	dlg, err := dialog.New(form)
+	if err != nil {
+		logger.Warn("[Agent] Cannot ask for %s arguments: %v", tc.Name, err)
+		return ""
+	}
+	dlg.Prefill(known)
+	question, status := dlg.Start()
+	if status != dialog.Asking {
+		return ""
+	}
+
+	name := tc.Name
+	a.dialogs.set(convKey, &dialogSession{
+		dlg:     dlg,
+		pending: question,
+		finish: func(ctx context.Context, values map[string]string) string {
+			for _, f := range form.Fields {
+				v := values[f.Name]
+				if n, err := strconv.ParseFloat(v, 64); err == nil && f.Type == dialog.TypeNumber {
+					args[f.Name] = n
+				} else {
+					args[f.Name] = v
+				}
+			}
+			input, _ := json.Marshal(args)
+			return strings.TrimSpace(redactSecretValues(a.executeTool(ctx, name, input)))
+		},
+	})
+
+	missingNames := make([]string, 0, len(form.Fields))
+	for _, f := range form.Fields {
+		if _, ok := known[f.Name]; !ok {
+			missingNames = append(missingNames, f.Name)
+		}
+	}
+	logger.Info("[Agent] Asking %s for missing %s arguments: %s", turnMessage(ctx).Username, tc.Name, strings.Join(missingNames, ", "))
+	return fmt.Sprintf("PENDING INPUT: required arguments %s are missing. coco is asking the user for them and will run %s itself once they answer. Do NOT call it again and do NOT ask for them yourself.",
+		strings.Join(missingNames, ", "), tc.Name)
+}
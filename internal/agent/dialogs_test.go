@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestAskMissingToolArgs(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	a := &Agent{memory: NewMemory(store, 0)}
+	a.applyAskMissing(nil)
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1", Username: "小王"}
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	ctx := withTurn(context.Background(), msg)
+
+	call := ToolCall{ID: "1", Name: "calendar_create_event", Input: json.RawMessage(`{"title":"周会"}`)}
+	results, _ := a.processToolCalls(ctx, []ToolCall{call})
+	if len(results) != 1 || !strings.HasPrefix(results[0].Content, "PENDING INPUT") || !strings.Contains(results[0].Content, "start_time") {
+		t.Fatalf("results = %#v", results)
+	}
+	question := a.dialogs.takePending(convKey)
+	if !strings.Contains(question, "Start time") {
+		t.Fatalf("question = %q", question)
+	}
+	if again := a.dialogs.takePending(convKey); again != "" {
+		t.Fatalf("question delivered twice: %q", again)
+	}
+
+	msg.Text = "明天 10:00"
+	resp, handled := a.continueDialog(ctx, msg)
+	if !handled || !strings.Contains(resp.Text, "周会") || !strings.Contains(resp.Text, "确认") {
+		t.Fatalf("summary = %q", resp.Text)
+	}
+	msg.Text = "取消"
+	if resp, _ = a.continueDialog(ctx, msg); !strings.HasPrefix(resp.Text, "已取消") {
+		t.Fatalf("cancel = %q", resp.Text)
+	}
+	if _, handled = a.continueDialog(ctx, msg); handled {
+		t.Fatal("cancelled dialog still intercepts messages")
+	}
+
+	// Complete calls and tools outside tools.ask_missing run as usual.
+	if a.askMissingArgs(ctx, ToolCall{Name: "calendar_create_event", Input: json.RawMessage(`{"title":"周会","start_time":"2024-05-01 10:00"}`)}) != "" {
+		t.Fatal("complete call started a dialog")
+	}
+	a.applyAskMissing([]string{"off"})
+	if a.askMissingArgs(ctx, call) != "" {
+		t.Fatal("ask_missing off still started a dialog")
+	}
+}
@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+// dndCheckInterval is how often held notifications are checked for chats
+// whose do-not-disturb hours have ended.
+const dndCheckInterval = time.Minute
+
+// applyDND installs the dnd section.
+func (a *Agent) applyDND(cfg config.DNDConfig) {
+	var hours quietHours
+	if raw := strings.TrimSpace(cfg.Hours); raw != "" {
+		from, to, err := parseQuietHours(raw)
+		if err != nil {
+			logger.Warn("[Agent] Invalid dnd.hours: %v; no do-not-disturb hours", err)
+		}
+		hours = quietHours{from, to}
+	}
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.dnd = hours
+}
+
+// dndHoursFor returns the do-not-disturb hours of a channel: its profile's
+// dnd, or dnd.hours.
+func (a *Agent) dndHoursFor(platform, channelID string) quietHours {
+	if p, ok := a.channelProfileFor(router.Message{Platform: platform, ChannelID: channelID}); ok {
+		if raw := strings.TrimSpace(p.DND); raw != "" {
+			from, to, err := parseQuietHours(raw)
+			if err == nil {
+				return quietHours{from, to}
+			}
+			logger.Warn("[Agent] Invalid dnd of channel %s:%s: %v", platform, channelID, err)
+		}
+	}
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.dnd
+}
+
+// dndState reports whether a chat is in do-not-disturb at now and when
+// that ends; until is zero when only /dnd off ends it.
+func (a *Agent) dndState(platform, channelID string, now time.Time) (on bool, until time.Time) {
+	if a.persistStore != nil {
+		o, err := a.persistStore.DNDOverride(platform, channelID)
+		if err != nil {
+			logger.Warn("[Agent] Failed to load do-not-disturb of %s:%s: %v", platform, channelID, err)
+		} else if o != nil && (o.Until.IsZero() || now.Before(o.Until)) {
+			return o.On, o.Until
+		}
+	}
+	hours := a.dndHoursFor(platform, channelID)
+	if hours.contains(now) {
+		return true, hours.end(now)
+	}
+	return false, time.Time{}
+}
+
+// HoldNotification keeps a notification for the chat's digest while it is
+// in do-not-disturb, and reports whether it did. RouterCronNotifier asks
+// before sending anything coco starts on its own.
+func (a *Agent) HoldNotification(platform, channelID, userID, message string) bool {
+	if a.persistStore == nil || platform == "" || channelID == "" {
+		return false
+	}
+	if on, _ := a.dndState(platform, channelID, time.Now()); !on {
+		return false
+	}
+	err := a.persistStore.HoldNotification(persist.HeldNotification{
+		Platform:  platform,
+		ChannelID: channelID,
+		UserID:    userID,
+		Message:   message,
+	})
+	if err != nil {
+		logger.Warn("[Agent] Failed to hold notification for %s:%s, sending it now: %v", platform, channelID, err)
+		return false
+	}
+	return true
+}
+
+// StartDND sends each chat the notifications held during its
+// do-not-disturb hours, as one digest, once they end.
+func (a *Agent) StartDND(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(dndCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.sendDNDDigests(time.Now())
+			}
+		}
+	}()
+}
+
+func (a *Agent) sendDNDDigests(now time.Time) {
+	if a.persistStore == nil || a.notifier == nil {
+		return
+	}
+	chats, err := a.persistStore.HeldChats()
+	if err != nil {
+		logger.Warn("[Agent] Failed to load held notifications: %v", err)
+		return
+	}
+	checker, _ := a.notifier.(cronpkg.TargetChecker)
+	for _, chat := range chats {
+		platform, channelID, _ := strings.Cut(chat, ":")
+		if on, _ := a.dndState(platform, channelID, now); on {
+			continue
+		}
+		if checker != nil && checker.TargetAvailable(platform, channelID, "") != nil {
+			continue
+		}
+		held, err := a.persistStore.HeldNotifications(platform, channelID)
+		if err != nil || len(held) == 0 {
+			continue
+		}
+		last := held[len(held)-1]
+		if err := a.notifier.NotifyChatUser(platform, channelID, last.UserID, formatDNDDigest(held)); err != nil {
+			logger.Warn("[Agent] Failed to send do-not-disturb digest to %s: %v", chat, err)
+			continue
+		}
+		if err := a.persistStore.ClearHeldNotifications(platform, channelID, last.ID); err != nil {
+			logger.Warn("[Agent] Failed to clear held notifications of %s: %v", chat, err)
+		}
+		logger.Info("[Agent] Sent do-not-disturb digest of %d notification(s) to %s", len(held), chat)
+	}
+}
+
+func formatDNDDigest(held []persist.HeldNotification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🌅 勿扰期间收到 %d 条通知：", len(held))
+	for _, n := range held {
+		fmt.Fprintf(&b, "\n\n🕐 %s\n%s", n.CreatedAt.Local().Format("01-02 15:04"), strings.TrimSpace(n.Message))
+	}
+	return b.String()
+}
+
+// handleDNDCommand answers "/dnd" (status), "/dnd on [duration]" and
+// "/dnd off", which hand the notifications held so far over at once.
+func (a *Agent) handleDNDCommand(msg router.Message, text string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(strings.TrimSpace(text)))
+	if len(fields) == 0 || (fields[0] != "/dnd" && fields[0] != "勿扰") {
+		return "", false
+	}
+	if a.persistStore == nil {
+		return "当前无法设置勿扰：数据库不可用", true
+	}
+	if msg.Platform == "" || msg.ChannelID == "" {
+		return "勿扰只能在聊天中设置", true
+	}
+	now := time.Now()
+	action := ""
+	if len(fields) > 1 {
+		action = fields[1]
+	}
+
+	switch action {
+	case "":
+		return a.dndStatus(msg.Platform, msg.ChannelID, now), true
+
+	case "on", "开":
+		o := &persist.DNDOverride{On: true}
+		if len(fields) > 2 {
+			d, err := time.ParseDuration(fields[2])
+			if err != nil || d <= 0 {
+				return "用法：/dnd on [时长]，例如 /dnd on 2h、/dnd on 30m；不带时长则一直勿扰到 /dnd off", true
+			}
+			o.Until = now.Add(d)
+		}
+		if err := a.persistStore.SetDNDOverride(msg.Platform, msg.ChannelID, o); err != nil {
+			return fmt.Sprintf("设置勿扰失败: %v", err), true
+		}
+		if o.Until.IsZero() {
+			return "🌙 已开启勿扰：定时任务、提醒等主动消息会先暂存，/dnd off 后汇总发送", true
+		}
+		return fmt.Sprintf("🌙 已开启勿扰，到 %s 结束：期间的定时任务、提醒等主动消息会先暂存，结束后汇总发送", formatDNDUntil(o.Until, now)), true
+
+	case "off", "关":
+		var o *persist.DNDOverride
+		if hours := a.dndHoursFor(msg.Platform, msg.ChannelID); hours.contains(now) {
+			// Stay out of tonight's hours; the next ones apply again.
+			o = &persist.DNDOverride{On: false, Until: hours.end(now)}
+		}
+		if err := a.persistStore.SetDNDOverride(msg.Platform, msg.ChannelID, o); err != nil {
+			return fmt.Sprintf("关闭勿扰失败: %v", err), true
+		}
+		reply := "☀️ 已关闭勿扰"
+		if o != nil {
+			reply += fmt.Sprintf("，今天的勿扰时段（%s）不再生效", a.dndHoursFor(msg.Platform, msg.ChannelID))
+		}
+		held, err := a.persistStore.HeldNotifications(msg.Platform, msg.ChannelID)
+		if err != nil {
+			logger.Warn("[Agent] Failed to load held notifications: %v", err)
+			return reply, true
+		}
+		if len(held) == 0 {
+			return reply, true
+		}
+		if err := a.persistStore.ClearHeldNotifications(msg.Platform, msg.ChannelID, held[len(held)-1].ID); err != nil {
+			logger.Warn("[Agent] Failed to clear held notifications: %v", err)
+		}
+		return reply + "\n\n" + formatDNDDigest(held), true
+	}
+	return "用法：/dnd 查看状态，/dnd on [时长] 开启（如 /dnd on 2h），/dnd off 关闭", true
+}
+
+func (a *Agent) dndStatus(platform, channelID string, now time.Time) string {
+	var lines []string
+	on, until := a.dndState(platform, channelID, now)
+	switch {
+	case on && until.IsZero():
+		lines = append(lines, "🌙 勿扰中，直到 /dnd off")
+	case on:
+		lines = append(lines, fmt.Sprintf("🌙 勿扰中，到 %s 结束", formatDNDUntil(until, now)))
+	default:
+		lines = append(lines, "☀️ 勿扰未开启")
+	}
+	if hours := a.dndHoursFor(platform, channelID); hours.from != hours.to {
+		lines = append(lines, fmt.Sprintf("每天勿扰时段: %s", hours))
+	}
+	if n, err := a.persistStore.CountHeldNotifications(platform, channelID); err == nil && n > 0 {
+		lines = append(lines, fmt.Sprintf("已暂存 %d 条通知", n))
+	}
+	lines = append(lines, "/dnd on [时长] 开启，/dnd off 关闭并立即收取暂存的通知")
+	return strings.Join(lines, "\n")
+}
+
+func formatDNDUntil(until, now time.Time) string {
+	if until.YearDay() == now.YearDay() && until.Year() == now.Year() {
+		return until.Format("15:04")
+	}
+	return until.Format("01-02 15:04")
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestDNDHoursPerChannel(t *testing.T) {
+	a := &Agent{}
+	a.applyDND(config.DNDConfig{Hours: "23:00-08:00"})
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{
+		"wecom:team":  {DND: "off"},
+		"wecom:night": {DND: "01:00-06:00"},
+	})
+	late := time.Date(2026, 10, 16, 23, 30, 0, 0, time.Local)
+
+	on, until := a.dndState("wecom", "dm", late)
+	if !on || !until.Equal(time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)) {
+		t.Fatalf("dm at 23:30 = %v until %v", on, until)
+	}
+	if on, _ := a.dndState("wecom", "dm", late.Add(10*time.Hour)); on {
+		t.Fatal("dm is quiet at 09:30")
+	}
+	if on, _ := a.dndState("wecom", "team", late); on {
+		t.Fatal("a channel with dnd off is quiet")
+	}
+	if on, _ := a.dndState("wecom", "night", late); on {
+		t.Fatal("channel hours should replace dnd.hours")
+	}
+	if on, _ := a.dndState("wecom", "night", late.Add(3*time.Hour)); !on {
+		t.Fatal("channel hours not applied")
+	}
+}
+
+func TestDNDCommandHoldsNotificationsUntilOff(t *testing.T) {
+	a := newTaskTestAgent(t)
+	notifier := &recordingNotifier{}
+	a.notifier = notifier
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1"}
+
+	if a.HoldNotification("wecom", "dm", "u1", "早") {
+		t.Fatal("held a notification outside do-not-disturb")
+	}
+	if got, _ := a.handleDNDCommand(msg, "/dnd on"); !strings.Contains(got, "已开启勿扰") {
+		t.Fatalf("/dnd on = %q", got)
+	}
+	for _, text := range []string{"⏰ 该交周报了", "💓 服务器正常"} {
+		if !a.HoldNotification("wecom", "dm", "u1", text) {
+			t.Fatalf("%q was not held", text)
+		}
+	}
+	if a.HoldNotification("wecom", "other", "u2", "别的会话") {
+		t.Fatal("do-not-disturb leaked into another chat")
+	}
+	if got, _ := a.handleDNDCommand(msg, "/dnd"); !strings.Contains(got, "直到 /dnd off") || !strings.Contains(got, "已暂存 2 条通知") {
+		t.Fatalf("/dnd = %q", got)
+	}
+	a.sendDNDDigests(time.Now())
+	if len(notifier.messages) != 0 {
+		t.Fatalf("digest sent during do-not-disturb: %q", notifier.messages)
+	}
+
+	got, _ := a.handleDNDCommand(msg, "/dnd off")
+	if !strings.Contains(got, "已关闭勿扰") || !strings.Contains(got, "勿扰期间收到 2 条通知") ||
+		strings.Index(got, "该交周报了") > strings.Index(got, "服务器正常") {
+		t.Fatalf("/dnd off = %q", got)
+	}
+	if n, _ := a.persistStore.CountHeldNotifications("wecom", "dm"); n != 0 {
+		t.Fatalf("%d notifications still held", n)
+	}
+	if got, _ := a.handleDNDCommand(msg, "/dnd on soon"); !strings.Contains(got, "用法") {
+		t.Fatalf("bad duration = %q", got)
+	}
+}
+
+func TestDNDDigestSentWhenHoursEnd(t *testing.T) {
+	a := newTaskTestAgent(t)
+	notifier := &recordingNotifier{}
+	a.notifier = notifier
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1"}
+
+	if got, _ := a.handleDNDCommand(msg, "/dnd on 2h"); !strings.Contains(got, "结束") {
+		t.Fatalf("/dnd on 2h = %q", got)
+	}
+	if !a.HoldNotification("wecom", "dm", "u1", "📦 快递已签收") {
+		t.Fatal("notification was not held")
+	}
+	a.sendDNDDigests(time.Now().Add(time.Hour))
+	if len(notifier.messages) != 0 {
+		t.Fatal("digest sent before do-not-disturb ended")
+	}
+	a.sendDNDDigests(time.Now().Add(3 * time.Hour))
+	if len(notifier.messages) != 1 || notifier.targets[0] != "wecom:dm:u1" ||
+		!strings.Contains(notifier.messages[0], "勿扰期间收到 1 条通知") || !strings.Contains(notifier.messages[0], "快递已签收") {
+		t.Fatalf("digest = %q to %q", notifier.messages, notifier.targets)
+	}
+	a.sendDNDDigests(time.Now().Add(3 * time.Hour))
+	if len(notifier.messages) != 1 {
+		t.Fatal("digest sent twice")
+	}
+
+	// An expired override leaves the chat to the configured hours.
+	if err := a.persistStore.SetDNDOverride("wecom", "dm", &persist.DNDOverride{On: true, Until: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if a.HoldNotification("wecom", "dm", "u1", "早") {
+		t.Fatal("expired /dnd still holds notifications")
+	}
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/docextract"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	docInlineChars    = 4000  // documents up to this size go into the prompt whole
+	docSummaryInput   = 20000 // text of longer documents and pages the summary is written from
+	docReadMaxPages   = 5     // pages one document_read call returns at most
+	docReadMaxChars   = 20000 // and characters
+	docExtractTimeout = 60 * time.Second
+	attachmentFileTTL = 24 * time.Hour // saved attachments are removed after this long
+)
+
+// readDocumentAttachments saves incoming files where the file tools can
+// reach them and puts the text of PDF and Office documents into the
+// message: whole when short, summarized otherwise.
+func (a *Agent) readDocumentAttachments(ctx context.Context, msg router.Message) router.Message {
+	var notes []string
+	for _, att := range msg.Attachments {
+		if att.Type == "image" || len(att.Data) == 0 {
+			continue
+		}
+		name := filepath.Base(strings.TrimSpace(msg.FileName))
+		if name == "." || name == "" {
+			name = "document"
+			if format := docextract.Detect(att.Data, ""); format != "" {
+				name += "." + format
+			}
+		}
+		path, err := saveAttachment("coco-files", fmt.Sprintf("%d-%s", time.Now().UnixNano(), name), att.Data)
+		if err != nil {
+			logger.Warn("[Agent] Failed to save file attachment %s: %v", name, err)
+			continue
+		}
+		if docextract.Detect(att.Data, name) == "" {
+			notes = append(notes, fmt.Sprintf("[文件: %s，已保存到 %s]", name, path))
+			continue
+		}
+
+		extractCtx, cancel := context.WithTimeout(ctx, docExtractTimeout)
+		doc, err := docextract.Extract(extractCtx, att.Data, name)
+		cancel()
+		if err != nil {
+			logger.Warn("[Agent] Failed to extract %s: %v", name, err)
+			notes = append(notes, fmt.Sprintf("[文件: %s，无法提取文字（%v），已保存到 %s]", name, err, path))
+			continue
+		}
+		note := fmt.Sprintf("[文件: %s，共 %d %s，可用 document_read 按页阅读，已保存到 %s]", name, len(doc.Pages), doc.PageUnit(), path)
+		text := doc.Text()
+		if len([]rune(text)) <= docInlineChars {
+			note += "\n[文件内容]\n" + text
+		} else {
+			summary := a.summarizeLayered(ctx, name, text)
+			a.sources.put(currentConversationKey(ctx), &summarySource{key: path, name: name, text: text, summary: &summary, at: time.Now()})
+			note += "\n[文件摘要]\n" + summary.String() + drillHint(summary)
+		}
+		notes = append(notes, note)
+	}
+	if len(notes) > 0 {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n" + strings.Join(notes, "\n\n"))
+	}
+	return msg
+}
+
+func (a *Agent) executeDocumentRead(ctx context.Context, args map[string]any) string {
+	path := normalizePath(getString(args, "path"))
+	if path == "" {
+		return "Error: path is required"
+	}
+	page, count := 1, 1
+	if n, ok := args["page"].(float64); ok && n >= 1 {
+		page = int(n)
+	}
+	if n, ok := args["pages"].(float64); ok && n >= 1 {
+		count = min(int(n), docReadMaxPages)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, docExtractTimeout)
+	defer cancel()
+	doc, err := docextract.ExtractFile(ctx, path)
+	if err != nil {
+		return fmt.Sprintf("Error reading document: %v", err)
+	}
+	total := len(doc.Pages)
+	if page > total {
+		return fmt.Sprintf("Error: %s has only %d pages", filepath.Base(path), total)
+	}
+	last := min(page+count-1, total)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: pages %d-%d of %d\n", filepath.Base(path), page, last, total)
+	for i := page; i <= last; i++ {
+		fmt.Fprintf(&sb, "\n--- page %d ---\n%s\n", i, doc.Pages[i-1])
+	}
+	out := sb.String()
+	if r := []rune(out); len(r) > docReadMaxChars {
+		out = string(r[:docReadMaxChars]) + "\n…(truncated; read fewer pages at a time)"
+	}
+	return out
+}
+
+// saveAttachment writes data under a coco directory in the temp dir,
+// clearing out files older than attachmentFileTTL first.
+func saveAttachment(dirName, name string, data []byte) (string, error) {
+	dir := filepath.Join(os.TempDir(), dirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > attachmentFileTTL {
+				os.Remove(filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, data, 0o600)
+}
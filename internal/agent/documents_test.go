@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func docxWithParagraphs(t *testing.T, paras []string) []byte {
+	t.Helper()
+	var body strings.Builder
+	for _, p := range paras {
+		body.WriteString("<w:p><w:r><w:t>" + p + "</w:t></w:r></w:p>")
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document xmlns:w="w"><w:body>` + body.String() + `</w:body></w:document>`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var savedFilePattern = regexp.MustCompile(`已保存到 ([^\]]+)\]`)
+
+func TestDocumentAttachmentsReachThePrompt(t *testing.T) {
+	a := &Agent{}
+
+	short := a.readDocumentAttachments(context.Background(), router.Message{
+		FileName:    "周报.docx",
+		Attachments: []router.Attachment{{Type: "file", Data: docxWithParagraphs(t, []string{"本周完成登录模块", "下周计划：支付"})}},
+	})
+	if !strings.Contains(short.Text, "[文件: 周报.docx，共 1 段") || !strings.Contains(short.Text, "[文件内容]\n本周完成登录模块\n下周计划：支付") {
+		t.Fatalf("short document text = %q", short.Text)
+	}
+	defer os.Remove(savedFilePattern.FindStringSubmatch(short.Text)[1])
+
+	var paras []string
+	for i := 0; i < 60; i++ {
+		paras = append(paras, strings.Repeat("合同条款", 30))
+	}
+	long := a.readDocumentAttachments(context.Background(), router.Message{
+		FileName:    "contract.docx",
+		Attachments: []router.Attachment{{Type: "file", Data: docxWithParagraphs(t, paras)}},
+	})
+	if !strings.Contains(long.Text, "[文件摘要]") || strings.Contains(long.Text, "[文件内容]") {
+		t.Fatalf("long document should be summarized: %.200q", long.Text)
+	}
+	path := savedFilePattern.FindStringSubmatch(long.Text)[1]
+	defer os.Remove(path)
+
+	got := a.executeDocumentRead(context.Background(), map[string]any{"path": path, "page": float64(2), "pages": float64(2)})
+	if !strings.Contains(got, "pages 2-3 of") || !strings.Contains(got, "--- page 3 ---") || strings.Contains(got, "--- page 4 ---") {
+		t.Fatalf("document_read = %.200q", got)
+	}
+	if got := a.executeDocumentRead(context.Background(), map[string]any{"path": path, "page": float64(99)}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("reading past the end = %q", got)
+	}
+
+	other := a.readDocumentAttachments(context.Background(), router.Message{
+		FileName:    "notes.zip",
+		Attachments: []router.Attachment{{Type: "file", Data: []byte("not a document")}},
+	})
+	if !strings.HasPrefix(other.Text, "[文件: notes.zip，已保存到 ") {
+		t.Fatalf("unsupported file = %q", other.Text)
+	}
+	os.Remove(savedFilePattern.FindStringSubmatch(other.Text)[1])
+}
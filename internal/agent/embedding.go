@@ -12,7 +12,7 @@ type EmbeddingProvider interface {
 	// CreateEmbedding creates embeddings for the given texts
 	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
 
-	// Name returns the provider name (e.g., "qwen", "openai")
+	// Name returns the provider name (e.g., "qwen", "openai", "local")
 	Name() string
 
 	// Dimension returns the embedding vector dimension
@@ -34,6 +34,8 @@ func NewEmbeddingProvider(cfg EmbeddingConfig) (EmbeddingProvider, error) {
 		return NewQwenEmbeddingProvider(cfg)
 	case "openai":
 		return NewOpenAIEmbeddingProvider(cfg)
+	case "local":
+		return NewLocalEmbeddingProvider(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported embedding provider: %s", cfg.Provider)
 	}
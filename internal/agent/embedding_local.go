@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const localEmbeddingDimension = 512
+
+// LocalEmbeddingProvider embeds text offline with a hashed bag-of-words model.
+// Latin words are lowercased unigrams; CJK runs contribute single characters and
+// bigrams so Chinese text matches without a segmenter. Term frequencies are
+// log-scaled and the vector is L2-normalized, so cosine similarity behaves like
+// a TF-weighted lexical overlap. No API key or network access is needed.
+type LocalEmbeddingProvider struct {
+	dimension int
+}
+
+// NewLocalEmbeddingProvider creates a new local embedding provider
+func NewLocalEmbeddingProvider(cfg EmbeddingConfig) (*LocalEmbeddingProvider, error) {
+	return &LocalEmbeddingProvider{dimension: localEmbeddingDimension}, nil
+}
+
+// Name returns the provider name
+func (p *LocalEmbeddingProvider) Name() string {
+	return "local"
+}
+
+// Dimension returns the embedding vector dimension
+func (p *LocalEmbeddingProvider) Dimension() int {
+	return p.dimension
+}
+
+// CreateEmbedding creates embeddings for the given texts
+func (p *LocalEmbeddingProvider) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embeddings[i] = p.embed(text)
+	}
+	return embeddings, nil
+}
+
+func (p *LocalEmbeddingProvider) embed(text string) []float32 {
+	counts := map[string]int{}
+	for _, term := range localEmbeddingTerms(text) {
+		counts[term]++
+	}
+
+	vec := make([]float32, p.dimension)
+	for term, n := range counts {
+		h := fnv.New64a()
+		h.Write([]byte(term))
+		sum := h.Sum64()
+		idx := int(sum % uint64(p.dimension))
+		// The sign bit spreads colliding terms so they cancel rather than accumulate.
+		weight := float32(1 + math.Log(float64(n)))
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vec[idx] += weight
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// localEmbeddingTerms splits text into word terms plus CJK unigrams and bigrams.
+func localEmbeddingTerms(text string) []string {
+	var terms []string
+	var word strings.Builder
+	var cjk []rune
+
+	flushWord := func() {
+		if word.Len() >= 2 {
+			terms = append(terms, word.String())
+		}
+		word.Reset()
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			terms = append(terms, string(r))
+			if i+1 < len(cjk) {
+				terms = append(terms, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+// embeddingNeedsAPIKey reports whether the configured provider calls a remote API.
+func embeddingNeedsAPIKey(provider string) bool {
+	return !strings.EqualFold(strings.TrimSpace(provider), "local")
+}
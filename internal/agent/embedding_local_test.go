@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLocalEmbeddingRanksRelatedTextHigher(t *testing.T) {
+	provider, err := NewEmbeddingProvider(EmbeddingConfig{Provider: "local"})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	vecs, err := provider.CreateEmbedding(context.Background(), []string{
+		"用户喜欢喝美式咖啡，不加糖",
+		"用户每天早上喝咖啡",
+		"The quarterly budget review is on Friday",
+	})
+	if err != nil {
+		t.Fatalf("create embedding: %v", err)
+	}
+	for _, v := range vecs {
+		if len(v) != provider.Dimension() {
+			t.Fatalf("dimension = %d, want %d", len(v), provider.Dimension())
+		}
+	}
+
+	related := cosineSimilarity(vecs[0], vecs[1])
+	unrelated := cosineSimilarity(vecs[0], vecs[2])
+	if related <= unrelated {
+		t.Fatalf("expected related texts to score higher: related=%.3f unrelated=%.3f", related, unrelated)
+	}
+	if self := cosineSimilarity(vecs[2], vecs[2]); self < 0.999 {
+		t.Fatalf("expected normalized vectors, self similarity = %.3f", self)
+	}
+}
+
+func TestLocalEmbeddingNeedsNoAPIKey(t *testing.T) {
+	if embeddingNeedsAPIKey("local") {
+		t.Fatal("local provider should not require an API key")
+	}
+	if !embeddingNeedsAPIKey("qwen") {
+		t.Fatal("qwen provider should require an API key")
+	}
+}
@@ -30,14 +30,15 @@ func (a *Agent) executeSpawnAgent(ctx context.Context, args map[string]any) stri
 		timeout = v
 	}
 
+	msg := turnMessage(ctx)
 	payload := map[string]any{
 		"type":      "spawn_agent",
 		"source":    "external-agent",
 		"prompt":    prompt,
-		"platform":  a.currentMsg.Platform,
-		"channelID": a.currentMsg.ChannelID,
-		"userID":    a.currentMsg.UserID,
-		"username":  a.currentMsg.Username,
+		"platform":  msg.Platform,
+		"channelID": msg.ChannelID,
+		"userID":    msg.UserID,
+		"username":  msg.Username,
 		"requested": time.Now().Format(time.RFC3339),
 	}
 	body, err := json.Marshal(payload)
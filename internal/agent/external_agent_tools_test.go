@@ -19,16 +19,15 @@ func TestExecuteSpawnAgentSuccess(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	a := &Agent{
-		currentMsg: router.Message{
-			Platform:  "wecom",
-			ChannelID: "ch",
-			UserID:    "u",
-			Username:  "name",
-		},
-	}
+	a := &Agent{}
+	ctx := withTurn(context.Background(), router.Message{
+		Platform:  "wecom",
+		ChannelID: "ch",
+		UserID:    "u",
+		Username:  "name",
+	})
 
-	out := a.executeSpawnAgent(context.Background(), map[string]any{
+	out := a.executeSpawnAgent(ctx, map[string]any{
 		"endpoint": srv.URL,
 		"prompt":   "run task",
 		"auth":     "Bearer abc",
@@ -0,0 +1,212 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+// feedbackWindow is how long after an answer a bare rating still counts for it.
+const feedbackWindow = 30 * time.Minute
+
+// Feedback scales for channels.<id>.feedback.
+const (
+	feedbackThumbs = "thumbs"
+	feedbackScale  = "scale"
+)
+
+// feedbackPrompts remembers the last answer per conversation that asked for a rating.
+type feedbackPrompts struct {
+	mu      sync.Mutex
+	pending map[string]pendingFeedback
+}
+
+type pendingFeedback struct {
+	scale    string
+	model    string
+	question string
+	answer   string
+	asked    time.Time
+}
+
+func (f *feedbackPrompts) set(convKey string, p pendingFeedback) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pending == nil {
+		f.pending = make(map[string]pendingFeedback)
+	}
+	for k, old := range f.pending {
+		if time.Since(old.asked) > feedbackWindow {
+			delete(f.pending, k)
+		}
+	}
+	f.pending[convKey] = p
+}
+
+// take returns and forgets the conversation's open prompt.
+func (f *feedbackPrompts) take(convKey string) (pendingFeedback, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.pending[convKey]
+	delete(f.pending, convKey)
+	if !ok || time.Since(p.asked) > feedbackWindow {
+		return pendingFeedback{}, false
+	}
+	return p, true
+}
+
+func (f *feedbackPrompts) peek(convKey string) (pendingFeedback, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.pending[convKey]
+	return p, ok && time.Since(p.asked) <= feedbackWindow
+}
+
+// channelFeedbackScale returns the feedback scale configured for the message's channel, or "".
+func (a *Agent) channelFeedbackScale(msg router.Message) string {
+	p, ok := a.channelProfileFor(msg)
+	if !ok {
+		return ""
+	}
+	switch scale := strings.ToLower(strings.TrimSpace(p.Feedback)); scale {
+	case feedbackThumbs, feedbackScale:
+		return scale
+	case "":
+		return ""
+	default:
+		logger.Warn("[Agent] Channel %s/%s has unknown feedback scale %q", msg.Platform, msg.ChannelID, p.Feedback)
+		return ""
+	}
+}
+
+// askFeedback appends the channel's rating prompt to an answer and remembers
+// the answer so the next bare rating can be linked to it.
+func (a *Agent) askFeedback(ctx context.Context, convKey string, msg router.Message, answer string) string {
+	scale := a.channelFeedbackScale(msg)
+	if scale == "" || a.persistStore == nil || strings.TrimSpace(answer) == "" || a.isIncognito(convKey) {
+		return answer
+	}
+	model := ""
+	if a.modelRouter != nil {
+		if m := a.pickModel(a.currentRequestModelRole(ctx), turnOf(ctx).model); m != nil {
+			model = m.Name
+		}
+	}
+	a.feedback.set(convKey, pendingFeedback{
+		scale:    scale,
+		model:    model,
+		question: feedbackExcerpt(msg.Text, 500),
+		answer:   feedbackExcerpt(answer, 1000),
+		asked:    time.Now(),
+	})
+	if scale == feedbackThumbs {
+		return answer + "\n\n（这个回答有帮助吗？回复 👍 或 👎）"
+	}
+	return answer + "\n\n（请为这个回答打分：回复 1-5）"
+}
+
+// captureFeedback records msg as a rating when it answers an open prompt.
+// It returns the reply to send and whether the message was consumed.
+func (a *Agent) captureFeedback(msg router.Message) (router.Response, bool) {
+	convKey := conversationKeyOf(msg)
+	p, ok := a.feedback.peek(convKey)
+	if !ok {
+		return router.Response{}, false
+	}
+	rating, ok := parseFeedbackRating(msg.Text, p.scale)
+	if !ok {
+		return router.Response{}, false
+	}
+	if _, ok := a.feedback.take(convKey); !ok {
+		return router.Response{}, false
+	}
+
+	err := a.persistStore.RecordFeedback(persist.Feedback{
+		Platform:  msg.Platform,
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+		Rating:    rating,
+		Scale:     p.scale,
+		Model:     p.model,
+		Question:  p.question,
+		Answer:    p.answer,
+	})
+	if err != nil {
+		logger.Warn("[Agent] Failed to record feedback from %s: %v", msg.UserID, err)
+	}
+	if rating <= 2 {
+		return router.Response{Text: "收到，感谢反馈！可以告诉我哪里不好，我会改进。"}, true
+	}
+	return router.Response{Text: "收到，感谢反馈！"}, true
+}
+
+// parseFeedbackRating reads a bare rating. Thumbs map to 5 and 1 so both
+// scales share one average.
+func parseFeedbackRating(text, scale string) (int, bool) {
+	t := strings.TrimSpace(text)
+	switch t {
+	case "👍", "👍🏻", "👍🏼", "👍🏽", "👍🏾", "👍🏿", "+1", "好", "有帮助":
+		return 5, true
+	case "👎", "👎🏻", "👎🏼", "👎🏽", "👎🏾", "👎🏿", "-1", "不好", "没帮助":
+		return 1, true
+	}
+	if scale != feedbackScale {
+		return 0, false
+	}
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "分"), "星")
+	n, err := strconv.Atoi(strings.TrimSpace(t))
+	if err != nil || n < 1 || n > 5 {
+		return 0, false
+	}
+	return n, true
+}
+
+// formatSatisfaction renders the rating trend of the last days and a per-model
+// comparison, or "" when nobody rated anything.
+func (a *Agent) formatSatisfaction(days int) string {
+	if a.persistStore == nil {
+		return ""
+	}
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	since := until.AddDate(0, 0, -days)
+	byDay, err := a.persistStore.FeedbackStats(since, until, "day")
+	if err != nil {
+		logger.Warn("[Agent] Failed to load feedback stats: %v", err)
+		return ""
+	}
+	if len(byDay) == 0 {
+		return ""
+	}
+	byModel, err := a.persistStore.FeedbackStats(since, until, "model")
+	if err != nil {
+		logger.Warn("[Agent] Failed to load feedback stats: %v", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "😊 满意度 (近 %d 天):\n", days)
+	for _, st := range byDay {
+		fmt.Fprintf(&sb, "  - %s: 平均 %.1f 分, %d 条评价 (👍 %d / 👎 %d)\n", st.Key, st.AvgRating, st.Count, st.Positive, st.Negative)
+	}
+	if len(byModel) > 1 {
+		sb.WriteString("  按模型:\n")
+		for _, st := range byModel {
+			fmt.Fprintf(&sb, "  - %s: 平均 %.1f 分, %d 条评价\n", st.Key, st.AvgRating, st.Count)
+		}
+	}
+	return sb.String()
+}
+
+func feedbackExcerpt(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestFeedbackCapture(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	a := &Agent{persistStore: store}
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{
+		"support": {Feedback: "scale"},
+		"team":    {Feedback: "thumbs"},
+	})
+
+	support := router.Message{Platform: "wecom", ChannelID: "support", UserID: "u1", Text: "how do I reset?"}
+	team := router.Message{Platform: "wecom", ChannelID: "team", UserID: "u2", Text: "status?"}
+	plain := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u3", Text: "hi"}
+
+	if got := a.askFeedback(context.Background(), ConversationKey(plain.Platform, plain.ChannelID, plain.UserID), plain, "hello"); got != "hello" {
+		t.Fatalf("unconfigured channel got prompt: %q", got)
+	}
+	if got := a.askFeedback(context.Background(), ConversationKey(support.Platform, support.ChannelID, support.UserID), support, "Press the button."); !strings.Contains(got, "1-5") {
+		t.Fatalf("scale prompt missing: %q", got)
+	}
+	if got := a.askFeedback(context.Background(), ConversationKey(team.Platform, team.ChannelID, team.UserID), team, "All green."); !strings.Contains(got, "👍") {
+		t.Fatalf("thumbs prompt missing: %q", got)
+	}
+
+	// A normal follow-up is not a rating and keeps the prompt open.
+	support.Text = "and then?"
+	if _, handled := a.captureFeedback(support); handled {
+		t.Fatal("follow-up question consumed as feedback")
+	}
+	support.Text = "4分"
+	if _, handled := a.captureFeedback(support); !handled {
+		t.Fatal("scale rating not captured")
+	}
+	if _, handled := a.captureFeedback(support); handled {
+		t.Fatal("second rating captured for the same answer")
+	}
+	// Thumbs channels do not take numbers.
+	team.Text = "3"
+	if _, handled := a.captureFeedback(team); handled {
+		t.Fatal("number captured on thumbs channel")
+	}
+	team.Text = "👎"
+	if resp, handled := a.captureFeedback(team); !handled || !strings.Contains(resp.Text, "哪里不好") {
+		t.Fatalf("thumbs down = %q, %v", resp.Text, handled)
+	}
+
+	stats, err := store.FeedbackStats(time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "day")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Count != 2 || stats[0].Positive != 1 || stats[0].Negative != 1 || stats[0].AvgRating != 2.5 {
+		t.Fatalf("stats = %#v", stats)
+	}
+	if got := a.formatSatisfaction(7); !strings.Contains(got, "平均 2.5 分") {
+		t.Fatalf("summary = %q", got)
+	}
+}
@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// fileEdit is a file_edit call resolved against the file on disk.
+type fileEdit struct {
+	path     string // absolute
+	old, new string
+	mode     os.FileMode
+}
+
+// searchReplace is one block of a file_edit "edits" list.
+type searchReplace struct {
+	search, replace string
+	all             bool
+}
+
+// diffHunk is one hunk of a unified diff.
+type diffHunk struct {
+	oldStart int      // 1-based line from the @@ header
+	old, new []string // the lines the hunk replaces, and their replacement
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// prepareFileEdit reads the file and applies either the search/replace
+// blocks in "edits" or the unified diff in "diff" in memory.
+func prepareFileEdit(args map[string]any) (*fileEdit, error) {
+	path := getString(args, "path")
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	abs, err := filepath.Abs(tools.ExpandTilde(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %v", path, err)
+	}
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist; use file_write to create it", abs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", abs)
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	edit := &fileEdit{path: abs, old: string(data), mode: info.Mode().Perm()}
+
+	edits := parseSearchReplace(args["edits"])
+	diff := getString(args, "diff")
+	switch {
+	case len(edits) > 0 && diff != "":
+		return nil, fmt.Errorf("pass either edits or diff, not both")
+	case len(edits) > 0:
+		edit.new, err = applySearchReplace(edit.old, edits)
+	case diff != "":
+		var hunks []diffHunk
+		if hunks, err = parseUnifiedDiff(diff); err == nil {
+			edit.new, err = applyUnifiedDiff(edit.old, hunks)
+		}
+	default:
+		return nil, fmt.Errorf("edits (search/replace blocks) or diff (a unified diff) is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if edit.new == edit.old {
+		return nil, fmt.Errorf("the edits leave %s unchanged", abs)
+	}
+	return edit, nil
+}
+
+func parseSearchReplace(raw any) []searchReplace {
+	list, _ := raw.([]any)
+	var edits []searchReplace
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		all, _ := m["replace_all"].(bool)
+		edits = append(edits, searchReplace{search: getString(m, "search"), replace: getString(m, "replace"), all: all})
+	}
+	return edits
+}
+
+// applySearchReplace applies the blocks in order. Each search text must
+// match exactly once, or at least once with replace_all.
+func applySearchReplace(content string, edits []searchReplace) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	for i, e := range edits {
+		if e.search == "" {
+			return "", fmt.Errorf("edits[%d]: search is empty", i)
+		}
+		n := strings.Count(content, e.search)
+		if n == 0 && crlf && !strings.Contains(e.search, "\r\n") {
+			// The file uses CRLF line endings but the model wrote LF.
+			e.search = strings.ReplaceAll(e.search, "\n", "\r\n")
+			e.replace = strings.ReplaceAll(e.replace, "\n", "\r\n")
+			n = strings.Count(content, e.search)
+		}
+		switch {
+		case n == 0:
+			return "", fmt.Errorf("edits[%d]: search text not found; it must match the file exactly, including whitespace. Read the file again", i)
+		case n > 1 && !e.all:
+			return "", fmt.Errorf("edits[%d]: search text matches %d places; include more surrounding lines to make it unique, or set replace_all", i, n)
+		}
+		if e.all {
+			content = strings.ReplaceAll(content, e.search, e.replace)
+		} else {
+			content = strings.Replace(content, e.search, e.replace, 1)
+		}
+	}
+	return content, nil
+}
+
+// parseUnifiedDiff reads the hunks of a unified diff. File headers are
+// skipped and the line counts in hunk headers are not trusted.
+func parseUnifiedDiff(diff string) ([]diffHunk, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	var hunks []diffHunk
+	var cur *diffHunk
+	for i, line := range lines {
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.Atoi(m[1])
+			hunks = append(hunks, diffHunk{oldStart: start})
+			cur = &hunks[len(hunks)-1]
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch {
+		case line == "" && i == len(lines)-1:
+		case line == "":
+			// Blank context lines often lose their leading space.
+			cur.old = append(cur.old, "")
+			cur.new = append(cur.new, "")
+		case line[0] == ' ':
+			cur.old = append(cur.old, line[1:])
+			cur.new = append(cur.new, line[1:])
+		case line[0] == '-':
+			cur.old = append(cur.old, line[1:])
+		case line[0] == '+':
+			cur.new = append(cur.new, line[1:])
+		case line[0] == '\\':
+			// "\ No newline at end of file"
+		default:
+			return nil, fmt.Errorf("diff line %d is not part of a hunk: %q", i+1, line)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("diff has no hunks (@@ -line,count +line,count @@)")
+	}
+	return hunks, nil
+}
+
+// applyUnifiedDiff applies hunks in order. A hunk is placed where its
+// context and removed lines match, nearest to the line its header names.
+func applyUnifiedDiff(content string, hunks []diffHunk) (string, error) {
+	crlf := strings.Contains(content, "\r\n")
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	lines := strings.Split(content, "\n")
+	var out []string
+	pos := 0
+	for i, h := range hunks {
+		at := findHunk(lines, h, pos)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (@@ -%d) does not match the file; read the file again and resend the diff", i+1, h.oldStart)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, h.new...)
+		pos = at + len(h.old)
+	}
+	out = append(out, lines[pos:]...)
+	result := strings.Join(out, "\n")
+	if crlf {
+		result = strings.ReplaceAll(result, "\n", "\r\n")
+	}
+	return result, nil
+}
+
+// findHunk returns the line at or after pos where h applies, or -1. Exact
+// matches win over ones that differ only in trailing whitespace.
+func findHunk(lines []string, h diffHunk, pos int) int {
+	hint := h.oldStart - 1
+	if len(h.old) == 0 {
+		// A pure insertion goes after line oldStart.
+		return min(max(h.oldStart, pos), len(lines))
+	}
+	for _, loose := range []bool{false, true} {
+		best := -1
+		for at := pos; at+len(h.old) <= len(lines); at++ {
+			if !hunkMatches(lines[at:at+len(h.old)], h.old, loose) {
+				continue
+			}
+			if best < 0 || lineDistance(at, hint) < lineDistance(best, hint) {
+				best = at
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func lineDistance(a, b int) int {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
+
+func hunkMatches(lines, want []string, loose bool) bool {
+	for i := range want {
+		a, b := lines[i], want[i]
+		if loose {
+			a, b = strings.TrimRight(a, " \t"), strings.TrimRight(b, " \t")
+		}
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
+
+// executeFileEdit applies a file_edit call. The previous version of the
+// file is copied to the trash first; with preview set only the diff is
+// returned.
+func executeFileEdit(ctx context.Context, args map[string]any) string {
+	edit, err := prepareFileEdit(args)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	diff := unifiedLineDiff(edit.old, edit.new, 3, 80)
+	if preview, _ := args["preview"].(bool); preview {
+		return fmt.Sprintf("Preview of %s (nothing written):\n%s", edit.path, diff)
+	}
+	if err := ctx.Err(); err != nil {
+		return "Error: " + err.Error()
+	}
+	backup, err := trashCopy(edit.path, []byte(edit.old), time.Now())
+	if err != nil {
+		return fmt.Sprintf("Error: nothing written, backing up %s failed: %v", edit.path, err)
+	}
+	if err := os.WriteFile(edit.path, []byte(edit.new), edit.mode); err != nil {
+		return fmt.Sprintf("Error: failed to write %s: %v", edit.path, err)
+	}
+	return fmt.Sprintf("Successfully edited %s (previous version saved to %s):\n%s", edit.path, backup, diff)
+}
+
+func planFileEdit(args map[string]any) string {
+	edit, err := prepareFileEdit(args)
+	if err != nil {
+		return fmt.Sprintf("Edit %s (will fail: %v)", resolveBestEffortPath(getString(args, "path")), err)
+	}
+	return fmt.Sprintf("Edit %s:\n%s", edit.path, unifiedLineDiff(edit.old, edit.new, 3, 60))
+}
+
+// trashDirs returns where trashCopy puts files: the Finder trash on macOS,
+// the freedesktop.org trash (with its info directory) elsewhere on Unix,
+// and the data directory on Windows.
+func trashDirs() (files, info string) {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, ".Trash"), ""
+	case "windows":
+		return datadir.Path(".coco", "trash"), ""
+	}
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "Trash", "files"), filepath.Join(base, "Trash", "info")
+}
+
+// trashCopy saves data, the content of path, to the trash under a name
+// stamped with now and returns where it went.
+func trashCopy(path string, data []byte, now time.Time) (string, error) {
+	filesDir, infoDir := trashDirs()
+	if err := os.MkdirAll(filesDir, 0o700); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	stamp := now.Format("20060102-150405")
+	name := fmt.Sprintf("%s.%s%s", stem, stamp, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(filepath.Join(filesDir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s.%s-%d%s", stem, stamp, i, ext)
+	}
+	dest := filepath.Join(filesDir, name)
+	if err := os.WriteFile(dest, data, 0o600); err != nil {
+		return "", err
+	}
+	if infoDir != "" && os.MkdirAll(infoDir, 0o700) == nil {
+		// Lets file managers show where the copy came from and restore it.
+		info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: path}).EscapedPath(), now.Format("2006-01-02T15:04:05"))
+		_ = os.WriteFile(filepath.Join(infoDir, name+".trashinfo"), []byte(info), 0o600)
+	}
+	return dest, nil
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeEditTarget(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileEditSearchReplace(t *testing.T) {
+	path := writeEditTarget(t, "alpha\nbeta\ngamma\nbeta\n")
+
+	out := executeFileEdit(context.Background(), map[string]any{
+		"path":  path,
+		"edits": []any{map[string]any{"search": "beta", "replace": "BETA"}},
+	})
+	if !strings.Contains(out, "matches 2 places") {
+		t.Fatalf("ambiguous search should fail, got %q", out)
+	}
+	out = executeFileEdit(context.Background(), map[string]any{
+		"path":  path,
+		"edits": []any{map[string]any{"search": "delta", "replace": "x"}},
+	})
+	if !strings.Contains(out, "not found") {
+		t.Fatalf("missing search should fail, got %q", out)
+	}
+
+	out = executeFileEdit(context.Background(), map[string]any{
+		"path": path,
+		"edits": []any{
+			map[string]any{"search": "alpha\nbeta", "replace": "alpha\nBETA"},
+			map[string]any{"search": "gamma", "replace": "GAMMA", "replace_all": true},
+		},
+	})
+	if !strings.HasPrefix(out, "Successfully edited") {
+		t.Fatalf("edit failed: %s", out)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "alpha\nBETA\nGAMMA\nbeta\n" {
+		t.Fatalf("content = %q", got)
+	}
+
+	// The previous version went to the trash.
+	files, _ := trashDirs()
+	entries, err := os.ReadDir(files)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("trash entries = %v, %v", entries, err)
+	}
+	backup, _ := os.ReadFile(filepath.Join(files, entries[0].Name()))
+	if string(backup) != "alpha\nbeta\ngamma\nbeta\n" {
+		t.Fatalf("backup = %q", backup)
+	}
+}
+
+func TestFileEditUnifiedDiff(t *testing.T) {
+	path := writeEditTarget(t, "one\ntwo\nthree\nfour\nfive\nsix\n")
+
+	// The header is off by two lines; the hunk is placed by its context.
+	diff := "--- a/notes.txt\n+++ b/notes.txt\n@@ -1,3 +1,3 @@\n three\n-four\n+FOUR\n five\n"
+	out := executeFileEdit(context.Background(), map[string]any{"path": path, "diff": diff})
+	if !strings.HasPrefix(out, "Successfully edited") {
+		t.Fatalf("edit failed: %s", out)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "one\ntwo\nthree\nFOUR\nfive\nsix\n" {
+		t.Fatalf("content = %q", got)
+	}
+
+	out = executeFileEdit(context.Background(), map[string]any{"path": path, "diff": "@@ -1,2 +1,2 @@\n one\n-zwei\n+deux\n"})
+	if !strings.Contains(out, "does not match") {
+		t.Fatalf("mismatched hunk should fail, got %q", out)
+	}
+	if got2, _ := os.ReadFile(path); string(got2) != string(got) {
+		t.Fatal("a failed edit changed the file")
+	}
+}
+
+func TestFileEditPreviewWritesNothing(t *testing.T) {
+	path := writeEditTarget(t, "hello world\n")
+
+	out := executeFileEdit(context.Background(), map[string]any{
+		"path":    path,
+		"edits":   []any{map[string]any{"search": "world", "replace": "there"}},
+		"preview": true,
+	})
+	if !strings.Contains(out, "nothing written") || !strings.Contains(out, "- hello world") || !strings.Contains(out, "+ hello there") {
+		t.Fatalf("preview = %q", out)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "hello world\n" {
+		t.Fatalf("preview wrote the file: %q", got)
+	}
+	if plan := describeToolPlan("file_edit", map[string]any{
+		"path":  path,
+		"edits": []any{map[string]any{"search": "world", "replace": "there"}},
+	}); !strings.Contains(plan, "+ hello there") {
+		t.Fatalf("plan = %q", plan)
+	}
+}
@@ -0,0 +1,357 @@
+package agent
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// platformFileLimits are the bot upload limits of the platforms, in MB.
+// Platforms not listed get defaultFileLimitMB.
+var platformFileLimits = map[string]int{
+	"telegram":   50,
+	"discord":    10,
+	"slack":      1000,
+	"feishu":     30,
+	"dingtalk":   20,
+	"wecom":      20,
+	"wechat":     10,
+	"whatsapp":   100,
+	"signal":     100,
+	"matrix":     50,
+	"mattermost": 100,
+	"teams":      4,
+	"email":      25,
+	"googlechat": 200,
+	"line":       10,
+}
+
+const (
+	defaultFileLimitMB  = 20
+	defaultFileMaxParts = 5
+	defaultFileLinkTTL  = 24 * time.Hour
+	// fileSendKeep is how long zipped and split files stay in the send
+	// directory; the platform uploads them after the turn ends.
+	fileSendKeep = 24 * time.Hour
+)
+
+// compressedExts are formats zipping does not shrink; they are split or
+// linked as they are.
+var compressedExts = map[string]bool{
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".7z": true, ".rar": true, ".zst": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true,
+	".mp4": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true,
+	".docx": true, ".xlsx": true, ".pptx": true,
+}
+
+type fileSendSettings struct {
+	limits   map[string]int64 // bytes, by platform
+	maxParts int
+	linkTTL  time.Duration
+}
+
+// applyFileSend installs tools.file_send.
+func (a *Agent) applyFileSend(cfg config.FileSendConfig) {
+	s := fileSendSettings{limits: make(map[string]int64), maxParts: cfg.MaxParts, linkTTL: defaultFileLinkTTL}
+	for platform, mb := range platformFileLimits {
+		s.limits[platform] = int64(mb) << 20
+	}
+	for platform, mb := range cfg.Limits {
+		if mb > 0 {
+			s.limits[strings.ToLower(platform)] = int64(mb) << 20
+		}
+	}
+	if s.maxParts <= 0 {
+		s.maxParts = defaultFileMaxParts
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(cfg.LinkTTL)); err == nil && d > 0 {
+		s.linkTTL = d
+	}
+	a.securityMu.Lock()
+	a.fileSend = s
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) fileSendSettings() fileSendSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.fileSend
+}
+
+// fileLimit is the upload limit of platform in bytes; 0 means none, for
+// the web UI and API, which take files of any size.
+func (s fileSendSettings) fileLimit(platform string) int64 {
+	if platform == "" || platform == "webui" || platform == "api" {
+		return 0
+	}
+	if limit, ok := s.limits[platform]; ok {
+		return limit
+	}
+	return defaultFileLimitMB << 20
+}
+
+// fitFileSend makes file fit the platform of the turn. A file over the
+// limit is zipped; if that is not enough it is split into parts; past
+// max_parts it is shared as an expiring Keeper download link and nothing
+// is attached. The note tells the model what was done.
+func (a *Agent) fitFileSend(ctx context.Context, file router.FileAttachment) ([]router.FileAttachment, string, error) {
+	platform := strings.ToLower(turnMessage(ctx).Platform)
+	s := a.fileSendSettings()
+	limit := s.fileLimit(platform)
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit == 0 || info.Size() <= limit {
+		return []router.FileAttachment{file}, "", nil
+	}
+
+	dir, err := newFileSendDir()
+	if err != nil {
+		return nil, "", err
+	}
+	archive, size := file.Path, info.Size()
+	if !compressedExts[strings.ToLower(filepath.Ext(file.Path))] {
+		zipped, zsize, err := zipForSend(file.Path, dir)
+		if err != nil {
+			logger.Warn("[Agent] file_send: failed to zip %s: %v", file.Path, err)
+		} else if zsize <= limit {
+			return []router.FileAttachment{{Path: zipped, Name: filepath.Base(zipped), MediaType: "file"}},
+				fmt.Sprintf("\n%s is over the %s limit of %s, so it was zipped to %s (%s).", file.Name, platform, formatMB(limit), filepath.Base(zipped), formatMB(zsize)), nil
+		} else {
+			archive, size = zipped, zsize
+		}
+	}
+
+	if parts := (size + limit - 1) / limit; parts <= int64(s.maxParts) {
+		paths, err := splitForSend(archive, dir, limit)
+		if err != nil {
+			return nil, "", err
+		}
+		files := make([]router.FileAttachment, 0, len(paths))
+		for _, p := range paths {
+			files = append(files, router.FileAttachment{Path: p, Name: filepath.Base(p), MediaType: "file"})
+		}
+		base := filepath.Base(archive)
+		return files, fmt.Sprintf("\n%s is over the %s limit of %s, so it was sent as %d parts %s.001…%03d. Tell the user to join them with `cat %s.0* > %s` (or copy /b on Windows), or open the .001 part with 7-Zip.",
+			file.Name, platform, formatMB(limit), len(paths), base, len(paths), base, base), nil
+	}
+
+	if a.fileLinks == nil {
+		return nil, "", fmt.Errorf("%s (%s) is over the %s limit of %s even in %d parts, and no Keeper is connected to host a download link. Offer remote_put to upload it to the user's storage instead",
+			file.Name, formatMB(info.Size()), platform, formatMB(limit), s.maxParts)
+	}
+	link, expires, err := a.fileLinks.share(ctx, archive, s.linkTTL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is too large for %s and sharing it through Keeper failed: %w", file.Name, platform, err)
+	}
+	return nil, fmt.Sprintf("\n%s is too large for %s (%s, limit %s), so nothing was attached. It is shared as a download link instead; give the user this link, valid until %s: %s",
+		file.Name, platform, formatMB(size), formatMB(limit), expires.Local().Format("2006-01-02 15:04"), link), nil
+}
+
+// executeFileShare uploads a file to Keeper and returns its signed,
+// expiring download link, for reports and videos the user wants as a link
+// rather than an attachment.
+func (a *Agent) executeFileShare(ctx context.Context, args map[string]any) string {
+	path := tools.ExpandTilde(strings.TrimSpace(getString(args, "path")))
+	if path == "" {
+		return "Error: path is required"
+	}
+	if isSensitiveFile(path) {
+		return "ACCESS DENIED: sharing sensitive files (.env, credentials, keys) is blocked for security. Do NOT retry."
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("Error: file not found: %s", path)
+	}
+	if info.IsDir() {
+		return fmt.Sprintf("Error: %s is a directory; zip it first", path)
+	}
+	if a.fileLinks == nil {
+		return "Error: no Keeper is connected to host download links; use file_send or remote_put instead"
+	}
+	ttl := a.fileSendSettings().linkTTL
+	if v := strings.TrimSpace(getString(args, "ttl")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Sprintf("Error: invalid ttl %q (use e.g. 2h or 72h)", v)
+		}
+		ttl = d
+	}
+	link, expires, err := a.fileLinks.share(ctx, path, ttl)
+	if err != nil {
+		return fmt.Sprintf("Error: sharing %s through Keeper failed: %v", filepath.Base(path), err)
+	}
+	logger.Info("[Agent] file_share: %s (%d bytes) until %s", path, info.Size(), expires.Format(time.RFC3339))
+	return fmt.Sprintf("Shared %s (%s) as a download link valid until %s: %s",
+		filepath.Base(path), formatMB(info.Size()), expires.Local().Format("2006-01-02 15:04"), link)
+}
+
+func formatMB(n int64) string {
+	return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+}
+
+// newFileSendDir makes a directory for one file's zip and parts, and
+// clears out those of earlier sends.
+func newFileSendDir() (string, error) {
+	root := filepath.Join(os.TempDir(), "coco-send")
+	if entries, err := os.ReadDir(root); err == nil {
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > fileSendKeep {
+				os.RemoveAll(filepath.Join(root, e.Name()))
+			}
+		}
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(root, "send-")
+}
+
+// zipForSend compresses path into dir/<name>.zip.
+func zipForSend(path, dir string) (string, int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+	target := filepath.Join(dir, filepath.Base(path)+".zip")
+	out, err := os.Create(target)
+	if err != nil {
+		return "", 0, err
+	}
+	zw := zip.NewWriter(out)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.Base(path), Method: zip.Deflate, Modified: time.Now()})
+	if err == nil {
+		_, err = io.Copy(w, src)
+	}
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", 0, err
+	}
+	return target, info.Size(), nil
+}
+
+// splitForSend cuts path into dir/<name>.001, .002, … of at most size
+// bytes each, which cat or 7-Zip join back together.
+func splitForSend(path, dir string, size int64) ([]string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	var parts []string
+	for i := 1; ; i++ {
+		target := filepath.Join(dir, fmt.Sprintf("%s.%03d", filepath.Base(path), i))
+		out, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+		n, err := io.CopyN(out, src, size)
+		if cerr := out.Close(); cerr != nil && (err == nil || err == io.EOF) {
+			err = cerr
+		}
+		if n > 0 {
+			parts = append(parts, target)
+		} else {
+			os.Remove(target)
+		}
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fileLinkClient shares files through Keeper's expiring download links.
+type fileLinkClient struct {
+	baseURL string
+	token   string
+	userID  string // relay.user_id; Keeper's quota is per coco
+	client  *http.Client
+}
+
+func newFileLinkClient(cfg *config.Config) *fileLinkClient {
+	if cfg == nil {
+		return nil
+	}
+	baseURL := inferKeeperBaseURLForCron(cfg.Relay.WebhookURL, cfg.Relay.ServerURL)
+	if baseURL == "" {
+		return nil
+	}
+	return &fileLinkClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   strings.TrimSpace(cfg.Relay.Token),
+		userID:  strings.TrimSpace(cfg.Relay.UserID),
+		client:  &http.Client{},
+	}
+}
+
+// share uploads path to Keeper and returns its download link.
+func (c *fileLinkClient) share(ctx context.Context, path string, ttl time.Duration) (string, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	query := url.Values{"name": {filepath.Base(path)}, "ttl": {ttl.String()}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/files?"+query.Encode(), f)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.userID != "" {
+		req.Header.Set("X-User-ID", c.userID)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", time.Time{}, fmt.Errorf("keeper returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		Path      string `json:"path"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", time.Time{}, fmt.Errorf("decode keeper response: %w", err)
+	}
+	expires, _ := time.Parse(time.RFC3339, out.ExpiresAt)
+	return c.baseURL + out.Path, expires, nil
+}
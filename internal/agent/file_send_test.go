@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestFileSendZipsSplitsAndLinks(t *testing.T) {
+	a := &Agent{}
+	a.applyFileSend(config.FileSendConfig{Limits: map[string]int{"telegram": 1}, MaxParts: 2})
+	tg := withTurn(context.Background(), router.Message{Platform: "telegram", UserID: "u1"})
+	dir := t.TempDir()
+
+	// Text compresses well below the 1MB limit.
+	text := filepath.Join(dir, "log.txt")
+	os.WriteFile(text, bytes.Repeat([]byte("all quiet\n"), 300_000), 0o644)
+	files, note, err := a.fitFileSend(tg, router.FileAttachment{Path: text, Name: "log.txt"})
+	if err != nil || len(files) != 1 || files[0].Name != "log.txt.zip" || !strings.Contains(note, "zipped") {
+		t.Fatalf("text = %+v %q %v", files, note, err)
+	}
+
+	// Already compressed data is split; the parts join back to the original.
+	noise := make([]byte, 3<<19)
+	for i := range noise {
+		noise[i] = byte(i*7919 + i>>8)
+	}
+	video := filepath.Join(dir, "clip.mp4")
+	os.WriteFile(video, noise, 0o644)
+	files, note, err = a.fitFileSend(tg, router.FileAttachment{Path: video, Name: "clip.mp4"})
+	if err != nil || len(files) != 2 || files[1].Name != "clip.mp4.002" || !strings.Contains(note, "cat clip.mp4.0*") {
+		t.Fatalf("video = %+v %q %v", files, note, err)
+	}
+	var joined []byte
+	for _, f := range files {
+		part, _ := os.ReadFile(f.Path)
+		joined = append(joined, part...)
+	}
+	if !bytes.Equal(joined, noise) {
+		t.Fatal("parts do not join back to the file")
+	}
+
+	// The web UI has no limit.
+	if files, _, _ := a.fitFileSend(withTurn(context.Background(), router.Message{Platform: "webui"}), router.FileAttachment{Path: video}); len(files) != 1 || files[0].Path != video {
+		t.Fatalf("webui = %+v", files)
+	}
+
+	// Past max_parts the file needs a Keeper link.
+	big := filepath.Join(dir, "movie.mkv")
+	os.WriteFile(big, append(append(noise, noise...), noise...), 0o644)
+	if _, _, err := a.fitFileSend(tg, router.FileAttachment{Path: big, Name: "movie.mkv"}); err == nil || !strings.Contains(err.Error(), "remote_put") {
+		t.Fatalf("no keeper: %v", err)
+	}
+
+	var uploaded int
+	keeper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/files" || r.URL.Query().Get("name") != "movie.mkv" || r.URL.Query().Get("ttl") != "24h0m0s" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		uploaded = len(body)
+		w.Write([]byte(`{"path":"/files/abc/movie.mkv","expires_at":"` + time.Now().Add(24*time.Hour).Format(time.RFC3339) + `"}`))
+	}))
+	defer keeper.Close()
+	a.fileLinks = &fileLinkClient{baseURL: keeper.URL, token: "tok", client: keeper.Client()}
+	files, note, err = a.fitFileSend(tg, router.FileAttachment{Path: big, Name: "movie.mkv"})
+	if err != nil || len(files) != 0 || uploaded != 3*len(noise) || !strings.Contains(note, keeper.URL+"/files/abc/movie.mkv") {
+		t.Fatalf("link = %+v %q %v (uploaded %d)", files, note, err, uploaded)
+	}
+}
+
+func TestFileShareLink(t *testing.T) {
+	a := &Agent{}
+	a.applyFileSend(config.FileSendConfig{LinkTTL: "2h"})
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(path, []byte("pdf"), 0o644)
+	if got := a.executeFileShare(context.Background(), map[string]any{"path": path}); !strings.Contains(got, "no Keeper is connected") {
+		t.Fatalf("without keeper = %q", got)
+	}
+
+	var ttls []string
+	keeper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ttls = append(ttls, r.URL.Query().Get("ttl"))
+		w.Write([]byte(`{"path":"/files/abc/report.pdf?exp=1&sig=s","expires_at":"2030-01-02T03:04:05Z"}`))
+	}))
+	defer keeper.Close()
+	a.fileLinks = &fileLinkClient{baseURL: keeper.URL, client: keeper.Client()}
+	if got := a.executeFileShare(context.Background(), map[string]any{"path": path}); !strings.HasSuffix(got, ": "+keeper.URL+"/files/abc/report.pdf?exp=1&sig=s") {
+		t.Fatalf("share = %q", got)
+	}
+	a.executeFileShare(context.Background(), map[string]any{"path": path, "ttl": "72h"})
+	if got := a.executeFileShare(context.Background(), map[string]any{"path": path, "ttl": "soon"}); !strings.Contains(got, "invalid ttl") {
+		t.Fatalf("bad ttl = %q", got)
+	}
+	if strings.Join(ttls, ",") != "2h0m0s,72h0m0s" {
+		t.Fatalf("ttls = %v", ttls)
+	}
+}
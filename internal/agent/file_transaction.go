@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// fileSnapshot is a file's state before a transaction touched it.
+type fileSnapshot struct {
+	path    string
+	existed bool
+	data    []byte
+	mode    os.FileMode
+}
+
+// fileTransaction applies a set of file writes all or nothing: every target
+// is snapshotted before the first write and restored if any write fails.
+type fileTransaction struct {
+	snapshots []fileSnapshot
+	written   int // snapshots whose file has been written
+}
+
+// beginFileTransaction snapshots paths. Nothing is written yet, so an
+// unreadable target aborts the transaction without side effects.
+func beginFileTransaction(paths []string) (*fileTransaction, error) {
+	tx := &fileTransaction{}
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if seen[p] {
+			return nil, fmt.Errorf("%s is listed twice", p)
+		}
+		seen[p] = true
+		snap := fileSnapshot{path: p, mode: 0o644}
+		info, err := os.Stat(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		case info.IsDir():
+			return nil, fmt.Errorf("%s is a directory", p)
+		default:
+			if snap.data, err = os.ReadFile(p); err != nil {
+				return nil, err
+			}
+			snap.existed, snap.mode = true, info.Mode().Perm()
+		}
+		tx.snapshots = append(tx.snapshots, snap)
+	}
+	return tx, nil
+}
+
+// rollback restores every written file, newest first, and returns the
+// paths it could not restore.
+func (tx *fileTransaction) rollback() []string {
+	var failed []string
+	for i := tx.written - 1; i >= 0; i-- {
+		snap := tx.snapshots[i]
+		var err error
+		if snap.existed {
+			err = os.WriteFile(snap.path, snap.data, snap.mode)
+		} else if err = os.Remove(snap.path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			logger.Error("[Agent] Rollback of %s failed: %v", snap.path, err)
+			failed = append(failed, snap.path)
+		}
+	}
+	tx.written = 0
+	return failed
+}
+
+// batchFileArgs splits file_write_batch arguments into file_write style
+// {"path", "content"} maps.
+func batchFileArgs(args map[string]any) []map[string]any {
+	raw, _ := args["files"].([]any)
+	out := make([]map[string]any, 0, len(raw))
+	for _, item := range raw {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, map[string]any{"path": m["path"], "content": m["content"]})
+		}
+	}
+	return out
+}
+
+// executeFileWriteBatch writes several files as one transaction.
+func executeFileWriteBatch(ctx context.Context, args map[string]any) string {
+	files := batchFileArgs(args)
+	if len(files) == 0 {
+		return "Error: files is required (array of {path, content})"
+	}
+	paths := make([]string, len(files))
+	contents := make([]string, len(files))
+	for i, f := range files {
+		path, _ := f["path"].(string)
+		content, ok := f["content"].(string)
+		if strings.TrimSpace(path) == "" || !ok {
+			return fmt.Sprintf("Error: files[%d] needs path and content", i)
+		}
+		abs, err := filepath.Abs(tools.ExpandTilde(path))
+		if err != nil {
+			return fmt.Sprintf("Error: invalid path %s: %v", path, err)
+		}
+		paths[i], contents[i] = abs, content
+	}
+
+	tx, err := beginFileTransaction(paths)
+	if err != nil {
+		return fmt.Sprintf("Error: nothing written, snapshot failed: %v", err)
+	}
+	for i, path := range paths {
+		err := ctx.Err()
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			tx.written = i + 1 // a failed write may still leave a partial file
+			err = os.WriteFile(path, []byte(contents[i]), tx.snapshots[i].mode)
+		}
+		if err != nil {
+			failed := tx.rollback()
+			logger.Warn("[Agent] file_write_batch failed at %s, rolled back: %v", path, err)
+			if len(failed) > 0 {
+				return fmt.Sprintf("Error: writing %s failed (%v); rollback could NOT restore: %s. Tell the user to check these files.", path, err, strings.Join(failed, ", "))
+			}
+			return fmt.Sprintf("Error: writing %s failed (%v); all %d files were restored to their previous state.", path, err, len(paths))
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Successfully wrote %d files as one transaction:", len(paths))
+	for i, path := range paths {
+		fmt.Fprintf(&sb, "\n- %s (%d bytes)", path, len(contents[i]))
+	}
+	return sb.String()
+}
+
+func planFileWriteBatch(args map[string]any) string {
+	files := batchFileArgs(args)
+	parts := make([]string, 0, len(files)+1)
+	parts = append(parts, fmt.Sprintf("Write %d files as one transaction (all or nothing):", len(files)))
+	for _, f := range files {
+		path, _ := f["path"].(string)
+		content, _ := f["content"].(string)
+		parts = append(parts, planFileWrite(path, content))
+	}
+	return strings.Join(parts, "\n\n")
+}
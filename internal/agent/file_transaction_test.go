@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileWriteBatch(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "docs", "b.md")
+	if err := os.WriteFile(a, []byte("old a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	batch := func(files ...[2]string) string {
+		list := make([]any, 0, len(files))
+		for _, f := range files {
+			list = append(list, map[string]any{"path": f[0], "content": f[1]})
+		}
+		return executeFileWriteBatch(context.Background(), map[string]any{"files": list})
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "<" + err.Error() + ">"
+		}
+		return string(data)
+	}
+
+	if out := batch([2]string{a, "new a"}, [2]string{b, "new b"}); !strings.HasPrefix(out, "Successfully wrote 2 files") {
+		t.Fatalf("batch = %s", out)
+	}
+	if read(a) != "new a" || read(b) != "new b" {
+		t.Fatalf("files = %q, %q", read(a), read(b))
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode of a = %v", info.Mode().Perm())
+	}
+
+	// A failing write restores every file written before it.
+	c := filepath.Join(dir, "c.md")
+	blocked := filepath.Join(c, "child.md") // c is written as a file first, so this cannot be created
+	out := batch([2]string{a, "broken a"}, [2]string{c, "c"}, [2]string{blocked, "x"})
+	if !strings.Contains(out, "all 3 files were restored") {
+		t.Fatalf("failed batch = %s", out)
+	}
+	if read(a) != "new a" {
+		t.Fatalf("a not restored: %q", read(a))
+	}
+	if _, err := os.Stat(c); !os.IsNotExist(err) {
+		t.Fatalf("c left behind: %v", err)
+	}
+
+	// Targets that cannot be snapshotted stop the batch before any write.
+	if out := batch([2]string{a, "x"}, [2]string{dir, "x"}); !strings.HasPrefix(out, "Error: nothing written") || read(a) != "new a" {
+		t.Fatalf("snapshot failure = %s, a = %q", out, read(a))
+	}
+	if out := batch([2]string{a, "x"}, [2]string{a, "y"}); !strings.Contains(out, "listed twice") {
+		t.Fatalf("duplicate = %s", out)
+	}
+}
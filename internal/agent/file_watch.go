@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/fswatch"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+const (
+	defaultFileWatchPrompt = "读取这个文件并总结要点"
+	// fileWatchQueueSize bounds the files waiting for the agent; more are
+	// dropped, so a folder full of copies does not queue hours of work.
+	fileWatchQueueSize = 32
+)
+
+// runWatchPrompt hands a new file to the agent; tests replace it.
+var runWatchPrompt = func(ctx context.Context, a *Agent, w persist.FileWatch, prompt string) (string, error) {
+	return a.ExecutePrompt(ctx, w.Platform, w.ChannelID, w.UserID, prompt)
+}
+
+func (a *Agent) executeFileWatch(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	action := strings.ToLower(strings.TrimSpace(getString(args, "action")))
+	if action == "" {
+		action = "list"
+		if getString(args, "path") != "" {
+			action = "add"
+		}
+	}
+	msg := turnMessage(ctx)
+	userID := timeUserID(msg)
+	switch action {
+	case "add":
+		path := normalizePath(getString(args, "path"))
+		if path == "" {
+			return "Error: path is required"
+		}
+		// Check access before touching the path, so a denied path does not
+		// reveal whether it exists.
+		snapshot := a.securitySnapshot()
+		if snapshot.disableFileTools {
+			return "ACCESS DENIED: file operations are disabled by security policy. Do NOT retry. Inform the user that file access is disabled."
+		}
+		if checker := snapshot.pathChecker; checker != nil && checker.HasRestrictions() {
+			if err := checker.CheckPath(path); err != nil {
+				return err.Error()
+			}
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return fmt.Sprintf("Error: %s is not a directory", path)
+		}
+		w := persist.FileWatch{
+			UserID:    userID,
+			Platform:  msg.Platform,
+			ChannelID: msg.ChannelID,
+			Path:      path,
+			Pattern:   strings.TrimSpace(getString(args, "pattern")),
+			Prompt:    strings.TrimSpace(getString(args, "prompt")),
+		}
+		if w.Prompt == "" {
+			w.Prompt = defaultFileWatchPrompt
+		}
+		for _, p := range splitWatchPatterns(w.Pattern) {
+			if _, err := filepath.Match(p, ""); err != nil {
+				return fmt.Sprintf("Error: invalid pattern %q", p)
+			}
+		}
+		id, err := a.persistStore.AddFileWatch(w)
+		if err != nil {
+			return fmt.Sprintf("Error saving file watch: %v", err)
+		}
+		w.ID = id
+		reply := fmt.Sprintf("📂 已开始监控 #%d：%s\n新文件出现时：%s", id, fileWatchTarget(w), w.Prompt)
+		if fw := a.currentFileWatcher(); fw != nil {
+			if err := fw.Add(path); err != nil {
+				reply += "\n⚠️ 暂时无法监控该目录: " + err.Error()
+			}
+		} else {
+			reply += "\n（监控在 coco relay 运行时生效）"
+		}
+		return reply
+	case "list":
+		return a.listFileWatches(userID)
+	case "remove", "delete":
+		id := int64(getFloat(args, "id"))
+		w, err := a.persistStore.GetFileWatch(id)
+		if err != nil {
+			return fmt.Sprintf("Error loading file watch: %v", err)
+		}
+		if w == nil || w.UserID != userID {
+			return fmt.Sprintf("没有编号为 #%d 的文件监控", id)
+		}
+		if err := a.persistStore.DeleteFileWatch(id); err != nil {
+			return fmt.Sprintf("Error removing file watch: %v", err)
+		}
+		a.unwatchUnusedDir(w.Path)
+		return fmt.Sprintf("已停止监控 #%d：%s", id, fileWatchTarget(*w))
+	default:
+		return "Error: action must be add, list or remove"
+	}
+}
+
+func (a *Agent) listFileWatches(userID string) string {
+	watches, err := a.persistStore.FileWatches(userID)
+	if err != nil {
+		return fmt.Sprintf("Error loading file watches: %v", err)
+	}
+	if len(watches) == 0 {
+		return "还没有监控任何目录"
+	}
+	var sb strings.Builder
+	sb.WriteString("📂 文件监控:\n")
+	for _, w := range watches {
+		fmt.Fprintf(&sb, "#%d %s\n  %s", w.ID, fileWatchTarget(w), w.Prompt)
+		if w.Fired > 0 {
+			fmt.Fprintf(&sb, "\n  已处理 %d 个文件，最近 %s", w.Fired, w.LastFired.Format("01-02 15:04"))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func fileWatchTarget(w persist.FileWatch) string {
+	if w.Pattern == "" {
+		return w.Path
+	}
+	return filepath.Join(w.Path, w.Pattern)
+}
+
+// splitWatchPatterns splits "*.pdf, *.docx".
+func splitWatchPatterns(pattern string) []string {
+	var out []string
+	for _, p := range strings.Split(pattern, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// watchMatches reports whether path is a new file for w.
+func watchMatches(w persist.FileWatch, path string) bool {
+	if filepath.Clean(w.Path) != filepath.Dir(path) {
+		return false
+	}
+	patterns := splitWatchPatterns(w.Pattern)
+	if len(patterns) == 0 {
+		return true
+	}
+	name := strings.ToLower(filepath.Base(path))
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Agent) currentFileWatcher() *fswatch.Watcher {
+	a.fileWatchMu.Lock()
+	defer a.fileWatchMu.Unlock()
+	return a.fileWatcher
+}
+
+// unwatchUnusedDir stops watching dir once no watch needs it.
+func (a *Agent) unwatchUnusedDir(dir string) {
+	fw := a.currentFileWatcher()
+	if fw == nil {
+		return
+	}
+	watches, err := a.persistStore.FileWatches("")
+	if err != nil {
+		return
+	}
+	for _, w := range watches {
+		if filepath.Clean(w.Path) == filepath.Clean(dir) {
+			return
+		}
+	}
+	if err := fw.Remove(dir); err != nil {
+		logger.Warn("[Agent] Failed to stop watching %s: %v", dir, err)
+	}
+}
+
+// StartFileWatches watches the directories of every stored file watch
+// until ctx ends and hands each new file to the agent in the chat that
+// asked for the watch.
+func (a *Agent) StartFileWatches(ctx context.Context) {
+	if a.persistStore == nil {
+		return
+	}
+	fw, err := fswatch.New(0)
+	if err != nil {
+		logger.Warn("[Agent] File watches disabled: %v", err)
+		return
+	}
+	watches, err := a.persistStore.FileWatches("")
+	if err != nil {
+		logger.Warn("[Agent] Failed to load file watches: %v", err)
+	}
+	for _, w := range watches {
+		if err := fw.Add(w.Path); err != nil {
+			logger.Warn("[Agent] Cannot watch %s (#%d): %v", w.Path, w.ID, err)
+		}
+	}
+	a.fileWatchMu.Lock()
+	a.fileWatcher = fw
+	a.fileWatchMu.Unlock()
+
+	queue := make(chan string, fileWatchQueueSize)
+	go fw.Run(ctx, func(path string) {
+		select {
+		case queue <- path:
+		default:
+			logger.Warn("[Agent] File watch queue full, skipped %s", path)
+		}
+	}, func(err error) {
+		logger.Warn("[Agent] File watcher: %v", err)
+	})
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				a.fileWatchMu.Lock()
+				a.fileWatcher = nil
+				a.fileWatchMu.Unlock()
+				return
+			case path := <-queue:
+				a.handleWatchedFile(ctx, path, time.Now())
+			}
+		}
+	}()
+	if len(watches) > 0 {
+		logger.Info("[Agent] Watching %d directories for new files", len(fw.Dirs()))
+	}
+}
+
+// handleWatchedFile runs the prompt of every watch path matches and sends
+// the answer to the watch's chat.
+func (a *Agent) handleWatchedFile(ctx context.Context, path string, now time.Time) {
+	watches, err := a.persistStore.FileWatches("")
+	if err != nil {
+		logger.Warn("[Agent] Failed to load file watches: %v", err)
+		return
+	}
+	for _, w := range watches {
+		if !watchMatches(w, path) {
+			continue
+		}
+		prompt := fmt.Sprintf("文件监控 #%d：%s 中出现了新文件 %s\n%s", w.ID, w.Path, path, w.Prompt)
+		reply, err := runWatchPrompt(ctx, a, w, prompt)
+		if err != nil {
+			logger.Warn("[Agent] File watch #%d failed on %s: %v", w.ID, path, err)
+			reply = fmt.Sprintf("⚠️ 处理失败: %v", err)
+		}
+		if err := a.persistStore.MarkFileWatchFired(w.ID, now); err != nil {
+			logger.Warn("[Agent] Failed to update file watch #%d: %v", w.ID, err)
+		}
+		if a.notifier == nil {
+			continue
+		}
+		text := fmt.Sprintf("📂 新文件 %s\n\n%s", filepath.Base(path), strings.TrimSpace(reply))
+		if err := a.notifier.NotifyChatUser(w.Platform, w.ChannelID, w.UserID, text); err != nil {
+			logger.Warn("[Agent] Failed to send file watch #%d: %v", w.ID, err)
+		}
+	}
+}
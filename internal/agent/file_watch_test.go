@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/persist"
+)
+
+func TestFileWatchRunsPromptInOriginatingChat(t *testing.T) {
+	var prompts []string
+	old := runWatchPrompt
+	runWatchPrompt = func(_ context.Context, _ *Agent, w persist.FileWatch, prompt string) (string, error) {
+		prompts = append(prompts, w.Platform+":"+w.ChannelID+" "+prompt)
+		return "三页报价单，总价 ¥12,000", nil
+	}
+	defer func() { runWatchPrompt = old }()
+
+	a, n := newFocusTestAgent(t)
+	dir := t.TempDir()
+	ctx := testTurn()
+	reply := a.executeFileWatch(ctx, map[string]any{"path": dir, "pattern": "*.pdf", "prompt": "总结这份 PDF"})
+	if !strings.Contains(reply, "已开始监控 #1") {
+		t.Fatalf("add = %q", reply)
+	}
+	if got := a.executeFileWatch(ctx, map[string]any{"path": filepath.Join(dir, "missing")}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("missing dir = %q", got)
+	}
+
+	pdf := filepath.Join(dir, "Quote.PDF")
+	os.WriteFile(pdf, []byte("%PDF"), 0o644)
+	a.handleWatchedFile(context.Background(), filepath.Join(dir, "notes.txt"), time.Now())
+	a.handleWatchedFile(context.Background(), pdf, time.Now())
+	if len(prompts) != 1 || !strings.HasPrefix(prompts[0], "telegram:c1 ") || !strings.Contains(prompts[0], pdf) || !strings.Contains(prompts[0], "总结这份 PDF") {
+		t.Fatalf("prompts = %q", prompts)
+	}
+	if sent := n.messages(); len(sent) != 1 || !strings.Contains(sent[0], "Quote.PDF") || !strings.Contains(sent[0], "总价") {
+		t.Fatalf("sent = %q", sent)
+	}
+	if list := a.executeFileWatch(ctx, map[string]any{"action": "list"}); !strings.Contains(list, "已处理 1 个文件") {
+		t.Fatalf("list = %q", list)
+	}
+
+	if got := a.executeFileWatch(ctx, map[string]any{"action": "remove", "id": 1.0}); !strings.Contains(got, "已停止监控 #1") {
+		t.Fatalf("remove = %q", got)
+	}
+	a.handleWatchedFile(context.Background(), pdf, time.Now())
+	if len(prompts) != 1 {
+		t.Fatal("removed watch still fired")
+	}
+}
+
+func TestFileWatchChecksAccessBeforeThePath(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	a.applySecurityConfig([]string{allowed}, false, nil, nil, nil, false)
+	ctx := testTurn()
+
+	existing := a.executeFileWatch(ctx, map[string]any{"path": outside})
+	missing := a.executeFileWatch(ctx, map[string]any{"path": filepath.Join(outside, "missing")})
+	if strings.Contains(existing, "not a directory") || strings.Contains(missing, "not a directory") || strings.Contains(existing, "已开始监控") {
+		t.Fatalf("outside allowed paths: existing = %q, missing = %q", existing, missing)
+	}
+	if got := a.executeFileWatch(ctx, map[string]any{"path": allowed}); !strings.Contains(got, "已开始监控") {
+		t.Fatalf("allowed dir = %q", got)
+	}
+}
@@ -0,0 +1,315 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	defaultFocusMinutes  = 25
+	defaultBreakMinutes  = 5
+	defaultFocusCategory = "专注"
+	maxFocusRounds       = 8
+	focusDNDTimeout      = 10 * time.Second
+)
+
+// focusUnit is one "minute" of a focus session; tests shorten it.
+var focusUnit = time.Minute
+
+// focusNotifier is a notifier that can hold a chat's proactive messages
+// while a focus session runs. RouterCronNotifier implements it.
+type focusNotifier interface {
+	Hold(platform, channelID string)
+	Release(platform, channelID string) []string
+	SendNow(platform, channelID, userID, message string) error
+}
+
+type focusSession struct {
+	task         string
+	category     string
+	platform     string
+	channelID    string
+	userID       string
+	minutes      int
+	breakMinutes int
+	rounds       int
+	dnd          bool
+
+	// guarded by Agent.focusMu
+	round     int
+	onBreak   bool
+	phaseEnds time.Time
+	focused   time.Duration
+	summary   string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// applyFocus installs the focus section.
+func (a *Agent) applyFocus(cfg config.FocusConfig) {
+	if runtime.GOOS == "darwin" {
+		if cfg.DNDOn == "" {
+			cfg.DNDOn = `shortcuts run "Do Not Disturb On"`
+		}
+		if cfg.DNDOff == "" {
+			cfg.DNDOff = `shortcuts run "Do Not Disturb Off"`
+		}
+	}
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.focusCfg = cfg
+}
+
+func (a *Agent) focusConfigSnapshot() config.FocusConfig {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.focusCfg
+}
+
+func (a *Agent) executeFocusSession(ctx context.Context, args map[string]any) string {
+	key := currentConversationKey(ctx)
+	switch strings.ToLower(strings.TrimSpace(getString(args, "action"))) {
+	case "", "start":
+		return a.startFocusSession(key, turnMessage(ctx), args)
+	case "stop", "cancel":
+		return a.stopFocusSession(key)
+	case "status":
+		a.focusMu.Lock()
+		defer a.focusMu.Unlock()
+		s := a.focusSessions[key]
+		if s == nil {
+			return "当前没有进行中的专注"
+		}
+		return s.status(time.Now())
+	}
+	return "Error: action must be start, stop or status"
+}
+
+func (a *Agent) startFocusSession(key string, msg router.Message, args map[string]any) string {
+	if msg.Platform == "" || msg.ChannelID == "" {
+		return "Error: focus sessions need a chat to send break reminders to"
+	}
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	cfg := a.focusConfigSnapshot()
+	s := &focusSession{
+		task:         strings.TrimSpace(getString(args, "task")),
+		category:     strings.TrimSpace(getString(args, "category")),
+		platform:     msg.Platform,
+		channelID:    msg.ChannelID,
+		userID:       timeUserID(msg),
+		minutes:      positiveInt(args["minutes"], cfg.Minutes, defaultFocusMinutes),
+		breakMinutes: positiveInt(args["break_minutes"], cfg.BreakMinutes, defaultBreakMinutes),
+		rounds:       min(positiveInt(args["rounds"], 1, 1), maxFocusRounds),
+		round:        1,
+		done:         make(chan struct{}),
+	}
+	if s.task == "" {
+		s.task = "专注"
+	}
+	if s.category == "" {
+		s.category = defaultFocusCategory
+	}
+	s.phaseEnds = time.Now().Add(time.Duration(s.minutes) * focusUnit)
+
+	a.focusMu.Lock()
+	if existing := a.focusSessions[key]; existing != nil {
+		status := existing.status(time.Now())
+		a.focusMu.Unlock()
+		return "Error: a focus session is already running. " + status
+	}
+	if a.focusSessions == nil {
+		a.focusSessions = make(map[string]*focusSession)
+	}
+	a.focusSessions[key] = s
+	a.focusMu.Unlock()
+
+	var notes []string
+	if fn, ok := a.notifier.(focusNotifier); ok {
+		fn.Hold(s.platform, s.channelID)
+		notes = append(notes, "定时任务和提醒的主动消息会在结束后汇总发送")
+	}
+	if dnd, _ := args["dnd"].(bool); dnd {
+		if cfg.DNDOn == "" {
+			notes = append(notes, "勿扰模式未开启：未配置 focus.dnd_on")
+		} else if err := runFocusDND(cfg.DNDOn); err != nil {
+			logger.Warn("[Agent] Failed to enable Do Not Disturb: %v", err)
+			notes = append(notes, fmt.Sprintf("勿扰模式开启失败: %v", err))
+		} else {
+			s.dnd = true
+			notes = append(notes, "已开启勿扰模式")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	first, err := a.startTimer(s.userID, s.task, s.category, time.Now())
+	if err != nil {
+		logger.Warn("[Agent] Failed to log focus session: %v", err)
+	}
+	go a.runFocusSession(ctx, key, s)
+
+	reply := fmt.Sprintf("🍅 开始专注：%s，%d 分钟", s.task, s.minutes)
+	if s.rounds > 1 {
+		reply += fmt.Sprintf(" × %d 轮，每轮间休息 %d 分钟", s.rounds, s.breakMinutes)
+	}
+	if i := strings.Index(first, "\n"); i >= 0 {
+		notes = append(notes, strings.TrimSpace(first[i:]))
+	}
+	if len(notes) > 0 {
+		reply += "\n" + strings.Join(notes, "\n")
+	}
+	return reply
+}
+
+func (a *Agent) stopFocusSession(key string) string {
+	a.focusMu.Lock()
+	s := a.focusSessions[key]
+	a.focusMu.Unlock()
+	if s == nil {
+		return "当前没有进行中的专注"
+	}
+	s.cancel()
+	<-s.done
+	return s.summary
+}
+
+// runFocusSession alternates focus blocks and breaks, logging each block as
+// a time entry, then lifts the mute and reports what happened.
+func (a *Agent) runFocusSession(ctx context.Context, key string, s *focusSession) {
+	defer close(s.done)
+	stopped := false
+	for round := 1; round <= s.rounds && !stopped; round++ {
+		if round > 1 {
+			if _, err := a.startTimer(s.userID, s.task, s.category, time.Now()); err != nil {
+				logger.Warn("[Agent] Failed to log focus session: %v", err)
+			}
+		}
+		start := time.Now()
+		a.setFocusPhase(s, round, false, start.Add(time.Duration(s.minutes)*focusUnit))
+		stopped = !sleepCtx(ctx, time.Duration(s.minutes)*focusUnit)
+		if _, err := a.stopTimer(s.userID, time.Now()); err != nil {
+			logger.Warn("[Agent] Failed to log focus session: %v", err)
+		}
+		a.focusMu.Lock()
+		s.focused += time.Since(start)
+		a.focusMu.Unlock()
+
+		if stopped || round == s.rounds {
+			break
+		}
+		a.setFocusPhase(s, round, true, time.Now().Add(time.Duration(s.breakMinutes)*focusUnit))
+		a.sendFocusNotice(s, fmt.Sprintf("☕ 第 %d/%d 轮专注结束，休息 %d 分钟吧", round, s.rounds, s.breakMinutes))
+		if stopped = !sleepCtx(ctx, time.Duration(s.breakMinutes)*focusUnit); !stopped {
+			a.sendFocusNotice(s, fmt.Sprintf("🍅 休息结束，开始第 %d/%d 轮：%s", round+1, s.rounds, s.task))
+		}
+	}
+
+	a.focusMu.Lock()
+	delete(a.focusSessions, key)
+	focused := s.focused
+	a.focusMu.Unlock()
+
+	var sb strings.Builder
+	if stopped {
+		fmt.Fprintf(&sb, "⏹ 专注已结束：%s，共专注 %s", s.task, formatTimeSpent(focused))
+	} else {
+		fmt.Fprintf(&sb, "✅ 专注完成：%s，共专注 %s，已记入计时（%s）", s.task, formatTimeSpent(focused), s.category)
+	}
+	if s.dnd {
+		if err := runFocusDND(a.focusConfigSnapshot().DNDOff); err != nil {
+			logger.Warn("[Agent] Failed to disable Do Not Disturb: %v", err)
+			sb.WriteString("\n勿扰模式关闭失败，请手动关闭")
+		}
+	}
+	if fn, ok := a.notifier.(focusNotifier); ok {
+		if held := fn.Release(s.platform, s.channelID); len(held) > 0 {
+			fmt.Fprintf(&sb, "\n\n📬 专注期间收到 %d 条通知：", len(held))
+			for _, m := range held {
+				sb.WriteString("\n- " + feedbackExcerpt(strings.ReplaceAll(m, "\n", " "), 200))
+			}
+		}
+	}
+	s.summary = sb.String()
+	if !stopped {
+		a.sendFocusNotice(s, s.summary)
+	}
+}
+
+func (a *Agent) setFocusPhase(s *focusSession, round int, onBreak bool, ends time.Time) {
+	a.focusMu.Lock()
+	defer a.focusMu.Unlock()
+	s.round, s.onBreak, s.phaseEnds = round, onBreak, ends
+}
+
+// sendFocusNotice delivers a session's own reminders, which are not held.
+func (a *Agent) sendFocusNotice(s *focusSession, text string) {
+	var err error
+	switch n := a.notifier.(type) {
+	case focusNotifier:
+		err = n.SendNow(s.platform, s.channelID, s.userID, text)
+	case nil:
+		logger.Info("[Agent] %s", text)
+	default:
+		err = n.NotifyChatUser(s.platform, s.channelID, s.userID, text)
+	}
+	if err != nil {
+		logger.Warn("[Agent] Failed to send focus reminder: %v", err)
+	}
+}
+
+// status describes the session; the caller holds Agent.focusMu.
+func (s *focusSession) status(now time.Time) string {
+	phase := "专注中"
+	if s.onBreak {
+		phase = "休息中"
+	}
+	return fmt.Sprintf("🍅 %s：%s，第 %d/%d 轮，本阶段还剩 %s", phase, s.task, s.round, s.rounds, formatTimeSpent(s.phaseEnds.Sub(now)))
+}
+
+func runFocusDND(command string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), focusDNDTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// sleepCtx waits for d and reports false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// positiveInt reads a positive number argument, falling back to configured
+// and then to def.
+func positiveInt(v any, configured, def int) int {
+	if n, ok := v.(float64); ok && n >= 1 {
+		return int(n)
+	}
+	if configured > 0 {
+		return configured
+	}
+	return def
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+type fakeFocusNotifier struct {
+	mu   sync.Mutex
+	held map[string][]string
+	sent []string
+}
+
+func (n *fakeFocusNotifier) NotifyChat(message string) error { return nil }
+
+func (n *fakeFocusNotifier) NotifyChatUser(platform, channelID, userID, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if held, ok := n.held[platform+":"+channelID]; ok {
+		n.held[platform+":"+channelID] = append(held, message)
+		return nil
+	}
+	n.sent = append(n.sent, message)
+	return nil
+}
+
+func (n *fakeFocusNotifier) TargetAvailable(platform, channelID, userID string) error { return nil }
+
+func (n *fakeFocusNotifier) SendNow(platform, channelID, userID, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, message)
+	return nil
+}
+
+func (n *fakeFocusNotifier) Hold(platform, channelID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.held[platform+":"+channelID] = []string{}
+}
+
+func (n *fakeFocusNotifier) Release(platform, channelID string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	held := n.held[platform+":"+channelID]
+	delete(n.held, platform+":"+channelID)
+	return held
+}
+
+func (n *fakeFocusNotifier) messages() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.sent...)
+}
+
+func newFocusTestAgent(t *testing.T) (*Agent, *fakeFocusNotifier) {
+	t.Helper()
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	n := &fakeFocusNotifier{held: map[string][]string{}}
+	return &Agent{persistStore: store, notifier: n}, n
+}
+
+// testTurn is the turn of a message from the chat newFocusTestAgent's
+// notifier delivers to.
+func testTurn() context.Context {
+	return withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1"})
+}
+
+func TestFocusSessionRunsRoundsAndHoldsNotifications(t *testing.T) {
+	old := focusUnit
+	focusUnit = 20 * time.Millisecond
+	defer func() { focusUnit = old }()
+
+	a, n := newFocusTestAgent(t)
+	dir := t.TempDir()
+	a.applyFocus(config.FocusConfig{
+		DNDOn:  "touch " + filepath.Join(dir, "on"),
+		DNDOff: "touch " + filepath.Join(dir, "off"),
+	})
+
+	ctx := testTurn()
+	reply := a.executeFocusSession(ctx, map[string]any{"task": "写报告", "category": "写作", "minutes": 2.0, "break_minutes": 1.0, "rounds": 2.0, "dnd": true})
+	if !strings.Contains(reply, "开始专注：写报告") || !strings.Contains(reply, "已开启勿扰模式") {
+		t.Fatalf("start reply: %q", reply)
+	}
+	if got := a.executeFocusSession(ctx, map[string]any{"task": "别的"}); !strings.Contains(got, "already running") {
+		t.Fatalf("second session started: %q", got)
+	}
+	n.NotifyChatUser("telegram", "c1", "u1", "⏰ 喝水")
+
+	key := currentConversationKey(ctx)
+	a.focusMu.Lock()
+	s := a.focusSessions[key]
+	a.focusMu.Unlock()
+	<-s.done
+
+	msgs := n.messages()
+	if len(msgs) != 3 || !strings.Contains(msgs[0], "休息 1 分钟") || !strings.Contains(msgs[1], "开始第 2/2 轮") {
+		t.Fatalf("messages = %q", msgs)
+	}
+	if !strings.Contains(msgs[2], "专注完成：写报告") || !strings.Contains(msgs[2], "收到 1 条通知") || !strings.Contains(msgs[2], "喝水") {
+		t.Fatalf("summary = %q", msgs[2])
+	}
+	for _, f := range []string{"on", "off"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Fatalf("dnd %s command did not run: %v", f, err)
+		}
+	}
+
+	entries, err := a.persistStore.TimeEntries("u1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Category != "写作" || entries[1].Running() {
+		t.Fatalf("time entries = %+v", entries)
+	}
+	if n.NotifyChatUser("telegram", "c1", "u1", "after"); len(n.messages()) != 4 {
+		t.Fatal("notifications still held after the session")
+	}
+}
+
+func TestFocusSessionStop(t *testing.T) {
+	a, n := newFocusTestAgent(t)
+	ctx := testTurn()
+	a.executeFocusSession(ctx, map[string]any{"task": "读论文"})
+	if got := a.executeFocusSession(ctx, map[string]any{"action": "status"}); !strings.Contains(got, "专注中：读论文，第 1/1 轮") {
+		t.Fatalf("status = %q", got)
+	}
+	n.NotifyChatUser("telegram", "c1", "u1", "日报已生成")
+
+	got := a.executeFocusSession(ctx, map[string]any{"action": "stop"})
+	if !strings.Contains(got, "专注已结束：读论文") || !strings.Contains(got, "日报已生成") {
+		t.Fatalf("stop = %q", got)
+	}
+	if len(n.messages()) != 0 {
+		t.Fatalf("stop also pushed messages: %q", n.messages())
+	}
+	if running, _ := a.persistStore.RunningTimeEntry("u1"); running != nil {
+		t.Fatalf("timer still running: %+v", running)
+	}
+	if got := a.executeFocusSession(ctx, map[string]any{"action": "stop"}); got != "当前没有进行中的专注" {
+		t.Fatalf("second stop = %q", got)
+	}
+}
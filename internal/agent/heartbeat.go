@@ -1,13 +1,18 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/fswatch"
 	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/router"
 	"gopkg.in/yaml.v3"
 )
@@ -41,33 +46,70 @@ func (a *Agent) ensureHeartbeatJobsForConversation(msg router.Message) {
 		logger.Warn("[HEARTBEAT] Failed to load HEARTBEAT.md: %v", err)
 		return
 	}
-	if spec == nil || !spec.Enabled || len(spec.Tasks) == 0 {
-		return
+	a.syncHeartbeatJobs(spec, msg)
+}
+
+// syncHeartbeatJobs makes the heartbeat jobs of msg's conversation match
+// spec: missing tasks are added, changed ones replaced and removed ones
+// (or all, when spec is nil or disabled) deleted.
+func (a *Agent) syncHeartbeatJobs(spec *heartbeatSpec, msg router.Message) {
+	want := map[string]heartbeatTask{}
+	var names []string
+	if spec != nil && spec.Enabled {
+		for idx, task := range spec.Tasks {
+			task.Name = strings.TrimSpace(task.Name)
+			if task.Name == "" {
+				task.Name = fmt.Sprintf("task-%d", idx+1)
+			}
+			task.Schedule = strings.TrimSpace(task.Schedule)
+			task.Prompt = strings.TrimSpace(task.Prompt)
+			if task.Schedule == "" || task.Prompt == "" {
+				continue
+			}
+			jobName := heartbeatJobName(msg.UserID, task.Name)
+			if _, dup := want[jobName]; !dup {
+				names = append(names, jobName)
+			}
+			want[jobName] = task
+		}
 	}
 
-	existing := a.cronScheduler.ListJobsByTag(heartbeatJobTag)
-	for idx, task := range spec.Tasks {
-		name := strings.TrimSpace(task.Name)
-		if name == "" {
-			name = fmt.Sprintf("task-%d", idx+1)
+	a.heartbeatMu.Lock()
+	defer a.heartbeatMu.Unlock()
+
+	platform, channelID, userID := resolveHeartbeatTarget(heartbeatTask{}, msg)
+	prefix := heartbeatJobPrefix(msg.UserID)
+	for _, job := range a.cronScheduler.ListJobsByTag(heartbeatJobTag) {
+		if !strings.HasPrefix(job.Name, prefix) || job.Platform != platform || job.ChannelID != channelID || job.UserID != userID {
+			continue
 		}
-		schedule := strings.TrimSpace(task.Schedule)
-		prompt := strings.TrimSpace(task.Prompt)
-		if schedule == "" || prompt == "" {
+		task, ok := want[job.Name]
+		if ok && job.Schedule == cronpkg.NormalizeSchedule(task.Schedule) && job.Prompt == decorateHeartbeatPrompt(task.Prompt, task.Notify) {
+			delete(want, job.Name)
 			continue
 		}
-
-		jobName := heartbeatJobName(msg.UserID, name)
-		platform, channelID, userID := resolveHeartbeatTarget(task, msg)
-		if heartbeatJobExists(existing, jobName, platform, channelID, userID) {
+		if err := a.cronScheduler.RemoveJob(job.ID); err != nil {
+			logger.Warn("[HEARTBEAT] Failed to remove heartbeat job %s: %v", job.Name, err)
+			delete(want, job.Name)
 			continue
 		}
+		if ok {
+			logger.Info("[HEARTBEAT] Heartbeat job changed: %s", job.Name)
+		} else {
+			logger.Info("[HEARTBEAT] Heartbeat job removed: %s", job.Name)
+		}
+	}
 
-		_, err := a.cronScheduler.AddJobWithPromptAndTag(
+	for _, jobName := range names {
+		task, ok := want[jobName]
+		if !ok {
+			continue
+		}
+		job, err := a.cronScheduler.AddJobWithPromptAndTag(
 			jobName,
 			heartbeatJobTag,
-			schedule,
-			decorateHeartbeatPrompt(prompt, task.Notify),
+			task.Schedule,
+			decorateHeartbeatPrompt(task.Prompt, task.Notify),
 			platform,
 			channelID,
 			userID,
@@ -76,17 +118,17 @@ func (a *Agent) ensureHeartbeatJobsForConversation(msg router.Message) {
 			logger.Warn("[HEARTBEAT] Failed to create heartbeat job %s: %v", jobName, err)
 			continue
 		}
-		logger.Info("[HEARTBEAT] Heartbeat job created: %s (%s)", jobName, schedule)
-	}
-}
-
-func heartbeatJobExists(jobs []*cronpkg.Job, name, platform, channelID, userID string) bool {
-	for _, j := range jobs {
-		if j.Name == name && j.Platform == platform && j.ChannelID == channelID && j.UserID == userID {
-			return true
+		if err := a.cronScheduler.SetProvenance(job.ID, provenance.Record{
+			Origin:       provenance.OriginHeartbeat,
+			Actor:        "HEARTBEAT.md",
+			MessageID:    msg.ID,
+			Conversation: strings.Join([]string{msg.Platform, msg.ChannelID, msg.UserID}, ":"),
+			Reason:       "task " + task.Name,
+		}); err != nil {
+			logger.Warn("[HEARTBEAT] Failed to record provenance for %s: %v", jobName, err)
 		}
+		logger.Info("[HEARTBEAT] Heartbeat job created: %s (%s)", jobName, task.Schedule)
 	}
-	return false
 }
 
 func resolveHeartbeatTarget(task heartbeatTask, msg router.Message) (platform, channelID, userID string) {
@@ -112,7 +154,12 @@ func decorateHeartbeatPrompt(prompt, notify string) string {
 }
 
 func heartbeatJobName(userID, taskName string) string {
-	return "heartbeat:" + sanitizeHeartbeatToken(userID) + ":" + sanitizeHeartbeatToken(taskName)
+	return heartbeatJobPrefix(userID) + sanitizeHeartbeatToken(taskName)
+}
+
+// heartbeatJobPrefix starts the names of all of a user's heartbeat jobs.
+func heartbeatJobPrefix(userID string) string {
+	return "heartbeat:" + sanitizeHeartbeatToken(userID) + ":"
 }
 
 func sanitizeHeartbeatToken(s string) string {
@@ -199,10 +246,96 @@ func splitMarkdownFrontMatter(content string) (frontMatter string, body string)
 	if !strings.HasPrefix(normalized, "---\n") {
 		return "", strings.TrimSpace(content)
 	}
-	rest := normalized[len("---\n"):]
+	// The closing --- may end the file.
+	rest := normalized[len("---\n"):] + "\n"
 	idx := strings.Index(rest, "\n---\n")
 	if idx < 0 {
 		return "", strings.TrimSpace(content)
 	}
 	return strings.TrimSpace(rest[:idx]), strings.TrimSpace(rest[idx+len("\n---\n"):])
 }
+
+// heartbeatSpecSettle is how long HEARTBEAT.md must stay unchanged before
+// an edit is applied, so a half-saved file is not.
+const heartbeatSpecSettle = time.Second
+
+// StartHeartbeat runs HEARTBEAT.md in this process: at startup it brings
+// the heartbeat jobs of every known conversation in line with the file, and
+// then again whenever the file changes, until ctx ends. Conversations that
+// have not talked to coco yet get their jobs with their first message.
+func (a *Agent) StartHeartbeat(ctx context.Context) {
+	if a.cronScheduler == nil {
+		return
+	}
+	a.reloadHeartbeats()
+
+	fw, err := fswatch.New(heartbeatSpecSettle)
+	if err != nil {
+		logger.Warn("[HEARTBEAT] Not watching HEARTBEAT.md: %v", err)
+		return
+	}
+	if err := fw.Add(getWorkspaceDir()); err != nil {
+		logger.Warn("[HEARTBEAT] Not watching HEARTBEAT.md: %v", err)
+		return
+	}
+	go fw.Run(ctx, func(path string) {
+		if filepath.Base(path) == "HEARTBEAT.md" {
+			logger.Info("[HEARTBEAT] HEARTBEAT.md changed, syncing heartbeat jobs")
+			a.reloadHeartbeats()
+		}
+	}, func(err error) {
+		logger.Warn("[HEARTBEAT] Watcher: %v", err)
+	})
+}
+
+// reloadHeartbeats syncs the heartbeat jobs of every known conversation
+// with HEARTBEAT.md. A file that does not parse leaves the jobs alone.
+func (a *Agent) reloadHeartbeats() {
+	spec, err := loadHeartbeatSpec()
+	if err != nil {
+		logger.Warn("[HEARTBEAT] Failed to load HEARTBEAT.md: %v", err)
+		return
+	}
+	for _, target := range a.heartbeatTargets() {
+		a.syncHeartbeatJobs(spec, target)
+	}
+}
+
+// heartbeatTargets returns the conversations heartbeat checks run in: those
+// that already have heartbeat jobs, and the most recently active stored
+// conversation, so a restarted relay resumes its checks before anyone
+// writes.
+func (a *Agent) heartbeatTargets() []router.Message {
+	seen := map[string]bool{}
+	var targets []router.Message
+	add := func(platform, channelID, userID string) {
+		if platform == "" || channelID == "" || userID == "" {
+			return
+		}
+		key := ConversationKey(platform, channelID, userID)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		targets = append(targets, router.Message{Platform: platform, ChannelID: channelID, UserID: userID})
+	}
+	for _, job := range a.cronScheduler.ListJobsByTag(heartbeatJobTag) {
+		add(job.Platform, job.ChannelID, job.UserID)
+	}
+	if a.persistStore != nil {
+		convs, err := a.persistStore.LoadAllActiveConversations()
+		if err != nil {
+			logger.Warn("[HEARTBEAT] Failed to load conversations: %v", err)
+		}
+		var latest *persist.Conversation
+		for _, c := range convs {
+			if latest == nil || c.UpdatedAt.After(latest.UpdatedAt) {
+				latest = c
+			}
+		}
+		if latest != nil {
+			add(latest.Platform, latest.ChannelID, latest.UserID)
+		}
+	}
+	return targets
+}
@@ -3,8 +3,11 @@ package agent
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/persist"
 	"github.com/kayz/coco/internal/router"
 )
 
@@ -87,3 +90,61 @@ func routerMessageForHeartbeatTest() router.Message {
 		UserID:    "u1",
 	}
 }
+
+func TestHeartbeatEngineFollowsHeartbeatMD(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tmp, "HEARTBEAT.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := persist.NewStore(filepath.Join(tmp, "coco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	cronStore, err := cronpkg.NewStore(filepath.Join(tmp, "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cronStore.Close() })
+	a := &Agent{persistStore: store, cronScheduler: cronpkg.NewScheduler(cronStore, nil, nil, nil)}
+	jobs := func() []string {
+		var out []string
+		for _, j := range a.cronScheduler.ListJobsByTag(heartbeatJobTag) {
+			out = append(out, j.Name+" "+j.Schedule+" "+j.Platform+":"+j.ChannelID)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	// A relay that has talked to someone before gets its checks at startup.
+	if _, err := store.GetOrCreateConversation("wecom", "c1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	write("---\ninterval: 6h\nchecks:\n  - name: memory\n    prompt: 检查记忆\n    notify: on_change\n---\n")
+	a.reloadHeartbeats()
+	if got := jobs(); len(got) != 1 || got[0] != "heartbeat:u1:memory @every 6h wecom:c1" {
+		t.Fatalf("after start = %v", got)
+	}
+
+	write("---\ninterval: 2h\nchecks:\n  - name: memory\n    prompt: 检查记忆\n  - name: inbox\n    prompt: 看看收件箱\n---\n")
+	a.reloadHeartbeats()
+	if got := jobs(); len(got) != 2 || got[0] != "heartbeat:u1:inbox @every 2h wecom:c1" || got[1] != "heartbeat:u1:memory @every 2h wecom:c1" {
+		t.Fatalf("after edit = %v", got)
+	}
+
+	// A broken edit keeps the jobs; disabling removes them.
+	write("---\nchecks: [\n---\n")
+	a.reloadHeartbeats()
+	if got := jobs(); len(got) != 2 {
+		t.Fatalf("after broken edit = %v", got)
+	}
+	write("---\nenabled: false\n---\n")
+	a.reloadHeartbeats()
+	if got := jobs(); len(got) != 0 {
+		t.Fatalf("after disable = %v", got)
+	}
+}
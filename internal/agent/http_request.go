@@ -0,0 +1,347 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/secrets"
+	"github.com/kayz/coco/internal/security"
+)
+
+const (
+	defaultHTTPMaxBytes = 64 << 10 // response bytes shown by default
+	maxHTTPMaxBytes     = 1 << 20  // most response bytes a call may ask for
+	httpRequestTimeout  = 60 * time.Second
+)
+
+var httpRequestMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// httpProfile is a credential profile with its vault references expanded.
+type httpProfile struct {
+	name    string
+	baseURL string
+	headers map[string]string
+	query   map[string]string
+}
+
+// secretValues are the profile's credentials, which are redacted from
+// anything shown to the model.
+func (p *httpProfile) secretValues() []string {
+	var values []string
+	for _, v := range p.headers {
+		values = append(values, v, strings.TrimPrefix(v, "Bearer "))
+	}
+	for _, v := range p.query {
+		values = append(values, v, url.QueryEscape(v))
+	}
+	return values
+}
+
+// urlWithin reports whether rawURL is base or below it, so a profile for
+// https://api.example.com is not sent to https://api.example.com.evil.net.
+func urlWithin(base, rawURL string) bool {
+	if !strings.HasPrefix(rawURL, base) {
+		return false
+	}
+	rest := rawURL[len(base):]
+	return rest == "" || strings.HasSuffix(base, "/") || strings.ContainsAny(rest[:1], "/?#")
+}
+
+// resolveHTTPProfile picks the profile named name, or the one whose
+// base_url rawURL falls under. It returns nil when no profile applies.
+func resolveHTTPProfile(profiles []config.HTTPProfileConfig, name, rawURL string) (*config.HTTPProfileConfig, error) {
+	name = strings.TrimSpace(name)
+	if name != "" {
+		for i := range profiles {
+			if strings.EqualFold(profiles[i].Name, name) {
+				return &profiles[i], nil
+			}
+		}
+		names := make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			names = append(names, p.Name)
+		}
+		return nil, fmt.Errorf("unknown http profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	var best *config.HTTPProfileConfig
+	for i := range profiles {
+		base := strings.TrimSpace(profiles[i].BaseURL)
+		if base != "" && urlWithin(base, rawURL) && (best == nil || len(base) > len(strings.TrimSpace(best.BaseURL))) {
+			best = &profiles[i]
+		}
+	}
+	return best, nil
+}
+
+// expandHTTPProfile turns a configured profile into the headers and query
+// parameters to send, expanding {{secret:name}} references.
+func expandHTTPProfile(cfg *config.HTTPProfileConfig) (*httpProfile, error) {
+	p := &httpProfile{
+		name:    cfg.Name,
+		baseURL: strings.TrimSpace(cfg.BaseURL),
+		headers: make(map[string]string),
+		query:   make(map[string]string),
+	}
+	var vault *secrets.Vault
+	expand := func(v string) (string, error) {
+		if !secrets.HasRef(v) {
+			return v, nil
+		}
+		if vault == nil {
+			var err error
+			if vault, err = OpenSecretVault(); err != nil {
+				return "", err
+			}
+		}
+		return vault.Expand(v)
+	}
+	for k, v := range cfg.Headers {
+		value, err := expand(v)
+		if err != nil {
+			return nil, err
+		}
+		p.headers[k] = value
+	}
+	if cfg.Bearer != "" {
+		token, err := expand(cfg.Bearer)
+		if err != nil {
+			return nil, err
+		}
+		p.headers["Authorization"] = "Bearer " + token
+	}
+	for k, v := range cfg.Query {
+		value, err := expand(v)
+		if err != nil {
+			return nil, err
+		}
+		p.query[k] = value
+	}
+	return p, nil
+}
+
+func executeHTTPRequest(ctx context.Context, args map[string]any) string {
+	method := strings.ToUpper(strings.TrimSpace(getString(args, "method")))
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !httpRequestMethods[method] {
+		return fmt.Sprintf("Error: unsupported method %s", method)
+	}
+	rawURL := strings.TrimSpace(getString(args, "url"))
+	if rawURL == "" {
+		return listHTTPProfiles()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Sprintf("Error: load config: %v", err)
+	}
+	profileName := getString(args, "profile")
+	if profileName != "" && strings.HasPrefix(rawURL, "/") {
+		// A path is relative to the named profile's base_url.
+		if pc, err := resolveHTTPProfile(cfg.HTTPProfiles, profileName, ""); err == nil {
+			rawURL = strings.TrimRight(strings.TrimSpace(pc.BaseURL), "/") + rawURL
+		}
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	pc, err := resolveHTTPProfile(cfg.HTTPProfiles, profileName, rawURL)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	var profile *httpProfile
+	if pc != nil {
+		if !urlWithin(strings.TrimSpace(pc.BaseURL), rawURL) {
+			return fmt.Sprintf("Error: profile %s only sends requests under %s", pc.Name, pc.BaseURL)
+		}
+		if profile, err = expandHTTPProfile(pc); err != nil {
+			return fmt.Sprintf("Error: profile %s: %v", pc.Name, err)
+		}
+	} else if cfg.Security.EnableSSRFProtection {
+		// Profiles may point at the user's own services; other URLs may not.
+		if err := security.ValidateFetchURL(rawURL); err != nil {
+			return fmt.Sprintf("Error: url blocked by SSRF protection: %v", err)
+		}
+	}
+
+	req, err := buildHTTPRequest(ctx, method, rawURL, args, profile)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	client := &http.Client{
+		Timeout:       httpRequestTimeout,
+		CheckRedirect: httpRedirectPolicy(profile, cfg.Security.EnableSSRFProtection),
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "Error: " + redactHTTPProfile(err.Error(), profile)
+	}
+	defer resp.Body.Close()
+
+	maxBytes := defaultHTTPMaxBytes
+	if n, ok := args["max_bytes"].(float64); ok && n > 0 {
+		maxBytes = min(int(n), maxHTTPMaxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return fmt.Sprintf("Error: read response: %v", err)
+	}
+	return redactHTTPProfile(formatHTTPResponse(resp, body, maxBytes), profile)
+}
+
+// httpRedirectPolicy follows at most five redirects. Credentials stay with
+// their profile: a redirect elsewhere is returned to the model instead of
+// being followed. Without a profile, a redirect into the local network is
+// refused like the URL itself would be.
+func httpRedirectPolicy(profile *httpProfile, ssrfProtection bool) func(*http.Request, []*http.Request) error {
+	return func(next *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if profile != nil {
+			if !urlWithin(profile.baseURL, next.URL.String()) {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}
+		if ssrfProtection {
+			if err := security.ValidateFetchURL(next.URL.String()); err != nil {
+				return fmt.Errorf("redirect blocked by SSRF protection: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+func buildHTTPRequest(ctx context.Context, method, rawURL string, args map[string]any, profile *httpProfile) (*http.Request, error) {
+	var body io.Reader
+	contentType := ""
+	switch b := args["body"].(type) {
+	case nil:
+	case string:
+		if b != "" {
+			body = strings.NewReader(b)
+		}
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("encode body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Coco/1.0")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := args["headers"].(map[string]any); ok {
+		for k, v := range headers {
+			if s, ok := v.(string); ok {
+				req.Header.Set(k, s)
+			}
+		}
+	}
+	if s, ok := args["body"].(string); ok && req.Header.Get("Content-Type") == "" && json.Valid([]byte(s)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if profile != nil {
+		// Profile credentials win over headers the model passes.
+		for k, v := range profile.headers {
+			req.Header.Set(k, v)
+		}
+		if len(profile.query) > 0 {
+			q := req.URL.Query()
+			for k, v := range profile.query {
+				q.Set(k, v)
+			}
+			req.URL.RawQuery = q.Encode()
+		}
+	}
+	return req, nil
+}
+
+// formatHTTPResponse shows the status, a few useful headers and the body,
+// with JSON pretty-printed.
+func formatHTTPResponse(resp *http.Response, body []byte, maxBytes int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %s\n", resp.Status)
+	for _, h := range []string{"Content-Type", "Location", "Retry-After", "X-RateLimit-Remaining"} {
+		if v := resp.Header.Get(h); v != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", h, v)
+		}
+	}
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+	if len(body) == 0 {
+		return strings.TrimRight(sb.String(), "\n")
+	}
+	sb.WriteString("\n")
+	switch {
+	case !utf8.Valid(body) && !truncated:
+		fmt.Fprintf(&sb, "(binary body of %d bytes not shown)", len(body))
+		return sb.String()
+	case !truncated && (strings.Contains(resp.Header.Get("Content-Type"), "json") || json.Valid(body)):
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			sb.Write(pretty.Bytes())
+			return sb.String()
+		}
+	}
+	sb.Write(bytes.ToValidUTF8(body, []byte("�")))
+	if truncated {
+		fmt.Fprintf(&sb, "\n\n[truncated at %d bytes; pass max_bytes (up to %d) or narrow the request]", maxBytes, maxHTTPMaxBytes)
+	}
+	return sb.String()
+}
+
+func redactHTTPProfile(s string, profile *httpProfile) string {
+	if profile == nil {
+		return s
+	}
+	values := profile.secretValues()
+	// Longest first, so a token is not half-replaced by a shorter value.
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		if len(v) >= 4 {
+			s = strings.ReplaceAll(s, v, "[redacted]")
+		}
+	}
+	return s
+}
+
+func listHTTPProfiles() string {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Sprintf("Error: load config: %v", err)
+	}
+	if len(cfg.HTTPProfiles) == 0 {
+		return "No http profiles configured; requests go out without credentials (add http_profiles entries to .coco.yaml)"
+	}
+	var sb strings.Builder
+	sb.WriteString("Configured http profiles (credentials are added automatically to requests under base_url):\n")
+	for _, p := range cfg.HTTPProfiles {
+		fmt.Fprintf(&sb, "- %s: %s\n", p.Name, p.BaseURL)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
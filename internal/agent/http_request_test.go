@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestHTTPRequestProfileCredentials(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.URL.Query().Get("key") != "" {
+			t.Errorf("credentials leaked to another host: %v %s", r.Header, r.URL)
+		}
+	}))
+	defer other.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/me":
+			// Echo the credentials back, as some APIs do in errors.
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"auth":%q,"key":%q,"trace":%q}`, r.Header.Get("Authorization"), r.URL.Query().Get("key"), r.Header.Get("X-Trace"))
+		case "/v1/away":
+			http.Redirect(w, r, other.URL+"/steal", http.StatusFound)
+		case "/v1/big":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		}
+	}))
+	defer api.Close()
+	yaml := fmt.Sprintf("http_profiles:\n  - name: svc\n    base_url: %s/v1\n    bearer: tok-123456\n    query:\n      key: k-abcdef\n", api.URL)
+	if err := os.WriteFile(config.ConfigPath(), []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	got := executeHTTPRequest(ctx, map[string]any{"profile": "svc", "url": "/me", "headers": map[string]any{"X-Trace": "t1", "Authorization": "Bearer model-guess"}})
+	want := "HTTP 200 OK\nContent-Type: application/json\n\n{\n  \"auth\": \"[redacted]\",\n  \"key\": \"[redacted]\",\n  \"trace\": \"t1\"\n}"
+	if got != want {
+		t.Fatalf("profile request = %q", got)
+	}
+	// The profile is picked by URL too.
+	if got := executeHTTPRequest(ctx, map[string]any{"url": api.URL + "/v1/me"}); !strings.Contains(got, `"auth": "[redacted]"`) {
+		t.Fatalf("matched by url = %q", got)
+	}
+	if got := executeHTTPRequest(ctx, map[string]any{"url": api.URL + "/v1/away"}); !strings.HasPrefix(got, "HTTP 302 Found\n") || !strings.Contains(got, "Location: "+other.URL) {
+		t.Fatalf("redirect = %q", got)
+	}
+	if got := executeHTTPRequest(ctx, map[string]any{"profile": "svc", "url": other.URL + "/v1/me"}); !strings.Contains(got, "only sends requests under") {
+		t.Fatalf("profile for another host = %q", got)
+	}
+	if got := executeHTTPRequest(ctx, map[string]any{"url": api.URL + "/v1/big", "max_bytes": float64(10)}); !strings.HasSuffix(got, "xxxxxxxxxx\n\n[truncated at 10 bytes; pass max_bytes (up to 1048576) or narrow the request]") {
+		t.Fatalf("truncated = %q", got)
+	}
+	if got := executeHTTPRequest(ctx, map[string]any{}); !strings.Contains(got, "- svc: "+api.URL+"/v1") {
+		t.Fatalf("list = %q", got)
+	}
+}
+
+func TestHTTPRedirectPolicy(t *testing.T) {
+	redirect := func(policy func(*http.Request, []*http.Request) error, to string) error {
+		next, err := http.NewRequest(http.MethodGet, to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return policy(next, []*http.Request{next})
+	}
+	if err := redirect(httpRedirectPolicy(nil, true), "http://127.0.0.1:8080/admin"); err == nil || !strings.Contains(err.Error(), "SSRF") {
+		t.Fatalf("redirect into the local network = %v", err)
+	}
+	if err := redirect(httpRedirectPolicy(nil, true), "http://169.254.169.254/latest/meta-data"); err == nil {
+		t.Fatal("redirect to the metadata address was followed")
+	}
+	if err := redirect(httpRedirectPolicy(nil, false), "http://127.0.0.1:8080/admin"); err != nil {
+		t.Fatalf("redirect without SSRF protection = %v", err)
+	}
+	// A profile may live on the local network; its redirects stay under base_url.
+	profile := &httpProfile{baseURL: "http://127.0.0.1:8123/api"}
+	if err := redirect(httpRedirectPolicy(profile, true), "http://127.0.0.1:8123/api/states"); err != nil {
+		t.Fatalf("redirect within the profile = %v", err)
+	}
+	if err := redirect(httpRedirectPolicy(profile, true), "http://127.0.0.1:9000/"); err != http.ErrUseLastResponse {
+		t.Fatalf("redirect out of the profile = %v", err)
+	}
+}
+
+func TestURLWithin(t *testing.T) {
+	for _, tc := range []struct {
+		base, url string
+		want      bool
+	}{
+		{"https://api.example.com", "https://api.example.com/v1", true},
+		{"https://api.example.com", "https://api.example.com?x=1", true},
+		{"https://api.example.com", "https://api.example.com.evil.net/", false},
+		{"https://api.example.com/v1/", "https://api.example.com/v1/items", true},
+		{"https://api.example.com/v1", "https://api.example.com/v10", false},
+	} {
+		if got := urlWithin(tc.base, tc.url); got != tc.want {
+			t.Errorf("urlWithin(%q, %q) = %v", tc.base, tc.url, got)
+		}
+	}
+}
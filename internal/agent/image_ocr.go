@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/ocr"
+	"github.com/kayz/coco/internal/router"
+)
+
+const ocrTimeout = 30 * time.Second // per image
+
+// applyOCR installs the ocr section. Without a usable backend image_ocr
+// reports the problem and attachments are passed on unread.
+func (a *Agent) applyOCR(cfg config.OCRConfig) {
+	var recognizer *ocr.Recognizer
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if provider != "off" {
+		r, err := ocr.NewRecognizer(ocr.Config{
+			Provider:  provider,
+			Languages: cfg.Languages,
+			Shortcut:  cfg.Shortcut,
+			APIKey:    cfg.APIKey,
+			BaseURL:   cfg.BaseURL,
+			Model:     cfg.Model,
+		})
+		switch {
+		case err == nil:
+			recognizer = r
+		case provider == "" || provider == "auto":
+			logger.Debug("[Agent] OCR unavailable: %v", err)
+		default:
+			logger.Warn("[Agent] OCR disabled: %v", err)
+		}
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.ocr = recognizer
+	a.ocrAttachments = !strings.EqualFold(strings.TrimSpace(cfg.Attachments), "off")
+}
+
+func (a *Agent) ocrSnapshot() (*ocr.Recognizer, bool) {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.ocr, a.ocrAttachments
+}
+
+// modelHasVision reports whether the current model reads images itself.
+func (a *Agent) modelHasVision() bool {
+	if a.modelRouter == nil {
+		return false
+	}
+	model := a.modelRouter.GetCurrentModel()
+	return model != nil && model.HasSkill("multimodal")
+}
+
+// readImageAttachments saves incoming images where image_ocr can reach them
+// and, when the model has no vision, adds their text to the message.
+func (a *Agent) readImageAttachments(ctx context.Context, msg router.Message) router.Message {
+	recognizer, auto := a.ocrSnapshot()
+	readText := auto && recognizer != nil && !a.modelHasVision()
+
+	var notes []string
+	for _, att := range msg.Attachments {
+		if att.Type != "image" || len(att.Data) == 0 {
+			continue
+		}
+		path, err := saveAttachment("coco-images", fmt.Sprintf("image-%d.%s", time.Now().UnixNano(), imageExtension(att.MIMEType)), att.Data)
+		if err != nil {
+			logger.Warn("[Agent] Failed to save image attachment: %v", err)
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("[图片: %s]", path))
+		if !readText {
+			continue
+		}
+
+		ocrCtx, cancel := context.WithTimeout(ctx, ocrTimeout)
+		text, err := recognizer.Recognize(ocrCtx, path)
+		cancel()
+		switch {
+		case err != nil:
+			logger.Warn("[Agent] OCR of image attachment failed: %v", err)
+			notes = append(notes, "[图片文字识别失败]")
+		case text == "":
+			notes = append(notes, "[图片中未识别到文字]")
+		default:
+			notes = append(notes, "[图片文字]\n"+text)
+		}
+	}
+	if len(notes) > 0 {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n" + strings.Join(notes, "\n"))
+	}
+	return msg
+}
+
+func (a *Agent) executeImageOCR(ctx context.Context, args map[string]any) string {
+	path := normalizePath(getString(args, "path"))
+	if path == "" {
+		return "Error: path is required"
+	}
+	recognizer, _ := a.ocrSnapshot()
+	if recognizer == nil {
+		return "Error: no OCR backend available (install tesseract, or set ocr.provider to vision or cloud in .coco.yaml)"
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ocrTimeout)
+	defer cancel()
+	text, err := recognizer.Recognize(ctx, path)
+	if err != nil {
+		return fmt.Sprintf("Error recognizing text: %v", err)
+	}
+	if text == "" {
+		return "No text found in the image."
+	}
+	return fmt.Sprintf("Text in %s (%s):\n%s", filepath.Base(path), recognizer.ProviderName(), text)
+}
+
+func imageExtension(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "image/png":
+		return "png"
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return "webp"
+	default:
+		return "jpg"
+	}
+}
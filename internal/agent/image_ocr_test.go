@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestImageAttachmentsAreReadForModelsWithoutVision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"content":"WiFi: coco-guest\n密码: 12345678"}}]}`))
+	}))
+	defer srv.Close()
+
+	a := &Agent{}
+	a.applyOCR(config.OCRConfig{Provider: "cloud", APIKey: "key", BaseURL: srv.URL})
+
+	msg := a.readImageAttachments(context.Background(), router.Message{
+		Text:        "帮我连上这个",
+		Attachments: []router.Attachment{{Type: "image", Data: []byte("jpeg"), MIMEType: "image/jpeg"}},
+	})
+	if !strings.HasPrefix(msg.Text, "帮我连上这个\n\n[图片: ") || !strings.Contains(msg.Text, "[图片文字]\nWiFi: coco-guest") {
+		t.Fatalf("text = %q", msg.Text)
+	}
+	path := strings.SplitN(strings.SplitN(msg.Text, "[图片: ", 2)[1], "]", 2)[0]
+	defer os.Remove(path)
+	if got := a.executeImageOCR(context.Background(), map[string]any{"path": path}); !strings.Contains(got, "密码: 12345678") {
+		t.Fatalf("image_ocr = %q", got)
+	}
+
+	a.applyOCR(config.OCRConfig{Provider: "cloud", APIKey: "key", BaseURL: srv.URL, Attachments: "off"})
+	msg = a.readImageAttachments(context.Background(), router.Message{
+		Attachments: []router.Attachment{{Type: "image", Data: []byte("jpeg")}},
+	})
+	if strings.Contains(msg.Text, "图片文字") || !strings.HasPrefix(msg.Text, "[图片: ") {
+		t.Fatalf("attachments: off should only save the image, got %q", msg.Text)
+	}
+	os.Remove(strings.TrimSuffix(strings.TrimPrefix(msg.Text, "[图片: "), "]"))
+}
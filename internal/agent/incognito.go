@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleIncognitoCommand turns incognito on or off for the conversation.
+// While it is on, messages are kept in memory for the conversation's context
+// only: nothing is written to the database, RAG memory or run traces, and
+// preferences are not learned from it.
+func (a *Agent) handleIncognitoCommand(convKey, text string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/incognito", "无痕模式":
+		if a.isIncognito(convKey) {
+			return "无痕模式已开启。发送 /incognito off 退出。", true
+		}
+		return "无痕模式未开启。发送 /incognito on 开启后，本会话的消息不会被保存或记住。", true
+	case "/incognito on", "无痕模式开":
+		if a.memory == nil {
+			return "当前无法开启无痕模式。", true
+		}
+		a.memory.SetIncognito(convKey, true)
+		return "🕶️ 已开启无痕模式：接下来的消息不会写入数据库和长期记忆，也不会用于学习偏好。发送 /incognito off 退出。", true
+	case "/incognito off", "无痕模式关":
+		if !a.isIncognito(convKey) {
+			return "无痕模式未开启。", true
+		}
+		dropped := a.memory.SetIncognito(convKey, false)
+		if dropped == 0 {
+			return "已退出无痕模式。", true
+		}
+		return fmt.Sprintf("已退出无痕模式，无痕期间的 %d 条消息已从上下文中清除。", dropped), true
+	}
+	return "", false
+}
+
+// isIncognito reports whether the conversation is in incognito.
+func (a *Agent) isIncognito(convKey string) bool {
+	return a.memory != nil && a.memory.IsIncognito(convKey)
+}
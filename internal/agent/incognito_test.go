@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncognitoKeepsConversationOutOfStore(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.memory = NewMemory(a.persistStore, 0)
+	key := ConversationKey("telegram", "c1", "u1") // the chat of testTurn
+	stored := func() int {
+		n, err := a.persistStore.CountBefore("messages", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	a.memory.AddExchange(key, Message{Role: "user", Content: "你好"}, Message{Role: "assistant", Content: "你好！"})
+	if reply, _ := a.handleIncognitoCommand(key, "/incognito on"); !strings.Contains(reply, "已开启") {
+		t.Fatalf("on reply = %q", reply)
+	}
+	a.memory.AddExchange(key, Message{Role: "user", Content: "我的体检报告"}, Message{Role: "assistant", Content: "收到"})
+	if n := stored(); n != 2 {
+		t.Fatalf("stored %d messages, want only the 2 before incognito", n)
+	}
+	if h := a.memory.GetHistory(key); len(h) != 4 {
+		t.Fatalf("history = %d, want the incognito exchange kept for context", len(h))
+	}
+	if got := a.runTool(testTurn(), "memory_write", json.RawMessage(`{"content":"体检"}`)); !strings.Contains(got, "incognito") {
+		t.Fatalf("memory_write = %q", got)
+	}
+
+	reply, _ := a.handleIncognitoCommand(key, "/incognito off")
+	if !strings.Contains(reply, "2 条消息") {
+		t.Fatalf("off reply = %q", reply)
+	}
+	if h := a.memory.GetHistory(key); len(h) != 2 || h[1].Content != "你好！" {
+		t.Fatalf("history after incognito = %+v", h)
+	}
+	a.memory.AddExchange(key, Message{Role: "user", Content: "谢谢"}, Message{Role: "assistant", Content: "不客气"})
+	if n := stored(); n != 4 {
+		t.Fatalf("stored %d messages after incognito, want 4", n)
+	}
+}
+
+func TestIncognitoFromFirstMessage(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.memory = NewMemory(a.persistStore, 0)
+	key := ConversationKey("wecom", "c2", "u2")
+
+	a.handleIncognitoCommand(key, "无痕模式开")
+	a.memory.AddExchange(key, Message{Role: "user", Content: "秘密"}, Message{Role: "assistant", Content: "好的"})
+	a.handleIncognitoCommand(key, "无痕模式关")
+	a.memory.AddExchange(key, Message{Role: "user", Content: "你好"}, Message{Role: "assistant", Content: "你好！"})
+
+	convs, err := a.persistStore.LoadAllActiveConversations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 1 || len(convs[0].Messages) != 2 || convs[0].Messages[0].Content != "你好" {
+		t.Fatalf("stored conversations = %+v", convs)
+	}
+}
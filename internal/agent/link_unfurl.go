@@ -0,0 +1,304 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+	"github.com/kayz/coco/internal/tools"
+)
+
+const (
+	defaultUnfurlMaxLinks = 3
+	defaultUnfurlTimeout  = 5 * time.Second
+	defaultUnfurlMaxChars = 500
+	unfurlCacheTTL        = time.Hour
+	unfurlCacheSize       = 256
+	unfurlMaxBodyBytes    = 512 << 10
+	unfurlMaxTextBytes    = 10000 // page text kept for summarize, as much as web_fetch returns
+)
+
+type unfurlSettings struct {
+	enabled  bool
+	maxLinks int
+	timeout  time.Duration
+	maxChars int
+}
+
+// applyUnfurl installs tools.unfurl.
+func (a *Agent) applyUnfurl(cfg config.UnfurlConfig) {
+	s := unfurlSettings{enabled: cfg.Enabled, maxLinks: cfg.MaxLinks, timeout: defaultUnfurlTimeout, maxChars: cfg.MaxChars}
+	if s.maxLinks <= 0 {
+		s.maxLinks = defaultUnfurlMaxLinks
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(cfg.Timeout)); err == nil && d > 0 {
+		s.timeout = d
+	}
+	if s.maxChars <= 0 {
+		s.maxChars = defaultUnfurlMaxChars
+	}
+	a.securityMu.Lock()
+	a.unfurl = s
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) unfurlSettings() unfurlSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.unfurl
+}
+
+// linkPreview is what a link shows before it is opened.
+type linkPreview struct {
+	Title       string
+	Description string
+	Text        string // page text, kept for summarize
+	ContentType string
+}
+
+// linkPreviewCache keeps previews of recently seen links across
+// conversations, so a link shared twice is fetched once.
+type linkPreviewCache struct {
+	mu      sync.Mutex
+	entries map[string]linkPreviewEntry
+}
+
+type linkPreviewEntry struct {
+	preview linkPreview
+	at      time.Time
+}
+
+func (c *linkPreviewCache) get(url string) (linkPreview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if !ok || time.Since(e.at) > unfurlCacheTTL {
+		return linkPreview{}, false
+	}
+	return e.preview, true
+}
+
+func (c *linkPreviewCache) put(url string, p linkPreview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]linkPreviewEntry)
+	}
+	if len(c.entries) >= unfurlCacheSize {
+		oldest := ""
+		for k, e := range c.entries {
+			if oldest == "" || e.at.Before(c.entries[oldest].at) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[url] = linkPreviewEntry{preview: p, at: time.Now()}
+}
+
+// fetchLinkPreview downloads a link's preview; tests replace it.
+var fetchLinkPreview = fetchLinkPreviewHTTP
+
+var (
+	htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlMetaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	htmlAttrPattern  = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*("[^"]*"|'[^']*')`)
+	// messageLinkPattern stops at the first character not allowed in a URL,
+	// so a link runs into no surrounding Chinese text.
+	messageLinkPattern = regexp.MustCompile(`https?://[A-Za-z0-9\-._~:/?#\[\]@!$&'()*+,;=%]+`)
+)
+
+func fetchLinkPreviewHTTP(ctx context.Context, url string) (linkPreview, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	client := &http.Client{}
+	if cfg.Security.EnableSSRFProtection {
+		if err := security.ValidateFetchURL(url); err != nil {
+			return linkPreview{}, err
+		}
+		// A public page must not redirect the fetch into the local network.
+		client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("too many redirects")
+			}
+			return security.ValidateFetchURL(next.URL.String())
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return linkPreview{}, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Coco/1.0)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return linkPreview{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return linkPreview{}, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "json") && !strings.Contains(contentType, "xml") {
+		return linkPreview{ContentType: contentType}, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, unfurlMaxBodyBytes))
+	if err != nil {
+		return linkPreview{}, err
+	}
+	if !strings.Contains(contentType, "html") {
+		return linkPreview{Text: truncateLinkText(string(body)), ContentType: contentType}, nil
+	}
+	p := parseLinkPreview(string(body))
+	p.Text = truncateLinkText(p.Text)
+	return p, nil
+}
+
+func truncateLinkText(s string) string {
+	if len(s) <= unfurlMaxTextBytes {
+		return s
+	}
+	return strings.ToValidUTF8(s[:unfurlMaxTextBytes], "") + "\n... (truncated)"
+}
+
+// parseLinkPreview takes the title and description of an HTML page,
+// preferring its Open Graph tags.
+func parseLinkPreview(page string) linkPreview {
+	var p linkPreview
+	p.ContentType = "text/html"
+	meta := map[string]string{}
+	for _, tag := range htmlMetaPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range htmlAttrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = strings.Trim(m[2], `"'`)
+		}
+		key := strings.ToLower(attrs["property"] + attrs["name"])
+		if key != "" && attrs["content"] != "" && meta[key] == "" {
+			meta[key] = html.UnescapeString(attrs["content"])
+		}
+	}
+	p.Title = meta["og:title"]
+	if p.Title == "" {
+		if m := htmlTitlePattern.FindStringSubmatch(page); m != nil {
+			p.Title = html.UnescapeString(m[1])
+		}
+	}
+	p.Title = strings.Join(strings.Fields(p.Title), " ")
+	p.Description = meta["og:description"]
+	if p.Description == "" {
+		p.Description = meta["description"]
+	}
+	p.Text = html.UnescapeString(tools.HTMLText(page))
+	return p
+}
+
+// messageLinks returns the distinct http(s) links of text, at most max.
+func messageLinks(text string, max int) []string {
+	var links []string
+	seen := map[string]bool{}
+	for _, link := range messageLinkPattern.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;:!?]'")
+		for strings.HasSuffix(link, ")") && strings.Count(link, ")") > strings.Count(link, "(") {
+			link = strings.TrimRight(strings.TrimSuffix(link, ")"), ".,;:!?]'")
+		}
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+		if len(links) == max {
+			break
+		}
+	}
+	return links
+}
+
+// unfurlLinks adds the title and a short preview of the links in msg to its
+// text, fetched in parallel within the configured timeout. Links that fail
+// or take too long are left for the model to fetch itself. Senders whose
+// tool profile does not allow web_fetch get no previews either.
+func (a *Agent) unfurlLinks(ctx context.Context, msg router.Message) router.Message {
+	s := a.unfurlSettings()
+	if !s.enabled || strings.HasPrefix(strings.TrimSpace(msg.Text), "/") {
+		return msg
+	}
+	if !a.toolProfileFor(msg).Allows("web_fetch") {
+		return msg
+	}
+	links := messageLinks(msg.Text, s.maxLinks)
+	if len(links) == 0 {
+		return msg
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	previews := make([]*linkPreview, len(links))
+	var wg sync.WaitGroup
+	for i, link := range links {
+		if p, ok := a.linkPreviews.get(link); ok {
+			previews[i] = &p
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := fetchLinkPreview(fetchCtx, link)
+			if err != nil {
+				logger.Debug("[Agent] Unfurl %s failed: %v", link, err)
+				return
+			}
+			a.linkPreviews.put(link, p)
+			previews[i] = &p
+		}()
+	}
+	wg.Wait() // the fetches end with fetchCtx at the latest
+
+	var notes []string
+	for i, p := range previews {
+		if p == nil {
+			continue
+		}
+		if p.Text != "" {
+			a.cacheFetchedPage(ctx, links[i], p.Text)
+		}
+		if note := formatLinkPreview(links[i], *p, s.maxChars); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	if len(notes) > 0 {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n" + strings.Join(notes, "\n\n"))
+	}
+	return msg
+}
+
+func formatLinkPreview(link string, p linkPreview, maxChars int) string {
+	preview := strings.TrimSpace(p.Description)
+	if preview == "" {
+		preview = strings.Join(strings.Fields(p.Text), " ")
+	}
+	if r := []rune(preview); len(r) > maxChars {
+		preview = string(r[:maxChars]) + "…"
+	}
+	switch {
+	case p.Title != "" && preview != "":
+		return fmt.Sprintf("[链接: %s（%s）]\n[链接预览]\n%s", p.Title, link, preview)
+	case p.Title != "":
+		return fmt.Sprintf("[链接: %s（%s）]", p.Title, link)
+	case preview != "":
+		return fmt.Sprintf("[链接: %s]\n[链接预览]\n%s", link, preview)
+	case p.ContentType != "":
+		return fmt.Sprintf("[链接: %s，类型 %s，可用 summarize 阅读]", link, p.ContentType)
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestParseLinkPreview(t *testing.T) {
+	p := parseLinkPreview(`<html><head><title>Fallback</title>
+<meta content="Go 1.24 发布" property="og:title">
+<meta name='description' content='Plain description'>
+<meta property="og:description" content="Generic type aliases &amp; more">
+</head><body><p>Body text</p></body></html>`)
+	if p.Title != "Go 1.24 发布" || p.Description != "Generic type aliases & more" || !strings.Contains(p.Text, "Body text") {
+		t.Fatalf("preview = %+v", p)
+	}
+	if got := messageLinks("看看这个 https://a.example/x。还有（https://b.example/y）和 https://a.example/x (see https://en.wikipedia.org/wiki/Go_(language)).", 3); strings.Join(got, " ") != "https://a.example/x https://b.example/y https://en.wikipedia.org/wiki/Go_(language)" {
+		t.Fatalf("links = %v", got)
+	}
+}
+
+func TestUnfurlLinksBoundedAndCached(t *testing.T) {
+	old := fetchLinkPreview
+	defer func() { fetchLinkPreview = old }()
+	var fetches atomic.Int32
+	fetchLinkPreview = func(ctx context.Context, url string) (linkPreview, error) {
+		fetches.Add(1)
+		if strings.Contains(url, "slow") {
+			<-ctx.Done()
+			return linkPreview{}, ctx.Err()
+		}
+		return linkPreview{Title: "标题", Text: strings.Repeat("正文", 50), ContentType: "text/html"}, nil
+	}
+
+	a := &Agent{}
+	ctx := withTurn(context.Background(), router.Message{Platform: "slack", ChannelID: "c", UserID: "u"})
+	msg := router.Message{Text: "看看这个 https://news.example/a"}
+	if got := a.unfurlLinks(ctx, msg); got.Text != msg.Text {
+		t.Fatalf("unfurled while disabled: %q", got.Text)
+	}
+
+	a.applyUnfurl(config.UnfurlConfig{Enabled: true, Timeout: "50ms", MaxChars: 10})
+	start := time.Now()
+	got := a.unfurlLinks(ctx, router.Message{Text: "看看这个 https://news.example/a 和 https://slow.example/b"})
+	if time.Since(start) > time.Second {
+		t.Fatal("a slow link held up the message")
+	}
+	want := "看看这个 https://news.example/a 和 https://slow.example/b\n\n[链接: 标题（https://news.example/a）]\n[链接预览]\n正文正文正文正文正文…"
+	if got.Text != want {
+		t.Fatalf("unfurled = %q", got.Text)
+	}
+	// The page is ready for summarize without another fetch.
+	if src := a.sources.find(ConversationKey("slack", "c", "u"), "https://news.example/a"); src == nil {
+		t.Fatal("page not cached for summarize")
+	}
+
+	a.unfurlLinks(ctx, msg)
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("fetches = %d, want the cached preview reused", n)
+	}
+	if got := a.unfurlLinks(ctx, router.Message{Text: "/summarize https://news.example/a"}); strings.Contains(got.Text, "链接预览") {
+		t.Fatalf("unfurled a command: %q", got.Text)
+	}
+
+	// A sender who may not fetch pages gets no previews either.
+	a.applyToolProfiles(map[string]config.ToolProfileConfig{"offline": {Deny: []string{"web_fetch"}}}, "offline")
+	if got := a.unfurlLinks(ctx, router.Message{Text: "再看 https://news.example/c"}); strings.Contains(got.Text, "链接预览") {
+		t.Fatalf("unfurled for a profile without web_fetch: %q", got.Text)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("fetches = %d, want none for a profile without web_fetch", n)
+	}
+}
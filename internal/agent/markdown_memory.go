@@ -13,6 +13,7 @@ import (
 	"unicode"
 
 	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/datadir"
 	"github.com/kayz/coco/internal/logger"
 )
 
@@ -118,7 +119,7 @@ func (m *MarkdownMemory) EnableSemanticSearch(cfg config.EmbeddingConfig) error
 		m.embProvider = nil
 		return nil
 	}
-	if strings.TrimSpace(cfg.APIKey) == "" {
+	if strings.TrimSpace(cfg.APIKey) == "" && embeddingNeedsAPIKey(cfg.Provider) {
 		m.semanticReady = false
 		m.embProvider = nil
 		return fmt.Errorf("embedding api key is required when semantic memory search is enabled")
@@ -470,6 +471,9 @@ func (m *MarkdownMemory) Put(path, content, mode string) (MarkdownMemoryResult,
 	if mode == "append" {
 		if existing, err := os.ReadFile(resolved); err == nil {
 			base := strings.TrimSpace(string(existing))
+			if memoryContainsNote(base, newContent) {
+				return MarkdownMemoryResult{}, ErrMemoryDuplicate
+			}
 			if base != "" {
 				newContent = base + "\n\n" + newContent
 			}
@@ -481,16 +485,19 @@ func (m *MarkdownMemory) Put(path, content, mode string) (MarkdownMemoryResult,
 	}
 
 	// Evict stale cache entries so subsequent reads use the latest content.
-	m.mu.Lock()
-	delete(m.cache, resolved)
-	m.mu.Unlock()
-	m.embMu.Lock()
-	delete(m.embeddingCache, resolved)
-	m.embMu.Unlock()
+	m.evict(resolved)
 
 	return m.Get(resolved)
 }
 
+// DefaultNotePath returns where memory_write puts notes when no path is given: the first core file.
+func (m *MarkdownMemory) DefaultNotePath() string {
+	if files := m.resolveCoreFiles(); len(files) > 0 {
+		return files[0]
+	}
+	return ""
+}
+
 func (m *MarkdownMemory) resolveCoreFiles() []string {
 	files := make([]string, 0, len(m.coreFiles))
 	for _, p := range m.coreFiles {
@@ -876,9 +883,8 @@ func resolveBestEffortPath(path string) string {
 		}
 	}
 
-	exeDir := normalizePath(getExecutableDir())
-	if exeDir != "" {
-		candidate := normalizePath(filepath.Join(exeDir, path))
+	if dataDir := normalizePath(datadir.Dir()); dataDir != "" {
+		candidate := normalizePath(filepath.Join(dataDir, path))
 		if _, err := os.Stat(candidate); err == nil {
 			return candidate
 		}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -306,3 +307,69 @@ func TestMarkdownMemoryPutAppendAndOverwrite(t *testing.T) {
 		t.Fatalf("overwrite should replace content, got: %s", r3.Content)
 	}
 }
+
+func TestMarkdownMemoryPutNoteFrontmatterAndDedup(t *testing.T) {
+	vaultDir := t.TempDir()
+	mem := NewMarkdownMemory(config.MemoryConfig{
+		Enabled:       true,
+		ObsidianVault: vaultDir,
+	})
+
+	r1, err := mem.PutNote("people/alice.md", MemoryNote{Title: "Coffee", Content: "Alice drinks oat latte", Tags: []string{"people", "#coffee"}}, "")
+	if err != nil {
+		t.Fatalf("first note failed: %v", err)
+	}
+	if !strings.HasPrefix(r1.Content, "---\ntitle: Coffee\n") || !strings.Contains(r1.Content, "tags: [people, coffee]") {
+		t.Fatalf("expected frontmatter with tags, got:\n%s", r1.Content)
+	}
+	if !strings.Contains(r1.Content, "## Coffee · ") || !strings.Contains(r1.Content, "#people #coffee") {
+		t.Fatalf("expected structured section, got:\n%s", r1.Content)
+	}
+
+	r2, err := mem.PutNote("people/alice.md", MemoryNote{Title: "Work", Content: "Alice works on the billing team", Tags: []string{"work", "people"}}, "append")
+	if err != nil {
+		t.Fatalf("second note failed: %v", err)
+	}
+	if strings.Count(r2.Content, "---\n") != 2 || !strings.Contains(r2.Content, "tags: [people, coffee, work]") {
+		t.Fatalf("expected single merged frontmatter, got:\n%s", r2.Content)
+	}
+	if !strings.Contains(r2.Content, "oat latte") || !strings.Contains(r2.Content, "billing team") {
+		t.Fatalf("append should keep both notes, got:\n%s", r2.Content)
+	}
+
+	if _, err := mem.PutNote("people/alice.md", MemoryNote{Content: "alice drinks  oat latte."}, "append"); !errors.Is(err, ErrMemoryDuplicate) {
+		t.Fatalf("expected duplicate note to be rejected, got %v", err)
+	}
+	if _, err := mem.Put("people/alice.md", "Alice works on the billing team", "append"); !errors.Is(err, ErrMemoryDuplicate) {
+		t.Fatalf("expected duplicate append to be rejected, got %v", err)
+	}
+}
+
+func TestMemoryContainsNoteComparesWholeEntries(t *testing.T) {
+	existing := "---\ntitle: prefs\n---\n\n## Drinks\n\nAlice dislikes oat milk.\n\n- 不喜欢咖啡\n- Works late\n\nMeets Bob on Friday"
+	for _, note := range []string{
+		"Alice likes oat milk",
+		"likes oat milk",
+		"喜欢咖啡",
+		"Works late Meets Bob",
+		"Alice dislikes oat milk\n\nand tea",
+	} {
+		if memoryContainsNote(existing, note) {
+			t.Errorf("%q reported as already recorded", note)
+		}
+	}
+	for _, note := range []string{
+		"alice dislikes  OAT milk",
+		"不喜欢咖啡。",
+		"works late",
+		"Meets Bob on Friday\n\nAlice dislikes oat milk",
+	} {
+		if !memoryContainsNote(existing, note) {
+			t.Errorf("%q not reported as already recorded", note)
+		}
+	}
+	soul := "# SOUL\n\n## Growth Ledger\n- Time: 2026-10-01 09:00\n- Entry: prefers short answers"
+	if !memoryContainsNote(soul, "Entry: prefers short answers") || memoryContainsNote(soul, "Entry: short answers") {
+		t.Error("SOUL entries should match by whole entry line")
+	}
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"encoding/json"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ type ConversationMemory struct {
 	mu            sync.RWMutex
 	store         *persist.Store
 	maxMessages   int
+	incognito     map[string]int // conversations in incognito, by the message count when it began
 }
 
 // Conversation holds messages for a single conversation
@@ -32,6 +34,7 @@ func NewMemory(store *persist.Store, maxMessages int) *ConversationMemory {
 
 	m := &ConversationMemory{
 		conversations: make(map[string]*Conversation),
+		incognito:     make(map[string]int),
 		store:         store,
 		maxMessages:   maxMessages,
 	}
@@ -89,34 +92,13 @@ func (m *ConversationMemory) AddMessage(key string, msg Message) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	conv, ok := m.conversations[key]
-	if !ok {
-		platform, channelID, userID := persist.ParseConversationKey(key)
-		pc, err := m.store.GetOrCreateConversation(platform, channelID, userID)
-		if err != nil {
-			log.Printf("[MEMORY] Failed to get/create conversation: %v", err)
-			return
-		}
-		conv = &Conversation{
-			ID:        pc.ID,
-			Messages:  make([]Message, 0),
-			UpdatedAt: time.Now(),
-		}
-		m.conversations[key] = conv
-	}
-
-	conv.Messages = append(conv.Messages, msg)
-	conv.UpdatedAt = time.Now()
-
-	if len(conv.Messages) > m.maxMessages {
-		startIdx := len(conv.Messages) - m.maxMessages
-		if startIdx%2 != 0 {
-			startIdx++
-		}
-		conv.Messages = conv.Messages[startIdx:]
+	conv := m.conversationLocked(key)
+	if conv == nil {
+		return
 	}
+	m.appendLocked(key, conv, msg)
 
-	if m.store != nil {
+	if m.store != nil && !m.isIncognitoLocked(key) {
 		pm := m.convertToPersistMessage(msg)
 		if err := m.store.AddMessage(conv.ID, pm); err != nil {
 			log.Printf("[MEMORY] Failed to persist message: %v", err)
@@ -129,23 +111,51 @@ func (m *ConversationMemory) AddExchange(key string, userMsg, assistantMsg Messa
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	conv := m.conversationLocked(key)
+	if conv == nil {
+		return
+	}
+	m.appendLocked(key, conv, userMsg, assistantMsg)
+
+	if m.store != nil && !m.isIncognitoLocked(key) {
+		pUserMsg := m.convertToPersistMessage(userMsg)
+		pAssistMsg := m.convertToPersistMessage(assistantMsg)
+		if err := m.store.AddMessage(conv.ID, pUserMsg); err != nil {
+			log.Printf("[MEMORY] Failed to persist user message: %v", err)
+		}
+		if err := m.store.AddMessage(conv.ID, pAssistMsg); err != nil {
+			log.Printf("[MEMORY] Failed to persist assistant message: %v", err)
+		}
+	}
+}
+
+// conversationLocked returns the conversation for key, creating it (and,
+// outside incognito, its stored row) when needed. Callers hold m.mu.
+func (m *ConversationMemory) conversationLocked(key string) *Conversation {
 	conv, ok := m.conversations[key]
 	if !ok {
+		conv = &Conversation{
+			Messages:  make([]Message, 0),
+			UpdatedAt: time.Now(),
+		}
+	}
+	if conv.ID == 0 && m.store != nil && !m.isIncognitoLocked(key) {
 		platform, channelID, userID := persist.ParseConversationKey(key)
 		pc, err := m.store.GetOrCreateConversation(platform, channelID, userID)
 		if err != nil {
 			log.Printf("[MEMORY] Failed to get/create conversation: %v", err)
-			return
-		}
-		conv = &Conversation{
-			ID:        pc.ID,
-			Messages:  make([]Message, 0),
-			UpdatedAt: time.Now(),
+			return nil
 		}
-		m.conversations[key] = conv
+		conv.ID = pc.ID
 	}
+	m.conversations[key] = conv
+	return conv
+}
 
-	conv.Messages = append(conv.Messages, userMsg, assistantMsg)
+// appendLocked adds msgs and trims the history to maxMessages, keeping
+// user/assistant pairs together. Callers hold m.mu.
+func (m *ConversationMemory) appendLocked(key string, conv *Conversation, msgs ...Message) {
+	conv.Messages = append(conv.Messages, msgs...)
 	conv.UpdatedAt = time.Now()
 
 	if len(conv.Messages) > m.maxMessages {
@@ -154,18 +164,110 @@ func (m *ConversationMemory) AddExchange(key string, userMsg, assistantMsg Messa
 			startIdx++
 		}
 		conv.Messages = conv.Messages[startIdx:]
+		if mark, ok := m.incognito[key]; ok {
+			m.incognito[key] = max(mark-startIdx, 0)
+		}
 	}
+}
 
-	if m.store != nil {
-		pUserMsg := m.convertToPersistMessage(userMsg)
-		pAssistMsg := m.convertToPersistMessage(assistantMsg)
-		if err := m.store.AddMessage(conv.ID, pUserMsg); err != nil {
-			log.Printf("[MEMORY] Failed to persist user message: %v", err)
+// SetIncognito starts or ends incognito for key. While incognito, new
+// messages stay in memory only. Ending it forgets them and returns how many
+// there were.
+func (m *ConversationMemory) SetIncognito(key string, on bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mark, was := m.incognito[key]
+	conv := m.conversations[key]
+	if on {
+		if !was {
+			if conv != nil {
+				mark = len(conv.Messages)
+			}
+			m.incognito[key] = mark
 		}
-		if err := m.store.AddMessage(conv.ID, pAssistMsg); err != nil {
-			log.Printf("[MEMORY] Failed to persist assistant message: %v", err)
+		return 0
+	}
+	if !was {
+		return 0
+	}
+	delete(m.incognito, key)
+	if conv == nil || mark >= len(conv.Messages) {
+		return 0
+	}
+	dropped := len(conv.Messages) - mark
+	conv.Messages = conv.Messages[:mark]
+	return dropped
+}
+
+// IsIncognito reports whether key is in incognito.
+func (m *ConversationMemory) IsIncognito(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isIncognitoLocked(key)
+}
+
+func (m *ConversationMemory) isIncognitoLocked(key string) bool {
+	_, ok := m.incognito[key]
+	return ok
+}
+
+// Keys returns the keys of all conversations, most recently updated first
+func (m *ConversationMemory) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.conversations))
+	for key := range m.conversations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return m.conversations[keys[i]].UpdatedAt.After(m.conversations[keys[j]].UpdatedAt)
+	})
+	return keys
+}
+
+// Stat returns how many messages a conversation holds and when it was
+// last used, or ok=false if there is no such conversation
+func (m *ConversationMemory) Stat(key string) (messages int, updatedAt time.Time, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conv, ok := m.conversations[key]
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return len(conv.Messages), conv.UpdatedAt, true
+}
+
+// Fork copies the history of from into the new conversation to, storing
+// the copies, and returns how many messages were copied
+func (m *ConversationMemory) Fork(from, to string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	src, ok := m.conversations[from]
+	if !ok || len(src.Messages) == 0 {
+		m.conversationLocked(to)
+		return 0
+	}
+	msgs := make([]Message, len(src.Messages))
+	copy(msgs, src.Messages)
+	conv := m.conversationLocked(to)
+	if conv == nil {
+		return 0
+	}
+	m.appendLocked(to, conv, msgs...)
+
+	if m.store != nil && !m.isIncognitoLocked(to) {
+		for _, msg := range msgs {
+			if err := m.store.AddMessage(conv.ID, m.convertToPersistMessage(msg)); err != nil {
+				log.Printf("[MEMORY] Failed to persist forked message: %v", err)
+				break
+			}
 		}
 	}
+	return len(msgs)
 }
 
 // Clear clears the conversation history for a key
@@ -173,6 +275,7 @@ func (m *ConversationMemory) Clear(key string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.conversations, key)
+	delete(m.incognito, key)
 }
 
 // ClearAll clears all conversation histories
@@ -180,6 +283,7 @@ func (m *ConversationMemory) ClearAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.conversations = make(map[string]*Conversation)
+	m.incognito = make(map[string]int)
 }
 
 func (m *ConversationMemory) convertPersistMessage(pm persist.Message) Message {
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrMemoryDuplicate is returned when the content is already recorded in the target file.
+var ErrMemoryDuplicate = errors.New("content already recorded")
+
+// MemoryNote is a structured entry for memory_write: a titled section with tags,
+// kept under a YAML frontmatter that tracks created/updated dates and the tag union.
+type MemoryNote struct {
+	Title   string
+	Content string
+	Tags    []string
+}
+
+// PutNote writes a structured note. mode is "append" (default) or "overwrite".
+func (m *MarkdownMemory) PutNote(path string, note MemoryNote, mode string) (MarkdownMemoryResult, error) {
+	if !m.IsEnabled() {
+		return MarkdownMemoryResult{}, fmt.Errorf("markdown memory is disabled")
+	}
+	if strings.TrimSpace(note.Content) == "" {
+		return MarkdownMemoryResult{}, fmt.Errorf("content is required")
+	}
+
+	resolved, err := m.resolveAllowedPath(strings.TrimSpace(path))
+	if err != nil {
+		return MarkdownMemoryResult{}, err
+	}
+
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = "append"
+	}
+	if mode != "append" && mode != "overwrite" {
+		return MarkdownMemoryResult{}, fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	existing := ""
+	if mode == "append" {
+		if data, err := os.ReadFile(resolved); err == nil {
+			existing = string(data)
+		}
+	}
+	if existing != "" && memoryContainsNote(existing, note.Content) {
+		return MarkdownMemoryResult{}, ErrMemoryDuplicate
+	}
+
+	now := time.Now()
+	front, body := splitMemoryFrontmatter(existing)
+	if existing == "" {
+		title := note.Title
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(resolved), filepath.Ext(resolved))
+		}
+		front = []string{
+			"title: " + title,
+			"created: " + now.Format("2006-01-02"),
+		}
+	}
+	// Files without frontmatter (e.g. hand-written MEMORY.md) keep their layout.
+	if front != nil {
+		front = setFrontmatterField(front, "updated", now.Format("2006-01-02"))
+		if tags := mergeFrontmatterTags(front, note.Tags); len(tags) > 0 {
+			front = setFrontmatterField(front, "tags", "["+strings.Join(tags, ", ")+"]")
+		}
+	}
+
+	var b strings.Builder
+	if front != nil {
+		b.WriteString("---\n")
+		b.WriteString(strings.Join(front, "\n"))
+		b.WriteString("\n---\n\n")
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		b.WriteString(body)
+		b.WriteString("\n\n")
+	}
+	heading := now.Format("2006-01-02 15:04")
+	if note.Title != "" {
+		heading = note.Title + " · " + heading
+	}
+	b.WriteString("## " + heading + "\n")
+	if len(note.Tags) > 0 {
+		b.WriteString("#" + strings.Join(normalizeMemoryTags(note.Tags), " #") + "\n")
+	}
+	b.WriteString("\n" + strings.TrimSpace(note.Content) + "\n")
+
+	if err := os.MkdirAll(filepath.Dir(resolved), 0o755); err != nil {
+		return MarkdownMemoryResult{}, err
+	}
+	if err := os.WriteFile(resolved, []byte(b.String()), 0o644); err != nil {
+		return MarkdownMemoryResult{}, err
+	}
+	m.evict(resolved)
+	return m.Get(resolved)
+}
+
+// evict drops cached content and embeddings for a file after a write.
+func (m *MarkdownMemory) evict(resolved string) {
+	m.mu.Lock()
+	delete(m.cache, resolved)
+	m.mu.Unlock()
+	m.embMu.Lock()
+	delete(m.embeddingCache, resolved)
+	m.embMu.Unlock()
+}
+
+// memoryContainsNote reports whether content is already recorded in
+// existing, ignoring case, whitespace and punctuation differences. Each
+// paragraph of content must equal a whole paragraph or line of existing:
+// "likes oat milk" is not a repeat of "dislikes oat milk", nor of the end of
+// one entry run together with the start of the next.
+func memoryContainsNote(existing, content string) bool {
+	if len([]rune(normalizeMemoryText(content))) < 4 {
+		return false
+	}
+	entries := map[string]bool{}
+	for _, para := range memoryParagraphs(existing) {
+		entries[normalizeMemoryText(para)] = true
+		for _, line := range strings.Split(para, "\n") {
+			entries[normalizeMemoryText(line)] = true
+		}
+	}
+	for _, para := range memoryParagraphs(content) {
+		if !entries[normalizeMemoryText(para)] {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryParagraphs splits s at blank lines, dropping paragraphs with no
+// letters or digits.
+func memoryParagraphs(s string) []string {
+	var out []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n\n") {
+		if normalizeMemoryText(para) != "" {
+			out = append(out, para)
+		}
+	}
+	return out
+}
+
+func normalizeMemoryText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitMemoryFrontmatter returns frontmatter lines (nil when absent) and the body.
+func splitMemoryFrontmatter(content string) ([]string, string) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return nil, content
+	}
+	rest := normalized[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return nil, content
+	}
+	body := strings.TrimPrefix(rest[end+len("\n---"):], "\n")
+	block := strings.TrimSpace(rest[:end])
+	if block == "" {
+		return []string{}, body
+	}
+	return strings.Split(block, "\n"), body
+}
+
+// setFrontmatterField replaces key (including any list items under it) or appends it.
+func setFrontmatterField(lines []string, key, value string) []string {
+	out := make([]string, 0, len(lines)+1)
+	replaced := false
+	skipList := false
+	for _, line := range lines {
+		if skipList {
+			if strings.HasPrefix(strings.TrimSpace(line), "- ") || strings.TrimSpace(line) == "" {
+				continue
+			}
+			skipList = false
+		}
+		if k, _, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == key && !strings.HasPrefix(line, " ") {
+			out = append(out, key+": "+value)
+			replaced = true
+			skipList = true
+			continue
+		}
+		out = append(out, line)
+	}
+	if !replaced {
+		out = append(out, key+": "+value)
+	}
+	return out
+}
+
+// mergeFrontmatterTags returns the existing frontmatter tags plus any new ones.
+func mergeFrontmatterTags(lines []string, extra []string) []string {
+	var tags []string
+	inList := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inList {
+			if strings.HasPrefix(trimmed, "- ") {
+				tags = append(tags, strings.TrimPrefix(trimmed, "- "))
+				continue
+			}
+			inList = false
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == "tags" {
+			v = strings.Trim(strings.TrimSpace(v), "[]")
+			if v == "" {
+				inList = true
+				continue
+			}
+			tags = append(tags, strings.Split(v, ",")...)
+		}
+	}
+	return normalizeMemoryTags(append(tags, extra...))
+}
+
+func normalizeMemoryTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(strings.Trim(strings.TrimSpace(t), `"'#`))
+		t = strings.ReplaceAll(t, " ", "-")
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		out = append(out, t)
+	}
+	return out
+}
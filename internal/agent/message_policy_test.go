@@ -1,9 +1,13 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
 )
 
 func TestEnforceMessageSecurityPolicyAllowFrom(t *testing.T) {
@@ -66,3 +70,65 @@ func TestEnforceMessageSecurityPolicyRequireMentionInGroup(t *testing.T) {
 		t.Fatalf("expected private message to pass, got denial=%q drop=%v", denial, drop)
 	}
 }
+
+func TestToolProfilesFromAllowFrom(t *testing.T) {
+	a := &Agent{}
+	a.applySecurityConfig(nil, false, nil, nil, []string{"telegram:1001", "wecom:guest=readonly", "bob=no-shell"}, false)
+	a.applyToolProfiles(nil, "")
+
+	owner := router.Message{Platform: "telegram", UserID: "1001"}
+	guest := router.Message{Platform: "wecom", UserID: "guest"}
+	bob := router.Message{Platform: "slack", UserID: "U1", Username: "bob"}
+
+	for _, msg := range []router.Message{owner, guest, bob} {
+		if denial, drop := a.enforceMessageSecurityPolicy(msg); drop || denial != "" {
+			t.Fatalf("expected %s/%s to be whitelisted, got denial=%q", msg.Platform, msg.UserID, denial)
+		}
+	}
+
+	tools := []Tool{{Name: "file_read"}, {Name: "file_write"}, {Name: "shell_execute"}}
+	if got := filterToolsForProfile(tools, a.toolProfileFor(owner)); len(got) != 3 {
+		t.Fatalf("owner should keep all tools, got %d", len(got))
+	}
+	if got := filterToolsForProfile(tools, a.toolProfileFor(guest)); len(got) != 1 || got[0].Name != "file_read" {
+		t.Fatalf("readonly guest should only see file_read, got %#v", got)
+	}
+	if got := filterToolsForProfile(tools, a.toolProfileFor(bob)); len(got) != 2 {
+		t.Fatalf("no-shell sender should lose shell_execute, got %#v", got)
+	}
+
+	if denied := a.checkToolProfile(withTurn(context.Background(), guest), "shell_execute"); !strings.HasPrefix(denied, "ACCESS DENIED") {
+		t.Fatalf("expected readonly sender to be denied shell_execute, got %q", denied)
+	}
+}
+
+func TestMutatingCommandsNeedAdminProfile(t *testing.T) {
+	a := &Agent{}
+	a.applySecurityConfig(nil, false, nil, nil, []string{"telegram:1001", "wecom:guest=readonly"}, false)
+	a.applyToolProfiles(nil, "")
+	owner := router.Message{Platform: "telegram", UserID: "1001"}
+	guest := router.Message{Platform: "wecom", UserID: "guest"}
+
+	for _, text := range []string{"/secret del nas", "/secret set nas hunter2", "/revert SOUL.md", "/sync", "/approve", "/config reload"} {
+		guest.Text = text
+		resp, handled := a.handleBuiltinCommand(context.Background(), guest)
+		if !handled || !strings.Contains(resp.Text, "admin") {
+			t.Errorf("readonly %q = %q, %v", text, resp.Text, handled)
+		}
+	}
+	owner.Text = "/sync"
+	if resp, _ := a.handleBuiltinCommand(context.Background(), owner); !strings.Contains(resp.Text, "同步未开启") {
+		t.Fatalf("admin /sync = %q", resp.Text)
+	}
+}
+
+func TestAPIToolCallsFollowTheAPIProfile(t *testing.T) {
+	a := &Agent{}
+	a.applyToolProfiles(nil, "")
+	if _, err := a.RunTool(context.Background(), security.ProfileReadonly, "shell_execute", map[string]any{"command": "true"}); !errors.Is(err, ErrToolDenied) {
+		t.Fatalf("readonly API shell_execute = %v", err)
+	}
+	if _, err := a.RunTool(context.Background(), "", "shell_execute", nil); !errors.Is(err, ErrToolDenied) {
+		t.Fatalf("API call without a profile = %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+// restoreModelHealth reloads the failure record of the last run so a
+// provider that was down stays in cooldown after a restart, and keeps the
+// record saved from now on.
+func (a *Agent) restoreModelHealth() {
+	if a.persistStore == nil || a.modelRouter == nil {
+		return
+	}
+	stored, err := a.persistStore.ListModelHealth()
+	if err != nil {
+		logger.Warn("[Agent] Failed to load model health: %v", err)
+	} else {
+		health := make([]ai.ModelHealth, 0, len(stored))
+		for _, h := range stored {
+			health = append(health, ai.ModelHealth(h))
+		}
+		a.modelRouter.RestoreHealth(health)
+	}
+	a.modelRouter.OnHealthChange(a.saveModelHealth)
+}
+
+func (a *Agent) saveModelHealth(h ai.ModelHealth) {
+	if a.persistStore == nil {
+		return
+	}
+	if err := a.persistStore.SaveModelHealth(persist.ModelHealth(h)); err != nil {
+		logger.Warn("[Agent] Failed to save health of %s: %v", h.Model, err)
+	}
+}
+
+// handleModelsCommand answers /models with every model's live status.
+func (a *Agent) handleModelsCommand(text string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/models", "模型状态":
+		return a.executeAIModelHealth(), true
+	}
+	return "", false
+}
+
+func (a *Agent) executeAIModelHealth() string {
+	if a.modelRouter == nil {
+		return "No models available"
+	}
+	current := ""
+	if m := a.modelRouter.GetCurrentModel(); m != nil {
+		current = m.Name
+	}
+	return formatModelHealth(a.modelRouter.Health(), current, time.Now())
+}
+
+func formatModelHealth(health []ai.ModelHealth, current string, now time.Time) string {
+	if len(health) == 0 {
+		return "No models available"
+	}
+	var sb strings.Builder
+	sb.WriteString("模型状态：\n")
+	for _, h := range health {
+		icon, status := "🟢", "正常"
+		switch h.Status(now) {
+		case "quarantined":
+			icon, status = "⛔", fmt.Sprintf("隔离中（至 %s）", h.QuarantineUntil.Format("01-02 15:04"))
+		case "cooldown":
+			icon, status = "🔴", fmt.Sprintf("冷却中（至 %s）", h.CooldownUntil.Format("01-02 15:04"))
+		case "failing":
+			icon, status = "🟡", fmt.Sprintf("连续失败 %d 次", h.ConsecutiveFailures)
+		}
+		name := h.Model
+		if name == current {
+			name += "（当前）"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s %s — %s\n", icon, name, status))
+		sb.WriteString(fmt.Sprintf("  成功 %d / 失败 %d", h.Successes, h.Failures))
+		if !h.LastSuccess.IsZero() {
+			sb.WriteString(fmt.Sprintf("，最近成功 %s", h.LastSuccess.Format("01-02 15:04")))
+		}
+		sb.WriteString("\n")
+		if h.LastError != "" && h.LastFailure.After(h.LastSuccess) {
+			sb.WriteString(fmt.Sprintf("  最近错误（%s）：%s\n", h.LastFailure.Format("01-02 15:04"), feedbackExcerpt(h.LastError, 120)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+)
+
+func newHealthTestRouter(t *testing.T) *ai.ModelRouter {
+	t.Helper()
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	if err := os.MkdirAll(filepath.Dir(ai.ProvidersPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ai.ProvidersPath(), []byte("providers:\n  - name: p\n    type: openai\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	models := "models:\n  - name: main\n    code: main\n    provider: p\n  - name: backup\n    code: backup\n    provider: p\n"
+	if err := os.WriteFile(ai.ModelsPath(), []byte(models), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := ai.LoadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ai.NewModelRouter(reg, time.Minute)
+}
+
+func TestModelHealthSurvivesRestart(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.modelRouter = newHealthTestRouter(t)
+	a.restoreModelHealth()
+
+	main := a.modelRouter.GetCurrentModel()
+	for i := 0; i < 3; i++ {
+		a.modelRouter.RecordFailure(main, errors.New("connection refused"))
+	}
+
+	restarted := &Agent{persistStore: a.persistStore, modelRouter: newHealthTestRouter(t)}
+	restarted.restoreModelHealth()
+	if !restarted.modelRouter.IsInCooldown("main") {
+		t.Fatal("cooldown was not restored")
+	}
+
+	reply, ok := restarted.handleModelsCommand("/models")
+	if !ok {
+		t.Fatal("/models not handled")
+	}
+	for _, want := range []string{"main（当前）", "冷却中", "失败 3", "connection refused", "🟢 backup"} {
+		if !strings.Contains(reply, want) {
+			t.Fatalf("reply lacks %q:\n%s", want, reply)
+		}
+	}
+}
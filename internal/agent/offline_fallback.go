@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/offlinecmd"
+)
+
+// offlineReply answers the turn's message without a model when it is one
+// of the requests offlinecmd recognizes. It is tried once every model has
+// failed, so reminders, weather and music keep working during an outage.
+func (a *Agent) offlineReply(ctx context.Context) (string, bool) {
+	msg := turnMessage(ctx)
+	cmd, ok := offlinecmd.Parse(msg.Text, time.Now())
+	if !ok {
+		return "", false
+	}
+	if denied := a.checkToolProfile(ctx, cmd.Tool); denied != "" {
+		return "", false
+	}
+	input, _ := json.Marshal(cmd.Args)
+	start := time.Now()
+	result := strings.TrimSpace(redactSecretValues(a.executeTool(ctx, cmd.Tool, input)))
+	a.recordToolMetric(cmd.Tool, time.Since(start), len(result), strings.HasPrefix(result, "Error"))
+	logger.Info("[Agent] Answered %s offline with %s", msg.Username, cmd.Tool)
+	return "⚠️ AI 模型暂时不可用，已按离线规则处理 — " + cmd.Summary + "\n\n" + result, true
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestOfflineReply(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+
+	ctx := withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1", Text: "10分钟后提醒我喝水"})
+	reply, ok := a.offlineReply(ctx)
+	if !ok {
+		t.Fatal("reminder not handled offline")
+	}
+	// No scheduler in the test agent, so the tool's own error is passed on.
+	if !strings.Contains(reply, "设置提醒（10 分钟后）：喝水") || !strings.Contains(reply, "cron scheduler not available") {
+		t.Fatalf("reply = %q", reply)
+	}
+
+	ctx = withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1", Text: "帮我总结一下这篇文章"})
+	if reply, ok := a.offlineReply(ctx); ok {
+		t.Fatalf("free-form request handled offline: %q", reply)
+	}
+}
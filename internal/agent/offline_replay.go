@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+// queuedAtMetadataKey is set by keeper on messages it held while coco was
+// offline and delivers after a reconnect.
+const queuedAtMetadataKey = "queued_at"
+
+// noteQueuedMessage tells the model when a message keeper held for coco was
+// sent and that keeper already answered it, so relative times such as "in
+// 10 minutes" count from then and stale requests are checked first.
+func noteQueuedMessage(msg router.Message, now time.Time) router.Message {
+	raw := msg.Metadata[queuedAtMetadataKey]
+	if raw == "" {
+		return msg
+	}
+	sentAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil || now.Sub(sentAt) < time.Minute {
+		return msg
+	}
+	msg.Text = fmt.Sprintf("[这条消息发送于 %s（%s前），当时 coco 离线，Keeper 已代为回复。消息里的相对时间（如“10 分钟后”“今晚”）按发送时间算；已经过时的请求先向用户确认，不要直接执行。]\n%s",
+		sentAt.Local().Format("01-02 15:04"), formatTimeSpent(now.Sub(sentAt)), msg.Text)
+	return msg
+}
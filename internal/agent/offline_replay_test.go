@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestNoteQueuedMessage(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	msg := router.Message{Text: "10 分钟后提醒我开会", Metadata: map[string]string{
+		queuedAtMetadataKey: now.Add(-90 * time.Minute).Format(time.RFC3339),
+	}}
+	got := noteQueuedMessage(msg, now).Text
+	if !strings.Contains(got, "发送于 10-16 08:00（1 小时 30 分前）") || !strings.HasSuffix(got, "\n10 分钟后提醒我开会") {
+		t.Fatalf("note = %q", got)
+	}
+	if got := noteQueuedMessage(router.Message{Text: "hi"}, now).Text; got != "hi" {
+		t.Fatalf("live message changed: %q", got)
+	}
+}
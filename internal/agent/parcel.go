@@ -0,0 +1,373 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/parcel"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	parcelJobName     = "parcel-watch"
+	parcelJobTag      = "assistant-task"
+	parcelJobSchedule = "17 */2 * * *" // every two hours
+	parcelEventLimit  = 5
+	parcelFirstCheck  = time.Minute
+)
+
+var parcelStatusNames = map[string]string{
+	string(parcel.StatusPending):        "待揽收",
+	string(parcel.StatusInTransit):      "运输中",
+	string(parcel.StatusOutForDelivery): "派送中",
+	string(parcel.StatusDelivered):      "已签收",
+	string(parcel.StatusException):      "异常",
+	string(parcel.StatusReturned):       "已退回",
+}
+
+// applyTracking installs the tracking section. Without a usable provider
+// parcel_track reports that tracking is not configured.
+func (a *Agent) applyTracking(cfg config.TrackingConfig) {
+	var tracker parcel.Tracker
+	if strings.TrimSpace(cfg.Provider) != "" {
+		t, err := parcel.New(parcel.Config{Provider: cfg.Provider, APIKey: cfg.APIKey, Customer: cfg.Customer, URL: cfg.URL})
+		if err != nil {
+			logger.Warn("[Agent] Parcel tracking disabled: %v", err)
+		} else {
+			tracker = t
+		}
+	}
+	a.securityMu.Lock()
+	a.parcelTracker = tracker
+	a.parcelAutoExtract = cfg.AutoExtract == nil || *cfg.AutoExtract
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) parcelSettings() (parcel.Tracker, bool) {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.parcelTracker, a.parcelAutoExtract
+}
+
+func (a *Agent) executeParcelTrack(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	action := strings.ToLower(strings.TrimSpace(getString(args, "action")))
+	if action == "" {
+		action = "list"
+		if getString(args, "number") != "" {
+			action = "track"
+		}
+	}
+	userID := timeUserID(turnMessage(ctx))
+	switch action {
+	case "track", "add":
+		return a.trackParcel(ctx, userID, args)
+	case "list":
+		return a.listParcels(userID)
+	case "remove", "delete":
+		return a.removeParcel(userID, args)
+	case "check":
+		return a.checkParcels(ctx, userID)
+	default:
+		return "Error: action must be track, list, remove or check"
+	}
+}
+
+func (a *Agent) trackParcel(ctx context.Context, userID string, args map[string]any) string {
+	number := strings.ToUpper(strings.TrimSpace(getString(args, "number")))
+	if number == "" {
+		return "Error: number is required"
+	}
+	if tracker, _ := a.parcelSettings(); tracker == nil {
+		return "快递查询未配置（在 .coco.yaml 中设置 tracking.provider 与 api_key）"
+	}
+	p, err := a.persistStore.FindParcel(userID, number)
+	if err != nil {
+		return fmt.Sprintf("Error loading parcel: %v", err)
+	}
+	if p == nil || !p.Active {
+		carrier := strings.TrimSpace(getString(args, "carrier"))
+		if carrier == "" {
+			carrier = parcel.DetectCarrier(number)
+		}
+		p = &persist.Parcel{
+			UserID:    userID,
+			Platform:  turnMessage(ctx).Platform,
+			ChannelID: turnMessage(ctx).ChannelID,
+			Number:    number,
+			Carrier:   carrier,
+			Active:    true,
+		}
+		if p.ID, err = a.persistStore.AddParcel(*p); err != nil {
+			return fmt.Sprintf("Error saving parcel: %v", err)
+		}
+	}
+	if label := strings.TrimSpace(getString(args, "label")); label != "" {
+		p.Label = label
+	}
+	if phone := strings.TrimSpace(getString(args, "phone")); phone != "" {
+		p.Phone = phone
+	}
+
+	info, err := a.checkParcel(ctx, p, time.Now(), false)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📦 #%d %s\n", p.ID, parcelTitle(*p))
+	if err != nil {
+		sb.WriteString("查询失败: " + err.Error() + "\n")
+	} else {
+		sb.WriteString("状态: " + parcelStatusName(p.Status) + "\n")
+		for i, e := range info.Events {
+			if i == parcelEventLimit {
+				break
+			}
+			sb.WriteString("  " + formatParcelEvent(e.Time, e.Description) + "\n")
+		}
+	}
+	if p.Active {
+		sb.WriteString("状态变化时会主动提醒你。")
+		if msg := a.ensureParcelJob(); msg != "" {
+			sb.WriteString("\n" + msg)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (a *Agent) removeParcel(userID string, args map[string]any) string {
+	var p *persist.Parcel
+	var err error
+	if number := strings.ToUpper(strings.TrimSpace(getString(args, "number"))); number != "" {
+		p, err = a.persistStore.FindParcel(userID, number)
+	} else {
+		p, err = a.persistStore.GetParcel(int64(getFloat(args, "id")))
+	}
+	if err != nil {
+		return fmt.Sprintf("Error loading parcel: %v", err)
+	}
+	if p == nil || p.UserID != userID {
+		return "没有找到这个快递"
+	}
+	if err := a.persistStore.DeleteParcel(p.ID); err != nil {
+		return fmt.Sprintf("Error removing parcel: %v", err)
+	}
+	a.ensureParcelJob()
+	return fmt.Sprintf("已取消跟踪 #%d %s", p.ID, parcelTitle(*p))
+}
+
+func (a *Agent) listParcels(userID string) string {
+	parcels, err := a.persistStore.Parcels(userID, false)
+	if err != nil {
+		return fmt.Sprintf("Error loading parcels: %v", err)
+	}
+	if len(parcels) == 0 {
+		return "还没有跟踪任何快递"
+	}
+	var sb strings.Builder
+	sb.WriteString("📦 快递跟踪:\n")
+	for _, p := range parcels {
+		fmt.Fprintf(&sb, "#%d %s  %s\n", p.ID, parcelTitle(p), parcelStatusName(p.Status))
+		if p.LastEvent != "" {
+			sb.WriteString("  " + formatParcelEvent(p.LastEventAt, p.LastEvent) + "\n")
+		}
+		if p.LastError != "" {
+			sb.WriteString("  ⚠️ 上次查询失败: " + p.LastError + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// checkParcels checks every active parcel of userID, or of all users when
+// userID is empty, notifying status changes, and reports one line per parcel.
+func (a *Agent) checkParcels(ctx context.Context, userID string) string {
+	parcels, err := a.persistStore.Parcels(userID, true)
+	if err != nil {
+		return fmt.Sprintf("Error loading parcels: %v", err)
+	}
+	if len(parcels) == 0 {
+		return "没有需要查询的快递"
+	}
+	var lines []string
+	for i := range parcels {
+		if ctx.Err() != nil {
+			break
+		}
+		p := &parcels[i]
+		line := fmt.Sprintf("#%d %s: ", p.ID, parcelTitle(*p))
+		if _, err := a.checkParcel(ctx, p, time.Now(), true); err != nil {
+			line += "查询失败: " + err.Error()
+		} else {
+			line += parcelStatusName(p.Status)
+		}
+		lines = append(lines, line)
+	}
+	a.ensureParcelJob()
+	return strings.Join(lines, "\n")
+}
+
+// checkParcel looks the parcel up and saves its status. With alert set, a
+// new status or scan is pushed to the chat the parcel was tracked from.
+// Delivered and returned parcels stop being checked.
+func (a *Agent) checkParcel(ctx context.Context, p *persist.Parcel, now time.Time, alert bool) (parcel.Info, error) {
+	tracker, _ := a.parcelSettings()
+	if tracker == nil {
+		return parcel.Info{}, fmt.Errorf("parcel tracking is not configured")
+	}
+	info, err := tracker.Track(ctx, parcel.Query{Number: p.Number, Carrier: p.Carrier, Phone: p.Phone})
+	p.LastChecked = now
+	if err != nil {
+		p.LastError = err.Error()
+		if uerr := a.persistStore.UpdateParcel(*p); uerr != nil {
+			logger.Warn("[Agent] Failed to save parcel %d: %v", p.ID, uerr)
+		}
+		return info, err
+	}
+
+	p.LastError = ""
+	if p.Carrier == "" {
+		p.Carrier = info.Carrier
+	}
+	latest, hasEvent := info.Latest()
+	changed := string(info.Status) != p.Status || (hasEvent && latest.Description != p.LastEvent)
+	p.Status = string(info.Status)
+	if hasEvent {
+		p.LastEvent = latest.Description
+		p.LastEventAt = latest.Time
+	}
+	if info.Status.Final() {
+		p.Active = false
+	}
+	if err := a.persistStore.UpdateParcel(*p); err != nil {
+		logger.Warn("[Agent] Failed to save parcel %d: %v", p.ID, err)
+	}
+	// A number that has no scans yet is not worth a message.
+	if alert && changed && (hasEvent || info.Status.Final()) {
+		a.sendParcelUpdate(*p)
+	}
+	return info, nil
+}
+
+func (a *Agent) sendParcelUpdate(p persist.Parcel) {
+	if a.notifier == nil || p.Platform == "" || p.ChannelID == "" {
+		return
+	}
+	icon := "📦"
+	switch parcel.Status(p.Status) {
+	case parcel.StatusDelivered:
+		icon = "✅"
+	case parcel.StatusException, parcel.StatusReturned:
+		icon = "⚠️"
+	}
+	msg := fmt.Sprintf("%s 快递 %s：%s", icon, parcelTitle(p), parcelStatusName(p.Status))
+	if p.LastEvent != "" {
+		msg += "\n" + formatParcelEvent(p.LastEventAt, p.LastEvent)
+	}
+	if err := a.notifier.NotifyChatUser(p.Platform, p.ChannelID, p.UserID, msg); err != nil {
+		logger.Warn("[Agent] Failed to send parcel update for %d: %v", p.ID, err)
+	}
+}
+
+// autoTrackParcels follows the tracking numbers in a chat message and
+// returns a note for the reply. The first status arrives as a notification
+// so the reply is not held up by the lookup.
+func (a *Agent) autoTrackParcels(msg router.Message) string {
+	if a.persistStore == nil || msg.Username == "cron" {
+		return ""
+	}
+	if tracker, auto := a.parcelSettings(); tracker == nil || !auto {
+		return ""
+	}
+	userID := timeUserID(msg)
+	var added []string
+	for _, n := range parcel.ExtractNumbers(msg.Text) {
+		if existing, err := a.persistStore.FindParcel(userID, n.Number); err != nil || existing != nil {
+			continue
+		}
+		p := persist.Parcel{
+			UserID:    userID,
+			Platform:  msg.Platform,
+			ChannelID: msg.ChannelID,
+			Number:    n.Number,
+			Carrier:   n.Carrier,
+			Active:    true,
+		}
+		id, err := a.persistStore.AddParcel(p)
+		if err != nil {
+			logger.Warn("[Agent] Failed to save parcel %s: %v", n.Number, err)
+			continue
+		}
+		p.ID = id
+		added = append(added, n.Number)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), parcelFirstCheck)
+			defer cancel()
+			if _, err := a.checkParcel(ctx, &p, time.Now(), true); err != nil {
+				logger.Warn("[Agent] First check of parcel %s failed: %v", p.Number, err)
+			}
+		}()
+	}
+	if len(added) == 0 {
+		return ""
+	}
+	a.ensureParcelJob()
+	return fmt.Sprintf("📦 已自动跟踪快递 %s，状态变化时提醒你。", strings.Join(added, "、"))
+}
+
+// ensureParcelJob keeps one scheduled checker while any parcel is active
+// and removes it when none is.
+func (a *Agent) ensureParcelJob() string {
+	if a.cronScheduler == nil {
+		return ""
+	}
+	parcels, err := a.persistStore.Parcels("", true)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load parcels: %v", err)
+		return ""
+	}
+	var existing []string
+	for _, job := range a.cronScheduler.ListJobsByTag(parcelJobTag) {
+		if job.Name == parcelJobName {
+			existing = append(existing, job.ID)
+		}
+	}
+	switch {
+	case len(parcels) > 0 && len(existing) == 0:
+		if _, err := a.cronScheduler.AddJobWithTag(parcelJobName, parcelJobTag, parcelJobSchedule, "parcel_track", map[string]any{"action": "check"}); err != nil {
+			logger.Warn("[Agent] Failed to schedule parcel checks: %v", err)
+			return fmt.Sprintf("Warning: scheduled parcel checks not set up: %v", err)
+		}
+	case len(parcels) == 0:
+		for _, id := range existing {
+			if err := a.cronScheduler.RemoveJob(id); err != nil {
+				logger.Warn("[Agent] Failed to remove parcel check job: %v", err)
+			}
+		}
+	}
+	return ""
+}
+
+func parcelTitle(p persist.Parcel) string {
+	if p.Label != "" {
+		return p.Label + " (" + p.Number + ")"
+	}
+	return p.Number
+}
+
+func parcelStatusName(status string) string {
+	if name, ok := parcelStatusNames[status]; ok {
+		return name
+	}
+	return "未查询"
+}
+
+func formatParcelEvent(at time.Time, description string) string {
+	if at.IsZero() {
+		return description
+	}
+	return at.Local().Format("01-02 15:04") + " " + description
+}
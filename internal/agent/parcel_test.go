@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/parcel"
+	"github.com/kayz/coco/internal/router"
+)
+
+type fakeTracker struct {
+	mu    sync.Mutex
+	infos map[string][]parcel.Info // successive answers per number
+}
+
+func (f *fakeTracker) Name() string { return "fake" }
+
+func (f *fakeTracker) Track(ctx context.Context, q parcel.Query) (parcel.Info, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	answers := f.infos[q.Number]
+	info := answers[0]
+	if len(answers) > 1 {
+		f.infos[q.Number] = answers[1:]
+	}
+	return info, nil
+}
+
+func parcelInfo(status parcel.Status, events ...string) parcel.Info {
+	info := parcel.Info{Status: status}
+	at := time.Date(2026, 3, 9, 10, 0, 0, 0, time.Local)
+	for i, e := range events {
+		info.Events = append(info.Events, parcel.Event{Time: at.Add(-time.Duration(i) * time.Hour), Description: e})
+	}
+	return info
+}
+
+func TestParcelTrackNotifiesStatusChanges(t *testing.T) {
+	a, n := newFocusTestAgent(t)
+	a.parcelTracker = &fakeTracker{infos: map[string][]parcel.Info{
+		"SF1234567890123": {
+			parcelInfo(parcel.StatusInTransit, "【深圳】已发出"),
+			parcelInfo(parcel.StatusInTransit, "【深圳】已发出"),
+			parcelInfo(parcel.StatusOutForDelivery, "【杭州】派件中", "【深圳】已发出"),
+			parcelInfo(parcel.StatusDelivered, "已签收", "【杭州】派件中"),
+		},
+	}}
+
+	ctx := testTurn()
+	reply := a.executeParcelTrack(ctx, map[string]any{"number": "sf1234567890123", "label": "键盘"})
+	if !strings.Contains(reply, "#1 键盘 (SF1234567890123)") || !strings.Contains(reply, "状态: 运输中") || !strings.Contains(reply, "03-09 10:00 【深圳】已发出") {
+		t.Fatalf("track: %q", reply)
+	}
+
+	for i, want := range []string{"运输中", "派送中", "已签收"} {
+		if got := a.executeParcelTrack(ctx, map[string]any{"action": "check"}); !strings.Contains(got, want) {
+			t.Fatalf("check %d: %q, want %q", i, got, want)
+		}
+	}
+	sent := n.messages()
+	if len(sent) != 2 || !strings.Contains(sent[0], "📦 快递 键盘 (SF1234567890123)：派送中\n03-09 10:00 【杭州】派件中") || !strings.HasPrefix(sent[1], "✅") {
+		t.Fatalf("notifications = %q", sent)
+	}
+	if got := a.executeParcelTrack(ctx, map[string]any{"action": "check"}); got != "没有需要查询的快递" {
+		t.Fatalf("delivered parcel still checked: %q", got)
+	}
+	if list := a.executeParcelTrack(ctx, map[string]any{}); !strings.Contains(list, "已签收") {
+		t.Fatalf("list: %q", list)
+	}
+	if got := a.executeParcelTrack(ctx, map[string]any{"action": "remove", "number": "SF1234567890123"}); !strings.Contains(got, "已取消跟踪 #1") {
+		t.Fatalf("remove: %q", got)
+	}
+}
+
+func TestAutoTrackParcelsFromMessage(t *testing.T) {
+	a, n := newFocusTestAgent(t)
+	a.parcelAutoExtract = true
+	a.parcelTracker = &fakeTracker{infos: map[string][]parcel.Info{
+		"4312345678901": {parcelInfo(parcel.StatusInTransit, "【上海】已揽收")},
+	}}
+	msg := router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1", Text: "帮我看看快递单号：4312345678901 到哪了"}
+
+	if note := a.autoTrackParcels(msg); !strings.Contains(note, "已自动跟踪快递 4312345678901") {
+		t.Fatalf("note = %q", note)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(n.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sent := n.messages(); len(sent) != 1 || !strings.Contains(sent[0], "【上海】已揽收") {
+		t.Fatalf("first status = %q", sent)
+	}
+	if note := a.autoTrackParcels(msg); note != "" {
+		t.Fatalf("number tracked twice: %q", note)
+	}
+
+	a.parcelAutoExtract = false
+	msg.Text = "运单号 YT1234567890123"
+	if note := a.autoTrackParcels(msg); note != "" {
+		t.Fatalf("auto_extract off: %q", note)
+	}
+}
@@ -0,0 +1,343 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// defaultPlanApprovalTools are held for "/approve" when security.plan_approval is on
+// and security.plan_approval_tools is empty.
+var defaultPlanApprovalTools = []string{"file_write", "file_write_batch", "file_edit", "file_trash", "shell_execute", "browser_click_all", "broadcast"}
+
+// planApprovalTTL is how long a proposed action waits for "/approve".
+const planApprovalTTL = 15 * time.Minute
+
+// pendingAction is a tool call held back until the user approves its plan.
+type pendingAction struct {
+	Tool      string
+	Input     json.RawMessage
+	Plan      string
+	CreatedAt time.Time
+}
+
+// planApprovalQueue holds pending actions per conversation.
+type planApprovalQueue struct {
+	mu      sync.Mutex
+	pending map[string][]pendingAction
+}
+
+func (q *planApprovalQueue) add(convKey string, action pendingAction) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[string][]pendingAction)
+	}
+	q.pending[convKey] = append(q.live(convKey), action)
+	return len(q.pending[convKey])
+}
+
+// take removes and returns the unexpired actions of a conversation.
+func (q *planApprovalQueue) take(convKey string) []pendingAction {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	actions := q.live(convKey)
+	delete(q.pending, convKey)
+	return actions
+}
+
+func (q *planApprovalQueue) peek(convKey string) []pendingAction {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]pendingAction(nil), q.live(convKey)...)
+}
+
+// live drops expired actions. Callers must hold q.mu.
+func (q *planApprovalQueue) live(convKey string) []pendingAction {
+	var out []pendingAction
+	for _, a := range q.pending[convKey] {
+		if time.Since(a.CreatedAt) < planApprovalTTL {
+			out = append(out, a)
+		}
+	}
+	if q.pending != nil {
+		q.pending[convKey] = out
+	}
+	return out
+}
+
+type planApprovedKey struct{}
+
+// withPlanApproved marks ctx as carrying a user-approved action.
+func withPlanApproved(ctx context.Context) context.Context {
+	return context.WithValue(ctx, planApprovedKey{}, true)
+}
+
+func isPlanApproved(ctx context.Context) bool {
+	approved, _ := ctx.Value(planApprovedKey{}).(bool)
+	return approved
+}
+
+// applyPlanApproval sets which tools must be approved over chat before running.
+func (a *Agent) applyPlanApproval(enabled bool, toolNames []string) {
+	set := make(map[string]bool)
+	if enabled {
+		if len(toolNames) == 0 {
+			toolNames = defaultPlanApprovalTools
+		}
+		for _, name := range toolNames {
+			if name = strings.TrimSpace(name); name != "" {
+				set[name] = true
+			}
+		}
+	}
+	a.securityMu.Lock()
+	a.planApprovalTools = set
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) requiresPlanApproval(name string) bool {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.planApprovalTools[name]
+}
+
+// proposePlan holds a tool call and returns its plan for the model to relay.
+func (a *Agent) proposePlan(ctx context.Context, name string, args map[string]any, input json.RawMessage) string {
+	plan := describeToolPlan(name, args)
+	convKey := currentConversationKey(ctx)
+	n := a.planApprovals.add(convKey, pendingAction{
+		Tool:      name,
+		Input:     append(json.RawMessage(nil), input...),
+		Plan:      plan,
+		CreatedAt: time.Now(),
+	})
+	logger.Info("[Agent] %s held for approval in %s (%d pending)", name, convKey, n)
+	return fmt.Sprintf("PENDING APPROVAL: %s was NOT executed. Show the user this plan verbatim and ask them to reply /approve to run it or /reject to cancel. Do NOT retry the tool.\n\n%s", name, plan)
+}
+
+// handlePlanApprovalCommand serves "/approve", "/reject" and "/pending".
+func (a *Agent) handlePlanApprovalCommand(ctx context.Context, convKey, text string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/approve", "批准", "确认执行":
+		actions := a.planApprovals.take(convKey)
+		if len(actions) == 0 {
+			return "没有待确认的操作（可能已超时，超过 15 分钟需重新发起）。", true
+		}
+		var sb strings.Builder
+		for i, action := range actions {
+			result := redactSecretValues(a.executeTool(withPlanApproved(ctx), action.Tool, action.Input))
+			if len(actions) > 1 {
+				sb.WriteString(fmt.Sprintf("[%d/%d] ", i+1, len(actions)))
+			}
+			sb.WriteString(fmt.Sprintf("%s:\n%s\n\n", action.Tool, strings.TrimSpace(result)))
+		}
+		reply := strings.TrimSpace(sb.String())
+		// Keep the outcome in history so the model knows what actually ran.
+		a.memory.AddExchange(convKey,
+			Message{Role: "user", Content: "/approve"},
+			Message{Role: "assistant", Content: "Approved actions executed:\n" + reply},
+		)
+		return reply, true
+	case "/reject", "拒绝", "取消执行":
+		actions := a.planApprovals.take(convKey)
+		if len(actions) == 0 {
+			return "没有待确认的操作。", true
+		}
+		names := make([]string, 0, len(actions))
+		for _, action := range actions {
+			names = append(names, action.Tool)
+		}
+		a.memory.AddExchange(convKey,
+			Message{Role: "user", Content: "/reject"},
+			Message{Role: "assistant", Content: "The user rejected: " + strings.Join(names, ", ")},
+		)
+		return fmt.Sprintf("已取消 %d 个待确认操作：%s", len(actions), strings.Join(names, ", ")), true
+	case "/pending", "待确认":
+		actions := a.planApprovals.peek(convKey)
+		if len(actions) == 0 {
+			return "没有待确认的操作。", true
+		}
+		var sb strings.Builder
+		for i, action := range actions {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, action.Plan))
+		}
+		sb.WriteString("\n回复 /approve 全部执行，/reject 全部取消。")
+		return sb.String(), true
+	}
+	return "", false
+}
+
+// describeToolPlan renders what a destructive tool call would do.
+func describeToolPlan(name string, args map[string]any) string {
+	switch name {
+	case "file_write":
+		path, _ := args["path"].(string)
+		content, _ := args["content"].(string)
+		return planFileWrite(path, content)
+	case "file_write_batch":
+		return planFileWriteBatch(args)
+	case "file_edit":
+		return planFileEdit(args)
+	case "file_trash":
+		return planFileTrash(args["files"])
+	case "shell_execute":
+		command, _ := args["command"].(string)
+		wd, _ := os.Getwd()
+		plan := fmt.Sprintf("Run shell command in %s:\n$ %s", wd, command)
+		if t, ok := args["timeout"].(float64); ok && t > 0 {
+			plan += fmt.Sprintf("\n(timeout %ds)", int(t))
+		}
+		return plan
+	case "browser_click_all":
+		selector, _ := args["selector"].(string)
+		skip, _ := args["skip_selector"].(string)
+		plan := fmt.Sprintf("Click every element matching %q", selector)
+		if skip != "" {
+			plan += fmt.Sprintf(", skipping %q", skip)
+		}
+		total, skipped, err := tools.BrowserCountMatches(selector, skip)
+		if err != nil {
+			return plan + fmt.Sprintf("\nMatches: unknown (%v)", err)
+		}
+		return plan + fmt.Sprintf("\nMatches on the current page: %d (%d skipped, %d to click); more may load while scrolling.", total, skipped, total-skipped)
+	}
+	data, _ := json.MarshalIndent(args, "", "  ")
+	return fmt.Sprintf("Call %s with:\n%s", name, data)
+}
+
+func planFileWrite(path, content string) string {
+	resolved := resolveBestEffortPath(path)
+	old, err := os.ReadFile(resolved)
+	if err != nil {
+		lines := strings.Count(content, "\n")
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			lines++
+		}
+		return fmt.Sprintf("Create %s (%d lines, %d bytes):\n%s", resolved, lines, len(content), truncatePlanText(content, 40))
+	}
+	if string(old) == content {
+		return fmt.Sprintf("Rewrite %s with identical content (no change)", resolved)
+	}
+	return fmt.Sprintf("Overwrite %s:\n%s", resolved, unifiedLineDiff(string(old), content, 3, 60))
+}
+
+func planFileTrash(raw any) string {
+	var files []string
+	switch v := raw.(type) {
+	case []any:
+		for _, f := range v {
+			if s, ok := f.(string); ok {
+				files = append(files, s)
+			}
+		}
+	case string:
+		files = append(files, v)
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Move %d item(s) to Trash:", len(files)))
+	for _, f := range files {
+		resolved := resolveBestEffortPath(f)
+		info, err := os.Stat(resolved)
+		switch {
+		case err != nil:
+			sb.WriteString(fmt.Sprintf("\n- %s (not found)", resolved))
+		case info.IsDir():
+			entries, _ := os.ReadDir(resolved)
+			sb.WriteString(fmt.Sprintf("\n- %s%c (folder, %d entries)", resolved, filepath.Separator, len(entries)))
+		default:
+			sb.WriteString(fmt.Sprintf("\n- %s (%d bytes, modified %s)", resolved, info.Size(), info.ModTime().Format("2006-01-02 15:04")))
+		}
+	}
+	return sb.String()
+}
+
+func truncatePlanText(s string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= maxLines {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:maxLines], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-maxLines)
+}
+
+// unifiedLineDiff returns a unified-style diff of two texts, limited to maxLines
+// of output. Large inputs fall back to a size summary.
+func unifiedLineDiff(oldText, newText string, contextLines, maxLines int) string {
+	a := strings.Split(strings.TrimRight(oldText, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(newText, "\n"), "\n")
+	if len(a)*len(b) > 4_000_000 {
+		return fmt.Sprintf("(too large to diff: %d lines -> %d lines)", len(a), len(b))
+	}
+
+	// Longest common subsequence table, filled from the end.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-', '+'
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+
+	// Keep changed lines plus surrounding context.
+	keep := make([]bool, len(ops))
+	for k, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		for c := max(0, k-contextLines); c <= min(len(ops)-1, k+contextLines); c++ {
+			keep[c] = true
+		}
+	}
+	var out []string
+	skipped := false
+	for k, o := range ops {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped && len(out) > 0 {
+			out = append(out, "@@")
+		}
+		skipped = false
+		out = append(out, string(o.kind)+" "+o.text)
+	}
+	if len(out) > maxLines {
+		out = append(out[:maxLines], fmt.Sprintf("... (%d more diff lines)", len(out)-maxLines))
+	}
+	return strings.Join(out, "\n")
+}
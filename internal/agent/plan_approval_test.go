@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestPlanApprovalHoldsFileWriteUntilApproved(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+
+	target := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(target, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	a := &Agent{memory: NewMemory(store, 0)}
+	a.applyPlanApproval(true, nil)
+	ctx := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "c1", UserID: "u1"})
+	convKey := ConversationKey("wecom", "c1", "u1")
+
+	input, _ := json.Marshal(map[string]any{"path": target, "content": "one\n2\nthree\n"})
+	result := a.executeTool(ctx, "file_write", input)
+	if !strings.HasPrefix(result, "PENDING APPROVAL") || !strings.Contains(result, "- two") || !strings.Contains(result, "+ 2") {
+		t.Fatalf("expected a pending plan with a diff, got %q", result)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "one\ntwo\nthree\n" {
+		t.Fatalf("file must not change before approval, got %q", data)
+	}
+
+	if reply, ok := a.handlePlanApprovalCommand(context.Background(), ConversationKey("wecom", "c1", "other"), "/approve"); !ok || strings.Contains(reply, "file_write") {
+		t.Fatalf("another conversation must not approve the action, got %q", reply)
+	}
+	if reply, ok := a.handlePlanApprovalCommand(context.Background(), convKey, "/approve"); !ok || !strings.Contains(reply, "file_write") {
+		t.Fatalf("unexpected approve reply: %q", reply)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "one\n2\nthree\n" {
+		t.Fatalf("file not written after approval, got %q", data)
+	}
+	if reply, _ := a.handlePlanApprovalCommand(context.Background(), convKey, "/approve"); !strings.Contains(reply, "没有待确认") {
+		t.Fatalf("approval must be single-use, got %q", reply)
+	}
+}
+
+func TestUnifiedLineDiffKeepsContext(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	diff := unifiedLineDiff(old, strings.Replace(old, "g", "G", 1), 1, 60)
+	want := "  f\n- g\n+ G\n  h"
+	if diff != want {
+		t.Fatalf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/finetune"
+	"github.com/kayz/coco/internal/logger"
+)
+
+const (
+	defaultPlannerConfidence   = 0.85
+	defaultLocalPlannerTimeout = 3 * time.Second
+)
+
+// plannerSettings is the planner section of the config.
+type plannerSettings struct {
+	localURL      string
+	minConfidence float64
+	timeout       time.Duration
+	record        bool
+}
+
+func (a *Agent) applyPlanner(cfg config.PlannerConfig) {
+	s := plannerSettings{
+		localURL:      strings.TrimRight(strings.TrimSpace(cfg.LocalURL), "/"),
+		minConfidence: cfg.MinConfidence,
+		timeout:       defaultLocalPlannerTimeout,
+		record:        cfg.RecordDataset,
+	}
+	if raw := strings.TrimSpace(cfg.LocalTimeout); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			s.timeout = d
+		} else {
+			logger.Warn("[Agent] Invalid planner.local_timeout %q, using %s", cfg.LocalTimeout, defaultLocalPlannerTimeout)
+		}
+	}
+	if s.minConfidence <= 0 || s.minConfidence > 1 {
+		s.minConfidence = defaultPlannerConfidence
+	}
+	a.securityMu.Lock()
+	a.planner = s
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) currentPlanner() plannerSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.planner
+}
+
+// localPlan asks the llama.cpp server for a plan and returns it with its
+// confidence, the mean probability of the generated tokens. A variable so
+// tests can stand in for the server.
+var localPlan = func(ctx context.Context, s plannerSettings, systemPrompt, userPrompt string) (string, float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]any{
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": userPrompt},
+		},
+		"max_tokens":      600,
+		"temperature":     0,
+		"logprobs":        true,
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.localURL+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("local planner: %s", resp.Status)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Logprobs struct {
+				Content []struct {
+					Logprob float64 `json:"logprob"`
+				} `json:"content"`
+			} `json:"logprobs"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", 0, fmt.Errorf("local planner: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", 0, fmt.Errorf("local planner: no choices")
+	}
+	choice := out.Choices[0]
+	if len(choice.Logprobs.Content) == 0 {
+		// Without token probabilities there is nothing to trust the plan on.
+		return choice.Message.Content, 0, nil
+	}
+	sum := 0.0
+	for _, t := range choice.Logprobs.Content {
+		sum += t.Logprob
+	}
+	return choice.Message.Content, math.Exp(sum / float64(len(choice.Logprobs.Content))), nil
+}
+
+// planLocally returns the local model's plan, or nil when there is no local
+// model, it fails, or it is not confident enough.
+func (a *Agent) planLocally(ctx context.Context, s plannerSettings, systemPrompt, userPrompt string) *orchestrationPlan {
+	if s.localURL == "" {
+		return nil
+	}
+	start := time.Now()
+	content, confidence, err := localPlan(ctx, s, systemPrompt, userPrompt)
+	if err != nil {
+		logger.Warn("[Agent] Local planner failed, using the cloud planner: %v", err)
+		return nil
+	}
+	if confidence < s.minConfidence {
+		logger.Debug("[Agent] Local planner confidence %.2f below %.2f, using the cloud planner", confidence, s.minConfidence)
+		return nil
+	}
+	plan, err := parseOrchestrationPlan(content)
+	if err != nil {
+		logger.Warn("[Agent] Local planner: %v", err)
+		return nil
+	}
+	logger.Debug("[Agent] Local planner answered in %s (confidence %.2f)", time.Since(start).Round(time.Millisecond), confidence)
+	return plan
+}
+
+var plannerDatasetMu sync.Mutex
+
+// plannerDatasetPath is where cloud plans are collected for distillation.
+func plannerDatasetPath() string {
+	return datadir.Path(".coco", "planner-dataset.jsonl")
+}
+
+// recordPlannerExample appends a cloud plan to the distillation dataset.
+func recordPlannerExample(systemPrompt, userPrompt string, plan *orchestrationPlan) {
+	output, err := json.Marshal(plan)
+	if err != nil {
+		return
+	}
+	line, err := finetune.Encode(finetune.Trace{
+		System: systemPrompt,
+		Messages: []finetune.Message{
+			{Role: "user", Content: userPrompt},
+			{Role: "assistant", Content: string(output)},
+		},
+	}, "openai")
+	if err != nil {
+		return
+	}
+
+	plannerDatasetMu.Lock()
+	defer plannerDatasetMu.Unlock()
+	path := plannerDatasetPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Warn("[Agent] Failed to record planner example: %v", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		logger.Warn("[Agent] Failed to record planner example: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logger.Warn("[Agent] Failed to record planner example: %v", err)
+	}
+}
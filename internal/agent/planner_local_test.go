@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestLocalPlanner(t *testing.T) {
+	logprob := math.Log(0.95)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Logprobs bool `json:"logprobs"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/chat/completions" || !req.Logprobs {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{
+				"message":  map[string]string{"content": `{"need_clarification":false,"memory_queries":["旅行偏好"],"final_instruction":"简短回答","task_complexity":"SIMPLE"}`},
+				"logprobs": map[string]any{"content": []map[string]float64{{"logprob": logprob}, {"logprob": logprob}}},
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	a := &Agent{}
+	a.applyPlanner(config.PlannerConfig{LocalURL: srv.URL + "/"})
+	s := a.currentPlanner()
+	plan := a.planLocally(context.Background(), s, "system", "user")
+	if plan == nil || plan.TaskComplexity != "simple" || strings.Join(plan.MemoryQueries, ",") != "旅行偏好" {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	// Below the confidence threshold the cloud planner takes over.
+	a.applyPlanner(config.PlannerConfig{LocalURL: srv.URL, MinConfidence: 0.99})
+	if plan := a.planLocally(context.Background(), a.currentPlanner(), "system", "user"); plan != nil {
+		t.Fatalf("low-confidence plan used: %+v", plan)
+	}
+	a.applyPlanner(config.PlannerConfig{LocalURL: "http://127.0.0.1:1"})
+	if plan := a.planLocally(context.Background(), a.currentPlanner(), "system", "user"); plan != nil {
+		t.Fatal("unreachable local planner returned a plan")
+	}
+}
+
+func TestRecordPlannerExample(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	recordPlannerExample("planner system", "User input:\n订机票", &orchestrationPlan{FinalInstruction: "先确认日期", TaskComplexity: "normal"})
+	recordPlannerExample("planner system", "User input:\n天气", &orchestrationPlan{TaskComplexity: "simple"})
+
+	data, err := os.ReadFile(plannerDatasetPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("dataset:\n%s", data)
+	}
+	var rec struct {
+		Messages []struct{ Role, Content string } `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Messages) != 3 || rec.Messages[0].Role != "system" || !strings.Contains(rec.Messages[2].Content, `"final_instruction":"先确认日期"`) {
+		t.Fatalf("example = %+v", rec)
+	}
+}
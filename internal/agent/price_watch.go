@@ -0,0 +1,322 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/tools"
+)
+
+const (
+	priceWatchJobName  = "price-watch"
+	priceWatchJobTag   = "assistant-task"
+	priceWatchSchedule = "23 */6 * * *" // every six hours
+	priceChartPoints   = 30
+)
+
+// fetchPrice reads a product page's price; tests replace it.
+var fetchPrice = tools.FetchPrice
+
+func (a *Agent) executePriceWatch(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	action := strings.ToLower(strings.TrimSpace(getString(args, "action")))
+	if action == "" {
+		action = "list"
+		if getString(args, "url") != "" {
+			action = "add"
+		}
+	}
+	userID := timeUserID(turnMessage(ctx))
+	switch action {
+	case "add":
+		return a.addPriceWatch(ctx, userID, args)
+	case "list":
+		return a.listPriceWatches(userID)
+	case "remove", "delete":
+		return a.removePriceWatch(userID, args)
+	case "check":
+		return a.checkPriceWatches(ctx, userID)
+	case "report":
+		return a.formatPriceReport(userID, int64(getFloat(args, "id")))
+	default:
+		return "Error: action must be add, list, remove, check or report"
+	}
+}
+
+func (a *Agent) addPriceWatch(ctx context.Context, userID string, args map[string]any) string {
+	url := strings.TrimSpace(getString(args, "url"))
+	if url == "" {
+		return "Error: url is required"
+	}
+	target := getFloat(args, "target_price")
+	if target <= 0 {
+		return "Error: target_price must be a positive number"
+	}
+	w := persist.PriceWatch{
+		UserID:      userID,
+		Platform:    turnMessage(ctx).Platform,
+		ChannelID:   turnMessage(ctx).ChannelID,
+		URL:         url,
+		Title:       strings.TrimSpace(getString(args, "title")),
+		TargetPrice: target,
+		Active:      true,
+	}
+	id, err := a.persistStore.AddPriceWatch(w)
+	if err != nil {
+		return fmt.Sprintf("Error saving price watch: %v", err)
+	}
+	w.ID = id
+
+	line := a.checkPriceWatch(ctx, &w, time.Now(), false)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🏷 已关注 #%d：%s\n目标价 %s\n%s", w.ID, priceWatchTitle(w), formatPrice(w.TargetPrice, w.Currency), line)
+	if msg := a.ensurePriceWatchJob(); msg != "" {
+		sb.WriteString("\n" + msg)
+	}
+	return sb.String()
+}
+
+func (a *Agent) removePriceWatch(userID string, args map[string]any) string {
+	id := int64(getFloat(args, "id"))
+	w, err := a.persistStore.GetPriceWatch(id)
+	if err != nil {
+		return fmt.Sprintf("Error loading price watch: %v", err)
+	}
+	if w == nil || w.UserID != userID {
+		return fmt.Sprintf("没有编号为 #%d 的价格关注", id)
+	}
+	if err := a.persistStore.DeletePriceWatch(id); err != nil {
+		return fmt.Sprintf("Error removing price watch: %v", err)
+	}
+	a.ensurePriceWatchJob()
+	return fmt.Sprintf("已取消关注 #%d：%s", id, priceWatchTitle(*w))
+}
+
+func (a *Agent) listPriceWatches(userID string) string {
+	watches, err := a.persistStore.PriceWatches(userID, false)
+	if err != nil {
+		return fmt.Sprintf("Error loading price watches: %v", err)
+	}
+	if len(watches) == 0 {
+		return "还没有关注任何商品价格"
+	}
+	var sb strings.Builder
+	sb.WriteString("🏷 价格关注:\n")
+	for _, w := range watches {
+		fmt.Fprintf(&sb, "#%d %s\n  目标 %s", w.ID, priceWatchTitle(w), formatPrice(w.TargetPrice, w.Currency))
+		if w.LastPrice > 0 {
+			fmt.Fprintf(&sb, "，现价 %s", formatPrice(w.LastPrice, w.Currency))
+		}
+		if !w.LastChecked.IsZero() {
+			fmt.Fprintf(&sb, "（%s 检查）", w.LastChecked.Format("01-02 15:04"))
+		}
+		if w.LastError != "" {
+			fmt.Fprintf(&sb, "\n  ⚠️ 上次检查失败: %s", w.LastError)
+		}
+		sb.WriteString("\n  " + w.URL + "\n")
+	}
+	return sb.String()
+}
+
+// checkPriceWatches checks every active watch of userID, or of all users
+// when userID is empty, and reports one line per watch.
+func (a *Agent) checkPriceWatches(ctx context.Context, userID string) string {
+	watches, err := a.persistStore.PriceWatches(userID, true)
+	if err != nil {
+		return fmt.Sprintf("Error loading price watches: %v", err)
+	}
+	if len(watches) == 0 {
+		return "没有需要检查的价格关注"
+	}
+	var lines []string
+	for i := range watches {
+		if ctx.Err() != nil {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s: %s", watches[i].ID, priceWatchTitle(watches[i]), a.checkPriceWatch(ctx, &watches[i], time.Now(), true)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkPriceWatch fetches the current price, records it and, with alert
+// set, notifies the watch's chat the first time the price reaches the
+// target. The alert re-arms once the price goes back above the target.
+func (a *Agent) checkPriceWatch(ctx context.Context, w *persist.PriceWatch, now time.Time, alert bool) string {
+	q, err := fetchPrice(ctx, w.URL)
+	w.LastChecked = now
+	if err != nil {
+		w.LastError = err.Error()
+		if uerr := a.persistStore.UpdatePriceWatch(*w); uerr != nil {
+			logger.Warn("[Agent] Failed to save price watch %d: %v", w.ID, uerr)
+		}
+		return "读取价格失败: " + err.Error()
+	}
+
+	w.LastError = ""
+	w.LastPrice = q.Price
+	if w.Title == "" {
+		w.Title = q.Title
+	}
+	if q.Currency != "" {
+		w.Currency = q.Currency
+	}
+	if err := a.persistStore.AddPricePoint(w.ID, persist.PricePoint{Price: q.Price, CheckedAt: now}); err != nil {
+		logger.Warn("[Agent] Failed to record price for watch %d: %v", w.ID, err)
+	}
+
+	line := "现价 " + formatPrice(q.Price, w.Currency)
+	hit := q.Price <= w.TargetPrice
+	switch {
+	case hit && w.NotifiedAt.IsZero():
+		w.NotifiedAt = now
+		line += "，🎯 已达到目标价"
+		if alert {
+			a.sendPriceAlert(*w)
+		}
+	case hit:
+		line += "，仍低于目标价"
+	default:
+		w.NotifiedAt = time.Time{}
+	}
+	if err := a.persistStore.UpdatePriceWatch(*w); err != nil {
+		logger.Warn("[Agent] Failed to save price watch %d: %v", w.ID, err)
+	}
+	return line
+}
+
+func (a *Agent) sendPriceAlert(w persist.PriceWatch) {
+	if a.notifier == nil || w.Platform == "" || w.ChannelID == "" {
+		return
+	}
+	msg := fmt.Sprintf("📉 降价提醒：%s\n现价 %s，目标 %s\n%s",
+		priceWatchTitle(w), formatPrice(w.LastPrice, w.Currency), formatPrice(w.TargetPrice, w.Currency), w.URL)
+	if points, err := a.persistStore.PriceHistory(w.ID, priceChartPoints); err == nil && len(points) > 1 {
+		msg += "\n" + formatPriceChart(points, w.Currency)
+	}
+	if err := a.notifier.NotifyChatUser(w.Platform, w.ChannelID, w.UserID, msg); err != nil {
+		logger.Warn("[Agent] Failed to send price alert for watch %d: %v", w.ID, err)
+	}
+}
+
+// ensurePriceWatchJob keeps one scheduled checker while any watch is
+// active and removes it when none is.
+func (a *Agent) ensurePriceWatchJob() string {
+	if a.cronScheduler == nil {
+		return ""
+	}
+	watches, err := a.persistStore.PriceWatches("", true)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load price watches: %v", err)
+		return ""
+	}
+	var existing []string
+	for _, job := range a.cronScheduler.ListJobsByTag(priceWatchJobTag) {
+		if job.Name == priceWatchJobName {
+			existing = append(existing, job.ID)
+		}
+	}
+	switch {
+	case len(watches) > 0 && len(existing) == 0:
+		if _, err := a.cronScheduler.AddJobWithTag(priceWatchJobName, priceWatchJobTag, priceWatchSchedule, "price_watch", map[string]any{"action": "check"}); err != nil {
+			logger.Warn("[Agent] Failed to schedule price checks: %v", err)
+			return fmt.Sprintf("Warning: scheduled price checks not set up: %v", err)
+		}
+		return "每 6 小时自动检查一次价格，达到目标价时提醒你。"
+	case len(watches) == 0:
+		for _, id := range existing {
+			if err := a.cronScheduler.RemoveJob(id); err != nil {
+				logger.Warn("[Agent] Failed to remove price check job: %v", err)
+			}
+		}
+	}
+	return ""
+}
+
+// formatPriceReport charts the price history of one watch, or of all the
+// user's watches when id is 0. An empty userID covers every user.
+func (a *Agent) formatPriceReport(userID string, id int64) string {
+	watches, err := a.persistStore.PriceWatches(userID, false)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load price watches: %v", err)
+		return ""
+	}
+	var sb strings.Builder
+	for _, w := range watches {
+		if id != 0 && w.ID != id {
+			continue
+		}
+		points, err := a.persistStore.PriceHistory(w.ID, priceChartPoints)
+		if err != nil {
+			logger.Warn("[Agent] Failed to load price history for watch %d: %v", w.ID, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "  #%d %s（目标 %s）\n", w.ID, priceWatchTitle(w), formatPrice(w.TargetPrice, w.Currency))
+		if len(points) == 0 {
+			sb.WriteString("    暂无价格记录\n")
+			continue
+		}
+		sb.WriteString("    " + formatPriceChart(points, w.Currency) + "\n")
+	}
+	if sb.Len() == 0 {
+		if id != 0 {
+			return fmt.Sprintf("没有编号为 #%d 的价格关注", id)
+		}
+		return ""
+	}
+	return "📈 价格走势:\n" + sb.String()
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// formatPriceChart draws the prices as a sparkline with the low, high and
+// latest price.
+func formatPriceChart(points []persist.PricePoint, currency string) string {
+	low, high := points[0].Price, points[0].Price
+	for _, p := range points {
+		low = min(low, p.Price)
+		high = max(high, p.Price)
+	}
+	spark := make([]rune, len(points))
+	for i, p := range points {
+		level := 0
+		if high > low {
+			level = int((p.Price - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		spark[i] = sparkBlocks[level]
+	}
+	last := points[len(points)-1]
+	return fmt.Sprintf("%s 最低 %s 最高 %s 现价 %s（%s 起 %d 次）", string(spark),
+		formatPrice(low, currency), formatPrice(high, currency), formatPrice(last.Price, currency),
+		points[0].CheckedAt.Format("01-02"), len(points))
+}
+
+func formatPrice(price float64, currency string) string {
+	s := strconv.FormatFloat(price, 'f', 2, 64)
+	s = strings.TrimSuffix(s, ".00")
+	switch currency {
+	case "", "CNY":
+		return "¥" + s
+	case "USD":
+		return "$" + s
+	case "EUR":
+		return "€" + s
+	case "GBP":
+		return "£" + s
+	default:
+		return s + " " + currency
+	}
+}
+
+func priceWatchTitle(w persist.PriceWatch) string {
+	if w.Title != "" {
+		return feedbackExcerpt(w.Title, 40)
+	}
+	return w.URL
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/tools"
+)
+
+func TestPriceWatchAlertsOncePerDip(t *testing.T) {
+	a, n := newFocusTestAgent(t)
+	prices := []float64{5999, 5499, 5299, 6099, 5399}
+	var fail bool
+	old := fetchPrice
+	fetchPrice = func(ctx context.Context, url string) (tools.PriceQuote, error) {
+		if fail {
+			return tools.PriceQuote{}, errors.New("no price found on the page")
+		}
+		p := prices[0]
+		prices = prices[1:]
+		return tools.PriceQuote{Price: p, Currency: "CNY", Title: "ThinkPad X1 Carbon"}, nil
+	}
+	t.Cleanup(func() { fetchPrice = old })
+
+	ctx := testTurn()
+	reply := a.executePriceWatch(ctx, map[string]any{"url": "https://shop.example.com/x1", "target_price": "5500"})
+	if !strings.Contains(reply, "已关注 #1：ThinkPad X1 Carbon") || !strings.Contains(reply, "现价 ¥5999") {
+		t.Fatalf("add: %q", reply)
+	}
+
+	for i, want := range []string{"已达到目标价", "仍低于目标价", "现价 ¥6099", "已达到目标价"} {
+		if got := a.executePriceWatch(ctx, map[string]any{"action": "check"}); !strings.Contains(got, want) {
+			t.Fatalf("check %d: %q, want %q", i, got, want)
+		}
+	}
+	if sent := n.messages(); len(sent) != 2 || !strings.Contains(sent[0], "📉 降价提醒：ThinkPad X1 Carbon\n现价 ¥5499，目标 ¥5500") {
+		t.Fatalf("alerts = %q", sent)
+	}
+
+	fail = true
+	a.executePriceWatch(ctx, map[string]any{"action": "check"})
+	if list := a.executePriceWatch(ctx, map[string]any{}); !strings.Contains(list, "现价 ¥5399") || !strings.Contains(list, "上次检查失败: no price found") {
+		t.Fatalf("list: %q", list)
+	}
+
+	report := a.executePriceWatch(ctx, map[string]any{"action": "report"})
+	if !strings.Contains(report, "▇▂▁█▁ 最低 ¥5299 最高 ¥6099 现价 ¥5399") {
+		t.Fatalf("report: %q", report)
+	}
+	if !strings.Contains(a.formatPriceReport("", 0), "#1 ThinkPad X1 Carbon") {
+		t.Fatal("daily report misses the price chart")
+	}
+
+	if got := a.executePriceWatch(ctx, map[string]any{"action": "remove", "id": float64(1)}); !strings.Contains(got, "已取消关注 #1") {
+		t.Fatalf("remove: %q", got)
+	}
+	if got := a.executePriceWatch(ctx, map[string]any{"action": "list"}); got != "还没有关注任何商品价格" {
+		t.Fatalf("list after remove: %q", got)
+	}
+}
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+)
+
+const (
+	// proactiveInterval is how often the idle check-in looks for a quiet user.
+	proactiveInterval      = 10 * time.Minute
+	defaultProactiveIdle   = 4 * time.Hour
+	defaultQuietHours      = "22:00-08:00"
+	defaultProactivePerDay = 2
+	proactiveDecision      = "CHECKIN:"
+)
+
+// runCheckInPrompt asks the model whether to check in; tests replace it.
+var runCheckInPrompt = func(ctx context.Context, a *Agent, msg router.Message) (string, error) {
+	resp, err := a.scheduledPrompt(ctx, msg)
+	return resp.Text, err
+}
+
+type proactiveSettings struct {
+	enabled   bool
+	idle      time.Duration
+	quietFrom int // minutes after midnight; equal to quietTo when there are no quiet hours
+	quietTo   int
+	maxPerDay int
+	target    []string // platform, channel, user; nil follows the user
+}
+
+// proactiveState is what the idle check-in knows about the user. It lives
+// in memory, so a restart starts a fresh idle period.
+type proactiveState struct {
+	mu          sync.Mutex
+	lastMessage time.Time
+	lastChat    []string // platform, channel, user of the last message
+	lastCheckIn time.Time
+	day         string
+	sent        int
+}
+
+// applyProactive installs the proactive section.
+func (a *Agent) applyProactive(cfg config.ProactiveConfig) {
+	s := proactiveSettings{enabled: cfg.Enabled, idle: defaultProactiveIdle, maxPerDay: cfg.MaxPerDay}
+	if raw := strings.TrimSpace(cfg.Idle); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			s.idle = d
+		} else {
+			logger.Warn("[Agent] Invalid proactive.idle %q, using %s", cfg.Idle, defaultProactiveIdle)
+		}
+	}
+	quiet := strings.TrimSpace(cfg.QuietHours)
+	if quiet == "" {
+		quiet = defaultQuietHours
+	}
+	from, to, err := parseQuietHours(quiet)
+	if err != nil {
+		logger.Warn("[Agent] Invalid proactive.quiet_hours: %v; using %s", err, defaultQuietHours)
+		from, to, _ = parseQuietHours(defaultQuietHours)
+	}
+	s.quietFrom, s.quietTo = from, to
+	if s.maxPerDay <= 0 {
+		s.maxPerDay = defaultProactivePerDay
+	}
+	if target := strings.TrimSpace(cfg.Target); target != "" {
+		if parts := splitChatTarget(target); len(parts) == 3 {
+			s.target = parts
+		} else {
+			logger.Warn("[Agent] proactive.target %q is not \"platform:channel_id:user_id\"; following the user instead", cfg.Target)
+		}
+	}
+
+	a.securityMu.Lock()
+	a.proactive = s
+	a.securityMu.Unlock()
+}
+
+func (a *Agent) currentProactive() proactiveSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.proactive
+}
+
+// parseQuietHours parses "HH:MM-HH:MM" into minutes after midnight. "off"
+// means no quiet hours.
+func parseQuietHours(raw string) (from, to int, err error) {
+	if strings.EqualFold(raw, "off") {
+		return 0, 0, nil
+	}
+	start, end, ok := strings.Cut(raw, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not HH:MM-HH:MM", raw)
+	}
+	var bounds [2]int
+	for i, part := range []string{start, end} {
+		at, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("%q is not HH:MM-HH:MM", raw)
+		}
+		bounds[i] = at.Hour()*60 + at.Minute()
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// quietHours is a daily window in minutes after midnight, as parsed by
+// parseQuietHours; from == to means there is none.
+type quietHours struct{ from, to int }
+
+// contains reports whether now falls in the window.
+func (h quietHours) contains(now time.Time) bool {
+	m := now.Hour()*60 + now.Minute()
+	switch {
+	case h.from == h.to:
+		return false
+	case h.from < h.to:
+		return m >= h.from && m < h.to
+	default:
+		return m >= h.from || m < h.to
+	}
+}
+
+// end returns when the window holding now is over.
+func (h quietHours) end(now time.Time) time.Time {
+	end := time.Date(now.Year(), now.Month(), now.Day(), h.to/60, h.to%60, 0, 0, now.Location())
+	if !end.After(now) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func (h quietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", h.from/60, h.from%60, h.to/60, h.to%60)
+}
+
+// quiet reports whether now falls in the quiet hours.
+func (s proactiveSettings) quiet(now time.Time) bool {
+	return quietHours{s.quietFrom, s.quietTo}.contains(now)
+}
+
+// noteUserMessage restarts the idle period; only messages the owner sent
+// count, not scheduled prompts or senders with less than the admin profile.
+// Check-ins review the owner's tasks, so they follow the owner's last direct
+// chat, never a group or a sender the security policy turns away.
+func (a *Agent) noteUserMessage(msg router.Message, now time.Time) {
+	if msg.Platform == "" || msg.ChannelID == "" || msg.UserID == "" {
+		return
+	}
+	snapshot := a.securitySnapshot()
+	if len(snapshot.allowFrom) > 0 && !isSenderAllowed(msg, snapshot.allowFrom) {
+		return
+	}
+	group := isGroupConversation(msg)
+	if group && snapshot.requireMentionInGroup && !isMessageExplicitlyMentioned(msg) {
+		return
+	}
+	if a.toolProfileFor(msg).Name != security.ProfileAdmin {
+		return
+	}
+	st := &a.proactiveState
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.lastMessage = now
+	if !group {
+		st.lastChat = []string{msg.Platform, msg.ChannelID, msg.UserID}
+	}
+}
+
+// claimCheckIn returns the chat to check in with when one is due at now,
+// and starts a new idle period so the user is not reviewed again before
+// another one has passed.
+func (a *Agent) claimCheckIn(now time.Time) ([]string, bool) {
+	s := a.currentProactive()
+	if !s.enabled || s.quiet(now) {
+		return nil, false
+	}
+	st := &a.proactiveState
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if day := now.Format("2006-01-02"); st.day != day {
+		st.day, st.sent = day, 0
+	}
+	since := st.lastMessage
+	if st.lastCheckIn.After(since) {
+		since = st.lastCheckIn
+	}
+	if since.IsZero() || now.Sub(since) < s.idle || st.sent >= s.maxPerDay {
+		return nil, false
+	}
+	target := s.target
+	if target == nil {
+		target = st.lastChat
+	}
+	if target == nil {
+		return nil, false
+	}
+	st.lastCheckIn = now
+	return target, true
+}
+
+func (a *Agent) countCheckIn() {
+	a.proactiveState.mu.Lock()
+	a.proactiveState.sent++
+	a.proactiveState.mu.Unlock()
+}
+
+// StartProactive looks for an idle user every few minutes and, following
+// the proactive section of the current config, may send a check-in.
+func (a *Agent) StartProactive(ctx context.Context) {
+	a.proactiveState.mu.Lock()
+	if a.proactiveState.lastMessage.IsZero() {
+		a.proactiveState.lastMessage = time.Now()
+	}
+	a.proactiveState.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(proactiveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.runCheckIn(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// runCheckIn reviews open tasks and the daily report with the model when
+// a check-in is due, and sends its message unless it finds nothing worth
+// the interruption.
+func (a *Agent) runCheckIn(ctx context.Context, now time.Time) {
+	if a.notifier == nil {
+		return
+	}
+	target, ok := a.claimCheckIn(now)
+	if !ok {
+		return
+	}
+	prompt := buildCheckInPrompt(briefingTasks(ctx), a.getReportNotification(), now)
+	msg := router.Message{Platform: target[0], ChannelID: target[1], UserID: target[2], Username: "proactive", Text: prompt}
+	result, err := runCheckInPrompt(ctx, a, msg)
+	if err != nil {
+		logger.Warn("[Agent] Idle check-in failed: %v", err)
+		return
+	}
+	send, body := parseCheckInDecision(result)
+	if !send {
+		logger.Info("[Agent] Idle check-in: nothing worth sending")
+		return
+	}
+	if err := a.notifier.NotifyChatUser(target[0], target[1], target[2], body); err != nil {
+		logger.Warn("[Agent] Failed to send idle check-in: %v", err)
+		return
+	}
+	a.countCheckIn()
+	logger.Info("[Agent] Sent idle check-in to %s", strings.Join(target, ":"))
+}
+
+func buildCheckInPrompt(tasks, report string, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[主动关怀] 用户已有一段时间没有发消息，现在是 %s。\n", now.Format("2006-01-02 15:04"))
+	b.WriteString("请回顾下面的待办和日报，判断是否值得主动打扰用户：例如有即将到期或逾期的任务、今天的安排需要提醒，或之前的对话有需要跟进的事。\n")
+	b.WriteString("没有具体、有用的内容时不要发送寒暄。\n\n")
+	if tasks = strings.TrimSpace(tasks); tasks != "" && !strings.HasPrefix(tasks, "Error") {
+		b.WriteString("## 今日待办\n" + tasks + "\n\n")
+	}
+	if report = strings.TrimSpace(report); report != "" {
+		b.WriteString("## 日报\n" + report + "\n\n")
+	}
+	b.WriteString("请在第一行严格输出：" + proactiveDecision + " yes 或 " + proactiveDecision + " no\n")
+	b.WriteString("选择 yes 时，从第二行开始写一条简短的消息（不超过三句话），直接发给用户。")
+	return b.String()
+}
+
+// parseCheckInDecision reads the model's decision line. Anything without a
+// clear yes and a message is not sent.
+func parseCheckInDecision(result string) (send bool, body string) {
+	result = strings.TrimSpace(strings.ReplaceAll(result, "\r\n", "\n"))
+	first, rest, _ := strings.Cut(result, "\n")
+	first = strings.TrimSpace(first)
+	if !strings.HasPrefix(strings.ToUpper(first), proactiveDecision) {
+		return false, ""
+	}
+	switch strings.ToLower(strings.TrimSpace(first[len(proactiveDecision):])) {
+	case "yes", "true", "1":
+		body = strings.TrimSpace(rest)
+		return body != "", body
+	}
+	return false, ""
+}
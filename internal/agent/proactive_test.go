@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestQuietHoursWrapMidnight(t *testing.T) {
+	a := &Agent{}
+	a.applyProactive(config.ProactiveConfig{Enabled: true})
+	s := a.currentProactive()
+	for clock, want := range map[string]bool{"21:59": false, "22:00": true, "03:00": true, "07:59": true, "08:00": false, "14:00": false} {
+		at, _ := time.Parse("15:04", clock)
+		if got := s.quiet(at); got != want {
+			t.Errorf("quiet(%s) = %v, want %v", clock, got, want)
+		}
+	}
+
+	a.applyProactive(config.ProactiveConfig{Enabled: true, QuietHours: "off"})
+	if a.currentProactive().quiet(time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)) {
+		t.Fatal("quiet hours should be off")
+	}
+}
+
+func TestIdleCheckInRespectsIdleQuietHoursAndDailyCap(t *testing.T) {
+	notifier := &recordingNotifier{}
+	a := &Agent{notifier: notifier}
+	a.applyProactive(config.ProactiveConfig{Enabled: true, Idle: "2h", QuietHours: "22:00-08:00", MaxPerDay: 1})
+
+	var prompts []string
+	reply := "CHECKIN: yes\n下午三点的报销截止，需要我帮你整理发票吗？"
+	orig := runCheckInPrompt
+	runCheckInPrompt = func(ctx context.Context, a *Agent, msg router.Message) (string, error) {
+		prompts = append(prompts, msg.Text)
+		return reply, nil
+	}
+	origTasks := briefingTasks
+	briefingTasks = func(ctx context.Context) string { return "- 15:00 报销截止" }
+	defer func() { runCheckInPrompt, briefingTasks = orig, origTasks }()
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	ctx := context.Background()
+	a.noteUserMessage(router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1", Text: "早"}, day.Add(9*time.Hour))
+
+	a.runCheckIn(ctx, day.Add(10*time.Hour))
+	if len(prompts) != 0 {
+		t.Fatal("checked in before the idle period passed")
+	}
+	a.runCheckIn(ctx, day.Add(11*time.Hour))
+	if len(notifier.messages) != 1 || notifier.targets[0] != "slack:dm:u1" || !strings.Contains(notifier.messages[0], "报销") {
+		t.Fatalf("check-in = %q to %q", notifier.messages, notifier.targets)
+	}
+	if !strings.Contains(prompts[0], "报销截止") || !strings.Contains(prompts[0], "CHECKIN: yes") {
+		t.Fatalf("prompt does not ask for a decision: %q", prompts[0])
+	}
+
+	a.runCheckIn(ctx, day.Add(14*time.Hour))
+	if len(prompts) != 1 {
+		t.Fatal("checked in past the daily cap")
+	}
+
+	// A new day resets the cap, but not during quiet hours.
+	a.runCheckIn(ctx, day.Add(25*time.Hour))
+	if len(prompts) != 1 {
+		t.Fatal("checked in during quiet hours")
+	}
+	reply = "CHECKIN: no"
+	a.runCheckIn(ctx, day.Add(33*time.Hour))
+	if len(prompts) != 2 || len(notifier.messages) != 1 {
+		t.Fatalf("prompts = %d, messages = %d", len(prompts), len(notifier.messages))
+	}
+	// Declining still starts a new idle period.
+	a.runCheckIn(ctx, day.Add(34*time.Hour))
+	if len(prompts) != 2 {
+		t.Fatal("reviewed again before another idle period passed")
+	}
+}
+
+func TestIdleCheckInFollowsOnlyTheOwnersDirectChat(t *testing.T) {
+	a := &Agent{
+		allowFrom:      []string{"slack:owner", "slack:guest"},
+		senderProfiles: map[string]string{"slack:guest": "readonly"},
+	}
+	a.applyProactive(config.ProactiveConfig{Enabled: true, Idle: "1h", QuietHours: "off"})
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+
+	a.noteUserMessage(router.Message{Platform: "slack", ChannelID: "dm", UserID: "owner"}, now)
+	for _, msg := range []router.Message{
+		{Platform: "slack", ChannelID: "dm2", UserID: "stranger"},
+		{Platform: "slack", ChannelID: "dm3", UserID: "guest"},
+		{Platform: "slack", ChannelID: "team", UserID: "owner", Metadata: map[string]string{"chat_type": "group"}},
+	} {
+		a.noteUserMessage(msg, now.Add(time.Minute))
+	}
+	target, ok := a.claimCheckIn(now.Add(2 * time.Hour))
+	if !ok || strings.Join(target, ":") != "slack:dm:owner" {
+		t.Fatalf("check-in target = %v, %v", target, ok)
+	}
+}
+
+func TestParseCheckInDecision(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		send bool
+	}{
+		{"CHECKIN: yes\n记得交周报", true},
+		{"checkin: YES\r\n记得交周报", true},
+		{"CHECKIN: yes", false},
+		{"CHECKIN: no\n没什么事", false},
+		{"记得交周报", false},
+	} {
+		if send, body := parseCheckInDecision(tc.in); send != tc.send || (send && body != "记得交周报") {
+			t.Errorf("parseCheckInDecision(%q) = %v, %q", tc.in, send, body)
+		}
+	}
+}
@@ -0,0 +1,563 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/provenance"
+	"github.com/kayz/coco/internal/router"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	projectJobPrefix    = "project:"
+	projectDefaultNudge = "0 9 * * 1" // Monday morning
+	projectProgressKeep = 50          // progress lines kept in the plan note
+)
+
+// Section headings of a plan note.
+const (
+	projectGoalHeading      = "目标"
+	projectMilestoneHeading = "里程碑"
+	projectNextHeading      = "下一步"
+	projectProgressHeading  = "进展"
+)
+
+// projectMeta is the frontmatter of a plan note.
+type projectMeta struct {
+	Project  string `yaml:"project"`
+	Goal     string `yaml:"goal"`
+	Status   string `yaml:"status"` // active | paused | done
+	Nudge    string `yaml:"nudge,omitempty"`
+	Created  string `yaml:"created"`
+	Deadline string `yaml:"deadline,omitempty"`
+}
+
+type planItem struct {
+	Text string
+	Done bool
+}
+
+type planSection struct {
+	Title string
+	Body  string
+}
+
+// projectPlan is a long-running project's living plan, kept as a Markdown
+// note so the user can read and edit it alongside the agent.
+type projectPlan struct {
+	projectMeta
+	Milestones  []planItem
+	NextActions []planItem
+	Progress    []string
+	Extra       []planSection // sections coco does not manage, kept as written
+}
+
+// projectsDir is where plan notes live: Projects/ in the Obsidian vault when
+// one is configured, the workspace otherwise.
+func (a *Agent) projectsDir() string {
+	md := a.markdownStore()
+	if md.IsEnabled() && md.obsidianVault != "" {
+		return filepath.Join(md.obsidianVault, "Projects")
+	}
+	return filepath.Join(getWorkspaceDir(), "projects")
+}
+
+func projectSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	return slug
+}
+
+func (a *Agent) projectPath(name string) string {
+	return filepath.Join(a.projectsDir(), projectSlug(name)+".md")
+}
+
+func (a *Agent) loadProject(name string) (*projectPlan, error) {
+	if projectSlug(name) == "" {
+		return nil, fmt.Errorf("project name is required")
+	}
+	data, err := os.ReadFile(a.projectPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("project %q not found", name)
+		}
+		return nil, err
+	}
+	return parseProjectPlan(string(data))
+}
+
+func (a *Agent) saveProject(p *projectPlan) error {
+	md := a.markdownStore()
+	path := a.projectPath(p.Project)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(renderProjectPlan(p)), 0o644); err != nil {
+		return err
+	}
+	if md.IsEnabled() {
+		md.evict(path)
+	}
+	return nil
+}
+
+// listProjects loads every plan note, active projects first.
+func (a *Agent) listProjects() []*projectPlan {
+	paths, _ := filepath.Glob(filepath.Join(a.projectsDir(), "*.md"))
+	var plans []*projectPlan
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		p, err := parseProjectPlan(string(data))
+		if err != nil {
+			logger.Warn("[Agent] Skipping project note %s: %v", path, err)
+			continue
+		}
+		plans = append(plans, p)
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		ai, aj := plans[i].Status == "active", plans[j].Status == "active"
+		if ai != aj {
+			return ai
+		}
+		return plans[i].Project < plans[j].Project
+	})
+	return plans
+}
+
+func parseProjectPlan(content string) (*projectPlan, error) {
+	fm, body := splitMarkdownFrontMatter(content)
+	p := &projectPlan{}
+	if fm == "" {
+		return nil, fmt.Errorf("plan note has no frontmatter")
+	}
+	if err := yaml.Unmarshal([]byte(fm), &p.projectMeta); err != nil {
+		return nil, fmt.Errorf("parse frontmatter: %w", err)
+	}
+	if strings.TrimSpace(p.Project) == "" {
+		return nil, fmt.Errorf("plan note has no project name")
+	}
+	if p.Status == "" {
+		p.Status = "active"
+	}
+
+	var title string
+	var lines []string
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		switch title {
+		case "":
+		case projectGoalHeading:
+			if p.Goal == "" {
+				p.Goal = text
+			}
+		case projectMilestoneHeading:
+			p.Milestones = parsePlanItems(text)
+		case projectNextHeading:
+			p.NextActions = parsePlanItems(text)
+		case projectProgressHeading:
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- ")); line != "" {
+					p.Progress = append(p.Progress, line)
+				}
+			}
+		default:
+			p.Extra = append(p.Extra, planSection{Title: title, Body: text})
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			flush()
+			title = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return p, nil
+}
+
+func parsePlanItems(text string) []planItem {
+	var items []planItem
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "- ") && !strings.HasPrefix(line, "* ") {
+			continue
+		}
+		line = strings.TrimSpace(line[2:])
+		item := planItem{Text: line}
+		switch {
+		case strings.HasPrefix(line, "[ ]"):
+			item.Text = strings.TrimSpace(line[3:])
+		case strings.HasPrefix(line, "[x]"), strings.HasPrefix(line, "[X]"):
+			item.Text, item.Done = strings.TrimSpace(line[3:]), true
+		}
+		if item.Text != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func renderProjectPlan(p *projectPlan) string {
+	fm, _ := yaml.Marshal(p.projectMeta)
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.Write(fm)
+	sb.WriteString("---\n\n")
+	fmt.Fprintf(&sb, "# %s\n", p.Project)
+
+	section := func(title, body string) {
+		fmt.Fprintf(&sb, "\n## %s\n\n", title)
+		if body != "" {
+			sb.WriteString(body)
+			sb.WriteString("\n")
+		}
+	}
+	section(projectGoalHeading, p.Goal)
+	section(projectMilestoneHeading, renderPlanItems(p.Milestones))
+	section(projectNextHeading, renderPlanItems(p.NextActions))
+	var progress []string
+	for _, line := range p.Progress {
+		progress = append(progress, "- "+line)
+	}
+	section(projectProgressHeading, strings.Join(progress, "\n"))
+	for _, s := range p.Extra {
+		section(s.Title, s.Body)
+	}
+	return sb.String()
+}
+
+func renderPlanItems(items []planItem) string {
+	lines := make([]string, 0, len(items))
+	for _, it := range items {
+		box := "[ ]"
+		if it.Done {
+			box = "[x]"
+		}
+		lines = append(lines, fmt.Sprintf("- %s %s", box, it.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *projectPlan) logProgress(entry string) {
+	entry = strings.Join(strings.Fields(entry), " ")
+	if entry == "" {
+		return
+	}
+	p.Progress = append(p.Progress, time.Now().Format("2006-01-02")+": "+entry)
+	if len(p.Progress) > projectProgressKeep {
+		p.Progress = p.Progress[len(p.Progress)-projectProgressKeep:]
+	}
+}
+
+// completeItem checks off the first milestone or next action matching text.
+// Finished next actions leave the list and go to the progress log.
+func (p *projectPlan) completeItem(text string) bool {
+	needle := strings.ToLower(strings.TrimSpace(text))
+	if needle == "" {
+		return false
+	}
+	for i, it := range p.NextActions {
+		if strings.Contains(strings.ToLower(it.Text), needle) {
+			p.NextActions = append(p.NextActions[:i:i], p.NextActions[i+1:]...)
+			p.logProgress("完成 " + it.Text)
+			return true
+		}
+	}
+	for i, it := range p.Milestones {
+		if !it.Done && strings.Contains(strings.ToLower(it.Text), needle) {
+			p.Milestones[i].Done = true
+			p.logProgress("达成里程碑 " + it.Text)
+			return true
+		}
+	}
+	return false
+}
+
+func stringListArg(args map[string]any, key string) []string {
+	var out []string
+	switch v := args[key].(type) {
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+	case string:
+		if strings.TrimSpace(v) != "" {
+			out = append(out, strings.TrimSpace(v))
+		}
+	}
+	return out
+}
+
+func planItemsFrom(texts []string) []planItem {
+	items := make([]planItem, 0, len(texts))
+	for _, t := range texts {
+		items = append(items, planItem{Text: t})
+	}
+	return items
+}
+
+func (a *Agent) executeProjectCreate(ctx context.Context, args map[string]any) string {
+	name := strings.TrimSpace(getString(args, "name"))
+	goal := strings.TrimSpace(getString(args, "goal"))
+	if projectSlug(name) == "" || goal == "" {
+		return "Error: name and goal are required"
+	}
+	if _, err := os.Stat(a.projectPath(name)); err == nil {
+		return fmt.Sprintf("Error: project %q already exists; use project_update to change its plan", name)
+	}
+
+	nudge := strings.TrimSpace(getString(args, "nudge"))
+	if nudge == "" {
+		nudge = projectDefaultNudge
+	}
+	p := &projectPlan{
+		projectMeta: projectMeta{
+			Project:  name,
+			Goal:     goal,
+			Status:   "active",
+			Nudge:    nudge,
+			Created:  time.Now().Format("2006-01-02"),
+			Deadline: strings.TrimSpace(getString(args, "deadline")),
+		},
+		Milestones:  planItemsFrom(stringListArg(args, "milestones")),
+		NextActions: planItemsFrom(stringListArg(args, "next_actions")),
+	}
+	p.logProgress("项目创建")
+	if err := a.saveProject(p); err != nil {
+		return fmt.Sprintf("Error saving project plan: %v", err)
+	}
+
+	result := fmt.Sprintf("Project %q created. Plan note: %s", name, a.projectPath(name))
+	if note := a.syncProjectNudge(p, turnMessage(ctx)); note != "" {
+		result += "\n" + note
+	}
+	return result
+}
+
+func (a *Agent) executeProjectUpdate(ctx context.Context, args map[string]any) string {
+	p, err := a.loadProject(getString(args, "name"))
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+
+	var missing []string
+	for _, text := range stringListArg(args, "done") {
+		if !p.completeItem(text) {
+			missing = append(missing, text)
+		}
+	}
+	p.Milestones = append(p.Milestones, planItemsFrom(stringListArg(args, "add_milestones"))...)
+	p.NextActions = append(p.NextActions, planItemsFrom(stringListArg(args, "add_next_actions"))...)
+	p.logProgress(getString(args, "progress"))
+	if d := strings.TrimSpace(getString(args, "deadline")); d != "" {
+		p.Deadline = d
+	}
+
+	oldStatus := p.Status
+	if status := strings.ToLower(strings.TrimSpace(getString(args, "status"))); status != "" {
+		switch status {
+		case "active", "paused", "done":
+			p.Status = status
+		default:
+			return "Error: status must be active, paused or done"
+		}
+	}
+	if p.Status != oldStatus {
+		p.logProgress(fmt.Sprintf("状态 %s → %s", oldStatus, p.Status))
+	}
+
+	if err := a.saveProject(p); err != nil {
+		return fmt.Sprintf("Error saving project plan: %v", err)
+	}
+	result := fmt.Sprintf("Project %q updated.\n\n%s", p.Project, formatProjectStatus(p, true))
+	if len(missing) > 0 {
+		result += "\nNot found in the plan (nothing checked off): " + strings.Join(missing, "; ")
+	}
+	if p.Status != oldStatus {
+		if note := a.syncProjectNudge(p, turnMessage(ctx)); note != "" {
+			result += "\n" + note
+		}
+	}
+	return result
+}
+
+func (a *Agent) executeProjectStatus(args map[string]any) string {
+	if name := strings.TrimSpace(getString(args, "name")); name != "" {
+		p, err := a.loadProject(name)
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		return formatProjectStatus(p, true)
+	}
+	return a.formatProjects()
+}
+
+// formatProjects summarizes every project for "/project status".
+func (a *Agent) formatProjects() string {
+	plans := a.listProjects()
+	if len(plans) == 0 {
+		return "暂无长期项目（让我用 project_create 建立一个，例如“帮我开个项目：三个月内学会日语 N3”）"
+	}
+	var sb strings.Builder
+	sb.WriteString("📁 长期项目:\n")
+	for _, p := range plans {
+		sb.WriteString("\n")
+		sb.WriteString(formatProjectStatus(p, false))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// formatProjectStatus renders a project's state; full lists every open item
+// instead of just the first few.
+func formatProjectStatus(p *projectPlan, full bool) string {
+	var sb strings.Builder
+	done := 0
+	for _, m := range p.Milestones {
+		if m.Done {
+			done++
+		}
+	}
+	fmt.Fprintf(&sb, "【%s】%s · 里程碑 %d/%d", p.Project, projectStatusLabel(p.Status), done, len(p.Milestones))
+	if p.Deadline != "" {
+		fmt.Fprintf(&sb, " · 截止 %s", p.Deadline)
+	}
+	sb.WriteString("\n")
+	if full {
+		fmt.Fprintf(&sb, "目标: %s\n", p.Goal)
+		for _, m := range p.Milestones {
+			if !m.Done {
+				fmt.Fprintf(&sb, "  里程碑: %s\n", m.Text)
+			}
+		}
+	}
+
+	limit := 3
+	if full {
+		limit = len(p.NextActions)
+	}
+	for i, it := range p.NextActions {
+		if i == limit {
+			fmt.Fprintf(&sb, "  …另有 %d 项\n", len(p.NextActions)-limit)
+			break
+		}
+		fmt.Fprintf(&sb, "  下一步: %s\n", it.Text)
+	}
+	if n := len(p.Progress); n > 0 {
+		fmt.Fprintf(&sb, "  最近进展: %s\n", p.Progress[n-1])
+	}
+	return sb.String()
+}
+
+func projectStatusLabel(status string) string {
+	switch status {
+	case "paused":
+		return "暂停"
+	case "done":
+		return "已完成"
+	default:
+		return "进行中"
+	}
+}
+
+// syncProjectNudge keeps the project's heartbeat job in line with its
+// status: active projects with a nudge schedule get one, others don't.
+func (a *Agent) syncProjectNudge(p *projectPlan, msg router.Message) string {
+	if a.cronScheduler == nil {
+		return ""
+	}
+	jobName := projectJobPrefix + projectSlug(p.Project)
+	for _, job := range a.cronScheduler.ListJobsByTag(heartbeatJobTag) {
+		if job.Name == jobName {
+			if err := a.cronScheduler.RemoveJob(job.ID); err != nil {
+				logger.Warn("[Agent] Failed to remove project nudge %s: %v", jobName, err)
+			}
+		}
+	}
+	if p.Status != "active" || p.Nudge == "" || strings.EqualFold(p.Nudge, "off") {
+		return ""
+	}
+	if msg.Platform == "" || msg.ChannelID == "" || msg.UserID == "" {
+		return ""
+	}
+
+	prompt := fmt.Sprintf(`推进长期项目「%s」：
+1. 用 project_status 读取它的计划（name=%s）
+2. 简短提醒用户最靠前的一两个下一步，并问一句进展
+3. 如果计划明显过时（截止日期已过、没有下一步），建议调整
+用户回复进展后，用 project_update 记录。`, p.Project, p.Project)
+	job, err := a.cronScheduler.AddJobWithPromptAndTag(
+		jobName,
+		heartbeatJobTag,
+		p.Nudge,
+		decorateHeartbeatPrompt(prompt, "always"),
+		msg.Platform,
+		msg.ChannelID,
+		msg.UserID,
+	)
+	if err != nil {
+		logger.Warn("[Agent] Failed to schedule project nudge %s: %v", jobName, err)
+		return fmt.Sprintf("Warning: progress reminders not scheduled: %v", err)
+	}
+	if err := a.cronScheduler.SetProvenance(job.ID, provenance.Record{
+		Origin:       provenance.OriginUser,
+		Actor:        msg.Username,
+		MessageID:    msg.ID,
+		Conversation: strings.Join([]string{msg.Platform, msg.ChannelID, msg.UserID}, ":"),
+		Reason:       "project " + p.Project,
+	}); err != nil {
+		logger.Warn("[Agent] Failed to record provenance for %s: %v", jobName, err)
+	}
+	return fmt.Sprintf("Progress reminders scheduled (%s).", p.Nudge)
+}
+
+// handleProjectCommand answers "/project [status] [name]" and "项目状态".
+func (a *Agent) handleProjectCommand(text string) (string, bool) {
+	if text == "项目状态" {
+		return a.formatProjects(), true
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	if cmd := strings.ToLower(fields[0]); cmd != "/project" && cmd != "/projects" {
+		return "", false
+	}
+	fields = fields[1:]
+	if len(fields) > 0 && (strings.EqualFold(fields[0], "status") || fields[0] == "状态") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return a.formatProjects(), true
+	}
+	p, err := a.loadProject(strings.Join(fields, " "))
+	if err != nil {
+		return fmt.Sprintf("找不到项目: %s", strings.Join(fields, " ")), true
+	}
+	return strings.TrimSpace(formatProjectStatus(p, true)), true
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectPlanLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", dir)
+	a := &Agent{}
+
+	result := a.executeProjectCreate(context.Background(), map[string]any{
+		"name":         "Learn Japanese",
+		"goal":         "Pass JLPT N3 by March",
+		"milestones":   []any{"Finish N4 grammar", "Mock exam above 60%"},
+		"next_actions": []any{"Buy textbook", "Schedule daily Anki"},
+		"deadline":     "2027-03-01",
+	})
+	if !strings.Contains(result, "created") {
+		t.Fatalf("create: %s", result)
+	}
+	path := filepath.Join(dir, "projects", "learn-japanese.md")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("plan note not written: %v", err)
+	}
+	if got := a.executeProjectCreate(context.Background(), map[string]any{"name": "learn japanese", "goal": "x"}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("duplicate create should fail, got %s", got)
+	}
+
+	// Sections the user adds by hand survive updates.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append(data, []byte("\n## 资料\n\n- Genki II\n")...), 0o644)
+
+	result = a.executeProjectUpdate(context.Background(), map[string]any{
+		"name":             "Learn Japanese",
+		"done":             []any{"textbook", "N4 grammar", "climb everest"},
+		"add_next_actions": []any{"Book the exam"},
+		"progress":         "Covered lessons 1-5",
+	})
+	if !strings.Contains(result, "climb everest") {
+		t.Fatalf("unmatched item should be reported: %s", result)
+	}
+
+	p, err := a.loadProject("Learn Japanese")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.NextActions) != 2 || p.NextActions[0].Text != "Schedule daily Anki" || p.NextActions[1].Text != "Book the exam" {
+		t.Fatalf("next actions = %+v", p.NextActions)
+	}
+	if !p.Milestones[0].Done || p.Milestones[1].Done {
+		t.Fatalf("milestones = %+v", p.Milestones)
+	}
+	if len(p.Extra) != 1 || p.Extra[0].Title != "资料" || p.Extra[0].Body != "- Genki II" {
+		t.Fatalf("extra sections = %+v", p.Extra)
+	}
+	if last := p.Progress[len(p.Progress)-1]; !strings.HasSuffix(last, "Covered lessons 1-5") {
+		t.Fatalf("last progress = %q", last)
+	}
+
+	status := a.formatProjects()
+	for _, want := range []string{"【Learn Japanese】进行中", "里程碑 1/2", "截止 2027-03-01", "下一步: Schedule daily Anki"} {
+		if !strings.Contains(status, want) {
+			t.Fatalf("status missing %q:\n%s", want, status)
+		}
+	}
+
+	if got := a.executeProjectUpdate(context.Background(), map[string]any{"name": "Learn Japanese", "status": "done"}); !strings.Contains(got, "已完成") {
+		t.Fatalf("status update: %s", got)
+	}
+	if reply, ok := a.handleProjectCommand("/project status Learn Japanese"); !ok || !strings.Contains(reply, "已完成") {
+		t.Fatalf("/project status = %q, %v", reply, ok)
+	}
+}
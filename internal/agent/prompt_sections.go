@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+// The system prompt sections that can be switched off, in /prompt order.
+const (
+	promptSectionModels           = "models"
+	promptSectionSkills           = "skills"
+	promptSectionReport           = "report"
+	promptSectionMarkdownMemories = "markdown_memories"
+)
+
+var promptSectionNames = []string{promptSectionModels, promptSectionSkills, promptSectionReport, promptSectionMarkdownMemories}
+
+var promptSectionLabels = map[string]string{
+	promptSectionModels:           "模型列表",
+	promptSectionSkills:           "技能列表",
+	promptSectionReport:           "日报",
+	promptSectionMarkdownMemories: "Markdown 记忆",
+}
+
+// promptSectionSwitches lists cfg's switches by section name; nil means
+// unset.
+func promptSectionSwitches(cfg config.PromptSectionsConfig) map[string]*bool {
+	return map[string]*bool{
+		promptSectionModels:           cfg.Models,
+		promptSectionSkills:           cfg.Skills,
+		promptSectionReport:           cfg.Report,
+		promptSectionMarkdownMemories: cfg.MarkdownMemories,
+	}
+}
+
+// applyPromptSections installs the top-level prompt_sections switches.
+func (a *Agent) applyPromptSections(cfg config.PromptSectionsConfig) {
+	a.securityMu.Lock()
+	a.promptSections = cfg
+	a.securityMu.Unlock()
+}
+
+// promptSections is which sections go into one turn's system prompt.
+type promptSections map[string]bool
+
+func (s promptSections) on(section string) bool {
+	return s[section]
+}
+
+// promptSectionsFor resolves the section switches for a turn: the
+// conversation's /prompt overrides win over the channel's profile, which
+// wins over the top-level config. Sections are on unless switched off.
+func (a *Agent) promptSectionsFor(msg router.Message, convKey string) promptSections {
+	a.securityMu.RLock()
+	global := promptSectionSwitches(a.promptSections)
+	a.securityMu.RUnlock()
+	var channel map[string]*bool
+	if p, ok := a.channelProfileFor(msg); ok {
+		channel = promptSectionSwitches(p.PromptSections)
+	}
+
+	out := make(promptSections, len(promptSectionNames))
+	for _, name := range promptSectionNames {
+		on := true
+		if v := global[name]; v != nil {
+			on = *v
+		}
+		if v := channel[name]; v != nil {
+			on = *v
+		}
+		if a.sessions != nil {
+			if v, set := a.sessions.PromptSection(convKey, name); set {
+				on = v
+			}
+		}
+		out[name] = on
+	}
+	return out
+}
+
+// promptSectionStat is one section of the last system prompt: whether it
+// was included and its size in tokens, measured even when it was left
+// out. Markdown memories are only measured when searched.
+type promptSectionStat struct {
+	On       bool
+	Tokens   int
+	Measured bool
+}
+
+type promptStats struct {
+	Total    int // tokens of the system prompt as sent
+	Sections map[string]promptSectionStat
+	// Parts are the other pieces of the system prompt that were not empty,
+	// for /context.
+	Parts []promptPart
+	Model string // model the turn went to, before any failover
+	At    time.Time
+}
+
+// promptStatsStore keeps the last turn's prompt measurements per
+// conversation for /prompt debug and /context.
+type promptStatsStore struct {
+	mu     sync.Mutex
+	byConv map[string]promptStats
+}
+
+// record stores a turn's measurements. A section left unmeasured keeps
+// the size it had last time it was measured.
+func (p *promptStatsStore) record(convKey string, stats promptStats) {
+	if convKey == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.byConv == nil {
+		p.byConv = make(map[string]promptStats)
+	}
+	if prev, ok := p.byConv[convKey]; ok {
+		for name, s := range stats.Sections {
+			if old := prev.Sections[name]; !s.Measured && old.Measured {
+				s.Tokens, s.Measured = old.Tokens, true
+				stats.Sections[name] = s
+			}
+		}
+	}
+	p.byConv[convKey] = stats
+}
+
+func (p *promptStatsStore) get(convKey string) (promptStats, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.byConv[convKey]
+	return s, ok
+}
+
+// handlePromptCommand shows the prompt sections of the conversation or
+// switches one on or off for it:
+//
+//	/prompt [debug]
+//	/prompt on|off <section>
+func (a *Agent) handlePromptCommand(msg router.Message, convKey, text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "/prompt" {
+		return "", false
+	}
+	if len(fields) == 1 || (len(fields) == 2 && strings.EqualFold(fields[1], "debug")) {
+		return a.formatPromptDebug(msg, convKey), true
+	}
+	usage := "用法：/prompt debug 查看各部分占用；/prompt on|off <部分> 开关本会话的提示词部分，可选：" + strings.Join(promptSectionNames, ", ")
+	if len(fields) != 3 || a.sessions == nil {
+		return usage, true
+	}
+	var on bool
+	switch strings.ToLower(fields[1]) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return usage, true
+	}
+	section := strings.ToLower(fields[2])
+	if _, ok := promptSectionLabels[section]; !ok {
+		return fmt.Sprintf("没有名为 %s 的提示词部分。%s", fields[2], usage), true
+	}
+	a.sessions.SetPromptSection(convKey, section, on)
+	state := "关闭"
+	if on {
+		state = "开启"
+	}
+	return fmt.Sprintf("本会话已%s %s（%s），/new 后恢复配置。", state, section, promptSectionLabels[section]), true
+}
+
+// formatPromptDebug lists each switchable section with its state and the
+// tokens it took, or saved, in the conversation's last turn.
+func (a *Agent) formatPromptDebug(msg router.Message, convKey string) string {
+	current := a.promptSectionsFor(msg, convKey)
+	stats, measured := a.promptStats.get(convKey)
+
+	var b strings.Builder
+	b.WriteString("系统提示词各部分:\n")
+	saved := 0
+	for _, name := range promptSectionNames {
+		state := "关闭"
+		if current.on(name) {
+			state = "开启"
+		}
+		fmt.Fprintf(&b, "- %s（%s）: %s", name, promptSectionLabels[name], state)
+		s, ok := stats.Sections[name]
+		switch {
+		case !measured || !ok || !s.Measured:
+			b.WriteString(" · 尚未测量")
+		case s.On:
+			fmt.Fprintf(&b, " · 约 %d tokens", s.Tokens)
+		default:
+			fmt.Fprintf(&b, " · 节省约 %d tokens", s.Tokens)
+			saved += s.Tokens
+		}
+		b.WriteString("\n")
+	}
+	if measured {
+		fmt.Fprintf(&b, "\n上一轮系统提示词约 %d tokens", stats.Total)
+		if saved > 0 {
+			fmt.Fprintf(&b, "，关闭的部分共节省约 %d tokens（%d%%）", saved, saved*100/(stats.Total+saved))
+		}
+		b.WriteString("\n")
+	} else {
+		b.WriteString("\n本会话还没有对话轮次，发一条消息后再查看占用。\n")
+	}
+	b.WriteString("用 /prompt on|off <部分> 调整本会话，或在 prompt_sections / channels.<频道>.prompt_sections 中配置。")
+	return b.String()
+}
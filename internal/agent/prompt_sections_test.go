@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestPromptSectionsResolveSessionOverChannelOverGlobal(t *testing.T) {
+	off, on := false, true
+	a := &Agent{sessions: NewSessionStore()}
+	a.applyPromptSections(config.PromptSectionsConfig{Models: &off, Skills: &off})
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{
+		"slack:C1": {PromptSections: config.PromptSectionsConfig{Skills: &on, MarkdownMemories: &off}},
+	})
+	msg := router.Message{Platform: "slack", ChannelID: "C1", UserID: "u1"}
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+
+	got := a.promptSectionsFor(msg, convKey)
+	want := promptSections{"models": false, "skills": true, "report": true, "markdown_memories": false}
+	for name, v := range want {
+		if got.on(name) != v {
+			t.Errorf("%s = %v, want %v", name, got.on(name), v)
+		}
+	}
+
+	if reply, ok := a.handlePromptCommand(msg, convKey, "/prompt on models"); !ok || !strings.Contains(reply, "已开启 models") {
+		t.Fatalf("/prompt on models = %q, %v", reply, ok)
+	}
+	if !a.promptSectionsFor(msg, convKey).on("models") {
+		t.Fatal("session override should win over the config")
+	}
+	if other := a.promptSectionsFor(router.Message{Platform: "slack", ChannelID: "C2", UserID: "u1"}, "slack:C2:u1"); other.on("models") {
+		t.Fatal("the override leaked into another conversation")
+	}
+	if reply, _ := a.handlePromptCommand(msg, convKey, "/prompt off tools"); !strings.Contains(reply, "没有名为 tools") {
+		t.Fatalf("unknown section = %q", reply)
+	}
+	if _, ok := a.handlePromptCommand(msg, convKey, "/prompts"); ok {
+		t.Fatal("/prompts is not the prompt command")
+	}
+}
+
+func TestPromptDebugShowsMeasuredSavings(t *testing.T) {
+	a := &Agent{sessions: NewSessionStore()}
+	msg := router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1"}
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+
+	if reply, _ := a.handlePromptCommand(msg, convKey, "/prompt debug"); !strings.Contains(reply, "还没有对话轮次") {
+		t.Fatalf("debug before a turn = %q", reply)
+	}
+
+	a.promptStats.record(convKey, promptStats{Total: 1000, Sections: map[string]promptSectionStat{
+		"models":            {On: true, Tokens: 120, Measured: true},
+		"skills":            {On: true, Tokens: 80, Measured: true},
+		"report":            {On: true, Measured: true},
+		"markdown_memories": {On: true, Tokens: 400, Measured: true},
+	}})
+	a.handlePromptCommand(msg, convKey, "/prompt off markdown_memories")
+	a.handlePromptCommand(msg, convKey, "/prompt off skills")
+	// The next turn does not search markdown memory, so its last size stands.
+	a.promptStats.record(convKey, promptStats{Total: 520, Sections: map[string]promptSectionStat{
+		"models":            {On: true, Tokens: 120, Measured: true},
+		"skills":            {On: false, Tokens: 80, Measured: true},
+		"report":            {On: true, Measured: true},
+		"markdown_memories": {On: false},
+	}})
+
+	reply, _ := a.handlePromptCommand(msg, convKey, "/prompt debug")
+	for _, want := range []string{
+		"models（模型列表）: 开启 · 约 120 tokens",
+		"skills（技能列表）: 关闭 · 节省约 80 tokens",
+		"markdown_memories（Markdown 记忆）: 关闭 · 节省约 400 tokens",
+		"上一轮系统提示词约 520 tokens，关闭的部分共节省约 480 tokens（48%）",
+	} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("debug lacks %q:\n%s", want, reply)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/provenance"
+	"github.com/kayz/coco/internal/router"
+)
+
+// messageProvenance attributes a change to msg: a user message, or a cron prompt acting on its own.
+func messageProvenance(msg router.Message) provenance.Record {
+	rec := provenance.Record{
+		Origin:       provenance.OriginUser,
+		Actor:        msg.Username,
+		MessageID:    msg.ID,
+		Conversation: strings.Join([]string{msg.Platform, msg.ChannelID, msg.UserID}, ":"),
+		Reason:       provenance.Excerpt(logSafeText(msg.Text), 80),
+		CreatedAt:    time.Now(),
+	}
+	if rec.Actor == "" {
+		rec.Actor = msg.UserID
+	}
+	if strings.EqualFold(strings.TrimSpace(msg.Username), "cron") {
+		rec.Origin = provenance.OriginCron
+	}
+	return rec
+}
+
+// recordConfigWrite adds an audit entry when the model rewrites the runtime config file.
+func (a *Agent) recordConfigWrite(ctx context.Context, args map[string]any, result string) {
+	if a.configPath == "" || strings.HasPrefix(result, "Error") || strings.HasPrefix(result, "ACCESS DENIED") {
+		return
+	}
+	p, _ := args["path"].(string)
+	target, err1 := filepath.Abs(resolveBestEffortPath(p))
+	cfgPath, err2 := filepath.Abs(a.configPath)
+	if err1 != nil || err2 != nil || target != cfgPath {
+		return
+	}
+	rec := messageProvenance(turnMessage(ctx))
+	rec.Reason = "file_write: " + rec.Reason
+	if err := provenance.RecordConfigChange(a.configPath, rec); err != nil {
+		logger.Warn("[Agent] Failed to record config change: %v", err)
+	}
+}
+
+// recordExternalConfigEdit attributes a config change that no coco component
+// recorded (hand edits, other tools) before it is applied.
+func (a *Agent) recordExternalConfigEdit() {
+	err := provenance.RecordConfigChange(a.configPath, provenance.Record{
+		Origin: provenance.OriginExternalEdit,
+		Reason: "changed outside coco",
+	})
+	if err != nil {
+		logger.Warn("[Agent] Failed to record config change: %v", err)
+		return
+	}
+	if last, err := provenance.LastChange(provenance.DefaultAuditPath(), a.configPath); err == nil && last != nil {
+		logger.Info("[Agent] Config change origin: %s", last.Record.Describe())
+	}
+}
@@ -29,6 +29,17 @@ type ChatResponse struct {
 	ReasoningContent string
 	// FinishReason indicates why the model stopped: "stop", "tool_use", etc.
 	FinishReason string
+	// Usage is the token count the provider reported; zero when it did not.
+	Usage TokenUsage
+}
+
+// TokenUsage counts the tokens billed for one chat request.
+type TokenUsage struct {
+	InputTokens  int
+	OutputTokens int
+	// CachedInputTokens is the part of the input the provider served from
+	// its prompt cache, such as tool schemas it already holds.
+	CachedInputTokens int
 }
 
 // Message represents a chat message
@@ -183,15 +183,7 @@ func (p *ClaudeProvider) Chat(ctx context.Context, req ChatRequest) (ChatRespons
 		messages = append(messages, p.toAnthropicMessage(msg))
 	}
 
-	// Convert tools to Anthropic format
-	tools := make([]anthropic.ToolDefinition, 0, len(req.Tools))
-	for _, tool := range req.Tools {
-		tools = append(tools, anthropic.ToolDefinition{
-			Name:        tool.Name,
-			Description: tool.Description,
-			InputSchema: tool.InputSchema,
-		})
-	}
+	tools := anthropicTools(req.Tools)
 
 	maxTokens := req.MaxTokens
 	if maxTokens <= 0 {
@@ -341,5 +333,30 @@ func (p *ClaudeProvider) fromAnthropicResponse(resp anthropic.MessagesResponse)
 		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
+		Usage: TokenUsage{
+			InputTokens:       resp.Usage.InputTokens,
+			OutputTokens:      resp.Usage.OutputTokens,
+			CachedInputTokens: resp.Usage.CacheReadInputTokens,
+		},
+	}
+}
+
+// anthropicTools converts tools to Anthropic format. The Messages API has
+// no tool registry to reference by id, so the last definition carries a
+// cache breakpoint instead: the whole tool block is stored provider-side
+// and later requests with the same tools read it from the cache rather
+// than being billed for the schemas again.
+func anthropicTools(tools []Tool) []anthropic.ToolDefinition {
+	out := make([]anthropic.ToolDefinition, 0, len(tools))
+	for _, tool := range tools {
+		out = append(out, anthropic.ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+	if len(out) > 0 {
+		out[len(out)-1].CacheControl = &anthropic.MessageCacheControl{Type: anthropic.CacheControlTypeEphemeral}
 	}
+	return out
 }
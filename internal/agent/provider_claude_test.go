@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/liushuangls/go-anthropic/v2"
+)
+
+func TestAnthropicToolsMarkLastDefinitionForCaching(t *testing.T) {
+	if got := anthropicTools(nil); len(got) != 0 {
+		t.Fatalf("expected no tools, got %d", len(got))
+	}
+
+	tools := anthropicTools([]Tool{
+		{Name: "read_file", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "write_file", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	})
+	if len(tools) != 2 {
+		t.Fatalf("expected 2 tools, got %d", len(tools))
+	}
+	if tools[0].CacheControl != nil {
+		t.Fatalf("only the last tool should carry a cache breakpoint")
+	}
+	if cc := tools[1].CacheControl; cc == nil || cc.Type != anthropic.CacheControlTypeEphemeral {
+		t.Fatalf("last tool should be cached, got %+v", cc)
+	}
+}
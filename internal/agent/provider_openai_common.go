@@ -195,10 +195,18 @@ func genericResponseFromOpenAI(resp openai.ChatCompletionResponse, codec *openAI
 		finishReason = "tool_use"
 	}
 
+	// OpenAI-compatible APIs cache long prompt prefixes automatically, and
+	// the tool schemas come first, so all they need is a stable tool order.
+	usage := TokenUsage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens}
+	if details := resp.Usage.PromptTokensDetails; details != nil {
+		usage.CachedInputTokens = details.CachedTokens
+	}
+
 	return ChatResponse{
 		Content:          choice.Message.Content,
 		ToolCalls:        toolCalls,
 		ReasoningContent: choice.Message.ReasoningContent,
 		FinishReason:     finishReason,
+		Usage:            usage,
 	}
 }
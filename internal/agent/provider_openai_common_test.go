@@ -94,3 +94,20 @@ func TestGenericResponseFromOpenAIDecodesToolNameAndReasoning(t *testing.T) {
 		t.Fatalf("tool name decode mismatch: %+v", got.ToolCalls)
 	}
 }
+
+func TestGenericResponseFromOpenAIReportsCachedTokens(t *testing.T) {
+	resp := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "ok"}}},
+		Usage: openai.Usage{
+			PromptTokens:        1200,
+			CompletionTokens:    30,
+			PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: 1024},
+		},
+	}
+
+	got := genericResponseFromOpenAI(resp, nil)
+	want := TokenUsage{InputTokens: 1200, OutputTokens: 30, CachedInputTokens: 1024}
+	if got.Usage != want {
+		t.Fatalf("usage mismatch: got %+v want %+v", got.Usage, want)
+	}
+}
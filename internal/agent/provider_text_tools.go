@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// textToolProvider lets a model without native function calling drive tools
+// through a ReAct-style text protocol: the tools are described in the system
+// prompt, the model writes <tool_call> blocks in its reply, and results are
+// fed back as <tool_result> blocks in user messages. To the agent loop it
+// looks like any other provider.
+type textToolProvider struct {
+	inner Provider
+	calls atomic.Int64
+}
+
+func newTextToolProvider(inner Provider) *textToolProvider {
+	return &textToolProvider{inner: inner}
+}
+
+func (p *textToolProvider) Name() string {
+	return p.inner.Name()
+}
+
+var (
+	toolCallBlockPattern = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+	// reactActionPattern is the classic "Action: / Action Input:" form some
+	// models fall back to.
+	reactActionPattern = regexp.MustCompile(`(?s)Action:\s*([A-Za-z0-9_.-]+)\s*\n\s*Action Input:\s*(\{.*\})`)
+	codeFencePattern   = regexp.MustCompile("(?s)^```[a-z]*\\s*(.*?)\\s*```$")
+)
+
+func (p *textToolProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	if len(req.Tools) == 0 {
+		return p.inner.Chat(ctx, req)
+	}
+	textReq := ChatRequest{
+		Messages:     textToolMessages(req.Messages),
+		SystemPrompt: req.SystemPrompt + "\n\n" + textToolInstructions(req.Tools),
+		MaxTokens:    req.MaxTokens,
+	}
+	resp, err := p.inner.Chat(ctx, textReq)
+	if err != nil {
+		return resp, err
+	}
+	content, calls := parseTextToolCalls(resp.Content, req.Tools)
+	if len(calls) == 0 {
+		return resp, nil
+	}
+	for i := range calls {
+		calls[i].ID = fmt.Sprintf("text_call_%d", p.calls.Add(1))
+	}
+	resp.Content = content
+	resp.ToolCalls = calls
+	resp.FinishReason = "tool_use"
+	return resp, nil
+}
+
+// textToolInstructions describes the protocol and the tools.
+func textToolInstructions(tools []Tool) string {
+	var sb strings.Builder
+	sb.WriteString(`## Calling tools
+You call tools by writing text. To call a tool, reply with one or more blocks exactly like this and nothing after them:
+<tool_call>
+{"name": "tool_name", "arguments": {"param": "value"}}
+</tool_call>
+The results come back in <tool_result> blocks in the next message. Call tools only when needed; when you have the answer, reply normally without any <tool_call> block. Never write a <tool_result> block yourself.
+
+### Tools`)
+	for _, t := range tools {
+		fmt.Fprintf(&sb, "\n- %s: %s", t.Name, t.Description)
+		var schema struct {
+			Properties json.RawMessage `json:"properties"`
+			Required   []string        `json:"required"`
+		}
+		if json.Unmarshal(t.InputSchema, &schema) == nil && len(schema.Properties) > 2 {
+			fmt.Fprintf(&sb, "\n  arguments: %s", schema.Properties)
+			if len(schema.Required) > 0 {
+				fmt.Fprintf(&sb, "\n  required: %s", strings.Join(schema.Required, ", "))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// textToolMessages rewrites native tool calls and results in the history as
+// the text protocol, merging consecutive results into one user message.
+func textToolMessages(messages []Message) []Message {
+	names := map[string]string{}
+	out := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		switch {
+		case m.ToolResult != nil:
+			block := fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", names[m.ToolResult.ToolCallID], m.ToolResult.Content)
+			if n := len(out); n > 0 && out[n-1].Role == "user" && strings.HasPrefix(out[n-1].Content, "<tool_result") {
+				out[n-1].Content += "\n" + block
+				continue
+			}
+			out = append(out, Message{Role: "user", Content: block})
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			var sb strings.Builder
+			sb.WriteString(m.Content)
+			for _, tc := range m.ToolCalls {
+				names[tc.ID] = tc.Name
+				args := tc.Input
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				}
+				fmt.Fprintf(&sb, "\n<tool_call>\n{\"name\": %q, \"arguments\": %s}\n</tool_call>", tc.Name, args)
+			}
+			out = append(out, Message{Role: "assistant", Content: strings.TrimSpace(sb.String()), ReasoningContent: m.ReasoningContent})
+		default:
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// parseTextToolCalls finds the tool calls in a reply and returns the text
+// before them. Calls to tools that were not offered are ignored, so prose
+// that merely mentions the protocol is left alone.
+func parseTextToolCalls(content string, tools []Tool) (string, []ToolCall) {
+	offered := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		offered[t.Name] = true
+	}
+
+	var calls []ToolCall
+	first := -1
+	for _, m := range toolCallBlockPattern.FindAllStringSubmatchIndex(content, -1) {
+		body := content[m[2]:m[3]]
+		if f := codeFencePattern.FindStringSubmatch(body); f != nil {
+			body = f[1]
+		}
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if json.Unmarshal([]byte(body), &call) != nil || !offered[call.Name] {
+			continue
+		}
+		calls = append(calls, ToolCall{Name: call.Name, Input: textToolArguments(call.Arguments)})
+		if first < 0 {
+			first = m[0]
+		}
+	}
+	if len(calls) == 0 {
+		if m := reactActionPattern.FindStringSubmatchIndex(content); m != nil && offered[content[m[2]:m[3]]] {
+			input := json.RawMessage(content[m[4]:m[5]])
+			if json.Valid(input) {
+				calls = append(calls, ToolCall{Name: content[m[2]:m[3]], Input: input})
+				first = m[0]
+			}
+		}
+	}
+	if len(calls) == 0 {
+		return content, nil
+	}
+	text := strings.TrimSpace(content[:first])
+	text = strings.TrimSpace(strings.TrimSuffix(text, "Thought:"))
+	return text, calls
+}
+
+// textToolArguments normalizes the arguments of a text tool call: missing
+// arguments become {}, and arguments written as a JSON string are unwrapped.
+func textToolArguments(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || string(raw) == "null" {
+		return json.RawMessage("{}")
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil && json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	return raw
+}
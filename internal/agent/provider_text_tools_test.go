@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// replyProvider answers every request with a fixed reply and keeps the
+// last request.
+type replyProvider struct {
+	reply string
+	last  ChatRequest
+}
+
+func (p *replyProvider) Name() string { return "reply" }
+
+func (p *replyProvider) Chat(_ context.Context, req ChatRequest) (ChatResponse, error) {
+	p.last = req
+	return ChatResponse{Content: p.reply, FinishReason: "stop"}, nil
+}
+
+var textTestTools = []Tool{{
+	Name:        "weather",
+	Description: "Get the weather",
+	InputSchema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+}}
+
+func TestTextToolProviderParsesToolCalls(t *testing.T) {
+	inner := &replyProvider{reply: "Let me check.\n<tool_call>\n{\"name\": \"weather\", \"arguments\": {\"city\": \"Beijing\"}}\n</tool_call>"}
+	p := newTextToolProvider(inner)
+
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		SystemPrompt: "You are coco.",
+		Messages:     []Message{{Role: "user", Content: "weather?"}},
+		Tools:        textTestTools,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.last.Tools) != 0 {
+		t.Fatal("tools were sent natively")
+	}
+	if !strings.Contains(inner.last.SystemPrompt, "- weather: Get the weather") || !strings.Contains(inner.last.SystemPrompt, "<tool_call>") {
+		t.Fatalf("system prompt lacks the protocol:\n%s", inner.last.SystemPrompt)
+	}
+	if resp.FinishReason != "tool_use" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.Name != "weather" || string(tc.Input) != `{"city": "Beijing"}` || tc.ID == "" {
+		t.Fatalf("tool call = %+v", tc)
+	}
+	if resp.Content != "Let me check." {
+		t.Fatalf("content = %q", resp.Content)
+	}
+}
+
+func TestTextToolProviderFeedsResultsBack(t *testing.T) {
+	inner := &replyProvider{reply: "It is sunny in Beijing."}
+	p := newTextToolProvider(inner)
+
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{
+			{Role: "user", Content: "weather?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "weather", Input: json.RawMessage(`{"city":"Beijing"}`)}}},
+			{Role: "user", ToolResult: &ToolResult{ToolCallID: "c1", Content: "sunny"}},
+		},
+		Tools: textTestTools,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != "stop" || len(resp.ToolCalls) != 0 || resp.Content != "It is sunny in Beijing." {
+		t.Fatalf("resp = %+v", resp)
+	}
+	msgs := inner.last.Messages
+	if len(msgs) != 3 || len(msgs[1].ToolCalls) != 0 || msgs[2].ToolResult != nil {
+		t.Fatalf("history was not rewritten: %+v", msgs)
+	}
+	if !strings.Contains(msgs[1].Content, `{"name": "weather", "arguments": {"city":"Beijing"}}`) {
+		t.Fatalf("assistant call = %q", msgs[1].Content)
+	}
+	if msgs[2].Role != "user" || msgs[2].Content != "<tool_result name=\"weather\">\nsunny\n</tool_result>" {
+		t.Fatalf("tool result = %+v", msgs[2])
+	}
+}
+
+func TestParseTextToolCallsForms(t *testing.T) {
+	// The ReAct form.
+	text, calls := parseTextToolCalls("Thought: I need the weather.\nAction: weather\nAction Input: {\"city\": \"Paris\"}", textTestTools)
+	if len(calls) != 1 || calls[0].Name != "weather" || text != "Thought: I need the weather." {
+		t.Fatalf("react: %q %+v", text, calls)
+	}
+	// Fenced JSON and string-encoded arguments.
+	_, calls = parseTextToolCalls("<tool_call>\n```json\n{\"name\":\"weather\",\"arguments\":\"{\\\"city\\\":\\\"Rome\\\"}\"}\n```\n</tool_call>", textTestTools)
+	if len(calls) != 1 || string(calls[0].Input) != `{"city":"Rome"}` {
+		t.Fatalf("fenced: %+v", calls)
+	}
+	// Tools that were not offered are plain text.
+	text, calls = parseTextToolCalls("<tool_call>{\"name\":\"shell_execute\",\"arguments\":{}}</tool_call>", textTestTools)
+	if len(calls) != 0 || !strings.Contains(text, "shell_execute") {
+		t.Fatalf("unknown tool: %q %+v", text, calls)
+	}
+}
@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+// quickCommands are the built-in commands answered from memory alone. They
+// skip the task queue, the conversation lock and the config check, so they
+// answer at once even while a long turn is running.
+var quickCommands = map[string]bool{
+	"/whoami": true, "whoami": true, "我是谁": true, "我的id": true,
+	"/help": true, "help": true, "帮助": true, "/commands": true,
+	"/status": true, "状态": true,
+}
+
+// handleQuickCommand answers a quick command on the caller's goroutine. The
+// sender policy still applies; it reads the settings already in memory.
+func (a *Agent) handleQuickCommand(ctx context.Context, msg router.Message) (router.Response, bool) {
+	if !quickCommands[strings.ToLower(strings.TrimSpace(msg.Text))] {
+		return router.Response{}, false
+	}
+	if denial, drop := a.enforceMessageSecurityPolicy(msg); drop {
+		return router.Response{Text: denial}, true
+	}
+	return a.handleBuiltinCommand(ctx, msg)
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestQuickCommandsAnswerWhileATurnRuns(t *testing.T) {
+	a := &Agent{memory: NewMemory(nil, 20), sessions: NewSessionStore(), modelRouter: newHealthTestRouter(t)}
+	a.applySecurityConfig(nil, false, nil, nil, nil, false)
+	msg := router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1", Username: "alice"}
+
+	// A long turn holds the conversation.
+	unlock, err := a.convLocks.lock(context.Background(), ConversationKey(msg.Platform, msg.ChannelID, msg.UserID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	for text, want := range map[string]string{"/whoami": "用户ID: u1", "/status": "会话状态", "帮助": "可用命令"} {
+		msg.Text = text
+		done := make(chan string, 1)
+		go func() {
+			resp, _ := a.HandleMessage(context.Background(), msg)
+			done <- resp.Text
+		}()
+		select {
+		case got := <-done:
+			if !strings.Contains(got, want) {
+				t.Errorf("%s = %q", text, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s waited for the running turn", text)
+		}
+	}
+
+	a.applySecurityConfig(nil, false, nil, nil, []string{"slack:someone-else"}, false)
+	msg.Text = "/status"
+	if resp, _ := a.HandleMessage(context.Background(), msg); !strings.Contains(resp.Text, "ACCESS DENIED") {
+		t.Fatalf("denied sender got %q", resp.Text)
+	}
+}
+
+func TestWatchedConfigIsNotCheckedPerTurn(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	cfgPath := filepath.Join(t.TempDir(), ".coco.yaml")
+	if err := os.WriteFile(cfgPath, []byte("focus:\n  minutes: 50\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{configPath: cfgPath}
+	a.applySecurityConfig(nil, false, nil, nil, nil, false)
+
+	a.setConfigWatched(true)
+	a.refreshRuntimeSecurityConfig()
+	if a.loadedConfig != nil {
+		t.Fatal("a watched config was reloaded by the turn")
+	}
+	a.setConfigWatched(false)
+	a.refreshRuntimeSecurityConfig()
+	if a.focusConfigSnapshot().Minutes != 50 {
+		t.Fatalf("focus = %d after the turn's reload", a.focusConfigSnapshot().Minutes)
+	}
+}
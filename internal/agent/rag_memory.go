@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/philippgille/chromem-go"
 	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/datadir"
 	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/vecindex"
 )
 
 const (
 	ragCollectionName = "coco-memory"
 	maxChunkSize      = 1000
 	maxChunks         = 10000
+
+	ragDefaultDedupeThreshold    = 0.97
+	ragIndexCompactMinTombstones = 256
 )
 
 // MemoryType represents the type of memory
@@ -38,24 +44,35 @@ type MemoryItem struct {
 	UpdatedAt time.Time
 }
 
-// RAGMemory provides long-term memory with semantic search
+// RAGMemory provides long-term memory with semantic search.
+// Chunks and their embeddings are stored in .coco.db and served from an in-process HNSW index.
 type RAGMemory struct {
-	db          *chromem.DB
-	collection  *chromem.Collection
+	store       *persist.Store
+	collection  string
 	embProvider EmbeddingProvider
 	enabled     bool
-	dataDir     string
+
+	maxItems        int
+	maxAge          time.Duration
+	dedupeThreshold float32
+
+	mu        sync.Mutex // serializes writes and index rebuilds
+	index     *vecindex.HNSW
+	lastPrune time.Time
 }
 
-// NewRAGMemory creates a new RAG memory store
-func NewRAGMemory(cfg config.EmbeddingConfig) (*RAGMemory, error) {
+// NewRAGMemory creates a new RAG memory store backed by the given SQLite store
+func NewRAGMemory(cfg config.EmbeddingConfig, memCfg config.MemoryConfig, store *persist.Store) (*RAGMemory, error) {
 	if !cfg.Enabled {
 		return &RAGMemory{enabled: false}, nil
 	}
 
-	if cfg.APIKey == "" {
+	if cfg.APIKey == "" && embeddingNeedsAPIKey(cfg.Provider) {
 		return nil, fmt.Errorf("embedding API key is required")
 	}
+	if store == nil {
+		return nil, fmt.Errorf("persistence store is required")
+	}
 
 	embCfg := EmbeddingConfig{
 		Provider: cfg.Provider,
@@ -69,29 +86,31 @@ func NewRAGMemory(cfg config.EmbeddingConfig) (*RAGMemory, error) {
 		return nil, fmt.Errorf("failed to create embedding provider: %w", err)
 	}
 
-	dataDir := filepath.Join(getExecutableDir(), ".coco", "rag")
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	// Vectors from different providers/models are not comparable, so each gets its own collection.
+	collection := ragCollectionName + ":" + embProvider.Name()
+	if cfg.Model != "" && embProvider.Name() != "local" {
+		collection += ":" + cfg.Model
 	}
 
-	dbPath := filepath.Join(dataDir, "chromem.db")
-	db, err := chromem.NewPersistentDB(dbPath, false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create chromem DB: %w", err)
+	m := &RAGMemory{
+		store:           store,
+		collection:      collection,
+		embProvider:     embProvider,
+		enabled:         true,
+		maxItems:        memCfg.RAGMaxItems,
+		maxAge:          time.Duration(memCfg.RAGMaxAgeDays) * 24 * time.Hour,
+		dedupeThreshold: ragDefaultDedupeThreshold,
 	}
-
-	collection, err := db.GetOrCreateCollection(ragCollectionName, nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get/create collection: %w", err)
+	if memCfg.RAGDedupeThreshold != 0 {
+		m.dedupeThreshold = float32(memCfg.RAGDedupeThreshold)
 	}
 
-	return &RAGMemory{
-		db:          db,
-		collection:  collection,
-		embProvider: embProvider,
-		enabled:     true,
-		dataDir:     dataDir,
-	}, nil
+	m.importLegacyStore(context.Background())
+	m.prune()
+	if err := m.rebuildIndex(); err != nil {
+		return nil, fmt.Errorf("failed to build memory index: %w", err)
+	}
+	return m, nil
 }
 
 // IsEnabled returns whether RAG memory is enabled
@@ -99,7 +118,7 @@ func (m *RAGMemory) IsEnabled() bool {
 	return m.enabled
 }
 
-// AddMemory adds a memory item
+// AddMemory adds a memory item. Chunks that nearly duplicate a stored chunk refresh it instead.
 func (m *RAGMemory) AddMemory(ctx context.Context, item MemoryItem) error {
 	if !m.enabled {
 		return nil
@@ -122,8 +141,22 @@ func (m *RAGMemory) AddMemory(ctx context.Context, item MemoryItem) error {
 		return fmt.Errorf("failed to create embeddings: %w", err)
 	}
 
-	docs := make([]chromem.Document, 0, len(chunks))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vectors := make([]persist.MemoryVector, 0, len(chunks))
+	deduped := 0
 	for i, chunk := range chunks {
+		if m.dedupeThreshold > 0 {
+			if hits := m.index.Search(embeddings[i], 1); len(hits) == 1 && hits[0].Similarity >= m.dedupeThreshold {
+				if err := m.store.TouchMemoryVector(m.collection, hits[0].ID, item.UpdatedAt); err != nil {
+					logger.Warn("[RAG] Failed to refresh duplicate memory %s: %v", hits[0].ID, err)
+				}
+				deduped++
+				continue
+			}
+		}
+
 		metadata := map[string]string{
 			"id":         item.ID,
 			"type":       string(item.Type),
@@ -135,19 +168,31 @@ func (m *RAGMemory) AddMemory(ctx context.Context, item MemoryItem) error {
 			metadata[k] = v
 		}
 
-		docs = append(docs, chromem.Document{
-			ID:        fmt.Sprintf("%s-%d", item.ID, i),
-			Embedding: embeddings[i],
-			Metadata:  metadata,
+		vectors = append(vectors, persist.MemoryVector{
+			DocID:     fmt.Sprintf("%s-%d", item.ID, i),
+			MemoryID:  item.ID,
 			Content:   chunk,
+			Metadata:  metadata,
+			Embedding: embeddings[i],
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
 		})
 	}
 
-	if err := m.collection.AddDocuments(ctx, docs, 1); err != nil {
-		return fmt.Errorf("failed to add documents: %w", err)
+	if len(vectors) > 0 {
+		if err := m.store.UpsertMemoryVectors(m.collection, vectors); err != nil {
+			return fmt.Errorf("failed to add documents: %w", err)
+		}
+		for _, v := range vectors {
+			m.index.Add(v.DocID, v.Embedding)
+		}
+	}
+
+	if (m.maxItems > 0 && m.index.Len() > m.maxItems) || (m.maxAge > 0 && time.Since(m.lastPrune) > time.Hour) {
+		m.prune()
 	}
 
-	logger.Debug("[RAG] Added memory: %s (%d chunks)", item.ID, len(chunks))
+	logger.Debug("[RAG] Added memory: %s (%d chunks, %d deduplicated)", item.ID, len(vectors), deduped)
 	return nil
 }
 
@@ -166,21 +211,30 @@ func (m *RAGMemory) SearchMemories(ctx context.Context, query string, limit int)
 		return nil, fmt.Errorf("failed to create query embedding: %w", err)
 	}
 
-	results, err := m.collection.QueryEmbedding(
-		ctx,
-		queryEmbedding[0],
-		limit,
-		nil,
-		nil,
-	)
+	m.mu.Lock()
+	index := m.index
+	m.mu.Unlock()
+
+	hits := index.Search(queryEmbedding[0], limit)
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	docs, err := m.store.GetMemoryVectors(m.collection, ids)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query collection: %w", err)
 	}
 
-	items := make([]MemoryItem, 0, len(results))
-	for _, res := range results {
-		item := m.resultToMemoryItem(res)
-		items = append(items, item)
+	items := make([]MemoryItem, 0, len(hits))
+	for _, h := range hits {
+		doc, ok := docs[h.ID]
+		if !ok {
+			continue
+		}
+		items = append(items, m.vectorToMemoryItem(doc))
 	}
 
 	logger.Debug("[RAG] Found %d memories for query: %s", len(items), query)
@@ -193,9 +247,14 @@ func (m *RAGMemory) DeleteMemory(ctx context.Context, id string) error {
 		return nil
 	}
 
-	if err := m.collection.Delete(ctx, map[string]string{"id": id}, nil); err != nil {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docIDs, err := m.store.DeleteMemoryVectors(m.collection, id)
+	if err != nil {
 		return fmt.Errorf("failed to delete memory: %w", err)
 	}
+	m.dropFromIndex(docIDs)
 
 	logger.Debug("[RAG] Deleted memory: %s", id)
 	return nil
@@ -207,9 +266,13 @@ func (m *RAGMemory) ClearAll(ctx context.Context) error {
 		return nil
 	}
 
-	if err := m.collection.Delete(ctx, nil, nil); err != nil {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.store.DeleteMemoryVectors(m.collection, ""); err != nil {
 		return fmt.Errorf("failed to clear all memories: %w", err)
 	}
+	m.index = vecindex.New()
 
 	logger.Debug("[RAG] Cleared all memories")
 	return nil
@@ -220,6 +283,113 @@ func (m *RAGMemory) Close() error {
 	return nil
 }
 
+// prune applies the age and size policies. Callers hold m.mu (or own m exclusively).
+func (m *RAGMemory) prune() {
+	m.lastPrune = time.Now()
+	if m.maxItems <= 0 && m.maxAge <= 0 {
+		return
+	}
+	var olderThan time.Time
+	if m.maxAge > 0 {
+		olderThan = time.Now().Add(-m.maxAge)
+	}
+	docIDs, err := m.store.PruneMemoryVectors(m.collection, olderThan, m.maxItems)
+	if err != nil {
+		logger.Warn("[RAG] Failed to prune memories: %v", err)
+		return
+	}
+	if len(docIDs) > 0 {
+		logger.Info("[RAG] Pruned %d memory chunks", len(docIDs))
+		m.dropFromIndex(docIDs)
+	}
+}
+
+// dropFromIndex removes doc IDs from the index and compacts it once tombstones pile up.
+func (m *RAGMemory) dropFromIndex(docIDs []string) {
+	if m.index == nil {
+		return
+	}
+	for _, id := range docIDs {
+		m.index.Delete(id)
+	}
+	if m.index.Tombstones() > ragIndexCompactMinTombstones && m.index.Tombstones() > m.index.Len()/4 {
+		if err := m.rebuildIndex(); err != nil {
+			logger.Warn("[RAG] Failed to compact memory index: %v", err)
+		}
+	}
+}
+
+// rebuildIndex loads every stored vector into a fresh index.
+func (m *RAGMemory) rebuildIndex() error {
+	vectors, err := m.store.LoadMemoryVectors(m.collection)
+	if err != nil {
+		return err
+	}
+	index := vecindex.New()
+	for _, v := range vectors {
+		index.Add(v.DocID, v.Embedding)
+	}
+	m.index = index
+	logger.Debug("[RAG] Indexed %d memory chunks", len(vectors))
+	return nil
+}
+
+// importLegacyStore moves memories from the old chromem-go directory into .coco.db once.
+func (m *RAGMemory) importLegacyStore(ctx context.Context) {
+	dbPath := datadir.Path(".coco", "rag", "chromem.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return
+	}
+	if n, err := m.store.CountMemoryVectors(m.collection); err != nil || n > 0 {
+		return
+	}
+
+	db, err := chromem.NewPersistentDB(dbPath, false)
+	if err != nil {
+		logger.Warn("[RAG] Failed to open legacy memory store: %v", err)
+		return
+	}
+	legacyName := ragCollectionName
+	if m.embProvider.Name() == "local" {
+		legacyName += "-local"
+	}
+	collection := db.GetCollection(legacyName, nil)
+	if collection == nil || collection.Count() == 0 {
+		return
+	}
+
+	// chromem has no iterator; an exhaustive query for Count() results returns every document.
+	probe := make([]float32, m.embProvider.Dimension())
+	probe[0] = 1
+	results, err := collection.QueryEmbedding(ctx, probe, collection.Count(), nil, nil)
+	if err != nil {
+		logger.Warn("[RAG] Failed to read legacy memory store: %v", err)
+		return
+	}
+
+	vectors := make([]persist.MemoryVector, 0, len(results))
+	for _, res := range results {
+		item := m.vectorToMemoryItem(persist.MemoryVector{Metadata: res.Metadata})
+		vectors = append(vectors, persist.MemoryVector{
+			DocID:     res.ID,
+			MemoryID:  item.ID,
+			Content:   res.Content,
+			Metadata:  res.Metadata,
+			Embedding: res.Embedding,
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
+		})
+	}
+	if err := m.store.UpsertMemoryVectors(m.collection, vectors); err != nil {
+		logger.Warn("[RAG] Failed to import legacy memories: %v", err)
+		return
+	}
+	if err := os.Rename(dbPath, dbPath+".imported"); err != nil {
+		logger.Warn("[RAG] Imported legacy memories but could not rename %s: %v", dbPath, err)
+	}
+	logger.Info("[RAG] Imported %d memory chunks from %s", len(vectors), dbPath)
+}
+
 // splitIntoChunks splits text into chunks (by paragraphs, not just bytes)
 func (m *RAGMemory) splitIntoChunks(text string) []string {
 	text = strings.TrimSpace(text)
@@ -279,7 +449,7 @@ func (m *RAGMemory) splitIntoChunks(text string) []string {
 	return chunks
 }
 
-func (m *RAGMemory) resultToMemoryItem(res chromem.Result) MemoryItem {
+func (m *RAGMemory) vectorToMemoryItem(res persist.MemoryVector) MemoryItem {
 	item := MemoryItem{
 		Content: res.Content,
 	}
@@ -307,3 +477,20 @@ func (m *RAGMemory) resultToMemoryItem(res chromem.Result) MemoryItem {
 	}
 	return item
 }
+
+// pruneBefore deletes chunks not updated since cutoff for the retention job
+// and returns how many.
+func (m *RAGMemory) pruneBefore(cutoff time.Time) int {
+	if m == nil || !m.enabled || m.store == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	docIDs, err := m.store.PruneMemoryVectors(m.collection, cutoff, 0)
+	if err != nil {
+		logger.Warn("[RAG] Failed to prune memories: %v", err)
+		return 0
+	}
+	m.dropFromIndex(docIDs)
+	return len(docIDs)
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+)
+
+func newTestRAGMemory(t *testing.T, memCfg config.MemoryConfig) (*RAGMemory, *persist.Store) {
+	t.Helper()
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), ".coco.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	mem, err := NewRAGMemory(config.EmbeddingConfig{Enabled: true, Provider: "local"}, memCfg, store)
+	if err != nil {
+		t.Fatalf("new rag memory: %v", err)
+	}
+	return mem, store
+}
+
+func TestRAGMemorySearchDedupeAndReload(t *testing.T) {
+	ctx := context.Background()
+	mem, store := newTestRAGMemory(t, config.MemoryConfig{})
+
+	add := func(id, content string) {
+		t.Helper()
+		if err := mem.AddMemory(ctx, MemoryItem{ID: id, Type: MemoryTypeFact, Content: content}); err != nil {
+			t.Fatalf("add %s: %v", id, err)
+		}
+	}
+	add("coffee", "用户喜欢喝美式咖啡，不加糖")
+	add("budget", "The quarterly budget review is on Friday")
+	add("coffee-again", "用户喜欢喝美式咖啡，不加糖")
+
+	if n, _ := store.CountMemoryVectors(mem.collection); n != 2 {
+		t.Fatalf("expected near-identical memory to be deduplicated, got %d chunks", n)
+	}
+
+	items, err := mem.SearchMemories(ctx, "美式咖啡", 1)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "coffee" || items[0].Type != MemoryTypeFact {
+		t.Fatalf("unexpected search result: %#v", items)
+	}
+
+	// A fresh instance rebuilds its index from .coco.db.
+	reloaded, err := NewRAGMemory(config.EmbeddingConfig{Enabled: true, Provider: "local"}, config.MemoryConfig{}, store)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	items, err = reloaded.SearchMemories(ctx, "budget review", 1)
+	if err != nil || len(items) != 1 || items[0].ID != "budget" {
+		t.Fatalf("unexpected result after reload: %#v, %v", items, err)
+	}
+
+	if err := reloaded.DeleteMemory(ctx, "budget"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	items, _ = reloaded.SearchMemories(ctx, "budget review", 5)
+	for _, item := range items {
+		if item.ID == "budget" {
+			t.Fatal("deleted memory still returned")
+		}
+	}
+}
+
+func TestRAGMemoryPrunesBeyondMaxItems(t *testing.T) {
+	ctx := context.Background()
+	mem, store := newTestRAGMemory(t, config.MemoryConfig{RAGMaxItems: 3})
+
+	for i := 0; i < 6; i++ {
+		content := fmt.Sprintf("note %d about topic%d and subject%d", i, i, i*7)
+		if err := mem.AddMemory(ctx, MemoryItem{ID: fmt.Sprintf("m%d", i), Type: MemoryTypeConversation, Content: content}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	if n, _ := store.CountMemoryVectors(mem.collection); n != 3 {
+		t.Fatalf("expected 3 chunks after pruning, got %d", n)
+	}
+	if mem.index.Len() != 3 {
+		t.Fatalf("expected index to track pruning, got %d live vectors", mem.index.Len())
+	}
+	items, _ := mem.SearchMemories(ctx, "topic0", 5)
+	for _, item := range items {
+		if item.ID == "m0" {
+			t.Fatal("oldest memory should have been pruned")
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+)
+
+const (
+	recallDefaultMessages = 10   // messages pulled when no query narrows them
+	recallMaxChars        = 3000 // size of the excerpt brought over
+	recallMessageChars    = 400  // per-message cut within the excerpt
+)
+
+// recallableConversations lists the conversations msg's sender may pull
+// context from, most recent first. Their own conversations (same user ID)
+// always qualify; other user IDs — the same person on another platform —
+// need the admin profile.
+func (a *Agent) recallableConversations(msg router.Message) []string {
+	current := conversationKeyOf(msg)
+	admin := a.toolProfileFor(msg).Name == security.ProfileAdmin
+	var keys []string
+	for _, key := range a.memory.Keys() {
+		if key == current {
+			continue
+		}
+		_, _, userID := persist.ParseConversationKey(key)
+		if userID, _, _ = strings.Cut(userID, persist.TopicSeparator); userID == msg.UserID || admin {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// resolveRecallSource picks the conversation source names: a full
+// conversation key, "platform:channel", a channel ID or a platform name
+// (its most recent conversation).
+func resolveRecallSource(keys []string, source string) (string, bool) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return "", false
+	}
+	matchers := []func(platform, channelID, key string) bool{
+		func(_, _, key string) bool { return key == source },
+		func(platform, channelID, _ string) bool { return platform+":"+channelID == source },
+		func(_, channelID, _ string) bool { return channelID == source },
+		func(platform, _, _ string) bool { return strings.EqualFold(platform, source) },
+	}
+	for _, match := range matchers {
+		for _, key := range keys {
+			platform, channelID, _ := persist.ParseConversationKey(key)
+			if match(platform, channelID, key) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// recallExcerpt renders the part of history worth bringing over: messages
+// mentioning query with the reply that followed, or the latest messages.
+func recallExcerpt(history []Message, query string, limit int) string {
+	var msgs []Message
+	for _, m := range history {
+		if (m.Role == "user" || m.Role == "assistant") && strings.TrimSpace(m.Content) != "" {
+			msgs = append(msgs, m)
+		}
+	}
+	if limit <= 0 {
+		limit = recallDefaultMessages
+	}
+
+	var picked []Message
+	if query = strings.ToLower(strings.TrimSpace(query)); query != "" {
+		for i := 0; i < len(msgs); i++ {
+			if !strings.Contains(strings.ToLower(msgs[i].Content), query) {
+				continue
+			}
+			picked = append(picked, msgs[i])
+			if msgs[i].Role == "user" && i+1 < len(msgs) {
+				i++
+				picked = append(picked, msgs[i])
+			}
+		}
+	} else {
+		picked = msgs
+	}
+	if len(picked) > limit {
+		picked = picked[len(picked)-limit:]
+	}
+
+	// Build from the newest message back so the budget keeps the latest.
+	var lines []string
+	size := 0
+	for i := len(picked) - 1; i >= 0; i-- {
+		speaker := "用户"
+		if picked[i].Role == "assistant" {
+			speaker = "助手"
+		}
+		content := strings.Join(strings.Fields(picked[i].Content), " ")
+		if r := []rune(content); len(r) > recallMessageChars {
+			content = string(r[:recallMessageChars]) + "…"
+		}
+		line := speaker + ": " + content
+		if size+len(line) > recallMaxChars && len(lines) > 0 {
+			break
+		}
+		size += len(line)
+		lines = append([]string{line}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatRecallList(keys []string) string {
+	if len(keys) == 0 {
+		return "没有可引用的其他对话"
+	}
+	var sb strings.Builder
+	sb.WriteString("可引用的对话（/recall-from <平台 或 平台:频道> [关键词]）:\n")
+	for i, key := range keys {
+		if i == 10 {
+			fmt.Fprintf(&sb, "…另有 %d 个\n", len(keys)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s\n", key)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// executeConversationRecall returns an excerpt of another of the sender's
+// conversations to the model, leaving both histories as they are.
+func (a *Agent) executeConversationRecall(ctx context.Context, args map[string]any) string {
+	keys := a.recallableConversations(turnMessage(ctx))
+	source := getString(args, "source")
+	if strings.TrimSpace(source) == "" {
+		return formatRecallList(keys)
+	}
+	key, ok := resolveRecallSource(keys, source)
+	if !ok {
+		return fmt.Sprintf("Error: no conversation of this user matches %q.\n%s", source, formatRecallList(keys))
+	}
+	limit := 0
+	if n, ok := args["limit"].(float64); ok {
+		limit = int(n)
+	}
+	excerpt := recallExcerpt(a.memory.GetHistory(key), getString(args, "query"), limit)
+	if excerpt == "" {
+		return fmt.Sprintf("Conversation %s has nothing matching.", key)
+	}
+	return fmt.Sprintf("Excerpt from conversation %s:\n%s", key, excerpt)
+}
+
+// handleRecallCommand answers "/recall-from <thread|platform> [query]" by
+// copying an excerpt of that conversation into the current one.
+func (a *Agent) handleRecallCommand(msg router.Message, convKey, text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "/recall-from" {
+		return "", false
+	}
+	if !a.toolProfileFor(msg).Allows("conversation_recall") {
+		return "当前身份无权引用其他对话", true
+	}
+	keys := a.recallableConversations(msg)
+	if len(fields) < 2 {
+		return formatRecallList(keys), true
+	}
+	key, ok := resolveRecallSource(keys, fields[1])
+	if !ok {
+		return fmt.Sprintf("找不到对话: %s\n\n%s", fields[1], formatRecallList(keys)), true
+	}
+	query := strings.Join(fields[2:], " ")
+	excerpt := recallExcerpt(a.memory.GetHistory(key), query, 0)
+	if excerpt == "" {
+		return fmt.Sprintf("对话 %s 中没有可引用的内容", key), true
+	}
+
+	// The excerpt joins this conversation's history as one exchange, so
+	// later turns see it without the two histories being merged.
+	a.memory.AddExchange(convKey,
+		Message{Role: "user", Content: text},
+		Message{Role: "assistant", Content: fmt.Sprintf("以下是从对话 %s 引入的上下文：\n%s", key, excerpt)},
+	)
+	return fmt.Sprintf("已从 %s 引入 %d 条消息到当前对话", key, strings.Count(excerpt, "\n")+1), true
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+)
+
+func TestRecallFromAnotherConversation(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	a := &Agent{memory: NewMemory(store, 0)}
+
+	telegram := ConversationKey("telegram", "chat-1", "alice")
+	a.memory.AddExchange(telegram,
+		Message{Role: "user", Content: "机票订的是 10 月 20 日 CA1234"},
+		Message{Role: "assistant", Content: "好的，已记下航班 CA1234"})
+	a.memory.AddExchange(telegram,
+		Message{Role: "user", Content: "明天天气怎么样"},
+		Message{Role: "assistant", Content: "晴"})
+	a.memory.AddExchange(ConversationKey("slack", "C1", "bob"),
+		Message{Role: "user", Content: "bob's secret plans"},
+		Message{Role: "assistant", Content: "noted"})
+
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "alice", Text: "/recall-from telegram CA1234"}
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+
+	reply, ok := a.handleRecallCommand(msg, convKey, msg.Text)
+	if !ok || !strings.Contains(reply, telegram) || !strings.Contains(reply, "2 条") {
+		t.Fatalf("reply = %q, %v", reply, ok)
+	}
+	history := a.memory.GetHistory(convKey)
+	if len(history) != 2 {
+		t.Fatalf("history has %d messages, want the recalled exchange", len(history))
+	}
+	if got := history[1].Content; !strings.Contains(got, "CA1234") || strings.Contains(got, "天气") {
+		t.Fatalf("excerpt = %q", got)
+	}
+	if n := len(a.memory.GetHistory(telegram)); n != 4 {
+		t.Fatalf("source conversation changed: %d messages", n)
+	}
+
+	// Without profiles every sender is admin and sees other user IDs too.
+	if keys := a.recallableConversations(msg); len(keys) != 2 {
+		t.Fatalf("admin recallable = %v", keys)
+	}
+
+	a.applyToolProfiles(nil, security.ProfileNoShell)
+	keys := a.recallableConversations(msg)
+	if len(keys) != 1 || keys[0] != telegram {
+		t.Fatalf("noshell recallable = %v", keys)
+	}
+	if got := a.executeConversationRecall(withTurn(context.Background(), msg), map[string]any{"source": "slack"}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("another user's conversation must not be recalled: %s", got)
+	}
+
+	a.applyToolProfiles(nil, security.ProfileReadonly)
+	if reply, _ := a.handleRecallCommand(msg, convKey, msg.Text); !strings.Contains(reply, "无权") {
+		t.Fatalf("readonly sender recalled: %q", reply)
+	}
+}
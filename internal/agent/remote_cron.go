@@ -13,6 +13,7 @@ import (
 
 	"github.com/kayz/coco/internal/config"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/router"
 )
 
@@ -27,6 +28,7 @@ type remoteCronCreateRequest struct {
 	Tag       string         `json:"tag,omitempty"`
 	Type      string         `json:"type,omitempty"`
 	Schedule  string         `json:"schedule"`
+	RunAt     string         `json:"run_at,omitempty"`
 	Message   string         `json:"message,omitempty"`
 	Prompt    string         `json:"prompt,omitempty"`
 	Tool      string         `json:"tool,omitempty"`
@@ -37,6 +39,13 @@ type remoteCronCreateRequest struct {
 	Platform  string         `json:"platform"`
 	ChannelID string         `json:"channel_id"`
 	UserID    string         `json:"user_id"`
+
+	Provenance *provenance.Record `json:"provenance,omitempty"` // Keeper signs it with its own key
+}
+
+func (r remoteCronCreateRequest) withProvenance(rec provenance.Record) remoteCronCreateRequest {
+	r.Provenance = &rec
+	return r
 }
 
 func newRemoteCronClient(cfg *config.Config) *remoteCronClient {
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/objstore"
+	"github.com/kayz/coco/internal/tools"
+)
+
+// maxRemoteTransferBytes bounds remote_put/remote_get since transfers are buffered in memory.
+const maxRemoteTransferBytes = 512 << 20
+
+// openRemoteStorage resolves the remote_storage entry named target (or the only one configured).
+func openRemoteStorage(target string) (objstore.Store, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", fmt.Errorf("load config: %w", err)
+	}
+	entries := cfg.RemoteStorage
+	if len(entries) == 0 {
+		return nil, "", fmt.Errorf("no remote storage configured (add remote_storage entries to .coco.yaml)")
+	}
+
+	target = strings.TrimSpace(target)
+	var entry *config.RemoteStorageConfig
+	if target == "" {
+		if len(entries) > 1 {
+			return nil, "", fmt.Errorf("multiple remote storages configured, specify target: %s", remoteStorageNames(entries))
+		}
+		entry = &entries[0]
+	} else {
+		for i := range entries {
+			if strings.EqualFold(entries[i].Name, target) {
+				entry = &entries[i]
+				break
+			}
+		}
+		if entry == nil {
+			return nil, "", fmt.Errorf("unknown remote storage %q (available: %s)", target, remoteStorageNames(entries))
+		}
+	}
+
+	store, err := objstore.New(objstore.Config{
+		Type:      entry.Type,
+		Endpoint:  entry.Endpoint,
+		Bucket:    entry.Bucket,
+		Region:    entry.Region,
+		AccessKey: entry.AccessKey,
+		SecretKey: entry.SecretKey,
+		Username:  entry.Username,
+		Password:  entry.Password,
+		Prefix:    entry.Prefix,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("remote storage %s: %w", entry.Name, err)
+	}
+	name := entry.Name
+	if name == "" {
+		name = entry.Type
+	}
+	return store, name, nil
+}
+
+func remoteStorageNames(entries []config.RemoteStorageConfig) string {
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func executeRemotePut(ctx context.Context, args map[string]any) string {
+	localPath, _ := args["local_path"].(string)
+	remotePath, _ := args["remote_path"].(string)
+	target, _ := args["target"].(string)
+	if strings.TrimSpace(localPath) == "" {
+		return "Error: local_path is required"
+	}
+	if isSensitiveFile(localPath) {
+		return "ACCESS DENIED: uploading sensitive files (.env, credentials, keys) is blocked for security. Do NOT retry."
+	}
+
+	absPath, err := filepath.Abs(tools.ExpandTilde(localPath))
+	if err != nil {
+		return fmt.Sprintf("Error: invalid path: %v", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if info.IsDir() {
+		return "Error: local_path is a directory; upload files one at a time"
+	}
+	if info.Size() > maxRemoteTransferBytes {
+		return fmt.Sprintf("Error: file is too large (%s, limit %s)", formatByteSize(int(info.Size())), formatByteSize(maxRemoteTransferBytes))
+	}
+
+	remotePath = strings.TrimLeft(strings.TrimSpace(remotePath), "/")
+	if remotePath == "" {
+		remotePath = filepath.Base(absPath)
+	} else if strings.HasSuffix(remotePath, "/") || (path.Ext(remotePath) == "" && filepath.Ext(absPath) != "") {
+		// Treat extension-less destinations as directories.
+		remotePath = path.Join(remotePath, filepath.Base(absPath))
+	}
+
+	store, name, err := openRemoteStorage(target)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := store.Put(ctx, remotePath, data); err != nil {
+		return fmt.Sprintf("Error: upload failed: %v", err)
+	}
+	return fmt.Sprintf("Uploaded %s (%s) to %s:%s", absPath, formatByteSize(len(data)), name, remotePath)
+}
+
+func executeRemoteGet(ctx context.Context, args map[string]any) string {
+	remotePath, _ := args["remote_path"].(string)
+	localPath, _ := args["local_path"].(string)
+	target, _ := args["target"].(string)
+	remotePath = strings.TrimLeft(strings.TrimSpace(remotePath), "/")
+	if remotePath == "" {
+		return "Error: remote_path is required"
+	}
+
+	if strings.TrimSpace(localPath) == "" {
+		localPath = path.Base(remotePath)
+	}
+	absPath, err := filepath.Abs(tools.ExpandTilde(localPath))
+	if err != nil {
+		return fmt.Sprintf("Error: invalid path: %v", err)
+	}
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		absPath = filepath.Join(absPath, path.Base(remotePath))
+	}
+
+	store, name, err := openRemoteStorage(target)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	data, err := store.Get(ctx, remotePath)
+	if errors.Is(err, objstore.ErrNotFound) {
+		return fmt.Sprintf("Error: %s:%s not found", name, remotePath)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: download failed: %v", err)
+	}
+	if len(data) > maxRemoteTransferBytes {
+		return fmt.Sprintf("Error: file is too large (%s)", formatByteSize(len(data)))
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return fmt.Sprintf("Error: failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(absPath, data, 0644); err != nil {
+		return fmt.Sprintf("Error: failed to write file: %v", err)
+	}
+	return fmt.Sprintf("Downloaded %s:%s (%s) to %s", name, remotePath, formatByteSize(len(data)), absPath)
+}
+
+func executeRemoteList(ctx context.Context, args map[string]any) string {
+	prefix, _ := args["prefix"].(string)
+	target, _ := args["target"].(string)
+
+	store, name, err := openRemoteStorage(target)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	objects, err := store.List(ctx, strings.TrimLeft(strings.TrimSpace(prefix), "/"))
+	if err != nil {
+		return fmt.Sprintf("Error: list failed: %v", err)
+	}
+	if len(objects) == 0 {
+		return fmt.Sprintf("No files found on %s under %q", name, prefix)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	const maxListed = 200
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Files on %s (%d):\n", name, len(objects)))
+	for i, o := range objects {
+		if i >= maxListed {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(objects)-maxListed))
+			break
+		}
+		modified := ""
+		if !o.LastModified.IsZero() {
+			modified = "  " + o.LastModified.Local().Format("2006-01-02 15:04")
+		}
+		sb.WriteString(fmt.Sprintf("- %s  %s%s\n", o.Key, formatByteSize(int(o.Size)), modified))
+	}
+	return strings.TrimSpace(sb.String())
+}
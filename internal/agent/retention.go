@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/provenance"
+)
+
+// retentionInterval is how often the retention job runs.
+const retentionInterval = 24 * time.Hour
+
+// RetentionResult is what retention removed, or would remove, from one
+// category.
+type RetentionResult struct {
+	Category string
+	Days     int
+	Cutoff   time.Time
+	Count    int
+}
+
+func (a *Agent) applyRetention(cfg config.RetentionConfig) {
+	a.securityMu.Lock()
+	a.retention = cfg
+	a.securityMu.Unlock()
+}
+
+// PruneRetention deletes the data of each category older than its
+// retention, or only counts it when dryRun is set. Categories kept forever
+// are left out of the results.
+func PruneRetention(store *persist.Store, cfg config.RetentionConfig, now time.Time, dryRun bool) ([]RetentionResult, error) {
+	var results []RetentionResult
+	for _, category := range config.RetentionCategories {
+		days := cfg.DaysFor(category)
+		if days == 0 {
+			continue
+		}
+		r := RetentionResult{Category: category, Days: days, Cutoff: now.AddDate(0, 0, -days)}
+		var err error
+		switch {
+		case category == "audit":
+			r.Count, err = provenance.PruneAudit(provenance.DefaultAuditPath(), r.Cutoff, dryRun)
+		case store == nil:
+			continue
+		case dryRun:
+			r.Count, err = store.CountBefore(category, r.Cutoff)
+		default:
+			r.Count, err = store.DeleteBefore(category, r.Cutoff)
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// StartRetention prunes old data now and then once a day, following the
+// retention section of the current config.
+func (a *Agent) StartRetention(ctx context.Context) {
+	go func() {
+		a.runRetention()
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.runRetention()
+			}
+		}
+	}()
+}
+
+func (a *Agent) runRetention() {
+	a.securityMu.RLock()
+	cfg := a.retention
+	a.securityMu.RUnlock()
+
+	now := time.Now()
+	// Memories go through RAG memory first so its index forgets them too.
+	if days := cfg.DaysFor("memories"); days > 0 {
+		if n := a.ragMemory.pruneBefore(now.AddDate(0, 0, -days)); n > 0 {
+			logger.Info("[Agent] Retention: deleted %d memory chunk(s)", n)
+		}
+	}
+	results, err := PruneRetention(a.persistStore, cfg, now, false)
+	for _, r := range results {
+		if r.Count > 0 {
+			logger.Info("[Agent] Retention: deleted %d %s older than %d days", r.Count, r.Category, r.Days)
+		}
+	}
+	if err != nil {
+		logger.Warn("[Agent] Retention pruning failed: %v", err)
+	}
+}
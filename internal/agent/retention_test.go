@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/provenance"
+)
+
+func TestPruneRetention(t *testing.T) {
+	t.Setenv("COCO_DATA_DIR", t.TempDir())
+	a, _ := newFocusTestAgent(t)
+	store := a.persistStore
+	now := time.Now()
+
+	conv, err := store.GetOrCreateConversation("telegram", "c1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"你好", "明天提醒我开会"} {
+		if err := store.AddMessage(conv.ID, persist.Message{Role: "user", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.RecordToolMetric(persist.ToolMetric{ToolName: "weather", CreatedAt: now.AddDate(0, 0, -400)})
+
+	cfgPath := filepath.Join(t.TempDir(), ".coco.yaml")
+	os.WriteFile(cfgPath, []byte("a: 1\n"), 0600)
+	audit := provenance.DefaultAuditPath()
+	for _, at := range []time.Time{now.AddDate(-3, 0, 0), now.AddDate(-3, 0, 1)} {
+		if err := provenance.RecordChange(audit, nil, cfgPath, provenance.Record{CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A week past 90 days from now, every message is out of retention.
+	later := now.AddDate(0, 0, 97)
+	cfg := config.RetentionConfig{Days: 730, Categories: map[string]int{"messages": 90, "metrics": -1}}
+	results, err := PruneRetention(store, cfg, later, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, r := range results {
+		got[r.Category] = r.Count
+	}
+	if _, ok := got["metrics"]; ok {
+		t.Fatal("metrics kept forever were reported")
+	}
+	if got["messages"] != 2 || got["audit"] != 1 || got["memories"] != 0 {
+		t.Fatalf("dry run = %v", got)
+	}
+	if n, _ := store.CountBefore("messages", later); n != 2 {
+		t.Fatalf("dry run deleted messages: %d left", n)
+	}
+
+	if _, err := PruneRetention(store, cfg, later, false); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.CountBefore("messages", later); n != 0 {
+		t.Fatalf("%d messages survived", n)
+	}
+	entries, _ := provenance.ReadAudit(audit)
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want the file's last one kept", len(entries))
+	}
+	if n, _ := store.CountBefore("metrics", now); n != 1 {
+		t.Fatalf("metrics = %d, want kept", n)
+	}
+}
@@ -0,0 +1,310 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/dialog"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	ritualJobTag        = "ritual"
+	ritualJobPrefix     = "ritual-"
+	defaultRitualFolder = "rituals"
+	// ritualSessionTTL is how long a ritual waits for the next answer; people
+	// answer a standup between other work, so it is longer than other forms.
+	ritualSessionTTL = 12 * time.Hour
+)
+
+type ritualSettings struct {
+	rituals []config.RitualConfig
+	vault   string
+}
+
+// applyRituals installs the rituals section and keeps one cron job per
+// scheduled ritual.
+func (a *Agent) applyRituals(cfgs []config.RitualConfig, vault string) {
+	seen := map[string]bool{}
+	var rituals []config.RitualConfig
+	for _, r := range cfgs {
+		r.Name = strings.TrimSpace(r.Name)
+		switch {
+		case r.Name == "":
+			logger.Warn("[Agent] Ritual without a name ignored")
+			continue
+		case seen[r.Name]:
+			logger.Warn("[Agent] Duplicate ritual %q ignored", r.Name)
+			continue
+		case len(r.Questions) == 0:
+			logger.Warn("[Agent] Ritual %q has no questions", r.Name)
+			continue
+		}
+		if _, err := ritualSchedule(r.Schedule); err != nil {
+			logger.Warn("[Agent] Ritual %q: %v", r.Name, err)
+			r.Schedule = ""
+		}
+		if r.Schedule != "" && len(splitChatTarget(r.Target)) != 3 {
+			logger.Warn("[Agent] Ritual %q has a schedule but no target \"platform:channel_id:user_id\"", r.Name)
+			r.Schedule = ""
+		}
+		seen[r.Name] = true
+		rituals = append(rituals, r)
+	}
+
+	a.securityMu.Lock()
+	a.rituals = ritualSettings{rituals: rituals, vault: normalizePath(vault)}
+	a.securityMu.Unlock()
+	a.ensureRitualJobs()
+}
+
+func (a *Agent) currentRituals() ritualSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.rituals
+}
+
+func (s ritualSettings) find(name string) (config.RitualConfig, bool) {
+	for _, r := range s.rituals {
+		if strings.EqualFold(r.Name, strings.TrimSpace(name)) {
+			return r, true
+		}
+	}
+	return config.RitualConfig{}, false
+}
+
+func ritualTitle(r config.RitualConfig) string {
+	if t := strings.TrimSpace(r.Title); t != "" {
+		return t
+	}
+	return r.Name
+}
+
+// ritualSchedule turns HH:MM into a daily cron expression and passes other
+// schedules through, in the six-field form the scheduler stores.
+func ritualSchedule(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if at, err := time.Parse("15:04", raw); err == nil {
+		return fmt.Sprintf("0 %d %d * * *", at.Minute(), at.Hour()), nil
+	}
+	switch len(strings.Fields(raw)) {
+	case 5:
+		return "0 " + raw, nil
+	case 6:
+		return raw, nil
+	}
+	return "", fmt.Errorf("schedule %q is neither HH:MM nor a cron expression", raw)
+}
+
+// splitChatTarget splits "platform:channel_id:user_id"; the user part may be
+// missing.
+func splitChatTarget(target string) []string {
+	parts := strings.SplitN(strings.TrimSpace(target), ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil
+	}
+	return parts
+}
+
+// ensureRitualJobs keeps exactly one cron job for each scheduled ritual.
+func (a *Agent) ensureRitualJobs() {
+	if a.cronScheduler == nil {
+		return
+	}
+	want := map[string]string{}
+	for _, r := range a.currentRituals().rituals {
+		if schedule, _ := ritualSchedule(r.Schedule); schedule != "" {
+			want[ritualJobPrefix+r.Name] = schedule
+		}
+	}
+	kept := map[string]bool{}
+	for _, job := range a.cronScheduler.ListJobsByTag(ritualJobTag) {
+		if schedule, ok := want[job.Name]; ok && job.Schedule == schedule && !kept[job.Name] {
+			kept[job.Name] = true
+			continue
+		}
+		if err := a.cronScheduler.RemoveJob(job.ID); err != nil {
+			logger.Warn("[Agent] Failed to remove ritual job %s: %v", job.Name, err)
+		}
+	}
+	for name, schedule := range want {
+		if kept[name] {
+			continue
+		}
+		args := map[string]any{"action": "start", "name": strings.TrimPrefix(name, ritualJobPrefix)}
+		if _, err := a.cronScheduler.AddJobWithTag(name, ritualJobTag, schedule, "ritual", args); err != nil {
+			logger.Warn("[Agent] Failed to schedule ritual %s: %v", name, err)
+		}
+	}
+}
+
+// runRitual starts a ritual in its target chat; the cron job calls it.
+func (a *Agent) runRitual(name string) string {
+	r, ok := a.currentRituals().find(name)
+	if !ok {
+		return fmt.Sprintf("Error: unknown ritual %q", name)
+	}
+	target := splitChatTarget(r.Target)
+	if len(target) != 3 {
+		return fmt.Sprintf("Error: ritual %q has no target", r.Name)
+	}
+	if a.notifier == nil {
+		return "Error: no chat notifier to ask the questions"
+	}
+	opening, err := a.startRitual(r, ConversationKey(target[0], target[1], target[2]))
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	if err := a.notifier.NotifyChatUser(target[0], target[1], target[2], opening); err != nil {
+		a.dialogs.clear(ConversationKey(target[0], target[1], target[2]))
+		return fmt.Sprintf("Error: asking %s: %v", r.Target, err)
+	}
+	return fmt.Sprintf("Ritual %s started in %s", r.Name, r.Target)
+}
+
+// startRitual opens r's dialog in convKey and returns its first question.
+func (a *Agent) startRitual(r config.RitualConfig, convKey string) (string, error) {
+	if a.dialogs.get(convKey) != nil {
+		return "", fmt.Errorf("another dialog is waiting for answers in %s", convKey)
+	}
+	title := ritualTitle(r)
+	form := dialog.Form{Title: title}
+	for i, q := range r.Questions {
+		form.Fields = append(form.Fields, dialog.Field{
+			Name:     fmt.Sprintf("q%d", i+1),
+			Label:    q,
+			Prompt:   q,
+			Optional: true,
+		})
+	}
+	dlg, err := dialog.New(form)
+	if err != nil {
+		return "", err
+	}
+	question, _ := dlg.Start()
+	a.dialogs.set(convKey, &dialogSession{
+		dlg: dlg,
+		ttl: ritualSessionTTL,
+		finish: func(ctx context.Context, values map[string]string) string {
+			return a.finishRitual(r, form, values, time.Now())
+		},
+	})
+	logger.Info("[Agent] Ritual %s started in %s", r.Name, convKey)
+	return fmt.Sprintf("📋 %s（回复“取消”结束）\n%s", title, question), nil
+}
+
+// finishRitual files the answers in the vault and posts the summary.
+func (a *Agent) finishRitual(r config.RitualConfig, form dialog.Form, values map[string]string, now time.Time) string {
+	title := ritualTitle(r)
+	var answers strings.Builder
+	for _, f := range form.Fields {
+		v := values[f.Name]
+		if v == "" {
+			v = "（跳过）"
+		}
+		fmt.Fprintf(&answers, "**%s**\n%s\n\n", f.Label, v)
+	}
+	body := strings.TrimSpace(answers.String())
+
+	reply := fmt.Sprintf("✅ 「%s」已完成", title)
+	if note, err := a.saveRitualNote(r, body, now); err != nil {
+		logger.Warn("[Agent] Failed to save ritual %s: %v", r.Name, err)
+		reply += "\n⚠️ 未保存到笔记库: " + err.Error()
+	} else if note != "" {
+		reply += "\n已保存到 " + note
+	}
+	if post := strings.TrimSpace(r.Post); post != "" {
+		target := splitChatTarget(post)
+		var err error
+		switch {
+		case target == nil:
+			err = fmt.Errorf("post %q is not \"platform:channel_id\"", post)
+		case a.notifier == nil:
+			err = fmt.Errorf("no chat notifier")
+		default:
+			target = append(target, "")
+			err = a.notifier.NotifyChatUser(target[0], target[1], target[2], fmt.Sprintf("📋 %s %s\n\n%s", title, now.Format("2006-01-02"), body))
+		}
+		if err != nil {
+			logger.Warn("[Agent] Failed to post ritual %s: %v", r.Name, err)
+			reply += "\n⚠️ 未发送到 " + post + ": " + err.Error()
+		} else {
+			reply += "\n已发送到 " + post
+		}
+	}
+	return reply
+}
+
+// saveRitualNote appends the answers to the day's note of the ritual and
+// returns its path, or "" when no vault is configured.
+func (a *Agent) saveRitualNote(r config.RitualConfig, body string, now time.Time) (string, error) {
+	vault := a.currentRituals().vault
+	if vault == "" {
+		return "", nil
+	}
+	folder := strings.TrimSpace(r.Folder)
+	if folder == "" {
+		folder = defaultRitualFolder
+	}
+	dir := filepath.Join(vault, folder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s %s.md", now.Format("2006-01-02"), strings.NewReplacer("/", "-", `\`, "-").Replace(r.Name)))
+	var sb strings.Builder
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintf(&sb, "# %s %s\n", ritualTitle(r), now.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&sb, "\n## %s\n\n%s\n", now.Format("15:04"), body)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(sb.String()); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// handleRitualCommand answers /ritual, which lists the rituals, and
+// /ritual name, which runs one now in this chat.
+func (a *Agent) handleRitualCommand(msg router.Message, text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || fields[0] != "/ritual" {
+		return "", false
+	}
+	s := a.currentRituals()
+	if len(fields) == 1 {
+		if len(s.rituals) == 0 {
+			return "还没有配置例行对话（.coco.yaml 的 rituals）", true
+		}
+		var sb strings.Builder
+		sb.WriteString("例行对话（/ritual 名称 立即开始）：")
+		for _, r := range s.rituals {
+			fmt.Fprintf(&sb, "\n- %s：%s，%d 个问题", r.Name, ritualTitle(r), len(r.Questions))
+			if r.Schedule != "" {
+				fmt.Fprintf(&sb, "，%s", r.Schedule)
+			}
+		}
+		return sb.String(), true
+	}
+	r, ok := s.find(strings.Join(fields[1:], " "))
+	if !ok {
+		return fmt.Sprintf("没有名为 %s 的例行对话，/ritual 查看全部", strings.Join(fields[1:], " ")), true
+	}
+	opening, err := a.startRitual(r, conversationKeyOf(msg))
+	if err != nil {
+		return "无法开始: " + err.Error(), true
+	}
+	return opening, true
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestRitualAsksQuestionsAndFilesAnswers(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	notifier := &recordingNotifier{}
+	a := &Agent{memory: NewMemory(store, 0), notifier: notifier}
+	vault := t.TempDir()
+	a.applyRituals([]config.RitualConfig{{
+		Name:      "standup",
+		Title:     "每日站会",
+		Schedule:  "09:30",
+		Target:    "slack:dm:u1",
+		Questions: []string{"昨天做了什么？", "今天计划做什么？", "有什么阻碍？"},
+		Post:      "slack:team",
+	}}, vault)
+
+	if got := a.runRitual("standup"); !strings.Contains(got, "started") {
+		t.Fatalf("run = %q", got)
+	}
+	if len(notifier.messages) != 1 || notifier.targets[0] != "slack:dm:u1" || !strings.Contains(notifier.messages[0], "昨天做了什么") {
+		t.Fatalf("opening = %q to %q", notifier.messages, notifier.targets)
+	}
+	if got := a.runRitual("standup"); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("second run while waiting = %q", got)
+	}
+
+	msg := router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1", Username: "小王"}
+	var reply string
+	for _, answer := range []string{"修了登录 bug", "写周报", "跳过"} {
+		msg.Text = answer
+		resp, handled := a.continueDialog(context.Background(), msg)
+		if !handled {
+			t.Fatalf("answer %q not taken", answer)
+		}
+		reply = resp.Text
+	}
+	if !strings.Contains(reply, "已完成") || !strings.Contains(reply, "已发送到 slack:team") {
+		t.Fatalf("reply = %q", reply)
+	}
+
+	notes, _ := filepath.Glob(filepath.Join(vault, "rituals", "*standup.md"))
+	if len(notes) != 1 {
+		t.Fatalf("notes = %v", notes)
+	}
+	data, _ := os.ReadFile(notes[0])
+	if !strings.Contains(string(data), "# 每日站会") || !strings.Contains(string(data), "**今天计划做什么？**\n写周报") || !strings.Contains(string(data), "（跳过）") {
+		t.Fatalf("note:\n%s", data)
+	}
+	if last := len(notifier.targets) - 1; notifier.targets[last] != "slack:team:" || !strings.Contains(notifier.messages[last], "修了登录 bug") {
+		t.Fatalf("post = %q to %q", notifier.messages[last], notifier.targets[last])
+	}
+}
+
+func TestRitualSchedule(t *testing.T) {
+	for raw, want := range map[string]string{"09:30": "0 30 9 * * *", "0 18 * * 5": "0 0 18 * * 5", "": ""} {
+		if got, err := ritualSchedule(raw); err != nil || got != want {
+			t.Errorf("ritualSchedule(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ritualSchedule("every morning"); err == nil {
+		t.Error("expected an error for an unknown schedule")
+	}
+}
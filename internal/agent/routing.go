@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+// plannerMinIntellect keeps the planner off models too weak to produce
+// reliable JSON plans.
+const plannerMinIntellect = 2
+
+// routingSettings is the routing section of the config.
+type routingSettings struct {
+	policy  ai.RoutingPolicy
+	planner ai.RoutingPolicy
+	tags    map[string]ai.RoutingPolicy
+}
+
+func (a *Agent) applyRouting(cfg config.RoutingConfig) {
+	s := routingSettings{
+		policy:  parsePolicy("routing.policy", cfg.Policy, ai.PolicyBestQuality),
+		planner: parsePolicy("routing.planner", cfg.Planner, ai.PolicyFastest),
+		tags:    make(map[string]ai.RoutingPolicy, len(cfg.Tags)),
+	}
+	for tag, raw := range cfg.Tags {
+		if p := parsePolicy("routing.tags."+tag, raw, ""); p != "" {
+			s.tags[strings.ToLower(strings.TrimSpace(tag))] = p
+		}
+	}
+	if a.budgets != nil {
+		a.budgets.SetCaps(cfg.Budgets)
+	}
+	a.securityMu.Lock()
+	a.routing = s
+	a.securityMu.Unlock()
+}
+
+func parsePolicy(key, raw string, fallback ai.RoutingPolicy) ai.RoutingPolicy {
+	if strings.TrimSpace(raw) == "" {
+		return fallback
+	}
+	p, ok := ai.ParseRoutingPolicy(raw)
+	switch {
+	case ok:
+		return p
+	case fallback == "":
+		logger.Warn("[Agent] Unknown %s %q, ignoring it", key, raw)
+	default:
+		logger.Warn("[Agent] Unknown %s %q, using %s", key, raw, fallback)
+	}
+	return fallback
+}
+
+func (a *Agent) currentRouting() routingSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	s := a.routing
+	if s.policy == "" {
+		s.policy = ai.PolicyBestQuality
+	}
+	if s.planner == "" {
+		s.planner = ai.PolicyFastest
+	}
+	return s
+}
+
+// answerPolicy is the policy for answering msg: the channel's routing, else
+// the one for the task's complexity, else routing.policy.
+func (a *Agent) answerPolicy(msg router.Message, complexity string) ai.RoutingPolicy {
+	if p, ok := a.channelProfileFor(msg); ok {
+		if policy, ok := ai.ParseRoutingPolicy(p.Routing); ok {
+			return policy
+		}
+	}
+	s := a.currentRouting()
+	if policy, ok := s.tags[complexity]; ok {
+		return policy
+	}
+	return s.policy
+}
+
+// complexityIntellect is the least intellect (see ai.ModelConfig.IntellectRank)
+// a model needs for a task of the planner's complexity.
+func complexityIntellect(complexity string) int {
+	switch complexity {
+	case "simple":
+		return 1
+	case "complex":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// recordModelUsage counts a reply's tokens against the model's daily budget
+// and stores them for the daily totals.
+func (a *Agent) recordModelUsage(model *ai.ModelConfig, usage TokenUsage) {
+	if model == nil || (usage.InputTokens == 0 && usage.OutputTokens == 0) {
+		return
+	}
+	now := time.Now()
+	cost := model.TokenCost(usage.InputTokens, usage.OutputTokens)
+	if a.budgets.Add(model.Name, cost, now) {
+		logger.Warn("[Agent] Model %s reached its daily budget; using cheaper models until midnight", model.Name)
+	}
+	if a.persistStore == nil {
+		return
+	}
+	if err := a.persistStore.AddModelUsage(now.Format("2006-01-02"), model.Name, usage.InputTokens, usage.OutputTokens, cost); err != nil {
+		logger.Warn("[Agent] Failed to record usage of %s: %v", model.Name, err)
+	}
+}
+
+// restoreModelSpend reloads today's spending so a restart does not reset
+// the budgets.
+func (a *Agent) restoreModelSpend() {
+	if a.persistStore == nil || a.budgets == nil {
+		return
+	}
+	day := time.Now().Format("2006-01-02")
+	usage, err := a.persistStore.ModelUsageOn(day)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load today's model usage: %v", err)
+		return
+	}
+	spent := make(map[string]float64, len(usage))
+	for _, u := range usage {
+		spent[u.Model] = u.Cost
+	}
+	a.budgets.Restore(day, spent)
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestAnswerPolicyPrecedence(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.applyChannelProfiles(map[string]config.ChannelProfileConfig{
+		"telegram:ops": {Routing: "fastest"},
+	})
+	a.applyRouting(config.RoutingConfig{
+		Policy: "cheapest-capable",
+		Tags:   map[string]string{"complex": "best-quality", "simple": "bogus"},
+	})
+
+	if got := a.currentRouting().planner; got != ai.PolicyFastest {
+		t.Fatalf("planner default = %s", got)
+	}
+	chat := router.Message{Platform: "telegram", ChannelID: "c1"}
+	if got := a.answerPolicy(chat, "normal"); got != ai.PolicyCheapestCapable {
+		t.Fatalf("default = %s", got)
+	}
+	if got := a.answerPolicy(chat, "complex"); got != ai.PolicyBestQuality {
+		t.Fatalf("tagged = %s", got)
+	}
+	if got := a.answerPolicy(chat, "simple"); got != ai.PolicyCheapestCapable {
+		t.Fatalf("invalid tag policy = %s", got)
+	}
+	if got := a.answerPolicy(router.Message{Platform: "telegram", ChannelID: "ops"}, "complex"); got != ai.PolicyFastest {
+		t.Fatalf("channel = %s", got)
+	}
+}
+
+func TestModelUsageSurvivesRestart(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	a.budgets = ai.NewBudgets()
+	a.applyRouting(config.RoutingConfig{Budgets: map[string]float64{"sonnet": 0.02}})
+	model := &ai.ModelConfig{Name: "sonnet", InputPrice: 3, OutputPrice: 15}
+
+	a.recordModelUsage(model, TokenUsage{InputTokens: 1000, OutputTokens: 500})
+	if a.budgets.Exhausted("sonnet", time.Now()) {
+		t.Fatalf("exhausted after spending %.4f of 0.02", a.budgets.Spent("sonnet", time.Now()))
+	}
+	a.recordModelUsage(model, TokenUsage{OutputTokens: 1000})
+	if !a.budgets.Exhausted("sonnet", time.Now()) {
+		t.Fatalf("spent %.4f, budget not exhausted", a.budgets.Spent("sonnet", time.Now()))
+	}
+
+	restarted := &Agent{persistStore: a.persistStore, budgets: ai.NewBudgets()}
+	restarted.applyRouting(config.RoutingConfig{Budgets: map[string]float64{"sonnet": 0.02}})
+	restarted.restoreModelSpend()
+	if !restarted.budgets.Exhausted("sonnet", time.Now()) {
+		t.Fatal("spending was not restored")
+	}
+}
@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/secrets"
+)
+
+// SecretVaultPaths returns the vault and key file locations under the data directory's .coco directory.
+func SecretVaultPaths() (vaultPath, keyPath string) {
+	dir := datadir.Path(".coco")
+	return filepath.Join(dir, "vault.json"), filepath.Join(dir, "vault.key")
+}
+
+// OpenSecretVault opens the local secret vault, creating its key on first use.
+func OpenSecretVault() (*secrets.Vault, error) {
+	vaultPath, keyPath := SecretVaultPaths()
+	return secrets.Open(vaultPath, keyPath)
+}
+
+// loadSecretValues returns every vault secret, or nil when no vault exists yet.
+func loadSecretValues() map[string]string {
+	vaultPath, _ := SecretVaultPaths()
+	if _, err := os.Stat(vaultPath); err != nil {
+		return nil
+	}
+	vault, err := OpenSecretVault()
+	if err != nil {
+		logger.Warn("[Secrets] Failed to open vault: %v", err)
+		return nil
+	}
+	values, err := vault.Values()
+	if err != nil {
+		logger.Warn("[Secrets] Failed to read vault: %v", err)
+		return nil
+	}
+	return values
+}
+
+// redactSecretValues replaces any vault plaintext in tool output with its {{secret:name}} reference.
+func redactSecretValues(s string) string {
+	if s == "" {
+		return s
+	}
+	values := loadSecretValues()
+	if len(values) == 0 {
+		return s
+	}
+	return secrets.Redact(s, values)
+}
+
+// secretArgFields lists the tool arguments where {{secret:name}} is expanded.
+// Anywhere else the plaintext could land in a shell, a file or a request to
+// any host, so a reference there is refused instead.
+var secretArgFields = map[string][]string{
+	"http_request": {"headers"},
+}
+
+// expandSecretArgs substitutes {{secret:name}} references in the allowed arguments of tool
+// (recursively) and refuses references anywhere else.
+// The original map is left untouched so plaintext never flows back into logs or history.
+func expandSecretArgs(tool string, args map[string]any) (map[string]any, error) {
+	if !argsHaveSecretRef(args) {
+		return args, nil
+	}
+	for k, v := range args {
+		if argsHaveSecretRef(v) && !slices.Contains(secretArgFields[tool], k) {
+			return nil, fmt.Errorf("{{secret:...}} is not allowed in %s.%s; secrets are only used in http_request headers with a profile", tool, k)
+		}
+	}
+	if tool == "http_request" && strings.TrimSpace(getString(args, "profile")) == "" {
+		// A profile pins the request to its base_url, so the secret cannot be sent to another host.
+		return nil, errors.New("{{secret:...}} in http_request headers needs a profile, which keeps the request under its base_url")
+	}
+	vault, err := OpenSecretVault()
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expandSecretValue(vault, args)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(map[string]any), nil
+}
+
+func argsHaveSecretRef(v any) bool {
+	switch t := v.(type) {
+	case string:
+		return secrets.HasRef(t)
+	case map[string]any:
+		for _, item := range t {
+			if argsHaveSecretRef(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range t {
+			if argsHaveSecretRef(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func expandSecretValue(vault *secrets.Vault, v any) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return vault.Expand(t)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			expanded, err := expandSecretValue(vault, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			expanded, err := expandSecretValue(vault, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func executeSecretsGenerate(args map[string]any) string {
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+	if !secrets.ValidName(name) {
+		return "Error: name is required (letters, digits, '.', '_' or '-', e.g. \"nas-password\")"
+	}
+	kind, _ := args["kind"].(string)
+	length := 0
+	if l, ok := args["length"].(float64); ok {
+		length = int(l)
+	}
+	symbols := true
+	if s, ok := args["symbols"].(bool); ok {
+		symbols = s
+	}
+	note, _ := args["note"].(string)
+	overwrite, _ := args["overwrite"].(bool)
+
+	vault, err := OpenSecretVault()
+	if err != nil {
+		return fmt.Sprintf("Error: failed to open vault: %v", err)
+	}
+	if !overwrite {
+		if _, err := vault.Get(name); err == nil {
+			return fmt.Sprintf("Error: secret %q already exists. Ask the user before replacing it (overwrite: true).", name)
+		} else if !errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+
+	value, err := secrets.Generate(kind, length, symbols)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := vault.Set(name, value, strings.TrimSpace(note)); err != nil {
+		return fmt.Sprintf("Error: failed to store secret: %v", err)
+	}
+	return fmt.Sprintf("Generated a %d-character secret and stored it as %s. The value is not shown to you; pass %s in http_request headers (with a profile) to use it. The user can reveal it with `coco secrets get %s`.",
+		len(value), secrets.Ref(name), secrets.Ref(name), name)
+}
+
+func executeSecretsList() string {
+	vault, err := OpenSecretVault()
+	if err != nil {
+		return fmt.Sprintf("Error: failed to open vault: %v", err)
+	}
+	entries, err := vault.List()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(entries) == 0 {
+		return "The vault is empty. The user can add secrets with `/secret set <name> <value>` or `coco secrets set <name>`."
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Stored secrets (%d), use them as {{secret:<name>}} in http_request headers with a profile:\n", len(entries)))
+	for _, e := range entries {
+		sb.WriteString("- " + e.Name)
+		if e.Note != "" {
+			sb.WriteString(" — " + e.Note)
+		}
+		sb.WriteString(fmt.Sprintf(" (updated %s)\n", e.UpdatedAt.Format("2006-01-02")))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// handleSecretCommand implements /secret set|list|del. These messages never reach the model.
+func handleSecretCommand(text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "/secret") {
+		return "", false
+	}
+	usage := "用法:\n  /secret set <名称> <值>   保存密钥（不会发送给模型）\n  /secret list              列出已保存的密钥名称\n  /secret del <名称>        删除密钥\nhttp_request 使用 profile 时可在请求头中以 {{secret:名称}} 引用；也可写在配置文件的 http_profiles 中。"
+	if len(fields) < 2 {
+		return usage, true
+	}
+
+	vault, err := OpenSecretVault()
+	if err != nil {
+		return fmt.Sprintf("打开密钥库失败: %v", err), true
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "set":
+		if len(fields) < 4 {
+			return usage, true
+		}
+		name := fields[2]
+		// Keep the value verbatim after the name, including inner spaces.
+		rest := text
+		for _, f := range fields[:3] {
+			rest = strings.TrimSpace(rest)[len(f):]
+		}
+		value := strings.TrimSpace(rest)
+		if err := vault.Set(name, value, ""); err != nil {
+			return fmt.Sprintf("保存失败: %v", err), true
+		}
+		return fmt.Sprintf("已保存密钥 %s。建议删除聊天记录中的这条消息。", name), true
+	case "list", "ls":
+		return executeSecretsList(), true
+	case "del", "delete", "rm":
+		if len(fields) < 3 {
+			return usage, true
+		}
+		if err := vault.Delete(fields[2]); err != nil {
+			return fmt.Sprintf("删除失败: %v", err), true
+		}
+		return fmt.Sprintf("已删除密钥 %s", fields[2]), true
+	}
+	return usage, true
+}
+
+// logSafeText hides /secret command arguments from logs.
+func logSafeText(text string) string {
+	if fields := strings.Fields(text); len(fields) > 0 && strings.EqualFold(fields[0], "/secret") {
+		return "/secret [redacted]"
+	}
+	return text
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+// legacyWorkspaceNames maps prompt file names used by older layouts to the
+// current ones. Only coco's own required files are listed, since the
+// workspace may be a directory the user shares with other projects.
+var legacyWorkspaceNames = map[string]string{
+	"AGENT.md":  "AGENTS.md",
+	"agent.md":  "AGENTS.md",
+	"agents.md": "AGENTS.md",
+	"soul.md":   "SOUL.md",
+}
+
+// selfCheckReport lists what the startup self-check repaired and what it could not.
+type selfCheckReport struct {
+	Fixed    []string
+	Problems []string
+}
+
+func (r *selfCheckReport) fixed(format string, args ...any) {
+	r.Fixed = append(r.Fixed, fmt.Sprintf(format, args...))
+}
+
+func (r *selfCheckReport) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// runStartupSelfCheck validates the workspace prompt files, the database
+// schema and the model registry, migrating old layouts where it can, and
+// logs what it fixed and what still needs attention.
+func runStartupSelfCheck(store *persist.Store, registry *ai.Registry) selfCheckReport {
+	var report selfCheckReport
+	checkWorkspaceLayout(getWorkspaceDir(), &report)
+	checkDatabaseSchema(store, &report)
+	if registry != nil {
+		for _, p := range registry.Validate() {
+			report.problem("registry: %s", p)
+		}
+	}
+
+	for _, f := range report.Fixed {
+		logger.Info("[SelfCheck] Fixed: %s", f)
+	}
+	for _, p := range report.Problems {
+		logger.Warn("[SelfCheck] %s", p)
+	}
+	return report
+}
+
+// checkWorkspaceLayout renames legacy prompt files, creates missing ones from
+// templates and refills required files that were left empty.
+func checkWorkspaceLayout(workspaceDir string, report *selfCheckReport) {
+	for legacy, current := range legacyWorkspaceNames {
+		from := filepath.Join(workspaceDir, legacy)
+		to := filepath.Join(workspaceDir, current)
+		if !fileExactlyExists(from) || fileExactlyExists(to) {
+			continue
+		}
+		if err := os.Rename(from, to); err != nil {
+			report.problem("workspace: rename %s to %s: %v", legacy, current, err)
+			continue
+		}
+		report.fixed("workspace: renamed legacy %s to %s", legacy, current)
+	}
+
+	created, err := ensureWorkspaceContractFiles()
+	for _, name := range created {
+		report.fixed("workspace: created missing %s from template", name)
+	}
+	if err != nil {
+		report.problem("workspace: %v", err)
+	}
+
+	for _, file := range workspaceTemplateFiles {
+		if !file.required {
+			continue
+		}
+		path := filepath.Join(workspaceDir, file.name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			report.problem("workspace: required %s is unreadable: %v", file.name, err)
+			continue
+		}
+		if strings.TrimSpace(stripYAMLFrontmatter(string(data))) != "" {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			report.problem("workspace: required %s is empty and could not be restored: %v", file.name, err)
+			continue
+		}
+		report.fixed("workspace: restored empty %s from template", file.name)
+	}
+}
+
+// fileExactlyExists reports whether dir contains an entry with exactly this
+// name, so case-insensitive file systems do not treat agents.md as AGENTS.md.
+func fileExactlyExists(path string) bool {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	name := filepath.Base(path)
+	for _, e := range entries {
+		if e.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkDatabaseSchema(store *persist.Store, report *selfCheckReport) {
+	if store == nil {
+		return
+	}
+	version, err := store.SchemaVersion()
+	if err != nil {
+		report.problem("database: %v", err)
+		return
+	}
+	if version > persist.SchemaVersion {
+		report.problem("database: schema v%d was written by a newer coco (this build knows v%d); upgrade coco before relying on it", version, persist.SchemaVersion)
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartupSelfCheckMigratesLegacyWorkspace(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+
+	if err := os.WriteFile(filepath.Join(tmp, "AGENT.md"), []byte("Legacy agent rules"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "SOUL.md"), []byte("---\ntitle: soul\n---\n  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := runStartupSelfCheck(nil, nil)
+	if len(report.Problems) != 0 {
+		t.Fatalf("unexpected problems: %v", report.Problems)
+	}
+	fixed := strings.Join(report.Fixed, "\n")
+	for _, want := range []string{"renamed legacy AGENT.md to AGENTS.md", "restored empty SOUL.md", "created missing USER.md"} {
+		if !strings.Contains(fixed, want) {
+			t.Fatalf("report missing %q:\n%s", want, fixed)
+		}
+	}
+
+	bundle := loadWorkspacePromptBundle()
+	if !strings.Contains(bundle, "Legacy agent rules") || !strings.Contains(bundle, "# SOUL.md") {
+		t.Fatalf("bundle after migration: %q", bundle)
+	}
+
+	if again := runStartupSelfCheck(nil, nil); len(again.Fixed) != 0 {
+		t.Fatalf("second run should find nothing to fix, got %v", again.Fixed)
+	}
+}
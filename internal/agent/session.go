@@ -18,6 +18,9 @@ const (
 type SessionSettings struct {
 	ThinkingLevel ThinkingLevel
 	Verbose       bool
+	// PromptSections overrides the configured prompt section switches, by
+	// section name, until the session is reset.
+	PromptSections map[string]bool
 }
 
 // SessionStore manages session settings
@@ -76,6 +79,41 @@ func (s *SessionStore) SetVerbose(key string, verbose bool) {
 	settings.Verbose = verbose
 }
 
+// SetPromptSection switches a prompt section on or off for a session
+func (s *SessionStore) SetPromptSection(key, section string, on bool) {
+	settings := s.Get(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings.PromptSections == nil {
+		settings.PromptSections = make(map[string]bool)
+	}
+	settings.PromptSections[section] = on
+}
+
+// PromptSection returns a session's switch for a prompt section, if set
+func (s *SessionStore) PromptSection(key, section string) (on, set bool) {
+	settings := s.Get(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	on, set = settings.PromptSections[section]
+	return on, set
+}
+
+// Copy gives session to a copy of the settings of session from
+func (s *SessionStore) Copy(from, to string) {
+	src := s.Get(from)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dst := &SessionSettings{ThinkingLevel: src.ThinkingLevel, Verbose: src.Verbose}
+	if len(src.PromptSections) > 0 {
+		dst.PromptSections = make(map[string]bool, len(src.PromptSections))
+		for section, on := range src.PromptSections {
+			dst.PromptSections[section] = on
+		}
+	}
+	s.settings[to] = dst
+}
+
 // Clear removes settings for a session
 func (s *SessionStore) Clear(key string) {
 	s.mu.Lock()
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/skills"
+)
+
+// skillTool is an executable tool declared by an installed skill.
+type skillTool struct {
+	entry skills.SkillEntry
+	spec  skills.ToolSpec
+}
+
+// applySkillTools registers the tools of every ready skill. Names that
+// clash with a built-in tool are skipped.
+func (a *Agent) applySkillTools(cfg config.SkillsConfig) {
+	builtin := map[string]bool{}
+	for _, t := range a.builtinTools() {
+		builtin[t.Name] = true
+	}
+	tools := map[string]skillTool{}
+	var offline []string // skills whose tools expect no network
+	report := skills.BuildStatusReport(cfg.Disabled, cfg.ExtraDirs)
+	for _, s := range report.EligibleSkills() {
+		if len(s.Tools) > 0 && !s.Permissions.Network {
+			offline = append(offline, s.Name)
+		}
+		for _, spec := range s.Tools {
+			name := skills.ToolName(s.Name, spec.Name)
+			if builtin[name] {
+				logger.Warn("[Agent] Skill %s: tool %s clashes with a built-in tool and is ignored", s.Name, name)
+				continue
+			}
+			tools[name] = skillTool{entry: s.SkillEntry, spec: spec}
+		}
+	}
+	a.securityMu.Lock()
+	a.skillTools = tools
+	a.skillUnisolatedNet = cfg.AllowUnisolatedNetwork
+	a.securityMu.Unlock()
+	if len(tools) > 0 {
+		logger.Info("[Agent] Registered %d skill tools", len(tools))
+	}
+	if len(offline) > 0 && !skills.CanIsolateNetwork() {
+		if cfg.AllowUnisolatedNetwork {
+			logger.Warn("[Agent] Cannot cut skill tools off the network on this system; tools of %s run WITH network access (skills.allow_unisolated_network)", strings.Join(offline, ", "))
+		} else {
+			logger.Warn("[Agent] Cannot cut skill tools off the network on this system; tools of %s will be refused (set skills.allow_unisolated_network to run them anyway)", strings.Join(offline, ", "))
+		}
+	}
+}
+
+func (a *Agent) lookupSkillTool(name string) (skillTool, bool) {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	t, ok := a.skillTools[name]
+	return t, ok
+}
+
+// skillToolList returns the skill tools for the model, sorted by name.
+func (a *Agent) skillToolList() []Tool {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	names := make([]string, 0, len(a.skillTools))
+	for name := range a.skillTools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	tools := make([]Tool, 0, len(names))
+	for _, name := range names {
+		t := a.skillTools[name]
+		desc := t.spec.Description
+		if desc == "" {
+			desc = t.entry.Description
+		}
+		tools = append(tools, Tool{
+			Name:        name,
+			Description: fmt.Sprintf("[skill %s] %s", t.entry.Name, desc),
+			InputSchema: jsonSchema(t.spec.InputSchema()),
+		})
+	}
+	return tools
+}
+
+// executeSkillTool runs a skill tool under the skill's permissions and the
+// agent's own file and command policy.
+func (a *Agent) executeSkillTool(ctx context.Context, t skillTool, args map[string]any) string {
+	snapshot := a.securitySnapshot()
+	a.securityMu.RLock()
+	opts := skills.RunOptions{BlockedCommands: snapshot.blockedCommands, AllowUnisolatedNetwork: a.skillUnisolatedNet}
+	a.securityMu.RUnlock()
+	for _, p := range t.spec.Parameters {
+		if p.Type == "path" && snapshot.disableFileTools {
+			return "ACCESS DENIED: file operations are disabled by security policy. Do NOT retry. Inform the user that file access is disabled."
+		}
+	}
+	if checker := snapshot.pathChecker; checker != nil && checker.HasRestrictions() {
+		opts.CheckPath = checker.CheckPath
+	}
+	out, err := skills.RunTool(ctx, t.entry, t.spec, args, opts)
+	if err != nil {
+		return fmt.Sprintf("Error: skill %s: %v", t.entry.Name, err)
+	}
+	if strings.TrimSpace(out) == "" {
+		return "(no output)"
+	}
+	return out
+}
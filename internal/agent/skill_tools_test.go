@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+)
+
+func TestSkillToolsAreRegisteredAndRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fixture uses echo")
+	}
+	bundled := t.TempDir()
+	dir := filepath.Join(bundled, "greeter")
+	os.MkdirAll(dir, 0o755)
+	md := "---\nname: greeter\ndescription: Greets\ntools:\n  - name: hello\n    description: Say hello\n    command: echo hello {{.who}}\n    parameters:\n      who: {type: string, required: true}\n---\n"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(md), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COCO_BUNDLED_SKILLS_DIR", bundled)
+
+	a := &Agent{}
+	a.applySkillTools(config.SkillsConfig{})
+	found := false
+	for _, tool := range a.buildToolsList() {
+		found = found || tool.Name == "skill_greeter_hello"
+	}
+	if !found {
+		t.Fatal("skill_greeter_hello not offered to the model")
+	}
+
+	input, _ := json.Marshal(map[string]any{"who": "coco"})
+	if got := a.runTool(context.Background(), "skill_greeter_hello", input); got != "hello coco" {
+		t.Fatalf("skill_greeter_hello = %q", got)
+	}
+
+	a.applySkillTools(config.SkillsConfig{Disabled: []string{"greeter"}})
+	if _, ok := a.lookupSkillTool("skill_greeter_hello"); ok {
+		t.Fatal("tools of a disabled skill stay registered")
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/secrets"
+)
+
+// StorePassphraseEnv supplies the database passphrase in place of the OS
+// keychain.
+const StorePassphraseEnv = "COCO_STORE_PASSPHRASE"
+
+// The keychain entry holding the generated database secret.
+const (
+	storeKeychainService = "coco"
+	storeKeychainAccount = "store-key"
+)
+
+// unlockStore unlocks an encrypted database, or encrypts the existing one
+// the first time storage.encrypt is set.
+func unlockStore(store *persist.Store, cfg config.StorageConfig) error {
+	encrypted, err := store.Encrypted()
+	if err != nil {
+		return err
+	}
+	if !cfg.Encrypt && !encrypted {
+		return nil
+	}
+	secret, err := storeSecret(!encrypted)
+	if err != nil {
+		return fmt.Errorf("database encryption: %w", err)
+	}
+	sealed, err := store.Unlock(secret)
+	if err != nil {
+		return err
+	}
+	if sealed > 0 {
+		logger.Info("[Agent] Encrypted %d stored rows", sealed)
+	}
+	if !cfg.Encrypt {
+		logger.Warn("[Agent] storage.encrypt is off, but the database is already encrypted and stays so")
+	}
+	return nil
+}
+
+// storeSecret returns StorePassphraseEnv, else the keychain entry. With
+// create, a missing entry is generated and saved.
+func storeSecret(create bool) (string, error) {
+	if v := strings.TrimSpace(os.Getenv(StorePassphraseEnv)); v != "" {
+		return v, nil
+	}
+	secret, err := secrets.KeychainGet(storeKeychainService, storeKeychainAccount)
+	if err == nil {
+		return secret, nil
+	}
+	if !create || !errors.Is(err, secrets.ErrKeychainNotFound) {
+		return "", fmt.Errorf("set %s or make the OS keychain available: %w", StorePassphraseEnv, err)
+	}
+	secret, err = secrets.Generate("hex", 64, false)
+	if err != nil {
+		return "", err
+	}
+	if err := secrets.KeychainSet(storeKeychainService, storeKeychainAccount, secret); err != nil {
+		return "", fmt.Errorf("set %s or make the OS keychain available: %w", StorePassphraseEnv, err)
+	}
+	logger.Info("[Agent] Database key saved to the OS keychain (%s/%s)", storeKeychainService, storeKeychainAccount)
+	return secret, nil
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+)
+
+func TestStoreEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coco.db")
+	store, err := persist.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv, err := store.GetOrCreateConversation("telegram", "c1", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddMessage(conv.ID, persist.Message{Role: "user", Content: "the surprise party is on Friday"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveDailyReport(&persist.DailyReport{Date: "2026-10-16", UserID: "u1", Content: "met the surprise committee"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddTask(persist.Task{UserID: "u1", Title: "buy the surprise cake"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveContact(persist.Contact{UserID: "u1", Name: "cake shop", Platform: "telegram", ChannelID: "c2", Note: "knows about the surprise"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveRunTrace("telegram:c1:u1", []byte(`{"prompt":"plan the surprise"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.HoldNotification(persist.HeldNotification{Platform: "telegram", ChannelID: "c1", Message: "surprise reminder"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(StorePassphraseEnv, "correct horse")
+	if err := unlockStore(store, config.StorageConfig{Encrypt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddMessage(conv.ID, persist.Message{Role: "assistant", Content: "I will keep the surprise"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var leaked int
+	raw.QueryRow(storeLeakQuery).Scan(&leaked)
+	raw.Close()
+	if leaked != 0 {
+		t.Fatalf("%d rows still in plaintext", leaked)
+	}
+
+	store, err = persist.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	t.Setenv(StorePassphraseEnv, "wrong horse")
+	if err := unlockStore(store, config.StorageConfig{}); !errors.Is(err, persist.ErrWrongKey) {
+		t.Fatalf("wrong passphrase err = %v", err)
+	}
+	t.Setenv(StorePassphraseEnv, "correct horse")
+	if err := unlockStore(store, config.StorageConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	conv, err = store.GetOrCreateConversation("telegram", "c1", "u1")
+	if err != nil || len(conv.Messages) != 2 || conv.Messages[1].Content != "I will keep the surprise" {
+		t.Fatalf("conversation = %+v, %v", conv, err)
+	}
+	found, err := store.SearchMessages("u1", "PARTY", 10)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("search = %+v, %v", found, err)
+	}
+	report, err := store.GetDailyReport("2026-10-16", "u1")
+	if err != nil || report.Content != "met the surprise committee" {
+		t.Fatalf("report = %+v, %v", report, err)
+	}
+	if tasks, err := store.Tasks("u1", time.Time{}); err != nil || len(tasks) != 1 || tasks[0].Title != "buy the surprise cake" {
+		t.Fatalf("tasks = %+v, %v", tasks, err)
+	}
+	if c, err := store.FindContact("u1", "Cake Shop"); err != nil || c == nil || c.ChannelID != "c2" || c.Note != "knows about the surprise" {
+		t.Fatalf("contact = %+v, %v", c, err)
+	}
+	if traces, err := store.RunTraces(time.Time{}); err != nil || len(traces) != 1 || !strings.Contains(traces[0].Data, "plan the surprise") {
+		t.Fatalf("traces = %+v, %v", traces, err)
+	}
+	if held, err := store.HeldNotifications("telegram", "c1"); err != nil || len(held) != 1 || held[0].Message != "surprise reminder" {
+		t.Fatalf("held = %+v, %v", held, err)
+	}
+}
+
+// storeLeakQuery counts rows with "surprise" in a sealed column.
+const storeLeakQuery = `SELECT (SELECT COUNT(*) FROM messages WHERE content LIKE '%surprise%')
+	+ (SELECT COUNT(*) FROM daily_reports WHERE content LIKE '%surprise%')
+	+ (SELECT COUNT(*) FROM tasks WHERE title LIKE '%surprise%')
+	+ (SELECT COUNT(*) FROM contacts WHERE note LIKE '%surprise%')
+	+ (SELECT COUNT(*) FROM run_traces WHERE data LIKE '%surprise%')
+	+ (SELECT COUNT(*) FROM dnd_held WHERE message LIKE '%surprise%')`
+
+// A database encrypted before tasks, contacts, traces and held
+// notifications were sealed gets them sealed on its next unlock.
+func TestStoreEncryptionSealsNewColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coco.db")
+	store, err := persist.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(StorePassphraseEnv, "correct horse")
+	if err := unlockStore(store, config.StorageConfig{Encrypt: true}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Roll the file back to an older build: store_encryption without
+	// sealed_version, and plaintext rows that build wrote.
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`ALTER TABLE store_encryption DROP COLUMN sealed_version`,
+		`PRAGMA user_version = 1`,
+		`INSERT INTO tasks (user_id, title, created_at, updated_at) VALUES ('u1', 'buy the surprise cake', '2026-10-16T00:00:00Z', '2026-10-16T00:00:00Z')`,
+		`INSERT INTO dnd_held (platform, channel_id, message, created_at) VALUES ('telegram', 'c1', 'surprise reminder', '2026-10-16T00:00:00Z')`,
+	} {
+		if _, err := raw.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	raw.Close()
+
+	store, err = persist.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.SchemaVersion(); err != nil || v != persist.SchemaVersion {
+		t.Fatalf("schema version = %d, %v", v, err)
+	}
+	sealed, err := store.Unlock("correct horse")
+	if err != nil || sealed != 2 {
+		t.Fatalf("sealed %d rows, %v; want the 2 plaintext rows", sealed, err)
+	}
+	if tasks, err := store.Tasks("u1", time.Time{}); err != nil || len(tasks) != 1 || tasks[0].Title != "buy the surprise cake" {
+		t.Fatalf("tasks = %+v, %v", tasks, err)
+	}
+	store.Close()
+
+	raw, err = sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var leaked int
+	raw.QueryRow(storeLeakQuery).Scan(&leaked)
+	if leaked != 0 {
+		t.Fatalf("%d rows still in plaintext", leaked)
+	}
+
+	store, err = persist.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if sealed, err := store.Unlock("correct horse"); err != nil || sealed != 0 {
+		t.Fatalf("second unlock sealed %d rows, %v", sealed, err)
+	}
+}
@@ -0,0 +1,418 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kayz/coco/internal/docextract"
+	"github.com/kayz/coco/internal/logger"
+)
+
+const (
+	sourceCacheTTL     = 2 * time.Hour // fetched pages and documents are kept this long for drill-down
+	sourceCacheSize    = 8             // sources kept per conversation
+	drillExcerptChars  = 12000         // source text an expansion is written from
+	drillFallbackChars = 1500          // passages shown when no model can expand
+	maxSummaryPoints   = 8
+)
+
+// fetchSourceText downloads a page for summarize; tests replace it.
+var fetchSourceText = executeWebFetch
+
+// layeredSummary is the standard shape of a summary: one line, numbered key
+// points, then details. The points are what "展开第N点" drills into.
+type layeredSummary struct {
+	OneLiner string
+	Points   []string
+	Details  string
+}
+
+const layeredSummaryPrompt = `Summarize the source for a reader who has not seen it, in the source's language, in exactly this layout:
+一句话：<one sentence>
+要点：
+1. <key point>
+2. <key point>
+细节：<a short paragraph of supporting details: numbers, names, dates>
+Use 3 to 6 key points, each one line. Output only the summary.`
+
+func (s layeredSummary) String() string {
+	var sb strings.Builder
+	sb.WriteString("一句话：" + s.OneLiner)
+	if len(s.Points) > 0 {
+		sb.WriteString("\n要点：")
+		for i, p := range s.Points {
+			fmt.Fprintf(&sb, "\n%d. %s", i+1, p)
+		}
+	}
+	if s.Details != "" {
+		sb.WriteString("\n细节：" + s.Details)
+	}
+	return sb.String()
+}
+
+var summaryPointPattern = regexp.MustCompile(`^\s*(?:\d+[.、)）]|[-*•])\s*(.+)$`)
+
+// parseLayeredSummary reads a model's summary back into its layers. Output
+// that ignores the layout still yields its bullets as points.
+func parseLayeredSummary(text string) layeredSummary {
+	var s layeredSummary
+	var details []string
+	section := ""
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(strings.Trim(line, "*# "))
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "一句话"):
+			s.OneLiner = afterLabel(line, "一句话")
+			section = "one"
+			continue
+		case strings.HasPrefix(line, "要点"):
+			section = "points"
+			continue
+		case strings.HasPrefix(line, "细节"):
+			section = "details"
+			if rest := afterLabel(line, "细节"); rest != "" {
+				details = append(details, rest)
+			}
+			continue
+		}
+		if m := summaryPointPattern.FindStringSubmatch(line); m != nil && section != "details" && len(s.Points) < maxSummaryPoints {
+			s.Points = append(s.Points, strings.TrimSpace(m[1]))
+			continue
+		}
+		switch {
+		case section == "details":
+			details = append(details, line)
+		case s.OneLiner == "":
+			s.OneLiner = line
+		default:
+			details = append(details, line)
+		}
+	}
+	s.Details = strings.Join(details, " ")
+	return s
+}
+
+// afterLabel is the text following a "一句话：" style label, without the
+// markdown emphasis models like to put around it.
+func afterLabel(line, label string) string {
+	return strings.TrimLeft(strings.TrimPrefix(line, label), ":：* ")
+}
+
+// summarizeLayered writes the layered summary of text with the model,
+// falling back to the opening sentences when no model is available.
+func (a *Agent) summarizeLayered(ctx context.Context, name, text string) layeredSummary {
+	head := text
+	if r := []rune(text); len(r) > docSummaryInput {
+		head = string(r[:docSummaryInput])
+	}
+	if a.modelRouter != nil {
+		resp, err := a.chatWithModel(ctx, ChatRequest{
+			Messages:     []Message{{Role: "user", Content: head}},
+			SystemPrompt: "The source is " + name + ". " + layeredSummaryPrompt,
+			MaxTokens:    800,
+		})
+		if err == nil {
+			if s := parseLayeredSummary(resp.Content); s.OneLiner != "" {
+				return s
+			}
+		}
+		logger.Warn("[Agent] Failed to summarize %s: %v", name, err)
+	}
+	return extractiveSummary(text)
+}
+
+// extractiveSummary takes the first sentence as the one-liner and the
+// opening sentence of the next paragraphs as points.
+func extractiveSummary(text string) layeredSummary {
+	var s layeredSummary
+	for _, para := range sourceParagraphs(text) {
+		sentence := firstSentence(para)
+		if s.OneLiner == "" {
+			s.OneLiner = sentence
+			continue
+		}
+		if len(s.Points) == 5 {
+			break
+		}
+		s.Points = append(s.Points, sentence)
+	}
+	return s
+}
+
+func firstSentence(para string) string {
+	r := []rune(para)
+	for i, c := range r {
+		if strings.ContainsRune("。！？!?", c) || (c == '.' && (i+1 == len(r) || r[i+1] == ' ')) {
+			r = r[:i+1]
+			break
+		}
+	}
+	if len(r) > 120 {
+		return string(r[:120]) + "…"
+	}
+	return string(r)
+}
+
+func sourceParagraphs(text string) []string {
+	var paras []string
+	for _, p := range strings.Split(text, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paras = append(paras, p)
+		}
+	}
+	return paras
+}
+
+// summarySource is a page or document kept for drill-down so expanding a
+// point never fetches it again.
+type summarySource struct {
+	key     string // URL or file path
+	name    string
+	text    string
+	summary *layeredSummary // nil until summarized
+	at      time.Time
+}
+
+// sourceCache keeps the recent sources of each conversation.
+type sourceCache struct {
+	mu     sync.Mutex
+	byConv map[string][]*summarySource
+}
+
+func (c *sourceCache) put(conv string, src *summarySource) {
+	if conv == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byConv == nil {
+		c.byConv = make(map[string][]*summarySource)
+	}
+	kept := []*summarySource{}
+	for _, s := range c.byConv[conv] {
+		if s.key != src.key && time.Since(s.at) < sourceCacheTTL {
+			kept = append(kept, s)
+		}
+	}
+	kept = append(kept, src)
+	if len(kept) > sourceCacheSize {
+		kept = kept[len(kept)-sourceCacheSize:]
+	}
+	c.byConv[conv] = kept
+}
+
+// find returns the cached source with key, if still fresh.
+func (c *sourceCache) find(conv, key string) *summarySource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.byConv[conv] {
+		if s.key == key && time.Since(s.at) < sourceCacheTTL {
+			return s
+		}
+	}
+	return nil
+}
+
+// lastSummarized returns the most recently summarized source.
+func (c *sourceCache) lastSummarized(conv string) *summarySource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sources := c.byConv[conv]
+	for i := len(sources) - 1; i >= 0; i-- {
+		if s := sources[i]; s.summary != nil && time.Since(s.at) < sourceCacheTTL {
+			return s
+		}
+	}
+	return nil
+}
+
+// cacheFetchedPage keeps a web_fetch result for later summaries.
+func (a *Agent) cacheFetchedPage(ctx context.Context, url, text string) {
+	if url == "" || strings.HasPrefix(text, "Error") {
+		return
+	}
+	a.sources.put(currentConversationKey(ctx), &summarySource{key: url, name: url, text: text, at: time.Now()})
+}
+
+// executeSummarize serves the summarize tool.
+func (a *Agent) executeSummarize(ctx context.Context, args map[string]any) string {
+	conv := currentConversationKey(ctx)
+	url := strings.TrimSpace(getString(args, "url"))
+	path := normalizePath(getString(args, "path"))
+	key, name := url, url
+	if key == "" {
+		key, name = path, filepath.Base(path)
+	}
+	if key == "" {
+		return "Error: url or path is required"
+	}
+
+	var text string
+	if cached := a.sources.find(conv, key); cached != nil {
+		text = cached.text
+	} else if url != "" {
+		text = fetchSourceText(ctx, url)
+		if strings.HasPrefix(text, "Error") {
+			return text
+		}
+	} else {
+		extractCtx, cancel := context.WithTimeout(ctx, docExtractTimeout)
+		doc, err := docextract.ExtractFile(extractCtx, path)
+		cancel()
+		if err != nil {
+			return fmt.Sprintf("Error reading document: %v", err)
+		}
+		text = doc.Text()
+	}
+	summary := a.summarizeLayered(ctx, name, text)
+	a.sources.put(conv, &summarySource{key: key, name: name, text: text, summary: &summary, at: time.Now()})
+	return summary.String() + drillHint(summary)
+}
+
+func drillHint(s layeredSummary) string {
+	if len(s.Points) == 0 {
+		return ""
+	}
+	return "\n\n（回复“展开第N点”查看某个要点的详情）"
+}
+
+var expandCommandPattern = regexp.MustCompile(`^(?:展开第?\s*([0-9一二三四五六七八九十]+)\s*(?:点|条)?|/expand\s+(\d+))$`)
+
+// handleExpandCommand answers "展开第2点" from the last summary's source.
+func (a *Agent) handleExpandCommand(ctx context.Context, convKey, text string) (string, bool) {
+	m := expandCommandPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", false
+	}
+	n := chineseNumber(m[1] + m[2])
+	src := a.sources.lastSummarized(convKey)
+	if src == nil {
+		return "没有可展开的摘要（先让我总结一个网页或文档）", true
+	}
+	if n < 1 || n > len(src.summary.Points) {
+		return fmt.Sprintf("摘要只有 %d 个要点", len(src.summary.Points)), true
+	}
+	return a.expandPoint(ctx, src, n), true
+}
+
+// expandPoint writes out one key point from the passages of the cached
+// source that are about it.
+func (a *Agent) expandPoint(ctx context.Context, src *summarySource, n int) string {
+	point := src.summary.Points[n-1]
+	excerpt := relevantExcerpt(src.text, point, drillExcerptChars)
+	header := fmt.Sprintf("🔍 第%d点：%s\n\n", n, point)
+	if a.modelRouter != nil {
+		resp, err := a.chatWithModel(ctx, ChatRequest{
+			Messages: []Message{{Role: "user", Content: "Key point: " + point + "\n\nSource excerpt from " + src.name + ":\n" + excerpt}},
+			SystemPrompt: "Expand on the key point using only the source excerpt, in the source's language. " +
+				"Give the supporting details, numbers and short quotes; say so if the excerpt does not cover it. Output only the expansion.",
+			MaxTokens: 1000,
+		})
+		if err == nil && strings.TrimSpace(resp.Content) != "" {
+			return header + strings.TrimSpace(resp.Content)
+		}
+		logger.Warn("[Agent] Failed to expand point %d of %s: %v", n, src.name, err)
+	}
+	if r := []rune(excerpt); len(r) > drillFallbackChars {
+		excerpt = string(r[:drillFallbackChars]) + "…"
+	}
+	return header + "原文相关段落：\n" + excerpt
+}
+
+// relevantExcerpt picks the paragraphs sharing the most character pairs
+// with point, kept in source order, up to limit characters.
+func relevantExcerpt(text, point string, limit int) string {
+	paras := sourceParagraphs(text)
+	want := runePairs(point)
+	type scored struct {
+		i, score int
+	}
+	var ranked []scored
+	for i, p := range paras {
+		score := 0
+		for pair := range runePairs(p) {
+			if want[pair] {
+				score++
+			}
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+	// Highest score first; earlier paragraphs win ties.
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	picked := make([]bool, len(paras))
+	used := 0
+	for _, r := range ranked {
+		n := len([]rune(paras[r.i]))
+		if used+n > limit && used > 0 {
+			continue
+		}
+		picked[r.i] = true
+		used += n
+	}
+	var out []string
+	for i, p := range paras {
+		if picked[i] {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		if r := []rune(text); len(r) > limit {
+			return string(r[:limit])
+		}
+		return text
+	}
+	return strings.Join(out, "\n")
+}
+
+// runePairs is the set of adjacent letter or digit pairs, lowercased; it
+// matches Chinese text without word segmentation.
+func runePairs(s string) map[string]bool {
+	pairs := map[string]bool{}
+	var prev rune
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			prev = 0
+			continue
+		}
+		if prev != 0 {
+			pairs[string([]rune{prev, r})] = true
+		}
+		prev = r
+	}
+	return pairs
+}
+
+var chineseDigits = map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+// chineseNumber reads "2", "二" or "十二".
+func chineseNumber(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	n, cur := 0, 0
+	for _, r := range s {
+		if r == '十' {
+			if cur == 0 {
+				cur = 1
+			}
+			n += cur * 10
+			cur = 0
+			continue
+		}
+		cur = chineseDigits[r]
+	}
+	return n + cur
+}
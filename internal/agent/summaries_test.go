@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLayeredSummary(t *testing.T) {
+	s := parseLayeredSummary(`**一句话：** 公司第三季度营收增长 20%。
+要点：
+1. 营收 52 亿元，同比增长 20%
+2、云业务首次盈利
+- 海外收入占比升至 35%
+细节：增长主要来自东南亚。
+管理层上调全年指引。`)
+	if s.OneLiner != "公司第三季度营收增长 20%。" {
+		t.Fatalf("one-liner = %q", s.OneLiner)
+	}
+	if len(s.Points) != 3 || s.Points[1] != "云业务首次盈利" {
+		t.Fatalf("points = %q", s.Points)
+	}
+	if s.Details != "增长主要来自东南亚。 管理层上调全年指引。" {
+		t.Fatalf("details = %q", s.Details)
+	}
+	if got := parseLayeredSummary(s.String()); strings.Join(got.Points, "|") != strings.Join(s.Points, "|") {
+		t.Fatalf("round trip = %+v", got)
+	}
+}
+
+func TestSummaryDrillDownUsesCachedSource(t *testing.T) {
+	page := strings.Join([]string{
+		"城市公交将全面改用电动车。",
+		"票价调整：起步价从 2 元调整为 2.5 元，学生卡维持半价。",
+		"线路优化：新增 12 条社区微循环线路，覆盖老旧小区。",
+		"充电设施：全市新建 300 个公交充电桩，夜间集中充电。",
+	}, "\n")
+	fetches := 0
+	old := fetchSourceText
+	fetchSourceText = func(ctx context.Context, url string) string {
+		fetches++
+		return page
+	}
+	defer func() { fetchSourceText = old }()
+
+	a := &Agent{}
+	ctx := testTurn()
+	convKey := currentConversationKey(ctx)
+	if reply, _ := a.handleExpandCommand(ctx, convKey, "展开第1点"); !strings.Contains(reply, "没有可展开的摘要") {
+		t.Fatalf("expand before a summary: %q", reply)
+	}
+
+	// A page already read with web_fetch is summarized without fetching it again.
+	a.cacheFetchedPage(ctx, "https://example.com/bus", page)
+	summary := a.executeSummarize(ctx, map[string]any{"url": "https://example.com/bus"})
+	if fetches != 0 {
+		t.Fatalf("summarize fetched a cached page %d times", fetches)
+	}
+	if !strings.HasPrefix(summary, "一句话：城市公交将全面改用电动车。\n要点：\n1. 票价调整") || !strings.Contains(summary, "展开第N点") {
+		t.Fatalf("summary = %q", summary)
+	}
+
+	reply, ok := a.handleExpandCommand(ctx, convKey, "展开第二点")
+	if !ok || !strings.Contains(reply, "第2点：线路优化") || !strings.Contains(reply, "新增 12 条社区微循环线路") || strings.Contains(reply, "起步价") {
+		t.Fatalf("expand = %q", reply)
+	}
+	if reply, _ := a.handleExpandCommand(ctx, convKey, "/expand 9"); reply != "摘要只有 3 个要点" {
+		t.Fatalf("out of range = %q", reply)
+	}
+	if fetches != 0 {
+		t.Fatalf("drill-down fetched the page %d times", fetches)
+	}
+
+	// Another conversation has nothing to expand.
+	if reply, _ := a.handleExpandCommand(ctx, "wecom:c9:u9", "展开第1点"); !strings.Contains(reply, "没有可展开的摘要") {
+		t.Fatalf("other conversation: %q", reply)
+	}
+	if _, ok := a.handleExpandCommand(ctx, convKey, "展开讲讲这个方案"); ok {
+		t.Fatal("ordinary message taken as a drill-down command")
+	}
+}
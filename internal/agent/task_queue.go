@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/taskqueue"
+)
+
+// applyQueue installs the queue section. The queue itself is kept across
+// reloads so waiting requests keep their place. A conversation's turns run
+// one at a time, so a second one would only hold a slot while it waits.
+func (a *Agent) applyQueue(cfg config.QueueConfig) {
+	limits := taskqueue.Limits{MaxConcurrent: cfg.MaxConcurrent, PerConversation: 1}
+	retry := taskqueue.Retry{Attempts: cfg.RetryAttempts}
+	if raw := strings.TrimSpace(cfg.RetryBackoff); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			logger.Warn("[Agent] Invalid queue.retry_backoff %q, using the default", cfg.RetryBackoff)
+		} else {
+			retry.Backoff = d
+		}
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	if a.tasks == nil {
+		a.tasks = taskqueue.New(limits)
+	} else {
+		a.tasks.SetLimits(limits)
+	}
+	a.taskRetry = retry
+}
+
+func (a *Agent) taskQueue() (*taskqueue.Queue, taskqueue.Retry) {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.tasks, a.taskRetry
+}
+
+// queuedMessage handles msg once the queue has a slot for its conversation.
+func (a *Agent) queuedMessage(ctx context.Context, msg router.Message, priority taskqueue.Priority) (router.Response, error) {
+	q, _ := a.taskQueue()
+	// "/cancel" must reach the turn it aborts, which holds the slot.
+	if q == nil || isCancelCommand(msg.Text) {
+		return a.handleMessage(ctx, msg)
+	}
+	release, err := q.Acquire(ctx, ConversationKey(msg.Platform, msg.ChannelID, msg.UserID), priority)
+	if err != nil {
+		return router.Response{}, fmt.Errorf("gave up waiting in the task queue: %w", err)
+	}
+	defer release()
+	return a.handleMessage(ctx, msg)
+}
+
+// scheduledPrompt runs a cron or heartbeat prompt behind live chats and
+// retries it with backoff when it fails.
+func (a *Agent) scheduledPrompt(ctx context.Context, msg router.Message) (router.Response, error) {
+	q, retry := a.taskQueue()
+	if q == nil {
+		return a.handleMessage(ctx, msg)
+	}
+	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	var resp router.Response
+	attempt := 0
+	err := q.DoRetry(ctx, convKey, taskqueue.Scheduled, retry, func(ctx context.Context) error {
+		attempt++
+		var err error
+		resp, err = a.handleMessage(ctx, msg)
+		if err != nil {
+			logger.Warn("[Agent] Scheduled prompt in %s failed (attempt %d): %v", convKey, attempt, err)
+		}
+		return err
+	})
+	return resp, err
+}
+
+// scheduledTool runs a cron tool job behind live chats. Tool jobs belong
+// to no conversation, so each tool gets its own lane.
+func (a *Agent) scheduledTool(ctx context.Context, toolName string, fn func(context.Context) any) (any, error) {
+	q, _ := a.taskQueue()
+	if q == nil {
+		return fn(ctx), nil
+	}
+	var result any
+	err := q.Do(ctx, "cron-tool:"+toolName, taskqueue.Scheduled, func(ctx context.Context) error {
+		result = fn(ctx)
+		return nil
+	})
+	return result, err
+}
+
+func (a *Agent) formatQueueStatus() string {
+	q, _ := a.taskQueue()
+	if q == nil {
+		return ""
+	}
+	s := q.Stats()
+	return fmt.Sprintf("- 任务队列: 运行 %d（定时任务 %d），等待 %d（定时任务 %d）",
+		s.Running, s.RunningScheduled, s.Waiting, s.WaitingScheduled)
+}
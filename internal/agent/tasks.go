@@ -0,0 +1,421 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+const (
+	taskReminderTag = "task-reminder"
+	// taskReminderHour is when a task due on a day, without a time, is
+	// reminded of.
+	taskReminderHour = 9
+	// taskReportPrefix marks the daily report items that are stored tasks.
+	taskReportPrefix = "task-"
+	taskDoneWindow   = 7 * 24 * time.Hour // how far back task_list shows finished tasks
+)
+
+var taskStatusNames = map[string]string{
+	persist.TaskPending:    "待办",
+	persist.TaskInProgress: "进行中",
+	persist.TaskCompleted:  "已完成",
+}
+
+var taskPriorityNames = map[string]string{
+	"high":   "高",
+	"medium": "中",
+	"low":    "低",
+}
+
+// parseTaskDue normalizes a due date to "2006-01-02" or "2006-01-02 15:04"
+// and returns when to remind of it.
+func parseTaskDue(s string) (string, time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", time.Time{}, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return s, day.Add(taskReminderHour * time.Hour), nil
+	}
+	for _, layout := range remindAtLayouts {
+		if at, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return at.Format("2006-01-02 15:04"), at, nil
+		}
+	}
+	return "", time.Time{}, fmt.Errorf("due_date %q is not YYYY-MM-DD or YYYY-MM-DD HH:MM", s)
+}
+
+// taskOverdue reports whether an open task is past its due date. A task
+// due on a day is overdue from the next day.
+func taskOverdue(t persist.Task, now time.Time) bool {
+	if t.Status == persist.TaskCompleted || t.DueDate == "" {
+		return false
+	}
+	if len(t.DueDate) == len("2006-01-02") {
+		return t.DueDate < now.Format("2006-01-02")
+	}
+	due, err := time.ParseInLocation("2006-01-02 15:04", t.DueDate, time.Local)
+	return err == nil && due.Before(now)
+}
+
+func formatTask(t persist.Task, now time.Time) string {
+	priority := taskPriorityNames[t.Priority]
+	if priority == "" {
+		priority = taskPriorityNames["medium"]
+	}
+	line := fmt.Sprintf("#%d [%s·%s] %s", t.ID, taskStatusNames[t.Status], priority, t.Title)
+	var details []string
+	if t.DueDate != "" {
+		details = append(details, "截止 "+t.DueDate)
+	}
+	if t.Project != "" {
+		details = append(details, "项目 "+t.Project)
+	}
+	if len(details) > 0 {
+		line += "（" + strings.Join(details, "，") + "）"
+	}
+	if taskOverdue(t, now) {
+		line += " ⚠️ 已逾期"
+	}
+	return line
+}
+
+// ownTask loads task id when it belongs to userID.
+func (a *Agent) ownTask(id int64, userID string) (*persist.Task, string) {
+	t, err := a.persistStore.GetTask(id)
+	if err != nil {
+		return nil, fmt.Sprintf("Error loading task: %v", err)
+	}
+	if t == nil || (t.UserID != userID && t.UserID != "default") {
+		return nil, fmt.Sprintf("没有编号为 #%d 的任务", id)
+	}
+	return t, ""
+}
+
+// scheduleTaskReminder replaces the task's due reminder with one for its
+// current due date, and returns a note on the reminder for the reply.
+// Finished tasks, tasks without a due date and tasks with no chat to remind
+// in get none.
+func (a *Agent) scheduleTaskReminder(t *persist.Task) string {
+	if a.cronScheduler != nil && t.ReminderJob != "" {
+		_ = a.cronScheduler.RemoveJob(t.ReminderJob)
+	}
+	t.ReminderJob = ""
+	if t.Status == persist.TaskCompleted || t.DueDate == "" || t.Platform == "" {
+		return ""
+	}
+	_, at, err := parseTaskDue(t.DueDate)
+	if err != nil || !at.After(time.Now()) {
+		return ""
+	}
+	if a.cronScheduler == nil {
+		return "（定时器未运行，不会提醒）"
+	}
+	job, err := a.cronScheduler.AddOnceJob("任务到期: "+t.Title, taskReminderTag, at,
+		fmt.Sprintf("⏰ 任务到期：#%d %s", t.ID, t.Title), "", t.Platform, t.ChannelID, t.UserID)
+	if err != nil {
+		logger.Warn("[Agent] Failed to schedule the reminder for task #%d: %v", t.ID, err)
+		return ""
+	}
+	t.ReminderJob = job.ID
+	return fmt.Sprintf("，%s 提醒", at.Format("01-02 15:04"))
+}
+
+// removeTask deletes a task and its due reminder.
+func (a *Agent) removeTask(id int64) error {
+	if a.persistStore == nil {
+		return fmt.Errorf("persist store not available")
+	}
+	t, err := a.persistStore.GetTask(id)
+	if err != nil || t == nil {
+		return err
+	}
+	if a.cronScheduler != nil && t.ReminderJob != "" {
+		_ = a.cronScheduler.RemoveJob(t.ReminderJob)
+	}
+	return a.persistStore.DeleteTask(id)
+}
+
+// setTaskStatus moves t to status, stamping or clearing its completion.
+func setTaskStatus(t *persist.Task, status string) {
+	if status == persist.TaskCompleted && t.Status != persist.TaskCompleted {
+		t.CompletedAt = time.Now()
+	}
+	if status != persist.TaskCompleted {
+		t.CompletedAt = time.Time{}
+	}
+	t.Status = status
+}
+
+func (a *Agent) executeTaskAdd(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	title := strings.TrimSpace(getString(args, "title"))
+	if title == "" {
+		return "Error: title is required"
+	}
+	priority := strings.ToLower(strings.TrimSpace(getString(args, "priority")))
+	if priority == "" {
+		priority = "medium"
+	}
+	if _, ok := taskPriorityNames[priority]; !ok {
+		return "Error: priority must be low, medium or high"
+	}
+	due, _, err := parseTaskDue(getString(args, "due_date"))
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+
+	msg := turnMessage(ctx)
+	t := persist.Task{
+		UserID:      timeUserID(msg),
+		Platform:    msg.Platform,
+		ChannelID:   msg.ChannelID,
+		Title:       title,
+		Description: strings.TrimSpace(getString(args, "description")),
+		Status:      persist.TaskPending,
+		Priority:    priority,
+		Project:     strings.TrimSpace(getString(args, "project")),
+		DueDate:     due,
+	}
+	id, err := a.persistStore.AddTask(t)
+	if err != nil {
+		return fmt.Sprintf("Error saving task: %v", err)
+	}
+	t.ID = id
+	note := a.scheduleTaskReminder(&t)
+	if t.ReminderJob != "" {
+		if err := a.persistStore.UpdateTask(t); err != nil {
+			logger.Warn("[Agent] Failed to save the reminder of task #%d: %v", id, err)
+		}
+	}
+	return "✅ 已添加任务 " + formatTask(t, time.Now()) + note
+}
+
+func (a *Agent) executeTaskUpdate(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	t, denial := a.ownTask(int64(getFloat(args, "id")), timeUserID(turnMessage(ctx)))
+	if t == nil {
+		return denial
+	}
+	reschedule := false
+	if v := strings.TrimSpace(getString(args, "title")); v != "" {
+		t.Title = v
+		reschedule = true
+	}
+	if v, ok := args["description"].(string); ok {
+		t.Description = strings.TrimSpace(v)
+	}
+	if v, ok := args["project"].(string); ok {
+		t.Project = strings.TrimSpace(v)
+	}
+	if v := strings.ToLower(strings.TrimSpace(getString(args, "priority"))); v != "" {
+		if _, ok := taskPriorityNames[v]; !ok {
+			return "Error: priority must be low, medium or high"
+		}
+		t.Priority = v
+	}
+	if v := strings.ToLower(strings.TrimSpace(getString(args, "status"))); v != "" {
+		if _, ok := taskStatusNames[v]; !ok {
+			return "Error: status must be pending, in_progress or completed"
+		}
+		setTaskStatus(t, v)
+		reschedule = true
+	}
+	if v, ok := args["due_date"].(string); ok {
+		due := ""
+		if v = strings.TrimSpace(v); v != "none" && v != "无" {
+			var err error
+			if due, _, err = parseTaskDue(v); err != nil {
+				return "Error: " + err.Error()
+			}
+		}
+		t.DueDate = due
+		reschedule = true
+	}
+	note := ""
+	if reschedule {
+		note = a.scheduleTaskReminder(t)
+	}
+	if err := a.persistStore.UpdateTask(*t); err != nil {
+		return fmt.Sprintf("Error saving task: %v", err)
+	}
+	return "已更新任务 " + formatTask(*t, time.Now()) + note
+}
+
+func (a *Agent) executeTaskComplete(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	t, denial := a.ownTask(int64(getFloat(args, "id")), timeUserID(turnMessage(ctx)))
+	if t == nil {
+		return denial
+	}
+	if t.Status == persist.TaskCompleted {
+		return fmt.Sprintf("任务 #%d 已经完成了", t.ID)
+	}
+	setTaskStatus(t, persist.TaskCompleted)
+	a.scheduleTaskReminder(t)
+	if err := a.persistStore.UpdateTask(*t); err != nil {
+		return fmt.Sprintf("Error saving task: %v", err)
+	}
+	return fmt.Sprintf("🎉 已完成任务 #%d %s", t.ID, t.Title)
+}
+
+func (a *Agent) executeTaskList(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	show := strings.ToLower(strings.TrimSpace(getString(args, "status")))
+	var doneSince time.Time
+	switch show {
+	case "", "open":
+	case "all", persist.TaskCompleted:
+		doneSince = time.Now().Add(-taskDoneWindow)
+	case persist.TaskPending, persist.TaskInProgress:
+	default:
+		return "Error: status must be open, all, pending, in_progress or completed"
+	}
+	tasks, err := a.persistStore.Tasks(timeUserID(turnMessage(ctx)), doneSince)
+	if err != nil {
+		return fmt.Sprintf("Error loading tasks: %v", err)
+	}
+	project := strings.TrimSpace(getString(args, "project"))
+	now := time.Now()
+	var lines []string
+	for _, t := range tasks {
+		if project != "" && !strings.EqualFold(t.Project, project) {
+			continue
+		}
+		if show != "" && show != "open" && show != "all" && t.Status != show {
+			continue
+		}
+		lines = append(lines, formatTask(t, now))
+	}
+	if len(lines) == 0 {
+		return "没有符合条件的任务"
+	}
+	return "📋 任务:\n" + strings.Join(lines, "\n")
+}
+
+// formatTaskStatus is the /status line on the user's open tasks.
+func (a *Agent) formatTaskStatus(userID string) string {
+	if a.persistStore == nil {
+		return ""
+	}
+	tasks, err := a.persistStore.Tasks(userID, time.Time{})
+	if err != nil || len(tasks) == 0 {
+		return ""
+	}
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	dueToday, overdue := 0, 0
+	for _, t := range tasks {
+		switch {
+		case taskOverdue(t, now):
+			overdue++
+		case strings.HasPrefix(t.DueDate, today):
+			dueToday++
+		}
+	}
+	line := fmt.Sprintf("- 待办任务: %d 项", len(tasks))
+	if dueToday > 0 || overdue > 0 {
+		line += fmt.Sprintf("（今天到期 %d，已逾期 %d）", dueToday, overdue)
+	}
+	return line
+}
+
+// taskItem is t as a daily report item.
+func taskItem(t persist.Task) persist.TaskItem {
+	return persist.TaskItem{
+		ID:          taskReportPrefix + strconv.FormatInt(t.ID, 10),
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Priority:    t.Priority,
+		DueDate:     t.DueDate,
+	}
+}
+
+// syncTasksFromReport carries a daily report's tasks into the task store:
+// items of stored tasks update their status and priority, and open items
+// the store does not know yet become tasks of the default user. New tasks
+// get their ID written back into the report.
+func (a *Agent) syncTasksFromReport(report *persist.DailyReport) {
+	if a.persistStore == nil || report == nil {
+		return
+	}
+	open, err := a.persistStore.Tasks("", time.Time{})
+	if err != nil {
+		logger.Warn("[Agent] Failed to load tasks for the daily report: %v", err)
+		return
+	}
+	known := make(map[string]bool, len(open))
+	for _, t := range open {
+		known[strings.ToLower(t.Title)] = true
+	}
+
+	for i, item := range report.Tasks {
+		status := strings.ToLower(strings.TrimSpace(item.Status))
+		if _, ok := taskStatusNames[status]; !ok {
+			status = ""
+		}
+		priority := strings.ToLower(strings.TrimSpace(item.Priority))
+		if _, ok := taskPriorityNames[priority]; !ok {
+			priority = ""
+		}
+
+		if id, err := strconv.ParseInt(strings.TrimPrefix(item.ID, taskReportPrefix), 10, 64); err == nil && strings.HasPrefix(item.ID, taskReportPrefix) {
+			t, err := a.persistStore.GetTask(id)
+			if err != nil || t == nil {
+				continue
+			}
+			changed := false
+			if status != "" && status != t.Status {
+				setTaskStatus(t, status)
+				a.scheduleTaskReminder(t)
+				changed = true
+			}
+			if priority != "" && priority != t.Priority {
+				t.Priority = priority
+				changed = true
+			}
+			if changed {
+				if err := a.persistStore.UpdateTask(*t); err != nil {
+					logger.Warn("[Agent] Failed to update task #%d from the daily report: %v", id, err)
+				}
+			}
+			continue
+		}
+
+		title := strings.TrimSpace(item.Title)
+		if title == "" || status == persist.TaskCompleted || known[strings.ToLower(title)] {
+			continue
+		}
+		if status == "" {
+			status = persist.TaskPending
+		}
+		if priority == "" {
+			priority = "medium"
+		}
+		due, _, _ := parseTaskDue(item.DueDate)
+		t := persist.Task{UserID: "default", Title: title, Description: item.Description, Status: status, Priority: priority, DueDate: due}
+		id, err := a.persistStore.AddTask(t)
+		if err != nil {
+			logger.Warn("[Agent] Failed to add task %q from the daily report: %v", title, err)
+			continue
+		}
+		t.ID = id
+		known[strings.ToLower(title)] = true
+		report.Tasks[i] = taskItem(t)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func newTaskTestAgent(t *testing.T) *Agent {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+	store, err := persist.NewStore(filepath.Join(tmp, "coco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	cronStore, err := cronpkg.NewStore(filepath.Join(tmp, "cron.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cronStore.Close() })
+	return &Agent{persistStore: store, cronScheduler: cronpkg.NewScheduler(cronStore, nil, nil, nil)}
+}
+
+func TestTaskDueDateKeepsOneReminder(t *testing.T) {
+	a := newTaskTestAgent(t)
+	ctx := withTurn(context.Background(), router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1"})
+	due := time.Now().AddDate(0, 0, 2).Format("2006-01-02") + " 15:00"
+
+	got := a.executeTaskAdd(ctx, map[string]any{"title": "交报销", "due_date": due, "priority": "high", "project": "出差"})
+	if !strings.Contains(got, "已添加任务 #1 [待办·高] 交报销（截止 "+due+"，项目 出差）") || !strings.Contains(got, "15:00 提醒") {
+		t.Fatalf("task_add = %q", got)
+	}
+	reminders := func() []*cronpkg.Job { return a.cronScheduler.ListJobsByTag(taskReminderTag) }
+	if jobs := reminders(); len(jobs) != 1 || jobs[0].Platform != "slack" || !strings.Contains(jobs[0].Message, "#1 交报销") {
+		t.Fatalf("reminders = %+v", jobs)
+	}
+
+	later := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+	a.executeTaskUpdate(ctx, map[string]any{"id": float64(1), "due_date": later, "status": "in_progress"})
+	if jobs := reminders(); len(jobs) != 1 || jobs[0].RunAt.Format("2006-01-02 15:04") != later+" 09:00" {
+		t.Fatalf("rescheduled reminders = %+v", jobs)
+	}
+	if got := a.formatTaskStatus("u1"); got != "- 待办任务: 1 项" {
+		t.Fatalf("status = %q", got)
+	}
+
+	other := withTurn(context.Background(), router.Message{Platform: "slack", ChannelID: "dm", UserID: "u2"})
+	if got := a.executeTaskComplete(other, map[string]any{"id": float64(1)}); !strings.Contains(got, "没有编号为 #1") {
+		t.Fatalf("another user completed the task: %q", got)
+	}
+	if got := a.executeTaskComplete(ctx, map[string]any{"id": float64(1)}); !strings.Contains(got, "已完成任务 #1") {
+		t.Fatalf("task_complete = %q", got)
+	}
+	if jobs := reminders(); len(jobs) != 0 {
+		t.Fatalf("a finished task kept its reminder: %+v", jobs)
+	}
+	if got := a.executeTaskList(ctx, map[string]any{}); got != "没有符合条件的任务" {
+		t.Fatalf("open tasks = %q", got)
+	}
+	if got := a.executeTaskList(ctx, map[string]any{"status": "completed"}); !strings.Contains(got, "#1 [已完成·高] 交报销") {
+		t.Fatalf("finished tasks = %q", got)
+	}
+}
+
+func TestDailyReportTasksSyncBothWays(t *testing.T) {
+	a := newTaskTestAgent(t)
+	origCal, origTasks := briefingCalendar, briefingTasks
+	briefingCalendar = func(ctx context.Context) string { return "" }
+	briefingTasks = func(ctx context.Context) string { return "" }
+	defer func() { briefingCalendar, briefingTasks = origCal, origTasks }()
+	ctx := withTurn(context.Background(), router.Message{Platform: "slack", ChannelID: "dm", UserID: "u1"})
+	a.executeTaskAdd(ctx, map[string]any{"title": "写周报"})
+
+	in, err := a.collectDailyReport(context.Background(), persist.GetTodayDate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(in.render(), "## 任务清单\n- task-1 [pending/medium] 写周报") {
+		t.Fatalf("render lacks the task list:\n%s", in.render())
+	}
+
+	report := &persist.DailyReport{Date: persist.GetTodayDate(), Tasks: []persist.TaskItem{
+		{ID: "task-1", Title: "写周报", Status: "completed"},
+		{ID: "1", Title: "订酒店", Status: "pending", Priority: "high", DueDate: "2030-01-02"},
+		{Title: "已经做完的事", Status: "completed"},
+	}}
+	a.syncTasksFromReport(report)
+	if task, _ := a.persistStore.GetTask(1); task.Status != persist.TaskCompleted || task.CompletedAt.IsZero() {
+		t.Fatalf("task 1 = %+v", task)
+	}
+	if report.Tasks[1].ID != "task-2" {
+		t.Fatalf("imported item = %+v", report.Tasks[1])
+	}
+	a.syncTasksFromReport(&persist.DailyReport{Tasks: []persist.TaskItem{{Title: "订酒店"}}})
+	tasks, _ := a.persistStore.Tasks("u1", time.Time{})
+	if len(tasks) != 1 || tasks[0].Title != "订酒店" || tasks[0].UserID != "default" || tasks[0].Priority != "high" || tasks[0].DueDate != "2030-01-02" {
+		t.Fatalf("open tasks = %+v", tasks)
+	}
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+const defaultTimeCategory = "未分类"
+
+// timeUserID is whose timers the current message starts and reports on.
+func timeUserID(msg router.Message) string {
+	if msg.UserID != "" {
+		return msg.UserID
+	}
+	return "default"
+}
+
+// startTimer starts a timer for task, stopping the user's running one first.
+func (a *Agent) startTimer(userID, task, category string, now time.Time) (string, error) {
+	if category = strings.TrimSpace(category); category == "" {
+		category = defaultTimeCategory
+	}
+	var prev string
+	if running, err := a.persistStore.RunningTimeEntry(userID); err != nil {
+		return "", err
+	} else if running != nil {
+		if err := a.persistStore.StopTimeEntry(running.ID, now); err != nil {
+			return "", err
+		}
+		prev = fmt.Sprintf("\n已停止上一个计时：%s（%s），用时 %s", running.Task, running.Category, formatTimeSpent(now.Sub(running.StartedAt)))
+	}
+	if _, err := a.persistStore.StartTimeEntry(persist.TimeEntry{
+		UserID:    userID,
+		Category:  category,
+		Task:      task,
+		StartedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⏱ 开始计时：%s（%s）%s", task, category, prev), nil
+}
+
+func (a *Agent) stopTimer(userID string, now time.Time) (string, error) {
+	running, err := a.persistStore.RunningTimeEntry(userID)
+	if err != nil {
+		return "", err
+	}
+	if running == nil {
+		return "当前没有进行中的计时", nil
+	}
+	if err := a.persistStore.StopTimeEntry(running.ID, now); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⏹ 已停止：%s（%s），用时 %s", running.Task, running.Category, formatTimeSpent(now.Sub(running.StartedAt))), nil
+}
+
+func (a *Agent) executeTimerStart(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	task := strings.TrimSpace(getString(args, "task"))
+	if task == "" {
+		return "Error: task is required"
+	}
+	reply, err := a.startTimer(timeUserID(turnMessage(ctx)), task, getString(args, "category"), time.Now())
+	if err != nil {
+		return fmt.Sprintf("Error starting timer: %v", err)
+	}
+	return reply
+}
+
+func (a *Agent) executeTimerStop(ctx context.Context) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	reply, err := a.stopTimer(timeUserID(turnMessage(ctx)), time.Now())
+	if err != nil {
+		return fmt.Sprintf("Error stopping timer: %v", err)
+	}
+	return reply
+}
+
+func (a *Agent) executeTimerReport(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	now := time.Now()
+	since, until, label, ok := timePeriod(getString(args, "period"), now)
+	if !ok {
+		return "Error: period must be today, week, last_week or month"
+	}
+	entries, err := a.persistStore.TimeEntries(timeUserID(turnMessage(ctx)), since, until)
+	if err != nil {
+		return fmt.Sprintf("Error loading time entries: %v", err)
+	}
+	query := strings.TrimSpace(getString(args, "query"))
+	report := formatTimeReport(entries, since, until, now, label, query)
+	if report == "" {
+		if query != "" {
+			return fmt.Sprintf("%s没有与「%s」相关的计时记录", label, query)
+		}
+		return label + "没有计时记录"
+	}
+	return report
+}
+
+// timePeriod resolves a report period to [since, until) in local time.
+// Weeks start on Monday.
+func timePeriod(period string, now time.Time) (since, until time.Time, label string, ok bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "today", "今天":
+		return today, today.AddDate(0, 0, 1), "今天", true
+	case "", "week", "this_week", "本周", "这周":
+		return monday, monday.AddDate(0, 0, 7), "本周", true
+	case "last_week", "上周":
+		return monday.AddDate(0, 0, -7), monday, "上周", true
+	case "month", "本月", "这个月":
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		return first, first.AddDate(0, 1, 0), "本月", true
+	}
+	return time.Time{}, time.Time{}, "", false
+}
+
+type timeTotal struct {
+	name  string
+	spent time.Duration
+}
+
+// formatTimeReport totals entries by category and task, counting only the
+// part of each entry inside [since, until) and running timers up to now.
+// query keeps entries whose task or category contains it. Returns "" when
+// nothing is left.
+func formatTimeReport(entries []persist.TimeEntry, since, until, now time.Time, label, query string) string {
+	byCategory := map[string]time.Duration{}
+	byTask := map[string]time.Duration{}
+	var total time.Duration
+	var running *persist.TimeEntry
+	for i, e := range entries {
+		if query != "" && !strings.Contains(strings.ToLower(e.Task+" "+e.Category), strings.ToLower(query)) {
+			continue
+		}
+		start, end := e.StartedAt, e.EndedAt
+		if e.Running() {
+			end = now
+			running = &entries[i]
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(until) {
+			end = until
+		}
+		if !end.After(start) {
+			continue
+		}
+		spent := end.Sub(start)
+		byCategory[e.Category] += spent
+		byTask[e.Category+" / "+e.Task] += spent
+		total += spent
+	}
+	if total == 0 && running == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	title := label
+	if query != "" {
+		title += "「" + query + "」"
+	}
+	fmt.Fprintf(&sb, "⏱ %s用时 %s (%s ~ %s)\n", title, formatTimeSpent(total), since.Format("01-02"), until.AddDate(0, 0, -1).Format("01-02"))
+	sb.WriteString("  按分类:\n")
+	for _, t := range sortedTimeTotals(byCategory) {
+		fmt.Fprintf(&sb, "  - %s: %s\n", t.name, formatTimeSpent(t.spent))
+	}
+	if len(byTask) > 1 {
+		sb.WriteString("  按任务:\n")
+		for i, t := range sortedTimeTotals(byTask) {
+			if i == 10 {
+				fmt.Fprintf(&sb, "  - …其余 %d 项\n", len(byTask)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "  - %s: %s\n", t.name, formatTimeSpent(t.spent))
+		}
+	}
+	if running != nil {
+		fmt.Fprintf(&sb, "  进行中: %s（已 %s）\n", running.Task, formatTimeSpent(now.Sub(running.StartedAt)))
+	}
+	return sb.String()
+}
+
+func sortedTimeTotals(m map[string]time.Duration) []timeTotal {
+	out := make([]timeTotal, 0, len(m))
+	for name, spent := range m {
+		out = append(out, timeTotal{name, spent})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].spent != out[j].spent {
+			return out[i].spent > out[j].spent
+		}
+		return out[i].name < out[j].name
+	})
+	return out
+}
+
+// formatTimeSpent renders a duration as "1 小时 12 分".
+func formatTimeSpent(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	switch {
+	case d < time.Minute:
+		return "不到 1 分钟"
+	case minutes < 60:
+		return fmt.Sprintf("%d 分钟", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d 小时", minutes/60)
+	}
+	return fmt.Sprintf("%d 小时 %d 分", minutes/60, minutes%60)
+}
+
+// formatWeeklyTime renders this week's time summary for reports, or "" when
+// nothing was tracked.
+func (a *Agent) formatWeeklyTime() string {
+	if a.persistStore == nil {
+		return ""
+	}
+	now := time.Now()
+	since, until, label, _ := timePeriod("week", now)
+	entries, err := a.persistStore.TimeEntries("", since, until)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load time entries: %v", err)
+		return ""
+	}
+	return formatTimeReport(entries, since, until, now, label, "")
+}
+
+// handleTimerCommand starts and stops timers without a model round trip:
+// "开始计时：写周报 #工作" and "停止计时".
+func (a *Agent) handleTimerCommand(msg router.Message, text string) (string, bool) {
+	if a.persistStore == nil {
+		return "", false
+	}
+	userID := timeUserID(msg)
+	switch text {
+	case "停止计时", "结束计时", "/timer stop":
+		reply, err := a.stopTimer(userID, time.Now())
+		if err != nil {
+			return fmt.Sprintf("停止计时失败: %v", err), true
+		}
+		return reply, true
+	case "/timer", "计时统计":
+		since, until, label, _ := timePeriod("week", time.Now())
+		entries, err := a.persistStore.TimeEntries(userID, since, until)
+		if err != nil {
+			return fmt.Sprintf("读取计时记录失败: %v", err), true
+		}
+		if report := formatTimeReport(entries, since, until, time.Now(), label, ""); report != "" {
+			return strings.TrimSpace(report), true
+		}
+		return "本周没有计时记录", true
+	}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(text, "开始计时：") || strings.HasPrefix(text, "开始计时:") || strings.HasPrefix(text, "开始计时 "):
+		rest = strings.TrimLeft(strings.TrimPrefix(text, "开始计时"), ":： ")
+	case strings.HasPrefix(strings.ToLower(text), "/timer start "):
+		rest = text[len("/timer start "):]
+	default:
+		return "", false
+	}
+	task, category := parseTimerTask(rest)
+	if task == "" {
+		return "用法: 开始计时：任务 #分类（分类可省略）", true
+	}
+	reply, err := a.startTimer(userID, task, category, time.Now())
+	if err != nil {
+		return fmt.Sprintf("开始计时失败: %v", err), true
+	}
+	return reply, true
+}
+
+// parseTimerTask splits "写周报 #工作" into task and category.
+func parseTimerTask(s string) (task, category string) {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "#"); i >= 0 {
+		category = strings.TrimSpace(s[i+1:])
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, category
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestTimerStartStopAndReport(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	a := &Agent{persistStore: store}
+
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.Local)
+	if _, err := a.startTimer("u1", "写周报", "写作", monday); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := a.startTimer("u1", "修 bug", "写代码", monday.Add(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "已停止上一个计时：写周报（写作），用时 30 分钟") {
+		t.Fatalf("previous timer not stopped: %q", reply)
+	}
+	if reply, _ := a.stopTimer("u1", monday.Add(30*time.Minute+72*time.Minute)); !strings.Contains(reply, "1 小时 12 分") {
+		t.Fatalf("stop reply: %q", reply)
+	}
+	if reply, _ := a.stopTimer("u1", monday.Add(2*time.Hour)); reply != "当前没有进行中的计时" {
+		t.Fatalf("second stop: %q", reply)
+	}
+	// Someone else's timer stays out of u1's report.
+	a.startTimer("u2", "开会", "会议", monday)
+	a.stopTimer("u2", monday.Add(time.Hour))
+
+	now := monday.AddDate(0, 0, 3)
+	since, until, label, _ := timePeriod("week", now)
+	if !since.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("week starts %v", since)
+	}
+	entries, err := store.TimeEntries("u1", since, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := formatTimeReport(entries, since, until, now, label, "")
+	for _, want := range []string{"本周用时 1 小时 42 分", "写代码: 1 小时 12 分", "写作: 30 分钟"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "会议") {
+		t.Fatalf("report includes another user's time:\n%s", report)
+	}
+	if got := formatTimeReport(entries, since, until, now, label, "代码"); !strings.Contains(got, "本周「代码」用时 1 小时 12 分") {
+		t.Fatalf("query report:\n%s", got)
+	}
+	if got := formatTimeReport(entries, until, until.AddDate(0, 0, 7), now, "下周", ""); got != "" {
+		t.Fatalf("entries leaked into another week:\n%s", got)
+	}
+}
+
+func TestTimerReportClipsRunningTimer(t *testing.T) {
+	since := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	until := since.AddDate(0, 0, 7)
+	entries := []persist.TimeEntry{{Category: "写代码", Task: "重构", StartedAt: since.Add(-time.Hour)}}
+	report := formatTimeReport(entries, since, until, since.Add(2*time.Hour), "本周", "")
+	if !strings.Contains(report, "本周用时 2 小时") || !strings.Contains(report, "进行中: 重构（已 3 小时）") {
+		t.Fatalf("report:\n%s", report)
+	}
+}
+
+func TestTimerCommand(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+	a := &Agent{persistStore: store}
+	msg := router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1"}
+
+	reply, ok := a.handleTimerCommand(msg, "开始计时：写周报 #写作")
+	if !ok || reply != "⏱ 开始计时：写周报（写作）" {
+		t.Fatalf("start: %v %q", ok, reply)
+	}
+	if running, _ := store.RunningTimeEntry("u1"); running == nil || running.Task != "写周报" || running.Category != "写作" {
+		t.Fatalf("running = %+v", running)
+	}
+	if reply, ok := a.handleTimerCommand(msg, "停止计时"); !ok || !strings.HasPrefix(reply, "⏹ 已停止：写周报") {
+		t.Fatalf("stop: %v %q", ok, reply)
+	}
+	if _, ok := a.handleTimerCommand(msg, "开始计时器怎么用"); ok {
+		t.Fatal("plain text handled as a timer command")
+	}
+}
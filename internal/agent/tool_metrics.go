@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+)
+
+// recordToolMetric logs a tool's latency and result size and persists it for reports
+func (a *Agent) recordToolMetric(name string, elapsed time.Duration, resultBytes int, isError bool) {
+	logger.Info("[Agent] Tool %s finished in %dms (%d bytes, error=%v)", name, elapsed.Milliseconds(), resultBytes, isError)
+	if a.persistStore == nil {
+		return
+	}
+	err := a.persistStore.RecordToolMetric(persist.ToolMetric{
+		ToolName:    name,
+		Duration:    elapsed,
+		ResultBytes: resultBytes,
+		IsError:     isError,
+	})
+	if err != nil {
+		logger.Warn("[Agent] Failed to record tool metric for %s: %v", name, err)
+	}
+}
+
+// formatSlowestTools renders the slowest tools for a date, or "" when there is no data
+func (a *Agent) formatSlowestTools(date string, limit int) string {
+	if a.persistStore == nil {
+		return ""
+	}
+	stats, err := a.persistStore.SlowestTools(date, limit)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load tool metrics for %s: %v", date, err)
+		return ""
+	}
+	if len(stats) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🐢 最慢工具 (%s):\n", date)
+	for _, st := range stats {
+		fmt.Fprintf(&sb, "  - %s: 平均 %s, 最长 %s, %d 次调用", st.ToolName,
+			st.AvgDuration.Round(time.Millisecond), st.MaxDuration.Round(time.Millisecond), st.Calls)
+		if st.Errors > 0 {
+			fmt.Fprintf(&sb, ", %d 次失败", st.Errors)
+		}
+		fmt.Fprintf(&sb, ", 平均结果 %s\n", formatByteSize(st.AvgResultBytes))
+	}
+	return sb.String()
+}
+
+func formatByteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/security"
+)
+
+// splitAllowFromProfiles separates "sender=profile" allow_from entries into the
+// sender whitelist and a sender → profile map.
+func splitAllowFromProfiles(entries []string) ([]string, map[string]string) {
+	senders := make([]string, 0, len(entries))
+	profiles := make(map[string]string)
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		sender, profile := security.SplitAllowFromEntry(entry)
+		if sender == "" {
+			continue
+		}
+		if profile != "" {
+			profiles[sender] = profile
+		}
+		if _, ok := seen[sender]; ok {
+			continue
+		}
+		seen[sender] = struct{}{}
+		senders = append(senders, sender)
+	}
+	return senders, profiles
+}
+
+// applyToolProfiles installs configured profiles on top of the built-in ones.
+func (a *Agent) applyToolProfiles(configured map[string]config.ToolProfileConfig, defaultProfile string) {
+	profiles := make(map[string]security.ToolProfile, len(security.BuiltinToolProfiles)+len(configured))
+	for name, p := range security.BuiltinToolProfiles {
+		profiles[name] = p
+	}
+	for name, p := range configured {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		profiles[name] = security.ToolProfile{Name: name, Allow: p.Allow, Deny: p.Deny}
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.toolProfiles = profiles
+	a.defaultToolProfile = strings.ToLower(strings.TrimSpace(defaultProfile))
+}
+
+// toolProfileFor returns the permission profile of the message sender.
+// Unknown profile names fall back to readonly rather than full access.
+func (a *Agent) toolProfileFor(msg router.Message) security.ToolProfile {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+
+	name := ""
+	for _, key := range senderCandidates(msg) {
+		if p, ok := a.senderProfiles[key]; ok {
+			name = p
+			break
+		}
+	}
+	if name == "" {
+		name = a.defaultToolProfile
+	}
+	if name == "" {
+		name = security.ProfileAdmin
+	}
+	return a.toolProfileNamedLocked(name, msg)
+}
+
+// toolProfileNamed returns the profile called name, readonly when there is
+// none; msg only labels the warning.
+func (a *Agent) toolProfileNamed(name string, msg router.Message) security.ToolProfile {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.toolProfileNamedLocked(strings.ToLower(strings.TrimSpace(name)), msg)
+}
+
+func (a *Agent) toolProfileNamedLocked(name string, msg router.Message) security.ToolProfile {
+	if p, ok := a.toolProfiles[name]; ok {
+		return p
+	}
+	if p, ok := security.BuiltinToolProfiles[name]; ok {
+		return p
+	}
+	logger.Warn("[Agent] Unknown tool profile %q for %s/%s, using readonly", name, msg.Platform, msg.UserID)
+	return security.BuiltinToolProfiles[security.ProfileReadonly]
+}
+
+// filterToolsForProfile drops tools the profile does not permit so the model never sees them.
+func filterToolsForProfile(tools []Tool, profile security.ToolProfile) []Tool {
+	if len(profile.Allow) == 0 && len(profile.Deny) == 0 {
+		return tools
+	}
+	filtered := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		if profile.Allows(t.Name) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// checkToolProfile rejects calls to tools outside the current sender's profile.
+func (a *Agent) checkToolProfile(ctx context.Context, name string) string {
+	msg := turnMessage(ctx)
+	profile := a.toolProfileFor(msg)
+	if profile.Allows(name) {
+		return a.checkChannelTools(ctx, name)
+	}
+	logger.Warn("[Agent] Tool %s denied by profile %q for %s/%s", name, profile.Name, msg.Platform, msg.UserID)
+	return fmt.Sprintf("ACCESS DENIED: tool %s is not permitted for this sender (profile %q). Do NOT retry. Tell the user this action needs a different permission profile.", name, profile.Name)
+}
+
+// denyAdminCommand rejects builtin commands that change state outside the
+// conversation — secrets, workspace files, sync, approved actions and the
+// config — unless the sender has the admin profile. Tool profiles only
+// gate the model's tool calls, so these need their own check.
+func (a *Agent) denyAdminCommand(msg router.Message, text string) (string, bool) {
+	if !isAdminCommand(text) {
+		return "", false
+	}
+	profile := a.toolProfileFor(msg)
+	if profile.Name == security.ProfileAdmin {
+		return "", false
+	}
+	logger.Warn("[Agent] Command %q denied by profile %q for %s/%s", strings.Fields(text)[0], profile.Name, msg.Platform, msg.UserID)
+	return fmt.Sprintf("该命令需要 admin 权限（当前权限: %s）", profile.Name), true
+}
+
+func isAdminCommand(text string) bool {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "/revert", "/sync", "同步", "/approve", "批准", "确认执行", "重新加载配置":
+		return true
+	case "/secret":
+		return len(fields) > 1 && (fields[1] == "set" || fields[1] == "del")
+	case "/config":
+		return len(fields) > 1 && fields[1] == "reload"
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+)
+
+// defaultToolTimeouts apply when tools.timeouts does not cover a tool.
+// Patterns use path.Match globs; "*" is the fallback.
+var defaultToolTimeouts = map[string]time.Duration{
+	"web_fetch":  45 * time.Second,
+	"web_search": 45 * time.Second,
+	"browser_*":  90 * time.Second,
+	"*":          2 * time.Minute,
+}
+
+// shellTimeoutGrace is added to shell_execute's own timeout argument so the
+// command's timeout fires before ours.
+const shellTimeoutGrace = 10 * time.Second
+
+// toolTimeoutRule is one entry of the timeout table; zero means no limit.
+type toolTimeoutRule struct {
+	pattern string
+	timeout time.Duration
+}
+
+// applyToolTimeouts sets the per-tool timeout table from tools.timeouts, on
+// top of the defaults. Values are durations; "0" or "off" removes the limit.
+func (a *Agent) applyToolTimeouts(configured map[string]string) {
+	merged := make(map[string]time.Duration, len(defaultToolTimeouts)+len(configured))
+	for pattern, d := range defaultToolTimeouts {
+		merged[pattern] = d
+	}
+	for pattern, value := range configured {
+		pattern = strings.TrimSpace(pattern)
+		value = strings.TrimSpace(value)
+		if value == "off" || value == "0" {
+			merged[pattern] = 0
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			logger.Warn("[Agent] Invalid tools.timeouts[%s] = %q, ignored", pattern, value)
+			continue
+		}
+		merged[pattern] = d
+	}
+
+	rules := make([]toolTimeoutRule, 0, len(merged))
+	for pattern, d := range merged {
+		rules = append(rules, toolTimeoutRule{pattern: pattern, timeout: d})
+	}
+	// Exact names first, then longer (more specific) globs, "*" last.
+	sort.Slice(rules, func(i, j int) bool {
+		gi, gj := strings.ContainsAny(rules[i].pattern, "*?["), strings.ContainsAny(rules[j].pattern, "*?[")
+		if gi != gj {
+			return !gi
+		}
+		if len(rules[i].pattern) != len(rules[j].pattern) {
+			return len(rules[i].pattern) > len(rules[j].pattern)
+		}
+		return rules[i].pattern < rules[j].pattern
+	})
+
+	a.securityMu.Lock()
+	a.toolTimeouts = rules
+	a.securityMu.Unlock()
+}
+
+// toolTimeout returns how long a tool call may run, or 0 for no limit.
+func (a *Agent) toolTimeout(name string, args map[string]any) time.Duration {
+	a.securityMu.RLock()
+	rules := a.toolTimeouts
+	a.securityMu.RUnlock()
+
+	var timeout time.Duration
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			timeout = rule.timeout
+			break
+		}
+	}
+	if name == "shell_execute" && timeout > 0 {
+		if secs, ok := args["timeout"].(float64); ok && secs > 0 {
+			timeout = max(timeout, time.Duration(secs)*time.Second+shellTimeoutGrace)
+		}
+	}
+	return timeout
+}
+
+// runToolWithTimeout runs fn under ctx, returning as soon as ctx ends even if
+// the tool ignores cancellation. Such a tool keeps running in the background,
+// but the tool loop is no longer blocked by it.
+func runToolWithTimeout(ctx context.Context, name string, timeout time.Duration, fn func(context.Context) string) string {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan string, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+			logger.Warn("[Agent] Tool %s timed out after %s", name, timeout)
+			return fmt.Sprintf("Error: %s timed out after %s and was abandoned. Try a smaller request or a different approach.", name, timeout)
+		}
+		return fmt.Sprintf("Error: %s was cancelled: %v", name, ctx.Err())
+	}
+}
+
+// turnRegistry tracks the cancel functions of in-flight HandleMessage calls
+// per conversation so "/cancel" can abort them.
+type turnRegistry struct {
+	mu     sync.Mutex
+	nextID uint64
+	turns  map[string]map[uint64]context.CancelFunc
+}
+
+// begin derives a cancellable context for a turn; end must be called when it returns.
+func (r *turnRegistry) begin(ctx context.Context, convKey string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	if r.turns == nil {
+		r.turns = make(map[string]map[uint64]context.CancelFunc)
+	}
+	if r.turns[convKey] == nil {
+		r.turns[convKey] = make(map[uint64]context.CancelFunc)
+	}
+	r.nextID++
+	id := r.nextID
+	r.turns[convKey][id] = cancel
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.turns[convKey], id)
+		if len(r.turns[convKey]) == 0 {
+			delete(r.turns, convKey)
+		}
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel aborts every in-flight turn of a conversation and reports how many there were.
+func (r *turnRegistry) cancel(convKey string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, cancel := range r.turns[convKey] {
+		cancel()
+		n++
+	}
+	return n
+}
+
+func isCancelCommand(text string) bool {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/cancel", "/stop", "停止":
+		return true
+	}
+	return false
+}
+
+// handleCancelCommand serves "/cancel", which aborts the conversation's in-flight request.
+func (a *Agent) handleCancelCommand(convKey, text string) (string, bool) {
+	if !isCancelCommand(text) {
+		return "", false
+	}
+	if n := a.turns.cancel(convKey); n > 0 {
+		logger.Info("[Agent] Cancelled %d in-flight request(s) in %s", n, convKey)
+		return fmt.Sprintf("已中止 %d 个进行中的请求。", n), true
+	}
+	return "当前没有进行中的请求。", true
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunToolWithTimeoutAbandonsHangingTool(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	result := runToolWithTimeout(context.Background(), "web_fetch", 50*time.Millisecond, func(ctx context.Context) string {
+		<-release // ignores ctx, like a stuck HTTP read
+		return "late"
+	})
+	if !strings.Contains(result, "timed out") {
+		t.Fatalf("result = %q, want timeout error", result)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("returned after %s, want ~50ms", elapsed)
+	}
+
+	if got := runToolWithTimeout(context.Background(), "file_read", 0, func(context.Context) string { return "ok" }); got != "ok" {
+		t.Fatalf("result = %q, want ok", got)
+	}
+}
+
+func TestToolTimeoutRules(t *testing.T) {
+	a := &Agent{}
+	a.applyToolTimeouts(map[string]string{
+		"browser_snapshot": "20s",
+		"shell_execute":    "30s",
+		"file_read":        "off",
+		"web_*":            "bogus",
+	})
+
+	cases := []struct {
+		name string
+		args map[string]any
+		want time.Duration
+	}{
+		{"browser_snapshot", nil, 20 * time.Second},
+		{"browser_click", nil, 90 * time.Second},
+		{"web_fetch", nil, 45 * time.Second},
+		{"file_read", nil, 0},
+		{"memory_search", nil, 2 * time.Minute},
+		{"shell_execute", map[string]any{"timeout": float64(5)}, 30 * time.Second},
+		{"shell_execute", map[string]any{"timeout": float64(300)}, 300*time.Second + shellTimeoutGrace},
+	}
+	for _, tc := range cases {
+		if got := a.toolTimeout(tc.name, tc.args); got != tc.want {
+			t.Errorf("toolTimeout(%s, %v) = %s, want %s", tc.name, tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCancelCommandAbortsInFlightTurn(t *testing.T) {
+	a := &Agent{}
+	convKey := ConversationKey("telegram", "c1", "u1")
+
+	if reply, ok := a.handleCancelCommand(convKey, "/cancel"); !ok || !strings.Contains(reply, "没有") {
+		t.Fatalf("idle cancel = %q, %v", reply, ok)
+	}
+
+	ctx, end := a.turns.begin(context.Background(), convKey)
+	defer end()
+	other, endOther := a.turns.begin(context.Background(), ConversationKey("telegram", "c2", "u1"))
+	defer endOther()
+
+	if reply, ok := a.handleCancelCommand(convKey, " /CANCEL "); !ok || !strings.Contains(reply, "1") {
+		t.Fatalf("cancel = %q, %v", reply, ok)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("turn context was not cancelled")
+	}
+	if other.Err() != nil {
+		t.Fatal("another conversation's turn was cancelled")
+	}
+	if _, ok := a.handleCancelCommand(convKey, "cancel the meeting"); ok {
+		t.Fatal("plain text must not be treated as /cancel")
+	}
+}
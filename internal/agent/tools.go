@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kayz/coco/internal/config"
 	"github.com/kayz/coco/internal/logger"
 	"github.com/kayz/coco/internal/router"
 	"github.com/kayz/coco/internal/security"
@@ -311,6 +312,25 @@ func extractText(result *mcp.CallToolResult) string {
 
 // === CALENDAR ===
 
+// formatPIMSection reports which backend serves the calendar, reminders
+// and notes tools on this machine.
+func formatPIMSection() string {
+	groups := map[string]string{"calendar": "日历", "reminders": "提醒事项", "notes": "备忘录"}
+	var sb strings.Builder
+	sb.WriteString("🗂 日历/提醒/备忘录后端:")
+	for _, b := range tools.PIMBackends() {
+		mark := "✅"
+		if !b.Available {
+			mark = "❌"
+		}
+		fmt.Fprintf(&sb, "\n  %s: %s %s", groups[b.Group], b.Name, mark)
+		if b.Detail != "" {
+			fmt.Fprintf(&sb, " %s", b.Detail)
+		}
+	}
+	return sb.String()
+}
+
 func executeCalendarCreate(ctx context.Context, args map[string]any) string {
 	req := mcp.CallToolRequest{}
 	req.Params.Arguments = args
@@ -341,6 +361,16 @@ func executeCalendarDelete(ctx context.Context, args map[string]any) string {
 	return extractText(result)
 }
 
+func executeCalendarFreeBusy(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.CalendarFreeBusy(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
 // === REMINDERS ===
 
 func executeRemindersToday(ctx context.Context) string {
@@ -611,6 +641,26 @@ func executeBrowserStop(ctx context.Context) string {
 	return extractText(result)
 }
 
+func executeBrowserNetworkStartCapture(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserNetworkStartCapture(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserNetworkGetRequests(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserNetworkGetRequests(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
 // === CLIPBOARD ===
 
 func executeClipboardRead(ctx context.Context) string {
@@ -645,6 +695,41 @@ func executeNotificationSend(ctx context.Context, args map[string]any) string {
 	return extractText(result)
 }
 
+// === PRINTING ===
+
+func executePrintFile(ctx context.Context, args map[string]any) string {
+	if p, ok := args["path"].(string); ok && isSensitiveFile(p) {
+		return "ACCESS DENIED: printing sensitive files (.env, credentials, keys) is blocked for security. Do NOT retry."
+	}
+
+	printArgs := make(map[string]any, len(args))
+	for k, v := range args {
+		printArgs[k] = v
+	}
+	if cfg, err := config.Load(); err == nil {
+		printer, _ := printArgs["printer"].(string)
+		printer = strings.TrimSpace(printer)
+		if printer == "" {
+			printer = cfg.Printing.DefaultPrinter
+		}
+		for alias, name := range cfg.Printing.Printers {
+			if strings.EqualFold(alias, printer) {
+				printer = name
+				break
+			}
+		}
+		printArgs["printer"] = printer
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = printArgs
+	result, err := tools.PrintFile(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
 // === SCREENSHOT ===
 
 func executeScreenshot(ctx context.Context, args map[string]any) string {
@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	// topicMetadataKey marks a message with the topic thread its chat is on.
+	// It is set on arrival, so every conversation key taken from the message
+	// points into the thread.
+	topicMetadataKey = "topic"
+	topicNameMaxLen  = 32
+)
+
+// topicSelections caches which topic thread each chat is on; the store
+// keeps it across restarts.
+type topicSelections struct {
+	mu     sync.Mutex
+	active map[string]string // chat conversation key -> topic, "" for the main thread
+}
+
+// conversationKeyOf is the key of the conversation msg belongs to: its
+// chat, or the topic thread the chat has switched to.
+func conversationKeyOf(msg router.Message) string {
+	key := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	if topic := msg.Metadata[topicMetadataKey]; topic != "" {
+		key += persist.TopicSeparator + topic
+	}
+	return key
+}
+
+// splitTopicKey separates a conversation key into its chat's key and topic.
+func splitTopicKey(key string) (chat, topic string) {
+	platform, channelID, userID := persist.ParseConversationKey(key)
+	userID, topic, _ = strings.Cut(userID, persist.TopicSeparator)
+	return ConversationKey(platform, channelID, userID), topic
+}
+
+func (a *Agent) activeTopic(chat string) string {
+	a.topics.mu.Lock()
+	defer a.topics.mu.Unlock()
+	if topic, ok := a.topics.active[chat]; ok {
+		return topic
+	}
+	topic := ""
+	if a.persistStore != nil {
+		var err error
+		if topic, err = a.persistStore.ActiveTopic(chat); err != nil {
+			logger.Warn("[Agent] Failed to load the topic of %s: %v", chat, err)
+			return ""
+		}
+	}
+	if a.topics.active == nil {
+		a.topics.active = make(map[string]string)
+	}
+	a.topics.active[chat] = topic
+	return topic
+}
+
+func (a *Agent) setActiveTopic(chat, topic string) error {
+	if a.persistStore != nil {
+		if err := a.persistStore.SetActiveTopic(chat, topic); err != nil {
+			return err
+		}
+	}
+	a.topics.mu.Lock()
+	defer a.topics.mu.Unlock()
+	if a.topics.active == nil {
+		a.topics.active = make(map[string]string)
+	}
+	a.topics.active[chat] = topic
+	return nil
+}
+
+// withActiveTopic marks msg with the topic thread its chat is on.
+func (a *Agent) withActiveTopic(msg router.Message) router.Message {
+	topic := a.activeTopic(ConversationKey(msg.Platform, msg.ChannelID, msg.UserID))
+	if topic == "" {
+		return msg
+	}
+	meta := make(map[string]string, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		meta[k] = v
+	}
+	meta[topicMetadataKey] = topic
+	msg.Metadata = meta
+	return msg
+}
+
+// chatTopics lists the topic threads forked in a chat, most recent first.
+func (a *Agent) chatTopics(chat string) []string {
+	var topics []string
+	for _, key := range a.memory.Keys() {
+		if c, topic := splitTopicKey(key); c == chat && topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+func isMainTopic(name string) bool {
+	return strings.EqualFold(name, "main") || name == "主线"
+}
+
+func validateTopicName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("话题名不能为空")
+	case isMainTopic(name):
+		return fmt.Errorf("“%s”是主线的名字，请换一个", name)
+	case strings.ContainsAny(name, ":"+persist.TopicSeparator):
+		return fmt.Errorf("话题名不能包含 : 或 %s", persist.TopicSeparator)
+	case utf8.RuneCountInString(name) > topicNameMaxLen:
+		return fmt.Errorf("话题名最多 %d 个字", topicNameMaxLen)
+	}
+	return nil
+}
+
+func topicLabel(topic string) string {
+	if topic == "" {
+		return "主线"
+	}
+	return "「" + topic + "」"
+}
+
+// handleTopicCommand answers "/fork <topic>", which copies the current
+// conversation into a new topic thread and switches to it, and
+// "/threads [topic|main]", which lists the chat's threads or switches.
+func (a *Agent) handleTopicCommand(msg router.Message, text string) (string, bool) {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	arg = strings.TrimSpace(arg)
+	chat := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	current := msg.Metadata[topicMetadataKey]
+
+	switch strings.ToLower(cmd) {
+	case "/fork", "分叉":
+		if arg == "" {
+			return "用法：/fork 话题名 —— 把当前对话复制成一个新话题，之后的消息只进入新话题，互不干扰", true
+		}
+		if err := validateTopicName(arg); err != nil {
+			return err.Error(), true
+		}
+		from := conversationKeyOf(msg)
+		if a.isIncognito(from) {
+			return "无痕模式下不能分叉话题，请先 /incognito off", true
+		}
+		to := chat + persist.TopicSeparator + arg
+		if _, _, exists := a.memory.Stat(to); exists {
+			return fmt.Sprintf("话题「%s」已存在，发送 /threads %s 切换过去", arg, arg), true
+		}
+		copied := a.memory.Fork(from, to)
+		a.sessions.Copy(from, to)
+		if err := a.setActiveTopic(chat, arg); err != nil {
+			return fmt.Sprintf("分叉失败: %v", err), true
+		}
+		return fmt.Sprintf("🔀 已从%s分叉出话题「%s」，带上了 %d 条历史；之后的消息只进入这个话题。\n/threads 查看或切换，/threads main 回到主线", topicLabel(current), arg, copied), true
+
+	case "/threads", "/thread", "话题":
+		if arg == "" {
+			return a.formatTopics(chat, current), true
+		}
+		target := arg
+		if isMainTopic(arg) {
+			target = ""
+		} else if _, _, exists := a.memory.Stat(chat + persist.TopicSeparator + arg); !exists {
+			return fmt.Sprintf("没有话题「%s」；/threads 查看已有话题，/fork %s 新开一个", arg, arg), true
+		}
+		if target == current {
+			return "已经在" + topicLabel(target) + "了", true
+		}
+		if err := a.setActiveTopic(chat, target); err != nil {
+			return fmt.Sprintf("切换失败: %v", err), true
+		}
+		key := chat
+		if target != "" {
+			key += persist.TopicSeparator + target
+		}
+		n, _, _ := a.memory.Stat(key)
+		return fmt.Sprintf("已切换到%s（%d 条历史）", topicLabel(target), n), true
+	}
+	return "", false
+}
+
+func (a *Agent) formatTopics(chat, current string) string {
+	line := func(topic string) string {
+		key := chat
+		if topic != "" {
+			key += persist.TopicSeparator + topic
+		}
+		marker := "   "
+		if topic == current {
+			marker = "👉 "
+		}
+		s := marker + topicLabel(topic)
+		if n, at, ok := a.memory.Stat(key); ok {
+			s += fmt.Sprintf(" · %d 条 · %s", n, at.Format("01-02 15:04"))
+		}
+		return s
+	}
+	lines := []string{"🧵 话题:", line("")}
+	for _, topic := range a.chatTopics(chat) {
+		lines = append(lines, line(topic))
+	}
+	if len(lines) == 2 {
+		lines = append(lines, "", "还没有分叉的话题；/fork 话题名 把当前对话复制成新话题")
+	} else {
+		lines = append(lines, "", "/threads 话题名 切换，/threads main 回到主线")
+	}
+	return strings.Join(lines, "\n")
+}
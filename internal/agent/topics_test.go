@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestForkTopicKeepsThreadsApart(t *testing.T) {
+	a := newTaskTestAgent(t)
+	a.memory = NewMemory(a.persistStore, 0)
+	a.sessions = NewSessionStore()
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1"}
+
+	main := conversationKeyOf(a.withActiveTopic(msg))
+	a.memory.AddExchange(main, Message{Role: "user", Content: "报销单怎么填"}, Message{Role: "assistant", Content: "先填金额"})
+	a.sessions.SetThinkingLevel(main, ThinkHigh)
+
+	if got, _ := a.handleTopicCommand(a.withActiveTopic(msg), "/fork 旅行"); !strings.Contains(got, "带上了 2 条历史") {
+		t.Fatalf("/fork = %q", got)
+	}
+	inTopic := a.withActiveTopic(msg)
+	topicKey := conversationKeyOf(inTopic)
+	if topicKey != main+"#旅行" {
+		t.Fatalf("topic key = %q", topicKey)
+	}
+	if msg.Metadata != nil {
+		t.Fatal("withActiveTopic changed the caller's metadata")
+	}
+	if a.sessions.Get(topicKey).ThinkingLevel != ThinkHigh {
+		t.Fatal("session settings were not copied")
+	}
+	a.memory.AddExchange(topicKey, Message{Role: "user", Content: "订下周去杭州的行程"}, Message{Role: "assistant", Content: "好的"})
+	if len(a.memory.GetHistory(main)) != 2 || len(a.memory.GetHistory(topicKey)) != 4 {
+		t.Fatalf("main %d, topic %d messages", len(a.memory.GetHistory(main)), len(a.memory.GetHistory(topicKey)))
+	}
+
+	for _, bad := range []string{"/fork 旅行", "/fork main", "/fork a#b", "/fork"} {
+		if got, _ := a.handleTopicCommand(inTopic, bad); strings.Contains(got, "已从") {
+			t.Fatalf("%s forked: %q", bad, got)
+		}
+	}
+	list, _ := a.handleTopicCommand(inTopic, "/threads")
+	if !strings.Contains(list, "👉 「旅行」 · 4 条") || !strings.Contains(list, "   主线 · 2 条") {
+		t.Fatalf("/threads = %q", list)
+	}
+	if got, _ := a.handleTopicCommand(inTopic, "/threads 工作"); !strings.Contains(got, "没有话题") {
+		t.Fatalf("unknown topic = %q", got)
+	}
+	if got, _ := a.handleTopicCommand(inTopic, "/threads main"); got != "已切换到主线（2 条历史）" {
+		t.Fatalf("/threads main = %q", got)
+	}
+	if conversationKeyOf(a.withActiveTopic(msg)) != main {
+		t.Fatal("still in the topic after /threads main")
+	}
+
+	// The topic and the choice of thread survive a restart.
+	a.handleTopicCommand(a.withActiveTopic(msg), "/threads 旅行")
+	restarted := &Agent{persistStore: a.persistStore, memory: NewMemory(a.persistStore, 0)}
+	key := conversationKeyOf(restarted.withActiveTopic(msg))
+	if key != topicKey || len(restarted.memory.GetHistory(key)) != 4 {
+		t.Fatalf("after restart: %q with %d messages", key, len(restarted.memory.GetHistory(key)))
+	}
+	if found, err := a.persistStore.SearchMessages("u1", "杭州", 10); err != nil || len(found) != 1 {
+		t.Fatalf("search in topic = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/finetune"
+	"github.com/kayz/coco/internal/logger"
+)
+
+const defaultTraceRetentionDays = 30
+
+// traceSettings is the traces section of the config.
+type traceSettings struct {
+	enabled   bool
+	retention time.Duration
+	prunedAt  time.Time
+}
+
+func (a *Agent) applyTraces(cfg config.TracesConfig) {
+	days := cfg.RetentionDays
+	if days <= 0 {
+		days = defaultTraceRetentionDays
+	}
+	a.securityMu.Lock()
+	a.traces.enabled = cfg.Enabled
+	a.traces.retention = time.Duration(days) * 24 * time.Hour
+	a.securityMu.Unlock()
+}
+
+// recordRunTrace stores a finished run for `coco traces export` when
+// traces are enabled. Traces past the retention are pruned once a day.
+func (a *Agent) recordRunTrace(convKey, systemPrompt string, tools []Tool, messages []Message, reply string) {
+	if a.persistStore == nil || a.isIncognito(convKey) {
+		return
+	}
+	a.securityMu.Lock()
+	s := a.traces
+	prune := s.enabled && time.Since(s.prunedAt) > 24*time.Hour
+	if prune {
+		a.traces.prunedAt = time.Now()
+	}
+	a.securityMu.Unlock()
+	if !s.enabled {
+		return
+	}
+
+	trace := buildRunTrace(systemPrompt, tools, append(messages[:len(messages):len(messages)], Message{Role: "assistant", Content: reply}))
+	data, err := json.Marshal(trace)
+	if err == nil {
+		err = a.persistStore.SaveRunTrace(convKey, data)
+	}
+	if err != nil {
+		logger.Warn("[Agent] Failed to record run trace: %v", err)
+	}
+	if prune {
+		if _, err := a.persistStore.DeleteRunTracesBefore(time.Now().Add(-s.retention)); err != nil {
+			logger.Warn("[Agent] Failed to prune run traces: %v", err)
+		}
+	}
+}
+
+func buildRunTrace(systemPrompt string, tools []Tool, messages []Message) finetune.Trace {
+	trace := finetune.Trace{System: systemPrompt}
+	for _, t := range tools {
+		trace.Tools = append(trace.Tools, finetune.Tool{Name: t.Name, Description: t.Description, Parameters: t.InputSchema})
+	}
+	for _, m := range messages {
+		if m.ToolResult != nil {
+			trace.Messages = append(trace.Messages, finetune.Message{
+				Role:       "tool",
+				Content:    m.ToolResult.Content,
+				ToolCallID: m.ToolResult.ToolCallID,
+			})
+			continue
+		}
+		fm := finetune.Message{Role: m.Role, Content: m.Content}
+		for _, tc := range m.ToolCalls {
+			args := string(tc.Input)
+			if args == "" {
+				args = "{}"
+			}
+			fm.ToolCalls = append(fm.ToolCalls, finetune.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: args})
+		}
+		trace.Messages = append(trace.Messages, fm)
+	}
+	return trace
+}
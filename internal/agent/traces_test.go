@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/finetune"
+)
+
+func TestRecordRunTrace(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	tools := []Tool{{Name: "weather", Description: "Current weather", InputSchema: json.RawMessage(`{"type":"object"}`)}}
+	messages := []Message{
+		{Role: "user", Content: "上海天气？"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "weather", Input: json.RawMessage(`{"location":"上海"}`)}}},
+		{Role: "user", ToolResult: &ToolResult{ToolCallID: "c1", Content: "晴 18°C"}},
+	}
+
+	a.recordRunTrace("wecom:c1:u1", "You are coco.", tools, messages, "上海晴，18°C。")
+	if traces, _ := a.persistStore.RunTraces(time.Time{}); len(traces) != 0 {
+		t.Fatalf("recorded %d traces while disabled", len(traces))
+	}
+
+	a.applyTraces(config.TracesConfig{Enabled: true})
+	a.recordRunTrace("wecom:c1:u1", "You are coco.", tools, messages, "上海晴，18°C。")
+	traces, err := a.persistStore.RunTraces(time.Time{})
+	if err != nil || len(traces) != 1 {
+		t.Fatalf("traces = %v, %v", traces, err)
+	}
+	var trace finetune.Trace
+	if err := json.Unmarshal([]byte(traces[0].Data), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.System != "You are coco." || len(trace.Tools) != 1 || len(trace.Messages) != 4 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if m := trace.Messages[2]; m.Role != "tool" || m.ToolCallID != "c1" || m.Content != "晴 18°C" {
+		t.Fatalf("tool result = %+v", m)
+	}
+	if m := trace.Messages[1]; len(m.ToolCalls) != 1 || m.ToolCalls[0].Arguments != `{"location":"上海"}` {
+		t.Fatalf("tool call = %+v", m)
+	}
+	if m := trace.Messages[3]; m.Role != "assistant" || m.Content != "上海晴，18°C。" {
+		t.Fatalf("reply = %+v", m)
+	}
+}
@@ -0,0 +1,549 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/tools"
+	"github.com/kayz/coco/internal/travel"
+)
+
+const (
+	tripJobName         = "trip-reminders"
+	tripJobTag          = "assistant-task"
+	tripJobSchedule     = "*/10 * * * *"
+	defaultFlightLead   = 3 * time.Hour
+	defaultTrainLead    = time.Hour
+	tripLookupTimeout   = 20 * time.Second
+	tripAssumedDuration = 6 * time.Hour // how long a trip without an arrival time stays active
+)
+
+// weatherAt reads the forecast folded into reminders; tests replace it.
+var weatherAt = tools.WeatherAt
+
+type travelSettings struct {
+	flights     travel.Source // nil without travel.flight_provider
+	trains      travel.Source // nil without travel.train_url
+	flightLead  time.Duration
+	trainLead   time.Duration
+	autoExtract bool
+}
+
+var travelStateNames = map[travel.State]string{
+	travel.StateScheduled: "正常",
+	travel.StateDelayed:   "延误",
+	travel.StateDeparted:  "已出发",
+	travel.StateArrived:   "已到达",
+	travel.StateCancelled: "已取消",
+	travel.StateDiverted:  "备降",
+	travel.StateUnknown:   "未知",
+}
+
+// applyTravel installs the travel section.
+func (a *Agent) applyTravel(cfg config.TravelConfig) {
+	s := travelSettings{
+		flightLead:  parseLeadTime(cfg.FlightReminder, defaultFlightLead, "flight_reminder"),
+		trainLead:   parseLeadTime(cfg.TrainReminder, defaultTrainLead, "train_reminder"),
+		autoExtract: cfg.AutoExtract == nil || *cfg.AutoExtract,
+	}
+	srcCfg := travel.Config{FlightProvider: cfg.FlightProvider, FlightAPIKey: cfg.FlightAPIKey, FlightURL: cfg.FlightURL, TrainURL: cfg.TrainURL}
+	var err error
+	if s.flights, err = travel.NewFlightSource(srcCfg); err != nil {
+		logger.Warn("[Agent] Flight status disabled: %v", err)
+	}
+	if s.trains, err = travel.NewTrainSource(srcCfg); err != nil {
+		logger.Warn("[Agent] Train status disabled: %v", err)
+	}
+	a.securityMu.Lock()
+	a.travel = s
+	a.securityMu.Unlock()
+}
+
+func parseLeadTime(raw string, def time.Duration, key string) time.Duration {
+	if strings.TrimSpace(raw) == "" {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || d <= 0 {
+		logger.Warn("[Agent] Invalid travel.%s %q, using %s", key, raw, def)
+		return def
+	}
+	return d
+}
+
+func (a *Agent) currentTravel() travelSettings {
+	a.securityMu.RLock()
+	defer a.securityMu.RUnlock()
+	return a.travel
+}
+
+func (s travelSettings) source(kind travel.Kind) travel.Source {
+	if kind == travel.Train {
+		return s.trains
+	}
+	return s.flights
+}
+
+func (s travelSettings) lead(kind string) time.Duration {
+	if kind == string(travel.Train) {
+		return s.trainLead
+	}
+	return s.flightLead
+}
+
+// executeTravelStatus serves flight_status and train_status.
+func (a *Agent) executeTravelStatus(ctx context.Context, kind travel.Kind, args map[string]any) string {
+	number := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(getString(args, "number")), " ", ""))
+	if number == "" {
+		return "Error: number is required"
+	}
+	date := time.Now()
+	if raw := strings.TrimSpace(getString(args, "date")); raw != "" {
+		d, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			return "Error: date must be YYYY-MM-DD"
+		}
+		date = d
+	}
+	src := a.currentTravel().source(kind)
+	if src == nil {
+		if kind == travel.Train {
+			return "列车状态查询未配置（在 .coco.yaml 中设置 travel.train_url）"
+		}
+		return "航班状态查询未配置（在 .coco.yaml 中设置 travel.flight_provider 与 flight_api_key）"
+	}
+	status, err := src.Status(ctx, number, date)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return formatTravelStatus(kind, status)
+}
+
+func (a *Agent) executeItinerary(ctx context.Context, args map[string]any) string {
+	if a.persistStore == nil {
+		return "Error: persist store not available"
+	}
+	action := strings.ToLower(strings.TrimSpace(getString(args, "action")))
+	if action == "" {
+		action = "list"
+		if getString(args, "text") != "" || getString(args, "number") != "" {
+			action = "add"
+		}
+	}
+	userID := timeUserID(turnMessage(ctx))
+	switch action {
+	case "add":
+		return a.addTrips(ctx, userID, args)
+	case "list":
+		return a.listTrips(userID)
+	case "remove", "delete":
+		return a.removeTrip(userID, int64(getFloat(args, "id")))
+	case "remind":
+		return a.remindTrips(ctx, time.Now())
+	default:
+		return "Error: action must be add, list or remove"
+	}
+}
+
+func (a *Agent) addTrips(ctx context.Context, userID string, args map[string]any) string {
+	var segments []travel.Segment
+	if text := getString(args, "text"); text != "" {
+		segments = travel.ParseItinerary(text, time.Now())
+		if len(segments) == 0 {
+			return "没有从文本中识别出航班或车次（需要班次号、日期和出发时间），可以直接给出 number 和 departure"
+		}
+	} else {
+		number := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(getString(args, "number")), " ", ""))
+		departure, err := parseTripTime(getString(args, "departure"))
+		if number == "" || err != nil {
+			return "Error: number and departure (YYYY-MM-DD HH:MM) are required"
+		}
+		seg := travel.Segment{
+			Kind:      travel.KindOf(number),
+			Number:    number,
+			Departure: departure,
+			From:      strings.TrimSpace(getString(args, "from")),
+			To:        strings.TrimSpace(getString(args, "to")),
+			Seat:      strings.TrimSpace(getString(args, "seat")),
+		}
+		if arrival, err := parseTripTime(getString(args, "arrival")); err == nil {
+			seg.Arrival = arrival
+		}
+		segments = []travel.Segment{seg}
+	}
+
+	trips, err := a.saveTrips(userID, turnMessage(ctx), segments)
+	if err != nil {
+		return fmt.Sprintf("Error saving trip: %v", err)
+	}
+	var sb strings.Builder
+	sb.WriteString("🧳 已记录行程:\n")
+	for _, t := range trips {
+		sb.WriteString(formatTrip(t) + "\n")
+	}
+	sb.WriteString("出发前会提醒你，并附上目的地天气。")
+	if msg := a.ensureTripJob(); msg != "" {
+		sb.WriteString("\n" + msg)
+	}
+	return sb.String()
+}
+
+// saveTrips stores the segments, updating a saved trip with the same number
+// on the same day instead of adding it twice.
+func (a *Agent) saveTrips(userID string, msg router.Message, segments []travel.Segment) ([]persist.Trip, error) {
+	var saved []persist.Trip
+	for _, s := range segments {
+		existing, err := a.persistStore.FindTrip(userID, s.Number, s.Departure)
+		if err != nil {
+			return saved, err
+		}
+		if existing != nil {
+			mergeSegment(existing, s)
+			if err := a.persistStore.UpdateTrip(*existing); err != nil {
+				return saved, err
+			}
+			saved = append(saved, *existing)
+			continue
+		}
+		t := persist.Trip{
+			UserID:       userID,
+			Platform:     msg.Platform,
+			ChannelID:    msg.ChannelID,
+			Kind:         string(s.Kind),
+			Number:       s.Number,
+			Departure:    s.Departure,
+			Arrival:      s.Arrival,
+			Origin:       s.From,
+			Destination:  s.To,
+			Seat:         s.Seat,
+			Confirmation: s.Confirmation,
+			Active:       true,
+		}
+		if t.ID, err = a.persistStore.AddTrip(t); err != nil {
+			return saved, err
+		}
+		saved = append(saved, t)
+	}
+	return saved, nil
+}
+
+func mergeSegment(t *persist.Trip, s travel.Segment) {
+	t.Departure = s.Departure
+	if !s.Arrival.IsZero() {
+		t.Arrival = s.Arrival
+	}
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{{&t.Origin, s.From}, {&t.Destination, s.To}, {&t.Seat, s.Seat}, {&t.Confirmation, s.Confirmation}} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	t.Active = true
+}
+
+func (a *Agent) listTrips(userID string) string {
+	trips, err := a.persistStore.Trips(userID, true)
+	if err != nil {
+		return fmt.Sprintf("Error loading trips: %v", err)
+	}
+	if len(trips) == 0 {
+		return "没有即将出发的行程"
+	}
+	var sb strings.Builder
+	sb.WriteString("🧳 行程:\n")
+	for _, t := range trips {
+		sb.WriteString(formatTrip(t) + "\n")
+	}
+	return sb.String()
+}
+
+func (a *Agent) removeTrip(userID string, id int64) string {
+	t, err := a.persistStore.GetTrip(id)
+	if err != nil {
+		return fmt.Sprintf("Error loading trip: %v", err)
+	}
+	if t == nil || t.UserID != userID {
+		return fmt.Sprintf("没有编号为 #%d 的行程", id)
+	}
+	if err := a.persistStore.DeleteTrip(id); err != nil {
+		return fmt.Sprintf("Error removing trip: %v", err)
+	}
+	a.ensureTripJob()
+	return fmt.Sprintf("已删除行程 #%d %s", id, t.Number)
+}
+
+// remindTrips sends the pre-departure reminder of every trip that has
+// entered its reminder window and retires trips that are over.
+func (a *Agent) remindTrips(ctx context.Context, now time.Time) string {
+	trips, err := a.persistStore.Trips("", true)
+	if err != nil {
+		return fmt.Sprintf("Error loading trips: %v", err)
+	}
+	settings := a.currentTravel()
+	sent := 0
+	for i := range trips {
+		t := &trips[i]
+		end := t.Arrival
+		if end.IsZero() {
+			end = t.Departure.Add(tripAssumedDuration)
+		}
+		switch {
+		case now.After(end):
+			t.Active = false
+		case t.RemindedAt.IsZero() && !now.Before(t.Departure.Add(-settings.lead(t.Kind))) && now.Before(t.Departure):
+			a.sendTripReminder(ctx, settings, *t, now)
+			t.RemindedAt = now
+			sent++
+		default:
+			continue
+		}
+		if err := a.persistStore.UpdateTrip(*t); err != nil {
+			logger.Warn("[Agent] Failed to save trip %d: %v", t.ID, err)
+		}
+	}
+	a.ensureTripJob()
+	return fmt.Sprintf("sent %d trip reminder(s)", sent)
+}
+
+func (a *Agent) sendTripReminder(ctx context.Context, settings travelSettings, t persist.Trip, now time.Time) {
+	if a.notifier == nil || t.Platform == "" || t.ChannelID == "" {
+		return
+	}
+	icon, noun := "✈️", "航班"
+	if t.Kind == string(travel.Train) {
+		icon, noun = "🚄", "列车"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s 将在 %s后出发（%s）", icon, noun, t.Number, formatLeadTime(t.Departure.Sub(now)), t.Departure.Format("01-02 15:04"))
+	if route := tripRoute(t); route != "" {
+		sb.WriteString("\n" + route)
+	}
+	if t.Seat != "" {
+		sb.WriteString(" · 座位 " + t.Seat)
+	}
+
+	destination, arrival := t.Destination, t.Arrival
+	if src := settings.source(travel.Kind(t.Kind)); src != nil {
+		lookupCtx, cancel := context.WithTimeout(ctx, tripLookupTimeout)
+		status, err := src.Status(lookupCtx, t.Number, t.Departure)
+		cancel()
+		if err != nil {
+			logger.Warn("[Agent] Status lookup for trip %d failed: %v", t.ID, err)
+		} else {
+			sb.WriteString("\n状态：" + statusSummary(status))
+			if destination == "" {
+				destination = status.To
+			}
+		}
+	}
+	if destination != "" {
+		if arrival.IsZero() {
+			arrival = t.Departure
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, tripLookupTimeout)
+		weather, err := weatherAt(lookupCtx, weatherPlace(destination), arrival)
+		cancel()
+		if err != nil {
+			logger.Warn("[Agent] Weather for trip %d failed: %v", t.ID, err)
+		} else {
+			sb.WriteString("\n🌤 " + weather)
+		}
+	}
+	if err := a.notifier.NotifyChatUser(t.Platform, t.ChannelID, t.UserID, sb.String()); err != nil {
+		logger.Warn("[Agent] Failed to send reminder for trip %d: %v", t.ID, err)
+	}
+}
+
+// autoSaveItinerary saves the trips in a forwarded booking confirmation and
+// returns a note for the reply.
+func (a *Agent) autoSaveItinerary(msg router.Message) string {
+	if a.persistStore == nil || msg.Username == "cron" || !a.currentTravel().autoExtract || !travel.IsBooking(msg.Text) {
+		return ""
+	}
+	now := time.Now()
+	var upcoming []travel.Segment
+	for _, s := range travel.ParseItinerary(msg.Text, now) {
+		if s.Departure.After(now) {
+			upcoming = append(upcoming, s)
+		}
+	}
+	if len(upcoming) == 0 {
+		return ""
+	}
+	trips, err := a.saveTrips(timeUserID(msg), msg, upcoming)
+	if err != nil {
+		logger.Warn("[Agent] Failed to save itinerary: %v", err)
+		return ""
+	}
+	a.ensureTripJob()
+	numbers := make([]string, len(trips))
+	for i, t := range trips {
+		numbers[i] = t.Number + " " + t.Departure.Format("01-02 15:04")
+	}
+	return fmt.Sprintf("🧳 已记录行程 %s，出发前会提醒你并附上目的地天气。", strings.Join(numbers, "、"))
+}
+
+// ensureTripJob keeps one scheduled reminder checker while any trip is
+// active and removes it when none is.
+func (a *Agent) ensureTripJob() string {
+	if a.cronScheduler == nil {
+		return ""
+	}
+	trips, err := a.persistStore.Trips("", true)
+	if err != nil {
+		logger.Warn("[Agent] Failed to load trips: %v", err)
+		return ""
+	}
+	var existing []string
+	for _, job := range a.cronScheduler.ListJobsByTag(tripJobTag) {
+		if job.Name == tripJobName {
+			existing = append(existing, job.ID)
+		}
+	}
+	switch {
+	case len(trips) > 0 && len(existing) == 0:
+		if _, err := a.cronScheduler.AddJobWithTag(tripJobName, tripJobTag, tripJobSchedule, "itinerary", map[string]any{"action": "remind"}); err != nil {
+			logger.Warn("[Agent] Failed to schedule trip reminders: %v", err)
+			return fmt.Sprintf("Warning: trip reminders not set up: %v", err)
+		}
+	case len(trips) == 0:
+		for _, id := range existing {
+			if err := a.cronScheduler.RemoveJob(id); err != nil {
+				logger.Warn("[Agent] Failed to remove trip reminder job: %v", err)
+			}
+		}
+	}
+	return ""
+}
+
+func parseTripTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range remindAtLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+func formatTrip(t persist.Trip) string {
+	line := fmt.Sprintf("#%d %s %s", t.ID, t.Number, t.Departure.Format("01-02 15:04"))
+	if route := tripRoute(t); route != "" {
+		line += " " + route
+	}
+	if !t.Arrival.IsZero() {
+		line += "，" + t.Arrival.Format("15:04") + " 到达"
+	}
+	if t.Seat != "" {
+		line += "，座位 " + t.Seat
+	}
+	return line
+}
+
+func tripRoute(t persist.Trip) string {
+	switch {
+	case t.Origin != "" && t.Destination != "":
+		return t.Origin + " → " + t.Destination
+	case t.Origin != "":
+		return t.Origin + " 出发"
+	case t.Destination != "":
+		return "前往 " + t.Destination
+	}
+	return ""
+}
+
+func formatLeadTime(d time.Duration) string {
+	d = d.Round(10 * time.Minute)
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d 小时", int(d.Hours()))
+	case d >= time.Hour:
+		return fmt.Sprintf("%d 小时 %d 分钟", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%d 分钟", int(d.Minutes()))
+	}
+}
+
+// weatherPlace turns "Shanghai (PVG)" into the name wttr.in looks up.
+func weatherPlace(place string) string {
+	if i := strings.Index(place, " ("); i > 0 {
+		return place[:i]
+	}
+	return place
+}
+
+func formatTravelStatus(kind travel.Kind, s travel.Status) string {
+	var sb strings.Builder
+	sb.WriteString(s.Number)
+	if s.From != "" || s.To != "" {
+		sb.WriteString(" " + s.From + " → " + s.To)
+	}
+	sb.WriteString("\n状态：" + statusSummary(s))
+	verbDep, verbArr := "起飞", "到达"
+	if kind == travel.Train {
+		verbDep = "发车"
+	}
+	if line := timeLine(s.ScheduledDeparture, s.EstimatedDeparture, s.ActualDeparture, verbDep); line != "" {
+		sb.WriteString("\n" + line)
+	}
+	if line := timeLine(s.ScheduledArrival, s.EstimatedArrival, s.ActualArrival, verbArr); line != "" {
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+func statusSummary(s travel.Status) string {
+	name, ok := travelStateNames[s.State]
+	if !ok {
+		name = string(s.State)
+	}
+	if s.DelayMinutes > 0 && s.State != travel.StateArrived {
+		name += fmt.Sprintf("，晚点 %d 分钟", s.DelayMinutes)
+	}
+	if !s.EstimatedDeparture.IsZero() && s.ActualDeparture.IsZero() {
+		name += "，预计 " + s.EstimatedDeparture.Format("15:04") + " 出发"
+	}
+	var where []string
+	if s.Terminal != "" {
+		where = append(where, "航站楼 "+s.Terminal)
+	}
+	if s.Gate != "" {
+		where = append(where, "登机口/检票口 "+s.Gate)
+	}
+	if len(where) > 0 {
+		name += " · " + strings.Join(where, " ")
+	}
+	return name
+}
+
+// timeLine shows the scheduled time with the estimated or actual one;
+// times are the station's local time.
+func timeLine(scheduled, estimated, actual time.Time, verb string) string {
+	switch {
+	case !actual.IsZero():
+		return fmt.Sprintf("计划 %s，实际 %s %s", clock(scheduled), actual.Format("15:04"), verb)
+	case !estimated.IsZero() && !estimated.Equal(scheduled):
+		return fmt.Sprintf("计划 %s，预计 %s %s", clock(scheduled), estimated.Format("15:04"), verb)
+	case !scheduled.IsZero():
+		return fmt.Sprintf("计划 %s %s", scheduled.Format("15:04"), verb)
+	}
+	return ""
+}
+
+func clock(t time.Time) string {
+	if t.IsZero() {
+		return "--:--"
+	}
+	return t.Format("15:04")
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/travel"
+)
+
+type fakeTravelSource struct {
+	status travel.Status
+}
+
+func (f *fakeTravelSource) Name() string { return "fake" }
+
+func (f *fakeTravelSource) Status(ctx context.Context, number string, date time.Time) (travel.Status, error) {
+	s := f.status
+	s.Number = number
+	return s, nil
+}
+
+func TestItineraryRemindsBeforeDeparture(t *testing.T) {
+	oldWeather := weatherAt
+	var weatherPlaceAsked string
+	weatherAt = func(ctx context.Context, location string, at time.Time) (string, error) {
+		weatherPlaceAsked = location
+		return location + " 小雨 12°C", nil
+	}
+	defer func() { weatherAt = oldWeather }()
+
+	a, n := newFocusTestAgent(t)
+	a.travel = travelSettings{
+		flights:     &fakeTravelSource{status: travel.Status{State: travel.StateDelayed, DelayMinutes: 25, Gate: "C12"}},
+		flightLead:  3 * time.Hour,
+		trainLead:   time.Hour,
+		autoExtract: true,
+	}
+
+	day := time.Now().AddDate(0, 0, 3)
+	text := fmt.Sprintf("【携程旅行】您预订的%s CA1234 北京首都T3 08:00-上海虹桥T2 10:15 航班已出票，订单号 1234567890。", day.Format("2006-01-02"))
+	msg := router.Message{Platform: "telegram", ChannelID: "c1", UserID: "u1", Text: text}
+	if note := a.autoSaveItinerary(msg); !strings.Contains(note, "CA1234") {
+		t.Fatalf("note = %q", note)
+	}
+	if note := a.autoSaveItinerary(msg); !strings.Contains(note, "CA1234") {
+		t.Fatalf("second note = %q", note)
+	}
+	trips, err := a.persistStore.Trips("u1", true)
+	if err != nil || len(trips) != 1 {
+		t.Fatalf("trips = %+v, %v", trips, err)
+	}
+	departure := trips[0].Departure
+
+	ctx := testTurn()
+	a.remindTrips(ctx, departure.Add(-4*time.Hour))
+	if sent := n.messages(); len(sent) != 0 {
+		t.Fatalf("reminded too early: %q", sent)
+	}
+	a.remindTrips(ctx, departure.Add(-2*time.Hour))
+	a.remindTrips(ctx, departure.Add(-time.Hour))
+	sent := n.messages()
+	if len(sent) != 1 {
+		t.Fatalf("reminders = %q", sent)
+	}
+	for _, want := range []string{"CA1234 将在 2 小时后出发", "北京首都 → 上海虹桥", "延误，晚点 25 分钟", "C12", "上海虹桥 小雨 12°C"} {
+		if !strings.Contains(sent[0], want) {
+			t.Fatalf("reminder %q missing %q", sent[0], want)
+		}
+	}
+	if weatherPlaceAsked != "上海虹桥" {
+		t.Fatalf("weather asked for %q", weatherPlaceAsked)
+	}
+
+	a.remindTrips(ctx, departure.Add(3*time.Hour))
+	if list := a.executeItinerary(ctx, map[string]any{"action": "list"}); list != "没有即将出发的行程" {
+		t.Fatalf("finished trip still listed: %q", list)
+	}
+}
+
+func TestItineraryAddByNumber(t *testing.T) {
+	a, _ := newFocusTestAgent(t)
+	ctx := testTurn()
+
+	if got := a.executeItinerary(ctx, map[string]any{"number": "g1234"}); !strings.HasPrefix(got, "Error") {
+		t.Fatalf("missing departure: %q", got)
+	}
+	got := a.executeItinerary(ctx, map[string]any{"number": "g1234", "departure": "2030-05-01 08:00", "from": "上海虹桥", "to": "杭州东", "seat": "5车12F"})
+	if !strings.Contains(got, "#1 G1234 05-01 08:00 上海虹桥 → 杭州东，座位 5车12F") {
+		t.Fatalf("add: %q", got)
+	}
+	trip, err := a.persistStore.GetTrip(1)
+	if err != nil || trip == nil || trip.Kind != string(travel.Train) {
+		t.Fatalf("trip = %+v, %v", trip, err)
+	}
+	if got := a.executeItinerary(ctx, map[string]any{"action": "remove", "id": 1.0}); !strings.Contains(got, "已删除行程 #1") {
+		t.Fatalf("remove: %q", got)
+	}
+	if got := a.executeTravelStatus(ctx, travel.Train, map[string]any{"number": "G1234"}); !strings.Contains(got, "未配置") {
+		t.Fatalf("status without source: %q", got)
+	}
+}
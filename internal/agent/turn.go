@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/router"
+)
+
+// turn is the state of one handled message. It travels in the context
+// rather than on the Agent, so turns of different conversations running at
+// the same time never see each other's message.
+type turn struct {
+	msg         router.Message
+	cronCreated int             // cron_create calls so far in this turn
+	model       *ai.ModelConfig // answers in place of the primary model; nil for the router's pick
+}
+
+type turnKey struct{}
+
+// withTurn starts a turn for msg.
+func withTurn(ctx context.Context, msg router.Message) context.Context {
+	return context.WithValue(ctx, turnKey{}, &turn{msg: msg})
+}
+
+// turnOf returns the turn ctx belongs to. Work outside a turn, such as
+// cron tool jobs and REST tool calls, gets an empty one.
+func turnOf(ctx context.Context) *turn {
+	if t, ok := ctx.Value(turnKey{}).(*turn); ok {
+		return t
+	}
+	return &turn{}
+}
+
+// turnMessage returns the message being handled, or an empty message
+// outside a turn.
+func turnMessage(ctx context.Context) router.Message {
+	return turnOf(ctx).msg
+}
+
+// conversationLocks lets one turn per conversation run at a time.
+type conversationLocks struct {
+	mu    sync.Mutex
+	locks map[string]*conversationLock
+}
+
+type conversationLock struct {
+	ch   chan struct{}
+	refs int // turns holding or waiting for the lock
+}
+
+// lock waits until no other turn of the conversation runs, or ctx ends.
+func (l *conversationLocks) lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*conversationLock)
+	}
+	cl := l.locks[key]
+	if cl == nil {
+		cl = &conversationLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = cl
+	}
+	cl.refs++
+	l.mu.Unlock()
+
+	select {
+	case cl.ch <- struct{}{}:
+		return func() {
+			<-cl.ch
+			l.release(key, cl)
+		}, nil
+	case <-ctx.Done():
+		l.release(key, cl)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *conversationLocks) release(key string, cl *conversationLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cl.refs--
+	if cl.refs == 0 {
+		delete(l.locks, key)
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/ai"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestConversationLocksSerializeOneConversation(t *testing.T) {
+	var l conversationLocks
+	ctx := context.Background()
+
+	unlock, err := l.lock(ctx, "wecom:c1:u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another conversation is not held up.
+	other, err := l.lock(ctx, "wecom:c2:u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	acquired := make(chan func())
+	go func() {
+		next, _ := l.lock(ctx, "wecom:c1:u1")
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second turn of the conversation ran while the first held the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	(<-acquired)()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	held, _ := l.lock(ctx, "wecom:c1:u1")
+	if _, err := l.lock(waitCtx, "wecom:c1:u1"); err == nil {
+		t.Fatal("lock ignored the cancelled context")
+	}
+	held()
+	if len(l.locks) != 0 {
+		t.Fatalf("locks left behind: %v", l.locks)
+	}
+}
+
+func TestTurnsDoNotShareState(t *testing.T) {
+	first := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "c1", UserID: "u1"})
+	second := withTurn(context.Background(), router.Message{Platform: "telegram", ChannelID: "c2", UserID: "u2"})
+
+	turnOf(first).cronCreated = 1
+	if turnOf(second).cronCreated != 0 {
+		t.Fatal("cron_create count leaked into another turn")
+	}
+	if got := currentConversationKey(second); got != "telegram:c2:u2" {
+		t.Fatalf("conversation key = %q", got)
+	}
+	if currentConversationKey(context.Background()) != "" {
+		t.Fatal("work outside a turn has a conversation")
+	}
+}
+
+func TestTurnModelDoesNotSwitchOtherTurns(t *testing.T) {
+	a := &Agent{modelRouter: newHealthTestRouter(t)}
+	var backup *ai.ModelConfig
+	for _, m := range a.modelRouter.ListModels() {
+		if m.Name == "backup" {
+			backup = m
+		}
+	}
+	channel := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "c1", UserID: "u1"})
+	plain := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "c2", UserID: "u2"})
+	turnOf(channel).model = backup
+
+	if got := a.pickModel(ai.RolePrimary, turnOf(channel).model); got == nil || got.Name != "backup" {
+		t.Fatalf("channel turn answers with %v", got)
+	}
+	if got := a.pickModel(ai.RolePrimary, turnOf(plain).model); got == nil || got.Name != "main" {
+		t.Fatalf("other turn answers with %v", got)
+	}
+	if a.currentModelName() != "main" {
+		t.Fatalf("current model switched to %s", a.currentModelName())
+	}
+
+	// A turn model cooling down falls back like the primary model does.
+	for i := 0; i < 3; i++ {
+		a.modelRouter.RecordFailure(backup, errors.New("502 bad gateway"))
+	}
+	if got := a.pickModel(ai.RolePrimary, turnOf(channel).model); got == nil || got.Name != "main" {
+		t.Fatalf("cooling turn model still picked: %v", got)
+	}
+}
@@ -0,0 +1,366 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/tools"
+)
+
+const (
+	undoMaxSets        = 10             // action sets kept per conversation
+	undoTTL            = 24 * time.Hour // older action sets can no longer be undone
+	undoBackupMaxBytes = 4 << 20        // larger files are not backed up before a write
+)
+
+// irreversibleTools have side effects /undo cannot revert; they are listed
+// in its report so the user knows what is left to clean up by hand.
+var irreversibleTools = map[string]string{
+	"file_trash":          "已移到废纸篓，请从废纸篓手动恢复",
+	"shell_execute":       "命令执行无法撤销",
+	"remote_put":          "已上传到远程主机的文件需手动删除",
+	"file_share":          "分享链接在到期前一直有效",
+	"reminders_add":       "提醒事项需手动删除",
+	"notes_create":        "备忘录需手动删除",
+	"github_issue_create": "GitHub issue 需手动关闭",
+	"calendar_delete":     "已删除的日程无法恢复",
+	"cron_delete":         "已删除的定时任务需重新创建",
+	"message_send_to":     "已发出的消息无法撤回",
+	"broadcast":           "已排队的广播无法撤回",
+	"contacts_remove":     "已删除的联系人需重新添加",
+}
+
+var createdJobIDPattern = regexp.MustCompile(`(?m)^- ID: (\S+)`)
+
+// addedTaskIDPattern finds the task number in task_add's reply.
+var addedTaskIDPattern = regexp.MustCompile(`已添加任务 #(\d+)`)
+
+// undoAction is one side effect of a tool call. revert is nil for actions
+// that cannot be undone; note then says why.
+type undoAction struct {
+	what   string
+	note   string
+	revert func(ctx context.Context) (string, error)
+}
+
+// undoSet is what one turn changed.
+type undoSet struct {
+	request string
+	at      time.Time
+	actions []undoAction
+}
+
+// undoLog keeps the recent action sets of each conversation, newest last.
+type undoLog struct {
+	mu   sync.Mutex
+	sets map[string][]*undoSet
+}
+
+// begin opens the action set of a new turn.
+func (u *undoLog) begin(convKey, request string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.sets == nil {
+		u.sets = make(map[string][]*undoSet)
+	}
+	sets := u.sets[convKey]
+	if n := len(sets); n > 0 && len(sets[n-1].actions) == 0 {
+		sets = sets[:n-1]
+	}
+	sets = append(sets, &undoSet{request: request, at: time.Now()})
+	if len(sets) > undoMaxSets {
+		sets = sets[len(sets)-undoMaxSets:]
+	}
+	u.sets[convKey] = sets
+}
+
+// record adds action to the conversation's open action set.
+func (u *undoLog) record(convKey string, action undoAction) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	sets := u.sets[convKey]
+	if len(sets) == 0 {
+		return
+	}
+	last := sets[len(sets)-1]
+	last.actions = append(last.actions, action)
+}
+
+// pop removes and returns the most recent action set that changed anything.
+func (u *undoLog) pop(convKey string) *undoSet {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	sets := u.sets[convKey]
+	for len(sets) > 0 {
+		last := sets[len(sets)-1]
+		sets = sets[:len(sets)-1]
+		if len(last.actions) > 0 && time.Since(last.at) < undoTTL {
+			u.sets[convKey] = sets
+			return last
+		}
+	}
+	delete(u.sets, convKey)
+	return nil
+}
+
+// trackUndo prepares to record the side effect of a tool call. The returned
+// function takes the tool's result and records what it changed; it is nil
+// for tools without side effects.
+func (a *Agent) trackUndo(ctx context.Context, name string, args map[string]any) func(result string) {
+	convKey := currentConversationKey(ctx)
+	if convKey == "" {
+		return nil
+	}
+	record := func(action undoAction) {
+		a.undo.record(convKey, action)
+	}
+
+	switch name {
+	case "file_write":
+		return a.trackFileWrite(args, record)
+
+	case "file_edit":
+		if preview, _ := args["preview"].(bool); preview {
+			return nil
+		}
+		edit, err := prepareFileEdit(args)
+		if err != nil {
+			return nil
+		}
+		return a.trackFileWrite(map[string]any{"path": edit.path, "content": edit.new}, record)
+
+	case "file_write_batch":
+		var tracks []func(string)
+		for _, f := range batchFileArgs(args) {
+			if track := a.trackFileWrite(f, record); track != nil {
+				tracks = append(tracks, track)
+			}
+		}
+		return func(result string) {
+			for _, track := range tracks {
+				track(result)
+			}
+		}
+
+	case "calendar_create_event":
+		title := getString(args, "title")
+		start := getString(args, "start_time")
+		calendar := getString(args, "calendar")
+		return func(result string) {
+			if !strings.HasPrefix(result, "Created: ") {
+				return
+			}
+			date, _, _ := strings.Cut(start, " ")
+			record(undoAction{
+				what: fmt.Sprintf("日程「%s」（%s）", title, start),
+				revert: func(ctx context.Context) (string, error) {
+					out := executeCalendarDelete(ctx, map[string]any{"title": title, "calendar": calendar, "date": date})
+					if !strings.HasPrefix(out, "Deleted event") {
+						return "", fmt.Errorf("%s", out)
+					}
+					return "已删除", nil
+				},
+			})
+		}
+
+	case "task_add":
+		return func(result string) {
+			m := addedTaskIDPattern.FindStringSubmatch(result)
+			if m == nil {
+				return
+			}
+			id, _ := strconv.ParseInt(m[1], 10, 64)
+			record(undoAction{
+				what: "任务 #" + m[1],
+				revert: func(ctx context.Context) (string, error) {
+					if err := a.removeTask(id); err != nil {
+						return "", err
+					}
+					return "已删除", nil
+				},
+			})
+		}
+
+	case "db_query":
+		query := getString(args, "query")
+		if strings.TrimSpace(query) == "" || dbReadStatements[sqlStatementKind(query)] {
+			return nil
+		}
+		return func(result string) {
+			if looksLikeToolError(result) {
+				return
+			}
+			record(undoAction{what: "数据库写入（" + strings.ToUpper(sqlStatementKind(query)) + "）", note: "数据库中的改动需手动还原"})
+		}
+
+	case "http_request":
+		method := strings.ToUpper(strings.TrimSpace(getString(args, "method")))
+		if method == "" || method == "GET" || method == "HEAD" {
+			return nil
+		}
+		target := getString(args, "url")
+		return func(result string) {
+			if looksLikeToolError(result) {
+				return
+			}
+			record(undoAction{what: method + " " + target, note: "已发出的 HTTP 请求无法撤销"})
+		}
+
+	case "cron_create", "remind_once":
+		return func(result string) {
+			m := createdJobIDPattern.FindStringSubmatch(result)
+			if m == nil {
+				return
+			}
+			id := m[1]
+			what := "定时任务 " + id
+			if name == "remind_once" {
+				what = "一次性提醒 " + id
+			}
+			record(undoAction{
+				what: what,
+				revert: func(ctx context.Context) (string, error) {
+					out := a.executeCronDelete(map[string]any{"id": id})
+					if strings.HasPrefix(out, "Error") {
+						return "", fmt.Errorf("%s", strings.TrimPrefix(out, "Error: "))
+					}
+					return "已删除", nil
+				},
+			})
+		}
+	}
+
+	if note, ok := irreversibleTools[name]; ok {
+		return func(result string) {
+			if looksLikeToolError(result) {
+				return
+			}
+			record(undoAction{what: name, note: note})
+		}
+	}
+	return nil
+}
+
+// trackFileWrite backs up the file file_write is about to replace. The
+// restore is skipped when the file changed again after the write.
+func (a *Agent) trackFileWrite(args map[string]any, record func(undoAction)) func(string) {
+	path := getString(args, "path")
+	content, ok := args["content"].(string)
+	if path == "" || !ok {
+		return nil
+	}
+	abs, err := filepath.Abs(tools.ExpandTilde(path))
+	if err != nil {
+		return nil
+	}
+
+	var backup []byte
+	existed, tooLarge := false, false
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(abs); err == nil {
+		existed, mode = true, info.Mode().Perm()
+		if info.Size() > undoBackupMaxBytes {
+			tooLarge = true
+		} else if backup, err = os.ReadFile(abs); err != nil {
+			return nil
+		}
+	}
+
+	return func(string) {
+		// The write may have been held for approval or failed; only a file
+		// that now holds the new content was changed by this call.
+		written, err := os.ReadFile(abs)
+		if err != nil || !bytes.Equal(written, []byte(content)) {
+			return
+		}
+		if tooLarge {
+			record(undoAction{what: "文件 " + abs, note: "文件过大，写入前未备份"})
+			return
+		}
+		sum := sha256.Sum256(written)
+		record(undoAction{
+			what: "文件 " + abs,
+			revert: func(context.Context) (string, error) {
+				current, err := os.ReadFile(abs)
+				if err != nil && !os.IsNotExist(err) {
+					return "", err
+				}
+				if err != nil || sha256.Sum256(current) != sum {
+					return "", fmt.Errorf("此后又被修改，未还原")
+				}
+				if !existed {
+					if err := os.Remove(abs); err != nil {
+						return "", err
+					}
+					return "已删除（写入前不存在）", nil
+				}
+				if err := os.WriteFile(abs, backup, mode); err != nil {
+					return "", err
+				}
+				return "已还原为修改前的内容", nil
+			},
+		})
+	}
+}
+
+// undoLastActions reverts the most recent action set of a conversation,
+// newest action first, and reports what could and could not be undone.
+func (a *Agent) undoLastActions(ctx context.Context, convKey string) string {
+	set := a.undo.pop(convKey)
+	if set == nil {
+		return "没有可以撤销的操作（只保留 24 小时内最近 10 轮的文件写入、日程和定时任务）。"
+	}
+
+	var done, failed []string
+	for i := len(set.actions) - 1; i >= 0; i-- {
+		action := set.actions[i]
+		if action.revert == nil {
+			failed = append(failed, fmt.Sprintf("⚠️ %s：%s", action.what, action.note))
+			continue
+		}
+		outcome, err := action.revert(ctx)
+		if err != nil {
+			logger.Warn("[Agent] Undo of %s failed: %v", action.what, err)
+			failed = append(failed, fmt.Sprintf("⚠️ %s：%v", action.what, err))
+			continue
+		}
+		done = append(done, fmt.Sprintf("✅ %s：%s", action.what, outcome))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "撤销「%s」（%s）的操作：", feedbackExcerpt(set.request, 40), set.at.Format("01-02 15:04"))
+	for _, line := range done {
+		sb.WriteString("\n" + line)
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\n\n未能撤销：")
+		for _, line := range failed {
+			sb.WriteString("\n" + line)
+		}
+	}
+	report := sb.String()
+	logger.Info("[Agent] Undo for %s: %d reverted, %d not undone", convKey, len(done), len(failed))
+
+	// Tell the model, or it would keep assuming the changes are in place.
+	a.memory.AddExchange(convKey,
+		Message{Role: "user", Content: "/undo"},
+		Message{Role: "assistant", Content: report},
+	)
+	return report
+}
+
+func looksLikeToolError(result string) bool {
+	lower := strings.ToLower(strings.TrimSpace(result))
+	return strings.HasPrefix(lower, "error") || strings.HasPrefix(lower, "access denied") ||
+		strings.HasPrefix(lower, "pending approval") || strings.HasPrefix(lower, "command blocked")
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestUndoLastActions(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "notes.txt")
+	created := filepath.Join(dir, "new.txt")
+	touched := filepath.Join(dir, "touched.txt")
+	if err := os.WriteFile(existing, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{memory: NewMemory(store, 0)}
+	ctx := withTurn(context.Background(), router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1"})
+	convKey := currentConversationKey(ctx)
+	write := func(path, content string) {
+		input, _ := json.Marshal(map[string]string{"path": path, "content": content})
+		if out := a.executeTool(ctx, "file_write", input); !strings.HasPrefix(out, "Successfully") {
+			t.Fatalf("file_write: %s", out)
+		}
+	}
+
+	a.undo.begin(convKey, "整理笔记")
+	write(existing, "first")
+	write(existing, "second")
+	write(created, "hello")
+	write(touched, "v1")
+	a.trackUndo(ctx, "shell_execute", nil)("done")
+	if err := os.WriteFile(touched, []byte("edited by hand"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a.undo.begin(convKey, "/undo") // the command itself changes nothing
+
+	report := a.undoLastActions(ctx, convKey)
+	if data, _ := os.ReadFile(existing); string(data) != "original" {
+		t.Fatalf("existing file = %q", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("created file still exists: %v", err)
+	}
+	if data, _ := os.ReadFile(touched); string(data) != "edited by hand" {
+		t.Fatalf("file changed after the write was restored: %q", data)
+	}
+	for _, want := range []string{"撤销「整理笔记」", "✅ 文件 " + created + "：已删除", "未能撤销", "⚠️ 文件 " + touched + "：此后又被修改", "⚠️ shell_execute：命令执行无法撤销"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+
+	if report := a.undoLastActions(ctx, convKey); !strings.HasPrefix(report, "没有可以撤销的操作") {
+		t.Fatalf("second undo = %q", report)
+	}
+}
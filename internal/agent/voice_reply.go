@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+	"github.com/kayz/coco/internal/voice"
+)
+
+const (
+	defaultVoiceMaxChars = 300              // voice.tts.max_chars default
+	voiceReplyTimeout    = 60 * time.Second // synthesis and conversion together
+	voiceFileTTL         = time.Hour        // synthesized files are removed after this long
+)
+
+var defaultVoicePlatforms = []string{"wecom", "wechat"}
+
+// voiceFormats are the audio formats each platform's voice messages accept,
+// preferred first. WeCom only takes AMR.
+var voiceFormats = map[string][]string{
+	"wecom":  {"amr"},
+	"wechat": {"mp3", "amr"},
+}
+
+// voiceRequestPhrases ask for a spoken answer to a text message.
+var voiceRequestPhrases = []string{"用语音回复", "语音回复", "用语音说", "发语音", "用语音回答", "reply with voice", "voice reply"}
+
+var (
+	markdownLinkPattern   = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownSymbolPattern = regexp.MustCompile("[*_#>`~|]+")
+	bareURLPattern        = regexp.MustCompile(`https?://\S+`)
+)
+
+// applyVoice installs voice.tts. An invalid provider leaves replies text-only.
+func (a *Agent) applyVoice(cfg config.TTSConfig) {
+	var synth *voice.Synthesizer
+	if provider := strings.ToLower(strings.TrimSpace(cfg.Provider)); provider != "" {
+		apiKey := cfg.APIKey
+		if apiKey == "" && provider == "openai" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		s, err := voice.NewSynthesizer(voice.SynthesizerConfig{
+			Provider: provider,
+			APIKey:   apiKey,
+			Voice:    cfg.Voice,
+			Model:    cfg.Model,
+			Speed:    cfg.Speed,
+		})
+		if err != nil {
+			logger.Warn("[Agent] Voice replies disabled: %v", err)
+		} else {
+			synth = s
+		}
+	}
+	if len(cfg.Platforms) == 0 {
+		cfg.Platforms = defaultVoicePlatforms
+	}
+	if cfg.MaxChars <= 0 {
+		cfg.MaxChars = defaultVoiceMaxChars
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.synthesizer = synth
+	a.ttsConfig = cfg
+}
+
+// wantsVoiceReply reports whether msg should be answered with a voice
+// message: it was voice itself, or its text asks for one.
+func wantsVoiceReply(msg router.Message) bool {
+	if msg.Metadata["message_type"] == "voice" {
+		return true
+	}
+	lower := strings.ToLower(msg.Text)
+	for _, phrase := range voiceRequestPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// voiceReply synthesizes text as a voice message for msg's platform. It
+// returns nil when voice replies do not apply or synthesis fails; the text
+// reply is sent either way.
+func (a *Agent) voiceReply(ctx context.Context, msg router.Message, text string) []router.FileAttachment {
+	a.securityMu.RLock()
+	synth, cfg := a.synthesizer, a.ttsConfig
+	a.securityMu.RUnlock()
+	if synth == nil || !slices.Contains(cfg.Platforms, strings.ToLower(msg.Platform)) || !wantsVoiceReply(msg) {
+		return nil
+	}
+	spoken := speakableText(text)
+	if spoken == "" || len([]rune(spoken)) > cfg.MaxChars {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, voiceReplyTimeout)
+	defer cancel()
+	audio, format, err := synth.Synthesize(ctx, spoken)
+	if err != nil {
+		logger.Warn("[Agent] Voice reply failed (%s): %v", synth.ProviderName(), err)
+		return nil
+	}
+	if accepted := voiceFormats[strings.ToLower(msg.Platform)]; len(accepted) > 0 && !slices.Contains(accepted, format) {
+		if audio, err = voice.ConvertAudio(ctx, audio, format, accepted[0]); err != nil {
+			logger.Warn("[Agent] Voice reply conversion to %s failed: %v", accepted[0], err)
+			return nil
+		}
+		format = accepted[0]
+	}
+
+	path, err := writeVoiceFile(audio, format)
+	if err != nil {
+		logger.Warn("[Agent] Failed to store voice reply: %v", err)
+		return nil
+	}
+	logger.Info("[Agent] Voice reply for %s: %d bytes of %s", msg.Username, len(audio), format)
+	return []router.FileAttachment{{Path: path, Name: "reply." + format, MediaType: "voice"}}
+}
+
+// speakableText drops markdown and links that make no sense read aloud.
+func speakableText(text string) string {
+	text = markdownLinkPattern.ReplaceAllString(text, "$1")
+	text = bareURLPattern.ReplaceAllString(text, "")
+	text = markdownSymbolPattern.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// writeVoiceFile stores audio for the platform to upload, removing voice
+// files left from earlier replies.
+func writeVoiceFile(audio []byte, format string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "coco-voice")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > voiceFileTTL {
+				os.Remove(filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("reply-%d.%s", time.Now().UnixNano(), format))
+	return path, os.WriteFile(path, audio, 0o600)
+}
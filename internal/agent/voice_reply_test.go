@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestWantsVoiceReply(t *testing.T) {
+	for _, c := range []struct {
+		msg  router.Message
+		want bool
+	}{
+		{router.Message{Text: "明天几点开会", Metadata: map[string]string{"message_type": "voice"}}, true},
+		{router.Message{Text: "天气怎么样，用语音回复我"}, true},
+		{router.Message{Text: "Please reply with voice"}, true},
+		{router.Message{Text: "天气怎么样"}, false},
+	} {
+		if got := wantsVoiceReply(c.msg); got != c.want {
+			t.Errorf("wantsVoiceReply(%q) = %v", c.msg.Text, got)
+		}
+	}
+}
+
+func TestSpeakableText(t *testing.T) {
+	got := speakableText("## 今日天气\n- **晴**，详见 [预报](https://example.com/a) 或 https://example.com/b\n`25°C`")
+	if want := "今日天气 - 晴，详见 预报 或 25°C"; got != want {
+		t.Fatalf("speakableText = %q, want %q", got, want)
+	}
+}
+
+func TestVoiceReplyConfig(t *testing.T) {
+	a := &Agent{}
+	msg := router.Message{Platform: "wecom", Text: "用语音回复"}
+
+	a.applyVoice(config.TTSConfig{})
+	if files := a.voiceReply(context.Background(), msg, "好的"); files != nil {
+		t.Fatalf("voice reply without a provider: %#v", files)
+	}
+	a.applyVoice(config.TTSConfig{Provider: "piper"}) // no model: rejected
+	if a.synthesizer != nil {
+		t.Fatal("piper accepted without a model")
+	}
+	a.applyVoice(config.TTSConfig{Provider: "piper", Model: "zh.onnx", Platforms: []string{"wechat"}})
+	if a.synthesizer == nil || a.ttsConfig.MaxChars != defaultVoiceMaxChars {
+		t.Fatalf("config not applied: %#v", a.ttsConfig)
+	}
+	if files := a.voiceReply(context.Background(), msg, "好的"); files != nil {
+		t.Fatalf("voice reply on a platform not listed: %#v", files)
+	}
+}
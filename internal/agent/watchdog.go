@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/watchdog"
+)
+
+// Defaults for watchdog settings left unset in config.
+const (
+	defaultProviderStuckAfter = 3 * time.Minute
+	defaultToolStuckAfter     = 10 * time.Minute
+	defaultLoopThreshold      = 3
+	defaultMemoryLimitMB      = 1024
+)
+
+// watchdogSettings is what the agent needs from config.WatchdogConfig at runtime.
+type watchdogSettings struct {
+	loopThreshold int
+	autoRestart   bool
+	notifyTo      []string // platform, channelID, userID
+}
+
+// initWatchdog starts self-monitoring unless watchdog.enabled is false.
+func (a *Agent) initWatchdog(cfg config.WatchdogConfig) {
+	if cfg.Enabled != nil && !*cfg.Enabled {
+		return
+	}
+	providerTimeout := parseWatchdogDuration(cfg.ProviderTimeout, defaultProviderStuckAfter)
+	toolTimeout := parseWatchdogDuration(cfg.ToolTimeout, defaultToolStuckAfter)
+	memoryLimitMB := cfg.MemoryLimitMB
+	if memoryLimitMB <= 0 {
+		memoryLimitMB = defaultMemoryLimitMB
+	}
+
+	a.watchdogCfg = watchdogSettings{loopThreshold: cfg.LoopThreshold, autoRestart: cfg.AutoRestart}
+	if a.watchdogCfg.loopThreshold <= 0 {
+		a.watchdogCfg.loopThreshold = defaultLoopThreshold
+	}
+	if parts := strings.SplitN(cfg.NotifyTo, ":", 3); len(parts) == 3 {
+		a.watchdogCfg.notifyTo = parts
+	} else if cfg.NotifyTo != "" {
+		logger.Warn("[Watchdog] notify_to must be platform:channel_id:user_id, got %q", cfg.NotifyTo)
+	}
+
+	dumpDir := datadir.Path(".coco", "diagnostics")
+	a.watchdog = watchdog.New(watchdog.Config{
+		Timeouts: map[string]time.Duration{
+			"provider": providerTimeout,
+			"tool":     toolTimeout,
+		},
+		MemoryLimit: uint64(memoryLimitMB) << 20,
+		DumpDir:     dumpDir,
+	}, a.handleWatchdogIncident)
+	a.watchdog.Start()
+	logger.Info("[Watchdog] Started (provider %s, tool %s, memory %dMB, auto restart %v)",
+		providerTimeout, toolTimeout, memoryLimitMB, cfg.AutoRestart)
+}
+
+func parseWatchdogDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Warn("[Watchdog] Invalid duration %q, using %s", value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// SetNotifier sets where the agent sends alerts that are not replies, such as
+// watchdog incidents.
+func (a *Agent) SetNotifier(n cronpkg.ChatNotifier) {
+	a.notifier = n
+}
+
+// handleWatchdogIncident recovers the affected subsystem if configured and
+// tells the owner what happened.
+func (a *Agent) handleWatchdogIncident(inc watchdog.Incident) {
+	logger.Warn("[Watchdog] %s in %s: %s (bundle: %s)", inc.Kind, inc.Subsystem, inc.Detail, inc.Bundle)
+
+	action := "未自动处理（watchdog.auto_restart 未开启）"
+	if a.watchdogCfg.autoRestart {
+		action = a.recoverFromIncident(inc)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("⚠️ 看门狗检测到异常：")
+	switch inc.Kind {
+	case watchdog.KindStuck:
+		fmt.Fprintf(&sb, "%s 调用 %s 已运行 %s 未返回", inc.Subsystem, inc.Detail, inc.Duration)
+	case watchdog.KindLoop:
+		fmt.Fprintf(&sb, "工具调用疑似死循环，%s", inc.Detail)
+	case watchdog.KindMemory:
+		fmt.Fprintf(&sb, "内存占用过高，%s", inc.Detail)
+	default:
+		sb.WriteString(inc.Detail)
+	}
+	if inc.Bundle != "" {
+		fmt.Fprintf(&sb, "\n诊断包：%s", inc.Bundle)
+	}
+	fmt.Fprintf(&sb, "\n处理：%s", action)
+	a.notifyOwner(inc.Conversation, sb.String())
+}
+
+// recoverFromIncident resets whatever the incident points at and describes what was done.
+func (a *Agent) recoverFromIncident(inc watchdog.Incident) string {
+	switch inc.Kind {
+	case watchdog.KindStuck:
+		if inc.Cancel != nil {
+			inc.Cancel()
+		}
+		if inc.Subsystem == "provider" {
+			a.resetProviders()
+			return "已取消该调用并重建模型客户端"
+		}
+		if strings.HasPrefix(inc.Detail, "browser_") {
+			callToolDirect(context.Background(), "browser_stop", map[string]any{})
+			return "已取消该调用并关闭浏览器，下次使用时会重新启动"
+		}
+		return "已取消该调用"
+	case watchdog.KindLoop:
+		return "已中止本轮工具调用"
+	case watchdog.KindMemory:
+		a.resetProviders()
+		debug.FreeOSMemory()
+		return "已重建模型客户端并回收内存"
+	}
+	return "无"
+}
+
+// resetProviders drops cached provider clients so the next call builds fresh ones.
+func (a *Agent) resetProviders() {
+	a.providerMu.Lock()
+	a.providerCache = make(map[string]Provider)
+	a.providerMu.Unlock()
+}
+
+// notifyOwner sends text to watchdog.notify_to, falling back to the given
+// conversation ("platform:channel:user") and finally the log.
+func (a *Agent) notifyOwner(conversation, text string) {
+	target := a.watchdogCfg.notifyTo
+	if len(target) != 3 && conversation != "" {
+		target = strings.SplitN(conversation, ":", 3)
+	}
+	if a.notifier == nil || len(target) != 3 {
+		logger.Warn("[Watchdog] %s", text)
+		return
+	}
+	if err := a.notifier.NotifyChatUser(target[0], target[1], target[2], text); err != nil {
+		logger.Warn("[Watchdog] Failed to notify %s: %v", strings.Join(target, ":"), err)
+		logger.Warn("[Watchdog] %s", text)
+	}
+}
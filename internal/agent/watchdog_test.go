@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/watchdog"
+)
+
+type recordingNotifier struct {
+	targets  []string
+	messages []string
+}
+
+func (n *recordingNotifier) NotifyChat(message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyChatUser(platform, channelID, userID, message string) error {
+	n.targets = append(n.targets, ConversationKey(platform, channelID, userID))
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func TestWatchdogIncidentRestartsProviderAndNotifiesConversation(t *testing.T) {
+	notifier := &recordingNotifier{}
+	a := &Agent{
+		providerCache: map[string]Provider{"deepseek:chat:key": nil},
+		watchdogCfg:   watchdogSettings{autoRestart: true},
+		notifier:      notifier,
+	}
+
+	cancelled := false
+	a.handleWatchdogIncident(watchdog.Incident{
+		Kind:         watchdog.KindStuck,
+		Subsystem:    "provider",
+		Detail:       "deepseek-chat",
+		Conversation: "wecom:c1:u1",
+		Bundle:       "/tmp/bundle",
+		Cancel:       func() { cancelled = true },
+	})
+
+	if !cancelled {
+		t.Fatalf("stuck call should be cancelled")
+	}
+	if len(a.providerCache) != 0 {
+		t.Fatalf("provider cache should be reset")
+	}
+	if len(notifier.targets) != 1 || notifier.targets[0] != "wecom:c1:u1" {
+		t.Fatalf("owner should be notified in the conversation, got %v", notifier.targets)
+	}
+	if !strings.Contains(notifier.messages[0], "/tmp/bundle") || !strings.Contains(notifier.messages[0], "重建模型客户端") {
+		t.Fatalf("unexpected alert: %s", notifier.messages[0])
+	}
+}
+
+func TestWatchdogNotifyToOverridesConversation(t *testing.T) {
+	notifier := &recordingNotifier{}
+	a := &Agent{
+		watchdogCfg: watchdogSettings{notifyTo: []string{"slack", "ops", "admin"}},
+		notifier:    notifier,
+	}
+	cancelled := false
+	a.handleWatchdogIncident(watchdog.Incident{
+		Kind:         watchdog.KindStuck,
+		Subsystem:    "tool",
+		Detail:       "shell_execute",
+		Conversation: "wecom:c1:u1",
+		Cancel:       func() { cancelled = true },
+	})
+	if cancelled {
+		t.Fatalf("without auto_restart the call must be left alone")
+	}
+	if len(notifier.targets) != 1 || notifier.targets[0] != "slack:ops:admin" {
+		t.Fatalf("expected notify_to target, got %v", notifier.targets)
+	}
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/dialog"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/router"
+)
+
+// intentWorkflow is a compiled intents.workflows entry.
+type intentWorkflow struct {
+	config.WorkflowConfig
+	keywords []string
+	regexps  []*regexp.Regexp
+	form     dialog.Form // collects the slots
+}
+
+// applyIntents installs the intents section of the config.
+func (a *Agent) applyIntents(cfg config.IntentsConfig) {
+	var flows []*intentWorkflow
+	for _, wc := range cfg.Workflows {
+		if strings.TrimSpace(wc.Name) == "" || strings.TrimSpace(wc.Template) == "" && wc.Tool == "" {
+			logger.Warn("[Agent] Skipping workflow %q: name and template (or tool) are required", wc.Name)
+			continue
+		}
+		flow := &intentWorkflow{WorkflowConfig: wc}
+		for _, t := range wc.Triggers {
+			t = strings.TrimSpace(t)
+			if len(t) > 2 && strings.HasPrefix(t, "/") && strings.HasSuffix(t, "/") {
+				re, err := regexp.Compile(t[1 : len(t)-1])
+				if err != nil {
+					logger.Warn("[Agent] Workflow %s: invalid trigger %s: %v", wc.Name, t, err)
+					continue
+				}
+				flow.regexps = append(flow.regexps, re)
+			} else if t != "" {
+				flow.keywords = append(flow.keywords, strings.ToLower(t))
+			}
+		}
+		flow.form = dialog.Form{Title: wc.Name, Confirm: wc.Confirm}
+		for _, slot := range wc.Slots {
+			flow.form.Fields = append(flow.form.Fields, dialog.Field{
+				Name:     slot.Name,
+				Label:    slot.Label,
+				Prompt:   slot.Prompt,
+				Type:     slot.Type,
+				Choices:  slot.Choices,
+				Pattern:  slot.Pattern,
+				Optional: slot.Optional,
+			})
+		}
+		if _, err := dialog.New(flow.form); err != nil {
+			logger.Warn("[Agent] Skipping workflow %s: %v", wc.Name, err)
+			continue
+		}
+		flows = append(flows, flow)
+	}
+
+	a.securityMu.Lock()
+	defer a.securityMu.Unlock()
+	a.intentWorkflows = flows
+	a.intentClassifier = strings.ToLower(strings.TrimSpace(cfg.Classifier))
+}
+
+// routeIntent answers msg with a workflow when it starts one. Everything
+// else falls through to free-form generation.
+func (a *Agent) routeIntent(ctx context.Context, msg router.Message) (router.Response, bool) {
+	flow, values := a.classifyIntent(ctx, msg.Text)
+	if flow == nil {
+		return router.Response{}, false
+	}
+	logger.Info("[Agent] Intent %s recognized for %s, starting workflow", flow.Name, msg.Username)
+	dlg, err := dialog.New(flow.form)
+	if err != nil {
+		return router.Response{}, false
+	}
+	// Take what the request already says: values the classifier extracted,
+	// or matches of slot patterns in the message itself.
+	dlg.Prefill(values)
+	dlg.Extract(msg.Text)
+
+	s := &dialogSession{dlg: dlg, finish: func(ctx context.Context, values map[string]string) string {
+		return a.finishWorkflow(ctx, flow, msg.Username, values)
+	}}
+	reply, status := dlg.Start()
+	convKey := conversationKeyOf(msg)
+	return router.Response{Text: a.settleDialog(ctx, convKey, msg, s, reply, status)}, true
+}
+
+// finishWorkflow renders the workflow's reply from its slot values, running
+// its tool first when it has one.
+func (a *Agent) finishWorkflow(ctx context.Context, flow *intentWorkflow, username string, values map[string]string) string {
+	vars := map[string]string{"user": username, "date": time.Now().Format("2006-01-02")}
+	for k, v := range values {
+		vars[k] = v
+	}
+	reply := renderWorkflowTemplate(flow.Template, vars)
+	if flow.Tool != "" {
+		args := make(map[string]string, len(flow.Args))
+		for k, v := range flow.Args {
+			args[k] = renderWorkflowTemplate(v, vars)
+		}
+		input, _ := json.Marshal(args)
+		result := strings.TrimSpace(redactSecretValues(a.executeTool(ctx, flow.Tool, input)))
+		if strings.Contains(reply, "{{result}}") {
+			reply = strings.ReplaceAll(reply, "{{result}}", result)
+		} else {
+			reply = strings.TrimSpace(reply + "\n\n" + result)
+		}
+	}
+	return reply
+}
+
+// classifyIntent picks the workflow for text, with any slot values the
+// classifier already extracted.
+func (a *Agent) classifyIntent(ctx context.Context, text string) (*intentWorkflow, map[string]string) {
+	a.securityMu.RLock()
+	flows, classifier := a.intentWorkflows, a.intentClassifier
+	a.securityMu.RUnlock()
+	if len(flows) == 0 || strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	if classifier == "model" && a.modelRouter != nil {
+		flow, values, err := a.classifyIntentWithModel(ctx, text, flows)
+		if err == nil {
+			return flow, values
+		}
+		logger.Warn("[Agent] Intent classifier failed, falling back to keywords: %v", err)
+	}
+
+	lower := strings.ToLower(text)
+	for _, flow := range flows {
+		for _, kw := range flow.keywords {
+			if strings.Contains(lower, kw) {
+				return flow, nil
+			}
+		}
+		for _, re := range flow.regexps {
+			if re.MatchString(text) {
+				return flow, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (a *Agent) classifyIntentWithModel(ctx context.Context, text string, flows []*intentWorkflow) (*intentWorkflow, map[string]string, error) {
+	var sb strings.Builder
+	sb.WriteString(`Classify the user's message into one of these intents, or "none".
+Output STRICT JSON only: {"intent": "<name or none>", "slots": {"<slot>": "<value>"}}.
+Only fill slots whose values the message states explicitly.
+
+Intents:
+`)
+	for _, flow := range flows {
+		fmt.Fprintf(&sb, "- %s", flow.Name)
+		if len(flow.Triggers) > 0 {
+			fmt.Fprintf(&sb, " (keywords: %s)", strings.Join(flow.Triggers, ", "))
+		}
+		sb.WriteString("\n")
+		for _, ex := range flow.Examples {
+			fmt.Fprintf(&sb, "  example: %s\n", ex)
+		}
+		for _, slot := range flow.Slots {
+			fmt.Fprintf(&sb, "  slot %s: %s\n", slot.Name, slot.Prompt)
+		}
+	}
+
+	resp, err := a.chatWithModel(ctx, ChatRequest{
+		Messages:     []Message{{Role: "user", Content: text}},
+		SystemPrompt: sb.String(),
+		MaxTokens:    300,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	payload := extractJSONObject(strings.TrimSpace(resp.Content))
+	if payload == "" {
+		return nil, nil, fmt.Errorf("classifier returned non-json content")
+	}
+	var result struct {
+		Intent string            `json:"intent"`
+		Slots  map[string]string `json:"slots"`
+	}
+	if err := json.Unmarshal([]byte(payload), &result); err != nil {
+		return nil, nil, fmt.Errorf("parse classifier output: %w", err)
+	}
+	for _, flow := range flows {
+		if strings.EqualFold(flow.Name, strings.TrimSpace(result.Intent)) {
+			return flow, result.Slots, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func renderWorkflowTemplate(tmpl string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
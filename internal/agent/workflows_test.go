@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/persist"
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestIntentWorkflow(t *testing.T) {
+	store, err := persist.NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	a := &Agent{memory: NewMemory(store, 0)}
+	a.applyIntents(config.IntentsConfig{Workflows: []config.WorkflowConfig{
+		{
+			Name:     "报销",
+			Triggers: []string{"报销", "/reimburs(e|ement)/"},
+			Slots: []config.WorkflowSlotConfig{
+				{Name: "amount", Prompt: "报销金额是多少？", Pattern: `(\d+(?:\.\d+)?)\s*元?`},
+				{Name: "reason", Prompt: "报销事由？"},
+				{Name: "note", Prompt: "备注？", Optional: true},
+			},
+			Template: "报销单\n申请人：{{user}}\n金额：{{amount}} 元\n事由：{{reason}}\n备注：{{note}}",
+		},
+		{Name: "broken", Triggers: []string{"broken"}, Template: "x", Slots: []config.WorkflowSlotConfig{{Name: "s", Pattern: "("}}},
+	}})
+
+	ctx := context.Background()
+	msg := router.Message{Platform: "wecom", ChannelID: "dm", UserID: "u1", Username: "小王"}
+	say := func(text string) (string, bool) {
+		msg.Text = text
+		if resp, handled := a.continueDialog(ctx, msg); handled {
+			return resp.Text, true
+		}
+		resp, handled := a.routeIntent(ctx, msg)
+		return resp.Text, handled
+	}
+
+	if _, handled := say("今天天气怎么样"); handled {
+		t.Fatal("unrelated message routed to a workflow")
+	}
+	if _, handled := say("broken"); handled {
+		t.Fatal("workflow with an invalid slot pattern was installed")
+	}
+	// The amount is taken from the request itself.
+	if got, _ := say("我要报销 128.5 元"); got != "报销事由？" {
+		t.Fatalf("first question = %q", got)
+	}
+	if got, _ := say("出差打车"); !strings.HasPrefix(got, "备注？") {
+		t.Fatalf("optional slot question = %q", got)
+	}
+	got, handled := say("跳过")
+	if !handled || got != "报销单\n申请人：小王\n金额：128.5 元\n事由：出差打车\n备注：" {
+		t.Fatalf("result = %q", got)
+	}
+	if _, handled := say("谢谢"); handled {
+		t.Fatal("finished workflow still intercepts messages")
+	}
+
+	// A reply that does not fit the slot is asked again; abort words end it.
+	say("I need to be reimbursed")
+	if got, _ := say("a lot"); !strings.Contains(got, "报销金额是多少？") || !strings.HasPrefix(got, "没能识别") {
+		t.Fatalf("re-ask = %q", got)
+	}
+	if got, _ := say("取消"); got != "已取消「报销」。" {
+		t.Fatalf("abort = %q", got)
+	}
+	if history := a.memory.GetHistory(ConversationKey("wecom", "dm", "u1")); len(history) != 2 {
+		t.Fatalf("history = %#v", history)
+	}
+}
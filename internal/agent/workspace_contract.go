@@ -181,15 +181,18 @@ checks:
 	},
 }
 
-func ensureWorkspaceContractFiles() error {
+// ensureWorkspaceContractFiles creates missing workspace files from templates
+// and returns the names it created.
+func ensureWorkspaceContractFiles() ([]string, error) {
 	workspaceDir := strings.TrimSpace(getWorkspaceDir())
 	if workspaceDir == "" {
-		return fmt.Errorf("workspace directory is empty")
+		return nil, fmt.Errorf("workspace directory is empty")
 	}
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
-		return fmt.Errorf("create workspace dir: %w", err)
+		return nil, fmt.Errorf("create workspace dir: %w", err)
 	}
 
+	var created []string
 	for _, file := range workspaceTemplateFiles {
 		target := filepath.Join(workspaceDir, file.name)
 		if _, err := os.Stat(target); err == nil {
@@ -197,9 +200,11 @@ func ensureWorkspaceContractFiles() error {
 		}
 		if err := os.WriteFile(target, []byte(file.content), 0644); err != nil {
 			if file.required {
-				return fmt.Errorf("create required workspace file %s: %w", file.name, err)
+				return created, fmt.Errorf("create required workspace file %s: %w", file.name, err)
 			}
+			continue
 		}
+		created = append(created, file.name)
 	}
-	return nil
+	return created, nil
 }
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/logger"
+)
+
+// workspaceHistoryGitDir is the git directory used for workspace versioning.
+// A separate git dir keeps history out of any repository the workspace already lives in.
+const workspaceHistoryGitDir = ".coco/workspace-history.git"
+
+// workspaceVersioner keeps persona and memory files under an automatic local git history.
+type workspaceVersioner struct {
+	workDir string
+	gitDir  string
+	mu      sync.Mutex
+}
+
+func newWorkspaceVersioner(enabled bool) *workspaceVersioner {
+	if !enabled {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		logger.Warn("[Agent] Workspace git versioning disabled: git not found in PATH")
+		return nil
+	}
+	workDir := getWorkspaceDir()
+	return &workspaceVersioner{
+		workDir: workDir,
+		gitDir:  filepath.Join(workDir, workspaceHistoryGitDir),
+	}
+}
+
+func (v *workspaceVersioner) git(ctx context.Context, args ...string) (string, error) {
+	base := []string{
+		"--git-dir=" + v.gitDir,
+		"--work-tree=" + v.workDir,
+		"-c", "user.name=coco",
+		"-c", "user.email=coco@localhost",
+		"-c", "core.autocrlf=false",
+	}
+	cmd := exec.CommandContext(ctx, "git", append(base, args...)...)
+	cmd.Dir = v.workDir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if err != nil {
+		return out.String(), fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+func (v *workspaceVersioner) ensureRepo(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(v.gitDir, "HEAD")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(v.gitDir), 0755); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "git", "--git-dir="+v.gitDir, "--work-tree="+v.workDir, "init", "--quiet")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git init: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// trackedFiles returns workspace-relative paths of persona files and any extra paths inside the workspace.
+func (v *workspaceVersioner) trackedFiles(extra ...string) []string {
+	seen := map[string]bool{}
+	var files []string
+	add := func(rel string) {
+		rel = filepath.ToSlash(filepath.Clean(rel))
+		if rel == "" || rel == "." || strings.HasPrefix(rel, "../") || seen[rel] {
+			return
+		}
+		if _, err := os.Stat(filepath.Join(v.workDir, rel)); err != nil {
+			return
+		}
+		seen[rel] = true
+		files = append(files, rel)
+	}
+	for _, f := range workspaceTemplateFiles {
+		add(f.name)
+	}
+	for _, p := range defaultCoreMemoryFiles {
+		add(p)
+	}
+	for _, p := range extra {
+		if rel, ok := v.relPath(p); ok {
+			add(rel)
+		}
+	}
+	return files
+}
+
+// relPath converts a path to workspace-relative form; ok is false for paths outside the workspace.
+func (v *workspaceVersioner) relPath(path string) (string, bool) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(v.workDir, path)
+	}
+	rel, err := filepath.Rel(v.workDir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Commit records the current state of tracked workspace files. It is a no-op when nothing changed.
+func (v *workspaceVersioner) Commit(reason string, extraPaths ...string) error {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := v.ensureRepo(ctx); err != nil {
+		return err
+	}
+	files := v.trackedFiles(extraPaths...)
+	if len(files) == 0 {
+		return nil
+	}
+	if _, err := v.git(ctx, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	status, err := v.git(ctx, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil
+	}
+	msg := strings.TrimSpace(reason)
+	if msg == "" {
+		msg = "workspace update"
+	}
+	_, err = v.git(ctx, "commit", "--quiet", "-m", msg)
+	return err
+}
+
+// History returns the recent diffs of a workspace file.
+func (v *workspaceVersioner) History(path string, limit int) (string, error) {
+	rel, ok := v.relPath(path)
+	if !ok {
+		return "", fmt.Errorf("path is outside workspace: %s", path)
+	}
+	if limit <= 0 {
+		limit = 3
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := v.ensureRepo(ctx); err != nil {
+		return "", err
+	}
+	out, err := v.git(ctx, "log", fmt.Sprintf("-n%d", limit), "-p", "--date=format:%Y-%m-%d %H:%M",
+		"--format=commit %h  %ad  %s", "--", rel)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Revert restores a workspace file to the version before its latest recorded change and commits the result.
+func (v *workspaceVersioner) Revert(path string) (string, error) {
+	rel, ok := v.relPath(path)
+	if !ok {
+		return "", fmt.Errorf("path is outside workspace: %s", path)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := v.ensureRepo(ctx); err != nil {
+		return "", err
+	}
+	out, err := v.git(ctx, "log", "-n2", "--format=%H", "--", rel)
+	if err != nil {
+		return "", err
+	}
+	hashes := strings.Fields(out)
+	if len(hashes) < 2 {
+		return "", fmt.Errorf("no earlier version of %s to revert to", rel)
+	}
+	target := hashes[1]
+	if _, err := v.git(ctx, "checkout", target, "--", rel); err != nil {
+		return "", err
+	}
+	if _, err := v.git(ctx, "commit", "--quiet", "-m", fmt.Sprintf("revert %s to %s", rel, target[:7])); err != nil {
+		return "", err
+	}
+	return target[:7], nil
+}
+
+// recordWorkspaceWrite commits workspace history after a successful agent write tool.
+func (a *Agent) recordWorkspaceWrite(ctx context.Context, toolName string, args map[string]any, result string) {
+	git := a.versioner()
+	if git == nil || strings.HasPrefix(result, "Error") || strings.HasPrefix(result, "ACCESS DENIED") {
+		return
+	}
+	var extra []string
+	if p, ok := args["path"].(string); ok {
+		extra = append(extra, resolveBestEffortPath(p))
+	}
+	if err := git.Commit(fmt.Sprintf("%s by %s", toolName, turnMessage(ctx).Username), extra...); err != nil {
+		logger.Warn("[Agent] Workspace history commit failed: %v", err)
+	}
+}
+
+// handleHistoryCommand serves "/history <file>" and "/revert <file>".
+func (a *Agent) handleHistoryCommand(text string) (string, bool) {
+	git := a.versioner()
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	cmd := strings.ToLower(fields[0])
+	if cmd != "/history" && cmd != "/revert" {
+		return "", false
+	}
+	if git == nil {
+		return "工作区版本记录未开启（在 .coco.yaml 中设置 memory.git_versioning: true）", true
+	}
+	if len(fields) < 2 {
+		return fmt.Sprintf("用法: %s <文件名>，例如 %s SOUL.md", cmd, cmd), true
+	}
+	file := strings.Join(fields[1:], " ")
+
+	if cmd == "/revert" {
+		hash, err := git.Revert(file)
+		if err != nil {
+			return fmt.Sprintf("回滚失败: %v", err), true
+		}
+		return fmt.Sprintf("已将 %s 回滚到版本 %s", file, hash), true
+	}
+
+	history, err := git.History(file, 3)
+	if err != nil {
+		return fmt.Sprintf("读取历史失败: %v", err), true
+	}
+	if history == "" {
+		return fmt.Sprintf("%s 暂无历史记录", file), true
+	}
+	if r := []rune(history); len(r) > 3500 {
+		history = string(r[:3500]) + "\n... (truncated)"
+	}
+	return fmt.Sprintf("%s 最近修改:\n\n%s\n\n使用 /revert %s 撤销最近一次修改", file, history, file), true
+}
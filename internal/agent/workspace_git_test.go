@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceVersionerCommitHistoryRevert(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmp := t.TempDir()
+	t.Setenv("COCO_WORKSPACE_DIR", tmp)
+
+	soul := filepath.Join(tmp, "SOUL.md")
+	if err := os.WriteFile(soul, []byte("# SOUL\n\n- original\n"), 0644); err != nil {
+		t.Fatalf("write soul: %v", err)
+	}
+
+	v := newWorkspaceVersioner(true)
+	if err := v.Commit("baseline"); err != nil {
+		t.Fatalf("baseline commit: %v", err)
+	}
+
+	if err := os.WriteFile(soul, []byte("# SOUL\n\n- bad agent edit\n"), 0644); err != nil {
+		t.Fatalf("edit soul: %v", err)
+	}
+	if err := v.Commit("file_write by test"); err != nil {
+		t.Fatalf("edit commit: %v", err)
+	}
+
+	history, err := v.History("SOUL.md", 3)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if !strings.Contains(history, "+- bad agent edit") || !strings.Contains(history, "file_write by test") {
+		t.Fatalf("unexpected history:\n%s", history)
+	}
+
+	if _, err := v.Revert("SOUL.md"); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	data, err := os.ReadFile(soul)
+	if err != nil {
+		t.Fatalf("read soul: %v", err)
+	}
+	if !strings.Contains(string(data), "original") {
+		t.Fatalf("revert did not restore content: %q", string(data))
+	}
+}
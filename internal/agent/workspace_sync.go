@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/config"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/objstore"
+	"github.com/kayz/coco/internal/wsync"
+)
+
+// workspaceSyncJobTag selects which cron jobs are replicated across devices.
+const workspaceSyncJobTag = "user-schedule"
+
+// NewWorkspaceSyncEngine builds the cross-device sync engine from config.
+// jobs may be nil to sync files only.
+func NewWorkspaceSyncEngine(cfg *config.Config, jobs wsync.JobStore) (*wsync.Engine, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is required")
+	}
+	sc := cfg.Sync
+	backend := strings.ToLower(strings.TrimSpace(sc.Backend))
+	if backend == "" {
+		backend = "keeper"
+	}
+	endpoint := strings.TrimSpace(sc.Endpoint)
+	token, namespace := "", ""
+	if backend == "keeper" {
+		if endpoint == "" {
+			endpoint = inferKeeperBaseURLForCron(cfg.Relay.WebhookURL, cfg.Relay.ServerURL)
+		}
+		token = strings.TrimSpace(cfg.Relay.Token)
+		if strings.TrimSpace(sc.Passphrase) == "" {
+			return nil, fmt.Errorf("sync passphrase is required")
+		}
+		ns, err := wsync.Namespace(sc.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("sync namespace: %w", err)
+		}
+		namespace = ns
+	}
+	prefix := strings.TrimSpace(sc.Prefix)
+	if prefix == "" {
+		prefix = "coco-sync"
+	}
+
+	store, err := objstore.New(objstore.Config{
+		Type:      backend,
+		Endpoint:  endpoint,
+		Bucket:    sc.Bucket,
+		Region:    sc.Region,
+		AccessKey: sc.AccessKey,
+		SecretKey: sc.SecretKey,
+		Username:  sc.Username,
+		Password:  sc.Password,
+		Token:     token,
+		Namespace: namespace,
+		Prefix:    prefix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sync backend: %w", err)
+	}
+
+	opts := wsync.Options{
+		Store:      store,
+		WorkDir:    getWorkspaceDir(),
+		Files:      workspaceSyncFiles(cfg.Memory),
+		Passphrase: sc.Passphrase,
+		DeviceID:   sc.DeviceID,
+	}
+	if jobs != nil && (sc.SyncCron == nil || *sc.SyncCron) {
+		opts.Jobs = jobs
+		opts.JobFilter = func(job *cronpkg.Job) bool {
+			return job.Tag == workspaceSyncJobTag
+		}
+	}
+	return wsync.New(opts)
+}
+
+// workspaceSyncFiles lists workspace-relative persona and memory files to replicate.
+func workspaceSyncFiles(memCfg config.MemoryConfig) []string {
+	var files []string
+	for _, f := range workspaceTemplateFiles {
+		files = append(files, f.name)
+	}
+	core := memCfg.CoreFiles
+	if len(core) == 0 {
+		core = defaultCoreMemoryFiles
+	}
+	for _, p := range core {
+		if !filepath.IsAbs(p) {
+			files = append(files, filepath.ToSlash(p))
+		}
+	}
+
+	workDir := getWorkspaceDir()
+	_ = filepath.WalkDir(filepath.Join(workDir, "memory"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if !strings.EqualFold(filepath.Ext(name), ".md") || strings.Contains(name, ".conflict-") {
+			return nil
+		}
+		if rel, err := filepath.Rel(workDir, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
+
+// StartWorkspaceSync runs periodic cross-device sync when sync.enabled and sync.interval are set.
+func (a *Agent) StartWorkspaceSync(ctx context.Context) {
+	cfg, err := config.Load()
+	if err != nil || cfg == nil || !cfg.Sync.Enabled {
+		return
+	}
+	var jobs wsync.JobStore
+	if a.cronScheduler != nil {
+		jobs = a.cronScheduler
+	}
+	engine, err := NewWorkspaceSyncEngine(cfg, jobs)
+	if err != nil {
+		logger.Warn("[Sync] Workspace sync disabled: %v", err)
+		return
+	}
+	a.workspaceSync = engine
+
+	interval, err := time.ParseDuration(strings.TrimSpace(cfg.Sync.Interval))
+	if err != nil || interval <= 0 {
+		logger.Info("[Sync] Workspace sync ready (manual: /sync)")
+		return
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	go func() {
+		a.runWorkspaceSync(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.runWorkspaceSync(ctx)
+			}
+		}
+	}()
+	logger.Info("[Sync] Workspace sync every %s", interval)
+}
+
+func (a *Agent) runWorkspaceSync(ctx context.Context) (string, error) {
+	git := a.versioner()
+	if a.workspaceSync == nil {
+		return "", fmt.Errorf("workspace sync is not enabled")
+	}
+	syncCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	res, err := a.workspaceSync.Sync(syncCtx)
+	if err != nil {
+		logger.Warn("[Sync] Workspace sync failed: %v", err)
+		return "", err
+	}
+	if res.Changed() || len(res.Errors) > 0 {
+		logger.Info("[Sync] %s", res.Summary())
+		for _, e := range res.Errors {
+			logger.Warn("[Sync] %s", e)
+		}
+		if git != nil && len(res.Pulled) > 0 {
+			if err := git.Commit("sync from other device", res.Pulled...); err != nil {
+				logger.Warn("[Agent] Workspace history commit failed: %v", err)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("同步完成: " + res.Summary())
+	for _, c := range res.Conflicts {
+		b.WriteString("\n⚠️ 冲突副本: " + c)
+	}
+	for _, e := range res.Errors {
+		b.WriteString("\n❌ " + e)
+	}
+	return b.String(), nil
+}
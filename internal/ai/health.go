@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"time"
+)
+
+// ModelHealth is a snapshot of a model's failure record, persisted so
+// cooldowns survive a restart.
+type ModelHealth struct {
+	Model               string
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	LastError           string
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	CooldownUntil       time.Time
+	QuarantineUntil     time.Time
+}
+
+// Status is quarantined, cooldown, failing or healthy at now.
+func (h ModelHealth) Status(now time.Time) string {
+	switch {
+	case now.Before(h.QuarantineUntil):
+		return "quarantined"
+	case now.Before(h.CooldownUntil):
+		return "cooldown"
+	case h.ConsecutiveFailures > 0:
+		return "failing"
+	default:
+		return "healthy"
+	}
+}
+
+// OnHealthChange registers fn to receive a model's health after every
+// recorded success or failure. fn runs outside the router's lock.
+func (r *ModelRouter) OnHealthChange(fn func(ModelHealth)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onHealth = fn
+}
+
+func (r *ModelRouter) healthUnlocked(name string) ModelHealth {
+	h := ModelHealth{
+		Model:           name,
+		CooldownUntil:   r.cooldowns[name],
+		QuarantineUntil: r.quarantines[name],
+	}
+	if s, ok := r.failoverStats[name]; ok {
+		h.Successes = s.successCount
+		h.Failures = s.failureCount
+		h.ConsecutiveFailures = s.consecutiveFailed
+		h.LastError = s.lastError
+		h.LastSuccess = s.lastSuccess
+		h.LastFailure = s.lastFailure
+	}
+	return h
+}
+
+// Health returns the health of every registered model in declared order.
+func (r *ModelRouter) Health() []ModelHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	models := r.registry.ListModels()
+	out := make([]ModelHealth, 0, len(models))
+	for _, m := range models {
+		out = append(out, r.healthUnlocked(m.Name))
+	}
+	return out
+}
+
+// RestoreHealth loads health saved earlier, e.g. by a previous run or a
+// router replaced on reload. Expired cooldowns are dropped.
+func (r *ModelRouter) RestoreHealth(health []ModelHealth) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, h := range health {
+		if h.Model == "" {
+			continue
+		}
+		r.failoverStats[h.Model] = &ModelStats{
+			successCount:      h.Successes,
+			failureCount:      h.Failures,
+			consecutiveFailed: h.ConsecutiveFailures,
+			lastError:         h.LastError,
+			lastSuccess:       h.LastSuccess,
+			lastFailure:       h.LastFailure,
+		}
+		if now.Before(h.CooldownUntil) {
+			r.cooldowns[h.Model] = h.CooldownUntil
+		}
+		if now.Before(h.QuarantineUntil) {
+			r.quarantines[h.Model] = h.QuarantineUntil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthSurvivesNewRouter(t *testing.T) {
+	reg := testRegistry(
+		&ModelConfig{Name: "main", Intellect: "excellent", Speed: "fast", Cost: "medium"},
+		&ModelConfig{Name: "backup", Intellect: "good", Speed: "fast", Cost: "low"},
+	)
+	r := NewModelRouter(reg, time.Minute)
+	var saved []ModelHealth
+	r.OnHealthChange(func(h ModelHealth) { saved = append(saved, h) })
+
+	main, _ := reg.GetModel("main")
+	backup, _ := reg.GetModel("backup")
+	r.RecordSuccess(backup)
+	for i := 0; i < 3; i++ {
+		r.RecordFailure(main, errors.New("502 bad gateway"))
+	}
+	if len(saved) != 4 {
+		t.Fatalf("hook called %d times", len(saved))
+	}
+	last := saved[len(saved)-1]
+	if last.Model != "main" || last.ConsecutiveFailures != 3 || last.LastError != "502 bad gateway" {
+		t.Fatalf("last saved = %+v", last)
+	}
+	if got := last.Status(time.Now()); got != "cooldown" {
+		t.Fatalf("status = %s", got)
+	}
+
+	restarted := NewModelRouter(reg, time.Minute)
+	restarted.RestoreHealth(r.Health())
+	if !restarted.IsInCooldown("main") {
+		t.Fatal("cooldown was not restored")
+	}
+	if got := restarted.PickModelForRole(RolePrimary); got == nil || got.Name != "backup" {
+		t.Fatalf("picked %#v while main is cooling down", got)
+	}
+	health := restarted.Health()
+	if len(health) != 2 || health[1].Successes != 1 || health[1].Status(time.Now()) != "healthy" {
+		t.Fatalf("health = %+v", health)
+	}
+
+	expired := last
+	expired.CooldownUntil = time.Now().Add(-time.Second)
+	fresh := NewModelRouter(reg, time.Minute)
+	fresh.RestoreHealth([]ModelHealth{expired})
+	if fresh.IsInCooldown("main") {
+		t.Fatal("expired cooldown was restored")
+	}
+}
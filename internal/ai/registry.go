@@ -3,43 +3,19 @@ package ai
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/kayz/coco/internal/datadir"
 	"gopkg.in/yaml.v3"
 )
 
-var (
-	exeDirCache string
-)
-
-func getExecutableDir() string {
-	if exeDirCache != "" {
-		return exeDirCache
-	}
-	execPath, err := os.Executable()
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	exeDirCache = filepath.Dir(execPath)
-	return exeDirCache
-}
-
 func ProvidersPath() string {
-	exeDir := getExecutableDir()
-	return filepath.Join(exeDir, ".coco", "providers.yaml")
+	return datadir.Path(".coco", "providers.yaml")
 }
 
 func ModelsPath() string {
-	exeDir := getExecutableDir()
-	return filepath.Join(exeDir, ".coco", "models.yaml")
+	return datadir.Path(".coco", "models.yaml")
 }
 
 type ProviderConfig struct {
@@ -62,6 +38,18 @@ type ModelConfig struct {
 	Enabled        *bool    `yaml:"enabled,omitempty"`
 	DisabledUntil  string   `yaml:"disabled_until,omitempty"`
 	DisabledReason string   `yaml:"disabled_reason,omitempty"`
+	ContextWindow  int      `yaml:"context_window,omitempty"` // Max prompt+completion tokens; 0 = infer from code
+	InputPrice     float64  `yaml:"input_price,omitempty"`    // Price per million prompt tokens
+	OutputPrice    float64  `yaml:"output_price,omitempty"`   // Price per million completion tokens
+	// ToolCalling is "native" (default) for models with function calling,
+	// or "text" for models that drive tools through a text protocol.
+	ToolCalling string `yaml:"tool_calling,omitempty"`
+}
+
+// TextToolCalling reports whether the model calls tools through the text
+// protocol instead of native function calling.
+func (m *ModelConfig) TextToolCalling() bool {
+	return m != nil && strings.EqualFold(strings.TrimSpace(m.ToolCalling), "text")
 }
 
 func (m *ModelConfig) IntellectText() string {
@@ -79,6 +67,48 @@ func (m *ModelConfig) IntellectText() string {
 	}
 }
 
+// EffectiveContextWindow returns the model's context size in tokens. When
+// context_window is not configured it is inferred from a size suffix in the
+// model code (e.g. "moonshot-v1-8k", "qwen-long-128k"); 0 means unknown.
+func (m *ModelConfig) EffectiveContextWindow() int {
+	if m.ContextWindow > 0 {
+		return m.ContextWindow
+	}
+	code := strings.ToLower(m.Code)
+	for i := len(code) - 1; i >= 0; i-- {
+		if code[i] != 'k' {
+			continue
+		}
+		j := i
+		for j > 0 && code[j-1] >= '0' && code[j-1] <= '9' {
+			j--
+		}
+		if j == i || (j > 0 && code[j-1] != '-' && code[j-1] != '_') {
+			continue
+		}
+		if i+1 < len(code) && code[i+1] != '-' && code[i+1] != '_' {
+			continue
+		}
+		n := 0
+		for _, c := range code[j:i] {
+			n = n*10 + int(c-'0')
+		}
+		if n > 0 {
+			return n * 1024
+		}
+	}
+	return 0
+}
+
+// TokenCost is what a request with the given token counts costs, in the
+// currency input_price and output_price are quoted in; 0 when unpriced.
+func (m *ModelConfig) TokenCost(inputTokens, outputTokens int) float64 {
+	if m == nil {
+		return 0
+	}
+	return (float64(inputTokens)*m.InputPrice + float64(outputTokens)*m.OutputPrice) / 1e6
+}
+
 func (m *ModelConfig) SpeedText() string {
 	switch m.Speed {
 	case "fast":
@@ -325,3 +355,38 @@ func (r *Registry) GetDefaultModel() *ModelConfig {
 	}
 	return nil
 }
+
+// Validate reports inconsistencies between models.yaml and providers.yaml
+// that would make models unusable at runtime.
+func (r *Registry) Validate() []string {
+	var problems []string
+	now := time.Now()
+	usable := 0
+	for _, m := range r.ListModels() {
+		if strings.TrimSpace(m.Code) == "" {
+			problems = append(problems, fmt.Sprintf("model %s has no code", m.Name))
+		}
+		switch strings.ToLower(strings.TrimSpace(m.ToolCalling)) {
+		case "", "native", "text":
+		default:
+			problems = append(problems, fmt.Sprintf("model %s has tool_calling %q, want native or text", m.Name, m.ToolCalling))
+		}
+		provider, ok := r.providers[m.Provider]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("model %s uses unknown provider %q", m.Name, m.Provider))
+			continue
+		}
+		if len(provider.Keys()) == 0 {
+			problems = append(problems, fmt.Sprintf("provider %s (used by %s) has no api key", provider.Name, m.Name))
+			continue
+		}
+		if !m.IsAvailable(now) {
+			continue
+		}
+		usable++
+	}
+	if usable == 0 {
+		problems = append(problems, "no enabled model has a configured provider")
+	}
+	return problems
+}
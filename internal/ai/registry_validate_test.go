@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryValidate(t *testing.T) {
+	r := &Registry{
+		providers: map[string]*ProviderConfig{
+			"ds":    {Name: "ds", APIKey: "k"},
+			"empty": {Name: "empty"},
+		},
+		models: map[string]*ModelConfig{
+			"good":    {Name: "good", Code: "deepseek-chat", Provider: "ds"},
+			"orphan":  {Name: "orphan", Code: "x", Provider: "gone"},
+			"nokey":   {Name: "nokey", Code: "y", Provider: "empty"},
+			"no-code": {Name: "no-code", Provider: "ds"},
+		},
+		modelOrder: []string{"good", "orphan", "nokey", "no-code"},
+	}
+
+	problems := strings.Join(r.Validate(), "\n")
+	for _, want := range []string{`unknown provider "gone"`, "provider empty (used by nokey) has no api key", "model no-code has no code"} {
+		if !strings.Contains(problems, want) {
+			t.Fatalf("missing %q in:\n%s", want, problems)
+		}
+	}
+	if strings.Contains(problems, "no enabled model") {
+		t.Fatalf("good model should count as usable:\n%s", problems)
+	}
+}
@@ -24,6 +24,8 @@ type ModelRouter struct {
 	quarantineTime  time.Duration
 	failoverAfter   int
 	quarantineAfter int
+	budgets         *Budgets // daily spend caps; nil means unlimited
+	onHealth        func(ModelHealth)
 	mu              sync.RWMutex
 }
 
@@ -31,6 +33,7 @@ type ModelStats struct {
 	successCount      int
 	failureCount      int
 	consecutiveFailed int
+	lastError         string
 	lastSuccess       time.Time
 	lastFailure       time.Time
 }
@@ -210,16 +213,32 @@ func (r *ModelRouter) PickModelForRole(role string) *ModelConfig {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	role = normalizeRole(role)
-	now := time.Now()
+	var preferred *ModelConfig
+	if role == RolePrimary {
+		preferred = r.currentModel
+	}
+	return r.pickModelUnlocked(preferred, role)
+}
 
-	if role == RolePrimary && r.currentModel != nil && r.isModelAvailableUnlocked(r.currentModel, now) && !r.IsInCooldown(r.currentModel.Name) {
-		return r.currentModel
+// PickModelPreferring is PickModelForRole with preferred in place of the
+// current model, for one request that answers with its own model without
+// switching the model every other conversation uses.
+func (r *ModelRouter) PickModelPreferring(preferred *ModelConfig, role string) *ModelConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pickModelUnlocked(preferred, normalizeRole(role))
+}
+
+func (r *ModelRouter) pickModelUnlocked(preferred *ModelConfig, role string) *ModelConfig {
+	now := time.Now()
+	if preferred != nil && r.isModelAvailableUnlocked(preferred, now) && !r.IsInCooldown(preferred.Name) {
+		return r.withinBudgetUnlocked(preferred, now)
 	}
 
 	candidates := r.roleModelsUnlocked(role)
 	for _, c := range candidates {
 		if !r.IsInCooldown(c.Name) {
-			return c
+			return r.withinBudgetUnlocked(c, now)
 		}
 	}
 	if len(candidates) > 0 {
@@ -228,6 +247,18 @@ func (r *ModelRouter) PickModelForRole(role string) *ModelConfig {
 	return nil
 }
 
+// withinBudgetUnlocked downgrades m to a cheaper model once m has spent its
+// daily budget. m is kept when there is nothing cheaper to fall back to.
+func (r *ModelRouter) withinBudgetUnlocked(m *ModelConfig, now time.Time) *ModelConfig {
+	if !r.budgets.Exhausted(m.Name, now) {
+		return m
+	}
+	if down := r.downgradeUnlocked(m, now); down != nil {
+		return down
+	}
+	return m
+}
+
 func (r *ModelRouter) ListModelsForRole(role string) []*ModelConfig {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -242,8 +273,6 @@ func (r *ModelRouter) RecordSuccess(model *ModelConfig) {
 		return
 	}
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	stats, ok := r.failoverStats[model.Name]
 	if !ok {
 		stats = &ModelStats{}
@@ -252,15 +281,21 @@ func (r *ModelRouter) RecordSuccess(model *ModelConfig) {
 	stats.successCount++
 	stats.consecutiveFailed = 0
 	stats.lastSuccess = time.Now()
+	health, notify := r.healthUnlocked(model.Name), r.onHealth
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify(health)
+	}
 }
 
-func (r *ModelRouter) RecordFailure(model *ModelConfig) {
+// RecordFailure counts a failed call to model; err, when given, is kept as
+// the model's last error.
+func (r *ModelRouter) RecordFailure(model *ModelConfig, err error) {
 	if model == nil {
 		return
 	}
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	stats, ok := r.failoverStats[model.Name]
 	if !ok {
 		stats = &ModelStats{}
@@ -269,6 +304,9 @@ func (r *ModelRouter) RecordFailure(model *ModelConfig) {
 	stats.failureCount++
 	stats.consecutiveFailed++
 	stats.lastFailure = time.Now()
+	if err != nil {
+		stats.lastError = err.Error()
+	}
 
 	if stats.consecutiveFailed >= r.failoverAfter {
 		r.cooldowns[model.Name] = time.Now().Add(r.cooldownTime)
@@ -276,6 +314,12 @@ func (r *ModelRouter) RecordFailure(model *ModelConfig) {
 	if stats.consecutiveFailed >= r.quarantineAfter {
 		r.quarantines[model.Name] = time.Now().Add(r.quarantineTime)
 	}
+	health, notify := r.healthUnlocked(model.Name), r.onHealth
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify(health)
+	}
 }
 
 func (r *ModelRouter) ConsecutiveFailures(modelName string) int {
@@ -44,13 +44,13 @@ func TestPrimaryRotationThreshold(t *testing.T) {
 		t.Fatalf("unexpected primary model: %#v", main)
 	}
 
-	r.RecordFailure(main)
-	r.RecordFailure(main)
+	r.RecordFailure(main, nil)
+	r.RecordFailure(main, nil)
 	if r.ShouldRotatePrimary(main) {
 		t.Fatalf("should not rotate primary before threshold")
 	}
 
-	r.RecordFailure(main)
+	r.RecordFailure(main, nil)
 	if !r.ShouldRotatePrimary(main) {
 		t.Fatalf("should rotate primary at threshold")
 	}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoutingPolicy orders models when coco picks one for a task.
+type RoutingPolicy string
+
+const (
+	PolicyCheapestCapable RoutingPolicy = "cheapest-capable"
+	PolicyFastest         RoutingPolicy = "fastest"
+	PolicyBestQuality     RoutingPolicy = "best-quality"
+)
+
+// RoutingPolicies lists the valid policies.
+var RoutingPolicies = []RoutingPolicy{PolicyCheapestCapable, PolicyFastest, PolicyBestQuality}
+
+// ParseRoutingPolicy returns the policy named s, ignoring case and
+// surrounding space.
+func ParseRoutingPolicy(s string) (RoutingPolicy, bool) {
+	p := RoutingPolicy(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range RoutingPolicies {
+		if p == known {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Budgets caps what each model may spend per day. Spending is counted in
+// the unit of input_price and output_price and resets at local midnight.
+// It is shared by successive routers so a registry reload keeps the count.
+type Budgets struct {
+	mu    sync.Mutex
+	caps  map[string]float64
+	day   string
+	spent map[string]float64
+}
+
+func NewBudgets() *Budgets {
+	return &Budgets{caps: map[string]float64{}, spent: map[string]float64{}}
+}
+
+// SetCaps replaces the daily caps; models without a positive cap are
+// unlimited.
+func (b *Budgets) SetCaps(caps map[string]float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.caps = make(map[string]float64, len(caps))
+	for name, limit := range caps {
+		if limit > 0 {
+			b.caps[name] = limit
+		}
+	}
+}
+
+// Restore sets what was already spent on day, e.g. before a restart.
+func (b *Budgets) Restore(day string, spent map[string]float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.day = day
+	b.spent = make(map[string]float64, len(spent))
+	for name, cost := range spent {
+		b.spent[name] = cost
+	}
+}
+
+func (b *Budgets) rollover(now time.Time) {
+	if day := now.Format("2006-01-02"); day != b.day {
+		b.day = day
+		b.spent = map[string]float64{}
+	}
+}
+
+// Add records cost against model and reports whether this pushed the model
+// over its cap.
+func (b *Budgets) Add(model string, cost float64, now time.Time) bool {
+	if b == nil || cost <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(now)
+	before := b.spent[model]
+	b.spent[model] = before + cost
+	limit, ok := b.caps[model]
+	return ok && before < limit && before+cost >= limit
+}
+
+// Spent is what model has spent today.
+func (b *Budgets) Spent(model string, now time.Time) float64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(now)
+	return b.spent[model]
+}
+
+// Exhausted reports whether model has reached today's cap.
+func (b *Budgets) Exhausted(model string, now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(now)
+	limit, ok := b.caps[model]
+	return ok && b.spent[model] >= limit
+}
+
+// SetBudgets makes the router skip models that reached their daily cap.
+func (r *ModelRouter) SetBudgets(b *Budgets) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.budgets = b
+}
+
+// cheaper reports whether a costs less than b: by price per token when both
+// are priced, by cost tier otherwise.
+func cheaper(a, b *ModelConfig) (less, equal bool) {
+	if pa, pb := a.InputPrice+a.OutputPrice, b.InputPrice+b.OutputPrice; pa > 0 && pb > 0 {
+		return pa < pb, pa == pb
+	}
+	ca, cb := costRank(a.Cost), costRank(b.Cost)
+	return ca < cb, ca == cb
+}
+
+// PickModelByPolicy returns the model policy prefers among the available
+// models with at least minIntellect (see IntellectRank), or among all of
+// them when none is capable enough. Models in cooldown or over their daily
+// budget are passed over while others remain.
+func (r *ModelRouter) PickModelByPolicy(policy RoutingPolicy, minIntellect int) *ModelConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+
+	var usable, capable []*ModelConfig
+	for _, m := range r.registry.ListModels() {
+		if !r.isModelAvailableUnlocked(m, now) || r.IsInCooldown(m.Name) {
+			continue
+		}
+		usable = append(usable, m)
+		if m.IntellectRank() >= minIntellect {
+			capable = append(capable, m)
+		}
+	}
+	candidates := capable
+	if len(candidates) == 0 {
+		candidates = usable
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sortByPolicy(candidates, policy)
+	for _, m := range candidates {
+		if !r.budgets.Exhausted(m.Name, now) {
+			return m
+		}
+	}
+	if down := r.downgradeUnlocked(candidates[0], now); down != nil {
+		return down
+	}
+	return candidates[0]
+}
+
+func sortByPolicy(models []*ModelConfig, policy RoutingPolicy) {
+	sort.SliceStable(models, func(i, j int) bool {
+		a, b := models[i], models[j]
+		less, equal := cheaper(a, b)
+		switch policy {
+		case PolicyCheapestCapable:
+			if !equal {
+				return less
+			}
+			if speedRank(a.Speed) != speedRank(b.Speed) {
+				return speedRank(a.Speed) > speedRank(b.Speed)
+			}
+			return a.IntellectRank() > b.IntellectRank()
+		case PolicyFastest:
+			if speedRank(a.Speed) != speedRank(b.Speed) {
+				return speedRank(a.Speed) > speedRank(b.Speed)
+			}
+			if a.IntellectRank() != b.IntellectRank() {
+				return a.IntellectRank() > b.IntellectRank()
+			}
+			return less
+		default:
+			if a.IntellectRank() != b.IntellectRank() {
+				return a.IntellectRank() > b.IntellectRank()
+			}
+			if a.HasSkill("thinking") != b.HasSkill("thinking") {
+				return a.HasSkill("thinking")
+			}
+			if speedRank(a.Speed) != speedRank(b.Speed) {
+				return speedRank(a.Speed) > speedRank(b.Speed)
+			}
+			return less
+		}
+	})
+}
+
+// downgradeUnlocked returns the most capable model cheaper than m that is
+// still within its budget, or nil when there is none.
+func (r *ModelRouter) downgradeUnlocked(m *ModelConfig, now time.Time) *ModelConfig {
+	var best *ModelConfig
+	for _, c := range r.registry.ListModels() {
+		if c.Name == m.Name || !r.isModelAvailableUnlocked(c, now) || r.IsInCooldown(c.Name) || r.budgets.Exhausted(c.Name, now) {
+			continue
+		}
+		if less, _ := cheaper(c, m); !less {
+			continue
+		}
+		if best == nil || c.IntellectRank() > best.IntellectRank() {
+			best = c
+		} else if c.IntellectRank() == best.IntellectRank() {
+			if less, _ := cheaper(c, best); less {
+				best = c
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func routingTestRouter() *ModelRouter {
+	reg := testRegistry(
+		&ModelConfig{Name: "opus", Intellect: "full", Speed: "slow", Cost: "expensive", InputPrice: 15, OutputPrice: 75},
+		&ModelConfig{Name: "sonnet", Intellect: "excellent", Speed: "medium", Cost: "high", InputPrice: 3, OutputPrice: 15},
+		&ModelConfig{Name: "flash", Intellect: "good", Speed: "fast", Cost: "low", InputPrice: 0.1, OutputPrice: 0.4},
+		&ModelConfig{Name: "tiny", Intellect: "usable", Speed: "fast", Cost: "free"},
+	)
+	return NewModelRouter(reg, time.Minute)
+}
+
+func TestPickModelByPolicy(t *testing.T) {
+	r := routingTestRouter()
+	cases := []struct {
+		policy       RoutingPolicy
+		minIntellect int
+		want         string
+	}{
+		{PolicyBestQuality, 0, "opus"},
+		{PolicyFastest, 2, "flash"},
+		{PolicyFastest, 0, "flash"},
+		{PolicyCheapestCapable, 1, "tiny"},
+		{PolicyCheapestCapable, 2, "flash"},
+		{PolicyCheapestCapable, 3, "sonnet"},
+		{PolicyCheapestCapable, 5, "tiny"}, // nothing capable enough: cheapest of all
+	}
+	for _, c := range cases {
+		if got := r.PickModelByPolicy(c.policy, c.minIntellect); got == nil || got.Name != c.want {
+			t.Errorf("%s (min %d) = %v, want %s", c.policy, c.minIntellect, got, c.want)
+		}
+	}
+}
+
+func TestBudgetsDowngradeToCheaperModel(t *testing.T) {
+	r := routingTestRouter()
+	b := NewBudgets()
+	b.SetCaps(map[string]float64{"opus": 1, "sonnet": 0.5})
+	r.SetBudgets(b)
+	now := time.Now()
+
+	if b.Add("opus", 0.6, now) {
+		t.Fatal("under the cap reported as exhausted")
+	}
+	if !b.Add("opus", 0.6, now) || !b.Exhausted("opus", now) {
+		t.Fatal("crossing the cap not reported")
+	}
+	if got := r.PickModelByPolicy(PolicyBestQuality, 3); got.Name != "sonnet" {
+		t.Fatalf("best-quality over budget = %s, want sonnet", got.Name)
+	}
+	if err := r.SwitchToModel("opus", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.PickModelForRole(RolePrimary); got.Name != "sonnet" {
+		t.Fatalf("primary over budget = %s, want sonnet", got.Name)
+	}
+
+	b.Add("sonnet", 1, now)
+	if got := r.PickModelByPolicy(PolicyBestQuality, 3); got.Name != "flash" {
+		t.Fatalf("capable models over budget = %s, want flash", got.Name)
+	}
+	if b.Exhausted("opus", now.AddDate(0, 0, 1)) {
+		t.Fatal("budget did not reset the next day")
+	}
+}
+
+func TestParseRoutingPolicy(t *testing.T) {
+	if p, ok := ParseRoutingPolicy(" Cheapest-Capable "); !ok || p != PolicyCheapestCapable {
+		t.Fatalf("parse = %q, %v", p, ok)
+	}
+	if _, ok := ParseRoutingPolicy("cheap"); ok {
+		t.Fatal("unknown policy accepted")
+	}
+}
@@ -0,0 +1,248 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+// ModelName is the model id coco advertises on the OpenAI-compatible endpoints.
+const ModelName = "coco"
+
+// ConversationHeader pins an OpenAI-style request to a coco conversation.
+const ConversationHeader = "X-Coco-Conversation"
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	User     string        `json:"user"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message content, which may be a string or a list of
+// parts of which only the text ones are kept.
+func (m chatMessage) text() string {
+	var s string
+	if json.Unmarshal(m.Content, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(m.Content, &parts) != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int        `json:"index"`
+	Message      *chatReply `json:"message,omitempty"`
+	Delta        *chatReply `json:"delta,omitempty"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type chatReply struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// chatUsage is always zero: the agent may make several model calls per
+// reply and does not report their token counts here.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// handleChatCompletions answers an OpenAI chat request through the agent.
+// OpenAI clients resend the whole transcript on every call while the agent
+// keeps its own history, so only the latest user message is forwarded.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	text := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			text = strings.TrimSpace(req.Messages[i].text())
+			break
+		}
+	}
+	if text == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "messages must include a non-empty user message")
+		return
+	}
+	model := strings.TrimSpace(req.Model)
+	if model == "" {
+		model = ModelName
+	}
+	userID := strings.TrimSpace(req.User)
+	if userID == "" {
+		userID = "openai-client"
+	}
+	msg := router.Message{
+		Platform:  Platform,
+		ChannelID: chatConversation(r, req),
+		UserID:    userID,
+		Username:  userID,
+		Text:      text,
+		Metadata:  map[string]string{"chat_type": "private"},
+	}
+
+	base := chatCompletion{ID: newCompletionID(), Created: time.Now().Unix(), Model: model}
+	if req.Stream {
+		s.streamChatCompletion(w, r, msg, base)
+		return
+	}
+	resp, err := s.backend.HandleMessage(r.Context(), msg)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stop := "stop"
+	base.Object = "chat.completion"
+	base.Choices = []chatChoice{{Message: &chatReply{Role: "assistant", Content: resp.Text}, FinishReason: &stop}}
+	base.Usage = &chatUsage{}
+	writeJSON(w, http.StatusOK, base)
+}
+
+// streamChatCompletion sends the reply as OpenAI chunks: the role, the whole
+// text once the agent is done (tools run before any text exists), a final
+// chunk with finish_reason, then "data: [DONE]".
+func (s *Server) streamChatCompletion(w http.ResponseWriter, r *http.Request, msg router.Message, base chatCompletion) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	sse := &sseWriter{w: w, flusher: flusher}
+	chunk := func(delta chatReply, finish *string) {
+		c := base
+		c.Object = "chat.completion.chunk"
+		c.Choices = []chatChoice{{Delta: &delta, FinishReason: finish}}
+		sse.data(c)
+	}
+	chunk(chatReply{Role: "assistant"}, nil)
+
+	type result struct {
+		resp router.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := s.backend.HandleMessage(r.Context(), msg)
+		done <- result{resp, err}
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sse.comment("keep-alive")
+		case res := <-done:
+			if res.err != nil {
+				sse.data(openAIErrorBody(res.err.Error()))
+			} else {
+				if res.resp.Text != "" {
+					chunk(chatReply{Content: res.resp.Text}, nil)
+				}
+				stop := "stop"
+				chunk(chatReply{}, &stop)
+			}
+			sse.raw("[DONE]")
+			return
+		case <-r.Context().Done():
+			sse.close()
+			return
+		}
+	}
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": ModelName, "object": "model", "created": 0, "owned_by": "coco"},
+		},
+	})
+}
+
+// chatConversation picks the coco conversation for an OpenAI-style request.
+func chatConversation(r *http.Request, req chatCompletionRequest) string {
+	if conv := strings.TrimSpace(r.Header.Get(ConversationHeader)); conv != "" {
+		return conv
+	}
+	if user := strings.TrimSpace(req.User); user != "" {
+		return "openai-" + user
+	}
+	// Hash the system prompt and first user message: they do not change as
+	// the client appends turns, but differ between unrelated chats.
+	h := sha256.New()
+	for _, m := range req.Messages {
+		if m.Role == "assistant" {
+			break
+		}
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.text()))
+		h.Write([]byte{0})
+		if m.Role == "user" {
+			break
+		}
+	}
+	return "openai-" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+func newCompletionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+func openAIErrorBody(msg string) map[string]any {
+	return map[string]any{"error": map[string]string{"message": msg, "type": "server_error"}}
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, msg string) {
+	body := openAIErrorBody(msg)
+	if status < http.StatusInternalServerError {
+		body["error"].(map[string]string)["type"] = "invalid_request_error"
+	}
+	writeJSON(w, status, body)
+}
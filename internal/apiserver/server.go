@@ -0,0 +1,434 @@
+// Package apiserver exposes the agent over a small local REST API so scripts
+// and custom UIs can talk to coco without a chat platform.
+//
+//	POST /v1/messages        send a message; SSE when "stream" is set or Accept is text/event-stream
+//	GET  /v1/conversations   list remembered conversations
+//	GET  /v1/cron/jobs       list scheduled jobs
+//	POST /v1/tools/{name}    run one tool with a JSON object of arguments
+//	POST /v1/batch           run many independent prompts; SSE per result when streaming
+//	POST /v1/chat/completions  OpenAI-compatible chat completions
+//	GET  /v1/models            the single "coco" model, for OpenAI clients
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	"github.com/kayz/coco/internal/batch"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/router"
+)
+
+// Platform is the router platform name used for messages sent over the API.
+const Platform = "api"
+
+// keepAliveInterval is how often an idle SSE stream gets a comment line so
+// proxies do not close it while the agent is thinking.
+const keepAliveInterval = 15 * time.Second
+
+// Backend is what the API needs from the agent.
+type Backend interface {
+	HandleMessage(ctx context.Context, msg router.Message) (router.Response, error)
+	Conversations() []agent.ConversationSummary
+	RunTool(ctx context.Context, profile, name string, args map[string]any) (string, error)
+}
+
+// Options configures a Server.
+type Options struct {
+	// Token must be sent as "Authorization: Bearer <token>". Without one
+	// every request is refused: a page open in the user's browser can
+	// reach a loopback address too.
+	Token string
+	// ToolProfile is the tool profile /v1/tools calls run under.
+	ToolProfile string
+	// CronJobs lists scheduled jobs; nil disables /v1/cron/jobs.
+	CronJobs func() ([]*cronpkg.Job, error)
+}
+
+// Server serves the REST API.
+type Server struct {
+	backend Backend
+	opts    Options
+}
+
+// NewServer creates an API server for backend.
+func NewServer(backend Backend, opts Options) *Server {
+	return &Server{backend: backend, opts: opts}
+}
+
+// Handler returns the HTTP handler with authentication applied.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/messages", s.handleMessages)
+	mux.HandleFunc("GET /v1/conversations", s.handleConversations)
+	mux.HandleFunc("GET /v1/cron/jobs", s.handleCronJobs)
+	mux.HandleFunc("POST /v1/tools/{name}", s.handleTool)
+	mux.HandleFunc("POST /v1/batch", s.handleBatch)
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if s.opts.Token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type messageRequest struct {
+	Conversation string `json:"conversation"`
+	UserID       string `json:"user_id"`
+	Text         string `json:"text"`
+	Stream       bool   `json:"stream"`
+}
+
+type messageResponse struct {
+	Conversation string                  `json:"conversation"`
+	Text         string                  `json:"text"`
+	Files        []router.FileAttachment `json:"files,omitempty"`
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var req messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if req.Conversation = strings.TrimSpace(req.Conversation); req.Conversation == "" {
+		req.Conversation = "default"
+	}
+	if req.UserID = strings.TrimSpace(req.UserID); req.UserID == "" {
+		req.UserID = "api-user"
+	}
+	msg := router.Message{
+		Platform:  Platform,
+		ChannelID: req.Conversation,
+		UserID:    req.UserID,
+		Username:  req.UserID,
+		Text:      req.Text,
+		Metadata:  map[string]string{"chat_type": "private"},
+	}
+
+	if req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamMessage(w, r, msg)
+		return
+	}
+	resp, err := s.backend.HandleMessage(r.Context(), msg)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Conversation: msg.ChannelID, Text: resp.Text, Files: resp.Files})
+}
+
+// streamMessage answers with server-sent events: "tool" for each tool call
+// start and end, then "message" with the reply (or "error"), then "done".
+func (s *Server) streamMessage(w http.ResponseWriter, r *http.Request, msg router.Message) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	sse := &sseWriter{w: w, flusher: flusher}
+	sse.flush()
+
+	ctx := agent.WithToolObserver(r.Context(), func(ev agent.ToolEvent) {
+		sse.event("tool", ev)
+	})
+
+	type result struct {
+		resp router.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := s.backend.HandleMessage(ctx, msg)
+		done <- result{resp, err}
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sse.comment("keep-alive")
+		case res := <-done:
+			if res.err != nil {
+				sse.event("error", map[string]string{"error": res.err.Error()})
+			} else {
+				sse.event("message", messageResponse{Conversation: msg.ChannelID, Text: res.resp.Text, Files: res.resp.Files})
+			}
+			sse.event("done", map[string]any{})
+			return
+		case <-r.Context().Done():
+			// The client went away; HandleMessage sees the same cancellation
+			// and may still report tool events, which close drops.
+			sse.close()
+			return
+		}
+	}
+}
+
+// sseWriter serializes writes from the tool loop and the request goroutine.
+type sseWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+func (s *sseWriter) event(name string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data)
+	s.flusher.Flush()
+}
+
+// data writes an unnamed event, as OpenAI streaming clients expect.
+func (s *sseWriter) data(payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	s.raw(string(data))
+}
+
+func (s *sseWriter) raw(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	fmt.Fprintf(s.w, "data: %s\n\n", data)
+	s.flusher.Flush()
+}
+
+func (s *sseWriter) comment(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	fmt.Fprintf(s.w, ": %s\n\n", text)
+	s.flusher.Flush()
+}
+
+func (s *sseWriter) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flusher.Flush()
+}
+
+func (s *sseWriter) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	platform := r.URL.Query().Get("platform")
+	convs := s.backend.Conversations()
+	out := make([]agent.ConversationSummary, 0, len(convs))
+	for _, c := range convs {
+		if platform == "" || c.Platform == platform {
+			out = append(out, c)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conversations": out})
+}
+
+func (s *Server) handleCronJobs(w http.ResponseWriter, _ *http.Request) {
+	if s.opts.CronJobs == nil {
+		writeError(w, http.StatusNotImplemented, "cron jobs are not available")
+		return
+	}
+	jobs, err := s.opts.CronJobs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]*cronpkg.Job, 0, len(jobs))
+	for _, job := range jobs {
+		job = job.Clone()
+		if job.AuthHeader != "" {
+			job.AuthHeader = "[REDACTED]"
+		}
+		out = append(out, job)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": out})
+}
+
+func (s *Server) handleTool(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var args map[string]any
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+			writeError(w, http.StatusBadRequest, "body must be a json object of tool arguments")
+			return
+		}
+	}
+	result, err := s.backend.RunTool(r.Context(), s.opts.ToolProfile, name, args)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, agent.ErrUnknownTool):
+			status = http.StatusNotFound
+		case errors.Is(err, agent.ErrToolDenied):
+			status = http.StatusForbidden
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tool":     name,
+		"result":   result,
+		"is_error": strings.HasPrefix(result, "Error"),
+	})
+}
+
+// maxBatchItems caps the prompts of one /v1/batch request.
+const maxBatchItems = 1000
+
+type batchRequest struct {
+	Items       []batch.Item `json:"items"`
+	Concurrency int          `json:"concurrency"`
+	Stream      bool         `json:"stream"`
+}
+
+// handleBatch runs the items of a batch. Results come back in input order,
+// as one JSON list or, when streaming, as a "result" event each followed by
+// "done".
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	if len(req.Items) == 0 {
+		writeError(w, http.StatusBadRequest, "items is required")
+		return
+	}
+	if len(req.Items) > maxBatchItems {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d items per batch", maxBatchItems))
+		return
+	}
+	for i, item := range req.Items {
+		if strings.TrimSpace(item.Prompt) == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("items[%d].prompt is required", i))
+			return
+		}
+	}
+	opts := batch.Options{Concurrency: req.Concurrency}
+
+	if !req.Stream && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		results := make([]batch.Result, 0, len(req.Items))
+		batch.Run(r.Context(), s.backend.HandleMessage, req.Items, opts, func(res batch.Result) {
+			results = append(results, res)
+		})
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	sse := &sseWriter{w: w, flusher: flusher}
+	sse.flush()
+
+	failed := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		batch.Run(r.Context(), s.backend.HandleMessage, req.Items, opts, func(res batch.Result) {
+			if res.Error != "" {
+				failed++
+			}
+			sse.event("result", res)
+		})
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sse.comment("keep-alive")
+		case <-done:
+			sse.event("done", map[string]int{"total": len(req.Items), "failed": failed})
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// LoadOrCreateToken returns the token saved at path, generating and saving
+// a new one the first time. created reports whether it was just made.
+func LoadOrCreateToken(path string) (token string, created bool, err error) {
+	if raw, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(raw)); token != "" {
+			return token, false, nil
+		}
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, err
+	}
+	token = hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", false, err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
@@ -0,0 +1,233 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/agent"
+	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/router"
+)
+
+type fakeBackend struct {
+	lastMsg router.Message
+}
+
+func (b *fakeBackend) HandleMessage(_ context.Context, msg router.Message) (router.Response, error) {
+	b.lastMsg = msg
+	return router.Response{Text: "echo: " + msg.Text}, nil
+}
+
+func (b *fakeBackend) Conversations() []agent.ConversationSummary {
+	return []agent.ConversationSummary{
+		{Key: "api:default:api-user", Platform: "api", Messages: 2},
+		{Key: "telegram:1:2", Platform: "telegram", Messages: 4},
+	}
+}
+
+func (b *fakeBackend) RunTool(_ context.Context, profile, name string, args map[string]any) (string, error) {
+	if name != "current_time" {
+		return "", fmt.Errorf("%w: %s", agent.ErrUnknownTool, name)
+	}
+	if profile != "admin" {
+		return "", fmt.Errorf("%w: %s", agent.ErrToolDenied, name)
+	}
+	return fmt.Sprintf("now (tz=%v)", args["timezone"]), nil
+}
+
+const testToken = "t0ken"
+
+func serve(t *testing.T, h http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMessagesJSONAndSSE(t *testing.T) {
+	backend := &fakeBackend{}
+	h := NewServer(backend, Options{Token: testToken, ToolProfile: "admin"}).Handler()
+
+	rr := serve(t, h, http.MethodPost, "/v1/messages", `{"conversation":"c1","text":"hi"}`, nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "echo: hi") {
+		t.Fatalf("json reply = %d %s", rr.Code, rr.Body.String())
+	}
+	if backend.lastMsg.Platform != Platform || backend.lastMsg.ChannelID != "c1" || backend.lastMsg.UserID != "api-user" {
+		t.Fatalf("message = %+v", backend.lastMsg)
+	}
+
+	rr = serve(t, h, http.MethodPost, "/v1/messages", `{"text":"hi","stream":true}`, nil)
+	body := rr.Body.String()
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+	msgAt, doneAt := strings.Index(body, "event: message\n"), strings.Index(body, "event: done\n")
+	if msgAt < 0 || doneAt < msgAt || !strings.Contains(body, `"text":"echo: hi"`) {
+		t.Fatalf("sse body = %s", body)
+	}
+
+	if rr := serve(t, h, http.MethodPost, "/v1/messages", `{"text":"  "}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty text = %d", rr.Code)
+	}
+}
+
+func TestConversationsCronAndTools(t *testing.T) {
+	now := time.Now()
+	h := NewServer(&fakeBackend{}, Options{Token: testToken, ToolProfile: "admin", CronJobs: func() ([]*cronpkg.Job, error) {
+		return []*cronpkg.Job{{ID: "j1", Name: "poll", Schedule: "0 * * * * *", AuthHeader: "Bearer s3cret", CreatedAt: now}}, nil
+	}}).Handler()
+
+	rr := serve(t, h, http.MethodGet, "/v1/conversations?platform=api", "", nil)
+	var convs struct {
+		Conversations []agent.ConversationSummary `json:"conversations"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &convs); err != nil || len(convs.Conversations) != 1 {
+		t.Fatalf("conversations = %s (%v)", rr.Body.String(), err)
+	}
+
+	rr = serve(t, h, http.MethodGet, "/v1/cron/jobs", "", nil)
+	if !strings.Contains(rr.Body.String(), `"name":"poll"`) || strings.Contains(rr.Body.String(), "s3cret") {
+		t.Fatalf("cron jobs = %s", rr.Body.String())
+	}
+
+	rr = serve(t, h, http.MethodPost, "/v1/tools/current_time", `{"timezone":"UTC"}`, nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "tz=UTC") {
+		t.Fatalf("tool = %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(t, h, http.MethodPost, "/v1/tools/nope", "", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown tool = %d", rr.Code)
+	}
+	readonly := NewServer(&fakeBackend{}, Options{Token: testToken, ToolProfile: "readonly"}).Handler()
+	if rr := serve(t, readonly, http.MethodPost, "/v1/tools/current_time", "{}", nil); rr.Code != http.StatusForbidden {
+		t.Fatalf("tool outside the profile = %d", rr.Code)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	h := NewServer(&fakeBackend{}, Options{Token: testToken, ToolProfile: "admin"}).Handler()
+
+	rr := serve(t, h, http.MethodPost, "/v1/batch", `{"items":[{"id":"a","prompt":"one"},{"prompt":"two"}],"concurrency":1}`, nil)
+	var out struct {
+		Results []struct {
+			Index  int    `json:"index"`
+			ID     string `json:"id"`
+			Output string `json:"output"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil || len(out.Results) != 2 {
+		t.Fatalf("batch = %d %s (%v)", rr.Code, rr.Body.String(), err)
+	}
+	if out.Results[0].ID != "a" || out.Results[0].Output != "echo: one" || out.Results[1].Index != 1 {
+		t.Fatalf("results = %+v", out.Results)
+	}
+
+	rr = serve(t, h, http.MethodPost, "/v1/batch", `{"items":[{"prompt":"one"}],"stream":true}`, nil)
+	body := rr.Body.String()
+	if !strings.Contains(body, "event: result") || !strings.Contains(body, "echo: one") || !strings.Contains(body, `event: done`+"\n"+`data: {"failed":0,"total":1}`) {
+		t.Fatalf("stream = %s", body)
+	}
+
+	if rr := serve(t, h, http.MethodPost, "/v1/batch", `{"items":[{"prompt":" "}]}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty prompt = %d", rr.Code)
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	h := NewServer(&fakeBackend{}, Options{Token: testToken}).Handler()
+
+	if rr := serve(t, h, http.MethodGet, "/v1/conversations", "", map[string]string{"Authorization": ""}); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("no token = %d", rr.Code)
+	}
+	if rr := serve(t, h, http.MethodGet, "/v1/conversations", "", nil); rr.Code != http.StatusOK {
+		t.Fatalf("with token = %d", rr.Code)
+	}
+	// A browser page can post text/plain to loopback without a preflight.
+	if rr := serve(t, h, http.MethodPost, "/v1/tools/current_time", "{}", map[string]string{"Authorization": "", "Content-Type": "text/plain"}); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("cross-site post = %d", rr.Code)
+	}
+
+	// Without a token nothing is served, loopback included.
+	open := NewServer(&fakeBackend{}, Options{}).Handler()
+	if rr := serve(t, open, http.MethodGet, "/v1/conversations", "", map[string]string{"Authorization": "Bearer "}); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("tokenless server = %d", rr.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), ".coco", "api.token")
+	first, created, err := LoadOrCreateToken(path)
+	if err != nil || !created || len(first) != 48 {
+		t.Fatalf("first token = %q %v %v", first, created, err)
+	}
+	again, created, err := LoadOrCreateToken(path)
+	if err != nil || created || again != first {
+		t.Fatalf("saved token = %q %v %v", again, created, err)
+	}
+}
+
+func TestChatCompletions(t *testing.T) {
+	backend := &fakeBackend{}
+	h := NewServer(backend, Options{Token: testToken, ToolProfile: "admin"}).Handler()
+
+	body := `{"model":"coco","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"first"},{"role":"assistant","content":"ok"},{"role":"user","content":[{"type":"text","text":"second"}]}]}`
+	rr := serve(t, h, http.MethodPost, "/v1/chat/completions", body, nil)
+	var completion struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &completion); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("completion = %d %s (%v)", rr.Code, rr.Body.String(), err)
+	}
+	if completion.Object != "chat.completion" || len(completion.Choices) != 1 ||
+		completion.Choices[0].Message.Content != "echo: second" || completion.Choices[0].FinishReason != "stop" {
+		t.Fatalf("completion = %+v", completion)
+	}
+	conv := backend.lastMsg.ChannelID
+
+	// Appending turns keeps the conversation; a different opening does not.
+	serve(t, h, http.MethodPost, "/v1/chat/completions",
+		`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"first"},{"role":"user","content":"third"}]}`, nil)
+	if backend.lastMsg.ChannelID != conv {
+		t.Fatalf("conversation changed: %q -> %q", conv, backend.lastMsg.ChannelID)
+	}
+	serve(t, h, http.MethodPost, "/v1/chat/completions", `{"messages":[{"role":"user","content":"other"}]}`, nil)
+	if backend.lastMsg.ChannelID == conv {
+		t.Fatal("unrelated chats must not share a conversation")
+	}
+	serve(t, h, http.MethodPost, "/v1/chat/completions", `{"messages":[{"role":"user","content":"x"}]}`,
+		map[string]string{ConversationHeader: "pinned"})
+	if backend.lastMsg.ChannelID != "pinned" {
+		t.Fatalf("header conversation = %q", backend.lastMsg.ChannelID)
+	}
+
+	rr = serve(t, h, http.MethodPost, "/v1/chat/completions", `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`, nil)
+	stream := rr.Body.String()
+	if !strings.Contains(stream, `"object":"chat.completion.chunk"`) || !strings.Contains(stream, `"content":"echo: hi"`) ||
+		!strings.HasSuffix(stream, "data: [DONE]\n\n") {
+		t.Fatalf("stream = %s", stream)
+	}
+
+	if rr := serve(t, h, http.MethodPost, "/v1/chat/completions", `{"messages":[{"role":"system","content":"x"}]}`, nil); rr.Code != http.StatusBadRequest ||
+		!strings.Contains(rr.Body.String(), "invalid_request_error") {
+		t.Fatalf("no user message = %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(t, h, http.MethodGet, "/v1/models", "", nil); !strings.Contains(rr.Body.String(), `"id":"coco"`) {
+		t.Fatalf("models = %s", rr.Body.String())
+	}
+}
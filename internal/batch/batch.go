@@ -0,0 +1,233 @@
+// Package batch runs many independent prompts through the agent with a
+// bounded number in flight, for scripts that use coco to process data.
+// Each prompt gets its own conversation unless it names one, and identical
+// prompts in a batch are answered once.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+// Platform is the router platform name of batch messages.
+const Platform = "batch"
+
+const (
+	// DefaultConcurrency is how many prompts run at once by default.
+	DefaultConcurrency = 4
+	// MaxConcurrency caps Options.Concurrency.
+	MaxConcurrency = 16
+)
+
+// Item is one prompt of a batch.
+type Item struct {
+	ID           string `json:"id,omitempty"`
+	Prompt       string `json:"prompt"`
+	Conversation string `json:"conversation,omitempty"` // share context with other items naming the same conversation
+}
+
+// Result is the outcome of one item.
+type Result struct {
+	Index      int    `json:"index"`
+	ID         string `json:"id,omitempty"`
+	Prompt     string `json:"prompt"`
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Cached     bool   `json:"cached,omitempty"` // answered from an identical prompt earlier in the batch
+}
+
+// Handler answers one message, like agent.HandleMessage.
+type Handler func(ctx context.Context, msg router.Message) (router.Response, error)
+
+// Options configures Run.
+type Options struct {
+	Concurrency int // prompts in flight (default DefaultConcurrency, at most MaxConcurrency)
+	// RunID keeps the conversations of different runs apart (default the
+	// start time).
+	RunID string
+	// Progress is called after each item finishes, in completion order.
+	Progress func(done, total int, r Result)
+}
+
+// ReadItems reads a JSONL file of items. A line that is not a JSON object
+// is taken as a bare prompt; blank lines are skipped.
+func ReadItems(r io.Reader) ([]Item, error) {
+	var items []Item
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var item Item
+		if strings.HasPrefix(text, "{") {
+			var raw struct {
+				Item
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(text), &raw); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			item = raw.Item
+			if item.Prompt == "" {
+				item.Prompt = raw.Text
+			}
+		} else {
+			item.Prompt = text
+		}
+		if strings.TrimSpace(item.Prompt) == "" {
+			return nil, fmt.Errorf("line %d: prompt is empty", line)
+		}
+		items = append(items, item)
+	}
+	return items, sc.Err()
+}
+
+// Run answers items with handle and calls emit with each result in input
+// order, as soon as it and every earlier item are done. It stops starting
+// new items when ctx is cancelled; those are reported with ctx's error.
+func Run(ctx context.Context, handle Handler, items []Item, opts Options, emit func(Result)) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	concurrency = min(concurrency, MaxConcurrency)
+	runID := opts.RunID
+	if runID == "" {
+		runID = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	r := &runner{
+		handle:  handle,
+		runID:   runID,
+		shared:  map[string]*sharedAnswer{},
+		results: make([]*Result, len(items)),
+		emit:    emit,
+		opts:    opts,
+		total:   len(items),
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			r.finish(Result{Index: i, ID: item.ID, Prompt: item.Prompt, Error: ctx.Err().Error()})
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.finish(r.run(ctx, i, item))
+		}()
+	}
+	wg.Wait()
+}
+
+// sharedAnswer is the answer to a prompt, shared by identical items.
+type sharedAnswer struct {
+	done   chan struct{}
+	output string
+	err    error
+}
+
+type runner struct {
+	handle Handler
+	runID  string
+	opts   Options
+	total  int
+
+	mu       sync.Mutex
+	shared   map[string]*sharedAnswer
+	results  []*Result
+	next     int // first result not emitted yet
+	finished int
+	emit     func(Result)
+}
+
+func (r *runner) run(ctx context.Context, index int, item Item) (res Result) {
+	res = Result{Index: index, ID: item.ID, Prompt: item.Prompt}
+	start := time.Now()
+	defer func() { res.DurationMS = time.Since(start).Milliseconds() }()
+
+	// Only prompts with their own conversation are independent enough to
+	// share an answer.
+	key := ""
+	if item.Conversation == "" {
+		key = strings.TrimSpace(item.Prompt)
+	}
+	r.mu.Lock()
+	answer, cached := r.shared[key]
+	if key != "" && !cached {
+		answer = &sharedAnswer{done: make(chan struct{})}
+		r.shared[key] = answer
+	}
+	r.mu.Unlock()
+
+	if cached {
+		select {
+		case <-answer.done:
+		case <-ctx.Done():
+			res.Error = ctx.Err().Error()
+			return res
+		}
+		res.Cached = true
+	} else {
+		conv := item.Conversation
+		if conv == "" {
+			conv = fmt.Sprintf("%s-%d", r.runID, index)
+		}
+		resp, err := r.handle(ctx, router.Message{
+			Platform:  Platform,
+			ChannelID: conv,
+			UserID:    "batch",
+			Username:  "batch",
+			Text:      item.Prompt,
+			Metadata:  map[string]string{"chat_type": "private"},
+		})
+		if answer == nil {
+			answer = &sharedAnswer{done: make(chan struct{})}
+		}
+		answer.output, answer.err = resp.Text, err
+		close(answer.done)
+	}
+	if answer.err != nil {
+		res.Error = answer.err.Error()
+	} else {
+		res.Output = answer.output
+	}
+	return res
+}
+
+func (r *runner) finish(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[res.Index] = &res
+	r.finished++
+	if r.opts.Progress != nil {
+		r.opts.Progress(r.finished, r.total, res)
+	}
+	for r.next < len(r.results) && r.results[r.next] != nil {
+		if r.emit != nil {
+			r.emit(*r.results[r.next])
+		}
+		r.results[r.next] = nil
+		r.next++
+	}
+}
@@ -0,0 +1,100 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+func TestReadItems(t *testing.T) {
+	items, err := ReadItems(strings.NewReader(`{"id":"a","prompt":"one"}
+
+{"text":"two","conversation":"c"}
+three
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].ID != "a" || items[1].Prompt != "two" || items[1].Conversation != "c" || items[2].Prompt != "three" {
+		t.Fatalf("items = %+v", items)
+	}
+	if _, err := ReadItems(strings.NewReader(`{"id":"x"}`)); err == nil {
+		t.Fatal("expected an error for an item without a prompt")
+	}
+}
+
+func TestRunOrderConcurrencyAndSharing(t *testing.T) {
+	var inFlight, peak, calls atomic.Int32
+	var mu sync.Mutex
+	convs := map[string]bool{}
+	handle := func(_ context.Context, msg router.Message) (router.Response, error) {
+		calls.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		convs[msg.ChannelID] = true
+		mu.Unlock()
+		// Later items finish first, so results arrive out of order.
+		time.Sleep(time.Duration(10-len(msg.Text)) * time.Millisecond)
+		if msg.Text == "fail" {
+			return router.Response{}, errors.New("boom")
+		}
+		return router.Response{Text: strings.ToUpper(msg.Text)}, nil
+	}
+	items := []Item{{Prompt: "a"}, {Prompt: "bb"}, {Prompt: "fail"}, {Prompt: "a"}, {Prompt: "cccc"}, {Prompt: "dddddd"}}
+
+	var got []Result
+	progress := 0
+	Run(context.Background(), handle, items, Options{Concurrency: 2, RunID: "t", Progress: func(done, total int, _ Result) {
+		progress = done
+		if total != len(items) {
+			t.Errorf("total = %d", total)
+		}
+	}}, func(r Result) { got = append(got, r) })
+
+	if len(got) != len(items) || progress != len(items) {
+		t.Fatalf("got %d results, progress %d", len(got), progress)
+	}
+	for i, r := range got {
+		if r.Index != i {
+			t.Fatalf("result %d has index %d", i, r.Index)
+		}
+	}
+	if got[0].Output != "A" || got[2].Error != "boom" || got[5].Output != "DDDDDD" {
+		t.Fatalf("results = %+v", got)
+	}
+	if !got[3].Cached || got[3].Output != "A" || calls.Load() != 5 {
+		t.Fatalf("duplicate prompt was not shared: %+v, %d calls", got[3], calls.Load())
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("%d prompts ran at once, want at most 2", peak.Load())
+	}
+	if len(convs) != 5 || !convs["t-0"] {
+		t.Fatalf("conversations = %v", convs)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var got []Result
+	Run(ctx, func(context.Context, router.Message) (router.Response, error) {
+		t.Error("handler called after cancel")
+		return router.Response{}, nil
+	}, []Item{{Prompt: "a"}, {Prompt: "b"}}, Options{}, func(r Result) { got = append(got, r) })
+	if len(got) != 2 || got[0].Error == "" || got[1].Error == "" {
+		t.Fatalf("results = %+v", got)
+	}
+}
@@ -184,6 +184,24 @@ func ClickAll(page *rod.Page, selector string, delay time.Duration, skipSelector
 	return clicked, nil
 }
 
+// CountMatches reports how many elements currently match selector and how many
+// of those ClickAll would skip. More may appear once ClickAll scrolls the page.
+func CountMatches(page *rod.Page, selector, skipSelector string) (total, skipped int, err error) {
+	res, err := page.Eval(`(sel, skip) => {
+		const all = Array.from(document.querySelectorAll(sel));
+		const skipped = skip ? all.filter(e => e.matches(skip) || e.querySelector(skip) !== null).length : 0;
+		return [all.length, skipped];
+	}`, selector, skipSelector)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count elements matching %q: %w", selector, err)
+	}
+	arr := res.Value.Arr()
+	if len(arr) != 2 {
+		return 0, 0, fmt.Errorf("unexpected count result")
+	}
+	return arr[0].Int(), arr[1].Int(), nil
+}
+
 // resolveRef looks up a ref number in the browser's ref map and returns the corresponding element.
 func resolveRef(page *rod.Page, b *Browser, ref int) (*rod.Element, error) {
 	entry, ok := b.GetRef(ref)
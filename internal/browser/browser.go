@@ -4,53 +4,36 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/logger"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
 )
 
-var (
-	exeDirCache string
-)
-
-func getExecutableDir() string {
-	if exeDirCache != "" {
-		return exeDirCache
-	}
-	execPath, err := os.Executable()
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	exeDirCache = filepath.Dir(execPath)
-	return exeDirCache
-}
-
 // Browser manages a browser instance for automation.
 type Browser struct {
 	mu        sync.Mutex
 	browser   *rod.Browser
 	running   bool
 	headless  bool
-	connected bool // true when attached to external Chrome (don't close on Stop)
-	dataDir   string
+	connected bool   // true when attached to external Chrome (don't close on Stop)
+	profile   string // profile of the launched browser
+	dataDir   string // its user-data dir
 
 	// refs holds the latest snapshot ref map (ref number → RefEntry).
 	refs map[int]RefEntry
 
+	// capture records XHR/fetch traffic after browser_network_start_capture.
+	capture *networkCapture
+
 	// Debug mode configuration
 	debugMode bool
 	debugDir  string
@@ -64,10 +47,8 @@ var (
 // Instance returns the singleton browser manager.
 func Instance() *Browser {
 	once.Do(func() {
-		exeDir := getExecutableDir()
 		instance = &Browser{
 			headless: false,
-			dataDir:  filepath.Join(exeDir, ".coco", "browser"),
 			refs:     make(map[int]RefEntry),
 		}
 	})
@@ -80,6 +61,7 @@ type StartOptions struct {
 	ExecutablePath string
 	URL            string
 	ConnectURL     string // CDP address to connect to existing Chrome (e.g. "127.0.0.1:9222")
+	Profile        string // named profile to launch with; "" uses browser.profile or the default
 }
 
 // Start launches a new browser instance or connects to an existing one.
@@ -96,18 +78,26 @@ func (b *Browser) Start(opts StartOptions) error {
 		return b.connectLocked(opts.ConnectURL, opts.URL)
 	}
 
-	b.headless = opts.Headless
-
-	if err := os.MkdirAll(b.dataDir, 0755); err != nil {
+	cfg, _ := config.Load()
+	profile := opts.Profile
+	if profile == "" {
+		profile = cfg.Browser.Profile
+	}
+	profile = normalizeProfile(profile)
+	dataDir, err := ProfileDir(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data dir: %w", err)
 	}
+	b.headless = opts.Headless
 
 	l := launcher.New().
-		UserDataDir(b.dataDir).
+		UserDataDir(dataDir).
 		Headless(opts.Headless)
 
 	// Apply screen size from config (default: fullscreen)
-	cfg, _ := config.Load()
 	screenSize := cfg.Browser.ScreenSize
 	if screenSize == "" {
 		screenSize = "fullscreen"
@@ -140,8 +130,16 @@ func (b *Browser) Start(opts StartOptions) error {
 	b.browser = brow
 	b.running = true
 	b.connected = false
+	b.profile = profile
+	b.dataDir = dataDir
 	b.refs = make(map[int]RefEntry)
 
+	if n, err := restoreSession(brow, dataDir, time.Now()); err != nil {
+		logger.Warn("[Browser] Failed to restore the session of profile %s: %v", profile, err)
+	} else if n > 0 {
+		logger.Debug("[Browser] Restored %d cookies into profile %s", n, profile)
+	}
+
 	if opts.URL != "" {
 		page, err := brow.Page(proto.TargetCreateTarget{URL: opts.URL})
 		if err != nil {
@@ -192,19 +190,36 @@ func (b *Browser) Stop() error {
 	}
 
 	if !b.connected {
+		if err := saveSession(b.browser, b.dataDir); err != nil {
+			logger.Warn("[Browser] Failed to save the session of profile %s: %v", b.profile, err)
+		}
 		if err := b.browser.Close(); err != nil {
 			return fmt.Errorf("failed to close browser: %w", err)
 		}
 	}
 	// When connected to external Chrome, just drop the reference — don't close it.
+	b.stopCaptureLocked()
 
 	b.browser = nil
 	b.running = false
 	b.connected = false
+	b.profile = ""
+	b.dataDir = ""
 	b.refs = make(map[int]RefEntry)
 	return nil
 }
 
+// Shutdown stops a browser coco launched so its session is saved before
+// coco exits; it does nothing when no browser is running.
+func (b *Browser) Shutdown() {
+	if !b.IsRunning() {
+		return
+	}
+	if err := b.Stop(); err != nil {
+		logger.Warn("[Browser] Failed to stop the browser: %v", err)
+	}
+}
+
 // EnsureRunning starts the browser if not already running.
 // Tries to connect to existing Chrome on port 9222 first, then launches a new one.
 func (b *Browser) EnsureRunning() error {
@@ -292,6 +307,7 @@ type StatusInfo struct {
 	Running   bool   `json:"running"`
 	Headless  bool   `json:"headless"`
 	Connected bool   `json:"connected"` // attached to external Chrome (vs launched)
+	Profile   string `json:"profile,omitempty"`
 	Pages     int    `json:"pages"`
 	ActiveURL string `json:"active_url"`
 }
@@ -323,6 +339,16 @@ func parseScreenSize(s string) (width, height int, ok bool) {
 	return w, h, true
 }
 
+// ExecutablePath returns the Chrome or Chromium browser_start would run,
+// or "" when it would have to download one first.
+func ExecutablePath() string {
+	if p := detectChrome(); p != "" {
+		return p
+	}
+	p, _ := launcher.LookPath()
+	return p
+}
+
 // detectChrome returns the path to a local Chrome installation, or empty string if not found.
 func detectChrome() string {
 	switch runtime.GOOS {
@@ -355,6 +381,7 @@ func (b *Browser) Status() StatusInfo {
 		Running:   b.running,
 		Headless:  b.headless,
 		Connected: b.connected,
+		Profile:   b.profile,
 	}
 
 	if !b.running {
@@ -0,0 +1,255 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	// DefaultMaxBodyBytes caps each captured request and response body.
+	DefaultMaxBodyBytes = 32 * 1024
+	// maxNetworkEntries is how many requests a capture keeps; older ones
+	// are dropped first.
+	maxNetworkEntries = 200
+)
+
+// NetworkEntry is an XHR or fetch request seen while capturing.
+type NetworkEntry struct {
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	Status       int       `json:"status,omitempty"`
+	MIMEType     string    `json:"mime_type,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Truncated    bool      `json:"truncated,omitempty"` // a body was cut at the capture's limit
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// networkCapture records the XHR and fetch traffic of the open tabs.
+type networkCapture struct {
+	filter  string
+	maxBody int
+	cancel  context.CancelFunc
+
+	mu      sync.Mutex
+	entries []*NetworkEntry
+	pending map[proto.NetworkRequestID]*NetworkEntry
+}
+
+func newNetworkCapture(filter string, maxBody int) *networkCapture {
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodyBytes
+	}
+	return &networkCapture{
+		filter:  filter,
+		maxBody: maxBody,
+		pending: map[proto.NetworkRequestID]*NetworkEntry{},
+	}
+}
+
+// StartNetworkCapture records the XHR and fetch requests of every open tab,
+// with URLs containing filter when it is set, until the browser stops or
+// the capture is restarted. It returns how many tabs it listens to.
+func (b *Browser) StartNetworkCapture(filter string, maxBody int) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.running {
+		return 0, fmt.Errorf("browser not running")
+	}
+	b.stopCaptureLocked()
+
+	pages, err := b.browser.Pages()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pages: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newNetworkCapture(strings.TrimSpace(filter), maxBody)
+	c.cancel = cancel
+	for _, page := range pages {
+		c.listen(page.Context(ctx))
+	}
+	b.capture = c
+	return len(pages), nil
+}
+
+// StopNetworkCapture ends the capture; its requests stay readable.
+func (b *Browser) StopNetworkCapture() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopCaptureLocked()
+}
+
+func (b *Browser) stopCaptureLocked() {
+	if b.capture != nil && b.capture.cancel != nil {
+		b.capture.cancel()
+		b.capture.cancel = nil
+	}
+}
+
+// NetworkRequests returns the captured requests whose URL contains filter,
+// oldest first, and whether a capture is running. clear forgets them.
+func (b *Browser) NetworkRequests(filter string, clear bool) ([]NetworkEntry, bool, error) {
+	b.mu.Lock()
+	c := b.capture
+	capturing := c != nil && c.cancel != nil
+	b.mu.Unlock()
+	if c == nil {
+		return nil, false, fmt.Errorf("no network capture; call browser_network_start_capture first")
+	}
+	return c.list(filter, clear), capturing, nil
+}
+
+func (c *networkCapture) listen(page *rod.Page) {
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		return
+	}
+	go page.EachEvent(
+		func(e *proto.NetworkRequestWillBeSent) {
+			if e.Request != nil {
+				c.request(e.RequestID, e.Type, e.Request.Method, e.Request.URL, e.Request.PostData, time.Now())
+			}
+		},
+		func(e *proto.NetworkResponseReceived) {
+			if e.Response != nil {
+				c.response(e.RequestID, e.Response.Status, e.Response.MIMEType)
+			}
+		},
+		func(e *proto.NetworkLoadingFinished) {
+			if entry := c.finished(e.RequestID); entry != nil {
+				// Fetching the body is a CDP call, which must not block the
+				// event loop.
+				go c.fetchBody(page, e.RequestID, entry)
+			}
+		},
+		func(e *proto.NetworkLoadingFailed) {
+			c.failed(e.RequestID, e.ErrorText)
+		},
+	)()
+}
+
+// request starts an entry for XHR and fetch requests that pass the filter.
+func (c *networkCapture) request(id proto.NetworkRequestID, typ proto.NetworkResourceType, method, url, body string, at time.Time) {
+	if typ != proto.NetworkResourceTypeXHR && typ != proto.NetworkResourceTypeFetch {
+		return
+	}
+	if c.filter != "" && !strings.Contains(url, c.filter) {
+		return
+	}
+	entry := &NetworkEntry{Method: method, URL: url, Time: at}
+	entry.RequestBody, entry.Truncated = truncateBody(body, c.maxBody)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[id] = entry
+	c.entries = append(c.entries, entry)
+	if over := len(c.entries) - maxNetworkEntries; over > 0 {
+		for _, old := range c.entries[:over] {
+			for pid, p := range c.pending {
+				if p == old {
+					delete(c.pending, pid)
+				}
+			}
+		}
+		c.entries = append([]*NetworkEntry(nil), c.entries[over:]...)
+	}
+}
+
+func (c *networkCapture) response(id proto.NetworkRequestID, status int, mime string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.pending[id]; entry != nil {
+		entry.Status = status
+		entry.MIMEType = mime
+	}
+}
+
+// finished returns the entry whose body should be fetched, or nil when the
+// request is not captured or its body is not text.
+func (c *networkCapture) finished(id proto.NetworkRequestID) *NetworkEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.pending[id]
+	delete(c.pending, id)
+	if entry == nil || !textualMIME(entry.MIMEType) {
+		return nil
+	}
+	return entry
+}
+
+func (c *networkCapture) failed(id proto.NetworkRequestID, errText string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.pending[id]; entry != nil {
+		entry.Error = errText
+		delete(c.pending, id)
+	}
+}
+
+func (c *networkCapture) fetchBody(page *rod.Page, id proto.NetworkRequestID, entry *NetworkEntry) {
+	res, err := proto.NetworkGetResponseBody{RequestID: id}.Call(page)
+	if err != nil {
+		return
+	}
+	body := res.Body
+	if res.Base64Encoded {
+		raw, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return
+		}
+		body = string(raw)
+	}
+	c.setBody(entry, body)
+}
+
+func (c *networkCapture) setBody(entry *NetworkEntry, body string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var cut bool
+	entry.ResponseBody, cut = truncateBody(body, c.maxBody)
+	entry.Truncated = entry.Truncated || cut
+}
+
+func (c *networkCapture) list(filter string, clear bool) []NetworkEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []NetworkEntry
+	for _, e := range c.entries {
+		if filter == "" || strings.Contains(e.URL, filter) {
+			out = append(out, *e)
+		}
+	}
+	if clear {
+		c.entries = nil
+		c.pending = map[proto.NetworkRequestID]*NetworkEntry{}
+	}
+	return out
+}
+
+// textualMIME reports whether a response body is worth keeping: JSON, text
+// or XML rather than images, fonts and other binary data.
+func textualMIME(mime string) bool {
+	mime = strings.ToLower(mime)
+	return strings.Contains(mime, "json") || strings.HasPrefix(mime, "text/") ||
+		strings.Contains(mime, "xml") || strings.Contains(mime, "x-www-form-urlencoded")
+}
+
+// truncateBody cuts s to at most n bytes without splitting a character.
+func truncateBody(s string, n int) (string, bool) {
+	if len(s) <= n {
+		return s, false
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n], true
+}
@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestNetworkCaptureRecordsAPIRequests(t *testing.T) {
+	c := newNetworkCapture("/api/", 16)
+	now := time.Now()
+
+	c.request("1", proto.NetworkResourceTypeFetch, "POST", "https://example.com/api/search", `{"q":"咖啡"}`, now)
+	c.request("2", proto.NetworkResourceTypeImage, "GET", "https://example.com/api/logo.png", "", now)
+	c.request("3", proto.NetworkResourceTypeXHR, "GET", "https://example.com/static/app.js", "", now)
+	c.request("4", proto.NetworkResourceTypeXHR, "GET", "https://example.com/api/broken", "", now)
+
+	c.response("1", 200, "application/json; charset=utf-8")
+	entry := c.finished("1")
+	if entry == nil {
+		t.Fatal("JSON response was not queued for its body")
+	}
+	c.setBody(entry, `{"items":[{"title":"咖啡豆"}]}`)
+	c.failed("4", "net::ERR_FAILED")
+
+	got := c.list("", false)
+	if len(got) != 2 {
+		t.Fatalf("captured %d requests, want the fetch and the failed XHR: %+v", len(got), got)
+	}
+	search := got[0]
+	if search.Method != "POST" || search.Status != 200 || search.RequestBody != `{"q":"咖啡"}` {
+		t.Fatalf("search = %+v", search)
+	}
+	if !search.Truncated || len(search.ResponseBody) > 16 || !strings.HasPrefix(search.ResponseBody, `{"items"`) {
+		t.Fatalf("response body = %q (truncated %v)", search.ResponseBody, search.Truncated)
+	}
+	if got[1].Error != "net::ERR_FAILED" {
+		t.Fatalf("failed request = %+v", got[1])
+	}
+
+	if got := c.list("search", true); len(got) != 1 {
+		t.Fatalf("filtered list = %d", len(got))
+	}
+	if got := c.list("", false); len(got) != 0 {
+		t.Fatalf("clear kept %d requests", len(got))
+	}
+}
+
+func TestNetworkCaptureSkipsBinaryBodiesAndCaps(t *testing.T) {
+	c := newNetworkCapture("", 0)
+	c.request("img", proto.NetworkResourceTypeXHR, "GET", "https://example.com/a.png", "", time.Now())
+	c.response("img", 200, "image/png")
+	if c.finished("img") != nil {
+		t.Fatal("binary body was queued")
+	}
+
+	for i := 0; i < maxNetworkEntries+5; i++ {
+		c.request(proto.NetworkRequestID(strings.Repeat("x", i+1)), proto.NetworkResourceTypeFetch, "GET", "https://example.com/p", "", time.Now())
+	}
+	if n := len(c.list("", false)); n != maxNetworkEntries {
+		t.Fatalf("kept %d requests, want %d", n, maxNetworkEntries)
+	}
+	if len(c.pending) > maxNetworkEntries {
+		t.Fatalf("pending grew to %d", len(c.pending))
+	}
+}
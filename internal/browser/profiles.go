@@ -0,0 +1,236 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/kayz/coco/internal/datadir"
+)
+
+// DefaultProfile is the profile browser_start uses without a profile
+// argument. It keeps the user-data dir coco used before named profiles.
+const DefaultProfile = "default"
+
+// sessionFile holds the cookies saved when the browser stops, including
+// session cookies Chrome itself would drop on restart.
+const sessionFile = "coco-session.json"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// cacheDirs are the parts of a profile that clean removes; cookies, local
+// storage and saved logins stay.
+var cacheDirs = []string{
+	filepath.Join("Default", "Cache"),
+	filepath.Join("Default", "Code Cache"),
+	filepath.Join("Default", "GPUCache"),
+	filepath.Join("Default", "Service Worker", "CacheStorage"),
+	filepath.Join("Default", "Service Worker", "ScriptCache"),
+	"GrShaderCache",
+	"GraphiteDawnCache",
+	"ShaderCache",
+	"component_crx_cache",
+}
+
+// Profile is a browser user-data dir kept across restarts.
+type Profile struct {
+	Name     string    `json:"name"`
+	Dir      string    `json:"dir"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	InUse    bool      `json:"in_use"`
+}
+
+// ProfilesDir is where named profiles live.
+func ProfilesDir() string {
+	return datadir.Path(".coco", "browser-profiles")
+}
+
+// ProfileDir returns the user-data dir of the named profile; "" is the
+// default profile.
+func ProfileDir(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == DefaultProfile {
+		return datadir.Path(".coco", "browser"), nil
+	}
+	if !profileNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q (use letters, digits, - and _)", name)
+	}
+	return filepath.Join(ProfilesDir(), name), nil
+}
+
+// ListProfiles returns the default profile, when it exists, followed by the
+// named profiles in name order.
+func ListProfiles() ([]Profile, error) {
+	var profiles []Profile
+	if p, err := readProfile(DefaultProfile); err == nil {
+		profiles = append(profiles, p)
+	}
+	entries, err := os.ReadDir(ProfilesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		if !e.IsDir() || !profileNamePattern.MatchString(e.Name()) {
+			continue
+		}
+		p, err := readProfile(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func readProfile(name string) (Profile, error) {
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Profile{}, err
+	}
+	p := Profile{Name: name, Dir: dir, Size: dirSize(dir), Modified: info.ModTime(), InUse: profileInUse(name, dir)}
+	// Chrome rewrites Local State every time it runs with the profile.
+	if st, err := os.Stat(filepath.Join(dir, "Local State")); err == nil {
+		p.Modified = st.ModTime()
+	}
+	return p, nil
+}
+
+// profileInUse reports whether coco or another Chrome has the profile open.
+func profileInUse(name, dir string) bool {
+	b := Instance()
+	b.mu.Lock()
+	current, running := b.profile, b.running && !b.connected
+	b.mu.Unlock()
+	if running && current == normalizeProfile(name) {
+		return true
+	}
+	for _, lock := range []string{"SingletonLock", "lockfile"} {
+		if _, err := os.Lstat(filepath.Join(dir, lock)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanProfile deletes the caches of the named profile and returns how many
+// bytes that freed. Logins survive.
+func CleanProfile(name string) (int64, error) {
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return 0, fmt.Errorf("profile %q not found", normalizeProfile(name))
+	}
+	if profileInUse(name, dir) {
+		return 0, fmt.Errorf("profile %q is in use; stop the browser first", normalizeProfile(name))
+	}
+	var freed int64
+	for _, rel := range cacheDirs {
+		path := filepath.Join(dir, rel)
+		size := dirSize(path)
+		if err := os.RemoveAll(path); err != nil {
+			return freed, err
+		}
+		freed += size
+	}
+	return freed, nil
+}
+
+// RemoveProfile deletes the named profile with its logins.
+func RemoveProfile(name string) error {
+	dir, err := ProfileDir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("profile %q not found", normalizeProfile(name))
+	}
+	if profileInUse(name, dir) {
+		return fmt.Errorf("profile %q is in use; stop the browser first", normalizeProfile(name))
+	}
+	return os.RemoveAll(dir)
+}
+
+func normalizeProfile(name string) string {
+	if name = strings.TrimSpace(name); name == "" {
+		return DefaultProfile
+	}
+	return name
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// saveSession writes the browser's cookies into dir so restoreSession can
+// bring back logins that live in session cookies.
+func saveSession(brow *rod.Browser, dir string) error {
+	cookies, err := brow.GetCookies()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cookies)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, sessionFile), data, 0600)
+}
+
+// restoreSession loads the cookies saveSession wrote, skipping expired ones.
+func restoreSession(brow *rod.Browser, dir string, now time.Time) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, sessionFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var saved []*proto.NetworkCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return 0, err
+	}
+	live := liveCookies(saved, now)
+	if len(live) == 0 {
+		return 0, nil
+	}
+	return len(live), brow.SetCookies(proto.CookiesToParams(live))
+}
+
+func liveCookies(cookies []*proto.NetworkCookie, now time.Time) []*proto.NetworkCookie {
+	var live []*proto.NetworkCookie
+	for _, c := range cookies {
+		if c == nil || c.Name == "" {
+			continue
+		}
+		if !c.Session && c.Expires > 0 && c.Expires.Time().Before(now) {
+			continue
+		}
+		live = append(live, c)
+	}
+	return live
+}
@@ -0,0 +1,594 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/provenance"
+	"gopkg.in/yaml.v3"
+)
+
+// Field is one key of .coco.yaml in the schema.
+type Field struct {
+	Key  string // dotted path; "*" stands for a map key and "#" for a list index
+	Type string // string, int, bool, float, duration, clock, list, map or object
+}
+
+// durationKeys are string fields holding a Go duration such as "30m".
+var durationKeys = map[string]bool{
+	"model_cooldown":                true,
+	"sync.interval":                 true,
+	"tools.timeouts.*":              true,
+	"travel.flight_reminder":        true,
+	"travel.train_reminder":         true,
+	"watchdog.provider_timeout":     true,
+	"watchdog.tool_timeout":         true,
+	"keeper.offline_queue_ttl":      true,
+	"keeper.spam.ban_duration":      true,
+	"platforms.email.poll_interval": true,
+	"planner.local_timeout":         true,
+	"proactive.idle":                true,
+	"tools.file_send.link_ttl":      true,
+	"tools.unfurl.timeout":          true,
+}
+
+// clockKeys are string fields holding a time of day, HH:MM.
+var clockKeys = map[string]bool{
+	"briefing.time": true,
+}
+
+// policyKeys are string fields holding a model routing policy.
+var policyKeys = map[string]bool{
+	"routing.policy":     true,
+	"routing.planner":    true,
+	"routing.tags.*":     true,
+	"channels.*.routing": true,
+}
+
+var routingPolicies = []string{"cheapest-capable", "fastest", "best-quality"}
+
+var configType = reflect.TypeOf(Config{})
+
+// Schema lists every key of .coco.yaml with its type, in file order.
+func Schema() []Field {
+	var fields []Field
+	walkSchema(configType, "", &fields)
+	return fields
+}
+
+func walkSchema(t reflect.Type, prefix string, out *[]Field) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		ft := t.Field(i).Type
+		*out = append(*out, Field{Key: key, Type: fieldType(key, ft)})
+		walkChildren(ft, key, out)
+	}
+}
+
+func walkChildren(t reflect.Type, key string, out *[]Field) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		walkSchema(t, key, out)
+	case reflect.Map, reflect.Slice:
+		elem := t.Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct && elem.Kind() != reflect.Map {
+			if t.Kind() == reflect.Map && durationKeys[key+".*"] {
+				*out = append(*out, Field{Key: key + ".*", Type: "duration"})
+			}
+			return
+		}
+		sub := key + ".*"
+		if t.Kind() == reflect.Slice {
+			sub = key + ".#"
+		}
+		*out = append(*out, Field{Key: sub, Type: fieldType(sub, elem)})
+		walkChildren(elem, sub, out)
+	}
+}
+
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func fieldType(key string, t reflect.Type) string {
+	if durationKeys[key] {
+		return "duration"
+	}
+	if clockKeys[key] {
+		return "clock"
+	}
+	if policyKeys[key] {
+		return "policy"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	default:
+		return "object"
+	}
+}
+
+// lookupType returns the Go type and schema key of a dotted config key.
+// Map keys and list indexes in key match "*" and "#" in the schema key.
+func lookupType(key string) (reflect.Type, string, error) {
+	t := configType
+	var schemaKey string
+	for _, seg := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			found := false
+			for i := 0; i < t.NumField(); i++ {
+				if yamlName(t.Field(i)) == seg {
+					t = t.Field(i).Type
+					schemaKey = joinKey(schemaKey, seg)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, "", unknownKeyError(key)
+			}
+		case reflect.Map:
+			if seg == "" {
+				return nil, "", unknownKeyError(key)
+			}
+			t = t.Elem()
+			schemaKey = joinKey(schemaKey, "*")
+		case reflect.Slice:
+			if _, err := strconv.Atoi(seg); err != nil {
+				return nil, "", fmt.Errorf("%s: %q is not a list index", key, seg)
+			}
+			t = t.Elem()
+			schemaKey = joinKey(schemaKey, "#")
+		default:
+			return nil, "", unknownKeyError(key)
+		}
+	}
+	return t, schemaKey, nil
+}
+
+func unknownKeyError(key string) error {
+	if s := suggestKey(key); s != "" {
+		return fmt.Errorf("unknown config key %q (did you mean %q?)", key, s)
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// suggestKey returns the schema key closest to key, or "" if none is close.
+func suggestKey(key string) string {
+	best, bestDist := "", len(key)/3+1
+	for _, f := range Schema() {
+		if strings.ContainsAny(f.Key, "*#") {
+			continue
+		}
+		if d := editDistance(key, f.Key); d < bestDist {
+			best, bestDist = f.Key, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Get returns the value of a dotted key, e.g. "security.allowed_paths".
+func (c *Config) Get(key string) (any, error) {
+	if _, _, err := lookupType(key); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(c).Elem()
+	for _, seg := range strings.Split(key, ".") {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if yamlName(v.Type().Field(i)) == seg {
+					v = v.Field(i)
+					break
+				}
+			}
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(seg))
+			if !v.IsValid() {
+				return nil, fmt.Errorf("%s is not set", key)
+			}
+		case reflect.Slice:
+			i, _ := strconv.Atoi(seg)
+			if i < 0 || i >= v.Len() {
+				return nil, fmt.Errorf("%s: index %d out of range (%d items)", key, i, v.Len())
+			}
+			v = v.Index(i)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// ParseValue converts the command-line text of a value to the type of key.
+// Strings are taken as they are; lists of strings may be comma separated;
+// anything else is read as YAML, e.g. "true", "8080" or "[a, b]".
+func ParseValue(key, text string) (any, error) {
+	t, schemaKey, err := lookupType(key)
+	if err != nil {
+		return nil, err
+	}
+	ptr := reflect.New(t)
+	switch {
+	case t.Kind() == reflect.String:
+		ptr.Elem().SetString(text)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(text), "["):
+		var items []string
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		ptr.Elem().Set(reflect.ValueOf(items))
+	default:
+		if err := yaml.Unmarshal([]byte(text), ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("%s expects %s: %v", key, fieldType(schemaKey, t), yamlErrorText(err))
+		}
+	}
+	if err := checkValue(schemaKey, ptr.Elem().Interface()); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// checkValue validates durations, times of day and routing policies.
+func checkValue(schemaKey string, v any) error {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	switch fieldType(schemaKey, reflect.TypeOf(v)) {
+	case "duration":
+		if s == "0" || s == "off" {
+			return nil
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s, 15m or 24h", s)
+		}
+	case "clock":
+		if _, err := time.Parse("15:04", s); err != nil {
+			return fmt.Errorf("%q is not a time of day such as 07:30", s)
+		}
+	case "policy":
+		if !slices.Contains(routingPolicies, s) {
+			return fmt.Errorf("%q is not %s", s, strings.Join(routingPolicies, ", "))
+		}
+	}
+	return nil
+}
+
+func yamlErrorText(err error) string {
+	return strings.TrimPrefix(err.Error(), "yaml: ")
+}
+
+// SetValue sets key in the config file at path, keeping the rest of the
+// file, including comments, as it is. The value is checked against the
+// schema and the resulting file must still load.
+func SetValue(path, key, text string) error {
+	value, err := ParseValue(key, text)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %v", path, yamlErrorText(err))
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if err := setNode(doc.Content[0], strings.Split(key, "."), &node); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	enc.Close()
+	if err := yaml.Unmarshal(buf.Bytes(), DefaultConfig()); err != nil {
+		return fmt.Errorf("%s: %v", key, yamlErrorText(err))
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	_ = provenance.RecordConfigChange(path, provenance.Record{Origin: provenance.OriginCLI, Actor: "coco config set"})
+	return nil
+}
+
+// setNode replaces the value at path below n, creating mappings on the way.
+func setNode(n *yaml.Node, path []string, value *yaml.Node) error {
+	seg := path[0]
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value != seg {
+				continue
+			}
+			if len(path) == 1 {
+				old := n.Content[i+1]
+				value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+				n.Content[i+1] = value
+				return nil
+			}
+			return setNode(n.Content[i+1], path[1:], value)
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}
+		if len(path) == 1 {
+			n.Content = append(n.Content, key, value)
+			return nil
+		}
+		child := &yaml.Node{Kind: yaml.MappingNode}
+		n.Content = append(n.Content, key, child)
+		return setNode(child, path[1:], value)
+	case yaml.SequenceNode:
+		i, _ := strconv.Atoi(seg)
+		if i < 0 || i >= len(n.Content) {
+			return fmt.Errorf("index %d out of range (%d items)", i, len(n.Content))
+		}
+		if len(path) == 1 {
+			n.Content[i] = value
+			return nil
+		}
+		return setNode(n.Content[i], path[1:], value)
+	default:
+		// A null or scalar placeholder such as "platforms:" with no value.
+		*n = yaml.Node{Kind: yaml.MappingNode}
+		return setNode(n, path, value)
+	}
+}
+
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (\S+) not found in type config\.(\w+)$`)
+
+// Validate checks the config file at path: YAML syntax, unknown keys,
+// value types, and values the schema constrains further. It returns one
+// line per problem.
+func Validate(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var problems []string
+	if err := dec.Decode(cfg); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return []string{yamlErrorText(err)}, nil
+		}
+		for _, msg := range typeErr.Errors {
+			if m := unknownFieldPattern.FindStringSubmatch(msg); m != nil {
+				msg = fmt.Sprintf("line %s: unknown key %q", m[1], m[2])
+				if s := suggestSibling(m[3], m[2]); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+			}
+			problems = append(problems, msg)
+		}
+	}
+	return append(problems, cfg.Check()...), nil
+}
+
+// suggestSibling suggests a key of the config struct named typeName.
+func suggestSibling(typeName, name string) string {
+	t := findStruct(configType, typeName, map[reflect.Type]bool{})
+	if t == nil {
+		return ""
+	}
+	best, bestDist := "", len(name)/3+1
+	for i := 0; i < t.NumField(); i++ {
+		if k := yamlName(t.Field(i)); k != "" {
+			if d := editDistance(name, k); d < bestDist {
+				best, bestDist = k, d
+			}
+		}
+	}
+	return best
+}
+
+func findStruct(t reflect.Type, name string, seen map[reflect.Type]bool) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	if t.Name() == name {
+		return t
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if found := findStruct(t.Field(i).Type, name, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Check reports values that load but cannot work: unknown enum values,
+// malformed durations and times of day, out-of-range numbers.
+func (c *Config) Check() []string {
+	var problems []string
+	bad := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if c.Transport != "" && c.Transport != "stdio" && c.Transport != "sse" {
+		bad("transport: %q is not stdio or sse", c.Transport)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		bad("port: %d is out of range", c.Port)
+	}
+	if c.Mode != "" && c.Mode != "relay" && c.Mode != "router" {
+		bad("mode: %q is not relay or router", c.Mode)
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "", "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+	default:
+		bad("logging.level: %q is not trace, debug, info, warn or error", c.Logging.Level)
+	}
+	if c.Planner.MinConfidence < 0 || c.Planner.MinConfidence > 1 {
+		bad("planner.min_confidence: %g is not between 0 and 1", c.Planner.MinConfidence)
+	}
+	if c.Traces.RetentionDays < 0 {
+		bad("traces.retention_days: %d is negative", c.Traces.RetentionDays)
+	}
+	if c.Retention.Days < 0 {
+		bad("retention.days: %d is negative", c.Retention.Days)
+	}
+	for _, cat := range slices.Sorted(maps.Keys(c.Retention.Categories)) {
+		if !slices.Contains(RetentionCategories, cat) {
+			bad("retention.categories.%s: not one of %s", cat, strings.Join(RetentionCategories, ", "))
+		} else if c.Retention.Categories[cat] < -1 {
+			bad("retention.categories.%s: %d is below -1", cat, c.Retention.Categories[cat])
+		}
+	}
+	if c.Backup.Days < 0 {
+		bad("backup.days: %d is negative", c.Backup.Days)
+	}
+	for _, ch := range slices.Sorted(maps.Keys(c.Channels)) {
+		if err := checkValue("channels.*.routing", c.Channels[ch].Routing); err != nil {
+			bad("channels.%s.routing: %v", ch, err)
+		}
+	}
+	for _, model := range slices.Sorted(maps.Keys(c.Routing.Budgets)) {
+		if c.Routing.Budgets[model] < 0 {
+			bad("routing.budgets.%s: %g is negative", model, c.Routing.Budgets[model])
+		}
+	}
+
+	keys := make([]string, 0, len(durationKeys)+len(clockKeys)+len(policyKeys))
+	for k := range durationKeys {
+		keys = append(keys, k)
+	}
+	for k := range clockKeys {
+		keys = append(keys, k)
+	}
+	for k := range policyKeys {
+		if !strings.HasPrefix(k, "channels.") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if base, ok := strings.CutSuffix(k, ".*"); ok {
+			v, _ := c.Get(base)
+			m, _ := v.(map[string]string)
+			names := make([]string, 0, len(m))
+			for name := range m {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if err := checkValue(k, m[name]); err != nil {
+					bad("%s.%s: %v", base, name, err)
+				}
+			}
+			continue
+		}
+		if v, err := c.Get(k); err == nil {
+			if err := checkValue(k, v); err != nil {
+				bad("%s: %v", k, err)
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaTypes(t *testing.T) {
+	types := map[string]string{}
+	for _, f := range Schema() {
+		types[f.Key] = f.Type
+	}
+	for key, want := range map[string]string{
+		"port":                          "int",
+		"security.disable_file_tools":   "bool",
+		"security.allowed_paths":        "list",
+		"platforms.telegram.token":      "string",
+		"channels.*.persona":            "string",
+		"tools.timeouts.*":              "duration",
+		"briefing.time":                 "clock",
+		"traces.retention_days":         "int",
+		"platforms.email.poll_interval": "duration",
+	} {
+		if types[key] != want {
+			t.Errorf("%s: type %q, want %q", key, types[key], want)
+		}
+	}
+}
+
+func TestSetValueKeepsCommentsAndChecksTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".coco.yaml")
+	original := `# coco settings
+port: 8686 # MCP port
+security:
+  allowed_paths:
+    - /tmp
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range map[string]string{
+		"port":                     "9000",
+		"security.allowed_paths":   "/tmp, /srv/data",
+		"platforms.telegram.token": "123:abc",
+		"tools.timeouts.web_fetch": "45s",
+	} {
+		if err := SetValue(path, key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# coco settings") || !strings.Contains(string(data), "port: 9000 # MCP port") {
+		t.Fatalf("comments lost:\n%s", data)
+	}
+	cfg, err := LoadFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 || strings.Join(cfg.Security.AllowedPaths, ",") != "/tmp,/srv/data" ||
+		cfg.Platforms.Telegram.Token != "123:abc" || cfg.Tools.Timeouts["web_fetch"] != "45s" {
+		t.Fatalf("config = %+v", cfg)
+	}
+	if v, _ := cfg.Get("security.allowed_paths"); len(v.([]string)) != 2 {
+		t.Fatalf("get = %v", v)
+	}
+
+	for key, value := range map[string]string{
+		"port":                       "eighty",
+		"security.disable_file_tool": "true",
+		"briefing.time":              "7.30",
+		"watchdog.tool_timeout":      "ten minutes",
+	} {
+		if err := SetValue(path, key, value); err == nil {
+			t.Errorf("set %s=%s accepted", key, value)
+		}
+	}
+	if err := SetValue(path, "securty.allowed_paths", "/x"); err == nil || !strings.Contains(err.Error(), `did you mean "security.allowed_paths"`) {
+		t.Fatalf("typo error = %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Fatal("rejected values changed the file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".coco.yaml")
+	content := `mode: relay
+security:
+  alowed_paths: [/tmp]
+logging:
+  level: loud
+briefing:
+  time: "25:00"
+tools:
+  timeouts:
+    web_fetch: soon
+routing:
+  tags:
+    complex: smartest
+channels:
+  ops:
+    routing: fastest
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := Validate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(problems, "\n")
+	for _, want := range []string{
+		`line 3: unknown key "alowed_paths" (did you mean "allowed_paths"?)`,
+		`logging.level: "loud"`,
+		`briefing.time: "25:00" is not a time of day`,
+		`tools.timeouts.web_fetch: "soon" is not a duration`,
+		`routing.tags.complex: "smartest" is not cheapest-capable`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if len(problems) != 5 {
+		t.Fatalf("problems:\n%s", got)
+	}
+}
@@ -0,0 +1,38 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+)
+
+type jobKey struct{}
+
+// WithJob marks ctx as running job, so a PromptExecutor can see which job
+// a prompt belongs to.
+func WithJob(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// JobFromContext returns the job ctx runs, or nil outside a scheduled run.
+func JobFromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobKey{}).(*Job)
+	return job
+}
+
+// SetContext records the conversation that asked for an existing job and
+// saves it. Prompt runs get it back through JobFromContext.
+func (s *Scheduler) SetContext(id, text string) error {
+	s.mu.Lock()
+	job, exists := s.jobs[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("job not found: %s", id)
+	}
+	job.Context = text
+	s.mu.Unlock()
+
+	if err := s.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package cron
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+type contextRecordingExecutor struct {
+	context string
+}
+
+func (e *contextRecordingExecutor) ExecutePrompt(ctx context.Context, platform, channelID, userID, prompt string) (string, error) {
+	if job := JobFromContext(ctx); job != nil {
+		e.context = job.Context
+	}
+	return "ok", nil
+}
+
+func TestPromptRunsSeeTheirJobContext(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	exec := &contextRecordingExecutor{}
+	s := NewScheduler(store, nil, exec, &testNotifier{})
+	job, err := s.AddJobWithPrompt("progress", "0 9 * * *", "提醒我项目进展", "slack", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	want := "用户: 我在重构 coco 的 cron 模块，每天提醒我项目进展"
+	if err := s.SetContext(job.ID, want); err != nil {
+		t.Fatalf("set context: %v", err)
+	}
+
+	s.executeJob(job)
+	if exec.context != want {
+		t.Fatalf("executor saw context %q, want %q", exec.context, want)
+	}
+
+	jobs, err := store.Load()
+	if err != nil || len(jobs) != 1 || jobs[0].Context != want {
+		t.Fatalf("context not persisted: %v %+v", err, jobs)
+	}
+}
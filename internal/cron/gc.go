@@ -0,0 +1,228 @@
+package cron
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TargetChecker reports whether a job's chat target can still receive messages.
+type TargetChecker interface {
+	TargetAvailable(platform, channelID, userID string) error
+}
+
+// GCConfig controls garbage collection of stale jobs. A job is stale when it
+// has failed MaxFailures times in a row or its target platform is gone. Its
+// owner is warned first; if it is still stale after GracePeriod it is deleted.
+type GCConfig struct {
+	MaxFailures int           // Consecutive failures before a job is stale (0 disables the check)
+	GracePeriod time.Duration // Time between the warning and deletion
+	Interval    time.Duration // How often to sweep
+	Targets     TargetChecker // Optional; without it targets are not checked
+
+	// Where summaries go. When empty, each job's own chat target is used if it
+	// is still reachable, otherwise the summary is only logged.
+	NotifyPlatform  string
+	NotifyChannelID string
+	NotifyUserID    string
+}
+
+// DefaultGCConfig returns the defaults used when the config leaves fields unset.
+func DefaultGCConfig() GCConfig {
+	return GCConfig{
+		MaxFailures: 5,
+		GracePeriod: 24 * time.Hour,
+		Interval:    time.Hour,
+	}
+}
+
+// EnableGC starts sweeping stale jobs every cfg.Interval. Calling it again
+// replaces the previous configuration.
+func (s *Scheduler) EnableGC(cfg GCConfig) {
+	def := DefaultGCConfig()
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = def.GracePeriod
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gcEntryID != 0 {
+		s.cron.Remove(s.gcEntryID)
+		s.gcEntryID = 0
+	}
+	s.gc = cfg
+	s.gcEntryID = s.cron.Schedule(cron.Every(cfg.Interval), cron.FuncJob(func() {
+		s.SweepStale(time.Now())
+	}))
+	log.Printf("[CRON] Stale job GC enabled (max failures %d, grace %s)", cfg.MaxFailures, cfg.GracePeriod)
+}
+
+// StaleJob is a job found stale by a sweep.
+type StaleJob struct {
+	Job     *Job
+	Reason  string
+	Deleted bool
+}
+
+// SweepStale flags newly stale jobs and warns their owners, clears jobs that
+// recovered, and deletes jobs whose grace period has run out.
+func (s *Scheduler) SweepStale(now time.Time) []StaleJob {
+	s.mu.RLock()
+	cfg := s.gc
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	var found []StaleJob
+	for _, job := range jobs {
+		reason := staleReason(cfg, job)
+
+		s.mu.Lock()
+		switch {
+		case reason == "":
+			if job.StaleSince == nil {
+				s.mu.Unlock()
+				continue
+			}
+			job.StaleSince = nil
+			s.mu.Unlock()
+			log.Printf("[CRON] Job %s (%s) recovered, no longer stale", job.ID, job.Name)
+			if err := s.store.SaveJob(job); err != nil {
+				log.Printf("[CRON] Failed to save job: %v", err)
+			}
+			continue
+		case job.StaleSince == nil:
+			flagged := now
+			job.StaleSince = &flagged
+			snapshot := job.Clone()
+			s.mu.Unlock()
+			log.Printf("[CRON] Job %s (%s) is stale: %s", job.ID, job.Name, reason)
+			if err := s.store.SaveJob(job); err != nil {
+				log.Printf("[CRON] Failed to save job: %v", err)
+			}
+			found = append(found, StaleJob{Job: snapshot, Reason: reason})
+		case now.Sub(*job.StaleSince) >= cfg.GracePeriod:
+			snapshot := job.Clone()
+			s.mu.Unlock()
+			if err := s.RemoveJob(job.ID); err != nil {
+				log.Printf("[CRON] Failed to remove stale job %s: %v", job.ID, err)
+				continue
+			}
+			found = append(found, StaleJob{Job: snapshot, Reason: reason, Deleted: true})
+		default:
+			s.mu.Unlock()
+		}
+	}
+
+	s.notifyStale(cfg, found)
+	return found
+}
+
+// staleReason explains why a job is stale, or returns "" if it is not.
+func staleReason(cfg GCConfig, job *Job) string {
+	// A paused job does not run, so its failure count says nothing new.
+	if job.Enabled && cfg.MaxFailures > 0 && job.FailCount >= cfg.MaxFailures {
+		reason := fmt.Sprintf("failed %d times in a row", job.FailCount)
+		if job.LastError != "" {
+			reason += ": " + truncateReason(job.LastError, 120)
+		}
+		return reason
+	}
+	if cfg.Targets != nil && job.Platform != "" {
+		if err := cfg.Targets.TargetAvailable(job.Platform, job.ChannelID, job.UserID); err != nil {
+			return "target unavailable: " + err.Error()
+		}
+	}
+	return ""
+}
+
+// notifyStale sends one summary per recipient.
+func (s *Scheduler) notifyStale(cfg GCConfig, found []StaleJob) {
+	if len(found) == 0 || s.chatNotifier == nil {
+		return
+	}
+
+	type recipient struct{ platform, channelID, userID string }
+	var order []recipient
+	groups := make(map[recipient][]StaleJob)
+	for _, sj := range found {
+		to := recipient{cfg.NotifyPlatform, cfg.NotifyChannelID, cfg.NotifyUserID}
+		if to.platform == "" && sj.Job.Platform != "" && sj.Job.ChannelID != "" && !strings.HasPrefix(sj.Reason, "target unavailable") {
+			to = recipient{sj.Job.Platform, sj.Job.ChannelID, sj.Job.UserID}
+		}
+		if _, ok := groups[to]; !ok {
+			order = append(order, to)
+		}
+		groups[to] = append(groups[to], sj)
+	}
+
+	for _, to := range order {
+		text := formatStaleSummary(groups[to], cfg.GracePeriod)
+		if to.platform == "" {
+			s.chatNotifier.NotifyChat(text)
+			continue
+		}
+		if err := s.chatNotifier.NotifyChatUser(to.platform, to.channelID, to.userID, text); err != nil {
+			log.Printf("[CRON] Failed to send stale job summary to %s: %v", to.platform, err)
+			s.chatNotifier.NotifyChat(text)
+		}
+	}
+}
+
+func formatStaleSummary(jobs []StaleJob, grace time.Duration) string {
+	var flagged, deleted []string
+	for _, sj := range jobs {
+		line := fmt.Sprintf("- %s (%s): %s", sj.Job.Name, shortID(sj.Job.ID), sj.Reason)
+		if sj.Deleted {
+			deleted = append(deleted, line)
+		} else {
+			flagged = append(flagged, line)
+		}
+	}
+
+	var sb strings.Builder
+	if len(flagged) > 0 {
+		sb.WriteString(fmt.Sprintf("🧹 %d scheduled job(s) look stale and will be deleted in %s unless they recover (pausing a failing job keeps it):\n", len(flagged), formatGrace(grace)))
+		sb.WriteString(strings.Join(flagged, "\n"))
+	}
+	if len(deleted) > 0 {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("🗑️ Deleted %d stale scheduled job(s):\n", len(deleted)))
+		sb.WriteString(strings.Join(deleted, "\n"))
+	}
+	return sb.String()
+}
+
+func formatGrace(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return d.String()
+}
+
+func truncateReason(s string, maxRunes int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxRunes {
+		return string(r[:maxRunes]) + "…"
+	}
+	return s
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
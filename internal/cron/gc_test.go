@@ -0,0 +1,122 @@
+package cron
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTargets map[string]bool
+
+func (f fakeTargets) TargetAvailable(platform, channelID, userID string) error {
+	if f[platform] {
+		return nil
+	}
+	return fmt.Errorf("platform %s not registered", platform)
+}
+
+func TestSweepStaleWarnsThenDeletes(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	notifier := &testNotifier{}
+	s := NewScheduler(store, nil, nil, notifier)
+	s.gc = GCConfig{MaxFailures: 3, GracePeriod: time.Hour, Targets: fakeTargets{"wecom": true}}
+
+	failing, err := s.AddJobWithMessage("failing", "0 9 * * *", "hi", "wecom", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	orphan, err := s.AddJobWithMessage("orphan", "0 9 * * *", "hi", "slack", "c2", "u2")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	healthy, err := s.AddJobWithMessage("healthy", "0 9 * * *", "hi", "wecom", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	s.mu.Lock()
+	failing.FailCount = 3
+	failing.LastError = "channel not found"
+	s.mu.Unlock()
+
+	now := time.Now()
+	found := s.SweepStale(now)
+	if len(found) != 2 || found[0].Deleted || found[1].Deleted {
+		t.Fatalf("expected two newly flagged jobs, got %+v", found)
+	}
+	if len(s.ListJobs()) != 3 {
+		t.Fatalf("no job should be deleted before the grace period")
+	}
+	if len(notifier.messages) != 2 {
+		t.Fatalf("expected a summary per recipient, got %q", notifier.messages)
+	}
+	if !strings.Contains(strings.Join(notifier.messages, "\n"), "failed 3 times in a row: channel not found") {
+		t.Fatalf("summary should explain the failure: %q", notifier.messages)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, job := range loaded {
+		if job.ID == failing.ID && (job.StaleSince == nil || job.FailCount != 3) {
+			t.Fatalf("stale state not persisted: %+v", job)
+		}
+	}
+
+	if found := s.SweepStale(now.Add(30 * time.Minute)); len(found) != 0 {
+		t.Fatalf("nothing should change within the grace period, got %+v", found)
+	}
+
+	found = s.SweepStale(now.Add(2 * time.Hour))
+	if len(found) != 2 || !found[0].Deleted || !found[1].Deleted {
+		t.Fatalf("expected both stale jobs deleted, got %+v", found)
+	}
+	jobs := s.ListJobs()
+	if len(jobs) != 1 || jobs[0].ID != healthy.ID {
+		t.Fatalf("only the healthy job should remain, got %d jobs", len(jobs))
+	}
+	if orphan.StaleSince == nil {
+		t.Fatalf("orphan should have been flagged before deletion")
+	}
+}
+
+func TestSweepStaleClearsRecoveredJob(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	s := NewScheduler(store, nil, nil, &testNotifier{})
+	s.gc = GCConfig{MaxFailures: 2, GracePeriod: time.Hour}
+
+	job, err := s.AddJobWithMessage("flaky", "0 9 * * *", "hi", "wecom", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	s.mu.Lock()
+	job.FailCount = 2
+	s.mu.Unlock()
+
+	now := time.Now()
+	s.SweepStale(now)
+	if job.StaleSince == nil {
+		t.Fatalf("job should be flagged")
+	}
+
+	// A successful run resets the failure count.
+	s.mu.Lock()
+	job.FailCount = 0
+	s.mu.Unlock()
+	s.SweepStale(now.Add(2 * time.Hour))
+	if job.StaleSince != nil || len(s.ListJobs()) != 1 {
+		t.Fatalf("recovered job should be kept and unflagged")
+	}
+}
@@ -1,8 +1,10 @@
 package cron
 
 import (
+	"slices"
 	"time"
 
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/robfig/cron/v3"
 )
 
@@ -13,6 +15,7 @@ type Job struct {
 	Tag        string         `json:"tag,omitempty"`         // Job tag: "user-schedule" or "assistant-task"
 	Type       string         `json:"type,omitempty"`        // "tool", "prompt", "message", "external"
 	Schedule   string         `json:"schedule"`              // Cron expression
+	RunAt      *time.Time     `json:"run_at,omitempty"`      // One-shot fire time (job is deleted after running)
 	Tool       string         `json:"tool,omitempty"`        // MCP tool to execute
 	Arguments  map[string]any `json:"arguments,omitempty"`   // Tool arguments
 	Message    string         `json:"message,omitempty"`     // Direct message to send (no tool execution)
@@ -24,10 +27,17 @@ type Job struct {
 	Platform   string         `json:"platform,omitempty"`    // Target platform ("slack", "wecom", etc.)
 	ChannelID  string         `json:"channel_id,omitempty"`  // Target channel/user to send to
 	UserID     string         `json:"user_id,omitempty"`     // User who created the job
+	NotifyVia  []string       `json:"notify_via,omitempty"`  // Channels for the output, e.g. "email:me@example.com"; empty means the job's chat
+	Context    string         `json:"context,omitempty"`     // The conversation that asked for the job, replayed into each prompt run
 	Enabled    bool           `json:"enabled"`               // Whether job is active
 	CreatedAt  time.Time      `json:"created_at"`            // Job creation timestamp
 	LastRun    *time.Time     `json:"last_run,omitempty"`    // Last execution timestamp
 	LastError  string         `json:"last_error,omitempty"`  // Last error message
+	FailCount  int            `json:"fail_count,omitempty"`  // Consecutive failed runs
+	StaleSince *time.Time     `json:"stale_since,omitempty"` // When the job was flagged stale and its owner warned
+	LastResult string         `json:"last_result,omitempty"` // Output of the last prompt run; heartbeat checks compare the next run with it
+
+	Provenance *provenance.Record `json:"provenance,omitempty"` // Who/what created the job, signed
 
 	// Runtime fields (not persisted)
 	EntryID cron.EntryID `json:"-"` // Cron scheduler entry ID
@@ -51,9 +61,13 @@ func (j *Job) Clone() *Job {
 		Platform:   j.Platform,
 		ChannelID:  j.ChannelID,
 		UserID:     j.UserID,
+		NotifyVia:  slices.Clone(j.NotifyVia),
+		Context:    j.Context,
 		Enabled:    j.Enabled,
 		CreatedAt:  j.CreatedAt,
 		LastError:  j.LastError,
+		LastResult: j.LastResult,
+		FailCount:  j.FailCount,
 		EntryID:    j.EntryID,
 	}
 
@@ -62,6 +76,21 @@ func (j *Job) Clone() *Job {
 		clone.LastRun = &lastRun
 	}
 
+	if j.RunAt != nil {
+		runAt := *j.RunAt
+		clone.RunAt = &runAt
+	}
+
+	if j.StaleSince != nil {
+		staleSince := *j.StaleSince
+		clone.StaleSince = &staleSince
+	}
+
+	if j.Provenance != nil {
+		prov := *j.Provenance
+		clone.Provenance = &prov
+	}
+
 	if j.Arguments != nil {
 		clone.Arguments = make(map[string]any, len(j.Arguments))
 		for k, v := range j.Arguments {
@@ -71,3 +100,45 @@ func (j *Job) Clone() *Job {
 
 	return clone
 }
+
+// IsOneShot reports whether the job fires once at RunAt instead of on a cron schedule
+func (j *Job) IsOneShot() bool {
+	return j.RunAt != nil
+}
+
+// signingSubject is the part of the job covered by its provenance signature:
+// what runs, when and where its output goes. Enabled, LastRun, LastError, FailCount and
+// StaleSince change during normal operation (as does Source, which heartbeat
+// jobs use to remember the last result) and are left out so pausing or
+// running a job keeps it valid.
+func (j *Job) signingSubject() any {
+	var runAt string
+	if j.RunAt != nil {
+		runAt = j.RunAt.UTC().Format(time.RFC3339)
+	}
+	return struct {
+		ID         string         `json:"id"`
+		Name       string         `json:"name"`
+		Tag        string         `json:"tag"`
+		Type       string         `json:"type"`
+		Schedule   string         `json:"schedule"`
+		RunAt      string         `json:"run_at"`
+		Tool       string         `json:"tool"`
+		Arguments  map[string]any `json:"arguments"`
+		Message    string         `json:"message"`
+		Prompt     string         `json:"prompt"`
+		Endpoint   string         `json:"endpoint"`
+		AuthHeader string         `json:"auth_header"`
+		RelayMode  bool           `json:"relay_mode"`
+		Platform   string         `json:"platform"`
+		ChannelID  string         `json:"channel_id"`
+		UserID     string         `json:"user_id"`
+		CreatedAt  string         `json:"created_at"`
+		NotifyVia  []string       `json:"notify_via,omitempty"` // omitted when empty so older signatures stay valid
+		Context    string         `json:"context,omitempty"`
+	}{
+		j.ID, j.Name, j.Tag, j.Type, j.Schedule, runAt, j.Tool, j.Arguments, j.Message, j.Prompt,
+		j.Endpoint, j.AuthHeader, j.RelayMode, j.Platform, j.ChannelID, j.UserID,
+		j.CreatedAt.UTC().Format(time.RFC3339), j.NotifyVia, j.Context,
+	}
+}
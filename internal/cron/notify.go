@@ -0,0 +1,251 @@
+package cron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/datadir"
+)
+
+// Delivery channels a job's notify_via can name. Chat, webhook and file are
+// built in; desktop and email are registered by the process that owns the
+// scheduler, since they need the platform's tools and SMTP settings.
+const (
+	NotifyViaChat    = "chat"
+	NotifyViaDesktop = "desktop"
+	NotifyViaEmail   = "email"
+	NotifyViaWebhook = "webhook"
+	NotifyViaFile    = "file"
+)
+
+const (
+	deliveryTimeout   = 30 * time.Second
+	defaultOutputFile = "cron-output.md"
+)
+
+// Delivery is a job's output on its way to one channel.
+type Delivery struct {
+	Job    *Job
+	Target string // what follows "name:" in the notify_via entry, e.g. a URL, path or address; may be empty
+	Text   string
+	Failed bool // Text reports that the job failed
+	Time   time.Time
+}
+
+// Notifier delivers job output over one channel.
+type Notifier interface {
+	Deliver(ctx context.Context, d Delivery) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, d Delivery) error
+
+// Deliver calls f.
+func (f NotifierFunc) Deliver(ctx context.Context, d Delivery) error {
+	return f(ctx, d)
+}
+
+// splitNotifyVia splits a notify_via entry such as "webhook:https://..."
+// into the channel name and its target.
+func splitNotifyVia(entry string) (name, target string) {
+	name, target, _ = strings.Cut(strings.TrimSpace(entry), ":")
+	return strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(target)
+}
+
+// RegisterNotifier makes name selectable in a job's notify_via, replacing
+// any notifier already registered under it.
+func (s *Scheduler) RegisterNotifier(name string, n Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers[strings.ToLower(name)] = n
+}
+
+// Notifiers returns the names of the registered delivery channels.
+func (s *Scheduler) Notifiers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Sorted(maps.Keys(s.notifiers))
+}
+
+// CheckNotifyVia reports entries naming channels that are not registered or
+// missing a target they need.
+func (s *Scheduler) CheckNotifyVia(via []string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range via {
+		name, target := splitNotifyVia(entry)
+		if _, ok := s.notifiers[name]; !ok {
+			return fmt.Errorf("unknown notify_via channel %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(s.notifiers)), ", "))
+		}
+		if name == NotifyViaWebhook && !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return fmt.Errorf("notify_via webhook needs a URL, e.g. webhook:https://example.com/hook")
+		}
+	}
+	return nil
+}
+
+// SetNotifyVia sets the channels that receive an existing job's output and
+// saves it. An empty via sends the output to the job's chat again.
+func (s *Scheduler) SetNotifyVia(id string, via []string) error {
+	if err := s.CheckNotifyVia(via); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	job, exists := s.jobs[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("job not found: %s", id)
+	}
+	job.NotifyVia = slices.Clone(via)
+	s.mu.Unlock()
+
+	if err := s.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// deliverVia sends text over the job's notify_via channels. It reports
+// false when the job has none, so the caller notifies the job's chat as
+// before; the error joins the channels that failed.
+func (s *Scheduler) deliverVia(job *Job, text string, failed bool) (bool, error) {
+	s.mu.RLock()
+	via := slices.Clone(job.NotifyVia)
+	s.mu.RUnlock()
+	if len(via) == 0 {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, entry := range via {
+		name, target := splitNotifyVia(entry)
+		s.mu.RLock()
+		n, ok := s.notifiers[name]
+		s.mu.RUnlock()
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: no such channel", name))
+			continue
+		}
+		if err := n.Deliver(ctx, Delivery{Job: job, Target: target, Text: text, Failed: failed, Time: time.Now()}); err != nil {
+			log.Printf("[CRON] Failed to deliver job %s (%s) via %s: %v", job.ID, job.Name, name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+// toolResultText renders a tool job's result for delivery.
+func toolResultText(result any) string {
+	switch v := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	}
+	data, err := json.Marshal(result)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
+
+// registerBuiltinNotifiers adds the chat, webhook and file channels.
+func (s *Scheduler) registerBuiltinNotifiers() {
+	s.notifiers[NotifyViaChat] = NotifierFunc(s.deliverChat)
+	s.notifiers[NotifyViaWebhook] = NotifierFunc(deliverWebhook)
+	s.notifiers[NotifyViaFile] = NotifierFunc(deliverFile)
+}
+
+// deliverChat sends to "platform:channel_id:user_id", or the job's own chat
+// when the entry has no target.
+func (s *Scheduler) deliverChat(_ context.Context, d Delivery) error {
+	if s.chatNotifier == nil {
+		return fmt.Errorf("no chat notifier")
+	}
+	platform, channelID, userID := d.Job.Platform, d.Job.ChannelID, d.Job.UserID
+	if d.Target != "" {
+		parts := strings.SplitN(d.Target, ":", 3)
+		if len(parts) < 2 {
+			return fmt.Errorf("chat target must be platform:channel_id[:user_id], got %q", d.Target)
+		}
+		platform, channelID, userID = parts[0], parts[1], ""
+		if len(parts) == 3 {
+			userID = parts[2]
+		}
+	}
+	if platform == "" || channelID == "" {
+		return s.chatNotifier.NotifyChat(fmt.Sprintf("[%s] %s", d.Job.Name, d.Text))
+	}
+	return s.chatNotifier.NotifyChatUser(platform, channelID, userID, d.Text)
+}
+
+// deliverWebhook POSTs the output as JSON.
+func deliverWebhook(ctx context.Context, d Delivery) error {
+	body, err := json.Marshal(map[string]any{
+		"job_id": d.Job.ID,
+		"name":   d.Job.Name,
+		"tag":    d.Job.Tag,
+		"text":   d.Text,
+		"failed": d.Failed,
+		"time":   d.Time.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// deliverFile appends the output to a Markdown file (default
+// cron-output.md in the data directory).
+func deliverFile(_ context.Context, d Delivery) error {
+	path := d.Target
+	if path == "" {
+		path = datadir.Path(defaultOutputFile)
+	} else if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	status := ""
+	if d.Failed {
+		status = " ⚠️"
+	}
+	if _, err := fmt.Fprintf(f, "## %s %s%s\n\n%s\n\n", d.Time.Format("2006-01-02 15:04"), d.Job.Name, status, strings.TrimSpace(d.Text)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchedulerDeliversViaNotifyVia(t *testing.T) {
+	var hook map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			t.Errorf("decode webhook: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	notifier := &testNotifier{}
+	s := NewScheduler(store, nil, nil, notifier)
+	job, err := s.AddJobWithMessage("digest", "0 9 * * *", "早报已生成", "slack", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+
+	if err := s.SetNotifyVia(job.ID, []string{"pager"}); err == nil {
+		t.Fatal("expected an error for an unknown channel")
+	}
+	if err := s.SetNotifyVia(job.ID, []string{"webhook"}); err == nil {
+		t.Fatal("expected an error for a webhook without URL")
+	}
+	out := filepath.Join(dir, "out.md")
+	if err := s.SetNotifyVia(job.ID, []string{"webhook:" + srv.URL, "file:" + out}); err != nil {
+		t.Fatalf("set notify_via: %v", err)
+	}
+
+	s.executeJob(job)
+
+	if len(notifier.messages) != 0 {
+		t.Fatalf("chat got %q, want nothing", notifier.messages)
+	}
+	if hook["text"] != "早报已生成" || hook["name"] != "digest" {
+		t.Fatalf("webhook payload = %v", hook)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(data), "digest") || !strings.Contains(string(data), "早报已生成") {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+	if job.LastError != "" {
+		t.Fatalf("last error = %q", job.LastError)
+	}
+
+	jobs, err := store.Load()
+	if err != nil || len(jobs) != 1 || len(jobs[0].NotifyVia) != 2 {
+		t.Fatalf("stored jobs = %+v, %v", jobs, err)
+	}
+}
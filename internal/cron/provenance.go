@@ -0,0 +1,62 @@
+package cron
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kayz/coco/internal/provenance"
+)
+
+// SetSigner sets the key used to sign job provenance. Without a signer jobs
+// are still attributed but left unsigned.
+func (s *Scheduler) SetSigner(signer *provenance.Signer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signer = signer
+}
+
+// SetProvenance attributes an existing job to rec, signs it and saves it.
+func (s *Scheduler) SetProvenance(id string, rec provenance.Record) error {
+	s.mu.Lock()
+	job, exists := s.jobs[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("job not found: %s", id)
+	}
+	s.signJob(job, rec)
+	s.mu.Unlock()
+
+	if err := s.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// VerifyJob checks the job's provenance signature against its definition.
+func (s *Scheduler) VerifyJob(job *Job) provenance.Status {
+	s.mu.RLock()
+	signer := s.signer
+	s.mu.RUnlock()
+	return VerifyJob(signer, job)
+}
+
+// VerifyJob checks a job's provenance signature with signer (which may be nil).
+func VerifyJob(signer *provenance.Signer, job *Job) provenance.Status {
+	return signer.Verify(job.Provenance, job.signingSubject())
+}
+
+// signJob sets the job's provenance. Callers must hold s.mu.
+func (s *Scheduler) signJob(job *Job, rec provenance.Record) {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = job.CreatedAt
+	}
+	rec.CreatedAt = rec.CreatedAt.UTC().Truncate(time.Second)
+	rec.Signature = ""
+	if s.signer != nil {
+		if err := s.signer.Sign(&rec, job.signingSubject()); err != nil {
+			log.Printf("[CRON] Failed to sign job %s: %v", job.ID, err)
+		}
+	}
+	job.Provenance = &rec
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/robfig/cron/v3"
 )
 
@@ -40,20 +41,27 @@ type Scheduler struct {
 	toolExecutor   ToolExecutor
 	promptExecutor PromptExecutor
 	chatNotifier   ChatNotifier
+	notifiers      map[string]Notifier
+	signer         *provenance.Signer
+	gc             GCConfig
+	gcEntryID      cron.EntryID
 	jobs           map[string]*Job
 	mu             sync.RWMutex
 }
 
 // NewScheduler creates a new scheduler
 func NewScheduler(store *Store, toolExecutor ToolExecutor, promptExecutor PromptExecutor, chatNotifier ChatNotifier) *Scheduler {
-	return &Scheduler{
+	s := &Scheduler{
 		cron:           cron.New(cron.WithSeconds()), // Support second-level precision
 		store:          store,
 		toolExecutor:   toolExecutor,
 		promptExecutor: promptExecutor,
 		chatNotifier:   chatNotifier,
+		notifiers:      make(map[string]Notifier),
 		jobs:           make(map[string]*Job),
 	}
+	s.registerBuiltinNotifiers()
+	return s
 }
 
 // normalizeCron prepends "0 " to standard 5-field cron expressions
@@ -65,6 +73,12 @@ func normalizeCron(schedule string) string {
 	return schedule
 }
 
+// NormalizeSchedule returns schedule the way jobs store it, so callers can
+// tell whether a stored job already has it.
+func NormalizeSchedule(schedule string) string {
+	return normalizeCron(schedule)
+}
+
 // Start loads jobs from storage and starts the scheduler
 func (s *Scheduler) Start() error {
 	// Load jobs from disk
@@ -195,6 +209,21 @@ func (s *Scheduler) AddExternalJob(name, tag, schedule, endpoint, authHeader str
 	})
 }
 
+// AddOnceJob creates a one-shot job that fires at runAt and is removed afterwards.
+// Exactly one of message or prompt should be set.
+func (s *Scheduler) AddOnceJob(name, tag string, runAt time.Time, message, prompt, platform, channelID, userID string) (*Job, error) {
+	return s.addJob(&Job{
+		Name:      name,
+		Tag:       tag,
+		RunAt:     &runAt,
+		Message:   message,
+		Prompt:    prompt,
+		Platform:  platform,
+		ChannelID: channelID,
+		UserID:    userID,
+	})
+}
+
 // ListJobsByTag returns jobs filtered by tag
 func (s *Scheduler) ListJobsByTag(tag string) []*Job {
 	s.mu.RLock()
@@ -212,13 +241,20 @@ func (s *Scheduler) ListJobsByTag(tag string) []*Job {
 
 // addJob validates and schedules a job
 func (s *Scheduler) addJob(job *Job) (*Job, error) {
-	// Normalize 5-field cron to 6-field (our cron instance uses WithSeconds)
-	job.Schedule = normalizeCron(job.Schedule)
+	if job.IsOneShot() {
+		if !job.RunAt.After(time.Now()) {
+			return nil, fmt.Errorf("run_at must be in the future: %s", job.RunAt.Format(time.RFC3339))
+		}
+		job.Schedule = onceScheduleSpec(*job.RunAt)
+	} else {
+		// Normalize 5-field cron to 6-field (our cron instance uses WithSeconds)
+		job.Schedule = normalizeCron(job.Schedule)
 
-	// Validate cron expression using the 6-field (with seconds) parser
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	if _, err := parser.Parse(job.Schedule); err != nil {
-		return nil, fmt.Errorf("invalid cron expression: %w", err)
+		// Validate cron expression using the 6-field (with seconds) parser
+		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+		if _, err := parser.Parse(job.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid cron expression: %w", err)
+		}
 	}
 
 	job.ID = uuid.New().String()
@@ -240,8 +276,9 @@ func (s *Scheduler) addJob(job *Job) (*Job, error) {
 		job.Source = "external-agent"
 	}
 
-	// Add to jobs map
+	// Add to jobs map; callers attribute the job afterwards with SetProvenance.
 	s.mu.Lock()
+	s.signJob(job, provenance.Record{Origin: provenance.OriginSystem})
 	s.jobs[job.ID] = job
 	s.mu.Unlock()
 
@@ -368,8 +405,71 @@ func (s *Scheduler) ListJobs() []*Job {
 	return jobs
 }
 
+// ImportJob inserts or replaces a job keeping its ID, e.g. a job replicated
+// from another device. Runtime state of an existing job is preserved.
+func (s *Scheduler) ImportJob(job *Job) error {
+	if job == nil || strings.TrimSpace(job.ID) == "" {
+		return fmt.Errorf("job id is required")
+	}
+	job = job.Clone()
+	job.EntryID = 0
+	if !job.IsOneShot() {
+		job.Schedule = normalizeCron(job.Schedule)
+		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+		if _, err := parser.Parse(job.Schedule); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	} else if !job.RunAt.After(time.Now()) {
+		return fmt.Errorf("run_at already passed: %s", job.RunAt.Format(time.RFC3339))
+	}
+
+	s.mu.Lock()
+	if existing, ok := s.jobs[job.ID]; ok {
+		if existing.EntryID != 0 {
+			s.cron.Remove(existing.EntryID)
+		}
+		job.LastRun = existing.LastRun
+		job.LastError = existing.LastError
+		job.FailCount = existing.FailCount
+		job.StaleSince = existing.StaleSince
+	}
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	if job.Enabled {
+		if err := s.scheduleJob(job); err != nil {
+			s.mu.Lock()
+			delete(s.jobs, job.ID)
+			s.mu.Unlock()
+			return fmt.Errorf("failed to schedule job: %w", err)
+		}
+	}
+
+	if err := s.store.SaveJob(job); err != nil {
+		log.Printf("[CRON] Failed to save job: %v", err)
+	}
+
+	log.Printf("[CRON] Job imported: %s (%s) - schedule: %s", job.ID, job.Name, job.Schedule)
+	return nil
+}
+
 // scheduleJob schedules a job in the cron scheduler
 func (s *Scheduler) scheduleJob(job *Job) error {
+	if job.IsOneShot() {
+		runAt := *job.RunAt
+		// A one-shot job missed while the process was down fires shortly after start.
+		if now := time.Now(); !runAt.After(now) {
+			runAt = now.Add(time.Second)
+		}
+		job.EntryID = s.cron.Schedule(onceSchedule{at: runAt}, cron.FuncJob(func() {
+			s.executeJob(job)
+			if err := s.RemoveJob(job.ID); err != nil {
+				log.Printf("[CRON] Failed to remove one-shot job %s: %v", job.ID, err)
+			}
+		}))
+		return nil
+	}
+
 	entryID, err := s.cron.AddFunc(job.Schedule, func() {
 		s.executeJob(job)
 	})
@@ -381,6 +481,25 @@ func (s *Scheduler) scheduleJob(job *Job) error {
 	return nil
 }
 
+// onceSchedule is a cron.Schedule that activates exactly once.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next returns the fire time until it has passed, then the zero time so the
+// entry is never activated again.
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(o.at) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+// onceScheduleSpec renders a human-readable schedule string for one-shot jobs.
+func onceScheduleSpec(at time.Time) string {
+	return "@at " + at.Format(time.RFC3339)
+}
+
 // executeJob executes a job
 func (s *Scheduler) executeJob(job *Job) {
 	now := time.Now()
@@ -400,19 +519,23 @@ func (s *Scheduler) executeJob(job *Job) {
 		if err != nil {
 			s.mu.Lock()
 			job.LastError = err.Error()
+			job.FailCount++
 			s.mu.Unlock()
 			log.Printf("[CRON] External job failed: %s (%s) - error: %v", job.ID, job.Name, err)
-			if s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
-				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID,
-					fmt.Sprintf("⚠️ External job '%s' failed: %v", job.Name, err))
+			msg := fmt.Sprintf("⚠️ External job '%s' failed: %v", job.Name, err)
+			if ok, _ := s.deliverVia(job, msg, true); !ok && s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, msg)
 			}
 		} else {
 			s.mu.Lock()
 			job.LastError = ""
+			job.FailCount = 0
 			s.mu.Unlock()
 			log.Printf("[CRON] External job completed: %s (%s)", job.ID, job.Name)
-			if s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" && strings.TrimSpace(text) != "" {
-				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, text)
+			if strings.TrimSpace(text) != "" {
+				if ok, _ := s.deliverVia(job, text, false); !ok && s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+					s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, text)
+				}
 			}
 		}
 
@@ -430,15 +553,21 @@ func (s *Scheduler) executeJob(job *Job) {
 		job.LastRun = &now
 		s.mu.Unlock()
 
-		if s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
-			if err := s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, job.Message); err != nil {
+		delivered, err := s.deliverVia(job, job.Message, false)
+		if !delivered && s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+			err = s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, job.Message)
+		}
+		if delivered || (s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "") {
+			if err != nil {
 				s.mu.Lock()
 				job.LastError = err.Error()
+				job.FailCount++
 				s.mu.Unlock()
 				log.Printf("[CRON] Job failed to send message: %s (%s) - error: %v", job.ID, job.Name, err)
 			} else {
 				s.mu.Lock()
 				job.LastError = ""
+				job.FailCount = 0
 				s.mu.Unlock()
 				log.Printf("[CRON] Job message sent: %s (%s)", job.ID, job.Name)
 			}
@@ -466,6 +595,7 @@ func (s *Scheduler) executeJob(job *Job) {
 		if s.promptExecutor == nil {
 			s.mu.Lock()
 			job.LastError = "prompt executor not available"
+			job.FailCount++
 			s.mu.Unlock()
 			log.Printf("[CRON] Job failed: %s (%s) - prompt executor not available", job.ID, job.Name)
 			if err := s.store.SaveJob(job); err != nil {
@@ -481,35 +611,51 @@ func (s *Scheduler) executeJob(job *Job) {
 		heartbeatNotifyMode := ""
 		if job.Tag == "heartbeat" {
 			heartbeatNotifyMode, promptToRun = parseHeartbeatPromptMeta(job.Prompt)
-			if heartbeatNotifyMode == "auto" {
-				promptToRun = buildHeartbeatAutoPrompt(promptToRun)
+			s.mu.RLock()
+			previous := job.LastResult
+			s.mu.RUnlock()
+			switch heartbeatNotifyMode {
+			case "auto":
+				promptToRun = buildHeartbeatAutoPrompt(promptToRun, previous)
+			case "on_change":
+				promptToRun = buildHeartbeatChangePrompt(promptToRun, previous)
 			}
 		}
 
-		result, err := s.promptExecutor.ExecutePrompt(ctx, job.Platform, job.ChannelID, job.UserID, promptToRun)
+		s.mu.RLock()
+		running := job.Clone()
+		s.mu.RUnlock()
+		result, err := s.promptExecutor.ExecutePrompt(WithJob(ctx, running), job.Platform, job.ChannelID, job.UserID, promptToRun)
 		if err != nil {
 			s.mu.Lock()
 			job.LastError = err.Error()
+			job.FailCount++
 			s.mu.Unlock()
 			log.Printf("[CRON] Job prompt failed: %s (%s) - error: %v", job.ID, job.Name, err)
 
-			if s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
-				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID,
-					fmt.Sprintf("⚠️ Scheduled AI task '%s' failed: %v", job.Name, err))
+			msg := fmt.Sprintf("⚠️ Scheduled AI task '%s' failed: %v", job.Name, err)
+			if ok, _ := s.deliverVia(job, msg, true); !ok && s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, msg)
 			}
 		} else {
 			s.mu.Lock()
 			job.LastError = ""
+			job.FailCount = 0
 			s.mu.Unlock()
 			log.Printf("[CRON] Job prompt completed: %s (%s)", job.ID, job.Name)
 
 			text := strings.TrimSpace(result)
-			shouldNotify := s.chatNotifier != nil && job.Platform != "" && job.ChannelID != ""
+			shouldNotify := true
 			if job.Tag == "heartbeat" {
+				s.mu.Lock()
 				shouldNotify, text = decideHeartbeatNotification(job, heartbeatNotifyMode, result)
+				job.LastResult = heartbeatLastResult(text)
+				s.mu.Unlock()
 			}
 			if shouldNotify && text != "" {
-				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, text)
+				if ok, _ := s.deliverVia(job, text, false); !ok && s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+					s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, text)
+				}
 			}
 		}
 
@@ -532,12 +678,13 @@ func (s *Scheduler) executeJob(job *Job) {
 	job.LastRun = &now
 	if err != nil {
 		job.LastError = err.Error()
+		job.FailCount++
 		s.mu.Unlock()
 
 		log.Printf("[CRON] Job failed: %s (%s) - error: %v", job.ID, job.Name, err)
 
-		if s.chatNotifier != nil {
-			errMsg := fmt.Sprintf("⚠️ Scheduled job '%s' failed: %v", job.Name, err)
+		errMsg := fmt.Sprintf("⚠️ Scheduled job '%s' failed: %v", job.Name, err)
+		if ok, _ := s.deliverVia(job, errMsg, true); !ok && s.chatNotifier != nil {
 			if job.Platform != "" && job.ChannelID != "" {
 				s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, errMsg)
 			} else {
@@ -546,6 +693,7 @@ func (s *Scheduler) executeJob(job *Job) {
 		}
 	} else {
 		job.LastError = ""
+		job.FailCount = 0
 		s.mu.Unlock()
 
 		resultStr := ""
@@ -555,6 +703,11 @@ func (s *Scheduler) executeJob(job *Job) {
 			}
 		}
 		log.Printf("[CRON] Job completed: %s (%s)%s", job.ID, job.Name, resultStr)
+
+		// Tool results only reach a chat when the job asks for it.
+		if text := toolResultText(result); text != "" {
+			s.deliverVia(job, text, false)
+		}
 	}
 
 	if err := s.store.SaveJob(job); err != nil {
@@ -660,13 +813,45 @@ func normalizeHeartbeatNotifyMode(mode string) string {
 	}
 }
 
-func buildHeartbeatAutoPrompt(prompt string) string {
+// maxHeartbeatResultRunes caps the previous result kept for the next run.
+const maxHeartbeatResultRunes = 4000
+
+func buildHeartbeatAutoPrompt(prompt, previous string) string {
 	instruction := `你正在执行 HEARTBEAT 巡检。
 请在第一行严格输出：HEARTBEAT_NOTIFY: yes 或 HEARTBEAT_NOTIFY: no
 然后再输出巡检正文（可多行）。`
+	if previous = strings.TrimSpace(previous); previous != "" {
+		instruction += "\n对比下面的上次巡检结果，只有出现需要用户关注的新情况时才输出 yes。\n\n上次巡检结果：\n" + previous
+	}
 	return instruction + "\n\n" + strings.TrimSpace(prompt)
 }
 
+// buildHeartbeatChangePrompt asks an on_change check to compare with its
+// previous result itself: two runs rarely word the same findings the same
+// way, so comparing the texts alone would notify on every run. The first
+// run has nothing to compare with and only sets the baseline.
+func buildHeartbeatChangePrompt(prompt, previous string) string {
+	previous = strings.TrimSpace(previous)
+	if previous == "" {
+		return strings.TrimSpace(prompt)
+	}
+	instruction := `你正在执行 HEARTBEAT 巡检，并与上次结果对比。
+请在第一行严格输出：HEARTBEAT_NOTIFY: yes 或 HEARTBEAT_NOTIFY: no
+只有巡检结论与上次相比有实质变化时输出 yes；措辞不同但结论相同输出 no。
+然后再输出巡检正文（可多行）。
+
+上次巡检结果：
+` + previous
+	return instruction + "\n\n" + strings.TrimSpace(prompt)
+}
+
+func heartbeatLastResult(text string) string {
+	if r := []rune(text); len(r) > maxHeartbeatResultRunes {
+		return string(r[:maxHeartbeatResultRunes])
+	}
+	return text
+}
+
 func decideHeartbeatNotification(job *Job, notifyMode string, rawResult string) (bool, string) {
 	notifyMode = normalizeHeartbeatNotifyMode(notifyMode)
 	text := strings.TrimSpace(rawResult)
@@ -691,6 +876,9 @@ func decideHeartbeatNotification(job *Job, notifyMode string, rawResult string)
 		if prevHash == "" {
 			return false, text
 		}
+		if hasDecision {
+			return explicitAuto && text != "", text
+		}
 		return hash != "" && hash != prevHash, text
 	case "auto":
 		if hasDecision {
@@ -1,6 +1,11 @@
 package cron
 
-import "testing"
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestParseHeartbeatPromptMeta(t *testing.T) {
 	mode, prompt := parseHeartbeatPromptMeta("[HEARTBEAT_NOTIFY=on_change]\ncheck memory")
@@ -58,3 +63,55 @@ func TestDecideHeartbeatNotificationAutoExplicit(t *testing.T) {
 		t.Fatalf("explicit auto no should not notify")
 	}
 }
+
+type scriptedExecutor struct {
+	replies []string
+	prompts []string
+}
+
+func (e *scriptedExecutor) ExecutePrompt(ctx context.Context, platform, channelID, userID, prompt string) (string, error) {
+	e.prompts = append(e.prompts, prompt)
+	reply := e.replies[0]
+	e.replies = e.replies[1:]
+	return reply, nil
+}
+
+func TestHeartbeatOnChangeComparesWithPreviousResult(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	exec := &scriptedExecutor{replies: []string{
+		"记忆一致，无风险",
+		"HEARTBEAT_NOTIFY: no\n记忆一致，没有发现风险",
+		"HEARTBEAT_NOTIFY: yes\n发现两条冲突的出差日期",
+	}}
+	notifier := &testNotifier{}
+	s := NewScheduler(store, nil, exec, notifier)
+	job, err := s.AddJobWithPromptAndTag("heartbeat:u1:memory", "heartbeat", "@every 6h", "[HEARTBEAT_NOTIFY=on_change]\n检查记忆", "wecom", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+
+	s.executeJob(job)
+	if exec.prompts[0] != "检查记忆" || len(notifier.messages) != 0 {
+		t.Fatalf("baseline run: prompt %q, sent %v", exec.prompts[0], notifier.messages)
+	}
+
+	// A reworded but unchanged result does not notify.
+	s.executeJob(job)
+	if !strings.Contains(exec.prompts[1], "上次巡检结果：\n记忆一致，无风险") || len(notifier.messages) != 0 {
+		t.Fatalf("second run: prompt %q, sent %v", exec.prompts[1], notifier.messages)
+	}
+
+	s.executeJob(job)
+	if len(notifier.messages) != 1 || notifier.messages[0] != "发现两条冲突的出差日期" {
+		t.Fatalf("third run sent %v", notifier.messages)
+	}
+	jobs, _ := store.Load()
+	if len(jobs) != 1 || jobs[0].LastResult != "发现两条冲突的出差日期" {
+		t.Fatalf("last result not persisted: %+v", jobs)
+	}
+}
@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulerOnceJobFiresAndIsRemoved(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	notifier := &testNotifier{}
+	s := NewScheduler(store, nil, nil, notifier)
+	if err := s.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer s.cron.Stop()
+
+	job, err := s.AddOnceJob("meeting", "user-schedule", time.Now().Add(1500*time.Millisecond),
+		"开会", "", "wecom", "channel", "user")
+	if err != nil {
+		t.Fatalf("add once job: %v", err)
+	}
+	if !strings.HasPrefix(job.Schedule, "@at ") {
+		t.Fatalf("unexpected one-shot schedule: %q", job.Schedule)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(s.ListJobs()) == 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if n := len(s.ListJobs()); n != 0 {
+		t.Fatalf("one-shot job should be removed after firing, still have %d jobs", n)
+	}
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if len(notifier.messages) != 1 || notifier.messages[0] != "开会" {
+		t.Fatalf("unexpected notifications: %#v", notifier.messages)
+	}
+
+	persisted, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(persisted) != 0 {
+		t.Fatalf("one-shot job should be deleted from store, got %d", len(persisted))
+	}
+}
+
+func TestSchedulerOnceJobRejectsPastTime(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	s := NewScheduler(store, nil, nil, nil)
+	if _, err := s.AddOnceJob("late", "", time.Now().Add(-time.Minute), "x", "", "", "", ""); err == nil {
+		t.Fatalf("expected error for past run_at")
+	}
+}
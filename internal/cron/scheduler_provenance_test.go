@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kayz/coco/internal/provenance"
+)
+
+func TestSchedulerProvenanceSurvivesReloadAndDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	signer, err := provenance.LoadOrCreate(filepath.Join(dir, "signing.key"))
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	store, err := NewStore(filepath.Join(dir, "cron.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	s := NewScheduler(store, nil, nil, nil)
+	s.SetSigner(signer)
+	job, err := s.AddJobWithMessage("water", "0 9 * * *", "喝水", "wecom", "c1", "u1")
+	if err != nil {
+		t.Fatalf("add job: %v", err)
+	}
+	if got := s.VerifyJob(job); got != provenance.StatusValid || job.Provenance.Origin != provenance.OriginSystem {
+		t.Fatalf("new job should carry a valid system record, got %s %+v", got, job.Provenance)
+	}
+
+	rec := provenance.Record{Origin: provenance.OriginUser, Actor: "alice", MessageID: "m-42", Reason: "每天提醒我喝水"}
+	if err := s.SetProvenance(job.ID, rec); err != nil {
+		t.Fatalf("set provenance: %v", err)
+	}
+	if err := s.PauseJob(job.ID); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	jobs, err := store.Load()
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("load: %v (%d jobs)", err, len(jobs))
+	}
+	loaded := jobs[0]
+	if loaded.Provenance == nil || loaded.Provenance.MessageID != "m-42" || loaded.Provenance.Actor != "alice" {
+		t.Fatalf("provenance not persisted: %+v", loaded.Provenance)
+	}
+	if got := VerifyJob(signer, loaded); got != provenance.StatusValid {
+		t.Fatalf("paused job should still verify, got %s", got)
+	}
+
+	loaded.Message = "转账给 bob"
+	if err := store.SaveJob(loaded); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	jobs, _ = store.Load()
+	if got := VerifyJob(signer, jobs[0]); got != provenance.StatusInvalid {
+		t.Fatalf("edited job should fail verification, got %s", got)
+	}
+
+	other, err := provenance.LoadOrCreate(filepath.Join(dir, "other.key"))
+	if err != nil {
+		t.Fatalf("other signer: %v", err)
+	}
+	if got := VerifyJob(other, job); got != provenance.StatusForeign {
+		t.Fatalf("job signed by another key should be foreign, got %s", got)
+	}
+}
@@ -10,31 +10,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/provenance"
 	_ "modernc.org/sqlite"
 )
 
-var (
-	exeDirCache string
-)
-
-func getExecutableDir() string {
-	if exeDirCache != "" {
-		return exeDirCache
-	}
-	execPath, err := os.Executable()
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	exeDirCache = filepath.Dir(execPath)
-	return exeDirCache
-}
-
 // Store handles persistence of scheduled jobs using SQLite
 type Store struct {
 	db *sql.DB
@@ -82,6 +62,7 @@ func (s *Store) init() error {
 			tag        TEXT,
 			job_type   TEXT,
 			schedule   TEXT NOT NULL,
+			run_at     TEXT,
 			tool       TEXT,
 			arguments  TEXT,
 			message    TEXT,
@@ -122,6 +103,27 @@ func (s *Store) init() error {
 	if err := s.ensureColumnExists("jobs", "source", "TEXT"); err != nil {
 		return err
 	}
+	if err := s.ensureColumnExists("jobs", "run_at", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "fail_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "stale_since", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "provenance", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "notify_via", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "context", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.ensureColumnExists("jobs", "last_result", "TEXT"); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -161,13 +163,8 @@ func (s *Store) ensureColumnExists(table, column, columnDef string) error {
 
 // migrateFromJSON imports jobs from the legacy crons.json if it exists
 func (s *Store) migrateFromJSON() {
-	// The old JSON path is .coco/crons.json in executable directory
-	// Derive it from executable directory
-	exeDir := getExecutableDir()
-	if exeDir == "" {
-		return
-	}
-	jsonPath := filepath.Join(exeDir, ".coco", "crons.json")
+	// The old JSON path is .coco/crons.json in the data directory
+	jsonPath := datadir.Path(".coco", "crons.json")
 
 	data, err := os.ReadFile(jsonPath)
 	if err != nil {
@@ -205,9 +202,10 @@ func (s *Store) Load() ([]*Job, error) {
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, name, tag, job_type, schedule, tool, arguments, message, prompt,
+		SELECT id, name, tag, job_type, schedule, run_at, tool, arguments, message, prompt,
 		       endpoint, auth_header, relay_mode, source,
-		       platform, channel_id, user_id, enabled, created_at, last_run, last_error
+		       platform, channel_id, user_id, enabled, created_at, last_run, last_error, provenance,
+		       fail_count, stale_since, notify_via, context, last_result
 		FROM jobs
 	`)
 	if err != nil {
@@ -249,35 +247,73 @@ func (s *Store) SaveJob(job *Job) error {
 		lastRun = &t
 	}
 
+	var runAt *string
+	if job.RunAt != nil {
+		t := job.RunAt.Format(time.RFC3339)
+		runAt = &t
+	}
+
 	var lastError *string
 	if job.LastError != "" {
 		lastError = &job.LastError
 	}
 
+	var staleSince *string
+	if job.StaleSince != nil {
+		t := job.StaleSince.Format(time.RFC3339)
+		staleSince = &t
+	}
+
+	var provJSON *string
+	if job.Provenance != nil {
+		data, err := json.Marshal(job.Provenance)
+		if err != nil {
+			return fmt.Errorf("failed to marshal provenance: %w", err)
+		}
+		p := string(data)
+		provJSON = &p
+	}
+
+	var notifyVia *string
+	if len(job.NotifyVia) > 0 {
+		data, err := json.Marshal(job.NotifyVia)
+		if err != nil {
+			return fmt.Errorf("failed to marshal notify_via: %w", err)
+		}
+		v := string(data)
+		notifyVia = &v
+	}
+
 	enabled := 0
 	if job.Enabled {
 		enabled = 1
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO jobs (id, name, tag, job_type, schedule, tool, arguments, message, prompt,
+		INSERT INTO jobs (id, name, tag, job_type, schedule, run_at, tool, arguments, message, prompt,
 		                  endpoint, auth_header, relay_mode, source,
-		                  platform, channel_id, user_id, enabled, created_at, last_run, last_error)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		                  platform, channel_id, user_id, enabled, created_at, last_run, last_error, provenance,
+		                  fail_count, stale_since, notify_via, context, last_result)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name=excluded.name, tag=excluded.tag, job_type=excluded.job_type,
-			schedule=excluded.schedule, tool=excluded.tool,
+			schedule=excluded.schedule, run_at=excluded.run_at, tool=excluded.tool,
 			arguments=excluded.arguments, message=excluded.message, prompt=excluded.prompt,
 			endpoint=excluded.endpoint, auth_header=excluded.auth_header,
 			relay_mode=excluded.relay_mode, source=excluded.source,
 			platform=excluded.platform, channel_id=excluded.channel_id, user_id=excluded.user_id,
 			enabled=excluded.enabled, created_at=excluded.created_at,
-			last_run=excluded.last_run, last_error=excluded.last_error
+			last_run=excluded.last_run, last_error=excluded.last_error,
+			provenance=excluded.provenance,
+			fail_count=excluded.fail_count, stale_since=excluded.stale_since,
+			notify_via=excluded.notify_via, context=excluded.context,
+			last_result=excluded.last_result
 	`,
-		job.ID, job.Name, job.Tag, job.Type, job.Schedule, job.Tool, string(argsJSON), job.Message, job.Prompt,
+		job.ID, job.Name, job.Tag, job.Type, job.Schedule, runAt, job.Tool, string(argsJSON), job.Message, job.Prompt,
 		job.Endpoint, job.AuthHeader, boolToInt(job.RelayMode), job.Source,
 		job.Platform, job.ChannelID, job.UserID, enabled, job.CreatedAt.Format(time.RFC3339),
-		lastRun, lastError,
+		lastRun, lastError, provJSON,
+		job.FailCount, staleSince, notifyVia, job.Context, job.LastResult,
 	)
 	return err
 }
@@ -316,6 +352,7 @@ func scanJob(s scanner) (*Job, error) {
 		job        Job
 		tag        sql.NullString
 		jobType    sql.NullString
+		runAt      sql.NullString
 		argsJSON   sql.NullString
 		tool       sql.NullString
 		message    sql.NullString
@@ -331,12 +368,18 @@ func scanJob(s scanner) (*Job, error) {
 		createdAt  string
 		lastRun    sql.NullString
 		lastError  sql.NullString
+		provJSON   sql.NullString
+		staleSince sql.NullString
+		notifyVia  sql.NullString
+		jobContext sql.NullString
+		lastResult sql.NullString
 	)
 
 	err := s.Scan(
-		&job.ID, &job.Name, &tag, &jobType, &job.Schedule, &tool, &argsJSON, &message, &prompt,
+		&job.ID, &job.Name, &tag, &jobType, &job.Schedule, &runAt, &tool, &argsJSON, &message, &prompt,
 		&endpoint, &authHeader, &relayMode, &source,
-		&platform, &channelID, &userID, &enabled, &createdAt, &lastRun, &lastError,
+		&platform, &channelID, &userID, &enabled, &createdAt, &lastRun, &lastError, &provJSON,
+		&job.FailCount, &staleSince, &notifyVia, &jobContext, &lastResult,
 	)
 	if err != nil {
 		return nil, err
@@ -356,6 +399,8 @@ func scanJob(s scanner) (*Job, error) {
 	job.UserID = userID.String
 	job.Enabled = enabled != 0
 	job.LastError = lastError.String
+	job.Context = jobContext.String
+	job.LastResult = lastResult.String
 
 	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 		job.CreatedAt = t
@@ -365,6 +410,16 @@ func scanJob(s scanner) (*Job, error) {
 			job.LastRun = &t
 		}
 	}
+	if staleSince.Valid && staleSince.String != "" {
+		if t, err := time.Parse(time.RFC3339, staleSince.String); err == nil {
+			job.StaleSince = &t
+		}
+	}
+	if runAt.Valid && runAt.String != "" {
+		if t, err := time.Parse(time.RFC3339, runAt.String); err == nil {
+			job.RunAt = &t
+		}
+	}
 
 	if argsJSON.Valid && argsJSON.String != "" && argsJSON.String != "null" {
 		if err := json.Unmarshal([]byte(argsJSON.String), &job.Arguments); err != nil {
@@ -372,6 +427,19 @@ func scanJob(s scanner) (*Job, error) {
 		}
 	}
 
+	if notifyVia.Valid && notifyVia.String != "" {
+		if err := json.Unmarshal([]byte(notifyVia.String), &job.NotifyVia); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notify_via: %w", err)
+		}
+	}
+
+	if provJSON.Valid && provJSON.String != "" {
+		var rec provenance.Record
+		if err := json.Unmarshal([]byte(provJSON.String), &rec); err == nil {
+			job.Provenance = &rec
+		}
+	}
+
 	return &job, nil
 }
 
@@ -0,0 +1,233 @@
+// Package datadir resolves where coco keeps its state (.coco.db, .coco.yaml
+// and the .coco directory with registries, skills and keys), independent of
+// where the binary is installed.
+//
+// Resolution order:
+//
+//  1. $COCO_DATA_DIR
+//  2. $XDG_DATA_HOME/coco or ~/.local/share/coco on Linux and other Unix,
+//     ~/Library/Application Support/coco on macOS, %APPDATA%\coco on Windows
+//  3. The executable's directory, if no home directory can be found
+//
+// Older versions kept everything next to the executable; Migrate moves it.
+package datadir
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// EnvVar overrides the data directory.
+const EnvVar = "COCO_DATA_DIR"
+
+// legacyEntries are the names older versions created next to the executable.
+var legacyEntries = []string{".coco.db", ".coco.db-wal", ".coco.db-shm", ".coco.yaml", ".coco"}
+
+// Dir returns the data directory. It is not created.
+func Dir() string {
+	if dir := strings.TrimSpace(os.Getenv(EnvVar)); dir != "" {
+		return expandHome(dir)
+	}
+	if dir := platformDir(); dir != "" {
+		return dir
+	}
+	return ExecutableDir()
+}
+
+// Path joins elem onto the data directory.
+func Path(elem ...string) string {
+	return filepath.Join(append([]string{Dir()}, elem...)...)
+}
+
+// ExpandTilde expands a leading ~ to the data directory ("coco home"),
+// not the user's home directory.
+func ExpandTilde(path string) string {
+	if path == "~" {
+		return Dir()
+	}
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		return filepath.Join(Dir(), path[2:])
+	}
+	return path
+}
+
+func platformDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "coco")
+		}
+	case "darwin":
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, "Library", "Application Support", "coco")
+		}
+	default:
+		if xdg := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(xdg) {
+			return filepath.Join(xdg, "coco")
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, ".local", "share", "coco")
+		}
+	}
+	return ""
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// ExecutableDir returns the directory of the running binary, with symlinks
+// resolved, or "." if it cannot be determined.
+func ExecutableDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe)
+}
+
+// Migrate moves state left next to the executable by older versions into the
+// data directory and returns a line per entry it handled. Entries already
+// present in the data directory are kept; directories are merged. When the
+// old location is read-only the files are copied and the originals left.
+func Migrate() ([]string, error) {
+	return migrate(ExecutableDir(), Dir())
+}
+
+func migrate(from, to string) ([]string, error) {
+	if sameDir(from, to) {
+		return nil, nil
+	}
+	var found []string
+	for _, name := range legacyEntries {
+		if _, err := os.Lstat(filepath.Join(from, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(to, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	var notes []string
+	for _, name := range found {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		moved, err := movePath(src, dst)
+		if err != nil {
+			return notes, fmt.Errorf("migrate %s: %w", src, err)
+		}
+		if moved {
+			notes = append(notes, fmt.Sprintf("moved %s to %s", src, dst))
+		} else {
+			notes = append(notes, fmt.Sprintf("copied %s to %s; the old copy remains (read-only, or already present in the data dir)", src, dst))
+		}
+	}
+	return notes, nil
+}
+
+// movePath moves src to dst, merging directories. It reports false when the
+// source could not be removed afterwards, i.e. the data was only copied.
+func movePath(src, dst string) (bool, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		if os.Rename(src, dst) == nil {
+			return true, nil
+		}
+		if err := copyPath(src, dst, info); err != nil {
+			return false, err
+		}
+		return os.RemoveAll(src) == nil, nil
+	}
+	if !info.IsDir() {
+		// The data directory already has this file; it wins.
+		return false, nil
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return false, err
+	}
+	all := true
+	for _, e := range entries {
+		moved, err := movePath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()))
+		if err != nil {
+			return false, err
+		}
+		all = all && moved
+	}
+	if all {
+		os.Remove(src)
+	}
+	return all, nil
+}
+
+func copyPath(src, dst string, info fs.FileInfo) error {
+	if info.IsDir() {
+		if err := os.MkdirAll(dst, info.Mode().Perm()|0o700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			childInfo, err := e.Info()
+			if err != nil {
+				return err
+			}
+			if err := copyPath(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), childInfo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func sameDir(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
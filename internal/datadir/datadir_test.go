@@ -0,0 +1,79 @@
+package datadir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirPrefersEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVar, dir)
+	if got := Dir(); got != dir {
+		t.Fatalf("Dir() = %q, want %q", got, dir)
+	}
+	if got := ExpandTilde("~/skills"); got != filepath.Join(dir, "skills") {
+		t.Fatalf("ExpandTilde = %q", got)
+	}
+	if got := ExpandTilde("~user/x"); got != "~user/x" {
+		t.Fatalf("ExpandTilde must leave ~user alone, got %q", got)
+	}
+}
+
+func TestMigrateMovesAndMergesLegacyLayout(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(from, ".coco.db"), "db")
+	write(filepath.Join(from, ".coco", "providers.yaml"), "old providers")
+	write(filepath.Join(from, ".coco", "skills", "x", "SKILL.md"), "skill")
+	write(filepath.Join(to, ".coco", "providers.yaml"), "new providers")
+	write(filepath.Join(from, "unrelated.txt"), "keep")
+
+	notes, err := migrate(from, to)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("notes = %v", notes)
+	}
+
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+	if read(filepath.Join(to, ".coco.db")) != "db" {
+		t.Fatal(".coco.db was not moved")
+	}
+	if read(filepath.Join(to, ".coco", "providers.yaml")) != "new providers" {
+		t.Fatal("existing file in the data dir must win")
+	}
+	if read(filepath.Join(to, ".coco", "skills", "x", "SKILL.md")) != "skill" {
+		t.Fatal("directory contents were not merged")
+	}
+	if _, err := os.Stat(filepath.Join(from, ".coco.db")); !os.IsNotExist(err) {
+		t.Fatal("moved file still in the old location")
+	}
+	if read(filepath.Join(from, "unrelated.txt")) != "keep" {
+		t.Fatal("unrelated files must not be touched")
+	}
+	if !strings.Contains(notes[1], "old copy remains") {
+		t.Fatalf("conflicting .coco should be reported as partially copied: %v", notes)
+	}
+
+	if again, err := migrate(from, to); err != nil || len(again) != 1 {
+		// Only the conflicting providers.yaml remains behind.
+		t.Fatalf("second migrate = %v, %v", again, err)
+	}
+	if notes, _ := migrate(to, to); notes != nil {
+		t.Fatalf("same dir should be a no-op, got %v", notes)
+	}
+}
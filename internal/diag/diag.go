@@ -0,0 +1,165 @@
+// Package diag builds support bundles: a zip of redacted configuration,
+// logs and runtime information that users can attach to bug reports.
+package diag
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces every secret value in a bundle.
+const Redacted = "[REDACTED]"
+
+// minSecretLen keeps very short values (e.g. "1", "on") from being scrubbed
+// everywhere they happen to appear.
+const minSecretLen = 6
+
+// sensitiveKeyParts mark YAML keys whose values must not leave the machine.
+var sensitiveKeyParts = []string{
+	"key", "token", "secret", "password", "passphrase", "credential", "cookie", "auth", "webhook",
+}
+
+// IsSensitiveKey reports whether a config key holds a credential.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactYAML replaces the values of sensitive keys in a YAML document and
+// returns the redacted document together with the values it removed, so they
+// can also be scrubbed from logs. Empty values are kept to show what is unset.
+func RedactYAML(data []byte) ([]byte, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+	var removed []string
+	redactNode(&root, false, &removed)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, nil, err
+	}
+	enc.Close()
+	return buf.Bytes(), removed, nil
+}
+
+func redactNode(n *yaml.Node, sensitive bool, removed *[]string) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			redactNode(c, sensitive, removed)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			redactNode(n.Content[i+1], sensitive || IsSensitiveKey(n.Content[i].Value), removed)
+		}
+	case yaml.ScalarNode:
+		if sensitive && n.Value != "" && n.Tag != "!!bool" {
+			*removed = append(*removed, n.Value)
+			n.Value = Redacted
+			n.Tag = "!!str"
+			n.Style = 0
+		}
+	}
+}
+
+// Redact replaces every occurrence of the given secret values in text.
+func Redact(text string, secrets []string) string {
+	sorted := append([]string(nil), secrets...)
+	// Longest first so a secret containing another is replaced whole.
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, s := range sorted {
+		if len(s) >= minSecretLen {
+			text = strings.ReplaceAll(text, s, Redacted)
+		}
+	}
+	return text
+}
+
+// Tail returns the last maxLines lines of a file.
+func Tail(path string, maxLines int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, maxLines)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) == maxLines {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// Bundle writes a zip archive, scrubbing known secrets from every file.
+type Bundle struct {
+	zw      *zip.Writer
+	out     io.Closer
+	secrets []string
+	files   []string
+}
+
+// Create starts a bundle at path.
+func Create(path string) (*Bundle, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{zw: zip.NewWriter(f), out: f}, nil
+}
+
+// AddSecrets registers values to scrub from files added afterwards.
+func (b *Bundle) AddSecrets(values ...string) {
+	b.secrets = append(b.secrets, values...)
+}
+
+// AddFile writes name into the bundle after redaction.
+func (b *Bundle) AddFile(name string, data []byte) error {
+	w, err := b.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, Redact(string(data), b.secrets)); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	b.files = append(b.files, name)
+	return nil
+}
+
+// Files lists what has been added so far.
+func (b *Bundle) Files() []string {
+	return append([]string(nil), b.files...)
+}
+
+// Close finishes the archive.
+func (b *Bundle) Close() error {
+	if err := b.zw.Close(); err != nil {
+		b.out.Close()
+		return err
+	}
+	return b.out.Close()
+}
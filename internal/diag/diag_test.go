@@ -0,0 +1,74 @@
+package diag
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactYAMLRemovesCredentials(t *testing.T) {
+	in := []byte(`
+platforms:
+  wecom:
+    corp_id: ww123
+    secret: s3cr3t-value
+    aes_key: ""
+relay:
+  use_media_proxy: true
+sync:
+  passphrase: correct horse battery
+providers:
+  - name: deepseek
+    api_keys: [sk-aaaaaaaa, sk-bbbbbbbb]
+`)
+	out, removed, err := RedactYAML(in)
+	if err != nil {
+		t.Fatalf("redact: %v", err)
+	}
+	text := string(out)
+	for _, secret := range []string{"s3cr3t-value", "correct horse battery", "sk-aaaaaaaa", "sk-bbbbbbbb"} {
+		if strings.Contains(text, secret) {
+			t.Fatalf("%q survived redaction:\n%s", secret, text)
+		}
+	}
+	for _, kept := range []string{"corp_id: ww123", `aes_key: ""`, "use_media_proxy: true", "name: deepseek"} {
+		if !strings.Contains(text, kept) {
+			t.Fatalf("expected %q to be kept:\n%s", kept, text)
+		}
+	}
+	if len(removed) != 4 {
+		t.Fatalf("expected 4 removed values, got %v", removed)
+	}
+}
+
+func TestBundleScrubsSecretsFromEveryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diag.zip")
+	b, err := Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	b.AddSecrets("sk-aaaaaaaa", "on")
+	if err := b.AddFile("logs/coco.log", []byte("calling api with sk-aaaaaaaa\nproxy on\n")); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer zr.Close()
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("open entry: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if got := string(data); got != "calling api with [REDACTED]\nproxy on\n" {
+		t.Fatalf("unexpected log content: %q", got)
+	}
+}
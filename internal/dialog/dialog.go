@@ -0,0 +1,397 @@
+// Package dialog collects structured input over several chat turns. It asks
+// for each missing field, validates the answers and, when the form wants it,
+// shows a summary to confirm before the caller acts on the values.
+package dialog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field types. Answers are normalized: numbers without units or separators,
+// dates as 2006-01-02, times as 15:04 and datetimes as "2006-01-02 15:04".
+const (
+	TypeText     = "text"
+	TypeNumber   = "number"
+	TypeDate     = "date"
+	TypeTime     = "time"
+	TypeDateTime = "datetime"
+	TypeChoice   = "choice"
+)
+
+// Status is where a dialog stands after a turn.
+type Status int
+
+const (
+	Asking    Status = iota // waiting for the user's next answer
+	Done                    // all values collected (and confirmed)
+	Cancelled               // the user gave up
+)
+
+var (
+	abortWords   = []string{"取消", "算了", "不办了", "cancel", "quit"}
+	skipWords    = []string{"跳过", "无", "没有", "skip", "none"}
+	confirmWords = []string{"确认", "确定", "提交", "是", "好", "对", "yes", "y", "ok", "confirm"}
+	editPrefixes = []string{"修改", "改", "edit ", "change "}
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Field is one value a form collects.
+type Field struct {
+	Name     string
+	Label    string   // shown in the summary (default: Name)
+	Prompt   string   // question asked while the field is missing
+	Type     string   // one of the Type constants (default: text)
+	Choices  []string // allowed answers of a choice field
+	Pattern  string   // regex the answer must match; its first group is kept
+	Optional bool     // may be skipped with "跳过"
+}
+
+// Form describes what a dialog collects.
+type Form struct {
+	Title   string
+	Fields  []Field
+	Confirm bool // show a summary and wait for "确认" before finishing
+}
+
+// Dialog is one run of a form.
+type Dialog struct {
+	form       Form
+	patterns   []*regexp.Regexp
+	values     map[string]string
+	asking     int // field of the last question, -1 when none
+	confirming bool
+}
+
+// New validates form and starts a dialog for it.
+func New(form Form) (*Dialog, error) {
+	d := &Dialog{form: form, values: map[string]string{}, asking: -1}
+	for i, f := range form.Fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("field %d has no name", i+1)
+		}
+		switch f.Type {
+		case "", TypeText, TypeNumber, TypeDate, TypeTime, TypeDateTime:
+		case TypeChoice:
+			if len(f.Choices) == 0 {
+				return nil, fmt.Errorf("choice field %s has no choices", f.Name)
+			}
+		default:
+			return nil, fmt.Errorf("field %s has unknown type %q", f.Name, f.Type)
+		}
+		var re *regexp.Regexp
+		if f.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(f.Pattern); err != nil {
+				return nil, fmt.Errorf("field %s: invalid pattern: %w", f.Name, err)
+			}
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	return d, nil
+}
+
+// Title returns the form's title.
+func (d *Dialog) Title() string {
+	return d.form.Title
+}
+
+// Prefill takes values already known, such as arguments a tool call did
+// provide. Values that do not validate are asked for again.
+func (d *Dialog) Prefill(values map[string]string) {
+	for i, f := range d.form.Fields {
+		if v, ok := d.validate(i, values[f.Name]); ok {
+			d.values[f.Name] = v
+		}
+	}
+}
+
+// Extract fills fields with a pattern from free text, such as the request
+// that started the dialog.
+func (d *Dialog) Extract(text string) {
+	for i, f := range d.form.Fields {
+		if _, ok := d.values[f.Name]; ok || d.patterns[i] == nil {
+			continue
+		}
+		if v, ok := d.validate(i, text); ok {
+			d.values[f.Name] = v
+		}
+	}
+}
+
+// Values returns the collected values; skipped optional fields are "".
+func (d *Dialog) Values() map[string]string {
+	out := make(map[string]string, len(d.values))
+	for k, v := range d.values {
+		out[k] = v
+	}
+	return out
+}
+
+// Start returns the first question, the summary to confirm, or Done when
+// nothing is missing.
+func (d *Dialog) Start() (string, Status) {
+	return d.next()
+}
+
+// Answer takes the user's reply and returns what to say next.
+func (d *Dialog) Answer(text string) (string, Status) {
+	text = strings.TrimSpace(text)
+	if matchesWord(text, abortWords) {
+		return fmt.Sprintf("已取消「%s」。", d.form.Title), Cancelled
+	}
+
+	if d.confirming {
+		if matchesWord(text, confirmWords) {
+			return "", Done
+		}
+		if i := d.editTarget(text); i >= 0 {
+			d.confirming = false
+			delete(d.values, d.form.Fields[i].Name)
+			return d.next()
+		}
+		return d.Summary() + "\n\n" + confirmHint, Asking
+	}
+
+	if d.asking >= 0 {
+		f := d.form.Fields[d.asking]
+		if f.Optional && matchesWord(text, skipWords) {
+			d.values[f.Name] = ""
+		} else if v, ok := d.validate(d.asking, text); ok {
+			d.values[f.Name] = v
+		} else {
+			return "没能识别" + typeHint(f) + "，请重新输入。" + d.question(f), Asking
+		}
+	}
+	return d.next()
+}
+
+const confirmHint = "回复“确认”提交，“修改 字段名”更改，或“取消”放弃。"
+
+// Summary lists the collected values.
+func (d *Dialog) Summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "请确认「%s」：", d.form.Title)
+	for _, f := range d.form.Fields {
+		v := d.values[f.Name]
+		if v == "" {
+			v = "（无）"
+		}
+		fmt.Fprintf(&sb, "\n- %s：%s", label(f), v)
+	}
+	return sb.String()
+}
+
+func (d *Dialog) next() (string, Status) {
+	for i, f := range d.form.Fields {
+		if _, ok := d.values[f.Name]; ok {
+			continue
+		}
+		d.asking = i
+		return d.question(f), Asking
+	}
+	d.asking = -1
+	if d.form.Confirm && !d.confirming {
+		d.confirming = true
+		return d.Summary() + "\n\n" + confirmHint, Asking
+	}
+	return "", Done
+}
+
+func (d *Dialog) question(f Field) string {
+	q := f.Prompt
+	if q == "" {
+		q = "请输入" + label(f) + "："
+	}
+	if f.Type == TypeChoice {
+		q += "（" + strings.Join(f.Choices, " / ") + "）"
+	}
+	if f.Optional {
+		q += "（可回复“跳过”）"
+	}
+	return q
+}
+
+// editTarget finds the field named in "修改 金额".
+func (d *Dialog) editTarget(text string) int {
+	lower := strings.ToLower(text)
+	for _, prefix := range editPrefixes {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		name := strings.TrimSpace(text[len(prefix):])
+		for i, f := range d.form.Fields {
+			if strings.EqualFold(name, f.Name) || name == label(f) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// validate applies field i's pattern and type to text.
+func (d *Dialog) validate(i int, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+	if re := d.patterns[i]; re != nil {
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			return "", false
+		}
+		text = strings.TrimSpace(m[0])
+		if len(m) > 1 {
+			text = strings.TrimSpace(m[1])
+		}
+	}
+
+	f := d.form.Fields[i]
+	switch f.Type {
+	case TypeNumber:
+		return parseNumber(text)
+	case TypeDate:
+		t, ok := parseDate(text)
+		return t.Format("2006-01-02"), ok
+	case TypeTime:
+		return parseClock(text)
+	case TypeDateTime:
+		return parseDateTime(text)
+	case TypeChoice:
+		return parseChoice(text, f.Choices)
+	}
+	return text, true
+}
+
+func parseNumber(text string) (string, bool) {
+	s := strings.NewReplacer(",", "", "，", "", "元", "", "¥", "", "￥", "", " ", "").Replace(text)
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+var monthDayPattern = regexp.MustCompile(`^(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]?$`)
+
+func parseDate(text string) (time.Time, bool) {
+	today := now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	switch text {
+	case "今天", "today":
+		return today, true
+	case "明天", "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "后天":
+		return today.AddDate(0, 0, 2), true
+	}
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006.01.02", "2006-1-2", "2006/1/2"} {
+		if t, err := time.ParseInLocation(layout, text, today.Location()); err == nil {
+			return t, true
+		}
+	}
+	if m := monthDayPattern.FindStringSubmatch(text); m != nil {
+		year := today.Year()
+		if m[1] != "" {
+			year, _ = strconv.Atoi(m[1])
+		}
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+		if t.Month() == time.Month(month) && t.Day() == day {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+var clockPattern = regexp.MustCompile(`^(上午|下午|晚上)?(\d{1,2})(?:[:：](\d{2})|点(?:(\d{1,2})分?|半)?)$`)
+
+func parseClock(text string) (string, bool) {
+	m := clockPattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute := 0
+	switch {
+	case m[3] != "":
+		minute, _ = strconv.Atoi(m[3])
+	case m[4] != "":
+		minute, _ = strconv.Atoi(m[4])
+	case strings.HasSuffix(text, "半"):
+		minute = 30
+	}
+	if (m[1] == "下午" || m[1] == "晚上") && hour < 12 {
+		hour += 12
+	}
+	if hour > 23 || minute > 59 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), true
+}
+
+func parseDateTime(text string) (string, bool) {
+	datePart, clockPart, ok := strings.Cut(strings.Join(strings.Fields(text), " "), " ")
+	if !ok {
+		return "", false
+	}
+	date, ok := parseDate(datePart)
+	if !ok {
+		return "", false
+	}
+	clock, ok := parseClock(clockPart)
+	if !ok {
+		return "", false
+	}
+	return date.Format("2006-01-02") + " " + clock, true
+}
+
+func parseChoice(text string, choices []string) (string, bool) {
+	for _, c := range choices {
+		if strings.EqualFold(text, c) {
+			return c, true
+		}
+	}
+	if n, err := strconv.Atoi(text); err == nil && n >= 1 && n <= len(choices) {
+		return choices[n-1], true
+	}
+	return "", false
+}
+
+func typeHint(f Field) string {
+	switch f.Type {
+	case TypeNumber:
+		return "数字"
+	case TypeDate:
+		return "日期（如 2024-05-01、明天）"
+	case TypeTime:
+		return "时间（如 14:30、下午3点）"
+	case TypeDateTime:
+		return "日期时间（如 2024-05-01 14:30、明天 下午3点）"
+	case TypeChoice:
+		return "选项"
+	}
+	return ""
+}
+
+func label(f Field) string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Name
+}
+
+func matchesWord(text string, words []string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, w := range words {
+		if text == w {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package dialog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialog(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	d, err := New(Form{
+		Title:   "预约会议室",
+		Confirm: true,
+		Fields: []Field{
+			{Name: "room", Label: "会议室", Type: TypeChoice, Choices: []string{"A101", "B202"}},
+			{Name: "when", Label: "时间", Type: TypeDateTime},
+			{Name: "people", Label: "人数", Type: TypeNumber, Pattern: `(\d+)\s*人`},
+			{Name: "note", Label: "备注", Optional: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.Extract("帮我订个 8 人的会议室")
+	d.Prefill(map[string]string{"room": "C303"}) // not a choice, asked again
+
+	q, status := d.Start()
+	if status != Asking || !strings.Contains(q, "A101 / B202") {
+		t.Fatalf("first question = %q, %v", q, status)
+	}
+	if q, _ = d.Answer("2"); !strings.Contains(q, "时间") {
+		t.Fatalf("second question = %q", q)
+	}
+	if q, _ = d.Answer("下周吧"); !strings.HasPrefix(q, "没能识别日期时间") {
+		t.Fatalf("re-ask = %q", q)
+	}
+	if q, _ = d.Answer("明天 下午3点半"); !strings.Contains(q, "跳过") {
+		t.Fatalf("optional question = %q", q)
+	}
+	summary, status := d.Answer("跳过")
+	want := "请确认「预约会议室」：\n- 会议室：B202\n- 时间：2024-05-02 15:30\n- 人数：8\n- 备注：（无）"
+	if status != Asking || !strings.HasPrefix(summary, want) {
+		t.Fatalf("summary = %q", summary)
+	}
+
+	// Editing a field asks for it again and confirms once more.
+	if q, _ = d.Answer("修改 人数"); !strings.Contains(q, "人数") {
+		t.Fatalf("edit question = %q", q)
+	}
+	if q, _ = d.Answer("12 人"); !strings.Contains(q, "- 人数：12") {
+		t.Fatalf("summary after edit = %q", q)
+	}
+	if _, status = d.Answer("确认"); status != Done {
+		t.Fatalf("confirm status = %v", status)
+	}
+	if v := d.Values(); v["room"] != "B202" || v["people"] != "12" || v["note"] != "" {
+		t.Fatalf("values = %#v", v)
+	}
+}
+
+func TestDialogCancelAndInvalidForm(t *testing.T) {
+	d, err := New(Form{Title: "报销", Fields: []Field{{Name: "amount", Type: TypeNumber}}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.Start()
+	if q, status := d.Answer("取消"); status != Cancelled || q != "已取消「报销」。" {
+		t.Fatalf("cancel = %q, %v", q, status)
+	}
+
+	for _, form := range []Form{
+		{Fields: []Field{{Name: "x", Type: "color"}}},
+		{Fields: []Field{{Name: "x", Type: TypeChoice}}},
+		{Fields: []Field{{Name: "x", Pattern: "("}}},
+		{Fields: []Field{{}}},
+	} {
+		if _, err := New(form); err == nil {
+			t.Fatalf("New(%#v) accepted an invalid form", form)
+		}
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 12, 30, 9, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	for _, c := range []struct{ in, want string }{
+		{"后天", "2025-01-01"},
+		{"2024/2/29", "2024-02-29"},
+		{"3月5日", "2024-03-05"},
+		{"2月30日", ""},
+	} {
+		got, ok := parseDate(c.in)
+		if (c.want == "") == ok || ok && got.Format("2006-01-02") != c.want {
+			t.Errorf("parseDate(%q) = %v, %v", c.in, got, ok)
+		}
+	}
+	for _, c := range []struct{ in, want string }{
+		{"9:05", "09:05"},
+		{"15点", "15:00"},
+		{"晚上8点20分", "20:20"},
+		{"25:00", ""},
+	} {
+		if got, _ := parseClock(c.in); got != c.want {
+			t.Errorf("parseClock(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	if got, ok := parseNumber("¥1,280.50"); !ok || got != "1280.50" {
+		t.Errorf("parseNumber = %q, %v", got, ok)
+	}
+}
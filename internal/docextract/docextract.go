@@ -0,0 +1,140 @@
+// Package docextract pulls plain text out of PDF and Office documents so the
+// agent can read files users send it.
+package docextract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats Extract understands.
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+	FormatXLSX = "xlsx"
+	FormatPPTX = "pptx"
+)
+
+// ErrUnsupported is returned for files that are not a supported document.
+var ErrUnsupported = errors.New("unsupported document format")
+
+// docxPageChars is the size of the chunks a DOCX is split into; Word files
+// carry no page breaks of their own.
+const docxPageChars = 3000
+
+// Document is an extracted document's text split into pages: PDF pages,
+// slides, sheets, or fixed-size chunks for DOCX.
+type Document struct {
+	Format string
+	Pages  []string
+}
+
+// Text returns the whole document, pages separated by blank lines.
+func (d *Document) Text() string {
+	return strings.Join(d.Pages, "\n\n")
+}
+
+// PageUnit names the document's pages in messages to the user.
+func (d *Document) PageUnit() string {
+	switch d.Format {
+	case FormatPPTX:
+		return "张幻灯片"
+	case FormatXLSX:
+		return "个工作表"
+	case FormatDOCX:
+		return "段"
+	default:
+		return "页"
+	}
+}
+
+// Detect returns the format of a document from its name, falling back to
+// its content, or "" when it is not one Extract handles.
+func Detect(data []byte, name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return FormatPDF
+	case ".docx":
+		return FormatDOCX
+	case ".xlsx", ".xlsm":
+		return FormatXLSX
+	case ".pptx":
+		return FormatPPTX
+	}
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return FormatPDF
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return detectOOXML(data)
+	}
+	return ""
+}
+
+// Extract reads the text of a document held in memory. name is used to
+// detect the format and may be empty.
+func Extract(ctx context.Context, data []byte, name string) (*Document, error) {
+	var pages []string
+	var err error
+	format := Detect(data, name)
+	switch format {
+	case FormatPDF:
+		pages, err = extractPDF(ctx, data)
+	case FormatDOCX:
+		var text string
+		if text, err = extractDOCX(data); err == nil {
+			pages = splitChunks(text, docxPageChars)
+		}
+	case FormatXLSX:
+		pages, err = extractXLSX(data)
+	case FormatPPTX:
+		pages, err = extractPPTX(data)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range pages {
+		pages[i] = strings.TrimSpace(pages[i])
+	}
+	return &Document{Format: format, Pages: pages}, nil
+}
+
+// ExtractFile reads the text of the document at path.
+func ExtractFile(ctx context.Context, path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Extract(ctx, data, filepath.Base(path))
+}
+
+// splitChunks cuts text into pieces of about size runes, preferring
+// paragraph boundaries.
+func splitChunks(text string, size int) []string {
+	var chunks []string
+	var cur strings.Builder
+	curLen := 0
+	for _, para := range strings.SplitAfter(text, "\n") {
+		r := []rune(para)
+		if curLen > 0 && curLen+len(r) > size {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			curLen = 0
+		}
+		for len(r) > size {
+			chunks = append(chunks, string(r[:size]))
+			r = r[size:]
+		}
+		cur.WriteString(string(r))
+		curLen += len(r)
+	}
+	if cur.Len() > 0 || len(chunks) == 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
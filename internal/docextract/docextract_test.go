@@ -0,0 +1,175 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := zipOf(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>季度</w:t></w:r><w:r><w:t xml:space="preserve">报告</w:t></w:r></w:p>
+<w:p><w:r><w:t>收入</w:t><w:tab/><w:t>120 万</w:t></w:r></w:p>
+</w:body></w:document>`,
+	})
+	doc, err := Extract(context.Background(), data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatDOCX || doc.Text() != "季度报告\n收入\t120 万" {
+		t.Fatalf("got %s %q", doc.Format, doc.Text())
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	rel := `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	data := zipOf(t, map[string]string{
+		"xl/workbook.xml":            `<workbook ` + rel + `><sheets><sheet name="预算" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>项目</t></si><si><r><t>金</t></r><r><t>额</t></r></si><si><t>差旅</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>3500</v></c></row>
+<row r="3"><c r="B3" t="inlineStr"><is><t>备注</t></is></c></row>
+</sheetData></worksheet>`,
+	})
+	doc, err := Extract(context.Background(), data, "budget.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## 预算\n项目\t\t金额\n差旅\t\t3500\n\t备注"
+	if len(doc.Pages) != 1 || doc.Pages[0] != want {
+		t.Fatalf("pages = %q", doc.Pages)
+	}
+}
+
+func TestExtractPPTXFollowsSlideOrder(t *testing.T) {
+	rel := `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	slide := func(text string) string {
+		return `<p:sld xmlns:a="a" xmlns:p="p"><p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	data := zipOf(t, map[string]string{
+		"ppt/presentation.xml":            `<p:presentation xmlns:p="p" ` + rel + `><p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships><Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="slides/slide2.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml":           slide("Second"),
+		"ppt/slides/slide2.xml":           slide("First"),
+	})
+	doc, err := Extract(context.Background(), data, "deck.pptx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Pages) != 2 || doc.Pages[0] != "First" || doc.Pages[1] != "Second" {
+		t.Fatalf("pages = %q", doc.Pages)
+	}
+}
+
+// buildPDF assembles a PDF from object bodies numbered from 1; object 1 must
+// be the catalog.
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func stream(dict, content string, compress bool) string {
+	data := []byte(content)
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func TestReadPDF(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar
+<0001> <4F1A>
+<0002> <8BAE>
+endbfchar
+1 beginbfrange
+<0010> <0011> <7EAA>
+endbfrange
+endcmap`
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [4 0 R 3 0 R] /Count 2 /Resources << /Font << /F1 7 0 R /F2 8 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [6 0 R] >>",
+		stream("", "BT /F1 12 Tf 72 720 Td (Second page) Tj ET", false),
+		stream("", "BT /F2 12 Tf 72 720 Td <000100020010> Tj 0 -14 Td /F1 12 Tf [(Hello) -300 (World \\(draft\\))] TJ ET", true),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /ToUnicode 9 0 R >>",
+		stream("", cmap, true),
+	)
+
+	pages, err := readPDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("pages = %q", pages)
+	}
+	if got := strings.TrimSpace(pages[0]); got != "会议纪\nHello World (draft)" {
+		t.Fatalf("page 1 = %q", got)
+	}
+	if got := strings.TrimSpace(pages[1]); got != "Second page" {
+		t.Fatalf("page 2 = %q", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for name, want := range map[string]string{"a.PDF": FormatPDF, "b.docx": FormatDOCX, "c.xlsm": FormatXLSX, "d.pptx": FormatPPTX, "e.txt": ""} {
+		if got := Detect(nil, name); got != want {
+			t.Errorf("Detect(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if got := Detect([]byte("%PDF-1.7\n"), "upload.bin"); got != FormatPDF {
+		t.Errorf("PDF magic not detected: %q", got)
+	}
+	if _, err := Extract(context.Background(), []byte("hello"), "notes.txt"); err != ErrUnsupported {
+		t.Errorf("Extract(txt) err = %v", err)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := strings.Repeat("一二三四五\n", 10)
+	chunks := splitChunks(text, 20)
+	if len(chunks) != 4 {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	if strings.Join(chunks, "") != text {
+		t.Fatal("chunks lost text")
+	}
+}
@@ -0,0 +1,354 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPartBytes caps how much of one zip entry is read, against zip bombs.
+const maxPartBytes = 64 << 20
+
+type ooxmlPackage struct {
+	files map[string]*zip.File
+}
+
+func openOOXML(data []byte) (*ooxmlPackage, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open document: %w", err)
+	}
+	pkg := &ooxmlPackage{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		pkg.files[f.Name] = f
+	}
+	return pkg, nil
+}
+
+func (p *ooxmlPackage) has(name string) bool {
+	_, ok := p.files[name]
+	return ok
+}
+
+func (p *ooxmlPackage) read(name string) ([]byte, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("document part %s missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxPartBytes))
+}
+
+// rels maps relationship IDs of part to their target part names.
+func (p *ooxmlPackage) rels(part string) map[string]string {
+	dir, file := path.Split(part)
+	data, err := p.read(dir + "_rels/" + file + ".rels")
+	if err != nil {
+		return nil
+	}
+	var doc struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if xml.Unmarshal(data, &doc) != nil {
+		return nil
+	}
+	out := make(map[string]string, len(doc.Rels))
+	for _, r := range doc.Rels {
+		if strings.HasPrefix(r.Target, "/") {
+			out[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			out[r.ID] = path.Clean(dir + r.Target)
+		}
+	}
+	return out
+}
+
+func detectOOXML(data []byte) string {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case pkg.has("word/document.xml"):
+		return FormatDOCX
+	case pkg.has("xl/workbook.xml"):
+		return FormatXLSX
+	case pkg.has("ppt/presentation.xml"):
+		return FormatPPTX
+	}
+	return ""
+}
+
+// paragraphText collects the text runs of a WordprocessingML or DrawingML
+// part: textTag elements hold text, paraTag elements end lines.
+func paragraphText(data []byte, textTag, paraTag string) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var sb strings.Builder
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse document xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case textTag:
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case textTag:
+				inText = false
+			case paraTag:
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+func extractDOCX(data []byte) (string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return "", err
+	}
+	doc, err := pkg.read("word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	return paragraphText(doc, "t", "p")
+}
+
+var slideNumberPattern = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+func extractPPTX(data []byte) ([]string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slide order comes from the presentation's slide list; numbered file
+	// names are only a fallback.
+	var slides []string
+	if pres, err := pkg.read("ppt/presentation.xml"); err == nil {
+		var doc struct {
+			IDs []struct {
+				RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+			} `xml:"sldIdLst>sldId"`
+		}
+		if xml.Unmarshal(pres, &doc) == nil {
+			rels := pkg.rels("ppt/presentation.xml")
+			for _, id := range doc.IDs {
+				if target := rels[id.RID]; target != "" && pkg.has(target) {
+					slides = append(slides, target)
+				}
+			}
+		}
+	}
+	if len(slides) == 0 {
+		for name := range pkg.files {
+			if slideNumberPattern.MatchString(name) {
+				slides = append(slides, name)
+			}
+		}
+		sort.Slice(slides, func(i, j int) bool {
+			a, _ := strconv.Atoi(slideNumberPattern.FindStringSubmatch(slides[i])[1])
+			b, _ := strconv.Atoi(slideNumberPattern.FindStringSubmatch(slides[j])[1])
+			return a < b
+		})
+	}
+
+	pages := make([]string, 0, len(slides))
+	for _, name := range slides {
+		part, err := pkg.read(name)
+		if err != nil {
+			return nil, err
+		}
+		text, err := paragraphText(part, "t", "p")
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, text)
+	}
+	return pages, nil
+}
+
+func extractXLSX(data []byte) ([]string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if ss, err := pkg.read("xl/sharedStrings.xml"); err == nil {
+		if shared, err = parseSharedStrings(ss); err != nil {
+			return nil, err
+		}
+	}
+
+	wb, err := pkg.read("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(wb, &doc); err != nil {
+		return nil, fmt.Errorf("parse workbook: %w", err)
+	}
+	rels := pkg.rels("xl/workbook.xml")
+
+	var pages []string
+	for _, sheet := range doc.Sheets {
+		part, err := pkg.read(rels[sheet.RID])
+		if err != nil {
+			continue
+		}
+		rows, err := parseSheet(part, shared)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, "## "+sheet.Name+"\n"+strings.Join(rows, "\n"))
+	}
+	return pages, nil
+}
+
+func parseSharedStrings(data []byte) ([]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var out []string
+	var cur strings.Builder
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse shared strings: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "t" {
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "si":
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				cur.Write(t)
+			}
+		}
+	}
+}
+
+// parseSheet renders a worksheet's rows as tab-separated lines.
+func parseSheet(data []byte, shared []string) ([]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var rows []string
+	var cells []string
+	var cellType, cellRef string
+	var value strings.Builder
+	inValue := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse sheet: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				cells = cells[:0]
+			case "c":
+				cellType, cellRef = "", ""
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "t":
+						cellType = attr.Value
+					case "r":
+						cellRef = attr.Value
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				text := value.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(text); err == nil && i >= 0 && i < len(shared) {
+						text = shared[i]
+					}
+				}
+				if col := columnIndex(cellRef); col >= len(cells) {
+					for len(cells) < col {
+						cells = append(cells, "")
+					}
+				}
+				cells = append(cells, strings.ReplaceAll(text, "\n", " "))
+			case "row":
+				if line := strings.TrimRight(strings.Join(cells, "\t"), "\t"); line != "" {
+					rows = append(rows, line)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// columnIndex turns the column letters of a cell reference ("C7") into a
+// zero-based index, or -1 when ref has none.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A') + 1
+	}
+	return col - 1
+}
@@ -0,0 +1,648 @@
+package docextract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// extractPDF prefers poppler's pdftotext when it is installed and falls back
+// to the built-in reader, which handles the common text-based PDFs.
+func extractPDF(ctx context.Context, data []byte) ([]string, error) {
+	if pages, err := pdftotext(ctx, data); err == nil && strings.TrimSpace(strings.Join(pages, "")) != "" {
+		return pages, nil
+	}
+	pages, err := readPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(strings.Join(pages, "")) == "" {
+		return nil, fmt.Errorf("no text layer found (scanned PDF? try image_ocr on page images)")
+	}
+	return pages, nil
+}
+
+func pdftotext(ctx context.Context, data []byte) ([]string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "coco-doc-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", f.Name(), "-").Output()
+	if err != nil {
+		return nil, err
+	}
+	// pdftotext ends every page with a form feed.
+	pages := strings.Split(strings.TrimSuffix(string(out), "\f"), "\f")
+	return pages, nil
+}
+
+var (
+	pdfObjPattern      = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRootPattern     = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	pdfRefPattern      = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfPageTypePattern = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfNamedRefPattern = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+)
+
+type pdfObject struct {
+	dict   string
+	stream []byte // decoded stream data, nil without a stream
+}
+
+type pdfReader struct {
+	objects map[int]*pdfObject
+	cmaps   map[int]*toUnicodeMap // by font object number
+}
+
+func readPDF(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	r := &pdfReader{objects: make(map[int]*pdfObject), cmaps: make(map[int]*toUnicodeMap)}
+	r.parseObjects(data)
+
+	var pages []string
+	for _, num := range r.pageObjects(data) {
+		pages = append(pages, r.pageText(num))
+	}
+	return pages, nil
+}
+
+func (r *pdfReader) parseObjects(data []byte) {
+	locs := pdfObjPattern.FindAllSubmatchIndex(data, -1)
+	for i, loc := range locs {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		end := len(data)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := data[loc[1]:end]
+		if j := bytes.Index(body, []byte("endobj")); j >= 0 {
+			body = body[:j]
+		}
+		obj := &pdfObject{dict: string(body)}
+		if j := bytes.Index(body, []byte("stream")); j >= 0 {
+			obj.dict = string(body[:j])
+			raw := body[j+len("stream"):]
+			raw = bytes.TrimPrefix(bytes.TrimPrefix(raw, []byte("\r")), []byte("\n"))
+			if k := bytes.LastIndex(raw, []byte("endstream")); k >= 0 {
+				raw = raw[:k]
+			}
+			obj.stream = decodePDFStream(obj.dict, raw)
+		}
+		r.objects[num] = obj
+		if strings.Contains(obj.dict, "/ObjStm") && obj.stream != nil {
+			r.parseObjectStream(obj)
+		}
+	}
+}
+
+// parseObjectStream unpacks the objects PDF 1.5 files compress together.
+func (r *pdfReader) parseObjectStream(stm *pdfObject) {
+	n := pdfInt(stm.dict, "/N")
+	first := pdfInt(stm.dict, "/First")
+	if n <= 0 || first <= 0 || first > len(stm.stream) {
+		return
+	}
+	header := strings.Fields(string(stm.stream[:first]))
+	for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		off, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil || first+off > len(stm.stream) {
+			continue
+		}
+		end := len(stm.stream)
+		if i+3 < len(header) {
+			if next, err := strconv.Atoi(header[i+3]); err == nil && first+next <= end && next >= off {
+				end = first + next
+			}
+		}
+		if _, ok := r.objects[num]; !ok {
+			r.objects[num] = &pdfObject{dict: string(stm.stream[first+off : end])}
+		}
+	}
+}
+
+func decodePDFStream(dict string, raw []byte) []byte {
+	if !strings.Contains(dict, "/Filter") {
+		return raw
+	}
+	if !strings.Contains(dict, "/FlateDecode") {
+		return nil // images and other encodings carry no text we can read
+	}
+	if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+		if out, err := io.ReadAll(io.LimitReader(zr, maxPartBytes)); err == nil || len(out) > 0 {
+			return out
+		}
+	}
+	out, _ := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(raw)), maxPartBytes))
+	return out
+}
+
+func pdfInt(dict, key string) int {
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + `\s+(\d+)`).FindStringSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// pdfRef returns the object number key refers to in dict, or 0.
+func pdfRef(dict, key string) int {
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + `\s+(\d+)\s+\d+\s+R`).FindStringSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// pdfRefs returns the object numbers of key's value: a single reference or
+// an array of them.
+func pdfRefs(dict, key string) []int {
+	if n := pdfRef(dict, key); n > 0 {
+		return []int{n}
+	}
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + `\s*\[([^\]]*)\]`).FindStringSubmatch(dict)
+	if m == nil {
+		return nil
+	}
+	var out []int
+	for _, ref := range pdfRefPattern.FindAllStringSubmatch(m[1], -1) {
+		n, _ := strconv.Atoi(ref[1])
+		out = append(out, n)
+	}
+	return out
+}
+
+// pageObjects lists the page objects in reading order by walking the page
+// tree from the catalog, or in file order when the tree can't be followed.
+func (r *pdfReader) pageObjects(data []byte) []int {
+	var pages []int
+	seen := make(map[int]bool)
+	var walk func(num int)
+	walk = func(num int) {
+		obj := r.objects[num]
+		if obj == nil || seen[num] {
+			return
+		}
+		seen[num] = true
+		if pdfPageTypePattern.MatchString(obj.dict) {
+			pages = append(pages, num)
+			return
+		}
+		for _, kid := range pdfRefs(obj.dict, "/Kids") {
+			walk(kid)
+		}
+	}
+
+	var root int
+	if m := pdfRootPattern.FindAllSubmatch(data, -1); len(m) > 0 {
+		root, _ = strconv.Atoi(string(m[len(m)-1][1]))
+	}
+	if catalog := r.objects[root]; catalog != nil {
+		walk(pdfRef(catalog.dict, "/Pages"))
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	for _, loc := range pdfObjPattern.FindAllSubmatch(data, -1) {
+		num, _ := strconv.Atoi(string(loc[1]))
+		if obj := r.objects[num]; obj != nil && !seen[num] && pdfPageTypePattern.MatchString(obj.dict) {
+			seen[num] = true
+			pages = append(pages, num)
+		}
+	}
+	return pages
+}
+
+func (r *pdfReader) pageText(num int) string {
+	page := r.objects[num]
+	var content []byte
+	for _, ref := range pdfRefs(page.dict, "/Contents") {
+		obj := r.objects[ref]
+		if obj == nil {
+			continue
+		}
+		if obj.stream == nil {
+			// An indirect array of content streams.
+			for _, inner := range pdfRefPattern.FindAllStringSubmatch(obj.dict, -1) {
+				n, _ := strconv.Atoi(inner[1])
+				if o := r.objects[n]; o != nil {
+					content = append(content, o.stream...)
+					content = append(content, '\n')
+				}
+			}
+			continue
+		}
+		content = append(content, obj.stream...)
+		content = append(content, '\n')
+	}
+	return contentText(content, r.pageFonts(num))
+}
+
+// pageFonts maps the font resource names of a page to their ToUnicode
+// maps, following inherited and indirect resource dictionaries.
+func (r *pdfReader) pageFonts(num int) map[string]*toUnicodeMap {
+	fonts := make(map[string]*toUnicodeMap)
+	for depth := 0; depth < 32 && r.objects[num] != nil; depth++ {
+		dict := r.objects[num].dict
+		if res := pdfRef(dict, "/Resources"); res > 0 && r.objects[res] != nil {
+			dict = r.objects[res].dict
+		}
+		fontDict := ""
+		if ref := pdfRef(dict, "/Font"); ref > 0 && r.objects[ref] != nil {
+			fontDict = r.objects[ref].dict
+		} else if i := strings.Index(dict, "/Font"); i >= 0 {
+			rest := dict[i+len("/Font"):]
+			if j := strings.Index(rest, ">>"); j >= 0 {
+				fontDict = rest[:j]
+			}
+		}
+		if fontDict != "" {
+			for _, m := range pdfNamedRefPattern.FindAllStringSubmatch(fontDict, -1) {
+				if _, ok := fonts[m[1]]; ok {
+					continue
+				}
+				fontNum, _ := strconv.Atoi(m[2])
+				fonts[m[1]] = r.fontCMap(fontNum)
+			}
+			return fonts
+		}
+		num = pdfRef(r.objects[num].dict, "/Parent")
+	}
+	return fonts
+}
+
+func (r *pdfReader) fontCMap(num int) *toUnicodeMap {
+	if m, ok := r.cmaps[num]; ok {
+		return m
+	}
+	var cmap *toUnicodeMap
+	if font := r.objects[num]; font != nil {
+		if obj := r.objects[pdfRef(font.dict, "/ToUnicode")]; obj != nil && obj.stream != nil {
+			cmap = parseToUnicode(obj.stream)
+		}
+	}
+	r.cmaps[num] = cmap
+	return cmap
+}
+
+// toUnicodeMap decodes a font's character codes to text.
+type toUnicodeMap struct {
+	codeLen int
+	chars   map[uint32]string
+}
+
+var (
+	cmapBfchar = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBfrng  = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapHex    = regexp.MustCompile(`<([0-9A-Fa-f]*)>`)
+	cmapRange  = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])`)
+)
+
+func parseToUnicode(data []byte) *toUnicodeMap {
+	m := &toUnicodeMap{chars: make(map[uint32]string)}
+	text := string(data)
+	for _, block := range cmapBfchar.FindAllStringSubmatch(text, -1) {
+		hexes := cmapHex.FindAllStringSubmatch(block[1], -1)
+		for i := 0; i+1 < len(hexes); i += 2 {
+			m.add(hexes[i][1], 0, hexDecodeUTF16(hexes[i+1][1]))
+		}
+	}
+	for _, block := range cmapBfrng.FindAllStringSubmatch(text, -1) {
+		for _, rng := range cmapRange.FindAllStringSubmatch(block[1], -1) {
+			lo, _ := strconv.ParseUint(rng[1], 16, 32)
+			hi, _ := strconv.ParseUint(rng[2], 16, 32)
+			if hi < lo || hi-lo > 0xffff {
+				continue
+			}
+			if strings.HasPrefix(rng[3], "[") {
+				for i, dst := range cmapHex.FindAllStringSubmatch(rng[3], -1) {
+					m.add(rng[1], uint32(i), hexDecodeUTF16(dst[1]))
+				}
+				continue
+			}
+			base := []rune(hexDecodeUTF16(strings.Trim(rng[3], "<>")))
+			if len(base) == 0 {
+				continue
+			}
+			for off := uint32(0); off <= uint32(hi-lo); off++ {
+				out := append([]rune{}, base...)
+				out[len(out)-1] += rune(off)
+				m.add(rng[1], off, string(out))
+			}
+		}
+	}
+	if len(m.chars) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *toUnicodeMap) add(srcHex string, offset uint32, dst string) {
+	src, err := strconv.ParseUint(srcHex, 16, 32)
+	if err != nil {
+		return
+	}
+	if n := (len(srcHex) + 1) / 2; n > m.codeLen {
+		m.codeLen = n
+	}
+	m.chars[uint32(src)+offset] = dst
+}
+
+func (m *toUnicodeMap) decode(b []byte) string {
+	n := m.codeLen
+	if n <= 0 {
+		n = 1
+	}
+	var sb strings.Builder
+	for i := 0; i+n <= len(b); i += n {
+		var code uint32
+		for _, c := range b[i : i+n] {
+			code = code<<8 | uint32(c)
+		}
+		sb.WriteString(m.chars[code])
+	}
+	return sb.String()
+}
+
+func hexDecodeUTF16(h string) string {
+	b := hexBytes(h)
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func hexBytes(h string) []byte {
+	h = strings.Join(strings.Fields(h), "")
+	if len(h)%2 == 1 {
+		h += "0"
+	}
+	out := make([]byte, 0, len(h)/2)
+	for i := 0; i+1 < len(h); i += 2 {
+		v, err := strconv.ParseUint(h[i:i+2], 16, 8)
+		if err != nil {
+			break
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+// decodePDFString turns a string operand into text with the current font's
+// ToUnicode map, or as UTF-16 (with BOM) or PDFDocEncoding without one.
+func decodePDFString(b []byte, cmap *toUnicodeMap) string {
+	if cmap != nil {
+		return cmap.decode(b)
+	}
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		return hexDecodeUTF16(fmt.Sprintf("%x", b[2:]))
+	}
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
+
+// contentText runs the text operators of a content stream.
+func contentText(content []byte, fonts map[string]*toUnicodeMap) string {
+	var sb strings.Builder
+	var operands []pdfToken
+	var cmap *toUnicodeMap
+	newline := func() {
+		if s := sb.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+
+	lex := &pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfTokOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.text {
+		case "Tf":
+			if len(operands) >= 2 && operands[len(operands)-2].kind == pdfTokName {
+				cmap = fonts[operands[len(operands)-2].text]
+			}
+		case "Tj":
+			if n := len(operands); n > 0 && operands[n-1].kind == pdfTokString {
+				sb.WriteString(decodePDFString(operands[n-1].data, cmap))
+			}
+		case "'", "\"":
+			newline()
+			if n := len(operands); n > 0 && operands[n-1].kind == pdfTokString {
+				sb.WriteString(decodePDFString(operands[n-1].data, cmap))
+			}
+		case "TJ":
+			for _, op := range operands {
+				switch op.kind {
+				case pdfTokString:
+					sb.WriteString(decodePDFString(op.data, cmap))
+				case pdfTokNumber:
+					// A large negative adjustment is a word gap.
+					if v, err := strconv.ParseFloat(op.text, 64); err == nil && v < -200 {
+						sb.WriteByte(' ')
+					}
+				}
+			}
+		case "T*", "ET":
+			newline()
+		case "Td", "TD":
+			if n := len(operands); n >= 2 {
+				if v, err := strconv.ParseFloat(operands[n-1].text, 64); err == nil && v != 0 {
+					newline()
+				} else if s := sb.String(); s != "" && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") {
+					sb.WriteByte(' ')
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return sb.String()
+}
+
+type pdfTokenKind int
+
+const (
+	pdfTokOperator pdfTokenKind = iota
+	pdfTokNumber
+	pdfTokString
+	pdfTokName
+	pdfTokOther
+)
+
+type pdfToken struct {
+	kind pdfTokenKind
+	text string
+	data []byte // decoded bytes of a string
+}
+
+// pdfLexer tokenizes content streams. Arrays are flattened: their elements
+// become operands of the operator that follows.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '[' || c == ']' || c == '{' || c == '}':
+			l.pos++
+		case c == '(':
+			return pdfToken{kind: pdfTokString, data: l.literalString()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return pdfToken{kind: pdfTokOther, text: "<<"}, true
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfTokOther, text: ">>"}, true
+		case c == '<':
+			end := bytes.IndexByte(l.data[l.pos:], '>')
+			if end < 0 {
+				end = len(l.data) - l.pos
+			}
+			h := string(l.data[l.pos+1 : l.pos+end])
+			l.pos += end + 1
+			return pdfToken{kind: pdfTokString, data: hexBytes(h)}, true
+		case c == '/':
+			start := l.pos + 1
+			l.pos++
+			for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			return pdfToken{kind: pdfTokName, text: string(l.data[start:l.pos])}, true
+		default:
+			start := l.pos
+			for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			if l.pos == start {
+				l.pos++ // stray delimiter such as ')' or '>'
+				continue
+			}
+			word := string(l.data[start:l.pos])
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfTokNumber, text: word}, true
+			}
+			if word == "BI" {
+				l.skipInlineImage()
+				continue
+			}
+			return pdfToken{kind: pdfTokOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+func (l *pdfLexer) literalString() []byte {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// skipInlineImage jumps past inline image data, which is binary.
+func (l *pdfLexer) skipInlineImage() {
+	if i := bytes.Index(l.data[l.pos:], []byte("EI")); i >= 0 {
+		l.pos += i + 2
+	} else {
+		l.pos = len(l.data)
+	}
+}
@@ -0,0 +1,170 @@
+// Package finetune converts recorded agent runs into fine-tuning datasets,
+// so a smaller local model can be trained on how coco answers and uses its
+// tools.
+package finetune
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Trace is one complete run: the system prompt, the tools offered, and the
+// conversation including every tool call and result.
+type Trace struct {
+	System   string    `json:"system,omitempty"`
+	Tools    []Tool    `json:"tools,omitempty"`
+	Messages []Message `json:"messages"`
+}
+
+// Tool is a tool offered to the model.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON schema
+}
+
+// Message is a user, assistant or tool message.
+type Message struct {
+	Role       string     `json:"role"` // "user", "assistant" or "tool"
+	Content    string     `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant messages
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool messages
+}
+
+// ToolCall is a tool invocation by the assistant.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
+}
+
+// Formats lists the dataset formats Encode accepts.
+var Formats = []string{"openai", "sharegpt"}
+
+// Encode returns the trace as one JSONL line in the given format.
+func Encode(t Trace, format string) ([]byte, error) {
+	switch format {
+	case "openai":
+		return json.Marshal(openAIRecord(t))
+	case "sharegpt":
+		return json.Marshal(shareGPTRecord(t))
+	default:
+		return nil, fmt.Errorf("unknown format %q (want one of %v)", format, Formats)
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function Tool   `json:"function"`
+}
+
+// openAIRecord follows the chat fine-tuning format with function calling:
+// {"messages": [...], "tools": [...]}.
+func openAIRecord(t Trace) map[string]any {
+	var messages []openAIMessage
+	if t.System != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: &t.System})
+	}
+	for _, m := range t.Messages {
+		om := openAIMessage{Role: m.Role, ToolCallID: m.ToolCallID}
+		if m.Content != "" || len(m.ToolCalls) == 0 {
+			content := m.Content
+			om.Content = &content
+		}
+		for _, tc := range m.ToolCalls {
+			call := openAIToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		messages = append(messages, om)
+	}
+	record := map[string]any{"messages": messages}
+	if len(t.Tools) > 0 {
+		tools := make([]openAITool, 0, len(t.Tools))
+		for _, tool := range t.Tools {
+			tools = append(tools, openAITool{Type: "function", Function: tool})
+		}
+		record["tools"] = tools
+	}
+	return record
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+// shareGPTRecord follows the ShareGPT layout used by LLaMA-Factory and
+// similar trainers: turns from human, gpt, function_call and observation,
+// with the system prompt and tools as separate fields. Text an assistant
+// sent along with tool calls is dropped, since a turn is either a reply or
+// a call, and the results of several parallel calls share one observation.
+func shareGPTRecord(t Trace) map[string]any {
+	var turns []shareGPTTurn
+	for _, m := range t.Messages {
+		switch {
+		case m.Role == "user":
+			turns = append(turns, shareGPTTurn{From: "human", Value: m.Content})
+		case m.Role == "tool":
+			if n := len(turns); n > 0 && turns[n-1].From == "observation" {
+				turns[n-1].Value += "\n" + m.Content
+				continue
+			}
+			turns = append(turns, shareGPTTurn{From: "observation", Value: m.Content})
+		case len(m.ToolCalls) > 0:
+			turns = append(turns, shareGPTTurn{From: "function_call", Value: shareGPTCalls(m.ToolCalls)})
+		default:
+			turns = append(turns, shareGPTTurn{From: "gpt", Value: m.Content})
+		}
+	}
+	record := map[string]any{"conversations": turns}
+	if t.System != "" {
+		record["system"] = t.System
+	}
+	if len(t.Tools) > 0 {
+		tools, _ := json.Marshal(t.Tools)
+		record["tools"] = string(tools)
+	}
+	return record
+}
+
+// shareGPTCalls encodes one call as {"name", "arguments"} and several as a
+// list of them.
+func shareGPTCalls(calls []ToolCall) string {
+	type call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	out := make([]call, 0, len(calls))
+	for _, tc := range calls {
+		args := json.RawMessage(tc.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		out = append(out, call{Name: tc.Name, Arguments: args})
+	}
+	var data []byte
+	if len(out) == 1 {
+		data, _ = json.Marshal(out[0])
+	} else {
+		data, _ = json.Marshal(out)
+	}
+	return string(data)
+}
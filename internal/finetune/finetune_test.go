@@ -0,0 +1,111 @@
+package finetune
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleTrace() Trace {
+	return Trace{
+		System: "You are coco.",
+		Tools:  []Tool{{Name: "weather", Description: "Current weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Messages: []Message{
+			{Role: "user", Content: "上海和北京天气怎么样？"},
+			{Role: "assistant", Content: "我查一下。", ToolCalls: []ToolCall{
+				{ID: "c1", Name: "weather", Arguments: `{"location":"上海"}`},
+				{ID: "c2", Name: "weather", Arguments: `{"location":"北京"}`},
+			}},
+			{Role: "tool", Content: "上海 晴 18°C", ToolCallID: "c1"},
+			{Role: "tool", Content: "北京 阴 9°C", ToolCallID: "c2"},
+			{Role: "assistant", Content: "上海晴，北京阴。"},
+		},
+	}
+}
+
+func TestEncodeOpenAI(t *testing.T) {
+	line, err := Encode(sampleTrace(), "openai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec struct {
+		Messages []struct {
+			Role       string  `json:"role"`
+			Content    *string `json:"content"`
+			ToolCallID string  `json:"tool_call_id"`
+			ToolCalls  []struct {
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Messages) != 6 || rec.Messages[0].Role != "system" || rec.Messages[3].ToolCallID != "c1" {
+		t.Fatalf("messages = %s", line)
+	}
+	call := rec.Messages[2].ToolCalls[1]
+	if call.Type != "function" || call.Function.Name != "weather" || call.Function.Arguments != `{"location":"北京"}` {
+		t.Fatalf("tool call = %+v", call)
+	}
+	if len(rec.Tools) != 1 || rec.Tools[0].Type != "function" || rec.Tools[0].Function.Name != "weather" {
+		t.Fatalf("tools = %+v", rec.Tools)
+	}
+}
+
+func TestEncodeShareGPT(t *testing.T) {
+	line, err := Encode(sampleTrace(), "sharegpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec struct {
+		Conversations []struct{ From, Value string } `json:"conversations"`
+		System        string                         `json:"system"`
+		Tools         string                         `json:"tools"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		t.Fatal(err)
+	}
+	var froms []string
+	for _, c := range rec.Conversations {
+		froms = append(froms, c.From)
+	}
+	if strings.Join(froms, ",") != "human,function_call,observation,gpt" {
+		t.Fatalf("turns = %v", froms)
+	}
+	if rec.Conversations[1].Value != `[{"name":"weather","arguments":{"location":"上海"}},{"name":"weather","arguments":{"location":"北京"}}]` {
+		t.Fatalf("function_call = %s", rec.Conversations[1].Value)
+	}
+	if rec.Conversations[2].Value != "上海 晴 18°C\n北京 阴 9°C" || rec.System != "You are coco." || !strings.Contains(rec.Tools, `"name":"weather"`) {
+		t.Fatalf("record = %s", line)
+	}
+	if _, err := Encode(sampleTrace(), "alpaca"); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]string{"hunter2-secret"})
+	got := r.String("密码 hunter2-secret，邮箱 li.lei@example.com，手机13812345678，单号 9913812345678000，身份证 110101199003071234，key sk-abcdefghijklmnop1234")
+	want := "密码 [REDACTED]，邮箱 [EMAIL]，手机[PHONE]，单号 9913812345678000，身份证 [ID]，key [REDACTED]"
+	if got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+
+	trace := r.Trace(Trace{Messages: []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "send_email", Arguments: `{"to":"han.mei@example.com"}`}}},
+	}})
+	if args := trace.Messages[0].ToolCalls[0].Arguments; args != `{"to":"[EMAIL]"}` || !json.Valid([]byte(args)) {
+		t.Fatalf("arguments = %s", args)
+	}
+}
@@ -0,0 +1,94 @@
+package finetune
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kayz/coco/internal/diag"
+)
+
+// piiPatterns replace credentials and personal data that should not end
+// up in training data.
+var piiPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)bearer\s+[a-z0-9._~+/-]{16,}=*`), "Bearer " + diag.Redacted},
+	{regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{16,}|xox[abposr]-[A-Za-z0-9-]{10,}|gh[pousr]_[A-Za-z0-9]{20,}|AKIA[0-9A-Z]{16})`), diag.Redacted},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+}
+
+// numberPatterns only match when not part of a longer number, which Go
+// regexps cannot express without look-behind.
+var numberPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{6}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]`), "[ID]"},
+	{regexp.MustCompile(`(?:\+?86[- ]?)?1[3-9]\d{9}`), "[PHONE]"},
+}
+
+// Redactor scrubs credentials and personal data from traces.
+type Redactor struct {
+	secrets []string
+}
+
+// NewRedactor returns a redactor that, besides the built-in patterns for
+// API keys, e-mail addresses, phone and ID numbers, replaces every given
+// secret value (API keys from the config, vault entries).
+func NewRedactor(secrets []string) *Redactor {
+	return &Redactor{secrets: secrets}
+}
+
+// String redacts one text.
+func (r *Redactor) String(s string) string {
+	s = diag.Redact(s, r.secrets)
+	for _, p := range piiPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	for _, p := range numberPatterns {
+		s = replaceNumbers(s, p.re, p.repl)
+	}
+	return s
+}
+
+func replaceNumbers(s string, re *regexp.Regexp, repl string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		if (loc[0] > 0 && isDigit(s[loc[0]-1])) || (loc[1] < len(s) && isDigit(s[loc[1]])) {
+			continue
+		}
+		sb.WriteString(s[last:loc[0]])
+		sb.WriteString(repl)
+		last = loc[1]
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Trace returns a redacted copy of t. Tool call arguments are redacted as
+// text; the replacements contain no quotes, so the JSON stays valid.
+func (r *Redactor) Trace(t Trace) Trace {
+	out := Trace{System: r.String(t.System), Tools: t.Tools}
+	for _, m := range t.Messages {
+		m.Content = r.String(m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(m.ToolCalls))
+			for i, tc := range m.ToolCalls {
+				tc.Arguments = r.String(tc.Arguments)
+				calls[i] = tc
+			}
+			m.ToolCalls = calls
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out
+}
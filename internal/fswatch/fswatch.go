@@ -0,0 +1,151 @@
+// Package fswatch reports new files in watched directories. A file is
+// reported once it has stopped changing for a moment, so a download or copy
+// in progress is seen once, complete, rather than on every write.
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultSettle is how long a file must stay unchanged before it is
+// reported.
+const DefaultSettle = 2 * time.Second
+
+// Watcher watches directories, not recursively.
+type Watcher struct {
+	fs     *fsnotify.Watcher
+	settle time.Duration
+
+	mu      sync.Mutex
+	dirs    map[string]bool
+	pending map[string]*time.Timer
+}
+
+// New returns a watcher that reports a file settle after its last change
+// (DefaultSettle when settle is 0).
+func New(settle time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+	return &Watcher{fs: fs, settle: settle, dirs: map[string]bool{}, pending: map[string]*time.Timer{}}, nil
+}
+
+// Add starts watching dir. Adding a directory twice is harmless.
+func (w *Watcher) Add(dir string) error {
+	dir = filepath.Clean(dir)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dirs[dir] {
+		return nil
+	}
+	if err := w.fs.Add(dir); err != nil {
+		return err
+	}
+	w.dirs[dir] = true
+	return nil
+}
+
+// Remove stops watching dir.
+func (w *Watcher) Remove(dir string) error {
+	dir = filepath.Clean(dir)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.dirs[dir] {
+		return nil
+	}
+	delete(w.dirs, dir)
+	return w.fs.Remove(dir)
+}
+
+// Dirs returns the watched directories.
+func (w *Watcher) Dirs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dirs := make([]string, 0, len(w.dirs))
+	for dir := range w.dirs {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Run calls found with the path of each new or rewritten regular file until
+// ctx ends, then closes the watcher. Hidden files and the temporary files
+// browsers write while downloading are skipped. found runs on its own
+// goroutine per file; errors from the platform go to onError when set.
+func (w *Watcher) Run(ctx context.Context, found func(path string), onError func(error)) {
+	defer w.fs.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			for path, t := range w.pending {
+				t.Stop()
+				delete(w.pending, path)
+			}
+			w.mu.Unlock()
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write) == 0 || skipped(ev.Name) {
+				continue
+			}
+			w.touch(ev.Name, found)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// touch (re)starts the settle timer of path.
+func (w *Watcher) touch(path string, found func(string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t, ok := w.pending[path]; ok {
+		t.Reset(w.settle)
+		return
+	}
+	w.pending[path] = time.AfterFunc(w.settle, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			found(path)
+		}
+	})
+}
+
+// partialSuffixes mark downloads in progress; the finished file appears
+// under its real name.
+var partialSuffixes = []string{".crdownload", ".part", ".partial", ".download", ".tmp"}
+
+func skipped(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcherReportsSettledFiles(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var found []string
+	go w.Run(ctx, func(path string) {
+		mu.Lock()
+		found = append(found, path)
+		mu.Unlock()
+	}, nil)
+
+	// Several writes to one file are one report; partial downloads and
+	// hidden files are none.
+	report := filepath.Join(dir, "report.pdf")
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(report, []byte("part"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	os.WriteFile(filepath.Join(dir, "movie.mp4.crdownload"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".DS_Store"), []byte("x"), 0o644)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(found)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(found) != 1 || found[0] != report {
+		t.Fatalf("found = %v", found)
+	}
+}
@@ -0,0 +1,246 @@
+// Package instance records the coco processes running on this machine so a
+// second launch against the same data directory, keeper port or relay
+// user-id fails with a clear message instead of two processes fighting over
+// one connection.
+//
+// Each running instance owns a pidfile in RegistryDir named after its data
+// directory; the file is created exclusively, which is the per-data-dir lock.
+// Files left behind by crashed processes are detected by PID and removed.
+package instance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Info describes what a running instance owns.
+type Info struct {
+	PID        int       `json:"pid"`
+	Mode       string    `json:"mode"`
+	DataDir    string    `json:"data_dir"`
+	KeeperPort int       `json:"keeper_port,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
+	Platform   string    `json:"platform,omitempty"`
+	ServerURL  string    `json:"server_url,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// ConflictError reports another live instance holding something we need.
+type ConflictError struct {
+	What  string // "data dir", "keeper port" or "relay user-id"
+	Owner Info
+}
+
+func (e *ConflictError) Error() string {
+	o := e.Owner
+	switch e.What {
+	case "keeper port":
+		return fmt.Sprintf("keeper port %d is already served by coco %s (pid %d, data dir %s)", o.KeeperPort, o.Mode, o.PID, o.DataDir)
+	case "relay user-id":
+		return fmt.Sprintf("relay user-id %s (%s) is already connected to %s by coco %s (pid %d, data dir %s); the server would disconnect one of them",
+			o.UserID, o.Platform, o.ServerURL, o.Mode, o.PID, o.DataDir)
+	default:
+		return fmt.Sprintf("coco %s is already running on data dir %s (pid %d, since %s)", o.Mode, o.DataDir, o.PID, o.StartedAt.Format(time.RFC3339))
+	}
+}
+
+// registryDirOverride lets tests keep their pidfiles apart.
+var registryDirOverride string
+
+// RegistryDir is where instance pidfiles live: the user cache dir, so
+// instances using different data directories still see each other.
+func RegistryDir() string {
+	if registryDirOverride != "" {
+		return registryDirOverride
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "coco", "instances")
+	}
+	return filepath.Join(os.TempDir(), "coco-instances")
+}
+
+// Lock is the pidfile held by this process.
+type Lock struct {
+	mu   sync.Mutex
+	path string
+	info Info
+}
+
+// Acquire takes the lock for dataDir, or returns a *ConflictError naming
+// the live instance that holds it.
+func Acquire(dataDir, mode string) (*Lock, error) {
+	dir := RegistryDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create instance registry: %w", err)
+	}
+	if abs, err := filepath.Abs(dataDir); err == nil {
+		dataDir = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(dataDir)))
+	l := &Lock{
+		path: filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		info: Info{PID: os.Getpid(), Mode: mode, DataDir: dataDir, StartedAt: time.Now()},
+	}
+	data, err := json.MarshalIndent(l.info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, werr := f.Write(data)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(l.path)
+				return nil, fmt.Errorf("write instance lock: %w", werr)
+			}
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("create instance lock: %w", err)
+		}
+		owner, ok := readLive(l.path)
+		if ok {
+			return nil, &ConflictError{What: "data dir", Owner: owner}
+		}
+		// Left behind by a process that is gone; take it over.
+		os.Remove(l.path)
+	}
+	return nil, fmt.Errorf("instance lock %s keeps reappearing", l.path)
+}
+
+// Claim records what this instance is about to use and checks it against
+// the other live instances. On conflict the record is left unchanged.
+func (l *Lock) Claim(update func(*Info)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next := l.info
+	update(&next)
+
+	others, err := List()
+	if err != nil {
+		return err
+	}
+	for _, o := range others {
+		if o.PID == next.PID {
+			continue
+		}
+		if next.KeeperPort != 0 && o.KeeperPort == next.KeeperPort {
+			return &ConflictError{What: "keeper port", Owner: o}
+		}
+		if next.UserID != "" && o.UserID == next.UserID && o.Platform == next.Platform && sameServer(o.ServerURL, next.ServerURL) {
+			return &ConflictError{What: "relay user-id", Owner: o}
+		}
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, data, 0o600); err != nil {
+		return fmt.Errorf("update instance lock: %w", err)
+	}
+	l.info = next
+	return nil
+}
+
+// Info returns what this instance has claimed so far.
+func (l *Lock) Info() Info {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// Release removes the pidfile if it is still ours.
+func (l *Lock) Release() {
+	if l == nil {
+		return
+	}
+	if owner, err := readInfo(l.path); err == nil && owner.PID == os.Getpid() {
+		os.Remove(l.path)
+	}
+}
+
+// List returns the live instances, oldest first, removing stale pidfiles.
+func List() ([]Info, error) {
+	entries, err := os.ReadDir(RegistryDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Info
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(RegistryDir(), e.Name())
+		if info, ok := readLive(path); ok {
+			out = append(out, info)
+		} else {
+			os.Remove(path)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out, nil
+}
+
+// readLive reads a pidfile and reports whether its process is still running.
+// An unreadable or half-written file counts as live for a short while, since
+// another process may be writing it right now.
+func readLive(path string) (Info, bool) {
+	info, err := readInfo(path)
+	if err != nil {
+		st, statErr := os.Stat(path)
+		return Info{DataDir: "(unknown)", Mode: "(starting)"}, statErr == nil && time.Since(st.ModTime()) < 5*time.Second
+	}
+	return info, info.PID == os.Getpid() || processAlive(info.PID)
+}
+
+func readInfo(path string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+	if info.PID <= 0 {
+		return info, fmt.Errorf("%s: no pid", path)
+	}
+	return info, nil
+}
+
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process, so it only succeeds while it runs.
+		proc.Release()
+		return true
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+func sameServer(a, b string) bool {
+	return strings.TrimRight(strings.TrimSpace(a), "/") == strings.TrimRight(strings.TrimSpace(b), "/")
+}
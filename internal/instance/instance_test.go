@@ -0,0 +1,77 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLocksDataDirAndClaimsDetectConflicts(t *testing.T) {
+	registryDirOverride = t.TempDir()
+	t.Cleanup(func() { registryDirOverride = "" })
+	dataDir := t.TempDir()
+
+	lock, err := Acquire(dataDir, "relay")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	// A pidfile from another live process (our parent) holds a keeper port
+	// and a relay user-id.
+	other := Info{PID: os.Getppid(), Mode: "both", DataDir: "/elsewhere", KeeperPort: 8080,
+		UserID: "u1", Platform: "wecom", ServerURL: "ws://127.0.0.1:8080/ws", StartedAt: time.Now()}
+	writeInfo(t, filepath.Join(registryDirOverride, "other.json"), other)
+
+	var conflict *ConflictError
+	if _, err := Acquire(dataDir, "relay"); !errors.As(err, &conflict) || conflict.What != "data dir" {
+		t.Fatalf("second Acquire = %v, want data dir conflict", err)
+	}
+	if err := lock.Claim(func(i *Info) { i.KeeperPort = 8080 }); !errors.As(err, &conflict) || conflict.What != "keeper port" {
+		t.Fatalf("port claim = %v", err)
+	}
+	if err := lock.Claim(func(i *Info) { i.UserID, i.Platform, i.ServerURL = "u1", "wecom", "ws://127.0.0.1:8080/ws/" }); !errors.As(err, &conflict) || conflict.What != "relay user-id" {
+		t.Fatalf("user-id claim = %v", err)
+	}
+	if lock.Info().UserID != "" {
+		t.Fatal("a refused claim must not be recorded")
+	}
+	if err := lock.Claim(func(i *Info) { i.UserID, i.Platform, i.KeeperPort = "u1", "feishu", 9090 }); err != nil {
+		t.Fatalf("unrelated claim: %v", err)
+	}
+
+	list, err := List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	lock.Release()
+	if _, err := Acquire(dataDir, "keeper"); err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+}
+
+func TestStalePidfileIsTakenOver(t *testing.T) {
+	registryDirOverride = t.TempDir()
+	t.Cleanup(func() { registryDirOverride = "" })
+	dataDir := t.TempDir()
+
+	lock, err := Acquire(dataDir, "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pretend the holder crashed: point the pidfile at a PID that cannot exist.
+	writeInfo(t, lock.path, Info{PID: 1 << 30, Mode: "relay", DataDir: dataDir, StartedAt: time.Now()})
+
+	if _, err := Acquire(dataDir, "keeper"); err != nil {
+		t.Fatalf("stale lock not taken over: %v", err)
+	}
+}
+
+func writeInfo(t *testing.T, path string, info Info) {
+	t.Helper()
+	data, _ := json.Marshal(info)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
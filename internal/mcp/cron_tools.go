@@ -3,9 +3,11 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/provenance"
 )
 
 var cronScheduler *cronpkg.Scheduler
@@ -45,6 +47,9 @@ func CronCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to create job: %v", err)), nil
 	}
+	if err := cronScheduler.SetProvenance(job.ID, provenance.Record{Origin: provenance.OriginMCP, Actor: "mcp-client"}); err != nil {
+		log.Printf("[CRON] Failed to record provenance for %s: %v", job.ID, err)
+	}
 
 	result := fmt.Sprintf("✓ Job created successfully\n\nID: %s\nName: %s\nSchedule: %s\nTool: %s\nStatus: enabled",
 		job.ID, job.Name, job.Schedule, job.Tool)
@@ -10,6 +10,8 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	cronpkg "github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/datadir"
+	"github.com/kayz/coco/internal/provenance"
 	"github.com/kayz/coco/internal/security"
 	"github.com/kayz/coco/internal/tools"
 )
@@ -66,17 +68,18 @@ func NewServer(opts ...SecurityOptions) *Server {
 	registerWebTools(s)
 
 	// Initialize cron scheduler
-	exeDir := tools.GetExecutableDir()
-	if exeDir == "" {
-		exeDir = os.TempDir()
-	}
-	cronPath := filepath.Join(exeDir, ".coco.db")
+	cronPath := datadir.Path(".coco.db")
 	cronStore, err := cronpkg.NewStore(cronPath)
 	if err != nil {
 		log.Printf("[CRON] Warning: Failed to open cron store: %v", err)
 		cronStore, _ = cronpkg.NewStore(filepath.Join(os.TempDir(), "coco.db"))
 	}
 	s.cronScheduler = cronpkg.NewScheduler(cronStore, s, nil, s)
+	if signer, err := provenance.LoadOrCreate(provenance.DefaultKeyPath()); err != nil {
+		log.Printf("[CRON] Warning: jobs will be unsigned: %v", err)
+	} else {
+		s.cronScheduler.SetSigner(signer)
+	}
 
 	// Register cron tools
 	registerCronTools(s)
@@ -316,19 +319,20 @@ func registerNetworkTools(s *Server) {
 func registerCalendarTools(s *Server) {
 	// calendar_list_events
 	s.addTool(mcp.NewTool("calendar_list_events",
-		mcp.WithDescription("List upcoming calendar events from macOS Calendar"),
+		mcp.WithDescription("List upcoming calendar events (Calendar.app, Outlook or khal)"),
 		mcp.WithNumber("days", mcp.Description("Number of days to look ahead (default: 7)")),
 	), tools.CalendarListEvents)
 
 	// calendar_create_event
 	s.addTool(mcp.NewTool("calendar_create_event",
-		mcp.WithDescription("Create a new event in macOS Calendar"),
+		mcp.WithDescription("Create a new calendar event (Calendar.app, Outlook or khal)"),
 		mcp.WithString("title", mcp.Required(), mcp.Description("Event title")),
 		mcp.WithString("start_time", mcp.Required(), mcp.Description("Start time (format: 2024-01-15 14:00)")),
 		mcp.WithNumber("duration", mcp.Description("Duration in minutes (default: 60)")),
 		mcp.WithString("calendar", mcp.Description("Calendar name (default: Calendar)")),
 		mcp.WithString("location", mcp.Description("Event location")),
 		mcp.WithString("notes", mcp.Description("Event notes")),
+		mcp.WithString("attendees", mcp.Description("Comma-separated emails to invite (CalDAV or Google calendar only)")),
 	), tools.CalendarCreateEvent)
 
 	// calendar_list_calendars
@@ -355,6 +359,15 @@ func registerCalendarTools(s *Server) {
 		mcp.WithString("calendar", mcp.Description("Calendar name to search in (optional)")),
 		mcp.WithString("date", mcp.Description("Date of the event (format: 2024-01-15, optional)")),
 	), tools.CalendarDeleteEvent)
+
+	// calendar_freebusy
+	s.addTool(mcp.NewTool("calendar_freebusy",
+		mcp.WithDescription("Show busy times of the user and attendees and the free slots common to all (CalDAV or Google calendar only)"),
+		mcp.WithString("start", mcp.Description("Window start (format: 2024-01-15 09:00, default: now)")),
+		mcp.WithString("end", mcp.Description("Window end (format: 2024-01-15 18:00, default: 18:00 the same day)")),
+		mcp.WithNumber("duration", mcp.Description("Shortest free slot in minutes (default: 30)")),
+		mcp.WithString("attendees", mcp.Description("Comma-separated attendee emails")),
+	), tools.CalendarFreeBusy)
 }
 
 func registerFileManagerTools(s *Server) {
@@ -394,6 +407,7 @@ func registerBrowserTools(s *Server) {
 		mcp.WithBoolean("headless", mcp.Description("Run in headless mode without visible window (default: true)")),
 		mcp.WithString("url", mcp.Description("Initial URL to navigate to after launch")),
 		mcp.WithString("executable_path", mcp.Description("Path to browser executable (auto-detected if omitted)")),
+		mcp.WithString("profile", mcp.Description("Named profile whose logins persist across restarts (default: browser.profile or \"default\")")),
 	), tools.BrowserStart)
 
 	// browser_stop
@@ -466,6 +480,22 @@ func registerBrowserTools(s *Server) {
 		mcp.WithDescription("Close a browser tab by target ID, or close the active tab if no ID given"),
 		mcp.WithString("target_id", mcp.Description("Target ID of the tab to close (from browser_tabs)")),
 	), tools.BrowserTabClose)
+
+	// browser_network_start_capture
+	s.addTool(mcp.NewTool("browser_network_start_capture",
+		mcp.WithDescription("Record the XHR/fetch requests of the open tabs, with JSON/text bodies, to read data from the site's API instead of the DOM"),
+		mcp.WithString("url_filter", mcp.Description("Only record requests whose URL contains this text")),
+		mcp.WithNumber("max_body_bytes", mcp.Description("Cut bodies at this many bytes (default: 32768)")),
+	), tools.BrowserNetworkStartCapture)
+
+	// browser_network_get_requests
+	s.addTool(mcp.NewTool("browser_network_get_requests",
+		mcp.WithDescription("List the requests recorded since browser_network_start_capture"),
+		mcp.WithString("url_filter", mcp.Description("Only list requests whose URL contains this text")),
+		mcp.WithBoolean("include_bodies", mcp.Description("Include request and response bodies (default: true)")),
+		mcp.WithBoolean("clear", mcp.Description("Forget the listed requests afterwards")),
+		mcp.WithBoolean("stop", mcp.Description("Stop capturing after listing")),
+	), tools.BrowserNetworkGetRequests)
 }
 
 func registerWebTools(s *Server) {
@@ -0,0 +1,314 @@
+package netcal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalDAV is a calendar collection on a CalDAV server (RFC 4791).
+// Invitations rely on the server's implicit scheduling (RFC 6638): events
+// PUT with ORGANIZER and ATTENDEE properties are mailed out by the server,
+// as iCloud, Fastmail and Nextcloud do.
+type CalDAV struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+	email    string
+}
+
+// NewCalDAV creates a client for the calendar collection at collectionURL.
+func NewCalDAV(client *http.Client, collectionURL, username, password, email string) *CalDAV {
+	if !strings.HasSuffix(collectionURL, "/") {
+		collectionURL += "/"
+	}
+	if email == "" && strings.Contains(username, "@") {
+		email = username
+	}
+	return &CalDAV{client: client, url: collectionURL, username: username, password: password, email: email}
+}
+
+func (c *CalDAV) Name() string {
+	if u, err := url.Parse(c.url); err == nil {
+		return "CalDAV (" + u.Host + ")"
+	}
+	return "CalDAV"
+}
+
+const icalStamp = "20060102T150405Z"
+
+func (c *CalDAV) do(ctx context.Context, method, target string, body []byte, header map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp, data, fmt.Errorf("%s %s: %s", method, target, resp.Status)
+	}
+	return resp, data, nil
+}
+
+type davMultistatus struct {
+	Responses []struct {
+		Href         string `xml:"href"`
+		CalendarData string `xml:"propstat>prop>calendar-data"`
+	} `xml:"response"`
+}
+
+func (c *CalDAV) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	// expand makes the server return recurring events as single instances.
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><C:calendar-data><C:expand start="%[1]s" end="%[2]s"/></C:calendar-data></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">
+    <C:time-range start="%[1]s" end="%[2]s"/>
+  </C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`, from.UTC().Format(icalStamp), to.UTC().Format(icalStamp))
+	_, data, err := c.do(ctx, "REPORT", c.url, []byte(body), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ms davMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("parse REPORT response: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		href := c.resolve(r.Href)
+		for _, e := range ParseICS(r.CalendarData) {
+			if !e.End.After(from) || !e.Start.Before(to) {
+				continue
+			}
+			e.ID = href
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+func (c *CalDAV) resolve(href string) string {
+	base, err := url.Parse(c.url)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (c *CalDAV) Create(ctx context.Context, e Event) (Event, error) {
+	uid := make([]byte, 16)
+	rand.Read(uid)
+	id := hex.EncodeToString(uid)
+	e.ID = c.url + id + ".ics"
+	ics := buildICS(id, e, c.email, time.Now())
+	_, _, err := c.do(ctx, http.MethodPut, e.ID, []byte(ics), map[string]string{
+		"Content-Type":  "text/calendar; charset=utf-8",
+		"If-None-Match": "*",
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	return e, nil
+}
+
+func (c *CalDAV) Delete(ctx context.Context, e Event) error {
+	_, _, err := c.do(ctx, http.MethodDelete, c.resolve(e.ID), nil, nil)
+	return err
+}
+
+// FreeBusy only knows the user's own calendar; asking a CalDAV server for
+// other people's availability needs scheduling-outbox support few servers
+// expose, so attendees are left out of the result.
+func (c *CalDAV) FreeBusy(ctx context.Context, from, to time.Time, attendees []string) (map[string][]Busy, error) {
+	events, err := c.Events(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]Busy{"me": BusyFromEvents(events, from, to)}, nil
+}
+
+// buildICS renders e as a VCALENDAR in UTC. With attendees, organizer
+// becomes the ORGANIZER and every attendee is asked to RSVP.
+func buildICS(uid string, e Event, organizer string, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//coco//calendar//EN",
+		"BEGIN:VEVENT",
+		"UID:" + uid + "@coco",
+		"DTSTAMP:" + now.UTC().Format(icalStamp),
+		"DTSTART:" + e.Start.UTC().Format(icalStamp),
+		"DTEND:" + e.End.UTC().Format(icalStamp),
+		"SUMMARY:" + escapeText(e.Title),
+	}
+	if e.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeText(e.Location))
+	}
+	if e.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeText(e.Description))
+	}
+	if len(e.Attendees) > 0 {
+		if organizer != "" {
+			lines = append(lines, "ORGANIZER:mailto:"+organizer)
+		}
+		for _, a := range e.Attendees {
+			lines = append(lines, "ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:"+a)
+		}
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR", "")
+	return strings.Join(lines, "\r\n")
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// ParseICS extracts the VEVENTs of an iCalendar document. It understands
+// UTC, TZID and floating times and all-day dates, which covers what CalDAV
+// servers return for expanded queries.
+func ParseICS(data string) []Event {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.NewReplacer("\n ", "", "\n\t", "").Replace(data)
+
+	var events []Event
+	var cur *Event
+	var duration time.Duration
+	for _, line := range strings.Split(data, "\n") {
+		nameParams, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(nameParams, ";")
+		name := strings.ToUpper(parts[0])
+		params := map[string]string{}
+		for _, p := range parts[1:] {
+			if k, v, ok := strings.Cut(p, "="); ok {
+				params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			cur, duration = &Event{}, 0
+		case name == "END" && value == "VEVENT" && cur != nil:
+			if cur.End.IsZero() {
+				switch {
+				case duration > 0:
+					cur.End = cur.Start.Add(duration)
+				case cur.AllDay:
+					cur.End = cur.Start.AddDate(0, 0, 1)
+				default:
+					cur.End = cur.Start
+				}
+			}
+			if !cur.Start.IsZero() {
+				events = append(events, *cur)
+			}
+			cur = nil
+		case cur == nil:
+		case name == "SUMMARY":
+			cur.Title = unescapeText(value)
+		case name == "LOCATION":
+			cur.Location = unescapeText(value)
+		case name == "DESCRIPTION":
+			cur.Description = unescapeText(value)
+		case name == "TRANSP":
+			cur.Free = strings.EqualFold(value, "TRANSPARENT")
+		case name == "ATTENDEE":
+			if addr, ok := strings.CutPrefix(strings.ToLower(value), "mailto:"); ok {
+				cur.Attendees = append(cur.Attendees, addr)
+			}
+		case name == "DTSTART":
+			cur.Start, cur.AllDay = parseICSTime(value, params)
+		case name == "DTEND":
+			cur.End, _ = parseICSTime(value, params)
+		case name == "DURATION":
+			duration = parseICSDuration(value)
+		}
+	}
+	return events
+}
+
+func parseICSTime(value string, params map[string]string) (time.Time, bool) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, _ := time.ParseInLocation("20060102", value, time.Local)
+		return t, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse(icalStamp, value)
+		return t, false
+	}
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false
+}
+
+// parseICSDuration handles the common "PT1H30M" / "P1D" forms.
+func parseICSDuration(value string) time.Duration {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	var d time.Duration
+	inTime := false
+	n := 0
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D':
+			d += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		}
+		n = 0
+	}
+	return d
+}
@@ -0,0 +1,294 @@
+package netcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Google endpoints; variables so tests can point them at a local server.
+var (
+	GoogleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	GoogleTokenURL = "https://oauth2.googleapis.com/token"
+	googleAPIURL   = "https://www.googleapis.com/calendar/v3"
+)
+
+// GoogleScope is the OAuth scope the calendar tools need.
+const GoogleScope = "https://www.googleapis.com/auth/calendar"
+
+// Google is one Google Calendar, authorized with an OAuth refresh token.
+type Google struct {
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	refreshToken string
+	calendarID   string
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// NewGoogle creates a client for calendarID (default "primary").
+func NewGoogle(client *http.Client, clientID, clientSecret, refreshToken, calendarID string) *Google {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	return &Google{client: client, clientID: clientID, clientSecret: clientSecret, refreshToken: refreshToken, calendarID: calendarID}
+}
+
+func (g *Google) Name() string {
+	return "Google Calendar (" + g.calendarID + ")"
+}
+
+// GoogleToken is the token endpoint's response.
+type GoogleToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// ExchangeGoogleToken posts form to the token endpoint, for both the
+// authorization-code and the refresh-token grant.
+func ExchangeGoogleToken(ctx context.Context, client *http.Client, form url.Values) (*GoogleToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, GoogleTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tok GoogleToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("parse token response: %w", err)
+	}
+	if tok.Error != "" || tok.AccessToken == "" {
+		return nil, fmt.Errorf("google token: %s %s", tok.Error, tok.Description)
+	}
+	return &tok, nil
+}
+
+func (g *Google) token(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.accessToken != "" && time.Now().Before(g.expires) {
+		return g.accessToken, nil
+	}
+	tok, err := ExchangeGoogleToken(ctx, g.client, url.Values{
+		"client_id":     {g.clientID},
+		"client_secret": {g.clientSecret},
+		"refresh_token": {g.refreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+	if err != nil {
+		return "", err
+	}
+	g.accessToken = tok.AccessToken
+	g.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return g.accessToken, nil
+}
+
+func (g *Google) call(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	token, err := g.token(ctx)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	target := googleAPIURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("google calendar: %s %s", resp.Status, apiErr.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type googleTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+func (t googleTime) parse() (time.Time, bool) {
+	if t.Date != "" {
+		d, _ := time.ParseInLocation("2006-01-02", t.Date, time.Local)
+		return d, true
+	}
+	d, _ := time.Parse(time.RFC3339, t.DateTime)
+	return d.Local(), false
+}
+
+type googleAttendee struct {
+	Email string `json:"email"`
+}
+
+type googleEvent struct {
+	ID           string           `json:"id,omitempty"`
+	Summary      string           `json:"summary"`
+	Location     string           `json:"location,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	Start        googleTime       `json:"start"`
+	End          googleTime       `json:"end"`
+	Attendees    []googleAttendee `json:"attendees,omitempty"`
+	Transparency string           `json:"transparency,omitempty"`
+	Status       string           `json:"status,omitempty"`
+}
+
+func (g *Google) eventsPath() string {
+	return "/calendars/" + url.PathEscape(g.calendarID) + "/events"
+}
+
+func (g *Google) Events(ctx context.Context, from, to time.Time) ([]Event, error) {
+	query := url.Values{
+		"timeMin":      {from.Format(time.RFC3339)},
+		"timeMax":      {to.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+	var events []Event
+	for {
+		var page struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := g.call(ctx, http.MethodGet, g.eventsPath(), query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if item.Status == "cancelled" {
+				continue
+			}
+			e := Event{
+				ID:          item.ID,
+				Title:       item.Summary,
+				Location:    item.Location,
+				Description: item.Description,
+				Calendar:    g.calendarID,
+				Free:        item.Transparency == "transparent",
+			}
+			e.Start, e.AllDay = item.Start.parse()
+			e.End, _ = item.End.parse()
+			for _, a := range item.Attendees {
+				e.Attendees = append(e.Attendees, a.Email)
+			}
+			events = append(events, e)
+		}
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Create inserts the event with sendUpdates=all, so Google mails the
+// invitation to every attendee.
+func (g *Google) Create(ctx context.Context, e Event) (Event, error) {
+	in := googleEvent{
+		Summary:     e.Title,
+		Location:    e.Location,
+		Description: e.Description,
+		Start:       googleTime{DateTime: e.Start.Format(time.RFC3339)},
+		End:         googleTime{DateTime: e.End.Format(time.RFC3339)},
+	}
+	for _, a := range e.Attendees {
+		in.Attendees = append(in.Attendees, googleAttendee{Email: a})
+	}
+	var out googleEvent
+	if err := g.call(ctx, http.MethodPost, g.eventsPath(), url.Values{"sendUpdates": {"all"}}, in, &out); err != nil {
+		return Event{}, err
+	}
+	e.ID = out.ID
+	e.Calendar = g.calendarID
+	return e, nil
+}
+
+func (g *Google) Delete(ctx context.Context, e Event) error {
+	return g.call(ctx, http.MethodDelete, g.eventsPath()+"/"+url.PathEscape(e.ID), url.Values{"sendUpdates": {"all"}}, nil, nil)
+}
+
+// FreeBusy asks the freeBusy API, which answers for anyone in the same
+// Workspace domain and for people who share their calendar with the user.
+func (g *Google) FreeBusy(ctx context.Context, from, to time.Time, attendees []string) (map[string][]Busy, error) {
+	type item struct {
+		ID string `json:"id"`
+	}
+	in := struct {
+		TimeMin string `json:"timeMin"`
+		TimeMax string `json:"timeMax"`
+		Items   []item `json:"items"`
+	}{TimeMin: from.Format(time.RFC3339), TimeMax: to.Format(time.RFC3339), Items: []item{{ID: g.calendarID}}}
+	for _, a := range attendees {
+		in.Items = append(in.Items, item{ID: a})
+	}
+	var out struct {
+		Calendars map[string]struct {
+			Busy []struct {
+				Start time.Time `json:"start"`
+				End   time.Time `json:"end"`
+			} `json:"busy"`
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"calendars"`
+	}
+	if err := g.call(ctx, http.MethodPost, "/freeBusy", nil, in, &out); err != nil {
+		return nil, err
+	}
+	result := map[string][]Busy{}
+	for id, cal := range out.Calendars {
+		if len(cal.Errors) > 0 {
+			continue
+		}
+		var spans []Busy
+		for _, b := range cal.Busy {
+			spans = append(spans, Busy{Start: b.Start.Local(), End: b.End.Local()})
+		}
+		if id == g.calendarID {
+			id = "me"
+		}
+		result[id] = MergeBusy(spans)
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+// Package netcal talks to calendars over the network: any CalDAV server
+// (iCloud, Fastmail, Nextcloud, …) or Google Calendar through its REST API.
+package netcal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is one calendar event.
+type Event struct {
+	ID          string // CalDAV: resource URL; Google: event id
+	Title       string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Location    string
+	Description string
+	Attendees   []string // email addresses
+	Calendar    string
+	Free        bool // marked transparent, does not count as busy
+}
+
+// Busy is a span of time in which someone is not available.
+type Busy struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Calendar is a network calendar.
+type Calendar interface {
+	// Name describes the backend, e.g. "Google Calendar (primary)".
+	Name() string
+	// Events lists events overlapping [from, to), ordered by start.
+	Events(ctx context.Context, from, to time.Time) ([]Event, error)
+	// Create adds an event. Attendees receive an invitation where the
+	// server supports it.
+	Create(ctx context.Context, e Event) (Event, error)
+	// Delete removes an event returned by Events.
+	Delete(ctx context.Context, e Event) error
+	// FreeBusy returns the busy spans in [from, to) of the user ("me") and
+	// of each attendee the server can answer for, keyed by email. Attendees
+	// missing from the result could not be queried.
+	FreeBusy(ctx context.Context, from, to time.Time, attendees []string) (map[string][]Busy, error)
+}
+
+// Config selects and configures the network calendar.
+type Config struct {
+	Provider string // "caldav" or "google"
+
+	// CalDAV
+	URL      string // calendar collection URL
+	Username string
+	Password string
+	Email    string // organizer address for invitations (default Username)
+
+	// Google
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	CalendarID   string // default "primary"
+}
+
+// New creates the configured calendar.
+func New(cfg Config) (Calendar, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "caldav":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("calendar.caldav.url is required")
+		}
+		return NewCalDAV(client, cfg.URL, cfg.Username, cfg.Password, cfg.Email), nil
+	case "google":
+		if cfg.ClientID == "" || cfg.RefreshToken == "" {
+			return nil, fmt.Errorf("calendar.google needs client_id and refresh_token (run: coco calendar google-auth)")
+		}
+		return NewGoogle(client, cfg.ClientID, cfg.ClientSecret, cfg.RefreshToken, cfg.CalendarID), nil
+	default:
+		return nil, fmt.Errorf("unknown calendar provider %q (use caldav or google)", cfg.Provider)
+	}
+}
+
+// BusyFromEvents turns events into merged busy spans clipped to [from, to).
+func BusyFromEvents(events []Event, from, to time.Time) []Busy {
+	var spans []Busy
+	for _, e := range events {
+		if e.Free || !e.End.After(from) || !e.Start.Before(to) {
+			continue
+		}
+		b := Busy{Start: e.Start, End: e.End}
+		if b.Start.Before(from) {
+			b.Start = from
+		}
+		if b.End.After(to) {
+			b.End = to
+		}
+		spans = append(spans, b)
+	}
+	return MergeBusy(spans)
+}
+
+// MergeBusy sorts spans and joins the ones that overlap or touch.
+func MergeBusy(spans []Busy) []Busy {
+	if len(spans) == 0 {
+		return nil
+	}
+	sorted := append([]Busy(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	merged := []Busy{sorted[0]}
+	for _, b := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if b.Start.After(last.End) {
+			merged = append(merged, b)
+			continue
+		}
+		if b.End.After(last.End) {
+			last.End = b.End
+		}
+	}
+	return merged
+}
+
+// FreeSlots returns the gaps of at least minLen in [from, to) that no span
+// in busy covers.
+func FreeSlots(busy []Busy, from, to time.Time, minLen time.Duration) []Busy {
+	var free []Busy
+	cursor := from
+	for _, b := range MergeBusy(busy) {
+		if b.Start.Sub(cursor) >= minLen {
+			free = append(free, Busy{Start: cursor, End: b.Start})
+		}
+		if b.End.After(cursor) {
+			cursor = b.End
+		}
+	}
+	if to.Sub(cursor) >= minLen {
+		free = append(free, Busy{Start: cursor, End: to})
+	}
+	return free
+}
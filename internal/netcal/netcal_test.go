@@ -0,0 +1,202 @@
+package netcal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reportResponse = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/cal/work/abc.ics</d:href>
+    <d:propstat><d:prop><cal:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:abc
+DTSTART;TZID=Asia/Shanghai:20260309T100000
+DTEND;TZID=Asia/Shanghai:20260309T110000
+SUMMARY:周会\, 产品
+DESCRIPTION:agenda line 1\nline 2 that is long enough to be
+  folded
+ATTENDEE;CN=Bob:mailto:Bob@example.com
+END:VEVENT
+END:VCALENDAR
+</cal:calendar-data></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/cal/work/allday.ics</d:href>
+    <d:propstat><d:prop><cal:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20260309
+SUMMARY:Holiday
+TRANSP:TRANSPARENT
+END:VEVENT
+END:VCALENDAR
+</cal:calendar-data></d:prop></d:propstat>
+  </d:response>
+</d:multistatus>`
+
+func TestCalDAVEventsCreateDelete(t *testing.T) {
+	var put, deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me@example.com" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "REPORT":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `<C:time-range start="20260309T000000Z"`) || r.Header.Get("Depth") != "1" {
+				t.Errorf("unexpected REPORT: %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, reportResponse)
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" {
+				t.Error("PUT would overwrite an existing event")
+			}
+			body, _ := io.ReadAll(r.Body)
+			put = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	cal := NewCalDAV(srv.Client(), srv.URL+"/cal/work", "me@example.com", "secret", "")
+	from := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	events, err := cal.Events(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	holiday, meeting := events[0], events[1]
+	if !holiday.AllDay {
+		holiday, meeting = meeting, holiday
+	}
+	if !holiday.AllDay || !holiday.Free || !holiday.End.Equal(holiday.Start.AddDate(0, 0, 1)) {
+		t.Fatalf("holiday = %+v", holiday)
+	}
+	if meeting.Title != "周会, 产品" || meeting.Description != "agenda line 1\nline 2 that is long enough to be folded" {
+		t.Fatalf("meeting = %+v", meeting)
+	}
+	if meeting.Start.UTC().Hour() != 2 || meeting.End.Sub(meeting.Start) != time.Hour {
+		t.Fatalf("meeting time = %v - %v", meeting.Start, meeting.End)
+	}
+	if len(meeting.Attendees) != 1 || meeting.Attendees[0] != "bob@example.com" || meeting.ID != srv.URL+"/cal/work/abc.ics" {
+		t.Fatalf("meeting = %+v", meeting)
+	}
+
+	start := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	created, err := cal.Create(context.Background(), Event{Title: "Review", Start: start, End: start.Add(30 * time.Minute), Attendees: []string{"bob@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"DTSTART:20260310T060000Z\r\n", "ORGANIZER:mailto:me@example.com\r\n", "RSVP=TRUE:mailto:bob@example.com\r\n"} {
+		if !strings.Contains(put, want) {
+			t.Fatalf("missing %q in:\n%s", want, put)
+		}
+	}
+
+	if err := cal.Delete(context.Background(), created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(deleted, "/cal/work/") || !strings.HasSuffix(deleted, ".ics") {
+		t.Fatalf("deleted %q", deleted)
+	}
+}
+
+func TestGoogleFreeBusyAndInvite(t *testing.T) {
+	tokens := 0
+	var inserted map[string]any
+	var sendUpdates string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokens++
+			r.ParseForm()
+			if r.Form.Get("refresh_token") != "refresh" || r.Form.Get("grant_type") != "refresh_token" {
+				t.Errorf("token form = %v", r.Form)
+			}
+			io.WriteString(w, `{"access_token":"access","expires_in":3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/calendars/primary/events":
+			sendUpdates = r.URL.Query().Get("sendUpdates")
+			json.NewDecoder(r.Body).Decode(&inserted)
+			io.WriteString(w, `{"id":"evt1"}`)
+		case "/freeBusy":
+			io.WriteString(w, `{"calendars":{
+				"primary":{"busy":[{"start":"2026-03-09T10:00:00Z","end":"2026-03-09T11:00:00Z"},{"start":"2026-03-09T10:30:00Z","end":"2026-03-09T12:00:00Z"}]},
+				"bob@example.com":{"busy":[]},
+				"eve@other.com":{"errors":[{"reason":"notFound"}]}}}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	oldToken, oldAPI := GoogleTokenURL, googleAPIURL
+	GoogleTokenURL, googleAPIURL = srv.URL+"/token", srv.URL
+	t.Cleanup(func() { GoogleTokenURL, googleAPIURL = oldToken, oldAPI })
+
+	cal := NewGoogle(srv.Client(), "id", "secret", "refresh", "")
+	start := time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)
+	busy, err := cal.FreeBusy(context.Background(), start, start.Add(9*time.Hour), []string{"bob@example.com", "eve@other.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me := busy["me"]; len(me) != 1 || !me[0].End.Equal(start.Add(3*time.Hour)) {
+		t.Fatalf("me = %+v", me)
+	}
+	if _, ok := busy["bob@example.com"]; !ok {
+		t.Fatal("bob missing")
+	}
+	if _, ok := busy["eve@other.com"]; ok {
+		t.Fatal("eve's error should leave her out")
+	}
+
+	created, err := cal.Create(context.Background(), Event{Title: "Sync", Start: start, End: start.Add(time.Hour), Attendees: []string{"bob@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "evt1" || sendUpdates != "all" {
+		t.Fatalf("created = %+v, sendUpdates = %q", created, sendUpdates)
+	}
+	if attendees, _ := inserted["attendees"].([]any); len(attendees) != 1 {
+		t.Fatalf("inserted = %v", inserted)
+	}
+	if tokens != 1 {
+		t.Fatalf("access token fetched %d times", tokens)
+	}
+}
+
+func TestFreeSlots(t *testing.T) {
+	day := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	busy := []Busy{{at(10, 0), at(11, 0)}, {at(10, 30), at(12, 0)}, {at(12, 10), at(13, 0)}, {at(17, 30), at(19, 0)}}
+	free := FreeSlots(busy, at(9, 0), at(18, 0), 30*time.Minute)
+	want := []Busy{{at(9, 0), at(10, 0)}, {at(13, 0), at(17, 30)}}
+	if len(free) != len(want) {
+		t.Fatalf("free = %+v", free)
+	}
+	for i := range want {
+		if !free[i].Start.Equal(want[i].Start) || !free[i].End.Equal(want[i].End) {
+			t.Fatalf("free[%d] = %+v, want %+v", i, free[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package news reads RSS and Atom feeds for the morning briefing.
+package news
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Item is one headline of a feed.
+type Item struct {
+	Title     string
+	Link      string
+	Summary   string
+	Source    string // the feed's title
+	Published time.Time
+}
+
+const maxFeedSize = 4 << 20
+
+// Fetch downloads and parses the feed at url.
+func Fetch(ctx context.Context, client *http.Client, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "coco/1.0 (+https://github.com/kayz/coco)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("feed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("feed: %w", err)
+	}
+	return Parse(data)
+}
+
+type rssDoc struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 (RDF) puts items beside the channel.
+	Items []rssItem `xml:"item"`
+	// Atom
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"` // dc:date
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom document. Items keep the feed's
+// order, which is newest first for nearly every feed.
+func Parse(data []byte) ([]Item, error) {
+	var doc rssDoc
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("feed: %w", err)
+	}
+
+	var items []Item
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		source := clean(doc.Channel.Title)
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			published := parseDate(it.PubDate)
+			if published.IsZero() {
+				published = parseDate(it.Date)
+			}
+			items = append(items, Item{
+				Title:     clean(it.Title),
+				Link:      strings.TrimSpace(it.Link),
+				Summary:   clean(it.Description),
+				Source:    source,
+				Published: published,
+			})
+		}
+	case "feed":
+		source := clean(doc.Title)
+		for _, e := range doc.Entries {
+			published := parseDate(e.Published)
+			if published.IsZero() {
+				published = parseDate(e.Updated)
+			}
+			summary := e.Summary
+			if summary == "" {
+				summary = e.Content
+			}
+			items = append(items, Item{
+				Title:     clean(e.Title),
+				Link:      atomLink(e),
+				Summary:   clean(summary),
+				Source:    source,
+				Published: published,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("feed: unsupported document <%s>", doc.XMLName.Local)
+	}
+
+	kept := items[:0]
+	for _, it := range items {
+		if it.Title != "" {
+			kept = append(kept, it)
+		}
+	}
+	return kept, nil
+}
+
+func atomLink(e atomEntry) string {
+	for _, l := range e.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	if len(e.Links) > 0 {
+		return strings.TrimSpace(e.Links[0].Href)
+	}
+	return ""
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// clean strips markup from a title or summary and collapses whitespace.
+func clean(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, " "))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Mentions reports whether the item's title or summary mentions topic,
+// ignoring case.
+func (it Item) Mentions(topic string) bool {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if topic == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(it.Title), topic) || strings.Contains(strings.ToLower(it.Summary), topic)
+}
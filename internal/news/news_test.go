@@ -0,0 +1,79 @@
+package news
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRSS(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<title>36氪</title>
+<item><title>AI 芯片&amp;新品发布</title><link>https://example.com/1</link>
+<description><![CDATA[<p>英伟达发布 <b>新一代</b> GPU</p>]]></description>
+<pubDate>Mon, 09 Mar 2026 08:00:00 +0800</pubDate></item>
+<item><title></title><link>https://example.com/empty</link></item>
+<item><title>电动车销量</title><link>https://example.com/2</link><dc:date>2026-03-09T07:00:00+08:00</dc:date></item>
+</channel></rss>`
+	items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("items = %+v", items)
+	}
+	first := items[0]
+	if first.Title != "AI 芯片&新品发布" || first.Source != "36氪" || first.Summary != "英伟达发布 新一代 GPU" {
+		t.Fatalf("first = %+v", first)
+	}
+	if want := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC); !first.Published.Equal(want) {
+		t.Fatalf("published = %v", first.Published)
+	}
+	if items[1].Published.IsZero() {
+		t.Fatal("dc:date not read")
+	}
+	if !first.Mentions("gpu") || first.Mentions("电动车") {
+		t.Fatal("Mentions matched wrongly")
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	data := `<feed xmlns="http://www.w3.org/2005/Atom"><title>Go Blog</title>
+<entry><title>Go 1.26 is released</title>
+<link rel="replies" href="https://example.com/comments"/>
+<link rel="alternate" href="https://go.dev/blog/go1.26"/>
+<updated>2026-02-10T00:00:00Z</updated><summary>New release.</summary></entry>
+</feed>`
+	items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Link != "https://go.dev/blog/go1.26" || items[0].Source != "Go Blog" || items[0].Published.IsZero() {
+		t.Fatalf("items = %+v", items)
+	}
+	if _, err := Parse([]byte(`<html><body>not a feed</body></html>`)); err == nil {
+		t.Fatal("HTML page parsed as a feed")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<rss><channel><title>t</title><item><title>hello</title></item></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	items, err := Fetch(context.Background(), srv.Client(), srv.URL+"/feed")
+	if err != nil || len(items) != 1 || items[0].Title != "hello" {
+		t.Fatalf("items = %+v, %v", items, err)
+	}
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/missing"); err == nil {
+		t.Fatal("404 not reported")
+	}
+}
@@ -0,0 +1,112 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// keeperStore uses the Keeper blob API (/api/sync/blob, /api/sync/list).
+type keeperStore struct {
+	baseURL   string
+	token     string
+	namespace string // sent as X-Sync-Namespace; Keeper keeps each namespace apart
+	prefix    string
+	client    *http.Client
+}
+
+func (s *keeperStore) blobURL(key string) string {
+	return s.baseURL + "/api/sync/blob?key=" + url.QueryEscape(joinKey(s.prefix, key))
+}
+
+func (s *keeperStore) do(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	if s.namespace != "" {
+		req.Header.Set("X-Sync-Namespace", s.namespace)
+	}
+	return s.client.Do(req)
+}
+
+func (s *keeperStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.blobURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("keeper GET %s returned %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *keeperStore) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.blobURL(key), data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("keeper PUT %s returned %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *keeperStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.blobURL(key), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("keeper DELETE %s returned %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *keeperStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	target := s.baseURL + "/api/sync/list?prefix=" + url.QueryEscape(joinKey(s.prefix, prefix))
+	resp, err := s.do(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("keeper LIST %s returned %d", prefix, resp.StatusCode)
+	}
+	var payload struct {
+		Objects []struct {
+			Key          string    `json:"key"`
+			Size         int64     `json:"size"`
+			LastModified time.Time `json:"last_modified"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("parse list response: %w", err)
+	}
+	objects := make([]Object, 0, len(payload.Objects))
+	for _, o := range payload.Objects {
+		objects = append(objects, Object{Key: stripPrefix(s.prefix, o.Key), Size: o.Size, LastModified: o.LastModified})
+	}
+	return objects, nil
+}
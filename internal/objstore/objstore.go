@@ -0,0 +1,156 @@
+// Package objstore provides a minimal blob storage abstraction over WebDAV,
+// S3-compatible endpoints (including Alibaba OSS) and the Keeper blob API.
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("object not found")
+
+// Object describes one stored blob.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Store is a flat key/value blob store.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Config describes how to reach a blob store.
+type Config struct {
+	Type      string // "webdav", "s3", "oss" or "keeper"
+	Endpoint  string // WebDAV collection URL, S3/OSS endpoint (e.g. https://oss-cn-hangzhou.aliyuncs.com) or Keeper base URL
+	Bucket    string // S3 bucket name
+	Region    string // S3 region, default us-east-1
+	AccessKey string // S3 access key
+	SecretKey string // S3 secret key
+	Username  string // WebDAV basic auth user
+	Password  string // WebDAV basic auth password
+	Token     string // Keeper API token
+	Namespace string // Keeper sync namespace the blobs are kept under
+	Prefix    string // Key prefix applied to every operation
+}
+
+// New creates a Store from config.
+func New(cfg Config) (Store, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/")
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+	prefix := strings.Trim(strings.TrimSpace(cfg.Prefix), "/")
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "webdav", "dav":
+		return &webdavStore{
+			baseURL:  endpoint,
+			username: cfg.Username,
+			password: cfg.Password,
+			prefix:   prefix,
+			client:   client,
+		}, nil
+	case "s3", "oss":
+		kind := strings.ToLower(strings.TrimSpace(cfg.Type))
+		if strings.TrimSpace(cfg.Bucket) == "" {
+			return nil, fmt.Errorf("bucket is required for %s", kind)
+		}
+		region := strings.TrimSpace(cfg.Region)
+		if region == "" && kind == "oss" {
+			region = ossRegionFromEndpoint(endpoint)
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &s3Store{
+			endpoint:  endpoint,
+			bucket:    strings.TrimSpace(cfg.Bucket),
+			region:    region,
+			accessKey: strings.TrimSpace(cfg.AccessKey),
+			secretKey: strings.TrimSpace(cfg.SecretKey),
+			prefix:    prefix,
+			// OSS only accepts virtual-hosted style requests on its S3-compatible API.
+			virtualHost: kind == "oss",
+			client:      client,
+		}, nil
+	case "keeper":
+		return &keeperStore{
+			baseURL:   endpoint,
+			token:     strings.TrimSpace(cfg.Token),
+			namespace: strings.TrimSpace(cfg.Namespace),
+			prefix:    prefix,
+			client:    client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %q (use webdav, s3, oss or keeper)", cfg.Type)
+	}
+}
+
+// ossRegionFromEndpoint extracts "oss-cn-hangzhou" from https://oss-cn-hangzhou.aliyuncs.com.
+func ossRegionFromEndpoint(endpoint string) string {
+	host := endpoint
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "./:"); i >= 0 {
+		host = host[:i]
+	}
+	if strings.HasPrefix(host, "oss-") {
+		return host
+	}
+	return ""
+}
+
+func joinKey(prefix, key string) string {
+	key = strings.TrimLeft(strings.TrimSpace(key), "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
+
+func stripPrefix(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, prefix+"/")
+}
+
+// escapeKey escapes each path segment of a key for use in a URL.
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = uriEscape(p, false)
+	}
+	return strings.Join(parts, "/")
+}
+
+// uriEscape implements the RFC 3986 unreserved-character escaping used by S3 signing.
+func uriEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package objstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer stores PUT bodies by request path and answers GET/DELETE.
+type fakeServer struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	hosts   []string
+	auth    []string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts = append(f.hosts, r.Host)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			w.Write([]byte(`<ListBucketResult><Contents><Key>docs/a.txt</Key><Size>3</Size><LastModified>2025-01-02T03:04:05Z</LastModified></Contents><IsTruncated>false</IsTruncated></ListBucketResult>`))
+			return
+		}
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case "MKCOL":
+		w.WriteHeader(http.StatusCreated)
+	case "PROPFIND":
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">
+<d:response><d:href>/dav/backup/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop></d:propstat></d:response>
+<d:response><d:href>/dav/backup/report%20today.md</d:href><d:propstat><d:prop><d:getcontentlength>5</d:getcontentlength><d:getlastmodified>Thu, 02 Jan 2025 03:04:05 GMT</d:getlastmodified><d:resourcetype/></d:prop></d:propstat></d:response>
+</d:multistatus>`))
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+	}
+}
+
+func TestWebDAVRoundTripAndList(t *testing.T) {
+	fake := &fakeServer{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := New(Config{Type: "webdav", Endpoint: srv.URL + "/dav", Username: "u", Password: "p", Prefix: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "report today.md", []byte("hello")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := fake.objects["/dav/backup/report today.md"]; !ok {
+		t.Fatalf("unexpected stored paths: %v", fake.objects)
+	}
+	got, err := store.Get(ctx, "report today.md")
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("get = %q, %v", got, err)
+	}
+	if _, err := store.Get(ctx, "missing.md"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	objects, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "report today.md" || objects[0].Size != 5 {
+		t.Fatalf("unexpected list: %#v", objects)
+	}
+	if !strings.HasPrefix(fake.auth[0], "Basic ") {
+		t.Fatalf("expected basic auth, got %q", fake.auth[0])
+	}
+}
+
+func TestS3PathStyleSignedRequests(t *testing.T) {
+	fake := &fakeServer{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := New(Config{Type: "s3", Endpoint: srv.URL, Bucket: "bkt", AccessKey: "AK", SecretKey: "SK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "docs/a.txt", []byte("abc")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := fake.objects["/bkt/docs/a.txt"]; !ok {
+		t.Fatalf("expected path-style key, got %v", fake.objects)
+	}
+	if auth := fake.auth[len(fake.auth)-1]; !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AK/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		t.Fatalf("unexpected authorization header: %q", auth)
+	}
+
+	objects, err := store.List(ctx, "docs/")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "docs/a.txt" || objects[0].LastModified.IsZero() {
+		t.Fatalf("unexpected list: %#v", objects)
+	}
+}
+
+func TestOSSUsesVirtualHostAndEndpointRegion(t *testing.T) {
+	store, err := New(Config{Type: "oss", Endpoint: "https://oss-cn-hangzhou.aliyuncs.com", Bucket: "bkt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3 := store.(*s3Store)
+	if !s3.virtualHost || s3.region != "oss-cn-hangzhou" {
+		t.Fatalf("unexpected oss store: virtualHost=%v region=%q", s3.virtualHost, s3.region)
+	}
+}
+
+func TestNewRejectsUnknownType(t *testing.T) {
+	if _, err := New(Config{Type: "ftp", Endpoint: "ftp://x"}); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+	if _, err := New(Config{Type: "s3", Endpoint: "https://s3.example.com"}); err == nil {
+		t.Fatal("expected error for missing bucket")
+	}
+}
@@ -0,0 +1,185 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type webdavStore struct {
+	baseURL  string
+	username string
+	password string
+	prefix   string
+	client   *http.Client
+}
+
+func (s *webdavStore) url(key string) string {
+	return s.baseURL + "/" + escapeKey(joinKey(s.prefix, key))
+}
+
+func (s *webdavStore) do(ctx context.Context, method, target string, body []byte, headers map[string]string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if s.username != "" || s.password != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return s.client.Do(req)
+}
+
+func (s *webdavStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.url(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webdav GET %s returned %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *webdavStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := s.ensureCollections(ctx, key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.url(key), data, map[string]string{"Content-Type": "application/octet-stream"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webdav PUT %s returned %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+// ensureCollections creates intermediate directories for a key (MKCOL is idempotent enough: 405 means it exists).
+func (s *webdavStore) ensureCollections(ctx context.Context, key string) error {
+	full := joinKey(s.prefix, key)
+	dir := path.Dir(full)
+	if dir == "." || dir == "/" || dir == "" {
+		return nil
+	}
+	parts := strings.Split(dir, "/")
+	current := ""
+	for _, p := range parts {
+		if current == "" {
+			current = p
+		} else {
+			current += "/" + p
+		}
+		resp, err := s.do(ctx, "MKCOL", s.baseURL+"/"+escapeKey(current)+"/", nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 && resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusConflict {
+			return fmt.Errorf("webdav MKCOL %s returned %d", current, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+func (s *webdavStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.url(key), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webdav DELETE %s returned %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// List returns the files directly under the collection named by prefix.
+func (s *webdavStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	dir := strings.Trim(joinKey(s.prefix, prefix), "/")
+	target := s.baseURL + "/"
+	if dir != "" {
+		target += escapeKey(dir) + "/"
+	}
+	body := []byte(`<?xml version="1.0" encoding="utf-8"?><d:propfind xmlns:d="DAV:"><d:prop><d:getcontentlength/><d:getlastmodified/><d:resourcetype/></d:prop></d:propfind>`)
+	resp, err := s.do(ctx, "PROPFIND", target, body, map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusMultiStatus && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return nil, fmt.Errorf("webdav PROPFIND returned %d", resp.StatusCode)
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("parse PROPFIND response: %w", err)
+	}
+
+	base, _ := url.Parse(s.baseURL)
+	basePath := ""
+	if base != nil {
+		basePath = strings.TrimRight(base.Path, "/")
+	}
+
+	var objects []Object
+	for _, r := range ms.Responses {
+		if len(r.Propstat) == 0 || r.Propstat[0].Prop.ResourceType.Collection != nil {
+			continue
+		}
+		href, err := url.PathUnescape(r.Href)
+		if err != nil {
+			href = r.Href
+		}
+		if u, err := url.Parse(href); err == nil && u.Path != "" {
+			href = u.Path
+		}
+		key := strings.TrimLeft(strings.TrimPrefix(href, basePath), "/")
+		obj := Object{Key: stripPrefix(s.prefix, key)}
+		fmt.Sscanf(r.Propstat[0].Prop.ContentLength, "%d", &obj.Size)
+		if t, err := time.Parse(time.RFC1123, r.Propstat[0].Prop.LastModified); err == nil {
+			obj.LastModified = t
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
@@ -0,0 +1,285 @@
+// Package ocr reads text out of images with a pluggable backend: macOS
+// Vision through a Shortcut, tesseract, or a cloud vision model.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Provider recognizes the text in an image file.
+type Provider interface {
+	Name() string
+	Recognize(ctx context.Context, imagePath string) (string, error)
+}
+
+// Config selects and configures the OCR backend.
+type Config struct {
+	Provider  string   // "auto" (default), "vision", "tesseract" or "cloud"
+	Languages []string // tesseract languages (default chi_sim, eng)
+	Shortcut  string   // macOS Shortcut that runs "Extract Text from Image" (default "Extract Text")
+	APIKey    string   // cloud: API key
+	BaseURL   string   // cloud: OpenAI-compatible endpoint (default https://api.openai.com/v1)
+	Model     string   // cloud: vision model (default gpt-4o-mini)
+}
+
+// Recognizer reads text from images with one provider.
+type Recognizer struct {
+	provider Provider
+}
+
+// NewRecognizer creates a Recognizer. "auto" picks macOS Vision when the
+// shortcuts command exists, then tesseract.
+func NewRecognizer(cfg Config) (*Recognizer, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if provider == "" || provider == "auto" {
+		switch {
+		case runtime.GOOS == "darwin" && hasCommand("shortcuts"):
+			provider = "vision"
+		case hasCommand("tesseract"):
+			provider = "tesseract"
+		default:
+			return nil, fmt.Errorf("no OCR backend found (install tesseract or configure ocr.provider: cloud)")
+		}
+	}
+
+	switch provider {
+	case "vision", "macos":
+		return &Recognizer{provider: NewVisionProvider(cfg.Shortcut)}, nil
+	case "tesseract":
+		return &Recognizer{provider: NewTesseractProvider(cfg.Languages)}, nil
+	case "cloud", "openai":
+		p, err := NewCloudProvider(cfg.APIKey, cfg.BaseURL, cfg.Model)
+		if err != nil {
+			return nil, err
+		}
+		return &Recognizer{provider: p}, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR provider: %s", cfg.Provider)
+	}
+}
+
+// Recognize returns the text in the image at path, or "" when there is none.
+func (r *Recognizer) Recognize(ctx context.Context, path string) (string, error) {
+	text, err := r.provider.Recognize(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", r.provider.Name(), err)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// RecognizeBytes writes image to a temporary file and recognizes it.
+func (r *Recognizer) RecognizeBytes(ctx context.Context, image []byte, ext string) (string, error) {
+	f, err := os.CreateTemp("", "coco-ocr-*."+strings.TrimPrefix(ext, "."))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(image)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return r.Recognize(ctx, f.Name())
+}
+
+// ProviderName returns the name of the underlying provider
+func (r *Recognizer) ProviderName() string {
+	return r.provider.Name()
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// VisionProvider runs a macOS Shortcut built around the "Extract Text from
+// Image" action, which uses the Vision framework.
+type VisionProvider struct {
+	shortcut string
+}
+
+// NewVisionProvider creates a VisionProvider for the named Shortcut.
+func NewVisionProvider(shortcut string) *VisionProvider {
+	if strings.TrimSpace(shortcut) == "" {
+		shortcut = "Extract Text"
+	}
+	return &VisionProvider{shortcut: shortcut}
+}
+
+// Name returns the provider name
+func (p *VisionProvider) Name() string {
+	return "vision"
+}
+
+// Recognize runs the Shortcut on imagePath
+func (p *VisionProvider) Recognize(ctx context.Context, imagePath string) (string, error) {
+	out, err := os.CreateTemp("", "coco-ocr-*.txt")
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.CommandContext(ctx, "shortcuts", "run", p.shortcut, "--input-path", imagePath, "--output-path", out.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("shortcut %q failed: %v %s", p.shortcut, err, strings.TrimSpace(stderr.String()))
+	}
+	text, err := os.ReadFile(out.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// TesseractProvider uses the tesseract command
+type TesseractProvider struct {
+	languages string
+}
+
+// NewTesseractProvider creates a TesseractProvider for languages such as
+// chi_sim or eng.
+func NewTesseractProvider(languages []string) *TesseractProvider {
+	if len(languages) == 0 {
+		languages = []string{"chi_sim", "eng"}
+	}
+	return &TesseractProvider{languages: strings.Join(languages, "+")}
+}
+
+// Name returns the provider name
+func (p *TesseractProvider) Name() string {
+	return "tesseract"
+}
+
+// Recognize runs tesseract on imagePath
+func (p *TesseractProvider) Recognize(ctx context.Context, imagePath string) (string, error) {
+	cmd := exec.CommandContext(ctx, "tesseract", imagePath, "stdout", "-l", p.languages)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return joinCJKSpacing(stdout.String()), nil
+}
+
+// joinCJKSpacing removes the spaces tesseract puts between CJK characters.
+func joinCJKSpacing(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == ' ' && i > 0 && i+1 < len(runes) && isCJK(runes[i-1]) && isCJK(runes[i+1]) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isCJK(r rune) bool {
+	return r >= 0x3000 && r <= 0x9fff || r >= 0xff00 && r <= 0xffef
+}
+
+// CloudProvider asks an OpenAI-compatible vision model to transcribe the image
+type CloudProvider struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewCloudProvider creates a CloudProvider
+func NewCloudProvider(apiKey, baseURL, model string) (*CloudProvider, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("cloud OCR requires an API key")
+	}
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &CloudProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *CloudProvider) Name() string {
+	return "cloud"
+}
+
+// Recognize sends the image to the vision model
+func (p *CloudProvider) Recognize(ctx context.Context, imagePath string) (string, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	mime := http.DetectContentType(data)
+	if !strings.HasPrefix(mime, "image/") {
+		mime = "image/" + strings.TrimPrefix(strings.ToLower(filepath.Ext(imagePath)), ".")
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"model": p.model,
+		"messages": []map[string]any{{
+			"role": "user",
+			"content": []map[string]any{
+				{"type": "text", "text": "Transcribe all text in this image exactly as written, keeping line breaks. Output only the text; output nothing if there is none."},
+				{"type": "image_url", "image_url": map[string]string{"url": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)}},
+			},
+		}},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("empty response")
+	}
+	return result.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,64 @@
+package ocr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCloudProviderSendsImage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content []map[string]any `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		url, _ := req.Messages[0].Content[1]["image_url"].(map[string]any)["url"].(string)
+		if req.Model != "vision-1" || !strings.HasPrefix(url, "data:image/png;base64,") {
+			t.Errorf("model %q, image url %.40q", req.Model, url)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"  会议室 B\nRoom 301  "}}]}`))
+	}))
+	defer srv.Close()
+
+	r, err := NewRecognizer(Config{Provider: "cloud", APIKey: "key", BaseURL: srv.URL + "/", Model: "vision-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "shot.png")
+	os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n0000"), 0o600)
+
+	text, err := r.Recognize(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "会议室 B\nRoom 301" {
+		t.Fatalf("text = %q", text)
+	}
+}
+
+func TestNewRecognizerRejectsUnknownProvider(t *testing.T) {
+	if _, err := NewRecognizer(Config{Provider: "magic"}); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+	if _, err := NewRecognizer(Config{Provider: "cloud"}); err == nil {
+		t.Fatal("cloud without a key should fail")
+	}
+}
+
+func TestJoinCJKSpacing(t *testing.T) {
+	if got := joinCJKSpacing("会 议 纪 要 v2 draft"); got != "会议纪要 v2 draft" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,306 @@
+// Package offlinecmd recognizes the most common assistant requests with
+// fixed rules, so coco can still set reminders, check the weather and
+// control music when no language model is reachable. Only phrasings that
+// leave no doubt are matched; anything else is left to the model.
+package offlinecmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command is a recognized request and the tool call that fulfils it.
+type Command struct {
+	Intent  string         // "reminder", "weather" or "music"
+	Tool    string         // agent tool to run
+	Args    map[string]any // tool input
+	Summary string         // what coco is doing, shown before the tool result
+}
+
+// Parse returns the command text asks for, if it is one of the known forms.
+// now anchors relative times.
+func Parse(text string, now time.Time) (Command, bool) {
+	text = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(text), "。.!！?？~～"))
+	if text == "" {
+		return Command{}, false
+	}
+	for _, parse := range []func(string, time.Time) (Command, bool){parseReminder, parseWeather, parseMusic} {
+		if cmd, ok := parse(text, now); ok {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+var (
+	// 10分钟后提醒我喝水, 半小时后提醒我开会, 2个小时后提醒我出门
+	zhRemindIn = regexp.MustCompile(`^(?:请)?(半|[0-9零一二两三四五六七八九十]+)(?:个)?(分钟|小时|钟头)(?:以)?后提醒我(?:一下)?[，,:： ]*(.+)$`)
+	// 明天下午3点提醒我开会, 8:30提醒我打卡, 提醒我明天9点半交报告
+	zhRemindAt  = regexp.MustCompile(`^(?:请)?(今天|明天|后天)?(早上|上午|中午|下午|晚上)?([0-9零一二两三四五六七八九十]+)(?:[:：]([0-9]{2})|点(半|[0-9零一二三四五六七八九十]+分?)?)(?:的时候)?提醒我(?:一下)?[，,:： ]*(.+)$`)
+	zhRemindAt2 = regexp.MustCompile(`^(?:请)?提醒我(今天|明天|后天)?(早上|上午|中午|下午|晚上)?([0-9零一二两三四五六七八九十]+)(?:[:：]([0-9]{2})|点(半|[0-9零一二三四五六七八九十]+分?)?)[，,:： ]*(.+)$`)
+	// remind me in 20 minutes to stretch, remind me to stretch in 2 hours
+	enRemindIn  = regexp.MustCompile(`(?i)^remind me in (\d+) (minute|min|hour|hr)s? to (.+)$`)
+	enRemindIn2 = regexp.MustCompile(`(?i)^remind me to (.+) in (\d+) (minute|min|hour|hr)s?$`)
+	// remind me at 3pm to call mom, remind me to call mom at 15:30 tomorrow
+	enRemindAt  = regexp.MustCompile(`(?i)^remind me (tomorrow )?at (\d{1,2})(?::(\d{2}))? ?(am|pm)?( tomorrow)? to (.+)$`)
+	enRemindAt2 = regexp.MustCompile(`(?i)^remind me to (.+) at (\d{1,2})(?::(\d{2}))? ?(am|pm)?( tomorrow)?$`)
+)
+
+func parseReminder(text string, now time.Time) (Command, bool) {
+	reminder := func(message string, args map[string]any, when string) (Command, bool) {
+		message = strings.TrimSpace(message)
+		if message == "" {
+			return Command{}, false
+		}
+		args["message"] = message
+		return Command{Intent: "reminder", Tool: "remind_once", Args: args, Summary: fmt.Sprintf("设置提醒（%s）：%s", when, message)}, true
+	}
+	at := func(t time.Time) map[string]any {
+		return map[string]any{"at": t.Format("2006-01-02 15:04")}
+	}
+
+	if m := zhRemindIn.FindStringSubmatch(text); m != nil {
+		minutes := 30
+		if m[1] != "半" {
+			n, ok := zhNumber(m[1])
+			if !ok || n == 0 {
+				return Command{}, false
+			}
+			minutes = n
+			if m[2] != "分钟" {
+				minutes *= 60
+			}
+		} else if m[2] == "分钟" {
+			return Command{}, false
+		}
+		return reminder(m[3], map[string]any{"in_minutes": float64(minutes)}, fmt.Sprintf("%d 分钟后", minutes))
+	}
+	for _, re := range []*regexp.Regexp{zhRemindAt, zhRemindAt2} {
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		t, ok := zhClock(now, m[1], m[2], m[3], m[4], m[5])
+		if !ok {
+			return Command{}, false
+		}
+		return reminder(m[6], at(t), t.Format("01-02 15:04"))
+	}
+
+	if m := enRemindIn.FindStringSubmatch(text); m != nil {
+		return enIn(m[1], m[2], m[3], reminder)
+	}
+	if m := enRemindIn2.FindStringSubmatch(text); m != nil {
+		return enIn(m[2], m[3], m[1], reminder)
+	}
+	if m := enRemindAt.FindStringSubmatch(text); m != nil {
+		t, ok := enClock(now, m[2], m[3], m[4], m[1] != "" || m[5] != "")
+		if !ok {
+			return Command{}, false
+		}
+		return reminder(m[6], at(t), t.Format("01-02 15:04"))
+	}
+	if m := enRemindAt2.FindStringSubmatch(text); m != nil {
+		t, ok := enClock(now, m[2], m[3], m[4], m[5] != "")
+		if !ok {
+			return Command{}, false
+		}
+		return reminder(m[1], at(t), t.Format("01-02 15:04"))
+	}
+	return Command{}, false
+}
+
+func enIn(amount, unit, message string, reminder func(string, map[string]any, string) (Command, bool)) (Command, bool) {
+	n, err := strconv.Atoi(amount)
+	if err != nil || n <= 0 {
+		return Command{}, false
+	}
+	if u := strings.ToLower(unit); u == "hour" || u == "hr" {
+		n *= 60
+	}
+	return reminder(message, map[string]any{"in_minutes": float64(n)}, fmt.Sprintf("%d 分钟后", n))
+}
+
+// zhClock resolves a Chinese day, period (上午, 下午, ...) and clock time.
+func zhClock(now time.Time, day, period, hourText, colonMinute, pointMinute string) (time.Time, bool) {
+	hour, ok := zhNumber(hourText)
+	if !ok {
+		return time.Time{}, false
+	}
+	minute := 0
+	switch {
+	case colonMinute != "":
+		minute, _ = strconv.Atoi(colonMinute)
+	case pointMinute == "半":
+		minute = 30
+	case pointMinute != "":
+		if minute, ok = zhNumber(strings.TrimSuffix(pointMinute, "分")); !ok {
+			return time.Time{}, false
+		}
+	}
+	switch period {
+	case "下午", "晚上":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 6 {
+			hour += 12
+		}
+	}
+	offset := map[string]int{"明天": 1, "后天": 2}[day]
+	return resolveClock(now, hour, minute, offset, day != "" || period != "")
+}
+
+func enClock(now time.Time, hourText, minuteText, ampm string, tomorrow bool) (time.Time, bool) {
+	hour, _ := strconv.Atoi(hourText)
+	minute := 0
+	if minuteText != "" {
+		minute, _ = strconv.Atoi(minuteText)
+	}
+	switch strings.ToLower(ampm) {
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+	offset := 0
+	if tomorrow {
+		offset = 1
+	}
+	return resolveClock(now, hour, minute, offset, tomorrow || ampm != "")
+}
+
+// resolveClock builds the time offset days from now. A time that has
+// passed today means tomorrow, except that a bare morning hour is first
+// read as the same hour in the evening.
+func resolveClock(now time.Time, hour, minute, offset int, explicit bool) (time.Time, bool) {
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day()+offset, hour, minute, 0, 0, now.Location())
+	if offset > 0 || t.After(now) {
+		return t, true
+	}
+	if !explicit && hour < 12 && t.Add(12*time.Hour).After(now) {
+		return t.Add(12 * time.Hour), true
+	}
+	return t.AddDate(0, 0, 1), true
+}
+
+var (
+	// 北京天气怎么样, 上海明天天气, 天气 杭州, 深圳的天气如何
+	zhWeather  = regexp.MustCompile(`^(?:查一?下|看看)?(.{0,12}?)(?:的)?(今天|明天|后天|这几天|未来几天|最近)?(?:的)?天气(?:怎么样|如何|预报|情况)?(?:啊|呢)?$`)
+	zhWeather2 = regexp.MustCompile(`^(?:查一?下)?天气[ ：:]+(.{1,12})$`)
+	enWeather  = regexp.MustCompile(`(?i)^(?:what(?:'s| is) the )?weather (?:forecast )?(?:in|for) ([a-z .'-]{2,40}?)( tomorrow)?$`)
+)
+
+func parseWeather(text string, now time.Time) (Command, bool) {
+	location, when := "", ""
+	if m := zhWeather.FindStringSubmatch(text); m != nil {
+		location, when = m[1], m[2]
+	} else if m := zhWeather2.FindStringSubmatch(text); m != nil {
+		location = m[1]
+	} else if m := enWeather.FindStringSubmatch(text); m != nil {
+		location, when = m[1], strings.TrimSpace(m[2])
+	} else {
+		return Command{}, false
+	}
+	location = strings.TrimSpace(location)
+	for _, filler := range []string{"今天", "现在", "我这里", "这里", "这边"} {
+		if location == filler {
+			location = ""
+		}
+	}
+	// Only place names: anything with a pronoun, verb or question word is
+	// more than a weather lookup.
+	if strings.ContainsAny(location, "?？吗么什哪怎我你他她它想要知道告诉帮说和跟") {
+		return Command{}, false
+	}
+
+	place := location
+	if place == "" {
+		place = "当前位置"
+	}
+	if when == "" || when == "今天" {
+		return Command{Intent: "weather", Tool: "weather_current", Args: map[string]any{"location": location}, Summary: "查询天气：" + place}, true
+	}
+	return Command{Intent: "weather", Tool: "weather_forecast", Args: map[string]any{"location": location, "days": float64(3)}, Summary: "查询天气预报：" + place}, true
+}
+
+var (
+	zhPlaySearch = regexp.MustCompile(`^(?:请)?(?:播放|放一?首|来一?首|我想听|放点|来点)(.{1,40})$`)
+	enPlaySearch = regexp.MustCompile(`(?i)^play (?:the song |songs by |music by )(.{1,60})$`)
+	enPlayOn     = regexp.MustCompile(`(?i)^play (.{1,60}) on spotify$`)
+)
+
+var musicControls = map[string]struct{ tool, summary string }{
+	"播放音乐": {"music_play", "继续播放"}, "继续播放": {"music_play", "继续播放"}, "放音乐": {"music_play", "继续播放"},
+	"play music": {"music_play", "继续播放"}, "resume music": {"music_play", "继续播放"},
+	"暂停": {"music_pause", "暂停播放"}, "暂停音乐": {"music_pause", "暂停播放"}, "暂停播放": {"music_pause", "暂停播放"},
+	"pause music": {"music_pause", "暂停播放"},
+	"下一首":         {"music_next", "下一首"}, "切歌": {"music_next", "下一首"}, "next song": {"music_next", "下一首"},
+	"上一首": {"music_previous", "上一首"}, "previous song": {"music_previous", "上一首"},
+}
+
+func parseMusic(text string, _ time.Time) (Command, bool) {
+	if c, ok := musicControls[strings.ToLower(text)]; ok {
+		return Command{Intent: "music", Tool: c.tool, Args: map[string]any{}, Summary: c.summary}, true
+	}
+	query := ""
+	if m := zhPlaySearch.FindStringSubmatch(text); m != nil {
+		query = m[1]
+	} else if m := enPlaySearch.FindStringSubmatch(text); m != nil {
+		query = m[1]
+	} else if m := enPlayOn.FindStringSubmatch(text); m != nil {
+		query = m[1]
+	}
+	query = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(query, "吧"), "的歌"))
+	if query == "" || strings.ContainsAny(query, "?？吗") {
+		return Command{}, false
+	}
+	if c, ok := musicControls[strings.ToLower(query)]; ok {
+		return Command{Intent: "music", Tool: c.tool, Args: map[string]any{}, Summary: c.summary}, true
+	}
+	switch query {
+	case "歌", "音乐", "歌曲", "一首歌", "点歌", "点音乐":
+		c := musicControls["播放音乐"]
+		return Command{Intent: "music", Tool: c.tool, Args: map[string]any{}, Summary: c.summary}, true
+	}
+	return Command{Intent: "music", Tool: "music_search", Args: map[string]any{"query": query}, Summary: "播放：" + query}, true
+}
+
+var zhDigits = map[rune]int{'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+// zhNumber reads 0-99 written in Arabic or Chinese numerals.
+func zhNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= 0
+	}
+	r := []rune(s)
+	switch {
+	case len(r) == 1 && r[0] == '十':
+		return 10, true
+	case len(r) == 1:
+		n, ok := zhDigits[r[0]]
+		return n, ok
+	case len(r) == 2 && r[0] == '十':
+		n, ok := zhDigits[r[1]]
+		return 10 + n, ok
+	case len(r) == 2 && r[1] == '十':
+		n, ok := zhDigits[r[0]]
+		return n * 10, ok
+	case len(r) == 3 && r[1] == '十':
+		tens, ok1 := zhDigits[r[0]]
+		ones, ok2 := zhDigits[r[2]]
+		return tens*10 + ones, ok1 && ok2
+	}
+	return 0, false
+}
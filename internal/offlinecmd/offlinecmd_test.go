@@ -0,0 +1,64 @@
+package offlinecmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, 3, 10, 10, 0, 0, 0, time.Local)
+	cases := []struct {
+		text string
+		tool string
+		args map[string]any
+	}{
+		{"10分钟后提醒我喝水", "remind_once", map[string]any{"in_minutes": 10.0, "message": "喝水"}},
+		{"半小时后提醒我开会。", "remind_once", map[string]any{"in_minutes": 30.0, "message": "开会"}},
+		{"两个小时后提醒我出门", "remind_once", map[string]any{"in_minutes": 120.0, "message": "出门"}},
+		{"明天下午3点提醒我开会", "remind_once", map[string]any{"at": "2025-03-11 15:00", "message": "开会"}},
+		{"提醒我9点半交报告", "remind_once", map[string]any{"at": "2025-03-10 21:30", "message": "交报告"}},
+		{"上午9点半提醒我交报告", "remind_once", map[string]any{"at": "2025-03-11 09:30", "message": "交报告"}},
+		{"11:15提醒我打卡", "remind_once", map[string]any{"at": "2025-03-10 11:15", "message": "打卡"}},
+		{"remind me in 20 minutes to stretch", "remind_once", map[string]any{"in_minutes": 20.0, "message": "stretch"}},
+		{"Remind me to call mom at 3pm", "remind_once", map[string]any{"at": "2025-03-10 15:00", "message": "call mom"}},
+		{"remind me tomorrow at 8:30 to water the plants", "remind_once", map[string]any{"at": "2025-03-11 08:30", "message": "water the plants"}},
+		{"北京天气怎么样？", "weather_current", map[string]any{"location": "北京"}},
+		{"天气", "weather_current", map[string]any{"location": ""}},
+		{"上海明天天气", "weather_forecast", map[string]any{"location": "上海", "days": 3.0}},
+		{"天气：杭州", "weather_current", map[string]any{"location": "杭州"}},
+		{"what's the weather in New York", "weather_current", map[string]any{"location": "New York"}},
+		{"播放周杰伦的歌", "music_search", map[string]any{"query": "周杰伦"}},
+		{"来一首晴天", "music_search", map[string]any{"query": "晴天"}},
+		{"下一首", "music_next", map[string]any{}},
+		{"播放音乐", "music_play", map[string]any{}},
+		{"play songs by Adele", "music_search", map[string]any{"query": "Adele"}},
+	}
+	for _, c := range cases {
+		cmd, ok := Parse(c.text, now)
+		if !ok {
+			t.Errorf("%q not recognized", c.text)
+			continue
+		}
+		if cmd.Tool != c.tool || !reflect.DeepEqual(cmd.Args, c.args) {
+			t.Errorf("%q = %s %v, want %s %v", c.text, cmd.Tool, cmd.Args, c.tool, c.args)
+		}
+	}
+}
+
+func TestParseLeavesOtherTextToTheModel(t *testing.T) {
+	now := time.Date(2025, 3, 10, 10, 0, 0, 0, time.Local)
+	for _, text := range []string{
+		"提醒我一下",
+		"你觉得明天天气会影响航班吗",
+		"我想知道天气",
+		"play chess with me",
+		"帮我写一首关于天气的诗",
+		"25点提醒我睡觉",
+		"",
+	} {
+		if cmd, ok := Parse(text, now); ok {
+			t.Errorf("%q parsed as %s %v", text, cmd.Tool, cmd.Args)
+		}
+	}
+}
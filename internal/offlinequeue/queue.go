@@ -0,0 +1,142 @@
+// Package offlinequeue keeps messages that arrived while their coco was
+// offline, so keeper can hand them over when it connects again. Entries
+// expire after a TTL and are deduplicated by the platform's message ID,
+// which also absorbs the retries platforms send for slow callbacks.
+package offlinequeue
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Item is one queued message.
+type Item struct {
+	ID       int64
+	MsgID    string
+	Platform string
+	UserID   string
+	Payload  []byte // the message as it would have been sent to coco
+	QueuedAt time.Time
+}
+
+// Queue is a SQLite-backed message queue.
+type Queue struct {
+	db  *sql.DB
+	ttl time.Duration
+	mu  sync.Mutex
+}
+
+// Open opens (or creates) the queue table in the database at path.
+// Messages older than ttl are dropped.
+func Open(path string, ttl time.Duration) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS offline_queue (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			msg_id     TEXT UNIQUE,
+			platform   TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			payload    BLOB NOT NULL,
+			queued_at  INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_offline_queue_queued ON offline_queue(queued_at);
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize offline queue: %w", err)
+	}
+	return &Queue{db: db, ttl: ttl}, nil
+}
+
+// Close closes the database.
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Enqueue stores a message. It reports false, without error, when a message
+// with the same non-empty msgID is already queued.
+func (q *Queue) Enqueue(msgID, platform, userID string, payload []byte) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var id any
+	if msgID != "" {
+		id = msgID
+	}
+	res, err := q.db.Exec(`
+		INSERT OR IGNORE INTO offline_queue (msg_id, platform, user_id, payload, queued_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, platform, userID, payload, time.Now().UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Pending drops expired messages and returns the rest, oldest first.
+func (q *Queue) Pending() ([]Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.pruneLocked(); err != nil {
+		return nil, err
+	}
+	rows, err := q.db.Query(`
+		SELECT id, COALESCE(msg_id, ''), platform, user_id, payload, queued_at
+		FROM offline_queue ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var it Item
+		var queuedAt int64
+		if err := rows.Scan(&it.ID, &it.MsgID, &it.Platform, &it.UserID, &it.Payload, &queuedAt); err != nil {
+			return nil, err
+		}
+		it.QueuedAt = time.Unix(0, queuedAt)
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// Remove deletes a delivered message.
+func (q *Queue) Remove(id int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, err := q.db.Exec(`DELETE FROM offline_queue WHERE id = ?`, id)
+	return err
+}
+
+// Prune deletes expired messages and returns how many were dropped.
+func (q *Queue) Prune() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pruneLocked()
+}
+
+func (q *Queue) pruneLocked() (int, error) {
+	res, err := q.db.Exec(`DELETE FROM offline_queue WHERE queued_at < ?`, time.Now().Add(-q.ttl).UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
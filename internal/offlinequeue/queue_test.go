@@ -0,0 +1,44 @@
+package offlinequeue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueueDedupTTLAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keeper.db")
+	q, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { q.Close() }()
+
+	if ok, err := q.Enqueue("m1", "wecom", "alice", []byte(`{"text":"one"}`)); err != nil || !ok {
+		t.Fatalf("enqueue = %v %v", ok, err)
+	}
+	if ok, _ := q.Enqueue("m1", "wecom", "alice", []byte(`{"text":"retry"}`)); ok {
+		t.Fatal("duplicate msg id queued")
+	}
+	q.Enqueue("", "wecom", "bob", []byte(`{"text":"two"}`))
+	q.Enqueue("", "wecom", "bob", []byte(`{"text":"two"}`))
+
+	items, err := q.Pending()
+	if err != nil || len(items) != 3 || string(items[0].Payload) != `{"text":"one"}` || items[1].UserID != "bob" {
+		t.Fatalf("pending = %+v, %v", items, err)
+	}
+	if err := q.Remove(items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen with a TTL everything is older than.
+	q.Close()
+	q, err = Open(path, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if n, err := q.Prune(); err != nil || n != 2 {
+		t.Fatalf("prune = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,84 @@
+package parcel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Custom queries a self-hosted or third-party endpoint. The URL template's
+// {number}, {carrier} and {phone} are filled in and the endpoint answers
+//
+//	{"carrier": "...", "status": "in_transit",
+//	 "events": [{"time": "2026-03-09T10:00:00+08:00", "description": "...", "location": "..."}]}
+//
+// with events newest first and status one of pending, in_transit,
+// out_for_delivery, delivered, exception or returned.
+type Custom struct {
+	client   *http.Client
+	template string
+	token    string
+}
+
+// NewCustom creates a tracker for the endpoint; token, if set, is sent as
+// a bearer token.
+func NewCustom(client *http.Client, template, token string) *Custom {
+	return &Custom{client: client, template: template, token: token}
+}
+
+func (c *Custom) Name() string {
+	if u, err := url.Parse(c.template); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "custom"
+}
+
+func (c *Custom) Track(ctx context.Context, q Query) (Info, error) {
+	u := strings.NewReplacer(
+		"{number}", url.QueryEscape(q.Number),
+		"{carrier}", url.QueryEscape(q.Carrier),
+		"{phone}", url.QueryEscape(q.Phone),
+	).Replace(c.template)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("tracking: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Info{}, fmt.Errorf("tracking: %s", resp.Status)
+	}
+	var out struct {
+		Carrier string `json:"carrier"`
+		Status  string `json:"status"`
+		Events  []struct {
+			Time        string `json:"time"`
+			Description string `json:"description"`
+			Location    string `json:"location"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return Info{}, fmt.Errorf("tracking: invalid response: %w", err)
+	}
+	info := Info{Number: q.Number, Carrier: q.Carrier, Status: Status(out.Status)}
+	if out.Carrier != "" {
+		info.Carrier = out.Carrier
+	}
+	if info.Status == "" {
+		info.Status = StatusPending
+	}
+	for _, e := range out.Events {
+		info.Events = append(info.Events, Event{Time: parseTime(e.Time), Description: e.Description, Location: e.Location})
+	}
+	return info, nil
+}
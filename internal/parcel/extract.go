@@ -0,0 +1,71 @@
+package parcel
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Number is a tracking number found in text.
+type Number struct {
+	Number  string
+	Carrier string // empty when the format does not tell
+}
+
+var carrierPatterns = []struct {
+	carrier string
+	re      *regexp.Regexp
+}{
+	{"shunfeng", regexp.MustCompile(`\bSF\d{12,13}\b`)},
+	{"jd", regexp.MustCompile(`\bJD[A-Z]{0,2}\d{10,13}\b`)},
+	{"yuantong", regexp.MustCompile(`\bYT\d{13}\b`)},
+	{"ups", regexp.MustCompile(`\b1Z[0-9A-Z]{16}\b`)},
+	// UPU S10 numbers used by postal services, e.g. EA123456789CN.
+	{"", regexp.MustCompile(`\b[A-Z]{2}\d{9}[A-Z]{2}\b`)},
+}
+
+// Bare digit strings are only taken after a keyword, so phone and order
+// numbers are not mistaken for tracking numbers. The optional 订 catches
+// 订单号 (order number), which is skipped.
+var keywordNumberPattern = regexp.MustCompile(`(?i)(订?)(?:快递单号|运单号|物流单号|快递号|单号|tracking\s*(?:number|no\.?|#))\s*(?:[:：是为]|is)?\s*([A-Z]{0,4}\d{8,20}[A-Z]{0,2})\b`)
+
+// ExtractNumbers finds tracking numbers in text: numbers in a recognizable
+// carrier format anywhere, and any number right after a word like 单号 or
+// "tracking number".
+func ExtractNumbers(text string) []Number {
+	var found []Number
+	seen := map[string]bool{}
+	add := func(number string) {
+		number = strings.ToUpper(number)
+		if seen[number] {
+			return
+		}
+		seen[number] = true
+		found = append(found, Number{Number: number, Carrier: DetectCarrier(number)})
+	}
+	for _, p := range carrierPatterns {
+		for _, m := range p.re.FindAllString(text, -1) {
+			add(m)
+		}
+	}
+	for _, m := range keywordNumberPattern.FindAllStringSubmatch(text, -1) {
+		if m[1] == "" {
+			add(m[2])
+		}
+	}
+	return found
+}
+
+// DetectCarrier guesses the carrier code from the number's format, or
+// returns "" when the format does not tell.
+func DetectCarrier(number string) string {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	for _, p := range carrierPatterns {
+		if p.carrier != "" && p.re.MatchString(number) {
+			return p.carrier
+		}
+	}
+	if len(number) == 13 && strings.HasSuffix(number, "CN") && carrierPatterns[len(carrierPatterns)-1].re.MatchString(number) {
+		return "ems"
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package parcel
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Kuaidi100 endpoints; tests point them at a local server.
+var (
+	kuaidi100QueryURL = "https://poll.kuaidi100.com/poll/query.do"
+	kuaidi100AutoURL  = "https://www.kuaidi100.com/autonumber/auto"
+)
+
+// Kuaidi100 uses the Kuaidi100 real-time query API, which covers the
+// Chinese carriers and the major international ones.
+type Kuaidi100 struct {
+	client   *http.Client
+	key      string
+	customer string
+}
+
+// NewKuaidi100 creates a Kuaidi100 tracker.
+func NewKuaidi100(client *http.Client, key, customer string) *Kuaidi100 {
+	return &Kuaidi100{client: client, key: key, customer: customer}
+}
+
+func (k *Kuaidi100) Name() string { return "Kuaidi100" }
+
+// kuaidi100States maps the API's state codes to statuses.
+var kuaidi100States = map[string]Status{
+	"0":  StatusInTransit,      // 在途
+	"1":  StatusInTransit,      // 揽收
+	"2":  StatusException,      // 疑难
+	"3":  StatusDelivered,      // 签收
+	"4":  StatusReturned,       // 退签
+	"5":  StatusOutForDelivery, // 派件
+	"6":  StatusReturned,       // 退回
+	"7":  StatusInTransit,      // 转投
+	"8":  StatusInTransit,      // 清关
+	"14": StatusException,      // 拒签
+}
+
+func (k *Kuaidi100) Track(ctx context.Context, q Query) (Info, error) {
+	carrier := q.Carrier
+	if carrier == "" {
+		carrier = DetectCarrier(q.Number)
+	}
+	if carrier == "" {
+		detected, err := k.detect(ctx, q.Number)
+		if err != nil {
+			return Info{}, err
+		}
+		carrier = detected
+	}
+
+	param, _ := json.Marshal(map[string]string{"com": carrier, "num": q.Number, "phone": q.Phone, "resultv2": "1"})
+	sum := md5.Sum([]byte(string(param) + k.key + k.customer))
+	form := url.Values{
+		"customer": {k.customer},
+		"sign":     {strings.ToUpper(hex.EncodeToString(sum[:]))},
+		"param":    {string(param)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, kuaidi100QueryURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Info{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("kuaidi100: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 400 {
+		return Info{}, fmt.Errorf("kuaidi100: %s", resp.Status)
+	}
+
+	var out struct {
+		Message    string `json:"message"`
+		Status     string `json:"status"`
+		ReturnCode string `json:"returnCode"`
+		State      string `json:"state"`
+		Com        string `json:"com"`
+		Data       []struct {
+			Time     string `json:"time"`
+			Context  string `json:"context"`
+			Location string `json:"location"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return Info{}, fmt.Errorf("kuaidi100: invalid response: %w", err)
+	}
+	if out.Status != "200" {
+		return Info{}, fmt.Errorf("kuaidi100: %s (code %s)", out.Message, out.ReturnCode)
+	}
+
+	info := Info{Number: q.Number, Carrier: carrier, Status: StatusPending}
+	if out.Com != "" {
+		info.Carrier = out.Com
+	}
+	for _, d := range out.Data {
+		info.Events = append(info.Events, Event{Time: parseTime(d.Time), Description: d.Context, Location: d.Location})
+	}
+	if status, ok := kuaidi100States[out.State]; ok {
+		info.Status = status
+	} else if len(info.Events) > 0 {
+		info.Status = StatusInTransit
+	}
+	return info, nil
+}
+
+// detect asks Kuaidi100 which carrier a number belongs to.
+func (k *Kuaidi100) detect(ctx context.Context, number string) (string, error) {
+	u := kuaidi100AutoURL + "?" + url.Values{"num": {number}, "key": {k.key}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("kuaidi100: %w", err)
+	}
+	defer resp.Body.Close()
+	var candidates []struct {
+		ComCode string `json:"comCode"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&candidates); err != nil || len(candidates) == 0 || candidates[0].ComCode == "" {
+		return "", fmt.Errorf("kuaidi100: carrier of %s not recognized, pass the carrier code", number)
+	}
+	return candidates[0].ComCode, nil
+}
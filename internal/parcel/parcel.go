@@ -0,0 +1,114 @@
+// Package parcel looks up delivery status by tracking number through a
+// tracking service (Kuaidi100, 17TRACK or a custom HTTP endpoint) and finds
+// tracking numbers in free text.
+package parcel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Status is the delivery stage of a parcel.
+type Status string
+
+const (
+	StatusPending        Status = "pending" // known to the service, no scan yet
+	StatusInTransit      Status = "in_transit"
+	StatusOutForDelivery Status = "out_for_delivery"
+	StatusDelivered      Status = "delivered"
+	StatusException      Status = "exception" // held, failed delivery, customs trouble
+	StatusReturned       Status = "returned"
+)
+
+// Final reports whether the parcel will not move any more.
+func (s Status) Final() bool {
+	return s == StatusDelivered || s == StatusReturned
+}
+
+// Event is one scan in a parcel's history.
+type Event struct {
+	Time        time.Time
+	Description string
+	Location    string
+}
+
+// Info is what the tracking service knows about a parcel.
+type Info struct {
+	Number  string
+	Carrier string // carrier code, e.g. shunfeng
+	Status  Status
+	Events  []Event // newest first
+}
+
+// Latest returns the newest event.
+func (i Info) Latest() (Event, bool) {
+	if len(i.Events) == 0 {
+		return Event{}, false
+	}
+	return i.Events[0], true
+}
+
+// Query identifies a parcel.
+type Query struct {
+	Number  string
+	Carrier string // optional; detected from the number when empty
+	Phone   string // last digits of the recipient's phone (SF Express needs it)
+}
+
+// Tracker is a tracking service.
+type Tracker interface {
+	// Name describes the service, e.g. "Kuaidi100".
+	Name() string
+	// Track returns the parcel's current status and events.
+	Track(ctx context.Context, q Query) (Info, error)
+}
+
+// Config selects and configures the tracking service.
+type Config struct {
+	Provider string // "kuaidi100", "17track" or "custom"
+	APIKey   string // kuaidi100 key, 17TRACK token or custom bearer token
+	Customer string // kuaidi100 customer ID
+	URL      string // custom: GET URL with {number}, {carrier} and {phone}
+}
+
+// New creates the configured tracker.
+func New(cfg Config) (Tracker, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "kuaidi100":
+		if cfg.APIKey == "" || cfg.Customer == "" {
+			return nil, fmt.Errorf("tracking.api_key and tracking.customer are required for kuaidi100")
+		}
+		return NewKuaidi100(client, cfg.APIKey, cfg.Customer), nil
+	case "17track":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("tracking.api_key is required for 17track")
+		}
+		return NewTrack17(client, cfg.APIKey), nil
+	case "custom":
+		if !strings.Contains(cfg.URL, "{number}") {
+			return nil, fmt.Errorf("tracking.url must contain {number}")
+		}
+		return NewCustom(client, cfg.URL, cfg.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown tracking provider %q (use kuaidi100, 17track or custom)", cfg.Provider)
+	}
+}
+
+// parseTime reads the timestamps tracking services return; local times
+// without a zone are taken as local.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,109 @@
+package parcel
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractNumbers(t *testing.T) {
+	text := `顺丰 sf1234567890123 已发货；另一个快递单号：4312345678901，订单号 98765432101234。
+EMS: EA123456789CN, ups 1Z999AA10123456784, 手机 13800138000`
+	got := ExtractNumbers(text)
+	want := []Number{
+		{"1Z999AA10123456784", "ups"},
+		{"EA123456789CN", "ems"},
+		{"4312345678901", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Only upper-case prefixes count outside a keyword.
+	if got := ExtractNumbers("运单号 SF1234567890123"); len(got) != 1 || got[0].Carrier != "shunfeng" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestKuaidi100Track(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auto":
+			if r.URL.Query().Get("num") != "4312345678901" {
+				t.Errorf("auto query = %v", r.URL.Query())
+			}
+			io.WriteString(w, `[{"comCode":"yunda"}]`)
+		case "/query":
+			r.ParseForm()
+			param := r.Form.Get("param")
+			sum := md5.Sum([]byte(param + "key" + "cust"))
+			if r.Form.Get("sign") != strings.ToUpper(hex.EncodeToString(sum[:])) || !strings.Contains(param, `"com":"yunda"`) {
+				t.Errorf("form = %v", r.Form)
+			}
+			io.WriteString(w, `{"message":"ok","status":"200","state":"5","com":"yunda","data":[
+				{"time":"2026-03-09 10:00:00","context":"【杭州】派件中","location":"杭州"},
+				{"time":"2026-03-08 20:00:00","context":"【上海】已发出"}]}`)
+		}
+	}))
+	defer srv.Close()
+	oldQuery, oldAuto := kuaidi100QueryURL, kuaidi100AutoURL
+	kuaidi100QueryURL, kuaidi100AutoURL = srv.URL+"/query", srv.URL+"/auto"
+	t.Cleanup(func() { kuaidi100QueryURL, kuaidi100AutoURL = oldQuery, oldAuto })
+
+	info, err := NewKuaidi100(srv.Client(), "key", "cust").Track(context.Background(), Query{Number: "4312345678901"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, _ := info.Latest()
+	if info.Status != StatusOutForDelivery || info.Carrier != "yunda" || len(info.Events) != 2 || latest.Location != "杭州" || latest.Time.Hour() != 10 {
+		t.Fatalf("info = %+v", info)
+	}
+}
+
+func TestTrack17RegistersUnknownNumbers(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("17token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		calls = append(calls, r.URL.Path)
+		switch {
+		case r.URL.Path == "/register":
+			io.WriteString(w, `{"code":0,"data":{"accepted":[{"number":"EA123456789CN"}],"rejected":[]}}`)
+		case len(calls) == 1:
+			io.WriteString(w, `{"code":0,"data":{"accepted":[],"rejected":[{"number":"EA123456789CN","error":{"code":-18019902,"message":"The tracking number does not register, please register first."}}]}}`)
+		default:
+			io.WriteString(w, `{"code":0,"data":{"accepted":[{"number":"EA123456789CN","track_info":{
+				"latest_status":{"status":"Delivered"},
+				"tracking":{"providers":[{"provider":{"name":"China Post"},"events":[{"time_iso":"2026-03-09T10:00:00+08:00","description":"Delivered","location":"Paris"}]}]}}}]}}`)
+		}
+	}))
+	defer srv.Close()
+	old := track17URL
+	track17URL = srv.URL
+	t.Cleanup(func() { track17URL = old })
+
+	tr := NewTrack17(srv.Client(), "tok")
+	info, err := tr.Track(context.Background(), Query{Number: "EA123456789CN"})
+	if err != nil || info.Status != StatusPending {
+		t.Fatalf("first track = %+v, %v", info, err)
+	}
+	info, err = tr.Track(context.Background(), Query{Number: "EA123456789CN"})
+	if err != nil || info.Status != StatusDelivered || info.Carrier != "China Post" || !info.Status.Final() {
+		t.Fatalf("second track = %+v, %v", info, err)
+	}
+	if strings.Join(calls, ",") != "/gettrackinfo,/register,/gettrackinfo" {
+		t.Fatalf("calls = %v", calls)
+	}
+}
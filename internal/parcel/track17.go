@@ -0,0 +1,137 @@
+package parcel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// track17URL is the 17TRACK API base; tests point it at a local server.
+var track17URL = "https://api.17track.net/track/v2.2"
+
+// Track17 uses the 17TRACK API, which covers international carriers and
+// detects the carrier itself. Numbers must be registered before 17TRACK
+// starts following them; Track registers unknown numbers on first use.
+type Track17 struct {
+	client *http.Client
+	token  string
+}
+
+// NewTrack17 creates a 17TRACK tracker.
+func NewTrack17(client *http.Client, token string) *Track17 {
+	return &Track17{client: client, token: token}
+}
+
+func (t *Track17) Name() string { return "17TRACK" }
+
+var track17States = map[string]Status{
+	"NotFound":           StatusPending,
+	"InfoReceived":       StatusPending,
+	"InTransit":          StatusInTransit,
+	"Expired":            StatusException,
+	"AvailableForPickup": StatusOutForDelivery,
+	"OutForDelivery":     StatusOutForDelivery,
+	"DeliveryFailure":    StatusException,
+	"Delivered":          StatusDelivered,
+	"Exception":          StatusException,
+}
+
+type track17Result struct {
+	Accepted []struct {
+		Number    string `json:"number"`
+		TrackInfo struct {
+			LatestStatus struct {
+				Status string `json:"status"`
+			} `json:"latest_status"`
+			Tracking struct {
+				Providers []struct {
+					Provider struct {
+						Name string `json:"name"`
+					} `json:"provider"`
+					Events []struct {
+						TimeISO     string `json:"time_iso"`
+						Description string `json:"description"`
+						Location    string `json:"location"`
+					} `json:"events"`
+				} `json:"providers"`
+			} `json:"tracking"`
+		} `json:"track_info"`
+	} `json:"accepted"`
+	Rejected []struct {
+		Number string `json:"number"`
+		Error  struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"rejected"`
+}
+
+func (t *Track17) Track(ctx context.Context, q Query) (Info, error) {
+	out, err := t.call(ctx, "gettrackinfo", q.Number)
+	if err != nil {
+		return Info{}, err
+	}
+	for _, a := range out.Accepted {
+		info := Info{Number: q.Number, Carrier: q.Carrier, Status: StatusPending}
+		if s, ok := track17States[a.TrackInfo.LatestStatus.Status]; ok {
+			info.Status = s
+		}
+		for _, p := range a.TrackInfo.Tracking.Providers {
+			if info.Carrier == "" {
+				info.Carrier = p.Provider.Name
+			}
+			for _, e := range p.Events {
+				info.Events = append(info.Events, Event{Time: parseTime(e.TimeISO), Description: e.Description, Location: e.Location})
+			}
+		}
+		return info, nil
+	}
+	for _, r := range out.Rejected {
+		if !strings.Contains(strings.ToLower(r.Error.Message), "register") {
+			return Info{}, fmt.Errorf("17track: %s", r.Error.Message)
+		}
+		reg, err := t.call(ctx, "register", q.Number)
+		if err != nil {
+			return Info{}, err
+		}
+		if len(reg.Accepted) == 0 && len(reg.Rejected) > 0 {
+			return Info{}, fmt.Errorf("17track: %s", reg.Rejected[0].Error.Message)
+		}
+		// Registered now; events arrive on the next check.
+		return Info{Number: q.Number, Carrier: q.Carrier, Status: StatusPending}, nil
+	}
+	return Info{}, fmt.Errorf("17track: no result for %s", q.Number)
+}
+
+func (t *Track17) call(ctx context.Context, method, number string) (*track17Result, error) {
+	body, _ := json.Marshal([]map[string]string{{"number": number}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, track17URL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("17token", t.token)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("17track: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("17track: %s", resp.Status)
+	}
+	var out struct {
+		Code int           `json:"code"`
+		Data track17Result `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("17track: invalid response: %w", err)
+	}
+	if out.Code != 0 {
+		return nil, fmt.Errorf("17track: error code %d", out.Code)
+	}
+	return &out.Data, nil
+}
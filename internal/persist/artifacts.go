@@ -0,0 +1,67 @@
+package persist
+
+import (
+	"time"
+)
+
+// Artifact is a tool output too large to hand to the model in one piece.
+type Artifact struct {
+	ID           string
+	Tool         string
+	Conversation string // conversation key of the turn that produced it
+	Content      string
+	CreatedAt    time.Time
+}
+
+// SaveArtifact stores a tool output under its ID
+func (s *Store) SaveArtifact(a Artifact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	content, err := s.seal(a.Content)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT OR REPLACE INTO artifacts (id, tool, conversation, content, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, a.ID, a.Tool, a.Conversation, content, a.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetArtifact returns the artifact with the ID, or sql.ErrNoRows
+func (s *Store) GetArtifact(id string) (*Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var a Artifact
+	var createdAt string
+	err := s.db.QueryRow(`
+		SELECT id, tool, conversation, content, created_at
+		FROM artifacts WHERE id = ?
+	`, id).Scan(&a.ID, &a.Tool, &a.Conversation, &a.Content, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if a.Content, err = s.open(a.Content); err != nil {
+		return nil, err
+	}
+	a.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return &a, nil
+}
+
+// PruneArtifacts deletes artifacts created before the cutoff and returns how many
+func (s *Store) PruneArtifacts(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`DELETE FROM artifacts WHERE created_at < ?`, before.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
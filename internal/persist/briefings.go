@@ -0,0 +1,94 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BriefingPrefs is a user's morning briefing subscription and topic choices.
+type BriefingPrefs struct {
+	UserID     string
+	Platform   string // where the daily briefing is sent
+	ChannelID  string
+	Subscribed bool
+	Followed   []string // topics the user follows besides the configured ones
+	Muted      []string // topics left out, including configured ones
+	LastSent   time.Time
+	UpdatedAt  time.Time
+}
+
+// SaveBriefingPrefs stores a user's briefing preferences, replacing any
+// saved before
+func (s *Store) SaveBriefingPrefs(p BriefingPrefs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO briefing_prefs (user_id, platform, channel_id, subscribed, followed, muted, last_sent, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			platform=excluded.platform, channel_id=excluded.channel_id, subscribed=excluded.subscribed,
+			followed=excluded.followed, muted=excluded.muted, last_sent=excluded.last_sent, updated_at=excluded.updated_at
+	`, p.UserID, p.Platform, p.ChannelID, p.Subscribed, toJSON(p.Followed), toJSON(p.Muted),
+		nullTime(p.LastSent), p.UpdatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetBriefingPrefs returns a user's briefing preferences, or nil if the
+// user has none
+func (s *Store) GetBriefingPrefs(userID string) (*BriefingPrefs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs, err := s.queryBriefingPrefs(`
+		SELECT user_id, platform, channel_id, subscribed, followed, muted, last_sent, updated_at
+		FROM briefing_prefs
+		WHERE user_id = ?
+	`, userID)
+	if err != nil || len(prefs) == 0 {
+		return nil, err
+	}
+	return &prefs[0], nil
+}
+
+// BriefingSubscribers returns the preferences of every subscribed user
+func (s *Store) BriefingSubscribers() ([]BriefingPrefs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryBriefingPrefs(`
+		SELECT user_id, platform, channel_id, subscribed, followed, muted, last_sent, updated_at
+		FROM briefing_prefs
+		WHERE subscribed = 1
+		ORDER BY user_id
+	`)
+}
+
+func (s *Store) queryBriefingPrefs(query string, args ...any) ([]BriefingPrefs, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []BriefingPrefs
+	for rows.Next() {
+		var p BriefingPrefs
+		var followed, muted, updatedAt string
+		var lastSent sql.NullString
+		if err := rows.Scan(&p.UserID, &p.Platform, &p.ChannelID, &p.Subscribed, &followed, &muted, &lastSent, &updatedAt); err != nil {
+			return nil, err
+		}
+		fromJSON(followed, &p.Followed)
+		fromJSON(muted, &p.Muted)
+		if lastSent.Valid {
+			p.LastSent, _ = time.Parse(time.RFC3339, lastSent.String)
+		}
+		p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
@@ -0,0 +1,131 @@
+package persist
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Broadcast statuses
+const (
+	BroadcastPending = "pending"
+	BroadcastDone    = "done"
+)
+
+// Broadcast is an announcement queued for every active conversation, or
+// the ones whose channel carries one of Tags. The running coco delivers it;
+// Platform/ChannelID/UserID are the chat that asked for it, empty when it
+// came from the command line.
+type Broadcast struct {
+	ID         int64
+	Message    string
+	Tags       []string
+	Source     string // "cli" or "tool"
+	Platform   string
+	ChannelID  string
+	UserID     string
+	Status     string
+	Total      int    // conversations it went to
+	Sent       int    // of which delivered
+	Report     string // one line per failed conversation
+	CreatedAt  time.Time
+	FinishedAt time.Time
+}
+
+// QueueBroadcast stores a pending broadcast and returns its ID
+func (s *Store) QueueBroadcast(b Broadcast) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`
+		INSERT INTO broadcasts (message, tags, source, platform, channel_id, user_id, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, b.Message, strings.Join(b.Tags, ","), b.Source, b.Platform, b.ChannelID, b.UserID, BroadcastPending, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishBroadcast records how a broadcast went
+func (s *Store) FinishBroadcast(id int64, total, sent int, report string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE broadcasts SET status = ?, total = ?, sent = ?, report = ?, finished_at = ?
+		WHERE id = ?
+	`, BroadcastDone, total, sent, report, time.Now().Format(time.RFC3339), id)
+	return err
+}
+
+// GetBroadcast returns a broadcast by ID, or nil if there is none
+func (s *Store) GetBroadcast(id int64) (*Broadcast, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, err := s.queryBroadcasts(`
+		SELECT id, message, tags, source, platform, channel_id, user_id, status, total, sent, report, created_at, finished_at
+		FROM broadcasts WHERE id = ?
+	`, id)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// PendingBroadcasts returns the broadcasts still to deliver, oldest first
+func (s *Store) PendingBroadcasts() ([]Broadcast, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryBroadcasts(`
+		SELECT id, message, tags, source, platform, channel_id, user_id, status, total, sent, report, created_at, finished_at
+		FROM broadcasts WHERE status = ? ORDER BY id
+	`, BroadcastPending)
+}
+
+// LastBroadcastAt returns when the latest broadcast was queued, or the zero
+// time if there was none
+func (s *Store) LastBroadcastAt() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var createdAt sql.NullString
+	if err := s.db.QueryRow(`SELECT MAX(created_at) FROM broadcasts`).Scan(&createdAt); err != nil {
+		return time.Time{}, err
+	}
+	if !createdAt.Valid {
+		return time.Time{}, nil
+	}
+	t, _ := time.Parse(time.RFC3339, createdAt.String)
+	return t, nil
+}
+
+func (s *Store) queryBroadcasts(query string, args ...any) ([]Broadcast, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Broadcast
+	for rows.Next() {
+		var b Broadcast
+		var tags, createdAt string
+		var finishedAt sql.NullString
+		if err := rows.Scan(&b.ID, &b.Message, &tags, &b.Source, &b.Platform, &b.ChannelID, &b.UserID, &b.Status,
+			&b.Total, &b.Sent, &b.Report, &createdAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		if tags != "" {
+			b.Tags = strings.Split(tags, ",")
+		}
+		b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		if finishedAt.Valid {
+			b.FinishedAt, _ = time.Parse(time.RFC3339, finishedAt.String)
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
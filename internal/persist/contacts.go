@@ -0,0 +1,129 @@
+package persist
+
+import (
+	"time"
+)
+
+// Contact kinds
+const (
+	ContactPerson = "person"
+	ContactGroup  = "group"
+)
+
+// Contact is a friendly name ("老板", "家庭群") for a chat the assistant can
+// send to. Contacts of the "default" user belong to everyone.
+type Contact struct {
+	ID          int64
+	UserID      string // owner of the contact
+	Name        string
+	Platform    string
+	ChannelID   string
+	RecipientID string // platform user ID, for platforms that address people by it
+	Kind        string // ContactPerson or ContactGroup
+	Note        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SaveContact stores a contact, replacing the owner's contact of the same
+// name, and returns its ID
+func (s *Store) SaveContact(c Contact) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Format(time.RFC3339)
+	if c.Kind == "" {
+		c.Kind = ContactPerson
+	}
+	var err error
+	for _, v := range []*string{&c.ChannelID, &c.RecipientID, &c.Note} {
+		if *v, err = s.seal(*v); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO contacts (user_id, name, platform, channel_id, recipient_id, kind, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, name) DO UPDATE SET
+			platform = excluded.platform, channel_id = excluded.channel_id, recipient_id = excluded.recipient_id,
+			kind = excluded.kind, note = excluded.note, updated_at = excluded.updated_at
+	`, c.UserID, c.Name, c.Platform, c.ChannelID, c.RecipientID, c.Kind, c.Note, now, now); err != nil {
+		return 0, err
+	}
+	var id int64
+	err = s.db.QueryRow(`SELECT id FROM contacts WHERE user_id = ? AND name = ?`, c.UserID, c.Name).Scan(&id)
+	return id, err
+}
+
+// DeleteContact removes the owner's contact called name (ignoring case, as
+// names do everywhere) and reports whether there was one
+func (s *Store) DeleteContact(userID, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`DELETE FROM contacts WHERE user_id = ? AND name = ?`, userID, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FindContact returns the contact called name (ignoring case) visible to
+// userID, preferring the user's own over a shared one, or nil if there is
+// none
+func (s *Store) FindContact(userID, name string) (*Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contacts, err := s.queryContacts(`
+		SELECT id, user_id, name, platform, channel_id, recipient_id, kind, note, created_at, updated_at
+		FROM contacts
+		WHERE user_id IN (?, 'default') AND name = ?
+		ORDER BY user_id = 'default'
+		LIMIT 1
+	`, userID, name)
+	if err != nil || len(contacts) == 0 {
+		return nil, err
+	}
+	return &contacts[0], nil
+}
+
+// Contacts returns the contacts visible to userID by name
+func (s *Store) Contacts(userID string) ([]Contact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryContacts(`
+		SELECT id, user_id, name, platform, channel_id, recipient_id, kind, note, created_at, updated_at
+		FROM contacts
+		WHERE user_id IN (?, 'default')
+		ORDER BY name
+	`, userID)
+}
+
+func (s *Store) queryContacts(query string, args ...any) ([]Contact, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []Contact
+	for rows.Next() {
+		var c Contact
+		var createdAt, updatedAt string
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Platform, &c.ChannelID, &c.RecipientID, &c.Kind, &c.Note, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		for _, v := range []*string{&c.ChannelID, &c.RecipientID, &c.Note} {
+			if *v, err = s.open(*v); err != nil {
+				return nil, err
+			}
+		}
+		c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
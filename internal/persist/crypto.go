@@ -0,0 +1,319 @@
+package persist
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// sealedPrefix marks a column value sealed by the store. Values without it
+// are plaintext, so databases from before encryption stay readable.
+const sealedPrefix = "enc1:"
+
+const (
+	unlockSaltSize   = 16
+	unlockIterations = 210000
+	unlockCheck      = "coco"
+)
+
+var (
+	// ErrLocked is returned when an encrypted value is read before Unlock.
+	ErrLocked = errors.New("database is encrypted; unlock it first")
+	// ErrWrongKey is returned when Unlock is given the wrong secret.
+	ErrWrongKey = errors.New("cannot unlock the database (wrong passphrase?)")
+)
+
+// sealedColumns are the columns holding what users said, what coco
+// remembers about them and what their tools returned. Columns used in
+// lookups, such as a contact's name, stay plaintext.
+var sealedColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"messages", []string{"content", "tool_calls", "tool_result"}},
+	{"daily_reports", []string{"content", "summary", "tasks", "calendars"}},
+	{"memory_vectors", []string{"content", "metadata"}},
+	{"artifacts", []string{"content"}},
+	{"run_traces", []string{"data"}},
+	{"tasks", []string{"title", "description", "project"}},
+	{"contacts", []string{"channel_id", "recipient_id", "note"}},
+	{"dnd_held", []string{"message"}},
+}
+
+// sealedColumnsVersion is bumped when sealedColumns grows, so the next
+// unlock of an encrypted store seals the rows already in the new columns.
+// Version 1 sealed messages, daily reports, memories and artifacts.
+const sealedColumnsVersion = 2
+
+// IsSealed reports whether v was sealed by an encrypted store.
+func IsSealed(v string) bool {
+	return strings.HasPrefix(v, sealedPrefix)
+}
+
+// Encrypted reports whether the database was ever unlocked with a secret,
+// which means it holds sealed values.
+func (s *Store) Encrypted() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM store_encryption`).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Unlock derives the store's key from secret and seals messages, memories
+// and daily reports from now on. The first unlock also seals what is
+// already stored and returns how many rows that took, as does the first
+// unlock after sealedColumns grows; later unlocks fail with ErrWrongKey
+// unless given the same secret.
+func (s *Store) Unlock(secret string) (int, error) {
+	if secret == "" {
+		return 0, fmt.Errorf("empty database passphrase")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var salt []byte
+	var check string
+	var version int
+	err := s.db.QueryRow(`SELECT salt, check_value, sealed_version FROM store_encryption WHERE id = 1`).Scan(&salt, &check, &version)
+	first := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !first {
+		return 0, err
+	}
+	if first {
+		salt = make([]byte, unlockSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return 0, err
+		}
+	}
+
+	k, err := pbkdf2.Key(sha256.New, secret, salt, unlockIterations, 32)
+	if err != nil {
+		return 0, err
+	}
+	key := new([32]byte)
+	copy(key[:], k)
+
+	if !first {
+		if got, err := openWith(key, check); err != nil || got != unlockCheck {
+			return 0, ErrWrongKey
+		}
+		if version >= sealedColumnsVersion {
+			s.key = key
+			return 0, nil
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if first {
+		if check, err = sealWith(key, unlockCheck); err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO store_encryption (id, salt, check_value, sealed_version) VALUES (1, ?, ?, ?)`, salt, check, sealedColumnsVersion)
+	} else {
+		_, err = tx.Exec(`UPDATE store_encryption SET sealed_version = ? WHERE id = 1`, sealedColumnsVersion)
+	}
+	if err != nil {
+		return 0, err
+	}
+	sealed := 0
+	for _, t := range sealedColumns {
+		n, err := sealExisting(tx, key, t.table, t.columns)
+		if err != nil {
+			return 0, fmt.Errorf("encrypt %s: %w", t.table, err)
+		}
+		sealed += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.key = key
+
+	if sealed > 0 {
+		// Rewrite the file so the plaintext does not linger in free pages.
+		if _, err := s.db.Exec(`VACUUM`); err != nil {
+			log.Printf("[PERSIST] VACUUM after encrypting failed: %v", err)
+		}
+		s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+	return sealed, nil
+}
+
+// sealExisting seals the plaintext values of columns in table.
+func sealExisting(tx *sql.Tx, key *[32]byte, table string, columns []string) (int, error) {
+	rows, err := tx.Query(`SELECT rowid, ` + strings.Join(columns, ", ") + ` FROM ` + table)
+	if err != nil {
+		return 0, err
+	}
+	type update struct {
+		rowid  int64
+		values []any
+	}
+	var updates []update
+	for rows.Next() {
+		vals := make([]sql.NullString, len(columns))
+		dest := []any{new(int64)}
+		for i := range vals {
+			dest = append(dest, &vals[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		u := update{rowid: *dest[0].(*int64)}
+		changed := false
+		for _, v := range vals {
+			if !v.Valid || v.String == "" || IsSealed(v.String) {
+				u.values = append(u.values, v)
+				continue
+			}
+			sealed, err := sealWith(key, v.String)
+			if err != nil {
+				rows.Close()
+				return 0, err
+			}
+			u.values = append(u.values, sealed)
+			changed = true
+		}
+		if changed {
+			updates = append(updates, u)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		set[i] = c + " = ?"
+	}
+	stmt, err := tx.Prepare(`UPDATE ` + table + ` SET ` + strings.Join(set, ", ") + ` WHERE rowid = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, u := range updates {
+		if _, err := stmt.Exec(append(u.values, u.rowid)...); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), nil
+}
+
+// Reveal returns the plaintext of a value read from the database. Values
+// that were never sealed come back as they are.
+func (s *Store) Reveal(v string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.open(v)
+}
+
+// seal encrypts v when the store is unlocked. Callers hold s.mu.
+func (s *Store) seal(v string) (string, error) {
+	if s.key == nil || v == "" {
+		return v, nil
+	}
+	return sealWith(s.key, v)
+}
+
+// open decrypts a sealed v. Callers hold s.mu.
+func (s *Store) open(v string) (string, error) {
+	if !IsSealed(v) {
+		return v, nil
+	}
+	if s.key == nil {
+		return "", ErrLocked
+	}
+	return openWith(s.key, v)
+}
+
+// openMessage decrypts what getMessagesInternal and SearchMessages read.
+func (s *Store) openMessage(msg *Message, toolCalls, toolResult sql.NullString) error {
+	var err error
+	if msg.Content, err = s.open(msg.Content); err != nil {
+		return err
+	}
+	if toolCalls.Valid {
+		calls, err := s.open(toolCalls.String)
+		if err != nil {
+			return err
+		}
+		_ = fromJSON(calls, &msg.ToolCalls)
+	}
+	if toolResult.Valid {
+		result, err := s.open(toolResult.String)
+		if err != nil {
+			return err
+		}
+		var tr ToolResult
+		if fromJSON(result, &tr) == nil {
+			msg.ToolResult = &tr
+		}
+	}
+	return nil
+}
+
+// openDailyReport decrypts a daily report read from the database.
+func (s *Store) openDailyReport(report *DailyReport, tasks, calendars sql.NullString) error {
+	var err error
+	if report.Content, err = s.open(report.Content); err != nil {
+		return err
+	}
+	if report.Summary, err = s.open(report.Summary); err != nil {
+		return err
+	}
+	if tasks.Valid {
+		v, err := s.open(tasks.String)
+		if err != nil {
+			return err
+		}
+		_ = fromJSON(v, &report.Tasks)
+	}
+	if calendars.Valid {
+		v, err := s.open(calendars.String)
+		if err != nil {
+			return err
+		}
+		_ = fromJSON(v, &report.Calendars)
+	}
+	return nil
+}
+
+func sealWith(key *[32]byte, v string) (string, error) {
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	box := secretbox.Seal(nonce[:], []byte(v), &nonce, key)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(box), nil
+}
+
+func openWith(key *[32]byte, v string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, sealedPrefix))
+	if err != nil || len(raw) < 24 {
+		return "", fmt.Errorf("corrupt encrypted value")
+	}
+	var nonce [24]byte
+	copy(nonce[:], raw[:24])
+	plain, ok := secretbox.Open(nil, raw[24:], &nonce, key)
+	if !ok {
+		return "", ErrWrongKey
+	}
+	return string(plain), nil
+}
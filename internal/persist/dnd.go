@@ -0,0 +1,156 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// HeldNotification is a message coco started, such as a cron result,
+// that arrived while its chat was in do-not-disturb hours
+type HeldNotification struct {
+	ID        int64
+	Platform  string
+	ChannelID string
+	UserID    string
+	Message   string
+	CreatedAt time.Time
+}
+
+// DNDOverride is a do-not-disturb switch set with /dnd that wins over the
+// configured hours until Until; a zero Until lasts until switched again
+type DNDOverride struct {
+	On    bool
+	Until time.Time
+}
+
+// HoldNotification queues a notification for the chat's digest
+func (s *Store) HoldNotification(n HeldNotification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, err := s.seal(n.Message)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO dnd_held (platform, channel_id, user_id, message, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, n.Platform, n.ChannelID, n.UserID, message, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// HeldChats returns the "platform:channel_id" of every chat with held
+// notifications
+func (s *Store) HeldChats() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT DISTINCT platform || ':' || channel_id FROM dnd_held ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []string
+	for rows.Next() {
+		var chat string
+		if err := rows.Scan(&chat); err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	return chats, rows.Err()
+}
+
+// CountHeldNotifications returns how many notifications a chat has held
+func (s *Store) CountHeldNotifications(platform, channelID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM dnd_held WHERE platform = ? AND channel_id = ?`, platform, channelID).Scan(&n)
+	return n, err
+}
+
+// HeldNotifications returns a chat's held notifications, oldest first
+func (s *Store) HeldNotifications(platform, channelID string) ([]HeldNotification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, platform, channel_id, user_id, message, created_at
+		FROM dnd_held WHERE platform = ? AND channel_id = ? ORDER BY id
+	`, platform, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var held []HeldNotification
+	for rows.Next() {
+		var n HeldNotification
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.Platform, &n.ChannelID, &n.UserID, &n.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		if n.Message, err = s.open(n.Message); err != nil {
+			return nil, err
+		}
+		n.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		held = append(held, n)
+	}
+	return held, rows.Err()
+}
+
+// ClearHeldNotifications deletes a chat's held notifications up to and
+// including throughID, once they have been delivered
+func (s *Store) ClearHeldNotifications(platform, channelID string, throughID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM dnd_held WHERE platform = ? AND channel_id = ? AND id <= ?`, platform, channelID, throughID)
+	return err
+}
+
+// DNDOverride returns the /dnd switch of a chat, or nil if it follows the
+// configured hours
+func (s *Store) DNDOverride(platform, channelID string) (*DNDOverride, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var on bool
+	var until string
+	err := s.db.QueryRow(`SELECT dnd_on, until FROM dnd_overrides WHERE platform = ? AND channel_id = ?`, platform, channelID).Scan(&on, &until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := &DNDOverride{On: on}
+	if until != "" {
+		o.Until, _ = time.Parse(time.RFC3339, until)
+	}
+	return o, nil
+}
+
+// SetDNDOverride switches do-not-disturb for a chat, or returns it to the
+// configured hours when o is nil
+func (s *Store) SetDNDOverride(platform, channelID string, o *DNDOverride) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o == nil {
+		_, err := s.db.Exec(`DELETE FROM dnd_overrides WHERE platform = ? AND channel_id = ?`, platform, channelID)
+		return err
+	}
+	until := ""
+	if !o.Until.IsZero() {
+		until = o.Until.Format(time.RFC3339)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO dnd_overrides (platform, channel_id, dnd_on, until) VALUES (?, ?, ?, ?)
+		ON CONFLICT(platform, channel_id) DO UPDATE SET dnd_on = excluded.dnd_on, until = excluded.until
+	`, platform, channelID, o.On, until)
+	return err
+}
@@ -0,0 +1,81 @@
+package persist
+
+import (
+	"fmt"
+	"time"
+)
+
+// Feedback is a user's rating of one answer.
+type Feedback struct {
+	Platform  string
+	ChannelID string
+	UserID    string
+	Rating    int    // 1-5; thumbs up is 5, thumbs down is 1
+	Scale     string // "thumbs" or "scale", how the rating was asked for
+	Model     string // model that produced the answer
+	Question  string
+	Answer    string
+	CreatedAt time.Time
+}
+
+// FeedbackStat aggregates ratings for one day or one model.
+type FeedbackStat struct {
+	Key       string // date (YYYY-MM-DD) or model name
+	Count     int
+	Positive  int // ratings of 4 or 5
+	Negative  int // ratings of 1 or 2
+	AvgRating float64
+}
+
+// RecordFeedback stores one rating
+func (s *Store) RecordFeedback(f Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO feedback (platform, channel_id, user_id, rating, scale, model, question, answer, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, f.Platform, f.ChannelID, f.UserID, f.Rating, f.Scale, f.Model, f.Question, f.Answer, f.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// FeedbackStats aggregates ratings given in [since, until), grouped by "day"
+// (oldest first) or "model" (best average first).
+func (s *Store) FeedbackStats(since, until time.Time, groupBy string) ([]FeedbackStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var key, order string
+	switch groupBy {
+	case "day":
+		key, order = "substr(created_at, 1, 10)", "1"
+	case "model":
+		key, order = "COALESCE(NULLIF(model, ''), '(unknown)')", "AVG(rating) DESC, COUNT(*) DESC"
+	default:
+		return nil, fmt.Errorf("unknown feedback grouping %q", groupBy)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+key+`, COUNT(*), SUM(rating >= 4), SUM(rating <= 2), AVG(rating)
+		FROM feedback
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY 1
+		ORDER BY `+order, since.Format(time.RFC3339), until.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []FeedbackStat
+	for rows.Next() {
+		var st FeedbackStat
+		if err := rows.Scan(&st.Key, &st.Count, &st.Positive, &st.Negative, &st.AvgRating); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
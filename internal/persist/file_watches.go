@@ -0,0 +1,111 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FileWatch is a directory watched for new files; each one is handed to
+// the agent with Prompt in the chat that asked for the watch.
+type FileWatch struct {
+	ID        int64
+	UserID    string
+	Platform  string
+	ChannelID string
+	Path      string // the watched directory
+	Pattern   string // file name glob such as *.pdf; empty matches every file
+	Prompt    string // what to do with each new file
+	Fired     int    // files handled so far
+	LastFired time.Time
+	CreatedAt time.Time
+}
+
+// AddFileWatch stores a new watch and returns its ID
+func (s *Store) AddFileWatch(w FileWatch) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO file_watches (user_id, platform, channel_id, path, pattern, prompt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, w.UserID, w.Platform, w.ChannelID, w.Path, w.Pattern, w.Prompt, w.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// MarkFileWatchFired counts a file handled by a watch
+func (s *Store) MarkFileWatchFired(id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE file_watches SET fired = fired + 1, last_fired = ? WHERE id = ?`, at.Format(time.RFC3339), id)
+	return err
+}
+
+// DeleteFileWatch removes a watch
+func (s *Store) DeleteFileWatch(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM file_watches WHERE id = ?`, id)
+	return err
+}
+
+// GetFileWatch returns one watch, or nil if it does not exist
+func (s *Store) GetFileWatch(id int64) (*FileWatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watches, err := s.queryFileWatches(`
+		SELECT id, user_id, platform, channel_id, path, pattern, prompt, fired, last_fired, created_at
+		FROM file_watches
+		WHERE id = ?
+	`, id)
+	if err != nil || len(watches) == 0 {
+		return nil, err
+	}
+	return &watches[0], nil
+}
+
+// FileWatches returns watches in creation order. An empty userID returns
+// every user's watches.
+func (s *Store) FileWatches(userID string) ([]FileWatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryFileWatches(`
+		SELECT id, user_id, platform, channel_id, path, pattern, prompt, fired, last_fired, created_at
+		FROM file_watches
+		WHERE (? = '' OR user_id = ?)
+		ORDER BY id
+	`, userID, userID)
+}
+
+func (s *Store) queryFileWatches(query string, args ...any) ([]FileWatch, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []FileWatch
+	for rows.Next() {
+		var w FileWatch
+		var lastFired sql.NullString
+		var createdAt string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Platform, &w.ChannelID, &w.Path, &w.Pattern, &w.Prompt, &w.Fired, &lastFired, &createdAt); err != nil {
+			return nil, err
+		}
+		if lastFired.Valid {
+			w.LastFired, _ = time.Parse(time.RFC3339, lastFired.String)
+		}
+		w.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		watches = append(watches, w)
+	}
+	return watches, rows.Err()
+}
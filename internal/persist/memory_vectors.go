@@ -0,0 +1,262 @@
+package persist
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// vectorTimeLayout is fixed-width so updated_at values sort lexically
+const vectorTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// MemoryVector is one embedded chunk of a RAG memory item
+type MemoryVector struct {
+	DocID     string
+	MemoryID  string
+	Content   string
+	Metadata  map[string]string
+	Embedding []float32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UpsertMemoryVectors inserts or replaces chunks in a collection
+func (s *Store) UpsertMemoryVectors(collection string, vectors []MemoryVector) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO memory_vectors (collection, doc_id, memory_id, content, metadata, embedding, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, v := range vectors {
+		metadata, _ := json.Marshal(v.Metadata)
+		content, err := s.seal(v.Content)
+		if err != nil {
+			return err
+		}
+		sealedMetadata, err := s.seal(string(metadata))
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(collection, v.DocID, v.MemoryID, content, sealedMetadata,
+			encodeEmbedding(v.Embedding), formatVectorTime(v.CreatedAt), formatVectorTime(v.UpdatedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadMemoryVectors returns every chunk in a collection, used to build the search index
+func (s *Store) LoadMemoryVectors(collection string) ([]MemoryVector, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT doc_id, memory_id, content, metadata, embedding, created_at, updated_at
+		FROM memory_vectors WHERE collection = ?
+	`, collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanMemoryVectors(rows)
+}
+
+// GetMemoryVectors returns the chunks with the given doc IDs (without embeddings), keyed by doc ID
+func (s *Store) GetMemoryVectors(collection string, docIDs []string) (map[string]MemoryVector, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]MemoryVector, len(docIDs))
+	if len(docIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, 0, len(docIDs)+1)
+	args = append(args, collection)
+	for _, id := range docIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.Query(`
+		SELECT doc_id, memory_id, content, metadata, NULL, created_at, updated_at
+		FROM memory_vectors WHERE collection = ? AND doc_id IN (?`+strings.Repeat(",?", len(docIDs)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vectors, err := s.scanMemoryVectors(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vectors {
+		result[v.DocID] = v
+	}
+	return result, nil
+}
+
+// CountMemoryVectors returns the number of chunks in a collection
+func (s *Store) CountMemoryVectors(collection string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM memory_vectors WHERE collection = ?`, collection).Scan(&n)
+	return n, err
+}
+
+// TouchMemoryVector bumps updated_at on a chunk so age-based pruning keeps it
+func (s *Store) TouchMemoryVector(collection, docID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE memory_vectors SET updated_at = ? WHERE collection = ? AND doc_id = ?`,
+		formatVectorTime(at), collection, docID)
+	return err
+}
+
+// DeleteMemoryVectors removes all chunks of a memory item (or the whole collection when memoryID is empty)
+// and returns the deleted doc IDs
+func (s *Store) DeleteMemoryVectors(collection, memoryID string) ([]string, error) {
+	where := "collection = ?"
+	args := []any{collection}
+	if memoryID != "" {
+		where += " AND memory_id = ?"
+		args = append(args, memoryID)
+	}
+	return s.deleteMemoryVectorsWhere(where, args...)
+}
+
+// PruneMemoryVectors deletes chunks not updated since olderThan (when non-zero) and then the
+// least recently updated chunks beyond maxItems (when > 0). It returns the deleted doc IDs.
+func (s *Store) PruneMemoryVectors(collection string, olderThan time.Time, maxItems int) ([]string, error) {
+	var deleted []string
+	if !olderThan.IsZero() {
+		ids, err := s.deleteMemoryVectorsWhere("collection = ? AND updated_at < ?", collection, formatVectorTime(olderThan))
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, ids...)
+	}
+	if maxItems > 0 {
+		ids, err := s.deleteMemoryVectorsWhere(`collection = ? AND doc_id IN (
+			SELECT doc_id FROM memory_vectors WHERE collection = ?
+			ORDER BY updated_at DESC LIMIT -1 OFFSET ?
+		)`, collection, collection, maxItems)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, ids...)
+	}
+	return deleted, nil
+}
+
+func (s *Store) deleteMemoryVectorsWhere(where string, args ...any) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT doc_id FROM memory_vectors WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM memory_vectors WHERE `+where, args...); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+func (s *Store) scanMemoryVectors(rows *sql.Rows) ([]MemoryVector, error) {
+	var vectors []MemoryVector
+	for rows.Next() {
+		var (
+			v                    MemoryVector
+			metadata             sql.NullString
+			embedding            []byte
+			createdAt, updatedAt string
+		)
+		if err := rows.Scan(&v.DocID, &v.MemoryID, &v.Content, &metadata, &embedding, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		var err error
+		if v.Content, err = s.open(v.Content); err != nil {
+			return nil, err
+		}
+		if metadata.Valid && metadata.String != "" {
+			raw, err := s.open(metadata.String)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal([]byte(raw), &v.Metadata)
+		}
+		v.Embedding = decodeEmbedding(embedding)
+		v.CreatedAt, _ = time.Parse(vectorTimeLayout, createdAt)
+		v.UpdatedAt, _ = time.Parse(vectorTimeLayout, updatedAt)
+		vectors = append(vectors, v)
+	}
+	return vectors, rows.Err()
+}
+
+func formatVectorTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(vectorTimeLayout)
+}
+
+func encodeEmbedding(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	if len(buf) == 0 {
+		return nil
+	}
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec
+}
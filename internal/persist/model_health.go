@@ -0,0 +1,83 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ModelHealth is a model's failure record as last seen by the router.
+type ModelHealth struct {
+	Model               string
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	LastError           string
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	CooldownUntil       time.Time
+	QuarantineUntil     time.Time
+}
+
+// SaveModelHealth replaces the stored health of h.Model
+func (s *Store) SaveModelHealth(h ModelHealth) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO model_health (model, successes, failures, consecutive_failures, last_error,
+			last_success, last_failure, cooldown_until, quarantine_until)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(model) DO UPDATE SET
+			successes = excluded.successes,
+			failures = excluded.failures,
+			consecutive_failures = excluded.consecutive_failures,
+			last_error = excluded.last_error,
+			last_success = excluded.last_success,
+			last_failure = excluded.last_failure,
+			cooldown_until = excluded.cooldown_until,
+			quarantine_until = excluded.quarantine_until
+	`, h.Model, h.Successes, h.Failures, h.ConsecutiveFailures, h.LastError,
+		nullTime(h.LastSuccess), nullTime(h.LastFailure), nullTime(h.CooldownUntil), nullTime(h.QuarantineUntil))
+	return err
+}
+
+// ListModelHealth returns the stored health of every model
+func (s *Store) ListModelHealth() ([]ModelHealth, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT model, successes, failures, consecutive_failures, last_error,
+			last_success, last_failure, cooldown_until, quarantine_until
+		FROM model_health
+		ORDER BY model
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ModelHealth
+	for rows.Next() {
+		var h ModelHealth
+		var lastSuccess, lastFailure, cooldown, quarantine sql.NullString
+		if err := rows.Scan(&h.Model, &h.Successes, &h.Failures, &h.ConsecutiveFailures, &h.LastError,
+			&lastSuccess, &lastFailure, &cooldown, &quarantine); err != nil {
+			return nil, err
+		}
+		h.LastSuccess = parseNullTime(lastSuccess)
+		h.LastFailure = parseNullTime(lastFailure)
+		h.CooldownUntil = parseNullTime(cooldown)
+		h.QuarantineUntil = parseNullTime(quarantine)
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func parseNullTime(v sql.NullString) time.Time {
+	if !v.Valid {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, v.String)
+	return t
+}
@@ -0,0 +1,55 @@
+package persist
+
+// ModelUsage is what one model used on one day.
+type ModelUsage struct {
+	Day          string // YYYY-MM-DD, local time
+	Model        string
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64 // in the unit of the model's input_price and output_price
+}
+
+// AddModelUsage adds one request to the model's total for the day
+func (s *Store) AddModelUsage(day, model string, inputTokens, outputTokens int, cost float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO model_usage (day, model, requests, input_tokens, output_tokens, cost)
+		VALUES (?, ?, 1, ?, ?, ?)
+		ON CONFLICT(day, model) DO UPDATE SET
+			requests = requests + 1,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cost = cost + excluded.cost
+	`, day, model, inputTokens, outputTokens, cost)
+	return err
+}
+
+// ModelUsageOn returns every model's usage on the day, costliest first
+func (s *Store) ModelUsageOn(day string) ([]ModelUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT day, model, requests, input_tokens, output_tokens, cost
+		FROM model_usage
+		WHERE day = ?
+		ORDER BY cost DESC, model
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ModelUsage
+	for rows.Next() {
+		var u ModelUsage
+		if err := rows.Scan(&u.Day, &u.Model, &u.Requests, &u.InputTokens, &u.OutputTokens, &u.Cost); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
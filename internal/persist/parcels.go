@@ -0,0 +1,142 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Parcel is a tracking number whose delivery status is checked on a schedule.
+type Parcel struct {
+	ID          int64
+	UserID      string
+	Platform    string // where to send status changes
+	ChannelID   string
+	Number      string
+	Carrier     string // carrier code; empty lets the tracking service detect it
+	Phone       string // last digits of the recipient's phone, required by some carriers
+	Label       string // what is in the parcel, e.g. "键盘"
+	Status      string
+	LastEvent   string
+	LastEventAt time.Time
+	LastChecked time.Time
+	LastError   string
+	Active      bool // false once delivered, returned or removed
+	CreatedAt   time.Time
+}
+
+// AddParcel stores a new parcel and returns its ID
+func (s *Store) AddParcel(p Parcel) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO parcels (user_id, platform, channel_id, number, carrier, phone, label, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+	`, p.UserID, p.Platform, p.ChannelID, p.Number, p.Carrier, p.Phone, p.Label, p.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateParcel saves the result of a check and the parcel's status
+func (s *Store) UpdateParcel(p Parcel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE parcels
+		SET carrier = ?, phone = ?, label = ?, status = ?, last_event = ?, last_event_at = ?, last_checked = ?, last_error = ?, active = ?
+		WHERE id = ?
+	`, p.Carrier, p.Phone, p.Label, p.Status, p.LastEvent, nullTime(p.LastEventAt), nullTime(p.LastChecked), p.LastError, p.Active, p.ID)
+	return err
+}
+
+// DeleteParcel removes a parcel
+func (s *Store) DeleteParcel(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM parcels WHERE id = ?`, id)
+	return err
+}
+
+// GetParcel returns one parcel, or nil if it does not exist
+func (s *Store) GetParcel(id int64) (*Parcel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parcels, err := s.queryParcels(`
+		SELECT id, user_id, platform, channel_id, number, carrier, phone, label, status, last_event, last_event_at, last_checked, last_error, active, created_at
+		FROM parcels
+		WHERE id = ?
+	`, id)
+	if err != nil || len(parcels) == 0 {
+		return nil, err
+	}
+	return &parcels[0], nil
+}
+
+// FindParcel returns the user's latest parcel with the tracking number, or
+// nil if there is none
+func (s *Store) FindParcel(userID, number string) (*Parcel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parcels, err := s.queryParcels(`
+		SELECT id, user_id, platform, channel_id, number, carrier, phone, label, status, last_event, last_event_at, last_checked, last_error, active, created_at
+		FROM parcels
+		WHERE user_id = ? AND number = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID, number)
+	if err != nil || len(parcels) == 0 {
+		return nil, err
+	}
+	return &parcels[0], nil
+}
+
+// Parcels returns parcels in creation order. An empty userID returns every
+// user's parcels.
+func (s *Store) Parcels(userID string, activeOnly bool) ([]Parcel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryParcels(`
+		SELECT id, user_id, platform, channel_id, number, carrier, phone, label, status, last_event, last_event_at, last_checked, last_error, active, created_at
+		FROM parcels
+		WHERE (? = '' OR user_id = ?) AND (? = 0 OR active = 1)
+		ORDER BY id
+	`, userID, userID, activeOnly)
+}
+
+func (s *Store) queryParcels(query string, args ...any) ([]Parcel, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parcels []Parcel
+	for rows.Next() {
+		var p Parcel
+		var lastEventAt, lastChecked sql.NullString
+		var createdAt string
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Platform, &p.ChannelID, &p.Number, &p.Carrier, &p.Phone, &p.Label,
+			&p.Status, &p.LastEvent, &lastEventAt, &lastChecked, &p.LastError, &p.Active, &createdAt); err != nil {
+			return nil, err
+		}
+		if lastEventAt.Valid {
+			p.LastEventAt, _ = time.Parse(time.RFC3339, lastEventAt.String)
+		}
+		if lastChecked.Valid {
+			p.LastChecked, _ = time.Parse(time.RFC3339, lastChecked.String)
+		}
+		p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		parcels = append(parcels, p)
+	}
+	return parcels, rows.Err()
+}
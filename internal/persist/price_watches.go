@@ -0,0 +1,179 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PriceWatch is a product page whose price is checked on a schedule.
+type PriceWatch struct {
+	ID          int64
+	UserID      string
+	Platform    string // where to send the alert
+	ChannelID   string
+	URL         string
+	Title       string
+	TargetPrice float64
+	Currency    string
+	LastPrice   float64 // 0 until the first successful check
+	LastChecked time.Time
+	LastError   string
+	NotifiedAt  time.Time // when the alert for the current dip was sent
+	Active      bool
+	CreatedAt   time.Time
+}
+
+// PricePoint is one recorded price.
+type PricePoint struct {
+	Price     float64
+	CheckedAt time.Time
+}
+
+// AddPriceWatch stores a new watch and returns its ID
+func (s *Store) AddPriceWatch(w PriceWatch) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO price_watches (user_id, platform, channel_id, url, title, target_price, currency, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+	`, w.UserID, w.Platform, w.ChannelID, w.URL, w.Title, w.TargetPrice, w.Currency, w.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdatePriceWatch saves the result of a check and the watch's status
+func (s *Store) UpdatePriceWatch(w PriceWatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE price_watches
+		SET title = ?, target_price = ?, currency = ?, last_price = ?, last_checked = ?, last_error = ?, notified_at = ?, active = ?
+		WHERE id = ?
+	`, w.Title, w.TargetPrice, w.Currency, w.LastPrice, nullTime(w.LastChecked), w.LastError, nullTime(w.NotifiedAt), w.Active, w.ID)
+	return err
+}
+
+// DeletePriceWatch removes a watch and its price history
+func (s *Store) DeletePriceWatch(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`DELETE FROM price_history WHERE watch_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM price_watches WHERE id = ?`, id)
+	return err
+}
+
+// GetPriceWatch returns one watch, or nil if it does not exist
+func (s *Store) GetPriceWatch(id int64) (*PriceWatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watches, err := s.queryPriceWatches(`
+		SELECT id, user_id, platform, channel_id, url, title, target_price, currency, last_price, last_checked, last_error, notified_at, active, created_at
+		FROM price_watches
+		WHERE id = ?
+	`, id)
+	if err != nil || len(watches) == 0 {
+		return nil, err
+	}
+	return &watches[0], nil
+}
+
+// PriceWatches returns watches in creation order. An empty userID returns
+// every user's watches.
+func (s *Store) PriceWatches(userID string, activeOnly bool) ([]PriceWatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryPriceWatches(`
+		SELECT id, user_id, platform, channel_id, url, title, target_price, currency, last_price, last_checked, last_error, notified_at, active, created_at
+		FROM price_watches
+		WHERE (? = '' OR user_id = ?) AND (? = 0 OR active = 1)
+		ORDER BY id
+	`, userID, userID, activeOnly)
+}
+
+// AddPricePoint records a checked price
+func (s *Store) AddPricePoint(watchID int64, p PricePoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`INSERT INTO price_history (watch_id, price, checked_at) VALUES (?, ?, ?)`,
+		watchID, p.Price, p.CheckedAt.Format(time.RFC3339))
+	return err
+}
+
+// PriceHistory returns the latest limit prices of a watch, oldest first
+func (s *Store) PriceHistory(watchID int64, limit int) ([]PricePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT price, checked_at FROM (
+			SELECT id, price, checked_at FROM price_history
+			WHERE watch_id = ?
+			ORDER BY checked_at DESC, id DESC
+			LIMIT ?
+		) ORDER BY checked_at, id
+	`, watchID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []PricePoint
+	for rows.Next() {
+		var p PricePoint
+		var checkedAt string
+		if err := rows.Scan(&p.Price, &checkedAt); err != nil {
+			return nil, err
+		}
+		p.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+func (s *Store) queryPriceWatches(query string, args ...any) ([]PriceWatch, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []PriceWatch
+	for rows.Next() {
+		var w PriceWatch
+		var lastChecked, notifiedAt sql.NullString
+		var createdAt string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Platform, &w.ChannelID, &w.URL, &w.Title, &w.TargetPrice, &w.Currency,
+			&w.LastPrice, &lastChecked, &w.LastError, &notifiedAt, &w.Active, &createdAt); err != nil {
+			return nil, err
+		}
+		if lastChecked.Valid {
+			w.LastChecked, _ = time.Parse(time.RFC3339, lastChecked.String)
+		}
+		if notifiedAt.Valid {
+			w.NotifiedAt, _ = time.Parse(time.RFC3339, notifiedAt.String)
+		}
+		w.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		watches = append(watches, w)
+	}
+	return watches, rows.Err()
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,54 @@
+package persist
+
+import (
+	"fmt"
+	"time"
+)
+
+// retentionTables maps the retention categories kept in the database to the
+// table and time column they are pruned by.
+var retentionTables = map[string]struct {
+	table, column string
+	format        func(time.Time) string
+}{
+	"messages": {"messages", "created_at", formatRFC3339},
+	"memories": {"memory_vectors", "updated_at", formatVectorTime},
+	"reports":  {"daily_reports", "created_at", formatRFC3339},
+	"feedback": {"feedback", "created_at", formatRFC3339},
+	"metrics":  {"tool_metrics", "created_at", formatRFC3339},
+}
+
+func formatRFC3339(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// CountBefore returns how many rows of a retention category are older than before
+func (s *Store) CountBefore(category string, before time.Time) (int, error) {
+	t, ok := retentionTables[category]
+	if !ok {
+		return 0, fmt.Errorf("unknown retention category %q", category)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM `+t.table+` WHERE `+t.column+` < ?`, t.format(before)).Scan(&n)
+	return n, err
+}
+
+// DeleteBefore deletes the rows of a retention category older than before and returns how many
+func (s *Store) DeleteBefore(category string, before time.Time) (int, error) {
+	t, ok := retentionTables[category]
+	if !ok {
+		return 0, fmt.Errorf("unknown retention category %q", category)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`DELETE FROM `+t.table+` WHERE `+t.column+` < ?`, t.format(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
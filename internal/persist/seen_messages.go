@@ -0,0 +1,27 @@
+package persist
+
+import (
+	"time"
+)
+
+// ClaimMessage records a platform message ID and reports whether it is the
+// first delivery. Message IDs are only unique within a chat on some
+// platforms, so the channel is part of the key. IDs seen longer than ttl
+// ago are forgotten.
+func (s *Store) ClaimMessage(platform, channelID, msgID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, err := s.db.Exec(`DELETE FROM seen_messages WHERE seen_at < ?`, now.Add(-ttl).Format(time.RFC3339)); err != nil {
+		return false, err
+	}
+	res, err := s.db.Exec(`
+		INSERT OR IGNORE INTO seen_messages (platform, channel_id, msg_id, seen_at) VALUES (?, ?, ?, ?)
+	`, platform, channelID, msgID, now.Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -6,16 +6,22 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// SchemaVersion is the database layout this build writes, stored in
+// PRAGMA user_version. Version 0 is a database from before versioning.
+const SchemaVersion = 2
+
 // Store handles persistence of conversation history and daily reports using SQLite
 type Store struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db  *sql.DB
+	mu  sync.RWMutex
+	key *[32]byte // set by Unlock; nil stores plaintext
 }
 
 // NewStore creates a new SQLite-backed persistence store at the given path
@@ -82,12 +88,363 @@ func (s *Store) init() error {
 			UNIQUE(date, user_id)
 		);
 
+		CREATE TABLE IF NOT EXISTS tool_metrics (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			tool_name     TEXT NOT NULL,
+			duration_ms   INTEGER NOT NULL,
+			result_bytes  INTEGER NOT NULL,
+			is_error      INTEGER NOT NULL DEFAULT 0,
+			created_at    TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS memory_vectors (
+			collection  TEXT NOT NULL,
+			doc_id      TEXT NOT NULL,
+			memory_id   TEXT NOT NULL,
+			content     TEXT NOT NULL,
+			metadata    TEXT,
+			embedding   BLOB NOT NULL,
+			created_at  TEXT NOT NULL,
+			updated_at  TEXT NOT NULL,
+			PRIMARY KEY (collection, doc_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS feedback (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			platform    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			user_id     TEXT NOT NULL,
+			rating      INTEGER NOT NULL,
+			scale       TEXT NOT NULL,
+			model       TEXT,
+			question    TEXT,
+			answer      TEXT,
+			created_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS time_entries (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id     TEXT NOT NULL,
+			category    TEXT NOT NULL,
+			task        TEXT NOT NULL,
+			started_at  TEXT NOT NULL,
+			ended_at    TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS price_watches (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id       TEXT NOT NULL,
+			platform      TEXT NOT NULL,
+			channel_id    TEXT NOT NULL,
+			url           TEXT NOT NULL,
+			title         TEXT NOT NULL DEFAULT '',
+			target_price  REAL NOT NULL,
+			currency      TEXT NOT NULL DEFAULT '',
+			last_price    REAL NOT NULL DEFAULT 0,
+			last_checked  TEXT,
+			last_error    TEXT NOT NULL DEFAULT '',
+			notified_at   TEXT,
+			active        INTEGER NOT NULL DEFAULT 1,
+			created_at    TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS price_history (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			watch_id    INTEGER NOT NULL,
+			price       REAL NOT NULL,
+			checked_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS parcels (
+			id             INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id        TEXT NOT NULL,
+			platform       TEXT NOT NULL,
+			channel_id     TEXT NOT NULL,
+			number         TEXT NOT NULL,
+			carrier        TEXT NOT NULL DEFAULT '',
+			phone          TEXT NOT NULL DEFAULT '',
+			label          TEXT NOT NULL DEFAULT '',
+			status         TEXT NOT NULL DEFAULT '',
+			last_event     TEXT NOT NULL DEFAULT '',
+			last_event_at  TEXT,
+			last_checked   TEXT,
+			last_error     TEXT NOT NULL DEFAULT '',
+			active         INTEGER NOT NULL DEFAULT 1,
+			created_at     TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS trips (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id       TEXT NOT NULL,
+			platform      TEXT NOT NULL,
+			channel_id    TEXT NOT NULL,
+			kind          TEXT NOT NULL,
+			number        TEXT NOT NULL,
+			departure     TEXT NOT NULL,
+			arrival       TEXT,
+			origin        TEXT NOT NULL DEFAULT '',
+			destination   TEXT NOT NULL DEFAULT '',
+			seat          TEXT NOT NULL DEFAULT '',
+			confirmation  TEXT NOT NULL DEFAULT '',
+			reminded_at   TEXT,
+			active        INTEGER NOT NULL DEFAULT 1,
+			created_at    TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS briefing_prefs (
+			user_id     TEXT PRIMARY KEY,
+			platform    TEXT NOT NULL DEFAULT '',
+			channel_id  TEXT NOT NULL DEFAULT '',
+			subscribed  INTEGER NOT NULL DEFAULT 0,
+			followed    TEXT NOT NULL DEFAULT '[]',
+			muted       TEXT NOT NULL DEFAULT '[]',
+			last_sent   TEXT,
+			updated_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS run_traces (
+			id                INTEGER PRIMARY KEY AUTOINCREMENT,
+			conversation_key  TEXT NOT NULL,
+			data              TEXT NOT NULL,
+			created_at        TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS model_usage (
+			day            TEXT NOT NULL,
+			model          TEXT NOT NULL,
+			requests       INTEGER NOT NULL DEFAULT 0,
+			input_tokens   INTEGER NOT NULL DEFAULT 0,
+			output_tokens  INTEGER NOT NULL DEFAULT 0,
+			cost           REAL NOT NULL DEFAULT 0,
+			PRIMARY KEY (day, model)
+		);
+
+		CREATE TABLE IF NOT EXISTS model_health (
+			model                 TEXT PRIMARY KEY,
+			successes             INTEGER NOT NULL DEFAULT 0,
+			failures              INTEGER NOT NULL DEFAULT 0,
+			consecutive_failures  INTEGER NOT NULL DEFAULT 0,
+			last_error            TEXT NOT NULL DEFAULT '',
+			last_success          TEXT,
+			last_failure          TEXT,
+			cooldown_until        TEXT,
+			quarantine_until      TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS artifacts (
+			id            TEXT PRIMARY KEY,
+			tool          TEXT NOT NULL,
+			conversation  TEXT NOT NULL DEFAULT '',
+			content       TEXT NOT NULL,
+			created_at    TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS file_watches (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id     TEXT NOT NULL,
+			platform    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			path        TEXT NOT NULL,
+			pattern     TEXT NOT NULL DEFAULT '',
+			prompt      TEXT NOT NULL,
+			fired       INTEGER NOT NULL DEFAULT 0,
+			last_fired  TEXT,
+			created_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS tasks (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id       TEXT NOT NULL,
+			platform      TEXT NOT NULL DEFAULT '',
+			channel_id    TEXT NOT NULL DEFAULT '',
+			title         TEXT NOT NULL,
+			description   TEXT NOT NULL DEFAULT '',
+			status        TEXT NOT NULL DEFAULT 'pending',
+			priority      TEXT NOT NULL DEFAULT '',
+			project       TEXT NOT NULL DEFAULT '',
+			due_date      TEXT NOT NULL DEFAULT '',
+			reminder_job  TEXT NOT NULL DEFAULT '',
+			created_at    TEXT NOT NULL,
+			updated_at    TEXT NOT NULL,
+			completed_at  TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS contacts (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id       TEXT NOT NULL,
+			name          TEXT NOT NULL COLLATE NOCASE,
+			platform      TEXT NOT NULL,
+			channel_id    TEXT NOT NULL,
+			recipient_id  TEXT NOT NULL DEFAULT '',
+			kind          TEXT NOT NULL DEFAULT 'person',
+			note          TEXT NOT NULL DEFAULT '',
+			created_at    TEXT NOT NULL,
+			updated_at    TEXT NOT NULL,
+			UNIQUE (user_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS broadcasts (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			message      TEXT NOT NULL,
+			tags         TEXT NOT NULL DEFAULT '',
+			source       TEXT NOT NULL,
+			platform     TEXT NOT NULL DEFAULT '',
+			channel_id   TEXT NOT NULL DEFAULT '',
+			user_id      TEXT NOT NULL DEFAULT '',
+			status       TEXT NOT NULL DEFAULT 'pending',
+			total        INTEGER NOT NULL DEFAULT 0,
+			sent         INTEGER NOT NULL DEFAULT 0,
+			report       TEXT NOT NULL DEFAULT '',
+			created_at   TEXT NOT NULL,
+			finished_at  TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS active_topics (
+			conv_key    TEXT PRIMARY KEY,
+			topic       TEXT NOT NULL,
+			updated_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS dnd_held (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			platform    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			user_id     TEXT NOT NULL DEFAULT '',
+			message     TEXT NOT NULL,
+			created_at  TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS dnd_overrides (
+			platform    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			dnd_on      INTEGER NOT NULL,
+			until       TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (platform, channel_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS seen_messages (
+			platform    TEXT NOT NULL,
+			channel_id  TEXT NOT NULL,
+			msg_id      TEXT NOT NULL,
+			seen_at     TEXT NOT NULL,
+			PRIMARY KEY (platform, channel_id, msg_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS store_encryption (
+			id              INTEGER PRIMARY KEY CHECK (id = 1),
+			salt            BLOB NOT NULL,
+			check_value     TEXT NOT NULL,
+			sealed_version  INTEGER NOT NULL DEFAULT 1
+		);
+
 		CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id);
 		CREATE INDEX IF NOT EXISTS idx_messages_created ON messages(created_at);
 		CREATE INDEX IF NOT EXISTS idx_dailyreport_date ON daily_reports(date);
 		CREATE INDEX IF NOT EXISTS idx_dailyreport_user ON daily_reports(user_id);
+		CREATE INDEX IF NOT EXISTS idx_toolmetrics_created ON tool_metrics(created_at);
+		CREATE INDEX IF NOT EXISTS idx_memvectors_memory ON memory_vectors(collection, memory_id);
+		CREATE INDEX IF NOT EXISTS idx_memvectors_updated ON memory_vectors(collection, updated_at);
+		CREATE INDEX IF NOT EXISTS idx_feedback_created ON feedback(created_at);
+		CREATE INDEX IF NOT EXISTS idx_timeentries_user ON time_entries(user_id, started_at);
+		CREATE INDEX IF NOT EXISTS idx_pricewatches_user ON price_watches(user_id);
+		CREATE INDEX IF NOT EXISTS idx_pricehistory_watch ON price_history(watch_id, checked_at);
+		CREATE INDEX IF NOT EXISTS idx_parcels_user ON parcels(user_id, number);
+		CREATE INDEX IF NOT EXISTS idx_trips_user ON trips(user_id, departure);
+		CREATE INDEX IF NOT EXISTS idx_runtraces_created ON run_traces(created_at);
+		CREATE INDEX IF NOT EXISTS idx_artifacts_created ON artifacts(created_at);
+		CREATE INDEX IF NOT EXISTS idx_filewatches_user ON file_watches(user_id);
+		CREATE INDEX IF NOT EXISTS idx_tasks_user ON tasks(user_id, status);
+		CREATE INDEX IF NOT EXISTS idx_broadcasts_status ON broadcasts(status, id);
+		CREATE INDEX IF NOT EXISTS idx_dnd_held_chat ON dnd_held(platform, channel_id, id);
+		CREATE INDEX IF NOT EXISTS idx_seen_messages_seen ON seen_messages(seen_at);
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version < 2 {
+		// v2: store_encryption records which sealedColumns its rows were
+		// sealed with; a database encrypted before has version 1.
+		has, err := s.hasColumn("store_encryption", "sealed_version")
+		if err != nil {
+			return err
+		}
+		if !has {
+			if _, err := s.db.Exec(`ALTER TABLE store_encryption ADD COLUMN sealed_version INTEGER NOT NULL DEFAULT 1`); err != nil {
+				return fmt.Errorf("failed to migrate store_encryption: %w", err)
+			}
+		}
+	}
+	if version < SchemaVersion {
+		// Otherwise the CREATE IF NOT EXISTS statements above are the migration.
+		if _, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+			return fmt.Errorf("failed to set schema version: %w", err)
+		}
+		log.Printf("[PERSIST] Upgraded database schema v%d -> v%d", version, SchemaVersion)
+	}
+	return nil
+}
+
+// hasColumn reports whether table has the named column.
+func (s *Store) hasColumn(table, column string) (bool, error) {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// SchemaVersion returns the schema version recorded in the database.
+func (s *Store) SchemaVersion() (int, error) {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// IntegrityCheck runs SQLite's quick check and returns its findings as an
+// error, or nil when the database is sound.
+func (s *Store) IntegrityCheck() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("PRAGMA quick_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check: %s", strings.Join(problems, "; "))
+	}
+	return nil
 }
 
 // GetOrCreateConversation gets an existing conversation or creates a new one
@@ -186,14 +543,8 @@ func (s *Store) getMessagesInternal(conversationID int64) ([]Message, error) {
 			return nil, err
 		}
 
-		if toolCalls.Valid {
-			_ = fromJSON(toolCalls.String, &msg.ToolCalls)
-		}
-		if toolResult.Valid {
-			var tr ToolResult
-			if fromJSON(toolResult.String, &tr) == nil {
-				msg.ToolResult = &tr
-			}
+		if err := s.openMessage(&msg, toolCalls, toolResult); err != nil {
+			return nil, err
 		}
 		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			msg.CreatedAt = t
@@ -250,6 +601,37 @@ func (s *Store) LoadAllActiveConversations() ([]*Conversation, error) {
 	return conversations, rows.Err()
 }
 
+// ConversationsSince lists the active conversations updated since the
+// given time, most recent first, without their messages
+func (s *Store) ConversationsSince(since time.Time) ([]*Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, platform, channel_id, user_id, created_at, updated_at
+		FROM conversations
+		WHERE is_active = 1 AND updated_at >= ?
+		ORDER BY updated_at DESC
+	`, since.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []*Conversation
+	for rows.Next() {
+		conv := Conversation{IsActive: true}
+		var createdAt, updatedAt string
+		if err := rows.Scan(&conv.ID, &conv.Platform, &conv.ChannelID, &conv.UserID, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		conv.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		conv.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		conversations = append(conversations, &conv)
+	}
+	return conversations, rows.Err()
+}
+
 // AddMessage adds a message to a conversation
 func (s *Store) AddMessage(conversationID int64, msg Message) error {
 	s.mu.Lock()
@@ -257,10 +639,23 @@ func (s *Store) AddMessage(conversationID int64, msg Message) error {
 
 	now := time.Now().Format(time.RFC3339)
 
-	_, err := s.db.Exec(`
+	content, err := s.seal(msg.Content)
+	if err != nil {
+		return err
+	}
+	toolCalls, err := s.seal(toJSON(msg.ToolCalls))
+	if err != nil {
+		return err
+	}
+	toolResult, err := s.seal(toJSON(msg.ToolResult))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
 		INSERT INTO messages (conversation_id, role, content, tool_calls, tool_result, created_at)
 		VALUES (?, ?, ?, ?, ?, ?)
-	`, conversationID, msg.Role, msg.Content, toJSON(msg.ToolCalls), toJSON(msg.ToolResult), now)
+	`, conversationID, msg.Role, content, toolCalls, toolResult, now)
 	if err != nil {
 		return err
 	}
@@ -278,14 +673,21 @@ func (s *Store) SaveDailyReport(report *DailyReport) error {
 
 	now := time.Now().Format(time.RFC3339)
 
+	sealed := make([]string, 4)
+	for i, v := range []string{report.Content, report.Summary, toJSON(report.Tasks), toJSON(report.Calendars)} {
+		var err error
+		if sealed[i], err = s.seal(v); err != nil {
+			return err
+		}
+	}
+
 	_, err := s.db.Exec(`
 		INSERT INTO daily_reports (date, user_id, content, summary, tasks, calendars, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(date, user_id) DO UPDATE SET
 			content=excluded.content, summary=excluded.summary,
 			tasks=excluded.tasks, calendars=excluded.calendars, created_at=excluded.created_at
-	`, report.Date, report.UserID, report.Content, report.Summary,
-		toJSON(report.Tasks), toJSON(report.Calendars), now)
+	`, report.Date, report.UserID, sealed[0], sealed[1], sealed[2], sealed[3], now)
 	return err
 }
 
@@ -310,11 +712,8 @@ func (s *Store) GetDailyReport(date, userID string) (*DailyReport, error) {
 		return nil, err
 	}
 
-	if tasks.Valid {
-		_ = fromJSON(tasks.String, &report.Tasks)
-	}
-	if calendars.Valid {
-		_ = fromJSON(calendars.String, &report.Calendars)
+	if err := s.openDailyReport(&report, tasks, calendars); err != nil {
+		return nil, err
 	}
 	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 		report.CreatedAt = t
@@ -346,11 +745,8 @@ func (s *Store) GetLatestDailyReport(userID string) (*DailyReport, error) {
 		return nil, err
 	}
 
-	if tasks.Valid {
-		_ = fromJSON(tasks.String, &report.Tasks)
-	}
-	if calendars.Valid {
-		_ = fromJSON(calendars.String, &report.Calendars)
+	if err := s.openDailyReport(&report, tasks, calendars); err != nil {
+		return nil, err
 	}
 	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 		report.CreatedAt = t
@@ -392,11 +788,8 @@ func (s *Store) ListDailyReports(userID string, limit int) ([]*DailyReport, erro
 			return nil, err
 		}
 
-		if tasks.Valid {
-			_ = fromJSON(tasks.String, &report.Tasks)
-		}
-		if calendars.Valid {
-			_ = fromJSON(calendars.String, &report.Calendars)
+		if err := s.openDailyReport(&report, tasks, calendars); err != nil {
+			return nil, err
 		}
 		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			report.CreatedAt = t
@@ -455,21 +848,28 @@ func (s *Store) SearchMessages(userID, keyword string, limit int) ([]Message, er
 		limit = 50
 	}
 
+	// Sealed content cannot be matched in SQL, so an encrypted store
+	// decrypts the user's messages and matches them here. The user's topic
+	// threads ("<id>#<topic>") sort between "<id>#" and "<id>$".
+	match, args := "AND m.content LIKE ?", []any{userID, userID + TopicSeparator, userID + "$", "%" + keyword + "%", limit}
+	if s.key != nil {
+		match, args = "", []any{userID, userID + TopicSeparator, userID + "$", -1}
+	}
 	rows, err := s.db.Query(`
 		SELECT m.id, m.role, m.content, m.tool_calls, m.tool_result, m.created_at
 		FROM messages m
 		JOIN conversations c ON m.conversation_id = c.id
-		WHERE c.user_id = ? AND m.content LIKE ?
+		WHERE (c.user_id = ? OR (c.user_id >= ? AND c.user_id < ?)) `+match+`
 		ORDER BY m.created_at DESC
 		LIMIT ?
-	`, userID, "%"+keyword+"%", limit)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var messages []Message
-	for rows.Next() {
+	for rows.Next() && len(messages) < limit {
 		var msg Message
 		var toolCalls, toolResult sql.NullString
 		var createdAt string
@@ -479,14 +879,11 @@ func (s *Store) SearchMessages(userID, keyword string, limit int) ([]Message, er
 			return nil, err
 		}
 
-		if toolCalls.Valid {
-			_ = fromJSON(toolCalls.String, &msg.ToolCalls)
+		if err := s.openMessage(&msg, toolCalls, toolResult); err != nil {
+			return nil, err
 		}
-		if toolResult.Valid {
-			var tr ToolResult
-			if fromJSON(toolResult.String, &tr) == nil {
-				msg.ToolResult = &tr
-			}
+		if s.key != nil && !strings.Contains(strings.ToLower(msg.Content), strings.ToLower(keyword)) {
+			continue
 		}
 		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			msg.CreatedAt = t
@@ -498,6 +895,61 @@ func (s *Store) SearchMessages(userID, keyword string, limit int) ([]Message, er
 	return messages, rows.Err()
 }
 
+// DayMessage is a message with the conversation it belongs to.
+type DayMessage struct {
+	Conversation string // platform:channel_id:user_id
+	Message
+}
+
+// MessagesBetween returns the user and assistant messages of every
+// conversation sent in [since, until), oldest first, at most limit.
+func (s *Store) MessagesBetween(since, until time.Time, limit int) ([]DayMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = 500
+	}
+	rows, err := s.db.Query(`
+		SELECT c.platform, c.channel_id, c.user_id, m.id, m.role, m.content, m.tool_calls, m.tool_result, m.created_at
+		FROM messages m
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE m.role IN ('user', 'assistant') AND m.created_at >= ? AND m.created_at < ?
+		ORDER BY m.created_at ASC
+		LIMIT ?
+	`, since.Format(time.RFC3339), until.Format(time.RFC3339), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []DayMessage
+	for rows.Next() {
+		var msg DayMessage
+		var platform, channelID, userID string
+		var toolCalls, toolResult sql.NullString
+		var createdAt string
+
+		err := rows.Scan(&platform, &channelID, &userID, &msg.ID, &msg.Role, &msg.Content, &toolCalls, &toolResult, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.openMessage(&msg.Message, toolCalls, toolResult); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			msg.CreatedAt = t
+		}
+		msg.Conversation = ConversationKey(platform, channelID, userID)
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
 // Close closes the database connection
 func (s *Store) Close() error {
 	return s.db.Close()
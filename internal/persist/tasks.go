@@ -0,0 +1,163 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Task statuses, shared with the daily report's TaskItem.
+const (
+	TaskPending    = "pending"
+	TaskInProgress = "in_progress"
+	TaskCompleted  = "completed"
+)
+
+// Task is a to-do the assistant keeps for the user. Tasks of the "default"
+// user, such as those taken from the daily report, belong to everyone.
+type Task struct {
+	ID          int64
+	UserID      string
+	Platform    string // where the due reminder is sent
+	ChannelID   string
+	Title       string
+	Description string
+	Status      string // TaskPending, TaskInProgress or TaskCompleted
+	Priority    string // "low" | "medium" | "high"
+	Project     string // long-term project the task belongs to, if any
+	DueDate     string // "2006-01-02" or "2006-01-02 15:04"
+	ReminderJob string // ID of the one-shot cron job for the due date
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// AddTask stores a new task and returns its ID
+func (s *Store) AddTask(t Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.Status == "" {
+		t.Status = TaskPending
+	}
+	if err := s.sealTask(&t); err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO tasks (user_id, platform, channel_id, title, description, status, priority, project, due_date, reminder_job, created_at, updated_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.UserID, t.Platform, t.ChannelID, t.Title, t.Description, t.Status, t.Priority, t.Project, t.DueDate, t.ReminderJob,
+		t.CreatedAt.Format(time.RFC3339), now.Format(time.RFC3339), nullTime(t.CompletedAt))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateTask saves every field of the task but its owner and creation time
+func (s *Store) UpdateTask(t Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.sealTask(&t); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		UPDATE tasks
+		SET platform = ?, channel_id = ?, title = ?, description = ?, status = ?, priority = ?, project = ?, due_date = ?, reminder_job = ?, updated_at = ?, completed_at = ?
+		WHERE id = ?
+	`, t.Platform, t.ChannelID, t.Title, t.Description, t.Status, t.Priority, t.Project, t.DueDate, t.ReminderJob,
+		time.Now().Format(time.RFC3339), nullTime(t.CompletedAt), t.ID)
+	return err
+}
+
+// DeleteTask removes a task
+func (s *Store) DeleteTask(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	return err
+}
+
+// GetTask returns one task, or nil if it does not exist
+func (s *Store) GetTask(id int64) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks, err := s.queryTasks(`
+		SELECT id, user_id, platform, channel_id, title, description, status, priority, project, due_date, reminder_job, created_at, updated_at, completed_at
+		FROM tasks
+		WHERE id = ?
+	`, id)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// Tasks returns the user's tasks and the default user's, open ones first
+// by due date, then finished ones completed since doneSince. A zero
+// doneSince leaves finished tasks out; an empty userID returns every
+// user's tasks.
+func (s *Store) Tasks(userID string, doneSince time.Time) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	since := ""
+	if !doneSince.IsZero() {
+		since = doneSince.Format(time.RFC3339)
+	}
+	return s.queryTasks(`
+		SELECT id, user_id, platform, channel_id, title, description, status, priority, project, due_date, reminder_job, created_at, updated_at, completed_at
+		FROM tasks
+		WHERE (? = '' OR user_id = ? OR user_id = 'default')
+			AND (status != 'completed' OR (? != '' AND completed_at >= ?))
+		ORDER BY status = 'completed', due_date = '', due_date, id
+	`, userID, userID, since, since)
+}
+
+// sealTask seals the task's text when the store is encrypted. Callers hold s.mu.
+func (s *Store) sealTask(t *Task) error {
+	var err error
+	for _, v := range []*string{&t.Title, &t.Description, &t.Project} {
+		if *v, err = s.seal(*v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) queryTasks(query string, args ...any) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		var createdAt, updatedAt string
+		var completedAt sql.NullString
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Platform, &t.ChannelID, &t.Title, &t.Description, &t.Status, &t.Priority,
+			&t.Project, &t.DueDate, &t.ReminderJob, &createdAt, &updatedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		for _, v := range []*string{&t.Title, &t.Description, &t.Project} {
+			if *v, err = s.open(*v); err != nil {
+				return nil, err
+			}
+		}
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		t.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		if completedAt.Valid {
+			t.CompletedAt, _ = time.Parse(time.RFC3339, completedAt.String)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,104 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TimeEntry is one tracked stretch of time.
+type TimeEntry struct {
+	ID        int64
+	UserID    string
+	Category  string
+	Task      string
+	StartedAt time.Time
+	EndedAt   time.Time // zero while the timer runs
+}
+
+// Running reports whether the entry's timer is still going.
+func (e TimeEntry) Running() bool {
+	return e.EndedAt.IsZero()
+}
+
+// StartTimeEntry records the start of a timer and returns its ID
+func (s *Store) StartTimeEntry(e TimeEntry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.StartedAt.IsZero() {
+		e.StartedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO time_entries (user_id, category, task, started_at)
+		VALUES (?, ?, ?, ?)
+	`, e.UserID, e.Category, e.Task, e.StartedAt.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// StopTimeEntry records when a timer stopped
+func (s *Store) StopTimeEntry(id int64, endedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE time_entries SET ended_at = ? WHERE id = ?`, endedAt.Format(time.RFC3339), id)
+	return err
+}
+
+// RunningTimeEntry returns the user's running timer, or nil
+func (s *Store) RunningTimeEntry(userID string) (*TimeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := s.queryTimeEntries(`
+		SELECT id, user_id, category, task, started_at, ended_at
+		FROM time_entries
+		WHERE user_id = ? AND ended_at IS NULL
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, userID)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// TimeEntries returns entries overlapping [since, until), oldest first.
+// An empty userID returns every user's entries.
+func (s *Store) TimeEntries(userID string, since, until time.Time) ([]TimeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryTimeEntries(`
+		SELECT id, user_id, category, task, started_at, ended_at
+		FROM time_entries
+		WHERE (? = '' OR user_id = ?) AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)
+		ORDER BY started_at
+	`, userID, userID, until.Format(time.RFC3339), since.Format(time.RFC3339))
+}
+
+func (s *Store) queryTimeEntries(query string, args ...any) ([]TimeEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []TimeEntry
+	for rows.Next() {
+		var e TimeEntry
+		var startedAt string
+		var endedAt sql.NullString
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Category, &e.Task, &startedAt, &endedAt); err != nil {
+			return nil, err
+		}
+		e.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+		if endedAt.Valid {
+			e.EndedAt, _ = time.Parse(time.RFC3339, endedAt.String)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
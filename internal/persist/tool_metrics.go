@@ -0,0 +1,93 @@
+package persist
+
+import (
+	"time"
+)
+
+// ToolMetric is a single tool execution measurement
+type ToolMetric struct {
+	ToolName    string
+	Duration    time.Duration
+	ResultBytes int
+	IsError     bool
+	CreatedAt   time.Time
+}
+
+// ToolLatencyStat aggregates tool executions for reporting
+type ToolLatencyStat struct {
+	ToolName       string
+	Calls          int
+	Errors         int
+	AvgDuration    time.Duration
+	MaxDuration    time.Duration
+	AvgResultBytes int
+}
+
+// RecordToolMetric stores one tool execution measurement
+func (s *Store) RecordToolMetric(m ToolMetric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	isError := 0
+	if m.IsError {
+		isError = 1
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO tool_metrics (tool_name, duration_ms, result_bytes, is_error, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, m.ToolName, m.Duration.Milliseconds(), m.ResultBytes, isError, m.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// SlowestTools returns per-tool stats for the given date (YYYY-MM-DD), slowest average first
+func (s *Store) SlowestTools(date string, limit int) ([]ToolLatencyStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = 5
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	start := day.Format(time.RFC3339)
+	end := day.AddDate(0, 0, 1).Format(time.RFC3339)
+
+	rows, err := s.db.Query(`
+		SELECT tool_name, COUNT(*), SUM(is_error), AVG(duration_ms), MAX(duration_ms), AVG(result_bytes)
+		FROM tool_metrics
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY tool_name
+		ORDER BY AVG(duration_ms) DESC
+		LIMIT ?
+	`, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ToolLatencyStat
+	for rows.Next() {
+		var (
+			stat     ToolLatencyStat
+			avgMS    float64
+			maxMS    int64
+			avgBytes float64
+		)
+		if err := rows.Scan(&stat.ToolName, &stat.Calls, &stat.Errors, &avgMS, &maxMS, &avgBytes); err != nil {
+			return nil, err
+		}
+		stat.AvgDuration = time.Duration(avgMS * float64(time.Millisecond))
+		stat.MaxDuration = time.Duration(maxMS) * time.Millisecond
+		stat.AvgResultBytes = int(avgBytes)
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
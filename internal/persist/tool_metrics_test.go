@@ -0,0 +1,60 @@
+package persist
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSlowestTools(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "coco.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	for _, m := range []ToolMetric{
+		{ToolName: "web_fetch", Duration: 1200 * time.Millisecond, ResultBytes: 3000},
+		{ToolName: "web_fetch", Duration: 800 * time.Millisecond, ResultBytes: 1000, IsError: true},
+		{ToolName: "shell_execute", Duration: 3 * time.Second, ResultBytes: 10},
+		{ToolName: "file_read", Duration: 10 * time.Millisecond, ResultBytes: 500},
+		{ToolName: "file_read", Duration: 30 * time.Millisecond, ResultBytes: 700},
+		// The day before and the day after are not counted.
+		{ToolName: "web_fetch", Duration: time.Minute, CreatedAt: day.AddDate(0, 0, -1)},
+		{ToolName: "db_query", Duration: time.Minute, CreatedAt: day.AddDate(0, 0, 1)},
+	} {
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = day
+		}
+		if err := store.RecordToolMetric(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := store.SlowestTools("2026-10-16", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ToolLatencyStat{
+		{ToolName: "shell_execute", Calls: 1, AvgDuration: 3 * time.Second, MaxDuration: 3 * time.Second, AvgResultBytes: 10},
+		{ToolName: "web_fetch", Calls: 2, Errors: 1, AvgDuration: time.Second, MaxDuration: 1200 * time.Millisecond, AvgResultBytes: 2000},
+		{ToolName: "file_read", Calls: 2, AvgDuration: 20 * time.Millisecond, MaxDuration: 30 * time.Millisecond, AvgResultBytes: 600},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("stats = %+v, want %d tools", stats, len(want))
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+
+	stats, err = store.SlowestTools("2026-10-16", 2)
+	if err != nil || len(stats) != 2 || stats[1].ToolName != "web_fetch" {
+		t.Fatalf("limit 2 = %+v, %v", stats, err)
+	}
+	if _, err := store.SlowestTools("16/10/2026", 5); err == nil {
+		t.Fatal("expected an error for a malformed date")
+	}
+}
@@ -0,0 +1,41 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TopicSeparator joins a user ID and a topic thread forked from their chat.
+// A thread's conversation is stored under user_id "<id>#<topic>".
+const TopicSeparator = "#"
+
+// ActiveTopic returns the topic thread a conversation has switched to, or
+// "" for its main thread
+func (s *Store) ActiveTopic(convKey string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var topic string
+	err := s.db.QueryRow(`SELECT topic FROM active_topics WHERE conv_key = ?`, convKey).Scan(&topic)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return topic, err
+}
+
+// SetActiveTopic switches a conversation to a topic thread, or back to its
+// main thread when topic is ""
+func (s *Store) SetActiveTopic(convKey, topic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if topic == "" {
+		_, err := s.db.Exec(`DELETE FROM active_topics WHERE conv_key = ?`, convKey)
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO active_topics (conv_key, topic, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(conv_key) DO UPDATE SET topic = excluded.topic, updated_at = excluded.updated_at
+	`, convKey, topic, time.Now().Format(time.RFC3339))
+	return err
+}
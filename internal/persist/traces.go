@@ -0,0 +1,74 @@
+package persist
+
+import "time"
+
+// RunTrace is one recorded agent run: the prompt, every tool call and
+// result, and the final reply, JSON encoded by the agent.
+type RunTrace struct {
+	ID              int64
+	ConversationKey string
+	Data            string
+	CreatedAt       time.Time
+}
+
+// SaveRunTrace records a run
+func (s *Store) SaveRunTrace(conversationKey string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sealed, err := s.seal(string(data))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO run_traces (conversation_key, data, created_at)
+		VALUES (?, ?, ?)
+	`, conversationKey, sealed, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// RunTraces returns the runs recorded since the given time, oldest first.
+// A zero time returns every run.
+func (s *Store) RunTraces(since time.Time) ([]RunTrace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, conversation_key, data, created_at
+		FROM run_traces
+		WHERE created_at >= ?
+		ORDER BY id
+	`, since.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var traces []RunTrace
+	for rows.Next() {
+		var t RunTrace
+		var createdAt string
+		if err := rows.Scan(&t.ID, &t.ConversationKey, &t.Data, &createdAt); err != nil {
+			return nil, err
+		}
+		if t.Data, err = s.open(t.Data); err != nil {
+			return nil, err
+		}
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		traces = append(traces, t)
+	}
+	return traces, rows.Err()
+}
+
+// DeleteRunTracesBefore deletes runs recorded before the given time and
+// returns how many were removed
+func (s *Store) DeleteRunTracesBefore(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`DELETE FROM run_traces WHERE created_at < ?`, before.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
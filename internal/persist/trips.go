@@ -0,0 +1,150 @@
+package persist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Trip is a flight or train the user is taking, reminded before departure.
+type Trip struct {
+	ID           int64
+	UserID       string
+	Platform     string // where to send the reminder
+	ChannelID    string
+	Kind         string // "flight" or "train"
+	Number       string
+	Departure    time.Time
+	Arrival      time.Time // zero when unknown
+	Origin       string
+	Destination  string
+	Seat         string
+	Confirmation string
+	RemindedAt   time.Time
+	Active       bool // false once the trip is over
+	CreatedAt    time.Time
+}
+
+// AddTrip stores a new trip and returns its ID
+func (s *Store) AddTrip(t Trip) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO trips (user_id, platform, channel_id, kind, number, departure, arrival, origin, destination, seat, confirmation, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+	`, t.UserID, t.Platform, t.ChannelID, t.Kind, t.Number, t.Departure.Format(time.RFC3339), nullTime(t.Arrival),
+		t.Origin, t.Destination, t.Seat, t.Confirmation, t.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateTrip saves a trip's details and reminder state
+func (s *Store) UpdateTrip(t Trip) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		UPDATE trips
+		SET departure = ?, arrival = ?, origin = ?, destination = ?, seat = ?, confirmation = ?, reminded_at = ?, active = ?
+		WHERE id = ?
+	`, t.Departure.Format(time.RFC3339), nullTime(t.Arrival), t.Origin, t.Destination, t.Seat, t.Confirmation,
+		nullTime(t.RemindedAt), t.Active, t.ID)
+	return err
+}
+
+// DeleteTrip removes a trip
+func (s *Store) DeleteTrip(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`DELETE FROM trips WHERE id = ?`, id)
+	return err
+}
+
+// GetTrip returns one trip, or nil if it does not exist
+func (s *Store) GetTrip(id int64) (*Trip, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trips, err := s.queryTrips(`
+		SELECT id, user_id, platform, channel_id, kind, number, departure, arrival, origin, destination, seat, confirmation, reminded_at, active, created_at
+		FROM trips
+		WHERE id = ?
+	`, id)
+	if err != nil || len(trips) == 0 {
+		return nil, err
+	}
+	return &trips[0], nil
+}
+
+// FindTrip returns the user's trip with the number departing on the same
+// day as departure, or nil if there is none
+func (s *Store) FindTrip(userID, number string, departure time.Time) (*Trip, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trips, err := s.queryTrips(`
+		SELECT id, user_id, platform, channel_id, kind, number, departure, arrival, origin, destination, seat, confirmation, reminded_at, active, created_at
+		FROM trips
+		WHERE user_id = ? AND number = ?
+		ORDER BY id
+	`, userID, number)
+	if err != nil {
+		return nil, err
+	}
+	y, m, d := departure.Date()
+	for i := range trips {
+		if ty, tm, td := trips[i].Departure.In(departure.Location()).Date(); ty == y && tm == m && td == d {
+			return &trips[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Trips returns trips by departure. An empty userID returns every user's
+// trips.
+func (s *Store) Trips(userID string, activeOnly bool) ([]Trip, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryTrips(`
+		SELECT id, user_id, platform, channel_id, kind, number, departure, arrival, origin, destination, seat, confirmation, reminded_at, active, created_at
+		FROM trips
+		WHERE (? = '' OR user_id = ?) AND (? = 0 OR active = 1)
+		ORDER BY departure, id
+	`, userID, userID, activeOnly)
+}
+
+func (s *Store) queryTrips(query string, args ...any) ([]Trip, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trips []Trip
+	for rows.Next() {
+		var t Trip
+		var departure, createdAt string
+		var arrival, remindedAt sql.NullString
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Platform, &t.ChannelID, &t.Kind, &t.Number, &departure, &arrival,
+			&t.Origin, &t.Destination, &t.Seat, &t.Confirmation, &remindedAt, &t.Active, &createdAt); err != nil {
+			return nil, err
+		}
+		t.Departure, _ = time.Parse(time.RFC3339, departure)
+		if arrival.Valid {
+			t.Arrival, _ = time.Parse(time.RFC3339, arrival.String)
+		}
+		if remindedAt.Valid {
+			t.RemindedAt, _ = time.Parse(time.RFC3339, remindedAt.String)
+		}
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		trips = append(trips, t)
+	}
+	return trips, rows.Err()
+}
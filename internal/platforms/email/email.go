@@ -0,0 +1,364 @@
+// Package email is a platform that reads mail from an IMAP inbox (or a
+// webhook that forwards raw messages) and answers over SMTP. Each mail
+// thread is one conversation.
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+const (
+	defaultPollInterval = time.Minute
+	imapTimeout         = 30 * time.Second
+	maxWebhookBytes     = 25 << 20
+)
+
+// Config holds email configuration
+type Config struct {
+	IMAPServer     string        // host:port of the IMAP server, TLS (usually 993)
+	SMTPServer     string        // host:port of the SMTP server; 465 uses TLS, others STARTTLS
+	Username       string        // login for both servers
+	Password       string        // password or app password
+	From           string        // sender address of replies (default: Username)
+	Folder         string        // mailbox to poll (default: INBOX)
+	PollInterval   time.Duration // default: 1 minute
+	WebhookListen  string        // if set, receive raw messages POSTed to /email here instead of polling
+	AllowedSenders []string      // addresses or "@domain" allowed to talk to coco; empty allows everyone
+}
+
+// Platform implements router.Platform for email
+type Platform struct {
+	cfg            Config
+	messageHandler func(msg router.Message)
+	httpServer     *http.Server
+	cancel         context.CancelFunc
+
+	mu      sync.Mutex
+	threads map[string]*thread // by thread ID (the channel ID)
+}
+
+// thread is what a reply needs to continue a conversation.
+type thread struct {
+	to         string
+	subject    string
+	references []string
+}
+
+// New creates a new email platform
+func New(cfg Config) (*Platform, error) {
+	if cfg.SMTPServer == "" || cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("smtp_server, username and password are required")
+	}
+	if cfg.IMAPServer == "" && cfg.WebhookListen == "" {
+		return nil, fmt.Errorf("imap_server or webhook_listen is required")
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	if cfg.Folder == "" {
+		cfg.Folder = "INBOX"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	return &Platform{cfg: cfg, threads: make(map[string]*thread)}, nil
+}
+
+// Name returns the platform name
+func (p *Platform) Name() string {
+	return "email"
+}
+
+// SetMessageHandler sets the callback for incoming messages
+func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
+	p.messageHandler = handler
+}
+
+// Start begins polling the inbox or serving the webhook
+func (p *Platform) Start(ctx context.Context) error {
+	ctx, p.cancel = context.WithCancel(ctx)
+	if len(p.cfg.AllowedSenders) == 0 {
+		log.Printf("[Email] Warning: no allowed_senders configured, anyone who can email %s can talk to coco", p.cfg.From)
+	}
+
+	if p.cfg.WebhookListen != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/email", p.handleWebhook)
+		p.httpServer = &http.Server{Addr: p.cfg.WebhookListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := p.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Email] Webhook server error: %v", err)
+			}
+		}()
+		log.Printf("[Email] Receiving mail on http://%s/email", p.cfg.WebhookListen)
+		return nil
+	}
+
+	go p.pollLoop(ctx)
+	log.Printf("[Email] Polling %s/%s every %s", p.cfg.IMAPServer, p.cfg.Folder, p.cfg.PollInterval)
+	return nil
+}
+
+// Stop stops polling and the webhook server
+func (p *Platform) Stop() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if p.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return p.httpServer.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (p *Platform) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.poll(); err != nil {
+			log.Printf("[Email] Poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches unseen mail. A message is marked seen only once it has been
+// handed over, so a failed poll picks it up again next time.
+func (p *Platform) poll() error {
+	c, err := dialIMAP(p.cfg.IMAPServer, imapTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.login(p.cfg.Username, p.cfg.Password); err != nil {
+		return err
+	}
+	if err := c.selectFolder(p.cfg.Folder); err != nil {
+		return err
+	}
+	uids, err := c.searchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		c.conn.SetDeadline(time.Now().Add(imapTimeout))
+		raw, err := c.fetch(uid)
+		if err != nil {
+			return err
+		}
+		p.receive(raw)
+		if err := c.markSeen(uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleWebhook accepts a raw RFC 822 message, as forwarded by mail
+// services or a procmail/sieve pipe.
+func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := p.receive(raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// receive parses a mail and hands it to coco.
+func (p *Platform) receive(raw []byte) error {
+	in, err := parseMessage(raw)
+	if err != nil {
+		log.Printf("[Email] Skipping unreadable message: %v", err)
+		return err
+	}
+	sender := strings.ToLower(in.from.Address)
+	if strings.EqualFold(sender, p.cfg.From) {
+		return nil // our own reply, e.g. when Sent is the polled folder
+	}
+	if !p.allowed(sender) {
+		log.Printf("[Email] Ignoring mail from %s (not in allowed_senders)", sender)
+		return nil
+	}
+
+	p.mu.Lock()
+	p.threads[in.threadID] = &thread{to: in.from.String(), subject: in.subject, references: in.references}
+	p.mu.Unlock()
+
+	text := in.text
+	if subject := normalizeSubject(in.subject); subject != "" && !strings.Contains(text, subject) {
+		text = "Subject: " + subject + "\n\n" + text
+	}
+	if len(in.files) > 0 {
+		text += "\n\n[Attachments not downloaded: " + strings.Join(in.files, ", ") + "]"
+	}
+	if p.messageHandler != nil {
+		name := in.from.Name
+		if name == "" {
+			name = sender
+		}
+		p.messageHandler(router.Message{
+			ID:          in.messageID,
+			Platform:    "email",
+			ChannelID:   in.threadID,
+			UserID:      sender,
+			Username:    name,
+			Text:        text,
+			Attachments: in.images,
+			Metadata: map[string]string{
+				"chat_type": "dm",
+				"subject":   in.subject,
+			},
+		})
+	}
+	return nil
+}
+
+func (p *Platform) allowed(sender string) bool {
+	if len(p.cfg.AllowedSenders) == 0 {
+		return true
+	}
+	for _, a := range p.cfg.AllowedSenders {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == sender || (strings.HasPrefix(a, "@") && strings.HasSuffix(sender, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send replies in the mail thread identified by channelID
+func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
+	p.mu.Lock()
+	t := p.threads[channelID]
+	p.mu.Unlock()
+	if t == nil {
+		return fmt.Errorf("unknown mail thread %s", channelID)
+	}
+
+	subject := normalizeSubject(t.subject)
+	if subject == "" {
+		subject = "coco"
+	}
+	raw, messageID, err := composeMessage(outbound{
+		from:       p.cfg.From,
+		to:         t.to,
+		subject:    "Re: " + subject,
+		references: t.references,
+		text:       resp.Text,
+		files:      resp.Files,
+	})
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(t.to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", t.to, err)
+	}
+	if err := p.sendMail(to.Address, raw); err != nil {
+		return err
+	}
+
+	// Further replies continue from this one.
+	p.mu.Lock()
+	t.references = append(t.references, messageID)
+	p.mu.Unlock()
+	return nil
+}
+
+// SendText mails text to a single address outside any conversation, e.g.
+// a scheduled job's output. An empty to sends it to the sender address.
+func SendText(cfg Config, to, subject, text string) error {
+	if cfg.SMTPServer == "" || cfg.Username == "" || cfg.Password == "" {
+		return fmt.Errorf("smtp_server, username and password are required")
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	if to == "" {
+		to = cfg.From
+	}
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	raw, _, err := composeMessage(outbound{from: cfg.From, to: to, subject: subject, text: text})
+	if err != nil {
+		return err
+	}
+	p := &Platform{cfg: cfg}
+	return p.sendMail(addr.Address, raw)
+}
+
+func (p *Platform) sendMail(to string, raw []byte) error {
+	host, port, err := net.SplitHostPort(p.cfg.SMTPServer)
+	if err != nil {
+		return fmt.Errorf("invalid smtp_server %q: %w", p.cfg.SMTPServer, err)
+	}
+	auth := smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, host)
+	from := p.cfg.From
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.Address
+	}
+	if port != "465" {
+		// SendMail upgrades with STARTTLS when the server offers it.
+		return smtp.SendMail(p.cfg.SMTPServer, auth, from, []string{to}, raw)
+	}
+
+	conn, err := tls.Dial("tcp", p.cfg.SMTPServer, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if err := c.Auth(auth); err != nil {
+		return err
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,178 @@
+package email
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+const replyMail = "From: =?utf-8?B?5byg5LiJ?= <zhang@example.com>\r\n" +
+	"To: coco@example.com\r\n" +
+	"Subject: =?utf-8?Q?Re:_=E5=91=A8=E6=8A=A5?=\r\n" +
+	"Message-ID: <m2@example.com>\r\n" +
+	"In-Reply-To: <r1@coco.example.com>\r\n" +
+	"References: <m1@example.com> <r1@coco.example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Please add the numbers=\r\n" +
+	" too.\r\n" +
+	"\r\n" +
+	"On Mon, 1 Jan 2024, coco wrote:\r\n" +
+	"> earlier answer\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-Disposition: attachment; filename=chart.png\r\n" +
+	"\r\n" +
+	"iVBORw0K\r\n" +
+	"GgoA\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"q1.pdf\"\r\n" +
+	"\r\n" +
+	"%PDF\r\n" +
+	"--XYZ--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	in, err := parseMessage([]byte(replyMail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.from.Name != "张三" || in.from.Address != "zhang@example.com" || in.subject != "Re: 周报" {
+		t.Fatalf("headers = %+v, %q", in.from, in.subject)
+	}
+	if in.threadID != "m1@example.com" {
+		t.Fatalf("thread = %q", in.threadID)
+	}
+	if got := strings.Join(in.references, " "); got != "m1@example.com r1@coco.example.com m2@example.com" {
+		t.Fatalf("references = %q", got)
+	}
+	if in.text != "Please add the numbers too." {
+		t.Fatalf("text = %q", in.text)
+	}
+	if len(in.images) != 1 || in.images[0].MIMEType != "image/png" || string(in.images[0].Data[:4]) != "\x89PNG" {
+		t.Fatalf("images = %#v", in.images)
+	}
+	if len(in.files) != 1 || in.files[0] != "q1.pdf" {
+		t.Fatalf("files = %v", in.files)
+	}
+}
+
+func TestReceiveAndCompose(t *testing.T) {
+	p, err := New(Config{SMTPServer: "smtp.example.com:587", IMAPServer: "imap.example.com:993",
+		Username: "coco@example.com", Password: "x", AllowedSenders: []string{"@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(m router.Message) { got = append(got, m) })
+
+	p.receive([]byte(replyMail))
+	p.receive([]byte(strings.Replace(replyMail, "zhang@example.com", "eve@evil.test", 1)))
+	if len(got) != 1 || got[0].ChannelID != "m1@example.com" || got[0].UserID != "zhang@example.com" {
+		t.Fatalf("messages = %#v", got)
+	}
+	if !strings.HasPrefix(got[0].Text, "Subject: 周报\n\n") || !strings.Contains(got[0].Text, "q1.pdf") {
+		t.Fatalf("text = %q", got[0].Text)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	os.WriteFile(path, []byte("numbers"), 0o644)
+	th := p.threads["m1@example.com"]
+	raw, id, err := composeMessage(outbound{
+		from:       "coco@example.com",
+		to:         th.to,
+		subject:    "Re: " + normalizeSubject(th.subject),
+		references: th.references,
+		text:       "见附件",
+		files:      []router.FileAttachment{{Path: path}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reply threads under the mail it answers and reads back intact.
+	back, err := parseMessage(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.threadID != "m1@example.com" || back.messageID != id || back.subject != "Re: 周报" {
+		t.Fatalf("reply = thread %q id %q subject %q", back.threadID, back.messageID, back.subject)
+	}
+	if back.text != "见附件" || len(back.files) != 1 || back.files[0] != "report.txt" {
+		t.Fatalf("reply body = %q, files %v", back.text, back.files)
+	}
+}
+
+func TestIMAPClient(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	mail := "Subject: hi\r\n\r\nbody\r\n"
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		reply := func(lines ...string) {
+			for _, l := range lines {
+				fmt.Fprint(server, l+"\r\n")
+			}
+		}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch {
+			case strings.HasPrefix(cmd, "LOGIN"):
+				if cmd != `LOGIN "me" "p\"w"` {
+					reply(tag + " NO bad login " + cmd)
+					continue
+				}
+				reply(tag + " OK logged in")
+			case strings.HasPrefix(cmd, "SELECT"):
+				reply("* 2 EXISTS", tag+" OK [READ-WRITE] selected")
+			case cmd == "UID SEARCH UNSEEN":
+				reply("* SEARCH 7 9", tag+" OK done")
+			case cmd == "UID FETCH 7 (BODY.PEEK[])":
+				fmt.Fprintf(server, "* 1 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n", len(mail), mail)
+				reply(tag + " OK done")
+			case strings.HasPrefix(cmd, "UID STORE"):
+				reply(tag + " OK stored")
+			default:
+				reply(tag + " BAD unknown")
+			}
+		}
+	}()
+
+	c := newIMAPClient(client)
+	if err := c.login("me", `p"w`); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.selectFolder("INBOX"); err != nil {
+		t.Fatal(err)
+	}
+	uids, err := c.searchUnseen()
+	if err != nil || len(uids) != 2 || uids[0] != 7 || uids[1] != 9 {
+		t.Fatalf("uids = %v, %v", uids, err)
+	}
+	raw, err := c.fetch(7)
+	if err != nil || string(raw) != mail {
+		t.Fatalf("fetch = %q, %v", raw, err)
+	}
+	if err := c.markSeen(7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.command("NOOP"); err == nil || !strings.Contains(err.Error(), "NOOP failed") {
+		t.Fatalf("bad command err = %v", err)
+	}
+}
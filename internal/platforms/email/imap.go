@@ -0,0 +1,187 @@
+package email
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient speaks just enough IMAP4rev1 to poll a mailbox: log in,
+// search for unseen mail, fetch it and mark it seen.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line with the literals it carried.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func dialIMAP(addr string, timeout time.Duration) (*imapClient, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid imap_server %q: %w", addr, err)
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	c := newIMAPClient(conn)
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+func newIMAPClient(conn net.Conn) *imapClient {
+	return &imapClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *imapClient) Close() error {
+	c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+func (c *imapClient) login(username, password string) error {
+	_, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password))
+	return err
+}
+
+func (c *imapClient) selectFolder(folder string) error {
+	_, err := c.command("SELECT " + imapQuote(folder))
+	return err
+}
+
+// searchUnseen returns the UIDs of unseen messages.
+func (c *imapClient) searchUnseen() ([]uint32, error) {
+	resps, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range resps {
+		fields := strings.Fields(r.line)
+		if len(fields) < 2 || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, f := range fields[2:] {
+			if n, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw RFC 822 message without setting \Seen.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	resps, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resps {
+		if strings.Contains(strings.ToUpper(r.line), "FETCH") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %d not returned", uid)
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf("UID STORE %d +FLAGS.SILENT (\\Seen)", uid))
+	return err
+}
+
+// command sends one command and collects untagged responses until its
+// tagged completion. Anything but OK is an error.
+func (c *imapClient) command(cmd string) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, err
+	}
+
+	var resps []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(resp.line, tag+" ") {
+			status := strings.TrimPrefix(resp.line, tag+" ")
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				verb, _, _ := strings.Cut(cmd, " ")
+				return nil, fmt.Errorf("IMAP %s failed: %s", verb, status)
+			}
+			return resps, nil
+		}
+		resps = append(resps, resp)
+	}
+}
+
+// readResponse reads one response line, following any {n} literals.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var sb strings.Builder
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return resp, err
+		}
+		sb.WriteString(line)
+		n, ok := literalSize(line)
+		if !ok {
+			resp.line = sb.String()
+			return resp, nil
+		}
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, lit)
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// literalSize reports the n of a line ending in "{n}".
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
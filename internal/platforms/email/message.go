@@ -0,0 +1,333 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/router"
+)
+
+// inbound is a parsed incoming email.
+type inbound struct {
+	messageID  string
+	threadID   string   // Message-ID of the thread's first mail
+	references []string // References chain to continue in replies
+	from       *mail.Address
+	subject    string
+	text       string
+	images     []router.Attachment
+	files      []string // names of non-image attachments, which are not downloaded
+}
+
+var headerDecoder = &mime.WordDecoder{}
+
+// parseMessage reads a raw RFC 822 message.
+func parseMessage(raw []byte) (*inbound, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	in := &inbound{messageID: trimID(msg.Header.Get("Message-Id"))}
+	if in.subject, err = headerDecoder.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		in.subject = msg.Header.Get("Subject")
+	}
+	if in.from, err = mail.ParseAddress(msg.Header.Get("From")); err != nil {
+		return nil, fmt.Errorf("invalid From: %w", err)
+	}
+
+	in.references = msgIDs(msg.Header.Get("References"))
+	if parent := trimID(msg.Header.Get("In-Reply-To")); parent != "" && !contains(in.references, parent) {
+		in.references = append(in.references, parent)
+	}
+	switch {
+	case len(in.references) > 0:
+		in.threadID = in.references[0]
+	case in.messageID != "":
+		in.threadID = in.messageID
+	default:
+		in.threadID = "subject:" + normalizeSubject(in.subject) + ":" + strings.ToLower(in.from.Address)
+	}
+	if in.messageID != "" {
+		in.references = append(in.references, in.messageID)
+	}
+
+	var plain, html string
+	err = walkPart(textproto.MIMEHeader(msg.Header), msg.Body, func(ctype, filename string, body []byte) {
+		switch {
+		case filename == "" && ctype == "text/plain" && plain == "":
+			plain = string(body)
+		case filename == "" && ctype == "text/html" && html == "":
+			html = string(body)
+		case strings.HasPrefix(ctype, "image/"):
+			in.images = append(in.images, router.Attachment{Type: "image", Data: body, MIMEType: ctype})
+		case filename != "":
+			in.files = append(in.files, filename)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if plain == "" && html != "" {
+		plain = htmlToText(html)
+	}
+	in.text = stripQuoted(plain)
+	return in, nil
+}
+
+// walkPart calls fn for every leaf part with its decoded body.
+func walkPart(header textproto.MIMEHeader, body io.Reader, fn func(ctype, filename string, body []byte)) error {
+	ctype, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		ctype, params = "text/plain", nil
+	}
+	if strings.HasPrefix(ctype, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkPart(part.Header, part, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &stripNewlines{r: body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	filename := ""
+	if _, dparams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = dparams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename != "" {
+		if decoded, err := headerDecoder.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+	}
+	fn(ctype, filename, data)
+	return nil
+}
+
+// stripNewlines drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type stripNewlines struct{ r io.Reader }
+
+func (s *stripNewlines) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	out := p[:0]
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			out = append(out, b)
+		}
+	}
+	return len(out), err
+}
+
+var (
+	quoteHeaderPattern = regexp.MustCompile(`(?m)^(On .+ wrote:|在.+写道[:：]|-+ ?Original Message ?-+|-+ ?原始邮件 ?-+)\s*$`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<(script|style).*?</(script|style)>|<[^>]+>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// stripQuoted drops the quoted previous mail a reply usually carries;
+// the thread's history is already in coco's memory.
+func stripQuoted(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if loc := quoteHeaderPattern.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, ">") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func htmlToText(html string) string {
+	html = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n", "</div>", "\n").Replace(html)
+	text := htmlTagPattern.ReplaceAllString(html, "")
+	text = strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'", "&amp;", "&").Replace(text)
+	return blankLinesPattern.ReplaceAllString(text, "\n\n")
+}
+
+// normalizeSubject strips reply and forward prefixes.
+func normalizeSubject(subject string) string {
+	s := strings.TrimSpace(subject)
+	for {
+		lower := strings.ToLower(s)
+		trimmed := false
+		for _, prefix := range []string{"re:", "fw:", "fwd:", "回复:", "回复：", "答复:", "答复：", "转发:", "转发："} {
+			if strings.HasPrefix(lower, prefix) {
+				s = strings.TrimSpace(s[len(prefix):])
+				trimmed = true
+				break
+			}
+		}
+		if !trimmed {
+			return s
+		}
+	}
+}
+
+func msgIDs(header string) []string {
+	var ids []string
+	for _, f := range strings.Fields(header) {
+		if id := trimID(f); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func trimID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// outbound is a reply to send.
+type outbound struct {
+	from       string
+	to         string
+	subject    string
+	references []string // the last entry is the mail being answered
+	text       string
+	files      []router.FileAttachment
+}
+
+// composeMessage renders o as a MIME message and returns it with its Message-ID.
+func composeMessage(o outbound) ([]byte, string, error) {
+	domain := "coco.local"
+	if _, d, ok := strings.Cut(o.from, "@"); ok {
+		domain = strings.Trim(d, "> ")
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	messageID := hex.EncodeToString(b) + "@" + domain
+
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+	header("From", o.from)
+	header("To", o.to)
+	header("Subject", mime.QEncoding.Encode("utf-8", o.subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+messageID+">")
+	if n := len(o.references); n > 0 {
+		header("In-Reply-To", "<"+o.references[n-1]+">")
+		refs := make([]string, n)
+		for i, r := range o.references {
+			refs[i] = "<" + r + ">"
+		}
+		header("References", strings.Join(refs, " "))
+	}
+	header("MIME-Version", "1.0")
+
+	if len(o.files) == 0 {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, o.text); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), messageID, nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	tw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := writeQuotedPrintable(tw, o.text); err != nil {
+		return nil, "", err
+	}
+	for _, f := range o.files {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			return nil, "", fmt.Errorf("read attachment: %w", err)
+		}
+		name := f.Name
+		if name == "" {
+			name = filepath.Base(f.Path)
+		}
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(ctype, map[string]string{"name": name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if err := writeBase64Lines(pw, data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), messageID, nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func writeBase64Lines(w io.Writer, data []byte) error {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		if _, err := io.WriteString(w, enc[:76]+"\r\n"); err != nil {
+			return err
+		}
+		enc = enc[76:]
+	}
+	_, err := io.WriteString(w, enc+"\r\n")
+	return err
+}
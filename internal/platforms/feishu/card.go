@@ -0,0 +1,95 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/kayz/coco/internal/router"
+
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
+)
+
+// Card button commands; a click is handled as if the user had typed it.
+var cardCommands = map[string]string{
+	"/approve": "确认执行",
+	"/reject":  "取消",
+}
+
+// approvalCard renders text as an interactive card with approve/reject
+// buttons. The buttons carry the thread and chat type so the click is routed
+// like a reply in the original conversation.
+func approvalCard(text, threadID, chatType string) string {
+	button := func(command, style string) map[string]any {
+		return map[string]any{
+			"tag":  "button",
+			"type": style,
+			"text": map[string]any{"tag": "plain_text", "content": cardCommands[command]},
+			"value": map[string]string{
+				"command":   command,
+				"thread_id": threadID,
+				"chat_type": chatType,
+			},
+		}
+	}
+	card := map[string]any{
+		"config": map[string]any{"wide_screen_mode": true},
+		"header": map[string]any{
+			"template": "orange",
+			"title":    map[string]any{"tag": "plain_text", "content": "待确认的操作"},
+		},
+		"elements": []any{
+			map[string]any{"tag": "markdown", "content": text},
+			map[string]any{
+				"tag":     "action",
+				"actions": []any{button("/approve", "primary"), button("/reject", "danger")},
+			},
+		},
+	}
+	data, _ := json.Marshal(card)
+	return string(data)
+}
+
+// handleCardAction turns a button click into a message from the clicking user.
+func (p *Platform) handleCardAction(ctx context.Context, event *callback.CardActionTriggerEvent) (*callback.CardActionTriggerResponse, error) {
+	if event == nil || event.Event == nil || event.Event.Action == nil || event.Event.Operator == nil {
+		return nil, nil
+	}
+	value := event.Event.Action.Value
+	command, _ := value["command"].(string)
+	if _, ok := cardCommands[command]; !ok || p.messageHandler == nil {
+		return nil, nil
+	}
+
+	chatID, messageID := "", ""
+	if event.Event.Context != nil {
+		chatID = event.Event.Context.OpenChatID
+		messageID = event.Event.Context.OpenMessageID
+	}
+	if chatID == "" {
+		log.Printf("[Feishu] Card action without chat context ignored")
+		return nil, nil
+	}
+	threadID, _ := value["thread_id"].(string)
+	chatType, _ := value["chat_type"].(string)
+	userID := event.Event.Operator.OpenID
+
+	p.messageHandler(router.Message{
+		ID:        messageID + ":" + command,
+		Platform:  "feishu",
+		ChannelID: chatID,
+		UserID:    userID,
+		Username:  p.getUsername(ctx, userID),
+		Text:      command,
+		ThreadID:  threadID,
+		Metadata: map[string]string{
+			"chat_type": chatType,
+			// Clicking the bot's own card addresses the bot.
+			"mentioned": "true",
+		},
+	})
+
+	return &callback.CardActionTriggerResponse{
+		Toast: &callback.Toast{Type: "info", Content: "已收到：" + cardCommands[command]},
+	}, nil
+}
@@ -0,0 +1,44 @@
+package feishu
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApprovalCard(t *testing.T) {
+	var card struct {
+		Elements []struct {
+			Tag     string `json:"tag"`
+			Content string `json:"content"`
+			Actions []struct {
+				Value map[string]string `json:"value"`
+			} `json:"actions"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(approvalCard("delete **a.txt**", "om_root", "group")), &card); err != nil {
+		t.Fatalf("card is not JSON: %v", err)
+	}
+	if len(card.Elements) != 2 || card.Elements[0].Content != "delete **a.txt**" {
+		t.Fatalf("elements = %#v", card.Elements)
+	}
+	actions := card.Elements[1].Actions
+	if len(actions) != 2 || actions[0].Value["command"] != "/approve" || actions[1].Value["command"] != "/reject" {
+		t.Fatalf("actions = %#v", actions)
+	}
+	if actions[0].Value["thread_id"] != "om_root" || actions[0].Value["chat_type"] != "group" {
+		t.Fatalf("button context = %#v", actions[0].Value)
+	}
+}
+
+func TestFileType(t *testing.T) {
+	for name, want := range map[string]string{
+		"report.pdf":  "pdf",
+		"notes.docx":  "doc",
+		"data.xlsx":   "xls",
+		"archive.zip": "stream",
+	} {
+		if got := fileType(name); got != want {
+			t.Errorf("fileType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
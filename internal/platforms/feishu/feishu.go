@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kayz/coco/internal/router"
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
+	"github.com/larksuite/oapi-sdk-go/v3/core/httpserverext"
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
 	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher"
 	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
@@ -22,6 +26,7 @@ import (
 type Platform struct {
 	client         *lark.Client
 	wsClient       *larkws.Client
+	httpServer     *http.Server // event subscription callbacks; nil in long-connection mode
 	botOpenID      string
 	messageHandler func(msg router.Message)
 	ctx            context.Context
@@ -32,6 +37,13 @@ type Platform struct {
 type Config struct {
 	AppID     string // from Feishu Developer Console
 	AppSecret string // from Feishu Developer Console
+
+	// CallbackListen switches from the long connection to HTTP event
+	// subscription, e.g. ":9000". Events are served at /feishu/event and
+	// card actions at /feishu/card.
+	CallbackListen    string
+	VerificationToken string // "Verification Token" of the event subscription
+	EncryptKey        string // "Encrypt Key"; required when callbacks are encrypted
 }
 
 // New creates a new Feishu platform
@@ -53,12 +65,27 @@ func New(cfg Config) (*Platform, error) {
 		botOpenID: botOpenID,
 	}
 
-	// Create WebSocket client with event handler
-	p.wsClient = larkws.NewClient(cfg.AppID, cfg.AppSecret,
-		larkws.WithEventHandler(p.buildEventHandler()),
-		larkws.WithLogLevel(larkcore.LogLevelInfo),
-	)
+	handler := p.buildEventHandler(cfg.VerificationToken, cfg.EncryptKey)
+	if cfg.CallbackListen == "" {
+		// Create WebSocket client with event handler
+		p.wsClient = larkws.NewClient(cfg.AppID, cfg.AppSecret,
+			larkws.WithEventHandler(handler),
+			larkws.WithLogLevel(larkcore.LogLevelInfo),
+		)
+		return p, nil
+	}
 
+	// The dispatcher verifies the token and decrypts encrypted callbacks.
+	// New-style card callbacks arrive as events, so both paths share it.
+	eventHandler := httpserverext.NewEventHandlerFunc(handler, larkevent.WithLogLevel(larkcore.LogLevelInfo))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feishu/event", eventHandler)
+	mux.HandleFunc("/feishu/card", eventHandler)
+	p.httpServer = &http.Server{
+		Addr:              cfg.CallbackListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 	return p, nil
 }
 
@@ -76,6 +103,16 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
+	if p.httpServer != nil {
+		go func() {
+			if err := p.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Feishu] Callback server error: %v", err)
+			}
+		}()
+		log.Printf("[Feishu] Listening for event callbacks on %s as bot: %s", p.httpServer.Addr, p.botOpenID)
+		return nil
+	}
+
 	go func() {
 		if err := p.wsClient.Start(p.ctx); err != nil {
 			log.Printf("[Feishu] WebSocket error: %v", err)
@@ -91,22 +128,73 @@ func (p *Platform) Stop() error {
 	if p.cancel != nil {
 		p.cancel()
 	}
+	if p.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return p.httpServer.Shutdown(ctx)
+	}
 	return nil
 }
 
-// Send sends a message to a Feishu chat
+// Send sends a message to a Feishu chat. Responses to a thread are
+// replied in that thread; pending approvals become an interactive card.
 func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response) error {
-	content, err := json.Marshal(map[string]string{"text": resp.Text})
-	if err != nil {
-		return fmt.Errorf("failed to marshal message content: %w", err)
+	if resp.Text != "" {
+		msgType, content := larkim.MsgTypeText, ""
+		if resp.Metadata["approval"] == "pending" {
+			msgType = larkim.MsgTypeInteractive
+			content = approvalCard(resp.Text, resp.ThreadID, resp.Metadata["chat_type"])
+		} else {
+			data, err := json.Marshal(map[string]string{"text": resp.Text})
+			if err != nil {
+				return fmt.Errorf("failed to marshal message content: %w", err)
+			}
+			content = string(data)
+		}
+		if err := p.sendMessage(ctx, chatID, resp.ThreadID, msgType, content); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range resp.Files {
+		msgType, content, err := p.uploadFile(ctx, file)
+		if err != nil {
+			return err
+		}
+		if err := p.sendMessage(ctx, chatID, resp.ThreadID, msgType, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendMessage posts to the chat, or replies in the thread of threadID.
+func (p *Platform) sendMessage(ctx context.Context, chatID, threadID, msgType, content string) error {
+	if threadID != "" {
+		req := larkim.NewReplyMessageReqBuilder().
+			MessageId(threadID).
+			Body(larkim.NewReplyMessageReqBodyBuilder().
+				MsgType(msgType).
+				Content(content).
+				ReplyInThread(true).
+				Build()).
+			Build()
+		result, err := p.client.Im.Message.Reply(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to reply in thread: %w", err)
+		}
+		if !result.Success() {
+			return fmt.Errorf("failed to reply in thread: code=%d, msg=%s", result.Code, result.Msg)
+		}
+		return nil
 	}
 
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
-			MsgType(larkim.MsgTypeText).
-			Content(string(content)).
+			MsgType(msgType).
+			Content(content).
 			Build()).
 		Build()
 
@@ -123,9 +211,10 @@ func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response
 }
 
 // buildEventHandler creates the event handler for WebSocket events
-func (p *Platform) buildEventHandler() *dispatcher.EventDispatcher {
-	handler := dispatcher.NewEventDispatcher("", "")
+func (p *Platform) buildEventHandler(verificationToken, encryptKey string) *dispatcher.EventDispatcher {
+	handler := dispatcher.NewEventDispatcher(verificationToken, encryptKey)
 	handler.OnP2MessageReceiveV1(p.handleMessageEvent)
+	handler.OnP2CardActionTrigger(p.handleCardAction)
 	return handler
 }
 
@@ -180,6 +269,15 @@ func (p *Platform) handleMessageEvent(ctx context.Context, event *larkim.P2Messa
 			msgID = *msg.MessageId
 		}
 
+		// Answer inside the thread the message belongs to. In topic groups
+		// every post starts a thread of its own.
+		threadID := ""
+		if msg.RootId != nil && *msg.RootId != "" {
+			threadID = *msg.RootId
+		} else if chatType == "topic_group" {
+			threadID = msgID
+		}
+
 		p.messageHandler(router.Message{
 			ID:        msgID,
 			Platform:  "feishu",
@@ -187,7 +285,7 @@ func (p *Platform) handleMessageEvent(ctx context.Context, event *larkim.P2Messa
 			UserID:    userID,
 			Username:  username,
 			Text:      text,
-			ThreadID:  "", // Feishu doesn't have traditional threading like Slack
+			ThreadID:  threadID,
 			Metadata: map[string]string{
 				"chat_type": chatType,
 				"mentioned": strconv.FormatBool(p.isFeishuMentioned(msg)),
@@ -295,30 +393,27 @@ func (p *Platform) getUsername(ctx context.Context, openID string) string {
 	return openID
 }
 
-// getBotOpenID retrieves the bot's open_id
+// getBotOpenID retrieves the bot's open_id, which also verifies the credentials
 func getBotOpenID(client *lark.Client) (string, error) {
-	// Use bot info API to get bot's open_id
-	// This is done by calling the auth endpoint which returns bot info
-	ctx := context.Background()
-
-	// Get tenant access token to verify credentials and get bot info
-	// The bot's open_id can be retrieved from the /bot/v3/info endpoint
-	req := larkim.NewListChatReqBuilder().
-		PageSize(1).
-		Build()
-
-	result, err := client.Im.Chat.List(ctx, req)
+	resp, err := client.Get(context.Background(), "/open-apis/bot/v3/info", nil, larkcore.AccessTokenTypeTenant)
 	if err != nil {
 		return "", fmt.Errorf("failed to verify credentials: %w", err)
 	}
 
-	if !result.Success() {
-		return "", fmt.Errorf("failed to verify credentials: code=%d, msg=%s", result.Code, result.Msg)
+	var info struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Bot  struct {
+			OpenID string `json:"open_id"`
+		} `json:"bot"`
+	}
+	if err := json.Unmarshal(resp.RawBody, &info); err != nil {
+		return "", fmt.Errorf("failed to parse bot info: %w", err)
+	}
+	if info.Code != 0 {
+		return "", fmt.Errorf("failed to verify credentials: code=%d, msg=%s", info.Code, info.Msg)
 	}
 
-	// For now, we'll identify the bot by its messages
-	// The bot's open_id will be populated when we receive our first message
-	// This is a workaround since the SDK doesn't expose bot info directly
 	log.Printf("[Feishu] Credentials verified successfully")
-	return "", nil
+	return info.Bot.OpenID, nil
 }
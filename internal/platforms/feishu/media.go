@@ -0,0 +1,82 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kayz/coco/internal/router"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// uploadFile uploads an attachment and returns the message type and content
+// that send it. Images are sent inline; everything else as a file.
+func (p *Platform) uploadFile(ctx context.Context, file router.FileAttachment) (string, string, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	if file.MediaType == "image" {
+		req := larkim.NewCreateImageReqBuilder().
+			Body(larkim.NewCreateImageReqBodyBuilder().
+				ImageType(larkim.ImageTypeMessage).
+				Image(f).
+				Build()).
+			Build()
+		result, err := p.client.Im.Image.Create(ctx, req)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to upload image: %w", err)
+		}
+		if !result.Success() || result.Data == nil || result.Data.ImageKey == nil {
+			return "", "", fmt.Errorf("failed to upload image: code=%d, msg=%s", result.Code, result.Msg)
+		}
+		content, _ := json.Marshal(map[string]string{"image_key": *result.Data.ImageKey})
+		return larkim.MsgTypeImage, string(content), nil
+	}
+
+	name := file.Name
+	if name == "" {
+		name = filepath.Base(file.Path)
+	}
+	req := larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(fileType(name)).
+			FileName(name).
+			File(f).
+			Build()).
+		Build()
+	result, err := p.client.Im.File.Create(ctx, req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	if !result.Success() || result.Data == nil || result.Data.FileKey == nil {
+		return "", "", fmt.Errorf("failed to upload file: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	content, _ := json.Marshal(map[string]string{"file_key": *result.Data.FileKey})
+	return larkim.MsgTypeFile, string(content), nil
+}
+
+// fileType maps a file name to the upload type Feishu expects.
+func fileType(name string) string {
+	switch filepath.Ext(name) {
+	case ".pdf":
+		return larkim.FileTypePdf
+	case ".doc", ".docx":
+		return larkim.FileTypeDoc
+	case ".xls", ".xlsx":
+		return larkim.FileTypeXls
+	case ".ppt", ".pptx":
+		return larkim.FileTypePpt
+	case ".mp4":
+		return larkim.FileTypeMp4
+	case ".opus":
+		return larkim.FileTypeOpus
+	default:
+		return larkim.FileTypeStream
+	}
+}
@@ -67,6 +67,8 @@ type Platform struct {
 	conn           *websocket.Conn
 	connMu         sync.Mutex
 	sessionID      string
+	lastSeq        int64 // highest message sequence number received in this session
+	features       map[string]bool // optional protocol features the server offered
 	messageHandler func(msg router.Message)
 	httpClient     *http.Client
 	ctx            context.Context
@@ -105,6 +107,10 @@ type AuthMessage struct {
 	WeComSecret  string `json:"wecom_secret,omitempty"`
 	WeComToken   string `json:"wecom_token,omitempty"`
 	WeComAESKey  string `json:"wecom_aes_key,omitempty"`
+	// ResumeSession asks the server to continue a session after a brief
+	// disconnect and redeliver messages with a sequence number above LastSeq.
+	ResumeSession string `json:"resume_session,omitempty"`
+	LastSeq       int64  `json:"last_seq,omitempty"`
 }
 
 // AuthResult is the response to authentication
@@ -113,6 +119,8 @@ type AuthResult struct {
 	Success   bool   `json:"success"`
 	SessionID string `json:"session_id"`
 	Error     string `json:"error,omitempty"`
+	Resumed   bool   `json:"resumed,omitempty"` // the requested session was continued
+	Features  []string `json:"features,omitempty"` // optional protocol features, e.g. FeatureGzip
 }
 
 // IncomingMessage is a message from the server
@@ -126,6 +134,7 @@ type IncomingMessage struct {
 	Text      string            `json:"text"`
 	ThreadID  string            `json:"thread_id"`
 	Metadata  map[string]string `json:"metadata"`
+	Seq       int64             `json:"seq,omitempty"` // per-session sequence number, for resumption
 }
 
 // OutgoingResponse is sent via webhook
@@ -143,6 +152,7 @@ type OutgoingFile struct {
 	Name      string `json:"name"`       // filename
 	MediaType string `json:"media_type"` // "image", "voice", "video", "file"
 	Data      string `json:"data"`       // base64-encoded file content
+	UploadID  string `json:"upload_id,omitempty"` // set instead of Data for a chunked upload
 }
 
 // ErrorMessage is an error notification from the server
@@ -415,26 +425,7 @@ func (p *Platform) sendWebhook(ctx context.Context, channelID string, resp route
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.WebhookURL, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Session-ID", p.sessionID)
-	req.Header.Set("X-User-ID", p.config.UserID)
-
-	httpResp, err := p.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send webhook: %w", err)
-	}
-	defer httpResp.Body.Close()
-
-	if httpResp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d", httpResp.StatusCode)
-	}
-
-	return nil
+	return p.postWebhook(ctx, body, "webhook")
 }
 
 // sendFileAsText reads a file and sends its content as a truncated text message via webhook (passive reply).
@@ -468,17 +459,25 @@ func (p *Platform) sendFileViaWebhook(ctx context.Context, channelID, filePath,
 		return fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
 
+	file := OutgoingFile{
+		Name:      filepath.Base(filePath),
+		MediaType: mediaType,
+	}
+	if len(content) > uploadThreshold && p.hasFeature(FeatureUpload) {
+		// Large files go up in resumable chunks; the webhook only references them.
+		uploadID, err := p.uploadFile(ctx, content)
+		if err != nil {
+			return fmt.Errorf("failed to upload file %s: %w", filePath, err)
+		}
+		file.UploadID = uploadID
+	} else {
+		file.Data = base64.StdEncoding.EncodeToString(content)
+	}
 	outgoing := OutgoingResponse{
 		Type:      "response",
 		Platform:  p.config.Platform,
 		ChannelID: channelID,
-		Files: []OutgoingFile{
-			{
-				Name:      filepath.Base(filePath),
-				MediaType: mediaType,
-				Data:      base64.StdEncoding.EncodeToString(content),
-			},
-		},
+		Files:     []OutgoingFile{file},
 	}
 	if metadata != nil {
 		outgoing.MessageID = metadata["message_id"]
@@ -489,23 +488,8 @@ func (p *Platform) sendFileViaWebhook(ctx context.Context, channelID, filePath,
 		return fmt.Errorf("failed to marshal file response: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.WebhookURL, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Session-ID", p.sessionID)
-	req.Header.Set("X-User-ID", p.config.UserID)
-
-	httpResp, err := p.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send file webhook: %w", err)
-	}
-	defer httpResp.Body.Close()
-
-	if httpResp.StatusCode >= 400 {
-		return fmt.Errorf("file webhook returned status %d", httpResp.StatusCode)
+	if err := p.postWebhook(ctx, body, "file webhook"); err != nil {
+		return err
 	}
 
 	log.Printf("[Relay] File sent via webhook successfully: %s -> %s", filePath, channelID)
@@ -518,6 +502,8 @@ func (p *Platform) connect() error {
 
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
+		// permessage-deflate, if the server agrees to it
+		EnableCompression: true,
 	}
 
 	conn, resp, err := dialer.DialContext(p.ctx, p.config.ServerURL, nil)
@@ -526,8 +512,13 @@ func (p *Platform) connect() error {
 	}
 	debug.Log("WebSocket connected, status: %s", resp.Status)
 
-	// Send authentication
+	// Send authentication, asking to resume the previous session if any
+	p.connMu.Lock()
+	resumeSession, lastSeq := p.sessionID, p.lastSeq
+	p.connMu.Unlock()
 	authMsg := AuthMessage{
+		ResumeSession: resumeSession,
+		LastSeq:       lastSeq,
 		Type:          "auth",
 		UserID:        p.config.UserID,
 		Platform:      p.config.Platform,
@@ -586,10 +577,24 @@ func (p *Platform) connect() error {
 
 	p.connMu.Lock()
 	p.conn = conn
+	if authResult.SessionID != p.sessionID {
+		p.lastSeq = 0
+	}
 	p.sessionID = authResult.SessionID
+	p.features = make(map[string]bool, len(authResult.Features))
+	for _, f := range authResult.Features {
+		p.features[f] = true
+	}
 	p.connMu.Unlock()
 
-	log.Printf("[Relay] Authenticated, session: %s", p.sessionID)
+	switch {
+	case authResult.Resumed:
+		log.Printf("[Relay] Resumed session %s; messages buffered while disconnected will follow", authResult.SessionID)
+	case resumeSession != "":
+		log.Printf("[Relay] Previous session %s could not be resumed; new session: %s", resumeSession, authResult.SessionID)
+	default:
+		log.Printf("[Relay] Authenticated, session: %s", authResult.SessionID)
+	}
 	return nil
 }
 
@@ -630,8 +635,9 @@ func (p *Platform) readLoop() {
 			if closeErr, ok := err.(*websocket.CloseError); ok {
 				// Policy violation means another client connected with same user-id
 				if closeErr.Code == websocket.ClosePolicyViolation {
-					log.Printf("[Relay] Disconnected: %s", closeErr.Text)
-					log.Printf("[Relay] Exiting - please ensure only one client is running per user-id")
+					log.Printf("[Relay] Disconnected by server: %s", closeErr.Text)
+					log.Printf("[Relay] Another coco connected with user-id %s and took over this session.", p.config.UserID)
+					log.Printf("[Relay] Exiting - run `coco status` on your machines to find the other instance; only one may run per user-id")
 					os.Exit(1)
 				}
 				if closeErr.Text != "" {
@@ -662,12 +668,17 @@ func (p *Platform) readLoop() {
 		// Parse message type
 		var jsonMsg struct {
 			Type string `json:"type"`
+			Seq  int64  `json:"seq"`
 		}
 		if err := json.Unmarshal(message, &jsonMsg); err != nil {
 			debug.Log("Failed to parse JSON: %v, raw: %s", err, string(message))
 			log.Printf("[Relay] Failed to parse message type: %v", err)
 			continue
 		}
+		if jsonMsg.Seq > 0 && !p.acceptSeq(jsonMsg.Seq) {
+			debug.Log("Skipping already handled message seq=%d", jsonMsg.Seq)
+			continue
+		}
 
 		debug.Log("Message type: %s", jsonMsg.Type)
 
@@ -691,6 +702,18 @@ func (p *Platform) readLoop() {
 	}
 }
 
+// acceptSeq records seq as received and reports whether it is new; a
+// resumed session may redeliver messages that arrived just before the drop.
+func (p *Platform) acceptSeq(seq int64) bool {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+	if seq <= p.lastSeq {
+		return false
+	}
+	p.lastSeq = seq
+	return true
+}
+
 // handleMessage processes an incoming message
 func (p *Platform) handleMessage(data []byte) {
 	var msg IncomingMessage
@@ -0,0 +1,181 @@
+package relay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Optional protocol features a server lists in AuthResult.Features. Clients
+// only use them when offered, so older servers keep working.
+const (
+	FeatureGzip   = "gzip"   // webhook bodies may be sent with Content-Encoding: gzip
+	FeatureUpload = "upload" // large files go through chunked uploads to <webhook>/upload
+)
+
+// Headers of a chunked upload request.
+const (
+	UploadIDHeader     = "X-Upload-ID"
+	UploadOffsetHeader = "X-Upload-Offset"
+	UploadTotalHeader  = "X-Upload-Total"
+)
+
+const (
+	gzipThreshold    = 1 << 10   // webhook bodies smaller than this are sent as is
+	uploadThreshold  = 1 << 20   // files larger than this are uploaded in chunks
+	uploadChunkSize  = 256 << 10 // bytes per chunk
+	uploadMaxRetries = 5         // consecutive failed attempts before giving up
+)
+
+// UploadStatus is the server's answer to a chunk or a status query.
+type UploadStatus struct {
+	Received int64 `json:"received"` // bytes stored so far; the next chunk starts here
+}
+
+// UploadURL returns the chunked upload endpoint belonging to a webhook URL.
+func UploadURL(webhookURL string) string {
+	return strings.TrimSuffix(webhookURL, "/") + "/upload"
+}
+
+// hasFeature reports whether the server offered feature in this session.
+func (p *Platform) hasFeature(feature string) bool {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+	return p.features[feature]
+}
+
+// postWebhook POSTs a JSON body to the webhook, gzipped when the server
+// accepts it and the body is worth compressing.
+func (p *Platform) postWebhook(ctx context.Context, body []byte, what string) error {
+	encoding := ""
+	if len(body) >= gzipThreshold && p.hasFeature(FeatureGzip) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err == nil && zw.Close() == nil {
+			body, encoding = buf.Bytes(), "gzip"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	p.setSessionHeaders(req.Header)
+
+	httpResp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", what, err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned status %d", what, httpResp.StatusCode)
+	}
+	return nil
+}
+
+func (p *Platform) setSessionHeaders(h http.Header) {
+	p.connMu.Lock()
+	sessionID := p.sessionID
+	p.connMu.Unlock()
+	h.Set("X-Session-ID", sessionID)
+	h.Set("X-User-ID", p.config.UserID)
+}
+
+// uploadFile sends data in chunks and returns the upload ID to reference
+// it in an OutgoingFile. A failed chunk is retried with backoff after
+// asking the server how much it already has, so nothing is sent twice.
+func (p *Platform) uploadFile(ctx context.Context, data []byte) (string, error) {
+	id := newUploadID()
+	url := UploadURL(p.config.WebhookURL)
+	total := int64(len(data))
+	var offset int64
+	failures := 0
+	delay := time.Second
+
+	for offset < total {
+		end := min(offset+uploadChunkSize, total)
+		status, err := p.sendChunk(ctx, url, id, offset, total, data[offset:end])
+		if err == nil {
+			offset, failures, delay = status.Received, 0, time.Second
+			continue
+		}
+		failures++
+		if failures >= uploadMaxRetries || ctx.Err() != nil {
+			return "", fmt.Errorf("upload %s failed at %d/%d bytes: %w", id, offset, total, err)
+		}
+		log.Printf("[Relay] Upload chunk at %d/%d failed (%v), retrying in %s", offset, total, err, delay)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+		if status, err := p.uploadStatus(ctx, url, id); err == nil {
+			offset = status.Received
+		}
+	}
+	return id, nil
+}
+
+func (p *Platform) sendChunk(ctx context.Context, url, id string, offset, total int64, chunk []byte) (UploadStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(chunk))
+	if err != nil {
+		return UploadStatus{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(UploadIDHeader, id)
+	req.Header.Set(UploadOffsetHeader, strconv.FormatInt(offset, 10))
+	req.Header.Set(UploadTotalHeader, strconv.FormatInt(total, 10))
+	p.setSessionHeaders(req.Header)
+	return p.doUpload(req)
+}
+
+func (p *Platform) uploadStatus(ctx context.Context, url, id string) (UploadStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return UploadStatus{}, err
+	}
+	req.Header.Set(UploadIDHeader, id)
+	p.setSessionHeaders(req.Header)
+	return p.doUpload(req)
+}
+
+// doUpload runs an upload request. A 409 means the offset was wrong; its
+// body still carries the server's position, which is all the caller needs.
+func (p *Platform) doUpload(req *http.Request) (UploadStatus, error) {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return UploadStatus{}, err
+	}
+	defer resp.Body.Close()
+	var status UploadStatus
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		io.Copy(io.Discard, resp.Body)
+		return status, fmt.Errorf("upload returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return status, fmt.Errorf("invalid upload response: %w", err)
+	}
+	return status, nil
+}
+
+func newUploadID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
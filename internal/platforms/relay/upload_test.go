@@ -0,0 +1,75 @@
+package relay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestUploadFileResumesAfterFailure(t *testing.T) {
+	var mu sync.Mutex
+	var stored []byte
+	failed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			offset, _ := strconv.Atoi(r.Header.Get(UploadOffsetHeader))
+			chunk, _ := io.ReadAll(r.Body)
+			if offset != len(stored) {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				stored = append(stored, chunk...)
+				if !failed && len(stored) > uploadChunkSize {
+					// Keep the chunk but lose the reply, as a dropped connection would.
+					failed = true
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(UploadStatus{Received: int64(len(stored))})
+	}))
+	defer srv.Close()
+
+	p := &Platform{config: Config{UserID: "u1", WebhookURL: srv.URL + "/webhook"}, httpClient: srv.Client()}
+	data := bytes.Repeat([]byte("0123456789"), uploadChunkSize/4)
+	id, err := p.uploadFile(context.Background(), data)
+	if err != nil || id == "" {
+		t.Fatalf("upload = %q, %v", id, err)
+	}
+	if !failed || !bytes.Equal(stored, data) {
+		t.Fatalf("stored %d of %d bytes (failure injected: %v)", len(stored), len(data), failed)
+	}
+}
+
+func TestPostWebhookGzipsWhenOffered(t *testing.T) {
+	var encoding string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		var rd io.Reader = r.Body
+		if encoding == "gzip" {
+			rd, _ = gzip.NewReader(r.Body)
+		}
+		body, _ = io.ReadAll(rd)
+	}))
+	defer srv.Close()
+
+	p := &Platform{config: Config{WebhookURL: srv.URL}, httpClient: srv.Client()}
+	payload := bytes.Repeat([]byte("a"), gzipThreshold)
+	if err := p.postWebhook(context.Background(), payload, "webhook"); err != nil || encoding != "" {
+		t.Fatalf("without feature: err=%v encoding=%q", err, encoding)
+	}
+	p.features = map[string]bool{FeatureGzip: true}
+	if err := p.postWebhook(context.Background(), payload, "webhook"); err != nil || encoding != "gzip" || !bytes.Equal(body, payload) {
+		t.Fatalf("with feature: err=%v encoding=%q len=%d", err, encoding, len(body))
+	}
+}
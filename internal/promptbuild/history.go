@@ -11,6 +11,7 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/kayz/coco/internal/logger"
+	"github.com/kayz/coco/internal/persist"
 )
 
 type historyMessage struct {
@@ -103,13 +104,22 @@ func loadHistory(dbPath string, spec HistorySpec, limit int) ([]historyMessage,
 		if err := rows.Scan(&role, &content, &createdAt); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
+		text := content.String
+		if persist.IsSealed(text) {
+			if spec.Reveal == nil {
+				continue
+			}
+			if text, err = spec.Reveal(text); err != nil {
+				return nil, fmt.Errorf("decrypt message: %w", err)
+			}
+		}
 		var ts time.Time
 		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			ts = t
 		}
 		reversed = append(reversed, historyMessage{
 			Role:      role,
-			Content:   content.String,
+			Content:   text,
 			CreatedAt: ts,
 		})
 	}
@@ -7,6 +7,10 @@ type HistorySpec struct {
 	ChannelID      string `json:"channel_id,omitempty"`
 	UserID         string `json:"user_id,omitempty"`
 	Limit          int    `json:"limit,omitempty"`
+
+	// Reveal decrypts content from an encrypted store. Without it, sealed
+	// messages are left out.
+	Reveal func(string) (string, error) `json:"-"`
 }
 
 // BuildRequest defines inputs for prompt assembly.
@@ -0,0 +1,202 @@
+package provenance
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one line of the config audit log: the file, its content digest
+// after the change, and the signed record of who made it.
+type AuditEntry struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+	Record Record `json:"record"`
+}
+
+var auditMu sync.Mutex
+
+// DefaultAuditPath is .coco/audit.jsonl next to the signing key.
+func DefaultAuditPath() string {
+	return filepath.Join(filepath.Dir(DefaultKeyPath()), "audit.jsonl")
+}
+
+// FileDigest returns the hex sha256 of a file's content.
+func FileDigest(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RecordChange appends a signed entry for the current content of path.
+// signer may be nil, in which case the entry is recorded unsigned.
+func RecordChange(auditPath string, signer *Signer, path string, rec Record) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	digest, err := FileDigest(abs)
+	if err != nil {
+		return err
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	rec.CreatedAt = rec.CreatedAt.UTC().Truncate(time.Second)
+	entry := AuditEntry{Path: abs, Digest: digest, Record: rec}
+	if signer != nil {
+		if err := signer.Sign(&entry.Record, entry.subject()); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadAudit returns all entries of the audit log, oldest first.
+// A missing log yields no entries; malformed lines are skipped.
+func ReadAudit(auditPath string) ([]AuditEntry, error) {
+	f, err := os.Open(auditPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Path != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// PruneAudit removes entries recorded before the cutoff and returns how
+// many, or how many it would remove when dryRun is set. The last entry of
+// each file is kept so its current content can still be verified.
+func PruneAudit(auditPath string, before time.Time, dryRun bool) (int, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	data, err := os.ReadFile(auditPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	entries := make([]*AuditEntry, len(lines))
+	last := map[string]int{}
+	for i, line := range lines {
+		var e AuditEntry
+		if json.Unmarshal([]byte(line), &e) == nil && e.Path != "" {
+			entries[i] = &e
+			last[e.Path] = i
+		}
+	}
+	var kept []string
+	removed := 0
+	for i, line := range lines {
+		if e := entries[i]; e != nil && last[e.Path] != i && e.Record.CreatedAt.Before(before) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if dryRun || removed == 0 {
+		return removed, nil
+	}
+
+	tmp := auditPath + ".tmp"
+	out := strings.Join(kept, "\n")
+	if out != "" {
+		out += "\n"
+	}
+	if err := os.WriteFile(tmp, []byte(out), 0o600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, auditPath); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return removed, nil
+}
+
+// LastChange returns the most recent entry for path, or nil.
+func LastChange(auditPath, path string) (*AuditEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ReadAudit(auditPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Path == abs {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Verify checks the entry's signature.
+func (e *AuditEntry) Verify(signer *Signer) Status {
+	return signer.Verify(&e.Record, e.subject())
+}
+
+func (e *AuditEntry) subject() any {
+	return struct {
+		Path   string `json:"path"`
+		Digest string `json:"digest"`
+	}{e.Path, e.Digest}
+}
+
+// RecordConfigChange records a change to a config file with the default key and
+// audit log, unless the last entry already covers the file's current content.
+func RecordConfigChange(path string, rec Record) error {
+	auditPath := DefaultAuditPath()
+	if last, err := LastChange(auditPath, path); err == nil && last != nil {
+		if digest, err := FileDigest(path); err == nil && digest == last.Digest {
+			return nil
+		}
+	}
+	signer, err := LoadOrCreate(DefaultKeyPath())
+	if err != nil {
+		signer = nil
+	}
+	return RecordChange(auditPath, signer, path, rec)
+}
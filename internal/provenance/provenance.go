@@ -0,0 +1,179 @@
+// Package provenance signs records of who or what created a cron job or
+// changed configuration, using a per-installation ed25519 key.
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kayz/coco/internal/datadir"
+)
+
+// Origins of a job or config change.
+const (
+	OriginUser          = "user"           // Explicit chat message from a user
+	OriginHeartbeat     = "heartbeat"      // HEARTBEAT.md task registration
+	OriginCron          = "cron"           // A scheduled prompt acting on its own
+	OriginExternalAgent = "external-agent" // Remote agent via the keeper API
+	OriginKeeperAPI     = "keeper-api"     // Keeper HTTP API caller
+	OriginMCP           = "mcp"            // MCP client
+	OriginCLI           = "cli"            // coco command line
+	OriginSystem        = "system"         // Built-in job created by coco itself
+	OriginSync          = "sync"           // Imported from another device
+	OriginExternalEdit  = "external-edit"  // File changed outside coco
+)
+
+// Record describes who or what caused a change.
+type Record struct {
+	Origin       string    `json:"origin"`
+	Actor        string    `json:"actor,omitempty"`        // Username, agent name or API client
+	MessageID    string    `json:"message_id,omitempty"`   // Triggering chat message
+	Conversation string    `json:"conversation,omitempty"` // platform:channel:user of the trigger
+	Reason       string    `json:"reason,omitempty"`       // Short excerpt or note
+	CreatedAt    time.Time `json:"created_at"`
+	Signature    string    `json:"signature,omitempty"` // ed25519:<pubkey>:<sig>, over the record and subject
+}
+
+// Status is the outcome of verifying a signature.
+type Status string
+
+const (
+	StatusValid    Status = "valid"    // Signed by this installation's key
+	StatusForeign  Status = "foreign"  // Correctly signed by another key (e.g. a synced device)
+	StatusInvalid  Status = "invalid"  // Signature does not match: the record or subject was altered
+	StatusUnsigned Status = "unsigned" // Created before provenance existed, or bypassed coco
+)
+
+// Describe renders the record for humans.
+func (r *Record) Describe() string {
+	if r == nil {
+		return "unknown origin"
+	}
+	parts := []string{r.Origin}
+	if r.Actor != "" {
+		parts = append(parts, "by "+r.Actor)
+	}
+	if r.Conversation != "" {
+		parts = append(parts, "in "+r.Conversation)
+	}
+	if r.MessageID != "" {
+		parts = append(parts, "msg "+r.MessageID)
+	}
+	s := strings.Join(parts, " ")
+	if r.Reason != "" {
+		s += fmt.Sprintf(" — %q", r.Reason)
+	}
+	return s
+}
+
+// Signer holds the installation's signing key.
+type Signer struct {
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+// DefaultKeyPath is .coco/signing.key in the data directory.
+func DefaultKeyPath() string {
+	return datadir.Path(".coco", "signing.key")
+}
+
+// LoadOrCreate reads the hex-encoded ed25519 seed at path, generating one (0600) if missing.
+func LoadOrCreate(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(seed)+"\n"), 0o600); err != nil {
+			return nil, fmt.Errorf("write signing key: %w", err)
+		}
+		return newSigner(seed), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key %s is malformed", path)
+	}
+	return newSigner(seed), nil
+}
+
+func newSigner(seed []byte) *Signer {
+	priv := ed25519.NewKeyFromSeed(seed)
+	return &Signer{priv: priv, pub: priv.Public().(ed25519.PublicKey)}
+}
+
+// KeyID is a short fingerprint of the public key.
+func (s *Signer) KeyID() string {
+	sum := sha256.Sum256(s.pub)
+	return hex.EncodeToString(sum[:6])
+}
+
+// Sign sets r.Signature over the record and the subject (e.g. the job definition).
+func (s *Signer) Sign(r *Record, subject any) error {
+	payload, err := signingPayload(r, subject)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(s.priv, payload)
+	r.Signature = "ed25519:" + base64.RawStdEncoding.EncodeToString(s.pub) + ":" + base64.RawStdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks r.Signature against the record and subject.
+func (s *Signer) Verify(r *Record, subject any) Status {
+	if r == nil || r.Signature == "" {
+		return StatusUnsigned
+	}
+	parts := strings.Split(r.Signature, ":")
+	if len(parts) != 3 || parts[0] != "ed25519" {
+		return StatusInvalid
+	}
+	pub, err1 := base64.RawStdEncoding.DecodeString(parts[1])
+	sig, err2 := base64.RawStdEncoding.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || len(pub) != ed25519.PublicKeySize {
+		return StatusInvalid
+	}
+	payload, err := signingPayload(r, subject)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return StatusInvalid
+	}
+	if s != nil && s.pub.Equal(ed25519.PublicKey(pub)) {
+		return StatusValid
+	}
+	return StatusForeign
+}
+
+func signingPayload(r *Record, subject any) ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	unsigned.CreatedAt = unsigned.CreatedAt.UTC().Truncate(time.Second)
+	return json.Marshal(struct {
+		Record  Record `json:"record"`
+		Subject any    `json:"subject"`
+	}{unsigned, subject})
+}
+
+// Excerpt shortens text for the Reason field.
+func Excerpt(text string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > maxRunes {
+		return string(r[:maxRunes]) + "…"
+	}
+	return text
+}
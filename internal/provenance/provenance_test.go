@@ -0,0 +1,68 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrCreateReusesKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".coco", "signing.key")
+	first, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("key file mode = %o, want 600", perm)
+	}
+	second, err := LoadOrCreate(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if first.KeyID() != second.KeyID() {
+		t.Fatalf("reloaded key differs: %s vs %s", first.KeyID(), second.KeyID())
+	}
+}
+
+func TestAuditEntriesAreSigned(t *testing.T) {
+	dir := t.TempDir()
+	signer, err := LoadOrCreate(filepath.Join(dir, "signing.key"))
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	auditPath := filepath.Join(dir, "audit.jsonl")
+	cfg := filepath.Join(dir, ".coco.yaml")
+	if err := os.WriteFile(cfg, []byte("ai:\n  model: a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecordChange(auditPath, signer, cfg, Record{Origin: OriginCLI, Actor: "coco onboard"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := os.WriteFile(cfg, []byte("ai:\n  model: b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordChange(auditPath, signer, cfg, Record{Origin: OriginExternalEdit}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	entries, err := ReadAudit(auditPath)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("read audit: %v (%d entries)", err, len(entries))
+	}
+	last, err := LastChange(auditPath, cfg)
+	if err != nil || last == nil || last.Record.Origin != OriginExternalEdit {
+		t.Fatalf("last change = %+v, %v", last, err)
+	}
+	if got := last.Verify(signer); got != StatusValid {
+		t.Fatalf("entry verify = %s", got)
+	}
+	last.Digest = "0000"
+	if got := last.Verify(signer); got != StatusInvalid {
+		t.Fatalf("tampered entry verify = %s", got)
+	}
+}
@@ -188,6 +188,14 @@ func (r *Router) SendToUser(platformName, channelID string, resp Response) error
 	return platform.Send(context.Background(), channelID, resp)
 }
 
+// HasPlatform reports whether a platform with this name is registered
+func (r *Router) HasPlatform(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.platforms[name]
+	return ok
+}
+
 // Wait blocks until the router is stopped
 func (r *Router) Wait() {
 	if r.ctx != nil {
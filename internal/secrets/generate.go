@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	lowerChars  = "abcdefghijkmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	digitChars  = "23456789"
+	symbolChars = "!@#$%^&*-_=+?"
+	hexChars    = "0123456789abcdef"
+)
+
+// Generate returns a random secret. kind is "password" (default), "pin" or "hex".
+// Passwords avoid look-alike characters and contain every enabled character class.
+func Generate(kind string, length int, symbols bool) (string, error) {
+	var classes []string
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", "password":
+		if length <= 0 {
+			length = 20
+		}
+		classes = []string{lowerChars, upperChars, digitChars}
+		if symbols {
+			classes = append(classes, symbolChars)
+		}
+	case "pin":
+		if length <= 0 {
+			length = 6
+		}
+		classes = []string{"0123456789"}
+	case "hex":
+		if length <= 0 {
+			length = 32
+		}
+		classes = []string{hexChars}
+	default:
+		return "", fmt.Errorf("unsupported kind %q (password, pin or hex)", kind)
+	}
+	if length < len(classes) || length > 256 {
+		return "", fmt.Errorf("length must be between %d and 256", len(classes))
+	}
+
+	all := strings.Join(classes, "")
+	out := make([]byte, length)
+	for i := range out {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+		c, err := randIndex(len(set))
+		if err != nil {
+			return "", err
+		}
+		out[i] = set[c]
+	}
+	// Shuffle so the guaranteed class characters are not always first.
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out), nil
+}
+
+func randIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
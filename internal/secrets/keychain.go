@@ -0,0 +1,19 @@
+package secrets
+
+import "errors"
+
+// ErrKeychainNotFound is returned when the keychain has no such entry.
+var ErrKeychainNotFound = errors.New("keychain entry not found")
+
+// KeychainGet reads the secret stored under service and account in the
+// OS keychain: the login keychain on macOS, the Secret Service (through
+// secret-tool) on Linux and the Credential Manager on Windows.
+func KeychainGet(service, account string) (string, error) {
+	return keychainGet(service, account)
+}
+
+// KeychainSet stores secret under service and account, replacing any
+// earlier value.
+func KeychainSet(service, account, secret string) error {
+	return keychainSet(service, account, secret)
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+func keychainGet(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		// Both tools exit non-zero for a missing entry.
+		if msg := strings.TrimSpace(stderr.String()); msg != "" && !strings.Contains(msg, "could not be found") {
+			return "", fmt.Errorf("%s: %s", cmd.Path, msg)
+		}
+		return "", ErrKeychainNotFound
+	case err != nil:
+		return "", fmt.Errorf("keychain unavailable: %w", err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", ErrKeychainNotFound
+	}
+	return secret, nil
+}
+
+func keychainSet(service, account, secret string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", service, "-a", account, "-w", secret)
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain store failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
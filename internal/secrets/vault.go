@@ -0,0 +1,310 @@
+// Package secrets implements an encrypted local vault for user secrets.
+//
+// Values are sealed with NaCl secretbox under a 32-byte master key kept next to
+// the vault (or supplied via COCO_VAULT_KEY). The agent only ever handles
+// references of the form {{secret:name}}; plaintext is substituted into tool
+// arguments at execution time and redacted from tool results.
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// KeyEnv overrides the key file with a hex/base64 key or a passphrase.
+const KeyEnv = "COCO_VAULT_KEY"
+
+// ErrNotFound is returned when a secret name is not in the vault.
+var ErrNotFound = errors.New("secret not found")
+
+var (
+	nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+	refRe  = regexp.MustCompile(`\{\{\s*secret:([A-Za-z0-9][A-Za-z0-9._-]{0,63})\s*\}\}`)
+)
+
+// Entry describes a stored secret without its value.
+type Entry struct {
+	Name      string    `json:"name"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type record struct {
+	Entry
+	Sealed string `json:"sealed"` // base64(nonce || secretbox)
+}
+
+type vaultFile struct {
+	Version int                `json:"version"`
+	Secrets map[string]*record `json:"secrets"`
+}
+
+// Vault is a file-backed secret store. It is safe for concurrent use.
+type Vault struct {
+	mu   sync.Mutex
+	path string
+	key  [32]byte
+}
+
+// Open opens (creating on first use) the vault at path. The key is read from
+// KeyEnv or from keyPath, which is generated with 0600 permissions if missing.
+func Open(path, keyPath string) (*Vault, error) {
+	v := &Vault{path: path}
+	if env := strings.TrimSpace(os.Getenv(KeyEnv)); env != "" {
+		v.key = parseKey(env)
+		return v, nil
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := rand.Read(v.key[:]); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyPath, []byte(hex.EncodeToString(v.key[:])+"\n"), 0o600); err != nil {
+			return nil, fmt.Errorf("write vault key: %w", err)
+		}
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vault key: %w", err)
+	}
+	v.key = parseKey(strings.TrimSpace(string(data)))
+	return v, nil
+}
+
+// parseKey accepts a 32-byte hex or base64 key; anything else is hashed as a passphrase.
+func parseKey(s string) [32]byte {
+	var key [32]byte
+	if b, err := hex.DecodeString(s); err == nil && len(b) == 32 {
+		copy(key[:], b)
+		return key
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 32 {
+		copy(key[:], b)
+		return key
+	}
+	return sha256.Sum256([]byte(s))
+}
+
+// ValidName reports whether name can be used as a secret name.
+func ValidName(name string) bool {
+	return nameRe.MatchString(name)
+}
+
+// Ref returns the placeholder that stands for the named secret.
+func Ref(name string) string {
+	return "{{secret:" + name + "}}"
+}
+
+// Set stores or replaces a secret.
+func (v *Vault) Set(name, value, note string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid secret name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	if value == "" {
+		return fmt.Errorf("secret value is empty")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := v.load()
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	sealed := secretbox.Seal(nonce[:], []byte(value), &nonce, &v.key)
+
+	now := time.Now()
+	rec, ok := f.Secrets[name]
+	if !ok {
+		rec = &record{Entry: Entry{Name: name, CreatedAt: now}}
+		f.Secrets[name] = rec
+	}
+	rec.UpdatedAt = now
+	if note != "" || !ok {
+		rec.Note = note
+	}
+	rec.Sealed = base64.StdEncoding.EncodeToString(sealed)
+	return v.save(f)
+}
+
+// Get returns the plaintext of a secret.
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := v.load()
+	if err != nil {
+		return "", err
+	}
+	rec, ok := f.Secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v.open(rec)
+}
+
+// Delete removes a secret.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Secrets[name]; !ok {
+		return ErrNotFound
+	}
+	delete(f.Secrets, name)
+	return v.save(f)
+}
+
+// List returns stored secrets sorted by name, without values.
+func (v *Vault) List() ([]Entry, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(f.Secrets))
+	for _, rec := range f.Secrets {
+		entries = append(entries, rec.Entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Expand replaces every {{secret:name}} in s with its plaintext.
+func (v *Vault) Expand(s string) (string, error) {
+	matches := refRe.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	values, err := v.Values()
+	if err != nil {
+		return "", err
+	}
+	for _, m := range matches {
+		if _, ok := values[m[1]]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, m[1])
+		}
+	}
+	return refRe.ReplaceAllStringFunc(s, func(ref string) string {
+		return values[refRe.FindStringSubmatch(ref)[1]]
+	}), nil
+}
+
+// Values decrypts every secret, keyed by name.
+func (v *Vault) Values() (map[string]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(f.Secrets))
+	for name, rec := range f.Secrets {
+		plain, err := v.open(rec)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = plain
+	}
+	return values, nil
+}
+
+// HasRef reports whether s contains a secret reference.
+func HasRef(s string) bool {
+	return refRe.MatchString(s)
+}
+
+// Redact replaces plaintext secret values in s with their references.
+// Values shorter than 4 characters are left alone to avoid mangling ordinary text.
+func Redact(s string, values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	// Longest first so a secret containing another is replaced whole.
+	sort.Slice(names, func(i, j int) bool { return len(values[names[i]]) > len(values[names[j]]) })
+	for _, name := range names {
+		if val := values[name]; len(val) >= 4 {
+			s = strings.ReplaceAll(s, val, Ref(name))
+		}
+	}
+	return s
+}
+
+func (v *Vault) open(rec *record) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(rec.Sealed)
+	if err != nil || len(sealed) < 24 {
+		return "", fmt.Errorf("secret %s is corrupted", rec.Name)
+	}
+	var nonce [24]byte
+	copy(nonce[:], sealed[:24])
+	plain, ok := secretbox.Open(nil, sealed[24:], &nonce, &v.key)
+	if !ok {
+		return "", fmt.Errorf("cannot decrypt secret %s (wrong vault key?)", rec.Name)
+	}
+	return string(plain), nil
+}
+
+func (v *Vault) load() (*vaultFile, error) {
+	f := &vaultFile{Version: 1, Secrets: map[string]*record{}}
+	data, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse vault: %w", err)
+	}
+	if f.Secrets == nil {
+		f.Secrets = map[string]*record{}
+	}
+	return f, nil
+}
+
+func (v *Vault) save(f *vaultFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
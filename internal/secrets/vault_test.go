@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestVaultRoundTripExpandAndRedact(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "vault.json")
+	keyPath := filepath.Join(dir, "vault.key")
+
+	v, err := Open(vaultPath, keyPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := v.Set("nas-password", "hunter2-secret", "home NAS"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	raw, _ := os.ReadFile(vaultPath)
+	if strings.Contains(string(raw), "hunter2-secret") {
+		t.Fatal("vault file contains plaintext")
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 key file, got %v %v", info, err)
+	}
+
+	reopened, err := Open(vaultPath, keyPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	expanded, err := reopened.Expand("mount -o password={{secret:nas-password}} //nas/share")
+	if err != nil || expanded != "mount -o password=hunter2-secret //nas/share" {
+		t.Fatalf("expand = %q, %v", expanded, err)
+	}
+	if _, err := reopened.Expand("{{secret:missing}}"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	values, _ := reopened.Values()
+	if got := Redact("login ok with hunter2-secret", values); got != "login ok with {{secret:nas-password}}" {
+		t.Fatalf("redact = %q", got)
+	}
+
+	entries, _ := reopened.List()
+	if len(entries) != 1 || entries[0].Name != "nas-password" || entries[0].Note != "home NAS" {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+
+	t.Setenv(KeyEnv, "some other passphrase")
+	wrongKey, _ := Open(vaultPath, keyPath)
+	if _, err := wrongKey.Get("nas-password"); err == nil {
+		t.Fatal("expected decryption failure with a different key")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	pw, err := Generate("", 16, true)
+	if err != nil || len(pw) != 16 {
+		t.Fatalf("generate = %q, %v", pw, err)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if !lower || !upper || !digit || !symbol {
+		t.Fatalf("password %q is missing a character class", pw)
+	}
+
+	pin, err := Generate("pin", 0, false)
+	if err != nil || len(pin) != 6 || strings.Trim(pin, "0123456789") != "" {
+		t.Fatalf("pin = %q, %v", pin, err)
+	}
+	if _, err := Generate("emoji", 8, false); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+}
@@ -2,13 +2,10 @@ package security
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
-)
 
-var (
-	exeDirCache string
+	"github.com/kayz/coco/internal/datadir"
 )
 
 // PathChecker validates file paths against an allowed list.
@@ -17,35 +14,9 @@ type PathChecker struct {
 	allowedPaths []string // resolved absolute paths
 }
 
-// getExecutableDir returns the directory where the executable is located
-func getExecutableDir() string {
-	if exeDirCache != "" {
-		return exeDirCache
-	}
-	execPath, err := os.Executable()
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	exeDirCache = filepath.Dir(execPath)
-	return exeDirCache
-}
-
-// expandTilde expands ~ to the executable directory instead of user home
+// expandTilde expands ~ to the data directory instead of user home
 func expandTilde(path string) string {
-	if len(path) > 0 && path[0] == '~' {
-		exeDir := getExecutableDir()
-		if len(path) == 1 {
-			return exeDir
-		}
-		return filepath.Join(exeDir, path[1:])
-	}
-	return path
+	return datadir.ExpandTilde(path)
 }
 
 // NewPathChecker creates a PathChecker from a list of allowed paths.
@@ -0,0 +1,77 @@
+package security
+
+import (
+	"path"
+	"strings"
+)
+
+// Built-in tool permission profiles.
+const (
+	ProfileAdmin    = "admin"
+	ProfileReadonly = "readonly"
+	ProfileNoShell  = "no-shell"
+)
+
+// ToolProfile limits which tools the model is offered and may call for a sender.
+// Patterns use path.Match syntax (e.g. "browser_*"). Deny wins over Allow;
+// an empty Allow list allows every tool not denied.
+type ToolProfile struct {
+	Name  string
+	Allow []string
+	Deny  []string
+}
+
+// BuiltinToolProfiles are available without configuration and can be
+// overridden by security.profiles entries of the same name.
+var BuiltinToolProfiles = map[string]ToolProfile{
+	ProfileAdmin: {Name: ProfileAdmin},
+	ProfileNoShell: {
+		Name: ProfileNoShell,
+		// Skill tools run commands the skill's author chose.
+		Deny: []string{"shell_execute", "browser_execute_js", "spawn_agent", "skill_*"},
+	},
+	ProfileReadonly: {
+		Name: ProfileReadonly,
+		Allow: []string{
+			"ai.list_models", "ai.get_current_model", "ai.model_health", "artifact_read",
+			"get_daily_report", "list_daily_reports", "search_messages", "get_conversation_summary",
+			"memory_search", "memory_get",
+			"file_read", "file_list", "file_list_old", "file_search", "file_info", "file_send", "remote_list", "image_ocr", "document_read",
+			"calendar_today", "calendar_list_events", "calendar_search", "calendar_freebusy",
+			"reminders_list", "notes_list", "notes_read", "notes_search",
+			"weather_*", "web_search", "web_fetch",
+			"system_info", "process_list", "music_now_playing",
+			"git_status", "git_log", "git_diff", "git_branch",
+			"github_pr_list", "github_pr_view", "github_issue_list", "github_issue_view", "github_repo_view",
+			"cron_list",
+		},
+	},
+}
+
+// Allows reports whether the profile permits calling tool.
+func (p ToolProfile) Allows(tool string) bool {
+	if matchToolPattern(tool, p.Deny) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchToolPattern(tool, p.Allow)
+}
+
+func matchToolPattern(tool string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == tool {
+			return true
+		}
+		if ok, err := path.Match(p, tool); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitAllowFromEntry splits an allow_from entry of the form "sender=profile".
+// Entries without "=" have an empty profile.
+func SplitAllowFromEntry(entry string) (sender, profile string) {
+	sender, profile, _ = strings.Cut(entry, "=")
+	return strings.TrimSpace(sender), strings.TrimSpace(profile)
+}
@@ -0,0 +1,33 @@
+package security
+
+import "testing"
+
+func TestToolProfileAllows(t *testing.T) {
+	readonly := BuiltinToolProfiles[ProfileReadonly]
+	if !readonly.Allows("file_read") || !readonly.Allows("weather_forecast") {
+		t.Fatalf("readonly should allow read tools")
+	}
+	if readonly.Allows("file_write") || readonly.Allows("shell_execute") || readonly.Allows("browser_click") {
+		t.Fatalf("readonly should not allow mutating tools")
+	}
+
+	noShell := BuiltinToolProfiles[ProfileNoShell]
+	if noShell.Allows("shell_execute") || noShell.Allows("skill_pdf_info") || !noShell.Allows("file_write") {
+		t.Fatalf("no-shell should only deny command execution")
+	}
+
+	custom := ToolProfile{Allow: []string{"browser_*"}, Deny: []string{"browser_execute_js"}}
+	if !custom.Allows("browser_click") || custom.Allows("browser_execute_js") || custom.Allows("file_read") {
+		t.Fatalf("unexpected custom profile decisions")
+	}
+}
+
+func TestSplitAllowFromEntry(t *testing.T) {
+	sender, profile := SplitAllowFromEntry(" wecom:guest = readonly ")
+	if sender != "wecom:guest" || profile != "readonly" {
+		t.Fatalf("got %q %q", sender, profile)
+	}
+	if sender, profile := SplitAllowFromEntry("telegram:1001"); sender != "telegram:1001" || profile != "" {
+		t.Fatalf("got %q %q", sender, profile)
+	}
+}
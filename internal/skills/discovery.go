@@ -9,7 +9,7 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/kayz/coco/internal/tools"
+	"github.com/kayz/coco/internal/datadir"
 	"gopkg.in/yaml.v3"
 )
 
@@ -33,6 +33,8 @@ type SkillEntry struct {
 	Source      SkillSource   `json:"source" yaml:"-"`
 	Content     string        `json:"-" yaml:"-"` // Markdown body after frontmatter
 	Metadata    SkillMetadata `json:"metadata" yaml:"metadata"`
+	Tools       []ToolSpec    `json:"tools,omitempty" yaml:"tools,omitempty"`
+	Permissions Permissions   `json:"permissions,omitempty" yaml:"permissions,omitempty"`
 	Enabled     bool          `json:"enabled" yaml:"-"`
 }
 
@@ -71,6 +73,9 @@ type skillFrontmatter struct {
 	Description string `yaml:"description"`
 	Homepage    string `yaml:"homepage,omitempty"`
 	Metadata    any    `yaml:"metadata,omitempty"` // Can be SkillMetadata or {"openclaw": SkillMetadata}
+
+	Tools       []ToolSpec  `yaml:"tools,omitempty"`
+	Permissions Permissions `yaml:"permissions,omitempty"`
 }
 
 // ParseSkillMD parses a SKILL.md file into a SkillEntry
@@ -104,8 +109,13 @@ func ParseSkillMD(path string) (*SkillEntry, error) {
 		BaseDir:     filepath.Dir(path),
 		Content:     body,
 		Metadata:    metadata,
+		Tools:       fm.Tools,
+		Permissions: fm.Permissions,
 		Enabled:     true,
 	}
+	if err := validateTools(entry); err != nil {
+		return nil, fmt.Errorf("skill %s in %s: %w", fm.Name, path, err)
+	}
 
 	return entry, nil
 }
@@ -311,8 +321,7 @@ func looksLikeSkillsDir(dir string) bool {
 
 // managedSkillsDir returns the user-level skills directory
 func managedSkillsDir() string {
-	exeDir := tools.GetExecutableDir()
-	return filepath.Join(exeDir, ".coco", "skills")
+	return datadir.Path(".coco", "skills")
 }
 
 // HasBinary checks if a binary exists in PATH
@@ -328,9 +337,9 @@ func RuntimeOS() string {
 
 // ShortenHomePath replaces the home directory with ~ for display
 func ShortenHomePath(path string) string {
-	exeDir := tools.GetExecutableDir()
-	if strings.HasPrefix(path, exeDir) {
-		return "~" + path[len(exeDir):]
+	home := datadir.Dir()
+	if strings.HasPrefix(path, home) {
+		return "~" + path[len(home):]
 	}
 	return path
 }
@@ -0,0 +1,73 @@
+package skills
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// maxSkillDepth is how deep below a repository root SKILL.md files are
+// looked for, e.g. skills/<name>/SKILL.md.
+const maxSkillDepth = 3
+
+// IsGitURL reports whether source names a git repository rather than a
+// discovered skill.
+func IsGitURL(source string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(source, ".git")
+}
+
+// FetchGitSkills clones url into a temporary directory and returns the
+// skills it contains. cleanup removes the clone; call it once the skills
+// are installed.
+func FetchGitSkills(ctx context.Context, url string) (entries []SkillEntry, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "coco-skill-git-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", "--", url, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("git clone %s: %v: %s", url, err, strings.TrimSpace(string(out)))
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			if d.Name() == ".git" || strings.Count(rel, string(filepath.Separator)) >= maxSkillDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "SKILL.md" {
+			return nil
+		}
+		entry, err := ParseSkillMD(path)
+		if err != nil {
+			return err
+		}
+		entry.Source = SourceManaged
+		entries = append(entries, *entry)
+		return nil
+	})
+	if err == nil && len(entries) == 0 {
+		err = fmt.Errorf("no SKILL.md found in %s", url)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return entries, cleanup, nil
+}
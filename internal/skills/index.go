@@ -0,0 +1,594 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// indexFileName is the index at the root of a git index repository.
+	indexFileName = "index.json"
+	// signatureSuffix names the detached signature next to an index:
+	// index.json.sig holds the base64 ed25519 signature of index.json.
+	signatureSuffix = ".sig"
+	// OriginFileName records where an installed skill came from, so it can
+	// be upgraded later. It is not part of the skill's checksum.
+	OriginFileName = ".coco-skill.json"
+
+	maxIndexSize   = 8 << 20
+	maxArchiveSize = 64 << 20
+)
+
+var indexHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// IndexVersion is one published version of an indexed skill. Its files
+// come from a .tar.gz archive at URL or, in a git index, from the
+// directory Path inside the repository. SHA256 is the DirChecksum of the
+// skill's files and is checked before anything is installed.
+type IndexVersion struct {
+	Version string `json:"version"`
+	URL     string `json:"url,omitempty"`
+	Path    string `json:"path,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// Kinds of index entries. Besides skills, an index may carry workflow
+// templates (a workflow.yaml for intents.workflows) and persona packs
+// (workspace files such as SOUL.md) that `coco onboard --template` applies.
+const (
+	KindSkill    = "skill"
+	KindWorkflow = "workflow"
+	KindPersona  = "persona"
+)
+
+// kindMarkers is the file every version of an entry of that kind has.
+var kindMarkers = map[string]string{
+	KindSkill:    "SKILL.md",
+	KindWorkflow: "workflow.yaml",
+	KindPersona:  "SOUL.md",
+}
+
+// KindMarker returns the file that identifies an entry of kind, or "" for
+// unknown kinds.
+func KindMarker(kind string) string {
+	return kindMarkers[kind]
+}
+
+// IndexSkill is an entry listed in a remote index: a skill unless Kind
+// says otherwise.
+type IndexSkill struct {
+	Name        string         `json:"name"`
+	Kind        string         `json:"kind,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Versions    []IndexVersion `json:"versions"`
+}
+
+// EntryKind returns the entry's kind, KindSkill when it has none.
+func (s IndexSkill) EntryKind() string {
+	if s.Kind == "" {
+		return KindSkill
+	}
+	return s.Kind
+}
+
+// Latest returns the newest version matching constraint.
+func (s IndexSkill) Latest(constraint string) (IndexVersion, bool) {
+	var best IndexVersion
+	found := false
+	for _, v := range s.Versions {
+		if !MatchVersion(constraint, v.Version) {
+			continue
+		}
+		if !found || CompareVersions(v.Version, best.Version) > 0 {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// RemoteIndex is a skill index loaded from skills.index: a JSON document
+// fetched over HTTPS, or a git repository with index.json at its root.
+// Keeper serves one at /market/index.json.
+type RemoteIndex struct {
+	Source string       `json:"source"`
+	Skills []IndexSkill `json:"skills"`
+
+	dir string // clone of a git index
+}
+
+// LoadIndex fetches the index at source. Close the index when done.
+func LoadIndex(ctx context.Context, source string) (*RemoteIndex, error) {
+	return LoadSignedIndex(ctx, source, "")
+}
+
+// LoadSignedIndex fetches the index at source and, when publicKey (base64
+// ed25519) is set, refuses it unless index.json.sig next to it is a valid
+// signature by that key. Versions carry checksums, so a verified index
+// vouches for every file installed from it.
+func LoadSignedIndex(ctx context.Context, source, publicKey string) (*RemoteIndex, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("no skill index configured; set skills.index in config.yaml or pass --index")
+	}
+	idx := &RemoteIndex{Source: source}
+	var data []byte
+	if isGitIndex(source) {
+		dir, err := os.MkdirTemp("", "coco-skill-index-*")
+		if err != nil {
+			return nil, err
+		}
+		idx.dir = dir
+		cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", "--", source, dir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			idx.Close()
+			return nil, fmt.Errorf("git clone %s: %v: %s", source, err, strings.TrimSpace(string(out)))
+		}
+		if data, err = os.ReadFile(filepath.Join(dir, indexFileName)); err != nil {
+			idx.Close()
+			return nil, fmt.Errorf("read %s from %s: %w", indexFileName, source, err)
+		}
+		if publicKey != "" {
+			sig, err := os.ReadFile(filepath.Join(dir, indexFileName+signatureSuffix))
+			if err == nil {
+				err = VerifyIndex(data, sig, publicKey)
+			}
+			if err != nil {
+				idx.Close()
+				return nil, fmt.Errorf("skill index %s: %w", source, err)
+			}
+		}
+	} else {
+		var err error
+		if data, err = fetchHTTPS(ctx, source, maxIndexSize); err != nil {
+			return nil, fmt.Errorf("fetch skill index: %w", err)
+		}
+		if publicKey != "" {
+			sig, err := fetchHTTPS(ctx, source+signatureSuffix, 1<<10)
+			if err == nil {
+				err = VerifyIndex(data, sig, publicKey)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("skill index %s: %w", source, err)
+			}
+		}
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		idx.Close()
+		return nil, fmt.Errorf("parse skill index %s: %w", source, err)
+	}
+	idx.Source = source
+	return idx, nil
+}
+
+// VerifyIndex checks sig, a base64 ed25519 signature, over an index
+// document.
+func VerifyIndex(data, sig []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid index public key")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, raw) {
+		return fmt.Errorf("signature does not match the configured index key")
+	}
+	return nil
+}
+
+// SignIndex returns the base64 signature VerifyIndex accepts.
+func SignIndex(data []byte, key ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)))
+}
+
+// isGitIndex reports whether source is a git repository rather than an
+// index document. Plain https URLs are documents unless they end in .git.
+func isGitIndex(source string) bool {
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		return strings.HasSuffix(source, ".git")
+	}
+	return IsGitURL(source)
+}
+
+// Close removes the clone of a git index.
+func (idx *RemoteIndex) Close() {
+	if idx.dir != "" {
+		os.RemoveAll(idx.dir)
+	}
+}
+
+// Lookup returns the indexed skill called name.
+func (idx *RemoteIndex) Lookup(name string) (IndexSkill, bool) {
+	return idx.LookupKind(KindSkill, name)
+}
+
+// LookupKind returns the entry of kind called name.
+func (idx *RemoteIndex) LookupKind(kind, name string) (IndexSkill, bool) {
+	for _, s := range idx.Skills {
+		if s.Name == name && s.EntryKind() == kind {
+			return s, true
+		}
+	}
+	return IndexSkill{}, false
+}
+
+// Search returns the skills whose name or description contains query,
+// sorted by name.
+func (idx *RemoteIndex) Search(query string) []IndexSkill {
+	return idx.SearchKind(query, KindSkill)
+}
+
+// SearchKind is Search over the entries of the given kinds.
+func (idx *RemoteIndex) SearchKind(query string, kinds ...string) []IndexSkill {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var out []IndexSkill
+	for _, s := range idx.Skills {
+		if !slices.Contains(kinds, s.EntryKind()) {
+			continue
+		}
+		if query == "" || strings.Contains(strings.ToLower(s.Name), query) || strings.Contains(strings.ToLower(s.Description), query) {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Resolve picks the newest version of name matching constraint.
+func (idx *RemoteIndex) Resolve(name, constraint string) (IndexVersion, error) {
+	return idx.ResolveKind(KindSkill, name, constraint)
+}
+
+// ResolveKind picks the newest version of the kind entry name matching
+// constraint.
+func (idx *RemoteIndex) ResolveKind(kind, name, constraint string) (IndexVersion, error) {
+	if err := CheckConstraint(constraint); err != nil {
+		return IndexVersion{}, err
+	}
+	s, ok := idx.LookupKind(kind, name)
+	if !ok {
+		return IndexVersion{}, fmt.Errorf("%s %q not found in index %s", kind, name, idx.Source)
+	}
+	v, ok := s.Latest(constraint)
+	if !ok {
+		return IndexVersion{}, fmt.Errorf("no version of %s matches %q", name, constraint)
+	}
+	return v, nil
+}
+
+// Fetch downloads version v of name, verifies its checksum and returns the
+// skill ready for InstallSkillEntry. cleanup removes the download.
+func (idx *RemoteIndex) Fetch(ctx context.Context, name string, v IndexVersion) (entry SkillEntry, cleanup func(), err error) {
+	root, cleanup, err := idx.FetchFiles(ctx, KindSkill, name, v)
+	if err != nil {
+		return SkillEntry{}, nil, err
+	}
+	parsed, err := ParseSkillMD(filepath.Join(root, "SKILL.md"))
+	if err == nil && parsed.Name != name {
+		err = fmt.Errorf("index entry %s points at skill %q", name, parsed.Name)
+	}
+	if err != nil {
+		cleanup()
+		return SkillEntry{}, nil, err
+	}
+	parsed.Source = SourceManaged
+	return *parsed, cleanup, nil
+}
+
+// FetchFiles downloads version v of the kind entry name, verifies its
+// checksum and returns the directory holding its files. cleanup removes
+// the download.
+func (idx *RemoteIndex) FetchFiles(ctx context.Context, kind, name string, v IndexVersion) (root string, cleanup func(), err error) {
+	if strings.TrimSpace(v.SHA256) == "" {
+		return "", nil, fmt.Errorf("%s %s has no sha256 in the index", name, v.Version)
+	}
+	marker := KindMarker(kind)
+	if marker == "" {
+		return "", nil, fmt.Errorf("unknown index entry kind %q", kind)
+	}
+	cleanup = func() {}
+	switch {
+	case v.URL != "":
+		tmp, err := os.MkdirTemp("", "coco-skill-dl-*")
+		if err != nil {
+			return "", nil, err
+		}
+		cleanup = func() { os.RemoveAll(tmp) }
+		data, err := fetchHTTPS(ctx, idx.resolveURL(v.URL), maxArchiveSize)
+		if err == nil {
+			root, err = extractArchive(data, tmp, marker)
+		}
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("download %s %s: %w", name, v.Version, err)
+		}
+	case v.Path != "" && idx.dir != "":
+		root = filepath.Join(idx.dir, filepath.FromSlash(v.Path))
+		if rel, err := filepath.Rel(idx.dir, root); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return "", nil, fmt.Errorf("%s %s: path %q leaves the index repository", name, v.Version, v.Path)
+		}
+		if _, err := os.Stat(filepath.Join(root, marker)); err != nil {
+			return "", nil, fmt.Errorf("%s %s has no %s", name, v.Version, marker)
+		}
+	default:
+		return "", nil, fmt.Errorf("%s %s has neither url nor a path in a git index", name, v.Version)
+	}
+
+	sum, err := DirChecksum(root)
+	if err == nil && !strings.EqualFold(sum, strings.TrimSpace(v.SHA256)) {
+		err = fmt.Errorf("checksum mismatch for %s %s: index says %s, got %s", name, v.Version, v.SHA256, sum)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return root, cleanup, nil
+}
+
+// resolveURL makes a version URL relative to an https index absolute, so
+// an index can refer to archives next to it.
+func (idx *RemoteIndex) resolveURL(ref string) string {
+	if idx.dir != "" || strings.Contains(ref, "://") {
+		return ref
+	}
+	base, err := url.Parse(idx.Source)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(r).String()
+}
+
+// fetchHTTPS downloads url. Plain http is only allowed to the local
+// machine, since the index carries the checksums everything else is
+// verified against.
+func fetchHTTPS(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLoopbackHost(u.Hostname())) {
+		return nil, fmt.Errorf("%s: only https URLs are allowed", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := indexHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// extractArchive unpacks a .tar.gz into dir and returns the directory
+// holding marker: dir itself, or the archive's single top-level folder.
+func extractArchive(data []byte, dir, marker string) (string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("not a .tar.gz archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("tar read error: %w", err)
+		}
+		rel := filepath.Clean(filepath.FromSlash(header.Name))
+		if rel == "." || filepath.IsAbs(rel) || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		target := filepath.Join(dir, rel)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			mode := os.FileMode(0644)
+			if header.Mode&0111 != 0 {
+				mode = 0755
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+			return root, nil
+		}
+	}
+	return "", fmt.Errorf("archive has no %s", marker)
+}
+
+// DirChecksum hashes the files of a skill directory: the sha256 of one
+// "<path>\x00<sha256 of content>\n" line per regular file, sorted by
+// slash-separated path. .git and the origin file are left out. Index
+// publishers put this value in each version's sha256.
+func DirChecksum(dir string) (string, error) {
+	var lines []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (d.Name() == OriginFileName && filepath.Dir(path) == filepath.Clean(dir)) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		lines = append(lines, filepath.ToSlash(rel)+"\x00"+hex.EncodeToString(sum[:])+"\n")
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		io.WriteString(h, line)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Origin records the index, version and pin an installed skill came from.
+type Origin struct {
+	Index       string    `json:"index"`
+	Version     string    `json:"version"`
+	Constraint  string    `json:"constraint,omitempty"`
+	SHA256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// WriteOrigin saves origin into an installed skill's directory.
+func WriteOrigin(dir string, origin Origin) error {
+	data, err := json.MarshalIndent(origin, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, OriginFileName), append(data, '\n'), 0644)
+}
+
+// ReadOrigin loads the origin of an installed skill; ok is false for
+// skills not installed from an index.
+func ReadOrigin(dir string) (origin Origin, ok bool, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, OriginFileName))
+	if os.IsNotExist(err) {
+		return Origin{}, false, nil
+	}
+	if err != nil {
+		return Origin{}, false, err
+	}
+	if err := json.Unmarshal(data, &origin); err != nil {
+		return Origin{}, false, fmt.Errorf("parse %s: %w", filepath.Join(dir, OriginFileName), err)
+	}
+	return origin, true, nil
+}
+
+// Upgrade is an installed skill with a newer version in the index.
+// Available honours the skill's pin; Latest ignores it, so a pin holding a
+// skill back is visible.
+type Upgrade struct {
+	Name       string `json:"name"`
+	Installed  string `json:"installed"`
+	Constraint string `json:"constraint,omitempty"`
+	Available  string `json:"available,omitempty"`
+	Latest     string `json:"latest"`
+	Dir        string `json:"dir"`
+}
+
+// Upgrades compares the skills installed from an index in managedDir (the
+// managed skills directory when empty) with idx.
+func (idx *RemoteIndex) Upgrades(managedDir string) ([]Upgrade, error) {
+	if strings.TrimSpace(managedDir) == "" {
+		managedDir = managedSkillsDir()
+	}
+	dirs, err := os.ReadDir(managedDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Upgrade
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(managedDir, d.Name())
+		origin, ok, err := ReadOrigin(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		s, found := idx.Lookup(d.Name())
+		if !found {
+			continue
+		}
+		latest, _ := s.Latest("")
+		if CompareVersions(latest.Version, origin.Version) <= 0 {
+			continue
+		}
+		u := Upgrade{Name: d.Name(), Installed: origin.Version, Constraint: origin.Constraint, Latest: latest.Version, Dir: dir}
+		if v, ok := s.Latest(origin.Constraint); ok && CompareVersions(v.Version, origin.Version) > 0 {
+			u.Available = v.Version
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
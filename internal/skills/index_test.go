@@ -0,0 +1,125 @@
+package skills
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skillArchive packs a one-file skill the way publishers do, under a
+// top-level folder, and returns it with its checksum.
+func skillArchive(t *testing.T, name, body string) ([]byte, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "---\nname: " + name + "\ndescription: demo\n---\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := DirChecksum(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: name + "/SKILL.md", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(content))
+	tw.Close()
+	gz.Close()
+	return buf.Bytes(), sum
+}
+
+func TestRemoteIndexInstallAndUpgrade(t *testing.T) {
+	v1, sum1 := skillArchive(t, "demo", "version one")
+	v2, sum2 := skillArchive(t, "demo", "version two")
+	v3, sum3 := skillArchive(t, "demo", "version three")
+
+	var index []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			w.Write(index)
+		case "/demo-1.0.0.tar.gz":
+			w.Write(v1)
+		case "/demo-1.1.0.tar.gz":
+			w.Write(v2)
+		case "/demo-2.0.0.tar.gz":
+			w.Write(v3)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	index, _ = json.Marshal(map[string]any{"skills": []IndexSkill{{
+		Name:        "demo",
+		Description: "a demo skill",
+		Versions: []IndexVersion{
+			{Version: "1.0.0", URL: srv.URL + "/demo-1.0.0.tar.gz", SHA256: sum1},
+			{Version: "1.1.0", URL: srv.URL + "/demo-1.1.0.tar.gz", SHA256: sum2},
+			{Version: "2.0.0", URL: srv.URL + "/demo-2.0.0.tar.gz", SHA256: sum3},
+		},
+	}}})
+
+	ctx := context.Background()
+	idx, err := LoadIndex(ctx, srv.URL+"/index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if found := idx.Search("demo"); len(found) != 1 {
+		t.Fatalf("search = %+v", found)
+	}
+
+	v, err := idx.Resolve("demo", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, cleanup, err := idx.Fetch(ctx, "demo", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	managed := t.TempDir()
+	result, err := InstallSkillEntry(entry, InstallOptions{ManagedDir: managed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteOrigin(result.InstalledPath, Origin{Index: idx.Source, Version: v.Version, Constraint: "^1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if sum, _ := DirChecksum(result.InstalledPath); sum != sum1 {
+		t.Fatalf("installed checksum = %s, want %s", sum, sum1)
+	}
+
+	// The ^1.0 pin allows 1.1.0 but not 2.0.0.
+	upgrades, err := idx.Upgrades(managed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upgrades) != 1 || upgrades[0].Available != "1.1.0" || upgrades[0].Latest != "2.0.0" {
+		t.Fatalf("upgrades = %+v", upgrades)
+	}
+
+	// A tampered archive is refused.
+	_, _, err = idx.Fetch(ctx, "demo", IndexVersion{Version: "1.1.0", URL: srv.URL + "/demo-1.1.0.tar.gz", SHA256: sum1})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("tampered fetch err = %v", err)
+	}
+}
+
+func TestLoadIndexRejectsPlainHTTP(t *testing.T) {
+	if _, err := LoadIndex(context.Background(), "http://example.com/index.json"); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -37,7 +37,7 @@ type InstallResult struct {
 
 // EvaluateSkillSecurity scores a skill for install-time risk based on metadata and body content.
 func EvaluateSkillSecurity(skill SkillEntry) SecurityAssessment {
-	content := strings.ToLower(skill.Content)
+	content := strings.ToLower(skill.Content + "\n" + toolSources(skill))
 	score := 0
 	reasons := make(map[string]struct{})
 
@@ -84,6 +84,22 @@ func EvaluateSkillSecurity(skill SkillEntry) SecurityAssessment {
 		score += 5
 		reasons["requires environment variables"] = struct{}{}
 	}
+	if len(skill.Tools) > 0 {
+		score += 10
+		reasons["declares executable tools"] = struct{}{}
+	}
+	if skill.Permissions.Network {
+		score += 10
+		reasons["tools may use the network"] = struct{}{}
+	}
+	if len(skill.Permissions.Env) > 0 {
+		score += 5
+		reasons[fmt.Sprintf("tools read environment variables: %s", strings.Join(skill.Permissions.Env, ", "))] = struct{}{}
+	}
+	if len(skill.Permissions.Paths) > 0 {
+		score += 10
+		reasons[fmt.Sprintf("tools may access files under: %s", strings.Join(skill.Permissions.Paths, ", "))] = struct{}{}
+	}
 
 	level := SecuritySafe
 	switch {
@@ -110,6 +126,23 @@ func EvaluateSkillSecurity(skill SkillEntry) SecurityAssessment {
 	}
 }
 
+// toolSources returns the commands and script contents of the skill's
+// tools so they are assessed like the skill body.
+func toolSources(skill SkillEntry) string {
+	var sb strings.Builder
+	for _, t := range skill.Tools {
+		sb.WriteString(t.Command)
+		sb.WriteString("\n")
+		if t.Script != "" {
+			if data, err := os.ReadFile(filepath.Join(skill.BaseDir, t.Script)); err == nil {
+				sb.Write(data)
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
 // FindSkillByName discovers skills and returns one by exact name.
 func FindSkillByName(name string, disabledList []string, extraDirs []string) (SkillEntry, bool) {
 	name = strings.TrimSpace(name)
@@ -193,6 +226,9 @@ func copyDir(src, dst string) error {
 		target := filepath.Join(dst, rel)
 
 		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
 			return os.MkdirAll(target, 0755)
 		}
 
@@ -200,6 +236,11 @@ func copyDir(src, dst string) error {
 		if err != nil {
 			return err
 		}
-		return os.WriteFile(target, data, 0644)
+		// Keep scripts executable; tools may run them directly.
+		mode := os.FileMode(0644)
+		if info, err := d.Info(); err == nil && info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		return os.WriteFile(target, data, mode)
 	})
 }
@@ -9,7 +9,7 @@ import (
 	"path/filepath"
 	"sync"
 
-	"github.com/kayz/coco/internal/tools"
+	"github.com/kayz/coco/internal/datadir"
 )
 
 // Skill represents a skill/plugin that can be executed
@@ -98,8 +98,7 @@ type Registry struct {
 // NewRegistry creates a new skill registry
 func NewRegistry(skillDir string) *Registry {
 	if skillDir == "" {
-		exeDir := tools.GetExecutableDir()
-		skillDir = filepath.Join(exeDir, ".coco", "skills")
+		skillDir = datadir.Path(".coco", "skills")
 	}
 
 	return &Registry{
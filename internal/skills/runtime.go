@@ -0,0 +1,471 @@
+package skills
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kayz/coco/internal/security"
+)
+
+const (
+	defaultToolTimeout = 30 * time.Second
+	maxToolTimeout     = 5 * time.Minute
+	maxToolOutput      = 64 << 10
+)
+
+// ToolSpec is an executable tool a skill declares in the "tools" list of its
+// SKILL.md frontmatter. A tool either fills a command template or runs a
+// script from the skill folder that reads its arguments as JSON on stdin.
+type ToolSpec struct {
+	Name        string               `json:"name" yaml:"name"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Command     string               `json:"command,omitempty" yaml:"command,omitempty"` // e.g. "pdfinfo {{.path}}"; run without a shell
+	Script      string               `json:"script,omitempty" yaml:"script,omitempty"`   // path relative to the skill folder
+	Parameters  map[string]ToolParam `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// ToolParam describes one argument of a skill tool.
+type ToolParam struct {
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"` // string (default), number, integer, boolean or path
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	// AllowDash lets a string filled into a command start with "-". Without
+	// it such values are refused, since the command would read them as
+	// options (e.g. --output=/path).
+	AllowDash bool `json:"allow_dash,omitempty" yaml:"allow_dash,omitempty"`
+}
+
+// Permissions is what a skill's tools may use. Tools see only a minimal
+// environment and the network is cut off unless Network is set. Cutting it
+// off needs Linux with unprivileged user namespaces; elsewhere tools
+// without Network are refused unless RunOptions.AllowUnisolatedNetwork.
+//
+// The filesystem is not confined: Paths only limits the path arguments
+// coco fills in, while the tool itself runs as the user and can read and
+// write whatever the user can. Install only skills whose code you trust
+// with your files.
+type Permissions struct {
+	Network bool     `json:"network,omitempty" yaml:"network,omitempty"`
+	Env     []string `json:"env,omitempty" yaml:"env,omitempty"`         // environment variables passed through
+	Paths   []string `json:"paths,omitempty" yaml:"paths,omitempty"`     // directories path arguments may point into
+	Timeout string   `json:"timeout,omitempty" yaml:"timeout,omitempty"` // per call, default 30s, at most 5m
+}
+
+// RunOptions is the caller's own policy, applied on top of the skill's
+// permissions.
+type RunOptions struct {
+	CheckPath       func(path string) error // e.g. the agent's allowed paths
+	BlockedCommands []string
+	// AllowUnisolatedNetwork runs tools that declare no network access even
+	// where CanIsolateNetwork is false, with the host's network.
+	AllowUnisolatedNetwork bool
+}
+
+// SkillToolPrefix starts the name of every skill tool, so tool profiles
+// can allow or deny them as a group ("skill_*").
+const SkillToolPrefix = "skill_"
+
+// ToolName is the name a skill tool is offered to the model under:
+// SkillToolPrefix, the skill and the tool.
+func ToolName(skill, tool string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+				return r
+			}
+			return '_'
+		}, s)
+	}
+	return SkillToolPrefix + clean(skill) + "_" + clean(tool)
+}
+
+// InputSchema returns the JSON schema of the tool's arguments.
+func (t ToolSpec) InputSchema() map[string]any {
+	props := map[string]any{}
+	var required []string
+	for name, p := range t.Parameters {
+		typ := p.Type
+		desc := p.Description
+		if typ == "path" {
+			typ = "string"
+			desc = strings.TrimSpace(desc + " (file or directory path)")
+		}
+		props[name] = map[string]string{"type": typ, "description": desc}
+		if p.Required {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// validateTools checks the tools a skill declares so mistakes show when it
+// is loaded rather than when the model first calls it.
+func validateTools(entry *SkillEntry) error {
+	seen := map[string]bool{}
+	for i := range entry.Tools {
+		t := &entry.Tools[i]
+		if t.Name == "" {
+			return fmt.Errorf("tool #%d has no name", i+1)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("duplicate tool %q", t.Name)
+		}
+		seen[t.Name] = true
+		if (t.Command == "") == (t.Script == "") {
+			return fmt.Errorf("tool %q needs exactly one of command or script", t.Name)
+		}
+		if t.Script != "" {
+			if filepath.IsAbs(t.Script) || !filepath.IsLocal(t.Script) {
+				return fmt.Errorf("tool %q: script must be inside the skill folder", t.Name)
+			}
+		}
+		for _, field := range splitCommand(t.Command) {
+			if _, err := template.New(t.Name).Parse(field); err != nil {
+				return fmt.Errorf("tool %q: %w", t.Name, err)
+			}
+		}
+		for name, p := range t.Parameters {
+			switch p.Type {
+			case "":
+				p.Type = "string"
+				t.Parameters[name] = p
+			case "string", "number", "integer", "boolean", "path":
+			default:
+				return fmt.Errorf("tool %q: parameter %q has unknown type %q", t.Name, name, p.Type)
+			}
+		}
+	}
+	if _, err := entry.Permissions.timeout(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p Permissions) timeout() (time.Duration, error) {
+	if p.Timeout == "" {
+		return defaultToolTimeout, nil
+	}
+	d, err := time.ParseDuration(p.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid permissions.timeout %q", p.Timeout)
+	}
+	return min(d, maxToolTimeout), nil
+}
+
+// RunTool runs one of entry's tools with args in a scratch directory and
+// returns its output. A script's output may be a JSON object with "result"
+// or "error"; anything else is returned as text.
+func RunTool(ctx context.Context, entry SkillEntry, tool ToolSpec, args map[string]any, opts RunOptions) (string, error) {
+	values, err := toolArguments(entry, tool, args, opts)
+	if err != nil {
+		return "", err
+	}
+	isolate := !entry.Permissions.Network && isolateNetwork()
+	if !entry.Permissions.Network && !isolate && !opts.AllowUnisolatedNetwork {
+		return "", fmt.Errorf("cannot cut this tool off the network on this system (needs Linux with unprivileged user namespaces); set skills.allow_unisolated_network to run it with network access")
+	}
+
+	var argv []string
+	var stdin []byte
+	if tool.Script != "" {
+		argv = scriptCommand(filepath.Join(entry.BaseDir, tool.Script))
+		if stdin, err = json.Marshal(values); err != nil {
+			return "", err
+		}
+	} else {
+		for _, field := range splitCommand(tool.Command) {
+			tmpl, err := template.New(tool.Name).Parse(field)
+			if err != nil {
+				return "", err
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, values); err != nil {
+				return "", fmt.Errorf("fill command: %w", err)
+			}
+			argv = append(argv, buf.String())
+		}
+		if len(argv) > 0 && strings.HasPrefix(argv[0], "./") {
+			argv[0] = filepath.Join(entry.BaseDir, argv[0])
+		}
+	}
+	if len(argv) == 0 {
+		return "", fmt.Errorf("tool %s has an empty command", tool.Name)
+	}
+	line := strings.Join(argv, " ")
+	for _, patterns := range [][]string{security.DefaultBlockedCommandPatterns, opts.BlockedCommands} {
+		if matched, ok := security.MatchCommandPattern(line, patterns); ok {
+			return "", fmt.Errorf("command blocked by security policy (matched %q)", matched)
+		}
+	}
+
+	scratch, err := os.MkdirTemp("", "coco-skill-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(scratch)
+
+	timeout, _ := entry.Permissions.timeout()
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if isolate {
+		argv = append([]string{"unshare", "--user", "--map-root-user", "--net", "--"}, argv...)
+	}
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	cmd.Dir = scratch
+	cmd.Env = toolEnv(entry, scratch)
+	cmd.Stdin = bytes.NewReader(stdin)
+	stdout := &cappedBuffer{max: maxToolOutput}
+	stderr := &cappedBuffer{max: 4 << 10}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	if err := cmd.Run(); err != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	out := strings.TrimSpace(stdout.String())
+	if stdout.truncated {
+		out += "\n…(output truncated)"
+	}
+	return decodeToolOutput(out)
+}
+
+// toolArguments checks args against the tool's parameters and the skill's
+// path permissions and returns them as template and JSON values.
+func toolArguments(entry SkillEntry, tool ToolSpec, args map[string]any, opts RunOptions) (map[string]any, error) {
+	values := make(map[string]any, len(tool.Parameters))
+	for name, p := range tool.Parameters {
+		v, ok := args[name]
+		if !ok || v == nil || v == "" {
+			if p.Required {
+				return nil, fmt.Errorf("%s is required", name)
+			}
+			values[name] = ""
+			continue
+		}
+		switch p.Type {
+		case "number", "integer":
+			f, ok := v.(float64)
+			if !ok {
+				s, _ := v.(string)
+				var err error
+				if f, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+					return nil, fmt.Errorf("%s must be a number", name)
+				}
+			}
+			if p.Type == "integer" {
+				values[name] = int64(f)
+			} else {
+				values[name] = f
+			}
+		case "boolean":
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s must be true or false", name)
+			}
+			values[name] = b
+		case "path":
+			path, err := allowedPath(entry.Permissions.Paths, fmt.Sprint(v))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if opts.CheckPath != nil {
+				if err := opts.CheckPath(path); err != nil {
+					return nil, err
+				}
+			}
+			values[name] = path
+		default:
+			str := fmt.Sprint(v)
+			if tool.Command != "" && !p.AllowDash && strings.HasPrefix(str, "-") {
+				return nil, fmt.Errorf("%s must not start with \"-\" (the command would read it as an option)", name)
+			}
+			values[name] = str
+		}
+	}
+	return values, nil
+}
+
+// allowedPath resolves path and checks it lies under one of the declared
+// directories.
+func allowedPath(allowed []string, path string) (string, error) {
+	path = filepath.Clean(expandHome(path))
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be absolute: %s", path)
+	}
+	for _, dir := range allowed {
+		dir = filepath.Clean(expandHome(dir))
+		if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+			return path, nil
+		}
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("skill declares no paths permission")
+	}
+	return "", fmt.Errorf("%s is outside the skill's permitted paths", path)
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// splitCommand splits a command template into arguments at spaces outside
+// quotes and {{ }} actions; a filled-in value is always one argument.
+func splitCommand(command string) []string {
+	var fields []string
+	var cur strings.Builder
+	var quote rune
+	inAction, started := false, false
+	for i, r := range command {
+		switch {
+		case inAction:
+			cur.WriteRune(r)
+			if strings.HasPrefix(command[i:], "}}") {
+				inAction = false
+			}
+			continue
+		case strings.HasPrefix(command[i:], "{{"):
+			inAction, started = true, true
+			cur.WriteRune(r)
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+			continue
+		case r == '"' || r == '\'':
+			quote, started = r, true
+			continue
+		case r == ' ' || r == '\t' || r == '\n':
+			if started {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				started = false
+			}
+			continue
+		}
+		cur.WriteRune(r)
+		started = true
+	}
+	if started {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// scriptCommand picks an interpreter from the script's extension; other
+// scripts must be executable.
+func scriptCommand(path string) []string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".py":
+		if runtime.GOOS == "windows" {
+			return []string{"python", path}
+		}
+		return []string{"python3", path}
+	case ".js", ".mjs":
+		return []string{"node", path}
+	case ".sh":
+		return []string{"sh", path}
+	case ".ps1":
+		return []string{"powershell", "-NoProfile", "-File", path}
+	default:
+		return []string{path}
+	}
+}
+
+// toolEnv is the minimal environment a tool runs with plus the variables the
+// skill declares.
+func toolEnv(entry SkillEntry, scratch string) []string {
+	keep := []string{"PATH", "LANG", "LC_ALL", "SYSTEMROOT", "COMSPEC", "PATHEXT"}
+	env := []string{"HOME=" + scratch, "TMPDIR=" + scratch, "TEMP=" + scratch, "TMP=" + scratch, "SKILL_DIR=" + entry.BaseDir}
+	for _, name := range append(keep, entry.Permissions.Env...) {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+func decodeToolOutput(out string) (string, error) {
+	if !strings.HasPrefix(out, "{") {
+		return out, nil
+	}
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(out), &reply); err != nil || (reply.Result == nil && reply.Error == "") {
+		return out, nil
+	}
+	if reply.Error != "" {
+		return "", fmt.Errorf("%s", reply.Error)
+	}
+	var text string
+	if err := json.Unmarshal(reply.Result, &text); err == nil {
+		return text, nil
+	}
+	return string(reply.Result), nil
+}
+
+var (
+	netnsOnce sync.Once
+	netnsOK   bool
+
+	isolateNetwork = CanIsolateNetwork // replaced in tests
+)
+
+// CanIsolateNetwork reports whether tools can run in their own network
+// namespace, which needs Linux with unprivileged user namespaces.
+func CanIsolateNetwork() bool {
+	netnsOnce.Do(func() {
+		if runtime.GOOS != "linux" {
+			return
+		}
+		netnsOK = exec.Command("unshare", "--user", "--map-root-user", "--net", "--", "true").Run() == nil
+	})
+	return netnsOK
+}
+
+// cappedBuffer keeps the first max bytes written to it.
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,140 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const toolSkillMD = `---
+name: files
+description: File helpers
+permissions:
+  paths: ["%s"]
+  timeout: 10s
+tools:
+  - name: greet
+    description: Say hello
+    command: echo "hello," {{.who}}
+    parameters:
+      who: {type: string, required: true}
+  - name: size
+    description: Report a file's size
+    script: size.sh
+    parameters:
+      path: {type: path, required: true}
+---
+
+# Files`
+
+func writeToolSkill(t *testing.T) (*SkillEntry, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("tool fixtures use sh")
+	}
+	dir := t.TempDir()
+	allowed := t.TempDir()
+	md := strings.Replace(toolSkillMD, "%s", allowed, 1)
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(md), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\nread args\necho \"{\\\"result\\\": \\\"got $args in $(pwd)\\\"}\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "size.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := ParseSkillMD(filepath.Join(dir, "SKILL.md"))
+	if err != nil {
+		t.Fatalf("ParseSkillMD: %v", err)
+	}
+	return entry, allowed
+}
+
+func TestRunToolCommandTemplate(t *testing.T) {
+	entry, _ := writeToolSkill(t)
+	if len(entry.Tools) != 2 || ToolName(entry.Name, entry.Tools[0].Name) != "skill_files_greet" {
+		t.Fatalf("tools = %+v", entry.Tools)
+	}
+
+	// A filled-in value stays one argument, so it cannot add flags or commands.
+	out, err := RunTool(context.Background(), *entry, entry.Tools[0], map[string]any{"who": "a; rm -rf ~"}, RunOptions{})
+	if err != nil || out != "hello, a; rm -rf ~" {
+		t.Fatalf("greet = %q, %v", out, err)
+	}
+	if _, err := RunTool(context.Background(), *entry, entry.Tools[0], map[string]any{}, RunOptions{}); err == nil {
+		t.Fatal("expected an error for a missing argument")
+	}
+
+	// ...but a value starting with "-" would still be read as an option.
+	if _, err := RunTool(context.Background(), *entry, entry.Tools[0], map[string]any{"who": "--output=/tmp/x"}, RunOptions{}); err == nil || !strings.Contains(err.Error(), "option") {
+		t.Fatalf("expected a dash value to be refused, got %v", err)
+	}
+	greet := entry.Tools[0]
+	greet.Parameters = map[string]ToolParam{"who": {Type: "string", Required: true, AllowDash: true}}
+	if out, err := RunTool(context.Background(), *entry, greet, map[string]any{"who": "-n"}, RunOptions{}); err != nil || out != "hello, -n" {
+		t.Fatalf("allow_dash greet = %q, %v", out, err)
+	}
+}
+
+func TestRunToolRefusesUnenforceableNetworkCutoff(t *testing.T) {
+	entry, allowed := writeToolSkill(t)
+	isolateNetwork = func() bool { return false }
+	defer func() { isolateNetwork = CanIsolateNetwork }()
+
+	args := map[string]any{"path": filepath.Join(allowed, "a.txt")}
+	if _, err := RunTool(context.Background(), *entry, entry.Tools[1], args, RunOptions{}); err == nil || !strings.Contains(err.Error(), "allow_unisolated_network") {
+		t.Fatalf("expected the tool to be refused, got %v", err)
+	}
+	if _, err := RunTool(context.Background(), *entry, entry.Tools[1], args, RunOptions{AllowUnisolatedNetwork: true}); err != nil {
+		t.Fatalf("opted-in run failed: %v", err)
+	}
+	entry.Permissions.Network = true
+	if _, err := RunTool(context.Background(), *entry, entry.Tools[1], args, RunOptions{}); err != nil {
+		t.Fatalf("a tool declaring network needs no cutoff: %v", err)
+	}
+}
+
+func TestRunToolScriptPermissions(t *testing.T) {
+	entry, allowed := writeToolSkill(t)
+	size := entry.Tools[1]
+
+	inside := filepath.Join(allowed, "a.txt")
+	out, err := RunTool(context.Background(), *entry, size, map[string]any{"path": inside}, RunOptions{})
+	if err != nil || !strings.Contains(out, inside) || strings.Contains(out, entry.BaseDir) {
+		t.Fatalf("size = %q, %v", out, err)
+	}
+
+	if _, err := RunTool(context.Background(), *entry, size, map[string]any{"path": "/etc/passwd"}, RunOptions{}); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Fatalf("expected a path outside the permission to be refused, got %v", err)
+	}
+	deny := RunOptions{CheckPath: func(string) error { return os.ErrPermission }}
+	if _, err := RunTool(context.Background(), *entry, size, map[string]any{"path": inside}, deny); err == nil {
+		t.Fatal("expected the caller's path policy to apply")
+	}
+}
+
+func TestParseSkillMDRejectsBadTools(t *testing.T) {
+	for name, tools := range map[string]string{
+		"both":    "  - name: x\n    command: ls\n    script: x.sh\n",
+		"escape":  "  - name: x\n    script: ../x.sh\n",
+		"type":    "  - name: x\n    command: ls\n    parameters:\n      n: {type: list}\n",
+		"no name": "  - command: ls\n",
+	} {
+		dir := t.TempDir()
+		md := "---\nname: bad\ndescription: bad\ntools:\n" + tools + "---\n"
+		os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(md), 0o644)
+		if _, err := ParseSkillMD(filepath.Join(dir, "SKILL.md")); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	got := splitCommand(`convert {{ .in }} -resize "50%" {{.out}}`)
+	want := []string{"convert", "{{ .in }}", "-resize", "50%", "{{.out}}"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("splitCommand = %q", got)
+	}
+}
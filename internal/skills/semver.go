@@ -0,0 +1,149 @@
+package skills
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version. Missing minor and patch numbers
+// count as zero, so "1.2" is 1.2.0.
+type version struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return version{}, false
+	}
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v version
+	s, v.pre, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return version{}, false
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		*nums[i] = n
+	}
+	return v, true
+}
+
+func (v version) compare(o version) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return d
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	return strings.Compare(v.pre, o.pre)
+}
+
+// CompareVersions orders two semantic versions like strings.Compare.
+// Versions that do not parse sort first.
+func CompareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	return va.compare(vb)
+}
+
+// CheckConstraint reports whether constraint is one MatchVersion accepts.
+func CheckConstraint(constraint string) error {
+	for _, term := range strings.Fields(strings.ReplaceAll(constraint, ",", " ")) {
+		if term == "*" || term == "latest" {
+			continue
+		}
+		_, rest := splitOperator(term)
+		if _, ok := parseVersion(rest); !ok {
+			return fmt.Errorf("invalid version constraint %q", term)
+		}
+	}
+	return nil
+}
+
+// MatchVersion reports whether ver satisfies constraint. A constraint is
+// one or more terms separated by spaces or commas, all of which must hold:
+// "1.2.3" or "=1.2.3" pins exactly, "^1.2" allows 1.x from 1.2.0, "~1.2"
+// allows 1.2.x, and >, >=, < and <= compare. An empty constraint, "*" or
+// "latest" matches any release. Pre-releases only match exact pins.
+func MatchVersion(constraint, ver string) bool {
+	v, ok := parseVersion(ver)
+	if !ok {
+		return false
+	}
+	terms := strings.Fields(strings.ReplaceAll(constraint, ",", " "))
+	exact := false
+	for _, term := range terms {
+		if term == "*" || term == "latest" {
+			continue
+		}
+		op, rest := splitOperator(term)
+		want, ok := parseVersion(rest)
+		if !ok {
+			return false
+		}
+		c := v.compare(want)
+		var match bool
+		switch op {
+		case "", "=":
+			exact = true
+			match = c == 0
+		case ">":
+			match = c > 0
+		case ">=":
+			match = c >= 0
+		case "<":
+			match = c < 0
+		case "<=":
+			match = c <= 0
+		case "~":
+			match = c >= 0 && v.major == want.major && v.minor == want.minor
+		case "^":
+			switch {
+			case want.major > 0:
+				match = c >= 0 && v.major == want.major
+			case want.minor > 0:
+				match = c >= 0 && v.major == 0 && v.minor == want.minor
+			default:
+				match = c == 0
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return v.pre == "" || exact
+}
+
+func splitOperator(term string) (op, rest string) {
+	for _, op := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if rest, ok := strings.CutPrefix(term, op); ok {
+			return op, rest
+		}
+	}
+	return "", term
+}
@@ -0,0 +1,39 @@
+package skills
+
+import "testing"
+
+func TestMatchVersion(t *testing.T) {
+	cases := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{"", "1.4.0", true},
+		{"latest", "0.1.0", true},
+		{"", "2.0.0-beta", false},
+		{"1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"2.0.0-beta", "2.0.0-beta", true},
+		{"^1.2", "1.9.0", true},
+		{"^1.2", "1.1.9", false},
+		{"^1.2", "2.0.0", false},
+		{"^0.3.1", "0.3.5", true},
+		{"^0.3.1", "0.4.0", false},
+		{"~1.2", "1.2.7", true},
+		{"~1.2", "1.3.0", false},
+		{">=1.0, <2", "1.5.0", true},
+		{">=1.0 <2", "2.0.0", false},
+		{"v1.0.0", "1.0.0", true},
+		{"^1", "not-a-version", false},
+	}
+	for _, c := range cases {
+		if got := MatchVersion(c.constraint, c.version); got != c.want {
+			t.Errorf("MatchVersion(%q, %q) = %v, want %v", c.constraint, c.version, got, c.want)
+		}
+	}
+	if err := CheckConstraint("^x"); err == nil {
+		t.Error("CheckConstraint accepted ^x")
+	}
+	if CompareVersions("1.10.0", "1.9.2") <= 0 || CompareVersions("1.0.0-rc1", "1.0.0") >= 0 {
+		t.Error("CompareVersions ordered versions wrong")
+	}
+}
@@ -0,0 +1,244 @@
+// Package taskqueue schedules the agent's work. Live chat messages go ahead
+// of scheduled jobs, each conversation runs a limited number of tasks at a
+// time, and scheduled jobs never take the last free slot, so a slow cron
+// prompt cannot hold up a user who is waiting for a reply.
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders waiting tasks; higher runs first.
+type Priority int
+
+const (
+	Scheduled   Priority = iota // cron, heartbeat and other background jobs
+	Interactive                 // a user waiting for a reply
+)
+
+// Limits bound how many tasks run at once.
+type Limits struct {
+	MaxConcurrent   int // tasks running across all conversations (default 4)
+	PerConversation int // tasks running in one conversation (default 1)
+}
+
+const (
+	defaultMaxConcurrent   = 4
+	defaultPerConversation = 1
+)
+
+// Stats is a snapshot of the queue.
+type Stats struct {
+	Running          int
+	RunningScheduled int
+	Waiting          int
+	WaitingScheduled int
+}
+
+type ticket struct {
+	key      string
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	granted  bool
+}
+
+// Queue admits tasks in priority order within its limits. The zero value is
+// not usable; call New.
+type Queue struct {
+	mu        sync.Mutex
+	limits    Limits
+	seq       uint64
+	waiting   []*ticket
+	running   map[string]int
+	total     int
+	scheduled int
+}
+
+// New returns a queue with the given limits; zero fields take the defaults.
+func New(limits Limits) *Queue {
+	q := &Queue{running: make(map[string]int)}
+	q.limits = normalize(limits)
+	return q
+}
+
+func normalize(l Limits) Limits {
+	if l.MaxConcurrent <= 0 {
+		l.MaxConcurrent = defaultMaxConcurrent
+	}
+	if l.PerConversation <= 0 {
+		l.PerConversation = defaultPerConversation
+	}
+	return l
+}
+
+// SetLimits changes the limits. Running tasks are not interrupted; lowering
+// a limit only delays new ones.
+func (q *Queue) SetLimits(limits Limits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limits = normalize(limits)
+	q.dispatchLocked()
+}
+
+// Stats reports how many tasks are running and waiting.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := Stats{Running: q.total, RunningScheduled: q.scheduled, Waiting: len(q.waiting)}
+	for _, t := range q.waiting {
+		if t.priority == Scheduled {
+			s.WaitingScheduled++
+		}
+	}
+	return s
+}
+
+// Acquire waits for a slot for a task of conversation key and returns the
+// function that frees it. It fails only when ctx ends first.
+func (q *Queue) Acquire(ctx context.Context, key string, priority Priority) (func(), error) {
+	q.mu.Lock()
+	q.seq++
+	t := &ticket{key: key, priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.waiting = append(q.waiting, t)
+	q.dispatchLocked()
+	q.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return q.releaseFunc(t), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		if t.granted {
+			// Granted while giving up: hand the slot to the next task.
+			q.releaseLocked(t)
+		} else {
+			q.removeLocked(t)
+		}
+		q.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Do runs fn once a slot is free.
+func (q *Queue) Do(ctx context.Context, key string, priority Priority, fn func(context.Context) error) error {
+	release, err := q.Acquire(ctx, key, priority)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+func (q *Queue) releaseFunc(t *ticket) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			q.releaseLocked(t)
+			q.mu.Unlock()
+		})
+	}
+}
+
+func (q *Queue) releaseLocked(t *ticket) {
+	q.running[t.key]--
+	if q.running[t.key] <= 0 {
+		delete(q.running, t.key)
+	}
+	q.total--
+	if t.priority == Scheduled {
+		q.scheduled--
+	}
+	q.dispatchLocked()
+}
+
+func (q *Queue) removeLocked(t *ticket) {
+	for i, w := range q.waiting {
+		if w == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchLocked grants slots to waiting tasks: highest priority first,
+// then oldest, skipping conversations that are at their limit.
+func (q *Queue) dispatchLocked() {
+	for q.total < q.limits.MaxConcurrent {
+		var next *ticket
+		for _, t := range q.waiting {
+			if !q.eligibleLocked(t) {
+				continue
+			}
+			if next == nil || t.priority > next.priority || (t.priority == next.priority && t.seq < next.seq) {
+				next = t
+			}
+		}
+		if next == nil {
+			return
+		}
+		q.removeLocked(next)
+		q.running[next.key]++
+		q.total++
+		if next.priority == Scheduled {
+			q.scheduled++
+		}
+		next.granted = true
+		close(next.ready)
+	}
+}
+
+func (q *Queue) eligibleLocked(t *ticket) bool {
+	if q.running[t.key] >= q.limits.PerConversation {
+		return false
+	}
+	// Keep one slot for live chats whenever there is more than one.
+	if t.priority == Scheduled && q.limits.MaxConcurrent > 1 && q.scheduled >= q.limits.MaxConcurrent-1 {
+		return false
+	}
+	return true
+}
+
+// Retry configures Queue.DoRetry.
+type Retry struct {
+	Attempts int           // total runs including the first (default 3)
+	Backoff  time.Duration // wait before the first retry, doubled each time (default 30s)
+}
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 30 * time.Second
+)
+
+// DoRetry runs fn like Do and, when it fails, runs it again after an
+// exponentially growing backoff. The slot is released while waiting, so
+// other tasks run in between. A task that was canceled is not retried.
+// It returns the last error.
+func (q *Queue) DoRetry(ctx context.Context, key string, priority Priority, retry Retry, fn func(context.Context) error) error {
+	if retry.Attempts <= 0 {
+		retry.Attempts = defaultRetryAttempts
+	}
+	if retry.Backoff <= 0 {
+		retry.Backoff = defaultRetryBackoff
+	}
+	backoff := retry.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = q.Do(ctx, key, priority, fn)
+		if err == nil || attempt >= retry.Attempts || errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
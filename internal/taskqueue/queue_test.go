@@ -0,0 +1,149 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustAcquire(t *testing.T, q *Queue, key string, p Priority) func() {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err := q.Acquire(ctx, key, p)
+	if err != nil {
+		t.Fatalf("acquire %s: %v", key, err)
+	}
+	return release
+}
+
+// acquireAsync starts waiting for a slot and reports the key once granted.
+func acquireAsync(q *Queue, key string, p Priority, granted chan<- string) {
+	go func() {
+		release, err := q.Acquire(context.Background(), key, p)
+		if err != nil {
+			return
+		}
+		granted <- key
+		release()
+	}()
+}
+
+func waitForWaiting(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.Stats().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting = %d, want %d", q.Stats().Waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInteractiveGoesFirst(t *testing.T) {
+	q := New(Limits{MaxConcurrent: 1})
+	release := mustAcquire(t, q, "busy", Interactive)
+
+	granted := make(chan string, 3)
+	acquireAsync(q, "cron-a", Scheduled, granted)
+	waitForWaiting(t, q, 1)
+	acquireAsync(q, "cron-b", Scheduled, granted)
+	waitForWaiting(t, q, 2)
+	acquireAsync(q, "chat", Interactive, granted)
+	waitForWaiting(t, q, 3)
+
+	release()
+	var order []string
+	for range 3 {
+		order = append(order, <-granted)
+	}
+	if order[0] != "chat" || order[1] != "cron-a" || order[2] != "cron-b" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestPerConversationLimit(t *testing.T) {
+	q := New(Limits{MaxConcurrent: 4})
+	release := mustAcquire(t, q, "c1", Interactive)
+
+	granted := make(chan string, 2)
+	acquireAsync(q, "c1", Interactive, granted)
+	waitForWaiting(t, q, 1)
+	// Another conversation is not held up by c1.
+	releaseOther := mustAcquire(t, q, "c2", Interactive)
+	defer releaseOther()
+
+	select {
+	case <-granted:
+		t.Fatal("second c1 task ran alongside the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	if key := <-granted; key != "c1" {
+		t.Fatalf("granted %q", key)
+	}
+}
+
+func TestScheduledKeepsSlotForChats(t *testing.T) {
+	q := New(Limits{MaxConcurrent: 2})
+	releaseCron := mustAcquire(t, q, "cron-a", Scheduled)
+	defer releaseCron()
+
+	granted := make(chan string, 1)
+	acquireAsync(q, "cron-b", Scheduled, granted)
+	waitForWaiting(t, q, 1)
+
+	releaseChat := mustAcquire(t, q, "chat", Interactive)
+	if s := q.Stats(); s.Running != 2 || s.RunningScheduled != 1 || s.WaitingScheduled != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+	releaseChat()
+	select {
+	case <-granted:
+		t.Fatal("second scheduled task took the chat slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestAcquireGivesUpWithContext(t *testing.T) {
+	q := New(Limits{MaxConcurrent: 1})
+	release := mustAcquire(t, q, "c1", Interactive)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Acquire(ctx, "c2", Interactive); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+	if s := q.Stats(); s.Waiting != 0 {
+		t.Fatalf("abandoned task still waiting: %+v", s)
+	}
+	release()
+	mustAcquire(t, q, "c2", Interactive)()
+}
+
+func TestDoRetryBacksOff(t *testing.T) {
+	q := New(Limits{})
+	var runs []time.Time
+	err := q.DoRetry(context.Background(), "c1", Scheduled, Retry{Attempts: 3, Backoff: 10 * time.Millisecond}, func(context.Context) error {
+		runs = append(runs, time.Now())
+		if len(runs) < 3 {
+			return errors.New("provider unavailable")
+		}
+		return nil
+	})
+	if err != nil || len(runs) != 3 {
+		t.Fatalf("err = %v, runs = %d", err, len(runs))
+	}
+	if gap := runs[2].Sub(runs[1]); gap < 20*time.Millisecond {
+		t.Fatalf("second backoff %v, want doubled", gap)
+	}
+
+	runs = nil
+	err = q.DoRetry(context.Background(), "c1", Scheduled, Retry{Attempts: 3, Backoff: time.Millisecond}, func(context.Context) error {
+		runs = append(runs, time.Now())
+		return context.Canceled
+	})
+	if !errors.Is(err, context.Canceled) || len(runs) != 1 {
+		t.Fatalf("canceled task retried: err = %v, runs = %d", err, len(runs))
+	}
+}
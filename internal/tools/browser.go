@@ -12,6 +12,7 @@ import (
 	"github.com/go-rod/rod/lib/proto"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/kayz/coco/internal/browser"
+	"github.com/kayz/coco/internal/datadir"
 	"github.com/kayz/coco/internal/logger"
 )
 
@@ -33,9 +34,12 @@ func BrowserStart(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 	if c, ok := req.Params.Arguments["cdp_url"].(string); ok {
 		opts.ConnectURL = c
 	}
+	if p, ok := req.Params.Arguments["profile"].(string); ok {
+		opts.Profile = strings.TrimSpace(p)
+	}
 
 	b := browser.Instance()
-	logger.Debug("[browser_start] headless=%v url=%q cdp_url=%q executable=%q", opts.Headless, opts.URL, opts.ConnectURL, opts.ExecutablePath)
+	logger.Debug("[browser_start] headless=%v url=%q cdp_url=%q executable=%q profile=%q", opts.Headless, opts.URL, opts.ConnectURL, opts.ExecutablePath, opts.Profile)
 	if err := b.Start(opts); err != nil {
 		logger.Debug("[browser_start] failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
@@ -49,7 +53,7 @@ func BrowserStart(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 		if !opts.Headless {
 			mode = "headed"
 		}
-		msg = fmt.Sprintf("Browser started (%s)", mode)
+		msg = fmt.Sprintf("Browser started (%s, profile %s)", mode, b.Status().Profile)
 	}
 	if opts.URL != "" {
 		msg += fmt.Sprintf(", navigated to %s", opts.URL)
@@ -210,9 +214,8 @@ func BrowserScreenshot(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 	if p, ok := req.Params.Arguments["path"].(string); ok && p != "" {
 		outputPath = p
 	} else {
-		exeDir := GetExecutableDir()
 		timestamp := time.Now().Format("2006-01-02_15-04-05")
-		outputPath = filepath.Join(exeDir, fmt.Sprintf("browser_screenshot_%s.png", timestamp))
+		outputPath = datadir.Path(fmt.Sprintf("browser_screenshot_%s.png", timestamp))
 	}
 
 	if len(outputPath) > 0 && outputPath[0] == '~' {
@@ -415,6 +418,20 @@ func BrowserExecuteJS(_ context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 	return mcp.NewToolResultText(result), nil
 }
 
+// BrowserCountMatches counts elements browser_click_all would target on the
+// active page without starting the browser or clicking anything.
+func BrowserCountMatches(selector, skipSelector string) (total, skipped int, err error) {
+	b := browser.Instance()
+	if !b.IsRunning() {
+		return 0, 0, fmt.Errorf("browser is not running")
+	}
+	page, err := b.ActivePage()
+	if err != nil {
+		return 0, 0, err
+	}
+	return browser.CountMatches(page, selector, skipSelector)
+}
+
 // BrowserClickAll clicks all elements matching a CSS selector with delay.
 func BrowserClickAll(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	selector, ok := req.Params.Arguments["selector"].(string)
@@ -557,3 +574,67 @@ func BrowserTabClose(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 func containsString(s, substr string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
+
+// BrowserNetworkStartCapture records the XHR and fetch requests of the open
+// tabs so data can be read from the site's own API responses.
+func BrowserNetworkStartCapture(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
+	}
+
+	filter, _ := req.Params.Arguments["url_filter"].(string)
+	maxBody := browser.DefaultMaxBodyBytes
+	if n, ok := req.Params.Arguments["max_body_bytes"].(float64); ok && n > 0 {
+		maxBody = int(n)
+	}
+
+	logger.Debug("[browser_network_start_capture] filter=%q max_body=%d", filter, maxBody)
+	tabs, err := b.StartNetworkCapture(filter, maxBody)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start capture: %v", err)), nil
+	}
+	msg := fmt.Sprintf("Capturing XHR/fetch requests in %d tab(s), bodies up to %d bytes", tabs, maxBody)
+	if filter != "" {
+		msg += fmt.Sprintf(", URLs containing %q", filter)
+	}
+	return mcp.NewToolResultText(msg + ". Load or scroll the page, then call browser_network_get_requests."), nil
+}
+
+// BrowserNetworkGetRequests returns the requests recorded since
+// browser_network_start_capture.
+func BrowserNetworkGetRequests(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter, _ := req.Params.Arguments["url_filter"].(string)
+	clear, _ := req.Params.Arguments["clear"].(bool)
+	stop, _ := req.Params.Arguments["stop"].(bool)
+	withBodies := true
+	if v, ok := req.Params.Arguments["include_bodies"].(bool); ok {
+		withBodies = v
+	}
+
+	b := browser.Instance()
+	entries, capturing, err := b.NetworkRequests(strings.TrimSpace(filter), clear)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if stop {
+		b.StopNetworkCapture()
+		capturing = false
+	}
+	if !withBodies {
+		for i := range entries {
+			entries[i].RequestBody, entries[i].ResponseBody = "", ""
+		}
+	}
+
+	state := "capture running"
+	if !capturing {
+		state = "capture stopped"
+	}
+	if len(entries) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No requests captured yet (%s)", state)), nil
+	}
+	data, _ := json.MarshalIndent(entries, "", "  ")
+	logger.Debug("[browser_network_get_requests] %d requests, %s", len(entries), state)
+	return mcp.NewToolResultText(fmt.Sprintf("%d requests (%s):\n%s", len(entries), state, string(data))), nil
+}
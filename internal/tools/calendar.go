@@ -7,16 +7,30 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kayz/coco/internal/netcal"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// CalendarListEvents lists calendar events (macOS)
+// CalendarListEvents lists calendar events
 func CalendarListEvents(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	days := 7
 	if d, ok := req.Params.Arguments["days"].(float64); ok && d > 0 {
 		days = int(d)
 	}
 
+	if cal := networkCalendar(); cal != nil {
+		now := time.Now()
+		return netEvents(ctx, cal, now, now.AddDate(0, 0, days), "", "", "No events found")
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookEvents(ctx, days, "", "", "No events found")
+	default:
+		return khalList(ctx, days, "", "No events found")
+	}
+
 	script := fmt.Sprintf(`
 		set output to ""
 		set startDate to current date
@@ -49,7 +63,7 @@ func CalendarListEvents(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 	return mcp.NewToolResultText(string(output)), nil
 }
 
-// CalendarCreateEvent creates a new calendar event (macOS)
+// CalendarCreateEvent creates a new calendar event
 func CalendarCreateEvent(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok {
@@ -81,13 +95,37 @@ func CalendarCreateEvent(ctx context.Context, req mcp.CallToolRequest) (*mcp.Cal
 		notes = n
 	}
 
-	t, err := time.Parse("2006-01-02 15:04", startTime)
+	attendees := attendeeList(req.Params.Arguments["attendees"])
+
+	t, err := time.ParseInLocation("2006-01-02 15:04", startTime, time.Local)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid start_time format, use YYYY-MM-DD HH:MM: %v", err)), nil
 	}
 
 	endTime := t.Add(time.Duration(duration) * time.Minute)
 
+	if cal := networkCalendar(); cal != nil {
+		return netCreateEvent(ctx, cal, netcal.Event{
+			Title:       title,
+			Start:       t,
+			End:         endTime,
+			Location:    location,
+			Description: notes,
+			Attendees:   attendees,
+		})
+	}
+	if len(attendees) > 0 {
+		return mcp.NewToolResultError("inviting attendees needs a network calendar: set calendar.provider to caldav or google in .coco.yaml"), nil
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookCreateEvent(ctx, calendar, title, location, notes, t, duration)
+	default:
+		return khalCreate(ctx, calendar, title, location, notes, t, endTime)
+	}
+
 	// Build AppleScript - use calendar 1 if no calendar specified
 	var calendarRef string
 	if calendar != "" {
@@ -135,6 +173,18 @@ func CalendarCreateEvent(ctx context.Context, req mcp.CallToolRequest) (*mcp.Cal
 
 // CalendarListCalendars lists available calendars
 func CalendarListCalendars(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if cal := networkCalendar(); cal != nil {
+		return mcp.NewToolResultText(cal.Name() + "\n"), nil
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookCalendars(ctx)
+	default:
+		return khalCalendars(ctx)
+	}
+
 	script := `
 		tell application "Calendar"
 			set output to ""
@@ -156,6 +206,19 @@ func CalendarListCalendars(ctx context.Context, req mcp.CallToolRequest) (*mcp.C
 
 // CalendarToday returns today's agenda
 func CalendarToday(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if cal := networkCalendar(); cal != nil {
+		today := startOfDay(time.Now())
+		return netEvents(ctx, cal, today, today.AddDate(0, 0, 1), "", "Today's agenda:\n", "No events scheduled for today")
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookEvents(ctx, 1, "", "Today's agenda:\n", "No events scheduled for today")
+	default:
+		return khalList(ctx, 1, "Today's agenda:\n", "No events scheduled for today")
+	}
+
 	script := `
 		set output to ""
 		set todayStart to current date
@@ -206,6 +269,19 @@ func CalendarSearchEvents(ctx context.Context, req mcp.CallToolRequest) (*mcp.Ca
 		days = int(d)
 	}
 
+	if cal := networkCalendar(); cal != nil {
+		now := time.Now()
+		return netEvents(ctx, cal, now, now.AddDate(0, 0, days), keyword, "", fmt.Sprintf("No events found matching '%s'", keyword))
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookEvents(ctx, days, keyword, "", fmt.Sprintf("No events found matching '%s'", keyword))
+	default:
+		return khalSearch(ctx, keyword)
+	}
+
 	script := fmt.Sprintf(`
 		set output to ""
 		set searchTerm to "%s"
@@ -260,6 +336,18 @@ func CalendarDeleteEvent(ctx context.Context, req mcp.CallToolRequest) (*mcp.Cal
 		date = d
 	}
 
+	if cal := networkCalendar(); cal != nil {
+		return netDeleteEvent(ctx, cal, title, date)
+	}
+
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookDeleteEvent(ctx, calendar, title, date)
+	default:
+		return mcp.NewToolResultError("khal cannot delete events from the command line; delete it in ikhal or another CalDAV client"), nil
+	}
+
 	var script string
 	if calendar != "" && date != "" {
 		// Delete from specific calendar on specific date
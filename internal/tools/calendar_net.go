@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/netcal"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// A network calendar configured in .coco.yaml (calendar.provider caldav or
+// google) takes over every calendar_* tool from the platform backend.
+
+var (
+	netCalMu sync.RWMutex
+	netCal   netcal.Calendar
+)
+
+// SetNetworkCalendar routes the calendar tools to cal. nil restores the
+// platform backend.
+func SetNetworkCalendar(cal netcal.Calendar) {
+	netCalMu.Lock()
+	defer netCalMu.Unlock()
+	netCal = cal
+}
+
+func networkCalendar() netcal.Calendar {
+	netCalMu.RLock()
+	defer netCalMu.RUnlock()
+	return netCal
+}
+
+func formatNetEvent(e netcal.Event) string {
+	var sb strings.Builder
+	if e.AllDay {
+		fmt.Fprintf(&sb, "%s all day", e.Start.Format("2006-01-02"))
+	} else {
+		fmt.Fprintf(&sb, "%s-%s", e.Start.Format("2006-01-02 15:04"), e.End.Format("15:04"))
+	}
+	fmt.Fprintf(&sb, " | %s", e.Title)
+	if e.Location != "" {
+		fmt.Fprintf(&sb, " @ %s", e.Location)
+	}
+	if len(e.Attendees) > 0 {
+		fmt.Fprintf(&sb, " (with %s)", strings.Join(e.Attendees, ", "))
+	}
+	return sb.String()
+}
+
+// netEvents lists the events in [from, to) whose title, location or
+// description contains keyword.
+func netEvents(ctx context.Context, cal netcal.Calendar, from, to time.Time, keyword, header, empty string) (*mcp.CallToolResult, error) {
+	events, err := cal.Events(ctx, from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get events: %v", err)), nil
+	}
+	needle := strings.ToLower(keyword)
+	var sb strings.Builder
+	for _, e := range events {
+		if needle != "" && !strings.Contains(strings.ToLower(e.Title+"\n"+e.Location+"\n"+e.Description), needle) {
+			continue
+		}
+		sb.WriteString(formatNetEvent(e) + "\n")
+	}
+	if sb.Len() == 0 {
+		return mcp.NewToolResultText(empty), nil
+	}
+	return mcp.NewToolResultText(header + sb.String()), nil
+}
+
+func netCreateEvent(ctx context.Context, cal netcal.Calendar, e netcal.Event) (*mcp.CallToolResult, error) {
+	created, err := cal.Create(ctx, e)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create event: %v", err)), nil
+	}
+	msg := fmt.Sprintf("Created: %s on %s for %d minutes", created.Title, created.Start.Format("2006-01-02 15:04"), int(created.End.Sub(created.Start).Minutes()))
+	if len(created.Attendees) > 0 {
+		msg += fmt.Sprintf("\nInvited: %s", strings.Join(created.Attendees, ", "))
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+// netDeleteEvent deletes the first event titled title on date, or from 30
+// days ago to a year ahead without a date.
+func netDeleteEvent(ctx context.Context, cal netcal.Calendar, title, date string) (*mcp.CallToolResult, error) {
+	today := startOfDay(time.Now())
+	from, to := today.AddDate(0, 0, -30), today.AddDate(1, 0, 0)
+	if date != "" {
+		d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid date format, use YYYY-MM-DD: %v", err)), nil
+		}
+		from, to = d, d.AddDate(0, 0, 1)
+	}
+	events, err := cal.Events(ctx, from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete event: %v", err)), nil
+	}
+	for _, e := range events {
+		if !strings.EqualFold(e.Title, title) {
+			continue
+		}
+		if err := cal.Delete(ctx, e); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete event: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Deleted event: %s", title)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Event '%s' not found", title)), nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// attendeeList accepts attendees as a JSON array or a comma-separated string.
+func attendeeList(v any) []string {
+	var raw []string
+	switch v := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' || r == '，' })
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var out []string
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// CalendarFreeBusy reports when the user and the given attendees are busy
+// and which slots are free for all of them.
+func CalendarFreeBusy(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cal := networkCalendar()
+	if cal == nil {
+		return mcp.NewToolResultError("free/busy needs a network calendar: set calendar.provider to caldav or google in .coco.yaml"), nil
+	}
+
+	now := time.Now()
+	from, to := workingHours(now)
+	if s, ok := req.Params.Arguments["start"].(string); ok && s != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid start format, use YYYY-MM-DD HH:MM: %v", err)), nil
+		}
+		from, to = t, startOfDay(t).Add(18*time.Hour)
+		if !to.After(from) {
+			to = from.Add(2 * time.Hour)
+		}
+	}
+	if s, ok := req.Params.Arguments["end"].(string); ok && s != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid end format, use YYYY-MM-DD HH:MM: %v", err)), nil
+		}
+		to = t
+	}
+	if !to.After(from) {
+		return mcp.NewToolResultError("end must be after start"), nil
+	}
+	minLen := 30 * time.Minute
+	if d, ok := req.Params.Arguments["duration"].(float64); ok && d > 0 {
+		minLen = time.Duration(d) * time.Minute
+	}
+	attendees := attendeeList(req.Params.Arguments["attendees"])
+
+	busy, err := cal.FreeBusy(ctx, from, to, attendees)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to query free/busy: %v", err)), nil
+	}
+	return mcp.NewToolResultText(formatFreeBusy(busy, attendees, from, to, minLen)), nil
+}
+
+// workingHours is 09:00-18:00 today, or tomorrow once today's are over.
+func workingHours(now time.Time) (time.Time, time.Time) {
+	day := startOfDay(now)
+	from, to := day.Add(9*time.Hour), day.Add(18*time.Hour)
+	if !now.Before(to) {
+		return from.AddDate(0, 0, 1), to.AddDate(0, 0, 1)
+	}
+	if now.After(from) {
+		from = now.Truncate(15 * time.Minute)
+	}
+	return from, to
+}
+
+func formatFreeBusy(busy map[string][]netcal.Busy, attendees []string, from, to time.Time, minLen time.Duration) string {
+	span := func(b netcal.Busy) string {
+		if startOfDay(b.Start) == startOfDay(from) && startOfDay(b.End) == startOfDay(from) {
+			return b.Start.Format("15:04") + "-" + b.End.Format("15:04")
+		}
+		return b.Start.Format("01-02 15:04") + "-" + b.End.Format("01-02 15:04")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Free/busy %s %s:\n", from.Format("2006-01-02"), span(netcal.Busy{Start: from, End: to}))
+	who := append([]string{"me"}, attendees...)
+	var all []netcal.Busy
+	var unknown []string
+	for _, w := range who {
+		spans, ok := busy[w]
+		if !ok {
+			unknown = append(unknown, w)
+			fmt.Fprintf(&sb, "%s: unavailable (calendar not shared or not supported by the server)\n", w)
+			continue
+		}
+		all = append(all, spans...)
+		if len(spans) == 0 {
+			fmt.Fprintf(&sb, "%s: free\n", w)
+			continue
+		}
+		parts := make([]string, len(spans))
+		for i, b := range spans {
+			parts[i] = span(b)
+		}
+		fmt.Fprintf(&sb, "%s: busy %s\n", w, strings.Join(parts, ", "))
+	}
+
+	free := netcal.FreeSlots(all, from, to, minLen)
+	label := "Free for everyone"
+	if len(unknown) > 0 {
+		label = "Free for everyone known"
+	}
+	if len(free) == 0 {
+		fmt.Fprintf(&sb, "%s: no slot of %d minutes\n", label, int(minLen.Minutes()))
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "%s (≥%d min):\n", label, int(minLen.Minutes()))
+	for _, f := range free {
+		sb.WriteString(span(f) + "\n")
+	}
+	return sb.String()
+}
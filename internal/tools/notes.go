@@ -9,8 +9,12 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// NotesListFolders lists all note folders (macOS)
+// NotesListFolders lists all note folders
 func NotesListFolders(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if pimOS != "darwin" {
+		return notesDirFolders()
+	}
+
 	script := `
 		tell application "Notes"
 			set output to ""
@@ -32,7 +36,7 @@ func NotesListFolders(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	return mcp.NewToolResultText("Note Folders:\n" + string(output)), nil
 }
 
-// NotesListNotes lists notes in a folder (macOS)
+// NotesListNotes lists notes in a folder
 func NotesListNotes(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	folder := "Notes"
 	if f, ok := req.Params.Arguments["folder"].(string); ok && f != "" {
@@ -44,6 +48,10 @@ func NotesListNotes(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 		limit = int(l)
 	}
 
+	if pimOS != "darwin" {
+		return notesDirList(folder, limit)
+	}
+
 	script := fmt.Sprintf(`
 		tell application "Notes"
 			set output to ""
@@ -74,13 +82,17 @@ func NotesListNotes(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 	return mcp.NewToolResultText(fmt.Sprintf("Notes in %s:\n%s", folder, output)), nil
 }
 
-// NotesRead reads a note's content (macOS)
+// NotesRead reads a note's content
 func NotesRead(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
 		return mcp.NewToolResultError("title is required"), nil
 	}
 
+	if pimOS != "darwin" {
+		return notesDirRead(title)
+	}
+
 	script := fmt.Sprintf(`
 		tell application "Notes"
 			repeat with f in folders
@@ -108,7 +120,7 @@ func NotesRead(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResul
 	return mcp.NewToolResultText(result), nil
 }
 
-// NotesCreate creates a new note (macOS)
+// NotesCreate creates a new note
 func NotesCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
@@ -125,6 +137,10 @@ func NotesCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 		folder = f
 	}
 
+	if pimOS != "darwin" {
+		return notesDirCreate(folder, title, body)
+	}
+
 	// Create HTML content (Notes app uses HTML internally)
 	content := fmt.Sprintf("<h1>%s</h1>", escapeHTML(title))
 	if body != "" {
@@ -149,13 +165,17 @@ func NotesCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 	return mcp.NewToolResultText(fmt.Sprintf("Created note: %s", title)), nil
 }
 
-// NotesSearch searches notes by keyword (macOS)
+// NotesSearch searches notes by keyword
 func NotesSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	keyword, ok := req.Params.Arguments["keyword"].(string)
 	if !ok || keyword == "" {
 		return mcp.NewToolResultError("keyword is required"), nil
 	}
 
+	if pimOS != "darwin" {
+		return notesDirSearch(keyword)
+	}
+
 	script := fmt.Sprintf(`
 		set searchTerm to "%s"
 		set output to ""
@@ -187,13 +207,17 @@ func NotesSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 	return mcp.NewToolResultText(fmt.Sprintf("Notes matching '%s':\n%s", keyword, output)), nil
 }
 
-// NotesDelete deletes a note (macOS)
+// NotesDelete deletes a note
 func NotesDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
 		return mcp.NewToolResultError("title is required"), nil
 	}
 
+	if pimOS != "darwin" {
+		return notesDirDelete(title)
+	}
+
 	script := fmt.Sprintf(`
 		tell application "Notes"
 			repeat with f in folders
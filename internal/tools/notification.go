@@ -31,6 +31,18 @@ func NotificationSend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 		sound = s
 	}
 
+	if err := notify(ctx, title, message, subtitle, sound); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText("Notification sent"), nil
+}
+
+// Notify shows a desktop notification with the default sound.
+func Notify(ctx context.Context, title, message string) error {
+	return notify(ctx, title, message, "", true)
+}
+
+func notify(ctx context.Context, title, message, subtitle string, sound bool) error {
 	switch runtime.GOOS {
 	case "darwin":
 		return notifyMacOS(ctx, title, message, subtitle, sound)
@@ -39,11 +51,11 @@ func NotificationSend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	case "windows":
 		return notifyWindows(ctx, title, message)
 	default:
-		return mcp.NewToolResultError(fmt.Sprintf("notifications not supported on %s", runtime.GOOS)), nil
+		return fmt.Errorf("notifications not supported on %s", runtime.GOOS)
 	}
 }
 
-func notifyMacOS(ctx context.Context, title, message, subtitle string, sound bool) (*mcp.CallToolResult, error) {
+func notifyMacOS(ctx context.Context, title, message, subtitle string, sound bool) error {
 	script := fmt.Sprintf(`display notification "%s"`, escapeAppleScript(message))
 	script += fmt.Sprintf(` with title "%s"`, escapeAppleScript(title))
 	if subtitle != "" {
@@ -56,21 +68,20 @@ func notifyMacOS(ctx context.Context, title, message, subtitle string, sound boo
 	cmd := exec.CommandContext(ctx, "osascript", "-e", script)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to send notification: %v - %s", err, output)), nil
+		return fmt.Errorf("failed to send notification: %v - %s", err, output)
 	}
-
-	return mcp.NewToolResultText("Notification sent"), nil
+	return nil
 }
 
-func notifyLinux(ctx context.Context, title, message string) (*mcp.CallToolResult, error) {
+func notifyLinux(ctx context.Context, title, message string) error {
 	cmd := exec.CommandContext(ctx, "notify-send", title, message)
 	if err := cmd.Run(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to send notification: %v", err)), nil
+		return fmt.Errorf("failed to send notification: %v", err)
 	}
-	return mcp.NewToolResultText("Notification sent"), nil
+	return nil
 }
 
-func notifyWindows(ctx context.Context, title, message string) (*mcp.CallToolResult, error) {
+func notifyWindows(ctx context.Context, title, message string) error {
 	// Use PowerShell to create a balloon notification
 	script := fmt.Sprintf(`
 		[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
@@ -87,8 +98,8 @@ func notifyWindows(ctx context.Context, title, message string) (*mcp.CallToolRes
 		// Fallback to msg command
 		cmd = exec.CommandContext(ctx, "msg", "*", fmt.Sprintf("%s: %s", title, message))
 		if err := cmd.Run(); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to send notification: %v", err)), nil
+			return fmt.Errorf("failed to send notification: %v", err)
 		}
 	}
-	return mcp.NewToolResultText("Notification sent"), nil
+	return nil
 }
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Calendar, reminders and notes use the platform's own apps where there is
+// a scriptable one: Calendar/Reminders/Notes through AppleScript on macOS,
+// Outlook through COM on Windows, and khal/todoman (synced with CalDAV by
+// vdirsyncer) elsewhere. Notes outside macOS are Markdown files in a folder.
+// A CalDAV or Google calendar configured in .coco.yaml serves the calendar
+// tools on every platform.
+
+// pimInstallHints say what to install when a command-line backend is missing.
+var pimInstallHints = map[string]string{
+	"khal": "khal not found (install khal and sync CalDAV with vdirsyncer)",
+	"todo": "todo not found (install todoman and sync CalDAV with vdirsyncer)",
+}
+
+// pimOS is the platform the calendar, reminders and notes tools dispatch on.
+var pimOS = runtime.GOOS
+
+// PIMBackend describes what serves one group of calendar_*, reminders_* or
+// notes_* tools on this machine.
+type PIMBackend struct {
+	Group     string // "calendar", "reminders" or "notes"
+	Name      string
+	Available bool
+	Detail    string // where the data lives, or what is missing
+}
+
+// PIMBackends reports the calendar, reminders and notes backends for this
+// platform and whether each can be used. A network calendar replaces the
+// platform's calendar backend.
+func PIMBackends() []PIMBackend {
+	backends := platformPIMBackends()
+	if cal := networkCalendar(); cal != nil {
+		for i := range backends {
+			if backends[i].Group == "calendar" {
+				backends[i] = PIMBackend{Group: "calendar", Name: cal.Name(), Available: true}
+			}
+		}
+	}
+	return backends
+}
+
+func platformPIMBackends() []PIMBackend {
+	notes := PIMBackend{Group: "notes", Name: "Markdown folder", Available: true, Detail: NotesDir()}
+	switch pimOS {
+	case "darwin":
+		ok := commandAvailable("osascript")
+		return []PIMBackend{
+			{Group: "calendar", Name: "Calendar.app", Available: ok},
+			{Group: "reminders", Name: "Reminders.app", Available: ok},
+			{Group: "notes", Name: "Notes.app", Available: ok},
+		}
+	case "windows":
+		outlook := PIMBackend{Name: "Outlook", Available: outlookAvailable()}
+		if !outlook.Available {
+			outlook.Detail = "Outlook desktop is not installed"
+		}
+		calendar, tasks := outlook, outlook
+		calendar.Group, tasks.Group = "calendar", "reminders"
+		tasks.Name = "Outlook tasks"
+		return []PIMBackend{calendar, tasks, notes}
+	default:
+		calendar := PIMBackend{Group: "calendar", Name: "khal", Available: commandAvailable("khal")}
+		if !calendar.Available {
+			calendar.Detail = pimInstallHints["khal"]
+		}
+		tasks := PIMBackend{Group: "reminders", Name: "todoman", Available: commandAvailable("todo")}
+		if !tasks.Available {
+			tasks.Detail = pimInstallHints["todo"]
+		}
+		return []PIMBackend{calendar, tasks, notes}
+	}
+}
+
+// NotesDir is where notes are kept on platforms without Notes.app:
+// $COCO_NOTES_DIR, or ~/Notes.
+func NotesDir() string {
+	if dir := os.Getenv("COCO_NOTES_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "Notes"
+	}
+	return filepath.Join(home, "Notes")
+}
+
+func commandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+var (
+	outlookOnce  sync.Once
+	outlookFound bool
+)
+
+// outlookAvailable reports whether Outlook's COM server is registered.
+func outlookAvailable() bool {
+	outlookOnce.Do(func() {
+		if !commandAvailable("powershell") {
+			return
+		}
+		err := exec.Command("reg", "query", `HKCR\Outlook.Application`).Run()
+		outlookFound = err == nil
+	})
+	return outlookFound
+}
@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// khal and todoman read the local vdir that vdirsyncer keeps in sync with
+// a CalDAV server, so changes made here reach the user's other devices on
+// the next sync.
+
+const khalEventFormat = "{start-date} {start-time}-{end-time} | {title} [{calendar}]"
+
+func runPIMCommand(ctx context.Context, stdin string, name string, args ...string) (string, error) {
+	if hint, ok := pimInstallHints[name]; ok && !commandAvailable(name) {
+		return "", errors.New(hint)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+func khalList(ctx context.Context, days int, header, empty string) (*mcp.CallToolResult, error) {
+	out, err := runPIMCommand(ctx, "", "khal", "list", "--format", khalEventFormat, "--day-format", "", "today", fmt.Sprintf("%dd", days))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get events: %v", err)), nil
+	}
+	if strings.TrimSpace(out) == "" {
+		return mcp.NewToolResultText(empty), nil
+	}
+	return mcp.NewToolResultText(header + strings.TrimLeft(out, "\n")), nil
+}
+
+func khalSearch(ctx context.Context, keyword string) (*mcp.CallToolResult, error) {
+	out, err := runPIMCommand(ctx, "", "khal", "search", "--format", khalEventFormat, keyword)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to search events: %v", err)), nil
+	}
+	if strings.TrimSpace(out) == "" {
+		return mcp.NewToolResultText(fmt.Sprintf("No events found matching '%s'", keyword)), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func khalCalendars(ctx context.Context) (*mcp.CallToolResult, error) {
+	out, err := runPIMCommand(ctx, "", "khal", "printcalendars")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list calendars: %v", err)), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+// khalCreate imports the event as an iCalendar file, which unlike
+// "khal new" does not depend on the user's configured date format.
+func khalCreate(ctx context.Context, calendar, title, location, notes string, start, end time.Time) (*mcp.CallToolResult, error) {
+	f, err := os.CreateTemp("", "coco-event-*.ics")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create event: %v", err)), nil
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(buildICSEvent(title, location, notes, start, end, time.Now()))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create event: %v", err)), nil
+	}
+
+	args := []string{"import", "--batch"}
+	if calendar != "" {
+		args = append(args, "-a", calendar)
+	}
+	if _, err := runPIMCommand(ctx, "", "khal", append(args, f.Name())...); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create event: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Created: %s on %s for %d minutes", title, start.Format("2006-01-02 15:04"), int(end.Sub(start).Minutes()))), nil
+}
+
+// buildICSEvent renders a single event in floating local time.
+func buildICSEvent(title, location, notes string, start, end, now time.Time) string {
+	uid := make([]byte, 8)
+	rand.Read(uid)
+	const stamp = "20060102T150405"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//coco//calendar//EN",
+		"BEGIN:VEVENT",
+		"UID:" + hex.EncodeToString(uid) + "@coco",
+		"DTSTAMP:" + now.UTC().Format(stamp) + "Z",
+		"DTSTART:" + start.Format(stamp),
+		"DTEND:" + end.Format(stamp),
+		"SUMMARY:" + escapeICS(title),
+	}
+	if location != "" {
+		lines = append(lines, "LOCATION:"+escapeICS(location))
+	}
+	if notes != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICS(notes))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR", "")
+	return strings.Join(lines, "\r\n")
+}
+
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// todoItem is one entry of "todo --porcelain list".
+type todoItem struct {
+	ID      int    `json:"id"`
+	Summary string `json:"summary"`
+	List    string `json:"list"`
+	Due     *int64 `json:"due"`
+}
+
+func todoList(ctx context.Context) ([]todoItem, error) {
+	out, err := runPIMCommand(ctx, "", "todo", "--porcelain", "list")
+	if err != nil {
+		return nil, err
+	}
+	var items []todoItem
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		return nil, fmt.Errorf("parse todo output: %w", err)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if (items[i].Due == nil) != (items[j].Due == nil) {
+			return items[i].Due != nil
+		}
+		return items[i].Due != nil && *items[i].Due < *items[j].Due
+	})
+	return items, nil
+}
+
+func todoToday(ctx context.Context) (*mcp.CallToolResult, error) {
+	items, err := todoList(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get reminders: %v", err)), nil
+	}
+	if len(items) == 0 {
+		return mcp.NewToolResultText("No reminders found"), nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Reminders:\n")
+	for _, item := range items {
+		fmt.Fprintf(&sb, "☐ %s [%s]", item.Summary, item.List)
+		if item.Due != nil {
+			fmt.Fprintf(&sb, " - Due: %s", time.Unix(*item.Due, 0).Format("2006-01-02 15:04"))
+		}
+		sb.WriteString("\n")
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func todoLists(ctx context.Context) (*mcp.CallToolResult, error) {
+	items, err := todoList(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list reminder lists: %v", err)), nil
+	}
+	counts := map[string]int{}
+	var names []string
+	for _, item := range items {
+		if counts[item.List] == 0 {
+			names = append(names, item.List)
+		}
+		counts[item.List]++
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("Reminder Lists:\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s (%d items)\n", name, counts[name])
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func todoAdd(ctx context.Context, list, title, notes string, due time.Time, hasTime bool) (*mcp.CallToolResult, error) {
+	args := []string{"new"}
+	if list != "" {
+		args = append(args, "--list", list)
+	}
+	if !due.IsZero() {
+		if hasTime {
+			args = append(args, "--due", due.Format("2006-01-02 15:04"))
+		} else {
+			args = append(args, "--due", due.Format("2006-01-02"))
+		}
+	}
+	if notes != "" {
+		args = append(args, "--read-description")
+	}
+	if _, err := runPIMCommand(ctx, notes, "todo", append(args, "--", title)...); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create reminder: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Created reminder: %s", title)), nil
+}
+
+// todoFinish marks done or deletes the first open todo titled title.
+func todoFinish(ctx context.Context, title string, remove bool) (*mcp.CallToolResult, error) {
+	verb, past := "complete", "Completed"
+	if remove {
+		verb, past = "delete", "Deleted"
+	}
+	items, err := todoList(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to %s reminder: %v", verb, err)), nil
+	}
+	for _, item := range items {
+		if item.Summary != title {
+			continue
+		}
+		args := []string{"done", fmt.Sprint(item.ID)}
+		if remove {
+			args = []string{"delete", "--yes", fmt.Sprint(item.ID)}
+		}
+		if _, err := runPIMCommand(ctx, "", "todo", args...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to %s reminder: %v", verb, err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("%s reminder: %s", past, title)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Reminder '%s' not found", title)), nil
+}
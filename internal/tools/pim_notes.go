@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Without Notes.app, notes are Markdown files under NotesDir: one file per
+// note, one subdirectory per folder. The default folder "Notes" is NotesDir
+// itself, so the folder can be an existing Markdown vault.
+
+const defaultNotesFolder = "Notes"
+
+type noteFile struct {
+	title  string
+	folder string
+	path   string
+	info   os.FileInfo
+}
+
+func notesFolderDir(folder string) (string, error) {
+	if folder == "" || folder == defaultNotesFolder {
+		return NotesDir(), nil
+	}
+	if strings.ContainsAny(folder, `/\`) || folder == "." || folder == ".." {
+		return "", fmt.Errorf("invalid folder name %q", folder)
+	}
+	return filepath.Join(NotesDir(), folder), nil
+}
+
+// notesIn lists the notes of one folder, newest first.
+func notesIn(folder string) ([]noteFile, error) {
+	dir, err := notesFolderDir(folder)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var notes []noteFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".md") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		notes = append(notes, noteFile{
+			title:  strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())),
+			folder: folder,
+			path:   filepath.Join(dir, e.Name()),
+			info:   info,
+		})
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].info.ModTime().After(notes[j].info.ModTime()) })
+	return notes, nil
+}
+
+func noteFolders() []string {
+	folders := []string{defaultNotesFolder}
+	entries, _ := os.ReadDir(NotesDir())
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			folders = append(folders, e.Name())
+		}
+	}
+	return folders
+}
+
+func allNotes() []noteFile {
+	var all []noteFile
+	for _, folder := range noteFolders() {
+		notes, _ := notesIn(folder)
+		all = append(all, notes...)
+	}
+	return all
+}
+
+func findNote(title string) (noteFile, bool) {
+	for _, n := range allNotes() {
+		if strings.EqualFold(n.title, title) {
+			return n, true
+		}
+	}
+	return noteFile{}, false
+}
+
+func notesDirFolders() (*mcp.CallToolResult, error) {
+	var sb strings.Builder
+	sb.WriteString("Note Folders:\n")
+	for _, folder := range noteFolders() {
+		notes, _ := notesIn(folder)
+		fmt.Fprintf(&sb, "%s (%d notes)\n", folder, len(notes))
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func notesDirList(folder string, limit int) (*mcp.CallToolResult, error) {
+	notes, err := notesIn(folder)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list notes: %v", err)), nil
+	}
+	if len(notes) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No notes found in folder '%s'", folder)), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Notes in %s:\n", folder)
+	for i, n := range notes {
+		if i == limit {
+			break
+		}
+		fmt.Fprintf(&sb, "%s | Modified: %s\n", n.title, n.info.ModTime().Format("2006-01-02 15:04"))
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func notesDirRead(title string) (*mcp.CallToolResult, error) {
+	n, ok := findNote(title)
+	if !ok {
+		return mcp.NewToolResultText(fmt.Sprintf("Note '%s' not found", title)), nil
+	}
+	data, err := os.ReadFile(n.path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read note: %v", err)), nil
+	}
+	return mcp.NewToolResultText(strings.TrimSpace(string(data))), nil
+}
+
+func notesDirCreate(folder, title, body string) (*mcp.CallToolResult, error) {
+	dir, err := notesFolderDir(folder)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create note: %v", err)), nil
+	}
+	path := filepath.Join(dir, name+".md")
+	content := "# " + title + "\n"
+	if body != "" {
+		content += "\n" + body + "\n"
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if os.IsExist(err) {
+			return mcp.NewToolResultError(fmt.Sprintf("note '%s' already exists", title)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create note: %v", err)), nil
+	}
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create note: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Created note: %s", title)), nil
+}
+
+func notesDirSearch(keyword string) (*mcp.CallToolResult, error) {
+	needle := strings.ToLower(keyword)
+	var sb strings.Builder
+	for _, n := range allNotes() {
+		match := strings.Contains(strings.ToLower(n.title), needle)
+		if !match {
+			data, err := os.ReadFile(n.path)
+			match = err == nil && strings.Contains(strings.ToLower(string(data)), needle)
+		}
+		if match {
+			fmt.Fprintf(&sb, "%s [%s]\n", n.title, n.folder)
+		}
+	}
+	if sb.Len() == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No notes found matching '%s'", keyword)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Notes matching '%s':\n%s", keyword, sb.String())), nil
+}
+
+func notesDirDelete(title string) (*mcp.CallToolResult, error) {
+	n, ok := findNote(title)
+	if !ok {
+		return mcp.NewToolResultText(fmt.Sprintf("Note '%s' not found", title)), nil
+	}
+	if err := os.Remove(n.path); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete note: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted note: %s", title)), nil
+}
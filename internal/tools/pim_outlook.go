@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Outlook is driven through its COM object model from PowerShell. Arguments
+// are passed in COCO_* environment variables so no user text is ever
+// spliced into the script. Default folder 9 is the calendar, 13 the tasks.
+
+const outlookPrelude = `$ErrorActionPreference = 'Stop'
+[Console]::OutputEncoding = [Text.Encoding]::UTF8
+$ns = (New-Object -ComObject Outlook.Application).GetNamespace('MAPI')
+`
+
+func runOutlook(ctx context.Context, script string, env map[string]string) (string, error) {
+	if !outlookAvailable() {
+		return "", errors.New("outlook desktop is not installed")
+	}
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", outlookPrelude+script)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, "COCO_"+k+"="+v)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.ReplaceAll(string(out), "\r\n", "\n"), nil
+}
+
+// outlookEventsScript lists events from today for COCO_DAYS days whose
+// subject contains COCO_KEYWORD.
+const outlookEventsScript = `$items = $ns.GetDefaultFolder(9).Items
+$items.IncludeRecurrences = $true
+$items.Sort('[Start]')
+$from = [datetime]::Today
+$to = $from.AddDays([int]$env:COCO_DAYS)
+$filter = "[Start] >= '" + $from.ToString('g') + "' AND [Start] < '" + $to.ToString('g') + "'"
+foreach ($i in $items.Restrict($filter)) {
+  if ($env:COCO_KEYWORD -and -not $i.Subject.Contains($env:COCO_KEYWORD)) { continue }
+  '{0:yyyy-MM-dd HH:mm} - {1:HH:mm} | {2} [{3}]' -f $i.Start, $i.End, $i.Subject, $i.Parent.Name
+}`
+
+func outlookEvents(ctx context.Context, days int, keyword, header, empty string) (*mcp.CallToolResult, error) {
+	out, err := runOutlook(ctx, outlookEventsScript, map[string]string{"DAYS": fmt.Sprint(days), "KEYWORD": keyword})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get events: %v", err)), nil
+	}
+	if strings.TrimSpace(out) == "" {
+		return mcp.NewToolResultText(empty), nil
+	}
+	return mcp.NewToolResultText(header + out), nil
+}
+
+func outlookCalendars(ctx context.Context) (*mcp.CallToolResult, error) {
+	out, err := runOutlook(ctx, `$cal = $ns.GetDefaultFolder(9)
+$cal.Name
+foreach ($f in $cal.Folders) { $f.Name }`, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list calendars: %v", err)), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func outlookCreateEvent(ctx context.Context, calendar, title, location, notes string, start time.Time, duration int) (*mcp.CallToolResult, error) {
+	_, err := runOutlook(ctx, `if ($env:COCO_CALENDAR) {
+  $a = $ns.GetDefaultFolder(9).Folders.Item($env:COCO_CALENDAR).Items.Add(1)
+} else {
+  $a = $ns.Application.CreateItem(1)
+}
+$a.Subject = $env:COCO_TITLE
+$a.Start = [datetime]::ParseExact($env:COCO_START, 'yyyy-MM-dd HH:mm', $null)
+$a.Duration = [int]$env:COCO_DURATION
+$a.Location = $env:COCO_LOCATION
+$a.Body = $env:COCO_NOTES
+$a.Save()`, map[string]string{
+		"CALENDAR": calendar,
+		"TITLE":    title,
+		"START":    start.Format("2006-01-02 15:04"),
+		"DURATION": fmt.Sprint(duration),
+		"LOCATION": location,
+		"NOTES":    notes,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create event: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Created: %s on %s for %d minutes", title, start.Format("2006-01-02 15:04"), duration)), nil
+}
+
+func outlookDeleteEvent(ctx context.Context, calendar, title, date string) (*mcp.CallToolResult, error) {
+	out, err := runOutlook(ctx, `$cal = $ns.GetDefaultFolder(9)
+if ($env:COCO_CALENDAR) { $cal = $cal.Folders.Item($env:COCO_CALENDAR) }
+foreach ($i in $cal.Items) {
+  if ($i.Subject -ne $env:COCO_TITLE) { continue }
+  if ($env:COCO_DATE -and $i.Start.ToString('yyyy-MM-dd') -ne $env:COCO_DATE) { continue }
+  $i.Delete()
+  'Deleted'
+  exit
+}
+'NotFound'`, map[string]string{"CALENDAR": calendar, "TITLE": title, "DATE": date})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete event: %v", err)), nil
+	}
+	if strings.TrimSpace(out) == "NotFound" {
+		return mcp.NewToolResultText(fmt.Sprintf("Event '%s' not found", title)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted event: %s", title)), nil
+}
+
+func outlookTasks(ctx context.Context) (*mcp.CallToolResult, error) {
+	out, err := runOutlook(ctx, `foreach ($t in $ns.GetDefaultFolder(13).Items.Restrict('[Complete] = False')) {
+  $line = '☐ ' + $t.Subject + ' [' + $t.Parent.Name + ']'
+  if ($t.DueDate.Year -lt 4000) { $line += ' - Due: ' + $t.DueDate.ToString('yyyy-MM-dd') }
+  $line
+}`, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get reminders: %v", err)), nil
+	}
+	if strings.TrimSpace(out) == "" {
+		return mcp.NewToolResultText("No reminders found"), nil
+	}
+	return mcp.NewToolResultText("Reminders:\n" + out), nil
+}
+
+func outlookTaskLists(ctx context.Context) (*mcp.CallToolResult, error) {
+	out, err := runOutlook(ctx, `$tasks = $ns.GetDefaultFolder(13)
+foreach ($f in @($tasks) + @($tasks.Folders)) {
+  '{0} ({1} items)' -f $f.Name, $f.Items.Restrict('[Complete] = False').Count
+}`, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list reminder lists: %v", err)), nil
+	}
+	return mcp.NewToolResultText("Reminder Lists:\n" + out), nil
+}
+
+func outlookAddTask(ctx context.Context, list, title, notes string, due time.Time, hasTime bool) (*mcp.CallToolResult, error) {
+	env := map[string]string{"LIST": list, "TITLE": title, "NOTES": notes}
+	if !due.IsZero() {
+		env["DUE"] = due.Format("2006-01-02 15:04")
+		if hasTime {
+			env["REMIND"] = "1"
+		}
+	}
+	_, err := runOutlook(ctx, `if ($env:COCO_LIST) {
+  $t = $ns.GetDefaultFolder(13).Folders.Item($env:COCO_LIST).Items.Add(3)
+} else {
+  $t = $ns.Application.CreateItem(3)
+}
+$t.Subject = $env:COCO_TITLE
+$t.Body = $env:COCO_NOTES
+if ($env:COCO_DUE) {
+  $due = [datetime]::ParseExact($env:COCO_DUE, 'yyyy-MM-dd HH:mm', $null)
+  $t.DueDate = $due.Date
+  if ($env:COCO_REMIND) { $t.ReminderSet = $true; $t.ReminderTime = $due }
+}
+$t.Save()`, env)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create reminder: %v", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Created reminder: %s", title)), nil
+}
+
+func outlookFinishTask(ctx context.Context, title string, remove bool) (*mcp.CallToolResult, error) {
+	verb, past, action := "complete", "Completed", "$t.MarkComplete()"
+	if remove {
+		verb, past, action = "delete", "Deleted", "$t.Delete()"
+	}
+	out, err := runOutlook(ctx, `foreach ($t in $ns.GetDefaultFolder(13).Items.Restrict('[Complete] = False')) {
+  if ($t.Subject -ne $env:COCO_TITLE) { continue }
+  `+action+`
+  'Done'
+  exit
+}
+'NotFound'`, map[string]string{"TITLE": title})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to %s reminder: %v", verb, err)), nil
+	}
+	if strings.TrimSpace(out) == "NotFound" {
+		return mcp.NewToolResultText(fmt.Sprintf("Reminder '%s' not found", title)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("%s reminder: %s", past, title)), nil
+}
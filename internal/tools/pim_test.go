@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/netcal"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func withPIMOS(t *testing.T, goos string) {
+	t.Helper()
+	old := pimOS
+	pimOS = goos
+	t.Cleanup(func() { pimOS = old })
+}
+
+func callPIM(t *testing.T, fn func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) (string, bool) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := fn(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestNotesFolderBackend(t *testing.T) {
+	withPIMOS(t, "linux")
+	t.Setenv("COCO_NOTES_DIR", t.TempDir())
+
+	if text, isErr := callPIM(t, NotesCreate, map[string]any{"title": "周会纪要", "body": "讨论 Q3 预算"}); isErr || text != "Created note: 周会纪要" {
+		t.Fatalf("create: %q", text)
+	}
+	if text, isErr := callPIM(t, NotesCreate, map[string]any{"title": "Ideas", "body": "ship it", "folder": "Work"}); isErr {
+		t.Fatalf("create in folder: %q", text)
+	}
+	if text, isErr := callPIM(t, NotesCreate, map[string]any{"title": "周会纪要"}); !isErr {
+		t.Fatalf("duplicate note overwritten: %q", text)
+	}
+	if text, _ := callPIM(t, NotesCreate, map[string]any{"title": "x", "folder": "../escape"}); !strings.Contains(text, "invalid folder") {
+		t.Fatalf("folder escape allowed: %q", text)
+	}
+
+	if text, _ := callPIM(t, NotesRead, map[string]any{"title": "ideas"}); text != "# Ideas\n\nship it" {
+		t.Fatalf("read: %q", text)
+	}
+	if text, _ := callPIM(t, NotesSearch, map[string]any{"keyword": "预算"}); !strings.Contains(text, "周会纪要 [Notes]") {
+		t.Fatalf("search: %q", text)
+	}
+	if text, _ := callPIM(t, NotesListNotes, map[string]any{"folder": "Work"}); !strings.Contains(text, "Ideas | Modified:") {
+		t.Fatalf("list: %q", text)
+	}
+	if text, _ := callPIM(t, NotesListFolders, map[string]any{}); !strings.Contains(text, "Notes (1 notes)") || !strings.Contains(text, "Work (1 notes)") {
+		t.Fatalf("folders: %q", text)
+	}
+	if text, _ := callPIM(t, NotesDelete, map[string]any{"title": "Ideas"}); text != "Deleted note: Ideas" {
+		t.Fatalf("delete: %q", text)
+	}
+	if text, _ := callPIM(t, NotesRead, map[string]any{"title": "Ideas"}); text != "Note 'Ideas' not found" {
+		t.Fatalf("read after delete: %q", text)
+	}
+}
+
+func TestPIMBackendsReportMissingTools(t *testing.T) {
+	withPIMOS(t, "linux")
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("COCO_NOTES_DIR", "/data/notes")
+
+	got := map[string]PIMBackend{}
+	for _, b := range PIMBackends() {
+		got[b.Group] = b
+	}
+	if b := got["calendar"]; b.Name != "khal" || b.Available || !strings.Contains(b.Detail, "vdirsyncer") {
+		t.Fatalf("calendar backend = %+v", b)
+	}
+	if b := got["reminders"]; b.Name != "todoman" || b.Available {
+		t.Fatalf("reminders backend = %+v", b)
+	}
+	if b := got["notes"]; !b.Available || b.Detail != "/data/notes" {
+		t.Fatalf("notes backend = %+v", b)
+	}
+
+	if text, isErr := callPIM(t, CalendarToday, map[string]any{}); !isErr || !strings.Contains(text, "install khal") {
+		t.Fatalf("calendar without khal: %q", text)
+	}
+	if text, isErr := callPIM(t, RemindersAdd, map[string]any{"title": "交电费"}); !isErr || !strings.Contains(text, "install todoman") {
+		t.Fatalf("reminders without todoman: %q", text)
+	}
+}
+
+func TestBuildICSEvent(t *testing.T) {
+	start := time.Date(2026, 3, 9, 14, 0, 0, 0, time.UTC)
+	ics := buildICSEvent("Review; v2, final", "Room 3", "line1\nline2", start, start.Add(90*time.Minute), start)
+	for _, want := range []string{
+		"DTSTART:20260309T140000\r\n",
+		"DTEND:20260309T153000\r\n",
+		`SUMMARY:Review\; v2\, final` + "\r\n",
+		`DESCRIPTION:line1\nline2` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Fatalf("missing %q in:\n%s", want, ics)
+		}
+	}
+}
+
+type fakeCalendar struct {
+	events  []netcal.Event
+	deleted []string
+}
+
+func (f *fakeCalendar) Name() string { return "Fake" }
+
+func (f *fakeCalendar) Events(ctx context.Context, from, to time.Time) ([]netcal.Event, error) {
+	return f.events, nil
+}
+
+func (f *fakeCalendar) Create(ctx context.Context, e netcal.Event) (netcal.Event, error) {
+	e.ID = "new"
+	f.events = append(f.events, e)
+	return e, nil
+}
+
+func (f *fakeCalendar) Delete(ctx context.Context, e netcal.Event) error {
+	f.deleted = append(f.deleted, e.ID)
+	return nil
+}
+
+func (f *fakeCalendar) FreeBusy(ctx context.Context, from, to time.Time, attendees []string) (map[string][]netcal.Busy, error) {
+	return map[string][]netcal.Busy{
+		"me":              netcal.BusyFromEvents(f.events, from, to),
+		"bob@example.com": {{Start: from, End: from.Add(time.Hour)}},
+	}, nil
+}
+
+func TestNetworkCalendarReplacesPlatformBackend(t *testing.T) {
+	withPIMOS(t, "linux")
+	t.Setenv("PATH", t.TempDir())
+	cal := &fakeCalendar{}
+	SetNetworkCalendar(cal)
+	t.Cleanup(func() { SetNetworkCalendar(nil) })
+
+	text, isErr := callPIM(t, CalendarCreateEvent, map[string]any{
+		"title": "方案评审", "start_time": "2026-03-09 14:00", "duration": float64(30),
+		"attendees": []any{"bob@example.com", " alice@example.com "},
+	})
+	if isErr || !strings.Contains(text, "Invited: bob@example.com, alice@example.com") {
+		t.Fatalf("create: %q", text)
+	}
+	if got := cal.events[0].Start; got.Hour() != 14 || got.Location() != time.Local {
+		t.Fatalf("start = %v", got)
+	}
+	if text, _ := callPIM(t, CalendarSearchEvents, map[string]any{"keyword": "评审"}); !strings.Contains(text, "2026-03-09 14:00-14:30 | 方案评审 (with bob@example.com, alice@example.com)") {
+		t.Fatalf("search: %q", text)
+	}
+
+	text, isErr = callPIM(t, CalendarFreeBusy, map[string]any{"start": "2026-03-09 09:00", "attendees": "bob@example.com, eve@example.com"})
+	for _, want := range []string{
+		"Free/busy 2026-03-09 09:00-18:00:",
+		"me: busy 14:00-14:30",
+		"bob@example.com: busy 09:00-10:00",
+		"eve@example.com: unavailable",
+		"Free for everyone known (≥30 min):\n10:00-14:00\n14:30-18:00\n",
+	} {
+		if isErr || !strings.Contains(text, want) {
+			t.Fatalf("freebusy missing %q:\n%s", want, text)
+		}
+	}
+
+	if text, _ := callPIM(t, CalendarDeleteEvent, map[string]any{"title": "方案评审", "date": "2026-03-09"}); text != "Deleted event: 方案评审" || len(cal.deleted) != 1 {
+		t.Fatalf("delete: %q", text)
+	}
+	for _, b := range PIMBackends() {
+		if b.Group == "calendar" && (b.Name != "Fake" || !b.Available) {
+			t.Fatalf("calendar backend = %+v", b)
+		}
+	}
+
+	SetNetworkCalendar(nil)
+	if text, isErr := callPIM(t, CalendarCreateEvent, map[string]any{"title": "x", "start_time": "2026-03-09 14:00", "attendees": "bob@example.com"}); !isErr || !strings.Contains(text, "network calendar") {
+		t.Fatalf("invite without network calendar: %q", text)
+	}
+}
@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/kayz/coco/internal/browser"
+	"github.com/kayz/coco/internal/config"
+	"github.com/kayz/coco/internal/security"
+)
+
+// PriceQuote is a price read off a product page.
+type PriceQuote struct {
+	Price    float64
+	Currency string // ISO code when known, e.g. CNY
+	Title    string
+	Source   string // "json-ld", "meta", "text" or "browser"
+}
+
+// FetchPrice reads the current price of the product at url. Pages that only
+// render the price with JavaScript are loaded in the browser.
+func FetchPrice(ctx context.Context, url string) (PriceQuote, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "https://" + url
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	if cfg.Security.EnableSSRFProtection {
+		if err := security.ValidateFetchURL(url); err != nil {
+			return PriceQuote{}, fmt.Errorf("url blocked by SSRF protection: %w", err)
+		}
+	}
+
+	page, fetchErr := fetchProductPage(ctx, url)
+	if fetchErr == nil {
+		if q, ok := ExtractPrice(page); ok {
+			return q, nil
+		}
+	}
+	rendered, err := renderInBrowser(ctx, url)
+	if err != nil {
+		if fetchErr != nil {
+			return PriceQuote{}, fetchErr
+		}
+		return PriceQuote{}, fmt.Errorf("no price found in the page and the browser is unavailable: %w", err)
+	}
+	q, ok := ExtractPrice(rendered)
+	if !ok {
+		return PriceQuote{}, fmt.Errorf("no price found on the page")
+	}
+	q.Source = "browser"
+	return q, nil
+}
+
+func fetchProductPage(ctx context.Context, url string) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	// Shops serve bot user agents a stripped page without prices.
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("fetch failed: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return string(body), nil
+}
+
+func renderInBrowser(ctx context.Context, url string) (string, error) {
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return "", err
+	}
+	page, err := b.Rod().Page(proto.TargetCreateTarget{URL: url})
+	if err != nil {
+		return "", err
+	}
+	defer page.Close()
+	page = page.Context(ctx).Timeout(45 * time.Second)
+	if err := page.WaitLoad(); err != nil {
+		return "", err
+	}
+	// Prices are often filled in by requests that finish after load.
+	_ = page.WaitIdle(5 * time.Second)
+	return page.HTML()
+}
+
+var (
+	jsonLDPattern    = regexp.MustCompile(`(?is)<script[^>]+application/ld\+json[^>]*>(.*?)</script>`)
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern      = regexp.MustCompile(`(?is)([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	titleTagPattern  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	textPricePattern = regexp.MustCompile(`(¥|￥|\$|€|£|RMB|CNY|USD|EUR|GBP)\s?([0-9][0-9,]*(?:\.[0-9]{1,2})?)`)
+)
+
+var currencySymbols = map[string]string{"¥": "CNY", "￥": "CNY", "RMB": "CNY", "$": "USD", "€": "EUR", "£": "GBP"}
+
+// ExtractPrice finds the product price in a page: schema.org Product data
+// first, then price meta tags, then the first amount with a currency sign
+// in the visible text.
+func ExtractPrice(page string) (PriceQuote, bool) {
+	title := pageTitle(page)
+
+	for _, m := range jsonLDPattern.FindAllStringSubmatch(page, -1) {
+		var data any
+		if json.Unmarshal([]byte(strings.TrimSpace(m[1])), &data) != nil {
+			continue
+		}
+		if q, ok := priceFromJSONLD(data); ok {
+			if q.Title == "" {
+				q.Title = title
+			}
+			q.Source = "json-ld"
+			return q, true
+		}
+	}
+
+	meta := map[string]string{}
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, a := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3])
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["itemprop"]
+		}
+		if key == "" {
+			key = attrs["name"]
+		}
+		if key != "" {
+			meta[strings.ToLower(key)] = attrs["content"]
+		}
+	}
+	for _, key := range []string{"product:price:amount", "og:price:amount", "price"} {
+		if price, ok := parsePrice(meta[key]); ok {
+			currency := meta["product:price:currency"]
+			if currency == "" {
+				currency = meta["og:price:currency"]
+			}
+			if currency == "" {
+				currency = meta["pricecurrency"]
+			}
+			return PriceQuote{Price: price, Currency: strings.ToUpper(currency), Title: title, Source: "meta"}, true
+		}
+	}
+
+	for _, m := range textPricePattern.FindAllStringSubmatch(extractTextFromHTML(page), -1) {
+		if price, ok := parsePrice(m[2]); ok {
+			currency := currencySymbols[m[1]]
+			if currency == "" {
+				currency = m[1]
+			}
+			return PriceQuote{Price: price, Currency: currency, Title: title, Source: "text"}, true
+		}
+	}
+	return PriceQuote{}, false
+}
+
+// priceFromJSONLD walks JSON-LD (objects, arrays and @graph) for a Product
+// or an Offer with a price.
+func priceFromJSONLD(v any) (PriceQuote, bool) {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if q, ok := priceFromJSONLD(item); ok {
+				return q, true
+			}
+		}
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			if q, ok := priceFromJSONLD(graph); ok {
+				return q, true
+			}
+		}
+		for _, key := range []string{"price", "lowPrice"} {
+			if price, ok := parsePrice(v[key]); ok {
+				currency, _ := v["priceCurrency"].(string)
+				return PriceQuote{Price: price, Currency: strings.ToUpper(currency)}, true
+			}
+		}
+		if offers, ok := v["offers"]; ok {
+			if q, ok := priceFromJSONLD(offers); ok {
+				if name, _ := v["name"].(string); name != "" {
+					q.Title = html.UnescapeString(name)
+				}
+				return q, true
+			}
+		}
+	}
+	return PriceQuote{}, false
+}
+
+func parsePrice(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, v > 0
+	case string:
+		s := strings.ReplaceAll(strings.TrimSpace(v), ",", "")
+		if s == "" {
+			return 0, false
+		}
+		price, err := strconv.ParseFloat(s, 64)
+		return price, err == nil && price > 0
+	}
+	return 0, false
+}
+
+func pageTitle(page string) string {
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		if !strings.Contains(tag, "og:title") {
+			continue
+		}
+		for _, a := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			if strings.EqualFold(a[1], "content") {
+				return strings.TrimSpace(html.UnescapeString(a[2] + a[3]))
+			}
+		}
+	}
+	if m := titleTagPattern.FindStringSubmatch(page); m != nil {
+		return strings.TrimSpace(html.UnescapeString(m[1]))
+	}
+	return ""
+}
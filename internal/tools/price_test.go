@@ -0,0 +1,38 @@
+package tools
+
+import "testing"
+
+func TestExtractPrice(t *testing.T) {
+	cases := []struct {
+		name string
+		page string
+		want PriceQuote
+	}{
+		{
+			name: "json-ld graph",
+			page: `<html><head><title>Shop</title><script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[{"@type":"WebPage"},{"@type":"Product","name":"AirPods Pro","offers":{"@type":"Offer","price":"1,899.00","priceCurrency":"cny"}}]}
+</script></head><body>¥9.9 shipping</body></html>`,
+			want: PriceQuote{Price: 1899, Currency: "CNY", Title: "AirPods Pro", Source: "json-ld"},
+		},
+		{
+			name: "meta tags",
+			page: `<meta property="og:title" content="Kindle &amp; case"><meta property="product:price:amount" content="89.99"><meta property="product:price:currency" content="USD">`,
+			want: PriceQuote{Price: 89.99, Currency: "USD", Title: "Kindle & case", Source: "meta"},
+		},
+		{
+			name: "visible text",
+			page: `<title>Desk lamp</title><body><div class="price">到手价 ￥ 129.50</div></body>`,
+			want: PriceQuote{Price: 129.5, Currency: "CNY", Title: "Desk lamp", Source: "text"},
+		},
+	}
+	for _, c := range cases {
+		got, ok := ExtractPrice(c.page)
+		if !ok || got != c.want {
+			t.Errorf("%s: got %+v, %v; want %+v", c.name, got, ok, c.want)
+		}
+	}
+	if _, ok := ExtractPrice(`<title>Sold out</title><p>Currently unavailable</p>`); ok {
+		t.Error("found a price on a page without one")
+	}
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// PrintFile sends a file to a printer (lp on macOS/Linux, the shell print verb on Windows)
+func PrintFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, ok := req.Params.Arguments["path"].(string)
+	if !ok || strings.TrimSpace(path) == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+
+	printer := ""
+	if p, ok := req.Params.Arguments["printer"].(string); ok {
+		printer = strings.TrimSpace(p)
+	}
+
+	copies := 1
+	if c, ok := req.Params.Arguments["copies"].(float64); ok && c >= 1 {
+		copies = int(c)
+	}
+	if copies > 20 {
+		return mcp.NewToolResultError("copies must be between 1 and 20"), nil
+	}
+
+	absPath, err := filepath.Abs(ExpandTilde(strings.TrimSpace(path)))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid path: %v", err)), nil
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("file not found: %s", absPath)), nil
+	}
+	if info.IsDir() {
+		return mcp.NewToolResultError("path is a directory, not a file"), nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin", "linux":
+		return printWithLP(ctx, absPath, printer, copies)
+	case "windows":
+		return printWindows(ctx, absPath, printer, copies)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("printing not supported on %s", runtime.GOOS)), nil
+	}
+}
+
+func printWithLP(ctx context.Context, path, printer string, copies int) (*mcp.CallToolResult, error) {
+	if _, err := exec.LookPath("lp"); err != nil {
+		return mcp.NewToolResultError("lp command not found (install CUPS)"), nil
+	}
+	args := []string{}
+	if printer != "" {
+		args = append(args, "-d", printer)
+	}
+	if copies > 1 {
+		args = append(args, "-n", strconv.Itoa(copies))
+	}
+	args = append(args, "--", path)
+
+	cmd := exec.CommandContext(ctx, "lp", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to print: %v - %s", err, strings.TrimSpace(string(output)))), nil
+	}
+
+	target := printer
+	if target == "" {
+		target = "default printer"
+	}
+	msg := fmt.Sprintf("Sent %s to %s (%d copies)", filepath.Base(path), target, copies)
+	if out := strings.TrimSpace(string(output)); out != "" {
+		msg += "\n" + out
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+func printWindows(ctx context.Context, path, printer string, copies int) (*mcp.CallToolResult, error) {
+	quoted := strings.ReplaceAll(path, "'", "''")
+	script := fmt.Sprintf("Start-Process -FilePath '%s' -Verb Print -WindowStyle Hidden", quoted)
+	if printer != "" {
+		script = fmt.Sprintf("Start-Process -FilePath '%s' -Verb PrintTo -ArgumentList '\"%s\"' -WindowStyle Hidden",
+			quoted, strings.ReplaceAll(printer, "'", "''"))
+	}
+
+	for i := 0; i < copies; i++ {
+		cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to print: %v - %s", err, strings.TrimSpace(string(output)))), nil
+		}
+	}
+
+	target := printer
+	if target == "" {
+		target = "default printer"
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Sent %s to %s (%d copies)", filepath.Base(path), target, copies)), nil
+}
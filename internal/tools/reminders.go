@@ -10,8 +10,16 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// RemindersToday gets today's reminders (macOS)
+// RemindersToday gets today's reminders
 func RemindersToday(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookTasks(ctx)
+	default:
+		return todoToday(ctx)
+	}
+
 	script := `
 		set output to ""
 		tell application "Reminders"
@@ -49,16 +57,17 @@ func RemindersToday(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 	return mcp.NewToolResultText("Reminders:\n" + string(output)), nil
 }
 
-// RemindersAdd creates a new reminder (macOS)
+// RemindersAdd creates a new reminder
 func RemindersAdd(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
 		return mcp.NewToolResultError("title is required"), nil
 	}
 
+	requestedList, _ := req.Params.Arguments["list"].(string)
 	list := "Reminders"
-	if l, ok := req.Params.Arguments["list"].(string); ok && l != "" {
-		list = l
+	if requestedList != "" {
+		list = requestedList
 	}
 
 	notes := ""
@@ -67,9 +76,12 @@ func RemindersAdd(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 	}
 
 	dueDate := ""
+	var due time.Time
+	hasTime := false
 	if d, ok := req.Params.Arguments["due"].(string); ok && d != "" {
 		// Parse the due date
 		t, err := time.Parse("2006-01-02 15:04", d)
+		hasTime = err == nil
 		if err != nil {
 			// Try date only
 			t, err = time.Parse("2006-01-02", d)
@@ -77,9 +89,18 @@ func RemindersAdd(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 				return mcp.NewToolResultError("invalid due date format, use YYYY-MM-DD or YYYY-MM-DD HH:MM"), nil
 			}
 		}
+		due = t
 		dueDate = t.Format("January 2, 2006 at 3:04:05 PM")
 	}
 
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookAddTask(ctx, requestedList, title, notes, due, hasTime)
+	default:
+		return todoAdd(ctx, requestedList, title, notes, due, hasTime)
+	}
+
 	var script string
 	if dueDate != "" {
 		script = fmt.Sprintf(`
@@ -110,13 +131,21 @@ func RemindersAdd(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 	return mcp.NewToolResultText(fmt.Sprintf("Created reminder: %s", title)), nil
 }
 
-// RemindersComplete marks a reminder as complete (macOS)
+// RemindersComplete marks a reminder as complete
 func RemindersComplete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
 		return mcp.NewToolResultError("title is required"), nil
 	}
 
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookFinishTask(ctx, title, false)
+	default:
+		return todoFinish(ctx, title, false)
+	}
+
 	script := fmt.Sprintf(`
 		tell application "Reminders"
 			repeat with reminderList in every list
@@ -144,13 +173,21 @@ func RemindersComplete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	return mcp.NewToolResultText(fmt.Sprintf("Completed reminder: %s", title)), nil
 }
 
-// RemindersDelete deletes a reminder (macOS)
+// RemindersDelete deletes a reminder
 func RemindersDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	title, ok := req.Params.Arguments["title"].(string)
 	if !ok || title == "" {
 		return mcp.NewToolResultError("title is required"), nil
 	}
 
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookFinishTask(ctx, title, true)
+	default:
+		return todoFinish(ctx, title, true)
+	}
+
 	script := fmt.Sprintf(`
 		tell application "Reminders"
 			repeat with reminderList in every list
@@ -178,8 +215,16 @@ func RemindersDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 	return mcp.NewToolResultText(fmt.Sprintf("Deleted reminder: %s", title)), nil
 }
 
-// RemindersListLists lists all reminder lists (macOS)
+// RemindersListLists lists all reminder lists
 func RemindersListLists(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	switch pimOS {
+	case "darwin": // AppleScript below
+	case "windows":
+		return outlookTaskLists(ctx)
+	default:
+		return todoLists(ctx)
+	}
+
 	script := `
 		tell application "Reminders"
 			set output to ""
@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/kayz/coco/internal/datadir"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -19,10 +20,9 @@ func ScreenshotCapture(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	if p, ok := req.Params.Arguments["path"].(string); ok && p != "" {
 		outputPath = p
 	} else {
-		// Default to executable directory with timestamp
-		exeDir := GetExecutableDir()
+		// Default to the data directory with timestamp
 		timestamp := time.Now().Format("2006-01-02_15-04-05")
-		outputPath = filepath.Join(exeDir, fmt.Sprintf("screenshot_%s.png", timestamp))
+		outputPath = datadir.Path(fmt.Sprintf("screenshot_%s.png", timestamp))
 	}
 
 	// Expand home directory
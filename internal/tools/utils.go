@@ -2,43 +2,13 @@ package tools
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-)
 
-var (
-	exeDirCache string
+	"github.com/kayz/coco/internal/datadir"
 )
 
-// GetExecutableDir returns the directory where the executable is located
-func GetExecutableDir() string {
-	if exeDirCache != "" {
-		return exeDirCache
-	}
-	execPath, err := os.Executable()
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		exeDirCache = "."
-		return exeDirCache
-	}
-	exeDirCache = filepath.Dir(execPath)
-	return exeDirCache
-}
-
-// ExpandTilde expands ~ to the executable directory instead of user home
+// ExpandTilde expands ~ to the data directory instead of user home
 func ExpandTilde(path string) string {
-	if len(path) > 0 && path[0] == '~' {
-		exeDir := GetExecutableDir()
-		if len(path) == 1 {
-			return exeDir
-		}
-		return filepath.Join(exeDir, path[1:])
-	}
-	return path
+	return datadir.ExpandTilde(path)
 }
 
 // FormatBytes formats bytes to human readable format
@@ -2,10 +2,12 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -81,3 +83,80 @@ func WeatherForecast(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 
 	return mcp.NewToolResultText(result), nil
 }
+
+// wttrURL is the wttr.in base URL; tests point it at a local server.
+var wttrURL = "https://wttr.in"
+
+// WeatherAt describes the forecast for location around at, e.g.
+// "上海 03-09 10:00 前后：小雨 12°C，降水概率 80%（全天 9~14°C）".
+// wttr.in forecasts three days ahead; later times report an error.
+func WeatherAt(ctx context.Context, location string, at time.Time) (string, error) {
+	apiURL := fmt.Sprintf("%s/%s?format=j1&lang=zh", wttrURL, url.PathEscape(location))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "curl/7.0")
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get forecast: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("failed to get forecast: %s", resp.Status)
+	}
+
+	type value struct {
+		Value string `json:"value"`
+	}
+	var out struct {
+		Weather []struct {
+			Date     string `json:"date"`
+			MaxTempC string `json:"maxtempC"`
+			MinTempC string `json:"mintempC"`
+			Hourly   []struct {
+				Time         string  `json:"time"` // "0", "300", ... "2100"
+				TempC        string  `json:"tempC"`
+				ChanceOfRain string  `json:"chanceofrain"`
+				WeatherDesc  []value `json:"weatherDesc"`
+				LangZh       []value `json:"lang_zh"`
+			} `json:"hourly"`
+		} `json:"weather"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("invalid forecast: %w", err)
+	}
+
+	day := at.Format("2006-01-02")
+	for _, w := range out.Weather {
+		if w.Date != day || len(w.Hourly) == 0 {
+			continue
+		}
+		// Hourly entries are three hours apart; take the one closest to at.
+		best := w.Hourly[0]
+		bestDiff := 24 * 60
+		for _, h := range w.Hourly {
+			hhmm, _ := strconv.Atoi(h.Time)
+			diff := hhmm/100*60 + hhmm%100 - (at.Hour()*60 + at.Minute())
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff < bestDiff {
+				best, bestDiff = h, diff
+			}
+		}
+		desc := ""
+		if len(best.LangZh) > 0 {
+			desc = strings.TrimSpace(best.LangZh[0].Value)
+		} else if len(best.WeatherDesc) > 0 {
+			desc = strings.TrimSpace(best.WeatherDesc[0].Value)
+		}
+		text := fmt.Sprintf("%s %s 前后：%s %s°C", location, at.Format("01-02 15:04"), desc, best.TempC)
+		if rain, _ := strconv.Atoi(best.ChanceOfRain); rain >= 30 {
+			text += fmt.Sprintf("，降水概率 %d%%", rain)
+		}
+		return text + fmt.Sprintf("（全天 %s~%s°C）", w.MinTempC, w.MaxTempC), nil
+	}
+	return "", fmt.Errorf("no forecast for %s on %s", location, day)
+}
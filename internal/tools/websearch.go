@@ -125,6 +125,11 @@ func WebFetch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult
 	return mcp.NewToolResultText(content), nil
 }
 
+// HTMLText returns the visible text of an HTML page, one line per block.
+func HTMLText(html string) string {
+	return extractTextFromHTML(html)
+}
+
 func extractTextFromHTML(html string) string {
 	for _, tag := range []string{"script", "style", "noscript"} {
 		for {
@@ -0,0 +1,264 @@
+package travel
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	trainNumberPattern = regexp.MustCompile(`^[GDCZTK]\d{1,4}$`)
+	// A train number only counts with 次 after it or 车次 before it, so
+	// terminals (T3) and seats are not mistaken for trains.
+	trainMentionPattern  = regexp.MustCompile(`(车次[:：\s]*)?\b([GDCZTK]\d{1,4})\b(次)?`)
+	flightMentionPattern = regexp.MustCompile(`\b([A-Z]{2}|[A-Z]\d|\d[A-Z])\s?(\d{3,4})\b`)
+	flightContextPattern = regexp.MustCompile(`(?i)航班|机票|登机|起飞|机场|flight|boarding|airline|airport`)
+	bookingPattern       = regexp.MustCompile(`(?i)已购|出票|订单|预订|预定|行程单|电子客票|booking|itinerary|e-ticket|confirmation|reservation`)
+
+	fullDatePattern    = regexp.MustCompile(`(\d{4})[-/年.](\d{1,2})[-/月.](\d{1,2})日?`)
+	chineseDatePattern = regexp.MustCompile(`(\d{1,2})月(\d{1,2})[日号]`)
+	monthDayPattern    = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b,?(?:\s+(\d{4}))?`)
+	dayMonthPattern    = regexp.MustCompile(`(?i)\b(\d{1,2})\s+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?(?:\s+(\d{4}))?`)
+	clockPattern       = regexp.MustCompile(`\b([01]?\d|2[0-3])[:：]([0-5]\d)\b`)
+
+	airportCodePattern = regexp.MustCompile(`([A-Za-z\p{Han}][A-Za-z\p{Han} .'-]{0,30}?)\s*[(（]([A-Z]{3})[)）]`)
+	arrowRoutePattern  = regexp.MustCompile(`([\p{Han}]{2,12}?)(?:站|机场)?\s*(?:T\d)?\s*(?:-|—|–|→|－|至|到)\s*([\p{Han}]{2,12}?)(?:站|机场|T\d|\s|[，,。；;]|$)`)
+	namedTimePattern   = regexp.MustCompile(`([\p{Han}]{2,12})\s*(?:T\d)?\s*\d{1,2}[:：]\d{2}`)
+
+	seatPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(\d{1,2}车\d{1,3}[A-F]?)号`),
+		regexp.MustCompile(`(?i)(?:座位号?|seat)\s*[:：]?\s*(\d{1,3}[A-K])\b`),
+	}
+	confirmationPattern = regexp.MustCompile(`(?i)(?:订单号?|预订号|确认号|booking (?:reference|code|number)|confirmation (?:code|number)|PNR|record locator)\s*[:：#]?\s*([A-Z0-9]{5,12})`)
+)
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// IsBooking reports whether text reads like a booking confirmation.
+func IsBooking(text string) bool {
+	return bookingPattern.MatchString(text)
+}
+
+type mention struct {
+	kind       Kind
+	number     string
+	start, end int
+}
+
+type dated struct {
+	pos  int
+	date time.Time
+}
+
+// ParseItinerary finds the flight and train segments in text, such as a
+// forwarded booking confirmation or a 12306 ticket message. Dates without
+// a year are placed on or after a month before now. Segments whose
+// departure date or time cannot be found are left out.
+func ParseItinerary(text string, now time.Time) []Segment {
+	mentions := findMentions(text)
+	dates := findDates(text, now)
+	confirmation := ""
+	if m := confirmationPattern.FindStringSubmatch(text); m != nil {
+		confirmation = strings.ToUpper(m[1])
+	}
+
+	var segments []Segment
+	for i, m := range mentions {
+		from := 0
+		if i > 0 {
+			from = mentions[i-1].end
+		}
+		to := len(text)
+		if i+1 < len(mentions) {
+			to = mentions[i+1].start
+		}
+		after := text[m.end:to]
+
+		day, ok := dateBefore(dates, m.start, from)
+		if !ok {
+			day, ok = dateAfter(dates, m.end, to)
+		}
+		if !ok {
+			continue
+		}
+		clocks := clockPattern.FindAllStringSubmatchIndex(after, -1)
+		if len(clocks) == 0 {
+			continue
+		}
+		at := func(c []int) time.Time {
+			d := day
+			if prior, ok := dateBefore(dates, m.end+c[0], m.end); ok {
+				d = prior
+			}
+			h, _ := strconv.Atoi(after[c[2]:c[3]])
+			minute, _ := strconv.Atoi(after[c[4]:c[5]])
+			return time.Date(d.Year(), d.Month(), d.Day(), h, minute, 0, 0, now.Location())
+		}
+
+		s := Segment{Kind: m.kind, Number: m.number, Departure: at(clocks[0]), Confirmation: confirmation}
+		if len(clocks) > 1 {
+			s.Arrival = at(clocks[1])
+			if s.Arrival.Before(s.Departure) {
+				s.Arrival = s.Arrival.AddDate(0, 0, 1)
+			}
+		}
+		s.From, s.To = findRoute(after)
+		for _, p := range seatPatterns {
+			if sm := p.FindStringSubmatch(after); sm != nil {
+				s.Seat = strings.ToUpper(sm[1])
+				break
+			}
+		}
+		segments = append(segments, s)
+	}
+	return segments
+}
+
+func findMentions(text string) []mention {
+	var mentions []mention
+	for _, m := range trainMentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		if m[2] < 0 && m[6] < 0 {
+			continue
+		}
+		mentions = append(mentions, mention{kind: Train, number: text[m[4]:m[5]], start: m[0], end: m[1]})
+	}
+	if flightContextPattern.MatchString(text) {
+		for _, m := range flightMentionPattern.FindAllStringSubmatchIndex(text, -1) {
+			overlaps := false
+			for _, t := range mentions {
+				if t.kind == Train && m[0] < t.end && t.start < m[1] {
+					overlaps = true
+				}
+			}
+			if !overlaps {
+				mentions = append(mentions, mention{kind: Flight, number: text[m[2]:m[3]] + text[m[4]:m[5]], start: m[0], end: m[1]})
+			}
+		}
+	}
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].start < mentions[j].start })
+	return mentions
+}
+
+func findDates(text string, now time.Time) []dated {
+	var dates []dated
+	add := func(pos, year int, month time.Month, day int) {
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return
+		}
+		if year == 0 {
+			year = now.Year()
+			if time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Before(now.AddDate(0, -1, 0)) {
+				year++
+			}
+		}
+		dates = append(dates, dated{pos: pos, date: time.Date(year, month, day, 0, 0, 0, 0, now.Location())})
+	}
+	atoi := func(s string) int { n, _ := strconv.Atoi(s); return n }
+
+	full := fullDatePattern.FindAllStringSubmatchIndex(text, -1)
+	for _, m := range full {
+		add(m[0], atoi(text[m[2]:m[3]]), time.Month(atoi(text[m[4]:m[5]])), atoi(text[m[6]:m[7]]))
+	}
+	for _, m := range chineseDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		inFull := false
+		for _, f := range full {
+			if m[0] >= f[0] && m[0] < f[1] {
+				inFull = true
+			}
+		}
+		if !inFull {
+			add(m[0], 0, time.Month(atoi(text[m[2]:m[3]])), atoi(text[m[4]:m[5]]))
+		}
+	}
+	for _, m := range monthDayPattern.FindAllStringSubmatchIndex(text, -1) {
+		year := 0
+		if m[6] >= 0 {
+			year = atoi(text[m[6]:m[7]])
+		}
+		add(m[0], year, months[strings.ToLower(text[m[2]:m[3]])], atoi(text[m[4]:m[5]]))
+	}
+	for _, m := range dayMonthPattern.FindAllStringSubmatchIndex(text, -1) {
+		year := 0
+		if m[6] >= 0 {
+			year = atoi(text[m[6]:m[7]])
+		}
+		add(m[0], year, months[strings.ToLower(text[m[4]:m[5]])], atoi(text[m[2]:m[3]]))
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].pos < dates[j].pos })
+	return dates
+}
+
+// dateBefore returns the last date found in [from, pos).
+func dateBefore(dates []dated, pos, from int) (time.Time, bool) {
+	for i := len(dates) - 1; i >= 0; i-- {
+		if dates[i].pos < pos && dates[i].pos >= from {
+			return dates[i].date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateAfter returns the first date found in [pos, to).
+func dateAfter(dates []dated, pos, to int) (time.Time, bool) {
+	for _, d := range dates {
+		if d.pos >= pos && d.pos < to {
+			return d.date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// findRoute reads the origin and destination: airports with their codes,
+// then "A-B" or "A至B", then the stations written right before the times.
+func findRoute(text string) (string, string) {
+	if m := airportCodePattern.FindAllStringSubmatch(text, 2); len(m) == 2 {
+		return airportLabel(m[0]), airportLabel(m[1])
+	}
+	if m := arrowRoutePattern.FindStringSubmatch(text); m != nil {
+		return placeName(m[1]), placeName(m[2])
+	}
+	var names []string
+	for _, m := range namedTimePattern.FindAllStringSubmatch(text, 2) {
+		names = append(names, placeName(m[1]))
+	}
+	switch len(names) {
+	case 2:
+		return names[0], names[1]
+	case 1:
+		return names[0], ""
+	}
+	return "", ""
+}
+
+func airportLabel(m []string) string {
+	name := strings.TrimSpace(m[1])
+	for _, filler := range []string{"to ", "from ", "departs ", "arrives "} {
+		if strings.HasPrefix(strings.ToLower(name), filler) {
+			name = strings.TrimSpace(name[len(filler):])
+		}
+	}
+	if name == "" {
+		return m[2]
+	}
+	return name + " (" + m[2] + ")"
+}
+
+// placeName drops the words that run into a station name in Chinese
+// messages ("您预订的上海虹桥站") and the station suffix.
+func placeName(name string) string {
+	if i := strings.LastIndexAny(name, "的日号次购从由"); i >= 0 {
+		_, size := utf8.DecodeRuneInString(name[i:])
+		name = name[i+size:]
+	}
+	for _, suffix := range []string{"国际机场", "机场", "站"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
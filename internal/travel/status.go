@@ -0,0 +1,211 @@
+package travel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// aviationStackURL is the flights endpoint; tests point it at a local server.
+var aviationStackURL = "http://api.aviationstack.com/v1/flights"
+
+// AviationStack looks flights up in the AviationStack API. Its times are the
+// airports' local times.
+type AviationStack struct {
+	client *http.Client
+	key    string
+}
+
+// NewAviationStack creates an AviationStack source.
+func NewAviationStack(client *http.Client, key string) *AviationStack {
+	return &AviationStack{client: client, key: key}
+}
+
+func (a *AviationStack) Name() string { return "AviationStack" }
+
+type aviationStackEnd struct {
+	Airport   string `json:"airport"`
+	IATA      string `json:"iata"`
+	Terminal  string `json:"terminal"`
+	Gate      string `json:"gate"`
+	Delay     int    `json:"delay"`
+	Scheduled string `json:"scheduled"`
+	Estimated string `json:"estimated"`
+	Actual    string `json:"actual"`
+}
+
+var aviationStackStates = map[string]State{
+	"scheduled": StateScheduled,
+	"active":    StateDeparted,
+	"landed":    StateArrived,
+	"cancelled": StateCancelled,
+	"incident":  StateUnknown,
+	"diverted":  StateDiverted,
+}
+
+func (a *AviationStack) Status(ctx context.Context, number string, date time.Time) (Status, error) {
+	number = strings.ToUpper(strings.ReplaceAll(number, " ", ""))
+	u := aviationStackURL + "?" + url.Values{"access_key": {a.key}, "flight_iata": {number}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Status{}, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("aviationstack: %w", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Data []struct {
+			FlightDate   string           `json:"flight_date"`
+			FlightStatus string           `json:"flight_status"`
+			Departure    aviationStackEnd `json:"departure"`
+			Arrival      aviationStackEnd `json:"arrival"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 2<<20)).Decode(&out); err != nil {
+		return Status{}, fmt.Errorf("aviationstack: invalid response: %w", err)
+	}
+	if out.Error != nil {
+		return Status{}, fmt.Errorf("aviationstack: %s", out.Error.Message)
+	}
+
+	day := date.Format("2006-01-02")
+	for _, f := range out.Data {
+		if !date.IsZero() && f.FlightDate != day {
+			continue
+		}
+		s := Status{
+			Number:             number,
+			State:              StateUnknown,
+			From:               airportName(f.Departure),
+			To:                 airportName(f.Arrival),
+			ScheduledDeparture: parseTime(f.Departure.Scheduled),
+			EstimatedDeparture: parseTime(f.Departure.Estimated),
+			ActualDeparture:    parseTime(f.Departure.Actual),
+			ScheduledArrival:   parseTime(f.Arrival.Scheduled),
+			EstimatedArrival:   parseTime(f.Arrival.Estimated),
+			ActualArrival:      parseTime(f.Arrival.Actual),
+			DelayMinutes:       f.Departure.Delay,
+			Terminal:           f.Departure.Terminal,
+			Gate:               f.Departure.Gate,
+		}
+		if state, ok := aviationStackStates[f.FlightStatus]; ok {
+			s.State = state
+		}
+		if s.State == StateScheduled && s.DelayMinutes > 0 {
+			s.State = StateDelayed
+		}
+		return s, nil
+	}
+	return Status{}, fmt.Errorf("aviationstack: no flight %s on %s", number, day)
+}
+
+func airportName(e aviationStackEnd) string {
+	switch {
+	case e.Airport != "" && e.IATA != "":
+		return fmt.Sprintf("%s (%s)", e.Airport, e.IATA)
+	case e.Airport != "":
+		return e.Airport
+	default:
+		return e.IATA
+	}
+}
+
+// Custom queries a self-hosted or third-party endpoint. The URL template's
+// {number} and {date} (YYYY-MM-DD) are filled in and the endpoint answers
+//
+//	{"state": "delayed", "from": "...", "to": "...", "delay_minutes": 25,
+//	 "departure": {"scheduled": "2026-03-09T08:00:00+08:00", "estimated": "...", "actual": "...",
+//	               "terminal": "T3", "gate": "C12"},
+//	 "arrival": {"scheduled": "...", "estimated": "...", "actual": "..."}}
+//
+// with state one of scheduled, delayed, departed, arrived, cancelled or
+// diverted.
+type Custom struct {
+	client   *http.Client
+	template string
+	token    string
+}
+
+// NewCustom creates a source for the endpoint; token, if set, is sent as a
+// bearer token.
+func NewCustom(client *http.Client, template, token string) *Custom {
+	return &Custom{client: client, template: template, token: token}
+}
+
+func (c *Custom) Name() string {
+	if u, err := url.Parse(c.template); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "custom"
+}
+
+func (c *Custom) Status(ctx context.Context, number string, date time.Time) (Status, error) {
+	number = strings.ToUpper(strings.ReplaceAll(number, " ", ""))
+	day := ""
+	if !date.IsZero() {
+		day = date.Format("2006-01-02")
+	}
+	u := strings.NewReplacer("{number}", url.QueryEscape(number), "{date}", day).Replace(c.template)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Status{}, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("status: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Status{}, fmt.Errorf("status: %s", resp.Status)
+	}
+	type end struct {
+		Scheduled string `json:"scheduled"`
+		Estimated string `json:"estimated"`
+		Actual    string `json:"actual"`
+		Terminal  string `json:"terminal"`
+		Gate      string `json:"gate"`
+	}
+	var out struct {
+		State        string `json:"state"`
+		From         string `json:"from"`
+		To           string `json:"to"`
+		DelayMinutes int    `json:"delay_minutes"`
+		Departure    end    `json:"departure"`
+		Arrival      end    `json:"arrival"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return Status{}, fmt.Errorf("status: invalid response: %w", err)
+	}
+	s := Status{
+		Number:             number,
+		State:              State(out.State),
+		From:               out.From,
+		To:                 out.To,
+		ScheduledDeparture: parseTime(out.Departure.Scheduled),
+		EstimatedDeparture: parseTime(out.Departure.Estimated),
+		ActualDeparture:    parseTime(out.Departure.Actual),
+		ScheduledArrival:   parseTime(out.Arrival.Scheduled),
+		EstimatedArrival:   parseTime(out.Arrival.Estimated),
+		ActualArrival:      parseTime(out.Arrival.Actual),
+		DelayMinutes:       out.DelayMinutes,
+		Terminal:           out.Departure.Terminal,
+		Gate:               out.Departure.Gate,
+	}
+	if s.State == "" {
+		s.State = StateUnknown
+	}
+	return s, nil
+}
@@ -0,0 +1,139 @@
+// Package travel reads flight and train segments out of booking
+// confirmations and looks up their live status through a flight data API
+// or a custom HTTP endpoint.
+package travel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kind is the mode of transport of a segment.
+type Kind string
+
+const (
+	Flight Kind = "flight"
+	Train  Kind = "train"
+)
+
+// Segment is one leg of an itinerary.
+type Segment struct {
+	Kind         Kind
+	Number       string // e.g. CA1234, G1234
+	Departure    time.Time
+	Arrival      time.Time // zero when unknown
+	From         string
+	To           string
+	Seat         string
+	Confirmation string // booking reference or order number
+}
+
+// State is where a flight or train is in its journey.
+type State string
+
+const (
+	StateScheduled State = "scheduled"
+	StateDelayed   State = "delayed"
+	StateDeparted  State = "departed"
+	StateArrived   State = "arrived"
+	StateCancelled State = "cancelled"
+	StateDiverted  State = "diverted"
+	StateUnknown   State = "unknown"
+)
+
+// Status is the live status of a flight or train. Times are in the local
+// time of the station they belong to.
+type Status struct {
+	Number             string
+	State              State
+	From               string
+	To                 string
+	ScheduledDeparture time.Time
+	EstimatedDeparture time.Time
+	ActualDeparture    time.Time
+	ScheduledArrival   time.Time
+	EstimatedArrival   time.Time
+	ActualArrival      time.Time
+	DelayMinutes       int
+	Terminal           string
+	Gate               string // flights: gate; trains: platform or check-in gate
+}
+
+// Source looks up live status.
+type Source interface {
+	// Name describes the source, e.g. "AviationStack".
+	Name() string
+	// Status returns the status of the flight or train number departing on
+	// date's day.
+	Status(ctx context.Context, number string, date time.Time) (Status, error)
+}
+
+// Config selects the status sources.
+type Config struct {
+	FlightProvider string // "aviationstack" or "custom"
+	FlightAPIKey   string
+	FlightURL      string // custom: GET URL with {number} and {date}
+	TrainURL       string // GET URL with {number} and {date}
+}
+
+// NewFlightSource creates the configured flight status source, or returns
+// nil when none is configured.
+func NewFlightSource(cfg Config) (Source, error) {
+	client := &http.Client{Timeout: 20 * time.Second}
+	switch strings.ToLower(strings.TrimSpace(cfg.FlightProvider)) {
+	case "":
+		return nil, nil
+	case "aviationstack":
+		if cfg.FlightAPIKey == "" {
+			return nil, fmt.Errorf("travel.flight_api_key is required for aviationstack")
+		}
+		return NewAviationStack(client, cfg.FlightAPIKey), nil
+	case "custom":
+		if !strings.Contains(cfg.FlightURL, "{number}") {
+			return nil, fmt.Errorf("travel.flight_url must contain {number}")
+		}
+		return NewCustom(client, cfg.FlightURL, cfg.FlightAPIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown flight provider %q (use aviationstack or custom)", cfg.FlightProvider)
+	}
+}
+
+// NewTrainSource creates the train status source, or returns nil when
+// travel.train_url is not set. There is no public train status API, so
+// trains always use a custom endpoint.
+func NewTrainSource(cfg Config) (Source, error) {
+	if strings.TrimSpace(cfg.TrainURL) == "" {
+		return nil, nil
+	}
+	if !strings.Contains(cfg.TrainURL, "{number}") {
+		return nil, fmt.Errorf("travel.train_url must contain {number}")
+	}
+	return NewCustom(&http.Client{Timeout: 20 * time.Second}, cfg.TrainURL, ""), nil
+}
+
+// KindOf tells a train number (G1234, D301, K1) from a flight number.
+func KindOf(number string) Kind {
+	if trainNumberPattern.MatchString(strings.ToUpper(strings.TrimSpace(number))) {
+		return Train
+	}
+	return Flight
+}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
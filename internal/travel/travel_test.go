@@ -0,0 +1,97 @@
+package travel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseItinerary(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name string
+		text string
+		want Segment
+	}{
+		{
+			name: "12306",
+			text: "【铁路客服】订单E123456789,张三您已购3月9日G1234次上海虹桥站08:00开，5车12F号，二等座，检票口B12，请持身份证乘车。",
+			want: Segment{Kind: Train, Number: "G1234", Departure: at(3, 9, 8, 0), From: "上海虹桥", Seat: "5车12F", Confirmation: "E123456789"},
+		},
+		{
+			name: "chinese flight",
+			text: "【携程旅行】您预订的3月9日 CA1234 北京首都T3 08:00-上海虹桥T2 10:15 航班已出票，订单号 1234567890。",
+			want: Segment{Kind: Flight, Number: "CA1234", Departure: at(3, 9, 8, 0), Arrival: at(3, 9, 10, 15), From: "北京首都", To: "上海虹桥", Confirmation: "1234567890"},
+		},
+		{
+			name: "english flight overnight",
+			text: "Your booking is confirmed. Booking reference: XK7P2Q\nFlight UA 857 San Francisco (SFO) to Shanghai (PVG)\nDeparts Mar 9, 2026 11:05\nArrives Mar 10, 2026 15:45\nSeat 34K",
+			want: Segment{Kind: Flight, Number: "UA857", Departure: at(3, 9, 11, 5), Arrival: at(3, 10, 15, 45), From: "San Francisco (SFO)", To: "Shanghai (PVG)", Seat: "34K", Confirmation: "XK7P2Q"},
+		},
+		{
+			name: "route with arrow",
+			text: "机票已出票：2026-03-12 MU5101 上海虹桥-北京首都 07:00起飞",
+			want: Segment{Kind: Flight, Number: "MU5101", Departure: at(3, 12, 7, 0), From: "上海虹桥", To: "北京首都"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !IsBooking(tt.text) {
+				t.Fatal("not recognized as a booking")
+			}
+			got := ParseItinerary(tt.text, now)
+			if len(got) != 1 {
+				t.Fatalf("segments = %+v", got)
+			}
+			s := got[0]
+			if s.Kind != tt.want.Kind || s.Number != tt.want.Number || !s.Departure.Equal(tt.want.Departure) || !s.Arrival.Equal(tt.want.Arrival) ||
+				s.From != tt.want.From || s.To != tt.want.To || s.Seat != tt.want.Seat || s.Confirmation != tt.want.Confirmation {
+				t.Fatalf("got  %+v\nwant %+v", s, tt.want)
+			}
+		})
+	}
+
+	// Dates without a year that are long past belong to next year.
+	if got := ParseItinerary("您已购1月5日D301次北京站21:00开", now); len(got) != 1 || got[0].Departure.Year() != 2027 {
+		t.Fatalf("got %+v", got)
+	}
+	// Terminals and plain numbers are not trains or flights.
+	if got := ParseItinerary("T3 航站楼 2026-03-09 08:00 集合，电话 13800138000", now); len(got) != 0 {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestAviationStackStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_key") != "key" || r.URL.Query().Get("flight_iata") != "CA1234" {
+			t.Errorf("query = %v", r.URL.Query())
+		}
+		io.WriteString(w, `{"data":[
+			{"flight_date":"2026-03-08","flight_status":"landed","departure":{"scheduled":"2026-03-08T08:00:00+00:00"},"arrival":{}},
+			{"flight_date":"2026-03-09","flight_status":"scheduled",
+			 "departure":{"airport":"Beijing Capital International","iata":"PEK","terminal":"3","gate":"C12","delay":25,
+			              "scheduled":"2026-03-09T08:00:00+00:00","estimated":"2026-03-09T08:25:00+00:00"},
+			 "arrival":{"airport":"Shanghai Hongqiao","iata":"SHA","scheduled":"2026-03-09T10:15:00+00:00"}}]}`)
+	}))
+	defer srv.Close()
+	old := aviationStackURL
+	aviationStackURL = srv.URL
+	t.Cleanup(func() { aviationStackURL = old })
+
+	s, err := NewAviationStack(srv.Client(), "key").Status(context.Background(), "CA 1234", time.Date(2026, 3, 9, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.State != StateDelayed || s.DelayMinutes != 25 || s.Gate != "C12" || s.To != "Shanghai Hongqiao (SHA)" || s.EstimatedDeparture.Format("15:04") != "08:25" {
+		t.Fatalf("status = %+v", s)
+	}
+	if KindOf("G1234") != Train || KindOf("CA1234") != Flight {
+		t.Fatal("KindOf")
+	}
+}
@@ -0,0 +1,134 @@
+package upgrade
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChecksumsAsset lists the sha256 of every other asset of a release.
+const ChecksumsAsset = "checksums.txt"
+
+// ReleaseAPI is the GitHub releases endpoint; tests point it elsewhere.
+var ReleaseAPI = "https://api.github.com/repos/kayz/coco/releases"
+
+var httpClient = &http.Client{Timeout: 10 * time.Minute}
+
+// Release is a published version and the download URL of each asset.
+type Release struct {
+	Version string
+	Assets  map[string]string
+}
+
+// FetchRelease returns the release tagged v<version>, or the latest release
+// when version is empty.
+func FetchRelease(ctx context.Context, version string) (*Release, error) {
+	url := ReleaseAPI + "/latest"
+	if version != "" {
+		url = ReleaseAPI + "/tags/v" + strings.TrimPrefix(version, "v")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && version != "" {
+		return nil, fmt.Errorf("release v%s not found", strings.TrimPrefix(version, "v"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release query failed with status %d", resp.StatusCode)
+	}
+	var body struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid release response: %w", err)
+	}
+	rel := &Release{Version: strings.TrimPrefix(body.TagName, "v"), Assets: make(map[string]string)}
+	for _, a := range body.Assets {
+		rel.Assets[a.Name] = a.URL
+	}
+	return rel, nil
+}
+
+// Download saves asset name of rel into dir and checks it against the
+// release's checksums, refusing assets that are not listed there.
+func (r *Release) Download(ctx context.Context, name, dir string) (string, error) {
+	sumsURL, ok := r.Assets[ChecksumsAsset]
+	if !ok {
+		return "", fmt.Errorf("release v%s has no %s; refusing an unverified download", r.Version, ChecksumsAsset)
+	}
+	url, ok := r.Assets[name]
+	if !ok {
+		return "", fmt.Errorf("release v%s has no asset %s", r.Version, name)
+	}
+
+	sums, err := fetch(ctx, sumsURL)
+	if err != nil {
+		return "", err
+	}
+	want := checksumFor(string(sums), name)
+	if want == "" {
+		return "", fmt.Errorf("%s is not listed in %s", name, ChecksumsAsset)
+	}
+
+	path := filepath.Join(dir, name)
+	data, err := fetch(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return "", fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed with status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// checksumFor finds name in sha256sum output ("<hex>  <name>" per line).
+func checksumFor(sums, name string) string {
+	sc := bufio.NewScanner(strings.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package upgrade
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Replace puts the binary at src in place of target. The new file is staged
+// next to target and renamed over it, so target is never half-written. A
+// running binary cannot be overwritten on Windows, so there target is first
+// moved aside to target.old, which the next upgrade removes.
+func Replace(src, target string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	staged := target + ".new"
+	if err := os.WriteFile(staged, data, mode); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", filepath.Base(target), err)
+	}
+
+	if runtime.GOOS == "windows" {
+		old := target + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(target, old); err != nil && !os.IsNotExist(err) {
+			os.Remove(staged)
+			return fmt.Errorf("failed to move %s aside: %w", target, err)
+		}
+		if err := os.Rename(staged, target); err != nil {
+			_ = os.Rename(old, target)
+			os.Remove(staged)
+			return err
+		}
+		return nil
+	}
+
+	if err := os.Rename(staged, target); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	return nil
+}
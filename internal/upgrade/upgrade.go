@@ -0,0 +1,82 @@
+// Package upgrade finds out how coco was installed and fetches the release
+// that replaces it. The data directory and service registrations are never
+// touched: an upgrade only swaps the binary.
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kayz/coco/internal/service"
+)
+
+// Method is how the running binary was installed, which decides how it is
+// upgraded.
+type Method string
+
+const (
+	MethodHomebrew Method = "homebrew" // brew install kayz/tap/coco
+	MethodScoop    Method = "scoop"    // scoop install coco
+	MethodPkg      Method = "pkg"      // macOS installer package
+	MethodMSI      Method = "msi"      // Windows installer
+	MethodService  Method = "service"  // copy made by `coco <mode> --service install`
+	MethodBinary   Method = "binary"   // a downloaded or self-built binary
+)
+
+// PkgIdentifier is the macOS package identifier, also used for its receipt.
+const PkgIdentifier = "com.kayz.coco.pkg"
+
+// pkgReceipt is left by installer(8) when the .pkg is installed.
+var pkgReceipt = "/var/db/receipts/" + PkgIdentifier + ".bom"
+
+// serviceBinary is where service installs copy the binary; tests replace it.
+var serviceBinary = func() string {
+	path, _, err := service.Paths(service.ModeRelay)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// Detect reports how the binary at execPath was installed on goos.
+func Detect(execPath, goos string) Method {
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+	p := strings.ToLower(strings.ReplaceAll(execPath, `\`, "/"))
+	switch {
+	case strings.Contains(p, "/cellar/coco/") || strings.Contains(p, "/homebrew/") || strings.Contains(p, "/.linuxbrew/"):
+		return MethodHomebrew
+	case goos == "windows" && strings.Contains(p, "/scoop/apps/coco/"):
+		return MethodScoop
+	case goos == "windows" && strings.Contains(p, "/program files/coco/"):
+		return MethodMSI
+	case goos == "darwin" && p == "/usr/local/bin/coco" && fileExists(pkgReceipt):
+		return MethodPkg
+	}
+	if sb := serviceBinary(); sb != "" && filepath.Clean(execPath) == filepath.Clean(sb) {
+		return MethodService
+	}
+	return MethodBinary
+}
+
+// AssetName is the release file that upgrades an install of method.
+func AssetName(method Method, version, goos, goarch string) string {
+	switch method {
+	case MethodPkg:
+		return "coco-" + version + "-darwin-universal.pkg"
+	case MethodMSI:
+		return "coco-" + version + "-windows-" + goarch + ".msi"
+	}
+	name := "coco-" + version + "-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
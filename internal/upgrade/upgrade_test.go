@@ -0,0 +1,141 @@
+package upgrade
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	origService := serviceBinary
+	serviceBinary = func() string { return "/usr/local/bin/coco" }
+	defer func() { serviceBinary = origService }()
+
+	tests := []struct {
+		path string
+		goos string
+		want Method
+	}{
+		{"/opt/homebrew/Cellar/coco/1.2.0/bin/coco", "darwin", MethodHomebrew},
+		{"/home/linuxbrew/.linuxbrew/Cellar/coco/1.2.0/bin/coco", "linux", MethodHomebrew},
+		{`C:\Users\me\scoop\apps\coco\1.2.0\coco.exe`, "windows", MethodScoop},
+		{`C:\Program Files\coco\coco.exe`, "windows", MethodMSI},
+		{"/usr/local/bin/coco", "linux", MethodService},
+		{"/home/me/bin/coco", "linux", MethodBinary},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.path, tt.goos); got != tt.want {
+			t.Errorf("Detect(%q, %s) = %s, want %s", tt.path, tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestDetectPkgNeedsReceipt(t *testing.T) {
+	origService, origReceipt := serviceBinary, pkgReceipt
+	serviceBinary = func() string { return "" }
+	pkgReceipt = filepath.Join(t.TempDir(), PkgIdentifier+".bom")
+	defer func() { serviceBinary, pkgReceipt = origService, origReceipt }()
+
+	if got := Detect("/usr/local/bin/coco", "darwin"); got != MethodBinary {
+		t.Fatalf("without receipt: %s", got)
+	}
+	os.WriteFile(pkgReceipt, nil, 0644)
+	if got := Detect("/usr/local/bin/coco", "darwin"); got != MethodPkg {
+		t.Fatalf("with receipt: %s", got)
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := []struct {
+		method       Method
+		goos, goarch string
+		want         string
+	}{
+		{MethodBinary, "linux", "amd64", "coco-1.2.0-linux-amd64"},
+		{MethodService, "windows", "arm64", "coco-1.2.0-windows-arm64.exe"},
+		{MethodPkg, "darwin", "arm64", "coco-1.2.0-darwin-universal.pkg"},
+		{MethodMSI, "windows", "amd64", "coco-1.2.0-windows-amd64.msi"},
+	}
+	for _, tt := range tests {
+		if got := AssetName(tt.method, "1.2.0", tt.goos, tt.goarch); got != tt.want {
+			t.Errorf("AssetName(%s, %s, %s) = %s, want %s", tt.method, tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestFetchReleaseAndDownload(t *testing.T) {
+	binary := []byte("new coco")
+	sum := sha256.Sum256(binary)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest":
+			fmt.Fprintf(w, `{"tag_name":"v1.2.0","assets":[
+				{"name":"coco-1.2.0-linux-amd64","browser_download_url":"%[1]s/dl/bin"},
+				{"name":"coco-1.2.0-linux-arm64","browser_download_url":"%[1]s/dl/bad"},
+				{"name":"checksums.txt","browser_download_url":"%[1]s/dl/sums"}]}`, srv.URL)
+		case "/dl/bin", "/dl/bad":
+			w.Write(binary)
+		case "/dl/sums":
+			fmt.Fprintf(w, "%s  coco-1.2.0-linux-amd64\n%s  coco-1.2.0-linux-arm64\n",
+				hex.EncodeToString(sum[:]), strings.Repeat("0", 64))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	origAPI := ReleaseAPI
+	ReleaseAPI = srv.URL + "/releases"
+	defer func() { ReleaseAPI = origAPI }()
+
+	rel, err := FetchRelease(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != "1.2.0" {
+		t.Fatalf("version = %q", rel.Version)
+	}
+	dir := t.TempDir()
+	path, err := rel.Download(context.Background(), "coco-1.2.0-linux-amd64", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(binary) {
+		t.Fatalf("downloaded %q", data)
+	}
+	if _, err := rel.Download(context.Background(), "coco-1.2.0-linux-arm64", dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := FetchRelease(context.Background(), "9.9.9"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing tag error, got %v", err)
+	}
+}
+
+func TestReplaceKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "coco")
+	src := filepath.Join(dir, "coco-new")
+	os.WriteFile(target, []byte("old"), 0750)
+	os.WriteFile(src, []byte("new"), 0644)
+
+	if err := Replace(src, target); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new" {
+		t.Fatalf("target = %q", data)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0750 {
+		t.Fatalf("mode = %v", info.Mode().Perm())
+	}
+	if _, err := os.Stat(target + ".new"); !os.IsNotExist(err) {
+		t.Fatal("staged file left behind")
+	}
+}
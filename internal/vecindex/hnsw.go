@@ -0,0 +1,345 @@
+// Package vecindex provides an in-process approximate nearest neighbor index
+// (HNSW) over cosine similarity, used for RAG memory retrieval.
+package vecindex
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultM              = 16
+	defaultEfConstruction = 128
+	defaultEfSearch       = 64
+
+	// Below this many live vectors a linear scan is exact and fast enough.
+	exactScanThreshold = 1024
+)
+
+// Match is a search hit with its cosine similarity to the query.
+type Match struct {
+	ID         string
+	Similarity float32
+}
+
+type node struct {
+	id        string
+	vec       []float32
+	neighbors [][]int32 // per level
+	deleted   bool
+}
+
+// HNSW is a hierarchical navigable small world graph. It is safe for concurrent use.
+// Deletes are tombstones; call Tombstones and rebuild when the ratio grows.
+type HNSW struct {
+	mu        sync.RWMutex
+	nodes     []*node
+	byID      map[string]int32
+	entry     int32
+	maxLevel  int
+	live      int
+	m         int
+	mMax0     int
+	efConst   int
+	efSearch  int
+	levelMult float64
+	rng       *rand.Rand
+	dimension int
+}
+
+// New creates an empty index.
+func New() *HNSW {
+	return &HNSW{
+		byID:      map[string]int32{},
+		entry:     -1,
+		m:         defaultM,
+		mMax0:     defaultM * 2,
+		efConst:   defaultEfConstruction,
+		efSearch:  defaultEfSearch,
+		levelMult: 1 / math.Log(defaultM),
+		rng:       rand.New(rand.NewSource(1)),
+	}
+}
+
+// Len returns the number of live vectors.
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.live
+}
+
+// Tombstones returns the number of deleted vectors still held by the graph.
+func (h *HNSW) Tombstones() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes) - h.live
+}
+
+// Add inserts or replaces the vector for id. Vectors with a different
+// dimension than the first one added are ignored.
+func (h *HNSW) Add(id string, vec []float32) {
+	v := normalize(vec)
+	if v == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dimension == 0 {
+		h.dimension = len(v)
+	} else if len(v) != h.dimension {
+		return
+	}
+	if idx, ok := h.byID[id]; ok && !h.nodes[idx].deleted {
+		h.nodes[idx].deleted = true
+		h.live--
+	}
+
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	n := &node{id: id, vec: v, neighbors: make([][]int32, level+1)}
+	idx := int32(len(h.nodes))
+	h.nodes = append(h.nodes, n)
+	h.byID[id] = idx
+	h.live++
+
+	if h.entry < 0 {
+		h.entry = idx
+		h.maxLevel = level
+		return
+	}
+
+	cur := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		cur = h.greedy(v, cur, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(v, cur, h.efConst, l)
+		maxConn := h.m
+		if l == 0 {
+			maxConn = h.mMax0
+		}
+		selected := closest(candidates, h.m)
+		n.neighbors[l] = selected
+		for _, nb := range selected {
+			h.link(nb, idx, l, maxConn)
+		}
+		if len(candidates) > 0 {
+			cur = candidates[0].idx
+		}
+	}
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = idx
+	}
+}
+
+// Delete removes id from search results.
+func (h *HNSW) Delete(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if idx, ok := h.byID[id]; ok {
+		if !h.nodes[idx].deleted {
+			h.nodes[idx].deleted = true
+			h.live--
+		}
+		delete(h.byID, id)
+	}
+}
+
+// Search returns up to k live vectors most similar to query, best first.
+func (h *HNSW) Search(query []float32, k int) []Match {
+	q := normalize(query)
+	if q == nil || k <= 0 {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 || len(q) != h.dimension || h.live == 0 {
+		return nil
+	}
+
+	var found []candidate
+	if h.live <= exactScanThreshold {
+		found = make([]candidate, 0, h.live)
+		for i, n := range h.nodes {
+			if !n.deleted {
+				found = append(found, candidate{idx: int32(i), dist: distance(q, n.vec)})
+			}
+		}
+		sortCandidates(found)
+	} else {
+		cur := h.entry
+		for l := h.maxLevel; l > 0; l-- {
+			cur = h.greedy(q, cur, l)
+		}
+		// Oversample so tombstoned nodes don't starve the result set.
+		ef := max(h.efSearch, k*2) * len(h.nodes) / h.live
+		found = h.searchLayer(q, cur, ef, 0)
+	}
+
+	matches := make([]Match, 0, k)
+	for _, c := range found {
+		n := h.nodes[c.idx]
+		if n.deleted {
+			continue
+		}
+		matches = append(matches, Match{ID: n.id, Similarity: 1 - c.dist})
+		if len(matches) == k {
+			break
+		}
+	}
+	return matches
+}
+
+// link adds a back-edge from nb to idx at level l, shrinking nb's list when full.
+func (h *HNSW) link(nb, idx int32, l, maxConn int) {
+	n := h.nodes[nb]
+	if l >= len(n.neighbors) {
+		return
+	}
+	n.neighbors[l] = append(n.neighbors[l], idx)
+	if len(n.neighbors[l]) <= maxConn {
+		return
+	}
+	cands := make([]candidate, 0, len(n.neighbors[l]))
+	for _, other := range n.neighbors[l] {
+		cands = append(cands, candidate{idx: other, dist: distance(n.vec, h.nodes[other].vec)})
+	}
+	sortCandidates(cands)
+	n.neighbors[l] = closest(cands, maxConn)
+}
+
+// greedy walks level l towards q and returns the closest node reached.
+func (h *HNSW) greedy(q []float32, start int32, l int) int32 {
+	cur := start
+	curDist := distance(q, h.nodes[cur].vec)
+	for changed := true; changed; {
+		changed = false
+		n := h.nodes[cur]
+		if l >= len(n.neighbors) {
+			break
+		}
+		for _, nb := range n.neighbors[l] {
+			if d := distance(q, h.nodes[nb].vec); d < curDist {
+				cur, curDist, changed = nb, d, true
+			}
+		}
+	}
+	return cur
+}
+
+// searchLayer is the beam search from the HNSW paper; results are sorted closest first.
+func (h *HNSW) searchLayer(q []float32, start int32, ef, l int) []candidate {
+	visited := map[int32]bool{start: true}
+	first := candidate{idx: start, dist: distance(q, h.nodes[start].vec)}
+	frontier := &minHeap{first}
+	results := &maxHeap{first}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+		if results.Len() >= ef && c.dist > (*results)[0].dist {
+			break
+		}
+		n := h.nodes[c.idx]
+		if l >= len(n.neighbors) {
+			continue
+		}
+		for _, nb := range n.neighbors[l] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			d := distance(q, h.nodes[nb].vec)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(frontier, candidate{idx: nb, dist: d})
+				heap.Push(results, candidate{idx: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(candidate)
+	}
+	return out
+}
+
+type candidate struct {
+	idx  int32
+	dist float32
+}
+
+func closest(sorted []candidate, n int) []int32 {
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	out := make([]int32, len(sorted))
+	for i, c := range sorted {
+		out[i] = c.idx
+	}
+	return out
+}
+
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(i, j int) bool { return c[i].dist < c[j].dist })
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// distance is cosine distance for unit vectors.
+func distance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return 1 - dot
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x * scale
+	}
+	return out
+}
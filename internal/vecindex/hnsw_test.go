@@ -0,0 +1,88 @@
+package vecindex
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomVec(r *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(r.NormFloat64())
+	}
+	return v
+}
+
+func TestHNSWRecallAgainstExactSearch(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	const n, dim, k = 4000, 32, 10
+
+	idx := New()
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vecs[i] = randomVec(r, dim)
+		idx.Add(fmt.Sprintf("v%d", i), vecs[i])
+	}
+	if idx.Len() != n {
+		t.Fatalf("Len = %d, want %d", idx.Len(), n)
+	}
+
+	hits, total := 0, 0
+	for q := 0; q < 50; q++ {
+		query := randomVec(r, dim)
+		nq := normalize(query)
+		type scored struct {
+			id  string
+			sim float32
+		}
+		exact := make([]scored, n)
+		for i, v := range vecs {
+			exact[i] = scored{fmt.Sprintf("v%d", i), 1 - distance(nq, normalize(v))}
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].sim > exact[j].sim })
+
+		want := map[string]bool{}
+		for _, e := range exact[:k] {
+			want[e.id] = true
+		}
+		for _, m := range idx.Search(query, k) {
+			if want[m.ID] {
+				hits++
+			}
+		}
+		total += k
+	}
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Fatalf("recall@%d = %.2f, want >= 0.9", k, recall)
+	}
+}
+
+func TestHNSWDeleteAndReplace(t *testing.T) {
+	idx := New()
+	idx.Add("a", []float32{1, 0, 0})
+	idx.Add("b", []float32{0, 1, 0})
+	idx.Add("c", []float32{0.9, 0.1, 0})
+
+	if got := idx.Search([]float32{1, 0, 0}, 1); len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("unexpected top hit: %v", got)
+	}
+
+	idx.Delete("a")
+	if got := idx.Search([]float32{1, 0, 0}, 1); len(got) != 1 || got[0].ID != "c" {
+		t.Fatalf("deleted vector still returned: %v", got)
+	}
+
+	idx.Add("b", []float32{1, 0, 0})
+	if got := idx.Search([]float32{1, 0, 0}, 3); len(got) != 2 || got[0].ID != "b" {
+		t.Fatalf("replaced vector not used: %v", got)
+	}
+	if idx.Len() != 2 || idx.Tombstones() != 2 {
+		t.Fatalf("Len=%d Tombstones=%d, want 2 and 2", idx.Len(), idx.Tombstones())
+	}
+
+	if got := idx.Search([]float32{1, 0}, 1); got != nil {
+		t.Fatalf("mismatched dimension should return nothing, got %v", got)
+	}
+}
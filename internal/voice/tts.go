@@ -0,0 +1,206 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Synthesizer turns reply text into audio for voice messages
+type Synthesizer struct {
+	provider Provider
+	format   string // audio format the provider produces
+	voice    string
+	speed    float64
+}
+
+// SynthesizerConfig holds synthesizer configuration
+type SynthesizerConfig struct {
+	Provider string  // "edge-tts", "openai", "piper"
+	APIKey   string  // API key for OpenAI
+	Voice    string  // Voice name (edge-tts, OpenAI)
+	Model    string  // Model file (piper)
+	Speed    float64 // Speech rate (1.0 = normal)
+}
+
+// NewSynthesizer creates a new Synthesizer
+func NewSynthesizer(cfg SynthesizerConfig) (*Synthesizer, error) {
+	s := &Synthesizer{voice: cfg.Voice, speed: cfg.Speed}
+	switch cfg.Provider {
+	case "edge-tts", "edge":
+		s.provider, s.format = NewEdgeTTSProvider(), "mp3"
+		if s.voice == "" {
+			s.voice = "zh-CN-XiaoxiaoNeural"
+		}
+	case "openai":
+		p, err := NewOpenAIProvider(cfg.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		s.provider, s.format = p, "mp3"
+	case "piper":
+		p, err := NewPiperProvider(cfg.Model)
+		if err != nil {
+			return nil, err
+		}
+		s.provider, s.format = p, "wav"
+	default:
+		return nil, fmt.Errorf("unknown TTS provider: %s", cfg.Provider)
+	}
+	return s, nil
+}
+
+// Synthesize converts text to audio and returns it with its format
+func (s *Synthesizer) Synthesize(ctx context.Context, text string) ([]byte, string, error) {
+	audio, err := s.provider.TextToSpeech(ctx, text, TTSOptions{
+		Voice:  s.voice,
+		Speed:  s.speed,
+		Format: s.format,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(audio) == 0 {
+		return nil, "", fmt.Errorf("%s produced no audio", s.provider.Name())
+	}
+	return audio, s.format, nil
+}
+
+// ProviderName returns the name of the underlying provider
+func (s *Synthesizer) ProviderName() string {
+	return s.provider.Name()
+}
+
+// EdgeTTSProvider uses the edge-tts command (pip install edge-tts)
+type EdgeTTSProvider struct{}
+
+// NewEdgeTTSProvider creates an edge-tts provider
+func NewEdgeTTSProvider() *EdgeTTSProvider {
+	return &EdgeTTSProvider{}
+}
+
+// Name returns the provider name
+func (p *EdgeTTSProvider) Name() string {
+	return "edge-tts"
+}
+
+// TextToSpeech runs edge-tts and returns MP3 audio
+func (p *EdgeTTSProvider) TextToSpeech(ctx context.Context, text string, opts TTSOptions) ([]byte, error) {
+	out, cleanup, err := tempAudioPath("mp3")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args := []string{"--text", text, "--write-media", out}
+	if opts.Voice != "" {
+		args = append(args, "--voice", opts.Voice)
+	}
+	if opts.Speed != 0 && opts.Speed != 1 {
+		args = append(args, fmt.Sprintf("--rate=%+d%%", int((opts.Speed-1)*100)))
+	}
+	cmd := exec.CommandContext(ctx, "edge-tts", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("edge-tts failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return os.ReadFile(out)
+}
+
+// SpeechToText - edge-tts only synthesizes, use system fallback
+func (p *EdgeTTSProvider) SpeechToText(ctx context.Context, audio []byte, opts STTOptions) (string, error) {
+	return NewSystemProvider().SpeechToText(ctx, audio, opts)
+}
+
+// PiperProvider uses a local piper model for offline TTS
+type PiperProvider struct {
+	model string
+}
+
+// NewPiperProvider creates a piper provider for an .onnx voice model
+func NewPiperProvider(model string) (*PiperProvider, error) {
+	if model == "" {
+		return nil, fmt.Errorf("piper model path required")
+	}
+	return &PiperProvider{model: model}, nil
+}
+
+// Name returns the provider name
+func (p *PiperProvider) Name() string {
+	return "piper"
+}
+
+// TextToSpeech runs piper and returns WAV audio
+func (p *PiperProvider) TextToSpeech(ctx context.Context, text string, opts TTSOptions) ([]byte, error) {
+	out, cleanup, err := tempAudioPath("wav")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args := []string{"--model", p.model, "--output_file", out}
+	if opts.Speed > 0 && opts.Speed != 1 {
+		// piper's length scale is the inverse of speed
+		args = append(args, "--length_scale", fmt.Sprintf("%.2f", 1/opts.Speed))
+	}
+	cmd := exec.CommandContext(ctx, "piper", args...)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("piper failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return os.ReadFile(out)
+}
+
+// SpeechToText - piper only synthesizes, use system fallback
+func (p *PiperProvider) SpeechToText(ctx context.Context, audio []byte, opts STTOptions) (string, error) {
+	return NewSystemProvider().SpeechToText(ctx, audio, opts)
+}
+
+// ConvertAudio re-encodes audio with ffmpeg, e.g. to AMR for WeCom voice
+// messages. Only formats ffmpeg names by extension are supported.
+func ConvertAudio(ctx context.Context, audio []byte, from, to string) ([]byte, error) {
+	if from == to {
+		return audio, nil
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	in, cleanupIn, err := tempAudioPath(from)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupIn()
+	out, cleanupOut, err := tempAudioPath(to)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupOut()
+	if err := os.WriteFile(in, audio, 0o600); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-y", "-i", in}
+	if to == "amr" {
+		// AMR-NB is 8 kHz mono; WeCom and WeChat reject anything else
+		args = append(args, "-ar", "8000", "-ac", "1", "-c:a", "libopencore_amrnb", "-b:a", "12.2k")
+	}
+	args = append(args, out)
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w\n%s", err, output)
+	}
+	return os.ReadFile(out)
+}
+
+// tempAudioPath returns a fresh temp file path with the given extension
+func tempAudioPath(ext string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "coco-tts-*")
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.Join(dir, "audio."+ext), func() { os.RemoveAll(dir) }, nil
+}
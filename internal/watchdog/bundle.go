@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dumpBundle writes goroutine stacks, the recent trace and a summary of the
+// incident to a new directory under DumpDir, then prunes old bundles.
+func (w *Watchdog) dumpBundle(inc Incident) (string, error) {
+	dir := filepath.Join(w.cfg.DumpDir, inc.At.Format("20060102-150405")+"-"+inc.Kind)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	stacks, err := os.Create(filepath.Join(dir, "goroutines.txt"))
+	if err != nil {
+		return "", err
+	}
+	err = pprof.Lookup("goroutine").WriteTo(stacks, 2)
+	stacks.Close()
+	if err != nil {
+		return "", fmt.Errorf("write goroutine stacks: %w", err)
+	}
+
+	w.mu.Lock()
+	trace := w.recentTraceLocked()
+	var inflight []string
+	for _, op := range w.ops {
+		inflight = append(inflight, fmt.Sprintf("%s %s (running %s, conversation %s)",
+			op.kind, op.detail, inc.At.Sub(op.started).Round(time.Second), op.conversation))
+	}
+	w.mu.Unlock()
+	sort.Strings(inflight)
+
+	if err := os.WriteFile(filepath.Join(dir, "trace.txt"), []byte(strings.Join(trace, "\n")+"\n"), 0o600); err != nil {
+		return "", err
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "time:         %s\n", inc.At.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "kind:         %s\n", inc.Kind)
+	fmt.Fprintf(&sb, "subsystem:    %s\n", inc.Subsystem)
+	fmt.Fprintf(&sb, "detail:       %s\n", inc.Detail)
+	if inc.Conversation != "" {
+		fmt.Fprintf(&sb, "conversation: %s\n", inc.Conversation)
+	}
+	if inc.Duration > 0 {
+		fmt.Fprintf(&sb, "duration:     %s\n", inc.Duration)
+	}
+	fmt.Fprintf(&sb, "goroutines:   %d\n", runtime.NumGoroutine())
+	fmt.Fprintf(&sb, "heap in use:  %s (sys %s, %d GCs)\n", formatBytes(ms.HeapInuse), formatBytes(ms.Sys), ms.NumGC)
+	fmt.Fprintf(&sb, "go:           %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	sb.WriteString("\nin-flight operations:\n")
+	for _, line := range inflight {
+		sb.WriteString("  " + line + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.txt"), []byte(sb.String()), 0o600); err != nil {
+		return "", err
+	}
+
+	w.pruneBundles()
+	return dir, nil
+}
+
+// pruneBundles keeps the newest MaxBundles bundle directories.
+func (w *Watchdog) pruneBundles() {
+	entries, err := os.ReadDir(w.cfg.DumpDir)
+	if err != nil {
+		return
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	// Names start with a timestamp, so lexical order is age order.
+	sort.Strings(dirs)
+	for len(dirs) > w.cfg.MaxBundles {
+		os.RemoveAll(filepath.Join(w.cfg.DumpDir, dirs[0]))
+		dirs = dirs[1:]
+	}
+}
@@ -0,0 +1,23 @@
+package watchdog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// LoopDetector counts identical tool calls within one turn.
+type LoopDetector struct {
+	seen map[string]int
+}
+
+// Observe records a call and returns how many times this exact call (same
+// tool and same input) has been made so far.
+func (d *LoopDetector) Observe(tool string, input []byte) int {
+	if d.seen == nil {
+		d.seen = make(map[string]int)
+	}
+	sum := sha256.Sum256(append([]byte(tool+"\x00"), input...))
+	key := hex.EncodeToString(sum[:])
+	d.seen[key]++
+	return d.seen[key]
+}
@@ -0,0 +1,251 @@
+// Package watchdog watches a running process for operations that never
+// finish, tool loops that repeat the same call and runaway memory, and
+// writes a diagnostic bundle when it finds one.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Incident kinds.
+const (
+	KindStuck  = "stuck"  // An operation ran past its timeout
+	KindLoop   = "loop"   // The same tool call keeps repeating
+	KindMemory = "memory" // Heap grew past the limit
+)
+
+// Config controls what counts as unhealthy.
+type Config struct {
+	Interval    time.Duration            // How often to check (default 15s)
+	Timeouts    map[string]time.Duration // Per operation kind, e.g. "provider", "tool"
+	MemoryLimit uint64                   // Heap bytes in use that trigger a memory incident (0 disables)
+	DumpDir     string                   // Where bundles are written (empty = no bundles)
+	MaxBundles  int                      // Bundles to keep (default 10)
+	TraceSize   int                      // Recent events kept for bundles (default 200)
+}
+
+// Incident describes a detected problem.
+type Incident struct {
+	Kind         string
+	Subsystem    string // Operation kind or "memory"
+	Detail       string
+	Conversation string // Conversation the operation belonged to, if any
+	Duration     time.Duration
+	Bundle       string // Path of the diagnostic bundle, if written
+	At           time.Time
+
+	// Cancel aborts the stuck operation; nil for other kinds.
+	Cancel func()
+}
+
+// Watchdog tracks in-flight operations and samples memory.
+type Watchdog struct {
+	cfg        Config
+	onIncident func(Incident)
+
+	mu       sync.Mutex
+	nextID   uint64
+	ops      map[uint64]*operation
+	trace    []string
+	traceAt  int
+	memAlarm bool
+	stop     chan struct{}
+	started  bool
+}
+
+type operation struct {
+	kind         string
+	detail       string
+	conversation string
+	started      time.Time
+	cancel       context.CancelFunc
+	reported     bool
+}
+
+// New creates a watchdog. onIncident is called from the watchdog goroutine
+// (or the caller of Report) after the bundle has been written.
+func New(cfg Config, onIncident func(Incident)) *Watchdog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.MaxBundles <= 0 {
+		cfg.MaxBundles = 10
+	}
+	if cfg.TraceSize <= 0 {
+		cfg.TraceSize = 200
+	}
+	return &Watchdog{
+		cfg:        cfg,
+		onIncident: onIncident,
+		ops:        make(map[uint64]*operation),
+		trace:      make([]string, 0, cfg.TraceSize),
+	}
+}
+
+// Start runs periodic checks until Stop is called.
+func (w *Watchdog) Start() {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return
+	}
+	w.started = true
+	w.stop = make(chan struct{})
+	stop := w.stop
+	w.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				w.Check(now)
+			}
+		}
+	}()
+}
+
+// Stop ends periodic checks.
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		close(w.stop)
+		w.started = false
+	}
+}
+
+// Begin registers an operation and returns a context the watchdog can cancel.
+// Call done when the operation returns. A nil Watchdog is a no-op.
+func (w *Watchdog) Begin(ctx context.Context, kind, detail, conversation string) (context.Context, func()) {
+	if w == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.nextID++
+	id := w.nextID
+	op := &operation{kind: kind, detail: detail, conversation: conversation, started: time.Now(), cancel: cancel}
+	w.ops[id] = op
+	w.addTraceLocked(fmt.Sprintf("begin %s %s", kind, detail))
+	w.mu.Unlock()
+
+	return ctx, func() {
+		w.mu.Lock()
+		delete(w.ops, id)
+		w.addTraceLocked(fmt.Sprintf("end   %s %s (%s)", kind, detail, time.Since(op.started).Round(time.Millisecond)))
+		w.mu.Unlock()
+		cancel()
+	}
+}
+
+// Tracef adds a line to the recent trace.
+func (w *Watchdog) Tracef(format string, args ...any) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.addTraceLocked(fmt.Sprintf(format, args...))
+	w.mu.Unlock()
+}
+
+func (w *Watchdog) addTraceLocked(line string) {
+	line = time.Now().Format("15:04:05.000") + " " + line
+	if len(w.trace) < w.cfg.TraceSize {
+		w.trace = append(w.trace, line)
+		return
+	}
+	w.trace[w.traceAt] = line
+	w.traceAt = (w.traceAt + 1) % w.cfg.TraceSize
+}
+
+// recentTraceLocked returns the trace oldest first. Callers must hold w.mu.
+func (w *Watchdog) recentTraceLocked() []string {
+	out := make([]string, 0, len(w.trace))
+	out = append(out, w.trace[w.traceAt:]...)
+	return append(out, w.trace[:w.traceAt]...)
+}
+
+// Report records an incident found by the caller, such as a tool loop.
+func (w *Watchdog) Report(kind, subsystem, detail, conversation string) Incident {
+	inc := Incident{Kind: kind, Subsystem: subsystem, Detail: detail, Conversation: conversation, At: time.Now()}
+	if w == nil {
+		return inc
+	}
+	w.raise(&inc)
+	return inc
+}
+
+// Check looks for stuck operations and memory over the limit once.
+func (w *Watchdog) Check(now time.Time) {
+	var incidents []Incident
+	w.mu.Lock()
+	for _, op := range w.ops {
+		timeout := w.cfg.Timeouts[op.kind]
+		if op.reported || timeout <= 0 || now.Sub(op.started) < timeout {
+			continue
+		}
+		op.reported = true
+		incidents = append(incidents, Incident{
+			Kind:         KindStuck,
+			Subsystem:    op.kind,
+			Detail:       op.detail,
+			Conversation: op.conversation,
+			Duration:     now.Sub(op.started).Round(time.Second),
+			At:           now,
+			Cancel:       op.cancel,
+		})
+	}
+	w.mu.Unlock()
+
+	if w.cfg.MemoryLimit > 0 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		w.mu.Lock()
+		switch {
+		case ms.HeapInuse > w.cfg.MemoryLimit && !w.memAlarm:
+			w.memAlarm = true
+			incidents = append(incidents, Incident{
+				Kind:      KindMemory,
+				Subsystem: "memory",
+				Detail:    fmt.Sprintf("heap in use %s exceeds %s (%d goroutines)", formatBytes(ms.HeapInuse), formatBytes(w.cfg.MemoryLimit), runtime.NumGoroutine()),
+				At:        now,
+			})
+		case ms.HeapInuse < w.cfg.MemoryLimit*8/10:
+			// Re-arm once memory is clearly back under the limit.
+			w.memAlarm = false
+		}
+		w.mu.Unlock()
+	}
+
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].Duration > incidents[j].Duration })
+	for i := range incidents {
+		w.raise(&incidents[i])
+	}
+}
+
+func (w *Watchdog) raise(inc *Incident) {
+	w.Tracef("incident %s %s: %s", inc.Kind, inc.Subsystem, inc.Detail)
+	if w.cfg.DumpDir != "" {
+		if path, err := w.dumpBundle(*inc); err == nil {
+			inc.Bundle = path
+		} else {
+			inc.Detail += fmt.Sprintf(" (bundle failed: %v)", err)
+		}
+	}
+	if w.onIncident != nil {
+		w.onIncident(*inc)
+	}
+}
+
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.0fMB", float64(n)/(1<<20))
+}
@@ -0,0 +1,83 @@
+package watchdog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckReportsStuckOperationOnce(t *testing.T) {
+	dir := t.TempDir()
+	var got []Incident
+	w := New(Config{
+		Timeouts:   map[string]time.Duration{"provider": time.Minute},
+		DumpDir:    dir,
+		MaxBundles: 1,
+	}, func(inc Incident) { got = append(got, inc) })
+
+	ctx, done := w.Begin(context.Background(), "provider", "deepseek-chat", "wecom:c:u")
+	defer done()
+	_, fast := w.Begin(context.Background(), "tool", "file_read", "")
+	fast()
+
+	w.Check(time.Now())
+	if len(got) != 0 {
+		t.Fatalf("nothing should be stuck yet, got %+v", got)
+	}
+
+	w.Check(time.Now().Add(2 * time.Minute))
+	w.Check(time.Now().Add(3 * time.Minute))
+	if len(got) != 1 {
+		t.Fatalf("expected one incident, got %d", len(got))
+	}
+	inc := got[0]
+	if inc.Kind != KindStuck || inc.Subsystem != "provider" || inc.Conversation != "wecom:c:u" {
+		t.Fatalf("unexpected incident: %+v", inc)
+	}
+
+	for _, name := range []string{"goroutines.txt", "trace.txt", "summary.txt"} {
+		if _, err := os.Stat(filepath.Join(inc.Bundle, name)); err != nil {
+			t.Fatalf("bundle missing %s: %v", name, err)
+		}
+	}
+	trace, _ := os.ReadFile(filepath.Join(inc.Bundle, "trace.txt"))
+	if !strings.Contains(string(trace), "end   tool file_read") {
+		t.Fatalf("trace should include finished operations:\n%s", trace)
+	}
+
+	inc.Cancel()
+	if ctx.Err() == nil {
+		t.Fatalf("Cancel should abort the operation's context")
+	}
+}
+
+func TestReportPrunesOldBundles(t *testing.T) {
+	dir := t.TempDir()
+	w := New(Config{DumpDir: dir, MaxBundles: 2}, nil)
+	for _, name := range []string{"20200101-000000-loop", "20200102-000000-loop"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inc := w.Report(KindLoop, "tool", "web_search repeated 3 times", "")
+	if inc.Bundle == "" {
+		t.Fatalf("expected a bundle")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != "20200102-000000-loop" {
+		t.Fatalf("expected the oldest bundle pruned, got %v", entries)
+	}
+}
+
+func TestLoopDetectorCountsIdenticalCalls(t *testing.T) {
+	var d LoopDetector
+	d.Observe("web_search", []byte(`{"query":"a"}`))
+	d.Observe("web_search", []byte(`{"query":"b"}`))
+	if n := d.Observe("web_search", []byte(`{"query":"a"}`)); n != 2 {
+		t.Fatalf("expected 2 identical calls, got %d", n)
+	}
+}
@@ -0,0 +1,95 @@
+package wsync
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	sealMagic       = "CSY1"
+	sealSaltSize    = 16
+	sealKeySize     = 32
+	sealIterations  = 210000
+	sealHeaderBytes = len(sealMagic) + sealSaltSize
+)
+
+// ErrBadPassphrase is returned when a blob cannot be decrypted.
+var ErrBadPassphrase = errors.New("cannot decrypt sync data (wrong passphrase?)")
+
+// namespaceSalt keeps Namespace from deriving the same bytes as a blob key.
+const namespaceSalt = "coco-sync-namespace"
+
+// Namespace names the store area shared by the devices that use passphrase,
+// so one Keeper can hold several people's workspaces apart. It goes through
+// the same PBKDF2 as the blob key: the store learns no more about the
+// passphrase from the namespace than from a sealed blob.
+func Namespace(passphrase string) (string, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, []byte(namespaceSalt), sealIterations, 16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// seal encrypts data with AES-256-GCM using a key derived from passphrase.
+// Layout: magic | salt | nonce | ciphertext.
+func seal(passphrase string, data []byte) ([]byte, error) {
+	salt := make([]byte, sealSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, sealHeaderBytes+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, sealMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(sealMagic)), nil
+}
+
+// open reverses seal.
+func open(passphrase string, blob []byte) ([]byte, error) {
+	if len(blob) < sealHeaderBytes || !bytes.Equal(blob[:len(sealMagic)], []byte(sealMagic)) {
+		return nil, fmt.Errorf("not a coco sync blob")
+	}
+	salt := blob[len(sealMagic):sealHeaderBytes]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := blob[sealHeaderBytes:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sync blob truncated")
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], []byte(sealMagic))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, sealIterations, sealKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
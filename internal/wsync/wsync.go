@@ -0,0 +1,485 @@
+// Package wsync replicates workspace prompt files, memories and user cron jobs
+// between devices through an encrypted manifest stored in an objstore.Store.
+//
+// Each sync does a three-way merge per item: the local copy, the remote copy and
+// the hash recorded at the last successful sync (the base). An item changed only
+// on one side is copied to the other. An item changed on both sides is a conflict:
+// the newer file wins and the other version is kept next to it as a
+// "<name>.conflict-<device>-<time>" copy.
+package wsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/objstore"
+)
+
+const (
+	manifestKey     = "manifest.enc"
+	manifestVersion = 1
+	stateFile       = ".coco/sync-state.json"
+)
+
+// JobStore is the subset of the cron scheduler used to replicate jobs.
+type JobStore interface {
+	ListJobs() []*cron.Job
+	ImportJob(job *cron.Job) error
+	RemoveJob(id string) error
+}
+
+// Options configures an Engine.
+type Options struct {
+	Store      objstore.Store
+	WorkDir    string   // workspace root
+	Files      []string // workspace-relative paths to replicate
+	Passphrase string
+	DeviceID   string
+	Jobs       JobStore             // nil disables job replication
+	JobFilter  func(*cron.Job) bool // which jobs to replicate; nil means all
+	Now        func() time.Time     // for tests
+}
+
+// Engine performs workspace syncs. It is safe for concurrent use.
+type Engine struct {
+	opts Options
+	mu   sync.Mutex
+}
+
+// Result summarizes one sync run.
+type Result struct {
+	Pulled       []string
+	Pushed       []string
+	Conflicts    []string
+	JobsImported int
+	JobsRemoved  int
+	JobsPushed   int
+	Errors       []string
+}
+
+// Changed reports whether the sync moved anything.
+func (r *Result) Changed() bool {
+	return len(r.Pulled)+len(r.Pushed)+len(r.Conflicts)+r.JobsImported+r.JobsRemoved+r.JobsPushed > 0
+}
+
+// Summary renders a one-line description of the result.
+func (r *Result) Summary() string {
+	parts := []string{
+		fmt.Sprintf("pulled %d", len(r.Pulled)),
+		fmt.Sprintf("pushed %d", len(r.Pushed)),
+		fmt.Sprintf("conflicts %d", len(r.Conflicts)),
+	}
+	if r.JobsImported+r.JobsRemoved+r.JobsPushed > 0 {
+		parts = append(parts, fmt.Sprintf("jobs +%d/-%d/↑%d", r.JobsImported, r.JobsRemoved, r.JobsPushed))
+	}
+	if len(r.Errors) > 0 {
+		parts = append(parts, fmt.Sprintf("errors %d", len(r.Errors)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type fileEntry struct {
+	Hash    string    `json:"hash"`
+	ModTime time.Time `json:"mod_time"`
+	Device  string    `json:"device"`
+	Content []byte    `json:"content"`
+}
+
+type manifest struct {
+	Version   int                  `json:"version"`
+	Device    string               `json:"device"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Files     map[string]fileEntry `json:"files"`
+	Jobs      map[string]*cron.Job `json:"jobs,omitempty"`
+}
+
+type syncState struct {
+	LastSync time.Time         `json:"last_sync"`
+	Files    map[string]string `json:"files"`
+	Jobs     map[string]string `json:"jobs"`
+}
+
+// New validates options and returns an Engine.
+func New(opts Options) (*Engine, error) {
+	if opts.Store == nil {
+		return nil, fmt.Errorf("sync store is required")
+	}
+	if strings.TrimSpace(opts.Passphrase) == "" {
+		return nil, fmt.Errorf("sync passphrase is required")
+	}
+	if strings.TrimSpace(opts.WorkDir) == "" {
+		return nil, fmt.Errorf("workspace dir is required")
+	}
+	if strings.TrimSpace(opts.DeviceID) == "" {
+		host, _ := os.Hostname()
+		if host == "" {
+			host = "device"
+		}
+		opts.DeviceID = host
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Engine{opts: opts}, nil
+}
+
+// Sync runs one pull/merge/push cycle.
+func (e *Engine) Sync(ctx context.Context) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	remote, err := e.loadRemote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := e.loadState()
+	res := &Result{}
+	now := e.opts.Now()
+
+	next := &manifest{
+		Version: manifestVersion,
+		Device:  e.opts.DeviceID,
+		Files:   map[string]fileEntry{},
+		Jobs:    map[string]*cron.Job{},
+	}
+	// Start from the remote set so entries that fail to merge are not dropped.
+	for name, entry := range remote.Files {
+		next.Files[name] = entry
+	}
+	remoteDirty := false
+
+	for _, name := range e.files(remote) {
+		dirty, err := e.mergeFile(name, remote, next, state, res, now)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		remoteDirty = remoteDirty || dirty
+	}
+
+	if e.opts.Jobs != nil {
+		if e.mergeJobs(remote, next, state, res) {
+			remoteDirty = true
+		}
+	} else {
+		next.Jobs = remote.Jobs
+	}
+
+	if remoteDirty || remote.Version == 0 {
+		next.UpdatedAt = now
+		if err := e.saveRemote(ctx, next); err != nil {
+			return res, err
+		}
+	}
+
+	state.LastSync = now
+	if err := e.saveState(state); err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("save state: %v", err))
+	}
+	return res, nil
+}
+
+// files returns the configured paths plus every path another device has published.
+func (e *Engine) files(remote *manifest) []string {
+	candidates := append([]string(nil), e.opts.Files...)
+	for name := range remote.Files {
+		candidates = append(candidates, name)
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, f := range candidates {
+		f = filepath.ToSlash(filepath.Clean(strings.TrimSpace(f)))
+		if f == "" || f == "." || strings.HasPrefix(f, "../") || filepath.IsAbs(f) || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mergeFile reconciles one file and reports whether the remote manifest changed.
+func (e *Engine) mergeFile(name string, remote, next *manifest, state *syncState, res *Result, now time.Time) (bool, error) {
+	path := filepath.Join(e.opts.WorkDir, filepath.FromSlash(name))
+	local, localMod, err := readFile(path)
+	if err != nil {
+		return false, err
+	}
+	localHash := ""
+	if local != nil {
+		localHash = hashBytes(local)
+	}
+	remoteEntry, hasRemote := remote.Files[name]
+	remoteHash := ""
+	if hasRemote {
+		remoteHash = remoteEntry.Hash
+	}
+	base := state.Files[name]
+
+	push := func() {
+		next.Files[name] = fileEntry{Hash: localHash, ModTime: localMod, Device: e.opts.DeviceID, Content: local}
+		state.Files[name] = localHash
+		res.Pushed = append(res.Pushed, name)
+	}
+	pull := func() error {
+		if err := writeFile(path, remoteEntry.Content); err != nil {
+			return err
+		}
+		state.Files[name] = remoteHash
+		res.Pulled = append(res.Pulled, name)
+		return nil
+	}
+
+	switch {
+	case localHash == remoteHash:
+		if localHash != "" {
+			state.Files[name] = localHash
+		}
+		return false, nil
+	case localHash == "":
+		// Missing locally: never propagate deletions, just restore.
+		return false, pull()
+	case !hasRemote || remoteHash == base:
+		push()
+		return true, nil
+	case localHash == base:
+		return false, pull()
+	}
+
+	// Both sides changed since the last sync.
+	if remoteEntry.ModTime.After(localMod) {
+		conflictPath := conflictName(path, e.opts.DeviceID, now)
+		if err := writeFile(conflictPath, local); err != nil {
+			return false, err
+		}
+		if err := pull(); err != nil {
+			return false, err
+		}
+		res.Conflicts = append(res.Conflicts, filepath.ToSlash(mustRel(e.opts.WorkDir, conflictPath)))
+		return false, nil
+	}
+	conflictPath := conflictName(path, remoteEntry.Device, now)
+	if err := writeFile(conflictPath, remoteEntry.Content); err != nil {
+		return false, err
+	}
+	push()
+	res.Conflicts = append(res.Conflicts, filepath.ToSlash(mustRel(e.opts.WorkDir, conflictPath)))
+	return true, nil
+}
+
+// mergeJobs reconciles replicated cron jobs by ID and reports whether the remote manifest changed.
+func (e *Engine) mergeJobs(remote, next *manifest, state *syncState, res *Result) bool {
+	local := map[string]*cron.Job{}
+	for _, job := range e.opts.Jobs.ListJobs() {
+		if e.opts.JobFilter == nil || e.opts.JobFilter(job) {
+			local[job.ID] = job
+		}
+	}
+
+	ids := map[string]bool{}
+	for id := range local {
+		ids[id] = true
+	}
+	for id := range remote.Jobs {
+		ids[id] = true
+	}
+	for id := range state.Jobs {
+		ids[id] = true
+	}
+
+	dirty := false
+	newState := map[string]string{}
+	for id := range ids {
+		l, r := local[id], remote.Jobs[id]
+		lh, rh, base := jobHash(l), jobHash(r), state.Jobs[id]
+
+		switch {
+		case lh == rh:
+			if l != nil {
+				next.Jobs[id] = r
+				newState[id] = lh
+			}
+		case rh == base || (lh != base && l != nil):
+			// Changed only locally, or on both sides (local edit wins).
+			dirty = true
+			if l == nil {
+				continue
+			}
+			next.Jobs[id] = syncableJob(l)
+			newState[id] = lh
+			res.JobsPushed++
+		case r == nil:
+			// Deleted remotely, unchanged locally.
+			if err := e.opts.Jobs.RemoveJob(id); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("remove job %s: %v", id, err))
+				continue
+			}
+			res.JobsRemoved++
+		default:
+			// Changed only remotely, or edited remotely while deleted locally.
+			next.Jobs[id] = r
+			if err := e.opts.Jobs.ImportJob(r); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("import job %s (%s): %v", id, r.Name, err))
+				continue
+			}
+			res.JobsImported++
+			newState[id] = rh
+		}
+	}
+	state.Jobs = newState
+	return dirty
+}
+
+func syncableJob(job *cron.Job) *cron.Job {
+	if job == nil {
+		return nil
+	}
+	c := job.Clone()
+	c.LastRun = nil
+	c.LastError = ""
+	c.EntryID = 0
+	return c
+}
+
+func jobHash(job *cron.Job) string {
+	if job == nil {
+		return ""
+	}
+	data, err := json.Marshal(syncableJob(job))
+	if err != nil {
+		return ""
+	}
+	return hashBytes(data)
+}
+
+func (e *Engine) loadRemote(ctx context.Context) (*manifest, error) {
+	blob, err := e.opts.Store.Get(ctx, manifestKey)
+	if errors.Is(err, objstore.ErrNotFound) {
+		return &manifest{Files: map[string]fileEntry{}, Jobs: map[string]*cron.Job{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch remote manifest: %w", err)
+	}
+	plain, err := open(e.opts.Passphrase, blob)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(plain, &m); err != nil {
+		return nil, fmt.Errorf("parse remote manifest: %w", err)
+	}
+	if m.Version > manifestVersion {
+		return nil, fmt.Errorf("remote manifest version %d is newer than supported %d; upgrade coco", m.Version, manifestVersion)
+	}
+	if m.Files == nil {
+		m.Files = map[string]fileEntry{}
+	}
+	if m.Jobs == nil {
+		m.Jobs = map[string]*cron.Job{}
+	}
+	return &m, nil
+}
+
+func (e *Engine) saveRemote(ctx context.Context, m *manifest) error {
+	plain, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	blob, err := seal(e.opts.Passphrase, plain)
+	if err != nil {
+		return err
+	}
+	if err := e.opts.Store.Put(ctx, manifestKey, blob); err != nil {
+		return fmt.Errorf("upload manifest: %w", err)
+	}
+	return nil
+}
+
+func (e *Engine) statePath() string {
+	return filepath.Join(e.opts.WorkDir, filepath.FromSlash(stateFile))
+}
+
+func (e *Engine) loadState() *syncState {
+	st := &syncState{}
+	if data, err := os.ReadFile(e.statePath()); err == nil {
+		_ = json.Unmarshal(data, st)
+	}
+	if st.Files == nil {
+		st.Files = map[string]string{}
+	}
+	if st.Jobs == nil {
+		st.Jobs = map[string]string{}
+	}
+	return st
+}
+
+func (e *Engine) saveState(st *syncState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(e.statePath(), data)
+}
+
+// readFile returns nil content when the file does not exist.
+func readFile(path string) ([]byte, time.Time, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".sync-tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func conflictName(path, device string, now time.Time) string {
+	ext := filepath.Ext(path)
+	device = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' || r == ':' {
+			return '_'
+		}
+		return r
+	}, device)
+	return fmt.Sprintf("%s.conflict-%s-%s%s", strings.TrimSuffix(path, ext), device, now.Format("20060102-150405"), ext)
+}
+
+func mustRel(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
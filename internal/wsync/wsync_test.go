@@ -0,0 +1,227 @@
+package wsync
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kayz/coco/internal/cron"
+	"github.com/kayz/coco/internal/objstore"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore { return &memStore{data: map[string][]byte{}} }
+
+func (m *memStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	if !ok {
+		return nil, objstore.ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func (m *memStore) Put(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *memStore) List(_ context.Context, _ string) ([]objstore.Object, error) { return nil, nil }
+
+type memJobs struct {
+	jobs map[string]*cron.Job
+}
+
+func (m *memJobs) ListJobs() []*cron.Job {
+	var out []*cron.Job
+	for _, j := range m.jobs {
+		out = append(out, j.Clone())
+	}
+	return out
+}
+
+func (m *memJobs) ImportJob(job *cron.Job) error {
+	m.jobs[job.ID] = job.Clone()
+	return nil
+}
+
+func (m *memJobs) RemoveJob(id string) error {
+	delete(m.jobs, id)
+	return nil
+}
+
+func newDevice(t *testing.T, store objstore.Store, device string, jobs JobStore) (*Engine, string) {
+	t.Helper()
+	dir := t.TempDir()
+	e, err := New(Options{
+		Store:      store,
+		WorkDir:    dir,
+		Files:      []string{"SOUL.md", "memory/MEMORY.md"},
+		Passphrase: "correct horse",
+		DeviceID:   device,
+		Jobs:       jobs,
+	})
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	return e, dir
+}
+
+func writeWS(t *testing.T, dir, name, content string, mod time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readWS(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func mustSync(t *testing.T, e *Engine) *Result {
+	t.Helper()
+	res, err := e.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	return res
+}
+
+func TestSyncReplicatesFilesBetweenDevices(t *testing.T) {
+	store := newMemStore()
+	laptop, laptopDir := newDevice(t, store, "laptop", nil)
+	server, serverDir := newDevice(t, store, "server", nil)
+
+	base := time.Now().Add(-time.Hour)
+	writeWS(t, laptopDir, "SOUL.md", "soul v1", base)
+	writeWS(t, laptopDir, "memory/MEMORY.md", "mem v1", base)
+	writeWS(t, laptopDir, "memory/travel.md", "extra", base)
+
+	mustSync(t, laptop)
+	res := mustSync(t, server)
+	if len(res.Pulled) != 2 {
+		t.Fatalf("server should pull 2 files, got %v", res.Pulled)
+	}
+	if got := readWS(t, serverDir, "SOUL.md"); got != "soul v1" {
+		t.Fatalf("server SOUL.md = %q", got)
+	}
+
+	// One-sided edit on the server flows back to the laptop.
+	writeWS(t, serverDir, "memory/MEMORY.md", "mem v2", base.Add(time.Minute))
+	mustSync(t, server)
+	mustSync(t, laptop)
+	if got := readWS(t, laptopDir, "memory/MEMORY.md"); got != "mem v2" {
+		t.Fatalf("laptop MEMORY.md = %q", got)
+	}
+
+	blob := store.data[manifestKey]
+	if bytes.Contains(blob, []byte("mem v2")) {
+		t.Fatalf("manifest must be encrypted")
+	}
+}
+
+func TestSyncConflictKeepsBothVersions(t *testing.T) {
+	store := newMemStore()
+	laptop, laptopDir := newDevice(t, store, "laptop", nil)
+	server, serverDir := newDevice(t, store, "server", nil)
+
+	base := time.Now().Add(-time.Hour)
+	writeWS(t, laptopDir, "SOUL.md", "v1", base)
+	mustSync(t, laptop)
+	mustSync(t, server)
+
+	writeWS(t, laptopDir, "SOUL.md", "laptop edit", base.Add(time.Minute))
+	writeWS(t, serverDir, "SOUL.md", "server edit", base.Add(2*time.Minute))
+	mustSync(t, server)
+	res := mustSync(t, laptop)
+
+	if len(res.Conflicts) != 1 || !strings.Contains(res.Conflicts[0], "SOUL.conflict-laptop-") {
+		t.Fatalf("expected one laptop conflict copy, got %v", res.Conflicts)
+	}
+	if got := readWS(t, laptopDir, "SOUL.md"); got != "server edit" {
+		t.Fatalf("newer edit should win, got %q", got)
+	}
+	if got := readWS(t, laptopDir, res.Conflicts[0]); got != "laptop edit" {
+		t.Fatalf("conflict copy = %q", got)
+	}
+}
+
+func TestSyncMergesJobs(t *testing.T) {
+	store := newMemStore()
+	laptopJobs := &memJobs{jobs: map[string]*cron.Job{
+		"j1": {ID: "j1", Name: "standup", Schedule: "0 0 9 * * *", Enabled: true, Tag: "user-schedule"},
+	}}
+	serverJobs := &memJobs{jobs: map[string]*cron.Job{}}
+	laptop, _ := newDevice(t, store, "laptop", laptopJobs)
+	server, _ := newDevice(t, store, "server", serverJobs)
+
+	mustSync(t, laptop)
+	if res := mustSync(t, server); res.JobsImported != 1 {
+		t.Fatalf("server should import 1 job, got %+v", res)
+	}
+
+	// Deleting on the server removes the job on the laptop.
+	delete(serverJobs.jobs, "j1")
+	mustSync(t, server)
+	if res := mustSync(t, laptop); res.JobsRemoved != 1 || len(laptopJobs.jobs) != 0 {
+		t.Fatalf("laptop should remove job, got %+v jobs=%d", res, len(laptopJobs.jobs))
+	}
+}
+
+func TestSyncRejectsWrongPassphrase(t *testing.T) {
+	store := newMemStore()
+	laptop, laptopDir := newDevice(t, store, "laptop", nil)
+	writeWS(t, laptopDir, "SOUL.md", "v1", time.Now())
+	mustSync(t, laptop)
+
+	other, err := New(Options{Store: store, WorkDir: t.TempDir(), Passphrase: "wrong", DeviceID: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Sync(context.Background()); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+}
+
+func TestNamespaceFollowsPassphrase(t *testing.T) {
+	a1, err := Namespace("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, _ := Namespace("correct horse")
+	b, _ := Namespace("battery staple")
+	if a1 != a2 || a1 == b || len(a1) != 32 || strings.Trim(a1, "0123456789abcdef") != "" {
+		t.Fatalf("namespaces = %q %q %q", a1, a2, b)
+	}
+}
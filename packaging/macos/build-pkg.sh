@@ -0,0 +1,39 @@
+#!/usr/bin/env bash
+# Build the macOS installer package: installs coco to /usr/local/bin.
+#
+#   packaging/macos/build-pkg.sh <version> <binary> <out.pkg>
+#
+# PKG_SIGN_IDENTITY  "Developer ID Installer: ..." to sign the package
+# NOTARY_PROFILE     notarytool keychain profile to notarize and staple it
+set -euo pipefail
+
+version="$1"
+binary="$2"
+out="$3"
+
+root="$(mktemp -d)"
+trap 'rm -rf "$root"' EXIT
+install -d "$root/usr/local/bin"
+install -m 0755 "$binary" "$root/usr/local/bin/coco"
+
+unsigned="$out"
+if [[ -n "${PKG_SIGN_IDENTITY:-}" ]]; then
+  unsigned="${out%.pkg}-unsigned.pkg"
+fi
+
+pkgbuild \
+  --root "$root" \
+  --identifier com.kayz.coco.pkg \
+  --version "$version" \
+  --install-location / \
+  "$unsigned"
+
+if [[ -n "${PKG_SIGN_IDENTITY:-}" ]]; then
+  productsign --sign "$PKG_SIGN_IDENTITY" "$unsigned" "$out"
+  rm -f "$unsigned"
+fi
+
+if [[ -n "${NOTARY_PROFILE:-}" ]]; then
+  xcrun notarytool submit "$out" --keychain-profile "$NOTARY_PROFILE" --wait
+  xcrun stapler staple "$out"
+fi
@@ -0,0 +1,23 @@
+# Build the Windows installer: installs coco.exe to Program Files\coco and
+# adds it to PATH.
+#
+#   packaging\windows\build-msi.ps1 -Version 1.11.0 -Binary dist\coco-1.11.0-windows-amd64.exe -Arch x64 -Out dist\coco-1.11.0-windows-amd64.msi
+#
+# SIGN_CERT_SHA1  thumbprint of the code-signing certificate to sign the .msi
+param(
+    [Parameter(Mandatory = $true)][string]$Version,
+    [Parameter(Mandatory = $true)][string]$Binary,
+    [Parameter(Mandatory = $true)][string]$Out,
+    [string]$Arch = "x64"
+)
+
+$ErrorActionPreference = "Stop"
+$wxs = Join-Path $PSScriptRoot "coco.wxs"
+
+wix build $wxs -arch $Arch -d "Version=$Version" -d "Binary=$Binary" -o $Out
+if ($LASTEXITCODE -ne 0) { throw "wix build failed" }
+
+if (-not [string]::IsNullOrWhiteSpace($env:SIGN_CERT_SHA1)) {
+    signtool sign /sha1 $env:SIGN_CERT_SHA1 /fd SHA256 /tr http://timestamp.digicert.com /td SHA256 $Out
+    if ($LASTEXITCODE -ne 0) { throw "signtool failed" }
+}
@@ -0,0 +1,26 @@
+#!/usr/bin/env bash
+# Fill the Homebrew formula and Scoop manifest templates with the release
+# version and the sha256 sums from dist/checksums.txt.
+#
+#   scripts/release-manifests.sh <version> [dist]
+set -euo pipefail
+
+version="$1"
+dist="${2:-dist}"
+here="$(cd "$(dirname "$0")/.." && pwd)"
+
+args=(-e "s/@VERSION@/$version/g")
+while read -r sum name; do
+  target="${name#coco-$version-}"
+  args+=(-e "s/@SHA256_${target}@/$sum/g")
+done < "$dist/checksums.txt"
+
+sed "${args[@]}" "$here/packaging/homebrew/coco.rb.in" > "$dist/coco.rb"
+sed "${args[@]}" "$here/packaging/scoop/coco.json.in" > "$dist/coco.json"
+
+if grep -q '@SHA256_' "$dist/coco.rb" "$dist/coco.json"; then
+  echo "missing checksums for some release assets:" >&2
+  grep -ho '@SHA256_[^@]*@' "$dist/coco.rb" "$dist/coco.json" >&2
+  exit 1
+fi
+echo "wrote $dist/coco.rb and $dist/coco.json"